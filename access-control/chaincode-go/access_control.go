@@ -0,0 +1,23 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"log"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/access-control/chaincode-go/chaincode"
+)
+
+func main() {
+	aclChaincode, err := contractapi.NewChaincode(&chaincode.SmartContract{})
+	if err != nil {
+		log.Panicf("Error creating access-control chaincode: %v", err)
+	}
+
+	if err := aclChaincode.Start(); err != nil {
+		log.Panicf("Error starting access-control chaincode: %v", err)
+	}
+}
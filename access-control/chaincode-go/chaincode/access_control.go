@@ -0,0 +1,328 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// This sample assumes admins of Org1 manage the access policy of the channel. Only they can
+// define roles and assign them.
+const policyAdminMSPID = "Org1MSP"
+
+// adminOU is the organizational unit Fabric CA puts in admin certificates when NodeOUs are enabled
+const adminOU = "admin"
+
+// object names for prefix
+const (
+	rolePrefix       = "role"
+	assignmentPrefix = "assignment"
+)
+
+// wildcard operation granting every function of a chaincode, e.g. "token.*"
+const wildcardOperation = "*"
+
+// SmartContract provides functions for managing roles and checking access for other chaincodes
+type SmartContract struct {
+	contractapi.Contract
+}
+
+// Role is a named set of operations. An operation is written chaincode.function, e.g. token.Mint.
+type Role struct {
+	ObjectType  string   `json:"objectType"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Operations  []string `json:"operations"`
+}
+
+// Assignment grants a role to a subject, which is either a client ID or an MSPID. A role
+// assigned to an MSPID applies to every client of that org.
+type Assignment struct {
+	ObjectType string `json:"objectType"`
+	Subject    string `json:"subject"`
+	Role       string `json:"role"`
+	AssignedBy string `json:"assignedBy"`
+}
+
+// event provides an organized struct for emitting policy change events
+type event struct {
+	Subject string `json:"subject"`
+	Role    string `json:"role"`
+	Action  string `json:"action"`
+}
+
+// DefineRole creates a role or replaces the operations of an existing one. operations is a
+// JSON array, e.g. ["token.Mint","token.Burn"].
+func (s *SmartContract) DefineRole(ctx contractapi.TransactionContextInterface, name string, description string, operations string) error {
+	err := _requirePolicyAdmin(ctx)
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		return fmt.Errorf("role name must be set")
+	}
+
+	var roleOperations []string
+	err = json.Unmarshal([]byte(operations), &roleOperations)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal operations: %v", err)
+	}
+	if len(roleOperations) == 0 {
+		return fmt.Errorf("role %s must grant at least one operation", name)
+	}
+	for _, operation := range roleOperations {
+		err = _validateOperation(operation)
+		if err != nil {
+			return err
+		}
+	}
+
+	role := Role{
+		ObjectType:  rolePrefix,
+		Name:        name,
+		Description: description,
+		Operations:  roleOperations,
+	}
+	roleJSON, err := json.Marshal(role)
+	if err != nil {
+		return fmt.Errorf("failed to marshal role: %v", err)
+	}
+
+	roleKey, err := ctx.GetStub().CreateCompositeKey(rolePrefix, []string{name})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = ctx.GetStub().PutState(roleKey, roleJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put role %s: %v", name, err)
+	}
+
+	return _emitEvent(ctx, "PolicyChanged", event{"", name, "DEFINE"})
+}
+
+// AssignRole grants a role to a client ID or an MSPID
+func (s *SmartContract) AssignRole(ctx contractapi.TransactionContextInterface, subject string, roleName string) error {
+	err := _requirePolicyAdmin(ctx)
+	if err != nil {
+		return err
+	}
+	if subject == "" {
+		return fmt.Errorf("subject must be set")
+	}
+
+	_, err = s.GetRole(ctx, roleName)
+	if err != nil {
+		return err
+	}
+
+	admin, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	assignment := Assignment{
+		ObjectType: assignmentPrefix,
+		Subject:    subject,
+		Role:       roleName,
+		AssignedBy: admin,
+	}
+	assignmentJSON, err := json.Marshal(assignment)
+	if err != nil {
+		return fmt.Errorf("failed to marshal assignment: %v", err)
+	}
+
+	assignmentKey, err := ctx.GetStub().CreateCompositeKey(assignmentPrefix, []string{subject, roleName})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = ctx.GetStub().PutState(assignmentKey, assignmentJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put assignment: %v", err)
+	}
+
+	return _emitEvent(ctx, "PolicyChanged", event{subject, roleName, "ASSIGN"})
+}
+
+// RevokeRole takes a role away from a client ID or an MSPID
+func (s *SmartContract) RevokeRole(ctx contractapi.TransactionContextInterface, subject string, roleName string) error {
+	err := _requirePolicyAdmin(ctx)
+	if err != nil {
+		return err
+	}
+
+	assignmentKey, err := ctx.GetStub().CreateCompositeKey(assignmentPrefix, []string{subject, roleName})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	assignmentJSON, err := ctx.GetStub().GetState(assignmentKey)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if assignmentJSON == nil {
+		return fmt.Errorf("subject %s does not have role %s", subject, roleName)
+	}
+
+	err = ctx.GetStub().DelState(assignmentKey)
+	if err != nil {
+		return fmt.Errorf("failed to delete assignment: %v", err)
+	}
+
+	return _emitEvent(ctx, "PolicyChanged", event{subject, roleName, "REVOKE"})
+}
+
+// CheckAccess returns true when the submitting client may perform the operation, either through
+// a role assigned to its client ID or to its org. Other chaincodes call it with InvokeChaincode;
+// the called chaincode sees the same submitting client as the caller, so a chaincode cannot
+// check access on behalf of another identity.
+func (s *SmartContract) CheckAccess(ctx contractapi.TransactionContextInterface, operation string) (bool, error) {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return false, fmt.Errorf("failed to get client id: %v", err)
+	}
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return false, fmt.Errorf("failed to get MSPID: %v", err)
+	}
+
+	for _, subject := range []string{clientID, mspID} {
+		permitted, err := s.HasPermission(ctx, subject, operation)
+		if err != nil {
+			return false, err
+		}
+		if permitted {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// HasPermission returns true when one of the roles assigned to the subject grants the operation
+func (s *SmartContract) HasPermission(ctx contractapi.TransactionContextInterface, subject string, operation string) (bool, error) {
+	roles, err := s.GetRoles(ctx, subject)
+	if err != nil {
+		return false, err
+	}
+
+	for _, roleName := range roles {
+		role, err := _getRole(ctx, roleName)
+		if err != nil {
+			return false, err
+		}
+		// a role deleted after it was assigned no longer grants anything
+		if role == nil {
+			continue
+		}
+		for _, granted := range role.Operations {
+			if _matchOperation(granted, operation) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// DeleteRole removes a role. Assignments of the role stay on the ledger but no longer grant access.
+func (s *SmartContract) DeleteRole(ctx contractapi.TransactionContextInterface, roleName string) error {
+	err := _requirePolicyAdmin(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.GetRole(ctx, roleName)
+	if err != nil {
+		return err
+	}
+
+	roleKey, err := ctx.GetStub().CreateCompositeKey(rolePrefix, []string{roleName})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = ctx.GetStub().DelState(roleKey)
+	if err != nil {
+		return fmt.Errorf("failed to delete role %s: %v", roleName, err)
+	}
+
+	return _emitEvent(ctx, "PolicyChanged", event{"", roleName, "DELETE"})
+}
+
+// _requirePolicyAdmin checks the client holds an admin certificate of the policy admin org
+func _requirePolicyAdmin(ctx contractapi.TransactionContextInterface) error {
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+	if mspID != policyAdminMSPID {
+		return fmt.Errorf("client from %s is not authorized to manage the access policy", mspID)
+	}
+
+	cert, err := ctx.GetClientIdentity().GetX509Certificate()
+	if err != nil {
+		return fmt.Errorf("failed to get client certificate: %v", err)
+	}
+	for _, ou := range cert.Subject.OrganizationalUnit {
+		if ou == adminOU {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("client is not an admin of %s", mspID)
+}
+
+// _validateOperation checks an operation is written chaincode.function or chaincode.*
+func _validateOperation(operation string) error {
+	parts := strings.Split(operation, ".")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return fmt.Errorf("operation %q must be written chaincode.function", operation)
+	}
+	return nil
+}
+
+// _matchOperation reports whether a granted operation covers the requested one
+func _matchOperation(granted string, operation string) bool {
+	if granted == operation {
+		return true
+	}
+	prefix := strings.TrimSuffix(granted, wildcardOperation)
+	return prefix != granted && strings.HasSuffix(prefix, ".") && strings.HasPrefix(operation, prefix)
+}
+
+// _getRole reads a role, returning nil when it is not defined
+func _getRole(ctx contractapi.TransactionContextInterface, roleName string) (*Role, error) {
+	roleKey, err := ctx.GetStub().CreateCompositeKey(rolePrefix, []string{roleName})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	roleJSON, err := ctx.GetStub().GetState(roleKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if roleJSON == nil {
+		return nil, nil
+	}
+
+	var role Role
+	err = json.Unmarshal(roleJSON, &role)
+	if err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+// _emitEvent marshals the payload and sets it as the chaincode event
+func _emitEvent(ctx contractapi.TransactionContextInterface, name string, payload interface{}) error {
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	err = ctx.GetStub().SetEvent(name, payloadJSON)
+	if err != nil {
+		return fmt.Errorf("failed to set event: %v", err)
+	}
+	return nil
+}
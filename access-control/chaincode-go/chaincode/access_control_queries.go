@@ -0,0 +1,76 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// GetRole returns the role defined with the given name
+func (s *SmartContract) GetRole(ctx contractapi.TransactionContextInterface, roleName string) (*Role, error) {
+	role, err := _getRole(ctx, roleName)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, fmt.Errorf("the role %s does not exist", roleName)
+	}
+	return role, nil
+}
+
+// GetAllRoles returns every defined role
+func (s *SmartContract) GetAllRoles(ctx contractapi.TransactionContextInterface) ([]*Role, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(rolePrefix, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get roles: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var roles []*Role
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var role Role
+		err = json.Unmarshal(response.Value, &role)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, &role)
+	}
+
+	return roles, nil
+}
+
+// GetRoles returns the names of the roles assigned to a client ID or an MSPID
+func (s *SmartContract) GetRoles(ctx contractapi.TransactionContextInterface, subject string) ([]string, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(assignmentPrefix, []string{subject})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get roles of %s: %v", subject, err)
+	}
+	defer resultsIterator.Close()
+
+	var roles []string
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var assignment Assignment
+		err = json.Unmarshal(response.Value, &assignment)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, assignment.Role)
+	}
+
+	return roles, nil
+}
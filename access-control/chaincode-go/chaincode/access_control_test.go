@@ -0,0 +1,93 @@
+package chaincode
+
+import (
+	"strings"
+	"testing"
+)
+
+func checkError(t *testing.T, err error, expected string) {
+	t.Helper()
+	if expected == "" && err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected != "" && (err == nil || !strings.Contains(err.Error(), expected)) {
+		t.Fatalf("expected error containing %q, got %v", expected, err)
+	}
+}
+
+// checkAccess checks whether the client may perform the operation
+func checkAccess(t *testing.T, stub *fakeStub, clientID string, mspID string, operation string, expected bool) {
+	t.Helper()
+	permitted, err := new(SmartContract).CheckAccess(newContext(stub, clientID, mspID), operation)
+	checkError(t, err, "")
+	if permitted != expected {
+		t.Fatalf("expected access of %s from %s to %s to be %t", clientID, mspID, operation, expected)
+	}
+}
+
+func TestDefineRole(t *testing.T) {
+	tests := []struct {
+		name       string
+		admin      bool
+		mspID      string
+		operations string
+		expected   string
+	}{
+		{"role", true, "Org1MSP", `["token.Mint","token.Burn"]`, ""},
+		{"not an admin", false, "Org1MSP", `["token.Mint"]`, "client is not an admin of Org1MSP"},
+		{"other org", true, "Org2MSP", `["token.Mint"]`, "client from Org2MSP is not authorized to manage the access policy"},
+		{"no operations", true, "Org1MSP", `[]`, "role minter must grant at least one operation"},
+		{"bad operation", true, "Org1MSP", `["Mint"]`, `operation "Mint" must be written chaincode.function`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stub := newFakeStub()
+			ctx := newContext(stub, "admin", test.mspID)
+			if test.admin {
+				ctx = newAdminContext(stub, "admin", test.mspID)
+			}
+			err := new(SmartContract).DefineRole(ctx, "minter", "Mints and burns tokens", test.operations)
+			checkError(t, err, test.expected)
+		})
+	}
+}
+
+func TestCheckAccess(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	checkError(t, contract.DefineRole(newAdminContext(stub, "admin", "Org1MSP"), "minter", "", `["token.Mint","token.Burn"]`), "")
+	checkError(t, contract.DefineRole(newAdminContext(stub, "admin", "Org1MSP"), "registrar", "", `["asset.*"]`), "")
+
+	err := contract.AssignRole(newContext(stub, "alice", "Org1MSP"), "Org1MSP", "minter")
+	checkError(t, err, "client is not an admin of Org1MSP")
+	err = contract.AssignRole(newAdminContext(stub, "admin", "Org1MSP"), "Org1MSP", "burner")
+	checkError(t, err, "the role burner does not exist")
+
+	// a role assigned to an org applies to all of its clients, one assigned to a client only to it
+	checkError(t, contract.AssignRole(newAdminContext(stub, "admin", "Org1MSP"), "Org1MSP", "minter"), "")
+	checkError(t, contract.AssignRole(newAdminContext(stub, "admin", "Org1MSP"), "bob", "registrar"), "")
+	if stub.eventName != "PolicyChanged" {
+		t.Fatalf("expected a PolicyChanged event, got %s", stub.eventName)
+	}
+	checkAccess(t, stub, "alice", "Org1MSP", "token.Mint", true)
+	checkAccess(t, stub, "alice", "Org1MSP", "token.Transfer", false)
+	checkAccess(t, stub, "alice", "Org1MSP", "asset.CreateAsset", false)
+	checkAccess(t, stub, "bob", "Org2MSP", "asset.CreateAsset", true)
+	checkAccess(t, stub, "bob", "Org2MSP", "assets.CreateAsset", false)
+	checkAccess(t, stub, "bob", "Org2MSP", "token.Mint", false)
+
+	roles, err := contract.GetRoles(newContext(stub, "bob", "Org2MSP"), "bob")
+	checkError(t, err, "")
+	if len(roles) != 1 || roles[0] != "registrar" {
+		t.Fatalf("unexpected roles %v", roles)
+	}
+
+	err = contract.RevokeRole(newAdminContext(stub, "admin", "Org1MSP"), "Org2MSP", "registrar")
+	checkError(t, err, "subject Org2MSP does not have role registrar")
+	checkError(t, contract.RevokeRole(newAdminContext(stub, "admin", "Org1MSP"), "Org1MSP", "minter"), "")
+	checkAccess(t, stub, "alice", "Org1MSP", "token.Mint", false)
+
+	// the assignment of a deleted role no longer grants anything
+	checkError(t, contract.DeleteRole(newAdminContext(stub, "admin", "Org1MSP"), "registrar"), "")
+	checkAccess(t, stub, "bob", "Org2MSP", "asset.CreateAsset", false)
+}
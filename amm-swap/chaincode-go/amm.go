@@ -0,0 +1,23 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"log"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/amm-swap/chaincode-go/chaincode"
+)
+
+func main() {
+	ammChaincode, err := contractapi.NewChaincode(&chaincode.SmartContract{})
+	if err != nil {
+		log.Panicf("Error creating amm-swap chaincode: %v", err)
+	}
+
+	if err := ammChaincode.Start(); err != nil {
+		log.Panicf("Error starting amm-swap chaincode: %v", err)
+	}
+}
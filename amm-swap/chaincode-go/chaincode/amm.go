@@ -0,0 +1,479 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// This sample assumes Org1 operates the exchange and creates the pools
+const operatorMSPID = "Org1MSP"
+
+// object names for prefix
+const (
+	poolPrefix   = "pool"
+	sharePrefix  = "share"
+	payoutPrefix = "payout"
+)
+
+// payout status values
+const (
+	statusPending = "PENDING"
+	statusPaid    = "PAID"
+)
+
+// basisPoints is the denominator of the swap fee
+const basisPoints = 10000
+
+// maxFeeBps caps the swap fee at 10%
+const maxFeeBps = 1000
+
+// SmartContract provides functions for constant-product swap pools between two ERC-20 deployments
+type SmartContract struct {
+	contractapi.Contract
+}
+
+// Pool pairs two token chaincodes. The reserves are held in the account of the pool agent on both
+// token chaincodes, which submits the payout transactions, since a chaincode cannot hold tokens.
+type Pool struct {
+	ObjectType  string `json:"objectType"`
+	ID          string `json:"poolID"`
+	TokenA      string `json:"tokenA"`
+	TokenB      string `json:"tokenB"`
+	ReserveA    int    `json:"reserveA"`
+	ReserveB    int    `json:"reserveB"`
+	TotalShares int    `json:"totalShares"`
+	FeeBps      int    `json:"feeBps"`
+	Agent       string `json:"agent"`
+}
+
+// Payout is a token payment the pool agent owes, for a swap or a liquidity withdrawal
+type Payout struct {
+	ID        string `json:"payoutID"`
+	PoolID    string `json:"poolID"`
+	Token     string `json:"token"`
+	Recipient string `json:"recipient"`
+	Amount    int    `json:"amount"`
+	Status    string `json:"status"`
+}
+
+// swapEvent provides an organized struct for emitting swap events
+type swapEvent struct {
+	PoolID    string `json:"poolID"`
+	Trader    string `json:"trader"`
+	TokenIn   string `json:"tokenIn"`
+	AmountIn  int    `json:"amountIn"`
+	TokenOut  string `json:"tokenOut"`
+	AmountOut int    `json:"amountOut"`
+}
+
+// CreatePool creates an empty pool between two token chaincodes. feeBps is charged on every swap
+// and stays in the reserves, so it accrues to the liquidity providers.
+func (s *SmartContract) CreatePool(ctx contractapi.TransactionContextInterface, poolID string, tokenA string, tokenB string, feeBps int, agent string) error {
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+	if clientMSPID != operatorMSPID {
+		return fmt.Errorf("client from %s is not authorized to create pools", clientMSPID)
+	}
+
+	if poolID == "" || tokenA == "" || tokenB == "" || agent == "" {
+		return fmt.Errorf("pool ID, token chaincodes and agent must be set")
+	}
+	if tokenA == tokenB {
+		return fmt.Errorf("a pool needs two different token chaincodes")
+	}
+	if feeBps < 0 || feeBps > maxFeeBps {
+		return fmt.Errorf("fee must be between 0 and %d basis points", maxFeeBps)
+	}
+
+	existing, err := _getPool(ctx, poolID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("the pool %s already exists", poolID)
+	}
+
+	pool := Pool{
+		ObjectType: poolPrefix,
+		ID:         poolID,
+		TokenA:     tokenA,
+		TokenB:     tokenB,
+		FeeBps:     feeBps,
+		Agent:      agent,
+	}
+	return _putPool(ctx, &pool)
+}
+
+// AddLiquidity deposits up to amountA and amountB into the pool in the ratio of its reserves and
+// mints LP shares to the client. The first deposit sets the price. Only the amounts used are
+// transferred to the pool agent. Fails if fewer than minShares would be minted.
+func (s *SmartContract) AddLiquidity(ctx contractapi.TransactionContextInterface, poolID string, amountA int, amountB int, minShares int) (int, error) {
+	provider, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get client id: %v", err)
+	}
+	if amountA <= 0 || amountB <= 0 {
+		return 0, fmt.Errorf("amounts must be positive integers")
+	}
+
+	pool, err := s.GetPool(ctx, poolID)
+	if err != nil {
+		return 0, err
+	}
+
+	var shares int
+	if pool.TotalShares == 0 {
+		// the geometric mean makes the initial share count independent of the price
+		product := new(big.Int).Mul(big.NewInt(int64(amountA)), big.NewInt(int64(amountB)))
+		shares = int(product.Sqrt(product).Int64())
+	} else {
+		// use all of amountA if amountB covers it at the pool price, otherwise all of amountB
+		neededB := _mulDiv(amountA, pool.ReserveB, pool.ReserveA)
+		if neededB <= amountB {
+			amountB = neededB
+		} else {
+			amountA = _mulDiv(amountB, pool.ReserveA, pool.ReserveB)
+		}
+		sharesA := _mulDiv(amountA, pool.TotalShares, pool.ReserveA)
+		sharesB := _mulDiv(amountB, pool.TotalShares, pool.ReserveB)
+		shares = sharesA
+		if sharesB < shares {
+			shares = sharesB
+		}
+	}
+	if shares <= 0 {
+		return 0, fmt.Errorf("deposit is too small to mint any shares")
+	}
+	if shares < minShares {
+		return 0, fmt.Errorf("deposit would mint %d shares, less than the minimum %d", shares, minShares)
+	}
+
+	err = _transferTokens(ctx, pool.TokenA, pool.Agent, amountA)
+	if err != nil {
+		return 0, err
+	}
+	err = _transferTokens(ctx, pool.TokenB, pool.Agent, amountB)
+	if err != nil {
+		return 0, err
+	}
+
+	held, err := s.GetShares(ctx, poolID, provider)
+	if err != nil {
+		return 0, err
+	}
+	err = _putShares(ctx, poolID, provider, held+shares)
+	if err != nil {
+		return 0, err
+	}
+
+	pool.ReserveA += amountA
+	pool.ReserveB += amountB
+	pool.TotalShares += shares
+	err = _putPool(ctx, pool)
+	if err != nil {
+		return 0, err
+	}
+
+	return shares, nil
+}
+
+// RemoveLiquidity burns LP shares of the client and creates payouts of its share of both reserves,
+// including the fees accrued since the deposit. Fails if either amount is below its minimum.
+func (s *SmartContract) RemoveLiquidity(ctx contractapi.TransactionContextInterface, poolID string, shares int, minAmountA int, minAmountB int) ([]string, error) {
+	provider, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client id: %v", err)
+	}
+	if shares <= 0 {
+		return nil, fmt.Errorf("shares must be a positive integer")
+	}
+
+	pool, err := s.GetPool(ctx, poolID)
+	if err != nil {
+		return nil, err
+	}
+	held, err := s.GetShares(ctx, poolID, provider)
+	if err != nil {
+		return nil, err
+	}
+	if held < shares {
+		return nil, fmt.Errorf("client holds %d shares of pool %s", held, poolID)
+	}
+
+	amountA := _mulDiv(shares, pool.ReserveA, pool.TotalShares)
+	amountB := _mulDiv(shares, pool.ReserveB, pool.TotalShares)
+	if amountA < minAmountA || amountB < minAmountB {
+		return nil, fmt.Errorf("withdrawal of %d and %d is below the minimum %d and %d", amountA, amountB, minAmountA, minAmountB)
+	}
+
+	err = _putShares(ctx, poolID, provider, held-shares)
+	if err != nil {
+		return nil, err
+	}
+
+	pool.ReserveA -= amountA
+	pool.ReserveB -= amountB
+	pool.TotalShares -= shares
+	err = _putPool(ctx, pool)
+	if err != nil {
+		return nil, err
+	}
+
+	payoutA, err := _createPayout(ctx, pool, pool.TokenA, provider, amountA, "a")
+	if err != nil {
+		return nil, err
+	}
+	payoutB, err := _createPayout(ctx, pool, pool.TokenB, provider, amountB, "b")
+	if err != nil {
+		return nil, err
+	}
+
+	return []string{payoutA, payoutB}, nil
+}
+
+// Swap transfers amountIn of tokenIn from the client to the pool agent and creates a payout of the
+// other token, priced so the product of the reserves does not fall. The slippage limit fails the
+// swap if the client would receive less than minAmountOut.
+func (s *SmartContract) Swap(ctx contractapi.TransactionContextInterface, poolID string, tokenIn string, amountIn int, minAmountOut int) (string, error) {
+	trader, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	pool, err := s.GetPool(ctx, poolID)
+	if err != nil {
+		return "", err
+	}
+	amountOut, tokenOut, err := _quote(pool, tokenIn, amountIn)
+	if err != nil {
+		return "", err
+	}
+	if amountOut < minAmountOut {
+		return "", fmt.Errorf("swap would return %d, less than the minimum %d", amountOut, minAmountOut)
+	}
+
+	err = _transferTokens(ctx, tokenIn, pool.Agent, amountIn)
+	if err != nil {
+		return "", err
+	}
+
+	if tokenIn == pool.TokenA {
+		pool.ReserveA += amountIn
+		pool.ReserveB -= amountOut
+	} else {
+		pool.ReserveB += amountIn
+		pool.ReserveA -= amountOut
+	}
+	err = _putPool(ctx, pool)
+	if err != nil {
+		return "", err
+	}
+
+	payoutID, err := _createPayout(ctx, pool, tokenOut, trader, amountOut, "")
+	if err != nil {
+		return "", err
+	}
+
+	swapJSON, err := json.Marshal(swapEvent{poolID, trader, tokenIn, amountIn, tokenOut, amountOut})
+	if err != nil {
+		return "", fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	err = ctx.GetStub().SetEvent("Swap", swapJSON)
+	if err != nil {
+		return "", fmt.Errorf("failed to set event: %v", err)
+	}
+
+	return payoutID, nil
+}
+
+// PayOut is submitted by the pool agent to pay a pending payout from its account
+func (s *SmartContract) PayOut(ctx contractapi.TransactionContextInterface, payoutID string) error {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	payout, err := s.GetPayout(ctx, payoutID)
+	if err != nil {
+		return err
+	}
+	if payout.Status != statusPending {
+		return fmt.Errorf("payout %s is %s", payoutID, payout.Status)
+	}
+	pool, err := s.GetPool(ctx, payout.PoolID)
+	if err != nil {
+		return err
+	}
+	if clientID != pool.Agent {
+		return fmt.Errorf("only the agent of pool %s can pay out", pool.ID)
+	}
+
+	err = _transferTokens(ctx, payout.Token, payout.Recipient, payout.Amount)
+	if err != nil {
+		return err
+	}
+
+	payout.Status = statusPaid
+	return _putPayout(ctx, payout)
+}
+
+// _quote returns the output amount and token of a swap. The fee is taken from the input before the
+// constant-product formula: out = in' * reserveOut / (reserveIn + in').
+func _quote(pool *Pool, tokenIn string, amountIn int) (int, string, error) {
+	if amountIn <= 0 {
+		return 0, "", fmt.Errorf("swap amount must be a positive integer")
+	}
+
+	var reserveIn, reserveOut int
+	var tokenOut string
+	switch tokenIn {
+	case pool.TokenA:
+		reserveIn, reserveOut, tokenOut = pool.ReserveA, pool.ReserveB, pool.TokenB
+	case pool.TokenB:
+		reserveIn, reserveOut, tokenOut = pool.ReserveB, pool.ReserveA, pool.TokenA
+	default:
+		return 0, "", fmt.Errorf("token %s is not traded in pool %s", tokenIn, pool.ID)
+	}
+	if reserveIn == 0 || reserveOut == 0 {
+		return 0, "", fmt.Errorf("pool %s has no liquidity", pool.ID)
+	}
+
+	inAfterFee := new(big.Int).Mul(big.NewInt(int64(amountIn)), big.NewInt(int64(basisPoints-pool.FeeBps)))
+	numerator := new(big.Int).Mul(inAfterFee, big.NewInt(int64(reserveOut)))
+	denominator := new(big.Int).Mul(big.NewInt(int64(reserveIn)), big.NewInt(basisPoints))
+	denominator.Add(denominator, inAfterFee)
+	amountOut := int(numerator.Quo(numerator, denominator).Int64())
+
+	if amountOut <= 0 {
+		return 0, "", fmt.Errorf("swap amount is too small to return any tokens")
+	}
+	return amountOut, tokenOut, nil
+}
+
+// _mulDiv returns a * b / c rounded down, using big.Int so the product cannot overflow
+func _mulDiv(a int, b int, c int) int {
+	result := new(big.Int).Mul(big.NewInt(int64(a)), big.NewInt(int64(b)))
+	return int(result.Quo(result, big.NewInt(int64(c))).Int64())
+}
+
+// _transferTokens pays amount tokens on tokenChaincode from the invoking client's account to receiver
+func _transferTokens(ctx contractapi.TransactionContextInterface, tokenChaincode string, receiver string, amount int) error {
+	if amount == 0 {
+		return nil
+	}
+	args := [][]byte{[]byte("Transfer"), []byte(receiver), []byte(strconv.Itoa(amount))}
+	response := ctx.GetStub().InvokeChaincode(tokenChaincode, args, "")
+	if response.Status != shim.OK {
+		return fmt.Errorf("failed to transfer %d tokens on %s: %s", amount, tokenChaincode, response.Message)
+	}
+	return nil
+}
+
+// _createPayout records a pending payout and returns its ID. The ID is the transaction ID plus a
+// suffix when a transaction creates more than one payout.
+func _createPayout(ctx contractapi.TransactionContextInterface, pool *Pool, token string, recipient string, amount int, suffix string) (string, error) {
+	payoutID := ctx.GetStub().GetTxID()
+	if suffix != "" {
+		payoutID += "-" + suffix
+	}
+
+	payout := Payout{
+		ID:        payoutID,
+		PoolID:    pool.ID,
+		Token:     token,
+		Recipient: recipient,
+		Amount:    amount,
+		Status:    statusPending,
+	}
+	if amount == 0 {
+		payout.Status = statusPaid
+	}
+	err := _putPayout(ctx, &payout)
+	if err != nil {
+		return "", err
+	}
+	return payoutID, nil
+}
+
+// _getPool reads a pool, returning nil when it does not exist
+func _getPool(ctx contractapi.TransactionContextInterface, poolID string) (*Pool, error) {
+	poolKey, err := ctx.GetStub().CreateCompositeKey(poolPrefix, []string{poolID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	poolJSON, err := ctx.GetStub().GetState(poolKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if poolJSON == nil {
+		return nil, nil
+	}
+
+	var pool Pool
+	err = json.Unmarshal(poolJSON, &pool)
+	if err != nil {
+		return nil, err
+	}
+	return &pool, nil
+}
+
+// _putPool writes the pool to the world state
+func _putPool(ctx contractapi.TransactionContextInterface, pool *Pool) error {
+	poolKey, err := ctx.GetStub().CreateCompositeKey(poolPrefix, []string{pool.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	poolJSON, err := json.Marshal(pool)
+	if err != nil {
+		return fmt.Errorf("failed to marshal pool: %v", err)
+	}
+	err = ctx.GetStub().PutState(poolKey, poolJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put pool %s: %v", pool.ID, err)
+	}
+	return nil
+}
+
+// _putShares writes the LP shares a provider holds in a pool, deleting the key when it reaches zero
+func _putShares(ctx contractapi.TransactionContextInterface, poolID string, provider string, shares int) error {
+	shareKey, err := ctx.GetStub().CreateCompositeKey(sharePrefix, []string{poolID, provider})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	if shares == 0 {
+		return ctx.GetStub().DelState(shareKey)
+	}
+	err = ctx.GetStub().PutState(shareKey, []byte(strconv.Itoa(shares)))
+	if err != nil {
+		return fmt.Errorf("failed to put shares: %v", err)
+	}
+	return nil
+}
+
+// _putPayout writes the payout to the world state
+func _putPayout(ctx contractapi.TransactionContextInterface, payout *Payout) error {
+	payoutKey, err := ctx.GetStub().CreateCompositeKey(payoutPrefix, []string{payout.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	payoutJSON, err := json.Marshal(payout)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payout: %v", err)
+	}
+	err = ctx.GetStub().PutState(payoutKey, payoutJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put payout %s: %v", payout.ID, err)
+	}
+	return nil
+}
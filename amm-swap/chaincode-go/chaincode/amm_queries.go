@@ -0,0 +1,108 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// GetPool returns the pool stored in the world state with the given ID
+func (s *SmartContract) GetPool(ctx contractapi.TransactionContextInterface, poolID string) (*Pool, error) {
+	pool, err := _getPool(ctx, poolID)
+	if err != nil {
+		return nil, err
+	}
+	if pool == nil {
+		return nil, fmt.Errorf("the pool %s does not exist", poolID)
+	}
+	return pool, nil
+}
+
+// GetShares returns the LP shares a provider holds in a pool
+func (s *SmartContract) GetShares(ctx contractapi.TransactionContextInterface, poolID string, provider string) (int, error) {
+	shareKey, err := ctx.GetStub().CreateCompositeKey(sharePrefix, []string{poolID, provider})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	sharesBytes, err := ctx.GetStub().GetState(shareKey)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if sharesBytes == nil {
+		return 0, nil
+	}
+
+	shares, err := strconv.Atoi(string(sharesBytes))
+	if err != nil {
+		return 0, fmt.Errorf("failed to convert shares: %v", err)
+	}
+	return shares, nil
+}
+
+// Quote returns the amount of the other token a swap of amountIn would return at the current reserves
+func (s *SmartContract) Quote(ctx contractapi.TransactionContextInterface, poolID string, tokenIn string, amountIn int) (int, error) {
+	pool, err := s.GetPool(ctx, poolID)
+	if err != nil {
+		return 0, err
+	}
+	amountOut, _, err := _quote(pool, tokenIn, amountIn)
+	return amountOut, err
+}
+
+// GetPayout returns the payout stored in the world state with the given ID
+func (s *SmartContract) GetPayout(ctx contractapi.TransactionContextInterface, payoutID string) (*Payout, error) {
+	payoutKey, err := ctx.GetStub().CreateCompositeKey(payoutPrefix, []string{payoutID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	payoutJSON, err := ctx.GetStub().GetState(payoutKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if payoutJSON == nil {
+		return nil, fmt.Errorf("the payout %s does not exist", payoutID)
+	}
+
+	var payout Payout
+	err = json.Unmarshal(payoutJSON, &payout)
+	if err != nil {
+		return nil, err
+	}
+	return &payout, nil
+}
+
+// GetPendingPayouts returns the payouts of a pool its agent has yet to pay
+func (s *SmartContract) GetPendingPayouts(ctx contractapi.TransactionContextInterface, poolID string) ([]*Payout, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(payoutPrefix, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payouts: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var payouts []*Payout
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var payout Payout
+		err = json.Unmarshal(response.Value, &payout)
+		if err != nil {
+			return nil, err
+		}
+		if payout.PoolID == poolID && payout.Status == statusPending {
+			payouts = append(payouts, &payout)
+		}
+	}
+
+	return payouts, nil
+}
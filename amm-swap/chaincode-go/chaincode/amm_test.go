@@ -0,0 +1,188 @@
+package chaincode
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+)
+
+const traderMSPID = "Org2MSP"
+
+// fakeToken stands in for a token chaincode. Transfers are paid from the payer's account.
+type fakeToken struct {
+	payer    string
+	balances map[string]int
+}
+
+func (f *fakeToken) invoke(args [][]byte) pb.Response {
+	if string(args[0]) != "Transfer" {
+		return shim.Error("unexpected function " + string(args[0]))
+	}
+	amount, err := strconv.Atoi(string(args[2]))
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if f.balances[f.payer] < amount {
+		return shim.Error(fmt.Sprintf("client account %s has insufficient funds", f.payer))
+	}
+	f.balances[f.payer] -= amount
+	f.balances[string(args[1])] += amount
+	return shim.Success(nil)
+}
+
+func checkError(t *testing.T, err error, expected string) {
+	t.Helper()
+	if expected == "" && err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected != "" && (err == nil || !strings.Contains(err.Error(), expected)) {
+		t.Fatalf("expected error containing %q, got %v", expected, err)
+	}
+}
+
+func checkReserves(t *testing.T, stub *fakeStub, reserveA int, reserveB int) {
+	t.Helper()
+	pool, err := new(SmartContract).GetPool(newContext(stub, "operator", operatorMSPID), "pool1")
+	if err != nil {
+		t.Fatalf("failed to read pool: %v", err)
+	}
+	if pool.ReserveA != reserveA || pool.ReserveB != reserveB {
+		t.Fatalf("expected reserves %d and %d, got %d and %d", reserveA, reserveB, pool.ReserveA, pool.ReserveB)
+	}
+}
+
+// payer sets the client paying on both token chaincodes
+func payer(account string, tokens ...*fakeToken) {
+	for _, token := range tokens {
+		token.payer = account
+	}
+}
+
+// createPool creates pool1 with a 0.3% fee, funded by the provider with 1000 of token A and 4000
+// of token B
+func createPool(t *testing.T, stub *fakeStub) (*fakeToken, *fakeToken) {
+	t.Helper()
+	tokenA := &fakeToken{balances: map[string]int{"provider": 1100, "trader": 100}}
+	tokenB := &fakeToken{balances: map[string]int{"provider": 5000}}
+	stub.chaincodes["tokenA"] = tokenA.invoke
+	stub.chaincodes["tokenB"] = tokenB.invoke
+
+	contract := new(SmartContract)
+	checkError(t, contract.CreatePool(newContext(stub, "operator", operatorMSPID), "pool1", "tokenA", "tokenB", 30, "agent"), "")
+
+	payer("provider", tokenA, tokenB)
+	shares, err := contract.AddLiquidity(newContext(stub, "provider", traderMSPID), "pool1", 1000, 4000, 2000)
+	checkError(t, err, "")
+	if shares != 2000 {
+		t.Fatalf("expected 2000 shares, got %d", shares)
+	}
+	return tokenA, tokenB
+}
+
+func TestCreatePool(t *testing.T) {
+	tests := []struct {
+		name     string
+		mspID    string
+		poolID   string
+		tokenB   string
+		feeBps   int
+		expected string
+	}{
+		{"operator", operatorMSPID, "pool2", "tokenB", 30, ""},
+		{"not operator", traderMSPID, "pool2", "tokenB", 30, "client from Org2MSP is not authorized to create pools"},
+		{"same token", operatorMSPID, "pool2", "tokenA", 30, "a pool needs two different token chaincodes"},
+		{"high fee", operatorMSPID, "pool2", "tokenB", 1001, "fee must be between 0 and 1000 basis points"},
+		{"existing pool", operatorMSPID, "pool1", "tokenB", 30, "the pool pool1 already exists"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stub := newFakeStub()
+			createPool(t, stub)
+
+			err := new(SmartContract).CreatePool(newContext(stub, "operator", test.mspID), test.poolID, "tokenA", test.tokenB, test.feeBps, "agent")
+			checkError(t, err, test.expected)
+		})
+	}
+}
+
+func TestSwap(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	tokenA, tokenB := createPool(t, stub)
+
+	_, err := contract.Swap(newContext(stub, "trader", traderMSPID), "pool1", "tokenC", 100, 0)
+	checkError(t, err, "token tokenC is not traded in pool pool1")
+
+	// 100 of token A less the fee returns 362 of token B
+	quote, err := contract.Quote(newContext(stub, "trader", traderMSPID), "pool1", "tokenA", 100)
+	checkError(t, err, "")
+	if quote != 362 {
+		t.Fatalf("expected a quote of 362, got %d", quote)
+	}
+	_, err = contract.Swap(newContext(stub, "trader", traderMSPID), "pool1", "tokenA", 100, 363)
+	checkError(t, err, "swap would return 362, less than the minimum 363")
+
+	payer("trader", tokenA, tokenB)
+	payoutID, err := contract.Swap(newContext(stub, "trader", traderMSPID), "pool1", "tokenA", 100, 362)
+	checkError(t, err, "")
+	checkReserves(t, stub, 1100, 3638)
+
+	err = contract.PayOut(newContext(stub, "trader", traderMSPID), payoutID)
+	checkError(t, err, "only the agent of pool pool1 can pay out")
+
+	payer("agent", tokenA, tokenB)
+	checkError(t, contract.PayOut(newContext(stub, "agent", operatorMSPID), payoutID), "")
+	if tokenA.balances["trader"] != 0 || tokenB.balances["trader"] != 362 || tokenB.balances["agent"] != 3638 {
+		t.Fatalf("unexpected balances %v and %v", tokenA.balances, tokenB.balances)
+	}
+
+	err = contract.PayOut(newContext(stub, "agent", operatorMSPID), payoutID)
+	checkError(t, err, fmt.Sprintf("payout %s is PAID", payoutID))
+}
+
+func TestSwapInsufficientFunds(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	tokenA, tokenB := createPool(t, stub)
+
+	payer("trader", tokenA, tokenB)
+	_, err := contract.Swap(newContext(stub, "trader", traderMSPID), "pool1", "tokenA", 101, 0)
+	checkError(t, err, "failed to transfer 101 tokens on tokenA: client account trader has insufficient funds")
+	checkReserves(t, stub, 1000, 4000)
+}
+
+func TestLiquidity(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	tokenA, tokenB := createPool(t, stub)
+
+	// only the amount of token B matching the pool price is taken
+	shares, err := contract.AddLiquidity(newContext(stub, "provider", traderMSPID), "pool1", 100, 1000, 0)
+	checkError(t, err, "")
+	if shares != 200 || tokenB.balances["provider"] != 600 {
+		t.Fatalf("expected 200 shares for 400 of token B, got %d with %v", shares, tokenB.balances)
+	}
+	checkReserves(t, stub, 1100, 4400)
+
+	_, err = contract.RemoveLiquidity(newContext(stub, "trader", traderMSPID), "pool1", 1, 0, 0)
+	checkError(t, err, "client holds 0 shares of pool pool1")
+
+	_, err = contract.RemoveLiquidity(newContext(stub, "provider", traderMSPID), "pool1", 1100, 551, 0)
+	checkError(t, err, "withdrawal of 550 and 2200 is below the minimum 551 and 0")
+
+	payoutIDs, err := contract.RemoveLiquidity(newContext(stub, "provider", traderMSPID), "pool1", 1100, 550, 2200)
+	checkError(t, err, "")
+	checkReserves(t, stub, 550, 2200)
+
+	payer("agent", tokenA, tokenB)
+	for _, payoutID := range payoutIDs {
+		checkError(t, contract.PayOut(newContext(stub, "agent", operatorMSPID), payoutID), "")
+	}
+	if tokenA.balances["provider"] != 550 || tokenB.balances["provider"] != 2800 {
+		t.Fatalf("unexpected balances %v and %v", tokenA.balances, tokenB.balances)
+	}
+}
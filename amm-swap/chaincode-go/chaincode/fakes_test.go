@@ -0,0 +1,203 @@
+package chaincode
+
+import (
+	"crypto/x509"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+)
+
+// fakeStub is an in-memory world state standing in for the peer. Functions the swap chaincode
+// does not call are left to the embedded interface and panic if called.
+type fakeStub struct {
+	shim.ChaincodeStubInterface
+	txCount int64
+	state   map[string][]byte
+	history map[string][]*queryresult.KeyModification
+	// chaincodes answers InvokeChaincode by chaincode name
+	chaincodes map[string]func(args [][]byte) pb.Response
+	eventName  string
+	eventValue []byte
+}
+
+func newFakeStub() *fakeStub {
+	return &fakeStub{
+		state:      make(map[string][]byte),
+		history:    make(map[string][]*queryresult.KeyModification),
+		chaincodes: make(map[string]func(args [][]byte) pb.Response),
+	}
+}
+
+func (s *fakeStub) GetTxID() string {
+	return fmt.Sprintf("tx%d", s.txCount)
+}
+
+// GetTxTimestamp moves the clock on by a second for every transaction
+func (s *fakeStub) GetTxTimestamp() (*timestamp.Timestamp, error) {
+	return &timestamp.Timestamp{Seconds: 1600000000 + s.txCount}, nil
+}
+
+func (s *fakeStub) GetState(key string) ([]byte, error) {
+	return s.state[key], nil
+}
+
+func (s *fakeStub) PutState(key string, value []byte) error {
+	s.state[key] = value
+	s.history[key] = append(s.history[key], &queryresult.KeyModification{
+		TxId:      s.GetTxID(),
+		Value:     value,
+		Timestamp: &timestamp.Timestamp{Seconds: 1600000000 + s.txCount},
+	})
+	return nil
+}
+
+func (s *fakeStub) DelState(key string) error {
+	delete(s.state, key)
+	s.history[key] = append(s.history[key], &queryresult.KeyModification{
+		TxId:      s.GetTxID(),
+		Timestamp: &timestamp.Timestamp{Seconds: 1600000000 + s.txCount},
+		IsDelete:  true,
+	})
+	return nil
+}
+
+// CreateCompositeKey builds keys the same way as the shim, so tests can look them up
+func (s *fakeStub) CreateCompositeKey(objectType string, attributes []string) (string, error) {
+	key := "\x00" + objectType + "\x00"
+	for _, attribute := range attributes {
+		if !utf8.ValidString(attribute) || strings.ContainsRune(attribute, 0) {
+			return "", fmt.Errorf("not a valid utf8 string without U+0000: [%x]", attribute)
+		}
+		key += attribute + "\x00"
+	}
+	return key, nil
+}
+
+func (s *fakeStub) SplitCompositeKey(compositeKey string) (string, []string, error) {
+	parts := strings.Split(strings.TrimSuffix(strings.TrimPrefix(compositeKey, "\x00"), "\x00"), "\x00")
+	return parts[0], parts[1:], nil
+}
+
+func (s *fakeStub) GetStateByPartialCompositeKey(objectType string, attributes []string) (shim.StateQueryIteratorInterface, error) {
+	prefix, err := s.CreateCompositeKey(objectType, attributes)
+	if err != nil {
+		return nil, err
+	}
+	return s.query(func(key string) bool { return strings.HasPrefix(key, prefix) }), nil
+}
+
+// GetStateByRange only returns simple keys, as on the peer
+func (s *fakeStub) GetStateByRange(startKey string, endKey string) (shim.StateQueryIteratorInterface, error) {
+	return s.query(func(key string) bool {
+		return !strings.HasPrefix(key, "\x00") && key >= startKey && (endKey == "" || key < endKey)
+	}), nil
+}
+
+// query returns the keys accepted by match in sorted order
+func (s *fakeStub) query(match func(key string) bool) *fakeStateIterator {
+	var keys []string
+	for key := range s.state {
+		if match(key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	iterator := &fakeStateIterator{}
+	for _, key := range keys {
+		iterator.results = append(iterator.results, &queryresult.KV{Key: key, Value: s.state[key]})
+	}
+	return iterator
+}
+
+func (s *fakeStub) GetHistoryForKey(key string) (shim.HistoryQueryIteratorInterface, error) {
+	return &fakeHistoryIterator{modifications: s.history[key]}, nil
+}
+
+func (s *fakeStub) SetEvent(name string, payload []byte) error {
+	s.eventName = name
+	s.eventValue = payload
+	return nil
+}
+
+func (s *fakeStub) InvokeChaincode(chaincodeName string, args [][]byte, channel string) pb.Response {
+	chaincode, ok := s.chaincodes[chaincodeName]
+	if !ok {
+		return shim.Error(fmt.Sprintf("chaincode %s not found", chaincodeName))
+	}
+	return chaincode(args)
+}
+
+// fakeStateIterator iterates over the results of a range or partial composite key query
+type fakeStateIterator struct {
+	results []*queryresult.KV
+}
+
+func (i *fakeStateIterator) HasNext() bool {
+	return len(i.results) > 0
+}
+
+func (i *fakeStateIterator) Next() (*queryresult.KV, error) {
+	result := i.results[0]
+	i.results = i.results[1:]
+	return result, nil
+}
+
+func (i *fakeStateIterator) Close() error {
+	return nil
+}
+
+// fakeHistoryIterator iterates over the modifications of a key
+type fakeHistoryIterator struct {
+	modifications []*queryresult.KeyModification
+}
+
+func (i *fakeHistoryIterator) HasNext() bool {
+	return len(i.modifications) > 0
+}
+
+func (i *fakeHistoryIterator) Next() (*queryresult.KeyModification, error) {
+	modification := i.modifications[0]
+	i.modifications = i.modifications[1:]
+	return modification, nil
+}
+
+func (i *fakeHistoryIterator) Close() error {
+	return nil
+}
+
+// fakeClientIdentity is the identity of the client submitting the transaction
+type fakeClientIdentity struct {
+	cid.ClientIdentity
+	id    string
+	mspID string
+}
+
+func (c *fakeClientIdentity) GetID() (string, error) {
+	return c.id, nil
+}
+
+func (c *fakeClientIdentity) GetMSPID() (string, error) {
+	return c.mspID, nil
+}
+
+func (c *fakeClientIdentity) GetX509Certificate() (*x509.Certificate, error) {
+	return &x509.Certificate{}, nil
+}
+
+// newContext starts a new transaction submitted by a client of the given org
+func newContext(stub *fakeStub, clientID string, mspID string) *contractapi.TransactionContext {
+	stub.txCount++
+	ctx := new(contractapi.TransactionContext)
+	ctx.SetStub(stub)
+	ctx.SetClientIdentity(&fakeClientIdentity{id: clientID, mspID: mspID})
+	return ctx
+}
@@ -0,0 +1,497 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package asset calls the functions of the secured agreement asset chaincode through the Fabric
+// Gateway, passing private data in the transient map and decoding results into the assettypes
+// shared with the chaincode.
+package asset
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-samples/asset-transfer-secured-agreement/assettypes"
+	"github.com/hyperledger/fabric-samples/internal/appclient"
+)
+
+// ContractName is the name the asset chaincode gives its contract
+const ContractName = "asset"
+
+// QueryContractName is the name of the contract holding the CouchDB rich queries, which the asset
+// contract does not run because the peer does not validate their results
+const QueryContractName = "assetquery"
+
+// Transient map keys read by the chaincode
+const (
+	propertiesKey   = "asset_properties"
+	priceKey        = "asset_price"
+	personalDataKey = "personal_data"
+	appraisalKey    = "appraisal"
+)
+
+// MaxPageSize is the largest page GetAssetsPage returns with the default query config of the chaincode
+const MaxPageSize = 100
+
+// Contract is the asset contract of a chaincode deployed on a channel, called by a client of the
+// org mspID
+type Contract struct {
+	contract *client.Contract
+	queries  *client.Contract
+	mspID    string
+	observer appclient.Observer
+	ctx      context.Context
+}
+
+// NewContract returns the asset contract of the chaincode deployed as chaincodeName on the network,
+// for a client of the org mspID
+func NewContract(network *client.Network, chaincodeName string, mspID string) *Contract {
+	return &Contract{
+		contract: network.GetContractWithName(chaincodeName, ContractName),
+		queries:  network.GetContractWithName(chaincodeName, QueryContractName),
+		mspID:    mspID,
+		ctx:      context.Background(),
+	}
+}
+
+// SetObserver sets the observer told of every transaction and query of the contract
+func (c *Contract) SetObserver(observer appclient.Observer) {
+	c.observer = observer
+}
+
+// WithContext returns a copy of the contract making its calls in ctx, whose trace the spans of the
+// calls are recorded in, e.g. the context of the API request they are made for
+func (c *Contract) WithContext(ctx context.Context) *Contract {
+	copy := *c
+	copy.ctx = ctx
+	return &copy
+}
+
+// CreateAsset creates an asset owned by the client's org. The properties are kept in the org's
+// implicit collection and only their hash reaches the ledger.
+func (c *Contract) CreateAsset(assetID string, publicDescription string, properties *assettypes.AssetProperties) (*assettypes.AssetResult, error) {
+	transient, err := transientMap(propertiesKey, properties)
+	if err != nil {
+		return nil, err
+	}
+	return submit[assettypes.AssetResult](c, "CreateAsset", []string{assetID, publicDescription}, transient, c.mspID)
+}
+
+// UpdateAsset changes the public description of an asset owned by the client's org
+func (c *Contract) UpdateAsset(assetID string, publicDescription string) (*assettypes.AssetResult, error) {
+	return submit[assettypes.AssetResult](c, "UpdateAsset", []string{assetID, publicDescription}, nil, c.mspID)
+}
+
+// SetPersonalData records the personal data of an asset owned by the client's org, such as the name
+// of its individual owner, in the org's implicit collection apart from the properties
+func (c *Contract) SetPersonalData(personalData *assettypes.PersonalData) (*assettypes.AssetResult, error) {
+	transient, err := transientMap(personalDataKey, personalData)
+	if err != nil {
+		return nil, err
+	}
+	return submit[assettypes.AssetResult](c, "SetPersonalData", []string{personalData.ID}, transient, c.mspID)
+}
+
+// GetPersonalData returns the personal data of an asset recorded by the client's org
+func (c *Contract) GetPersonalData(assetID string) (*assettypes.PersonalData, error) {
+	return evaluate[assettypes.PersonalData](c, "GetPersonalData", []string{assetID}, nil)
+}
+
+// PurgePersonalData erases the personal data of an asset recorded by the client's org, to honor an
+// erasure request. The client's org needs the asset.PurgePersonalData operation in the
+// access-control chaincode.
+func (c *Contract) PurgePersonalData(assetID string) (*assettypes.PurgeResult, error) {
+	return submit[assettypes.PurgeResult](c, "PurgePersonalData", []string{assetID}, nil, c.mspID)
+}
+
+// RecordAppraisal adds an appraisal of an asset owned by the client's org to its valuation history
+// and makes it the current value. Only the asset ID and value of the appraisal are passed.
+func (c *Contract) RecordAppraisal(assetID string, value assettypes.Decimal) (*assettypes.Valuation, error) {
+	transient, err := transientMap(appraisalKey, assettypes.Valuation{AssetID: assetID, Value: value})
+	if err != nil {
+		return nil, err
+	}
+	return submit[assettypes.Valuation](c, "RecordAppraisal", []string{assetID}, transient, c.mspID)
+}
+
+// GetValuationHistory returns the appraisals of an asset the client's org recorded, the earliest first
+func (c *Contract) GetValuationHistory(assetID string) ([]*assettypes.Valuation, error) {
+	valuations, err := evaluate[[]*assettypes.Valuation](c, "GetValuationHistory", []string{assetID}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return *valuations, nil
+}
+
+// GetCurrentValuation returns the latest appraisal of an asset the client's org recorded
+func (c *Contract) GetCurrentValuation(assetID string) (*assettypes.Valuation, error) {
+	return evaluate[assettypes.Valuation](c, "GetCurrentValuation", []string{assetID}, nil)
+}
+
+// SetContentRef references the IPFS content name of an asset owned by the client's org, e.g.
+// "photo", by its CID and the lowercase hex SHA-256 of its bytes
+func (c *Contract) SetContentRef(assetID string, name string, cid string, sha256 string) (*assettypes.AssetResult, error) {
+	return submit[assettypes.AssetResult](c, "SetContentRef", []string{assetID, name, cid, sha256}, nil, c.mspID)
+}
+
+// RemoveContentRef removes the IPFS content name from an asset owned by the client's org
+func (c *Contract) RemoveContentRef(assetID string, name string) (*assettypes.AssetResult, error) {
+	return submit[assettypes.AssetResult](c, "RemoveContentRef", []string{assetID, name}, nil, c.mspID)
+}
+
+// VerifyContentHash records that the client fetched the content cid of an asset and found it hashes
+// to sha256. The client's org needs the asset.VerifyContentHash operation in the access-control
+// chaincode.
+func (c *Contract) VerifyContentHash(assetID string, cid string, sha256 string) (*assettypes.ContentAttestation, error) {
+	return submit[assettypes.ContentAttestation](c, "VerifyContentHash", []string{assetID, cid, sha256}, nil, c.mspID)
+}
+
+// GetContentAttestations returns the attestations recorded for the content cid of an asset
+func (c *Contract) GetContentAttestations(assetID string, cid string) ([]*assettypes.ContentAttestation, error) {
+	attestations, err := evaluate[[]*assettypes.ContentAttestation](c, "GetContentAttestations", []string{assetID, cid}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return *attestations, nil
+}
+
+// AgreeToSell records the price the owner org asks for an asset
+func (c *Contract) AgreeToSell(agreement *assettypes.Agreement) (*assettypes.AssetResult, error) {
+	transient, err := transientMap(priceKey, agreement)
+	if err != nil {
+		return nil, err
+	}
+	return submit[assettypes.AssetResult](c, "AgreeToSell", []string{agreement.ID}, transient, c.mspID)
+}
+
+// AgreeToBuy records the price the client's org bids for an asset
+func (c *Contract) AgreeToBuy(agreement *assettypes.Agreement) (*assettypes.AssetResult, error) {
+	transient, err := transientMap(priceKey, agreement)
+	if err != nil {
+		return nil, err
+	}
+	return submit[assettypes.AssetResult](c, "AgreeToBuy", []string{agreement.ID}, transient, c.mspID)
+}
+
+// TransferAsset sells an asset owned by the client's org to buyerMSPID. The properties and
+// agreement must be the ones the owner created the asset with and both orgs agreed to, and peers
+// of both orgs endorse the transfer.
+func (c *Contract) TransferAsset(assetID string, buyerMSPID string, properties *assettypes.AssetProperties, agreement *assettypes.Agreement) (*assettypes.AssetResult, error) {
+	transient, err := transferTransient(properties, agreement)
+	if err != nil {
+		return nil, err
+	}
+	return submit[assettypes.AssetResult](c, "TransferAsset", []string{assetID, buyerMSPID}, transient, c.mspID, buyerMSPID)
+}
+
+// TransferAssetAsOperator sells an asset of sellerMSPID to buyerMSPID as the operator the seller
+// approved, with the properties and agreement the seller shared. Peers of the seller and buyer
+// orgs endorse the transfer.
+func (c *Contract) TransferAssetAsOperator(assetID string, sellerMSPID string, buyerMSPID string, properties *assettypes.AssetProperties, agreement *assettypes.Agreement) (*assettypes.AssetResult, error) {
+	transient, err := transferTransient(properties, agreement)
+	if err != nil {
+		return nil, err
+	}
+	return submit[assettypes.AssetResult](c, "TransferAsset", []string{assetID, buyerMSPID}, transient, sellerMSPID, buyerMSPID)
+}
+
+// ScheduleTransfer records the sale of an asset owned by the client's org to buyerMSPID, taking
+// effect at effectiveTime. The properties and agreement are checked as for TransferAsset and must
+// be passed again to ExecuteScheduledTransfer.
+func (c *Contract) ScheduleTransfer(assetID string, buyerMSPID string, effectiveTime time.Time, properties *assettypes.AssetProperties, agreement *assettypes.Agreement) (*assettypes.ScheduledTransfer, error) {
+	transient, err := transferTransient(properties, agreement)
+	if err != nil {
+		return nil, err
+	}
+	args := []string{assetID, buyerMSPID, effectiveTime.UTC().Format(time.RFC3339)}
+	return submit[assettypes.ScheduledTransfer](c, "ScheduleTransfer", args, transient, c.mspID, buyerMSPID)
+}
+
+// ExecuteScheduledTransfer transfers the asset of a scheduled transfer to its buyer once the
+// effective time has passed, as a client of either the seller or the buyer org
+func (c *Contract) ExecuteScheduledTransfer(scheduled *assettypes.ScheduledTransfer, properties *assettypes.AssetProperties, agreement *assettypes.Agreement) (*assettypes.AssetResult, error) {
+	transient, err := transferTransient(properties, agreement)
+	if err != nil {
+		return nil, err
+	}
+	return submit[assettypes.AssetResult](c, "ExecuteScheduledTransfer", []string{scheduled.ID}, transient, scheduled.SellerOrg, scheduled.BuyerOrg)
+}
+
+// CancelScheduledTransfer cancels a scheduled transfer before its effective time, as a client of
+// either the seller or the buyer org
+func (c *Contract) CancelScheduledTransfer(scheduled *assettypes.ScheduledTransfer) (*assettypes.AssetResult, error) {
+	return submit[assettypes.AssetResult](c, "CancelScheduledTransfer", []string{scheduled.ID}, nil, scheduled.SellerOrg, scheduled.BuyerOrg)
+}
+
+// GetScheduledTransfer returns the pending scheduled transfer of an asset
+func (c *Contract) GetScheduledTransfer(assetID string) (*assettypes.ScheduledTransfer, error) {
+	return evaluate[assettypes.ScheduledTransfer](c, "GetScheduledTransfer", []string{assetID}, nil)
+}
+
+// ApproveAssetOperator approves operator, the client ID of a broker, to transfer an asset owned by
+// the client's org on its behalf, for one transfer when singleUse is set or until revoked
+func (c *Contract) ApproveAssetOperator(assetID string, operator string, singleUse bool) (*assettypes.AssetOperator, error) {
+	return submit[assettypes.AssetOperator](c, "ApproveAssetOperator", []string{assetID, operator, strconv.FormatBool(singleUse)}, nil, c.mspID)
+}
+
+// RevokeAssetOperator removes the operator approved for an asset owned by the client's org
+func (c *Contract) RevokeAssetOperator(assetID string) (*assettypes.AssetResult, error) {
+	return submit[assettypes.AssetResult](c, "RevokeAssetOperator", []string{assetID}, nil, c.mspID)
+}
+
+// GetAssetOperator returns the operator approved for an asset
+func (c *Contract) GetAssetOperator(assetID string) (*assettypes.AssetOperator, error) {
+	return evaluate[assettypes.AssetOperator](c, "GetAssetOperator", []string{assetID}, nil)
+}
+
+// PutAssetTemplate creates or replaces an asset template. The client needs asset.PutAssetTemplate
+// in the access-control chaincode.
+func (c *Contract) PutAssetTemplate(template *assettypes.AssetTemplate) (*assettypes.AssetTemplate, error) {
+	templateJSON, err := json.Marshal(template)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal asset template: %v", err)
+	}
+	return submit[assettypes.AssetTemplate](c, "PutAssetTemplate", []string{string(templateJSON)}, nil, c.mspID)
+}
+
+// GetAssetTemplate returns an asset template
+func (c *Contract) GetAssetTemplate(templateID string) (*assettypes.AssetTemplate, error) {
+	return evaluate[assettypes.AssetTemplate](c, "GetAssetTemplate", []string{templateID}, nil)
+}
+
+// CreateAssetFromTemplate creates an asset of the client's org from a template, with the attributes,
+// extra tags and description of overrides, keeping its properties in the org's implicit collection
+func (c *Contract) CreateAssetFromTemplate(templateID string, overrides *assettypes.TemplateOverrides, properties *assettypes.AssetProperties) (*assettypes.AssetResult, error) {
+	overridesJSON, err := json.Marshal(overrides)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal template overrides: %v", err)
+	}
+	transient, err := transientMap(propertiesKey, properties)
+	if err != nil {
+		return nil, err
+	}
+	return submit[assettypes.AssetResult](c, "CreateAssetFromTemplate", []string{templateID, string(overridesJSON)}, transient, c.mspID)
+}
+
+// SetAssetExpiry sets the time an asset owned by the client's org lapses, after which it can no
+// longer be sold; a zero expiresAt removes the expiry
+func (c *Contract) SetAssetExpiry(assetID string, expiresAt time.Time) (*assettypes.AssetResult, error) {
+	expiry := ""
+	if !expiresAt.IsZero() {
+		expiry = expiresAt.UTC().Format(time.RFC3339)
+	}
+	return submit[assettypes.AssetResult](c, "SetAssetExpiry", []string{assetID, expiry}, nil, c.mspID)
+}
+
+// ExpireAssets marks up to pageSize lapsed assets EXPIRED and returns their IDs. ownerMSPIDs are the
+// orgs owning them, whose peers endorse.
+func (c *Contract) ExpireAssets(pageSize int, ownerMSPIDs ...string) ([]string, error) {
+	expired, err := submit[[]string](c, "ExpireAssets", []string{strconv.Itoa(pageSize)}, nil, ownerMSPIDs...)
+	if err != nil {
+		return nil, err
+	}
+	return *expired, nil
+}
+
+// SetInspection checks the properties a seller showed the client's org against the hash of the
+// asset's properties on the ledger
+func (c *Contract) SetInspection(assetID string, properties *assettypes.AssetProperties) (bool, error) {
+	transient, err := transientMap(propertiesKey, properties)
+	if err != nil {
+		return false, err
+	}
+	matches, err := evaluate[bool](c, "SetInspection", []string{assetID}, transient)
+	if err != nil {
+		return false, err
+	}
+	return *matches, nil
+}
+
+// ReadAsset returns the public data of an asset
+func (c *Contract) ReadAsset(assetID string) (*assettypes.Asset, error) {
+	return evaluate[assettypes.Asset](c, "ReadAsset", []string{assetID}, nil)
+}
+
+// GetAssetPrivateProperties returns the properties of an asset kept in the client org's collection
+func (c *Contract) GetAssetPrivateProperties(assetID string) (*assettypes.AssetProperties, error) {
+	return evaluate[assettypes.AssetProperties](c, "GetAssetPrivateProperties", []string{assetID}, nil)
+}
+
+// GetAssetSalesPrice returns the price the client's org asks for an asset
+func (c *Contract) GetAssetSalesPrice(assetID string) (*assettypes.Agreement, error) {
+	return evaluate[assettypes.Agreement](c, "GetAssetSalesPrice", []string{assetID}, nil)
+}
+
+// GetAssetBidPrice returns the price the client's org bids for an asset
+func (c *Contract) GetAssetBidPrice(assetID string) (*assettypes.Agreement, error) {
+	return evaluate[assettypes.Agreement](c, "GetAssetBidPrice", []string{assetID}, nil)
+}
+
+// GetAssetReceipts returns the receipts of the client org's sales and purchases of an asset
+func (c *Contract) GetAssetReceipts(assetID string) ([]assettypes.Receipt, error) {
+	receipts, err := evaluate[[]assettypes.Receipt](c, "GetAssetReceipts", []string{assetID}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return *receipts, nil
+}
+
+// QueryAssetHistory returns every modification of an asset, oldest first. It fails with
+// RESULTS_TRUNCATED for an asset with more modifications than the chaincode returns at once, whose
+// history QueryAssetHistoryPage reads a page at a time.
+func (c *Contract) QueryAssetHistory(assetID string) ([]assettypes.QueryResult, error) {
+	history, err := evaluate[[]assettypes.QueryResult](c, "QueryAssetHistory", []string{assetID}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return *history, nil
+}
+
+// QueryAssetHistoryPage returns up to pageSize modifications of an asset starting at bookmark, empty
+// for the first page
+func (c *Contract) QueryAssetHistoryPage(assetID string, pageSize int, bookmark string) (*assettypes.HistoryPage, error) {
+	return evaluate[assettypes.HistoryPage](c, "QueryAssetHistoryPage", []string{assetID, strconv.Itoa(pageSize), bookmark}, nil)
+}
+
+// ListByCompositeKey returns up to pageSize raw entries of a composite key prefix whose keys start
+// with partialKeys, from bookmark on: audit entries and records from the world state, or receipts
+// and prices from the client org's collection. The client needs asset.ListByCompositeKey in the
+// access-control chaincode.
+func (c *Contract) ListByCompositeKey(objectType string, partialKeys []string, pageSize int, bookmark string) (*appclient.KeyPage, error) {
+	keysJSON, err := json.Marshal(append([]string{}, partialKeys...))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal partial keys: %v", err)
+	}
+	return evaluate[appclient.KeyPage](c, "ListByCompositeKey", []string{objectType, string(keysJSON), strconv.Itoa(pageSize), bookmark}, nil)
+}
+
+// WhoAmI returns the identity of the client as the chaincode decodes it from its certificate
+func (c *Contract) WhoAmI() (*appclient.ClientIdentity, error) {
+	return evaluate[appclient.ClientIdentity](c, "WhoAmI", nil, nil)
+}
+
+// GetVersion returns the version and commit of the chaincode build running on the peer
+func (c *Contract) GetVersion() (*appclient.BuildInfo, error) {
+	return evaluate[appclient.BuildInfo](c, "GetVersion", nil, nil)
+}
+
+// Ping evaluates Ping on a peer of the client's org, checking it runs the chaincode and reads its
+// world state
+func (c *Contract) Ping() (*appclient.PingResult, error) {
+	return evaluate[appclient.PingResult](c, "Ping", nil, nil)
+}
+
+// GetAssetsPage returns up to pageSize assets starting at bookmark, empty for the first page
+func (c *Contract) GetAssetsPage(pageSize int, bookmark string) (*assettypes.AssetPage, error) {
+	return evaluate[assettypes.AssetPage](c, "GetAssetsPage", []string{strconv.Itoa(pageSize), bookmark}, nil)
+}
+
+// QueryAssetsByOwner returns up to pageSize assets of ownerOrg starting at bookmark, empty for the
+// first page. The channel needs CouchDB as its state database.
+func (c *Contract) QueryAssetsByOwner(ownerOrg string, pageSize int, bookmark string) (*assettypes.AssetPage, error) {
+	return evaluateOn[assettypes.AssetPage](c, c.queries, "QueryAssetsByOwner", []string{ownerOrg, strconv.Itoa(pageSize), bookmark}, nil)
+}
+
+// QueryActiveAssetsByOwner is QueryAssetsByOwner leaving out the expired assets
+func (c *Contract) QueryActiveAssetsByOwner(ownerOrg string, pageSize int, bookmark string) (*assettypes.AssetPage, error) {
+	return evaluateOn[assettypes.AssetPage](c, c.queries, "QueryActiveAssetsByOwner", []string{ownerOrg, strconv.Itoa(pageSize), bookmark}, nil)
+}
+
+// QueryAssetsByTimeRange returns up to pageSize assets changed from from up to but not including to,
+// to the second, starting at bookmark. The channel needs CouchDB as its state database.
+func (c *Contract) QueryAssetsByTimeRange(from time.Time, to time.Time, pageSize int, bookmark string) (*assettypes.AssetPage, error) {
+	args := []string{from.UTC().Format(time.RFC3339), to.UTC().Format(time.RFC3339), strconv.Itoa(pageSize), bookmark}
+	return evaluateOn[assettypes.AssetPage](c, c.queries, "QueryAssetsByTimeRange", args, nil)
+}
+
+// GetAllAssets reads every asset page by page, calling fn with each page until the last one or an
+// error from fn
+func (c *Contract) GetAllAssets(pageSize int, fn func(assets []*assettypes.Asset) error) error {
+	bookmark := ""
+	for {
+		page, err := c.GetAssetsPage(pageSize, bookmark)
+		if err != nil {
+			return err
+		}
+		err = fn(page.Assets)
+		if err != nil {
+			return err
+		}
+		if page.Bookmark == "" || page.Bookmark == bookmark {
+			return nil
+		}
+		bookmark = page.Bookmark
+	}
+}
+
+// submit submits a transaction endorsed by peers of the given orgs, waits for it to be committed and
+// decodes its JSON result
+func submit[T any](c *Contract, function string, args []string, transient map[string][]byte, endorsers ...string) (*T, error) {
+	start := time.Now()
+	resultJSON, err := appclient.Submit(c.ctx, c.contract, function, transient, client.WithArguments(args...), client.WithEndorsingOrganizations(endorsers...))
+	c.observer.Observe(c.contract.ChaincodeName(), function, true, start, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to submit %s: %w", function, err)
+	}
+	return decode[T](function, resultJSON)
+}
+
+// evaluate evaluates a query on a peer of the client's org, which holds the org's private data, and
+// decodes its JSON result
+func evaluate[T any](c *Contract, function string, args []string, transient map[string][]byte) (*T, error) {
+	return evaluateOn[T](c, c.contract, function, args, transient)
+}
+
+// evaluateOn is evaluate calling a function of another contract of the chaincode
+func evaluateOn[T any](c *Contract, contract *client.Contract, function string, args []string, transient map[string][]byte) (*T, error) {
+	options := []client.ProposalOption{client.WithArguments(args...), client.WithEndorsingOrganizations(c.mspID)}
+	if transient != nil {
+		options = append(options, client.WithTransient(transient))
+	}
+	start := time.Now()
+	resultJSON, err := appclient.Evaluate(c.ctx, contract, function, options...)
+	c.observer.Observe(contract.ChaincodeName(), function, false, start, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate %s: %w", function, err)
+	}
+	return decode[T](function, resultJSON)
+}
+
+func decode[T any](function string, resultJSON []byte) (*T, error) {
+	result := new(T)
+	err := json.Unmarshal(resultJSON, result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal result of %s: %v", function, err)
+	}
+	return result, nil
+}
+
+// transferTransient returns the transient map of a transfer, holding the asset properties and the
+// agreed price
+func transferTransient(properties *assettypes.AssetProperties, agreement *assettypes.Agreement) (map[string][]byte, error) {
+	transient, err := transientMap(propertiesKey, properties)
+	if err != nil {
+		return nil, err
+	}
+	priceTransient, err := transientMap(priceKey, agreement)
+	if err != nil {
+		return nil, err
+	}
+	transient[priceKey] = priceTransient[priceKey]
+	return transient, nil
+}
+
+// transientMap returns a transient map holding the JSON encoding of value under key. The chaincode
+// compares hashes of these bytes, so the same value must always encode the same way, which
+// encoding/json guarantees for structs.
+func transientMap(key string, value interface{}) (map[string][]byte, error) {
+	valueJSON, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s: %v", key, err)
+	}
+	return map[string][]byte{key: valueJSON}, nil
+}
@@ -0,0 +1,249 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-secured-agreement/application-go/asset"
+	"github.com/hyperledger/fabric-samples/asset-transfer-secured-agreement/assettypes"
+	"github.com/hyperledger/fabric-samples/internal/appclient"
+	"github.com/hyperledger/fabric-samples/internal/tracing"
+)
+
+const usage = `Usage: application-go [flags] <command> [args]
+
+Commands:
+  demo                                 create an asset as Org1, agree on a price with Org2 and transfer it
+  read <assetID>                       public data of an asset
+  properties <assetID>                 private properties of an asset held by the client's org
+  history <assetID>                    every modification of an asset
+  receipts <assetID>                   receipts of the client org's trades of an asset
+  list [pageSize]                      every asset, read page by page
+
+Flags:
+`
+
+var (
+	networkDir = flag.String("network", "../../test-network", "directory of the test network")
+	org        = flag.Int("org", 1, "org of the client, 1 or 2")
+	user       = flag.String("user", "User1", "user of the org to connect as")
+	walletDir  = flag.String("wallet", "", "wallet directory to connect from as the identity labeled org<N>-<user>, e.g. org1-user1, instead of the user's MSP directory")
+	channel    = flag.String("channel", "mychannel", "channel the chaincode is deployed on")
+	chaincode  = flag.String("chaincode", "secured", "name the asset chaincode is deployed as")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	args := flag.Args()
+	if len(args) == 0 {
+		args = []string{"demo"}
+	}
+	shutdownTracing, err := tracing.Start(context.Background(), "asset-transfer-secured-agreement/application-go")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer shutdownTracing(context.Background())
+
+	if args[0] == "demo" {
+		err = demo()
+		if err != nil {
+			log.Fatalf("demo failed: %v", err)
+		}
+		return
+	}
+
+	contract, closeConnection, err := connect(*org, *user)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer closeConnection()
+
+	err = run(contract, args[0], args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// connect connects to the peer of the org as the user and returns the asset contract
+func connect(orgNumber int, userName string) (*asset.Contract, func(), error) {
+	domain := fmt.Sprintf("org%d.example.com", orgNumber)
+	orgDir := filepath.Join(*networkDir, "organizations", "peerOrganizations", domain)
+
+	profile, err := appclient.LoadProfile(filepath.Join(orgDir, fmt.Sprintf("connection-org%d.json", orgNumber)))
+	if err != nil {
+		return nil, nil, err
+	}
+	connection, err := connectUser(profile, filepath.Join(orgDir, "users", userName+"@"+domain, "msp"), orgNumber, userName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	closeConnection := func() {
+		if err := connection.Close(); err != nil {
+			log.Printf("failed to close connection: %v", err)
+		}
+	}
+	return asset.NewContract(connection.GetNetwork(*channel), *chaincode, profile.MSPID()), closeConnection, nil
+}
+
+// connectUser connects as the user of the org, from the wallet when one is given and from the user's
+// MSP directory in the test network otherwise
+func connectUser(profile *appclient.Profile, mspDir string, orgNumber int, userName string) (*appclient.Connection, error) {
+	if *walletDir == "" {
+		return appclient.Connect(profile, "", mspDir)
+	}
+	wallet, err := appclient.OpenWallet(*walletDir)
+	if err != nil {
+		return nil, err
+	}
+	return appclient.ConnectWallet(profile, "", wallet, fmt.Sprintf("org%d-%s", orgNumber, strings.ToLower(userName)))
+}
+
+// run runs one command as the connected client
+func run(contract *asset.Contract, command string, args []string) error {
+	switch command {
+	case "read", "properties", "history", "receipts":
+		if len(args) != 1 {
+			return fmt.Errorf("%s takes an asset ID", command)
+		}
+		switch command {
+		case "read":
+			return printResult(contract.ReadAsset(args[0]))
+		case "properties":
+			return printResult(contract.GetAssetPrivateProperties(args[0]))
+		case "history":
+			return printResult(contract.QueryAssetHistory(args[0]))
+		default:
+			return printResult(contract.GetAssetReceipts(args[0]))
+		}
+	case "list":
+		pageSize := 10
+		if len(args) > 0 {
+			size, err := strconv.Atoi(args[0])
+			if err != nil || size < 1 || size > asset.MaxPageSize {
+				return fmt.Errorf("page size must be an integer between 1 and %d", asset.MaxPageSize)
+			}
+			pageSize = size
+		}
+		return listAssets(contract, pageSize)
+	default:
+		flag.Usage()
+		return fmt.Errorf("unknown command %s", command)
+	}
+}
+
+// demo runs the flow of the asset-transfer-secured-agreement README: Org1 creates an asset, both orgs
+// agree on a price, Org2 inspects the private properties Org1 shows it and Org1 transfers the asset
+// to Org2. The history, receipts and a paginated listing of the assets are printed at the end.
+func demo() error {
+	org1, closeOrg1, err := connect(1, *user)
+	if err != nil {
+		return err
+	}
+	defer closeOrg1()
+	org2, closeOrg2, err := connect(2, *user)
+	if err != nil {
+		return err
+	}
+	defer closeOrg2()
+
+	assetID := fmt.Sprintf("asset%d", time.Now().Unix())
+	properties := &assettypes.AssetProperties{
+		ObjectType:     "asset_properties",
+		ID:             assetID,
+		Color:          "blue",
+		Size:           35,
+		AppraisedValue: "1250.75",
+		Salt:           fmt.Sprintf("%x", time.Now().UnixNano()),
+	}
+	agreement := &assettypes.Agreement{ID: assetID, Price: 100, TradeID: fmt.Sprintf("trade%d", time.Now().Unix())}
+
+	fmt.Printf("--> Create %s as Org1\n", assetID)
+	if err := printResult(org1.CreateAsset(assetID, "This asset is for sale", properties)); err != nil {
+		return err
+	}
+	fmt.Println("--> Read the asset as Org2")
+	if err := printResult(org2.ReadAsset(assetID)); err != nil {
+		return err
+	}
+	fmt.Println("--> Org1 agrees to sell for 100")
+	if err := printResult(org1.AgreeToSell(agreement)); err != nil {
+		return err
+	}
+	fmt.Println("--> Org2 agrees to buy for 100")
+	if err := printResult(org2.AgreeToBuy(agreement)); err != nil {
+		return err
+	}
+
+	fmt.Println("--> Org2 inspects the properties Org1 shared off-chain")
+	matches, err := org2.SetInspection(assetID, properties)
+	if err != nil {
+		return err
+	}
+	if !matches {
+		return fmt.Errorf("properties of %s do not match the hash on the ledger", assetID)
+	}
+	fmt.Println("--> Properties match the hash on the ledger")
+
+	fmt.Println("--> Org1 transfers the asset to Org2")
+	if err := printResult(org1.TransferAsset(assetID, "Org2MSP", properties, agreement)); err != nil {
+		return err
+	}
+	fmt.Println("--> Read the asset as Org2")
+	if err := printResult(org2.ReadAsset(assetID)); err != nil {
+		return err
+	}
+
+	fmt.Println("--> History of the asset")
+	if err := printResult(org2.QueryAssetHistory(assetID)); err != nil {
+		return err
+	}
+	fmt.Println("--> Receipts of Org1 and Org2")
+	if err := printResult(org1.GetAssetReceipts(assetID)); err != nil {
+		return err
+	}
+	if err := printResult(org2.GetAssetReceipts(assetID)); err != nil {
+		return err
+	}
+
+	fmt.Println("--> All assets, 2 per page")
+	return listAssets(org2, 2)
+}
+
+// listAssets prints every asset, one page at a time
+func listAssets(contract *asset.Contract, pageSize int) error {
+	page := 0
+	return contract.GetAllAssets(pageSize, func(assets []*assettypes.Asset) error {
+		page++
+		fmt.Printf("--> Page %d\n", page)
+		return printResult(assets, nil)
+	})
+}
+
+// printResult prints the result of a function as indented JSON, or returns its error
+func printResult(result interface{}, err error) error {
+	if err != nil {
+		return err
+	}
+	resultJSON, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal result: %v", err)
+	}
+	fmt.Println(string(resultJSON))
+	return nil
+}
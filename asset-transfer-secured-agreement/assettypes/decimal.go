@@ -0,0 +1,100 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assettypes
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// MaxDecimalScale is the largest number of fraction digits of a Decimal
+const MaxDecimalScale = 18
+
+// decimalPattern matches a non-negative decimal without sign, exponent or leading zeros
+var decimalPattern = regexp.MustCompile(`^(0|[1-9][0-9]*)(\.[0-9]+)?$`)
+
+// Decimal is a non-negative decimal number kept as its digits and encoded as a JSON string, e.g.
+// "1250.75". Values the endorsing peers agree on never pass through a float, so every peer writes
+// the same bytes and no precision is lost.
+type Decimal string
+
+// ParseDecimal returns value as a Decimal, or an error when it is not a non-negative decimal of at
+// most MaxDecimalScale fraction digits
+func ParseDecimal(value string) (Decimal, error) {
+	if !decimalPattern.MatchString(value) {
+		return "", fmt.Errorf("%q is not a non-negative decimal such as \"1250.75\"", value)
+	}
+	if i := strings.IndexByte(value, '.'); i >= 0 && len(value)-i-1 > MaxDecimalScale {
+		return "", fmt.Errorf("%q has more than %d fraction digits", value, MaxDecimalScale)
+	}
+	return Decimal(value), nil
+}
+
+// Cmp compares d and other by value, so "2.50" equals "2.5": it returns -1 when d is less than
+// other, 0 when they are equal and 1 when d is greater
+func (d Decimal) Cmp(other Decimal) int {
+	dInt, dFrac := d.split()
+	otherInt, otherFrac := other.split()
+	if len(dInt) != len(otherInt) {
+		return sign(len(dInt) - len(otherInt))
+	}
+	if c := strings.Compare(dInt, otherInt); c != 0 {
+		return c
+	}
+	for len(dFrac) < len(otherFrac) {
+		dFrac += "0"
+	}
+	for len(otherFrac) < len(dFrac) {
+		otherFrac += "0"
+	}
+	return strings.Compare(dFrac, otherFrac)
+}
+
+// IsZero reports whether d is unset or zero
+func (d Decimal) IsZero() bool {
+	return d == "" || d.Cmp("0") == 0
+}
+
+// UnmarshalJSON reads a Decimal from a JSON string. A JSON number, as written by clients before
+// the value was a Decimal, is read from its digits rather than through a float.
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	value := string(data)
+	if strings.HasPrefix(value, `"`) {
+		if err := json.Unmarshal(data, &value); err != nil {
+			return err
+		}
+	}
+	parsed, err := ParseDecimal(value)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// split returns the integer and fraction digits of d, an unset Decimal being 0
+func (d Decimal) split() (string, string) {
+	if d == "" {
+		return "0", ""
+	}
+	parts := strings.SplitN(string(d), ".", 2)
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
+
+// sign returns -1, 0 or 1 as n is negative, zero or positive
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,68 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assettypes
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestParseDecimal(t *testing.T) {
+	for _, value := range []string{"0", "1250", "1250.75", "0.000000000000000001", "99999999999999999999999999.5"} {
+		if _, err := ParseDecimal(value); err != nil {
+			t.Errorf("failed to parse %q: %v", value, err)
+		}
+	}
+	for _, value := range []string{"", "-1", "+1", "01", "1.", ".5", "1e3", "1,5", " 1", "NaN", "0.0000000000000000001"} {
+		if _, err := ParseDecimal(value); err == nil {
+			t.Errorf("parsed %q", value)
+		}
+	}
+}
+
+func TestDecimalCmp(t *testing.T) {
+	tests := []struct {
+		a, b Decimal
+		want int
+	}{
+		{"2.5", "2.50", 0},
+		{"2.5", "2.49", 1},
+		{"10", "9.99", 1},
+		{"0.1", "0.01", 1},
+		{"100", "100.000001", -1},
+		{"", "0", 0},
+	}
+	for _, tt := range tests {
+		if got := tt.a.Cmp(tt.b); got != tt.want {
+			t.Errorf("%q.Cmp(%q) is %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := tt.b.Cmp(tt.a); got != -tt.want {
+			t.Errorf("%q.Cmp(%q) is %d, want %d", tt.b, tt.a, got, -tt.want)
+		}
+	}
+}
+
+func TestDecimalJSON(t *testing.T) {
+	var properties AssetProperties
+	err := json.Unmarshal([]byte(`{"asset_id":"asset1","appraised_value":"1250.10"}`), &properties)
+	if err != nil || properties.AppraisedValue != "1250.10" {
+		t.Fatalf("appraised value is %q, error %v", properties.AppraisedValue, err)
+	}
+	// a number written before the value was a Decimal keeps its digits
+	err = json.Unmarshal([]byte(`{"asset_id":"asset1","appraised_value":0.10000000000000001}`), &properties)
+	if err != nil || properties.AppraisedValue != "0.10000000000000001" {
+		t.Fatalf("appraised value is %q, error %v", properties.AppraisedValue, err)
+	}
+	err = json.Unmarshal([]byte(`{"asset_id":"asset1","appraised_value":"1e3"}`), &properties)
+	if err == nil || !strings.Contains(err.Error(), "is not a non-negative decimal") {
+		t.Errorf("expected an invalid decimal error, got %v", err)
+	}
+
+	propertiesJSON, err := json.Marshal(AssetProperties{ID: "asset1", Salt: "s"})
+	if err != nil || strings.Contains(string(propertiesJSON), "appraised_value") {
+		t.Errorf("properties without appraised value are %s", propertiesJSON)
+	}
+}
@@ -0,0 +1,3 @@
+module github.com/hyperledger/fabric-samples/asset-transfer-secured-agreement/assettypes
+
+go 1.13
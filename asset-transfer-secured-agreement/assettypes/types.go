@@ -0,0 +1,278 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package assettypes holds the types the secured agreement asset chaincode stores and returns, so
+// the chaincode and its client applications encode and decode the same JSON.
+package assettypes
+
+import "time"
+
+// Asset is the public data of an asset, e.g.
+// {"objectType":"asset","assetID":"asset1","ownerOrg":"Org1MSP","publicDescription":"This asset is for sale"}
+type Asset struct {
+	ObjectType        string `json:"objectType"` // ObjectType is used to distinguish different object types in the same chaincode namespace
+	ID                string `json:"assetID"`
+	OwnerOrg          string `json:"ownerOrg"`
+	PublicDescription string `json:"publicDescription"`
+	// Content references the images and documents of the asset kept on IPFS, sorted by name
+	Content []ContentRef `json:"content,omitempty" metadata:",optional"`
+	// ItemType, TemplateID, Tags and Attributes are set for assets created from a template
+	ItemType   string            `json:"itemType,omitempty" metadata:",optional"`
+	TemplateID string            `json:"templateID,omitempty" metadata:",optional"`
+	Tags       []string          `json:"tags,omitempty" metadata:",optional"`
+	Attributes map[string]string `json:"attributes,omitempty" metadata:",optional"`
+	// ExpiresAt is the RFC 3339 time a perishable asset lapses at, after which it can no longer be
+	// sold. Status is AssetStatusExpired once ExpireAssets has recorded it.
+	ExpiresAt string `json:"expiresAt,omitempty" metadata:",optional"`
+	Status    string `json:"status,omitempty" metadata:",optional"`
+	// CreatedAt and UpdatedAt are the RFC 3339 UTC timestamps of the transactions that created the
+	// asset and last changed its public data, unset on assets last written before they were recorded
+	CreatedAt string `json:"createdAt,omitempty" metadata:",optional"`
+	UpdatedAt string `json:"updatedAt,omitempty" metadata:",optional"`
+}
+
+// AssetStatusExpired is the status of an asset ExpireAssets found past its ExpiresAt
+const AssetStatusExpired = "EXPIRED"
+
+// AssetTemplate standardizes the assets of one item type: the public attributes they must have,
+// the rules each attribute must follow and the tags they get, so every business unit creating them
+// records them the same way. An attribute is allowed when it is required or has a rule.
+type AssetTemplate struct {
+	ObjectType         string               `json:"objectType"`
+	ID                 string               `json:"templateID"`
+	ItemType           string               `json:"itemType"`
+	PublicDescription  string               `json:"publicDescription,omitempty" metadata:",optional"`
+	RequiredAttributes []string             `json:"requiredAttributes"`
+	Rules              map[string]FieldRule `json:"rules,omitempty" metadata:",optional"`
+	DefaultTags        []string             `json:"defaultTags,omitempty" metadata:",optional"`
+	UpdatedBy          string               `json:"updatedBy,omitempty" metadata:",optional"`
+	TxID               string               `json:"txID,omitempty" metadata:",optional"`
+	Timestamp          string               `json:"timestamp,omitempty" metadata:",optional"`
+}
+
+// FieldRule is a check an attribute of an asset created from a template must pass: a regular
+// expression its whole value matches and the values it may take, each applied when set, and its
+// maximum length, 0 for no limit
+type FieldRule struct {
+	Pattern   string   `json:"pattern,omitempty" metadata:",optional"`
+	OneOf     []string `json:"oneOf,omitempty" metadata:",optional"`
+	MaxLength int      `json:"maxLength"`
+}
+
+// TemplateOverrides are the values of an asset created from a template: its ID, its attributes,
+// tags added to the template's defaults and a public description replacing the template's
+type TemplateOverrides struct {
+	ID                string            `json:"assetID"`
+	PublicDescription string            `json:"publicDescription,omitempty"`
+	Attributes        map[string]string `json:"attributes,omitempty"`
+	Tags              []string          `json:"tags,omitempty"`
+}
+
+// ContentRef is a named image or document of an asset kept on IPFS, e.g. "photo" or "deed", by its
+// CID and the SHA-256 of its bytes, so anyone fetching the content can check it is what the owner
+// referenced
+type ContentRef struct {
+	Name      string    `json:"name"`
+	CID       string    `json:"cid"`
+	SHA256    string    `json:"sha256"`
+	UpdatedBy string    `json:"updatedBy"`
+	TxID      string    `json:"txID"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// ContentAttestation records that a verifier fetched the content of a CID and hashed it. Matches
+// is whether the hash it found equals the one the asset references for the CID.
+type ContentAttestation struct {
+	ObjectType  string    `json:"objectType"`
+	AssetID     string    `json:"assetID"`
+	CID         string    `json:"cid"`
+	SHA256      string    `json:"sha256"`
+	Matches     bool      `json:"matches"`
+	Verifier    string    `json:"verifier"`
+	VerifierOrg string    `json:"verifierOrg"`
+	TxID        string    `json:"txID"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// AssetResult is returned by the functions that change an asset or agree to its price, so clients
+// learn the outcome of their submission without a follow-up query
+type AssetResult struct {
+	Status    string    `json:"status"`
+	TxID      string    `json:"txID"`
+	Timestamp time.Time `json:"timestamp"`
+	// Asset is the public asset after the transaction
+	Asset *Asset `json:"asset,omitempty" metadata:",optional"`
+}
+
+// AssetPage is one page of the assets on the ledger. Bookmark is passed to the next query to
+// continue after the last asset and is empty on the last page.
+type AssetPage struct {
+	Assets   []*Asset `json:"assets"`
+	Bookmark string   `json:"bookmark"`
+}
+
+// QueryResult is one modification of an asset in its history. Record is unset when the asset was
+// deleted.
+type QueryResult struct {
+	Record    *Asset    `metadata:",optional"`
+	TxId      string    `json:"txId"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// HistoryPage is one page of the history of an asset, in the order QueryAssetHistory returns it.
+// Bookmark is the transaction ID of the first modification of the next page and is empty on the
+// last page.
+type HistoryPage struct {
+	History  []QueryResult `json:"history"`
+	Bookmark string        `json:"bookmark"`
+}
+
+// Agreement is the price an org agrees to sell or buy an asset for, passed as asset_price in the
+// transient map. The seller and buyer must pass the same JSON for the transfer to complete.
+type Agreement struct {
+	ID      string `json:"asset_id"`
+	Price   int    `json:"price"`
+	TradeID string `json:"trade_id"`
+}
+
+// Receipt records a completed sale in the buyer's and seller's implicit collections.
+// Counterparty is the org on the other side of the trade.
+type Receipt struct {
+	AssetID      string    `json:"asset_id"`
+	Type         string    `json:"type"`
+	Counterparty string    `json:"counterparty"`
+	Price        int       `json:"price"`
+	Timestamp    time.Time `json:"timestamp"`
+	// TraceID is the OpenTelemetry trace ID the seller passed with the transfer, if any
+	TraceID string `json:"trace_id,omitempty" metadata:",optional"`
+}
+
+// AssetProperties are the private properties of an asset, passed as asset_properties in the
+// transient map when the asset is created and kept in the owner's implicit collection. The salt
+// keeps other orgs from guessing the properties from their hash on the ledger. AppraisedValue is
+// optional and left out of the JSON when unset, so properties created before it keep their hash.
+type AssetProperties struct {
+	ObjectType     string  `json:"object_type"`
+	ID             string  `json:"asset_id"`
+	Color          string  `json:"color"`
+	Size           int     `json:"size"`
+	AppraisedValue Decimal `json:"appraised_value,omitempty"`
+	Salt           string  `json:"salt"`
+}
+
+// Names of the chaincode events set by the transactions that change an asset's public data
+const (
+	EventAssetCreated     = "AssetCreated"
+	EventAssetUpdated     = "AssetUpdated"
+	EventAssetTransferred = "AssetTransferred"
+	// EventPersonalDataPurged is set by PurgePersonalData
+	EventPersonalDataPurged = "PersonalDataPurged"
+	// EventContentUpdated is set by SetContentRef and RemoveContentRef
+	EventContentUpdated = "ContentUpdated"
+	// EventAssetsExpired is set by ExpireAssets
+	EventAssetsExpired = "AssetsExpired"
+)
+
+// AssetEvent is the payload of the asset events. PreviousOwnerOrg is set by AssetTransferred. The
+// private properties and the agreed price are left out.
+type AssetEvent struct {
+	ID                string `json:"assetID"`
+	OwnerOrg          string `json:"ownerOrg"`
+	PreviousOwnerOrg  string `json:"previousOwnerOrg,omitempty"`
+	PublicDescription string `json:"publicDescription"`
+}
+
+// ExpiryEvent is the payload of the AssetsExpired event, the assets one ExpireAssets transaction
+// marked EXPIRED
+type ExpiryEvent struct {
+	AssetIDs []string `json:"assetIDs"`
+}
+
+// PersonalData identifies the individuals behind an asset, such as its named owner, passed as
+// personal_data in the transient map. It is kept only in the implicit collection of the org that
+// recorded it and apart from the asset properties, whose hash a sale relies on, so an erasure
+// request can be honored with PurgePersonalData without breaking a later transfer.
+type PersonalData struct {
+	ID           string `json:"asset_id"`
+	OwnerName    string `json:"owner_name"`
+	OwnerContact string `json:"owner_contact,omitempty" metadata:",optional"`
+}
+
+// PurgeResult is returned by PurgePersonalData
+type PurgeResult struct {
+	Status    string    `json:"status"`
+	TxID      string    `json:"txID"`
+	Timestamp time.Time `json:"timestamp"`
+	AssetID   string    `json:"assetID"`
+}
+
+// PersonalDataEvent is the payload of the PersonalDataPurged event: the org that erased the personal
+// data of an asset, never the data itself
+type PersonalDataEvent struct {
+	ID  string `json:"assetID"`
+	Org string `json:"org"`
+}
+
+// ContentEvent is the payload of the ContentUpdated event: the CID a content reference of an asset
+// has now, empty when it was removed, and the one it had before, empty when it is new
+type ContentEvent struct {
+	ID          string `json:"assetID"`
+	Name        string `json:"name"`
+	CID         string `json:"cid,omitempty"`
+	SHA256      string `json:"sha256,omitempty"`
+	PreviousCID string `json:"previousCID,omitempty"`
+}
+
+// ScheduledTransfer is a sale of an asset at an agreed price that takes effect at EffectiveTime,
+// kept in the world state until a client of the seller or buyer org executes it at or after that
+// time, or cancels it before. PriceHash is the SHA-256 of the agreed price JSON, which must be
+// passed again to execute the transfer; the price itself stays in the implicit collections.
+type ScheduledTransfer struct {
+	ObjectType    string    `json:"objectType"`
+	ID            string    `json:"assetID"`
+	SellerOrg     string    `json:"sellerOrg"`
+	BuyerOrg      string    `json:"buyerOrg"`
+	EffectiveTime time.Time `json:"effectiveTime"`
+	PriceHash     string    `json:"priceHash"`
+	ScheduledBy   string    `json:"scheduledBy"`
+	TxID          string    `json:"txID"`
+	Timestamp     time.Time `json:"timestamp"`
+}
+
+// AssetLock keeps an asset out of transfers while a lot it was put in is for sale. LockedBy is the
+// ID of the lot, whose sale or cancellation removes the lock.
+type AssetLock struct {
+	ObjectType string    `json:"objectType"`
+	ID         string    `json:"assetID"`
+	OwnerOrg   string    `json:"ownerOrg"`
+	LockedBy   string    `json:"lockedBy"`
+	TxID       string    `json:"txID"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// AssetOperator is a client the owner org approved to transfer an asset on its behalf, such as a
+// broker, by its client ID. A SingleUse approval is consumed by the first transfer or scheduled
+// transfer it authorizes; any approval ends when the owner revokes it or the asset changes owner.
+type AssetOperator struct {
+	ObjectType string    `json:"objectType"`
+	ID         string    `json:"assetID"`
+	OwnerOrg   string    `json:"ownerOrg"`
+	Operator   string    `json:"operator"`
+	SingleUse  bool      `json:"singleUse"`
+	ApprovedBy string    `json:"approvedBy"`
+	TxID       string    `json:"txID"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// Valuation is one appraisal of an asset, kept in the implicit collection of the org owning it
+// when it was recorded, next to the earlier ones rather than replacing them. The appraised value
+// in the asset properties is the first. A new appraisal is passed as appraisal in the transient map
+// with only the asset ID and value set.
+type Valuation struct {
+	AssetID   string    `json:"asset_id"`
+	Value     Decimal   `json:"value"`
+	Appraiser string    `json:"appraiser,omitempty" metadata:",optional"`
+	TxID      string    `json:"tx_id,omitempty" metadata:",optional"`
+	Timestamp time.Time `json:"timestamp" metadata:",optional"`
+}
@@ -0,0 +1,929 @@
+//Function calls:
+
+/*
+Chaincode Invoke Functions:
+Create Asset
+DeleteAsset
+UpdateAsset
+ReadAsset
+
+AssetExists
+TransferAsset
+GetAllAssets
+
+
+//NEED TO ADD ANOTHER ORG WHCIH INSPECTS AND APPROVES
+//func Inspection (ctx , assetID, clientOrgID){
+	check asset is owned by clientorgID
+	if asset is owned Set approval == true{
+		return
+	}
+}
+
+*/
+package chaincode
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-chaincode-go/pkg/statebased"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi" //Provides the smart contract api interface
+	"github.com/hyperledger/fabric-contract-api-go/metadata"
+	"github.com/hyperledger/fabric-samples/asset-transfer-secured-agreement/assettypes"
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil"
+)
+
+const (
+	sellerPrice = "S"
+	bidderPrice = "B"
+)
+
+// receipt prefixes, kept in the buyer's and seller's implicit collections after a sale
+const (
+	typeAssetSaleReceipt = "salereceipt"
+	typeAssetBuyReceipt  = "buyreceipt"
+)
+
+// assetPrefix is the object type of the composite keys of assets. Keeping them out of the simple
+// keys leaves those to the balances of the token contract when both are bundled in one chaincode.
+const assetPrefix = "asset"
+
+// accessControlName is the name the access-control chaincode is deployed under on the channel
+const accessControlName = "acl"
+
+//Init SmartContract
+type SmartContract struct {
+	contractapi.Contract
+}
+
+// Asset details (start with capitals) to work with contract api metadata
+// The types are shared with the client applications through the assettypes module
+type Asset = assettypes.Asset
+
+// AssetResult is returned by the functions that change an asset or agree to its price
+type AssetResult = assettypes.AssetResult
+
+// ****************************  CreateAsset  *********************************************
+
+/*Creates an asset and sets it as owned by the client's org.
+The function checks to see if the asset already exist before taking any action in creating the contract.
+Transient data is private to the application-smart contract interaction. It is not recorded on the ledger and is often used in conjunction with private data collections
+Transient data is confidental and excluded from the ledger.
+Each time the contractapi is passed in a function a transaction context "ctx" is used, from which you can get the chaincode api functions e.g GetStub() , GetState() .
+*/
+func (s *SmartContract) CreateAsset(ctx ledgerutil.TransactionContextInterface, assetID, publicDescription string) (*AssetResult, error) {
+	assetID = ledgerutil.NormalizeID(assetID)
+	v := ledgerutil.NewValidator()
+	v.Key("assetID", assetID)
+	v.Text("publicDescription", publicDescription, ledgerutil.MaxTextLength)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	return _createAsset(ctx, &Asset{ID: assetID, PublicDescription: publicDescription})
+}
+
+// _createAsset creates an asset owned by the client's org from its checked public data, with the
+// private properties and optional personal data passed in the transient map
+func _createAsset(ctx ledgerutil.TransactionContextInterface, assetCreate *Asset) (*AssetResult, error) {
+	assetID := assetCreate.ID
+	v := ledgerutil.NewValidator()
+	transientMap, err := ctx.GetStub().GetTransient() // Transient data is private to the application-smart contract interaction.
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "error getting transient")
+	}
+
+	// Must use transient to access Asset properties  as they are private
+	privatePropertiesJSON, key := transientMap["asset_properties"]
+	if !key {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "asset_properties key not found in the private transient map")
+	}
+	v.Payload("asset_properties", privatePropertiesJSON)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	appraisedValue, err := _validateAppraisedValue(privatePropertiesJSON)
+	if err != nil {
+		return nil, err
+	}
+	// personal data such as the name of an individual owner is optional and kept apart from the
+	// properties, so it can be purged
+	var personalData *PersonalData
+	if personalDataJSON, ok := transientMap["personal_data"]; ok {
+		personalData, err = _parsePersonalData(assetID, personalDataJSON)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Verify client id of org and verify it matches peer org id.
+	// Client is only authorized to read/write private data from its own peer for this contract.
+	clientOrgID, err := _getClientOrgID(ctx, true) //get the client org id from transaction context
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to get verified OrgID")
+	}
+	// the access-control chaincode decides which clients and orgs may register assets
+	err = _checkAccess(ctx, "asset.CreateAsset")
+	if err != nil {
+		return nil, err
+	}
+	err = _checkKYC(ctx, clientOrgID)
+	if err != nil {
+		return nil, err
+	}
+	assetKey, err := _assetKey(ctx.GetStub(), assetID)
+	if err != nil {
+		return nil, err
+	}
+	existingAsset, err := ctx.GetStub().GetState(assetKey)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to read from world state")
+	}
+	if existingAsset != nil {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeAssetExists, "asset %s already exists", assetID)
+	}
+	//create asset data from struct Asset
+	assetCreate.ObjectType = "asset"
+	assetCreate.OwnerOrg = clientOrgID
+	createdAt, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	assetCreate.CreatedAt = ledgerutil.FormatTime(createdAt)
+	//getStub accesses the ledger and requests to update the state to ledger
+	err = _putAsset(ctx, assetCreate) //check and verify assetCreated.ID
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to put asset in public data")
+	}
+
+	// add private immutable asset properties to owner's private data collection
+	collection := _buildClientOrgName(clientOrgID) //_buildClientOrgName function passing in ownerOrg: clientOrgID
+	//call ledger add private data
+	err = ctx.GetStub().PutPrivateData(collection, assetCreate.ID, privatePropertiesJSON)
+
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to put Asset private details")
+	}
+	if personalData != nil {
+		err = _putPersonalData(ctx, clientOrgID, personalData)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// the appraised value the asset is created with is the first record of its valuation history
+	if appraisedValue != "" {
+		_, err = _putValuation(ctx, clientOrgID, assetCreate.ID, appraisedValue)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// only peers of the owner org can endorse changes to the asset from now on
+	err = _setAssetStateBasedEndorsement(ctx, assetCreate.ID, clientOrgID)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed setting state based endorsement for owner")
+	}
+	err = _emitAssetEvent(ctx, assetCreatedEvent, assetCreate, "")
+	if err != nil {
+		return nil, err
+	}
+	return _assetResult(ctx, assetCreate)
+}
+
+// ******************************* Update Asset  ******************************************
+
+/*Update the asset e.g change public description only callable by the current owner of the asset.
+Must verify the ID of the org */
+
+func (s *SmartContract) UpdateAsset(ctx ledgerutil.TransactionContextInterface, assetID string, newDescription string) (*AssetResult, error) {
+	assetID = ledgerutil.NormalizeID(assetID)
+	v := ledgerutil.NewValidator()
+	v.Key("assetID", assetID)
+	v.Text("newDescription", newDescription, ledgerutil.MaxTextLength)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	// check client org id matches peer org id not needed, use asset ownership check instead.
+	assetUpdate, err := s.ReadAsset(ctx, assetID) //Read smartcontract ledger passing in CTX and assetID to modify data
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to get asset")
+	}
+
+	// verify to ensure that client org owns the asset
+	err = _requireOwnerOrg(ctx.GetIdentity(), assetUpdate.OwnerOrg, "update the description of a asset")
+	if err != nil {
+		return nil, err
+	}
+
+	assetUpdate.PublicDescription = newDescription //set new description
+
+	err = _putAsset(ctx, assetUpdate) //update ledger changing id and updated description
+	if err != nil {
+		return nil, err
+	}
+	err = _emitAssetEvent(ctx, assetUpdatedEvent, assetUpdate, "")
+	if err != nil {
+		return nil, err
+	}
+	return _assetResult(ctx, assetUpdate)
+}
+
+// ******************************* Private functions  ******************************************
+//INSPECTOR AND APPROVAL
+func _getClientOrgID(ctx ledgerutil.TransactionContextInterface, verifyOrg bool) (string, error) {
+	clientOrgID := ctx.GetClientMSPID() //membershipservice provider ID of organisation e.g {mspid:Org1MSP}, resolved before the transaction
+
+	if verifyOrg {
+		err := _verifyClientOrgMatchesPeerOrg(ctx.GetIdentity()) //pass into function to verify client
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return clientOrgID, nil
+}
+
+// _checkAccess asks the access-control chaincode whether the submitting client may perform the operation.
+// The ACL chaincode sees the same submitting client, so roles are checked for this client and its org.
+func _checkAccess(ctx contractapi.TransactionContextInterface, operation string) error {
+	return ledgerutil.CheckAccess(ctx, accessControlName, operation)
+}
+
+// _checkKYC checks the orgs have a profile in the identity registry chaincode while the strictKYC
+// feature flag is on. The parties of an asset sale are orgs, so their org profiles are checked.
+func _checkKYC(ctx contractapi.TransactionContextInterface, orgIDs ...string) error {
+	strict, err := ledgerutil.FlagEnabled(ctx.GetStub(), ledgerutil.StrictKYCFlag)
+	if err != nil || !strict {
+		return err
+	}
+	for _, orgID := range orgIDs {
+		err = ledgerutil.CheckRegistered(ctx, identityRegistryName, "GetOrgProfile", orgID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// *Aproval of transactions, assets and pricing *
+// _verifyClientOrgMatchesPeerOrg checks the client org id matches the peer org id.
+func _verifyClientOrgMatchesPeerOrg(identity *ledgerutil.Identity) error {
+	peerOrgID, err := shim.GetMSPID() //returns the local mspid of the peer by checking the CORE_PEER_LOCALMSPID env var and returns an error if the env var is not set
+	if err != nil {
+		return ledgerutil.Wrap(err, "failed getting peer's orgID")
+	}
+
+	if !identity.InOrg(peerOrgID) {
+		return ledgerutil.Errorf(ledgerutil.CodeNotAuthorized, "client from org %s is not authorized to read or write private data from an org %s peer", identity.MSPID, peerOrgID)
+	}
+	return nil
+}
+
+// _requireOwnerOrg checks the client belongs to the org owning an asset before it performs the action
+func _requireOwnerOrg(identity *ledgerutil.Identity, ownerOrg string, action string) error {
+	if !identity.InOrg(ownerOrg) {
+		return ledgerutil.Errorf(ledgerutil.CodeNotAuthorized, "a client from %s cannot %s owned by %s", identity.MSPID, action, ownerOrg)
+	}
+	return nil
+}
+
+// _setApproval checks that client org currently owns asset and that both parties have agreed on price
+//privatePropertiesJSON makes object unable to change
+func _SetApproval(ctx contractapi.TransactionContextInterface, asset *Asset, privatePropertiesJSON []byte, identity *ledgerutil.Identity, buyerOrgID string, priceJSON []byte) error {
+
+	// CHECK1: Auth check to ensure that client's org actually owns the asset, or that the client is
+	// the operator the owner approved
+
+	err := _requireTransferAuthority(ctx, asset, identity, "transfer a asset")
+	if err != nil {
+		return err
+	}
+	return _verifyAgreement(ctx, asset, privatePropertiesJSON, buyerOrgID, priceJSON)
+}
+
+// _verifyAgreement checks the passed properties match the owner's and that the owner and buyer
+// agreed on the passed price, whichever party's client passed them
+func _verifyAgreement(ctx contractapi.TransactionContextInterface, asset *Asset, privatePropertiesJSON []byte, buyerOrgID string, priceJSON []byte) error {
+
+	// CHECK2: Verify that the hash of the passed immutable properties matches the on-chain hash
+
+	err := _verifyProperties(ctx, asset, privatePropertiesJSON)
+	if err != nil {
+		return err
+	}
+
+	// CHECK3: Verify that seller and buyer agreed on the same price
+
+	// Get sellers asking price
+	collectionSeller := _buildClientOrgName(asset.OwnerOrg)
+	assetForSaleKey, err := ctx.GetStub().CreateCompositeKey(sellerPrice, []string{asset.ID})
+	if err != nil {
+		return ledgerutil.Wrap(err, "failed to create composite key")
+	}
+	sellerPriceHash, err := ctx.GetStub().GetPrivateDataHash(collectionSeller, assetForSaleKey)
+	if err != nil {
+		return ledgerutil.Wrap(err, "failed to get seller price hash")
+	}
+	if sellerPriceHash == nil {
+		return ledgerutil.Errorf(ledgerutil.CodeNotFound, "seller price for %s does not exist", asset.ID)
+	}
+
+	// Get buyers bid price
+	collectionBuyer := _buildClientOrgName(buyerOrgID)
+	assetBidKey, err := ctx.GetStub().CreateCompositeKey(bidderPrice, []string{asset.ID})
+	if err != nil {
+		return ledgerutil.Wrap(err, "failed to create composite key")
+	}
+	buyerPriceHash, err := ctx.GetStub().GetPrivateDataHash(collectionBuyer, assetBidKey)
+	if err != nil {
+		return ledgerutil.Wrap(err, "failed to get buyer price hash")
+	}
+	if buyerPriceHash == nil {
+		return ledgerutil.Errorf(ledgerutil.CodeNotFound, "buyer price for %s does not exist", asset.ID)
+	}
+
+	hash := sha256.New()
+	hash.Write(priceJSON)
+	calculatedPriceHash := hash.Sum(nil)
+
+	// Verify that the hash of the passed price matches the on-chain sellers price hash
+	if !bytes.Equal(calculatedPriceHash, sellerPriceHash) {
+		return ledgerutil.Errorf(ledgerutil.CodeAgreementMismatch, "hash %x for passed price JSON %s does not match on-chain hash %x, seller hasn't agreed to the passed trade id and price",
+			calculatedPriceHash,
+			priceJSON,
+			sellerPriceHash,
+		)
+	}
+
+	// Verify that the hash of the passed price matches the on-chain buyer price hash
+	if !bytes.Equal(calculatedPriceHash, buyerPriceHash) {
+		return ledgerutil.Errorf(ledgerutil.CodeAgreementMismatch, "hash %x for passed price JSON %s does not match on-chain hash %x, buyer hasn't agreed to the passed trade id and price",
+			calculatedPriceHash,
+			priceJSON,
+			buyerPriceHash,
+		)
+	}
+
+	return nil
+}
+
+// _verifyProperties checks the hash of the passed properties matches the hash of the properties in
+// the owner's collection, which every peer can read
+func _verifyProperties(ctx contractapi.TransactionContextInterface, asset *Asset, privatePropertiesJSON []byte) error {
+	collectionSeller := _buildClientOrgName(asset.OwnerOrg)
+	setImmutableDataOnChainHash, err := ctx.GetStub().GetPrivateDataHash(collectionSeller, asset.ID)
+	if err != nil {
+		return ledgerutil.Wrap(err, "failed to read asset private properties hash from seller's collection")
+	}
+	if setImmutableDataOnChainHash == nil {
+		return ledgerutil.Errorf(ledgerutil.CodeNotFound, "asset private properties hash does not exist: %s", asset.ID)
+	}
+
+	hash := sha256.New()
+	hash.Write(privatePropertiesJSON)
+	calculatedDataHash := hash.Sum(nil)
+
+	// verify that the hash of the passed immutable properties matches the on-chain hash
+	if !bytes.Equal(setImmutableDataOnChainHash, calculatedDataHash) {
+		return ledgerutil.Errorf(ledgerutil.CodeAgreementMismatch, "hash %x for passed immutable properties %s does not match on-chain hash %x",
+			calculatedDataHash,
+			privatePropertiesJSON,
+			setImmutableDataOnChainHash,
+		)
+	}
+	return nil
+}
+
+// _assetResult builds the result returned to the client from the transaction ID and timestamp, which are
+// the same on every endorsing peer
+func _assetResult(ctx contractapi.TransactionContextInterface, asset *Asset) (*AssetResult, error) {
+	txID, timestamp, err := ledgerutil.TxInfo(ctx.GetStub())
+	if err != nil {
+		return nil, err
+	}
+	return &AssetResult{Status: ledgerutil.StatusSuccess, TxID: txID, Timestamp: timestamp, Asset: asset}, nil
+}
+
+// _putAsset writes the public data of an asset to the world state under its key, stamping it with
+// the time of the transaction so reconciliation jobs can query what changed
+func _putAsset(ctx contractapi.TransactionContextInterface, asset *Asset) error {
+	updatedAt, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return err
+	}
+	asset.UpdatedAt = ledgerutil.FormatTime(updatedAt)
+	assetKey, err := _assetKey(ctx.GetStub(), asset.ID)
+	if err != nil {
+		return err
+	}
+	return ledgerutil.PutRecord(ctx.GetStub(), assetKey, (*assetRecord)(asset))
+}
+
+// _emitAssetEvent sets the event of the transaction to the public data of the asset, so listeners
+// can follow assets without reading the ledger
+func _emitAssetEvent(ctx contractapi.TransactionContextInterface, schema ledgerutil.EventSchema, asset *Asset, previousOwnerOrg string) error {
+	return ledgerutil.EmitEvent(ctx.GetStub(), schema, assettypes.AssetEvent{
+		ID:                asset.ID,
+		OwnerOrg:          asset.OwnerOrg,
+		PreviousOwnerOrg:  previousOwnerOrg,
+		PublicDescription: asset.PublicDescription,
+	})
+}
+
+//Get clientorg name used to add and verify to private data collection
+func _buildClientOrgName(clientOrgID string) string {
+	return fmt.Sprintf("_implicit_org_%s", clientOrgID)
+}
+
+// _validateAssetID trims the asset ID passed by the client and checks it can be used as a world
+// state key and composite key attribute
+func _validateAssetID(assetID string) (string, error) {
+	assetID = ledgerutil.NormalizeID(assetID)
+	v := ledgerutil.NewValidator()
+	v.Key("assetID", assetID)
+	return assetID, v.Err()
+}
+
+// _validateAppraisedValue checks the appraised value of new asset properties is a decimal string and
+// returns it, empty when the properties have none. The properties are stored as the client passed
+// them, so a JSON number would be read back as a float by the clients of other orgs.
+func _validateAppraisedValue(privatePropertiesJSON []byte) (assettypes.Decimal, error) {
+	var properties struct {
+		AppraisedValue json.RawMessage `json:"appraised_value"`
+	}
+	err := json.Unmarshal(privatePropertiesJSON, &properties)
+	if err != nil {
+		return "", ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "invalid arguments: asset_properties is not a JSON object")
+	}
+	if properties.AppraisedValue == nil {
+		return "", nil
+	}
+	var value string
+	err = json.Unmarshal(properties.AppraisedValue, &value)
+	if err != nil {
+		return "", ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "invalid arguments: appraised_value must be a decimal string such as \"1250.75\", not %s", properties.AppraisedValue)
+	}
+	appraisedValue, err := assettypes.ParseDecimal(value)
+	if err != nil {
+		return "", ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "invalid arguments: appraised_value %v", err)
+	}
+	return appraisedValue, nil
+}
+
+//Set State
+// _SetTransferAssetState performs the public and private state updates for the transferred asset
+//privatePropertiesJSON makes object unable to change
+func _SetTransferAssetState(ctx contractapi.TransactionContextInterface, asset *Asset, privatePropertiesJSON []byte, clientOrgID string, buyerOrgID string, price int) error {
+
+	err := _moveAsset(ctx, asset, privatePropertiesJSON, clientOrgID, buyerOrgID)
+	if err != nil {
+		return err
+	}
+	collectionSeller := _buildClientOrgName(clientOrgID)
+	collectionBuyer := _buildClientOrgName(buyerOrgID)
+
+	// Delete the price records for seller
+	assetPriceKey, err := ctx.GetStub().CreateCompositeKey(sellerPrice, []string{asset.ID})
+	if err != nil {
+		return ledgerutil.Wrap(err, "failed to create composite key for seller")
+	}
+
+	err = ctx.GetStub().DelPrivateData(collectionSeller, assetPriceKey)
+	if err != nil {
+		return ledgerutil.Wrap(err, "failed to delete asset price from implicit private data collection for seller")
+	}
+
+	// Delete the price records for buyer
+	assetPriceKey, err = ctx.GetStub().CreateCompositeKey(bidderPrice, []string{asset.ID})
+	if err != nil {
+		return ledgerutil.Wrap(err, "failed to create composite key for buyer")
+	}
+
+	err = ctx.GetStub().DelPrivateData(collectionBuyer, assetPriceKey)
+	if err != nil {
+		return ledgerutil.Wrap(err, "failed to delete asset price from implicit private data collection for buyer")
+	}
+
+	// Keep record for a 'receipt' in both buyers and sellers private data collection to record the sale price and date.
+	// Persist the agreed to price in a collection sub-namespace based on receipt key prefix.
+	receiptBuyKey, err := ctx.GetStub().CreateCompositeKey(typeAssetBuyReceipt, []string{asset.ID, ctx.GetStub().GetTxID()})
+	if err != nil {
+		return ledgerutil.Wrap(err, "failed to create composite key for receipt")
+	}
+
+	timestamp, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return ledgerutil.Wrap(err, "failed to create timestamp for receipt")
+	}
+	traceID := ledgerutil.TraceID(ctx.GetStub())
+	err = ledgerutil.PutPrivateRecord(ctx.GetStub(), collectionBuyer, receiptBuyKey, &receiptRecord{AssetID: asset.ID, Type: typeAssetBuyReceipt, Counterparty: clientOrgID, Price: price, Timestamp: timestamp, TraceID: traceID})
+	if err != nil {
+		return ledgerutil.Wrap(err, "failed to put private asset receipt for buyer")
+	}
+
+	receiptSaleKey, err := ctx.GetStub().CreateCompositeKey(typeAssetSaleReceipt, []string{asset.ID, ctx.GetStub().GetTxID()})
+	if err != nil {
+		return ledgerutil.Wrap(err, "failed to create composite key for receipt")
+	}
+
+	err = ledgerutil.PutPrivateRecord(ctx.GetStub(), collectionSeller, receiptSaleKey, &receiptRecord{AssetID: asset.ID, Type: typeAssetSaleReceipt, Counterparty: buyerOrgID, Price: price, Timestamp: timestamp, TraceID: traceID})
+	if err != nil {
+		return ledgerutil.Wrap(err, "failed to put private asset receipt for seller")
+	}
+
+	return nil
+}
+
+// _assetKey returns the world state key of the public data of an asset
+func _assetKey(stub shim.ChaincodeStubInterface, assetID string) (string, error) {
+	key, err := stub.CreateCompositeKey(assetPrefix, []string{assetID})
+	if err != nil {
+		return "", ledgerutil.Wrap(err, "failed to create the key of asset %s", assetID)
+	}
+	return key, nil
+}
+
+// _moveAsset makes the buyer org the owner of the asset and the only endorser of its changes, and
+// moves its private properties from the seller's collection to the buyer's. The operator the seller
+// approved, if any, is removed.
+func _moveAsset(ctx contractapi.TransactionContextInterface, asset *Asset, privatePropertiesJSON []byte, sellerOrgID string, buyerOrgID string) error {
+	asset.OwnerOrg = buyerOrgID //set the buyerorgid to the owner in the struct asset
+
+	err := _delAssetOperator(ctx, asset.ID)
+	if err != nil {
+		return err
+	}
+	err = _putAsset(ctx, asset) //write state PutState(ID, updated asset)
+	if err != nil {
+		return ledgerutil.Wrap(err, "failed to write asset for buyer")
+	}
+
+	// Changes the endorsement policy to the new owner org
+	err = _setAssetStateBasedEndorsement(ctx, asset.ID, buyerOrgID)
+	if err != nil {
+		return ledgerutil.Wrap(err, "failed setting state based endorsement for new owner")
+	}
+
+	// Transfer the private properties (delete from seller collection, create in buyer collection)
+	err = ctx.GetStub().DelPrivateData(_buildClientOrgName(sellerOrgID), asset.ID)
+	if err != nil {
+		return ledgerutil.Wrap(err, "failed to delete Asset private details from seller")
+	}
+	err = ctx.GetStub().PutPrivateData(_buildClientOrgName(buyerOrgID), asset.ID, privatePropertiesJSON)
+	if err != nil {
+		return ledgerutil.Wrap(err, "failed to put Asset private properties for buyer")
+	}
+	return nil
+}
+
+// _setAssetStateBasedEndorsement adds an endorsement policy to an asset so that only a peer from the
+// owning org can endorse changes to it. Both orgs still endorse a transfer, but the seller's peer is
+// the one the policy requires, so another org cannot move the asset by itself.
+func _setAssetStateBasedEndorsement(ctx contractapi.TransactionContextInterface, assetID string, orgToEndorse string) error {
+	endorsementPolicy, err := statebased.NewStateEP(nil)
+	if err != nil {
+		return err
+	}
+	err = endorsementPolicy.AddOrgs(statebased.RoleTypePeer, orgToEndorse)
+	if err != nil {
+		return ledgerutil.Wrap(err, "failed to add org to endorsement policy")
+	}
+	policy, err := endorsementPolicy.Policy()
+	if err != nil {
+		return ledgerutil.Wrap(err, "failed to create endorsement policy bytes from org")
+	}
+	assetKey, err := _assetKey(ctx.GetStub(), assetID)
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().SetStateValidationParameter(assetKey, policy)
+	if err != nil {
+		return ledgerutil.Wrap(err, "failed to set validation parameter on asset")
+	}
+
+	return nil
+}
+
+// * Transactions and pricing *
+// approvePrice adds a bid or ask price to caller's implicit private data collection
+func approvePrice(ctx ledgerutil.TransactionContextInterface, assetID string, priceType string) error {
+	assetID, err := _validateAssetID(assetID)
+	if err != nil {
+		return err
+	}
+	// client is only authorized to read/write private data from its own data.
+	clientOrgID, err := _getClientOrgID(ctx, true) //verify
+	if err != nil {
+		return ledgerutil.Wrap(err, "failed to verify OrgID")
+	}
+
+	transMap, err := ctx.GetStub().GetTransient() //get private data
+	if err != nil {
+		return ledgerutil.Wrap(err, "error getting transient data")
+	}
+
+	// Asset price must be retrieved from the transient field as they are private
+	price, ok := transMap["asset_price"]
+	if !ok {
+		return ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "asset_price key not found transient map")
+	}
+	v := ledgerutil.NewValidator()
+	v.Payload("asset_price", price)
+	if err := v.Err(); err != nil {
+		return err
+	}
+
+	collection := _buildClientOrgName(clientOrgID) //get org id
+
+	// set the agreed price in a collection of sub-namespace on priceType key prefix,
+	// Compositekey to avoid collisions between private asset properties, sell price, and buy price
+	assetPriceKey, err := ctx.GetStub().CreateCompositeKey(priceType, []string{assetID})
+	if err != nil {
+		return ledgerutil.Wrap(err, "failed creating composite key")
+	}
+
+	// The Price hash will be verified later, the persist price bytes are passed as is,
+	// so there is no risk of nondeterministic marshaling.
+	err = ctx.GetStub().PutPrivateData(collection, assetPriceKey, price)
+	if err != nil {
+		return ledgerutil.Wrap(err, "failed to put asset bid")
+	}
+	return nil
+}
+
+// ******************************* AgreeToSell  ******************************************
+
+// AgreeToSell adds seller's asking price to seller's private data
+//Make sure noone authorised can list the item to sell, only the owner can
+func (s *SmartContract) AgreeToSell(ctx ledgerutil.TransactionContextInterface, assetID string) (*AssetResult, error) {
+	assetID, err := _validateAssetID(assetID)
+	if err != nil {
+		return nil, err
+	}
+	asset, err := s.ReadAsset(ctx, assetID) //read asset from ledger
+	if err != nil {
+		return nil, err
+	}
+	//make sure org is verified for payment
+	_, err = _getClientOrgID(ctx, true)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to get verified OrgID")
+	}
+
+	// Verify (inspect and aproval) that this clientOrgId actually owns the asset.
+	err = _requireOwnerOrg(ctx.GetIdentity(), asset.OwnerOrg, "sell an asset")
+	if err != nil {
+		return nil, err
+	}
+	err = _requireNotExpired(ctx, asset)
+	if err != nil {
+		return nil, err
+	}
+
+	err = approvePrice(ctx, assetID, sellerPrice)
+	if err != nil {
+		return nil, err
+	}
+	return _assetResult(ctx, asset)
+}
+
+// ******************************* AgreeToBuy ******************************************
+
+// AgreeToBuy adds buyer's bid price to buyer's private data collection
+func (s *SmartContract) AgreeToBuy(ctx ledgerutil.TransactionContextInterface, assetID string) (*AssetResult, error) {
+	err := approvePrice(ctx, assetID, bidderPrice)
+	if err != nil {
+		return nil, err
+	}
+	// the buyer may bid before it can read the asset, so the result has no asset
+	return _assetResult(ctx, nil)
+}
+
+// SetInspection verifies asset and allows buyer to validate the properties of
+// an asset against the owners private data collection
+func (s *SmartContract) SetInspection(ctx ledgerutil.TransactionContextInterface, assetID string) (bool, error) {
+	assetID, err := _validateAssetID(assetID)
+	if err != nil {
+		return false, err
+	}
+	transMap, err := ctx.GetStub().GetTransient() //private data
+	if err != nil {
+		return false, ledgerutil.Wrap(err, "error getting transient")
+	}
+
+	/// Asset properties retrieved from the transient field as they are private
+	privatePropertiesJSON, ok := transMap["asset_properties"]
+	if !ok {
+		return false, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "asset_properties key not found in the transient map")
+	}
+	v := ledgerutil.NewValidator()
+	v.Payload("asset_properties", privatePropertiesJSON)
+	if err := v.Err(); err != nil {
+		return false, err
+	}
+
+	asset, err := s.ReadAsset(ctx, assetID) //find and read asset from ledger
+	if err != nil {
+		return false, ledgerutil.Wrap(err, "failed to get asset")
+	}
+
+	collectionOwner := _buildClientOrgName(asset.OwnerOrg) //verifty client org with the asset.ownerOrg from readAsset function
+	setImmutableDataOnChainHash, err := ctx.GetStub().GetPrivateDataHash(collectionOwner, assetID)
+	if err != nil {
+		return false, ledgerutil.Wrap(err, "failed to read asset private properties hash from seller's collection")
+	}
+	//set secure hash e.g the salt tag, so people cant attack and guess the chaincode asset.
+	if setImmutableDataOnChainHash == nil {
+		return false, ledgerutil.Errorf(ledgerutil.CodeNotFound, "asset private properties hash does not exist: %s", assetID)
+	}
+
+	hash := sha256.New()
+	hash.Write(privatePropertiesJSON) //create hash256 for private data
+	calculatedDataHash := hash.Sum(nil)
+
+	// verify hash of the passed immutable properties matches the on-chain hash
+	if !bytes.Equal(setImmutableDataOnChainHash, calculatedDataHash) {
+		return false, ledgerutil.Errorf(ledgerutil.CodeAgreementMismatch, "hash %x for passed immutable data %s does not match on-chain hash %x",
+			calculatedDataHash,
+			privatePropertiesJSON,
+			setImmutableDataOnChainHash,
+		)
+	}
+
+	return true, nil
+}
+
+// SetQueryConfig sets the largest page size of the paginated queries and the most results
+// QueryAssetHistory and GetAssetReceipts return. Only clients allowed asset.SetQueryConfig in the
+// access-control chaincode may change them.
+func (s *SmartContract) SetQueryConfig(ctx ledgerutil.TransactionContextInterface, maxPageSize int, maxResults int) error {
+	err := _checkAccess(ctx, "asset.SetQueryConfig")
+	if err != nil {
+		return err
+	}
+	return ledgerutil.PutQueryConfig(ctx, ledgerutil.QueryConfig{MaxPageSize: maxPageSize, MaxResults: maxResults})
+}
+
+// ******************************* TransferAsset ******************************************
+
+func getClientImplicitCollectionName(ctx ledgerutil.TransactionContextInterface) (string, error) {
+	clientOrgID, err := _getClientOrgID(ctx, true)
+	if err != nil {
+		return "", ledgerutil.Wrap(err, "failed to get verified OrgID")
+	}
+
+	err = _verifyClientOrgMatchesPeerOrg(ctx.GetIdentity())
+	if err != nil {
+		return "", err
+	}
+
+	return _buildClientOrgName(clientOrgID), nil
+}
+
+// TransferAsset checks transfer conditions and then transfers asset state to buyer.
+// TransferAsset can only be called by current owner of the asset, or the operator it approved
+func (s *SmartContract) TransferAsset(ctx ledgerutil.TransactionContextInterface, assetID string, buyerOrgID string) (*AssetResult, error) {
+	assetID = ledgerutil.NormalizeID(assetID)
+	buyerOrgID = ledgerutil.NormalizeID(buyerOrgID)
+	v := ledgerutil.NewValidator()
+	v.Key("assetID", assetID)
+	v.Key("buyerOrgID", buyerOrgID)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	privatePropertiesJSON, priceJSON, agreement, err := _getTransferTransient(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+
+	asset, err := s.ReadAsset(ctx, assetID) //read data
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to get asset")
+	}
+	err = _requireNotExpired(ctx, asset)
+	if err != nil {
+		return nil, err
+	}
+	// a scheduled transfer is executed or cancelled first, so the buyer it was scheduled for is not passed over
+	err = _requireNoScheduledTransfer(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	err = _requireNotLocked(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	// the seller is the owner org, which an operator the owner approved need not belong to
+	sellerOrgID := asset.OwnerOrg
+	err = _checkKYC(ctx, sellerOrgID, buyerOrgID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = _SetApproval(ctx, asset, privatePropertiesJSON, ctx.GetIdentity(), buyerOrgID, priceJSON) //approve
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed transfer verification")
+	}
+
+	err = _SetTransferAssetState(ctx, asset, privatePropertiesJSON, sellerOrgID, buyerOrgID, agreement.Price) //set state tp transfer
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed asset transfer")
+	}
+	err = _emitAssetEvent(ctx, assetTransferredEvent, asset, sellerOrgID)
+	if err != nil {
+		return nil, err
+	}
+
+	return _assetResult(ctx, asset)
+}
+
+// _getTransferTransient returns the asset properties and agreed price a transfer of the asset is
+// passed in the transient map, with the price decoded
+func _getTransferTransient(ctx contractapi.TransactionContextInterface, assetID string) ([]byte, []byte, *Agreement, error) {
+	transMap, err := ctx.GetStub().GetTransient() //get private data
+	if err != nil {
+		return nil, nil, nil, ledgerutil.Wrap(err, "error getting transient data")
+	}
+
+	privatePropertiesJSON, key := transMap["asset_properties"] //get the description of asset_properties
+	if !key {
+		return nil, nil, nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "asset_properties key not found in the transient map")
+	}
+
+	priceJSON, key := transMap["asset_price"] //get price
+	if !key {
+		return nil, nil, nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "asset_price key not found in the transient map")
+	}
+	v := ledgerutil.NewValidator()
+	v.Payload("asset_properties", privatePropertiesJSON)
+	v.Payload("asset_price", priceJSON)
+	if err := v.Err(); err != nil {
+		return nil, nil, nil, err
+	}
+
+	var agreement Agreement                     //make variable based on agreement struct
+	err = json.Unmarshal(priceJSON, &agreement) //string to datastruct pointer to the agreement variable memory address
+	if err != nil {
+		return nil, nil, nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "failed to unmarshal price JSON: %v", err)
+	}
+	if agreement.ID != assetID {
+		return nil, nil, nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "price JSON is for asset %q, not %s", agreement.ID, assetID)
+	}
+	v.PositiveAmount("asset_price price", agreement.Price)
+	if err := v.Err(); err != nil {
+		return nil, nil, nil, err
+	}
+	return privatePropertiesJSON, priceJSON, &agreement, nil
+}
+
+// schemas of the asset events, whose payload is an assettypes.AssetEvent
+var (
+	assetCreatedEvent = ledgerutil.EventSchema{Name: assettypes.EventAssetCreated, Version: 1,
+		Description: "asset created with its public description",
+		Fields:      map[string]string{"assetID": "string", "ownerOrg": "string", "publicDescription": "string"}}
+	assetUpdatedEvent = ledgerutil.EventSchema{Name: assettypes.EventAssetUpdated, Version: 1,
+		Description: "public description of an asset changed by its owner org",
+		Fields:      map[string]string{"assetID": "string", "ownerOrg": "string", "publicDescription": "string"}}
+	assetTransferredEvent = ledgerutil.EventSchema{Name: assettypes.EventAssetTransferred, Version: 1,
+		Description: "asset transferred to the buyer org at the agreed price",
+		Fields:      map[string]string{"assetID": "string", "ownerOrg": "string", "previousOwnerOrg": "string", "publicDescription": "string"}}
+)
+
+// EventSchemas are the schemas of the events the asset contract emits, for the events contract of
+// the chaincodes registering it
+var EventSchemas = []ledgerutil.EventSchema{assetCreatedEvent, assetUpdatedEvent, assetTransferredEvent, personalDataPurgedEvent, contentUpdatedEvent, assetsExpiredEvent}
+
+// NewEventsContract returns the events contract of the asset chaincode, holding EventSchemas and the
+// ConfigChanged schema
+func NewEventsContract() *ledgerutil.EventsContract {
+	return ledgerutil.NewEventsContract(accessControlName, EventSchemas...)
+}
+
+// NewConfigContract returns the config contract of the asset chaincode, holding the query limits and
+// the strictKYC feature flag
+func NewConfigContract() *ledgerutil.ConfigContract {
+	return ledgerutil.NewConfigContract(accessControlName, ledgerutil.StrictKYCFlag)
+}
+
+// NewContract returns the asset contract named "asset" with the transaction hooks set, for the
+// chaincode of this module and for chaincodes bundling it with other contracts
+func NewContract() *SmartContract {
+	assetContract := new(SmartContract)
+	assetContract.Name = "asset"
+	assetContract.Info = metadata.InfoMetadata{
+		Title:       "Secured agreement asset transfer",
+		Description: "Assets with public descriptions and private properties, sold once the owner and buyer agree on a price in their implicit collections",
+		Version:     "1.0.0",
+		License:     &metadata.LicenseMetadata{Name: "Apache-2.0"},
+	}
+	// resolve the client once per transaction, keep read-only clients to queries and audit the rest;
+	// the stub of the keyed context fails rich queries, which are in the query contract
+	assetContract.TransactionContextHandler = new(ledgerutil.KeyedTransactionContext)
+	assetContract.BeforeTransaction = ledgerutil.BeforeTransaction(assetContract.GetEvaluateTransactions())
+	// list the functions and their arguments when a client calls one that does not exist
+	assetContract.UnknownTransaction = ledgerutil.UnknownTransaction(assetContract)
+	return assetContract
+}
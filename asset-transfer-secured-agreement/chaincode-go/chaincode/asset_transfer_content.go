@@ -0,0 +1,240 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-secured-agreement/assettypes"
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil"
+)
+
+// typeContentAttestation is the prefix of the attestations of the content of the assets in the
+// world state, keyed by asset ID, CID and transaction ID
+const typeContentAttestation = "contentattestation"
+
+// maxContentRefs bounds the content references of an asset, which are kept in the asset record
+const maxContentRefs = 20
+
+var (
+	// cidPattern matches an IPFS CIDv0, base58 starting Qm, or a CIDv1 in the default base32
+	cidPattern = regexp.MustCompile(`^(Qm[1-9A-HJ-NP-Za-km-z]{44}|b[a-z2-7]{58,})$`)
+	// sha256Pattern matches a SHA-256 hash in lowercase hex
+	sha256Pattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+)
+
+// ContentRef is a named image or document of an asset kept on IPFS
+type ContentRef = assettypes.ContentRef
+
+// ContentAttestation records a verifier's check of the content of a CID an asset references
+type ContentAttestation = assettypes.ContentAttestation
+
+// contentUpdatedEvent is the schema of the event set by SetContentRef and RemoveContentRef
+var contentUpdatedEvent = ledgerutil.EventSchema{Name: assettypes.EventContentUpdated, Version: 1,
+	Description: "content reference of an asset added, replaced or removed by its owner org",
+	Fields:      map[string]string{"assetID": "string", "name": "string", "cid": "string", "sha256": "string", "previousCID": "string"}}
+
+// SetContentRef references the IPFS content name of an asset, e.g. "photo" or "deed", by its CID and
+// the lowercase hex SHA-256 of its bytes, replacing the CID the name referenced before. Only clients
+// of the owner org may change the content of an asset.
+func (s *SmartContract) SetContentRef(ctx ledgerutil.TransactionContextInterface, assetID string, name string, cid string, sha256 string) (*AssetResult, error) {
+	assetID, err := _validateAssetID(assetID)
+	if err != nil {
+		return nil, err
+	}
+	v := ledgerutil.NewValidator()
+	v.Key("name", name)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	err = _validateContentHash(cid, sha256)
+	if err != nil {
+		return nil, err
+	}
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to get asset")
+	}
+	err = _requireOwnerOrg(ctx.GetIdentity(), asset.OwnerOrg, "change the content of an asset")
+	if err != nil {
+		return nil, err
+	}
+
+	txID, timestamp, err := ledgerutil.TxInfo(ctx.GetStub())
+	if err != nil {
+		return nil, err
+	}
+	ref := ContentRef{Name: name, CID: cid, SHA256: sha256, UpdatedBy: ctx.GetClientID(), TxID: txID, Timestamp: timestamp}
+	previousCID := ""
+	i := _findContentRef(asset, name)
+	if i < len(asset.Content) {
+		previousCID = asset.Content[i].CID
+		asset.Content[i] = ref
+	} else {
+		if len(asset.Content) == maxContentRefs {
+			return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "asset %s already references %d contents, remove one first", assetID, maxContentRefs)
+		}
+		asset.Content = append(asset.Content, ref)
+		sort.Slice(asset.Content, func(i, j int) bool { return asset.Content[i].Name < asset.Content[j].Name })
+	}
+	return _putContent(ctx, asset, assettypes.ContentEvent{ID: assetID, Name: name, CID: cid, SHA256: sha256, PreviousCID: previousCID})
+}
+
+// RemoveContentRef removes the IPFS content name from an asset. Only clients of the owner org may
+// change the content of an asset.
+func (s *SmartContract) RemoveContentRef(ctx ledgerutil.TransactionContextInterface, assetID string, name string) (*AssetResult, error) {
+	assetID, err := _validateAssetID(assetID)
+	if err != nil {
+		return nil, err
+	}
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to get asset")
+	}
+	err = _requireOwnerOrg(ctx.GetIdentity(), asset.OwnerOrg, "change the content of an asset")
+	if err != nil {
+		return nil, err
+	}
+	i := _findContentRef(asset, name)
+	if i == len(asset.Content) {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeNotFound, "asset %s has no content %q", assetID, name)
+	}
+	previousCID := asset.Content[i].CID
+	asset.Content = append(asset.Content[:i], asset.Content[i+1:]...)
+	if len(asset.Content) == 0 {
+		asset.Content = nil
+	}
+	return _putContent(ctx, asset, assettypes.ContentEvent{ID: assetID, Name: name, PreviousCID: previousCID})
+}
+
+// VerifyContentHash records the attestation of the client, a third party that fetched the content
+// of cid from IPFS, that its bytes hash to sha256, and whether that matches the hash the asset
+// references for the CID. The attestations are kept next to each other, so a reader sees who
+// checked the content and when. Only clients allowed asset.VerifyContentHash in the access-control
+// chaincode may attest.
+func (s *SmartContract) VerifyContentHash(ctx ledgerutil.TransactionContextInterface, assetID string, cid string, sha256 string) (*ContentAttestation, error) {
+	assetID, err := _validateAssetID(assetID)
+	if err != nil {
+		return nil, err
+	}
+	err = _validateContentHash(cid, sha256)
+	if err != nil {
+		return nil, err
+	}
+	err = _checkAccess(ctx, "asset.VerifyContentHash")
+	if err != nil {
+		return nil, err
+	}
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to get asset")
+	}
+	var ref *ContentRef
+	for i := range asset.Content {
+		if asset.Content[i].CID == cid {
+			ref = &asset.Content[i]
+			break
+		}
+	}
+	if ref == nil {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeNotFound, "asset %s does not reference content %s", assetID, cid)
+	}
+
+	stub := ctx.GetStub()
+	txID, timestamp, err := ledgerutil.TxInfo(stub)
+	if err != nil {
+		return nil, err
+	}
+	attestation := &ContentAttestation{
+		ObjectType:  typeContentAttestation,
+		AssetID:     assetID,
+		CID:         cid,
+		SHA256:      sha256,
+		Matches:     sha256 == ref.SHA256,
+		Verifier:    ctx.GetClientID(),
+		VerifierOrg: ctx.GetClientMSPID(),
+		TxID:        txID,
+		Timestamp:   timestamp,
+	}
+	key, err := stub.CreateCompositeKey(typeContentAttestation, []string{assetID, cid, txID})
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to create composite key for content attestation")
+	}
+	err = ledgerutil.PutJSON(stub, key, attestation)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to put content attestation")
+	}
+	return attestation, nil
+}
+
+// GetContentAttestations returns the attestations recorded for the content cid of an asset. It
+// fails with RESULTS_TRUNCATED when there are more than maxResults.
+func (s *SmartContract) GetContentAttestations(ctx ledgerutil.TransactionContextInterface, assetID string, cid string) ([]*ContentAttestation, error) {
+	assetID, err := _validateAssetID(assetID)
+	if err != nil {
+		return nil, err
+	}
+	if !cidPattern.MatchString(cid) {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "invalid arguments: cid %q is not an IPFS CID", cid)
+	}
+	config, err := ledgerutil.GetQueryConfig(ctx.GetStub())
+	if err != nil {
+		return nil, err
+	}
+	attestations := []*ContentAttestation{}
+	err = ledgerutil.ForEachByPartialCompositeKey(ctx.GetStub(), typeContentAttestation, []string{assetID, cid}, func(_ []string, value []byte) error {
+		if len(attestations) == config.MaxResults {
+			return ledgerutil.Errorf(ledgerutil.CodeResultsTruncated, "results truncated at %d attestations of content %s", config.MaxResults, cid)
+		}
+		var attestation ContentAttestation
+		err := json.Unmarshal(value, &attestation)
+		if err != nil {
+			return ledgerutil.Wrap(err, "failed to unmarshal content attestation")
+		}
+		attestations = append(attestations, &attestation)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return attestations, nil
+}
+
+// _validateContentHash checks the CID and hash of a content reference or attestation
+func _validateContentHash(cid string, sha256 string) error {
+	if !cidPattern.MatchString(cid) {
+		return ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "invalid arguments: cid %q is not an IPFS CID such as a CIDv0 starting Qm or a base32 CIDv1", cid)
+	}
+	if !sha256Pattern.MatchString(sha256) {
+		return ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "invalid arguments: sha256 must be 64 lowercase hex digits, not %q", sha256)
+	}
+	return nil
+}
+
+// _findContentRef returns the index of the content name of an asset, len(asset.Content) when the
+// asset has none
+func _findContentRef(asset *Asset, name string) int {
+	for i := range asset.Content {
+		if asset.Content[i].Name == name {
+			return i
+		}
+	}
+	return len(asset.Content)
+}
+
+// _putContent writes the asset with its changed content and sets the ContentUpdated event
+func _putContent(ctx ledgerutil.TransactionContextInterface, asset *Asset, event assettypes.ContentEvent) (*AssetResult, error) {
+	err := _putAsset(ctx, asset)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to put asset")
+	}
+	err = ledgerutil.EmitEvent(ctx.GetStub(), contentUpdatedEvent, event)
+	if err != nil {
+		return nil, err
+	}
+	return _assetResult(ctx, asset)
+}
@@ -0,0 +1,217 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"time"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-secured-agreement/assettypes"
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil"
+)
+
+// typeAssetExpiry is the prefix of the expiry index in the world state, keyed by expiry time and
+// asset ID so ExpireAssets reads the lapsed assets first
+const typeAssetExpiry = "assetexpiry"
+
+// expiryKeyFormat formats the expiry time of the index key in UTC with a fixed width, so the keys
+// sort in time order
+const expiryKeyFormat = "2006-01-02T15:04:05.000000000Z"
+
+// assetsExpiredEvent is the schema of the event set by ExpireAssets
+var assetsExpiredEvent = ledgerutil.EventSchema{Name: assettypes.EventAssetsExpired, Version: 1,
+	Description: "assets past their expiry time marked EXPIRED",
+	Fields:      map[string]string{"assetIDs": "[]string"}}
+
+// SetAssetExpiry sets the RFC 3339 time at which an asset, e.g. perishable goods, lapses, after which
+// it can no longer be sold, transferred or put in a lot. An empty expiresAt removes the expiry.
+// Only clients of the owner org may set it, to a time after the transaction's, and an asset
+// already EXPIRED keeps its status.
+func (s *SmartContract) SetAssetExpiry(ctx ledgerutil.TransactionContextInterface, assetID string, expiresAt string) (*AssetResult, error) {
+	assetID, err := _validateAssetID(assetID)
+	if err != nil {
+		return nil, err
+	}
+	var expiry time.Time
+	if expiresAt != "" {
+		expiry, err = time.Parse(time.RFC3339, expiresAt)
+		if err != nil {
+			return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "invalid arguments: expiresAt must be an RFC 3339 time such as 2024-01-31T12:00:00Z, not %q", expiresAt)
+		}
+	}
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to get asset")
+	}
+	err = _requireOwnerOrg(ctx.GetIdentity(), asset.OwnerOrg, "set the expiry of an asset")
+	if err != nil {
+		return nil, err
+	}
+	if asset.Status == assettypes.AssetStatusExpired {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "asset %s has expired", assetID)
+	}
+	timestamp, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if expiresAt != "" && !expiry.After(timestamp) {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "invalid arguments: expiresAt %s is not after the transaction time %s",
+			expiresAt, timestamp.Format(time.RFC3339))
+	}
+
+	if asset.ExpiresAt != "" {
+		err = _delExpiryIndex(ctx, asset)
+		if err != nil {
+			return nil, err
+		}
+	}
+	asset.ExpiresAt = ""
+	if expiresAt != "" {
+		asset.ExpiresAt = ledgerutil.FormatTime(expiry)
+		key, err := _expiryIndexKey(ctx, asset)
+		if err != nil {
+			return nil, err
+		}
+		// the value is not read, but an empty value would delete the key
+		err = ctx.GetStub().PutState(key, []byte{0x00})
+		if err != nil {
+			return nil, ledgerutil.Wrap(err, "failed to put asset expiry")
+		}
+	}
+	err = _putAsset(ctx, asset)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to put asset")
+	}
+	err = _emitAssetEvent(ctx, assetUpdatedEvent, asset, "")
+	if err != nil {
+		return nil, err
+	}
+	return _assetResult(ctx, asset)
+}
+
+// ExpireAssets marks up to pageSize assets whose expiry time the transaction time has reached
+// EXPIRED, earliest first, and returns their IDs. Call it until it returns fewer than pageSize. An
+// asset is already refused for sale once its expiry time passes, so this housekeeping only makes
+// the status visible to queries. Any client may call it, but the endorsement policy of each asset
+// needs a peer of its owner org to endorse.
+func (s *SmartContract) ExpireAssets(ctx ledgerutil.TransactionContextInterface, pageSize int) ([]string, error) {
+	_, err := ledgerutil.CheckPageSize(ctx.GetStub(), pageSize)
+	if err != nil {
+		return nil, err
+	}
+	timestamp, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// read the lapsed keys first, as the assets are written while the iterator is open otherwise
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(typeAssetExpiry, []string{})
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to get asset expiry index")
+	}
+	var assetIDs []string
+	for iterator.HasNext() && len(assetIDs) < pageSize {
+		response, err := iterator.Next()
+		if err != nil {
+			iterator.Close()
+			return nil, ledgerutil.Wrap(err, "failed to read asset expiry index")
+		}
+		_, attributes, err := ctx.GetStub().SplitCompositeKey(response.Key)
+		if err != nil || len(attributes) != 2 {
+			iterator.Close()
+			return nil, ledgerutil.Errorf(ledgerutil.CodeCorruptState, "asset expiry key %q is not an expiry time and asset ID", response.Key)
+		}
+		expiry, err := time.Parse(expiryKeyFormat, attributes[0])
+		if err != nil {
+			iterator.Close()
+			return nil, ledgerutil.Errorf(ledgerutil.CodeCorruptState, "asset expiry key %q has no valid time: %v", response.Key, err)
+		}
+		if expiry.After(timestamp) {
+			break
+		}
+		assetIDs = append(assetIDs, attributes[1])
+	}
+	iterator.Close()
+
+	for _, assetID := range assetIDs {
+		asset, err := s.ReadAsset(ctx, assetID)
+		if err != nil {
+			return nil, ledgerutil.Wrap(err, "failed to get asset")
+		}
+		err = _delExpiryIndex(ctx, asset)
+		if err != nil {
+			return nil, err
+		}
+		asset.Status = assettypes.AssetStatusExpired
+		err = _putAsset(ctx, asset)
+		if err != nil {
+			return nil, ledgerutil.Wrap(err, "failed to put asset")
+		}
+	}
+	if len(assetIDs) == 0 {
+		return []string{}, nil
+	}
+	err = ledgerutil.EmitEvent(ctx.GetStub(), assetsExpiredEvent, assettypes.ExpiryEvent{AssetIDs: assetIDs})
+	if err != nil {
+		return nil, err
+	}
+	return assetIDs, nil
+}
+
+// _requireNotExpired fails when the asset has expired at the transaction time
+func _requireNotExpired(ctx ledgerutil.TransactionContextInterface, asset *Asset) error {
+	timestamp, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return err
+	}
+	expired, err := _isExpired(asset, timestamp)
+	if err != nil {
+		return err
+	}
+	if expired {
+		return ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "asset %s has expired and cannot be sold", asset.ID)
+	}
+	return nil
+}
+
+// _isExpired reports whether the asset is marked EXPIRED or its expiry time is not after now
+func _isExpired(asset *Asset, now time.Time) (bool, error) {
+	if asset.Status == assettypes.AssetStatusExpired {
+		return true, nil
+	}
+	if asset.ExpiresAt == "" {
+		return false, nil
+	}
+	expiry, err := ledgerutil.ParseTime(asset.ExpiresAt)
+	if err != nil {
+		return false, err
+	}
+	return !expiry.After(now), nil
+}
+
+// _delExpiryIndex removes the asset from the expiry index
+func _delExpiryIndex(ctx ledgerutil.TransactionContextInterface, asset *Asset) error {
+	key, err := _expiryIndexKey(ctx, asset)
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().DelState(key)
+	if err != nil {
+		return ledgerutil.Wrap(err, "failed to delete asset expiry")
+	}
+	return nil
+}
+
+// _expiryIndexKey returns the world state key of the asset in the expiry index
+func _expiryIndexKey(ctx ledgerutil.TransactionContextInterface, asset *Asset) (string, error) {
+	expiry, err := ledgerutil.ParseTime(asset.ExpiresAt)
+	if err != nil {
+		return "", err
+	}
+	key, err := ctx.GetStub().CreateCompositeKey(typeAssetExpiry, []string{expiry.UTC().Format(expiryKeyFormat), asset.ID})
+	if err != nil {
+		return "", ledgerutil.Wrap(err, "failed to create composite key for asset expiry")
+	}
+	return key, nil
+}
@@ -0,0 +1,124 @@
+//go:build go1.18
+// +build go1.18
+
+package chaincode
+
+import (
+	"strings"
+	"testing"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil"
+)
+
+// FuzzReadAsset stores arbitrary bytes as the public asset and checks that reading and updating it
+// fail cleanly instead of panicking on a nil or foreign record
+func FuzzReadAsset(f *testing.F) {
+	f.Add([]byte(`{"objectType":"asset","assetID":"asset1","ownerOrg":"Org1MSP","publicDescription":"A new asset"}`))
+	f.Add([]byte(`null`))
+	f.Add([]byte(`{}`))
+	f.Add([]byte(`{"assetID":"asset2","ownerOrg":"Org1MSP"}`))
+	f.Add([]byte(`[1,2,3]`))
+	f.Add([]byte(`{"assetID":`))
+	f.Fuzz(func(t *testing.T, value []byte) {
+		stub := newLedger()
+		stub.state[assetKey(t, stub, assetID)] = value
+		contract := new(SmartContract)
+
+		asset, err := contract.ReadAsset(newContext(stub, sellerOrg), assetID)
+		if err == nil && (asset == nil || asset.ID != assetID) {
+			t.Fatalf("read %q as asset %+v", value, asset)
+		}
+
+		err = tx{clientOrg: sellerOrg}.run(stub, func(ctx ledgerutil.TransactionContextInterface) error {
+			_, err := contract.UpdateAsset(ctx, assetID, "updated")
+			return err
+		})
+		if err == nil && asset.OwnerOrg != sellerOrg {
+			t.Fatalf("updated asset %+v owned by another org", asset)
+		}
+	})
+}
+
+// FuzzTransferAssetPrice passes arbitrary price JSON to a transfer both orgs agreed on, which must
+// only succeed with the exact agreed JSON
+func FuzzTransferAssetPrice(f *testing.F) {
+	f.Add(price100)
+	f.Add(price110)
+	f.Add(`null`)
+	f.Add(`{}`)
+	f.Add(`{"asset_id":"asset1","price":-1}`)
+	f.Add(`{"asset_id":"asset1","trade_id":"109f4b3c50d7b0df729d299bc6f8e9ef9066971f","price":"100"}`)
+	f.Add(`garbage`)
+	f.Fuzz(func(t *testing.T, price string) {
+		stub := newLedger()
+		createAsset(t, stub)
+		agree(t, stub, price100, price100)
+
+		transient := map[string]string{"asset_properties": assetProperties, "asset_price": price}
+		err := tx{clientOrg: sellerOrg, transient: transient}.run(stub, func(ctx ledgerutil.TransactionContextInterface) error {
+			_, err := new(SmartContract).TransferAsset(ctx, assetID, buyerOrg)
+			return err
+		})
+		if err == nil && price != price100 {
+			t.Fatalf("transferred with price %q", price)
+		}
+		if err != nil && price == price100 {
+			t.Fatalf("failed to transfer with the agreed price: %v", err)
+		}
+	})
+}
+
+// FuzzAssetKeys uses arbitrary asset IDs, which become world state keys and attributes of the price
+// composite keys once trimmed and validated
+func FuzzAssetKeys(f *testing.F) {
+	f.Add("asset1")
+	f.Add("")
+	f.Add("asset\x00")
+	f.Add("\xff\xfe")
+	f.Add("asset\U0010FFFF")
+	f.Add(" asset~1\n")
+	f.Fuzz(func(t *testing.T, id string) {
+		stub := newLedger()
+		contract := new(SmartContract)
+		sellTransient := map[string]string{"asset_price": price100}
+
+		err := tx{clientOrg: sellerOrg, transient: map[string]string{"asset_properties": assetProperties}}.run(stub, func(ctx ledgerutil.TransactionContextInterface) error {
+			_, err := contract.CreateAsset(ctx, id, "A new asset")
+			return err
+		})
+		trimmed := strings.TrimSpace(id)
+		invalidKey := trimmed == "" || len(trimmed) > ledgerutil.MaxKeyLength || !utf8.ValidString(trimmed) ||
+			strings.ContainsAny(trimmed, "~\x00\U0010FFFF") || strings.IndexFunc(trimmed, unicode.IsControl) >= 0
+		if invalidKey {
+			if err == nil {
+				t.Fatalf("created asset %q that cannot be part of a composite key", id)
+			}
+			if len(stub.state) != 0 {
+				t.Fatalf("failed creation of asset %q wrote the world state", id)
+			}
+			return
+		}
+		if err != nil {
+			t.Fatalf("failed to create asset %q: %v", id, err)
+		}
+		err = tx{clientOrg: sellerOrg, transient: sellTransient}.run(stub, func(ctx ledgerutil.TransactionContextInterface) error {
+			_, err := contract.AgreeToSell(ctx, id)
+			return err
+		})
+		if err != nil {
+			t.Fatalf("failed to agree to sell asset %q: %v", id, err)
+		}
+
+		var price string
+		err = tx{clientOrg: sellerOrg}.run(stub, func(ctx ledgerutil.TransactionContextInterface) error {
+			var err error
+			price, err = contract.GetAssetSalesPrice(ctx, id)
+			return err
+		})
+		if err != nil || price != price100 {
+			t.Fatalf("asking price of asset %q is %q, %v", id, price, err)
+		}
+	})
+}
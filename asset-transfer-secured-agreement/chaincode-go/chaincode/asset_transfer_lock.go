@@ -0,0 +1,201 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"github.com/hyperledger/fabric-samples/asset-transfer-secured-agreement/assettypes"
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil"
+)
+
+// typeAssetLock is the prefix of the locks on the assets in the world state, one per asset
+const typeAssetLock = "assetlock"
+
+// AssetLock keeps an asset out of transfers while a lot it was put in is for sale
+type AssetLock = assettypes.AssetLock
+
+// LockAsset keeps an asset out of TransferAsset and ScheduleTransfer until the lot lockedBy is sold
+// with SettleLockedAsset or cancelled with UnlockAsset. It is called by the contracts of chaincodes
+// bundling the asset contract, e.g. to sell several assets as one lot, and is not a transaction of
+// the asset contract. Only clients of the owner org may lock an asset, which must not be locked or
+// have a transfer scheduled.
+func LockAsset(ctx ledgerutil.TransactionContextInterface, assetID string, lockedBy string) (*AssetLock, error) {
+	assetID, err := _validateAssetID(assetID)
+	if err != nil {
+		return nil, err
+	}
+	asset, err := new(SmartContract).ReadAsset(ctx, assetID)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to get asset")
+	}
+	err = _requireOwnerOrg(ctx.GetIdentity(), asset.OwnerOrg, "lock an asset")
+	if err != nil {
+		return nil, err
+	}
+	err = _requireNotExpired(ctx, asset)
+	if err != nil {
+		return nil, err
+	}
+	err = _requireNotLocked(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	err = _requireNoScheduledTransfer(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+
+	txID, timestamp, err := ledgerutil.TxInfo(ctx.GetStub())
+	if err != nil {
+		return nil, err
+	}
+	lock := &AssetLock{ObjectType: typeAssetLock, ID: assetID, OwnerOrg: asset.OwnerOrg, LockedBy: lockedBy, TxID: txID, Timestamp: timestamp}
+	key, err := _assetLockKey(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	err = ledgerutil.PutJSON(ctx.GetStub(), key, lock)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to put asset lock")
+	}
+	return lock, nil
+}
+
+// UnlockAsset removes the lock lockedBy holds on an asset, leaving the asset with its owner
+func UnlockAsset(ctx ledgerutil.TransactionContextInterface, assetID string, lockedBy string) error {
+	lock, err := _getLock(ctx, assetID, lockedBy)
+	if err != nil {
+		return err
+	}
+	return _delAssetLock(ctx, lock.ID)
+}
+
+// SettleLockedAsset transfers an asset locked by lockedBy to buyerOrgID and removes the lock, for
+// the contract that sold the lot and took the payment in the same transaction. The seller agreed to
+// the sale when it locked the asset, so neither org records a price for it; the buyer passes the
+// asset properties the seller shared with it, which are checked against the on-chain hash. The
+// asset's endorsement policy still needs the seller's peer to endorse.
+func SettleLockedAsset(ctx ledgerutil.TransactionContextInterface, assetID string, lockedBy string, buyerOrgID string, privatePropertiesJSON []byte) (*Asset, error) {
+	lock, err := _getLock(ctx, assetID, lockedBy)
+	if err != nil {
+		return nil, err
+	}
+	asset, err := new(SmartContract).ReadAsset(ctx, lock.ID)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to get asset")
+	}
+	if asset.OwnerOrg != lock.OwnerOrg {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeCorruptState, "asset %s is owned by %s, not %s which locked it", asset.ID, asset.OwnerOrg, lock.OwnerOrg)
+	}
+	if buyerOrgID == asset.OwnerOrg {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "invalid arguments: asset %s is already owned by %s", asset.ID, buyerOrgID)
+	}
+	err = _requireNotExpired(ctx, asset)
+	if err != nil {
+		return nil, err
+	}
+	err = _checkKYC(ctx, asset.OwnerOrg, buyerOrgID)
+	if err != nil {
+		return nil, err
+	}
+	v := ledgerutil.NewValidator()
+	v.Payload("asset_properties", privatePropertiesJSON)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	err = _verifyProperties(ctx, asset, privatePropertiesJSON)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed transfer verification")
+	}
+
+	err = _moveAsset(ctx, asset, privatePropertiesJSON, lock.OwnerOrg, buyerOrgID)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed asset transfer")
+	}
+	err = _delAssetLock(ctx, asset.ID)
+	if err != nil {
+		return nil, err
+	}
+	return asset, nil
+}
+
+// GetAssetLock returns the lock on an asset, which names the lot the asset is for sale in
+func (s *SmartContract) GetAssetLock(ctx ledgerutil.TransactionContextInterface, assetID string) (*AssetLock, error) {
+	assetID, err := _validateAssetID(assetID)
+	if err != nil {
+		return nil, err
+	}
+	lock, err := _readAssetLock(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	if lock == nil {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeNotFound, "asset %s is not locked", assetID)
+	}
+	return lock, nil
+}
+
+// _requireNotLocked fails when the asset is locked
+func _requireNotLocked(ctx ledgerutil.TransactionContextInterface, assetID string) error {
+	lock, err := _readAssetLock(ctx, assetID)
+	if err != nil {
+		return err
+	}
+	if lock != nil {
+		return ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "asset %s is locked by %s, sell or cancel it first", assetID, lock.LockedBy)
+	}
+	return nil
+}
+
+// _getLock returns the lock on an asset, failing when the asset is not locked by lockedBy
+func _getLock(ctx ledgerutil.TransactionContextInterface, assetID string, lockedBy string) (*AssetLock, error) {
+	assetID, err := _validateAssetID(assetID)
+	if err != nil {
+		return nil, err
+	}
+	lock, err := _readAssetLock(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	if lock == nil || lock.LockedBy != lockedBy {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "asset %s is not locked by %s", assetID, lockedBy)
+	}
+	return lock, nil
+}
+
+// _readAssetLock reads the lock on an asset, nil when it is not locked
+func _readAssetLock(ctx ledgerutil.TransactionContextInterface, assetID string) (*AssetLock, error) {
+	key, err := _assetLockKey(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	var lock AssetLock
+	found, err := ledgerutil.ReadJSON(ctx.GetStub(), key, &lock)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &lock, nil
+}
+
+// _delAssetLock removes the lock on an asset from the world state
+func _delAssetLock(ctx ledgerutil.TransactionContextInterface, assetID string) error {
+	key, err := _assetLockKey(ctx, assetID)
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().DelState(key)
+	if err != nil {
+		return ledgerutil.Wrap(err, "failed to delete asset lock")
+	}
+	return nil
+}
+
+// _assetLockKey returns the world state key of the lock on an asset
+func _assetLockKey(ctx ledgerutil.TransactionContextInterface, assetID string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(typeAssetLock, []string{assetID})
+	if err != nil {
+		return "", ledgerutil.Wrap(err, "failed to create composite key for asset lock")
+	}
+	return key, nil
+}
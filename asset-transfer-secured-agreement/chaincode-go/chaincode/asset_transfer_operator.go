@@ -0,0 +1,163 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-secured-agreement/assettypes"
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil"
+)
+
+// typeAssetOperator is the prefix of the operators approved for the assets in the world state, one
+// per asset
+const typeAssetOperator = "assetoperator"
+
+// AssetOperator is a client the owner org approved to transfer an asset on its behalf
+type AssetOperator = assettypes.AssetOperator
+
+// ApproveAssetOperator approves operator, the client ID of a broker as returned by WhoAmI, to
+// transfer an asset or schedule its transfer on behalf of the owner org, like an ERC-721 approval.
+// The operator passes the asset properties and agreed price as the owner would. A singleUse
+// approval is consumed by the first transfer it authorizes, otherwise it lasts until revoked; either
+// ends when the asset changes owner. An asset has one operator, which a new approval replaces. Only
+// clients of the owner org may approve an operator.
+func (s *SmartContract) ApproveAssetOperator(ctx ledgerutil.TransactionContextInterface, assetID string, operator string, singleUse bool) (*AssetOperator, error) {
+	assetID = ledgerutil.NormalizeID(assetID)
+	operator = ledgerutil.NormalizeID(operator)
+	v := ledgerutil.NewValidator()
+	v.Key("assetID", assetID)
+	v.Key("operator", operator)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to get asset")
+	}
+	err = _requireOwnerOrg(ctx.GetIdentity(), asset.OwnerOrg, "approve an operator of an asset")
+	if err != nil {
+		return nil, err
+	}
+
+	txID, timestamp, err := ledgerutil.TxInfo(ctx.GetStub())
+	if err != nil {
+		return nil, err
+	}
+	approval := &AssetOperator{
+		ObjectType: typeAssetOperator,
+		ID:         assetID,
+		OwnerOrg:   asset.OwnerOrg,
+		Operator:   operator,
+		SingleUse:  singleUse,
+		ApprovedBy: ctx.GetClientID(),
+		TxID:       txID,
+		Timestamp:  timestamp,
+	}
+	key, err := _assetOperatorKey(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	err = ledgerutil.PutJSON(ctx.GetStub(), key, approval)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to put asset operator")
+	}
+	return approval, nil
+}
+
+// RevokeAssetOperator removes the operator approved for an asset. Only clients of the owner org may
+// revoke it.
+func (s *SmartContract) RevokeAssetOperator(ctx ledgerutil.TransactionContextInterface, assetID string) (*AssetResult, error) {
+	assetID, err := _validateAssetID(assetID)
+	if err != nil {
+		return nil, err
+	}
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to get asset")
+	}
+	err = _requireOwnerOrg(ctx.GetIdentity(), asset.OwnerOrg, "revoke the operator of an asset")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.GetAssetOperator(ctx, assetID); err != nil {
+		return nil, err
+	}
+	err = _delAssetOperator(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	return _assetResult(ctx, asset)
+}
+
+// GetAssetOperator returns the operator approved for an asset
+func (s *SmartContract) GetAssetOperator(ctx ledgerutil.TransactionContextInterface, assetID string) (*AssetOperator, error) {
+	assetID, err := _validateAssetID(assetID)
+	if err != nil {
+		return nil, err
+	}
+	approval, err := _readAssetOperator(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	if approval == nil {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeNotFound, "no operator is approved for asset %s", assetID)
+	}
+	return approval, nil
+}
+
+// _requireTransferAuthority checks the client belongs to the org owning an asset or is the operator
+// the owner org approved for it, consuming a single-use approval
+func _requireTransferAuthority(ctx contractapi.TransactionContextInterface, asset *Asset, identity *ledgerutil.Identity, action string) error {
+	if identity.InOrg(asset.OwnerOrg) {
+		return nil
+	}
+	approval, err := _readAssetOperator(ctx, asset.ID)
+	if err != nil {
+		return err
+	}
+	if approval == nil || approval.Operator != identity.ID || approval.OwnerOrg != asset.OwnerOrg {
+		return _requireOwnerOrg(identity, asset.OwnerOrg, action)
+	}
+	if approval.SingleUse {
+		return _delAssetOperator(ctx, asset.ID)
+	}
+	return nil
+}
+
+// _readAssetOperator reads the operator approved for an asset, nil when there is none
+func _readAssetOperator(ctx contractapi.TransactionContextInterface, assetID string) (*AssetOperator, error) {
+	key, err := _assetOperatorKey(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	var approval AssetOperator
+	found, err := ledgerutil.ReadJSON(ctx.GetStub(), key, &approval)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &approval, nil
+}
+
+// _delAssetOperator removes the operator approved for an asset from the world state, if any
+func _delAssetOperator(ctx contractapi.TransactionContextInterface, assetID string) error {
+	key, err := _assetOperatorKey(ctx, assetID)
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().DelState(key)
+	if err != nil {
+		return ledgerutil.Wrap(err, "failed to delete asset operator")
+	}
+	return nil
+}
+
+// _assetOperatorKey returns the world state key of the operator approved for an asset
+func _assetOperatorKey(ctx contractapi.TransactionContextInterface, assetID string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(typeAssetOperator, []string{assetID})
+	if err != nil {
+		return "", ledgerutil.Wrap(err, "failed to create composite key for asset operator")
+	}
+	return key, nil
+}
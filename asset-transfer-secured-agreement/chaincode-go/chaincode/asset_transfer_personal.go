@@ -0,0 +1,189 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-secured-agreement/assettypes"
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil"
+)
+
+// typePersonalData is the prefix of the personal data of the assets in the implicit collections.
+// ListByCompositeKey does not list it, so operators cannot read it.
+const typePersonalData = "personaldata"
+
+// PersonalData identifies the individuals behind an asset, kept in the implicit collection of the
+// org that recorded it
+type PersonalData = assettypes.PersonalData
+
+// PurgeResult is returned by PurgePersonalData
+type PurgeResult = assettypes.PurgeResult
+
+// personalDataPurgedEvent is the schema of the event set by PurgePersonalData
+var personalDataPurgedEvent = ledgerutil.EventSchema{Name: assettypes.EventPersonalDataPurged, Version: 1,
+	Description: "personal data of an asset purged from the implicit collection of an org",
+	Fields:      map[string]string{"assetID": "string", "org": "string"}}
+
+// SetPersonalData records the personal data of an asset, passed as personal_data in the transient
+// map, in the implicit collection of the owner org, replacing any recorded before. Only clients of
+// the owner org may call it, on a peer of their org.
+func (s *SmartContract) SetPersonalData(ctx ledgerutil.TransactionContextInterface, assetID string) (*AssetResult, error) {
+	assetID, err := _validateAssetID(assetID)
+	if err != nil {
+		return nil, err
+	}
+	transientMap, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "error getting transient")
+	}
+	personalDataJSON, ok := transientMap["personal_data"]
+	if !ok {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "personal_data key not found in the transient map")
+	}
+	personalData, err := _parsePersonalData(assetID, personalDataJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	clientOrgID, err := _getClientOrgID(ctx, true)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to get verified OrgID")
+	}
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to get asset")
+	}
+	err = _requireOwnerOrg(ctx.GetIdentity(), asset.OwnerOrg, "record the personal data of an asset")
+	if err != nil {
+		return nil, err
+	}
+	err = _putPersonalData(ctx, clientOrgID, personalData)
+	if err != nil {
+		return nil, err
+	}
+	return _assetResult(ctx, asset)
+}
+
+// GetPersonalData returns the personal data of an asset recorded by the client's org, read from its
+// implicit collection on a peer of its org
+func (s *SmartContract) GetPersonalData(ctx ledgerutil.TransactionContextInterface, assetID string) (*PersonalData, error) {
+	assetID, err := _validateAssetID(assetID)
+	if err != nil {
+		return nil, err
+	}
+	collection, err := getClientImplicitCollectionName(ctx)
+	if err != nil {
+		return nil, err
+	}
+	key, err := _personalDataKey(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	var personalData PersonalData
+	found, err := ledgerutil.ReadPrivateJSON(ctx.GetStub(), collection, key, &personalData)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeNotFound, "no personal data of asset %s in client org's collection", assetID)
+	}
+	return &personalData, nil
+}
+
+// PurgePersonalData erases the personal data of an asset recorded by the client's org to honor an
+// erasure request, whether or not the org still owns the asset. The data is purged from the private
+// data stores of the peers, which needs Fabric v2.5 or later, and cannot be read from earlier blocks.
+// Only clients allowed asset.PurgePersonalData in the access-control chaincode may call it, on a
+// peer of their org.
+func (s *SmartContract) PurgePersonalData(ctx ledgerutil.TransactionContextInterface, assetID string) (*PurgeResult, error) {
+	assetID, err := _validateAssetID(assetID)
+	if err != nil {
+		return nil, err
+	}
+	collection, err := getClientImplicitCollectionName(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = _checkAccess(ctx, "asset.PurgePersonalData")
+	if err != nil {
+		return nil, err
+	}
+	stub := ctx.GetStub()
+	key, err := _personalDataKey(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	// the hash is enough to know the data exists, without reading it into the transaction
+	hash, err := stub.GetPrivateDataHash(collection, key)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to read personal data hash from client org's collection")
+	}
+	if hash == nil {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeNotFound, "no personal data of asset %s in client org's collection", assetID)
+	}
+	err = stub.PurgePrivateData(collection, key)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to purge personal data")
+	}
+	err = ledgerutil.EmitEvent(stub, personalDataPurgedEvent, assettypes.PersonalDataEvent{ID: assetID, Org: ctx.GetClientMSPID()})
+	if err != nil {
+		return nil, err
+	}
+
+	txID, timestamp, err := ledgerutil.TxInfo(stub)
+	if err != nil {
+		return nil, err
+	}
+	return &PurgeResult{Status: ledgerutil.StatusSuccess, TxID: txID, Timestamp: timestamp, AssetID: assetID}, nil
+}
+
+// _parsePersonalData checks the personal data passed for an asset and returns it
+func _parsePersonalData(assetID string, personalDataJSON []byte) (*PersonalData, error) {
+	v := ledgerutil.NewValidator()
+	v.Payload("personal_data", personalDataJSON)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	var personalData PersonalData
+	err := json.Unmarshal(personalDataJSON, &personalData)
+	if err != nil {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "invalid arguments: personal_data is not a JSON object: %v", err)
+	}
+	if personalData.ID != assetID {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "invalid arguments: personal_data is for asset %q, not %s", personalData.ID, assetID)
+	}
+	if personalData.OwnerName == "" {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "invalid arguments: personal_data has no owner_name")
+	}
+	v.Text("owner_name", personalData.OwnerName, ledgerutil.MaxTextLength)
+	v.Text("owner_contact", personalData.OwnerContact, ledgerutil.MaxTextLength)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	return &personalData, nil
+}
+
+// _putPersonalData writes the personal data of an asset to the implicit collection of the org
+func _putPersonalData(ctx ledgerutil.TransactionContextInterface, orgID string, personalData *PersonalData) error {
+	key, err := _personalDataKey(ctx, personalData.ID)
+	if err != nil {
+		return err
+	}
+	err = ledgerutil.PutPrivateJSON(ctx.GetStub(), _buildClientOrgName(orgID), key, personalData)
+	if err != nil {
+		return ledgerutil.Wrap(err, "failed to put personal data")
+	}
+	return nil
+}
+
+// _personalDataKey returns the key of the personal data of an asset in an implicit collection
+func _personalDataKey(ctx ledgerutil.TransactionContextInterface, assetID string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(typePersonalData, []string{assetID})
+	if err != nil {
+		return "", ledgerutil.Wrap(err, "failed to create composite key for personal data")
+	}
+	return key, nil
+}
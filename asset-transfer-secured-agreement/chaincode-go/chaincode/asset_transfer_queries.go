@@ -0,0 +1,414 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"github.com/golang/protobuf/ptypes"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-samples/asset-transfer-secured-agreement/assettypes"
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil"
+)
+
+// identityRegistryName is the name the identity-registry chaincode is deployed under on the channel
+const identityRegistryName = "identity"
+
+// QueryResult structure used for handling result of query
+type QueryResult = assettypes.QueryResult
+
+type Agreement = assettypes.Agreement
+
+// Receipt records a completed sale in the buyer's and seller's implicit collections.
+type Receipt = assettypes.Receipt
+
+// AssetPage is one page of the assets returned by GetAssetsPage
+type AssetPage = assettypes.AssetPage
+
+// HistoryPage is one page of the history returned by QueryAssetHistoryPage
+type HistoryPage = assettypes.HistoryPage
+
+// publicObjectTypes and privateObjectTypes are the composite key prefixes ListByCompositeKey may
+// list from the world state and from the client org's implicit collection
+var (
+	publicObjectTypes  = []string{ledgerutil.AuditPrefix, ledgerutil.AuditRecordPrefix, typeScheduledTransfer, typeAssetLock, typeContentAttestation, typeAssetOperator, typeAssetTemplate, typeAssetExpiry}
+	privateObjectTypes = []string{typeAssetBuyReceipt, typeAssetSaleReceipt, sellerPrice, bidderPrice, typeValuation}
+)
+
+// GetEvaluateTransactions lists the read-only functions, which the contract metadata tags as evaluate
+// so SDKs query one peer for them instead of submitting a transaction
+func (s *SmartContract) GetEvaluateTransactions() []string {
+	return []string{"ReadAsset", "GetOwnerProfile", "GetAssetPrivateProperties", "GetAssetSalesPrice",
+		"GetAssetBidPrice", "GetAssetReceipts", "QueryAssetHistory", "QueryAssetHistoryPage", "GetAssetsPage", "SetInspection", "WhoAmI",
+		"GetQueryConfig", "ListByCompositeKey", "GetVersion", "Ping", "GetPersonalData",
+		"GetScheduledTransfer", "GetValuationHistory", "GetCurrentValuation", "GetAssetLock", "GetContentAttestations", "GetAssetOperator", "GetAssetTemplate"}
+}
+
+// ReadAsset returns the public asset data
+func (s *SmartContract) ReadAsset(ctx ledgerutil.TransactionContextInterface, assetID string) (*Asset, error) {
+	assetID, err := _validateAssetID(assetID)
+	if err != nil {
+		return nil, err
+	}
+	// Since only public data is accessed in this function, no access control is required
+	assetKey, err := _assetKey(ctx.GetStub(), assetID)
+	if err != nil {
+		return nil, err
+	}
+	var asset Asset
+	found, err := ledgerutil.ReadRecord(ctx.GetStub(), assetKey, (*assetRecord)(&asset)) //GET ledger data
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeAssetNotFound, "%s does not exist", assetID)
+	}
+	if asset.ID != assetID {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeCorruptState, "world state value of %s is not a valid asset", assetID)
+	}
+	return &asset, nil
+}
+
+// GetOwnerProfile returns the profile of the org that owns the asset from the identity registry
+// chaincode, so clients can display the owner's name rather than its MSP ID
+func (s *SmartContract) GetOwnerProfile(ctx ledgerutil.TransactionContextInterface, assetID string) (string, error) {
+	assetID, err := _validateAssetID(assetID)
+	if err != nil {
+		return "", err
+	}
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return "", err
+	}
+
+	args := [][]byte{[]byte("GetOrgProfile"), []byte(asset.OwnerOrg)}
+	response := ctx.GetStub().InvokeChaincode(identityRegistryName, args, "")
+	if response.Status != shim.OK {
+		return "", ledgerutil.Wrap(ledgerutil.ParseError(response.Message), "failed to get profile for owner org %s", asset.OwnerOrg)
+	}
+
+	return string(response.Payload), nil
+}
+
+// WhoAmI returns the identity of the client as the contract sees it, so a client can check the org,
+// organizational units and attributes its authorization depends on
+func (s *SmartContract) WhoAmI(ctx ledgerutil.TransactionContextInterface) (*ledgerutil.Identity, error) {
+	return ctx.GetIdentity(), nil
+}
+
+// GetVersion returns the version and commit the chaincode was built from, so operators can check which
+// build each peer runs
+func (s *SmartContract) GetVersion(ctx ledgerutil.TransactionContextInterface) (*ledgerutil.BuildInfo, error) {
+	return ledgerutil.GetBuildInfo(s.Name), nil
+}
+
+// Ping checks the peer can run the contract and read its world state, without changing it. Evaluate it
+// to check one peer, or submit it to check the endorsement of every org the policy needs.
+func (s *SmartContract) Ping(ctx ledgerutil.TransactionContextInterface) (*ledgerutil.PingResult, error) {
+	return ledgerutil.Ping(ctx.GetStub(), s.Name)
+}
+
+// GetQueryConfig returns the query limits, the defaults until SetQueryConfig is called
+func (s *SmartContract) GetQueryConfig(ctx ledgerutil.TransactionContextInterface) (*ledgerutil.QueryConfig, error) {
+	config, err := ledgerutil.GetQueryConfig(ctx.GetStub())
+	if err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// GetAssetPrivateProperties returns the immutable asset properties from owner's private data collection
+func (s *SmartContract) GetAssetPrivateProperties(ctx ledgerutil.TransactionContextInterface, assetID string) (string, error) {
+	assetID, err := _validateAssetID(assetID)
+	if err != nil {
+		return "", err
+	}
+	// In this scenario, client is only authorized to read/write private data from its own peer.
+	collection, err := getClientImplicitCollectionName(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	privatePropertiesJSON, err := ctx.GetStub().GetPrivateData(collection, assetID)
+	if err != nil {
+		return "", ledgerutil.Wrap(err, "failed to read asset private properties from client org's collection")
+	}
+	if privatePropertiesJSON == nil {
+		return "", ledgerutil.Errorf(ledgerutil.CodeNotFound, "asset private details does not exist in client org's collection: %s", assetID)
+	}
+
+	return string(privatePropertiesJSON), nil
+}
+
+// GetAssetSalesPrice returns the sales price
+func (s *SmartContract) GetAssetSalesPrice(ctx ledgerutil.TransactionContextInterface, assetID string) (string, error) {
+	return getAssetPrice(ctx, assetID, sellerPrice)
+}
+
+// GetAssetBidPrice returns the bid price
+func (s *SmartContract) GetAssetBidPrice(ctx ledgerutil.TransactionContextInterface, assetID string) (string, error) {
+	return getAssetPrice(ctx, assetID, bidderPrice)
+}
+
+// getAssetPrice gets the bid or ask price from caller's implicit private data collection
+func getAssetPrice(ctx ledgerutil.TransactionContextInterface, assetID string, priceType string) (string, error) {
+	assetID, err := _validateAssetID(assetID)
+	if err != nil {
+		return "", err
+	}
+	collection, err := getClientImplicitCollectionName(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	assetPriceKey, err := ctx.GetStub().CreateCompositeKey(priceType, []string{assetID})
+	if err != nil {
+		return "", ledgerutil.Wrap(err, "failed to create composite key")
+	}
+
+	price, err := ctx.GetStub().GetPrivateData(collection, assetPriceKey)
+	if err != nil {
+		return "", ledgerutil.Wrap(err, "failed to read asset price from implicit private data collection")
+	}
+	if price == nil {
+		return "", ledgerutil.Errorf(ledgerutil.CodeNotFound, "asset price does not exist: %s", assetID)
+	}
+
+	return string(price), nil
+}
+
+// GetAssetReceipts returns the receipts of the client org's purchases and sales of an asset
+func (s *SmartContract) GetAssetReceipts(ctx ledgerutil.TransactionContextInterface, assetID string) ([]Receipt, error) {
+	assetID, err := _validateAssetID(assetID)
+	if err != nil {
+		return nil, err
+	}
+	collection, err := getClientImplicitCollectionName(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := ledgerutil.GetQueryConfig(ctx.GetStub())
+	if err != nil {
+		return nil, err
+	}
+
+	var receipts []Receipt
+	for _, receiptType := range []string{typeAssetBuyReceipt, typeAssetSaleReceipt} {
+		err = ledgerutil.ForEachPrivateByPartialCompositeKey(ctx.GetStub(), collection, receiptType, []string{assetID}, func(_ []string, value []byte) error {
+			// private data queries cannot be paginated, so there is no paged variant to point to
+			if len(receipts) == config.MaxResults {
+				return ledgerutil.Errorf(ledgerutil.CodeResultsTruncated, "results truncated at %d receipts of asset %s", config.MaxResults, assetID)
+			}
+			var receipt Receipt
+			err := ledgerutil.UnmarshalRecord("receipt of "+assetID, value, (*receiptRecord)(&receipt))
+			if err != nil {
+				return ledgerutil.Wrap(err, "failed to unmarshal receipt")
+			}
+			receipts = append(receipts, receipt)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return receipts, nil
+}
+
+// ListByCompositeKey returns up to pageSize raw entries of a composite key prefix, starting at
+// bookmark. Audit entries, audit records and scheduled transfers are read from the world state;
+// receipts (buyreceipt, salereceipt), agreed prices (S, B) and appraisals (valuation) from the
+// client org's implicit collection. partialKeys are the leading attributes of the key, e.g. an
+// asset ID. Only clients allowed asset.ListByCompositeKey in the access-control chaincode may list.
+func (s *SmartContract) ListByCompositeKey(ctx ledgerutil.TransactionContextInterface, objectType string, partialKeys []string, pageSize int, bookmark string) (*ledgerutil.KeyPage, error) {
+	v := ledgerutil.NewValidator()
+	v.OneOf("objectType", objectType, append(append([]string{}, publicObjectTypes...), privateObjectTypes...))
+	for i := range partialKeys {
+		partialKeys[i] = ledgerutil.NormalizeID(partialKeys[i])
+		v.Key("partialKeys", partialKeys[i])
+	}
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	_, err := ledgerutil.CheckPageSize(ctx.GetStub(), pageSize)
+	if err != nil {
+		return nil, err
+	}
+	err = _checkAccess(ctx, "asset.ListByCompositeKey")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, privateType := range privateObjectTypes {
+		if objectType != privateType {
+			continue
+		}
+		collection, err := getClientImplicitCollectionName(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return ledgerutil.ListPrivateByCompositeKey(ctx.GetStub(), collection, objectType, partialKeys, int32(pageSize), bookmark)
+	}
+	return ledgerutil.ListByCompositeKey(ctx.GetStub(), objectType, partialKeys, int32(pageSize), bookmark)
+}
+
+// QueryAssetHistory returns the chain of custody for a asset since issuance. It fails with
+// RESULTS_TRUNCATED when the asset has more than maxResults modifications, which
+// QueryAssetHistoryPage then returns a page at a time.
+func (s *SmartContract) QueryAssetHistory(ctx ledgerutil.TransactionContextInterface, assetID string) ([]QueryResult, error) {
+	assetID, err := _validateAssetID(assetID)
+	if err != nil {
+		return nil, err
+	}
+	config, err := ledgerutil.GetQueryConfig(ctx.GetStub())
+	if err != nil {
+		return nil, err
+	}
+	assetKey, err := _assetKey(ctx.GetStub(), assetID)
+	if err != nil {
+		return nil, err
+	}
+	resultsIterator, err := ctx.GetStub().GetHistoryForKey(assetKey)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var results []QueryResult
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		if len(results) == config.MaxResults {
+			return nil, ledgerutil.ResultsTruncated(config.MaxResults, "QueryAssetHistoryPage")
+		}
+
+		record, err := _historyRecord(response)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, *record)
+	}
+
+	return results, nil
+}
+
+// QueryAssetHistoryPage returns up to pageSize modifications of an asset, starting at bookmark. Pass
+// the bookmark of each page to get the next one until it is empty; an empty bookmark starts at the
+// first modification. The history of a key cannot be paginated by the peer, so the modifications
+// before bookmark are read and skipped.
+func (s *SmartContract) QueryAssetHistoryPage(ctx ledgerutil.TransactionContextInterface, assetID string, pageSize int, bookmark string) (*HistoryPage, error) {
+	assetID, err := _validateAssetID(assetID)
+	if err != nil {
+		return nil, err
+	}
+	_, err = ledgerutil.CheckPageSize(ctx.GetStub(), pageSize)
+	if err != nil {
+		return nil, err
+	}
+	assetKey, err := _assetKey(ctx.GetStub(), assetID)
+	if err != nil {
+		return nil, err
+	}
+	resultsIterator, err := ctx.GetStub().GetHistoryForKey(assetKey)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	page := &HistoryPage{History: []QueryResult{}}
+	started := bookmark == ""
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		if !started {
+			if response.TxId != bookmark {
+				continue
+			}
+			started = true
+		}
+		if len(page.History) == pageSize {
+			page.Bookmark = response.TxId
+			break
+		}
+
+		record, err := _historyRecord(response)
+		if err != nil {
+			return nil, err
+		}
+		page.History = append(page.History, *record)
+	}
+	if !started {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "invalid arguments: bookmark %s is not a transaction in the history of %s", bookmark, assetID)
+	}
+
+	return page, nil
+}
+
+// _historyRecord reads one modification of an asset from its history
+func _historyRecord(response *queryresult.KeyModification) (*QueryResult, error) {
+	var asset *Asset
+	if !response.IsDelete {
+		asset = new(Asset)
+		err := ledgerutil.UnmarshalRecord("asset written by "+response.TxId, response.Value, (*assetRecord)(asset))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	timestamp, err := ptypes.Timestamp(response.Timestamp)
+	if err != nil {
+		return nil, err
+	}
+	return &QueryResult{
+		TxId:      response.TxId,
+		Timestamp: timestamp,
+		Record:    asset,
+	}, nil
+}
+
+// GetAssetsPage returns up to pageSize assets in key order, starting at bookmark. Pass the bookmark
+// of each page to get the next one until it is empty; an empty bookmark starts at the first asset.
+func (s *SmartContract) GetAssetsPage(ctx ledgerutil.TransactionContextInterface, pageSize int, bookmark string) (*AssetPage, error) {
+	_, err := ledgerutil.CheckPageSize(ctx.GetStub(), pageSize)
+	if err != nil {
+		return nil, err
+	}
+	resultsIterator, metadata, err := ctx.GetStub().GetStateByPartialCompositeKeyWithPagination(assetPrefix, []string{}, int32(pageSize), bookmark)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to get assets from world state")
+	}
+	defer resultsIterator.Close()
+	return readAssetPage(resultsIterator, metadata, pageSize)
+}
+
+// readAssetPage reads a page of assets from a paginated query. The bookmark is left empty when the
+// page is not full, as there is no next page.
+func readAssetPage(resultsIterator shim.StateQueryIteratorInterface, metadata *pb.QueryResponseMetadata, pageSize int) (*AssetPage, error) {
+	page := &AssetPage{Assets: []*Asset{}}
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		var asset Asset
+		err = ledgerutil.UnmarshalRecord(response.Key, response.Value, (*assetRecord)(&asset))
+		if err != nil {
+			return nil, err
+		}
+		if asset.ObjectType != "asset" {
+			continue
+		}
+		page.Assets = append(page.Assets, &asset)
+	}
+	if metadata.FetchedRecordsCount == int32(pageSize) {
+		page.Bookmark = metadata.Bookmark
+	}
+	return page, nil
+}
@@ -0,0 +1,165 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"time"
+
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil"
+)
+
+// assetRecord and receiptRecord are the public assets and the sale receipts as ledgerutil records,
+// stored in protobuf with the schema of protos/records.proto when the stateCodec of the chaincode is
+// protobuf. They convert from the assettypes types, which client applications share without a
+// dependency on ledgerutil. Fields are written in field number order.
+type assetRecord Asset
+
+type receiptRecord Receipt
+
+// MarshalProto encodes the asset in protobuf
+func (a *assetRecord) MarshalProto() ([]byte, error) {
+	expiresAt, err := _parseRecordTime(a.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	createdAt, err := _parseRecordTime(a.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	updatedAt, err := _parseRecordTime(a.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	var w ledgerutil.ProtoWriter
+	w.String(1, a.ObjectType)
+	w.String(2, a.ID)
+	w.String(3, a.OwnerOrg)
+	w.String(4, a.PublicDescription)
+	for _, content := range a.Content {
+		var c ledgerutil.ProtoWriter
+		c.String(1, content.Name)
+		c.String(2, content.CID)
+		c.String(3, content.SHA256)
+		c.String(4, content.UpdatedBy)
+		c.String(5, content.TxID)
+		c.Timestamp(6, content.Timestamp)
+		w.BytesField(5, c.Bytes())
+	}
+	w.String(6, a.ItemType)
+	w.String(7, a.TemplateID)
+	w.Strings(8, a.Tags)
+	w.StringMap(9, a.Attributes)
+	w.Timestamp(10, expiresAt)
+	w.String(11, a.Status)
+	w.Timestamp(12, createdAt)
+	w.Timestamp(13, updatedAt)
+	return w.Bytes(), nil
+}
+
+// UnmarshalProto decodes the asset from protobuf
+func (a *assetRecord) UnmarshalProto(data []byte) error {
+	reader := ledgerutil.NewProtoReader(data)
+	for reader.Next() {
+		switch reader.Field() {
+		case 1:
+			a.ObjectType = reader.ReadString()
+		case 2:
+			a.ID = reader.ReadString()
+		case 3:
+			a.OwnerOrg = reader.ReadString()
+		case 4:
+			a.PublicDescription = reader.ReadString()
+		case 5:
+			content, err := _unmarshalContentRef(reader.ReadBytes())
+			if err != nil {
+				return err
+			}
+			a.Content = append(a.Content, content)
+		case 6:
+			a.ItemType = reader.ReadString()
+		case 7:
+			a.TemplateID = reader.ReadString()
+		case 8:
+			a.Tags = append(a.Tags, reader.ReadString())
+		case 9:
+			reader.ReadStringMapEntry(&a.Attributes)
+		case 10:
+			a.ExpiresAt = ledgerutil.FormatTime(reader.ReadTimestamp())
+		case 11:
+			a.Status = reader.ReadString()
+		case 12:
+			a.CreatedAt = ledgerutil.FormatTime(reader.ReadTimestamp())
+		case 13:
+			a.UpdatedAt = ledgerutil.FormatTime(reader.ReadTimestamp())
+		}
+	}
+	return reader.Err()
+}
+
+// _parseRecordTime parses an optional time of an asset, the zero time when it is not set, which
+// ProtoWriter leaves out
+func _parseRecordTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return ledgerutil.ParseTime(value)
+}
+
+// _unmarshalContentRef decodes a content reference embedded in an asset
+func _unmarshalContentRef(data []byte) (ContentRef, error) {
+	var content ContentRef
+	reader := ledgerutil.NewProtoReader(data)
+	for reader.Next() {
+		switch reader.Field() {
+		case 1:
+			content.Name = reader.ReadString()
+		case 2:
+			content.CID = reader.ReadString()
+		case 3:
+			content.SHA256 = reader.ReadString()
+		case 4:
+			content.UpdatedBy = reader.ReadString()
+		case 5:
+			content.TxID = reader.ReadString()
+		case 6:
+			content.Timestamp = reader.ReadTimestamp()
+		}
+	}
+	return content, reader.Err()
+}
+
+// MarshalProto encodes the receipt in protobuf
+func (r *receiptRecord) MarshalProto() ([]byte, error) {
+	var w ledgerutil.ProtoWriter
+	w.String(1, r.AssetID)
+	w.String(2, r.Type)
+	w.String(3, r.Counterparty)
+	w.Int64(4, int64(r.Price))
+	w.Timestamp(5, r.Timestamp)
+	w.String(6, r.TraceID)
+	return w.Bytes(), nil
+}
+
+// UnmarshalProto decodes the receipt from protobuf
+func (r *receiptRecord) UnmarshalProto(data []byte) error {
+	reader := ledgerutil.NewProtoReader(data)
+	for reader.Next() {
+		switch reader.Field() {
+		case 1:
+			r.AssetID = reader.ReadString()
+		case 2:
+			r.Type = reader.ReadString()
+		case 3:
+			r.Counterparty = reader.ReadString()
+		case 4:
+			r.Price = int(reader.ReadInt64())
+		case 5:
+			r.Timestamp = reader.ReadTimestamp()
+		case 6:
+			r.TraceID = reader.ReadString()
+		}
+	}
+	return reader.Err()
+}
@@ -0,0 +1,176 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-contract-api-go/metadata"
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil"
+)
+
+// QueryContract holds the functions of the asset chaincode that run CouchDB rich queries. The peer
+// does not check the results of a rich query again when it validates a transaction, so a submitted
+// function acting on them could commit what another transaction made stale, and the asset contract
+// reads only by key and composite key. Its functions are all evaluate functions and its context
+// fails any write, so clients call them as assetquery:<Function> with evaluate.
+type QueryContract struct {
+	contractapi.Contract
+}
+
+// ownerIndex is the design document and name of the CouchDB index on objectType and ownerOrg in
+// META-INF/statedb/couchdb/indexes, which QueryAssetsByOwner names so CouchDB does not scan
+const ownerIndexDoc, ownerIndexName = "_design/indexOwnerDoc", "indexOwner"
+
+// updatedAtIndex is the design document and name of the CouchDB index on objectType and updatedAt,
+// which QueryAssetsByTimeRange names
+const updatedAtIndexDoc, updatedAtIndexName = "_design/indexUpdatedAtDoc", "indexUpdatedAt"
+
+// timeRangeFormat formats the bounds of QueryAssetsByTimeRange to the second without a zone. Go
+// writes the UTC timestamps of the assets in RFC 3339 with a variable fraction, so every timestamp
+// within a second sorts after the second written this way and before the next one.
+const timeRangeFormat = "2006-01-02T15:04:05"
+
+// GetEvaluateTransactions lists every function of the contract, which only queries
+func (q *QueryContract) GetEvaluateTransactions() []string {
+	return []string{"QueryAssetsByOwner", "QueryActiveAssetsByOwner", "QueryAssetsByTimeRange"}
+}
+
+// QueryAssetsByOwner returns up to pageSize assets owned by ownerOrg, starting at bookmark, with a
+// CouchDB rich query served by the owner index. Pass the bookmark of each page to get the next one
+// until it is empty. It needs CouchDB as the state database.
+func (q *QueryContract) QueryAssetsByOwner(ctx ledgerutil.TransactionContextInterface, ownerOrg string, pageSize int, bookmark string) (*AssetPage, error) {
+	if ownerOrg == "" {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "invalid arguments: ownerOrg must not be empty")
+	}
+	_, err := ledgerutil.CheckPageSize(ctx.GetStub(), pageSize)
+	if err != nil {
+		return nil, err
+	}
+	err = _checkJSONState(ctx)
+	if err != nil {
+		return nil, err
+	}
+	query, err := json.Marshal(map[string]interface{}{
+		"selector":  map[string]string{"objectType": "asset", "ownerOrg": ownerOrg},
+		"use_index": []string{ownerIndexDoc, ownerIndexName},
+	})
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to build query")
+	}
+	resultsIterator, metadata, err := ctx.GetStub().GetQueryResultWithPagination(string(query), int32(pageSize), bookmark)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to query assets of %s", ownerOrg)
+	}
+	defer resultsIterator.Close()
+	return readAssetPage(resultsIterator, metadata, pageSize)
+}
+
+// QueryAssetsByTimeRange returns up to pageSize assets whose public data was last changed, or
+// created, from the RFC 3339 time from up to but not including to, starting at bookmark, with a
+// CouchDB rich query served by the updatedAt index. Both bounds are taken to the second. Pass the
+// bookmark of each page to get the next one until it is empty, e.g. to reconcile what changed in a
+// day. It needs CouchDB as the state database, and assets last written before the timestamps were
+// recorded are not found.
+func (q *QueryContract) QueryAssetsByTimeRange(ctx ledgerutil.TransactionContextInterface, from string, to string, pageSize int, bookmark string) (*AssetPage, error) {
+	fromTime, err := time.Parse(time.RFC3339, from)
+	if err != nil {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "invalid arguments: from must be an RFC 3339 time such as 2024-01-31T00:00:00Z, not %q", from)
+	}
+	toTime, err := time.Parse(time.RFC3339, to)
+	if err != nil {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "invalid arguments: to must be an RFC 3339 time such as 2024-02-01T00:00:00Z, not %q", to)
+	}
+	if !toTime.After(fromTime) {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "invalid arguments: to %s is not after from %s", to, from)
+	}
+	_, err = ledgerutil.CheckPageSize(ctx.GetStub(), pageSize)
+	if err != nil {
+		return nil, err
+	}
+	err = _checkJSONState(ctx)
+	if err != nil {
+		return nil, err
+	}
+	query, err := json.Marshal(map[string]interface{}{
+		"selector": map[string]interface{}{
+			"objectType": "asset",
+			"updatedAt": map[string]string{
+				"$gte": fromTime.UTC().Format(timeRangeFormat),
+				"$lt":  toTime.UTC().Format(timeRangeFormat),
+			},
+		},
+		"sort":      []map[string]string{{"objectType": "asc"}, {"updatedAt": "asc"}},
+		"use_index": []string{updatedAtIndexDoc, updatedAtIndexName},
+	})
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to build query")
+	}
+	resultsIterator, metadata, err := ctx.GetStub().GetQueryResultWithPagination(string(query), int32(pageSize), bookmark)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to query assets changed from %s to %s", from, to)
+	}
+	defer resultsIterator.Close()
+	return readAssetPage(resultsIterator, metadata, pageSize)
+}
+
+// QueryActiveAssetsByOwner is QueryAssetsByOwner leaving out the assets that have expired, whether
+// or not ExpireAssets has marked them yet. A page may hold fewer than pageSize assets and still
+// have a bookmark.
+func (q *QueryContract) QueryActiveAssetsByOwner(ctx ledgerutil.TransactionContextInterface, ownerOrg string, pageSize int, bookmark string) (*AssetPage, error) {
+	page, err := q.QueryAssetsByOwner(ctx, ownerOrg, pageSize, bookmark)
+	if err != nil {
+		return nil, err
+	}
+	timestamp, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	active := []*Asset{}
+	for _, asset := range page.Assets {
+		expired, err := _isExpired(asset, timestamp)
+		if err != nil {
+			return nil, err
+		}
+		if !expired {
+			active = append(active, asset)
+		}
+	}
+	page.Assets = active
+	return page, nil
+}
+
+// NewQueryContract returns the query contract named "assetquery" with the transaction hooks set, for
+// the chaincode of this module and for chaincodes bundling it with other contracts
+func NewQueryContract() *QueryContract {
+	queryContract := new(QueryContract)
+	queryContract.Name = "assetquery"
+	queryContract.Info = metadata.InfoMetadata{
+		Title:       "Secured agreement asset queries",
+		Description: "CouchDB rich queries over the assets of the secured agreement asset transfer, evaluated only",
+		Version:     "1.0.0",
+		License:     &metadata.LicenseMetadata{Name: "Apache-2.0"},
+	}
+	// the stub of the query context fails writes, so rich query results are never committed
+	queryContract.TransactionContextHandler = new(ledgerutil.QueryTransactionContext)
+	queryContract.BeforeTransaction = ledgerutil.BeforeTransaction(queryContract.GetEvaluateTransactions())
+	queryContract.UnknownTransaction = ledgerutil.UnknownTransaction(queryContract)
+	return queryContract
+}
+
+// _checkJSONState fails a rich query when the chaincode stores its records in protobuf, which CouchDB
+// cannot select on
+func _checkJSONState(ctx ledgerutil.TransactionContextInterface) error {
+	codec, err := ledgerutil.GetStateCodec(ctx.GetStub())
+	if err != nil {
+		return err
+	}
+	if codec != ledgerutil.CodecJSON {
+		return ledgerutil.Errorf(ledgerutil.CodeInternal, "rich queries need the json state codec, the chaincode stores its records in %s", codec)
+	}
+	return nil
+}
@@ -0,0 +1,275 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-secured-agreement/assettypes"
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil"
+)
+
+// typeScheduledTransfer is the prefix of the transfers scheduled in the world state, one per asset
+const typeScheduledTransfer = "scheduledtransfer"
+
+// ScheduledTransfer is a sale of an asset that takes effect at a later time
+type ScheduledTransfer = assettypes.ScheduledTransfer
+
+// ScheduleTransfer records the sale of an asset to buyerOrgID, at the price both orgs agreed on,
+// to take effect at effectiveTime, an RFC 3339 time after the transaction's. The asset properties
+// and agreed price are passed in the transient map as for TransferAsset and checked now, and the
+// transfer stays pending until ExecuteScheduledTransfer. Only clients of the owner org, or the
+// operator it approved, may schedule a transfer, and an asset has at most one scheduled.
+func (s *SmartContract) ScheduleTransfer(ctx ledgerutil.TransactionContextInterface, assetID string, buyerOrgID string, effectiveTime string) (*ScheduledTransfer, error) {
+	assetID = ledgerutil.NormalizeID(assetID)
+	buyerOrgID = ledgerutil.NormalizeID(buyerOrgID)
+	v := ledgerutil.NewValidator()
+	v.Key("assetID", assetID)
+	v.Key("buyerOrgID", buyerOrgID)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	effective, err := time.Parse(time.RFC3339, effectiveTime)
+	if err != nil {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "invalid arguments: effectiveTime must be an RFC 3339 time such as 2024-01-31T12:00:00Z, not %q", effectiveTime)
+	}
+	privatePropertiesJSON, priceJSON, _, err := _getTransferTransient(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to get asset")
+	}
+	if buyerOrgID == asset.OwnerOrg {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "invalid arguments: asset %s is already owned by %s", assetID, buyerOrgID)
+	}
+	err = _requireNotExpired(ctx, asset)
+	if err != nil {
+		return nil, err
+	}
+	err = _requireNoScheduledTransfer(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	err = _requireNotLocked(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	err = _checkKYC(ctx, asset.OwnerOrg, buyerOrgID)
+	if err != nil {
+		return nil, err
+	}
+	err = _SetApproval(ctx, asset, privatePropertiesJSON, ctx.GetIdentity(), buyerOrgID, priceJSON)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed transfer verification")
+	}
+
+	txID, timestamp, err := ledgerutil.TxInfo(ctx.GetStub())
+	if err != nil {
+		return nil, err
+	}
+	if !effective.After(timestamp) {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "invalid arguments: effectiveTime %s is not after the transaction time %s, use TransferAsset",
+			effectiveTime, timestamp.Format(time.RFC3339))
+	}
+	scheduled := &ScheduledTransfer{
+		ObjectType:    typeScheduledTransfer,
+		ID:            assetID,
+		SellerOrg:     asset.OwnerOrg,
+		BuyerOrg:      buyerOrgID,
+		EffectiveTime: effective.UTC(),
+		PriceHash:     fmt.Sprintf("%x", sha256.Sum256(priceJSON)),
+		ScheduledBy:   ctx.GetClientID(),
+		TxID:          txID,
+		Timestamp:     timestamp,
+	}
+	key, err := _scheduledTransferKey(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	err = ledgerutil.PutJSON(ctx.GetStub(), key, scheduled)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to put scheduled transfer")
+	}
+	return scheduled, nil
+}
+
+// ExecuteScheduledTransfer transfers an asset to the buyer of its scheduled transfer once the
+// transaction time has reached the effective time. A client of either the seller or the buyer org
+// may execute it, passing the asset properties and the price agreed when it was scheduled in the
+// transient map, which are checked again. The asset's endorsement policy still needs the seller's
+// peer to endorse.
+func (s *SmartContract) ExecuteScheduledTransfer(ctx ledgerutil.TransactionContextInterface, assetID string) (*AssetResult, error) {
+	assetID, err := _validateAssetID(assetID)
+	if err != nil {
+		return nil, err
+	}
+	privatePropertiesJSON, priceJSON, agreement, err := _getTransferTransient(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	scheduled, err := s.GetScheduledTransfer(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	err = _requireTransferParty(ctx.GetIdentity(), scheduled, "execute")
+	if err != nil {
+		return nil, err
+	}
+	timestamp, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if timestamp.Before(scheduled.EffectiveTime) {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "the transfer of asset %s takes effect at %s and cannot be executed before",
+			assetID, scheduled.EffectiveTime.Format(time.RFC3339))
+	}
+	if priceHash := fmt.Sprintf("%x", sha256.Sum256(priceJSON)); priceHash != scheduled.PriceHash {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeAgreementMismatch, "hash %s for passed price JSON does not match hash %s of the price the transfer was scheduled at",
+			priceHash, scheduled.PriceHash)
+	}
+
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to get asset")
+	}
+	if asset.OwnerOrg != scheduled.SellerOrg {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeCorruptState, "asset %s is owned by %s, not %s which scheduled its transfer", assetID, asset.OwnerOrg, scheduled.SellerOrg)
+	}
+	err = _requireNotExpired(ctx, asset)
+	if err != nil {
+		return nil, err
+	}
+	err = _checkKYC(ctx, scheduled.SellerOrg, scheduled.BuyerOrg)
+	if err != nil {
+		return nil, err
+	}
+	err = _verifyAgreement(ctx, asset, privatePropertiesJSON, scheduled.BuyerOrg, priceJSON)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed transfer verification")
+	}
+
+	err = _SetTransferAssetState(ctx, asset, privatePropertiesJSON, scheduled.SellerOrg, scheduled.BuyerOrg, agreement.Price)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed asset transfer")
+	}
+	err = _delScheduledTransfer(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	err = _emitAssetEvent(ctx, assetTransferredEvent, asset, scheduled.SellerOrg)
+	if err != nil {
+		return nil, err
+	}
+	return _assetResult(ctx, asset)
+}
+
+// CancelScheduledTransfer removes the scheduled transfer of an asset before its effective time.
+// A client of either the seller or the buyer org may cancel it; the agreed prices are kept.
+func (s *SmartContract) CancelScheduledTransfer(ctx ledgerutil.TransactionContextInterface, assetID string) (*AssetResult, error) {
+	assetID, err := _validateAssetID(assetID)
+	if err != nil {
+		return nil, err
+	}
+	scheduled, err := s.GetScheduledTransfer(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	err = _requireTransferParty(ctx.GetIdentity(), scheduled, "cancel")
+	if err != nil {
+		return nil, err
+	}
+	timestamp, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !timestamp.Before(scheduled.EffectiveTime) {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "the transfer of asset %s took effect at %s and can no longer be cancelled",
+			assetID, scheduled.EffectiveTime.Format(time.RFC3339))
+	}
+	err = _delScheduledTransfer(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	return _assetResult(ctx, asset)
+}
+
+// GetScheduledTransfer returns the pending scheduled transfer of an asset
+func (s *SmartContract) GetScheduledTransfer(ctx ledgerutil.TransactionContextInterface, assetID string) (*ScheduledTransfer, error) {
+	assetID, err := _validateAssetID(assetID)
+	if err != nil {
+		return nil, err
+	}
+	key, err := _scheduledTransferKey(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	var scheduled ScheduledTransfer
+	found, err := ledgerutil.ReadJSON(ctx.GetStub(), key, &scheduled)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeNotFound, "no transfer of asset %s is scheduled", assetID)
+	}
+	return &scheduled, nil
+}
+
+// _requireNoScheduledTransfer fails when the asset has a scheduled transfer
+func _requireNoScheduledTransfer(ctx ledgerutil.TransactionContextInterface, assetID string) error {
+	key, err := _scheduledTransferKey(ctx, assetID)
+	if err != nil {
+		return err
+	}
+	var scheduled ScheduledTransfer
+	found, err := ledgerutil.ReadJSON(ctx.GetStub(), key, &scheduled)
+	if err != nil {
+		return err
+	}
+	if found {
+		return ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "asset %s has a transfer to %s scheduled at %s, execute or cancel it first",
+			assetID, scheduled.BuyerOrg, scheduled.EffectiveTime.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// _requireTransferParty checks the client belongs to the seller or buyer org of a scheduled transfer
+func _requireTransferParty(identity *ledgerutil.Identity, scheduled *ScheduledTransfer, action string) error {
+	if !identity.InOrg(scheduled.SellerOrg) && !identity.InOrg(scheduled.BuyerOrg) {
+		return ledgerutil.Errorf(ledgerutil.CodeNotAuthorized, "a client from %s cannot %s the transfer of asset %s from %s to %s",
+			identity.MSPID, action, scheduled.ID, scheduled.SellerOrg, scheduled.BuyerOrg)
+	}
+	return nil
+}
+
+// _delScheduledTransfer removes the scheduled transfer of an asset from the world state
+func _delScheduledTransfer(ctx ledgerutil.TransactionContextInterface, assetID string) error {
+	key, err := _scheduledTransferKey(ctx, assetID)
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().DelState(key)
+	if err != nil {
+		return ledgerutil.Wrap(err, "failed to delete scheduled transfer")
+	}
+	return nil
+}
+
+// _scheduledTransferKey returns the world state key of the scheduled transfer of an asset
+func _scheduledTransferKey(ctx ledgerutil.TransactionContextInterface, assetID string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(typeScheduledTransfer, []string{assetID})
+	if err != nil {
+		return "", ledgerutil.Wrap(err, "failed to create composite key for scheduled transfer")
+	}
+	return key, nil
+}
@@ -0,0 +1,269 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-secured-agreement/assettypes"
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil"
+)
+
+// typeAssetTemplate is the prefix of the asset templates in the world state
+const typeAssetTemplate = "assettemplate"
+
+// maxTemplateFields bounds the attributes a template declares and the tags of an asset, which are
+// kept in the asset record
+const maxTemplateFields = 50
+
+// maxPatternLength bounds the regular expression of a field rule
+const maxPatternLength = 256
+
+// AssetTemplate standardizes the assets of one item type
+type AssetTemplate = assettypes.AssetTemplate
+
+// FieldRule is a check an attribute of an asset created from a template must pass
+type FieldRule = assettypes.FieldRule
+
+// PutAssetTemplate creates or replaces the template templateJSON describes, e.g.
+// {"templateID":"vehicle","itemType":"VEHICLE","requiredAttributes":["vin","make"],
+// "rules":{"vin":{"pattern":"[A-HJ-NPR-Z0-9]{17}"},"fuel":{"oneOf":["PETROL","DIESEL","ELECTRIC"]}},
+// "defaultTags":["fleet"]}. Assets already created from it keep the attributes they were created
+// with. Only clients allowed asset.PutAssetTemplate in the access-control chaincode may manage
+// templates.
+func (s *SmartContract) PutAssetTemplate(ctx ledgerutil.TransactionContextInterface, templateJSON string) (*AssetTemplate, error) {
+	v := ledgerutil.NewValidator()
+	v.Payload("templateJSON", []byte(templateJSON))
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	var template AssetTemplate
+	err := _decodeStrict(templateJSON, &template)
+	if err != nil {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "invalid arguments: templateJSON is not an asset template: %v", err)
+	}
+	template.ID = ledgerutil.NormalizeID(template.ID)
+	v.Key("templateID", template.ID)
+	v.Key("itemType", template.ItemType)
+	v.Text("publicDescription", template.PublicDescription, ledgerutil.MaxTextLength)
+	for _, name := range template.RequiredAttributes {
+		v.Key("requiredAttributes", name)
+	}
+	for _, tag := range template.DefaultTags {
+		v.Key("defaultTags", tag)
+	}
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	if len(template.RequiredAttributes) > maxTemplateFields || len(template.Rules) > maxTemplateFields || len(template.DefaultTags) > maxTemplateFields {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "invalid arguments: a template has at most %d required attributes, rules and default tags each", maxTemplateFields)
+	}
+	for name, rule := range template.Rules {
+		v.Key("rules", name)
+		if err := v.Err(); err != nil {
+			return nil, err
+		}
+		if len(rule.Pattern) > maxPatternLength {
+			return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "invalid arguments: pattern of %s is longer than %d characters", name, maxPatternLength)
+		}
+		if _, err := _compileRule(rule); err != nil {
+			return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "invalid arguments: pattern of %s is not a regular expression: %v", name, err)
+		}
+		if rule.MaxLength < 0 {
+			return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "invalid arguments: maxLength of %s must not be negative", name)
+		}
+	}
+	err = _checkAccess(ctx, "asset.PutAssetTemplate")
+	if err != nil {
+		return nil, err
+	}
+
+	txID, timestamp, err := ledgerutil.TxInfo(ctx.GetStub())
+	if err != nil {
+		return nil, err
+	}
+	template.ObjectType = typeAssetTemplate
+	template.UpdatedBy = ctx.GetClientID()
+	template.TxID = txID
+	template.Timestamp = ledgerutil.FormatTime(timestamp)
+	key, err := _assetTemplateKey(ctx, template.ID)
+	if err != nil {
+		return nil, err
+	}
+	err = ledgerutil.PutJSON(ctx.GetStub(), key, template)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to put asset template")
+	}
+	return &template, nil
+}
+
+// GetAssetTemplate returns an asset template
+func (s *SmartContract) GetAssetTemplate(ctx ledgerutil.TransactionContextInterface, templateID string) (*AssetTemplate, error) {
+	templateID = ledgerutil.NormalizeID(templateID)
+	v := ledgerutil.NewValidator()
+	v.Key("templateID", templateID)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	key, err := _assetTemplateKey(ctx, templateID)
+	if err != nil {
+		return nil, err
+	}
+	var template AssetTemplate
+	found, err := ledgerutil.ReadJSON(ctx.GetStub(), key, &template)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeNotFound, "asset template %s does not exist", templateID)
+	}
+	return &template, nil
+}
+
+// CreateAssetFromTemplate creates an asset of the template's item type from overridesJSON, e.g.
+// {"assetID":"car1","attributes":{"vin":"1HGCM82633A004352","make":"Honda"},"tags":["leased"]}.
+// The attributes must include the template's required ones, follow its rules and have no others;
+// the tags are added to the template's default tags and the public description, when set, replaces
+// the template's. The private properties are passed in the transient map and access is checked as
+// for CreateAsset.
+func (s *SmartContract) CreateAssetFromTemplate(ctx ledgerutil.TransactionContextInterface, templateID string, overridesJSON string) (*AssetResult, error) {
+	template, err := s.GetAssetTemplate(ctx, templateID)
+	if err != nil {
+		return nil, err
+	}
+	v := ledgerutil.NewValidator()
+	v.Payload("overridesJSON", []byte(overridesJSON))
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	var overrides assettypes.TemplateOverrides
+	err = _decodeStrict(overridesJSON, &overrides)
+	if err != nil {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "invalid arguments: overridesJSON is not an asset ID with attributes and tags: %v", err)
+	}
+	overrides.ID = ledgerutil.NormalizeID(overrides.ID)
+	v.Key("assetID", overrides.ID)
+	description := template.PublicDescription
+	if overrides.PublicDescription != "" {
+		description = overrides.PublicDescription
+	}
+	v.Text("publicDescription", description, ledgerutil.MaxTextLength)
+	for _, tag := range overrides.Tags {
+		v.Key("tags", tag)
+	}
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	err = _checkAttributes(template, overrides.Attributes)
+	if err != nil {
+		return nil, err
+	}
+	tags := _mergeTags(template.DefaultTags, overrides.Tags)
+	if len(tags) > maxTemplateFields {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "invalid arguments: an asset has at most %d tags", maxTemplateFields)
+	}
+
+	return _createAsset(ctx, &Asset{
+		ID:                overrides.ID,
+		PublicDescription: description,
+		ItemType:          template.ItemType,
+		TemplateID:        template.ID,
+		Tags:              tags,
+		Attributes:        overrides.Attributes,
+	})
+}
+
+// _checkAttributes checks the attributes of an asset against its template
+func _checkAttributes(template *AssetTemplate, attributes map[string]string) error {
+	for _, name := range template.RequiredAttributes {
+		if attributes[name] == "" {
+			return ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "invalid arguments: template %s requires attribute %s", template.ID, name)
+		}
+	}
+	required := make(map[string]bool)
+	for _, name := range template.RequiredAttributes {
+		required[name] = true
+	}
+	names := make([]string, 0, len(attributes))
+	for name := range attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := attributes[name]
+		rule, hasRule := template.Rules[name]
+		if !required[name] && !hasRule {
+			return ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "invalid arguments: template %s has no attribute %s", template.ID, name)
+		}
+		v := ledgerutil.NewValidator()
+		v.Text(name, value, ledgerutil.MaxTextLength)
+		if err := v.Err(); err != nil {
+			return err
+		}
+		if !hasRule {
+			continue
+		}
+		if rule.MaxLength > 0 && len(value) > rule.MaxLength {
+			return ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "invalid arguments: attribute %s is longer than %d characters", name, rule.MaxLength)
+		}
+		if len(rule.OneOf) > 0 {
+			v.OneOf(name, value, rule.OneOf)
+			if err := v.Err(); err != nil {
+				return err
+			}
+		}
+		pattern, err := _compileRule(rule)
+		if err != nil {
+			return ledgerutil.Errorf(ledgerutil.CodeCorruptState, "pattern of %s in template %s is not a regular expression: %v", name, template.ID, err)
+		}
+		if pattern != nil && !pattern.MatchString(value) {
+			return ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "invalid arguments: attribute %s does not match %s", name, rule.Pattern)
+		}
+	}
+	return nil
+}
+
+// _compileRule compiles the pattern of a field rule to match whole values, nil when it has none
+func _compileRule(rule FieldRule) (*regexp.Regexp, error) {
+	if rule.Pattern == "" {
+		return nil, nil
+	}
+	return regexp.Compile("^(?:" + rule.Pattern + ")$")
+}
+
+// _mergeTags returns the default tags followed by the extra tags not among them
+func _mergeTags(defaults []string, extra []string) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, tag := range append(append([]string{}, defaults...), extra...) {
+		tag = strings.TrimSpace(tag)
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// _decodeStrict decodes a JSON object, failing on fields the value does not have so a misspelt
+// field is reported rather than ignored
+func _decodeStrict(data string, value interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader([]byte(data)))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(value)
+}
+
+// _assetTemplateKey returns the world state key of an asset template
+func _assetTemplateKey(ctx ledgerutil.TransactionContextInterface, templateID string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(typeAssetTemplate, []string{templateID})
+	if err != nil {
+		return "", ledgerutil.Wrap(err, "failed to create composite key for asset template")
+	}
+	return key, nil
+}
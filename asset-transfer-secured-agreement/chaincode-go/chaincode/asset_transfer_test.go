@@ -0,0 +1,2086 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/pkg/statebased"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-samples/asset-transfer-secured-agreement/assettypes"
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil"
+)
+
+const (
+	sellerOrg = "Org1MSP"
+	buyerOrg  = "Org2MSP"
+	assetID   = "asset1"
+
+	assetProperties = `{"object_type":"asset_properties","asset_id":"asset1","color":"blue","size":35,"salt":"a94a8fe5ccb19ba61c4c0873d391e987982fbbd3"}`
+	wrongProperties = `{"object_type":"asset_properties","asset_id":"asset1","color":"red","size":35,"salt":"a94a8fe5ccb19ba61c4c0873d391e987982fbbd3"}`
+	// appraisedProperties are the properties of an asset with an appraised value
+	appraisedProperties = `{"object_type":"asset_properties","asset_id":"asset1","color":"blue","size":35,"appraised_value":"1250.75","salt":"a94a8fe5ccb19ba61c4c0873d391e987982fbbd3"}`
+	price100            = `{"asset_id":"asset1","trade_id":"109f4b3c50d7b0df729d299bc6f8e9ef9066971f","price":100}`
+	price110            = `{"asset_id":"asset1","trade_id":"109f4b3c50d7b0df729d299bc6f8e9ef9066971f","price":110}`
+)
+
+// tx is one transaction submitted by a client of clientOrg to a peer of peerOrg. peerOrg defaults
+// to the client's org.
+type tx struct {
+	clientOrg string
+	peerOrg   string
+	transient map[string]string
+}
+
+// run submits the transaction, giving it the next transaction ID and timestamp
+func (x tx) run(stub *fakeStub, fn func(ctx ledgerutil.TransactionContextInterface) error) error {
+	peerOrg := x.peerOrg
+	if peerOrg == "" {
+		peerOrg = x.clientOrg
+	}
+	previous, wasSet := os.LookupEnv("CORE_PEER_LOCALMSPID")
+	os.Setenv("CORE_PEER_LOCALMSPID", peerOrg)
+	defer func() {
+		if wasSet {
+			os.Setenv("CORE_PEER_LOCALMSPID", previous)
+		} else {
+			os.Unsetenv("CORE_PEER_LOCALMSPID")
+		}
+	}()
+
+	transient := make(map[string][]byte)
+	for key, value := range x.transient {
+		transient[key] = []byte(value)
+	}
+	stub.startTx(transient)
+
+	return fn(newKeyedContext(stub, x.clientOrg))
+}
+
+// newLedger returns a stub with the access-control and identity-registry chaincodes deployed
+func newLedger() *fakeStub {
+	stub := newFakeStub()
+	stub.chaincodes[accessControlName] = accessControl("asset.CreateAsset")
+	stub.chaincodes[identityRegistryName] = func(args [][]byte) pb.Response {
+		if string(args[0]) != "GetOrgProfile" {
+			return shim.Error("unexpected function " + string(args[0]))
+		}
+		return shim.Success([]byte(`{"mspID":"` + string(args[1]) + `","name":"Org One"}`))
+	}
+	return stub
+}
+
+// mustRun fails the test when a setup transaction fails
+func mustRun(t *testing.T, stub *fakeStub, x tx, fn func(ctx ledgerutil.TransactionContextInterface) error) {
+	t.Helper()
+	err := x.run(stub, fn)
+	if err != nil {
+		t.Fatalf("setup transaction failed: %v", err)
+	}
+}
+
+// createAsset creates asset1 owned by the seller org
+func createAsset(t *testing.T, stub *fakeStub) {
+	t.Helper()
+	mustRun(t, stub, tx{clientOrg: sellerOrg, transient: map[string]string{"asset_properties": assetProperties}}, func(ctx ledgerutil.TransactionContextInterface) error {
+		_, err := new(SmartContract).CreateAsset(ctx, assetID, "A new asset for Org1MSP")
+		return err
+	})
+}
+
+// agree records the seller's asking price and the buyer's bid
+func agree(t *testing.T, stub *fakeStub, sellPrice string, bidPrice string) {
+	t.Helper()
+	mustRun(t, stub, tx{clientOrg: sellerOrg, transient: map[string]string{"asset_price": sellPrice}}, func(ctx ledgerutil.TransactionContextInterface) error {
+		_, err := new(SmartContract).AgreeToSell(ctx, assetID)
+		return err
+	})
+	mustRun(t, stub, tx{clientOrg: buyerOrg, transient: map[string]string{"asset_price": bidPrice}}, func(ctx ledgerutil.TransactionContextInterface) error {
+		_, err := new(SmartContract).AgreeToBuy(ctx, assetID)
+		return err
+	})
+}
+
+// checkResult fails the test when err does not match the expected error substring, "" meaning success
+func checkResult(t *testing.T, err error, wantErr string) {
+	t.Helper()
+	if wantErr == "" && err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if wantErr != "" && (err == nil || !strings.Contains(err.Error(), wantErr)) {
+		t.Fatalf("expected error containing %q, got %v", wantErr, err)
+	}
+}
+
+// checkAssetResult fails the test when the result of the last transaction does not report its ID,
+// timestamp and the asset
+func checkAssetResult(t *testing.T, stub *fakeStub, got *AssetResult, asset *Asset) {
+	t.Helper()
+	want := &AssetResult{
+		Status:    ledgerutil.StatusSuccess,
+		TxID:      stub.txID,
+		Timestamp: time.Unix(1600000000+stub.txCount, 0).UTC(),
+		Asset:     asset,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("result is %+v, want %+v", got, want)
+	}
+}
+
+// assetKey returns the world state key of the public data of an asset
+func assetKey(t *testing.T, stub *fakeStub, id string) string {
+	key, err := stub.CreateCompositeKey(assetPrefix, []string{id})
+	if err != nil {
+		t.Fatalf("failed to create asset key: %v", err)
+	}
+	return key
+}
+
+// readAsset returns the public asset record, failing the test when it does not exist
+func readAsset(t *testing.T, stub *fakeStub) *Asset {
+	t.Helper()
+	var asset Asset
+	err := json.Unmarshal(stub.state[assetKey(t, stub, assetID)], &asset)
+	if err != nil {
+		t.Fatalf("failed to unmarshal asset: %v", err)
+	}
+	return &asset
+}
+
+// endorsers returns the orgs of the key-level endorsement policy of the asset
+func endorsers(t *testing.T, stub *fakeStub) []string {
+	t.Helper()
+	policy, err := statebased.NewStateEP(stub.validationParameters[assetKey(t, stub, assetID)])
+	if err != nil {
+		t.Fatalf("failed to parse endorsement policy: %v", err)
+	}
+	return policy.ListOrgs()
+}
+
+// priceKey returns the private data key of an asking price or bid
+func priceKey(t *testing.T, stub *fakeStub, priceType string) string {
+	t.Helper()
+	key, err := stub.CreateCompositeKey(priceType, []string{assetID})
+	if err != nil {
+		t.Fatalf("failed to create price key: %v", err)
+	}
+	return key
+}
+
+func TestCreateAsset(t *testing.T) {
+	tests := []struct {
+		name    string
+		tx      tx
+		granted []string
+		// properties are the private properties stored, assetProperties when empty
+		properties string
+		wantErr    string
+	}{
+		{
+			name:    "creates an asset owned by the client org",
+			tx:      tx{clientOrg: sellerOrg, transient: map[string]string{"asset_properties": assetProperties}},
+			granted: []string{"asset.CreateAsset"},
+		},
+		{
+			name:    "fails without the asset properties",
+			tx:      tx{clientOrg: sellerOrg},
+			granted: []string{"asset.CreateAsset"},
+			wantErr: "asset_properties key not found",
+		},
+		{
+			name:       "creates an asset with an appraised value",
+			tx:         tx{clientOrg: sellerOrg, transient: map[string]string{"asset_properties": appraisedProperties}},
+			granted:    []string{"asset.CreateAsset"},
+			properties: appraisedProperties,
+		},
+		{
+			name:    "fails on an appraised value that is a JSON number",
+			tx:      tx{clientOrg: sellerOrg, transient: map[string]string{"asset_properties": `{"asset_id":"asset1","appraised_value":1250.75}`}},
+			granted: []string{"asset.CreateAsset"},
+			wantErr: "appraised_value must be a decimal string",
+		},
+		{
+			name:    "fails on an appraised value that is not a decimal",
+			tx:      tx{clientOrg: sellerOrg, transient: map[string]string{"asset_properties": `{"asset_id":"asset1","appraised_value":"-1e3"}`}},
+			granted: []string{"asset.CreateAsset"},
+			wantErr: "is not a non-negative decimal",
+		},
+		{
+			name:    "fails on properties that are not a JSON object",
+			tx:      tx{clientOrg: sellerOrg, transient: map[string]string{"asset_properties": `blue`}},
+			granted: []string{"asset.CreateAsset"},
+			wantErr: "asset_properties is not a JSON object",
+		},
+		{
+			name:    "fails on a peer of another org",
+			tx:      tx{clientOrg: sellerOrg, peerOrg: buyerOrg, transient: map[string]string{"asset_properties": assetProperties}},
+			granted: []string{"asset.CreateAsset"},
+			wantErr: "is not authorized to read or write private data",
+		},
+		{
+			name:    "fails when the access policy does not allow it",
+			tx:      tx{clientOrg: sellerOrg, transient: map[string]string{"asset_properties": assetProperties}},
+			wantErr: "not authorized to perform asset.CreateAsset",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newLedger()
+			stub.chaincodes[accessControlName] = accessControl(tt.granted...)
+
+			var result *AssetResult
+			err := tt.tx.run(stub, func(ctx ledgerutil.TransactionContextInterface) error {
+				var err error
+				result, err = new(SmartContract).CreateAsset(ctx, assetID, "A new asset for Org1MSP")
+				return err
+			})
+			checkResult(t, err, tt.wantErr)
+			if tt.wantErr != "" {
+				if stub.state[assetKey(t, stub, assetID)] != nil {
+					t.Errorf("asset was written although the transaction failed")
+				}
+				return
+			}
+
+			asset := readAsset(t, stub)
+			if asset.OwnerOrg != sellerOrg || asset.PublicDescription != "A new asset for Org1MSP" {
+				t.Errorf("asset is %+v", asset)
+			}
+			checkAssetResult(t, stub, result, asset)
+			wantProperties := tt.properties
+			if wantProperties == "" {
+				wantProperties = assetProperties
+			}
+			if got := string(stub.privateData[_buildClientOrgName(sellerOrg)][assetID]); got != wantProperties {
+				t.Errorf("private properties are %q, want %q", got, wantProperties)
+			}
+			if got := endorsers(t, stub); !reflect.DeepEqual(got, []string{sellerOrg}) {
+				t.Errorf("asset endorsers are %v, want [%s]", got, sellerOrg)
+			}
+			checkEvent(t, stub, assettypes.EventAssetCreated, assettypes.AssetEvent{ID: assetID, OwnerOrg: sellerOrg, PublicDescription: "A new asset for Org1MSP"})
+		})
+	}
+}
+
+// checkEvent checks the event set by the last transaction
+func checkEvent(t *testing.T, stub *fakeStub, name string, want assettypes.AssetEvent) {
+	t.Helper()
+	if stub.eventName != name {
+		t.Fatalf("event is %q, want %q", stub.eventName, name)
+	}
+	var got assettypes.AssetEvent
+	if err := json.Unmarshal(stub.eventPayload, &got); err != nil {
+		t.Fatalf("failed to unmarshal event payload: %v", err)
+	}
+	if got != want {
+		t.Errorf("event payload is %+v, want %+v", got, want)
+	}
+	var version struct {
+		SchemaVersion int `json:"schemaVersion"`
+	}
+	if err := json.Unmarshal(stub.eventPayload, &version); err != nil || version.SchemaVersion != 1 {
+		t.Errorf("event payload %s has no schema version 1", stub.eventPayload)
+	}
+}
+
+func TestUpdateAsset(t *testing.T) {
+	tests := []struct {
+		name      string
+		clientOrg string
+		assetID   string
+		wantErr   string
+	}{
+		{
+			name:      "owner changes the description",
+			clientOrg: sellerOrg,
+			assetID:   assetID,
+		},
+		{
+			name:      "fails for another org",
+			clientOrg: buyerOrg,
+			assetID:   assetID,
+			wantErr:   "cannot update the description",
+		},
+		{
+			name:      "fails for a missing asset",
+			clientOrg: sellerOrg,
+			assetID:   "asset2",
+			wantErr:   "does not exist",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newLedger()
+			createAsset(t, stub)
+
+			err := tx{clientOrg: tt.clientOrg}.run(stub, func(ctx ledgerutil.TransactionContextInterface) error {
+				_, err := new(SmartContract).UpdateAsset(ctx, tt.assetID, "This asset is for sale")
+				return err
+			})
+			checkResult(t, err, tt.wantErr)
+
+			want := "This asset is for sale"
+			if tt.wantErr != "" {
+				want = "A new asset for Org1MSP"
+			}
+			if got := readAsset(t, stub).PublicDescription; got != want {
+				t.Errorf("description is %q, want %q", got, want)
+			}
+			if tt.wantErr == "" {
+				checkEvent(t, stub, assettypes.EventAssetUpdated, assettypes.AssetEvent{ID: assetID, OwnerOrg: sellerOrg, PublicDescription: want})
+			}
+		})
+	}
+}
+
+func TestAgreeToSell(t *testing.T) {
+	tests := []struct {
+		name    string
+		tx      tx
+		assetID string
+		wantErr string
+	}{
+		{
+			name:    "owner sets an asking price",
+			tx:      tx{clientOrg: sellerOrg, transient: map[string]string{"asset_price": price110}},
+			assetID: assetID,
+		},
+		{
+			name:    "fails for another org",
+			tx:      tx{clientOrg: buyerOrg, transient: map[string]string{"asset_price": price110}},
+			assetID: assetID,
+			wantErr: "cannot sell an asset owned by",
+		},
+		{
+			name:    "fails without a price",
+			tx:      tx{clientOrg: sellerOrg},
+			assetID: assetID,
+			wantErr: "asset_price key not found",
+		},
+		{
+			name:    "fails for a missing asset",
+			tx:      tx{clientOrg: sellerOrg, transient: map[string]string{"asset_price": price110}},
+			assetID: "asset2",
+			wantErr: "does not exist",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newLedger()
+			createAsset(t, stub)
+
+			err := tt.tx.run(stub, func(ctx ledgerutil.TransactionContextInterface) error {
+				_, err := new(SmartContract).AgreeToSell(ctx, tt.assetID)
+				return err
+			})
+			checkResult(t, err, tt.wantErr)
+
+			got := stub.privateData[_buildClientOrgName(sellerOrg)][priceKey(t, stub, sellerPrice)]
+			if tt.wantErr == "" && string(got) != price110 {
+				t.Errorf("asking price is %q, want %q", got, price110)
+			}
+			if tt.wantErr != "" && got != nil {
+				t.Errorf("asking price was written although the transaction failed")
+			}
+		})
+	}
+}
+
+func TestAgreeToBuy(t *testing.T) {
+	tests := []struct {
+		name    string
+		tx      tx
+		wantErr string
+	}{
+		{
+			name: "buyer sets a bid",
+			tx:   tx{clientOrg: buyerOrg, transient: map[string]string{"asset_price": price100}},
+		},
+		{
+			name:    "fails without a price",
+			tx:      tx{clientOrg: buyerOrg},
+			wantErr: "asset_price key not found",
+		},
+		{
+			name:    "fails on a peer of another org",
+			tx:      tx{clientOrg: buyerOrg, peerOrg: sellerOrg, transient: map[string]string{"asset_price": price100}},
+			wantErr: "is not authorized to read or write private data",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newLedger()
+			createAsset(t, stub)
+
+			err := tt.tx.run(stub, func(ctx ledgerutil.TransactionContextInterface) error {
+				_, err := new(SmartContract).AgreeToBuy(ctx, assetID)
+				return err
+			})
+			checkResult(t, err, tt.wantErr)
+
+			got := stub.privateData[_buildClientOrgName(buyerOrg)][priceKey(t, stub, bidderPrice)]
+			if tt.wantErr == "" && string(got) != price100 {
+				t.Errorf("bid is %q, want %q", got, price100)
+			}
+			if tt.wantErr != "" && got != nil {
+				t.Errorf("bid was written although the transaction failed")
+			}
+		})
+	}
+}
+
+func TestSetInspection(t *testing.T) {
+	tests := []struct {
+		name      string
+		transient map[string]string
+		assetID   string
+		wantErr   string
+	}{
+		{
+			name:      "matches the owner's properties",
+			transient: map[string]string{"asset_properties": assetProperties},
+			assetID:   assetID,
+		},
+		{
+			name:      "fails for different properties",
+			transient: map[string]string{"asset_properties": wrongProperties},
+			assetID:   assetID,
+			wantErr:   "does not match on-chain hash",
+		},
+		{
+			name:    "fails without properties",
+			assetID: assetID,
+			wantErr: "asset_properties key not found",
+		},
+		{
+			name:      "fails for a missing asset",
+			transient: map[string]string{"asset_properties": assetProperties},
+			assetID:   "asset2",
+			wantErr:   "does not exist",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newLedger()
+			createAsset(t, stub)
+
+			var verified bool
+			err := tx{clientOrg: buyerOrg, transient: tt.transient}.run(stub, func(ctx ledgerutil.TransactionContextInterface) error {
+				var err error
+				verified, err = new(SmartContract).SetInspection(ctx, tt.assetID)
+				return err
+			})
+			checkResult(t, err, tt.wantErr)
+			if verified != (tt.wantErr == "") {
+				t.Errorf("inspection returned %v", verified)
+			}
+		})
+	}
+}
+
+func TestTransferAsset(t *testing.T) {
+	tests := []struct {
+		name       string
+		sellPrice  string
+		bidPrice   string
+		clientOrg  string
+		properties string
+		price      string
+		wantErr    string
+	}{
+		{
+			name:       "transfers when both orgs agreed on the price",
+			sellPrice:  price100,
+			bidPrice:   price100,
+			clientOrg:  sellerOrg,
+			properties: assetProperties,
+			price:      price100,
+		},
+		{
+			name:       "fails when the seller asks more than the bid",
+			sellPrice:  price110,
+			bidPrice:   price100,
+			clientOrg:  sellerOrg,
+			properties: assetProperties,
+			price:      price100,
+			wantErr:    "seller hasn't agreed",
+		},
+		{
+			name:       "fails when the buyer did not bid the price",
+			sellPrice:  price110,
+			bidPrice:   price100,
+			clientOrg:  sellerOrg,
+			properties: assetProperties,
+			price:      price110,
+			wantErr:    "buyer hasn't agreed",
+		},
+		{
+			name:       "fails for properties that do not match",
+			sellPrice:  price100,
+			bidPrice:   price100,
+			clientOrg:  sellerOrg,
+			properties: wrongProperties,
+			price:      price100,
+			wantErr:    "does not match on-chain hash",
+		},
+		{
+			name:       "fails when not submitted by the owner",
+			sellPrice:  price100,
+			bidPrice:   price100,
+			clientOrg:  buyerOrg,
+			properties: assetProperties,
+			price:      price100,
+			wantErr:    "cannot transfer a asset owned by",
+		},
+		{
+			name:       "fails for a price that is not JSON",
+			sellPrice:  price100,
+			bidPrice:   price100,
+			clientOrg:  sellerOrg,
+			properties: assetProperties,
+			price:      "100",
+			wantErr:    "failed to unmarshal price JSON",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newLedger()
+			createAsset(t, stub)
+			agree(t, stub, tt.sellPrice, tt.bidPrice)
+
+			transient := map[string]string{"asset_properties": tt.properties, "asset_price": tt.price}
+			var result *AssetResult
+			err := tx{clientOrg: tt.clientOrg, transient: transient}.run(stub, func(ctx ledgerutil.TransactionContextInterface) error {
+				var err error
+				result, err = new(SmartContract).TransferAsset(ctx, assetID, buyerOrg)
+				return err
+			})
+			checkResult(t, err, tt.wantErr)
+
+			sellerCollection := stub.privateData[_buildClientOrgName(sellerOrg)]
+			buyerCollection := stub.privateData[_buildClientOrgName(buyerOrg)]
+			if tt.wantErr != "" {
+				if owner := readAsset(t, stub).OwnerOrg; owner != sellerOrg {
+					t.Errorf("owner is %s after a failed transfer", owner)
+				}
+				if got := endorsers(t, stub); !reflect.DeepEqual(got, []string{sellerOrg}) {
+					t.Errorf("asset endorsers are %v after a failed transfer", got)
+				}
+				return
+			}
+
+			if owner := readAsset(t, stub).OwnerOrg; owner != buyerOrg {
+				t.Errorf("owner is %s, want %s", owner, buyerOrg)
+			}
+			checkAssetResult(t, stub, result, readAsset(t, stub))
+			if got := endorsers(t, stub); !reflect.DeepEqual(got, []string{buyerOrg}) {
+				t.Errorf("asset endorsers are %v, want [%s]", got, buyerOrg)
+			}
+			if _, ok := sellerCollection[assetID]; ok {
+				t.Errorf("properties were not removed from the seller's collection")
+			}
+			if got := string(buyerCollection[assetID]); got != assetProperties {
+				t.Errorf("buyer's properties are %q, want %q", got, assetProperties)
+			}
+			checkEvent(t, stub, assettypes.EventAssetTransferred, assettypes.AssetEvent{ID: assetID, OwnerOrg: buyerOrg, PreviousOwnerOrg: sellerOrg, PublicDescription: "A new asset for Org1MSP"})
+			if sellerCollection[priceKey(t, stub, sellerPrice)] != nil || buyerCollection[priceKey(t, stub, bidderPrice)] != nil {
+				t.Errorf("agreed prices were not removed")
+			}
+		})
+	}
+}
+
+func TestStrictKYC(t *testing.T) {
+	stub := newLedger()
+	createAsset(t, stub)
+	agree(t, stub, price100, price100)
+	registered := map[string]bool{sellerOrg: true}
+	stub.chaincodes[identityRegistryName] = func(args [][]byte) pb.Response {
+		if !registered[string(args[1])] {
+			return shim.Error("org " + string(args[1]) + " has no registered profile")
+		}
+		return shim.Success([]byte(`{"mspID":"` + string(args[1]) + `"}`))
+	}
+	stub.chaincodes[accessControlName] = accessControl("config.SetFlag")
+	mustRun(t, stub, tx{clientOrg: sellerOrg}, func(ctx ledgerutil.TransactionContextInterface) error {
+		_, err := NewConfigContract().SetFlag(ctx, ledgerutil.StrictKYCFlag.Name, true)
+		return err
+	})
+
+	transfer := func(ctx ledgerutil.TransactionContextInterface) error {
+		_, err := new(SmartContract).TransferAsset(ctx, assetID, buyerOrg)
+		return err
+	}
+	transient := map[string]string{"asset_properties": assetProperties, "asset_price": price100}
+	err := tx{clientOrg: sellerOrg, transient: transient}.run(stub, transfer)
+	checkResult(t, err, "Org2MSP has no verified profile in the identity registry, required in strict KYC mode: org Org2MSP has no registered profile")
+	if got := ledgerutil.CodeOf(err); got != ledgerutil.CodeNotAuthorized {
+		t.Errorf("error code is %s, want %s", got, ledgerutil.CodeNotAuthorized)
+	}
+	if owner := readAsset(t, stub).OwnerOrg; owner != sellerOrg {
+		t.Errorf("owner is %s after a failed transfer", owner)
+	}
+
+	registered[buyerOrg] = true
+	err = tx{clientOrg: sellerOrg, transient: transient}.run(stub, transfer)
+	checkResult(t, err, "")
+	if owner := readAsset(t, stub).OwnerOrg; owner != buyerOrg {
+		t.Errorf("owner is %s, want %s", owner, buyerOrg)
+	}
+}
+
+func TestScheduledTransfer(t *testing.T) {
+	stub := newLedger()
+	createAsset(t, stub)
+	agree(t, stub, price100, price100)
+
+	transfer := map[string]string{"asset_properties": assetProperties, "asset_price": price100}
+	schedule := func(org string, effectiveTime int64) error {
+		return tx{clientOrg: org, transient: transfer}.run(stub, func(ctx ledgerutil.TransactionContextInterface) error {
+			_, err := new(SmartContract).ScheduleTransfer(ctx, assetID, buyerOrg, time.Unix(effectiveTime, 0).UTC().Format(time.RFC3339))
+			return err
+		})
+	}
+	execute := func(org string, transient map[string]string) error {
+		return tx{clientOrg: org, transient: transient}.run(stub, func(ctx ledgerutil.TransactionContextInterface) error {
+			_, err := new(SmartContract).ExecuteScheduledTransfer(ctx, assetID)
+			return err
+		})
+	}
+	cancel := func(org string) error {
+		return tx{clientOrg: org}.run(stub, func(ctx ledgerutil.TransactionContextInterface) error {
+			_, err := new(SmartContract).CancelScheduledTransfer(ctx, assetID)
+			return err
+		})
+	}
+	getScheduled := func() (*ScheduledTransfer, error) {
+		var scheduled *ScheduledTransfer
+		err := tx{clientOrg: buyerOrg}.run(stub, func(ctx ledgerutil.TransactionContextInterface) error {
+			var err error
+			scheduled, err = new(SmartContract).GetScheduledTransfer(ctx, assetID)
+			return err
+		})
+		return scheduled, err
+	}
+
+	// transaction n has timestamp 1600000000+n, the transfer takes effect at transaction 10
+	effective := int64(1600000010)
+	checkResult(t, schedule(buyerOrg, effective), "cannot transfer a asset owned by")
+	checkResult(t, schedule(sellerOrg, 1600000000), "is not after the transaction time")
+	checkResult(t, schedule(sellerOrg, effective), "")
+	scheduled, err := getScheduled()
+	checkResult(t, err, "")
+	if scheduled.SellerOrg != sellerOrg || scheduled.BuyerOrg != buyerOrg || !scheduled.EffectiveTime.Equal(time.Unix(effective, 0)) || scheduled.TxID != "tx6" {
+		t.Errorf("scheduled transfer is %+v", scheduled)
+	}
+
+	err = tx{clientOrg: sellerOrg, transient: transfer}.run(stub, func(ctx ledgerutil.TransactionContextInterface) error {
+		_, err := new(SmartContract).TransferAsset(ctx, assetID, "Org3MSP")
+		return err
+	})
+	checkResult(t, err, "has a transfer to Org2MSP scheduled")
+	checkResult(t, execute(buyerOrg, transfer), "cannot be executed before")
+	checkResult(t, cancel("Org3MSP"), "cannot cancel the transfer of asset asset1")
+	checkResult(t, execute(buyerOrg, map[string]string{"asset_properties": assetProperties, "asset_price": price110}), "of the price the transfer was scheduled at")
+	checkResult(t, cancel(sellerOrg), "can no longer be cancelled")
+	checkResult(t, execute(buyerOrg, transfer), "")
+
+	if owner := readAsset(t, stub).OwnerOrg; owner != buyerOrg {
+		t.Errorf("owner is %s, want %s", owner, buyerOrg)
+	}
+	if got := endorsers(t, stub); !reflect.DeepEqual(got, []string{buyerOrg}) {
+		t.Errorf("asset endorsers are %v, want [%s]", got, buyerOrg)
+	}
+	checkEvent(t, stub, assettypes.EventAssetTransferred, assettypes.AssetEvent{ID: assetID, OwnerOrg: buyerOrg, PreviousOwnerOrg: sellerOrg, PublicDescription: "A new asset for Org1MSP"})
+	_, err = getScheduled()
+	checkResult(t, err, "no transfer of asset asset1 is scheduled")
+}
+
+func TestCancelScheduledTransfer(t *testing.T) {
+	stub := newLedger()
+	createAsset(t, stub)
+	agree(t, stub, price100, price100)
+
+	transfer := map[string]string{"asset_properties": assetProperties, "asset_price": price100}
+	mustRun(t, stub, tx{clientOrg: sellerOrg, transient: transfer}, func(ctx ledgerutil.TransactionContextInterface) error {
+		_, err := new(SmartContract).ScheduleTransfer(ctx, assetID, buyerOrg, "2030-01-01T00:00:00Z")
+		return err
+	})
+	err := tx{clientOrg: buyerOrg}.run(stub, func(ctx ledgerutil.TransactionContextInterface) error {
+		_, err := new(SmartContract).CancelScheduledTransfer(ctx, assetID)
+		return err
+	})
+	checkResult(t, err, "")
+
+	// the agreed prices are kept, so the owner can still transfer the asset
+	err = tx{clientOrg: sellerOrg, transient: transfer}.run(stub, func(ctx ledgerutil.TransactionContextInterface) error {
+		_, err := new(SmartContract).TransferAsset(ctx, assetID, buyerOrg)
+		return err
+	})
+	checkResult(t, err, "")
+	if owner := readAsset(t, stub).OwnerOrg; owner != buyerOrg {
+		t.Errorf("owner is %s, want %s", owner, buyerOrg)
+	}
+}
+
+func TestAssetLock(t *testing.T) {
+	stub := newLedger()
+	createAsset(t, stub)
+	agree(t, stub, price100, price100)
+
+	lock := func(org string) error {
+		return tx{clientOrg: org}.run(stub, func(ctx ledgerutil.TransactionContextInterface) error {
+			_, err := LockAsset(ctx, assetID, "lot1")
+			return err
+		})
+	}
+	settle := func(lockedBy string, properties string) error {
+		return tx{clientOrg: buyerOrg, peerOrg: sellerOrg}.run(stub, func(ctx ledgerutil.TransactionContextInterface) error {
+			_, err := SettleLockedAsset(ctx, assetID, lockedBy, buyerOrg, []byte(properties))
+			return err
+		})
+	}
+	checkResult(t, lock(buyerOrg), "cannot lock an asset owned by Org1MSP")
+	checkResult(t, settle("lot1", assetProperties), "asset asset1 is not locked by lot1")
+	checkResult(t, lock(sellerOrg), "")
+	checkResult(t, lock(sellerOrg), "asset asset1 is locked by lot1")
+
+	var got *AssetLock
+	mustRun(t, stub, tx{clientOrg: buyerOrg}, func(ctx ledgerutil.TransactionContextInterface) error {
+		var err error
+		got, err = new(SmartContract).GetAssetLock(ctx, assetID)
+		return err
+	})
+	if got.LockedBy != "lot1" || got.OwnerOrg != sellerOrg || got.TxID != "tx6" {
+		t.Errorf("asset lock is %+v", got)
+	}
+
+	transfer := map[string]string{"asset_properties": assetProperties, "asset_price": price100}
+	err := tx{clientOrg: sellerOrg, transient: transfer}.run(stub, func(ctx ledgerutil.TransactionContextInterface) error {
+		_, err := new(SmartContract).TransferAsset(ctx, assetID, buyerOrg)
+		return err
+	})
+	checkResult(t, err, "asset asset1 is locked by lot1")
+	err = tx{clientOrg: sellerOrg, transient: transfer}.run(stub, func(ctx ledgerutil.TransactionContextInterface) error {
+		_, err := new(SmartContract).ScheduleTransfer(ctx, assetID, buyerOrg, "2030-01-01T00:00:00Z")
+		return err
+	})
+	checkResult(t, err, "asset asset1 is locked by lot1")
+
+	checkResult(t, settle("lot2", assetProperties), "asset asset1 is not locked by lot2")
+	checkResult(t, settle("lot1", wrongProperties), "does not match on-chain hash")
+	checkResult(t, settle("lot1", assetProperties), "")
+	if owner := readAsset(t, stub).OwnerOrg; owner != buyerOrg {
+		t.Errorf("owner is %s, want %s", owner, buyerOrg)
+	}
+	if got := endorsers(t, stub); !reflect.DeepEqual(got, []string{buyerOrg}) {
+		t.Errorf("asset endorsers are %v, want [%s]", got, buyerOrg)
+	}
+	if properties := string(stub.privateData[_buildClientOrgName(buyerOrg)][assetID]); properties != assetProperties {
+		t.Errorf("buyer's asset properties are %s, want %s", properties, assetProperties)
+	}
+	err = tx{clientOrg: buyerOrg}.run(stub, func(ctx ledgerutil.TransactionContextInterface) error {
+		_, err := new(SmartContract).GetAssetLock(ctx, assetID)
+		return err
+	})
+	checkResult(t, err, "asset asset1 is not locked")
+}
+
+func TestUnlockAsset(t *testing.T) {
+	stub := newLedger()
+	createAsset(t, stub)
+	mustRun(t, stub, tx{clientOrg: sellerOrg}, func(ctx ledgerutil.TransactionContextInterface) error {
+		_, err := LockAsset(ctx, assetID, "lot1")
+		return err
+	})
+	unlock := func(lockedBy string) error {
+		return tx{clientOrg: sellerOrg}.run(stub, func(ctx ledgerutil.TransactionContextInterface) error {
+			return UnlockAsset(ctx, assetID, lockedBy)
+		})
+	}
+	checkResult(t, unlock("lot2"), "asset asset1 is not locked by lot2")
+	checkResult(t, unlock("lot1"), "")
+	checkResult(t, unlock("lot1"), "asset asset1 is not locked by lot1")
+	// the asset can be locked again once unlocked
+	mustRun(t, stub, tx{clientOrg: sellerOrg}, func(ctx ledgerutil.TransactionContextInterface) error {
+		_, err := LockAsset(ctx, assetID, "lot2")
+		return err
+	})
+}
+
+func TestAssetOperator(t *testing.T) {
+	stub := newLedger()
+	createAsset(t, stub)
+	agree(t, stub, price100, price100)
+
+	const brokerOrg = "Org3MSP"
+	broker := "client of " + brokerOrg
+	transfer := map[string]string{"asset_properties": assetProperties, "asset_price": price100}
+	approve := func(org string, singleUse bool) error {
+		return tx{clientOrg: org}.run(stub, func(ctx ledgerutil.TransactionContextInterface) error {
+			_, err := new(SmartContract).ApproveAssetOperator(ctx, assetID, broker, singleUse)
+			return err
+		})
+	}
+	brokerTransfer := func() error {
+		return tx{clientOrg: brokerOrg, peerOrg: sellerOrg, transient: transfer}.run(stub, func(ctx ledgerutil.TransactionContextInterface) error {
+			_, err := new(SmartContract).TransferAsset(ctx, assetID, buyerOrg)
+			return err
+		})
+	}
+	brokerSchedule := func() error {
+		return tx{clientOrg: brokerOrg, peerOrg: sellerOrg, transient: transfer}.run(stub, func(ctx ledgerutil.TransactionContextInterface) error {
+			_, err := new(SmartContract).ScheduleTransfer(ctx, assetID, buyerOrg, "2030-01-01T00:00:00Z")
+			return err
+		})
+	}
+	getOperator := func() (*AssetOperator, error) {
+		var approval *AssetOperator
+		err := tx{clientOrg: buyerOrg}.run(stub, func(ctx ledgerutil.TransactionContextInterface) error {
+			var err error
+			approval, err = new(SmartContract).GetAssetOperator(ctx, assetID)
+			return err
+		})
+		return approval, err
+	}
+	cancelScheduled := func() {
+		mustRun(t, stub, tx{clientOrg: sellerOrg}, func(ctx ledgerutil.TransactionContextInterface) error {
+			_, err := new(SmartContract).CancelScheduledTransfer(ctx, assetID)
+			return err
+		})
+	}
+
+	checkResult(t, brokerTransfer(), "a client from Org3MSP cannot transfer a asset owned by Org1MSP")
+	checkResult(t, approve(buyerOrg, true), "cannot approve an operator of an asset owned by Org1MSP")
+
+	// a single-use approval is consumed by the transfer it authorizes
+	checkResult(t, approve(sellerOrg, true), "")
+	approval, err := getOperator()
+	checkResult(t, err, "")
+	if approval.Operator != broker || !approval.SingleUse || approval.OwnerOrg != sellerOrg {
+		t.Errorf("operator is %+v", approval)
+	}
+	checkResult(t, brokerSchedule(), "")
+	_, err = getOperator()
+	checkResult(t, err, "no operator is approved for asset asset1")
+	cancelScheduled()
+
+	// an approval until revoked authorizes any number of transfers
+	checkResult(t, approve(sellerOrg, false), "")
+	checkResult(t, brokerSchedule(), "")
+	cancelScheduled()
+	_, err = getOperator()
+	checkResult(t, err, "")
+	err = tx{clientOrg: sellerOrg}.run(stub, func(ctx ledgerutil.TransactionContextInterface) error {
+		_, err := new(SmartContract).RevokeAssetOperator(ctx, assetID)
+		return err
+	})
+	checkResult(t, err, "")
+	checkResult(t, brokerTransfer(), "a client from Org3MSP cannot transfer a asset owned by Org1MSP")
+
+	// the approval ends with the transfer to the buyer
+	checkResult(t, approve(sellerOrg, false), "")
+	checkResult(t, brokerTransfer(), "")
+	if owner := readAsset(t, stub).OwnerOrg; owner != buyerOrg {
+		t.Errorf("owner is %s, want %s", owner, buyerOrg)
+	}
+	checkEvent(t, stub, assettypes.EventAssetTransferred, assettypes.AssetEvent{ID: assetID, OwnerOrg: buyerOrg, PreviousOwnerOrg: sellerOrg, PublicDescription: "A new asset for Org1MSP"})
+	_, err = getOperator()
+	checkResult(t, err, "no operator is approved for asset asset1")
+}
+
+func TestAssetTemplates(t *testing.T) {
+	stub := newLedger()
+	const template = `{"templateID":"vehicle","itemType":"VEHICLE","publicDescription":"A fleet vehicle",` +
+		`"requiredAttributes":["vin","make"],"rules":{"vin":{"pattern":"[A-HJ-NPR-Z0-9]{17}"},` +
+		`"fuel":{"oneOf":["PETROL","ELECTRIC"]},"make":{"maxLength":10}},"defaultTags":["fleet"]}`
+	putTemplate := func(templateJSON string) error {
+		return tx{clientOrg: sellerOrg}.run(stub, func(ctx ledgerutil.TransactionContextInterface) error {
+			_, err := new(SmartContract).PutAssetTemplate(ctx, templateJSON)
+			return err
+		})
+	}
+	create := func(overridesJSON string) (*AssetResult, error) {
+		var result *AssetResult
+		err := tx{clientOrg: sellerOrg, transient: map[string]string{"asset_properties": assetProperties}}.run(stub, func(ctx ledgerutil.TransactionContextInterface) error {
+			var err error
+			result, err = new(SmartContract).CreateAssetFromTemplate(ctx, "vehicle", overridesJSON)
+			return err
+		})
+		return result, err
+	}
+
+	checkResult(t, putTemplate(template), "client is not authorized to perform asset.PutAssetTemplate")
+	stub.chaincodes[accessControlName] = accessControl("asset.PutAssetTemplate", "asset.CreateAsset")
+	checkResult(t, putTemplate(`{"templateID":"vehicle","itemType":"VEHICLE","rules":{"vin":{"pattern":"("}}}`), "pattern of vin is not a regular expression")
+	checkResult(t, putTemplate(`{"templateID":"vehicle","itemType":"VEHICLE","required":["vin"]}`), "templateJSON is not an asset template")
+	checkResult(t, putTemplate(template), "")
+
+	_, err := create(`{"assetID":"car1","attributes":{"vin":"1HGCM82633A004352"}}`)
+	checkResult(t, err, "template vehicle requires attribute make")
+	_, err = create(`{"assetID":"car1","attributes":{"vin":"1HGCM82633A00435","make":"Honda"}}`)
+	checkResult(t, err, "attribute vin does not match [A-HJ-NPR-Z0-9]{17}")
+	_, err = create(`{"assetID":"car1","attributes":{"vin":"1HGCM82633A004352","make":"Honda","fuel":"DIESEL"}}`)
+	checkResult(t, err, "fuel must be one of PETROL, ELECTRIC")
+	_, err = create(`{"assetID":"car1","attributes":{"vin":"1HGCM82633A004352","make":"Mercedes-Benz"}}`)
+	checkResult(t, err, "attribute make is longer than 10 characters")
+	_, err = create(`{"assetID":"car1","attributes":{"vin":"1HGCM82633A004352","make":"Honda","color":"red"}}`)
+	checkResult(t, err, "template vehicle has no attribute color")
+
+	result, err := create(`{"assetID":"car1","attributes":{"vin":"1HGCM82633A004352","make":"Honda","fuel":"PETROL"},"tags":["leased","fleet"]}`)
+	checkResult(t, err, "")
+	created := result.Asset
+	if created.ItemType != "VEHICLE" || created.TemplateID != "vehicle" || created.PublicDescription != "A fleet vehicle" || created.OwnerOrg != sellerOrg {
+		t.Errorf("asset is %+v", created)
+	}
+	if strings.Join(created.Tags, ",") != "fleet,leased" || created.Attributes["vin"] != "1HGCM82633A004352" {
+		t.Errorf("asset tags are %v and attributes %v", created.Tags, created.Attributes)
+	}
+	if _, ok := stub.privateData[_buildClientOrgName(sellerOrg)][created.ID]; !ok {
+		t.Errorf("private properties of %s were not stored", created.ID)
+	}
+	_, err = create(`{"assetID":"car1","attributes":{"vin":"1HGCM82633A004352","make":"Honda"}}`)
+	checkResult(t, err, "asset car1 already exists")
+
+	var stored *AssetTemplate
+	mustRun(t, stub, tx{clientOrg: buyerOrg}, func(ctx ledgerutil.TransactionContextInterface) error {
+		var err error
+		stored, err = new(SmartContract).GetAssetTemplate(ctx, " vehicle ")
+		return err
+	})
+	if stored.UpdatedBy != "client of "+sellerOrg || stored.TxID != "tx4" || stored.Rules["vin"].Pattern == "" {
+		t.Errorf("template is %+v", stored)
+	}
+}
+
+func TestAssetExpiry(t *testing.T) {
+	stub := newLedger()
+	createAsset(t, stub)
+	agree(t, stub, price100, price100)
+
+	setExpiry := func(org string, expiresAt int64) (*AssetResult, error) {
+		var result *AssetResult
+		err := tx{clientOrg: org}.run(stub, func(ctx ledgerutil.TransactionContextInterface) error {
+			var err error
+			result, err = new(SmartContract).SetAssetExpiry(ctx, assetID, time.Unix(expiresAt, 0).UTC().Format(time.RFC3339))
+			return err
+		})
+		return result, err
+	}
+	expire := func() []string {
+		var expired []string
+		mustRun(t, stub, tx{clientOrg: "Org3MSP"}, func(ctx ledgerutil.TransactionContextInterface) error {
+			var err error
+			expired, err = new(SmartContract).ExpireAssets(ctx, 10)
+			return err
+		})
+		return expired
+	}
+	activeAssets := func() int {
+		page, err := NewQueryContract().QueryActiveAssetsByOwner(newQueryContext(stub, buyerOrg), sellerOrg, 10, "")
+		checkResult(t, err, "")
+		return len(page.Assets)
+	}
+
+	// transaction n has timestamp 1600000000+n, the asset lapses at transaction 8
+	_, err := setExpiry(buyerOrg, 1600000008)
+	checkResult(t, err, "cannot set the expiry of an asset owned by Org1MSP")
+	_, err = setExpiry(sellerOrg, 1600000000)
+	checkResult(t, err, "is not after the transaction time")
+	result, err := setExpiry(sellerOrg, 1600000008)
+	checkResult(t, err, "")
+	if result.Asset.ExpiresAt != "2020-09-13T12:26:48Z" {
+		t.Errorf("asset expires at %v", result.Asset.ExpiresAt)
+	}
+	if expired := expire(); len(expired) != 0 {
+		t.Errorf("expired %v before the expiry time", expired)
+	}
+	if n := activeAssets(); n != 1 {
+		t.Errorf("%d active assets before the expiry time, want 1", n)
+	}
+
+	err = tx{clientOrg: sellerOrg, transient: map[string]string{"asset_properties": assetProperties, "asset_price": price100}}.run(stub, func(ctx ledgerutil.TransactionContextInterface) error {
+		_, err := new(SmartContract).TransferAsset(ctx, assetID, buyerOrg)
+		return err
+	})
+	checkResult(t, err, "asset asset1 has expired and cannot be sold")
+	if n := activeAssets(); n != 0 {
+		t.Errorf("%d active assets after the expiry time, want none", n)
+	}
+	if asset := readAsset(t, stub); asset.Status != "" || asset.OwnerOrg != sellerOrg {
+		t.Errorf("asset is %+v before ExpireAssets", asset)
+	}
+
+	if expired := expire(); strings.Join(expired, ",") != assetID {
+		t.Errorf("expired %v, want %s", expired, assetID)
+	}
+	if stub.eventName != assettypes.EventAssetsExpired || !strings.Contains(string(stub.eventPayload), `"assetIDs":["asset1"]`) {
+		t.Errorf("event is %s %s", stub.eventName, stub.eventPayload)
+	}
+	if asset := readAsset(t, stub); asset.Status != assettypes.AssetStatusExpired {
+		t.Errorf("asset status is %q, want EXPIRED", asset.Status)
+	}
+	for key := range stub.state {
+		if strings.HasPrefix(key, "\x00"+typeAssetExpiry+"\x00") {
+			t.Errorf("expiry index key %q left after the asset expired", key)
+		}
+	}
+	if expired := expire(); len(expired) != 0 {
+		t.Errorf("expired %v again", expired)
+	}
+	_, err = setExpiry(sellerOrg, 1600000100)
+	checkResult(t, err, "asset asset1 has expired")
+}
+
+func TestContentRefs(t *testing.T) {
+	stub := newLedger()
+	createAsset(t, stub)
+
+	const (
+		photoV1  = "QmYwAPJzv5CZsnA625s3Xf2nemtYgPpHdWEz79ojWnPbdG"
+		photoV2  = "bafybeigdyrzt5sfp7udm7hu76uh7y26nf3efuylqabf3oclgtqy55fbzdi"
+		photoSum = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+		otherSum = "486ea46224d1bb4fb680f34f7c9ad96a8f24ec88be73ea8e5a6c65260e9cb8a7"
+	)
+	setContent := func(org string, cid string, sum string) (*AssetResult, error) {
+		var result *AssetResult
+		err := tx{clientOrg: org}.run(stub, func(ctx ledgerutil.TransactionContextInterface) error {
+			var err error
+			result, err = new(SmartContract).SetContentRef(ctx, assetID, "photo", cid, sum)
+			return err
+		})
+		return result, err
+	}
+	verify := func(cid string, sum string) (*ContentAttestation, error) {
+		var attestation *ContentAttestation
+		err := tx{clientOrg: "Org3MSP"}.run(stub, func(ctx ledgerutil.TransactionContextInterface) error {
+			var err error
+			attestation, err = new(SmartContract).VerifyContentHash(ctx, assetID, cid, sum)
+			return err
+		})
+		return attestation, err
+	}
+
+	_, err := setContent(buyerOrg, photoV1, photoSum)
+	checkResult(t, err, "cannot change the content of an asset owned by Org1MSP")
+	_, err = setContent(sellerOrg, "not-a-cid", photoSum)
+	checkResult(t, err, "is not an IPFS CID")
+	_, err = setContent(sellerOrg, photoV1, "ABC")
+	checkResult(t, err, "sha256 must be 64 lowercase hex digits")
+	result, err := setContent(sellerOrg, photoV1, photoSum)
+	checkResult(t, err, "")
+	if len(result.Asset.Content) != 1 || result.Asset.Content[0].CID != photoV1 || result.Asset.Content[0].TxID != "tx5" {
+		t.Errorf("asset content is %+v", result.Asset.Content)
+	}
+	if stub.eventName != assettypes.EventContentUpdated || !strings.Contains(string(stub.eventPayload), `"cid":"`+photoV1+`"`) || strings.Contains(string(stub.eventPayload), "previousCID") {
+		t.Errorf("event is %s %s", stub.eventName, stub.eventPayload)
+	}
+
+	checkResult(t, func() error { _, err := verify(photoV1, photoSum); return err }(), "client is not authorized to perform asset.VerifyContentHash")
+	stub.chaincodes[accessControlName] = accessControl("asset.VerifyContentHash")
+	attestation, err := verify(photoV1, photoSum)
+	checkResult(t, err, "")
+	if !attestation.Matches || attestation.VerifierOrg != "Org3MSP" {
+		t.Errorf("attestation is %+v, want a match by Org3MSP", attestation)
+	}
+	attestation, err = verify(photoV1, otherSum)
+	checkResult(t, err, "")
+	if attestation.Matches {
+		t.Errorf("attestation of another hash is %+v, want no match", attestation)
+	}
+
+	stub.chaincodes[accessControlName] = accessControl("asset.CreateAsset")
+	_, err = setContent(sellerOrg, photoV2, otherSum)
+	checkResult(t, err, "")
+	if !strings.Contains(string(stub.eventPayload), `"previousCID":"`+photoV1+`"`) {
+		t.Errorf("event payload %s has no previous CID", stub.eventPayload)
+	}
+	stub.chaincodes[accessControlName] = accessControl("asset.VerifyContentHash")
+	_, err = verify(photoV1, photoSum)
+	checkResult(t, err, "asset asset1 does not reference content "+photoV1)
+
+	var attestations []*ContentAttestation
+	mustRun(t, stub, tx{clientOrg: buyerOrg}, func(ctx ledgerutil.TransactionContextInterface) error {
+		var err error
+		attestations, err = new(SmartContract).GetContentAttestations(ctx, assetID, photoV1)
+		return err
+	})
+	if len(attestations) != 2 || !attestations[0].Matches || attestations[1].Matches {
+		t.Errorf("attestations are %+v, want a match then a mismatch", attestations)
+	}
+
+	err = tx{clientOrg: sellerOrg}.run(stub, func(ctx ledgerutil.TransactionContextInterface) error {
+		_, err := new(SmartContract).RemoveContentRef(ctx, assetID, "photo")
+		return err
+	})
+	checkResult(t, err, "")
+	if content := readAsset(t, stub).Content; content != nil {
+		t.Errorf("asset content is %+v after removing it", content)
+	}
+	if !strings.Contains(string(stub.eventPayload), `"previousCID":"`+photoV2+`"`) || strings.Contains(string(stub.eventPayload), `"cid"`) {
+		t.Errorf("event payload %s of the removal", stub.eventPayload)
+	}
+}
+
+func TestValuationHistory(t *testing.T) {
+	stub := newLedger()
+	mustRun(t, stub, tx{clientOrg: sellerOrg, transient: map[string]string{"asset_properties": appraisedProperties}}, func(ctx ledgerutil.TransactionContextInterface) error {
+		_, err := new(SmartContract).CreateAsset(ctx, assetID, "A new asset for Org1MSP")
+		return err
+	})
+
+	appraise := func(org string, appraisal string) error {
+		return tx{clientOrg: org, transient: map[string]string{"appraisal": appraisal}}.run(stub, func(ctx ledgerutil.TransactionContextInterface) error {
+			_, err := new(SmartContract).RecordAppraisal(ctx, assetID)
+			return err
+		})
+	}
+	checkResult(t, appraise(sellerOrg, `{"asset_id":"asset1","value":"1300.00"}`), "")
+	checkResult(t, appraise(buyerOrg, `{"asset_id":"asset1","value":"900"}`), "cannot appraise an asset owned by Org1MSP")
+	checkResult(t, appraise(sellerOrg, `{"asset_id":"asset2","value":"900"}`), "appraisal is for asset")
+	checkResult(t, appraise(sellerOrg, `{"asset_id":"asset1"}`), "appraisal has no value")
+	checkResult(t, appraise(sellerOrg, `{"asset_id":"asset1","value":"-900"}`), "is not a non-negative decimal")
+
+	history := func(org string) ([]*Valuation, error) {
+		var valuations []*Valuation
+		err := tx{clientOrg: org}.run(stub, func(ctx ledgerutil.TransactionContextInterface) error {
+			var err error
+			valuations, err = new(SmartContract).GetValuationHistory(ctx, assetID)
+			return err
+		})
+		return valuations, err
+	}
+	current := func(org string) (*Valuation, error) {
+		var valuation *Valuation
+		err := tx{clientOrg: org}.run(stub, func(ctx ledgerutil.TransactionContextInterface) error {
+			var err error
+			valuation, err = new(SmartContract).GetCurrentValuation(ctx, assetID)
+			return err
+		})
+		return valuation, err
+	}
+
+	valuations, err := history(sellerOrg)
+	checkResult(t, err, "")
+	if len(valuations) != 2 || valuations[0].Value != "1250.75" || valuations[0].TxID != "tx1" || valuations[1].Value != "1300.00" || valuations[1].TxID != "tx2" {
+		t.Fatalf("valuation history is %+v", valuations)
+	}
+	if !valuations[1].Timestamp.Equal(time.Unix(1600000002, 0)) || valuations[1].Appraiser == "" {
+		t.Errorf("appraisal is %+v", valuations[1])
+	}
+	valuation, err := current(sellerOrg)
+	checkResult(t, err, "")
+	if !reflect.DeepEqual(valuation, valuations[1]) {
+		t.Errorf("current valuation is %+v, want %+v", valuation, valuations[1])
+	}
+
+	valuations, err = history(buyerOrg)
+	checkResult(t, err, "")
+	if len(valuations) != 0 {
+		t.Errorf("buyer's valuation history is %+v", valuations)
+	}
+	_, err = current(buyerOrg)
+	checkResult(t, err, "no valuation of asset asset1 in client org's collection")
+
+	// the properties a sale relies on keep the appraised value the asset was created with
+	if got := string(stub.privateData[_buildClientOrgName(sellerOrg)][assetID]); got != appraisedProperties {
+		t.Errorf("private properties are %q after an appraisal", got)
+	}
+}
+
+func TestPersonalData(t *testing.T) {
+	stub := newLedger()
+	personalData := `{"asset_id":"asset1","owner_name":"Alice Smith","owner_contact":"alice@example.com"}`
+	mustRun(t, stub, tx{clientOrg: sellerOrg, transient: map[string]string{"asset_properties": assetProperties, "personal_data": personalData}}, func(ctx ledgerutil.TransactionContextInterface) error {
+		_, err := new(SmartContract).CreateAsset(ctx, assetID, "A new asset for Org1MSP")
+		return err
+	})
+	if strings.Contains(string(stub.state[assetID]), "Alice") || strings.Contains(string(stub.privateData[_buildClientOrgName(sellerOrg)][assetID]), "Alice") {
+		t.Errorf("personal data is in the public asset or its properties")
+	}
+	getPersonalData := func(org string) (*PersonalData, error) {
+		var data *PersonalData
+		err := tx{clientOrg: org}.run(stub, func(ctx ledgerutil.TransactionContextInterface) error {
+			var err error
+			data, err = new(SmartContract).GetPersonalData(ctx, assetID)
+			return err
+		})
+		return data, err
+	}
+	data, err := getPersonalData(sellerOrg)
+	checkResult(t, err, "")
+	if data.OwnerName != "Alice Smith" || data.OwnerContact != "alice@example.com" {
+		t.Errorf("personal data is %+v", data)
+	}
+	_, err = getPersonalData(buyerOrg)
+	checkResult(t, err, "no personal data of asset asset1 in client org's collection")
+
+	setPersonalData := func(org string, personalData string) error {
+		return tx{clientOrg: org, transient: map[string]string{"personal_data": personalData}}.run(stub, func(ctx ledgerutil.TransactionContextInterface) error {
+			_, err := new(SmartContract).SetPersonalData(ctx, assetID)
+			return err
+		})
+	}
+	err = setPersonalData(buyerOrg, `{"asset_id":"asset1","owner_name":"Bob"}`)
+	checkResult(t, err, "cannot record the personal data of an asset owned by Org1MSP")
+	err = setPersonalData(sellerOrg, `{"asset_id":"asset2","owner_name":"Bob"}`)
+	checkResult(t, err, "personal_data is for asset")
+	err = setPersonalData(sellerOrg, `{"asset_id":"asset1"}`)
+	checkResult(t, err, "personal_data has no owner_name")
+	err = setPersonalData(sellerOrg, `{"asset_id":"asset1","owner_name":"Bob Jones"}`)
+	checkResult(t, err, "")
+	data, err = getPersonalData(sellerOrg)
+	checkResult(t, err, "")
+	if data.OwnerName != "Bob Jones" || data.OwnerContact != "" {
+		t.Errorf("personal data is %+v", data)
+	}
+
+	purge := func(ctx ledgerutil.TransactionContextInterface) error {
+		result, err := new(SmartContract).PurgePersonalData(ctx, assetID)
+		if err == nil && (result.AssetID != assetID || result.TxID != stub.txID) {
+			t.Errorf("purge result is %+v", result)
+		}
+		return err
+	}
+	err = tx{clientOrg: sellerOrg}.run(stub, purge)
+	checkResult(t, err, "not authorized to perform asset.PurgePersonalData")
+	stub.chaincodes[accessControlName] = accessControl("asset.PurgePersonalData")
+	err = tx{clientOrg: sellerOrg, peerOrg: buyerOrg}.run(stub, purge)
+	checkResult(t, err, "is not authorized to read or write private data")
+	err = tx{clientOrg: sellerOrg}.run(stub, purge)
+	checkResult(t, err, "")
+	if len(stub.purged) != 1 || stub.eventName != assettypes.EventPersonalDataPurged || string(stub.eventPayload) != `{"assetID":"asset1","org":"Org1MSP","schemaVersion":1}` {
+		t.Errorf("purged %v with event %s %s", stub.purged, stub.eventName, stub.eventPayload)
+	}
+	_, err = getPersonalData(sellerOrg)
+	checkResult(t, err, "no personal data of asset asset1")
+	err = tx{clientOrg: sellerOrg}.run(stub, purge)
+	checkResult(t, err, "no personal data of asset asset1")
+
+	// the properties a sale relies on are left as they were
+	if got := string(stub.privateData[_buildClientOrgName(sellerOrg)][assetID]); got != assetProperties {
+		t.Errorf("private properties are %q after the purge", got)
+	}
+}
+
+func TestReadAsset(t *testing.T) {
+	stub := newLedger()
+	createAsset(t, stub)
+	contract := new(SmartContract)
+
+	asset, err := contract.ReadAsset(newContext(stub, buyerOrg), assetID)
+	checkResult(t, err, "")
+	if asset.ID != assetID || asset.OwnerOrg != sellerOrg {
+		t.Errorf("asset is %+v", asset)
+	}
+
+	_, err = contract.ReadAsset(newContext(stub, buyerOrg), "asset2")
+	checkResult(t, err, "asset2 does not exist")
+}
+
+func TestGetOwnerProfile(t *testing.T) {
+	stub := newLedger()
+	createAsset(t, stub)
+	contract := new(SmartContract)
+
+	profile, err := contract.GetOwnerProfile(newContext(stub, buyerOrg), assetID)
+	checkResult(t, err, "")
+	if !strings.Contains(profile, sellerOrg) {
+		t.Errorf("profile %s is not the owner's", profile)
+	}
+
+	delete(stub.chaincodes, identityRegistryName)
+	_, err = contract.GetOwnerProfile(newContext(stub, buyerOrg), assetID)
+	checkResult(t, err, "failed to get profile for owner org")
+}
+
+func TestWhoAmI(t *testing.T) {
+	identity, err := new(SmartContract).WhoAmI(newContext(newLedger(), buyerOrg, "inspector", "true"))
+	checkResult(t, err, "")
+	if identity.MSPID != buyerOrg || identity.CommonName != "client of "+buyerOrg || !identity.HasOU("client") ||
+		!identity.HasAttribute("inspector", "true") {
+		t.Errorf("identity is %+v", identity)
+	}
+}
+
+func TestGetVersionAndPing(t *testing.T) {
+	stub := newLedger()
+	info, err := NewContract().GetVersion(newContext(stub, buyerOrg))
+	checkResult(t, err, "")
+	if info.Contract != "asset" || info.Version != ledgerutil.Version || info.Commit != ledgerutil.Commit {
+		t.Errorf("build info is %+v", info)
+	}
+
+	var result *ledgerutil.PingResult
+	mustRun(t, stub, tx{clientOrg: buyerOrg}, func(ctx ledgerutil.TransactionContextInterface) error {
+		result, err = NewContract().Ping(ctx)
+		return err
+	})
+	if result.Status != "ok" || result.Contract != "asset" || result.PeerMSPID != buyerOrg || result.SchemaVersion != 1 || result.TxID != stub.txID {
+		t.Errorf("ping result is %+v", result)
+	}
+}
+
+func TestGetAssetPrivateProperties(t *testing.T) {
+	tests := []struct {
+		name    string
+		tx      tx
+		wantErr string
+	}{
+		{
+			name: "owner reads its properties",
+			tx:   tx{clientOrg: sellerOrg},
+		},
+		{
+			name:    "fails for an org without the properties",
+			tx:      tx{clientOrg: buyerOrg},
+			wantErr: "does not exist in client org's collection",
+		},
+		{
+			name:    "fails on a peer of another org",
+			tx:      tx{clientOrg: sellerOrg, peerOrg: buyerOrg},
+			wantErr: "is not authorized to read or write private data",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newLedger()
+			createAsset(t, stub)
+
+			var properties string
+			err := tt.tx.run(stub, func(ctx ledgerutil.TransactionContextInterface) error {
+				var err error
+				properties, err = new(SmartContract).GetAssetPrivateProperties(ctx, assetID)
+				return err
+			})
+			checkResult(t, err, tt.wantErr)
+			if tt.wantErr == "" && properties != assetProperties {
+				t.Errorf("properties are %q, want %q", properties, assetProperties)
+			}
+		})
+	}
+}
+
+func TestGetAssetPrices(t *testing.T) {
+	tests := []struct {
+		name      string
+		clientOrg string
+		query     func(s *SmartContract, ctx ledgerutil.TransactionContextInterface, assetID string) (string, error)
+		want      string
+		wantErr   string
+	}{
+		{
+			name:      "seller reads its asking price",
+			clientOrg: sellerOrg,
+			query:     (*SmartContract).GetAssetSalesPrice,
+			want:      price110,
+		},
+		{
+			name:      "buyer reads its bid",
+			clientOrg: buyerOrg,
+			query:     (*SmartContract).GetAssetBidPrice,
+			want:      price100,
+		},
+		{
+			name:      "buyer has no asking price",
+			clientOrg: buyerOrg,
+			query:     (*SmartContract).GetAssetSalesPrice,
+			wantErr:   "asset price does not exist",
+		},
+		{
+			name:      "seller has no bid",
+			clientOrg: sellerOrg,
+			query:     (*SmartContract).GetAssetBidPrice,
+			wantErr:   "asset price does not exist",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newLedger()
+			createAsset(t, stub)
+			agree(t, stub, price110, price100)
+
+			var price string
+			err := tx{clientOrg: tt.clientOrg}.run(stub, func(ctx ledgerutil.TransactionContextInterface) error {
+				var err error
+				price, err = tt.query(new(SmartContract), ctx, assetID)
+				return err
+			})
+			checkResult(t, err, tt.wantErr)
+			if price != tt.want {
+				t.Errorf("price is %q, want %q", price, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryAssetHistory(t *testing.T) {
+	stub := newLedger()
+	createAsset(t, stub)
+	mustRun(t, stub, tx{clientOrg: sellerOrg}, func(ctx ledgerutil.TransactionContextInterface) error {
+		_, err := new(SmartContract).UpdateAsset(ctx, assetID, "This asset is for sale")
+		return err
+	})
+
+	results, err := new(SmartContract).QueryAssetHistory(newContext(stub, buyerOrg), assetID)
+	checkResult(t, err, "")
+	if len(results) != 2 {
+		t.Fatalf("history has %d records, want 2", len(results))
+	}
+	if results[0].Record.PublicDescription != "A new asset for Org1MSP" || results[1].Record.PublicDescription != "This asset is for sale" {
+		t.Errorf("history records are %+v and %+v", results[0].Record, results[1].Record)
+	}
+	if results[0].TxId == results[1].TxId || !results[0].Timestamp.Before(results[1].Timestamp) {
+		t.Errorf("history records are not separate transactions in order")
+	}
+}
+
+func TestQueryAssetHistoryPage(t *testing.T) {
+	stub := newLedger()
+	createAsset(t, stub)
+	for _, description := range []string{"This asset is for sale", "This asset is sold"} {
+		description := description
+		mustRun(t, stub, tx{clientOrg: sellerOrg}, func(ctx ledgerutil.TransactionContextInterface) error {
+			_, err := new(SmartContract).UpdateAsset(ctx, assetID, description)
+			return err
+		})
+	}
+
+	page, err := new(SmartContract).QueryAssetHistoryPage(newContext(stub, buyerOrg), assetID, 2, "")
+	checkResult(t, err, "")
+	if len(page.History) != 2 || page.Bookmark == "" {
+		t.Fatalf("first page is %+v, want 2 records and a bookmark", page)
+	}
+	page, err = new(SmartContract).QueryAssetHistoryPage(newContext(stub, buyerOrg), assetID, 2, page.Bookmark)
+	checkResult(t, err, "")
+	if len(page.History) != 1 || page.History[0].Record.PublicDescription != "This asset is sold" || page.Bookmark != "" {
+		t.Errorf("last page is %+v, want the last record", page)
+	}
+
+	_, err = new(SmartContract).QueryAssetHistoryPage(newContext(stub, buyerOrg), assetID, 2, "tx0")
+	checkResult(t, err, "bookmark tx0 is not a transaction in the history of asset1")
+	_, err = new(SmartContract).QueryAssetHistoryPage(newContext(stub, buyerOrg), assetID, 101, "")
+	checkResult(t, err, "pageSize must be between 1 and 100")
+}
+
+func TestSetQueryConfig(t *testing.T) {
+	stub := newLedger()
+	createAsset(t, stub)
+	mustRun(t, stub, tx{clientOrg: sellerOrg}, func(ctx ledgerutil.TransactionContextInterface) error {
+		_, err := new(SmartContract).UpdateAsset(ctx, assetID, "This asset is for sale")
+		return err
+	})
+
+	err := tx{clientOrg: sellerOrg}.run(stub, func(ctx ledgerutil.TransactionContextInterface) error {
+		return new(SmartContract).SetQueryConfig(ctx, 1, 1)
+	})
+	checkResult(t, err, "client is not authorized to perform asset.SetQueryConfig")
+
+	stub.chaincodes[accessControlName] = accessControl("asset.SetQueryConfig")
+	mustRun(t, stub, tx{clientOrg: sellerOrg}, func(ctx ledgerutil.TransactionContextInterface) error {
+		return new(SmartContract).SetQueryConfig(ctx, 1, 1)
+	})
+	config, err := new(SmartContract).GetQueryConfig(newContext(stub, buyerOrg))
+	checkResult(t, err, "")
+	if *config != (ledgerutil.QueryConfig{MaxPageSize: 1, MaxResults: 1}) {
+		t.Errorf("query config is %+v", config)
+	}
+
+	_, err = new(SmartContract).QueryAssetHistory(newContext(stub, buyerOrg), assetID)
+	checkResult(t, err, "results truncated at 1, use QueryAssetHistoryPage with a bookmark")
+	if got := ledgerutil.ParseError(err.Error()).Code; got != ledgerutil.CodeResultsTruncated {
+		t.Errorf("error code is %s, want %s", got, ledgerutil.CodeResultsTruncated)
+	}
+	_, err = new(SmartContract).GetAssetsPage(newContext(stub, buyerOrg), 2, "")
+	checkResult(t, err, "pageSize must be between 1 and 1")
+}
+
+func TestGetAssetsPage(t *testing.T) {
+	stub := newLedger()
+	for _, id := range []string{"asset3", "asset1", "asset2"} {
+		id := id
+		mustRun(t, stub, tx{clientOrg: sellerOrg, transient: map[string]string{"asset_properties": assetProperties}}, func(ctx ledgerutil.TransactionContextInterface) error {
+			_, err := new(SmartContract).CreateAsset(ctx, id, "Asset "+id)
+			return err
+		})
+	}
+	auditKey, _ := stub.CreateCompositeKey("audit", []string{"tx1"})
+	stub.state[auditKey] = []byte(`{"txID":"tx1"}`)
+	// a balance and the total supply of a token contract bundled in the same chaincode
+	stub.state["account1"] = []byte("10")
+	stub.state["totalSupply"] = []byte("10")
+
+	var ids []string
+	bookmark := ""
+	for pages := 0; pages == 0 || bookmark != ""; pages++ {
+		if pages == 3 {
+			t.Fatalf("more pages than assets")
+		}
+		page, err := new(SmartContract).GetAssetsPage(newContext(stub, buyerOrg), 2, bookmark)
+		checkResult(t, err, "")
+		for _, asset := range page.Assets {
+			ids = append(ids, asset.ID)
+		}
+		bookmark = page.Bookmark
+	}
+	if strings.Join(ids, ",") != "asset1,asset2,asset3" {
+		t.Errorf("assets are %v, want asset1, asset2 and asset3", ids)
+	}
+
+	_, err := new(SmartContract).GetAssetsPage(newContext(stub, buyerOrg), 0, "")
+	checkResult(t, err, "pageSize must be between 1 and 100")
+}
+
+func TestQueryAssetsByOwner(t *testing.T) {
+	stub := newLedger()
+	for _, id := range []string{"asset3", "asset1", "asset2"} {
+		id := id
+		mustRun(t, stub, tx{clientOrg: sellerOrg, transient: map[string]string{"asset_properties": assetProperties}}, func(ctx ledgerutil.TransactionContextInterface) error {
+			_, err := new(SmartContract).CreateAsset(ctx, id, "Asset "+id)
+			return err
+		})
+	}
+	mustRun(t, stub, tx{clientOrg: buyerOrg, transient: map[string]string{"asset_properties": assetProperties}}, func(ctx ledgerutil.TransactionContextInterface) error {
+		_, err := new(SmartContract).CreateAsset(ctx, "asset0", "Asset of the buyer")
+		return err
+	})
+
+	var ids []string
+	bookmark := ""
+	for pages := 0; pages == 0 || bookmark != ""; pages++ {
+		if pages == 3 {
+			t.Fatalf("more pages than assets")
+		}
+		page, err := NewQueryContract().QueryAssetsByOwner(newQueryContext(stub, buyerOrg), sellerOrg, 2, bookmark)
+		checkResult(t, err, "")
+		for _, asset := range page.Assets {
+			if asset.OwnerOrg != sellerOrg {
+				t.Errorf("asset %s is owned by %s", asset.ID, asset.OwnerOrg)
+			}
+			ids = append(ids, asset.ID)
+		}
+		bookmark = page.Bookmark
+	}
+	if strings.Join(ids, ",") != "asset1,asset2,asset3" {
+		t.Errorf("assets are %v, want asset1, asset2 and asset3", ids)
+	}
+
+	_, err := NewQueryContract().QueryAssetsByOwner(newQueryContext(stub, buyerOrg), "", 10, "")
+	checkResult(t, err, "ownerOrg must not be empty")
+	_, err = NewQueryContract().QueryAssetsByOwner(newQueryContext(stub, buyerOrg), sellerOrg, 101, "")
+	checkResult(t, err, "pageSize must be between 1 and 100")
+}
+
+func TestQueryAssetsByTimeRange(t *testing.T) {
+	stub := newLedger()
+	for _, id := range []string{"asset1", "asset2", "asset3"} {
+		id := id
+		mustRun(t, stub, tx{clientOrg: sellerOrg, transient: map[string]string{"asset_properties": assetProperties}}, func(ctx ledgerutil.TransactionContextInterface) error {
+			_, err := new(SmartContract).CreateAsset(ctx, id, "Asset "+id)
+			return err
+		})
+	}
+	mustRun(t, stub, tx{clientOrg: sellerOrg}, func(ctx ledgerutil.TransactionContextInterface) error {
+		_, err := new(SmartContract).UpdateAsset(ctx, "asset1", "Changed")
+		return err
+	})
+	asset := readAsset(t, stub)
+	if asset.CreatedAt != "2020-09-13T12:26:41Z" || asset.UpdatedAt != "2020-09-13T12:26:44Z" {
+		t.Errorf("asset was created at %v and updated at %v", asset.CreatedAt, asset.UpdatedAt)
+	}
+
+	// transaction n has timestamp 1600000000+n
+	changed := func(from int64, to int64) []string {
+		t.Helper()
+		var ids []string
+		bookmark := ""
+		for pages := 0; pages == 0 || bookmark != ""; pages++ {
+			if pages == 3 {
+				t.Fatalf("more pages than assets")
+			}
+			page, err := NewQueryContract().QueryAssetsByTimeRange(newQueryContext(stub, buyerOrg),
+				time.Unix(from, 0).UTC().Format(time.RFC3339), time.Unix(to, 0).UTC().Format(time.RFC3339), 2, bookmark)
+			checkResult(t, err, "")
+			for _, asset := range page.Assets {
+				ids = append(ids, asset.ID)
+			}
+			bookmark = page.Bookmark
+		}
+		return ids
+	}
+	if ids := strings.Join(changed(1600000002, 1600000004), ","); ids != "asset2,asset3" {
+		t.Errorf("assets changed before the update are %s, want asset2 and asset3", ids)
+	}
+	if ids := strings.Join(changed(1600000000, 1600000005), ","); ids != "asset1,asset2,asset3" {
+		t.Errorf("assets changed in the whole range are %s", ids)
+	}
+	if ids := strings.Join(changed(1600000004, 1600000005), ","); ids != "asset1" {
+		t.Errorf("assets changed by the update are %s, want asset1", ids)
+	}
+
+	_, err := NewQueryContract().QueryAssetsByTimeRange(newQueryContext(stub, buyerOrg), "yesterday", "2020-09-14T00:00:00Z", 10, "")
+	checkResult(t, err, "from must be an RFC 3339 time")
+	_, err = NewQueryContract().QueryAssetsByTimeRange(newQueryContext(stub, buyerOrg), "2020-09-14T00:00:00Z", "2020-09-13T00:00:00Z", 10, "")
+	checkResult(t, err, "is not after from")
+}
+
+func TestRichQueriesOnlyInQueryContract(t *testing.T) {
+	stub := newLedger()
+	createAsset(t, stub)
+
+	// the asset contract's stub fails rich queries, whose results the peer does not validate
+	_, err := NewQueryContract().QueryAssetsByOwner(newKeyedContext(stub, buyerOrg), sellerOrg, 10, "")
+	checkResult(t, err, "rich queries are not validated at commit")
+	if evaluate := strings.Join(NewContract().GetEvaluateTransactions(), ","); strings.Contains(evaluate, "QueryAssets") {
+		t.Errorf("asset contract evaluates %s", evaluate)
+	}
+
+	// the query contract's stub fails writes, even for a client submitting a function
+	err = tx{clientOrg: sellerOrg}.run(stub, func(ledgerutil.TransactionContextInterface) error {
+		_, err := new(SmartContract).UpdateAsset(newQueryContext(stub, sellerOrg), assetID, "Changed")
+		return err
+	})
+	checkResult(t, err, "functions of a query-only contract cannot write to the ledger")
+	if asset := readAsset(t, stub); asset.PublicDescription == "Changed" {
+		t.Errorf("asset was changed from the query contract")
+	}
+	page, err := NewQueryContract().QueryAssetsByOwner(newQueryContext(stub, buyerOrg), sellerOrg, 10, "")
+	checkResult(t, err, "")
+	if len(page.Assets) != 1 {
+		t.Errorf("query contract found %d assets, want 1", len(page.Assets))
+	}
+}
+
+func TestGetAssetReceipts(t *testing.T) {
+	stub := newLedger()
+	createAsset(t, stub)
+	agree(t, stub, price100, price100)
+	transient := map[string]string{"asset_properties": assetProperties, "asset_price": price100}
+	mustRun(t, stub, tx{clientOrg: sellerOrg, transient: transient}, func(ctx ledgerutil.TransactionContextInterface) error {
+		_, err := new(SmartContract).TransferAsset(ctx, assetID, buyerOrg)
+		return err
+	})
+
+	tests := []struct {
+		clientOrg string
+		want      Receipt
+	}{
+		{sellerOrg, Receipt{AssetID: assetID, Type: typeAssetSaleReceipt, Counterparty: buyerOrg, Price: 100}},
+		{buyerOrg, Receipt{AssetID: assetID, Type: typeAssetBuyReceipt, Counterparty: sellerOrg, Price: 100}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.clientOrg, func(t *testing.T) {
+			var receipts []Receipt
+			err := tx{clientOrg: tt.clientOrg}.run(stub, func(ctx ledgerutil.TransactionContextInterface) error {
+				var err error
+				receipts, err = new(SmartContract).GetAssetReceipts(ctx, assetID)
+				return err
+			})
+			checkResult(t, err, "")
+			if len(receipts) != 1 {
+				t.Fatalf("got %d receipts, want 1", len(receipts))
+			}
+			got := receipts[0]
+			if got.Timestamp.IsZero() {
+				t.Errorf("receipt has no timestamp")
+			}
+			got.Timestamp = tt.want.Timestamp
+			if got != tt.want {
+				t.Errorf("receipt is %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProtobufStateCodec(t *testing.T) {
+	stub := newLedger()
+	stub.chaincodes[accessControlName] = accessControl("asset.CreateAsset", "config.Initialize")
+	mustRun(t, stub, tx{clientOrg: sellerOrg}, func(ctx ledgerutil.TransactionContextInterface) error {
+		_, err := NewConfigContract().Initialize(ctx, ledgerutil.CodecProtobuf)
+		return err
+	})
+	createAsset(t, stub)
+	mustRun(t, stub, tx{clientOrg: sellerOrg}, func(ctx ledgerutil.TransactionContextInterface) error {
+		_, err := new(SmartContract).UpdateAsset(ctx, assetID, "This asset is for sale")
+		return err
+	})
+	key, err := _assetKey(stub, assetID)
+	checkResult(t, err, "")
+	if json.Valid(stub.state[key]) {
+		t.Fatalf("asset is not stored in protobuf: %q", stub.state[key])
+	}
+
+	asset, err := new(SmartContract).ReadAsset(newContext(stub, buyerOrg), assetID)
+	checkResult(t, err, "")
+	if asset.ID != assetID || asset.OwnerOrg != sellerOrg || asset.PublicDescription != "This asset is for sale" || asset.CreatedAt == "" || asset.UpdatedAt == "" {
+		t.Errorf("asset is %+v", asset)
+	}
+	history, err := new(SmartContract).QueryAssetHistory(newContext(stub, buyerOrg), assetID)
+	checkResult(t, err, "")
+	if len(history) != 2 || history[0].Record.PublicDescription != "A new asset for Org1MSP" {
+		t.Errorf("history is %+v", history)
+	}
+	// a balance of a token contract bundled in the same chaincode is skipped
+	stub.state["account1"] = []byte("10")
+	page, err := new(SmartContract).GetAssetsPage(newContext(stub, buyerOrg), 10, "")
+	checkResult(t, err, "")
+	if len(page.Assets) != 1 || !reflect.DeepEqual(page.Assets[0], asset) {
+		t.Errorf("assets are %+v, want %+v", page.Assets, asset)
+	}
+	_, err = NewQueryContract().QueryAssetsByOwner(newQueryContext(stub, buyerOrg), sellerOrg, 10, "")
+	checkResult(t, err, "rich queries need the json state codec, the chaincode stores its records in protobuf")
+
+	agree(t, stub, price100, price100)
+	transient := map[string]string{"asset_properties": assetProperties, "asset_price": price100}
+	mustRun(t, stub, tx{clientOrg: sellerOrg, transient: transient}, func(ctx ledgerutil.TransactionContextInterface) error {
+		_, err := new(SmartContract).TransferAsset(ctx, assetID, buyerOrg)
+		return err
+	})
+	var receipts []Receipt
+	mustRun(t, stub, tx{clientOrg: buyerOrg}, func(ctx ledgerutil.TransactionContextInterface) error {
+		receipts, err = new(SmartContract).GetAssetReceipts(ctx, assetID)
+		return err
+	})
+	if len(receipts) != 1 || receipts[0].Counterparty != sellerOrg || receipts[0].Price != 100 || receipts[0].Timestamp.IsZero() {
+		t.Errorf("receipts are %+v", receipts)
+	}
+}
+
+func TestAssetRecordProto(t *testing.T) {
+	timestamp := time.Date(2024, 1, 31, 12, 30, 0, 500, time.UTC)
+	before := ledgerutil.FormatTime(timestamp.AddDate(-60, 0, 0))
+	asset := Asset{
+		ObjectType:        "asset",
+		ID:                assetID,
+		OwnerOrg:          sellerOrg,
+		PublicDescription: "A new asset",
+		Content:           []ContentRef{{Name: "deed", CID: "bafy", SHA256: "ab", UpdatedBy: "x509::alice", TxID: "tx1", Timestamp: timestamp}, {Name: "photo"}},
+		ItemType:          "painting",
+		TemplateID:        "painting-v1",
+		Tags:              []string{"art", ""},
+		Attributes:        map[string]string{"width": "40", "artist": "", "height": "60"},
+		ExpiresAt:         before,
+		Status:            assettypes.AssetStatusExpired,
+		CreatedAt:         ledgerutil.FormatTime(timestamp),
+		UpdatedAt:         ledgerutil.FormatTime(timestamp),
+	}
+	data, err := (*assetRecord)(&asset).MarshalProto()
+	checkResult(t, err, "")
+	again, _ := (*assetRecord)(&asset).MarshalProto()
+	if string(again) != string(data) {
+		t.Errorf("asset encoded to %q and then %q", data, again)
+	}
+	var decoded Asset
+	err = ledgerutil.UnmarshalRecord(assetID, data, (*assetRecord)(&decoded))
+	checkResult(t, err, "")
+	if !reflect.DeepEqual(decoded, asset) {
+		t.Errorf("decoded asset is %+v, want %+v", decoded, asset)
+	}
+
+	err = ledgerutil.UnmarshalRecord(assetID, data[:len(data)-1], (*assetRecord)(&decoded))
+	checkResult(t, err, "failed to unmarshal asset1")
+}
+
+func TestListByCompositeKey(t *testing.T) {
+	stub := newLedger()
+	createAsset(t, stub)
+	agree(t, stub, price100, price100)
+	transient := map[string]string{"asset_properties": assetProperties, "asset_price": price100}
+	mustRun(t, stub, tx{clientOrg: sellerOrg, transient: transient}, func(ctx ledgerutil.TransactionContextInterface) error {
+		_, err := new(SmartContract).TransferAsset(ctx, assetID, buyerOrg)
+		return err
+	})
+	auditKey, _ := stub.CreateCompositeKey(ledgerutil.AuditPrefix, []string{"tx1"})
+	stub.state[auditKey] = []byte(`{"txID":"tx1"}`)
+	stub.chaincodes[accessControlName] = accessControl("asset.ListByCompositeKey")
+
+	var page *ledgerutil.KeyPage
+	err := tx{clientOrg: buyerOrg}.run(stub, func(ctx ledgerutil.TransactionContextInterface) error {
+		var err error
+		page, err = new(SmartContract).ListByCompositeKey(ctx, typeAssetBuyReceipt, []string{assetID}, 10, "")
+		return err
+	})
+	checkResult(t, err, "")
+	if len(page.Entries) != 1 || page.Entries[0].Attributes[0] != assetID || !strings.Contains(page.Entries[0].Value, sellerOrg) || page.Bookmark != "" {
+		t.Errorf("receipts page is %+v, want the buy receipt", page)
+	}
+
+	page, err = new(SmartContract).ListByCompositeKey(newContext(stub, buyerOrg), ledgerutil.AuditPrefix, nil, 10, "")
+	checkResult(t, err, "")
+	want := []ledgerutil.KeyEntry{{Attributes: []string{"tx1"}, Value: `{"txID":"tx1"}`}}
+	if !reflect.DeepEqual(page.Entries, want) {
+		t.Errorf("audit entries are %+v, want %+v", page.Entries, want)
+	}
+
+	_, err = new(SmartContract).ListByCompositeKey(newContext(stub, buyerOrg), "asset", nil, 10, "")
+	checkResult(t, err, "objectType must be one of audit, auditrecord, scheduledtransfer, assetlock, contentattestation, assetoperator, assettemplate, assetexpiry, buyreceipt, salereceipt, S, B, valuation")
+
+	stub.chaincodes[accessControlName] = accessControl()
+	_, err = new(SmartContract).ListByCompositeKey(newContext(stub, buyerOrg), ledgerutil.AuditPrefix, nil, 10, "")
+	checkResult(t, err, "client is not authorized to perform asset.ListByCompositeKey")
+}
+
+func TestErrorCodes(t *testing.T) {
+	tests := []struct {
+		name     string
+		tx       tx
+		fn       func(s *SmartContract, ctx ledgerutil.TransactionContextInterface) error
+		wantCode ledgerutil.Code
+	}{
+		{
+			name: "creating an existing asset",
+			tx:   tx{clientOrg: sellerOrg, transient: map[string]string{"asset_properties": assetProperties}},
+			fn: func(s *SmartContract, ctx ledgerutil.TransactionContextInterface) error {
+				_, err := s.CreateAsset(ctx, assetID, "Again")
+				return err
+			},
+			wantCode: ledgerutil.CodeAssetExists,
+		},
+		{
+			name: "reading a missing asset",
+			tx:   tx{clientOrg: buyerOrg},
+			fn: func(s *SmartContract, ctx ledgerutil.TransactionContextInterface) error {
+				_, err := s.ReadAsset(ctx, "asset2")
+				return err
+			},
+			wantCode: ledgerutil.CodeAssetNotFound,
+		},
+		{
+			name: "updating another org's asset",
+			tx:   tx{clientOrg: buyerOrg},
+			fn: func(s *SmartContract, ctx ledgerutil.TransactionContextInterface) error {
+				_, err := s.UpdateAsset(ctx, assetID, "Mine now")
+				return err
+			},
+			wantCode: ledgerutil.CodeNotAuthorized,
+		},
+		{
+			name: "transferring at a price the buyer did not agree to",
+			tx:   tx{clientOrg: sellerOrg, transient: map[string]string{"asset_properties": assetProperties, "asset_price": price110}},
+			fn: func(s *SmartContract, ctx ledgerutil.TransactionContextInterface) error {
+				_, err := s.TransferAsset(ctx, assetID, buyerOrg)
+				return err
+			},
+			wantCode: ledgerutil.CodeAgreementMismatch,
+		},
+		{
+			name: "transferring without properties",
+			tx:   tx{clientOrg: sellerOrg, transient: map[string]string{"asset_price": price100}},
+			fn: func(s *SmartContract, ctx ledgerutil.TransactionContextInterface) error {
+				_, err := s.TransferAsset(ctx, assetID, buyerOrg)
+				return err
+			},
+			wantCode: ledgerutil.CodeInvalidArgument,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newLedger()
+			createAsset(t, stub)
+			agree(t, stub, price110, price100)
+
+			err := tt.tx.run(stub, func(ctx ledgerutil.TransactionContextInterface) error {
+				return tt.fn(new(SmartContract), ctx)
+			})
+			if err == nil {
+				t.Fatalf("expected error with code %s", tt.wantCode)
+			}
+			// clients only see the message of the error response
+			if got := ledgerutil.ParseError(err.Error()).Code; got != tt.wantCode {
+				t.Errorf("error code is %s, want %s: %v", got, tt.wantCode, err)
+			}
+		})
+	}
+}
+
+func TestValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		tx      tx
+		fn      func(s *SmartContract, ctx ledgerutil.TransactionContextInterface) error
+		wantErr string
+	}{
+		{
+			name: "creating an asset with a tilde in its ID",
+			tx:   tx{clientOrg: sellerOrg, transient: map[string]string{"asset_properties": assetProperties}},
+			fn: func(s *SmartContract, ctx ledgerutil.TransactionContextInterface) error {
+				_, err := s.CreateAsset(ctx, "asset~2", "A new asset")
+				return err
+			},
+			wantErr: "assetID must not contain",
+		},
+		{
+			name: "creating an asset with an oversized description",
+			tx:   tx{clientOrg: sellerOrg, transient: map[string]string{"asset_properties": assetProperties}},
+			fn: func(s *SmartContract, ctx ledgerutil.TransactionContextInterface) error {
+				_, err := s.CreateAsset(ctx, "asset2", strings.Repeat("x", ledgerutil.MaxTextLength+1))
+				return err
+			},
+			wantErr: "publicDescription is longer than",
+		},
+		{
+			name: "creating an asset with empty properties",
+			tx:   tx{clientOrg: sellerOrg, transient: map[string]string{"asset_properties": ""}},
+			fn: func(s *SmartContract, ctx ledgerutil.TransactionContextInterface) error {
+				_, err := s.CreateAsset(ctx, "asset2", "A new asset")
+				return err
+			},
+			wantErr: "asset_properties must be set",
+		},
+		{
+			name: "agreeing to buy an asset with a null byte in its ID",
+			tx:   tx{clientOrg: buyerOrg, transient: map[string]string{"asset_price": price100}},
+			fn: func(s *SmartContract, ctx ledgerutil.TransactionContextInterface) error {
+				_, err := s.AgreeToBuy(ctx, "asset1\x00")
+				return err
+			},
+			wantErr: "assetID must not contain",
+		},
+		{
+			name: "transferring to an empty buyer org",
+			tx:   tx{clientOrg: sellerOrg, transient: map[string]string{"asset_properties": assetProperties, "asset_price": price100}},
+			fn: func(s *SmartContract, ctx ledgerutil.TransactionContextInterface) error {
+				_, err := s.TransferAsset(ctx, assetID, " ")
+				return err
+			},
+			wantErr: "buyerOrgID must be set",
+		},
+		{
+			name: "transferring at a price of 0",
+			tx: tx{clientOrg: sellerOrg, transient: map[string]string{
+				"asset_properties": assetProperties,
+				"asset_price":      `{"asset_id":"asset1","trade_id":"109f4b3c50d7b0df729d299bc6f8e9ef9066971f","price":0}`,
+			}},
+			fn: func(s *SmartContract, ctx ledgerutil.TransactionContextInterface) error {
+				_, err := s.TransferAsset(ctx, assetID, buyerOrg)
+				return err
+			},
+			wantErr: "asset_price price must be a positive integer",
+		},
+		{
+			name: "querying the history of an empty asset ID",
+			tx:   tx{clientOrg: buyerOrg},
+			fn: func(s *SmartContract, ctx ledgerutil.TransactionContextInterface) error {
+				_, err := s.QueryAssetHistory(ctx, "")
+				return err
+			},
+			wantErr: "assetID must be set",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newLedger()
+			createAsset(t, stub)
+
+			err := tt.tx.run(stub, func(ctx ledgerutil.TransactionContextInterface) error {
+				return tt.fn(new(SmartContract), ctx)
+			})
+			checkResult(t, err, tt.wantErr)
+			if got := ledgerutil.ParseError(err.Error()).Code; got != ledgerutil.CodeInvalidArgument {
+				t.Errorf("error code is %s, want %s", got, ledgerutil.CodeInvalidArgument)
+			}
+			if len(stub.state) != 1 {
+				t.Errorf("world state changed by an invalid transaction: %d keys", len(stub.state))
+			}
+		})
+	}
+}
+
+func TestReadAssetNormalizesID(t *testing.T) {
+	stub := newLedger()
+	mustRun(t, stub, tx{clientOrg: sellerOrg, transient: map[string]string{"asset_properties": assetProperties}}, func(ctx ledgerutil.TransactionContextInterface) error {
+		_, err := new(SmartContract).CreateAsset(ctx, " asset1\t", "A new asset for Org1MSP")
+		return err
+	})
+
+	var asset *Asset
+	err := tx{clientOrg: buyerOrg}.run(stub, func(ctx ledgerutil.TransactionContextInterface) error {
+		var err error
+		asset, err = new(SmartContract).ReadAsset(ctx, "asset1 ")
+		return err
+	})
+	checkResult(t, err, "")
+	if asset.ID != assetID {
+		t.Errorf("asset ID is %q, want %q", asset.ID, assetID)
+	}
+}
+
+func TestGetEvaluateTransactions(t *testing.T) {
+	contract := new(SmartContract)
+	contractType := reflect.TypeOf(contract)
+	for _, name := range contract.GetEvaluateTransactions() {
+		if _, ok := contractType.MethodByName(name); !ok {
+			t.Errorf("evaluate transaction %s is not a function of the contract", name)
+		}
+	}
+}
+
+func TestBeforeTransaction(t *testing.T) {
+	before := ledgerutil.BeforeTransaction(new(SmartContract).GetEvaluateTransactions())
+
+	stub := newLedger()
+	stub.startTx(nil)
+	stub.function = "asset:ReadAsset"
+	err := before(newContext(stub, buyerOrg, ledgerutil.ReadOnlyAttribute, "true"))
+	checkResult(t, err, "")
+
+	stub.function = "asset:CreateAsset"
+	err = before(newContext(stub, buyerOrg, ledgerutil.ReadOnlyAttribute, "true"))
+	checkResult(t, err, "cannot submit CreateAsset")
+	if len(stub.state) != 0 {
+		t.Fatalf("read-only client wrote the world state")
+	}
+
+	stub.startTx(nil)
+	stub.function = "TransferAsset"
+	err = before(newContext(stub, sellerOrg))
+	checkResult(t, err, "")
+	auditKey, _ := stub.CreateCompositeKey("audit", []string{stub.txID})
+	var entry ledgerutil.AuditEntry
+	err = json.Unmarshal(stub.state[auditKey], &entry)
+	if err != nil {
+		t.Fatalf("failed to unmarshal audit entry: %v", err)
+	}
+	want := ledgerutil.AuditEntry{
+		TxID:      "tx2",
+		Function:  "TransferAsset",
+		ClientID:  "client of " + sellerOrg,
+		MSPID:     sellerOrg,
+		Timestamp: time.Unix(1600000002, 0).UTC(),
+	}
+	if entry != want {
+		t.Errorf("audit entry is %+v, want %+v", entry, want)
+	}
+}
+
+func TestUnknownTransaction(t *testing.T) {
+	stub := newLedger()
+	stub.startTx(nil)
+	stub.function = "ReadAssets"
+
+	err := ledgerutil.UnknownTransaction(new(SmartContract))(newContext(stub, buyerOrg))
+	checkResult(t, err, "ReadAssets with 0 arguments is not a function of this contract")
+	for _, function := range []string{"CreateAsset(string, string)", "ReadAsset(string)", "TransferAsset(string, string)"} {
+		if !strings.Contains(err.Error(), function) {
+			t.Errorf("error does not list %s: %v", function, err)
+		}
+	}
+	if strings.Contains(err.Error(), "GetEvaluateTransactions") {
+		t.Errorf("error lists a function that is not a transaction: %v", err)
+	}
+}
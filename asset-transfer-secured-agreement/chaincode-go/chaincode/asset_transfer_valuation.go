@@ -0,0 +1,186 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-secured-agreement/assettypes"
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil"
+)
+
+// typeValuation is the prefix of the appraisals of the assets in the implicit collections, keyed by
+// asset ID, time and transaction ID so they list in the order they were made. typeCurrentValuation
+// is the prefix of the latest appraisal of each asset.
+const (
+	typeValuation        = "valuation"
+	typeCurrentValuation = "currentvaluation"
+)
+
+// valuationTimeLayout formats the time of an appraisal in its key, fixed width so the keys sort by time
+const valuationTimeLayout = "2006-01-02T15:04:05.000000000Z"
+
+// Valuation is one appraisal of an asset
+type Valuation = assettypes.Valuation
+
+// RecordAppraisal adds an appraisal of an asset, passed as appraisal in the transient map, e.g.
+// {"asset_id":"asset1","value":"1300.00"}, to the valuation history in the implicit collection of the
+// owner org and makes it the current value. The appraised value of the asset properties is left as
+// it was, so the properties keep the hash a sale relies on. Only clients of the owner org may record
+// an appraisal, on a peer of their org.
+func (s *SmartContract) RecordAppraisal(ctx ledgerutil.TransactionContextInterface, assetID string) (*Valuation, error) {
+	assetID, err := _validateAssetID(assetID)
+	if err != nil {
+		return nil, err
+	}
+	transientMap, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "error getting transient")
+	}
+	appraisalJSON, ok := transientMap["appraisal"]
+	if !ok {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "appraisal key not found in the transient map")
+	}
+	v := ledgerutil.NewValidator()
+	v.Payload("appraisal", appraisalJSON)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	var appraisal Valuation
+	err = json.Unmarshal(appraisalJSON, &appraisal)
+	if err != nil {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "invalid arguments: appraisal is not a JSON object with a decimal string value: %v", err)
+	}
+	if appraisal.AssetID != assetID {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "invalid arguments: appraisal is for asset %q, not %s", appraisal.AssetID, assetID)
+	}
+	if appraisal.Value == "" {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "invalid arguments: appraisal has no value")
+	}
+
+	clientOrgID, err := _getClientOrgID(ctx, true)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to get verified OrgID")
+	}
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to get asset")
+	}
+	err = _requireOwnerOrg(ctx.GetIdentity(), asset.OwnerOrg, "appraise an asset")
+	if err != nil {
+		return nil, err
+	}
+	return _putValuation(ctx, clientOrgID, assetID, appraisal.Value)
+}
+
+// GetValuationHistory returns the appraisals of an asset the client's org recorded while it owned
+// the asset, the earliest first, read from its implicit collection on a peer of its org. It fails
+// with RESULTS_TRUNCATED when there are more than maxResults.
+func (s *SmartContract) GetValuationHistory(ctx ledgerutil.TransactionContextInterface, assetID string) ([]*Valuation, error) {
+	assetID, err := _validateAssetID(assetID)
+	if err != nil {
+		return nil, err
+	}
+	collection, err := getClientImplicitCollectionName(ctx)
+	if err != nil {
+		return nil, err
+	}
+	config, err := ledgerutil.GetQueryConfig(ctx.GetStub())
+	if err != nil {
+		return nil, err
+	}
+
+	valuations := []*Valuation{}
+	err = ledgerutil.ForEachPrivateByPartialCompositeKey(ctx.GetStub(), collection, typeValuation, []string{assetID}, func(_ []string, value []byte) error {
+		if len(valuations) == config.MaxResults {
+			return ledgerutil.Errorf(ledgerutil.CodeResultsTruncated, "results truncated at %d valuations of asset %s", config.MaxResults, assetID)
+		}
+		var valuation Valuation
+		err := json.Unmarshal(value, &valuation)
+		if err != nil {
+			return ledgerutil.Wrap(err, "failed to unmarshal valuation")
+		}
+		valuations = append(valuations, &valuation)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return valuations, nil
+}
+
+// GetCurrentValuation returns the latest appraisal of an asset the client's org recorded, read from
+// its implicit collection on a peer of its org
+func (s *SmartContract) GetCurrentValuation(ctx ledgerutil.TransactionContextInterface, assetID string) (*Valuation, error) {
+	assetID, err := _validateAssetID(assetID)
+	if err != nil {
+		return nil, err
+	}
+	collection, err := getClientImplicitCollectionName(ctx)
+	if err != nil {
+		return nil, err
+	}
+	key, err := _currentValuationKey(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	var valuation Valuation
+	found, err := ledgerutil.ReadPrivateJSON(ctx.GetStub(), collection, key, &valuation)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeNotFound, "no valuation of asset %s in client org's collection", assetID)
+	}
+	return &valuation, nil
+}
+
+// _putValuation records an appraisal of an asset made in this transaction in the implicit collection
+// of the org and makes it the current value, unless the current one was made later
+func _putValuation(ctx ledgerutil.TransactionContextInterface, orgID string, assetID string, value assettypes.Decimal) (*Valuation, error) {
+	stub := ctx.GetStub()
+	txID, timestamp, err := ledgerutil.TxInfo(stub)
+	if err != nil {
+		return nil, err
+	}
+	valuation := &Valuation{AssetID: assetID, Value: value, Appraiser: ctx.GetClientID(), TxID: txID, Timestamp: timestamp}
+
+	collection := _buildClientOrgName(orgID)
+	key, err := stub.CreateCompositeKey(typeValuation, []string{assetID, timestamp.UTC().Format(valuationTimeLayout), txID})
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to create composite key for valuation")
+	}
+	err = ledgerutil.PutPrivateJSON(stub, collection, key, valuation)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to put valuation")
+	}
+
+	currentKey, err := _currentValuationKey(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	var current Valuation
+	found, err := ledgerutil.ReadPrivateJSON(stub, collection, currentKey, &current)
+	if err != nil {
+		return nil, err
+	}
+	if found && current.Timestamp.After(timestamp) {
+		return valuation, nil
+	}
+	err = ledgerutil.PutPrivateJSON(stub, collection, currentKey, valuation)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to put current valuation")
+	}
+	return valuation, nil
+}
+
+// _currentValuationKey returns the key of the current valuation of an asset in an implicit collection
+func _currentValuationKey(ctx ledgerutil.TransactionContextInterface, assetID string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(typeCurrentValuation, []string{assetID})
+	if err != nil {
+		return "", ledgerutil.Wrap(err, "failed to create composite key for current valuation")
+	}
+	return key, nil
+}
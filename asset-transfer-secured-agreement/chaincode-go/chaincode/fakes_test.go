@@ -0,0 +1,463 @@
+package chaincode
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil"
+)
+
+// fakeStub is an in-memory ledger standing in for the peer, with the public world state, every
+// org's private data and the history of each public key. Functions the asset chaincode does not
+// call are left to the embedded interface and panic if called.
+type fakeStub struct {
+	shim.ChaincodeStubInterface
+	txCount     int64
+	txID        string
+	function    string
+	txTimestamp *timestamp.Timestamp
+	transient   map[string][]byte
+	state       map[string][]byte
+	privateData map[string]map[string][]byte
+	history     map[string][]*queryresult.KeyModification
+	// validationParameters are the key-level endorsement policies set on public keys
+	validationParameters map[string][]byte
+	// chaincodes answers InvokeChaincode by chaincode name
+	chaincodes map[string]func(args [][]byte) pb.Response
+	// failures makes the named stub function return the error
+	failures map[string]error
+	// eventName and eventPayload are the event set by the last transaction
+	eventName    string
+	eventPayload []byte
+	// purged are the collection/key of the private data purged
+	purged []string
+}
+
+func newFakeStub() *fakeStub {
+	return &fakeStub{
+		transient:            make(map[string][]byte),
+		state:                make(map[string][]byte),
+		privateData:          make(map[string]map[string][]byte),
+		history:              make(map[string][]*queryresult.KeyModification),
+		validationParameters: make(map[string][]byte),
+		chaincodes:           make(map[string]func(args [][]byte) pb.Response),
+		failures:             make(map[string]error),
+	}
+}
+
+// startTx begins a new transaction with its own ID, a later timestamp and the transient data
+func (s *fakeStub) startTx(transient map[string][]byte) {
+	s.txCount++
+	s.txID = fmt.Sprintf("tx%d", s.txCount)
+	s.txTimestamp = &timestamp.Timestamp{Seconds: 1600000000 + s.txCount}
+	s.transient = transient
+	s.eventName = ""
+	s.eventPayload = nil
+}
+
+func (s *fakeStub) GetTxID() string {
+	return s.txID
+}
+
+func (s *fakeStub) GetFunctionAndParameters() (string, []string) {
+	return s.function, nil
+}
+
+func (s *fakeStub) GetTxTimestamp() (*timestamp.Timestamp, error) {
+	return s.txTimestamp, nil
+}
+
+func (s *fakeStub) GetTransient() (map[string][]byte, error) {
+	if err := s.failures["GetTransient"]; err != nil {
+		return nil, err
+	}
+	return s.transient, nil
+}
+
+func (s *fakeStub) GetState(key string) ([]byte, error) {
+	if err := s.failures["GetState"]; err != nil {
+		return nil, err
+	}
+	return s.state[key], nil
+}
+
+func (s *fakeStub) PutState(key string, value []byte) error {
+	if err := s.failures["PutState"]; err != nil {
+		return err
+	}
+	s.state[key] = value
+	s.history[key] = append(s.history[key], &queryresult.KeyModification{TxId: s.txID, Value: value, Timestamp: s.txTimestamp})
+	return nil
+}
+
+func (s *fakeStub) DelState(key string) error {
+	delete(s.state, key)
+	s.history[key] = append(s.history[key], &queryresult.KeyModification{TxId: s.txID, IsDelete: true, Timestamp: s.txTimestamp})
+	return nil
+}
+
+func (s *fakeStub) SetEvent(name string, payload []byte) error {
+	s.eventName = name
+	s.eventPayload = payload
+	return nil
+}
+
+func (s *fakeStub) SetStateValidationParameter(key string, ep []byte) error {
+	s.validationParameters[key] = ep
+	return nil
+}
+
+func (s *fakeStub) GetHistoryForKey(key string) (shim.HistoryQueryIteratorInterface, error) {
+	return &fakeHistoryIterator{modifications: s.history[key]}, nil
+}
+
+// GetStateByPartialCompositeKey returns the keys of the prefix in key order
+func (s *fakeStub) GetStateByPartialCompositeKey(objectType string, attributes []string) (shim.StateQueryIteratorInterface, error) {
+	prefix, err := s.CreateCompositeKey(objectType, attributes)
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	for key := range s.state {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	iterator := &fakeStateIterator{}
+	for _, key := range keys {
+		iterator.results = append(iterator.results, &queryresult.KV{Key: key, Value: s.state[key]})
+	}
+	return iterator, nil
+}
+
+// GetStateByPartialCompositeKeyWithPagination returns the keys of the prefix from bookmark on. The
+// bookmark of a page is the key after it.
+func (s *fakeStub) GetStateByPartialCompositeKeyWithPagination(objectType string, attributes []string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	prefix, err := s.CreateCompositeKey(objectType, attributes)
+	if err != nil {
+		return nil, nil, err
+	}
+	var keys []string
+	for key := range s.state {
+		if strings.HasPrefix(key, prefix) && key >= bookmark {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	iterator := &fakeStateIterator{}
+	metadata := &pb.QueryResponseMetadata{}
+	for i, key := range keys {
+		if i == int(pageSize) {
+			metadata.Bookmark = key
+			break
+		}
+		iterator.results = append(iterator.results, &queryresult.KV{Key: key, Value: s.state[key]})
+	}
+	metadata.FetchedRecordsCount = int32(len(iterator.results))
+	return iterator, metadata, nil
+}
+
+// GetQueryResultWithPagination runs the selector of a rich query, equalities and string
+// comparisons, over the keys from bookmark on, in key order like
+// GetStateByPartialCompositeKeyWithPagination. Other query operators, sorts and index names are not
+// checked, as CouchDB does that.
+func (s *fakeStub) GetQueryResultWithPagination(query string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	var richQuery struct {
+		Selector map[string]interface{} `json:"selector"`
+	}
+	if err := json.Unmarshal([]byte(query), &richQuery); err != nil {
+		return nil, nil, fmt.Errorf("invalid query: %v", err)
+	}
+	var keys []string
+	for key, value := range s.state {
+		if key < bookmark {
+			continue
+		}
+		var document map[string]interface{}
+		if json.Unmarshal(value, &document) != nil {
+			continue
+		}
+		matches := true
+		for field, want := range richQuery.Selector {
+			if !selectorMatches(document[field], want) {
+				matches = false
+			}
+		}
+		if matches {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	iterator := &fakeStateIterator{}
+	metadata := &pb.QueryResponseMetadata{}
+	for i, key := range keys {
+		if i == int(pageSize) {
+			metadata.Bookmark = key
+			break
+		}
+		iterator.results = append(iterator.results, &queryresult.KV{Key: key, Value: s.state[key]})
+	}
+	metadata.FetchedRecordsCount = int32(len(iterator.results))
+	return iterator, metadata, nil
+}
+
+// selectorMatches checks a field of a document against its selector, a value to equal or the string
+// comparison operators $gt, $gte, $lt and $lte
+func selectorMatches(value interface{}, want interface{}) bool {
+	operators, ok := want.(map[string]interface{})
+	if !ok {
+		return value == want
+	}
+	text, ok := value.(string)
+	if !ok {
+		return false
+	}
+	for operator, bound := range operators {
+		bound := bound.(string)
+		switch {
+		case operator == "$gt" && !(text > bound),
+			operator == "$gte" && !(text >= bound),
+			operator == "$lt" && !(text < bound),
+			operator == "$lte" && !(text <= bound):
+			return false
+		}
+	}
+	return true
+}
+
+// CreateCompositeKey builds keys the same way as the shim, so tests can look them up
+func (s *fakeStub) CreateCompositeKey(objectType string, attributes []string) (string, error) {
+	key := "\x00" + objectType + "\x00"
+	for _, attribute := range attributes {
+		if !utf8.ValidString(attribute) || strings.ContainsRune(attribute, 0) || strings.ContainsRune(attribute, utf8.MaxRune) {
+			return "", fmt.Errorf("not a valid utf8 string without U+0000 and U+10FFFF: [%x]", attribute)
+		}
+		key += attribute + "\x00"
+	}
+	return key, nil
+}
+
+func (s *fakeStub) SplitCompositeKey(compositeKey string) (string, []string, error) {
+	parts := strings.Split(compositeKey, "\x00")
+	if len(parts) < 3 || parts[0] != "" || parts[len(parts)-1] != "" {
+		return "", nil, fmt.Errorf("not a composite key: %q", compositeKey)
+	}
+	return parts[1], parts[2 : len(parts)-1], nil
+}
+
+func (s *fakeStub) GetPrivateData(collection string, key string) ([]byte, error) {
+	if err := s.failures["GetPrivateData"]; err != nil {
+		return nil, err
+	}
+	return s.privateData[collection][key], nil
+}
+
+// GetPrivateDataHash returns the SHA-256 hash every peer of the channel holds for private data
+func (s *fakeStub) GetPrivateDataHash(collection string, key string) ([]byte, error) {
+	value, ok := s.privateData[collection][key]
+	if !ok {
+		return nil, nil
+	}
+	hash := sha256.Sum256(value)
+	return hash[:], nil
+}
+
+func (s *fakeStub) PutPrivateData(collection string, key string, value []byte) error {
+	if err := s.failures["PutPrivateData"]; err != nil {
+		return err
+	}
+	if s.privateData[collection] == nil {
+		s.privateData[collection] = make(map[string][]byte)
+	}
+	s.privateData[collection][key] = value
+	return nil
+}
+
+func (s *fakeStub) DelPrivateData(collection string, key string) error {
+	delete(s.privateData[collection], key)
+	return nil
+}
+
+// PurgePrivateData deletes the key and records it as purged
+func (s *fakeStub) PurgePrivateData(collection string, key string) error {
+	delete(s.privateData[collection], key)
+	s.purged = append(s.purged, collection+"/"+key)
+	return nil
+}
+
+func (s *fakeStub) GetPrivateDataByPartialCompositeKey(collection string, objectType string, attributes []string) (shim.StateQueryIteratorInterface, error) {
+	prefix, err := s.CreateCompositeKey(objectType, attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for key := range s.privateData[collection] {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	iterator := &fakeStateIterator{}
+	for _, key := range keys {
+		iterator.results = append(iterator.results, &queryresult.KV{Key: key, Value: s.privateData[collection][key]})
+	}
+	return iterator, nil
+}
+
+func (s *fakeStub) InvokeChaincode(chaincodeName string, args [][]byte, channel string) pb.Response {
+	chaincode, ok := s.chaincodes[chaincodeName]
+	if !ok {
+		return shim.Error(fmt.Sprintf("chaincode %s not found", chaincodeName))
+	}
+	return chaincode(args)
+}
+
+// fakeStateIterator iterates over the results of a range or partial composite key query
+type fakeStateIterator struct {
+	results []*queryresult.KV
+}
+
+func (i *fakeStateIterator) HasNext() bool {
+	return len(i.results) > 0
+}
+
+func (i *fakeStateIterator) Next() (*queryresult.KV, error) {
+	result := i.results[0]
+	i.results = i.results[1:]
+	return result, nil
+}
+
+func (i *fakeStateIterator) Close() error {
+	return nil
+}
+
+// fakeHistoryIterator iterates over the modifications of a key
+type fakeHistoryIterator struct {
+	modifications []*queryresult.KeyModification
+}
+
+func (i *fakeHistoryIterator) HasNext() bool {
+	return len(i.modifications) > 0
+}
+
+func (i *fakeHistoryIterator) Next() (*queryresult.KeyModification, error) {
+	modification := i.modifications[0]
+	i.modifications = i.modifications[1:]
+	return modification, nil
+}
+
+func (i *fakeHistoryIterator) Close() error {
+	return nil
+}
+
+// fakeClientIdentity is the identity of the client submitting the transaction
+type fakeClientIdentity struct {
+	cid.ClientIdentity
+	id    string
+	mspID string
+	cert  *x509.Certificate
+}
+
+func (c *fakeClientIdentity) GetID() (string, error) {
+	return c.id, nil
+}
+
+func (c *fakeClientIdentity) GetMSPID() (string, error) {
+	return c.mspID, nil
+}
+
+func (c *fakeClientIdentity) GetX509Certificate() (*x509.Certificate, error) {
+	return c.cert, nil
+}
+
+// newCertificate returns a client certificate with the common name and the attributes in the
+// extension Fabric CA puts them in
+func newCertificate(commonName string, attrs map[string]string) *x509.Certificate {
+	value, err := json.Marshal(map[string]map[string]string{"attrs": attrs})
+	if err != nil {
+		panic(err)
+	}
+	return &x509.Certificate{
+		Subject:    pkix.Name{CommonName: commonName, OrganizationalUnit: []string{"client"}},
+		Issuer:     pkix.Name{CommonName: "ca.example.com"},
+		Extensions: []pkix.Extension{{Id: asn1.ObjectIdentifier{1, 2, 3, 4, 5, 6, 7, 8, 1}, Value: value}},
+	}
+}
+
+// newContext returns a transaction context for a client of the given org, resolved as the
+// BeforeTransaction hook does. attrs are the attributes of the client's certificate.
+func newContext(stub *fakeStub, mspID string, attrs ...string) *ledgerutil.TransactionContext {
+	ctx := new(ledgerutil.TransactionContext)
+	setClient(ctx, stub, mspID, attrs...)
+	return ctx
+}
+
+// newKeyedContext is newContext with the context of the asset contract, whose stub fails rich queries
+func newKeyedContext(stub *fakeStub, mspID string, attrs ...string) *ledgerutil.KeyedTransactionContext {
+	ctx := new(ledgerutil.KeyedTransactionContext)
+	setClient(ctx, stub, mspID, attrs...)
+	return ctx
+}
+
+// newQueryContext is newContext with the context of the query contract, whose stub fails writes
+func newQueryContext(stub *fakeStub, mspID string, attrs ...string) *ledgerutil.QueryTransactionContext {
+	ctx := new(ledgerutil.QueryTransactionContext)
+	setClient(ctx, stub, mspID, attrs...)
+	return ctx
+}
+
+// settableContext is the part of a transaction context contractapi sets up before a function
+type settableContext interface {
+	SetStub(stub shim.ChaincodeStubInterface)
+	SetClientIdentity(identity cid.ClientIdentity)
+	ResolveClient() error
+}
+
+// setClient sets the stub and a client of the org with the certificate attributes, given as name and value pairs
+func setClient(ctx settableContext, stub *fakeStub, mspID string, attrs ...string) {
+	attributes := make(map[string]string)
+	for i := 0; i+1 < len(attrs); i += 2 {
+		attributes[attrs[i]] = attrs[i+1]
+	}
+	identity := &fakeClientIdentity{id: "client of " + mspID, mspID: mspID, cert: newCertificate("client of "+mspID, attributes)}
+
+	ctx.SetStub(stub)
+	ctx.SetClientIdentity(identity)
+	err := ctx.ResolveClient()
+	if err != nil {
+		panic(err)
+	}
+}
+
+// accessControl fakes the access-control chaincode, granting the listed operations
+func accessControl(granted ...string) func(args [][]byte) pb.Response {
+	return func(args [][]byte) pb.Response {
+		if string(args[0]) != "CheckAccess" {
+			return shim.Error("unexpected function " + string(args[0]))
+		}
+		for _, operation := range granted {
+			if string(args[1]) == operation {
+				return shim.Success([]byte("true"))
+			}
+		}
+		return shim.Success([]byte("false"))
+	}
+}
@@ -3,8 +3,15 @@ module github.com/hyperledger/fabric-samples/chaincode/tradingMarbles
 go 1.13
 
 require (
-	github.com/golang/protobuf v1.3.2
-	github.com/hyperledger/fabric-chaincode-go v0.0.0-20200128192331-2d899240a7ed
-	github.com/hyperledger/fabric-contract-api-go v1.0.0
-	golang.org/x/tools v0.1.0 // indirect
+	github.com/golang/protobuf v1.5.3
+	github.com/hyperledger/fabric-chaincode-go v0.0.0-20230731094759-d626e9ab09b9
+	github.com/hyperledger/fabric-contract-api-go v1.2.2
+	github.com/hyperledger/fabric-protos-go v0.3.0
+	github.com/hyperledger/fabric-samples/asset-transfer-secured-agreement/assettypes v0.0.0
+	github.com/hyperledger/fabric-samples/internal/ledgerutil v0.0.0
+)
+
+replace (
+	github.com/hyperledger/fabric-samples/asset-transfer-secured-agreement/assettypes => ../assettypes
+	github.com/hyperledger/fabric-samples/internal/ledgerutil => ../../internal/ledgerutil
 )
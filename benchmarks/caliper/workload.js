@@ -0,0 +1,58 @@
+/*
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+'use strict';
+
+const fs = require('fs');
+const path = require('path');
+const { WorkloadModuleBase } = require('@hyperledger/caliper-core');
+
+/**
+ * Sends the transactions of a workload file generated by the benchmarks command. Worker w of W
+ * sends ops w, w+W, w+2W and so on, starting over at its first op when the round has more
+ * transactions than the file.
+ */
+class ReplayWorkload extends WorkloadModuleBase {
+    async initializeWorkloadModule(workerIndex, totalWorkers, roundIndex, roundArguments, sutAdapter, sutContext) {
+        await super.initializeWorkloadModule(workerIndex, totalWorkers, roundIndex, roundArguments, sutAdapter, sutContext);
+        if (!roundArguments.file) {
+            throw new Error(`round ${roundIndex} has no file argument`);
+        }
+        const workload = JSON.parse(fs.readFileSync(path.resolve(__dirname, roundArguments.file), 'utf8'));
+        this.chaincode = roundArguments.chaincode || workload.chaincode;
+        this.ops = workload.ops.filter((op, i) => i % totalWorkers === workerIndex);
+        if (this.ops.length === 0) {
+            throw new Error(`${workload.name} has fewer ops than the ${totalWorkers} workers`);
+        }
+        this.next = 0;
+    }
+
+    async submitTransaction() {
+        const op = this.ops[this.next % this.ops.length];
+        this.next++;
+
+        const request = {
+            contractId: this.chaincode,
+            contractFunction: op.function,
+            contractArguments: op.args,
+            readOnly: Boolean(op.readOnly),
+        };
+        if (op.invoker) {
+            request.invokerIdentity = op.invoker;
+        }
+        if (op.transient) {
+            request.transientMap = {};
+            for (const [key, value] of Object.entries(op.transient)) {
+                request.transientMap[key] = Buffer.from(value);
+            }
+        }
+        await this.sutAdapter.sendRequests(request);
+    }
+}
+
+function createWorkloadModule() {
+    return new ReplayWorkload();
+}
+
+module.exports.createWorkloadModule = createWorkloadModule;
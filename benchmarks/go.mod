@@ -0,0 +1,3 @@
+module github.com/hyperledger/fabric-samples/benchmarks
+
+go 1.18
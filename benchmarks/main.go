@@ -0,0 +1,62 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// benchmarks generates the transactions of the Caliper benchmark rounds of the token and secured
+// agreement asset chaincodes as JSON files, which the Caliper workload module in caliper/ sends.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	outputDir      = flag.String("o", "caliper/data", "directory the workload files are written to")
+	txCount        = flag.Int("n", 1000, "number of transactions of each workload")
+	seed           = flag.Int64("seed", 1, "seed of the random data, the same seed generates the same workloads")
+	identities     = flag.String("identities", "User1", "comma-separated Caliper identities sending the token transactions")
+	accounts       = flag.Int("accounts", 100, "number of accounts receiving transfers and allowances")
+	maxAmount      = flag.Int("max-amount", 10, "largest amount transferred or approved")
+	assets         = flag.Int("assets", 1000, "number of assets created and then updated and read")
+	pageSize       = flag.Int("page-size", 20, "page size of the asset page queries")
+	tokenChaincode = flag.String("token-chaincode", "token_erc20", "name the token chaincode is deployed as")
+	assetChaincode = flag.String("asset-chaincode", "secured", "name the asset chaincode is deployed as")
+)
+
+func main() {
+	flag.Parse()
+	if *txCount <= 0 || *accounts <= 0 || *maxAmount <= 0 || *assets <= 0 || *pageSize <= 0 || *identities == "" {
+		log.Fatal("-n, -accounts, -max-amount, -assets and -page-size must be positive and -identities set")
+	}
+
+	generator := NewGenerator(*seed)
+	generator.Identities = strings.Split(*identities, ",")
+	generator.Accounts = *accounts
+	generator.MaxAmount = *maxAmount
+	generator.Assets = *assets
+	generator.PageSize = *pageSize
+	generator.TokenChaincode = *tokenChaincode
+	generator.AssetChaincode = *assetChaincode
+
+	err := os.MkdirAll(*outputDir, 0755)
+	if err != nil {
+		log.Fatalf("failed to create output directory: %v", err)
+	}
+	for _, workload := range generator.Workloads(*txCount) {
+		data, err := json.Marshal(workload)
+		if err != nil {
+			log.Fatalf("failed to marshal workload %s: %v", workload.Name, err)
+		}
+		path := filepath.Join(*outputDir, workload.Name+".json")
+		err = os.WriteFile(path, data, 0644)
+		if err != nil {
+			log.Fatalf("failed to write %s: %v", path, err)
+		}
+		log.Printf("wrote %d transactions to %s", len(workload.Ops), path)
+	}
+}
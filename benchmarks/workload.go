@@ -0,0 +1,199 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strconv"
+)
+
+// Op is one transaction of a workload, sent as is by the Caliper workload module
+type Op struct {
+	Function  string            `json:"function"`
+	Args      []string          `json:"args"`
+	Transient map[string]string `json:"transient,omitempty"`
+	ReadOnly  bool              `json:"readOnly,omitempty"`
+	// Invoker is the name of the Caliper identity sending the transaction, the identity of the
+	// worker when empty
+	Invoker string `json:"invoker,omitempty"`
+}
+
+// Workload is the list of transactions of a Caliper round. Workers take the ops in turn and start
+// over at the first when the round has more transactions than ops.
+type Workload struct {
+	Name      string `json:"name"`
+	Chaincode string `json:"chaincode"`
+	Ops       []*Op  `json:"ops"`
+}
+
+// assetProperties is the transient asset_properties value of CreateAsset
+type assetProperties struct {
+	ObjectType string `json:"object_type"`
+	ID         string `json:"asset_id"`
+	Color      string `json:"color"`
+	Size       int    `json:"size"`
+	Salt       string `json:"salt"`
+}
+
+var colors = []string{"blue", "red", "green", "yellow", "black", "white"}
+
+// Generator generates the workloads. The same seed and settings give the same workloads, so runs
+// before and after a change send the same transactions.
+type Generator struct {
+	rand *rand.Rand
+	// Identities are the Caliper identities holding tokens, which send the token transactions
+	Identities []string
+	// Accounts is the number of receiver and spender accounts token transactions go to
+	Accounts int
+	// MaxAmount bounds the amounts of transfers and approvals
+	MaxAmount int
+	// Assets is the number of assets created, which the updates and reads pick from
+	Assets int
+	// PageSize is the page size of the asset page queries
+	PageSize       int
+	TokenChaincode string
+	AssetChaincode string
+}
+
+// NewGenerator returns a generator with the default settings
+func NewGenerator(seed int64) *Generator {
+	return &Generator{
+		rand:           rand.New(rand.NewSource(seed)),
+		Identities:     []string{"User1"},
+		Accounts:       100,
+		MaxAmount:      10,
+		Assets:         1000,
+		PageSize:       20,
+		TokenChaincode: "token_erc20",
+		AssetChaincode: "secured",
+	}
+}
+
+// Workloads returns the workloads of n transactions each, in the order of the rounds. TokenMint
+// has one Mint per identity, large enough for the transfers, and AssetCreate creates each asset
+// once.
+func (g *Generator) Workloads(n int) []*Workload {
+	return []*Workload{
+		g.TokenMint(n),
+		g.TokenTransfer(n),
+		g.TokenApprove(n),
+		g.AssetCreate(),
+		g.AssetUpdate(n),
+		g.AssetRead(n),
+		g.AssetPage(n),
+	}
+}
+
+// TokenMint mints tokens for every identity, enough for transfers tokens transfers of at most
+// MaxAmount each
+func (g *Generator) TokenMint(transfers int) *Workload {
+	w := &Workload{Name: "token-mint", Chaincode: g.TokenChaincode}
+	amount := transfers * g.MaxAmount
+	for _, identity := range g.Identities {
+		w.Ops = append(w.Ops, &Op{Function: "Mint", Args: []string{strconv.Itoa(amount)}, Invoker: identity})
+	}
+	return w
+}
+
+// TokenTransfer transfers random amounts from the identities to random accounts
+func (g *Generator) TokenTransfer(n int) *Workload {
+	w := &Workload{Name: "token-transfer", Chaincode: g.TokenChaincode}
+	for i := 0; i < n; i++ {
+		w.Ops = append(w.Ops, &Op{
+			Function: "Transfer",
+			Args:     []string{g.account(), strconv.Itoa(g.amount())},
+			Invoker:  g.identity(i),
+		})
+	}
+	return w
+}
+
+// TokenApprove sets allowances of random amounts from the identities to random accounts
+func (g *Generator) TokenApprove(n int) *Workload {
+	w := &Workload{Name: "token-approve", Chaincode: g.TokenChaincode}
+	for i := 0; i < n; i++ {
+		w.Ops = append(w.Ops, &Op{
+			Function: "Approve",
+			Args:     []string{g.account(), strconv.Itoa(g.amount())},
+			Invoker:  g.identity(i),
+		})
+	}
+	return w
+}
+
+// AssetCreate creates Assets assets with random private properties
+func (g *Generator) AssetCreate() *Workload {
+	w := &Workload{Name: "asset-create", Chaincode: g.AssetChaincode}
+	for i := 0; i < g.Assets; i++ {
+		id := assetID(i)
+		properties, _ := json.Marshal(&assetProperties{
+			ObjectType: "asset_properties",
+			ID:         id,
+			Color:      colors[g.rand.Intn(len(colors))],
+			Size:       1 + g.rand.Intn(100),
+			Salt:       fmt.Sprintf("%016x", g.rand.Uint64()),
+		})
+		w.Ops = append(w.Ops, &Op{
+			Function:  "CreateAsset",
+			Args:      []string{id, "Benchmark asset " + strconv.Itoa(i)},
+			Transient: map[string]string{"asset_properties": string(properties)},
+		})
+	}
+	return w
+}
+
+// AssetUpdate changes the public description of random assets
+func (g *Generator) AssetUpdate(n int) *Workload {
+	w := &Workload{Name: "asset-update", Chaincode: g.AssetChaincode}
+	for i := 0; i < n; i++ {
+		w.Ops = append(w.Ops, &Op{
+			Function: "UpdateAsset",
+			Args:     []string{assetID(g.rand.Intn(g.Assets)), "Updated benchmark asset " + strconv.Itoa(i)},
+		})
+	}
+	return w
+}
+
+// AssetRead reads random assets
+func (g *Generator) AssetRead(n int) *Workload {
+	w := &Workload{Name: "asset-read", Chaincode: g.AssetChaincode}
+	for i := 0; i < n; i++ {
+		w.Ops = append(w.Ops, &Op{Function: "ReadAsset", Args: []string{assetID(g.rand.Intn(g.Assets))}, ReadOnly: true})
+	}
+	return w
+}
+
+// AssetPage reads pages of assets starting at random assets, which are bookmarks of GetAssetsPage
+func (g *Generator) AssetPage(n int) *Workload {
+	w := &Workload{Name: "asset-page", Chaincode: g.AssetChaincode}
+	for i := 0; i < n; i++ {
+		w.Ops = append(w.Ops, &Op{
+			Function: "GetAssetsPage",
+			Args:     []string{strconv.Itoa(g.PageSize), assetID(g.rand.Intn(g.Assets))},
+			ReadOnly: true,
+		})
+	}
+	return w
+}
+
+func (g *Generator) account() string {
+	return fmt.Sprintf("bench-account-%d", g.rand.Intn(g.Accounts))
+}
+
+func (g *Generator) amount() int {
+	return 1 + g.rand.Intn(g.MaxAmount)
+}
+
+// identity spreads the token transactions over the identities in turn
+func (g *Generator) identity(i int) string {
+	return g.Identities[i%len(g.Identities)]
+}
+
+// assetID returns the ID of the ith asset, zero-padded so the key order is the creation order
+func assetID(i int) string {
+	return fmt.Sprintf("bench-asset-%06d", i)
+}
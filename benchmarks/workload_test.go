@@ -0,0 +1,85 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestWorkloadsAreDeterministic(t *testing.T) {
+	first := NewGenerator(7).Workloads(50)
+	second := NewGenerator(7).Workloads(50)
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("workloads of the same seed differ")
+	}
+	other := NewGenerator(8).Workloads(50)
+	if reflect.DeepEqual(first, other) {
+		t.Errorf("workloads of different seeds are the same")
+	}
+}
+
+func TestTokenWorkloads(t *testing.T) {
+	generator := NewGenerator(1)
+	generator.Identities = []string{"User1", "User2"}
+	generator.MaxAmount = 5
+
+	mint := generator.TokenMint(100)
+	if len(mint.Ops) != 2 || mint.Ops[0].Invoker != "User1" || mint.Ops[1].Invoker != "User2" || mint.Ops[0].Args[0] != "500" {
+		t.Errorf("mint ops are %+v and %+v, want 500 for each identity", mint.Ops[0], mint.Ops[1])
+	}
+
+	transfers := generator.TokenTransfer(100)
+	if len(transfers.Ops) != 100 || transfers.Chaincode != "token_erc20" {
+		t.Fatalf("transfer workload has %d ops of %s", len(transfers.Ops), transfers.Chaincode)
+	}
+	for i, op := range transfers.Ops {
+		amount, err := strconv.Atoi(op.Args[1])
+		if op.Function != "Transfer" || err != nil || amount < 1 || amount > 5 {
+			t.Errorf("op %d is %+v", i, op)
+		}
+		if op.Invoker != generator.Identities[i%2] {
+			t.Errorf("op %d is sent by %s", i, op.Invoker)
+		}
+	}
+}
+
+func TestAssetWorkloads(t *testing.T) {
+	generator := NewGenerator(1)
+	generator.Assets = 10
+
+	creates := generator.AssetCreate()
+	if len(creates.Ops) != 10 {
+		t.Fatalf("create workload has %d ops, want 10", len(creates.Ops))
+	}
+	for _, op := range creates.Ops {
+		var properties assetProperties
+		err := json.Unmarshal([]byte(op.Transient["asset_properties"]), &properties)
+		if err != nil || properties.ID != op.Args[0] || properties.ObjectType != "asset_properties" || properties.Salt == "" {
+			t.Errorf("properties of %s are %+v: %v", op.Args[0], properties, err)
+		}
+	}
+
+	created := make(map[string]bool)
+	for _, op := range creates.Ops {
+		created[op.Args[0]] = true
+	}
+	for _, workload := range []*Workload{generator.AssetUpdate(50), generator.AssetRead(50), generator.AssetPage(50)} {
+		for _, op := range workload.Ops {
+			assetID := op.Args[0]
+			if op.Function == "GetAssetsPage" {
+				assetID = op.Args[1]
+			}
+			if !created[assetID] {
+				t.Errorf("%s op %+v is of an asset not created", workload.Name, op)
+			}
+			if op.ReadOnly != (op.Function != "UpdateAsset") {
+				t.Errorf("%s op %+v has readOnly %t", workload.Name, op, op.ReadOnly)
+			}
+		}
+	}
+}
@@ -0,0 +1,23 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"log"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/carbon-credits/chaincode-go/chaincode"
+)
+
+func main() {
+	carbonChaincode, err := contractapi.NewChaincode(&chaincode.SmartContract{})
+	if err != nil {
+		log.Panicf("Error creating carbon-credits chaincode: %v", err)
+	}
+
+	if err := carbonChaincode.Start(); err != nil {
+		log.Panicf("Error starting carbon-credits chaincode: %v", err)
+	}
+}
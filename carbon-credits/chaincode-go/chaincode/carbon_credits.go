@@ -0,0 +1,314 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// This sample assumes Org1 is the registry that verifies projects and issues credits
+const registryMSPID = "Org1MSP"
+
+// object names for prefix
+const (
+	projectPrefix    = "project"
+	balancePrefix    = "balance"
+	supplyPrefix     = "supply"
+	retirementPrefix = "retirement"
+)
+
+// range of vintage years the registry accepts
+const (
+	minVintage = 1990
+	maxVintage = 2100
+)
+
+// SmartContract provides functions for issuing, transferring and retiring carbon credits
+type SmartContract struct {
+	contractapi.Contract
+}
+
+// Project is a verified emission reduction or removal project
+type Project struct {
+	ObjectType  string `json:"objectType"`
+	ID          string `json:"projectID"`
+	Name        string `json:"name"`
+	Methodology string `json:"methodology"`
+	Country     string `json:"country"`
+	Developer   string `json:"developer"`
+}
+
+// VintageSupply tracks issued and retired credits for one project vintage.
+// One credit represents one tonne of CO2 equivalent.
+type VintageSupply struct {
+	ProjectID string `json:"projectID"`
+	Vintage   int    `json:"vintage"`
+	Issued    int    `json:"issued"`
+	Retired   int    `json:"retired"`
+}
+
+// Retirement is the permanent record of credits taken out of circulation
+type Retirement struct {
+	ObjectType  string `json:"objectType"`
+	ID          string `json:"retirementID"`
+	ProjectID   string `json:"projectID"`
+	Vintage     int    `json:"vintage"`
+	Amount      int    `json:"amount"`
+	RetiredBy   string `json:"retiredBy"`
+	Beneficiary string `json:"beneficiary"`
+	Statement   string `json:"statement"`
+}
+
+// event provides an organized struct for emitting credit movements
+type event struct {
+	From      string `json:"from"`
+	To        string `json:"to"`
+	ProjectID string `json:"projectID"`
+	Vintage   int    `json:"vintage"`
+	Value     int    `json:"value"`
+}
+
+// RegisterProject is called by the registry once a project has passed verification
+func (s *SmartContract) RegisterProject(ctx contractapi.TransactionContextInterface, projectID string, name string, methodology string, country string, developer string) error {
+	err := _requireRegistry(ctx)
+	if err != nil {
+		return err
+	}
+
+	if projectID == "" || name == "" || methodology == "" || developer == "" {
+		return fmt.Errorf("project ID, name, methodology and developer must be set")
+	}
+	if len(country) != 2 {
+		return fmt.Errorf("country must be a two letter ISO 3166 code")
+	}
+
+	projectKey, err := ctx.GetStub().CreateCompositeKey(projectPrefix, []string{projectID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	existing, err := ctx.GetStub().GetState(projectKey)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if existing != nil {
+		return fmt.Errorf("the project %s already exists", projectID)
+	}
+
+	project := Project{
+		ObjectType:  projectPrefix,
+		ID:          projectID,
+		Name:        name,
+		Methodology: methodology,
+		Country:     country,
+		Developer:   developer,
+	}
+	projectJSON, err := json.Marshal(project)
+	if err != nil {
+		return fmt.Errorf("failed to marshal project: %v", err)
+	}
+
+	return ctx.GetStub().PutState(projectKey, projectJSON)
+}
+
+// IssueCredits is called by the registry to issue verified credits of a vintage to an account,
+// normally the project developer's client ID
+func (s *SmartContract) IssueCredits(ctx contractapi.TransactionContextInterface, projectID string, vintage int, amount int, account string) error {
+	err := _requireRegistry(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.GetProject(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	err = _validateVintage(vintage)
+	if err != nil {
+		return err
+	}
+	if amount <= 0 {
+		return fmt.Errorf("issue amount must be a positive integer")
+	}
+	if account == "" {
+		return fmt.Errorf("account must be set")
+	}
+
+	supply, err := s.GetVintageSupply(ctx, projectID, vintage)
+	if err != nil {
+		return err
+	}
+	supply.Issued += amount
+	err = _putSupply(ctx, supply)
+	if err != nil {
+		return err
+	}
+
+	err = _addBalance(ctx, account, projectID, vintage, amount)
+	if err != nil {
+		return err
+	}
+
+	return _emitEvent(ctx, "Transfer", event{"0x0", account, projectID, vintage, amount})
+}
+
+// Transfer moves credits of one project vintage from the client's account to the receiver
+func (s *SmartContract) Transfer(ctx contractapi.TransactionContextInterface, receiver string, projectID string, vintage int, amount int) error {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	if receiver == "" || receiver == clientID {
+		return fmt.Errorf("receiver must be set and differ from the sender")
+	}
+	if amount <= 0 {
+		return fmt.Errorf("transfer amount must be a positive integer")
+	}
+
+	err = _addBalance(ctx, clientID, projectID, vintage, -amount)
+	if err != nil {
+		return err
+	}
+	err = _addBalance(ctx, receiver, projectID, vintage, amount)
+	if err != nil {
+		return err
+	}
+
+	return _emitEvent(ctx, "Transfer", event{clientID, receiver, projectID, vintage, amount})
+}
+
+// Retire permanently burns credits from the client's account on behalf of a beneficiary.
+// The statement records the claim being offset, e.g. "2023 scope 1 emissions of Example Ltd".
+func (s *SmartContract) Retire(ctx contractapi.TransactionContextInterface, projectID string, vintage int, amount int, beneficiary string, statement string) error {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	if amount <= 0 {
+		return fmt.Errorf("retire amount must be a positive integer")
+	}
+	if beneficiary == "" || statement == "" {
+		return fmt.Errorf("beneficiary and retirement statement must be set")
+	}
+
+	err = _addBalance(ctx, clientID, projectID, vintage, -amount)
+	if err != nil {
+		return err
+	}
+
+	supply, err := s.GetVintageSupply(ctx, projectID, vintage)
+	if err != nil {
+		return err
+	}
+	supply.Retired += amount
+	err = _putSupply(ctx, supply)
+	if err != nil {
+		return err
+	}
+
+	retirement := Retirement{
+		ObjectType:  retirementPrefix,
+		ID:          ctx.GetStub().GetTxID(),
+		ProjectID:   projectID,
+		Vintage:     vintage,
+		Amount:      amount,
+		RetiredBy:   clientID,
+		Beneficiary: beneficiary,
+		Statement:   statement,
+	}
+	retirementJSON, err := json.Marshal(retirement)
+	if err != nil {
+		return fmt.Errorf("failed to marshal retirement: %v", err)
+	}
+	retirementKey, err := ctx.GetStub().CreateCompositeKey(retirementPrefix, []string{projectID, strconv.Itoa(vintage), retirement.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = ctx.GetStub().PutState(retirementKey, retirementJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put retirement: %v", err)
+	}
+
+	return _emitEvent(ctx, "Retire", event{clientID, "0x0", projectID, vintage, amount})
+}
+
+// _requireRegistry checks the client belongs to the registry org
+func _requireRegistry(ctx contractapi.TransactionContextInterface) error {
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+	if clientMSPID != registryMSPID {
+		return fmt.Errorf("client from %s is not authorized to act as the registry", clientMSPID)
+	}
+	return nil
+}
+
+// _validateVintage checks the vintage is a plausible year
+func _validateVintage(vintage int) error {
+	if vintage < minVintage || vintage > maxVintage {
+		return fmt.Errorf("vintage %d is not a year between %d and %d", vintage, minVintage, maxVintage)
+	}
+	return nil
+}
+
+// _addBalance adds delta (which may be negative) to an account's holding of a project vintage
+func _addBalance(ctx contractapi.TransactionContextInterface, account string, projectID string, vintage int, delta int) error {
+	balanceKey, err := ctx.GetStub().CreateCompositeKey(balancePrefix, []string{account, projectID, strconv.Itoa(vintage)})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	balanceBytes, err := ctx.GetStub().GetState(balanceKey)
+	if err != nil {
+		return fmt.Errorf("failed to read balance from world state: %v", err)
+	}
+
+	var balance int
+	if balanceBytes != nil {
+		balance, _ = strconv.Atoi(string(balanceBytes)) // Error handling not needed since Itoa() was used when setting the balance
+	}
+
+	updatedBalance := balance + delta
+	if updatedBalance < 0 {
+		return fmt.Errorf("account %s holds %d credits of %s vintage %d, %d needed", account, balance, projectID, vintage, -delta)
+	}
+	if updatedBalance == 0 {
+		return ctx.GetStub().DelState(balanceKey)
+	}
+
+	return ctx.GetStub().PutState(balanceKey, []byte(strconv.Itoa(updatedBalance)))
+}
+
+// _putSupply writes the issued and retired totals of a project vintage
+func _putSupply(ctx contractapi.TransactionContextInterface, supply *VintageSupply) error {
+	supplyKey, err := ctx.GetStub().CreateCompositeKey(supplyPrefix, []string{supply.ProjectID, strconv.Itoa(supply.Vintage)})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	supplyJSON, err := json.Marshal(supply)
+	if err != nil {
+		return fmt.Errorf("failed to marshal supply: %v", err)
+	}
+	return ctx.GetStub().PutState(supplyKey, supplyJSON)
+}
+
+// _emitEvent marshals the payload and sets it as the chaincode event
+func _emitEvent(ctx contractapi.TransactionContextInterface, name string, payload event) error {
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	err = ctx.GetStub().SetEvent(name, payloadJSON)
+	if err != nil {
+		return fmt.Errorf("failed to set event: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Holding is an account's balance of one project vintage
+type Holding struct {
+	ProjectID string `json:"projectID"`
+	Vintage   int    `json:"vintage"`
+	Balance   int    `json:"balance"`
+}
+
+// GetProject returns the registered project with the given ID
+func (s *SmartContract) GetProject(ctx contractapi.TransactionContextInterface, projectID string) (*Project, error) {
+	projectKey, err := ctx.GetStub().CreateCompositeKey(projectPrefix, []string{projectID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	projectJSON, err := ctx.GetStub().GetState(projectKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if projectJSON == nil {
+		return nil, fmt.Errorf("the project %s does not exist", projectID)
+	}
+
+	var project Project
+	err = json.Unmarshal(projectJSON, &project)
+	if err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+// GetVintageSupply returns the issued and retired totals of a project vintage
+func (s *SmartContract) GetVintageSupply(ctx contractapi.TransactionContextInterface, projectID string, vintage int) (*VintageSupply, error) {
+	supplyKey, err := ctx.GetStub().CreateCompositeKey(supplyPrefix, []string{projectID, strconv.Itoa(vintage)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	supplyJSON, err := ctx.GetStub().GetState(supplyKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if supplyJSON == nil {
+		return &VintageSupply{ProjectID: projectID, Vintage: vintage}, nil
+	}
+
+	var supply VintageSupply
+	err = json.Unmarshal(supplyJSON, &supply)
+	if err != nil {
+		return nil, err
+	}
+	return &supply, nil
+}
+
+// GetVintagesByProject returns the supply of every vintage issued for a project
+func (s *SmartContract) GetVintagesByProject(ctx contractapi.TransactionContextInterface, projectID string) ([]*VintageSupply, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(supplyPrefix, []string{projectID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vintages of project %s: %v", projectID, err)
+	}
+	defer resultsIterator.Close()
+
+	var vintages []*VintageSupply
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var supply VintageSupply
+		err = json.Unmarshal(response.Value, &supply)
+		if err != nil {
+			return nil, err
+		}
+		vintages = append(vintages, &supply)
+	}
+
+	return vintages, nil
+}
+
+// BalanceOf returns the number of credits of a project vintage held by an account
+func (s *SmartContract) BalanceOf(ctx contractapi.TransactionContextInterface, account string, projectID string, vintage int) (int, error) {
+	balanceKey, err := ctx.GetStub().CreateCompositeKey(balancePrefix, []string{account, projectID, strconv.Itoa(vintage)})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	balanceBytes, err := ctx.GetStub().GetState(balanceKey)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read balance from world state: %v", err)
+	}
+	if balanceBytes == nil {
+		return 0, nil
+	}
+
+	balance, _ := strconv.Atoi(string(balanceBytes))
+	return balance, nil
+}
+
+// GetHoldings returns every project vintage an account holds credits of
+func (s *SmartContract) GetHoldings(ctx contractapi.TransactionContextInterface, account string) ([]*Holding, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(balancePrefix, []string{account})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get holdings of %s: %v", account, err)
+	}
+	defer resultsIterator.Close()
+
+	var holdings []*Holding
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(response.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split composite key: %v", err)
+		}
+		if len(keyParts) != 3 {
+			return nil, fmt.Errorf("unexpected balance key %s", response.Key)
+		}
+		vintage, err := strconv.Atoi(keyParts[2])
+		if err != nil {
+			return nil, fmt.Errorf("unexpected vintage in balance key %s", response.Key)
+		}
+		balance, _ := strconv.Atoi(string(response.Value))
+
+		holdings = append(holdings, &Holding{keyParts[1], vintage, balance})
+	}
+
+	return holdings, nil
+}
+
+// GetRetirements returns the retirements recorded against a project. A vintage of 0 returns
+// the retirements of every vintage.
+func (s *SmartContract) GetRetirements(ctx contractapi.TransactionContextInterface, projectID string, vintage int) ([]*Retirement, error) {
+	keys := []string{projectID}
+	if vintage != 0 {
+		keys = append(keys, strconv.Itoa(vintage))
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(retirementPrefix, keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get retirements of project %s: %v", projectID, err)
+	}
+	defer resultsIterator.Close()
+
+	var retirements []*Retirement
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var retirement Retirement
+		err = json.Unmarshal(response.Value, &retirement)
+		if err != nil {
+			return nil, err
+		}
+		retirements = append(retirements, &retirement)
+	}
+
+	return retirements, nil
+}
@@ -0,0 +1,136 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const developerMSPID = "Org2MSP"
+
+func checkError(t *testing.T, err error, expected string) {
+	t.Helper()
+	if expected == "" && err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected != "" && (err == nil || err.Error() != expected) {
+		t.Fatalf("expected error %q, got %v", expected, err)
+	}
+}
+
+func checkBalance(t *testing.T, stub *fakeStub, account string, expected int) {
+	t.Helper()
+	balance, err := new(SmartContract).BalanceOf(newContext(stub, "registry", registryMSPID), account, "project1", 2020)
+	if err != nil {
+		t.Fatalf("failed to read balance: %v", err)
+	}
+	if balance != expected {
+		t.Fatalf("expected %s to hold %d credits, got %d", account, expected, balance)
+	}
+}
+
+// issueCredits registers project1 and issues 1000 credits of vintage 2020 to the developer
+func issueCredits(t *testing.T, stub *fakeStub) {
+	t.Helper()
+	contract := new(SmartContract)
+	checkError(t, contract.RegisterProject(newContext(stub, "registry", registryMSPID), "project1", "Mangrove restoration", "VM0033", "ID", "developer"), "")
+	checkError(t, contract.IssueCredits(newContext(stub, "registry", registryMSPID), "project1", 2020, 1000, "developer"), "")
+}
+
+func TestIssueCredits(t *testing.T) {
+	tests := []struct {
+		name     string
+		mspID    string
+		vintage  int
+		amount   int
+		expected string
+	}{
+		{"registry", registryMSPID, 2020, 500, ""},
+		{"not registry", developerMSPID, 2020, 500, "client from Org2MSP is not authorized to act as the registry"},
+		{"bad vintage", registryMSPID, 1900, 500, "vintage 1900 is not a year between 1990 and 2100"},
+		{"no amount", registryMSPID, 2020, 0, "issue amount must be a positive integer"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stub := newFakeStub()
+			issueCredits(t, stub)
+
+			err := new(SmartContract).IssueCredits(newContext(stub, "registry", test.mspID), "project1", test.vintage, test.amount, "developer")
+			checkError(t, err, test.expected)
+			if test.expected == "" {
+				checkBalance(t, stub, "developer", 1500)
+			}
+		})
+	}
+}
+
+func TestTransfer(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	issueCredits(t, stub)
+
+	checkError(t, contract.Transfer(newContext(stub, "developer", developerMSPID), "airline", "project1", 2020, 400), "")
+	checkBalance(t, stub, "developer", 600)
+	checkBalance(t, stub, "airline", 400)
+
+	var transfer event
+	err := json.Unmarshal(stub.eventValue, &transfer)
+	if err != nil {
+		t.Fatalf("failed to unmarshal event: %v", err)
+	}
+	if stub.eventName != "Transfer" || transfer != (event{"developer", "airline", "project1", 2020, 400}) {
+		t.Fatalf("unexpected %s event %+v", stub.eventName, transfer)
+	}
+
+	err = contract.Transfer(newContext(stub, "airline", developerMSPID), "developer", "project1", 2020, 401)
+	checkError(t, err, "account airline holds 400 credits of project1 vintage 2020, 401 needed")
+	checkBalance(t, stub, "airline", 400)
+
+	err = contract.Transfer(newContext(stub, "airline", developerMSPID), "developer", "project1", 2021, 1)
+	checkError(t, err, "account airline holds 0 credits of project1 vintage 2021, 1 needed")
+
+	err = contract.Transfer(newContext(stub, "airline", developerMSPID), "airline", "project1", 2020, 1)
+	checkError(t, err, "receiver must be set and differ from the sender")
+}
+
+func TestRetire(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	issueCredits(t, stub)
+
+	err := contract.Retire(newContext(stub, "airline", developerMSPID), "project1", 2020, 100, "Example Air", "2020 scope 1 emissions")
+	checkError(t, err, "account airline holds 0 credits of project1 vintage 2020, 100 needed")
+
+	checkError(t, contract.Retire(newContext(stub, "developer", developerMSPID), "project1", 2020, 1000, "Example Air", "2020 scope 1 emissions"), "")
+	checkBalance(t, stub, "developer", 0)
+	if _, ok := stub.state["\x00balance\x00developer\x00project1\x002020\x00"]; ok {
+		t.Fatalf("expected an empty balance to be deleted")
+	}
+
+	supply, err := contract.GetVintageSupply(newContext(stub, "registry", registryMSPID), "project1", 2020)
+	checkError(t, err, "")
+	if supply.Issued != 1000 || supply.Retired != 1000 {
+		t.Fatalf("unexpected supply %+v", supply)
+	}
+
+	retirements, err := contract.GetRetirements(newContext(stub, "registry", registryMSPID), "project1", 0)
+	checkError(t, err, "")
+	if len(retirements) != 1 || retirements[0].RetiredBy != "developer" || retirements[0].Amount != 1000 {
+		t.Fatalf("unexpected retirements %+v", retirements)
+	}
+	if stub.eventName != "Retire" {
+		t.Fatalf("expected Retire event, got %q", stub.eventName)
+	}
+}
+
+func TestGetHoldings(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	issueCredits(t, stub)
+	checkError(t, contract.IssueCredits(newContext(stub, "registry", registryMSPID), "project1", 2021, 50, "developer"), "")
+
+	holdings, err := contract.GetHoldings(newContext(stub, "registry", registryMSPID), "developer")
+	checkError(t, err, "")
+	if len(holdings) != 2 || *holdings[0] != (Holding{"project1", 2020, 1000}) || *holdings[1] != (Holding{"project1", 2021, 50}) {
+		t.Fatalf("unexpected holdings %+v", holdings)
+	}
+}
@@ -0,0 +1,23 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"log"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/cbdc/chaincode-go/chaincode"
+)
+
+func main() {
+	cbdcChaincode, err := contractapi.NewChaincode(&chaincode.SmartContract{})
+	if err != nil {
+		log.Panicf("Error creating cbdc chaincode: %v", err)
+	}
+
+	if err := cbdcChaincode.Start(); err != nil {
+		log.Panicf("Error starting cbdc chaincode: %v", err)
+	}
+}
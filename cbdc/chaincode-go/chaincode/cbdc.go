@@ -0,0 +1,825 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil"
+)
+
+// This sample assumes Org1 is the central bank that issues the currency and Org2 the regulator
+const (
+	centralBankMSPID = "Org1MSP"
+	regulatorMSPID   = "Org2MSP"
+)
+
+// flowsCollection is the private data collection defined in collections_config.json. Only the
+// regulator's peers store it.
+const flowsCollection = "regulatorFlowsCollection"
+
+// object names for prefix
+const (
+	intermediaryPrefix = "intermediary"
+	walletPrefix       = "wallet"
+	spentPrefix        = "spent"
+	voucherPrefix      = "voucher"
+	flowPrefix         = "flow"
+)
+
+// voucher status values
+const (
+	voucherIssued    = "ISSUED"
+	voucherRedeemed  = "REDEEMED"
+	voucherReclaimed = "RECLAIMED"
+)
+
+// flow kinds reported to the regulator
+const (
+	flowDistribute = "DISTRIBUTE"
+	flowCashOut    = "CASH_OUT"
+	flowPayment    = "PAYMENT"
+	flowVoucher    = "VOUCHER"
+)
+
+// dateLayout is the layout of the day daily limits and flows are counted in
+const dateLayout = "2006-01-02"
+
+// Tier holds the limits of a wallet at a KYC level. Wallets opened with little identification
+// get a low tier; the intermediary raises the tier as the customer provides more.
+type Tier struct {
+	Level      int `json:"level"`
+	MaxBalance int `json:"maxBalance"`
+	DailyLimit int `json:"dailyLimit"`
+}
+
+// tiers are the KYC levels a wallet can be opened at
+var tiers = []Tier{
+	{Level: 1, MaxBalance: 1000, DailyLimit: 250},
+	{Level: 2, MaxBalance: 10000, DailyLimit: 2500},
+	{Level: 3, MaxBalance: 100000, DailyLimit: 25000},
+}
+
+// SmartContract provides functions for issuing, distributing and paying with central bank money
+type SmartContract struct {
+	contractapi.Contract
+}
+
+// Intermediary is an org the central bank issues currency to, which it distributes to the
+// wallets of its customers
+type Intermediary struct {
+	ObjectType string `json:"objectType"`
+	MSPID      string `json:"mspID"`
+	Reserve    int    `json:"reserve"`
+	Issued     int    `json:"issued"`
+	Active     bool   `json:"active"`
+}
+
+// Wallet is a retail wallet opened by an intermediary for a client ID
+type Wallet struct {
+	ObjectType   string `json:"objectType"`
+	Owner        string `json:"owner"`
+	Intermediary string `json:"intermediary"`
+	Tier         int    `json:"tier"`
+	Balance      int    `json:"balance"`
+	OpenedAt     string `json:"openedAt"`
+}
+
+// Voucher is an offline payment. The payer locks the amount while online and hands the voucher ID
+// and the secret to the payee, who redeems it once back online. HashLock is the hex SHA-256 of the secret.
+type Voucher struct {
+	ObjectType string `json:"objectType"`
+	ID         string `json:"voucherID"`
+	Payer      string `json:"payer"`
+	Amount     int    `json:"amount"`
+	HashLock   string `json:"hashLock"`
+	Expiry     string `json:"expiry"`
+	Status     string `json:"status"`
+	Payee      string `json:"payee"`
+}
+
+// Flow is the record of a movement kept for the regulator. It names intermediaries and tiers but no
+// wallet owners.
+type Flow struct {
+	Date             string `json:"date"`
+	Kind             string `json:"kind"`
+	FromIntermediary string `json:"fromIntermediary"`
+	FromTier         int    `json:"fromTier"`
+	ToIntermediary   string `json:"toIntermediary"`
+	ToTier           int    `json:"toTier"`
+	Amount           int    `json:"amount"`
+}
+
+// event provides an organized struct for emitting currency movements
+type event struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Amount int    `json:"amount"`
+}
+
+// AdmitIntermediary is called by the central bank to let an org distribute currency
+func (s *SmartContract) AdmitIntermediary(ctx contractapi.TransactionContextInterface, mspID string) error {
+	err := _requireOrg(ctx, centralBankMSPID, "admit intermediaries")
+	if err != nil {
+		return err
+	}
+	if mspID == "" {
+		return fmt.Errorf("intermediary MSPID must be set")
+	}
+
+	intermediary, err := _getIntermediary(ctx, mspID)
+	if err != nil {
+		return err
+	}
+	if intermediary == nil {
+		intermediary = &Intermediary{
+			ObjectType: intermediaryPrefix,
+			MSPID:      mspID,
+		}
+	}
+	intermediary.Active = true
+	return _putIntermediary(ctx, intermediary)
+}
+
+// SuspendIntermediary is called by the central bank to stop an intermediary receiving new issuance
+// and opening or funding wallets. Its customers can still pay and cash out.
+func (s *SmartContract) SuspendIntermediary(ctx contractapi.TransactionContextInterface, mspID string) error {
+	err := _requireOrg(ctx, centralBankMSPID, "suspend intermediaries")
+	if err != nil {
+		return err
+	}
+
+	intermediary, err := s.GetIntermediary(ctx, mspID)
+	if err != nil {
+		return err
+	}
+	intermediary.Active = false
+	return _putIntermediary(ctx, intermediary)
+}
+
+// Issue is called by the central bank to mint currency into the reserve of an intermediary
+func (s *SmartContract) Issue(ctx contractapi.TransactionContextInterface, mspID string, amount int) error {
+	err := _requireOrg(ctx, centralBankMSPID, "issue currency")
+	if err != nil {
+		return err
+	}
+	if amount <= 0 {
+		return fmt.Errorf("issue amount must be a positive integer")
+	}
+
+	intermediary, err := _requireActiveIntermediary(ctx, mspID)
+	if err != nil {
+		return err
+	}
+	intermediary.Reserve += amount
+	intermediary.Issued += amount
+	err = _putIntermediary(ctx, intermediary)
+	if err != nil {
+		return err
+	}
+
+	return _emitEvent(ctx, "Issued", event{centralBankMSPID, mspID, amount})
+}
+
+// Redeem is called by an intermediary to return currency from its reserve to the central bank,
+// which takes it out of circulation
+func (s *SmartContract) Redeem(ctx contractapi.TransactionContextInterface, amount int) error {
+	if amount <= 0 {
+		return fmt.Errorf("redeem amount must be a positive integer")
+	}
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+
+	intermediary, err := s.GetIntermediary(ctx, mspID)
+	if err != nil {
+		return err
+	}
+	if intermediary.Reserve < amount {
+		return fmt.Errorf("reserve of %s has insufficient funds", mspID)
+	}
+	intermediary.Reserve -= amount
+	intermediary.Issued -= amount
+	err = _putIntermediary(ctx, intermediary)
+	if err != nil {
+		return err
+	}
+
+	return _emitEvent(ctx, "Redeemed", event{mspID, centralBankMSPID, amount})
+}
+
+// OpenWallet is called by an intermediary once it has identified the customer to the given tier
+func (s *SmartContract) OpenWallet(ctx contractapi.TransactionContextInterface, owner string, tier int) error {
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+	_, err = _requireActiveIntermediary(ctx, mspID)
+	if err != nil {
+		return err
+	}
+	if owner == "" {
+		return fmt.Errorf("wallet owner must be set")
+	}
+	_, err = _getTier(tier)
+	if err != nil {
+		return err
+	}
+
+	existing, err := _getWallet(ctx, owner)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("a wallet is already open for %s", owner)
+	}
+
+	now, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	wallet := Wallet{
+		ObjectType:   walletPrefix,
+		Owner:        owner,
+		Intermediary: mspID,
+		Tier:         tier,
+		OpenedAt:     now.Format(time.RFC3339),
+	}
+	return _putWallet(ctx, &wallet)
+}
+
+// SetWalletTier is called by the wallet's intermediary when the customer's KYC level changes. A
+// wallet cannot be lowered to a tier whose balance limit it exceeds.
+func (s *SmartContract) SetWalletTier(ctx contractapi.TransactionContextInterface, owner string, tier int) error {
+	wallet, err := s.GetWallet(ctx, owner)
+	if err != nil {
+		return err
+	}
+	err = _requireOrg(ctx, wallet.Intermediary, "change the tier")
+	if err != nil {
+		return err
+	}
+
+	limits, err := _getTier(tier)
+	if err != nil {
+		return err
+	}
+	if wallet.Balance > limits.MaxBalance {
+		return fmt.Errorf("wallet balance %d exceeds the balance limit %d of tier %d", wallet.Balance, limits.MaxBalance, tier)
+	}
+
+	wallet.Tier = tier
+	return _putWallet(ctx, wallet)
+}
+
+// Distribute is called by an intermediary to fund the wallet of one of its customers from its reserve
+func (s *SmartContract) Distribute(ctx contractapi.TransactionContextInterface, owner string, amount int) error {
+	if amount <= 0 {
+		return fmt.Errorf("distribute amount must be a positive integer")
+	}
+	wallet, err := s.GetWallet(ctx, owner)
+	if err != nil {
+		return err
+	}
+	err = _requireOrg(ctx, wallet.Intermediary, "fund the wallet")
+	if err != nil {
+		return err
+	}
+
+	intermediary, err := _requireActiveIntermediary(ctx, wallet.Intermediary)
+	if err != nil {
+		return err
+	}
+	if intermediary.Reserve < amount {
+		return fmt.Errorf("reserve of %s has insufficient funds", intermediary.MSPID)
+	}
+	intermediary.Reserve -= amount
+	err = _putIntermediary(ctx, intermediary)
+	if err != nil {
+		return err
+	}
+
+	err = _credit(ctx, wallet, amount)
+	if err != nil {
+		return err
+	}
+
+	err = _recordFlow(ctx, flowDistribute, intermediary.MSPID, 0, wallet.Intermediary, wallet.Tier, amount)
+	if err != nil {
+		return err
+	}
+	return _emitEvent(ctx, "Transfer", event{intermediary.MSPID, owner, amount})
+}
+
+// CashOut is called by a wallet owner to return currency to the reserve of its intermediary, for
+// example to withdraw it to a bank account
+func (s *SmartContract) CashOut(ctx contractapi.TransactionContextInterface, amount int) error {
+	if amount <= 0 {
+		return fmt.Errorf("cash out amount must be a positive integer")
+	}
+	wallet, err := _getClientWallet(ctx)
+	if err != nil {
+		return err
+	}
+	if wallet.Balance < amount {
+		return fmt.Errorf("wallet has insufficient funds")
+	}
+
+	intermediary, err := s.GetIntermediary(ctx, wallet.Intermediary)
+	if err != nil {
+		return err
+	}
+	intermediary.Reserve += amount
+	err = _putIntermediary(ctx, intermediary)
+	if err != nil {
+		return err
+	}
+
+	wallet.Balance -= amount
+	err = _putWallet(ctx, wallet)
+	if err != nil {
+		return err
+	}
+
+	err = _recordFlow(ctx, flowCashOut, wallet.Intermediary, wallet.Tier, intermediary.MSPID, 0, amount)
+	if err != nil {
+		return err
+	}
+	return _emitEvent(ctx, "Transfer", event{wallet.Owner, intermediary.MSPID, amount})
+}
+
+// Pay moves currency from the client's wallet to the receiver's wallet, within the client's daily
+// limit and the receiver's balance limit
+func (s *SmartContract) Pay(ctx contractapi.TransactionContextInterface, receiver string, amount int) error {
+	if amount <= 0 {
+		return fmt.Errorf("payment amount must be a positive integer")
+	}
+	payer, err := _getClientWallet(ctx)
+	if err != nil {
+		return err
+	}
+	if payer.Owner == receiver {
+		return fmt.Errorf("cannot pay own wallet")
+	}
+	payee, err := s.GetWallet(ctx, receiver)
+	if err != nil {
+		return err
+	}
+
+	err = _debit(ctx, payer, amount)
+	if err != nil {
+		return err
+	}
+	err = _credit(ctx, payee, amount)
+	if err != nil {
+		return err
+	}
+
+	err = _recordFlow(ctx, flowPayment, payer.Intermediary, payer.Tier, payee.Intermediary, payee.Tier, amount)
+	if err != nil {
+		return err
+	}
+	return _emitEvent(ctx, "Transfer", event{payer.Owner, payee.Owner, amount})
+}
+
+// IssueVoucher locks an amount of the client's wallet in an offline payment voucher. hashLock is the
+// hex SHA-256 of a secret the payer gives the payee with the voucher ID. expiry is an RFC3339
+// timestamp after which the payer can reclaim a voucher that was not redeemed. The amount counts
+// towards the payer's daily limit on the day the voucher is issued.
+func (s *SmartContract) IssueVoucher(ctx contractapi.TransactionContextInterface, voucherID string, amount int, hashLock string, expiry string) error {
+	if amount <= 0 {
+		return fmt.Errorf("voucher amount must be a positive integer")
+	}
+	hashBytes, err := hex.DecodeString(hashLock)
+	if err != nil || len(hashBytes) != sha256.Size {
+		return fmt.Errorf("hash lock must be a hex encoded SHA-256 hash")
+	}
+
+	now, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return err
+	}
+	expiryTime, err := time.Parse(time.RFC3339, expiry)
+	if err != nil {
+		return fmt.Errorf("failed to parse expiry: %v", err)
+	}
+	if !expiryTime.After(now) {
+		return fmt.Errorf("voucher expiry must be in the future")
+	}
+
+	existing, err := _getVoucher(ctx, voucherID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("the voucher %s already exists", voucherID)
+	}
+
+	payer, err := _getClientWallet(ctx)
+	if err != nil {
+		return err
+	}
+	err = _debit(ctx, payer, amount)
+	if err != nil {
+		return err
+	}
+
+	voucher := Voucher{
+		ObjectType: voucherPrefix,
+		ID:         voucherID,
+		Payer:      payer.Owner,
+		Amount:     amount,
+		HashLock:   hashLock,
+		Expiry:     expiryTime.UTC().Format(time.RFC3339),
+		Status:     voucherIssued,
+	}
+	err = _putVoucher(ctx, &voucher)
+	if err != nil {
+		return err
+	}
+
+	return _emitEvent(ctx, "VoucherIssued", event{payer.Owner, "", amount})
+}
+
+// RedeemVoucher is called by the payee with the secret it received from the payer. The amount is
+// credited to the payee's wallet within its balance limit.
+func (s *SmartContract) RedeemVoucher(ctx contractapi.TransactionContextInterface, voucherID string, secret string) error {
+	voucher, err := s.GetVoucher(ctx, voucherID)
+	if err != nil {
+		return err
+	}
+	if voucher.Status != voucherIssued {
+		return fmt.Errorf("voucher %s is %s", voucherID, voucher.Status)
+	}
+
+	now, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return err
+	}
+	expiry, err := time.Parse(time.RFC3339, voucher.Expiry)
+	if err != nil {
+		return fmt.Errorf("failed to parse expiry: %v", err)
+	}
+	if now.After(expiry) {
+		return fmt.Errorf("voucher %s expired at %s", voucherID, voucher.Expiry)
+	}
+
+	hash := sha256.Sum256([]byte(secret))
+	if hex.EncodeToString(hash[:]) != voucher.HashLock {
+		return fmt.Errorf("secret does not match the hash lock of voucher %s", voucherID)
+	}
+
+	payee, err := _getClientWallet(ctx)
+	if err != nil {
+		return err
+	}
+	payer, err := s.GetWallet(ctx, voucher.Payer)
+	if err != nil {
+		return err
+	}
+	err = _credit(ctx, payee, voucher.Amount)
+	if err != nil {
+		return err
+	}
+
+	voucher.Status = voucherRedeemed
+	voucher.Payee = payee.Owner
+	err = _putVoucher(ctx, voucher)
+	if err != nil {
+		return err
+	}
+
+	err = _recordFlow(ctx, flowVoucher, payer.Intermediary, payer.Tier, payee.Intermediary, payee.Tier, voucher.Amount)
+	if err != nil {
+		return err
+	}
+	return _emitEvent(ctx, "Transfer", event{voucher.Payer, payee.Owner, voucher.Amount})
+}
+
+// ReclaimVoucher returns the amount of an expired voucher that was not redeemed to the payer's wallet
+func (s *SmartContract) ReclaimVoucher(ctx contractapi.TransactionContextInterface, voucherID string) error {
+	voucher, err := s.GetVoucher(ctx, voucherID)
+	if err != nil {
+		return err
+	}
+	if voucher.Status != voucherIssued {
+		return fmt.Errorf("voucher %s is %s", voucherID, voucher.Status)
+	}
+
+	payer, err := _getClientWallet(ctx)
+	if err != nil {
+		return err
+	}
+	if payer.Owner != voucher.Payer {
+		return fmt.Errorf("only the payer can reclaim voucher %s", voucherID)
+	}
+
+	now, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return err
+	}
+	expiry, err := time.Parse(time.RFC3339, voucher.Expiry)
+	if err != nil {
+		return fmt.Errorf("failed to parse expiry: %v", err)
+	}
+	if !now.After(expiry) {
+		return fmt.Errorf("voucher %s can be redeemed until %s", voucherID, voucher.Expiry)
+	}
+
+	// the limits were applied when the voucher was issued, so the amount goes back in full
+	payer.Balance += voucher.Amount
+	err = _putWallet(ctx, payer)
+	if err != nil {
+		return err
+	}
+
+	voucher.Status = voucherReclaimed
+	err = _putVoucher(ctx, voucher)
+	if err != nil {
+		return err
+	}
+
+	return _emitEvent(ctx, "VoucherReclaimed", event{"", payer.Owner, voucher.Amount})
+}
+
+// _debit takes an amount from a wallet, counting it towards the daily limit of the wallet's tier
+func _debit(ctx contractapi.TransactionContextInterface, wallet *Wallet, amount int) error {
+	if wallet.Balance < amount {
+		return fmt.Errorf("wallet has insufficient funds")
+	}
+	limits, err := _getTier(wallet.Tier)
+	if err != nil {
+		return err
+	}
+
+	now, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return err
+	}
+	spentKey, err := ctx.GetStub().CreateCompositeKey(spentPrefix, []string{wallet.Owner, now.Format(dateLayout)})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	spentJSON, err := ctx.GetStub().GetState(spentKey)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	spent := 0
+	if spentJSON != nil {
+		err = json.Unmarshal(spentJSON, &spent)
+		if err != nil {
+			return err
+		}
+	}
+	if spent+amount > limits.DailyLimit {
+		return fmt.Errorf("payment would exceed the daily limit %d of tier %d, %d already spent today", limits.DailyLimit, wallet.Tier, spent)
+	}
+
+	spentJSON, err = json.Marshal(spent + amount)
+	if err != nil {
+		return fmt.Errorf("failed to marshal daily spend: %v", err)
+	}
+	err = ctx.GetStub().PutState(spentKey, spentJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put daily spend: %v", err)
+	}
+
+	wallet.Balance -= amount
+	return _putWallet(ctx, wallet)
+}
+
+// _credit adds an amount to a wallet within the balance limit of the wallet's tier
+func _credit(ctx contractapi.TransactionContextInterface, wallet *Wallet, amount int) error {
+	limits, err := _getTier(wallet.Tier)
+	if err != nil {
+		return err
+	}
+	if wallet.Balance+amount > limits.MaxBalance {
+		return fmt.Errorf("wallet of %s would exceed the balance limit %d of tier %d", wallet.Owner, limits.MaxBalance, wallet.Tier)
+	}
+
+	wallet.Balance += amount
+	return _putWallet(ctx, wallet)
+}
+
+// _recordFlow writes a movement to the regulator's collection. Any endorsing peer can write to the
+// collection, but only the regulator's peers keep the data.
+func _recordFlow(ctx contractapi.TransactionContextInterface, kind string, fromIntermediary string, fromTier int, toIntermediary string, toTier int, amount int) error {
+	now, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	flow := Flow{
+		Date:             now.Format(dateLayout),
+		Kind:             kind,
+		FromIntermediary: fromIntermediary,
+		FromTier:         fromTier,
+		ToIntermediary:   toIntermediary,
+		ToTier:           toTier,
+		Amount:           amount,
+	}
+	flowJSON, err := json.Marshal(flow)
+	if err != nil {
+		return fmt.Errorf("failed to marshal flow: %v", err)
+	}
+
+	flowKey, err := ctx.GetStub().CreateCompositeKey(flowPrefix, []string{flow.Date, ctx.GetStub().GetTxID()})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = ctx.GetStub().PutPrivateData(flowsCollection, flowKey, flowJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put flow: %v", err)
+	}
+	return nil
+}
+
+// _getTier returns the limits of a KYC level
+func _getTier(level int) (*Tier, error) {
+	for i := range tiers {
+		if tiers[i].Level == level {
+			return &tiers[i], nil
+		}
+	}
+	return nil, fmt.Errorf("tier %d does not exist", level)
+}
+
+// _requireOrg checks the client belongs to the given org
+func _requireOrg(ctx contractapi.TransactionContextInterface, org string, action string) error {
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+	if clientMSPID != org {
+		return fmt.Errorf("client from %s is not authorized to %s", clientMSPID, action)
+	}
+	return nil
+}
+
+// _requireActiveIntermediary reads an intermediary and checks it has not been suspended
+func _requireActiveIntermediary(ctx contractapi.TransactionContextInterface, mspID string) (*Intermediary, error) {
+	intermediary, err := _getIntermediary(ctx, mspID)
+	if err != nil {
+		return nil, err
+	}
+	if intermediary == nil || !intermediary.Active {
+		return nil, fmt.Errorf("%s is not an active intermediary", mspID)
+	}
+	return intermediary, nil
+}
+
+// _getClientWallet reads the wallet of the submitting client
+func _getClientWallet(ctx contractapi.TransactionContextInterface) (*Wallet, error) {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client id: %v", err)
+	}
+	wallet, err := _getWallet(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if wallet == nil {
+		return nil, fmt.Errorf("client does not have a wallet")
+	}
+	return wallet, nil
+}
+
+// _getIntermediary reads an intermediary, returning nil when it has not been admitted
+func _getIntermediary(ctx contractapi.TransactionContextInterface, mspID string) (*Intermediary, error) {
+	intermediaryKey, err := ctx.GetStub().CreateCompositeKey(intermediaryPrefix, []string{mspID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	intermediaryJSON, err := ctx.GetStub().GetState(intermediaryKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if intermediaryJSON == nil {
+		return nil, nil
+	}
+
+	var intermediary Intermediary
+	err = json.Unmarshal(intermediaryJSON, &intermediary)
+	if err != nil {
+		return nil, err
+	}
+	return &intermediary, nil
+}
+
+// _putIntermediary writes the intermediary to the world state
+func _putIntermediary(ctx contractapi.TransactionContextInterface, intermediary *Intermediary) error {
+	intermediaryKey, err := ctx.GetStub().CreateCompositeKey(intermediaryPrefix, []string{intermediary.MSPID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	intermediaryJSON, err := json.Marshal(intermediary)
+	if err != nil {
+		return fmt.Errorf("failed to marshal intermediary: %v", err)
+	}
+	err = ctx.GetStub().PutState(intermediaryKey, intermediaryJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put intermediary %s: %v", intermediary.MSPID, err)
+	}
+	return nil
+}
+
+// _getWallet reads a wallet, returning nil when none is open for the owner
+func _getWallet(ctx contractapi.TransactionContextInterface, owner string) (*Wallet, error) {
+	walletKey, err := ctx.GetStub().CreateCompositeKey(walletPrefix, []string{owner})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	walletJSON, err := ctx.GetStub().GetState(walletKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if walletJSON == nil {
+		return nil, nil
+	}
+
+	var wallet Wallet
+	err = json.Unmarshal(walletJSON, &wallet)
+	if err != nil {
+		return nil, err
+	}
+	return &wallet, nil
+}
+
+// _putWallet writes the wallet to the world state
+func _putWallet(ctx contractapi.TransactionContextInterface, wallet *Wallet) error {
+	walletKey, err := ctx.GetStub().CreateCompositeKey(walletPrefix, []string{wallet.Owner})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	walletJSON, err := json.Marshal(wallet)
+	if err != nil {
+		return fmt.Errorf("failed to marshal wallet: %v", err)
+	}
+	err = ctx.GetStub().PutState(walletKey, walletJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put wallet: %v", err)
+	}
+	return nil
+}
+
+// _getVoucher reads a voucher, returning nil when it does not exist
+func _getVoucher(ctx contractapi.TransactionContextInterface, voucherID string) (*Voucher, error) {
+	voucherKey, err := ctx.GetStub().CreateCompositeKey(voucherPrefix, []string{voucherID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	voucherJSON, err := ctx.GetStub().GetState(voucherKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if voucherJSON == nil {
+		return nil, nil
+	}
+
+	var voucher Voucher
+	err = json.Unmarshal(voucherJSON, &voucher)
+	if err != nil {
+		return nil, err
+	}
+	return &voucher, nil
+}
+
+// _putVoucher writes the voucher to the world state
+func _putVoucher(ctx contractapi.TransactionContextInterface, voucher *Voucher) error {
+	voucherKey, err := ctx.GetStub().CreateCompositeKey(voucherPrefix, []string{voucher.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	voucherJSON, err := json.Marshal(voucher)
+	if err != nil {
+		return fmt.Errorf("failed to marshal voucher: %v", err)
+	}
+	err = ctx.GetStub().PutState(voucherKey, voucherJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put voucher %s: %v", voucher.ID, err)
+	}
+	return nil
+}
+
+// _emitEvent marshals the payload and sets it as the chaincode event
+func _emitEvent(ctx contractapi.TransactionContextInterface, name string, payload interface{}) error {
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	err = ctx.GetStub().SetEvent(name, payloadJSON)
+	if err != nil {
+		return fmt.Errorf("failed to set event: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// FlowTotal is the sum of the flows of one kind between two intermediaries over a period
+type FlowTotal struct {
+	Kind             string `json:"kind"`
+	FromIntermediary string `json:"fromIntermediary"`
+	ToIntermediary   string `json:"toIntermediary"`
+	Count            int    `json:"count"`
+	Amount           int    `json:"amount"`
+}
+
+// GetIntermediary returns the intermediary record of an org
+func (s *SmartContract) GetIntermediary(ctx contractapi.TransactionContextInterface, mspID string) (*Intermediary, error) {
+	intermediary, err := _getIntermediary(ctx, mspID)
+	if err != nil {
+		return nil, err
+	}
+	if intermediary == nil {
+		return nil, fmt.Errorf("the intermediary %s does not exist", mspID)
+	}
+	return intermediary, nil
+}
+
+// GetWallet returns the wallet of a client ID
+func (s *SmartContract) GetWallet(ctx contractapi.TransactionContextInterface, owner string) (*Wallet, error) {
+	wallet, err := _getWallet(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	if wallet == nil {
+		return nil, fmt.Errorf("the wallet of %s does not exist", owner)
+	}
+	return wallet, nil
+}
+
+// ClientWallet returns the wallet of the submitting client
+func (s *SmartContract) ClientWallet(ctx contractapi.TransactionContextInterface) (*Wallet, error) {
+	return _getClientWallet(ctx)
+}
+
+// GetVoucher returns the voucher stored in the world state with the given ID
+func (s *SmartContract) GetVoucher(ctx contractapi.TransactionContextInterface, voucherID string) (*Voucher, error) {
+	voucher, err := _getVoucher(ctx, voucherID)
+	if err != nil {
+		return nil, err
+	}
+	if voucher == nil {
+		return nil, fmt.Errorf("the voucher %s does not exist", voucherID)
+	}
+	return voucher, nil
+}
+
+// GetTiers returns the limits of every KYC tier
+func (s *SmartContract) GetTiers(ctx contractapi.TransactionContextInterface) ([]Tier, error) {
+	return tiers, nil
+}
+
+// GetAggregateFlows returns the flows between fromDate and toDate, both YYYY-MM-DD and inclusive,
+// summed by kind and pair of intermediaries. Only the regulator can call it, on one of its own peers.
+func (s *SmartContract) GetAggregateFlows(ctx contractapi.TransactionContextInterface, fromDate string, toDate string) ([]*FlowTotal, error) {
+	err := _requireOrg(ctx, regulatorMSPID, "read flows")
+	if err != nil {
+		return nil, err
+	}
+	from, err := time.Parse(dateLayout, fromDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse from date: %v", err)
+	}
+	to, err := time.Parse(dateLayout, toDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse to date: %v", err)
+	}
+	if to.Before(from) {
+		return nil, fmt.Errorf("to date must not be before from date")
+	}
+
+	resultsIterator, err := ctx.GetStub().GetPrivateDataByPartialCompositeKey(flowsCollection, flowPrefix, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get flows: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	totals := make(map[[3]string]*FlowTotal)
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var flow Flow
+		err = json.Unmarshal(response.Value, &flow)
+		if err != nil {
+			return nil, err
+		}
+		// dates share one layout, so they compare as strings
+		if flow.Date < fromDate || flow.Date > toDate {
+			continue
+		}
+
+		key := [3]string{flow.Kind, flow.FromIntermediary, flow.ToIntermediary}
+		total, ok := totals[key]
+		if !ok {
+			total = &FlowTotal{
+				Kind:             flow.Kind,
+				FromIntermediary: flow.FromIntermediary,
+				ToIntermediary:   flow.ToIntermediary,
+			}
+			totals[key] = total
+		}
+		total.Count++
+		total.Amount += flow.Amount
+	}
+
+	results := make([]*FlowTotal, 0, len(totals))
+	for _, total := range totals {
+		results = append(results, total)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Kind != results[j].Kind {
+			return results[i].Kind < results[j].Kind
+		}
+		if results[i].FromIntermediary != results[j].FromIntermediary {
+			return results[i].FromIntermediary < results[j].FromIntermediary
+		}
+		return results[i].ToIntermediary < results[j].ToIntermediary
+	})
+
+	return results, nil
+}
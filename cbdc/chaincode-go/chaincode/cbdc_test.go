@@ -0,0 +1,158 @@
+package chaincode
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+const (
+	intermediaryMSPID = "Org3MSP"
+	voucherExpiry     = "2020-09-13T13:00:00Z"
+)
+
+func checkError(t *testing.T, err error, expected string) {
+	t.Helper()
+	if expected == "" && err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected != "" && (err == nil || !strings.Contains(err.Error(), expected)) {
+		t.Fatalf("expected error containing %q, got %v", expected, err)
+	}
+}
+
+func checkBalance(t *testing.T, stub *fakeStub, owner string, expected int) {
+	t.Helper()
+	wallet, err := new(SmartContract).GetWallet(newContext(stub, owner, intermediaryMSPID), owner)
+	checkError(t, err, "")
+	if wallet.Balance != expected {
+		t.Fatalf("expected %s to hold %d, got %d", owner, expected, wallet.Balance)
+	}
+}
+
+// hashLock returns the hash lock of a voucher secret
+func hashLock(secret string) string {
+	hash := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(hash[:])
+}
+
+// openWallets admits Org3MSP, issues it 5000 and has it open a tier 1 wallet for alice and a tier
+// 2 wallet for bob, funding alice's with 1000
+func openWallets(t *testing.T, stub *fakeStub) {
+	t.Helper()
+	contract := new(SmartContract)
+	checkError(t, contract.AdmitIntermediary(newContext(stub, "bank", centralBankMSPID), intermediaryMSPID), "")
+	checkError(t, contract.Issue(newContext(stub, "bank", centralBankMSPID), intermediaryMSPID, 5000), "")
+	checkError(t, contract.OpenWallet(newContext(stub, "teller", intermediaryMSPID), "alice", 1), "")
+	checkError(t, contract.OpenWallet(newContext(stub, "teller", intermediaryMSPID), "bob", 2), "")
+	checkError(t, contract.Distribute(newContext(stub, "teller", intermediaryMSPID), "alice", 1000), "")
+}
+
+func TestIssue(t *testing.T) {
+	tests := []struct {
+		name     string
+		mspID    string
+		target   string
+		amount   int
+		expected string
+	}{
+		{"issue", centralBankMSPID, intermediaryMSPID, 100, ""},
+		{"not the central bank", intermediaryMSPID, intermediaryMSPID, 100, "client from Org3MSP is not authorized to issue currency"},
+		{"no amount", centralBankMSPID, intermediaryMSPID, 0, "issue amount must be a positive integer"},
+		{"not an intermediary", centralBankMSPID, "Org4MSP", 100, "Org4MSP is not an active intermediary"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stub := newFakeStub()
+			checkError(t, new(SmartContract).AdmitIntermediary(newContext(stub, "bank", centralBankMSPID), intermediaryMSPID), "")
+
+			err := new(SmartContract).Issue(newContext(stub, "bank", test.mspID), test.target, test.amount)
+			checkError(t, err, test.expected)
+		})
+	}
+}
+
+func TestDistribute(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	openWallets(t, stub)
+
+	err := contract.Distribute(newContext(stub, "teller", "Org4MSP"), "bob", 100)
+	checkError(t, err, "client from Org4MSP is not authorized to fund the wallet")
+	err = contract.Distribute(newContext(stub, "teller", intermediaryMSPID), "bob", 4001)
+	checkError(t, err, "reserve of Org3MSP has insufficient funds")
+	intermediary, err := contract.GetIntermediary(newContext(stub, "bank", centralBankMSPID), intermediaryMSPID)
+	checkError(t, err, "")
+	if intermediary.Reserve != 4000 || intermediary.Issued != 5000 {
+		t.Fatalf("unexpected intermediary %+v", intermediary)
+	}
+	err = contract.Distribute(newContext(stub, "teller", intermediaryMSPID), "alice", 1)
+	checkError(t, err, "wallet of alice would exceed the balance limit 1000 of tier 1")
+
+	checkError(t, contract.SuspendIntermediary(newContext(stub, "bank", centralBankMSPID), intermediaryMSPID), "")
+	err = contract.Distribute(newContext(stub, "teller", intermediaryMSPID), "bob", 100)
+	checkError(t, err, "Org3MSP is not an active intermediary")
+
+	// customers of a suspended intermediary can still cash out
+	checkError(t, contract.CashOut(newContext(stub, "alice", intermediaryMSPID), 400), "")
+	checkBalance(t, stub, "alice", 600)
+}
+
+func TestPay(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	openWallets(t, stub)
+
+	err := contract.Pay(newContext(stub, "carol", intermediaryMSPID), "bob", 100)
+	checkError(t, err, "client does not have a wallet")
+
+	checkError(t, contract.Pay(newContext(stub, "alice", intermediaryMSPID), "bob", 200), "")
+	checkBalance(t, stub, "alice", 800)
+	checkBalance(t, stub, "bob", 200)
+
+	// tier 1 pays out at most 250 a day
+	err = contract.Pay(newContext(stub, "alice", intermediaryMSPID), "bob", 51)
+	checkError(t, err, "payment would exceed the daily limit 250 of tier 1, 200 already spent today")
+	err = contract.Pay(newContext(stub, "bob", intermediaryMSPID), "alice", 201)
+	checkError(t, err, "wallet has insufficient funds")
+
+	// the regulator sees the flows by intermediary and tier only
+	_, err = contract.GetAggregateFlows(newContext(stub, "teller", intermediaryMSPID), "2020-09-13", "2020-09-13")
+	checkError(t, err, "client from Org3MSP is not authorized to read flows")
+	flows, err := contract.GetAggregateFlows(newContext(stub, "auditor", regulatorMSPID), "2020-09-13", "2020-09-13")
+	checkError(t, err, "")
+	if len(flows) != 2 || *flows[1] != (FlowTotal{flowPayment, intermediaryMSPID, intermediaryMSPID, 1, 200}) {
+		t.Fatalf("unexpected flows %+v", flows)
+	}
+}
+
+func TestVoucher(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	openWallets(t, stub)
+
+	err := contract.IssueVoucher(newContext(stub, "alice", intermediaryMSPID), "v1", 251, hashLock("secret"), voucherExpiry)
+	checkError(t, err, "payment would exceed the daily limit 250 of tier 1, 0 already spent today")
+	checkError(t, contract.IssueVoucher(newContext(stub, "alice", intermediaryMSPID), "v1", 50, hashLock("secret"), voucherExpiry), "")
+	checkError(t, contract.IssueVoucher(newContext(stub, "alice", intermediaryMSPID), "v2", 50, hashLock("other"), voucherExpiry), "")
+	checkBalance(t, stub, "alice", 900)
+
+	err = contract.RedeemVoucher(newContext(stub, "bob", intermediaryMSPID), "v1", "guess")
+	checkError(t, err, "secret does not match the hash lock of voucher v1")
+	checkError(t, contract.RedeemVoucher(newContext(stub, "bob", intermediaryMSPID), "v1", "secret"), "")
+	checkBalance(t, stub, "bob", 50)
+	err = contract.RedeemVoucher(newContext(stub, "bob", intermediaryMSPID), "v1", "secret")
+	checkError(t, err, "voucher v1 is REDEEMED")
+
+	// an expired voucher goes back to the payer
+	err = contract.ReclaimVoucher(newContext(stub, "alice", intermediaryMSPID), "v2")
+	checkError(t, err, "voucher v2 can be redeemed until "+voucherExpiry)
+	stub.txCount += 3600
+	err = contract.RedeemVoucher(newContext(stub, "bob", intermediaryMSPID), "v2", "other")
+	checkError(t, err, "voucher v2 expired at "+voucherExpiry)
+	err = contract.ReclaimVoucher(newContext(stub, "bob", intermediaryMSPID), "v2")
+	checkError(t, err, "only the payer can reclaim voucher v2")
+	checkError(t, contract.ReclaimVoucher(newContext(stub, "alice", intermediaryMSPID), "v2"), "")
+	checkBalance(t, stub, "alice", 950)
+}
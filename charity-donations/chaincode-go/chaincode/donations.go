@@ -0,0 +1,413 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// defaultTokenChaincode is the name the token-erc-20 sample is deployed under in the README
+const defaultTokenChaincode = "token_erc20"
+
+// object names for prefix
+const (
+	campaignPrefix     = "campaign"
+	donationPrefix     = "donation"
+	donorPrefix        = "donor~donation"
+	disbursementPrefix = "disbursement"
+	allocationPrefix   = "allocation"
+)
+
+// campaign status values
+const (
+	statusOpen   = "OPEN"
+	statusClosed = "CLOSED"
+)
+
+// SmartContract provides functions for tracking charity donations and how they are spent
+type SmartContract struct {
+	contractapi.Contract
+}
+
+// Campaign collects donations into the treasury account of the charity that created it. Spending
+// recorded through Disburse is matched to the donations it was paid from.
+type Campaign struct {
+	ObjectType     string `json:"objectType"`
+	ID             string `json:"campaignID"`
+	Name           string `json:"name"`
+	Description    string `json:"description"`
+	Treasury       string `json:"treasury"`
+	TokenChaincode string `json:"tokenChaincode"`
+	Donations      int    `json:"donations"`
+	Raised         int    `json:"raised"`
+	Spent          int    `json:"spent"`
+	Status         string `json:"status"`
+}
+
+// Donation is one donation to a campaign. Spent is how much of it disbursements have used.
+type Donation struct {
+	CampaignID string `json:"campaignID"`
+	Sequence   int    `json:"sequence"`
+	Donor      string `json:"donor"`
+	Amount     int    `json:"amount"`
+	Spent      int    `json:"spent"`
+	TxID       string `json:"txID"`
+}
+
+// Item is one line of a disbursement
+type Item struct {
+	Description string `json:"description"`
+	Category    string `json:"category"`
+	Amount      int    `json:"amount"`
+}
+
+// Disbursement is a payment from campaign funds with the items it paid for
+type Disbursement struct {
+	CampaignID  string `json:"campaignID"`
+	ID          string `json:"disbursementID"`
+	Payee       string `json:"payee"`
+	Items       []Item `json:"items"`
+	Total       int    `json:"total"`
+	ReceiptHash string `json:"receiptHash"`
+	TxID        string `json:"txID"`
+}
+
+// Allocation records how much of a donation a disbursement used
+type Allocation struct {
+	CampaignID     string `json:"campaignID"`
+	Sequence       int    `json:"sequence"`
+	DisbursementID string `json:"disbursementID"`
+	Amount         int    `json:"amount"`
+}
+
+// CreateCampaign opens a campaign with the client's account as its treasury. tokenChaincode defaults
+// to token_erc20.
+func (s *SmartContract) CreateCampaign(ctx contractapi.TransactionContextInterface, campaignID string, name string, description string, tokenChaincode string) error {
+	treasury, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	if campaignID == "" || name == "" {
+		return fmt.Errorf("campaign ID and name must be set")
+	}
+	if tokenChaincode == "" {
+		tokenChaincode = defaultTokenChaincode
+	}
+
+	existing, err := _getCampaign(ctx, campaignID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("the campaign %s already exists", campaignID)
+	}
+
+	campaign := Campaign{
+		ObjectType:     campaignPrefix,
+		ID:             campaignID,
+		Name:           name,
+		Description:    description,
+		Treasury:       treasury,
+		TokenChaincode: tokenChaincode,
+		Status:         statusOpen,
+	}
+	return _putCampaign(ctx, &campaign)
+}
+
+// Donate transfers amount tokens from the client to the campaign treasury and records the donation
+func (s *SmartContract) Donate(ctx contractapi.TransactionContextInterface, campaignID string, amount int) (int, error) {
+	donor, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get client id: %v", err)
+	}
+	if amount <= 0 {
+		return 0, fmt.Errorf("donation must be a positive integer")
+	}
+
+	campaign, err := s.GetCampaign(ctx, campaignID)
+	if err != nil {
+		return 0, err
+	}
+	if campaign.Status != statusOpen {
+		return 0, fmt.Errorf("campaign %s is %s", campaignID, campaign.Status)
+	}
+
+	err = _transferTokens(ctx, campaign.TokenChaincode, campaign.Treasury, amount)
+	if err != nil {
+		return 0, err
+	}
+
+	campaign.Donations++
+	campaign.Raised += amount
+	donation := Donation{
+		CampaignID: campaignID,
+		Sequence:   campaign.Donations,
+		Donor:      donor,
+		Amount:     amount,
+		TxID:       ctx.GetStub().GetTxID(),
+	}
+	err = _putDonation(ctx, &donation)
+	if err != nil {
+		return 0, err
+	}
+
+	donorKey, err := ctx.GetStub().CreateCompositeKey(donorPrefix, []string{donor, campaignID, _padded(donation.Sequence)})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = ctx.GetStub().PutState(donorKey, []byte{0x00})
+	if err != nil {
+		return 0, fmt.Errorf("failed to put donor index: %v", err)
+	}
+
+	err = _putCampaign(ctx, campaign)
+	if err != nil {
+		return 0, err
+	}
+	return donation.Sequence, nil
+}
+
+// Disburse pays campaign funds from the treasury to payee. items is a JSON array of Item and the
+// payment is their total. receiptHash is the hex hash of the supporting invoice or receipt kept off
+// chain. The disbursement is paid from the oldest unspent donations first, and every donation it uses
+// gets an allocation record, so each donor can see what their money paid for.
+func (s *SmartContract) Disburse(ctx contractapi.TransactionContextInterface, campaignID string, disbursementID string, payee string, items string, receiptHash string) error {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	campaign, err := s.GetCampaign(ctx, campaignID)
+	if err != nil {
+		return err
+	}
+	if clientID != campaign.Treasury {
+		return fmt.Errorf("only the campaign treasury can disburse funds of campaign %s", campaignID)
+	}
+	if disbursementID == "" || payee == "" {
+		return fmt.Errorf("disbursement ID and payee must be set")
+	}
+
+	var lineItems []Item
+	err = json.Unmarshal([]byte(items), &lineItems)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal items: %v", err)
+	}
+	if len(lineItems) == 0 {
+		return fmt.Errorf("a disbursement needs at least one item")
+	}
+	total := 0
+	for _, item := range lineItems {
+		if item.Description == "" || item.Amount <= 0 {
+			return fmt.Errorf("every item needs a description and a positive amount")
+		}
+		total += item.Amount
+	}
+	if total > campaign.Raised-campaign.Spent {
+		return fmt.Errorf("disbursement of %d exceeds the %d unspent funds of campaign %s", total, campaign.Raised-campaign.Spent, campaignID)
+	}
+
+	disbursementKey, err := ctx.GetStub().CreateCompositeKey(disbursementPrefix, []string{campaignID, disbursementID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	existing, err := ctx.GetStub().GetState(disbursementKey)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if existing != nil {
+		return fmt.Errorf("the disbursement %s already exists", disbursementID)
+	}
+
+	err = _transferTokens(ctx, campaign.TokenChaincode, payee, total)
+	if err != nil {
+		return err
+	}
+
+	err = s.allocate(ctx, campaignID, disbursementID, total)
+	if err != nil {
+		return err
+	}
+
+	disbursement := Disbursement{
+		CampaignID:  campaignID,
+		ID:          disbursementID,
+		Payee:       payee,
+		Items:       lineItems,
+		Total:       total,
+		ReceiptHash: receiptHash,
+		TxID:        ctx.GetStub().GetTxID(),
+	}
+	disbursementJSON, err := json.Marshal(disbursement)
+	if err != nil {
+		return fmt.Errorf("failed to marshal disbursement: %v", err)
+	}
+	err = ctx.GetStub().PutState(disbursementKey, disbursementJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put disbursement: %v", err)
+	}
+
+	campaign.Spent += total
+	err = _putCampaign(ctx, campaign)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().SetEvent("Disbursement", disbursementJSON)
+}
+
+// CloseCampaign stops new donations. Unspent funds can still be disbursed.
+func (s *SmartContract) CloseCampaign(ctx contractapi.TransactionContextInterface, campaignID string) error {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	campaign, err := s.GetCampaign(ctx, campaignID)
+	if err != nil {
+		return err
+	}
+	if clientID != campaign.Treasury {
+		return fmt.Errorf("only the campaign treasury can close campaign %s", campaignID)
+	}
+	if campaign.Status != statusOpen {
+		return fmt.Errorf("campaign %s is already %s", campaignID, campaign.Status)
+	}
+
+	campaign.Status = statusClosed
+	return _putCampaign(ctx, campaign)
+}
+
+// allocate spends amount from the campaign's donations, oldest first, writing an allocation record
+// for every donation used
+func (s *SmartContract) allocate(ctx contractapi.TransactionContextInterface, campaignID string, disbursementID string, amount int) error {
+	donations, err := s.GetDonations(ctx, campaignID)
+	if err != nil {
+		return err
+	}
+
+	for _, donation := range donations {
+		if amount == 0 {
+			break
+		}
+		unspent := donation.Amount - donation.Spent
+		if unspent == 0 {
+			continue
+		}
+
+		used := unspent
+		if amount < used {
+			used = amount
+		}
+		donation.Spent += used
+		amount -= used
+
+		err = _putDonation(ctx, donation)
+		if err != nil {
+			return err
+		}
+
+		allocation := Allocation{
+			CampaignID:     campaignID,
+			Sequence:       donation.Sequence,
+			DisbursementID: disbursementID,
+			Amount:         used,
+		}
+		allocationJSON, err := json.Marshal(allocation)
+		if err != nil {
+			return fmt.Errorf("failed to marshal allocation: %v", err)
+		}
+		allocationKey, err := ctx.GetStub().CreateCompositeKey(allocationPrefix, []string{campaignID, _padded(donation.Sequence), disbursementID})
+		if err != nil {
+			return fmt.Errorf("failed to create composite key: %v", err)
+		}
+		err = ctx.GetStub().PutState(allocationKey, allocationJSON)
+		if err != nil {
+			return fmt.Errorf("failed to put allocation: %v", err)
+		}
+	}
+
+	if amount > 0 {
+		return fmt.Errorf("donations of campaign %s do not cover the disbursement", campaignID)
+	}
+	return nil
+}
+
+// _padded zero pads a donation sequence so composite keys sort in donation order
+func _padded(sequence int) string {
+	return fmt.Sprintf("%010d", sequence)
+}
+
+// _transferTokens pays amount tokens from the invoking client's account to receiver
+func _transferTokens(ctx contractapi.TransactionContextInterface, tokenChaincode string, receiver string, amount int) error {
+	args := [][]byte{[]byte("Transfer"), []byte(receiver), []byte(strconv.Itoa(amount))}
+	response := ctx.GetStub().InvokeChaincode(tokenChaincode, args, "")
+	if response.Status != shim.OK {
+		return fmt.Errorf("failed to transfer %d tokens on %s: %s", amount, tokenChaincode, response.Message)
+	}
+	return nil
+}
+
+// _getCampaign reads a campaign, returning nil when it does not exist
+func _getCampaign(ctx contractapi.TransactionContextInterface, campaignID string) (*Campaign, error) {
+	campaignKey, err := ctx.GetStub().CreateCompositeKey(campaignPrefix, []string{campaignID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	campaignJSON, err := ctx.GetStub().GetState(campaignKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if campaignJSON == nil {
+		return nil, nil
+	}
+
+	var campaign Campaign
+	err = json.Unmarshal(campaignJSON, &campaign)
+	if err != nil {
+		return nil, err
+	}
+	return &campaign, nil
+}
+
+// _putCampaign writes the campaign to the world state
+func _putCampaign(ctx contractapi.TransactionContextInterface, campaign *Campaign) error {
+	campaignKey, err := ctx.GetStub().CreateCompositeKey(campaignPrefix, []string{campaign.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	campaignJSON, err := json.Marshal(campaign)
+	if err != nil {
+		return fmt.Errorf("failed to marshal campaign: %v", err)
+	}
+	err = ctx.GetStub().PutState(campaignKey, campaignJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put campaign %s: %v", campaign.ID, err)
+	}
+	return nil
+}
+
+// _putDonation writes the donation to the world state
+func _putDonation(ctx contractapi.TransactionContextInterface, donation *Donation) error {
+	donationKey, err := ctx.GetStub().CreateCompositeKey(donationPrefix, []string{donation.CampaignID, _padded(donation.Sequence)})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	donationJSON, err := json.Marshal(donation)
+	if err != nil {
+		return fmt.Errorf("failed to marshal donation: %v", err)
+	}
+	err = ctx.GetStub().PutState(donationKey, donationJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put donation: %v", err)
+	}
+	return nil
+}
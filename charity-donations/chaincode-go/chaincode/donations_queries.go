@@ -0,0 +1,179 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// SpendingDetail is a disbursement as far as it was paid from one donation
+type SpendingDetail struct {
+	Amount       int           `json:"amount"`
+	Disbursement *Disbursement `json:"disbursement"`
+}
+
+// DonationReport shows a donation and the disbursements it paid for
+type DonationReport struct {
+	Donation *Donation         `json:"donation"`
+	Spending []*SpendingDetail `json:"spending"`
+}
+
+// GetCampaign returns the campaign stored in the world state with the given ID
+func (s *SmartContract) GetCampaign(ctx contractapi.TransactionContextInterface, campaignID string) (*Campaign, error) {
+	campaign, err := _getCampaign(ctx, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	if campaign == nil {
+		return nil, fmt.Errorf("the campaign %s does not exist", campaignID)
+	}
+	return campaign, nil
+}
+
+// GetDonations returns the donations to a campaign, oldest first
+func (s *SmartContract) GetDonations(ctx contractapi.TransactionContextInterface, campaignID string) ([]*Donation, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(donationPrefix, []string{campaignID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get donations for campaign %s: %v", campaignID, err)
+	}
+	defer resultsIterator.Close()
+
+	var donations []*Donation
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var donation Donation
+		err = json.Unmarshal(response.Value, &donation)
+		if err != nil {
+			return nil, err
+		}
+		donations = append(donations, &donation)
+	}
+
+	return donations, nil
+}
+
+// GetDisbursements returns the disbursements of a campaign
+func (s *SmartContract) GetDisbursements(ctx contractapi.TransactionContextInterface, campaignID string) ([]*Disbursement, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(disbursementPrefix, []string{campaignID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get disbursements for campaign %s: %v", campaignID, err)
+	}
+	defer resultsIterator.Close()
+
+	var disbursements []*Disbursement
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var disbursement Disbursement
+		err = json.Unmarshal(response.Value, &disbursement)
+		if err != nil {
+			return nil, err
+		}
+		disbursements = append(disbursements, &disbursement)
+	}
+
+	return disbursements, nil
+}
+
+// GetDonorReport returns every donation a donor made, each with the itemized disbursements it paid for
+func (s *SmartContract) GetDonorReport(ctx contractapi.TransactionContextInterface, donor string) ([]*DonationReport, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(donorPrefix, []string{donor})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get donations of %s: %v", donor, err)
+	}
+	defer resultsIterator.Close()
+
+	var reports []*DonationReport
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(response.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split composite key: %v", err)
+		}
+		if len(keyParts) != 3 {
+			return nil, fmt.Errorf("unexpected index key %s", response.Key)
+		}
+
+		report, err := s.donationReport(ctx, keyParts[1], keyParts[2])
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+
+	return reports, nil
+}
+
+// donationReport assembles the report of one donation from its allocation records
+func (s *SmartContract) donationReport(ctx contractapi.TransactionContextInterface, campaignID string, sequence string) (*DonationReport, error) {
+	donationKey, err := ctx.GetStub().CreateCompositeKey(donationPrefix, []string{campaignID, sequence})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	donationJSON, err := ctx.GetStub().GetState(donationKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if donationJSON == nil {
+		return nil, fmt.Errorf("donation %s of campaign %s does not exist", sequence, campaignID)
+	}
+	var donation Donation
+	err = json.Unmarshal(donationJSON, &donation)
+	if err != nil {
+		return nil, err
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(allocationPrefix, []string{campaignID, sequence})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get allocations: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	report := DonationReport{Donation: &donation}
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var allocation Allocation
+		err = json.Unmarshal(response.Value, &allocation)
+		if err != nil {
+			return nil, err
+		}
+
+		disbursementKey, err := ctx.GetStub().CreateCompositeKey(disbursementPrefix, []string{campaignID, allocation.DisbursementID})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create composite key: %v", err)
+		}
+		disbursementJSON, err := ctx.GetStub().GetState(disbursementKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read from world state: %v", err)
+		}
+		var disbursement Disbursement
+		err = json.Unmarshal(disbursementJSON, &disbursement)
+		if err != nil {
+			return nil, err
+		}
+
+		report.Spending = append(report.Spending, &SpendingDetail{Amount: allocation.Amount, Disbursement: &disbursement})
+	}
+
+	return &report, nil
+}
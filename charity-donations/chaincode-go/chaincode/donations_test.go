@@ -0,0 +1,149 @@
+package chaincode
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+)
+
+const donorMSPID = "Org2MSP"
+
+// fakeToken stands in for the token chaincode. Transfers are paid from the payer's account.
+type fakeToken struct {
+	payer    string
+	balances map[string]int
+}
+
+func (f *fakeToken) invoke(args [][]byte) pb.Response {
+	if string(args[0]) != "Transfer" {
+		return shim.Error("unexpected function " + string(args[0]))
+	}
+	amount, err := strconv.Atoi(string(args[2]))
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if f.balances[f.payer] < amount {
+		return shim.Error(fmt.Sprintf("client account %s has insufficient funds", f.payer))
+	}
+	f.balances[f.payer] -= amount
+	f.balances[string(args[1])] += amount
+	return shim.Success(nil)
+}
+
+func checkError(t *testing.T, err error, expected string) {
+	t.Helper()
+	if expected == "" && err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected != "" && (err == nil || !strings.Contains(err.Error(), expected)) {
+		t.Fatalf("expected error containing %q, got %v", expected, err)
+	}
+}
+
+// createCampaign has the charity open campaign wells and alice donate 300, then bob 200
+func createCampaign(t *testing.T, stub *fakeStub) *fakeToken {
+	t.Helper()
+	token := &fakeToken{balances: map[string]int{"alice": 300, "bob": 500}}
+	stub.chaincodes[defaultTokenChaincode] = token.invoke
+
+	contract := new(SmartContract)
+	checkError(t, contract.CreateCampaign(newContext(stub, "charity", "Org1MSP"), "wells", "Village wells", "Drill two wells", ""), "")
+
+	for _, donation := range []Donation{{Donor: "alice", Amount: 300}, {Donor: "bob", Amount: 200}} {
+		token.payer = donation.Donor
+		_, err := contract.Donate(newContext(stub, donation.Donor, donorMSPID), "wells", donation.Amount)
+		checkError(t, err, "")
+	}
+	return token
+}
+
+func TestCreateCampaign(t *testing.T) {
+	tests := []struct {
+		name       string
+		campaignID string
+		title      string
+		expected   string
+	}{
+		{"campaign", "school", "School roof", ""},
+		{"no name", "school", "", "campaign ID and name must be set"},
+		{"existing campaign", "wells", "Village wells", "the campaign wells already exists"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stub := newFakeStub()
+			createCampaign(t, stub)
+
+			err := new(SmartContract).CreateCampaign(newContext(stub, "charity", "Org1MSP"), test.campaignID, test.title, "", "")
+			checkError(t, err, test.expected)
+		})
+	}
+}
+
+func TestDonateInsufficientFunds(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	token := createCampaign(t, stub)
+
+	token.payer = "alice"
+	_, err := contract.Donate(newContext(stub, "alice", donorMSPID), "wells", 1)
+	checkError(t, err, "failed to transfer 1 tokens on token_erc20: client account alice has insufficient funds")
+
+	campaign, err := contract.GetCampaign(newContext(stub, "alice", donorMSPID), "wells")
+	checkError(t, err, "")
+	if campaign.Donations != 2 || campaign.Raised != 500 {
+		t.Fatalf("unexpected campaign %+v", campaign)
+	}
+
+	err = contract.CloseCampaign(newContext(stub, "alice", donorMSPID), "wells")
+	checkError(t, err, "only the campaign treasury can close campaign wells")
+	checkError(t, contract.CloseCampaign(newContext(stub, "charity", "Org1MSP"), "wells"), "")
+
+	token.payer = "bob"
+	_, err = contract.Donate(newContext(stub, "bob", donorMSPID), "wells", 100)
+	checkError(t, err, "campaign wells is CLOSED")
+}
+
+func TestDisburse(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	token := createCampaign(t, stub)
+
+	drill := `[{"description":"Drill rental","category":"equipment","amount":400}]`
+	err := contract.Disburse(newContext(stub, "alice", donorMSPID), "wells", "d1", "supplier", drill, "")
+	checkError(t, err, "only the campaign treasury can disburse funds of campaign wells")
+
+	err = contract.Disburse(newContext(stub, "charity", "Org1MSP"), "wells", "d1", "supplier", `[{"description":"Pipes","amount":501}]`, "")
+	checkError(t, err, "disbursement of 501 exceeds the 500 unspent funds of campaign wells")
+
+	// the disbursement uses all of alice's donation and 100 of bob's
+	token.payer = "charity"
+	checkError(t, contract.Disburse(newContext(stub, "charity", "Org1MSP"), "wells", "d1", "supplier", drill, "ab12"), "")
+	if token.balances["supplier"] != 400 || token.balances["charity"] != 100 {
+		t.Fatalf("unexpected balances %v", token.balances)
+	}
+	if stub.eventName != "Disbursement" {
+		t.Fatalf("expected a Disbursement event, got %s", stub.eventName)
+	}
+
+	err = contract.Disburse(newContext(stub, "charity", "Org1MSP"), "wells", "d1", "supplier", `[{"description":"Pipes","amount":50}]`, "")
+	checkError(t, err, "the disbursement d1 already exists")
+
+	reports, err := contract.GetDonorReport(newContext(stub, "bob", donorMSPID), "bob")
+	checkError(t, err, "")
+	if len(reports) != 1 || reports[0].Donation.Spent != 100 || len(reports[0].Spending) != 1 {
+		t.Fatalf("unexpected report %+v", reports)
+	}
+	if spending := reports[0].Spending[0]; spending.Amount != 100 || spending.Disbursement.Payee != "supplier" || spending.Disbursement.Items[0].Description != "Drill rental" {
+		t.Fatalf("unexpected spending %+v", spending)
+	}
+
+	reports, err = contract.GetDonorReport(newContext(stub, "alice", donorMSPID), "alice")
+	checkError(t, err, "")
+	if len(reports) != 1 || reports[0].Donation.Spent != 300 || reports[0].Spending[0].Amount != 300 {
+		t.Fatalf("unexpected report %+v", reports)
+	}
+}
@@ -0,0 +1,23 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"log"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/charity-donations/chaincode-go/chaincode"
+)
+
+func main() {
+	donationsChaincode, err := contractapi.NewChaincode(&chaincode.SmartContract{})
+	if err != nil {
+		log.Panicf("Error creating charity-donations chaincode: %v", err)
+	}
+
+	if err := donationsChaincode.Start(); err != nil {
+		log.Panicf("Error starting charity-donations chaincode: %v", err)
+	}
+}
@@ -0,0 +1,387 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil"
+)
+
+// defaultTokenChaincode is the name the token-erc-20 sample is deployed under in the README
+const defaultTokenChaincode = "token_erc20"
+
+// This sample assumes Org1 operates the crowdfunding platform and appoints the escrow agent
+const platformMSPID = "Org1MSP"
+
+// Define key names for options
+const escrowAgentKey = "escrowAgent"
+
+// object names for prefix
+const (
+	campaignPrefix = "campaign"
+	pledgePrefix   = "pledge"
+)
+
+// campaign status values
+const (
+	statusOpen      = "OPEN"
+	statusFunded    = "FUNDED"
+	statusRefunding = "REFUNDING"
+	statusRefunded  = "REFUNDED"
+	statusCancelled = "CANCELLED"
+)
+
+// maxRefundsPerSettle is the most backers one Settle refunds, the most payments one BatchTransfer
+// of the token chaincode makes
+const maxRefundsPerSettle = 100
+
+// SmartContract provides functions for all-or-nothing crowdfunding campaigns
+type SmartContract struct {
+	contractapi.Contract
+}
+
+// Campaign raises a token goal before a deadline for a beneficiary
+type Campaign struct {
+	ObjectType     string `json:"objectType"`
+	ID             string `json:"campaignID"`
+	Title          string `json:"title"`
+	Creator        string `json:"creator"`
+	Beneficiary    string `json:"beneficiary"`
+	Goal           int    `json:"goal"`
+	Deadline       string `json:"deadline"`
+	Raised         int    `json:"raised"`
+	EscrowAgent    string `json:"escrowAgent"`
+	TokenChaincode string `json:"tokenChaincode"`
+	Status         string `json:"status"`
+}
+
+// Pledge is the total amount one backer has pledged to a campaign
+type Pledge struct {
+	CampaignID string `json:"campaignID"`
+	Backer     string `json:"backer"`
+	Amount     int    `json:"amount"`
+	Refunded   bool   `json:"refunded"`
+}
+
+// event provides an organized struct for emitting campaign events
+type event struct {
+	CampaignID string `json:"campaignID"`
+	Account    string `json:"account"`
+	Amount     int    `json:"amount"`
+	Status     string `json:"status"`
+}
+
+// SetEscrowAgent appoints the account that holds pledged tokens until campaigns settle.
+// Only the platform org can appoint the escrow agent.
+func (s *SmartContract) SetEscrowAgent(ctx contractapi.TransactionContextInterface, account string) error {
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+	if clientMSPID != platformMSPID {
+		return fmt.Errorf("client from %s is not authorized to appoint the escrow agent", clientMSPID)
+	}
+	if account == "" {
+		return fmt.Errorf("escrow agent account must be set")
+	}
+
+	return ctx.GetStub().PutState(escrowAgentKey, []byte(account))
+}
+
+// CreateCampaign opens a campaign that raises goal tokens for the beneficiary before the
+// RFC3339 deadline. Pledges are paid to the current escrow agent.
+func (s *SmartContract) CreateCampaign(ctx contractapi.TransactionContextInterface, campaignID string, title string, beneficiary string, goal int, deadline string, tokenChaincode string) error {
+	creator, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	if campaignID == "" || title == "" || beneficiary == "" {
+		return fmt.Errorf("campaign ID, title and beneficiary must be set")
+	}
+	if goal <= 0 {
+		return fmt.Errorf("goal must be a positive integer")
+	}
+	deadlineTime, err := time.Parse(time.RFC3339, deadline)
+	if err != nil {
+		return fmt.Errorf("deadline %s is not an RFC3339 timestamp: %v", deadline, err)
+	}
+	now, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return err
+	}
+	if !deadlineTime.After(now) {
+		return fmt.Errorf("deadline %s is in the past", deadline)
+	}
+	if tokenChaincode == "" {
+		tokenChaincode = defaultTokenChaincode
+	}
+
+	escrowAgent, err := ctx.GetStub().GetState(escrowAgentKey)
+	if err != nil {
+		return fmt.Errorf("failed to read escrow agent: %v", err)
+	}
+	if escrowAgent == nil {
+		return fmt.Errorf("no escrow agent has been appointed")
+	}
+
+	exists, err := _campaignExists(ctx, campaignID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("the campaign %s already exists", campaignID)
+	}
+
+	campaign := Campaign{
+		ObjectType:     campaignPrefix,
+		ID:             campaignID,
+		Title:          title,
+		Creator:        creator,
+		Beneficiary:    beneficiary,
+		Goal:           goal,
+		Deadline:       deadline,
+		EscrowAgent:    string(escrowAgent),
+		TokenChaincode: tokenChaincode,
+		Status:         statusOpen,
+	}
+	return _putCampaign(ctx, &campaign)
+}
+
+// Pledge transfers amount tokens from the client to the campaign's escrow agent and records the
+// pledge. Pledges are accepted until the deadline.
+func (s *SmartContract) Pledge(ctx contractapi.TransactionContextInterface, campaignID string, amount int) error {
+	backer, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	campaign, err := s.ReadCampaign(ctx, campaignID)
+	if err != nil {
+		return err
+	}
+	if campaign.Status != statusOpen {
+		return fmt.Errorf("campaign %s is %s", campaignID, campaign.Status)
+	}
+	passed, err := _deadlinePassed(ctx, campaign)
+	if err != nil {
+		return err
+	}
+	if passed {
+		return fmt.Errorf("campaign %s closed at %s", campaignID, campaign.Deadline)
+	}
+	if amount <= 0 {
+		return fmt.Errorf("pledge amount must be a positive integer")
+	}
+
+	err = ledgerutil.TransferTokens(ctx, campaign.TokenChaincode, ledgerutil.TokenPayment{Receiver: campaign.EscrowAgent, Amount: amount})
+	if err != nil {
+		return err
+	}
+
+	pledge, err := s.GetPledge(ctx, campaignID, backer)
+	if err != nil {
+		return err
+	}
+	pledge.Amount += amount
+	err = _putPledge(ctx, pledge)
+	if err != nil {
+		return err
+	}
+
+	campaign.Raised += amount
+	err = _putCampaign(ctx, campaign)
+	if err != nil {
+		return err
+	}
+
+	return _emitEvent(ctx, "Pledge", event{campaignID, backer, amount, campaign.Status})
+}
+
+// CancelCampaign lets the creator cancel a campaign before its deadline. Pledges are refunded
+// when the escrow agent settles the campaign.
+func (s *SmartContract) CancelCampaign(ctx contractapi.TransactionContextInterface, campaignID string) error {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	campaign, err := s.ReadCampaign(ctx, campaignID)
+	if err != nil {
+		return err
+	}
+	if clientID != campaign.Creator {
+		return fmt.Errorf("only the creator can cancel campaign %s", campaignID)
+	}
+	if campaign.Status != statusOpen {
+		return fmt.Errorf("campaign %s is %s", campaignID, campaign.Status)
+	}
+
+	campaign.Status = statusCancelled
+	err = _putCampaign(ctx, campaign)
+	if err != nil {
+		return err
+	}
+
+	return _emitEvent(ctx, "CampaignStatus", event{campaignID, clientID, campaign.Raised, campaign.Status})
+}
+
+// Settle is submitted by the escrow agent once the deadline has passed, or after a cancellation.
+// If the goal was reached the raised tokens are paid to the beneficiary, otherwise every backer is
+// refunded in full. Either way the escrowed tokens leave the escrow agent's account.
+//
+// Refunds are paid with one BatchTransfer, debiting the escrow agent once, so one Settle refunds
+// at most maxRefundsPerSettle backers. A campaign with more backers stays REFUNDING, and the escrow
+// agent submits Settle again until it is REFUNDED.
+func (s *SmartContract) Settle(ctx contractapi.TransactionContextInterface, campaignID string) error {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	campaign, err := s.ReadCampaign(ctx, campaignID)
+	if err != nil {
+		return err
+	}
+	if clientID != campaign.EscrowAgent {
+		return fmt.Errorf("only the escrow agent can settle campaign %s", campaignID)
+	}
+
+	switch campaign.Status {
+	case statusCancelled, statusRefunding:
+	case statusOpen:
+		passed, err := _deadlinePassed(ctx, campaign)
+		if err != nil {
+			return err
+		}
+		if !passed {
+			return fmt.Errorf("campaign %s is open until %s", campaignID, campaign.Deadline)
+		}
+	default:
+		return fmt.Errorf("campaign %s is already %s", campaignID, campaign.Status)
+	}
+
+	if campaign.Status == statusOpen && campaign.Raised >= campaign.Goal {
+		err = ledgerutil.TransferTokens(ctx, campaign.TokenChaincode, ledgerutil.TokenPayment{Receiver: campaign.Beneficiary, Amount: campaign.Raised})
+		if err != nil {
+			return err
+		}
+		campaign.Status = statusFunded
+	} else {
+		pledges, err := s.GetPledges(ctx, campaignID)
+		if err != nil {
+			return err
+		}
+		var refunds []*Pledge
+		var payments []ledgerutil.TokenPayment
+		campaign.Status = statusRefunded
+		for _, pledge := range pledges {
+			if pledge.Refunded {
+				continue
+			}
+			if len(refunds) == maxRefundsPerSettle {
+				campaign.Status = statusRefunding
+				break
+			}
+			refunds = append(refunds, pledge)
+			payments = append(payments, ledgerutil.TokenPayment{Receiver: pledge.Backer, Amount: pledge.Amount})
+		}
+		err = ledgerutil.TransferTokens(ctx, campaign.TokenChaincode, payments...)
+		if err != nil {
+			return fmt.Errorf("failed to refund backers: %v", err)
+		}
+		for _, pledge := range refunds {
+			pledge.Refunded = true
+			err = _putPledge(ctx, pledge)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	err = _putCampaign(ctx, campaign)
+	if err != nil {
+		return err
+	}
+
+	return _emitEvent(ctx, "CampaignStatus", event{campaignID, campaign.Beneficiary, campaign.Raised, campaign.Status})
+}
+
+// _deadlinePassed compares the campaign deadline with the transaction timestamp
+func _deadlinePassed(ctx contractapi.TransactionContextInterface, campaign *Campaign) (bool, error) {
+	deadline, err := time.Parse(time.RFC3339, campaign.Deadline)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse deadline: %v", err)
+	}
+	now, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return false, err
+	}
+	return !now.Before(deadline), nil
+}
+
+// _campaignExists returns true when a campaign with the given ID exists
+func _campaignExists(ctx contractapi.TransactionContextInterface, campaignID string) (bool, error) {
+	campaignKey, err := ctx.GetStub().CreateCompositeKey(campaignPrefix, []string{campaignID})
+	if err != nil {
+		return false, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	campaignJSON, err := ctx.GetStub().GetState(campaignKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	return campaignJSON != nil, nil
+}
+
+// _putCampaign writes the campaign to the world state
+func _putCampaign(ctx contractapi.TransactionContextInterface, campaign *Campaign) error {
+	campaignKey, err := ctx.GetStub().CreateCompositeKey(campaignPrefix, []string{campaign.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	campaignJSON, err := json.Marshal(campaign)
+	if err != nil {
+		return fmt.Errorf("failed to marshal campaign: %v", err)
+	}
+	err = ctx.GetStub().PutState(campaignKey, campaignJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put campaign %s: %v", campaign.ID, err)
+	}
+	return nil
+}
+
+// _putPledge writes a backer's pledge to the world state
+func _putPledge(ctx contractapi.TransactionContextInterface, pledge *Pledge) error {
+	pledgeKey, err := ctx.GetStub().CreateCompositeKey(pledgePrefix, []string{pledge.CampaignID, pledge.Backer})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	pledgeJSON, err := json.Marshal(pledge)
+	if err != nil {
+		return fmt.Errorf("failed to marshal pledge: %v", err)
+	}
+	err = ctx.GetStub().PutState(pledgeKey, pledgeJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put pledge: %v", err)
+	}
+	return nil
+}
+
+// _emitEvent marshals the payload and sets it as the chaincode event
+func _emitEvent(ctx contractapi.TransactionContextInterface, name string, payload event) error {
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	err = ctx.GetStub().SetEvent(name, payloadJSON)
+	if err != nil {
+		return fmt.Errorf("failed to set event: %v", err)
+	}
+	return nil
+}
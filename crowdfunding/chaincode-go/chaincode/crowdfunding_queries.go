@@ -0,0 +1,84 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// ReadCampaign returns the campaign stored in the world state with the given ID
+func (s *SmartContract) ReadCampaign(ctx contractapi.TransactionContextInterface, campaignID string) (*Campaign, error) {
+	campaignKey, err := ctx.GetStub().CreateCompositeKey(campaignPrefix, []string{campaignID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	campaignJSON, err := ctx.GetStub().GetState(campaignKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if campaignJSON == nil {
+		return nil, fmt.Errorf("the campaign %s does not exist", campaignID)
+	}
+
+	var campaign Campaign
+	err = json.Unmarshal(campaignJSON, &campaign)
+	if err != nil {
+		return nil, err
+	}
+	return &campaign, nil
+}
+
+// GetPledge returns the total a backer has pledged to a campaign, which is zero if they have not pledged
+func (s *SmartContract) GetPledge(ctx contractapi.TransactionContextInterface, campaignID string, backer string) (*Pledge, error) {
+	pledgeKey, err := ctx.GetStub().CreateCompositeKey(pledgePrefix, []string{campaignID, backer})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	pledgeJSON, err := ctx.GetStub().GetState(pledgeKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if pledgeJSON == nil {
+		return &Pledge{CampaignID: campaignID, Backer: backer}, nil
+	}
+
+	var pledge Pledge
+	err = json.Unmarshal(pledgeJSON, &pledge)
+	if err != nil {
+		return nil, err
+	}
+	return &pledge, nil
+}
+
+// GetPledges returns every pledge made to a campaign
+func (s *SmartContract) GetPledges(ctx contractapi.TransactionContextInterface, campaignID string) ([]*Pledge, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(pledgePrefix, []string{campaignID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pledges for campaign %s: %v", campaignID, err)
+	}
+	defer resultsIterator.Close()
+
+	var pledges []*Pledge
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var pledge Pledge
+		err = json.Unmarshal(response.Value, &pledge)
+		if err != nil {
+			return nil, err
+		}
+		pledges = append(pledges, &pledge)
+	}
+
+	return pledges, nil
+}
@@ -0,0 +1,193 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+)
+
+const (
+	backerMSPID = "Org2MSP"
+
+	// deadline is 1000 transactions after the first one of a test
+	deadline = "2020-09-13T12:43:20Z"
+)
+
+// fakeToken stands in for the token chaincode. Batches are paid from the payer's account,
+// debited once with the total as by the token chaincode.
+type fakeToken struct {
+	payer    string
+	balances map[string]int
+	batches  int
+}
+
+func (f *fakeToken) invoke(args [][]byte) pb.Response {
+	if string(args[0]) != "BatchTransfer" {
+		return shim.Error("unexpected function " + string(args[0]))
+	}
+	var payments []struct {
+		Receiver string `json:"receiver"`
+		Amount   int    `json:"amount"`
+	}
+	err := json.Unmarshal(args[1], &payments)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	total := 0
+	for _, payment := range payments {
+		total += payment.Amount
+	}
+	if f.balances[f.payer] < total {
+		return shim.Error(fmt.Sprintf("failed to transfer: client account %s has insufficient funds", f.payer))
+	}
+	f.balances[f.payer] -= total
+	for _, payment := range payments {
+		f.balances[payment.Receiver] += payment.Amount
+	}
+	f.batches++
+	return shim.Success(nil)
+}
+
+func checkError(t *testing.T, err error, expected string) {
+	t.Helper()
+	if expected == "" && err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected != "" && (err == nil || !strings.Contains(err.Error(), expected)) {
+		t.Fatalf("expected error containing %q, got %v", expected, err)
+	}
+}
+
+func checkCampaign(t *testing.T, stub *fakeStub, status string, raised int) {
+	t.Helper()
+	campaign, err := new(SmartContract).ReadCampaign(newContext(stub, "escrow", platformMSPID), "campaign1")
+	if err != nil {
+		t.Fatalf("failed to read campaign: %v", err)
+	}
+	if campaign.Status != status || campaign.Raised != raised {
+		t.Fatalf("expected campaign %s with %d raised, got %s with %d", status, raised, campaign.Status, campaign.Raised)
+	}
+}
+
+// createCampaign appoints the escrow agent and opens a campaign for a goal of 1000
+func createCampaign(t *testing.T, stub *fakeStub) *fakeToken {
+	t.Helper()
+	token := &fakeToken{balances: make(map[string]int)}
+	stub.chaincodes[defaultTokenChaincode] = token.invoke
+
+	contract := new(SmartContract)
+	checkError(t, contract.SetEscrowAgent(newContext(stub, "operator", platformMSPID), "escrow"), "")
+	checkError(t, contract.CreateCampaign(newContext(stub, "creator", backerMSPID), "campaign1", "Community garden", "gardener", 1000, deadline, ""), "")
+	return token
+}
+
+// pledge pays a pledge from a backer holding just enough tokens
+func pledge(t *testing.T, stub *fakeStub, token *fakeToken, backer string, amount int) {
+	t.Helper()
+	token.payer = backer
+	token.balances[backer] += amount
+	checkError(t, new(SmartContract).Pledge(newContext(stub, backer, backerMSPID), "campaign1", amount), "")
+}
+
+// passDeadline moves the clock past the campaign deadline
+func passDeadline(stub *fakeStub) {
+	stub.txCount = 1000
+}
+
+func TestCreateCampaign(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+
+	err := contract.CreateCampaign(newContext(stub, "creator", backerMSPID), "campaign1", "Garden", "gardener", 1000, deadline, "")
+	checkError(t, err, "no escrow agent has been appointed")
+
+	err = contract.SetEscrowAgent(newContext(stub, "creator", backerMSPID), "creator")
+	checkError(t, err, "client from Org2MSP is not authorized to appoint the escrow agent")
+
+	createCampaign(t, stub)
+	err = contract.CreateCampaign(newContext(stub, "creator", backerMSPID), "campaign2", "Garden", "gardener", 1000, "2020-09-13T12:26:40Z", "")
+	checkError(t, err, "deadline 2020-09-13T12:26:40Z is in the past")
+}
+
+func TestSettleFunded(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	token := createCampaign(t, stub)
+	pledge(t, stub, token, "backer1", 600)
+	pledge(t, stub, token, "backer2", 400)
+	checkCampaign(t, stub, statusOpen, 1000)
+
+	token.payer = "escrow"
+	err := contract.Settle(newContext(stub, "escrow", platformMSPID), "campaign1")
+	checkError(t, err, "campaign campaign1 is open until "+deadline)
+
+	passDeadline(stub)
+	err = contract.Pledge(newContext(stub, "backer1", backerMSPID), "campaign1", 100)
+	checkError(t, err, "campaign campaign1 closed at "+deadline)
+
+	err = contract.Settle(newContext(stub, "backer1", backerMSPID), "campaign1")
+	checkError(t, err, "only the escrow agent can settle campaign campaign1")
+
+	checkError(t, contract.Settle(newContext(stub, "escrow", platformMSPID), "campaign1"), "")
+	checkCampaign(t, stub, statusFunded, 1000)
+	if token.balances["gardener"] != 1000 || token.balances["escrow"] != 0 {
+		t.Fatalf("unexpected balances %v", token.balances)
+	}
+
+	err = contract.Settle(newContext(stub, "escrow", platformMSPID), "campaign1")
+	checkError(t, err, "campaign campaign1 is already FUNDED")
+}
+
+func TestSettleRefunds(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	token := createCampaign(t, stub)
+	for i := 0; i < maxRefundsPerSettle+1; i++ {
+		pledge(t, stub, token, fmt.Sprintf("backer%03d", i), 5)
+	}
+
+	err := contract.CancelCampaign(newContext(stub, "backer000", backerMSPID), "campaign1")
+	checkError(t, err, "only the creator can cancel campaign campaign1")
+	checkError(t, contract.CancelCampaign(newContext(stub, "creator", backerMSPID), "campaign1"), "")
+
+	// the first Settle refunds as many backers as one BatchTransfer pays, the second the rest
+	token.payer = "escrow"
+	checkError(t, contract.Settle(newContext(stub, "escrow", platformMSPID), "campaign1"), "")
+	checkCampaign(t, stub, statusRefunding, 505)
+	if token.balances["escrow"] != 5 || token.balances["backer099"] != 5 || token.balances["backer100"] != 0 {
+		t.Fatalf("unexpected balances after the first refunds %v", token.balances)
+	}
+
+	checkError(t, contract.Settle(newContext(stub, "escrow", platformMSPID), "campaign1"), "")
+	checkCampaign(t, stub, statusRefunded, 505)
+	if token.balances["escrow"] != 0 || token.balances["backer100"] != 5 || token.batches != maxRefundsPerSettle+3 {
+		t.Fatalf("unexpected balances after the last refunds %v, %d batches", token.balances, token.batches)
+	}
+
+	pledges, err := contract.GetPledges(newContext(stub, "escrow", platformMSPID), "campaign1")
+	checkError(t, err, "")
+	for _, pledge := range pledges {
+		if !pledge.Refunded {
+			t.Fatalf("pledge of %s was not refunded", pledge.Backer)
+		}
+	}
+}
+
+func TestSettleInsufficientFunds(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	token := createCampaign(t, stub)
+	pledge(t, stub, token, "backer1", 300)
+	passDeadline(stub)
+
+	// the escrow agent spent the escrowed tokens elsewhere
+	token.balances["escrow"] = 299
+	token.payer = "escrow"
+	err := contract.Settle(newContext(stub, "escrow", platformMSPID), "campaign1")
+	checkError(t, err, "failed to refund backers: failed to transfer tokens on token_erc20: failed to transfer: client account escrow has insufficient funds")
+	checkCampaign(t, stub, statusOpen, 300)
+}
@@ -0,0 +1,23 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"log"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/crowdfunding/chaincode-go/chaincode"
+)
+
+func main() {
+	crowdfundingChaincode, err := contractapi.NewChaincode(&chaincode.SmartContract{})
+	if err != nil {
+		log.Panicf("Error creating crowdfunding chaincode: %v", err)
+	}
+
+	if err := crowdfundingChaincode.Start(); err != nil {
+		log.Panicf("Error starting crowdfunding chaincode: %v", err)
+	}
+}
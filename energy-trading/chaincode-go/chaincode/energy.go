@@ -0,0 +1,899 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil"
+)
+
+// This sample assumes Org1 operates the local energy market. Only the operator can register
+// meters, whitelist the meter data oracles and open delivery windows.
+const operatorMSPID = "Org1MSP"
+
+// chaincode name used in the test network READMEs
+const defaultTokenChaincode = "token_erc20"
+
+// object names for prefix
+const (
+	meterPrefix   = "meter"
+	oraclePrefix  = "oracle"
+	windowPrefix  = "window"
+	offerPrefix   = "offer"
+	tradePrefix   = "trade"
+	readingPrefix = "reading"
+)
+
+// window status values
+const (
+	windowOpen    = "OPEN"
+	windowClosed  = "CLOSED"
+	windowSettled = "SETTLED"
+)
+
+// bounds keeping energy values and prices small enough that their product cannot overflow
+const (
+	maxEnergyWh    = 1000000000
+	maxPricePerKWh = 1000000
+)
+
+// readingGracePeriod is how long after the end of a window the operator waits for missing meter
+// readings before settling without them
+const readingGracePeriod = 24 * time.Hour
+
+// maxInstructionsPerSettlement is the most instructions one ExecuteSettlement pays, the most
+// payments one BatchTransfer of the token chaincode makes
+const maxInstructionsPerSettlement = 100
+
+// SmartContract provides functions for trading locally generated energy between prosumers and
+// consumers
+type SmartContract struct {
+	contractapi.Contract
+}
+
+// Meter is a smart meter registered by the operator. Owner is the client ID that trades for the
+// meter, and the token account its trades are settled from and into.
+type Meter struct {
+	ObjectType string `json:"objectType"`
+	ID         string `json:"meterID"`
+	Owner      string `json:"owner"`
+	Active     bool   `json:"active"`
+}
+
+// Window is a delivery period of the market. Offers are posted and accepted until the window
+// starts; once it has ended and the meters have reported, the window is settled. The window is
+// only read while trading, so trades in the same window do not conflict, and its totals are set
+// by SettleWindow.
+type Window struct {
+	ObjectType     string         `json:"objectType"`
+	ID             string         `json:"windowID"`
+	Start          string         `json:"start"`
+	End            string         `json:"end"`
+	Status         string         `json:"status"`
+	TokenChaincode string         `json:"tokenChaincode"`
+	TradeCount     int            `json:"tradeCount"`
+	TradedWh       int            `json:"tradedWh"`
+	DeliveredWh    int            `json:"deliveredWh"`
+	Positions      []*Position    `json:"positions,omitempty" metadata:",optional"`
+	Instructions   []*Instruction `json:"instructions,omitempty" metadata:",optional"`
+}
+
+// Offer is energy a prosumer offers to export from its meter during a window at a price in
+// tokens per kWh. RemainingWh is the energy not accepted yet.
+type Offer struct {
+	ObjectType  string `json:"objectType"`
+	WindowID    string `json:"windowID"`
+	ID          string `json:"offerID"`
+	MeterID     string `json:"meterID"`
+	Seller      string `json:"seller"`
+	EnergyWh    int    `json:"energyWh"`
+	RemainingWh int    `json:"remainingWh"`
+	PricePerKWh int    `json:"pricePerKWh"`
+	// TradeSeq is the sequence number of the latest trade of the offer
+	TradeSeq int `json:"tradeSeq"`
+}
+
+// Trade is the acceptance of part or all of an offer by a consumer. DeliveredWh and Value are
+// set when the window is settled.
+type Trade struct {
+	ObjectType   string `json:"objectType"`
+	WindowID     string `json:"windowID"`
+	ID           string `json:"tradeID"`
+	OfferID      string `json:"offerID"`
+	SellerMeter  string `json:"sellerMeter"`
+	Seller       string `json:"seller"`
+	BuyerMeter   string `json:"buyerMeter"`
+	Buyer        string `json:"buyer"`
+	EnergyWh     int    `json:"energyWh"`
+	PricePerKWh  int    `json:"pricePerKWh"`
+	DeliveredWh  int    `json:"deliveredWh"`
+	Value        int    `json:"value"`
+	AcceptedTxID string `json:"acceptedTxID"`
+}
+
+// Reading is the energy a meter exported and imported during a window, submitted by a
+// whitelisted meter data oracle
+type Reading struct {
+	ObjectType string `json:"objectType"`
+	WindowID   string `json:"windowID"`
+	MeterID    string `json:"meterID"`
+	ExportedWh int    `json:"exportedWh"`
+	ImportedWh int    `json:"importedWh"`
+	Oracle     string `json:"oracle"`
+	TxID       string `json:"txID"`
+}
+
+// Position is an account's net position in a closed window, positive when it receives
+type Position struct {
+	Account string `json:"account"`
+	Net     int    `json:"net"`
+}
+
+// Instruction is one settlement transfer from a net payer to a net receiver
+type Instruction struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Amount   int    `json:"amount"`
+	Executed bool   `json:"executed"`
+	TxID     string `json:"txID,omitempty" metadata:",optional"`
+}
+
+// tradeEvent is emitted when an offer is accepted
+type tradeEvent struct {
+	WindowID    string `json:"windowID"`
+	TradeID     string `json:"tradeID"`
+	SellerMeter string `json:"sellerMeter"`
+	BuyerMeter  string `json:"buyerMeter"`
+	EnergyWh    int    `json:"energyWh"`
+	PricePerKWh int    `json:"pricePerKWh"`
+}
+
+// windowEvent is emitted when a window is closed and when settlement transfers are executed
+type windowEvent struct {
+	WindowID     string `json:"windowID"`
+	Status       string `json:"status"`
+	DeliveredWh  int    `json:"deliveredWh"`
+	Instructions int    `json:"instructions"`
+}
+
+// RegisterMeter registers a smart meter traded by the owner client ID, or moves it to a new
+// owner. Only the operator can register meters.
+func (s *SmartContract) RegisterMeter(ctx contractapi.TransactionContextInterface, meterID string, owner string) error {
+	err := _requireOperator(ctx)
+	if err != nil {
+		return err
+	}
+	if meterID == "" || owner == "" {
+		return fmt.Errorf("meter ID and owner must be set")
+	}
+
+	meter := Meter{
+		ObjectType: meterPrefix,
+		ID:         meterID,
+		Owner:      owner,
+		Active:     true,
+	}
+	return _putMeter(ctx, &meter)
+}
+
+// DeactivateMeter stops a meter posting and accepting offers. Its trades in windows already
+// traded are still settled.
+func (s *SmartContract) DeactivateMeter(ctx contractapi.TransactionContextInterface, meterID string) error {
+	err := _requireOperator(ctx)
+	if err != nil {
+		return err
+	}
+
+	meter, err := s.GetMeter(ctx, meterID)
+	if err != nil {
+		return err
+	}
+	meter.Active = false
+	return _putMeter(ctx, meter)
+}
+
+// AddOracle whitelists the client ID of a meter data service to submit readings
+func (s *SmartContract) AddOracle(ctx contractapi.TransactionContextInterface, oracle string) error {
+	err := _requireOperator(ctx)
+	if err != nil {
+		return err
+	}
+	if oracle == "" {
+		return fmt.Errorf("oracle must be set")
+	}
+
+	oracleKey, err := ctx.GetStub().CreateCompositeKey(oraclePrefix, []string{oracle})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	return ctx.GetStub().PutState(oracleKey, []byte{0x00})
+}
+
+// RemoveOracle takes a meter data service off the whitelist
+func (s *SmartContract) RemoveOracle(ctx contractapi.TransactionContextInterface, oracle string) error {
+	err := _requireOperator(ctx)
+	if err != nil {
+		return err
+	}
+
+	oracleKey, err := ctx.GetStub().CreateCompositeKey(oraclePrefix, []string{oracle})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	return ctx.GetStub().DelState(oracleKey)
+}
+
+// OpenWindow opens a delivery window for trading. start is an RFC 3339 time in the future and the
+// window lasts durationMinutes. Leave tokenChaincode empty to use token_erc20. Only the operator can
+// open windows.
+func (s *SmartContract) OpenWindow(ctx contractapi.TransactionContextInterface, windowID string, start string, durationMinutes int, tokenChaincode string) error {
+	err := _requireOperator(ctx)
+	if err != nil {
+		return err
+	}
+	if windowID == "" {
+		return fmt.Errorf("window ID must be set")
+	}
+	if durationMinutes <= 0 || durationMinutes > 24*60 {
+		return fmt.Errorf("window duration must be between 1 and %d minutes", 24*60)
+	}
+	if tokenChaincode == "" {
+		tokenChaincode = defaultTokenChaincode
+	}
+	startTime, err := time.Parse(time.RFC3339, start)
+	if err != nil {
+		return fmt.Errorf("start %s is not an RFC 3339 time", start)
+	}
+
+	now, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return err
+	}
+	if !startTime.After(now) {
+		return fmt.Errorf("window %s must start in the future", windowID)
+	}
+	existing, err := _getWindow(ctx, windowID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("the window %s already exists", windowID)
+	}
+
+	window := Window{
+		ObjectType:     windowPrefix,
+		ID:             windowID,
+		Start:          startTime.UTC().Format(time.RFC3339),
+		End:            startTime.Add(time.Duration(durationMinutes) * time.Minute).UTC().Format(time.RFC3339),
+		Status:         windowOpen,
+		TokenChaincode: tokenChaincode,
+	}
+	return _putWindow(ctx, &window)
+}
+
+// PostOffer is called by the owner of a meter to offer energy exported from it during a window,
+// at a price in tokens per kWh, until the window starts
+func (s *SmartContract) PostOffer(ctx contractapi.TransactionContextInterface, windowID string, offerID string, meterID string, energyWh int, pricePerKWh int) error {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	meter, err := _requireMeterOwner(ctx, meterID, clientID)
+	if err != nil {
+		return err
+	}
+	if offerID == "" {
+		return fmt.Errorf("offer ID must be set")
+	}
+	if energyWh <= 0 || energyWh > maxEnergyWh {
+		return fmt.Errorf("energy must be between 1 and %d Wh", maxEnergyWh)
+	}
+	if pricePerKWh <= 0 || pricePerKWh > maxPricePerKWh {
+		return fmt.Errorf("price must be between 1 and %d tokens per kWh", maxPricePerKWh)
+	}
+	_, err = s._tradingWindow(ctx, windowID)
+	if err != nil {
+		return err
+	}
+	existing, err := _getOffer(ctx, windowID, offerID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("offer %s already exists in window %s", offerID, windowID)
+	}
+
+	offer := Offer{
+		ObjectType:  offerPrefix,
+		WindowID:    windowID,
+		ID:          offerID,
+		MeterID:     meter.ID,
+		Seller:      clientID,
+		EnergyWh:    energyWh,
+		RemainingWh: energyWh,
+		PricePerKWh: pricePerKWh,
+	}
+	return _putOffer(ctx, &offer)
+}
+
+// CancelOffer withdraws the part of an offer not accepted yet. Only the seller can cancel an
+// offer, and only until the window starts.
+func (s *SmartContract) CancelOffer(ctx contractapi.TransactionContextInterface, windowID string, offerID string) error {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	offer, err := s.GetOffer(ctx, windowID, offerID)
+	if err != nil {
+		return err
+	}
+	if offer.Seller != clientID {
+		return fmt.Errorf("only the seller can cancel offer %s", offerID)
+	}
+	_, err = s._tradingWindow(ctx, windowID)
+	if err != nil {
+		return err
+	}
+
+	offer.RemainingWh = 0
+	return _putOffer(ctx, offer)
+}
+
+// AcceptOffer is called by the owner of a meter to buy energyWh of an offer for delivery to that
+// meter. Offers can be accepted in parts until the window starts. It returns the ID of the trade.
+func (s *SmartContract) AcceptOffer(ctx contractapi.TransactionContextInterface, windowID string, offerID string, meterID string, energyWh int) (string, error) {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	meter, err := _requireMeterOwner(ctx, meterID, clientID)
+	if err != nil {
+		return "", err
+	}
+	_, err = s._tradingWindow(ctx, windowID)
+	if err != nil {
+		return "", err
+	}
+	offer, err := s.GetOffer(ctx, windowID, offerID)
+	if err != nil {
+		return "", err
+	}
+	if offer.Seller == clientID || offer.MeterID == meterID {
+		return "", fmt.Errorf("a meter owner cannot buy its own offer")
+	}
+	if energyWh <= 0 || energyWh > offer.RemainingWh {
+		return "", fmt.Errorf("energy must be between 1 and the %d Wh remaining in offer %s", offer.RemainingWh, offerID)
+	}
+
+	offer.RemainingWh -= energyWh
+	offer.TradeSeq++
+	err = _putOffer(ctx, offer)
+	if err != nil {
+		return "", err
+	}
+
+	trade := Trade{
+		ObjectType:   tradePrefix,
+		WindowID:     windowID,
+		ID:           fmt.Sprintf("%s.%04d", offerID, offer.TradeSeq),
+		OfferID:      offerID,
+		SellerMeter:  offer.MeterID,
+		Seller:       offer.Seller,
+		BuyerMeter:   meter.ID,
+		Buyer:        clientID,
+		EnergyWh:     energyWh,
+		PricePerKWh:  offer.PricePerKWh,
+		AcceptedTxID: ctx.GetStub().GetTxID(),
+	}
+	err = _putTrade(ctx, &trade)
+	if err != nil {
+		return "", err
+	}
+
+	err = _emitEvent(ctx, "TradeMatched", tradeEvent{windowID, trade.ID, trade.SellerMeter, trade.BuyerMeter, energyWh, trade.PricePerKWh})
+	if err != nil {
+		return "", err
+	}
+	return trade.ID, nil
+}
+
+// SubmitReading records the energy a meter exported and imported during a window. It is called
+// by a whitelisted meter data oracle once the window has ended, once per meter and window, and
+// only the reading key is written so readings of different meters do not conflict.
+func (s *SmartContract) SubmitReading(ctx contractapi.TransactionContextInterface, windowID string, meterID string, exportedWh int, importedWh int) error {
+	oracle, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	whitelisted, err := _isOracle(ctx, oracle)
+	if err != nil {
+		return err
+	}
+	if !whitelisted {
+		return fmt.Errorf("client is not a whitelisted meter data oracle")
+	}
+	if _, err := s.GetMeter(ctx, meterID); err != nil {
+		return err
+	}
+	if exportedWh < 0 || exportedWh > maxEnergyWh || importedWh < 0 || importedWh > maxEnergyWh {
+		return fmt.Errorf("exported and imported energy must be between 0 and %d Wh", maxEnergyWh)
+	}
+
+	window, err := s.GetWindow(ctx, windowID)
+	if err != nil {
+		return err
+	}
+	if window.Status != windowOpen {
+		return fmt.Errorf("window %s is %s and takes no more readings", windowID, window.Status)
+	}
+	now, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return err
+	}
+	end, err := time.Parse(time.RFC3339, window.End)
+	if err != nil {
+		return fmt.Errorf("failed to parse end of window %s: %v", windowID, err)
+	}
+	if now.Before(end) {
+		return fmt.Errorf("window %s has not ended", windowID)
+	}
+
+	existing, err := _getReading(ctx, windowID, meterID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("meter %s has already been read for window %s", meterID, windowID)
+	}
+	reading := Reading{
+		ObjectType: readingPrefix,
+		WindowID:   windowID,
+		MeterID:    meterID,
+		ExportedWh: exportedWh,
+		ImportedWh: importedWh,
+		Oracle:     oracle,
+		TxID:       ctx.GetStub().GetTxID(),
+	}
+	readingKey, err := ctx.GetStub().CreateCompositeKey(readingPrefix, []string{windowID, meterID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	readingJSON, err := json.Marshal(reading)
+	if err != nil {
+		return fmt.Errorf("failed to marshal reading: %v", err)
+	}
+	return ctx.GetStub().PutState(readingKey, readingJSON)
+}
+
+// SettleWindow confirms the delivery of every trade of an ended window from the meter readings and
+// nets the payments. Trades are confirmed in trade ID order, each for the energy its seller's meter
+// exported and its buyer's meter imported that earlier trades did not use, and valued at the
+// trade price rounded down to whole tokens. Each account's net position is what it sold minus
+// what it bought; the positions are settled with at most one transfer fewer than there are
+// accounts with a non-zero position. Readings must be in for every traded meter, unless the
+// reading grace period has passed, after which a missing reading counts as no energy. Only the
+// operator can settle windows.
+func (s *SmartContract) SettleWindow(ctx contractapi.TransactionContextInterface, windowID string) error {
+	err := _requireOperator(ctx)
+	if err != nil {
+		return err
+	}
+
+	window, err := s.GetWindow(ctx, windowID)
+	if err != nil {
+		return err
+	}
+	if window.Status != windowOpen {
+		return fmt.Errorf("window %s is already %s", windowID, window.Status)
+	}
+	now, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return err
+	}
+	end, err := time.Parse(time.RFC3339, window.End)
+	if err != nil {
+		return fmt.Errorf("failed to parse end of window %s: %v", windowID, err)
+	}
+	if now.Before(end) {
+		return fmt.Errorf("window %s has not ended", windowID)
+	}
+
+	trades, err := s.GetTrades(ctx, windowID)
+	if err != nil {
+		return err
+	}
+	exported := make(map[string]int)
+	imported := make(map[string]int)
+	var missing []string
+	for _, trade := range trades {
+		for _, meterID := range []string{trade.SellerMeter, trade.BuyerMeter} {
+			if _, read := exported[meterID]; read {
+				continue
+			}
+			reading, err := _getReading(ctx, windowID, meterID)
+			if err != nil {
+				return err
+			}
+			if reading == nil {
+				missing = append(missing, meterID)
+				exported[meterID], imported[meterID] = 0, 0
+				continue
+			}
+			exported[meterID], imported[meterID] = reading.ExportedWh, reading.ImportedWh
+		}
+	}
+	if len(missing) > 0 && now.Before(end.Add(readingGracePeriod)) {
+		return fmt.Errorf("window %s is missing the readings of meters %v", windowID, missing)
+	}
+
+	net := make(map[string]int)
+	window.TradeCount = len(trades)
+	for _, trade := range trades {
+		delivered := trade.EnergyWh
+		if exported[trade.SellerMeter] < delivered {
+			delivered = exported[trade.SellerMeter]
+		}
+		if imported[trade.BuyerMeter] < delivered {
+			delivered = imported[trade.BuyerMeter]
+		}
+		exported[trade.SellerMeter] -= delivered
+		imported[trade.BuyerMeter] -= delivered
+
+		trade.DeliveredWh = delivered
+		trade.Value = delivered * trade.PricePerKWh / 1000
+		err = _putTrade(ctx, trade)
+		if err != nil {
+			return err
+		}
+		window.TradedWh += trade.EnergyWh
+		window.DeliveredWh += delivered
+		net[trade.Seller] += trade.Value
+		net[trade.Buyer] -= trade.Value
+	}
+
+	var payers, receivers []*Position
+	window.Positions = nil
+	for account, amount := range net {
+		window.Positions = append(window.Positions, &Position{Account: account, Net: amount})
+		if amount < 0 {
+			payers = append(payers, &Position{Account: account, Net: -amount})
+		} else if amount > 0 {
+			receivers = append(receivers, &Position{Account: account, Net: amount})
+		}
+	}
+	sort.Slice(window.Positions, func(i, j int) bool { return window.Positions[i].Account < window.Positions[j].Account })
+	_sortPositions(payers)
+	_sortPositions(receivers)
+
+	window.Instructions = nil
+	for len(payers) > 0 && len(receivers) > 0 {
+		payer, receiver := payers[0], receivers[0]
+		amount := payer.Net
+		if receiver.Net < amount {
+			amount = receiver.Net
+		}
+		window.Instructions = append(window.Instructions, &Instruction{From: payer.Account, To: receiver.Account, Amount: amount})
+
+		payer.Net -= amount
+		receiver.Net -= amount
+		if payer.Net == 0 {
+			payers = payers[1:]
+		}
+		if receiver.Net == 0 {
+			receivers = receivers[1:]
+		}
+		_sortPositions(payers)
+		_sortPositions(receivers)
+	}
+
+	window.Status = windowClosed
+	if len(window.Instructions) == 0 {
+		window.Status = windowSettled
+	}
+	err = _putWindow(ctx, window)
+	if err != nil {
+		return err
+	}
+	return _emitEvent(ctx, "WindowClosed", windowEvent{window.ID, window.Status, window.DeliveredWh, len(window.Instructions)})
+}
+
+// ExecuteSettlement is called by a net paying account of a closed window. Every instruction of the
+// account is paid from its token account to the receiving accounts with one BatchTransfer,
+// debiting it once; an account with more than maxInstructionsPerSettlement instructions calls it
+// again for the rest. The window is settled once every instruction has been executed.
+func (s *SmartContract) ExecuteSettlement(ctx contractapi.TransactionContextInterface, windowID string) error {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	window, err := s.GetWindow(ctx, windowID)
+	if err != nil {
+		return err
+	}
+	if window.Status != windowClosed {
+		return fmt.Errorf("window %s is %s, only closed windows are settled", windowID, window.Status)
+	}
+
+	var payments []ledgerutil.TokenPayment
+	executed := 0
+	pending := 0
+	for _, instruction := range window.Instructions {
+		if instruction.From == clientID && !instruction.Executed && executed < maxInstructionsPerSettlement {
+			payments = append(payments, ledgerutil.TokenPayment{Receiver: instruction.To, Amount: instruction.Amount})
+			instruction.Executed = true
+			instruction.TxID = ctx.GetStub().GetTxID()
+			executed++
+		}
+		if !instruction.Executed {
+			pending++
+		}
+	}
+	if executed == 0 {
+		return fmt.Errorf("client has nothing to settle in window %s", windowID)
+	}
+	err = ledgerutil.TransferTokens(ctx, window.TokenChaincode, payments...)
+	if err != nil {
+		return err
+	}
+
+	if pending == 0 {
+		window.Status = windowSettled
+	}
+	err = _putWindow(ctx, window)
+	if err != nil {
+		return err
+	}
+	return _emitEvent(ctx, "SettlementExecuted", windowEvent{window.ID, window.Status, window.DeliveredWh, executed})
+}
+
+// _tradingWindow returns a window that is open and has not started, so offers can be posted,
+// cancelled and accepted
+func (s *SmartContract) _tradingWindow(ctx contractapi.TransactionContextInterface, windowID string) (*Window, error) {
+	window, err := s.GetWindow(ctx, windowID)
+	if err != nil {
+		return nil, err
+	}
+	now, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	start, err := time.Parse(time.RFC3339, window.Start)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse start of window %s: %v", windowID, err)
+	}
+	if window.Status != windowOpen || !now.Before(start) {
+		return nil, fmt.Errorf("trading in window %s closed at %s", windowID, window.Start)
+	}
+	return window, nil
+}
+
+// _sortPositions orders positions largest first, then by account
+func _sortPositions(positions []*Position) {
+	sort.Slice(positions, func(i, j int) bool {
+		if positions[i].Net != positions[j].Net {
+			return positions[i].Net > positions[j].Net
+		}
+		return positions[i].Account < positions[j].Account
+	})
+}
+
+// _requireOperator checks the submitting client belongs to the operator org
+func _requireOperator(ctx contractapi.TransactionContextInterface) error {
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+	if clientMSPID != operatorMSPID {
+		return fmt.Errorf("client from %s is not the market operator", clientMSPID)
+	}
+	return nil
+}
+
+// _requireMeterOwner returns the meter when it is active and owned by the client
+func _requireMeterOwner(ctx contractapi.TransactionContextInterface, meterID string, clientID string) (*Meter, error) {
+	meter, err := _getMeter(ctx, meterID)
+	if err != nil {
+		return nil, err
+	}
+	if meter == nil || meter.Owner != clientID {
+		return nil, fmt.Errorf("client does not own meter %s", meterID)
+	}
+	if !meter.Active {
+		return nil, fmt.Errorf("meter %s is not active", meterID)
+	}
+	return meter, nil
+}
+
+// _isOracle returns true when the client is a whitelisted meter data oracle
+func _isOracle(ctx contractapi.TransactionContextInterface, oracle string) (bool, error) {
+	oracleKey, err := ctx.GetStub().CreateCompositeKey(oraclePrefix, []string{oracle})
+	if err != nil {
+		return false, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	whitelisted, err := ctx.GetStub().GetState(oracleKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	return whitelisted != nil, nil
+}
+
+// _getMeter reads a meter, returning nil when it is not registered
+func _getMeter(ctx contractapi.TransactionContextInterface, meterID string) (*Meter, error) {
+	meterKey, err := ctx.GetStub().CreateCompositeKey(meterPrefix, []string{meterID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	meterJSON, err := ctx.GetStub().GetState(meterKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if meterJSON == nil {
+		return nil, nil
+	}
+
+	var meter Meter
+	err = json.Unmarshal(meterJSON, &meter)
+	if err != nil {
+		return nil, err
+	}
+	return &meter, nil
+}
+
+// _putMeter writes the meter to the world state
+func _putMeter(ctx contractapi.TransactionContextInterface, meter *Meter) error {
+	meterKey, err := ctx.GetStub().CreateCompositeKey(meterPrefix, []string{meter.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	meterJSON, err := json.Marshal(meter)
+	if err != nil {
+		return fmt.Errorf("failed to marshal meter: %v", err)
+	}
+	err = ctx.GetStub().PutState(meterKey, meterJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put meter %s: %v", meter.ID, err)
+	}
+	return nil
+}
+
+// _getWindow reads a window, returning nil when it does not exist
+func _getWindow(ctx contractapi.TransactionContextInterface, windowID string) (*Window, error) {
+	windowKey, err := ctx.GetStub().CreateCompositeKey(windowPrefix, []string{windowID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	windowJSON, err := ctx.GetStub().GetState(windowKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if windowJSON == nil {
+		return nil, nil
+	}
+
+	var window Window
+	err = json.Unmarshal(windowJSON, &window)
+	if err != nil {
+		return nil, err
+	}
+	return &window, nil
+}
+
+// _putWindow writes the window to the world state
+func _putWindow(ctx contractapi.TransactionContextInterface, window *Window) error {
+	windowKey, err := ctx.GetStub().CreateCompositeKey(windowPrefix, []string{window.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	windowJSON, err := json.Marshal(window)
+	if err != nil {
+		return fmt.Errorf("failed to marshal window: %v", err)
+	}
+	err = ctx.GetStub().PutState(windowKey, windowJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put window %s: %v", window.ID, err)
+	}
+	return nil
+}
+
+// _getOffer reads an offer, returning nil when it does not exist
+func _getOffer(ctx contractapi.TransactionContextInterface, windowID string, offerID string) (*Offer, error) {
+	offerKey, err := ctx.GetStub().CreateCompositeKey(offerPrefix, []string{windowID, offerID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	offerJSON, err := ctx.GetStub().GetState(offerKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if offerJSON == nil {
+		return nil, nil
+	}
+
+	var offer Offer
+	err = json.Unmarshal(offerJSON, &offer)
+	if err != nil {
+		return nil, err
+	}
+	return &offer, nil
+}
+
+// _putOffer writes the offer to the world state
+func _putOffer(ctx contractapi.TransactionContextInterface, offer *Offer) error {
+	offerKey, err := ctx.GetStub().CreateCompositeKey(offerPrefix, []string{offer.WindowID, offer.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	offerJSON, err := json.Marshal(offer)
+	if err != nil {
+		return fmt.Errorf("failed to marshal offer: %v", err)
+	}
+	err = ctx.GetStub().PutState(offerKey, offerJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put offer %s: %v", offer.ID, err)
+	}
+	return nil
+}
+
+// _putTrade writes the trade to the world state
+func _putTrade(ctx contractapi.TransactionContextInterface, trade *Trade) error {
+	tradeKey, err := ctx.GetStub().CreateCompositeKey(tradePrefix, []string{trade.WindowID, trade.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	tradeJSON, err := json.Marshal(trade)
+	if err != nil {
+		return fmt.Errorf("failed to marshal trade: %v", err)
+	}
+	err = ctx.GetStub().PutState(tradeKey, tradeJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put trade %s: %v", trade.ID, err)
+	}
+	return nil
+}
+
+// _getReading reads the reading of a meter for a window, returning nil when it was not submitted
+func _getReading(ctx contractapi.TransactionContextInterface, windowID string, meterID string) (*Reading, error) {
+	readingKey, err := ctx.GetStub().CreateCompositeKey(readingPrefix, []string{windowID, meterID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	readingJSON, err := ctx.GetStub().GetState(readingKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if readingJSON == nil {
+		return nil, nil
+	}
+
+	var reading Reading
+	err = json.Unmarshal(readingJSON, &reading)
+	if err != nil {
+		return nil, err
+	}
+	return &reading, nil
+}
+
+// _emitEvent marshals the payload and sets it as the chaincode event
+func _emitEvent(ctx contractapi.TransactionContextInterface, name string, payload interface{}) error {
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	err = ctx.GetStub().SetEvent(name, payloadJSON)
+	if err != nil {
+		return fmt.Errorf("failed to set event: %v", err)
+	}
+	return nil
+}
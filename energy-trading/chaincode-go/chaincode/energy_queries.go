@@ -0,0 +1,125 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// GetMeter returns the registration of a meter
+func (s *SmartContract) GetMeter(ctx contractapi.TransactionContextInterface, meterID string) (*Meter, error) {
+	meter, err := _getMeter(ctx, meterID)
+	if err != nil {
+		return nil, err
+	}
+	if meter == nil {
+		return nil, fmt.Errorf("meter %s is not registered", meterID)
+	}
+	return meter, nil
+}
+
+// GetWindow returns a delivery window, with its net positions and settlement instructions once
+// it is closed
+func (s *SmartContract) GetWindow(ctx contractapi.TransactionContextInterface, windowID string) (*Window, error) {
+	window, err := _getWindow(ctx, windowID)
+	if err != nil {
+		return nil, err
+	}
+	if window == nil {
+		return nil, fmt.Errorf("the window %s does not exist", windowID)
+	}
+	return window, nil
+}
+
+// GetOffer returns an offer of a window
+func (s *SmartContract) GetOffer(ctx contractapi.TransactionContextInterface, windowID string, offerID string) (*Offer, error) {
+	offer, err := _getOffer(ctx, windowID, offerID)
+	if err != nil {
+		return nil, err
+	}
+	if offer == nil {
+		return nil, fmt.Errorf("offer %s does not exist in window %s", offerID, windowID)
+	}
+	return offer, nil
+}
+
+// GetOffers returns the offers of a window, including offers fully accepted or cancelled
+func (s *SmartContract) GetOffers(ctx contractapi.TransactionContextInterface, windowID string) ([]*Offer, error) {
+	offers := []*Offer{}
+	err := _forEachInWindow(ctx, offerPrefix, windowID, func(value []byte) error {
+		var offer Offer
+		err := json.Unmarshal(value, &offer)
+		if err != nil {
+			return err
+		}
+		offers = append(offers, &offer)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return offers, nil
+}
+
+// GetTrades returns the trades of a window in trade ID order, which is the order their delivery
+// is confirmed in
+func (s *SmartContract) GetTrades(ctx contractapi.TransactionContextInterface, windowID string) ([]*Trade, error) {
+	trades := []*Trade{}
+	err := _forEachInWindow(ctx, tradePrefix, windowID, func(value []byte) error {
+		var trade Trade
+		err := json.Unmarshal(value, &trade)
+		if err != nil {
+			return err
+		}
+		trades = append(trades, &trade)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return trades, nil
+}
+
+// GetReadings returns the meter readings submitted for a window
+func (s *SmartContract) GetReadings(ctx contractapi.TransactionContextInterface, windowID string) ([]*Reading, error) {
+	readings := []*Reading{}
+	err := _forEachInWindow(ctx, readingPrefix, windowID, func(value []byte) error {
+		var reading Reading
+		err := json.Unmarshal(value, &reading)
+		if err != nil {
+			return err
+		}
+		readings = append(readings, &reading)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return readings, nil
+}
+
+// _forEachInWindow calls fn with the value of every key of an object type in a window
+func _forEachInWindow(ctx contractapi.TransactionContextInterface, objectType string, windowID string, fn func(value []byte) error) error {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(objectType, []string{windowID})
+	if err != nil {
+		return fmt.Errorf("failed to get %s records of window %s: %v", objectType, windowID, err)
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return err
+		}
+		err = fn(response.Value)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
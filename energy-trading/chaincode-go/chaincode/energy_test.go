@@ -0,0 +1,213 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/pkg/mockledger"
+	tokencc "github.com/hyperledger/fabric-samples/token-erc-20/chaincode-go/chaincode"
+)
+
+const energyName = "energy"
+
+// market is a mock ledger with the energy market and the token chaincode trades are settled in
+// deployed, its operator and oracle, and the client IDs of the clients enrolled on it
+type market struct {
+	ledger   *mockledger.Ledger
+	operator *mockledger.Client
+	oracle   *mockledger.Client
+	ids      map[*mockledger.Client]string
+}
+
+func newMarket(t *testing.T) *market {
+	t.Helper()
+	ledger := mockledger.New()
+	tokenContract := tokencc.NewContract()
+	token, err := contractapi.NewChaincode(tokenContract, tokencc.NewConfigContract(), tokencc.NewEventsContract())
+	if err != nil {
+		t.Fatal(err)
+	}
+	energy, err := contractapi.NewChaincode(new(SmartContract))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ledger.Deploy(defaultTokenChaincode, token); err != nil {
+		t.Fatal(err)
+	}
+	if err := ledger.Deploy(energyName, energy); err != nil {
+		t.Fatal(err)
+	}
+
+	m := &market{ledger: ledger, ids: make(map[*mockledger.Client]string)}
+	m.operator = m.client(t, operatorMSPID, "operator", 0)
+	m.oracle = m.client(t, operatorMSPID, "oracle", 0)
+	m.submit(t, m.operator, "AddOracle", m.ids[m.oracle])
+	return m
+}
+
+// client enrolls a client of the org and funds its token account with balance
+func (m *market) client(t *testing.T, mspID string, name string, balance int) *mockledger.Client {
+	t.Helper()
+	client, err := m.ledger.NewClient(mspID, name, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := m.ledger.Evaluate(client, mockledger.Transaction{Chaincode: defaultTokenChaincode, Function: "ClientAccountID"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.ids[client] = string(id)
+
+	stub, err := m.ledger.NewStub(nil, mockledger.Transaction{Chaincode: defaultTokenChaincode})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stub.PutState(string(id), []byte(strconv.Itoa(balance))); err != nil {
+		t.Fatal(err)
+	}
+	if err := stub.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+// meter enrolls a client of the org owning a registered meter
+func (m *market) meter(t *testing.T, mspID string, meterID string, balance int) *mockledger.Client {
+	t.Helper()
+	owner := m.client(t, mspID, meterID+"-owner", balance)
+	m.submit(t, m.operator, "RegisterMeter", meterID, m.ids[owner])
+	return owner
+}
+
+func (m *market) submit(t *testing.T, client *mockledger.Client, function string, args ...string) []byte {
+	t.Helper()
+	result, err := m.ledger.Submit(client, mockledger.Transaction{Chaincode: energyName, Function: function, Args: args})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result.Payload
+}
+
+func (m *market) submitError(t *testing.T, client *mockledger.Client, want string, function string, args ...string) {
+	t.Helper()
+	_, err := m.ledger.Submit(client, mockledger.Transaction{Chaincode: energyName, Function: function, Args: args})
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("%s failed with %v, want %q", function, err, want)
+	}
+}
+
+func (m *market) window(t *testing.T, windowID string) *Window {
+	t.Helper()
+	payload, err := m.ledger.Evaluate(m.operator, mockledger.Transaction{Chaincode: energyName, Function: "GetWindow", Args: []string{windowID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var window Window
+	if err := json.Unmarshal(payload, &window); err != nil {
+		t.Fatal(err)
+	}
+	return &window
+}
+
+// checkBalances fails the test when a client's token balance differs from want
+func (m *market) checkBalances(t *testing.T, want map[*mockledger.Client]int) {
+	t.Helper()
+	for client, balance := range want {
+		got := string(m.ledger.GetState(defaultTokenChaincode, m.ids[client]))
+		if got != strconv.Itoa(balance) {
+			t.Errorf("balance of %s is %s, want %d", m.ids[client], got, balance)
+		}
+	}
+}
+
+func TestTradeAndSettleWindow(t *testing.T) {
+	m := newMarket(t)
+	start := mockledger.StartTime.Add(time.Hour)
+	m.submit(t, m.operator, "OpenWindow", "w1", start.Format(time.RFC3339), "60", "")
+	seller1 := m.meter(t, "Org1MSP", "meter-1", 0)
+	seller2 := m.meter(t, "Org1MSP", "meter-2", 0)
+	buyer := m.meter(t, "Org2MSP", "meter-3", 100)
+	m.submitError(t, buyer, "client from Org2MSP is not the market operator", "RegisterMeter", "meter-4", m.ids[buyer])
+
+	m.submit(t, seller1, "PostOffer", "w1", "offer1", "meter-1", "5000", "12")
+	m.submit(t, seller2, "PostOffer", "w1", "offer2", "meter-2", "2000", "10")
+	m.submitError(t, buyer, "client does not own meter meter-1", "PostOffer", "w1", "offer3", "meter-1", "1000", "10")
+	if tradeID := string(m.submit(t, buyer, "AcceptOffer", "w1", "offer1", "meter-3", "3000")); tradeID != "offer1.0001" {
+		t.Errorf("AcceptOffer returned trade %s, want offer1.0001", tradeID)
+	}
+	m.submit(t, buyer, "AcceptOffer", "w1", "offer2", "meter-3", "2000")
+	m.submitError(t, buyer, "between 1 and the 2000 Wh remaining", "AcceptOffer", "w1", "offer1", "meter-3", "2001")
+	m.submitError(t, seller1, "cannot buy its own offer", "AcceptOffer", "w1", "offer1", "meter-1", "1000")
+
+	m.ledger.SetTime(start.Add(30 * time.Minute))
+	m.submitError(t, buyer, "trading in window w1 closed", "AcceptOffer", "w1", "offer1", "meter-3", "1000")
+	m.submitError(t, m.oracle, "window w1 has not ended", "SubmitReading", "w1", "meter-1", "4200", "0")
+
+	// meter-2 exported less than it sold, so its trade is delivered and paid in part
+	m.ledger.SetTime(start.Add(time.Hour))
+	m.submit(t, m.oracle, "SubmitReading", "w1", "meter-1", "4200", "0")
+	m.submit(t, m.oracle, "SubmitReading", "w1", "meter-2", "1500", "0")
+	m.submitError(t, m.operator, "missing the readings of meters [meter-3]", "SettleWindow", "w1")
+	m.submitError(t, buyer, "not a whitelisted meter data oracle", "SubmitReading", "w1", "meter-3", "0", "9000")
+	m.submit(t, m.oracle, "SubmitReading", "w1", "meter-3", "0", "6100")
+	m.submitError(t, m.oracle, "already been read", "SubmitReading", "w1", "meter-3", "0", "6100")
+	m.submit(t, m.operator, "SettleWindow", "w1")
+
+	window := m.window(t, "w1")
+	if window.Status != windowClosed || window.TradeCount != 2 || window.TradedWh != 5000 || window.DeliveredWh != 4500 {
+		t.Errorf("window after settling is %+v", window)
+	}
+	want := map[string]int{m.ids[seller1]: 36, m.ids[seller2]: 15}
+	if len(window.Instructions) != 2 {
+		t.Fatalf("window has %d instructions, want 2", len(window.Instructions))
+	}
+	for _, instruction := range window.Instructions {
+		if instruction.From != m.ids[buyer] || instruction.Amount != want[instruction.To] {
+			t.Errorf("unexpected instruction %+v", instruction)
+		}
+	}
+
+	// the buyer pays both sellers in one transaction, debited once with the whole 51 tokens
+	m.submitError(t, seller1, "client has nothing to settle in window w1", "ExecuteSettlement", "w1")
+	m.submit(t, buyer, "ExecuteSettlement", "w1")
+	m.checkBalances(t, map[*mockledger.Client]int{buyer: 49, seller1: 36, seller2: 15})
+	if window := m.window(t, "w1"); window.Status != windowSettled {
+		t.Errorf("window is %s after every instruction was paid, want %s", window.Status, windowSettled)
+	}
+	m.submitError(t, buyer, "only closed windows are settled", "ExecuteSettlement", "w1")
+}
+
+func TestSettleWindowAfterGracePeriod(t *testing.T) {
+	m := newMarket(t)
+	start := mockledger.StartTime.Add(time.Hour)
+	m.submit(t, m.operator, "OpenWindow", "w1", start.Format(time.RFC3339), "60", "")
+	seller := m.meter(t, "Org1MSP", "meter-1", 0)
+	buyer1 := m.meter(t, "Org2MSP", "meter-2", 100)
+	buyer2 := m.meter(t, "Org2MSP", "meter-3", 100)
+	m.submit(t, seller, "PostOffer", "w1", "offer1", "meter-1", "4000", "20")
+	m.submit(t, buyer1, "AcceptOffer", "w1", "offer1", "meter-2", "2000")
+	m.submit(t, buyer2, "AcceptOffer", "w1", "offer1", "meter-3", "2000")
+
+	m.ledger.SetTime(start.Add(time.Hour))
+	m.submit(t, m.oracle, "SubmitReading", "w1", "meter-1", "4000", "0")
+	m.submit(t, m.oracle, "SubmitReading", "w1", "meter-2", "0", "2000")
+	m.submitError(t, m.operator, "missing the readings of meters [meter-3]", "SettleWindow", "w1")
+
+	// once the grace period has passed the unread meter counts as having imported nothing
+	m.ledger.SetTime(start.Add(time.Hour + readingGracePeriod))
+	m.submit(t, m.operator, "SettleWindow", "w1")
+	window := m.window(t, "w1")
+	if window.DeliveredWh != 2000 || len(window.Instructions) != 1 {
+		t.Fatalf("window after settling is %+v", window)
+	}
+	m.submitError(t, buyer2, "client has nothing to settle", "ExecuteSettlement", "w1")
+	m.submit(t, buyer1, "ExecuteSettlement", "w1")
+	m.checkBalances(t, map[*mockledger.Client]int{seller: 40, buyer1: 60, buyer2: 100})
+	if window := m.window(t, "w1"); window.Status != windowSettled {
+		t.Errorf("window is %s, want %s", window.Status, windowSettled)
+	}
+}
@@ -0,0 +1,23 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"log"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/energy-trading/chaincode-go/chaincode"
+)
+
+func main() {
+	energyChaincode, err := contractapi.NewChaincode(&chaincode.SmartContract{})
+	if err != nil {
+		log.Panicf("Error creating energy-trading chaincode: %v", err)
+	}
+
+	if err := energyChaincode.Start(); err != nil {
+		log.Panicf("Error starting energy-trading chaincode: %v", err)
+	}
+}
@@ -0,0 +1,216 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric-samples/internal/metrics"
+)
+
+// eventsPage is the response of an events query. Next is the afterID of the following page, zero
+// when the page is the last one.
+type eventsPage struct {
+	Events []*Event `json:"events"`
+	Next   int64    `json:"next,omitempty"`
+}
+
+// balancesPage is the response of a balances query. Next is the after parameter of the following
+// page, empty when the page is the last one.
+type balancesPage struct {
+	Balances []*Balance `json:"balances"`
+	Next     string     `json:"next,omitempty"`
+}
+
+// assetsPage is the response of an assets query, paged as balancesPage
+type assetsPage struct {
+	Assets []*IndexedAsset `json:"assets"`
+	Next   string          `json:"next,omitempty"`
+}
+
+// newHandler returns the handler of the query endpoints and the metrics
+func newHandler(store *Store) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	mux.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		filter, err := parseFilter(r.URL.Query())
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		events, err := store.Events(r.Context(), filter)
+		if err != nil {
+			log.Print(err)
+			writeError(w, http.StatusInternalServerError, "failed to query events")
+			return
+		}
+		page := &eventsPage{Events: events}
+		if len(events) > 0 && len(events) == filter.Limit {
+			page.Next = events[len(events)-1].ID
+		}
+		writeJSON(w, http.StatusOK, page)
+	})
+	mux.HandleFunc("/events/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		txID := strings.TrimPrefix(r.URL.Path, "/events/")
+		events, err := store.Events(r.Context(), &EventFilter{TxID: txID, Limit: maxLimit})
+		if err != nil {
+			log.Print(err)
+			writeError(w, http.StatusInternalServerError, "failed to query events")
+			return
+		}
+		if len(events) == 0 {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("no event of transaction %s", txID))
+			return
+		}
+		writeJSON(w, http.StatusOK, events)
+	})
+	mux.HandleFunc("/checkpoints", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		checkpoints, err := store.Checkpoints(r.Context())
+		if err != nil {
+			log.Print(err)
+			writeError(w, http.StatusInternalServerError, "failed to query checkpoints")
+			return
+		}
+		writeJSON(w, http.StatusOK, checkpoints)
+	})
+	mux.HandleFunc("/balances", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		filter, err := parseIndexFilter(r.URL.Query(), "account")
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		balances, err := store.Balances(r.Context(), filter)
+		if err != nil {
+			log.Print(err)
+			writeError(w, http.StatusInternalServerError, "failed to query balances")
+			return
+		}
+		page := &balancesPage{Balances: balances}
+		if len(balances) == filter.Limit {
+			page.Next = balances[len(balances)-1].Account
+		}
+		writeJSON(w, http.StatusOK, page)
+	})
+	mux.HandleFunc("/assets", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		filter, err := parseIndexFilter(r.URL.Query(), "owner")
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		assets, err := store.Assets(r.Context(), filter)
+		if err != nil {
+			log.Print(err)
+			writeError(w, http.StatusInternalServerError, "failed to query assets")
+			return
+		}
+		page := &assetsPage{Assets: assets}
+		if len(assets) == filter.Limit {
+			page.Next = assets[len(assets)-1].AssetID
+		}
+		writeJSON(w, http.StatusOK, page)
+	})
+	return mux
+}
+
+// parseFilter reads the event filter of the query parameters of an events request
+func parseFilter(query url.Values) (*EventFilter, error) {
+	filter := &EventFilter{
+		Chaincode: query.Get("chaincode"),
+		Name:      query.Get("name"),
+		TxID:      query.Get("txID"),
+		Account:   query.Get("account"),
+		AssetID:   query.Get("assetID"),
+		Limit:     defaultLimit,
+	}
+	var err error
+	if filter.FromBlock, err = blockParam(query, "fromBlock"); err != nil {
+		return nil, err
+	}
+	if filter.ToBlock, err = blockParam(query, "toBlock"); err != nil {
+		return nil, err
+	}
+	if value := query.Get("afterID"); value != "" {
+		filter.AfterID, err = strconv.ParseInt(value, 10, 64)
+		if err != nil || filter.AfterID < 0 {
+			return nil, fmt.Errorf("afterID must be a non-negative integer")
+		}
+	}
+	if value := query.Get("limit"); value != "" {
+		filter.Limit, err = strconv.Atoi(value)
+		if err != nil || filter.Limit < 1 || filter.Limit > maxLimit {
+			return nil, fmt.Errorf("limit must be between 1 and %d", maxLimit)
+		}
+	}
+	return filter, nil
+}
+
+// parseIndexFilter reads the filter of the query parameters of a balances or assets request, where
+// keyParam names the parameter selecting the account or owner org
+func parseIndexFilter(query url.Values, keyParam string) (*IndexFilter, error) {
+	filter := &IndexFilter{
+		Chaincode: query.Get("chaincode"),
+		Key:       query.Get(keyParam),
+		After:     query.Get("after"),
+		Limit:     defaultLimit,
+	}
+	if value := query.Get("limit"); value != "" {
+		var err error
+		filter.Limit, err = strconv.Atoi(value)
+		if err != nil || filter.Limit < 1 || filter.Limit > maxLimit {
+			return nil, fmt.Errorf("limit must be between 1 and %d", maxLimit)
+		}
+	}
+	return filter, nil
+}
+
+func blockParam(query url.Values, name string) (*uint64, error) {
+	value := query.Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	block, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be a block number", name)
+	}
+	return &block, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		log.Printf("failed to write response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
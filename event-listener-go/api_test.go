@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestParseFilter(t *testing.T) {
+	filter, err := parseFilter(url.Values{"name": {"Transfer"}, "toBlock": {"9"}, "limit": {"20"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filter.Name != "Transfer" || filter.ToBlock == nil || *filter.ToBlock != 9 || filter.FromBlock != nil || filter.Limit != 20 {
+		t.Errorf("filter is %+v", filter)
+	}
+
+	filter, err = parseFilter(url.Values{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filter.Limit != defaultLimit {
+		t.Errorf("limit is %d", filter.Limit)
+	}
+
+	for _, query := range []url.Values{
+		{"limit": {"0"}},
+		{"limit": {"1001"}},
+		{"fromBlock": {"-1"}},
+		{"afterID": {"x"}},
+	} {
+		if _, err := parseFilter(query); err == nil {
+			t.Errorf("expected %v to be refused", query)
+		}
+	}
+}
+
+func TestParseIndexFilter(t *testing.T) {
+	filter, err := parseIndexFilter(url.Values{"owner": {"Org1MSP"}, "after": {"asset1"}}, "owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filter.Key != "Org1MSP" || filter.After != "asset1" || filter.Limit != defaultLimit {
+		t.Errorf("filter is %+v", filter)
+	}
+	if _, err := parseIndexFilter(url.Values{"limit": {"x"}}, "account"); err == nil {
+		t.Error("expected an invalid limit to be refused")
+	}
+}
@@ -0,0 +1,272 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+)
+
+// indexSchema creates the tables the indexer maintains from the stored events: the balance of
+// each token account and the owner of each asset. Each row keeps the block and transaction that
+// last changed it, so an event older than the row is never applied over it.
+const indexSchema = `
+CREATE TABLE IF NOT EXISTS account_balances (
+	channel      TEXT NOT NULL,
+	chaincode    TEXT NOT NULL,
+	account      TEXT NOT NULL,
+	balance      BIGINT NOT NULL,
+	block_number BIGINT NOT NULL,
+	tx_id        TEXT NOT NULL,
+	PRIMARY KEY (channel, chaincode, account)
+);
+CREATE TABLE IF NOT EXISTS assets (
+	channel            TEXT NOT NULL,
+	chaincode          TEXT NOT NULL,
+	asset_id           TEXT NOT NULL,
+	owner_org          TEXT NOT NULL,
+	public_description TEXT NOT NULL,
+	block_number       BIGINT NOT NULL,
+	tx_id              TEXT NOT NULL,
+	PRIMARY KEY (channel, chaincode, asset_id)
+);
+CREATE INDEX IF NOT EXISTS assets_owner ON assets (owner_org);`
+
+// reindexBatchSize is the number of stored events Reindex reads at a time
+const reindexBatchSize = 1000
+
+// Balance is the indexed token balance of an account
+type Balance struct {
+	Channel     string `json:"channel"`
+	Chaincode   string `json:"chaincode"`
+	Account     string `json:"account"`
+	Balance     int64  `json:"balance"`
+	BlockNumber uint64 `json:"blockNumber"`
+	TxID        string `json:"txID"`
+}
+
+// IndexedAsset is the indexed owner and public description of an asset
+type IndexedAsset struct {
+	Channel           string `json:"channel"`
+	Chaincode         string `json:"chaincode"`
+	AssetID           string `json:"assetID"`
+	OwnerOrg          string `json:"ownerOrg"`
+	PublicDescription string `json:"publicDescription"`
+	BlockNumber       uint64 `json:"blockNumber"`
+	TxID              string `json:"txID"`
+}
+
+// IndexFilter selects indexed rows. Empty fields match every row.
+type IndexFilter struct {
+	Chaincode string
+	// Key is the account of a balance or the owner org of an asset
+	Key string
+	// After continues a listing after the last account or asset ID of the previous page
+	After string
+	Limit int
+}
+
+// indexUpdate is what an event changes in the indexed tables
+type indexUpdate struct {
+	balances map[string]int64
+	asset    *IndexedAsset
+}
+
+// indexedSchemaVersion is the latest version of the event schemas the indexer reads. Events emitted
+// before the chaincodes versioned their payloads have no schemaVersion and are read as version 1.
+const indexedSchemaVersion = 1
+
+// eventPayload holds the fields of the token and asset event payloads the indexer reads
+type eventPayload struct {
+	SchemaVersion     int    `json:"schemaVersion"`
+	AssetID           string `json:"assetID"`
+	OwnerOrg          string `json:"ownerOrg"`
+	PublicDescription string `json:"publicDescription"`
+	Audit             *struct {
+		Changes []struct {
+			Kind    string   `json:"kind"`
+			Subject []string `json:"subject"`
+			New     int64    `json:"new"`
+		} `json:"changes"`
+	} `json:"audit"`
+}
+
+// parseIndexUpdate returns what an event changes in the indexed tables. Token events carry the new
+// balances in their audit record; asset events carry the public fields of the asset. Other events,
+// events whose payload cannot be read and events of a schema version newer than the indexer reads
+// change nothing rather than stopping the listener; they are stored and applied by a -reindex after
+// the listener is upgraded.
+func parseIndexUpdate(name string, payload []byte) *indexUpdate {
+	update := &indexUpdate{}
+	var fields eventPayload
+	if json.Unmarshal(payload, &fields) != nil || fields.SchemaVersion > indexedSchemaVersion {
+		return update
+	}
+
+	switch name {
+	case "Transfer", "BatchTransfer", "Approval":
+		if fields.Audit == nil {
+			return update
+		}
+		for _, change := range fields.Audit.Changes {
+			if change.Kind == "balance" && len(change.Subject) == 1 {
+				if update.balances == nil {
+					update.balances = make(map[string]int64)
+				}
+				update.balances[change.Subject[0]] = change.New
+			}
+		}
+	case "AssetCreated", "AssetUpdated", "AssetTransferred":
+		if fields.AssetID != "" {
+			update.asset = &IndexedAsset{AssetID: fields.AssetID, OwnerOrg: fields.OwnerOrg, PublicDescription: fields.PublicDescription}
+		}
+	}
+	return update
+}
+
+// indexEvent applies an event to the indexed tables within the database transaction storing it
+func indexEvent(ctx context.Context, tx *sql.Tx, channel string, chaincode string, name string, blockNumber uint64, txID string, payload []byte) error {
+	update := parseIndexUpdate(name, payload)
+	for account, balance := range update.balances {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO account_balances (channel, chaincode, account, balance, block_number, tx_id)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (channel, chaincode, account) DO UPDATE
+			SET balance = EXCLUDED.balance, block_number = EXCLUDED.block_number, tx_id = EXCLUDED.tx_id
+			WHERE account_balances.block_number <= EXCLUDED.block_number`,
+			channel, chaincode, account, balance, blockNumber, txID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to index balance of transaction %s: %v", txID, err)
+		}
+	}
+	if update.asset != nil {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO assets (channel, chaincode, asset_id, owner_org, public_description, block_number, tx_id)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT (channel, chaincode, asset_id) DO UPDATE
+			SET owner_org = EXCLUDED.owner_org, public_description = EXCLUDED.public_description,
+				block_number = EXCLUDED.block_number, tx_id = EXCLUDED.tx_id
+			WHERE assets.block_number <= EXCLUDED.block_number`,
+			channel, chaincode, update.asset.AssetID, update.asset.OwnerOrg, update.asset.PublicDescription, blockNumber, txID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to index asset of transaction %s: %v", txID, err)
+		}
+	}
+	return nil
+}
+
+// Reindex rebuilds the indexed tables by replaying every stored event in the order it was stored,
+// in one database transaction so queries see either the old or the rebuilt tables
+func (s *Store) Reindex(ctx context.Context) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin database transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM account_balances; DELETE FROM assets`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to clear indexed tables: %v", err)
+	}
+	replayed := 0
+	var afterID int64
+	for {
+		// read a batch before applying it, as the transaction's connection serves one query at a time
+		events, err := eventsAfter(ctx, tx, afterID)
+		if err != nil {
+			return 0, err
+		}
+		for _, event := range events {
+			err := indexEvent(ctx, tx, event.Channel, event.Chaincode, event.Name, event.BlockNumber, event.TxID, event.Payload)
+			if err != nil {
+				return 0, err
+			}
+		}
+		replayed += len(events)
+		if len(events) < reindexBatchSize {
+			break
+		}
+		afterID = events[len(events)-1].ID
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, fmt.Errorf("failed to commit database transaction: %v", err)
+	}
+	return replayed, nil
+}
+
+func eventsAfter(ctx context.Context, tx *sql.Tx, afterID int64) ([]*Event, error) {
+	rows, err := tx.QueryContext(ctx,
+		`SELECT id, channel, chaincode, block_number, tx_id, event_name, payload, received_at FROM chaincode_events
+		WHERE id > $1 ORDER BY id LIMIT $2`,
+		afterID, reindexBatchSize,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read events: %v", err)
+	}
+	return scanEvents(rows)
+}
+
+// Balances returns the indexed balances selected by a filter, ordered by account
+func (s *Store) Balances(ctx context.Context, filter *IndexFilter) ([]*Balance, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT channel, chaincode, account, balance, block_number, tx_id FROM account_balances
+		WHERE ($1 = '' OR chaincode = $1) AND ($2 = '' OR account = $2) AND account > $3
+		ORDER BY account LIMIT $4`,
+		filter.Chaincode, filter.Key, filter.After, indexLimit(filter.Limit),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query balances: %v", err)
+	}
+	defer rows.Close()
+
+	balances := []*Balance{}
+	for rows.Next() {
+		balance := &Balance{}
+		err := rows.Scan(&balance.Channel, &balance.Chaincode, &balance.Account, &balance.Balance, &balance.BlockNumber, &balance.TxID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read balance: %v", err)
+		}
+		balances = append(balances, balance)
+	}
+	return balances, rows.Err()
+}
+
+// Assets returns the indexed assets selected by a filter, ordered by asset ID
+func (s *Store) Assets(ctx context.Context, filter *IndexFilter) ([]*IndexedAsset, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT channel, chaincode, asset_id, owner_org, public_description, block_number, tx_id FROM assets
+		WHERE ($1 = '' OR chaincode = $1) AND ($2 = '' OR owner_org = $2) AND asset_id > $3
+		ORDER BY asset_id LIMIT $4`,
+		filter.Chaincode, filter.Key, filter.After, indexLimit(filter.Limit),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query assets: %v", err)
+	}
+	defer rows.Close()
+
+	assets := []*IndexedAsset{}
+	for rows.Next() {
+		asset := &IndexedAsset{}
+		err := rows.Scan(&asset.Channel, &asset.Chaincode, &asset.AssetID, &asset.OwnerOrg, &asset.PublicDescription, &asset.BlockNumber, &asset.TxID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read asset: %v", err)
+		}
+		assets = append(assets, asset)
+	}
+	return assets, rows.Err()
+}
+
+func indexLimit(limit int) int {
+	if limit < 1 || limit > maxLimit {
+		return defaultLimit
+	}
+	return limit
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseIndexUpdate(t *testing.T) {
+	update := parseIndexUpdate("Transfer", []byte(`{"from":"acc1","to":"acc2","value":4,"audit":{"changes":[
+		{"kind":"balance","subject":["acc1"],"old":10,"new":6},
+		{"kind":"balance","subject":["acc2"],"old":0,"new":4},
+		{"kind":"allowance","subject":["acc1","acc3"],"old":5,"new":1}]}}`))
+	if !reflect.DeepEqual(update.balances, map[string]int64{"acc1": 6, "acc2": 4}) || update.asset != nil {
+		t.Errorf("update of a transfer is %+v", update)
+	}
+
+	update = parseIndexUpdate("BatchTransfer", []byte(`{"from":"acc1","payments":[{"receiver":"acc2","amount":4},{"receiver":"acc3","amount":1}],"value":5,"audit":{"changes":[
+		{"kind":"balance","subject":["acc1"],"old":10,"new":5},
+		{"kind":"balance","subject":["acc2"],"old":0,"new":4},
+		{"kind":"balance","subject":["acc3"],"old":2,"new":3}]}}`))
+	if !reflect.DeepEqual(update.balances, map[string]int64{"acc1": 5, "acc2": 4, "acc3": 3}) || update.asset != nil {
+		t.Errorf("update of a batch transfer is %+v", update)
+	}
+
+	update = parseIndexUpdate("Approval", []byte(`{"from":"acc1","to":"acc3","value":5,"audit":{"changes":[
+		{"kind":"allowance","subject":["acc1","acc3"],"old":0,"new":5}]}}`))
+	if update.balances != nil || update.asset != nil {
+		t.Errorf("expected an approval to change no balance, got %+v", update)
+	}
+
+	update = parseIndexUpdate("AssetTransferred", []byte(`{"assetID":"asset1","ownerOrg":"Org2MSP","previousOwnerOrg":"Org1MSP","publicDescription":"sold","schemaVersion":1}`))
+	want := &IndexedAsset{AssetID: "asset1", OwnerOrg: "Org2MSP", PublicDescription: "sold"}
+	if !reflect.DeepEqual(update.asset, want) || update.balances != nil {
+		t.Errorf("update of an asset transfer is %+v", update)
+	}
+
+	for name, payload := range map[string]string{
+		"Transfer":     `{"from":"acc1","to":"acc2","value":4}`,
+		"AssetCreated": `{"ownerOrg":"Org1MSP"}`,
+		"AssetUpdated": `not json`,
+		"Other":        `{"assetID":"asset1","ownerOrg":"Org1MSP"}`,
+		// a payload version the indexer does not know yet
+		"AssetTransferred": `{"assetID":"asset1","ownerOrg":"Org2MSP","schemaVersion":2}`,
+	} {
+		update := parseIndexUpdate(name, []byte(payload))
+		if update.balances != nil || update.asset != nil {
+			t.Errorf("expected %s event %s to change nothing, got %+v", name, payload, update)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-samples/internal/metrics"
+)
+
+const (
+	minRetryDelay = time.Second
+	maxRetryDelay = time.Minute
+)
+
+// listener stores the events of one chaincode
+type listener struct {
+	store      *Store
+	network    *client.Network
+	channel    string
+	chaincode  string
+	startBlock uint64
+}
+
+// run stores the events of the chaincode until ctx is done. When the event stream fails, e.g.
+// because the peer went down, it is reopened at the checkpoint after a growing delay.
+func (l *listener) run(ctx context.Context) {
+	delay := minRetryDelay
+	for {
+		stored, err := l.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if stored {
+			delay = minRetryDelay
+		}
+		log.Printf("%s: event listening stopped, resuming in %s: %v", l.chaincode, delay, err)
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return
+		}
+		delay *= 2
+		if delay > maxRetryDelay {
+			delay = maxRetryDelay
+		}
+	}
+}
+
+// listen stores events from the checkpoint on until the event stream ends. It reports whether any
+// event was stored.
+func (l *listener) listen(ctx context.Context) (bool, error) {
+	startBlock, err := l.resumeBlock(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	events, err := l.network.ChaincodeEvents(ctx, l.chaincode, client.WithStartBlock(startBlock))
+	if err != nil {
+		return false, fmt.Errorf("failed to start chaincode event listening: %v", err)
+	}
+	log.Printf("%s: listening from block %d", l.chaincode, startBlock)
+
+	stored := false
+	for event := range events {
+		err := l.store.SaveEvent(ctx, l.channel, event)
+		if err != nil {
+			return stored, err
+		}
+		metrics.EventProcessed(event.ChaincodeName, event.EventName, event.BlockNumber)
+		stored = true
+	}
+	return stored, fmt.Errorf("event stream closed")
+}
+
+// resumeBlock returns the block to listen from. It is the block of the checkpoint itself, as the
+// other events of that block may not have been stored yet; events stored before are ignored.
+func (l *listener) resumeBlock(ctx context.Context) (uint64, error) {
+	checkpoint, err := l.store.Checkpoint(ctx, l.channel, l.chaincode)
+	if err != nil {
+		return 0, err
+	}
+	if checkpoint == nil {
+		return l.startBlock, nil
+	}
+	return checkpoint.BlockNumber, nil
+}
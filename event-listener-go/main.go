@@ -0,0 +1,106 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// event-listener-go stores the chaincode events of the token and secured agreement asset
+// chaincodes in PostgreSQL, resuming from its checkpoints after restarts, indexes the balance of
+// each account and the owner of each asset, and serves queries of both over HTTP.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric-samples/internal/appclient"
+	_ "github.com/lib/pq"
+)
+
+var (
+	listen      = flag.String("listen", ":8081", "address the query endpoints listen on")
+	database    = flag.String("database", os.Getenv("DATABASE_URL"), "PostgreSQL connection string, $DATABASE_URL by default")
+	walletDir   = flag.String("wallet", "wallet", "wallet directory holding the identity")
+	label       = flag.String("identity", "", "label of the wallet identity the events are received as")
+	profilePath = flag.String("profile", "", "connection profile of the identity's org")
+	peer        = flag.String("peer", "", "peer of the profile to connect to, the first peer of the org by default")
+	channel     = flag.String("channel", "mychannel", "channel the chaincodes are deployed on")
+	chaincodes  = flag.String("chaincodes", "token_erc20,secured", "comma-separated names of the chaincodes whose events are stored")
+	startBlock  = flag.Uint64("start-block", 0, "block to listen from for a chaincode without a checkpoint")
+	reindex     = flag.Bool("reindex", false, "rebuild the balance and asset tables from the stored events before listening")
+)
+
+func main() {
+	flag.Parse()
+	if *label == "" || *profilePath == "" || *database == "" {
+		log.Fatal("-identity, -profile and -database are required")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	store, err := OpenStore(ctx, *database)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer store.Close()
+	if *reindex {
+		replayed, err := store.Reindex(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("rebuilt the balance and asset tables from %d events", replayed)
+	}
+
+	profile, err := appclient.LoadProfile(*profilePath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	wallet, err := appclient.OpenWallet(*walletDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	connection, err := appclient.ConnectWallet(profile, *peer, wallet, *label)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer connection.Close()
+	network := connection.GetNetwork(*channel)
+
+	var listeners sync.WaitGroup
+	for _, chaincode := range strings.Split(*chaincodes, ",") {
+		l := &listener{
+			store:      store,
+			network:    network,
+			channel:    *channel,
+			chaincode:  strings.TrimSpace(chaincode),
+			startBlock: *startBlock,
+		}
+		listeners.Add(1)
+		go func() {
+			defer listeners.Done()
+			l.run(ctx)
+		}()
+	}
+
+	server := &http.Server{Addr: *listen, Handler: newHandler(store)}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("storing events of %s on %s as %s of %s, serving queries on %s", *chaincodes, *channel, *label, profile.MSPID(), *listen)
+	err = server.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
+	listeners.Wait()
+}
@@ -0,0 +1,278 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+)
+
+// schema creates the tables of the store. A transaction sets at most one chaincode event, so an
+// event is identified by its chaincode and transaction ID.
+const schema = `
+CREATE TABLE IF NOT EXISTS chaincode_events (
+	id           BIGSERIAL PRIMARY KEY,
+	channel      TEXT NOT NULL,
+	chaincode    TEXT NOT NULL,
+	block_number BIGINT NOT NULL,
+	tx_id        TEXT NOT NULL,
+	event_name   TEXT NOT NULL,
+	payload      JSONB,
+	received_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+	UNIQUE (channel, chaincode, tx_id)
+);
+CREATE INDEX IF NOT EXISTS chaincode_events_block ON chaincode_events (channel, chaincode, block_number);
+CREATE INDEX IF NOT EXISTS chaincode_events_name ON chaincode_events (event_name);
+CREATE TABLE IF NOT EXISTS checkpoints (
+	channel      TEXT NOT NULL,
+	chaincode    TEXT NOT NULL,
+	block_number BIGINT NOT NULL,
+	tx_id        TEXT NOT NULL,
+	updated_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+	PRIMARY KEY (channel, chaincode)
+);`
+
+const (
+	defaultLimit = 100
+	maxLimit     = 1000
+)
+
+// Event is a stored chaincode event
+type Event struct {
+	ID          int64           `json:"id"`
+	Channel     string          `json:"channel"`
+	Chaincode   string          `json:"chaincode"`
+	BlockNumber uint64          `json:"blockNumber"`
+	TxID        string          `json:"txID"`
+	Name        string          `json:"name"`
+	Payload     json.RawMessage `json:"payload,omitempty"`
+	ReceivedAt  time.Time       `json:"receivedAt"`
+}
+
+// Checkpoint is the last event stored of a chaincode
+type Checkpoint struct {
+	Channel     string    `json:"channel"`
+	Chaincode   string    `json:"chaincode"`
+	BlockNumber uint64    `json:"blockNumber"`
+	TxID        string    `json:"txID"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// EventFilter selects stored events. Empty fields match every event.
+type EventFilter struct {
+	Chaincode string
+	Name      string
+	TxID      string
+	// Account matches the from and to accounts of token events
+	Account string
+	// AssetID matches the asset of asset events
+	AssetID   string
+	FromBlock *uint64
+	ToBlock   *uint64
+	// AfterID continues a listing after the last event ID of the previous page
+	AfterID int64
+	Limit   int
+}
+
+// Store persists chaincode events and the checkpoints of their listeners in PostgreSQL
+type Store struct {
+	db *sql.DB
+}
+
+// OpenStore connects to the database and creates the tables of the store and the indexer when
+// missing
+func OpenStore(ctx context.Context, dataSource string) (*Store, error) {
+	db, err := sql.Open("postgres", dataSource)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %v", err)
+	}
+	_, err = db.ExecContext(ctx, schema+indexSchema)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create tables: %v", err)
+	}
+	return &Store{db: db}, nil
+}
+
+// Close closes the database connections
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Checkpoint returns the checkpoint of a chaincode, or nil when none of its events was stored yet
+func (s *Store) Checkpoint(ctx context.Context, channel string, chaincode string) (*Checkpoint, error) {
+	checkpoint := &Checkpoint{}
+	err := s.db.QueryRowContext(ctx,
+		`SELECT channel, chaincode, block_number, tx_id, updated_at FROM checkpoints WHERE channel = $1 AND chaincode = $2`,
+		channel, chaincode,
+	).Scan(&checkpoint.Channel, &checkpoint.Chaincode, &checkpoint.BlockNumber, &checkpoint.TxID, &checkpoint.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint of %s: %v", chaincode, err)
+	}
+	return checkpoint, nil
+}
+
+// Checkpoints returns the checkpoints of every chaincode
+func (s *Store) Checkpoints(ctx context.Context) ([]*Checkpoint, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT channel, chaincode, block_number, tx_id, updated_at FROM checkpoints ORDER BY channel, chaincode`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoints: %v", err)
+	}
+	defer rows.Close()
+
+	checkpoints := []*Checkpoint{}
+	for rows.Next() {
+		checkpoint := &Checkpoint{}
+		err := rows.Scan(&checkpoint.Channel, &checkpoint.Chaincode, &checkpoint.BlockNumber, &checkpoint.TxID, &checkpoint.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read checkpoint: %v", err)
+		}
+		checkpoints = append(checkpoints, checkpoint)
+	}
+	return checkpoints, rows.Err()
+}
+
+// SaveEvent stores an event, applies it to the indexed tables and moves the checkpoint of its
+// chaincode to it in one database transaction. An event stored before, as redelivered after a
+// restart, is ignored, so it is never applied twice.
+func (s *Store) SaveEvent(ctx context.Context, channel string, event *client.ChaincodeEvent) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin database transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	payload := jsonPayload(event.Payload)
+	result, err := tx.ExecContext(ctx,
+		`INSERT INTO chaincode_events (channel, chaincode, block_number, tx_id, event_name, payload)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (channel, chaincode, tx_id) DO NOTHING`,
+		channel, event.ChaincodeName, event.BlockNumber, event.TransactionID, event.EventName, payload,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store event of transaction %s: %v", event.TransactionID, err)
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to store event of transaction %s: %v", event.TransactionID, err)
+	}
+	if inserted > 0 {
+		err = indexEvent(ctx, tx, channel, event.ChaincodeName, event.EventName, event.BlockNumber, event.TransactionID, event.Payload)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO checkpoints (channel, chaincode, block_number, tx_id, updated_at)
+		VALUES ($1, $2, $3, $4, now())
+		ON CONFLICT (channel, chaincode) DO UPDATE
+		SET block_number = EXCLUDED.block_number, tx_id = EXCLUDED.tx_id, updated_at = EXCLUDED.updated_at
+		WHERE checkpoints.block_number <= EXCLUDED.block_number`,
+		channel, event.ChaincodeName, event.BlockNumber, event.TransactionID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store checkpoint of transaction %s: %v", event.TransactionID, err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit database transaction: %v", err)
+	}
+	return nil
+}
+
+// Events returns the stored events selected by a filter in the order they were stored
+func (s *Store) Events(ctx context.Context, filter *EventFilter) ([]*Event, error) {
+	query, args := eventsQuery(filter)
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query events: %v", err)
+	}
+	return scanEvents(rows)
+}
+
+func scanEvents(rows *sql.Rows) ([]*Event, error) {
+	defer rows.Close()
+
+	events := []*Event{}
+	for rows.Next() {
+		event := &Event{}
+		var payload []byte
+		err := rows.Scan(&event.ID, &event.Channel, &event.Chaincode, &event.BlockNumber, &event.TxID, &event.Name, &payload, &event.ReceivedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read event: %v", err)
+		}
+		event.Payload = payload
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// eventsQuery builds the query of the events selected by a filter
+func eventsQuery(filter *EventFilter) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	where := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, strings.ReplaceAll(condition, "?", "$"+strconv.Itoa(len(args))))
+	}
+
+	if filter.Chaincode != "" {
+		where("chaincode = ?", filter.Chaincode)
+	}
+	if filter.Name != "" {
+		where("event_name = ?", filter.Name)
+	}
+	if filter.TxID != "" {
+		where("tx_id = ?", filter.TxID)
+	}
+	if filter.Account != "" {
+		where("(payload->>'from' = ? OR payload->>'to' = ?)", filter.Account)
+	}
+	if filter.AssetID != "" {
+		where("payload->>'assetID' = ?", filter.AssetID)
+	}
+	if filter.FromBlock != nil {
+		where("block_number >= ?", *filter.FromBlock)
+	}
+	if filter.ToBlock != nil {
+		where("block_number <= ?", *filter.ToBlock)
+	}
+	if filter.AfterID > 0 {
+		where("id > ?", filter.AfterID)
+	}
+
+	query := "SELECT id, channel, chaincode, block_number, tx_id, event_name, payload, received_at FROM chaincode_events"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	limit := filter.Limit
+	if limit < 1 || limit > maxLimit {
+		limit = defaultLimit
+	}
+	args = append(args, limit)
+	query += " ORDER BY id LIMIT $" + strconv.Itoa(len(args))
+	return query, args
+}
+
+// jsonPayload returns the payload of an event for the JSONB column, or nil for a payload that is
+// empty or not JSON
+func jsonPayload(payload []byte) interface{} {
+	if len(payload) == 0 || !json.Valid(payload) {
+		return nil
+	}
+	return string(payload)
+}
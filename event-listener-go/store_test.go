@@ -0,0 +1,40 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEventsQuery(t *testing.T) {
+	query, args := eventsQuery(&EventFilter{})
+	want := "SELECT id, channel, chaincode, block_number, tx_id, event_name, payload, received_at FROM chaincode_events ORDER BY id LIMIT $1"
+	if query != want {
+		t.Errorf("query is %q", query)
+	}
+	if !reflect.DeepEqual(args, []interface{}{defaultLimit}) {
+		t.Errorf("args are %v", args)
+	}
+
+	fromBlock := uint64(5)
+	query, args = eventsQuery(&EventFilter{Chaincode: "token_erc20", Account: "acc1", FromBlock: &fromBlock, AfterID: 42, Limit: 10})
+	want = "SELECT id, channel, chaincode, block_number, tx_id, event_name, payload, received_at FROM chaincode_events" +
+		" WHERE chaincode = $1 AND (payload->>'from' = $2 OR payload->>'to' = $2) AND block_number >= $3 AND id > $4 ORDER BY id LIMIT $5"
+	if query != want {
+		t.Errorf("query is %q", query)
+	}
+	if !reflect.DeepEqual(args, []interface{}{"token_erc20", "acc1", uint64(5), int64(42), 10}) {
+		t.Errorf("args are %v", args)
+	}
+}
+
+func TestJSONPayload(t *testing.T) {
+	if payload := jsonPayload([]byte(`{"from":"a","to":"b","value":1}`)); payload != `{"from":"a","to":"b","value":1}` {
+		t.Errorf("payload is %v", payload)
+	}
+	if payload := jsonPayload(nil); payload != nil {
+		t.Errorf("expected no payload for an empty one, got %v", payload)
+	}
+	if payload := jsonPayload([]byte("not json")); payload != nil {
+		t.Errorf("expected no payload for one that is not JSON, got %v", payload)
+	}
+}
@@ -0,0 +1,421 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil"
+)
+
+// defaultTokenChaincode is the name the token-erc-20 sample is deployed under in the README
+const defaultTokenChaincode = "token_erc20"
+
+// object names for prefix
+const (
+	eventPrefix        = "event"
+	ticketPrefix       = "ticket"
+	eventTicketPrefix  = "event~ticket"
+	holderTicketPrefix = "holder~ticket"
+)
+
+// basisPoints is 100%, caps and royalties are expressed in basis points of the face value or sale price
+const basisPoints = 10000
+
+// SmartContract provides functions for issuing, reselling and redeeming event tickets
+type SmartContract struct {
+	contractapi.Contract
+}
+
+// Event is a concert, match or other event tickets are issued for
+type Event struct {
+	ObjectType     string `json:"objectType"`
+	ID             string `json:"eventID"`
+	Name           string `json:"name"`
+	Venue          string `json:"venue"`
+	StartTime      string `json:"startTime"`
+	Organizer      string `json:"organizer"`
+	ResaleCapBps   int    `json:"resaleCapBps"`
+	RoyaltyBps     int    `json:"royaltyBps"`
+	TokenChaincode string `json:"tokenChaincode"`
+}
+
+// Ticket is a non-fungible token giving its holder entry to one seat
+type Ticket struct {
+	ObjectType string `json:"objectType"`
+	ID         string `json:"ticketID"`
+	EventID    string `json:"eventID"`
+	Section    string `json:"section"`
+	Row        string `json:"row"`
+	Seat       string `json:"seat"`
+	FaceValue  int    `json:"faceValue"`
+	Holder     string `json:"holder"`
+	ListPrice  int    `json:"listPrice"`
+	Redeemed   bool   `json:"redeemed"`
+}
+
+// transferEvent is emitted whenever a ticket changes holder
+type transferEvent struct {
+	TicketID string `json:"ticketID"`
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Price    int    `json:"price"`
+	Royalty  int    `json:"royalty"`
+}
+
+// CreateEvent registers an event with the client as organizer. resaleCapBps limits resale prices,
+// e.g. 11000 allows resale at up to 110% of face value, and royaltyBps is the share of every
+// resale paid to the organizer.
+func (s *SmartContract) CreateEvent(ctx contractapi.TransactionContextInterface, eventID string, name string, venue string, startTime string, resaleCapBps int, royaltyBps int, tokenChaincode string) error {
+	organizer, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	if eventID == "" || name == "" || venue == "" || startTime == "" {
+		return fmt.Errorf("event ID, name, venue and start time must be set")
+	}
+	if resaleCapBps < basisPoints {
+		return fmt.Errorf("resale cap must be at least %d basis points (face value)", basisPoints)
+	}
+	if royaltyBps < 0 || royaltyBps > basisPoints {
+		return fmt.Errorf("royalty must be between 0 and %d basis points", basisPoints)
+	}
+	if tokenChaincode == "" {
+		tokenChaincode = defaultTokenChaincode
+	}
+
+	eventKey, err := ctx.GetStub().CreateCompositeKey(eventPrefix, []string{eventID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	existing, err := ctx.GetStub().GetState(eventKey)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if existing != nil {
+		return fmt.Errorf("the event %s already exists", eventID)
+	}
+
+	ev := Event{
+		ObjectType:     eventPrefix,
+		ID:             eventID,
+		Name:           name,
+		Venue:          venue,
+		StartTime:      startTime,
+		Organizer:      organizer,
+		ResaleCapBps:   resaleCapBps,
+		RoyaltyBps:     royaltyBps,
+		TokenChaincode: tokenChaincode,
+	}
+	eventJSON, err := json.Marshal(ev)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %v", err)
+	}
+
+	return ctx.GetStub().PutState(eventKey, eventJSON)
+}
+
+// MintTicket is called by the organizer to issue a ticket for a seat. The organizer holds the
+// ticket until it is sold.
+func (s *SmartContract) MintTicket(ctx contractapi.TransactionContextInterface, eventID string, ticketID string, section string, row string, seat string, faceValue int) error {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	ev, err := s.ReadEvent(ctx, eventID)
+	if err != nil {
+		return err
+	}
+	if clientID != ev.Organizer {
+		return fmt.Errorf("only the organizer can mint tickets for event %s", eventID)
+	}
+	if ticketID == "" || seat == "" {
+		return fmt.Errorf("ticket ID and seat must be set")
+	}
+	if faceValue < 0 {
+		return fmt.Errorf("face value must not be negative")
+	}
+
+	ticketKey, err := ctx.GetStub().CreateCompositeKey(ticketPrefix, []string{ticketID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	existing, err := ctx.GetStub().GetState(ticketKey)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if existing != nil {
+		return fmt.Errorf("the ticket %s already exists", ticketID)
+	}
+
+	ticket := Ticket{
+		ObjectType: ticketPrefix,
+		ID:         ticketID,
+		EventID:    eventID,
+		Section:    section,
+		Row:        row,
+		Seat:       seat,
+		FaceValue:  faceValue,
+		Holder:     clientID,
+	}
+	err = _putTicket(ctx, &ticket)
+	if err != nil {
+		return err
+	}
+
+	err = _putIndex(ctx, eventTicketPrefix, eventID, ticketID)
+	if err != nil {
+		return err
+	}
+	err = _putIndex(ctx, holderTicketPrefix, clientID, ticketID)
+	if err != nil {
+		return err
+	}
+
+	return _emitTransfer(ctx, transferEvent{ticketID, "0x0", clientID, 0, 0})
+}
+
+// ListTicket offers a ticket held by the client for sale. The price may not exceed the event's
+// resale cap. A price of 0 withdraws the listing.
+func (s *SmartContract) ListTicket(ctx contractapi.TransactionContextInterface, ticketID string, price int) error {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	ticket, err := s.ReadTicket(ctx, ticketID)
+	if err != nil {
+		return err
+	}
+	if clientID != ticket.Holder {
+		return fmt.Errorf("only the holder can list ticket %s", ticketID)
+	}
+	if ticket.Redeemed {
+		return fmt.Errorf("ticket %s has been used", ticketID)
+	}
+
+	ev, err := s.ReadEvent(ctx, ticket.EventID)
+	if err != nil {
+		return err
+	}
+	priceCap := ticket.FaceValue * ev.ResaleCapBps / basisPoints
+	if price < 0 || price > priceCap {
+		return fmt.Errorf("price %d is outside the allowed range 0 to %d for ticket %s", price, priceCap, ticketID)
+	}
+
+	ticket.ListPrice = price
+	return _putTicket(ctx, ticket)
+}
+
+// BuyTicket buys a listed ticket. The buyer pays the seller, less the organizer's royalty on
+// resales, through the token chaincode.
+func (s *SmartContract) BuyTicket(ctx contractapi.TransactionContextInterface, ticketID string) error {
+	buyer, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	ticket, ev, royalty, err := s._purchaseTerms(ctx, ticketID, buyer)
+	if err != nil {
+		return err
+	}
+	price := ticket.ListPrice
+
+	err = ledgerutil.TransferTokens(ctx, ev.TokenChaincode,
+		ledgerutil.TokenPayment{Receiver: ticket.Holder, Amount: price - royalty},
+		ledgerutil.TokenPayment{Receiver: ev.Organizer, Amount: royalty})
+	if err != nil {
+		return err
+	}
+
+	seller := ticket.Holder
+	err = _changeHolder(ctx, ticket, buyer)
+	if err != nil {
+		return err
+	}
+
+	return _emitTransfer(ctx, transferEvent{ticketID, seller, buyer, price, royalty})
+}
+
+// _purchaseTerms checks buyer can buy the ticket and returns it with its event and the royalty
+// owed to the organizer
+func (s *SmartContract) _purchaseTerms(ctx contractapi.TransactionContextInterface, ticketID string, buyer string) (*Ticket, *Event, int, error) {
+	ticket, err := s.ReadTicket(ctx, ticketID)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	if ticket.ListPrice == 0 {
+		return nil, nil, 0, fmt.Errorf("ticket %s is not for sale", ticketID)
+	}
+	if ticket.Redeemed {
+		return nil, nil, 0, fmt.Errorf("ticket %s has been used", ticketID)
+	}
+	if buyer == ticket.Holder {
+		return nil, nil, 0, fmt.Errorf("ticket %s is already held by the buyer", ticketID)
+	}
+
+	ev, err := s.ReadEvent(ctx, ticket.EventID)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	royalty := 0
+	if ticket.Holder != ev.Organizer {
+		royalty = ticket.ListPrice * ev.RoyaltyBps / basisPoints
+	}
+	return ticket, ev, royalty, nil
+}
+
+// TransferTicket gives a ticket held by the client to someone else without payment
+func (s *SmartContract) TransferTicket(ctx contractapi.TransactionContextInterface, ticketID string, receiver string) error {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	ticket, err := s.ReadTicket(ctx, ticketID)
+	if err != nil {
+		return err
+	}
+	if clientID != ticket.Holder {
+		return fmt.Errorf("only the holder can transfer ticket %s", ticketID)
+	}
+	if ticket.Redeemed {
+		return fmt.Errorf("ticket %s has been used", ticketID)
+	}
+	if receiver == "" || receiver == clientID {
+		return fmt.Errorf("receiver must be set and differ from the holder")
+	}
+
+	err = _changeHolder(ctx, ticket, receiver)
+	if err != nil {
+		return err
+	}
+
+	return _emitTransfer(ctx, transferEvent{ticketID, clientID, receiver, 0, 0})
+}
+
+// CheckIn redeems a ticket at the gate. It is called by the organizer and can succeed only once,
+// after which the ticket can no longer be sold or transferred.
+func (s *SmartContract) CheckIn(ctx contractapi.TransactionContextInterface, ticketID string, holder string) error {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	ticket, err := s.ReadTicket(ctx, ticketID)
+	if err != nil {
+		return err
+	}
+	ev, err := s.ReadEvent(ctx, ticket.EventID)
+	if err != nil {
+		return err
+	}
+	if clientID != ev.Organizer {
+		return fmt.Errorf("only the organizer of event %s can check tickets in", ev.ID)
+	}
+	if ticket.Holder != holder {
+		return fmt.Errorf("ticket %s is not held by the presenting account", ticketID)
+	}
+	if ticket.Redeemed {
+		return fmt.Errorf("ticket %s has already been used", ticketID)
+	}
+
+	ticket.Redeemed = true
+	ticket.ListPrice = 0
+	err = _putTicket(ctx, ticket)
+	if err != nil {
+		return err
+	}
+
+	checkInJSON, err := json.Marshal(map[string]string{"ticketID": ticketID, "eventID": ev.ID, "holder": holder})
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	return ctx.GetStub().SetEvent("CheckIn", checkInJSON)
+}
+
+// _changeHolder moves the ticket and the holder index to a new holder
+func _changeHolder(ctx contractapi.TransactionContextInterface, ticket *Ticket, newHolder string) error {
+	oldIndexKey, err := ctx.GetStub().CreateCompositeKey(holderTicketPrefix, []string{ticket.Holder, ticket.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = ctx.GetStub().DelState(oldIndexKey)
+	if err != nil {
+		return fmt.Errorf("failed to delete holder index: %v", err)
+	}
+	err = _putIndex(ctx, holderTicketPrefix, newHolder, ticket.ID)
+	if err != nil {
+		return err
+	}
+
+	ticket.Holder = newHolder
+	ticket.ListPrice = 0
+	return _putTicket(ctx, ticket)
+}
+
+// _putIndex writes an index entry pointing from owner to ticket
+func _putIndex(ctx contractapi.TransactionContextInterface, prefix string, owner string, ticketID string) error {
+	indexKey, err := ctx.GetStub().CreateCompositeKey(prefix, []string{owner, ticketID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = ctx.GetStub().PutState(indexKey, []byte{0x00})
+	if err != nil {
+		return fmt.Errorf("failed to put %s index: %v", prefix, err)
+	}
+	return nil
+}
+
+// _putTicket writes the ticket to the world state
+func _putTicket(ctx contractapi.TransactionContextInterface, ticket *Ticket) error {
+	ticketKey, err := ctx.GetStub().CreateCompositeKey(ticketPrefix, []string{ticket.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	ticketJSON, err := json.Marshal(ticket)
+	if err != nil {
+		return fmt.Errorf("failed to marshal ticket: %v", err)
+	}
+	err = ctx.GetStub().PutState(ticketKey, ticketJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put ticket %s: %v", ticket.ID, err)
+	}
+	return nil
+}
+
+// _tokenBalance reads the balance of account from the token chaincode
+func _tokenBalance(ctx contractapi.TransactionContextInterface, tokenChaincode string, account string) (int, error) {
+	args := [][]byte{[]byte("BalanceOf"), []byte(account)}
+	response := ctx.GetStub().InvokeChaincode(tokenChaincode, args, "")
+	if response.Status != shim.OK {
+		return 0, fmt.Errorf("failed to read balance on %s: %s", tokenChaincode, response.Message)
+	}
+	balance, err := strconv.Atoi(string(response.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("invalid balance %q on %s: %v", response.Payload, tokenChaincode, err)
+	}
+	return balance, nil
+}
+
+// _emitTransfer sets the ticket Transfer event
+func _emitTransfer(ctx contractapi.TransactionContextInterface, transfer transferEvent) error {
+	transferJSON, err := json.Marshal(transfer)
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	err = ctx.GetStub().SetEvent("Transfer", transferJSON)
+	if err != nil {
+		return fmt.Errorf("failed to set event: %v", err)
+	}
+	return nil
+}
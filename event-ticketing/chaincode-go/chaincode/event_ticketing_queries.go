@@ -0,0 +1,212 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// PurchasePreview is the outcome SimulatePurchase predicts for buying a ticket
+type PurchasePreview struct {
+	TicketID string `json:"ticketID"`
+	Seller   string `json:"seller"`
+	Buyer    string `json:"buyer"`
+	Price    int    `json:"price"`
+	// Royalty is the fee paid to the organizer on resales, SellerProceeds the rest of the price
+	Royalty        int `json:"royalty"`
+	SellerProceeds int `json:"sellerProceeds"`
+	// BuyerBalance is the buyer's token balance after paying
+	BuyerBalance int `json:"buyerBalance"`
+}
+
+// SimulatePurchase runs the checks of BuyTicket for the client and returns the price, royalty and
+// the client's token balance after buying, without buying. Wallets evaluate it to show the cost of
+// a ticket before the client submits BuyTicket.
+func (s *SmartContract) SimulatePurchase(ctx contractapi.TransactionContextInterface, ticketID string) (*PurchasePreview, error) {
+	buyer, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	ticket, ev, royalty, err := s._purchaseTerms(ctx, ticketID, buyer)
+	if err != nil {
+		return nil, err
+	}
+	balance, err := _tokenBalance(ctx, ev.TokenChaincode, buyer)
+	if err != nil {
+		return nil, err
+	}
+	if balance < ticket.ListPrice {
+		return nil, fmt.Errorf("buyer balance %d is less than the price %d of ticket %s", balance, ticket.ListPrice, ticketID)
+	}
+
+	return &PurchasePreview{
+		TicketID:       ticketID,
+		Seller:         ticket.Holder,
+		Buyer:          buyer,
+		Price:          ticket.ListPrice,
+		Royalty:        royalty,
+		SellerProceeds: ticket.ListPrice - royalty,
+		BuyerBalance:   balance - ticket.ListPrice,
+	}, nil
+}
+
+// ReadEvent returns the event stored in the world state with the given ID
+func (s *SmartContract) ReadEvent(ctx contractapi.TransactionContextInterface, eventID string) (*Event, error) {
+	eventKey, err := ctx.GetStub().CreateCompositeKey(eventPrefix, []string{eventID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	eventJSON, err := ctx.GetStub().GetState(eventKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if eventJSON == nil {
+		return nil, fmt.Errorf("the event %s does not exist", eventID)
+	}
+
+	var ev Event
+	err = json.Unmarshal(eventJSON, &ev)
+	if err != nil {
+		return nil, err
+	}
+	return &ev, nil
+}
+
+// ReadTicket returns the ticket stored in the world state with the given ID
+func (s *SmartContract) ReadTicket(ctx contractapi.TransactionContextInterface, ticketID string) (*Ticket, error) {
+	ticketKey, err := ctx.GetStub().CreateCompositeKey(ticketPrefix, []string{ticketID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	ticketJSON, err := ctx.GetStub().GetState(ticketKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if ticketJSON == nil {
+		return nil, fmt.Errorf("the ticket %s does not exist", ticketID)
+	}
+
+	var ticket Ticket
+	err = json.Unmarshal(ticketJSON, &ticket)
+	if err != nil {
+		return nil, err
+	}
+	return &ticket, nil
+}
+
+// maxTicketResults bounds the tickets GetTicketsByEvent and GetTicketsByHolder return, so a large
+// event or holder cannot exhaust the peer's memory. Longer listings are read with the page variants.
+const maxTicketResults = 1000
+
+// maxTicketPageSize bounds the page size of GetTicketsByEventPage and GetTicketsByHolderPage
+const maxTicketPageSize = 100
+
+// TicketPage is one page of tickets. Bookmark is passed to the next query to continue after the
+// last ticket and is empty on the last page.
+type TicketPage struct {
+	Tickets  []*Ticket `json:"tickets"`
+	Bookmark string    `json:"bookmark"`
+}
+
+// GetTicketsByEvent returns every ticket minted for an event, failing for events with more than
+// maxTicketResults tickets
+func (s *SmartContract) GetTicketsByEvent(ctx contractapi.TransactionContextInterface, eventID string) ([]*Ticket, error) {
+	return s._getTicketsByIndex(ctx, eventTicketPrefix, eventID, "GetTicketsByEventPage")
+}
+
+// GetTicketsByHolder returns every ticket held by an account, failing for holders of more than
+// maxTicketResults tickets
+func (s *SmartContract) GetTicketsByHolder(ctx contractapi.TransactionContextInterface, holder string) ([]*Ticket, error) {
+	return s._getTicketsByIndex(ctx, holderTicketPrefix, holder, "GetTicketsByHolderPage")
+}
+
+// GetTicketsByEventPage returns up to pageSize tickets of an event starting at bookmark, empty for
+// the first page
+func (s *SmartContract) GetTicketsByEventPage(ctx contractapi.TransactionContextInterface, eventID string, pageSize int, bookmark string) (*TicketPage, error) {
+	return s._getTicketsPageByIndex(ctx, eventTicketPrefix, eventID, pageSize, bookmark)
+}
+
+// GetTicketsByHolderPage returns up to pageSize tickets of a holder starting at bookmark, empty for
+// the first page
+func (s *SmartContract) GetTicketsByHolderPage(ctx contractapi.TransactionContextInterface, holder string, pageSize int, bookmark string) (*TicketPage, error) {
+	return s._getTicketsPageByIndex(ctx, holderTicketPrefix, holder, pageSize, bookmark)
+}
+
+// _getTicketsByIndex reads the tickets an index entry points to, up to maxTicketResults. Beyond it
+// the error names the page variant to call instead.
+func (s *SmartContract) _getTicketsByIndex(ctx contractapi.TransactionContextInterface, prefix string, owner string, pagedFunction string) ([]*Ticket, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(prefix, []string{owner})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tickets from %s index: %v", prefix, err)
+	}
+	defer resultsIterator.Close()
+
+	var tickets []*Ticket
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		if len(tickets) == maxTicketResults {
+			return nil, fmt.Errorf("results truncated at %d tickets, use %s with a bookmark to read them all", maxTicketResults, pagedFunction)
+		}
+
+		ticket, err := s._readIndexedTicket(ctx, response.Key)
+		if err != nil {
+			return nil, err
+		}
+		tickets = append(tickets, ticket)
+	}
+
+	return tickets, nil
+}
+
+// _getTicketsPageByIndex reads one page of the tickets an index entry points to
+func (s *SmartContract) _getTicketsPageByIndex(ctx contractapi.TransactionContextInterface, prefix string, owner string, pageSize int, bookmark string) (*TicketPage, error) {
+	if pageSize <= 0 || pageSize > maxTicketPageSize {
+		return nil, fmt.Errorf("pageSize must be between 1 and %d", maxTicketPageSize)
+	}
+	resultsIterator, metadata, err := ctx.GetStub().GetStateByPartialCompositeKeyWithPagination(prefix, []string{owner}, int32(pageSize), bookmark)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tickets from %s index: %v", prefix, err)
+	}
+	defer resultsIterator.Close()
+
+	page := &TicketPage{Tickets: []*Ticket{}}
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		ticket, err := s._readIndexedTicket(ctx, response.Key)
+		if err != nil {
+			return nil, err
+		}
+		page.Tickets = append(page.Tickets, ticket)
+	}
+	if metadata.FetchedRecordsCount == int32(pageSize) {
+		page.Bookmark = metadata.Bookmark
+	}
+	return page, nil
+}
+
+// _readIndexedTicket reads the ticket an event~ticket or holder~ticket index key points to
+func (s *SmartContract) _readIndexedTicket(ctx contractapi.TransactionContextInterface, indexKey string) (*Ticket, error) {
+	_, keyParts, err := ctx.GetStub().SplitCompositeKey(indexKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to split composite key: %v", err)
+	}
+	if len(keyParts) != 2 {
+		return nil, fmt.Errorf("unexpected index key %s", indexKey)
+	}
+	return s.ReadTicket(ctx, keyParts[1])
+}
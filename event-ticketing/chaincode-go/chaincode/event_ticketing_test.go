@@ -0,0 +1,180 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+)
+
+const fanMSPID = "Org2MSP"
+
+// fakeToken stands in for the token chaincode. Batches are paid from the payer's account,
+// debited once with the total as by the token chaincode.
+type fakeToken struct {
+	payer    string
+	balances map[string]int
+	batches  int
+}
+
+func (f *fakeToken) invoke(args [][]byte) pb.Response {
+	if string(args[0]) != "BatchTransfer" {
+		return shim.Error("unexpected function " + string(args[0]))
+	}
+	var payments []struct {
+		Receiver string `json:"receiver"`
+		Amount   int    `json:"amount"`
+	}
+	err := json.Unmarshal(args[1], &payments)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	total := 0
+	for _, payment := range payments {
+		total += payment.Amount
+	}
+	if f.balances[f.payer] < total {
+		return shim.Error(fmt.Sprintf("failed to transfer: client account %s has insufficient funds", f.payer))
+	}
+	f.balances[f.payer] -= total
+	for _, payment := range payments {
+		f.balances[payment.Receiver] += payment.Amount
+	}
+	f.batches++
+	return shim.Success(nil)
+}
+
+func checkError(t *testing.T, err error, expected string) {
+	t.Helper()
+	if expected == "" && err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected != "" && (err == nil || !strings.Contains(err.Error(), expected)) {
+		t.Fatalf("expected error containing %q, got %v", expected, err)
+	}
+}
+
+func checkHolder(t *testing.T, stub *fakeStub, holder string) {
+	t.Helper()
+	ticket, err := new(SmartContract).ReadTicket(newContext(stub, "organizer", "Org1MSP"), "ticket1")
+	if err != nil {
+		t.Fatalf("failed to read ticket: %v", err)
+	}
+	if ticket.Holder != holder {
+		t.Fatalf("expected ticket held by %s, got %s", holder, ticket.Holder)
+	}
+}
+
+// mintTicket creates an event allowing resales at up to 120% of face value with a 10% royalty
+// and mints a ticket with a face value of 100
+func mintTicket(t *testing.T, stub *fakeStub) *fakeToken {
+	t.Helper()
+	token := &fakeToken{balances: map[string]int{"fan1": 1000, "fan2": 1000}}
+	stub.chaincodes[defaultTokenChaincode] = token.invoke
+
+	contract := new(SmartContract)
+	checkError(t, contract.CreateEvent(newContext(stub, "organizer", "Org1MSP"), "gig1", "Gig", "Arena", "2020-10-01T20:00:00Z", 12000, 1000, ""), "")
+	checkError(t, contract.MintTicket(newContext(stub, "organizer", "Org1MSP"), "gig1", "ticket1", "A", "1", "12", 100), "")
+	return token
+}
+
+func TestMintTicket(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	mintTicket(t, stub)
+
+	err := contract.MintTicket(newContext(stub, "fan1", fanMSPID), "gig1", "ticket2", "A", "1", "13", 100)
+	checkError(t, err, "only the organizer can mint tickets for event gig1")
+
+	err = contract.MintTicket(newContext(stub, "organizer", "Org1MSP"), "gig1", "ticket1", "A", "1", "13", 100)
+	checkError(t, err, "the ticket ticket1 already exists")
+
+	tickets, err := contract.GetTicketsByHolder(newContext(stub, "organizer", "Org1MSP"), "organizer")
+	checkError(t, err, "")
+	if len(tickets) != 1 || tickets[0].ID != "ticket1" {
+		t.Fatalf("unexpected tickets %+v", tickets)
+	}
+}
+
+func TestBuyTicket(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	token := mintTicket(t, stub)
+
+	err := contract.ListTicket(newContext(stub, "fan1", fanMSPID), "ticket1", 100)
+	checkError(t, err, "only the holder can list ticket ticket1")
+
+	// the first sale pays the organizer the whole price without royalty
+	checkError(t, contract.ListTicket(newContext(stub, "organizer", "Org1MSP"), "ticket1", 100), "")
+	token.payer = "fan1"
+	checkError(t, contract.BuyTicket(newContext(stub, "fan1", fanMSPID), "ticket1"), "")
+	checkHolder(t, stub, "fan1")
+
+	err = contract.ListTicket(newContext(stub, "fan1", fanMSPID), "ticket1", 121)
+	checkError(t, err, "price 121 is outside the allowed range 0 to 120 for ticket ticket1")
+
+	// the resale pays the seller and the royalty in one batch debiting the buyer once
+	checkError(t, contract.ListTicket(newContext(stub, "fan1", fanMSPID), "ticket1", 120), "")
+	token.payer = "fan2"
+	checkError(t, contract.BuyTicket(newContext(stub, "fan2", fanMSPID), "ticket1"), "")
+	checkHolder(t, stub, "fan2")
+
+	expected := map[string]int{"fan1": 1008, "fan2": 880, "organizer": 112}
+	for account, balance := range expected {
+		if token.balances[account] != balance {
+			t.Fatalf("expected %s to hold %d, got %d", account, balance, token.balances[account])
+		}
+	}
+	if token.batches != 2 {
+		t.Fatalf("expected one batch per sale, got %d", token.batches)
+	}
+
+	var transfer transferEvent
+	err = json.Unmarshal(stub.eventValue, &transfer)
+	if err != nil {
+		t.Fatalf("failed to unmarshal event: %v", err)
+	}
+	if transfer != (transferEvent{"ticket1", "fan1", "fan2", 120, 12}) {
+		t.Fatalf("unexpected event %+v", transfer)
+	}
+
+	err = contract.BuyTicket(newContext(stub, "fan1", fanMSPID), "ticket1")
+	checkError(t, err, "ticket ticket1 is not for sale")
+}
+
+func TestBuyTicketInsufficientFunds(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	token := mintTicket(t, stub)
+	token.balances["fan1"] = 99
+
+	checkError(t, contract.ListTicket(newContext(stub, "organizer", "Org1MSP"), "ticket1", 100), "")
+	token.payer = "fan1"
+	err := contract.BuyTicket(newContext(stub, "fan1", fanMSPID), "ticket1")
+	checkError(t, err, "failed to transfer tokens on token_erc20: failed to transfer: client account fan1 has insufficient funds")
+	checkHolder(t, stub, "organizer")
+}
+
+func TestCheckIn(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	mintTicket(t, stub)
+	checkError(t, contract.TransferTicket(newContext(stub, "organizer", "Org1MSP"), "ticket1", "fan1"), "")
+
+	err := contract.CheckIn(newContext(stub, "fan1", fanMSPID), "ticket1", "fan1")
+	checkError(t, err, "only the organizer of event gig1 can check tickets in")
+
+	err = contract.CheckIn(newContext(stub, "organizer", "Org1MSP"), "ticket1", "fan2")
+	checkError(t, err, "ticket ticket1 is not held by the presenting account")
+
+	checkError(t, contract.CheckIn(newContext(stub, "organizer", "Org1MSP"), "ticket1", "fan1"), "")
+	if stub.eventName != "CheckIn" {
+		t.Fatalf("expected CheckIn event, got %q", stub.eventName)
+	}
+
+	err = contract.TransferTicket(newContext(stub, "fan1", fanMSPID), "ticket1", "fan2")
+	checkError(t, err, "ticket ticket1 has been used")
+}
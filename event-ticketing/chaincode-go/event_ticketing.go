@@ -0,0 +1,23 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"log"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/event-ticketing/chaincode-go/chaincode"
+)
+
+func main() {
+	ticketChaincode, err := contractapi.NewChaincode(&chaincode.SmartContract{})
+	if err != nil {
+		log.Panicf("Error creating event-ticketing chaincode: %v", err)
+	}
+
+	if err := ticketChaincode.Start(); err != nil {
+		log.Panicf("Error starting event-ticketing chaincode: %v", err)
+	}
+}
@@ -0,0 +1,13 @@
+module github.com/hyperledger/fabric-samples/event-ticketing/chaincode-go
+
+go 1.14
+
+require (
+	github.com/golang/protobuf v1.5.3
+	github.com/hyperledger/fabric-chaincode-go v0.0.0-20230731094759-d626e9ab09b9
+	github.com/hyperledger/fabric-contract-api-go v1.2.2
+	github.com/hyperledger/fabric-protos-go v0.3.0
+	github.com/hyperledger/fabric-samples/internal/ledgerutil v0.0.0
+)
+
+replace github.com/hyperledger/fabric-samples/internal/ledgerutil => ../../internal/ledgerutil
@@ -0,0 +1,771 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil"
+)
+
+// This sample assumes Org1 plays the food safety scheme operator (the authority). Only the
+// authority can register the orgs taking part in the supply chain and the roles they play.
+const authorityMSPID = "Org1MSP"
+
+// object names for prefix
+const (
+	participantPrefix = "participant"
+	lotPrefix         = "lot"
+	processPrefix     = "process"
+	outputIndexPrefix = "input~output"
+	eventPrefix       = "lotEvent"
+)
+
+// participant roles
+const (
+	roleProducer    = "PRODUCER"
+	roleProcessor   = "PROCESSOR"
+	roleDistributor = "DISTRIBUTOR"
+	roleRetailer    = "RETAILER"
+)
+
+var validRoles = map[string]bool{roleProducer: true, roleProcessor: true, roleDistributor: true, roleRetailer: true}
+
+// lot status values
+const (
+	statusActive    = "ACTIVE"
+	statusInTransit = "IN_TRANSIT"
+	statusConsumed  = "CONSUMED"
+	statusSold      = "SOLD"
+)
+
+// lot event types, recorded in the order they happen to a lot
+const (
+	eventHarvested   = "HARVESTED"
+	eventProduced    = "PRODUCED"
+	eventConsumed    = "CONSUMED"
+	eventShipped     = "SHIPPED"
+	eventReceived    = "RECEIVED"
+	eventTemperature = "TEMPERATURE"
+	eventSold        = "SOLD"
+)
+
+// maxTraceDepth bounds how many processing steps a trace will follow back to the harvest
+const maxTraceDepth = 50
+
+// SmartContract provides functions for tracing food lots from the farm to the retailer
+type SmartContract struct {
+	contractapi.Contract
+}
+
+// Participant is an org registered by the authority with the roles it plays in the supply chain
+type Participant struct {
+	ObjectType string   `json:"objectType"`
+	MSPID      string   `json:"mspID"`
+	Name       string   `json:"name"`
+	Roles      []string `json:"roles"`
+}
+
+// Origin describes where and when the produce of a lot was harvested, caught or slaughtered
+type Origin struct {
+	Site        string `json:"site"`
+	Location    string `json:"location"`
+	HarvestDate string `json:"harvestDate"`
+}
+
+// TemperatureRange is the range in degrees Celsius a lot must be kept in. The zero value is no
+// cold chain requirement.
+type TemperatureRange struct {
+	MinCelsius float64 `json:"minCelsius"`
+	MaxCelsius float64 `json:"maxCelsius"`
+}
+
+// LotSpec describes a lot produced by a processing event
+type LotSpec struct {
+	ID        string           `json:"lotID"`
+	Product   string           `json:"product"`
+	Quantity  int              `json:"quantity"`
+	Unit      string           `json:"unit"`
+	ColdChain TemperatureRange `json:"coldChain"`
+}
+
+// Lot is a quantity of food that moves between organizations as a single unit. Lots created at
+// origin carry their Origin; lots produced by processing carry the process and its inputs.
+type Lot struct {
+	ObjectType   string            `json:"objectType"`
+	ID           string            `json:"lotID"`
+	Product      string            `json:"product"`
+	Quantity     int               `json:"quantity"`
+	Unit         string            `json:"unit"`
+	OriginOrg    string            `json:"originOrg"`
+	CustodianOrg string            `json:"custodianOrg"`
+	PendingOrg   string            `json:"pendingOrg,omitempty" metadata:",optional"`
+	Status       string            `json:"status"`
+	Origin       *Origin           `json:"origin,omitempty" metadata:",optional"`
+	ProcessID    string            `json:"processID,omitempty" metadata:",optional"`
+	Inputs       []string          `json:"inputs,omitempty" metadata:",optional"`
+	ColdChain    *TemperatureRange `json:"coldChain,omitempty" metadata:",optional"`
+	// Excursions is the number of temperature attestations that found the lot outside its range
+	Excursions int    `json:"excursions"`
+	CreatedAt  string `json:"createdAt"`
+	// EventSeq is the sequence number of the latest event of the lot
+	EventSeq int `json:"eventSeq"`
+}
+
+// Process records which input lots a processor turned into which output lots, e.g. washing and
+// packing, slaughter and cutting, or blending
+type Process struct {
+	ObjectType string   `json:"objectType"`
+	ID         string   `json:"processID"`
+	Org        string   `json:"org"`
+	Process    string   `json:"process"`
+	Inputs     []string `json:"inputs"`
+	Outputs    []string `json:"outputs"`
+	TxID       string   `json:"txID"`
+	Timestamp  string   `json:"timestamp"`
+}
+
+// TemperatureAttestation is a summary of the temperature readings of a lot over a period,
+// attested by the org holding or receiving it. The readings themselves stay off chain; their
+// digest is recorded as evidence.
+type TemperatureAttestation struct {
+	From         string  `json:"from"`
+	To           string  `json:"to"`
+	MinCelsius   float64 `json:"minCelsius"`
+	MaxCelsius   float64 `json:"maxCelsius"`
+	Readings     int     `json:"readings"`
+	DeviceID     string  `json:"deviceID"`
+	EvidenceHash string  `json:"evidenceHash"`
+	Compliant    bool    `json:"compliant"`
+}
+
+// LotEvent is one step in the life of a lot. Events are numbered per lot from 0, its harvest or
+// production.
+type LotEvent struct {
+	ObjectType  string                  `json:"objectType"`
+	LotID       string                  `json:"lotID"`
+	Seq         int                     `json:"seq"`
+	Type        string                  `json:"type"`
+	Org         string                  `json:"org"`
+	ProcessID   string                  `json:"processID,omitempty" metadata:",optional"`
+	ToOrg       string                  `json:"toOrg,omitempty" metadata:",optional"`
+	Temperature *TemperatureAttestation `json:"temperature,omitempty" metadata:",optional"`
+	TxID        string                  `json:"txID"`
+	Timestamp   string                  `json:"timestamp"`
+}
+
+// custodyEvent is emitted whenever a lot changes hands
+type custodyEvent struct {
+	LotID string `json:"lotID"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+// excursionEvent is emitted when a temperature attestation finds a lot outside its range
+type excursionEvent struct {
+	LotID      string  `json:"lotID"`
+	Org        string  `json:"org"`
+	MinCelsius float64 `json:"minCelsius"`
+	MaxCelsius float64 `json:"maxCelsius"`
+	From       string  `json:"from"`
+	To         string  `json:"to"`
+}
+
+// RegisterParticipant registers an org and the roles it plays, replacing any roles registered
+// before. Only the authority can register participants.
+func (s *SmartContract) RegisterParticipant(ctx contractapi.TransactionContextInterface, mspID string, name string, roles []string) error {
+	err := _requireOrg(ctx, authorityMSPID, "register participants")
+	if err != nil {
+		return err
+	}
+	if mspID == "" || name == "" {
+		return fmt.Errorf("participant MSPID and name must be set")
+	}
+	if len(roles) == 0 {
+		return fmt.Errorf("a participant must play at least one role")
+	}
+	seen := make(map[string]bool)
+	for _, role := range roles {
+		if !validRoles[role] {
+			return fmt.Errorf("role %s must be one of %s, %s, %s or %s", role, roleProducer, roleProcessor, roleDistributor, roleRetailer)
+		}
+		if seen[role] {
+			return fmt.Errorf("role %s is listed more than once", role)
+		}
+		seen[role] = true
+	}
+
+	participant := Participant{
+		ObjectType: participantPrefix,
+		MSPID:      mspID,
+		Name:       name,
+		Roles:      roles,
+	}
+	participantJSON, err := json.Marshal(participant)
+	if err != nil {
+		return fmt.Errorf("failed to marshal participant: %v", err)
+	}
+	participantKey, err := ctx.GetStub().CreateCompositeKey(participantPrefix, []string{mspID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = ctx.GetStub().PutState(participantKey, participantJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put participant %s: %v", mspID, err)
+	}
+	return nil
+}
+
+// CreateLot registers a lot at its origin, held by the client's org, which must be a registered
+// producer. harvestDate is YYYY-MM-DD. Pass a zero coldChain for produce kept at ambient
+// temperature.
+func (s *SmartContract) CreateLot(ctx contractapi.TransactionContextInterface, lotID string, product string, quantity int, unit string, origin Origin, coldChain TemperatureRange) error {
+	clientOrgID, err := _requireRole(ctx, roleProducer)
+	if err != nil {
+		return err
+	}
+
+	spec := LotSpec{lotID, product, quantity, unit, coldChain}
+	err = _validateLot(spec)
+	if err != nil {
+		return err
+	}
+	if origin.Site == "" || origin.Location == "" {
+		return fmt.Errorf("origin site and location must be set")
+	}
+	if _, err := time.Parse("2006-01-02", origin.HarvestDate); err != nil {
+		return fmt.Errorf("harvest date %s is not a date as YYYY-MM-DD", origin.HarvestDate)
+	}
+	existing, err := _getLot(ctx, lotID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("the lot %s already exists", lotID)
+	}
+
+	now, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return err
+	}
+	if origin.HarvestDate > now.Format("2006-01-02") {
+		return fmt.Errorf("harvest date %s is in the future", origin.HarvestDate)
+	}
+	lot := _newLot(spec, clientOrgID, now)
+	lot.Origin = &origin
+	err = _putLot(ctx, lot)
+	if err != nil {
+		return err
+	}
+	return _putLotEvent(ctx, &LotEvent{LotID: lotID, Seq: 0, Type: eventHarvested, Org: clientOrgID}, now)
+}
+
+// Process consumes input lots held by the client's org, which must be a registered processor,
+// and produces new output lots, e.g. washing and bagging salad or cutting carcasses into retail
+// packs. The outputs are held by the processor and refer back to the inputs, so a trace of an
+// output reaches every lot it was made from.
+func (s *SmartContract) Process(ctx contractapi.TransactionContextInterface, processID string, process string, inputIDs []string, outputs []LotSpec) error {
+	clientOrgID, err := _requireRole(ctx, roleProcessor)
+	if err != nil {
+		return err
+	}
+
+	if processID == "" || process == "" {
+		return fmt.Errorf("process ID and description must be set")
+	}
+	if len(inputIDs) == 0 || len(outputs) == 0 {
+		return fmt.Errorf("a process needs at least one input and one output")
+	}
+	processKey, err := ctx.GetStub().CreateCompositeKey(processPrefix, []string{processID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	existing, err := ctx.GetStub().GetState(processKey)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if existing != nil {
+		return fmt.Errorf("the process %s already exists", processID)
+	}
+
+	now, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return err
+	}
+	seen := make(map[string]bool)
+	for _, inputID := range inputIDs {
+		if seen[inputID] {
+			return fmt.Errorf("input lot %s is listed more than once", inputID)
+		}
+		seen[inputID] = true
+
+		input, err := s.ReadLot(ctx, inputID)
+		if err != nil {
+			return err
+		}
+		if input.CustodianOrg != clientOrgID {
+			return fmt.Errorf("a client from %s cannot process lot %s held by %s", clientOrgID, inputID, input.CustodianOrg)
+		}
+		if input.Status != statusActive {
+			return fmt.Errorf("lot %s is %s and cannot be processed", inputID, input.Status)
+		}
+
+		input.Status = statusConsumed
+		input.EventSeq++
+		err = _putLot(ctx, input)
+		if err != nil {
+			return err
+		}
+		err = _putLotEvent(ctx, &LotEvent{LotID: inputID, Seq: input.EventSeq, Type: eventConsumed, Org: clientOrgID, ProcessID: processID}, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	var outputIDs []string
+	for _, output := range outputs {
+		err = _validateLot(output)
+		if err != nil {
+			return err
+		}
+		if seen[output.ID] {
+			return fmt.Errorf("lot %s is listed more than once", output.ID)
+		}
+		seen[output.ID] = true
+		existing, err := _getLot(ctx, output.ID)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			return fmt.Errorf("the lot %s already exists", output.ID)
+		}
+
+		lot := _newLot(output, clientOrgID, now)
+		lot.ProcessID = processID
+		lot.Inputs = inputIDs
+		err = _putLot(ctx, lot)
+		if err != nil {
+			return err
+		}
+		err = _putLotEvent(ctx, &LotEvent{LotID: output.ID, Seq: 0, Type: eventProduced, Org: clientOrgID, ProcessID: processID}, now)
+		if err != nil {
+			return err
+		}
+
+		// index every input -> output edge so lots can be traced forward without a rich query
+		for _, inputID := range inputIDs {
+			indexKey, err := ctx.GetStub().CreateCompositeKey(outputIndexPrefix, []string{inputID, output.ID})
+			if err != nil {
+				return fmt.Errorf("failed to create composite key: %v", err)
+			}
+			err = ctx.GetStub().PutState(indexKey, []byte{0x00})
+			if err != nil {
+				return fmt.Errorf("failed to put index for %s: %v", output.ID, err)
+			}
+		}
+		outputIDs = append(outputIDs, output.ID)
+	}
+
+	record := Process{
+		ObjectType: processPrefix,
+		ID:         processID,
+		Org:        clientOrgID,
+		Process:    process,
+		Inputs:     inputIDs,
+		Outputs:    outputIDs,
+		TxID:       ctx.GetStub().GetTxID(),
+		Timestamp:  now.Format(time.RFC3339),
+	}
+	recordJSON, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal process: %v", err)
+	}
+	err = ctx.GetStub().PutState(processKey, recordJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put process %s: %v", processID, err)
+	}
+	return ctx.GetStub().SetEvent("Process", recordJSON)
+}
+
+// Ship hands a lot to another registered participant. The lot stays in transit until the
+// receiving org accepts it with Receive.
+func (s *SmartContract) Ship(ctx contractapi.TransactionContextInterface, lotID string, toOrg string) error {
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+
+	lot, err := s.ReadLot(ctx, lotID)
+	if err != nil {
+		return err
+	}
+	if lot.CustodianOrg != clientOrgID {
+		return fmt.Errorf("a client from %s cannot ship lot %s held by %s", clientOrgID, lotID, lot.CustodianOrg)
+	}
+	if lot.Status != statusActive {
+		return fmt.Errorf("lot %s is %s and cannot be shipped", lotID, lot.Status)
+	}
+	if toOrg == "" || toOrg == clientOrgID {
+		return fmt.Errorf("receiving org must be set and differ from the current custodian")
+	}
+	receiver, err := _getParticipant(ctx, toOrg)
+	if err != nil {
+		return err
+	}
+	if receiver == nil {
+		return fmt.Errorf("%s is not a registered participant", toOrg)
+	}
+
+	now, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return err
+	}
+	lot.PendingOrg = toOrg
+	lot.Status = statusInTransit
+	lot.EventSeq++
+	err = _putLot(ctx, lot)
+	if err != nil {
+		return err
+	}
+	return _putLotEvent(ctx, &LotEvent{LotID: lotID, Seq: lot.EventSeq, Type: eventShipped, Org: clientOrgID, ToOrg: toOrg}, now)
+}
+
+// Receive is called by the receiving org to confirm it has taken delivery of a lot
+func (s *SmartContract) Receive(ctx contractapi.TransactionContextInterface, lotID string) error {
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+
+	lot, err := s.ReadLot(ctx, lotID)
+	if err != nil {
+		return err
+	}
+	if lot.Status != statusInTransit || lot.PendingOrg != clientOrgID {
+		return fmt.Errorf("lot %s is not in transit to %s", lotID, clientOrgID)
+	}
+
+	now, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return err
+	}
+	previousOrg := lot.CustodianOrg
+	lot.CustodianOrg = clientOrgID
+	lot.PendingOrg = ""
+	lot.Status = statusActive
+	lot.EventSeq++
+	err = _putLot(ctx, lot)
+	if err != nil {
+		return err
+	}
+	err = _putLotEvent(ctx, &LotEvent{LotID: lotID, Seq: lot.EventSeq, Type: eventReceived, Org: clientOrgID}, now)
+	if err != nil {
+		return err
+	}
+	return _emitEvent(ctx, "CustodyTransfer", custodyEvent{lotID, previousOrg, clientOrgID})
+}
+
+// AttestTemperature records the lowest and highest temperature logged for a cold chain lot
+// between from and to, as RFC 3339 times, by the org holding the lot or the org it is shipped to.
+// evidenceHash is the hex SHA-256 digest of the logger's readings. The attestation is compliant
+// when both temperatures are within the lot's range; an excursion is counted on the lot and
+// emits a TemperatureExcursion event.
+func (s *SmartContract) AttestTemperature(ctx contractapi.TransactionContextInterface, lotID string, from string, to string, minCelsius float64, maxCelsius float64, readings int, deviceID string, evidenceHash string) (*TemperatureAttestation, error) {
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get MSPID: %v", err)
+	}
+
+	lot, err := s.ReadLot(ctx, lotID)
+	if err != nil {
+		return nil, err
+	}
+	if clientOrgID != lot.CustodianOrg && clientOrgID != lot.PendingOrg {
+		return nil, fmt.Errorf("a client from %s is not authorized to attest the temperature of lot %s", clientOrgID, lotID)
+	}
+	if lot.ColdChain == nil {
+		return nil, fmt.Errorf("lot %s has no cold chain requirement", lotID)
+	}
+	if lot.Status == statusConsumed || lot.Status == statusSold {
+		return nil, fmt.Errorf("lot %s is %s", lotID, lot.Status)
+	}
+	if minCelsius > maxCelsius || math.IsNaN(minCelsius) || math.IsNaN(maxCelsius) {
+		return nil, fmt.Errorf("minimum temperature must not be above the maximum")
+	}
+	if readings <= 0 {
+		return nil, fmt.Errorf("number of readings must be a positive integer")
+	}
+	if deviceID == "" || !_validDigest(evidenceHash) {
+		return nil, fmt.Errorf("device ID and a hex SHA-256 evidence hash must be set")
+	}
+
+	now, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	fromTime, err := time.Parse(time.RFC3339, from)
+	if err != nil {
+		return nil, fmt.Errorf("from %s is not an RFC 3339 time", from)
+	}
+	toTime, err := time.Parse(time.RFC3339, to)
+	if err != nil {
+		return nil, fmt.Errorf("to %s is not an RFC 3339 time", to)
+	}
+	createdAt, err := time.Parse(time.RFC3339, lot.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse creation time of lot %s: %v", lotID, err)
+	}
+	if !fromTime.Before(toTime) || fromTime.Before(createdAt) || toTime.After(now) {
+		return nil, fmt.Errorf("the period from %s to %s must be after the lot was created and not in the future", from, to)
+	}
+
+	attestation := &TemperatureAttestation{
+		From:         fromTime.UTC().Format(time.RFC3339),
+		To:           toTime.UTC().Format(time.RFC3339),
+		MinCelsius:   minCelsius,
+		MaxCelsius:   maxCelsius,
+		Readings:     readings,
+		DeviceID:     deviceID,
+		EvidenceHash: evidenceHash,
+		Compliant:    minCelsius >= lot.ColdChain.MinCelsius && maxCelsius <= lot.ColdChain.MaxCelsius,
+	}
+	if !attestation.Compliant {
+		lot.Excursions++
+	}
+	lot.EventSeq++
+	err = _putLot(ctx, lot)
+	if err != nil {
+		return nil, err
+	}
+	err = _putLotEvent(ctx, &LotEvent{LotID: lotID, Seq: lot.EventSeq, Type: eventTemperature, Org: clientOrgID, Temperature: attestation}, now)
+	if err != nil {
+		return nil, err
+	}
+	if !attestation.Compliant {
+		err = _emitEvent(ctx, "TemperatureExcursion", excursionEvent{lotID, clientOrgID, minCelsius, maxCelsius, attestation.From, attestation.To})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return attestation, nil
+}
+
+// RecordSale is called by the retailer holding a lot when it has been sold to consumers, which
+// ends the lot's path through the supply chain
+func (s *SmartContract) RecordSale(ctx contractapi.TransactionContextInterface, lotID string) error {
+	clientOrgID, err := _requireRole(ctx, roleRetailer)
+	if err != nil {
+		return err
+	}
+
+	lot, err := s.ReadLot(ctx, lotID)
+	if err != nil {
+		return err
+	}
+	if lot.CustodianOrg != clientOrgID {
+		return fmt.Errorf("a client from %s cannot sell lot %s held by %s", clientOrgID, lotID, lot.CustodianOrg)
+	}
+	if lot.Status != statusActive {
+		return fmt.Errorf("lot %s is %s and cannot be sold", lotID, lot.Status)
+	}
+
+	now, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return err
+	}
+	lot.Status = statusSold
+	lot.EventSeq++
+	err = _putLot(ctx, lot)
+	if err != nil {
+		return err
+	}
+	return _putLotEvent(ctx, &LotEvent{LotID: lotID, Seq: lot.EventSeq, Type: eventSold, Org: clientOrgID}, now)
+}
+
+// _newLot returns an active lot held by the org that created it
+func _newLot(spec LotSpec, org string, now time.Time) *Lot {
+	lot := &Lot{
+		ObjectType:   lotPrefix,
+		ID:           spec.ID,
+		Product:      spec.Product,
+		Quantity:     spec.Quantity,
+		Unit:         spec.Unit,
+		OriginOrg:    org,
+		CustodianOrg: org,
+		Status:       statusActive,
+		CreatedAt:    now.Format(time.RFC3339),
+	}
+	if spec.ColdChain != (TemperatureRange{}) {
+		coldChain := spec.ColdChain
+		lot.ColdChain = &coldChain
+	}
+	return lot
+}
+
+// _validateLot checks the fields of a new lot
+func _validateLot(spec LotSpec) error {
+	if spec.ID == "" {
+		return fmt.Errorf("lot ID must be set")
+	}
+	if spec.Product == "" {
+		return fmt.Errorf("product must be set for lot %s", spec.ID)
+	}
+	if spec.Quantity <= 0 {
+		return fmt.Errorf("quantity for lot %s must be a positive integer", spec.ID)
+	}
+	if spec.Unit == "" {
+		return fmt.Errorf("unit must be set for lot %s", spec.ID)
+	}
+	if spec.ColdChain != (TemperatureRange{}) && !(spec.ColdChain.MinCelsius < spec.ColdChain.MaxCelsius) {
+		return fmt.Errorf("cold chain range of lot %s must have its minimum below its maximum", spec.ID)
+	}
+	return nil
+}
+
+// _validDigest checks a hex encoded SHA-256 digest
+func _validDigest(digest string) bool {
+	if len(digest) != 64 {
+		return false
+	}
+	for _, c := range digest {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// _requireOrg checks the client belongs to the given org
+func _requireOrg(ctx contractapi.TransactionContextInterface, org string, action string) error {
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+	if clientMSPID != org {
+		return fmt.Errorf("client from %s is not authorized to %s", clientMSPID, action)
+	}
+	return nil
+}
+
+// _requireRole checks the client's org is registered with the given role and returns its MSPID
+func _requireRole(ctx contractapi.TransactionContextInterface, role string) (string, error) {
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get MSPID: %v", err)
+	}
+	participant, err := _getParticipant(ctx, clientMSPID)
+	if err != nil {
+		return "", err
+	}
+	if participant != nil {
+		for _, r := range participant.Roles {
+			if r == role {
+				return clientMSPID, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("client from %s is not a registered %s", clientMSPID, role)
+}
+
+// _getParticipant reads a participant, returning nil when the org is not registered
+func _getParticipant(ctx contractapi.TransactionContextInterface, mspID string) (*Participant, error) {
+	participantKey, err := ctx.GetStub().CreateCompositeKey(participantPrefix, []string{mspID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	participantJSON, err := ctx.GetStub().GetState(participantKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if participantJSON == nil {
+		return nil, nil
+	}
+
+	var participant Participant
+	err = json.Unmarshal(participantJSON, &participant)
+	if err != nil {
+		return nil, err
+	}
+	return &participant, nil
+}
+
+// _getLot reads a lot, returning nil when it does not exist
+func _getLot(ctx contractapi.TransactionContextInterface, lotID string) (*Lot, error) {
+	lotKey, err := ctx.GetStub().CreateCompositeKey(lotPrefix, []string{lotID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	lotJSON, err := ctx.GetStub().GetState(lotKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if lotJSON == nil {
+		return nil, nil
+	}
+
+	var lot Lot
+	err = json.Unmarshal(lotJSON, &lot)
+	if err != nil {
+		return nil, err
+	}
+	return &lot, nil
+}
+
+// _putLot writes the lot to the world state
+func _putLot(ctx contractapi.TransactionContextInterface, lot *Lot) error {
+	lotKey, err := ctx.GetStub().CreateCompositeKey(lotPrefix, []string{lot.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	lotJSON, err := json.Marshal(lot)
+	if err != nil {
+		return fmt.Errorf("failed to marshal lot: %v", err)
+	}
+	err = ctx.GetStub().PutState(lotKey, lotJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put lot %s: %v", lot.ID, err)
+	}
+	return nil
+}
+
+// _putLotEvent completes an event of a lot with the transaction that recorded it and writes it
+// under the event composite key of its lot and sequence number, zero-padded so the events of a
+// lot are listed in order
+func _putLotEvent(ctx contractapi.TransactionContextInterface, event *LotEvent, now time.Time) error {
+	event.ObjectType = eventPrefix
+	event.TxID = ctx.GetStub().GetTxID()
+	event.Timestamp = now.Format(time.RFC3339)
+
+	eventKey, err := ctx.GetStub().CreateCompositeKey(eventPrefix, []string{event.LotID, fmt.Sprintf("%08d", event.Seq)})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	eventJSON, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal lot event: %v", err)
+	}
+	err = ctx.GetStub().PutState(eventKey, eventJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put event %d of lot %s: %v", event.Seq, event.LotID, err)
+	}
+	return nil
+}
+
+// _emitEvent marshals the payload and sets it as the chaincode event
+func _emitEvent(ctx contractapi.TransactionContextInterface, name string, payload interface{}) error {
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	err = ctx.GetStub().SetEvent(name, payloadJSON)
+	if err != nil {
+		return fmt.Errorf("failed to set event: %v", err)
+	}
+	return nil
+}
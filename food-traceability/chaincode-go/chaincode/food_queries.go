@@ -0,0 +1,241 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// TracedLot is a lot on the path of a trace with its events. Depth is the number of processing
+// steps between the lot and the traced lot: negative for the lots it was made from, positive for
+// the lots made from it.
+type TracedLot struct {
+	Lot    *Lot        `json:"lot"`
+	Depth  int         `json:"depth"`
+	Events []*LotEvent `json:"events"`
+}
+
+// Trace is the path of a lot through the supply chain, from the producers of the lots it was made
+// from, through every process, to the lots made from it and the retailers holding them
+type Trace struct {
+	LotID        string         `json:"lotID"`
+	Lots         []*TracedLot   `json:"lots"`
+	Processes    []*Process     `json:"processes"`
+	Participants []*Participant `json:"participants"`
+	// Excursions is the number of temperature excursions of all lots on the path, and
+	// ColdChainCompliant is true when there are none
+	Excursions         int  `json:"excursions"`
+	ColdChainCompliant bool `json:"coldChainCompliant"`
+}
+
+// ReadParticipant returns the registration of an org
+func (s *SmartContract) ReadParticipant(ctx contractapi.TransactionContextInterface, mspID string) (*Participant, error) {
+	participant, err := _getParticipant(ctx, mspID)
+	if err != nil {
+		return nil, err
+	}
+	if participant == nil {
+		return nil, fmt.Errorf("%s is not a registered participant", mspID)
+	}
+	return participant, nil
+}
+
+// ReadLot returns the lot stored in the world state with the given ID
+func (s *SmartContract) ReadLot(ctx contractapi.TransactionContextInterface, lotID string) (*Lot, error) {
+	lot, err := _getLot(ctx, lotID)
+	if err != nil {
+		return nil, err
+	}
+	if lot == nil {
+		return nil, fmt.Errorf("the lot %s does not exist", lotID)
+	}
+	return lot, nil
+}
+
+// GetProcess returns the record of a processing event
+func (s *SmartContract) GetProcess(ctx contractapi.TransactionContextInterface, processID string) (*Process, error) {
+	processKey, err := ctx.GetStub().CreateCompositeKey(processPrefix, []string{processID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	processJSON, err := ctx.GetStub().GetState(processKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if processJSON == nil {
+		return nil, fmt.Errorf("the process %s does not exist", processID)
+	}
+
+	var process Process
+	err = json.Unmarshal(processJSON, &process)
+	if err != nil {
+		return nil, err
+	}
+	return &process, nil
+}
+
+// GetLotEvents returns the events of a lot in order, from its harvest or production to its
+// latest handoff, temperature attestation or sale
+func (s *SmartContract) GetLotEvents(ctx contractapi.TransactionContextInterface, lotID string) ([]*LotEvent, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(eventPrefix, []string{lotID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get events of lot %s: %v", lotID, err)
+	}
+	defer resultsIterator.Close()
+
+	events := []*LotEvent{}
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var event LotEvent
+		err = json.Unmarshal(response.Value, &event)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, &event)
+	}
+	return events, nil
+}
+
+// Trace returns the full path of a lot in one call: every lot it was made from back to the
+// harvested lots, every lot made from it, the events of each, the processes between them and the
+// participants that handled them. Lots are ordered from the origin to the retail end of the path.
+func (s *SmartContract) Trace(ctx contractapi.TransactionContextInterface, lotID string) (*Trace, error) {
+	lot, err := s.ReadLot(ctx, lotID)
+	if err != nil {
+		return nil, err
+	}
+
+	traced := []*TracedLot{{Lot: lot, Depth: 0}}
+	visited := map[string]bool{lotID: true}
+
+	// walk back through the inputs of each process to the harvested lots
+	frontier := lot.Inputs
+	for depth := 1; len(frontier) > 0; depth++ {
+		if depth > maxTraceDepth {
+			return nil, fmt.Errorf("trace of lot %s exceeds %d processing steps", lotID, maxTraceDepth)
+		}
+		var next []string
+		for _, id := range frontier {
+			if visited[id] {
+				continue
+			}
+			visited[id] = true
+			input, err := s.ReadLot(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+			traced = append(traced, &TracedLot{Lot: input, Depth: -depth})
+			next = append(next, input.Inputs...)
+		}
+		frontier = next
+	}
+
+	// and forward through the input~output index to the lots made from it
+	frontier = []string{lotID}
+	for depth := 1; len(frontier) > 0; depth++ {
+		if depth > maxTraceDepth {
+			return nil, fmt.Errorf("trace of lot %s exceeds %d processing steps", lotID, maxTraceDepth)
+		}
+		var next []string
+		for _, id := range frontier {
+			outputIDs, err := _getOutputIDs(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+			for _, outputID := range outputIDs {
+				if visited[outputID] {
+					continue
+				}
+				visited[outputID] = true
+				output, err := s.ReadLot(ctx, outputID)
+				if err != nil {
+					return nil, err
+				}
+				traced = append(traced, &TracedLot{Lot: output, Depth: depth})
+				next = append(next, outputID)
+			}
+		}
+		frontier = next
+	}
+	sort.SliceStable(traced, func(i, j int) bool { return traced[i].Depth < traced[j].Depth })
+
+	trace := &Trace{
+		LotID:        lotID,
+		Lots:         traced,
+		Processes:    []*Process{},
+		Participants: []*Participant{},
+	}
+	processes := make(map[string]bool)
+	orgs := make(map[string]bool)
+	for _, t := range traced {
+		t.Events, err = s.GetLotEvents(ctx, t.Lot.ID)
+		if err != nil {
+			return nil, err
+		}
+		trace.Excursions += t.Lot.Excursions
+
+		if t.Lot.ProcessID != "" && !processes[t.Lot.ProcessID] {
+			processes[t.Lot.ProcessID] = true
+			process, err := s.GetProcess(ctx, t.Lot.ProcessID)
+			if err != nil {
+				return nil, err
+			}
+			trace.Processes = append(trace.Processes, process)
+		}
+		for _, event := range t.Events {
+			for _, org := range []string{event.Org, event.ToOrg} {
+				if org == "" || orgs[org] {
+					continue
+				}
+				orgs[org] = true
+				participant, err := _getParticipant(ctx, org)
+				if err != nil {
+					return nil, err
+				}
+				if participant == nil {
+					participant = &Participant{ObjectType: participantPrefix, MSPID: org}
+				}
+				trace.Participants = append(trace.Participants, participant)
+			}
+		}
+	}
+	trace.ColdChainCompliant = trace.Excursions == 0
+	return trace, nil
+}
+
+// _getOutputIDs returns the IDs of lots directly produced from the given input
+func _getOutputIDs(ctx contractapi.TransactionContextInterface, inputID string) ([]string, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(outputIndexPrefix, []string{inputID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get outputs of lot %s: %v", inputID, err)
+	}
+	defer resultsIterator.Close()
+
+	var outputIDs []string
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(response.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split composite key: %v", err)
+		}
+		if len(keyParts) != 2 {
+			return nil, fmt.Errorf("unexpected index key %s", response.Key)
+		}
+		outputIDs = append(outputIDs, keyParts[1])
+	}
+	return outputIDs, nil
+}
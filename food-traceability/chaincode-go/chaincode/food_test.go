@@ -0,0 +1,286 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/pkg/mockledger"
+)
+
+const (
+	foodName     = "food"
+	origin       = `{"site":"Green Acres","location":"Salinas, CA","harvestDate":"2020-09-10"}`
+	coldChain    = `{"minCelsius":1,"maxCelsius":5}`
+	evidenceHash = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+)
+
+// network is a mock ledger with the food chaincode deployed, the authority and a farm, processor
+// and retailer registered with their roles
+type network struct {
+	ledger    *mockledger.Ledger
+	authority *mockledger.Client
+	farm      *mockledger.Client
+	processor *mockledger.Client
+	retailer  *mockledger.Client
+}
+
+func newNetwork(t *testing.T) *network {
+	t.Helper()
+	ledger := mockledger.New()
+	food, err := contractapi.NewChaincode(new(SmartContract))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ledger.Deploy(foodName, food); err != nil {
+		t.Fatal(err)
+	}
+
+	n := &network{ledger: ledger}
+	n.authority = n.client(t, authorityMSPID, "authority")
+	n.farm = n.client(t, "Org2MSP", "farm")
+	n.processor = n.client(t, "Org3MSP", "processor")
+	n.retailer = n.client(t, "Org4MSP", "retailer")
+	n.submit(t, n.authority, "RegisterParticipant", "Org2MSP", "Green Acres Farm", `["PRODUCER"]`)
+	n.submit(t, n.authority, "RegisterParticipant", "Org3MSP", "Fresh Pack", `["PROCESSOR","DISTRIBUTOR"]`)
+	n.submit(t, n.authority, "RegisterParticipant", "Org4MSP", "Corner Grocer", `["RETAILER"]`)
+	return n
+}
+
+func (n *network) client(t *testing.T, mspID string, name string) *mockledger.Client {
+	t.Helper()
+	client, err := n.ledger.NewClient(mspID, name, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func (n *network) submit(t *testing.T, client *mockledger.Client, function string, args ...string) *mockledger.Result {
+	t.Helper()
+	result, err := n.ledger.Submit(client, mockledger.Transaction{Chaincode: foodName, Function: function, Args: args})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func (n *network) submitError(t *testing.T, client *mockledger.Client, want string, function string, args ...string) {
+	t.Helper()
+	_, err := n.ledger.Submit(client, mockledger.Transaction{Chaincode: foodName, Function: function, Args: args})
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("%s failed with %v, want %q", function, err, want)
+	}
+}
+
+func (n *network) evaluate(t *testing.T, result interface{}, function string, args ...string) {
+	t.Helper()
+	payload, err := n.ledger.Evaluate(n.authority, mockledger.Transaction{Chaincode: foodName, Function: function, Args: args})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(payload, result); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// deliver ships a lot from its custodian to the org of the receiving client, who receives it
+func (n *network) deliver(t *testing.T, from *mockledger.Client, to *mockledger.Client, mspID string, lotID string) {
+	t.Helper()
+	n.submit(t, from, "Ship", lotID, mspID)
+	n.submit(t, to, "Receive", lotID)
+}
+
+// checkLots fails the test when a lot's custodian or status differs from want
+func (n *network) checkLots(t *testing.T, custodian string, status string, lotIDs ...string) {
+	t.Helper()
+	for _, lotID := range lotIDs {
+		var lot Lot
+		n.evaluate(t, &lot, "ReadLot", lotID)
+		if lot.CustodianOrg != custodian || lot.Status != status {
+			t.Errorf("lot %s is %s held by %s, want %s held by %s", lotID, lot.Status, lot.CustodianOrg, status, custodian)
+		}
+	}
+}
+
+// checkEvents fails the test when the types of a lot's events differ from want
+func (n *network) checkEvents(t *testing.T, lotID string, want ...string) {
+	t.Helper()
+	var events []*LotEvent
+	n.evaluate(t, &events, "GetLotEvents", lotID)
+	var types []string
+	for i, event := range events {
+		if event.Seq != i {
+			t.Errorf("event %d of lot %s has sequence number %d", i, lotID, event.Seq)
+		}
+		types = append(types, event.Type)
+	}
+	if strings.Join(types, ",") != strings.Join(want, ",") {
+		t.Errorf("lot %s has events %v, want %v", lotID, types, want)
+	}
+}
+
+func TestRegisterAndCreateLot(t *testing.T) {
+	n := newNetwork(t)
+	n.submitError(t, n.farm, "client from Org2MSP is not authorized to register participants", "RegisterParticipant", "Org2MSP", "Green Acres Farm", `["RETAILER"]`)
+	n.submitError(t, n.authority, "role GROWER must be one of", "RegisterParticipant", "Org5MSP", "Hill Farm", `["GROWER"]`)
+	var participant Participant
+	n.evaluate(t, &participant, "ReadParticipant", "Org3MSP")
+	if participant.Name != "Fresh Pack" || strings.Join(participant.Roles, ",") != "PROCESSOR,DISTRIBUTOR" {
+		t.Errorf("registered participant is %+v", participant)
+	}
+
+	n.submitError(t, n.processor, "client from Org3MSP is not a registered PRODUCER", "CreateLot", "L1", "romaine", "500", "kg", origin, coldChain)
+	n.submitError(t, n.farm, "harvest date 2099-01-01 is in the future", "CreateLot", "L1", "romaine", "500", "kg", `{"site":"Green Acres","location":"Salinas, CA","harvestDate":"2099-01-01"}`, coldChain)
+	n.submitError(t, n.farm, "cold chain range of lot L1 must have its minimum below its maximum", "CreateLot", "L1", "romaine", "500", "kg", origin, `{"minCelsius":5,"maxCelsius":1}`)
+	n.submit(t, n.farm, "CreateLot", "L1", "romaine", "500", "kg", origin, coldChain)
+	n.submitError(t, n.farm, "the lot L1 already exists", "CreateLot", "L1", "romaine", "500", "kg", origin, coldChain)
+
+	var lot Lot
+	n.evaluate(t, &lot, "ReadLot", "L1")
+	if lot.OriginOrg != "Org2MSP" || lot.Origin == nil || lot.Origin.Site != "Green Acres" || lot.ColdChain == nil || lot.ColdChain.MaxCelsius != 5 {
+		t.Errorf("created lot is %+v", lot)
+	}
+	n.checkLots(t, "Org2MSP", statusActive, "L1")
+	n.checkEvents(t, "L1", eventHarvested)
+}
+
+func TestProcessAndTrace(t *testing.T) {
+	n := newNetwork(t)
+	n.submit(t, n.farm, "CreateLot", "L1", "romaine", "500", "kg", origin, coldChain)
+	n.submit(t, n.farm, "CreateLot", "L2", "spinach", "200", "kg", origin, coldChain)
+
+	n.submitError(t, n.farm, "Org5MSP is not a registered participant", "Ship", "L1", "Org5MSP")
+	n.submit(t, n.farm, "Ship", "L1", "Org3MSP")
+	n.submitError(t, n.retailer, "lot L1 is not in transit to Org4MSP", "Receive", "L1")
+	result := n.submit(t, n.processor, "Receive", "L1")
+	if result.Event == nil || result.Event.EventName != "CustodyTransfer" {
+		t.Fatalf("Receive set event %v, want CustodyTransfer", result.Event)
+	}
+	var custody custodyEvent
+	if err := json.Unmarshal(result.Event.Payload, &custody); err != nil {
+		t.Fatal(err)
+	}
+	if custody != (custodyEvent{"L1", "Org2MSP", "Org3MSP"}) {
+		t.Errorf("CustodyTransfer event is %+v", custody)
+	}
+	n.submitError(t, n.processor, "a client from Org3MSP cannot process lot L2 held by Org2MSP", "Process", "P1", "wash and bag", `["L1","L2"]`, `[{"lotID":"S1","product":"salad","quantity":600,"unit":"bag"}]`)
+	n.deliver(t, n.farm, n.processor, "Org3MSP", "L2")
+
+	// the processor turns both lots into two bagged salad lots
+	outputs := `[{"lotID":"S1","product":"salad mix","quantity":400,"unit":"bag","coldChain":{"minCelsius":1,"maxCelsius":5}},` +
+		`{"lotID":"S2","product":"salad mix","quantity":300,"unit":"bag","coldChain":{"minCelsius":1,"maxCelsius":5}}]`
+	n.submitError(t, n.farm, "client from Org2MSP is not a registered PROCESSOR", "Process", "P1", "wash and bag", `["L1","L2"]`, outputs)
+	n.submit(t, n.processor, "Process", "P1", "wash and bag", `["L1","L2"]`, outputs)
+	n.submitError(t, n.processor, "the process P1 already exists", "Process", "P1", "wash and bag", `["L1"]`, outputs)
+	n.checkLots(t, "Org3MSP", statusConsumed, "L1", "L2")
+	n.checkLots(t, "Org3MSP", statusActive, "S1", "S2")
+	n.submitError(t, n.processor, "lot L1 is CONSUMED and cannot be shipped", "Ship", "L1", "Org4MSP")
+	var process Process
+	n.evaluate(t, &process, "GetProcess", "P1")
+	if process.Org != "Org3MSP" || strings.Join(process.Inputs, ",") != "L1,L2" || strings.Join(process.Outputs, ",") != "S1,S2" {
+		t.Errorf("process record is %+v", process)
+	}
+
+	n.deliver(t, n.processor, n.retailer, "Org4MSP", "S1")
+	n.submitError(t, n.processor, "client from Org3MSP is not a registered RETAILER", "RecordSale", "S2")
+	n.submit(t, n.retailer, "RecordSale", "S1")
+	n.checkLots(t, "Org4MSP", statusSold, "S1")
+	n.checkEvents(t, "L1", eventHarvested, eventShipped, eventReceived, eventConsumed)
+	n.checkEvents(t, "S1", eventProduced, eventShipped, eventReceived, eventSold)
+
+	// a trace of the sold lot reaches back to both harvested lots and every org that handled them
+	var trace Trace
+	n.evaluate(t, &trace, "Trace", "S1")
+	var path []string
+	for _, lot := range trace.Lots {
+		path = append(path, lot.Lot.ID)
+		if lot.Depth != map[string]int{"L1": -1, "L2": -1, "S1": 0}[lot.Lot.ID] {
+			t.Errorf("lot %s is at depth %d", lot.Lot.ID, lot.Depth)
+		}
+	}
+	if strings.Join(path, ",") != "L1,L2,S1" {
+		t.Errorf("trace of S1 has lots %v, want L1,L2,S1", path)
+	}
+	if len(trace.Processes) != 1 || trace.Processes[0].ID != "P1" {
+		t.Errorf("trace of S1 has processes %+v", trace.Processes)
+	}
+	var orgs []string
+	for _, participant := range trace.Participants {
+		orgs = append(orgs, participant.MSPID)
+	}
+	if strings.Join(orgs, ",") != "Org2MSP,Org3MSP,Org4MSP" {
+		t.Errorf("trace of S1 has participants %v", orgs)
+	}
+	if len(trace.Lots[0].Events) != 4 || !trace.ColdChainCompliant {
+		t.Errorf("trace of S1 is %+v", trace)
+	}
+
+	// and a trace of a harvested lot reaches forward to every lot made from it
+	n.evaluate(t, &trace, "Trace", "L1")
+	path = nil
+	for _, lot := range trace.Lots {
+		path = append(path, lot.Lot.ID)
+	}
+	if strings.Join(path, ",") != "L1,S1,S2" {
+		t.Errorf("trace of L1 has lots %v, want L1,S1,S2", path)
+	}
+}
+
+func TestAttestTemperature(t *testing.T) {
+	n := newNetwork(t)
+	start := mockledger.StartTime
+	n.submit(t, n.farm, "CreateLot", "L1", "romaine", "500", "kg", origin, coldChain)
+	n.submit(t, n.farm, "CreateLot", "L2", "potatoes", "900", "kg", origin, `{"minCelsius":0,"maxCelsius":0}`)
+	n.ledger.SetTime(start.Add(6 * time.Hour))
+	from := start.Add(time.Minute).Format(time.RFC3339)
+	to := start.Add(4 * time.Hour).Format(time.RFC3339)
+
+	n.submitError(t, n.farm, "lot L2 has no cold chain requirement", "AttestTemperature", "L2", from, to, "2", "4", "12", "logger-7", evidenceHash)
+	n.submitError(t, n.farm, "the period from", "AttestTemperature", "L1", from, start.Add(7*time.Hour).Format(time.RFC3339), "2", "4", "12", "logger-7", evidenceHash)
+	n.submitError(t, n.farm, "a hex SHA-256 evidence hash must be set", "AttestTemperature", "L1", from, to, "2", "4", "12", "logger-7", "abc")
+	n.submitError(t, n.processor, "a client from Org3MSP is not authorized to attest the temperature of lot L1", "AttestTemperature", "L1", from, to, "2", "4", "12", "logger-7", evidenceHash)
+	result := n.submit(t, n.farm, "AttestTemperature", "L1", from, to, "2", "4", "12", "logger-7", evidenceHash)
+	var attestation TemperatureAttestation
+	if err := json.Unmarshal(result.Payload, &attestation); err != nil {
+		t.Fatal(err)
+	}
+	if !attestation.Compliant || result.Event != nil {
+		t.Errorf("attestation within the range is %+v with event %v", attestation, result.Event)
+	}
+
+	// the org a lot is shipped to attests the transport, which left the range
+	n.submit(t, n.farm, "Ship", "L1", "Org3MSP")
+	n.ledger.SetTime(start.Add(10 * time.Hour))
+	from = start.Add(6 * time.Hour).Format(time.RFC3339)
+	to = start.Add(9 * time.Hour).Format(time.RFC3339)
+	result = n.submit(t, n.processor, "AttestTemperature", "L1", from, to, "3", "9.5", "36", "truck-2", evidenceHash)
+	if result.Event == nil || result.Event.EventName != "TemperatureExcursion" {
+		t.Fatalf("AttestTemperature set event %v, want TemperatureExcursion", result.Event)
+	}
+	var excursion excursionEvent
+	if err := json.Unmarshal(result.Event.Payload, &excursion); err != nil {
+		t.Fatal(err)
+	}
+	if excursion != (excursionEvent{"L1", "Org3MSP", 3, 9.5, from, to}) {
+		t.Errorf("TemperatureExcursion event is %+v", excursion)
+	}
+	var lot Lot
+	n.evaluate(t, &lot, "ReadLot", "L1")
+	if lot.Excursions != 1 {
+		t.Errorf("lot has %d excursions, want 1", lot.Excursions)
+	}
+	n.checkEvents(t, "L1", eventHarvested, eventTemperature, eventShipped, eventTemperature)
+
+	// the excursion of an input shows in the trace of the lots made from it
+	n.submit(t, n.processor, "Receive", "L1")
+	n.submit(t, n.processor, "Process", "P1", "wash and bag", `["L1"]`, `[{"lotID":"S1","product":"salad mix","quantity":400,"unit":"bag"}]`)
+	n.submitError(t, n.processor, "lot L1 is CONSUMED", "AttestTemperature", "L1", from, to, "2", "4", "36", "truck-2", evidenceHash)
+	var trace Trace
+	n.evaluate(t, &trace, "Trace", "S1")
+	if trace.Excursions != 1 || trace.ColdChainCompliant {
+		t.Errorf("trace of a lot made from an excursion lot has %d excursions, compliant %t", trace.Excursions, trace.ColdChainCompliant)
+	}
+}
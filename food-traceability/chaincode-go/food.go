@@ -0,0 +1,23 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"log"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/food-traceability/chaincode-go/chaincode"
+)
+
+func main() {
+	foodChaincode, err := contractapi.NewChaincode(&chaincode.SmartContract{})
+	if err != nil {
+		log.Panicf("Error creating food-traceability chaincode: %v", err)
+	}
+
+	if err := foodChaincode.Start(); err != nil {
+		log.Panicf("Error starting food-traceability chaincode: %v", err)
+	}
+}
@@ -0,0 +1,573 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// This sample assumes Org1 operates the game: only it defines item templates and recipes and grants items
+const gameMasterMSPID = "Org1MSP"
+
+// tokenChaincodeName is the name the token-erc-20 chaincode that trades are settled in is deployed under
+const tokenChaincodeName = "token_erc20"
+
+// object names for prefix
+const (
+	templatePrefix  = "template"
+	recipePrefix    = "recipe"
+	inventoryPrefix = "inventory"
+	offerPrefix     = "offer"
+)
+
+// offer status values
+const (
+	offerOpen      = "OPEN"
+	offerSold      = "SOLD"
+	offerCancelled = "CANCELLED"
+)
+
+// SmartContract provides functions for managing game items, crafting and trading them between players
+type SmartContract struct {
+	contractapi.Contract
+}
+
+// Template describes a kind of item. Players hold quantities of templates in their inventory.
+// MaxSupply of 0 means the game master can grant any number of the item.
+type Template struct {
+	ObjectType string `json:"objectType"`
+	ID         string `json:"templateID"`
+	Name       string `json:"name"`
+	Rarity     string `json:"rarity"`
+	Tradable   bool   `json:"tradable"`
+	MaxSupply  int    `json:"maxSupply"`
+	Supply     int    `json:"supply"`
+}
+
+// ItemAmount is a quantity of one template
+type ItemAmount struct {
+	TemplateID string `json:"templateID"`
+	Quantity   int    `json:"quantity"`
+}
+
+// Recipe turns input items into an output item. The inputs are burned when a player crafts.
+type Recipe struct {
+	ObjectType string       `json:"objectType"`
+	ID         string       `json:"recipeID"`
+	Inputs     []ItemAmount `json:"inputs"`
+	Output     ItemAmount   `json:"output"`
+}
+
+// InventoryItem is the quantity of a template held by a player
+type InventoryItem struct {
+	Player     string `json:"player"`
+	TemplateID string `json:"templateID"`
+	Quantity   int    `json:"quantity"`
+}
+
+// Offer is a player selling items for tokens. The items are taken out of the seller's inventory while
+// the offer is open.
+type Offer struct {
+	ObjectType string `json:"objectType"`
+	ID         string `json:"offerID"`
+	Seller     string `json:"seller"`
+	TemplateID string `json:"templateID"`
+	Quantity   int    `json:"quantity"`
+	Price      int    `json:"price"`
+	Status     string `json:"status"`
+	Buyer      string `json:"buyer"`
+}
+
+// event provides an organized struct for emitting item movements
+type event struct {
+	From       string `json:"from"`
+	To         string `json:"to"`
+	TemplateID string `json:"templateID"`
+	Quantity   int    `json:"quantity"`
+	Price      int    `json:"price"`
+}
+
+// CreateTemplate is called by the game master to define a kind of item
+func (s *SmartContract) CreateTemplate(ctx contractapi.TransactionContextInterface, templateID string, name string, rarity string, tradable bool, maxSupply int) error {
+	err := _requireOrg(ctx, gameMasterMSPID, "create item templates")
+	if err != nil {
+		return err
+	}
+	if templateID == "" || name == "" {
+		return fmt.Errorf("template ID and name must be set")
+	}
+	if maxSupply < 0 {
+		return fmt.Errorf("max supply must not be negative")
+	}
+
+	existing, err := _getTemplate(ctx, templateID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("the template %s already exists", templateID)
+	}
+
+	template := Template{
+		ObjectType: templatePrefix,
+		ID:         templateID,
+		Name:       name,
+		Rarity:     rarity,
+		Tradable:   tradable,
+		MaxSupply:  maxSupply,
+	}
+	return _putTemplate(ctx, &template)
+}
+
+// DefineRecipe is called by the game master. inputs is a JSON array of {"templateID","quantity"}
+// burned to craft outputQuantity of the output template.
+func (s *SmartContract) DefineRecipe(ctx contractapi.TransactionContextInterface, recipeID string, inputs string, outputTemplateID string, outputQuantity int) error {
+	err := _requireOrg(ctx, gameMasterMSPID, "define recipes")
+	if err != nil {
+		return err
+	}
+	if recipeID == "" {
+		return fmt.Errorf("recipe ID must be set")
+	}
+
+	var recipeInputs []ItemAmount
+	err = json.Unmarshal([]byte(inputs), &recipeInputs)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal inputs: %v", err)
+	}
+	if len(recipeInputs) == 0 {
+		return fmt.Errorf("recipe %s must have at least one input", recipeID)
+	}
+
+	seen := make(map[string]bool)
+	for _, input := range append(recipeInputs, ItemAmount{outputTemplateID, outputQuantity}) {
+		if seen[input.TemplateID] {
+			return fmt.Errorf("template %s appears more than once in recipe %s", input.TemplateID, recipeID)
+		}
+		seen[input.TemplateID] = true
+		if input.Quantity <= 0 {
+			return fmt.Errorf("quantity of %s must be a positive integer", input.TemplateID)
+		}
+		_, err = s.GetTemplate(ctx, input.TemplateID)
+		if err != nil {
+			return err
+		}
+	}
+
+	recipe := Recipe{
+		ObjectType: recipePrefix,
+		ID:         recipeID,
+		Inputs:     recipeInputs,
+		Output:     ItemAmount{outputTemplateID, outputQuantity},
+	}
+	recipeJSON, err := json.Marshal(recipe)
+	if err != nil {
+		return fmt.Errorf("failed to marshal recipe: %v", err)
+	}
+
+	recipeKey, err := ctx.GetStub().CreateCompositeKey(recipePrefix, []string{recipeID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = ctx.GetStub().PutState(recipeKey, recipeJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put recipe %s: %v", recipeID, err)
+	}
+	return nil
+}
+
+// GrantItem is called by the game master to mint items into a player's inventory, e.g. as a reward
+func (s *SmartContract) GrantItem(ctx contractapi.TransactionContextInterface, player string, templateID string, quantity int) error {
+	err := _requireOrg(ctx, gameMasterMSPID, "grant items")
+	if err != nil {
+		return err
+	}
+	if player == "" {
+		return fmt.Errorf("player must be set")
+	}
+
+	err = _mint(ctx, player, templateID, quantity)
+	if err != nil {
+		return err
+	}
+
+	return _emitEvent(ctx, "ItemTransfer", event{"", player, templateID, quantity, 0})
+}
+
+// TransferItem gives items from the client's inventory to another player
+func (s *SmartContract) TransferItem(ctx contractapi.TransactionContextInterface, receiver string, templateID string, quantity int) error {
+	player, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+	if receiver == "" || receiver == player {
+		return fmt.Errorf("receiver must be another player")
+	}
+	if quantity <= 0 {
+		return fmt.Errorf("quantity must be a positive integer")
+	}
+
+	err = _requireTradable(ctx, templateID)
+	if err != nil {
+		return err
+	}
+	err = _addItems(ctx, player, templateID, -quantity)
+	if err != nil {
+		return err
+	}
+	err = _addItems(ctx, receiver, templateID, quantity)
+	if err != nil {
+		return err
+	}
+
+	return _emitEvent(ctx, "ItemTransfer", event{player, receiver, templateID, quantity, 0})
+}
+
+// Craft burns the inputs of a recipe from the client's inventory and mints its output
+func (s *SmartContract) Craft(ctx contractapi.TransactionContextInterface, recipeID string) error {
+	player, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	recipe, err := s.GetRecipe(ctx, recipeID)
+	if err != nil {
+		return err
+	}
+
+	for _, input := range recipe.Inputs {
+		err = _burn(ctx, player, input.TemplateID, input.Quantity)
+		if err != nil {
+			return err
+		}
+	}
+	err = _mint(ctx, player, recipe.Output.TemplateID, recipe.Output.Quantity)
+	if err != nil {
+		return err
+	}
+
+	return _emitEvent(ctx, "Crafted", event{"", player, recipe.Output.TemplateID, recipe.Output.Quantity, 0})
+}
+
+// CreateOffer puts items of the client's inventory up for sale at a price in tokens for the whole quantity
+func (s *SmartContract) CreateOffer(ctx contractapi.TransactionContextInterface, offerID string, templateID string, quantity int, price int) error {
+	seller, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+	if quantity <= 0 || price <= 0 {
+		return fmt.Errorf("quantity and price must be positive integers")
+	}
+
+	existing, err := _getOffer(ctx, offerID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("the offer %s already exists", offerID)
+	}
+
+	err = _requireTradable(ctx, templateID)
+	if err != nil {
+		return err
+	}
+	err = _addItems(ctx, seller, templateID, -quantity)
+	if err != nil {
+		return err
+	}
+
+	offer := Offer{
+		ObjectType: offerPrefix,
+		ID:         offerID,
+		Seller:     seller,
+		TemplateID: templateID,
+		Quantity:   quantity,
+		Price:      price,
+		Status:     offerOpen,
+	}
+	return _putOffer(ctx, &offer)
+}
+
+// CancelOffer withdraws an open offer and returns the items to the seller
+func (s *SmartContract) CancelOffer(ctx contractapi.TransactionContextInterface, offerID string) error {
+	seller, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	offer, err := s.GetOffer(ctx, offerID)
+	if err != nil {
+		return err
+	}
+	if offer.Seller != seller {
+		return fmt.Errorf("only the seller can cancel offer %s", offerID)
+	}
+	if offer.Status != offerOpen {
+		return fmt.Errorf("offer %s is %s", offerID, offer.Status)
+	}
+
+	err = _addItems(ctx, seller, offer.TemplateID, offer.Quantity)
+	if err != nil {
+		return err
+	}
+
+	offer.Status = offerCancelled
+	return _putOffer(ctx, offer)
+}
+
+// AcceptOffer buys the items of an open offer. The buyer pays the seller the price with the token
+// chaincode in the same transaction, so the items and the tokens move together or not at all.
+func (s *SmartContract) AcceptOffer(ctx contractapi.TransactionContextInterface, offerID string) error {
+	buyer, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	offer, err := s.GetOffer(ctx, offerID)
+	if err != nil {
+		return err
+	}
+	if offer.Status != offerOpen {
+		return fmt.Errorf("offer %s is %s", offerID, offer.Status)
+	}
+	if offer.Seller == buyer {
+		return fmt.Errorf("cannot accept own offer %s", offerID)
+	}
+
+	err = _transferTokens(ctx, offer.Seller, offer.Price)
+	if err != nil {
+		return err
+	}
+	err = _addItems(ctx, buyer, offer.TemplateID, offer.Quantity)
+	if err != nil {
+		return err
+	}
+
+	offer.Status = offerSold
+	offer.Buyer = buyer
+	err = _putOffer(ctx, offer)
+	if err != nil {
+		return err
+	}
+
+	return _emitEvent(ctx, "ItemTransfer", event{offer.Seller, buyer, offer.TemplateID, offer.Quantity, offer.Price})
+}
+
+// _mint adds newly created items to a player's inventory within the template's max supply
+func _mint(ctx contractapi.TransactionContextInterface, player string, templateID string, quantity int) error {
+	if quantity <= 0 {
+		return fmt.Errorf("quantity must be a positive integer")
+	}
+	template, err := _getTemplate(ctx, templateID)
+	if err != nil {
+		return err
+	}
+	if template == nil {
+		return fmt.Errorf("the template %s does not exist", templateID)
+	}
+	if template.MaxSupply > 0 && template.Supply+quantity > template.MaxSupply {
+		return fmt.Errorf("minting %d %s would exceed its max supply %d", quantity, templateID, template.MaxSupply)
+	}
+
+	template.Supply += quantity
+	err = _putTemplate(ctx, template)
+	if err != nil {
+		return err
+	}
+	return _addItems(ctx, player, templateID, quantity)
+}
+
+// _burn removes items from a player's inventory and from the template's supply
+func _burn(ctx contractapi.TransactionContextInterface, player string, templateID string, quantity int) error {
+	err := _addItems(ctx, player, templateID, -quantity)
+	if err != nil {
+		return err
+	}
+
+	template, err := _getTemplate(ctx, templateID)
+	if err != nil {
+		return err
+	}
+	if template == nil {
+		return fmt.Errorf("the template %s does not exist", templateID)
+	}
+	template.Supply -= quantity
+	return _putTemplate(ctx, template)
+}
+
+// _addItems changes the quantity of a template in a player's inventory. A negative delta removes items
+// and fails when the player holds fewer. Entries that reach zero are deleted.
+func _addItems(ctx contractapi.TransactionContextInterface, player string, templateID string, delta int) error {
+	if delta == 0 {
+		return fmt.Errorf("quantity must not be zero")
+	}
+
+	inventoryKey, err := ctx.GetStub().CreateCompositeKey(inventoryPrefix, []string{player, templateID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	quantityBytes, err := ctx.GetStub().GetState(inventoryKey)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	quantity := 0
+	if quantityBytes != nil {
+		quantity, err = strconv.Atoi(string(quantityBytes))
+		if err != nil {
+			return fmt.Errorf("failed to parse quantity of %s: %v", templateID, err)
+		}
+	}
+
+	if quantity+delta < 0 {
+		return fmt.Errorf("player holds %d %s, fewer than %d", quantity, templateID, -delta)
+	}
+	quantity += delta
+
+	if quantity == 0 {
+		err = ctx.GetStub().DelState(inventoryKey)
+		if err != nil {
+			return fmt.Errorf("failed to delete inventory entry: %v", err)
+		}
+		return nil
+	}
+	err = ctx.GetStub().PutState(inventoryKey, []byte(strconv.Itoa(quantity)))
+	if err != nil {
+		return fmt.Errorf("failed to put inventory entry: %v", err)
+	}
+	return nil
+}
+
+// _requireTradable checks a template exists and its items can change hands between players
+func _requireTradable(ctx contractapi.TransactionContextInterface, templateID string) error {
+	template, err := _getTemplate(ctx, templateID)
+	if err != nil {
+		return err
+	}
+	if template == nil {
+		return fmt.Errorf("the template %s does not exist", templateID)
+	}
+	if !template.Tradable {
+		return fmt.Errorf("items of %s are bound to their player and cannot be traded", templateID)
+	}
+	return nil
+}
+
+// _requireOrg checks the client belongs to the given org
+func _requireOrg(ctx contractapi.TransactionContextInterface, org string, action string) error {
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+	if clientMSPID != org {
+		return fmt.Errorf("client from %s is not authorized to %s", clientMSPID, action)
+	}
+	return nil
+}
+
+// _transferTokens pays the receiver from the client's account with the token chaincode
+func _transferTokens(ctx contractapi.TransactionContextInterface, receiver string, amount int) error {
+	args := [][]byte{[]byte("Transfer"), []byte(receiver), []byte(strconv.Itoa(amount))}
+	response := ctx.GetStub().InvokeChaincode(tokenChaincodeName, args, "")
+	if response.Status != shim.OK {
+		return fmt.Errorf("failed to transfer %d tokens on %s: %s", amount, tokenChaincodeName, response.Message)
+	}
+	return nil
+}
+
+// _getTemplate reads a template, returning nil when it does not exist
+func _getTemplate(ctx contractapi.TransactionContextInterface, templateID string) (*Template, error) {
+	templateKey, err := ctx.GetStub().CreateCompositeKey(templatePrefix, []string{templateID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	templateJSON, err := ctx.GetStub().GetState(templateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if templateJSON == nil {
+		return nil, nil
+	}
+
+	var template Template
+	err = json.Unmarshal(templateJSON, &template)
+	if err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// _putTemplate writes the template to the world state
+func _putTemplate(ctx contractapi.TransactionContextInterface, template *Template) error {
+	templateKey, err := ctx.GetStub().CreateCompositeKey(templatePrefix, []string{template.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	templateJSON, err := json.Marshal(template)
+	if err != nil {
+		return fmt.Errorf("failed to marshal template: %v", err)
+	}
+	err = ctx.GetStub().PutState(templateKey, templateJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put template %s: %v", template.ID, err)
+	}
+	return nil
+}
+
+// _getOffer reads an offer, returning nil when it does not exist
+func _getOffer(ctx contractapi.TransactionContextInterface, offerID string) (*Offer, error) {
+	offerKey, err := ctx.GetStub().CreateCompositeKey(offerPrefix, []string{offerID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	offerJSON, err := ctx.GetStub().GetState(offerKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if offerJSON == nil {
+		return nil, nil
+	}
+
+	var offer Offer
+	err = json.Unmarshal(offerJSON, &offer)
+	if err != nil {
+		return nil, err
+	}
+	return &offer, nil
+}
+
+// _putOffer writes the offer to the world state
+func _putOffer(ctx contractapi.TransactionContextInterface, offer *Offer) error {
+	offerKey, err := ctx.GetStub().CreateCompositeKey(offerPrefix, []string{offer.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	offerJSON, err := json.Marshal(offer)
+	if err != nil {
+		return fmt.Errorf("failed to marshal offer: %v", err)
+	}
+	err = ctx.GetStub().PutState(offerKey, offerJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put offer %s: %v", offer.ID, err)
+	}
+	return nil
+}
+
+// _emitEvent marshals the payload and sets it as the chaincode event
+func _emitEvent(ctx contractapi.TransactionContextInterface, name string, payload interface{}) error {
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	err = ctx.GetStub().SetEvent(name, payloadJSON)
+	if err != nil {
+		return fmt.Errorf("failed to set event: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// GetTemplate returns the item template with the given ID
+func (s *SmartContract) GetTemplate(ctx contractapi.TransactionContextInterface, templateID string) (*Template, error) {
+	template, err := _getTemplate(ctx, templateID)
+	if err != nil {
+		return nil, err
+	}
+	if template == nil {
+		return nil, fmt.Errorf("the template %s does not exist", templateID)
+	}
+	return template, nil
+}
+
+// GetTemplates returns every item template
+func (s *SmartContract) GetTemplates(ctx contractapi.TransactionContextInterface) ([]*Template, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(templatePrefix, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get templates: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var templates []*Template
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var template Template
+		err = json.Unmarshal(response.Value, &template)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, &template)
+	}
+
+	return templates, nil
+}
+
+// GetRecipe returns the recipe with the given ID
+func (s *SmartContract) GetRecipe(ctx contractapi.TransactionContextInterface, recipeID string) (*Recipe, error) {
+	recipeKey, err := ctx.GetStub().CreateCompositeKey(recipePrefix, []string{recipeID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	recipeJSON, err := ctx.GetStub().GetState(recipeKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if recipeJSON == nil {
+		return nil, fmt.Errorf("the recipe %s does not exist", recipeID)
+	}
+
+	var recipe Recipe
+	err = json.Unmarshal(recipeJSON, &recipe)
+	if err != nil {
+		return nil, err
+	}
+	return &recipe, nil
+}
+
+// GetInventory returns the items held by a player
+func (s *SmartContract) GetInventory(ctx contractapi.TransactionContextInterface, player string) ([]*InventoryItem, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(inventoryPrefix, []string{player})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get inventory of %s: %v", player, err)
+	}
+	defer resultsIterator.Close()
+
+	var items []*InventoryItem
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(response.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split composite key: %v", err)
+		}
+		quantity, err := strconv.Atoi(string(response.Value))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse quantity of %s: %v", keyParts[1], err)
+		}
+		items = append(items, &InventoryItem{player, keyParts[1], quantity})
+	}
+
+	return items, nil
+}
+
+// ClientInventory returns the items held by the submitting client
+func (s *SmartContract) ClientInventory(ctx contractapi.TransactionContextInterface) ([]*InventoryItem, error) {
+	player, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client id: %v", err)
+	}
+	return s.GetInventory(ctx, player)
+}
+
+// GetOffer returns the offer with the given ID
+func (s *SmartContract) GetOffer(ctx contractapi.TransactionContextInterface, offerID string) (*Offer, error) {
+	offer, err := _getOffer(ctx, offerID)
+	if err != nil {
+		return nil, err
+	}
+	if offer == nil {
+		return nil, fmt.Errorf("the offer %s does not exist", offerID)
+	}
+	return offer, nil
+}
+
+// GetOpenOffers returns the offers that can still be accepted. An empty templateID returns the open
+// offers of every template.
+func (s *SmartContract) GetOpenOffers(ctx contractapi.TransactionContextInterface, templateID string) ([]*Offer, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(offerPrefix, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get offers: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var offers []*Offer
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var offer Offer
+		err = json.Unmarshal(response.Value, &offer)
+		if err != nil {
+			return nil, err
+		}
+		if offer.Status != offerOpen || (templateID != "" && offer.TemplateID != templateID) {
+			continue
+		}
+		offers = append(offers, &offer)
+	}
+
+	return offers, nil
+}
@@ -0,0 +1,160 @@
+package chaincode
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+)
+
+// fakeToken stands in for the token chaincode. Transfers are paid from the payer's account.
+type fakeToken struct {
+	payer    string
+	balances map[string]int
+}
+
+func (f *fakeToken) invoke(args [][]byte) pb.Response {
+	if string(args[0]) != "Transfer" {
+		return shim.Error("unexpected function " + string(args[0]))
+	}
+	amount, err := strconv.Atoi(string(args[2]))
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if f.balances[f.payer] < amount {
+		return shim.Error(fmt.Sprintf("client account %s has insufficient funds", f.payer))
+	}
+	f.balances[f.payer] -= amount
+	f.balances[string(args[1])] += amount
+	return shim.Success(nil)
+}
+
+func checkError(t *testing.T, err error, expected string) {
+	t.Helper()
+	if expected == "" && err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected != "" && (err == nil || !strings.Contains(err.Error(), expected)) {
+		t.Fatalf("expected error containing %q, got %v", expected, err)
+	}
+}
+
+func checkInventory(t *testing.T, stub *fakeStub, player string, expected map[string]int) {
+	t.Helper()
+	items, err := new(SmartContract).GetInventory(newContext(stub, player, "Org2MSP"), player)
+	checkError(t, err, "")
+	inventory := make(map[string]int)
+	for _, item := range items {
+		inventory[item.TemplateID] = item.Quantity
+	}
+	if fmt.Sprint(inventory) != fmt.Sprint(expected) {
+		t.Fatalf("expected %s to hold %v, got %v", player, expected, inventory)
+	}
+}
+
+// createGame defines ore, a sword forged from three ore with a max supply of 2 and a bound badge,
+// and grants alice six ore and a badge
+func createGame(t *testing.T, stub *fakeStub) {
+	t.Helper()
+	contract := new(SmartContract)
+	checkError(t, contract.CreateTemplate(newContext(stub, "master", gameMasterMSPID), "ore", "Iron ore", "common", true, 0), "")
+	checkError(t, contract.CreateTemplate(newContext(stub, "master", gameMasterMSPID), "sword", "Iron sword", "rare", true, 2), "")
+	checkError(t, contract.CreateTemplate(newContext(stub, "master", gameMasterMSPID), "badge", "Founder badge", "epic", false, 0), "")
+	checkError(t, contract.DefineRecipe(newContext(stub, "master", gameMasterMSPID), "forge", `[{"templateID":"ore","quantity":3}]`, "sword", 1), "")
+	checkError(t, contract.GrantItem(newContext(stub, "master", gameMasterMSPID), "alice", "ore", 6), "")
+	checkError(t, contract.GrantItem(newContext(stub, "master", gameMasterMSPID), "alice", "badge", 1), "")
+}
+
+func TestCreateTemplate(t *testing.T) {
+	tests := []struct {
+		name       string
+		mspID      string
+		templateID string
+		maxSupply  int
+		expected   string
+	}{
+		{"template", gameMasterMSPID, "shield", 0, ""},
+		{"not the game master", "Org2MSP", "shield", 0, "client from Org2MSP is not authorized to create item templates"},
+		{"negative supply", gameMasterMSPID, "shield", -1, "max supply must not be negative"},
+		{"existing template", gameMasterMSPID, "ore", 0, "the template ore already exists"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stub := newFakeStub()
+			createGame(t, stub)
+
+			err := new(SmartContract).CreateTemplate(newContext(stub, "master", test.mspID), test.templateID, "Item", "common", true, test.maxSupply)
+			checkError(t, err, test.expected)
+		})
+	}
+}
+
+func TestCraft(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	createGame(t, stub)
+
+	err := contract.GrantItem(newContext(stub, "alice", "Org2MSP"), "alice", "ore", 1)
+	checkError(t, err, "client from Org2MSP is not authorized to grant items")
+
+	checkError(t, contract.Craft(newContext(stub, "alice", "Org2MSP"), "forge"), "")
+	checkError(t, contract.Craft(newContext(stub, "alice", "Org2MSP"), "forge"), "")
+	checkInventory(t, stub, "alice", map[string]int{"badge": 1, "sword": 2})
+	if stub.eventName != "Crafted" {
+		t.Fatalf("expected a Crafted event, got %s", stub.eventName)
+	}
+
+	err = contract.Craft(newContext(stub, "alice", "Org2MSP"), "forge")
+	checkError(t, err, "player holds 0 ore, fewer than 3")
+	checkError(t, contract.GrantItem(newContext(stub, "master", gameMasterMSPID), "alice", "ore", 3), "")
+	err = contract.Craft(newContext(stub, "alice", "Org2MSP"), "forge")
+	checkError(t, err, "minting 1 sword would exceed its max supply 2")
+}
+
+func TestTransferItem(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	createGame(t, stub)
+
+	err := contract.TransferItem(newContext(stub, "alice", "Org2MSP"), "bob", "badge", 1)
+	checkError(t, err, "items of badge are bound to their player and cannot be traded")
+	err = contract.TransferItem(newContext(stub, "bob", "Org2MSP"), "alice", "ore", 1)
+	checkError(t, err, "player holds 0 ore, fewer than 1")
+
+	checkError(t, contract.TransferItem(newContext(stub, "alice", "Org2MSP"), "bob", "ore", 2), "")
+	checkInventory(t, stub, "alice", map[string]int{"badge": 1, "ore": 4})
+	checkInventory(t, stub, "bob", map[string]int{"ore": 2})
+}
+
+func TestAcceptOffer(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	createGame(t, stub)
+	token := &fakeToken{balances: map[string]int{"bob": 49}}
+	stub.chaincodes[tokenChaincodeName] = token.invoke
+
+	checkError(t, contract.CreateOffer(newContext(stub, "alice", "Org2MSP"), "offer1", "ore", 5, 50), "")
+	checkInventory(t, stub, "alice", map[string]int{"badge": 1, "ore": 1})
+
+	err := contract.AcceptOffer(newContext(stub, "alice", "Org2MSP"), "offer1")
+	checkError(t, err, "cannot accept own offer offer1")
+	err = contract.CancelOffer(newContext(stub, "bob", "Org2MSP"), "offer1")
+	checkError(t, err, "only the seller can cancel offer offer1")
+
+	token.payer = "bob"
+	err = contract.AcceptOffer(newContext(stub, "bob", "Org2MSP"), "offer1")
+	checkError(t, err, "failed to transfer 50 tokens on token_erc20: client account bob has insufficient funds")
+
+	token.balances["bob"] = 50
+	checkError(t, contract.AcceptOffer(newContext(stub, "bob", "Org2MSP"), "offer1"), "")
+	checkInventory(t, stub, "bob", map[string]int{"ore": 5})
+	if token.balances["alice"] != 50 || token.balances["bob"] != 0 {
+		t.Fatalf("unexpected balances %v", token.balances)
+	}
+
+	err = contract.CancelOffer(newContext(stub, "alice", "Org2MSP"), "offer1")
+	checkError(t, err, "offer offer1 is SOLD")
+}
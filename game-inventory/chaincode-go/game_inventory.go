@@ -0,0 +1,23 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"log"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/game-inventory/chaincode-go/chaincode"
+)
+
+func main() {
+	gameChaincode, err := contractapi.NewChaincode(&chaincode.SmartContract{})
+	if err != nil {
+		log.Panicf("Error creating game-inventory chaincode: %v", err)
+	}
+
+	if err := gameChaincode.Start(); err != nil {
+		log.Panicf("Error starting game-inventory chaincode: %v", err)
+	}
+}
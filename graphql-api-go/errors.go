@@ -0,0 +1,52 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/hyperledger/fabric-samples/internal/appclient"
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil/errcode"
+)
+
+// Codes of the errors raised by the service rather than the chaincodes
+const (
+	codeUnauthenticated  errcode.Code = "UNAUTHENTICATED"
+	codeMethodNotAllowed errcode.Code = "METHOD_NOT_ALLOWED"
+)
+
+// resolverError is an error returned by a resolver. Its code is set in the extensions of the error
+// in the response, so clients handle the errors of the chaincodes as with the REST API.
+type resolverError struct {
+	code    errcode.Code
+	message string
+}
+
+func (e *resolverError) Error() string {
+	return e.message
+}
+
+// Extensions returns the fields added to the error in the response
+func (e *resolverError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": e.code}
+}
+
+func invalidArgument(format string, args ...interface{}) error {
+	return &resolverError{code: errcode.CodeInvalidArgument, message: fmt.Sprintf(format, args...)}
+}
+
+// contractError returns the coded error of an error returned by a contract call
+func contractError(err error) error {
+	gatewayErr := appclient.ParseError(err)
+	return &resolverError{code: errcode.Code(gatewayErr.Code), message: gatewayErr.Message}
+}
+
+// indexError logs an error reading the index and returns an INTERNAL error, so the response does
+// not reveal the database
+func indexError(err error) error {
+	log.Print(err)
+	return &resolverError{code: errcode.CodeInternal, message: "failed to read the index"}
+}
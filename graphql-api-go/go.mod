@@ -0,0 +1,36 @@
+module github.com/hyperledger/fabric-samples/graphql-api-go
+
+go 1.18
+
+require (
+	github.com/graph-gophers/graphql-go v1.5.0
+	github.com/hyperledger/fabric-samples/asset-transfer-secured-agreement/application-go v0.0.0
+	github.com/hyperledger/fabric-samples/asset-transfer-secured-agreement/assettypes v0.0.0
+	github.com/hyperledger/fabric-samples/internal/appclient v0.0.0
+	github.com/hyperledger/fabric-samples/internal/ledgerutil v0.0.0
+	github.com/lib/pq v1.10.7
+)
+
+require (
+	github.com/go-logr/logr v1.2.3 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/golang/protobuf v1.5.3 // indirect
+	github.com/hyperledger/fabric-gateway v1.1.1 // indirect
+	github.com/hyperledger/fabric-protos-go-apiv2 v0.0.0-20220615102044-467be1c7b2e7 // indirect
+	github.com/miekg/pkcs11 v1.1.1 // indirect
+	go.opentelemetry.io/otel v1.10.0 // indirect
+	go.opentelemetry.io/otel/trace v1.10.0 // indirect
+	golang.org/x/net v0.17.0 // indirect
+	golang.org/x/sys v0.14.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20231030173426-d783a09b4405 // indirect
+	google.golang.org/grpc v1.59.0 // indirect
+	google.golang.org/protobuf v1.31.0 // indirect
+)
+
+replace (
+	github.com/hyperledger/fabric-samples/asset-transfer-secured-agreement/application-go => ../asset-transfer-secured-agreement/application-go
+	github.com/hyperledger/fabric-samples/asset-transfer-secured-agreement/assettypes => ../asset-transfer-secured-agreement/assettypes
+	github.com/hyperledger/fabric-samples/internal/appclient => ../internal/appclient
+	github.com/hyperledger/fabric-samples/internal/ledgerutil => ../internal/ledgerutil
+)
@@ -0,0 +1,79 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// graphql-api-go exposes the assets, owners, token balances and events indexed by event-listener-go
+// as a GraphQL API, with the receipts and provenance of assets evaluated on a peer. Each API user
+// authenticates with an API key and its evaluations are made as a Fabric identity of a wallet.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+var (
+	configPath = flag.String("config", "config.json", "configuration file")
+	database   = flag.String("database", os.Getenv("DATABASE_URL"), "PostgreSQL connection string of the event listener's database, $DATABASE_URL by default")
+	hashKey    = flag.String("hash-key", "", "print the hash of an API key for the configuration and exit")
+)
+
+func main() {
+	flag.Parse()
+	if *hashKey != "" {
+		fmt.Println(HashAPIKey(*hashKey))
+		return
+	}
+	if *database == "" {
+		log.Fatal("-database is required")
+	}
+
+	config, err := LoadConfig(*configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	store, err := OpenStore(ctx, *database, config.Channel)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer store.Close()
+	s, err := newServer(config, store)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer s.Close()
+	for _, user := range s.users {
+		log.Printf("user %s connected as a member of %s", user.name, user.mspID)
+	}
+
+	httpServer := &http.Server{
+		Addr:              config.Listen,
+		Handler:           s,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			log.Printf("failed to shut down: %v", err)
+		}
+	}()
+
+	log.Printf("listening on %s", config.Listen)
+	err = httpServer.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
+}
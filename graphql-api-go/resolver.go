@@ -0,0 +1,591 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/hyperledger/fabric-samples/asset-transfer-secured-agreement/assettypes"
+)
+
+// schemaGraphQL is the GraphQL schema of the API, served at /graphql/schema.graphql
+//
+//go:embed schema.graphql
+var schemaGraphQL string
+
+const (
+	defaultLimit = 100
+	maxLimit     = 1000
+	// maxDepth is the deepest selection a query may have, so a query cannot follow the links
+	// between assets, owners and events without bound
+	maxDepth = 8
+)
+
+// index is the indexed state of the chaincodes, implemented by Store
+type index interface {
+	Balances(ctx context.Context, filter *BalanceFilter) ([]*Balance, error)
+	Assets(ctx context.Context, filter *AssetFilter) ([]*IndexedAsset, error)
+	Events(ctx context.Context, filter *EventFilter) ([]*Event, error)
+}
+
+// assetReader is the part of the asset contract the resolvers evaluate, implemented by
+// asset.Contract
+type assetReader interface {
+	GetAssetReceipts(assetID string) ([]assettypes.Receipt, error)
+	QueryAssetHistoryPage(assetID string, pageSize int, bookmark string) (*assettypes.HistoryPage, error)
+}
+
+// Long is the Long scalar of the schema. Values are read from integers, and from strings so clients
+// can pass amounts beyond the precision of JavaScript numbers.
+type Long int64
+
+// ImplementsGraphQLType returns whether Long implements a scalar of the schema
+func (Long) ImplementsGraphQLType(name string) bool {
+	return name == "Long"
+}
+
+// UnmarshalGraphQL reads a Long argument or variable
+func (l *Long) UnmarshalGraphQL(input interface{}) error {
+	switch value := input.(type) {
+	case int32:
+		*l = Long(value)
+	case int64:
+		*l = Long(value)
+	case float64:
+		if value != math.Trunc(value) || value < math.MinInt64 || value >= math.MaxInt64 {
+			return fmt.Errorf("%v is not a Long", value)
+		}
+		*l = Long(value)
+	case string:
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a Long", value)
+		}
+		*l = Long(parsed)
+	default:
+		return fmt.Errorf("%v is not a Long", input)
+	}
+	return nil
+}
+
+// MarshalJSON writes a Long result as a JSON number
+func (l Long) MarshalJSON() ([]byte, error) {
+	return strconv.AppendInt(nil, int64(l), 10), nil
+}
+
+// newSchema returns the schema resolved from an index and the asset contract of the user of each
+// request
+func newSchema(idx index, tokenChaincode string, assetChaincode string) (*graphql.Schema, error) {
+	return graphql.ParseSchema(schemaGraphQL, &resolver{index: idx, tokenChaincode: tokenChaincode, assetChaincode: assetChaincode},
+		graphql.MaxDepth(maxDepth),
+		graphql.UseStringDescriptions(),
+	)
+}
+
+// resolver resolves the queries of the schema
+type resolver struct {
+	index          index
+	tokenChaincode string
+	assetChaincode string
+}
+
+// pageArgs are the arguments of a paginated field. First defaults to defaultLimit in the schema.
+type pageArgs struct {
+	First int32
+	After *string
+}
+
+// limit returns the number of items of the page, which is fetched with one more item to learn
+// whether a next page exists
+func (args *pageArgs) limit() (int, error) {
+	if args.First < 1 || args.First > maxLimit {
+		return 0, invalidArgument("first must be between 1 and %d", maxLimit)
+	}
+	return int(args.First), nil
+}
+
+func (args *pageArgs) after() string {
+	if args.After == nil {
+		return ""
+	}
+	return *args.After
+}
+
+// pageInfo is the PageInfo of a connection
+type pageInfo struct {
+	hasNextPage bool
+	endCursor   *string
+}
+
+func (p *pageInfo) HasNextPage() bool {
+	return p.hasNextPage
+}
+
+func (p *pageInfo) EndCursor() *string {
+	return p.endCursor
+}
+
+// page returns the PageInfo of a page fetched with one item more than limit, and the number of
+// items of the page
+func page(fetched int, limit int, cursor func(i int) string) (*pageInfo, int) {
+	info := &pageInfo{hasNextPage: fetched > limit}
+	if fetched > limit {
+		fetched = limit
+	}
+	if fetched > 0 {
+		endCursor := cursor(fetched - 1)
+		info.endCursor = &endCursor
+	}
+	return info, fetched
+}
+
+func (r *resolver) Asset(ctx context.Context, args struct{ ID graphql.ID }) (*assetResolver, error) {
+	assets, err := r.index.Assets(ctx, &AssetFilter{Chaincode: r.assetChaincode, AssetID: string(args.ID), Limit: 1})
+	if err != nil {
+		return nil, indexError(err)
+	}
+	if len(assets) == 0 {
+		return nil, nil
+	}
+	return &assetResolver{r, assets[0]}, nil
+}
+
+func (r *resolver) Assets(ctx context.Context, args struct {
+	Owner *string
+	pageArgs
+}) (*assetConnection, error) {
+	filter := &AssetFilter{}
+	if args.Owner != nil {
+		filter.OwnerOrg = *args.Owner
+	}
+	return r.assets(ctx, filter, &args.pageArgs)
+}
+
+func (r *resolver) assets(ctx context.Context, filter *AssetFilter, args *pageArgs) (*assetConnection, error) {
+	limit, err := args.limit()
+	if err != nil {
+		return nil, err
+	}
+	filter.Chaincode = r.assetChaincode
+	filter.After = args.after()
+	filter.Limit = limit + 1
+	assets, err := r.index.Assets(ctx, filter)
+	if err != nil {
+		return nil, indexError(err)
+	}
+	info, n := page(len(assets), limit, func(i int) string { return assets[i].AssetID })
+	connection := &assetConnection{nodes: []*assetResolver{}, pageInfo: info}
+	for _, asset := range assets[:n] {
+		connection.nodes = append(connection.nodes, &assetResolver{r, asset})
+	}
+	return connection, nil
+}
+
+func (r *resolver) Owner(args struct{ MspID graphql.ID }) *ownerResolver {
+	return &ownerResolver{r, string(args.MspID)}
+}
+
+func (r *resolver) Account(ctx context.Context, args struct{ ID graphql.ID }) (*accountResolver, error) {
+	return r.account(ctx, string(args.ID))
+}
+
+// account returns the indexed account with an ID, or nil when the index has none
+func (r *resolver) account(ctx context.Context, id string) (*accountResolver, error) {
+	balances, err := r.index.Balances(ctx, &BalanceFilter{Chaincode: r.tokenChaincode, Account: id, Limit: 1})
+	if err != nil {
+		return nil, indexError(err)
+	}
+	if len(balances) == 0 {
+		return nil, nil
+	}
+	return &accountResolver{r, balances[0]}, nil
+}
+
+func (r *resolver) Accounts(ctx context.Context, args struct {
+	MinBalance *Long
+	pageArgs
+}) (*accountConnection, error) {
+	limit, err := args.limit()
+	if err != nil {
+		return nil, err
+	}
+	filter := &BalanceFilter{Chaincode: r.tokenChaincode, After: args.after(), Limit: limit + 1}
+	if args.MinBalance != nil {
+		minBalance := int64(*args.MinBalance)
+		filter.MinBalance = &minBalance
+	}
+	balances, err := r.index.Balances(ctx, filter)
+	if err != nil {
+		return nil, indexError(err)
+	}
+	info, n := page(len(balances), limit, func(i int) string { return balances[i].Account })
+	connection := &accountConnection{nodes: []*accountResolver{}, pageInfo: info}
+	for _, balance := range balances[:n] {
+		connection.nodes = append(connection.nodes, &accountResolver{r, balance})
+	}
+	return connection, nil
+}
+
+// eventFilterInput is the EventFilter input of the schema
+type eventFilterInput struct {
+	Chaincode *string
+	Name      *string
+	Account   *string
+	AssetID   *string
+	FromBlock *Long
+	ToBlock   *Long
+}
+
+func (r *resolver) Events(ctx context.Context, args struct {
+	Filter *eventFilterInput
+	pageArgs
+}) (*eventConnection, error) {
+	filter := &EventFilter{}
+	if input := args.Filter; input != nil {
+		filter.Chaincode = stringValue(input.Chaincode)
+		filter.Name = stringValue(input.Name)
+		filter.Account = stringValue(input.Account)
+		filter.AssetID = stringValue(input.AssetID)
+		if input.FromBlock != nil {
+			fromBlock := int64(*input.FromBlock)
+			filter.FromBlock = &fromBlock
+		}
+		if input.ToBlock != nil {
+			toBlock := int64(*input.ToBlock)
+			filter.ToBlock = &toBlock
+		}
+	}
+	return r.events(ctx, filter, &args.pageArgs)
+}
+
+func (r *resolver) events(ctx context.Context, filter *EventFilter, args *pageArgs) (*eventConnection, error) {
+	limit, err := args.limit()
+	if err != nil {
+		return nil, err
+	}
+	if after := args.after(); after != "" {
+		filter.AfterID, err = strconv.ParseInt(after, 10, 64)
+		if err != nil {
+			return nil, invalidArgument("after %q is not an event cursor", after)
+		}
+	}
+	filter.Limit = limit + 1
+	events, err := r.index.Events(ctx, filter)
+	if err != nil {
+		return nil, indexError(err)
+	}
+	info, n := page(len(events), limit, func(i int) string { return strconv.FormatInt(events[i].ID, 10) })
+	connection := &eventConnection{nodes: []*eventResolver{}, pageInfo: info}
+	for _, event := range events[:n] {
+		connection.nodes = append(connection.nodes, &eventResolver{r, event})
+	}
+	return connection, nil
+}
+
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+// assetResolver resolves an Asset from its indexed state
+type assetResolver struct {
+	r     *resolver
+	asset *IndexedAsset
+}
+
+func (a *assetResolver) ID() graphql.ID {
+	return graphql.ID(a.asset.AssetID)
+}
+
+func (a *assetResolver) Owner() *ownerResolver {
+	return &ownerResolver{a.r, a.asset.OwnerOrg}
+}
+
+func (a *assetResolver) PublicDescription() string {
+	return a.asset.PublicDescription
+}
+
+func (a *assetResolver) BlockNumber() Long {
+	return Long(a.asset.BlockNumber)
+}
+
+func (a *assetResolver) TxID() string {
+	return a.asset.TxID
+}
+
+func (a *assetResolver) Receipts(ctx context.Context) ([]*receiptResolver, error) {
+	receipts, err := userFrom(ctx).asset.GetAssetReceipts(a.asset.AssetID)
+	if err != nil {
+		return nil, contractError(err)
+	}
+	resolvers := []*receiptResolver{}
+	for i := range receipts {
+		resolvers = append(resolvers, &receiptResolver{a.r, &receipts[i]})
+	}
+	return resolvers, nil
+}
+
+func (a *assetResolver) Provenance(ctx context.Context, args pageArgs) (*modificationConnection, error) {
+	limit, err := args.limit()
+	if err != nil {
+		return nil, err
+	}
+	history, err := userFrom(ctx).asset.QueryAssetHistoryPage(a.asset.AssetID, limit, args.after())
+	if err != nil {
+		return nil, contractError(err)
+	}
+	// the bookmark of the chaincode is the cursor of the next page
+	connection := &modificationConnection{nodes: []*modificationResolver{}, pageInfo: &pageInfo{hasNextPage: history.Bookmark != ""}}
+	if history.Bookmark != "" {
+		connection.pageInfo.endCursor = &history.Bookmark
+	}
+	for i := range history.History {
+		connection.nodes = append(connection.nodes, &modificationResolver{a.r, &history.History[i]})
+	}
+	return connection, nil
+}
+
+func (a *assetResolver) Events(ctx context.Context, args pageArgs) (*eventConnection, error) {
+	return a.r.events(ctx, &EventFilter{Chaincode: a.r.assetChaincode, AssetID: a.asset.AssetID}, &args)
+}
+
+// ownerResolver resolves an Owner from its MSP ID
+type ownerResolver struct {
+	r     *resolver
+	mspID string
+}
+
+func (o *ownerResolver) MspID() graphql.ID {
+	return graphql.ID(o.mspID)
+}
+
+func (o *ownerResolver) Assets(ctx context.Context, args pageArgs) (*assetConnection, error) {
+	return o.r.assets(ctx, &AssetFilter{OwnerOrg: o.mspID}, &args)
+}
+
+// accountResolver resolves an Account from its indexed balance
+type accountResolver struct {
+	r       *resolver
+	balance *Balance
+}
+
+func (a *accountResolver) ID() graphql.ID {
+	return graphql.ID(a.balance.Account)
+}
+
+func (a *accountResolver) Balance() Long {
+	return Long(a.balance.Balance)
+}
+
+func (a *accountResolver) BlockNumber() Long {
+	return Long(a.balance.BlockNumber)
+}
+
+func (a *accountResolver) TxID() string {
+	return a.balance.TxID
+}
+
+func (a *accountResolver) Events(ctx context.Context, args pageArgs) (*eventConnection, error) {
+	return a.r.events(ctx, &EventFilter{Chaincode: a.r.tokenChaincode, Account: a.balance.Account}, &args)
+}
+
+// receiptResolver resolves a Receipt read from the asset contract
+type receiptResolver struct {
+	r       *resolver
+	receipt *assettypes.Receipt
+}
+
+func (rr *receiptResolver) Type() string {
+	return rr.receipt.Type
+}
+
+func (rr *receiptResolver) Counterparty() *ownerResolver {
+	return &ownerResolver{rr.r, rr.receipt.Counterparty}
+}
+
+func (rr *receiptResolver) Price() Long {
+	return Long(rr.receipt.Price)
+}
+
+func (rr *receiptResolver) Timestamp() graphql.Time {
+	return graphql.Time{Time: rr.receipt.Timestamp}
+}
+
+// modificationResolver resolves a Modification from an entry of the history of an asset
+type modificationResolver struct {
+	r      *resolver
+	result *assettypes.QueryResult
+}
+
+func (m *modificationResolver) TxID() string {
+	return m.result.TxId
+}
+
+func (m *modificationResolver) Timestamp() graphql.Time {
+	return graphql.Time{Time: m.result.Timestamp}
+}
+
+func (m *modificationResolver) Deleted() bool {
+	return m.result.Record == nil
+}
+
+func (m *modificationResolver) Owner() *ownerResolver {
+	if m.result.Record == nil {
+		return nil
+	}
+	return &ownerResolver{m.r, m.result.Record.OwnerOrg}
+}
+
+func (m *modificationResolver) PublicDescription() *string {
+	if m.result.Record == nil {
+		return nil
+	}
+	return &m.result.Record.PublicDescription
+}
+
+// eventPayload holds the fields of the token and asset event payloads linking events to accounts
+// and assets
+type eventPayload struct {
+	From    string `json:"from"`
+	To      string `json:"to"`
+	AssetID string `json:"assetID"`
+}
+
+// eventResolver resolves an Event from its stored row
+type eventResolver struct {
+	r     *resolver
+	event *Event
+}
+
+func (e *eventResolver) ID() graphql.ID {
+	return graphql.ID(strconv.FormatInt(e.event.ID, 10))
+}
+
+func (e *eventResolver) Chaincode() string {
+	return e.event.Chaincode
+}
+
+func (e *eventResolver) Name() string {
+	return e.event.Name
+}
+
+func (e *eventResolver) BlockNumber() Long {
+	return Long(e.event.BlockNumber)
+}
+
+func (e *eventResolver) TxID() string {
+	return e.event.TxID
+}
+
+func (e *eventResolver) Payload() *string {
+	if e.event.Payload == nil {
+		return nil
+	}
+	payload := string(e.event.Payload)
+	return &payload
+}
+
+func (e *eventResolver) ReceivedAt() graphql.Time {
+	return graphql.Time{Time: e.event.ReceivedAt}
+}
+
+// payload returns the linking fields of the payload, empty for a payload that is not JSON
+func (e *eventResolver) payload() *eventPayload {
+	fields := &eventPayload{}
+	json.Unmarshal(e.event.Payload, fields)
+	return fields
+}
+
+func (e *eventResolver) isTokenEvent() bool {
+	return e.event.Chaincode == e.r.tokenChaincode && (e.event.Name == "Transfer" || e.event.Name == "Approval")
+}
+
+func (e *eventResolver) From(ctx context.Context) (*accountResolver, error) {
+	from := e.payload().From
+	if !e.isTokenEvent() || from == "" {
+		return nil, nil
+	}
+	return e.r.account(ctx, from)
+}
+
+func (e *eventResolver) To(ctx context.Context) (*accountResolver, error) {
+	to := e.payload().To
+	if !e.isTokenEvent() || to == "" {
+		return nil, nil
+	}
+	return e.r.account(ctx, to)
+}
+
+func (e *eventResolver) Asset(ctx context.Context) (*assetResolver, error) {
+	assetID := e.payload().AssetID
+	if e.event.Chaincode != e.r.assetChaincode || assetID == "" {
+		return nil, nil
+	}
+	return e.r.Asset(ctx, struct{ ID graphql.ID }{graphql.ID(assetID)})
+}
+
+// assetConnection is an AssetConnection
+type assetConnection struct {
+	nodes    []*assetResolver
+	pageInfo *pageInfo
+}
+
+func (c *assetConnection) Nodes() []*assetResolver {
+	return c.nodes
+}
+
+func (c *assetConnection) PageInfo() *pageInfo {
+	return c.pageInfo
+}
+
+// accountConnection is an AccountConnection
+type accountConnection struct {
+	nodes    []*accountResolver
+	pageInfo *pageInfo
+}
+
+func (c *accountConnection) Nodes() []*accountResolver {
+	return c.nodes
+}
+
+func (c *accountConnection) PageInfo() *pageInfo {
+	return c.pageInfo
+}
+
+// eventConnection is an EventConnection
+type eventConnection struct {
+	nodes    []*eventResolver
+	pageInfo *pageInfo
+}
+
+func (c *eventConnection) Nodes() []*eventResolver {
+	return c.nodes
+}
+
+func (c *eventConnection) PageInfo() *pageInfo {
+	return c.pageInfo
+}
+
+// modificationConnection is a ModificationConnection
+type modificationConnection struct {
+	nodes    []*modificationResolver
+	pageInfo *pageInfo
+}
+
+func (c *modificationConnection) Nodes() []*modificationResolver {
+	return c.nodes
+}
+
+func (c *modificationConnection) PageInfo() *pageInfo {
+	return c.pageInfo
+}
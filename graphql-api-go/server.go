@@ -0,0 +1,159 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/hyperledger/fabric-samples/asset-transfer-secured-agreement/application-go/asset"
+	"github.com/hyperledger/fabric-samples/internal/appclient"
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil/errcode"
+)
+
+// apiKeyHeader is the request header carrying the API key of the user
+const apiKeyHeader = "X-API-Key"
+
+// maxBodySize is the largest request body the service reads
+const maxBodySize = 1 << 20
+
+// userClient is an API user with its Gateway connection and asset contract
+type userClient struct {
+	name       string
+	mspID      string
+	connection *appclient.Connection
+	asset      assetReader
+}
+
+// userKey is the context key of the user of a request
+type userKey struct{}
+
+// userFrom returns the user of the request a resolver runs for
+func userFrom(ctx context.Context) *userClient {
+	return ctx.Value(userKey{}).(*userClient)
+}
+
+// server runs the GraphQL queries of API users, reading the index and evaluating the asset contract
+// as the Fabric identities of the users
+type server struct {
+	users  map[string]*userClient
+	schema *graphql.Schema
+}
+
+// newServer connects every user of the configuration to its peer as its wallet identity
+func newServer(config *Config, idx index) (*server, error) {
+	schema, err := newSchema(idx, config.TokenChaincode, config.AssetChaincode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse schema: %v", err)
+	}
+	wallet, err := appclient.OpenWallet(config.Wallet)
+	if err != nil {
+		return nil, err
+	}
+	s := &server{users: make(map[string]*userClient), schema: schema}
+	for _, user := range config.Users {
+		profile, err := appclient.LoadProfile(user.Profile)
+		if err != nil {
+			s.Close()
+			return nil, fmt.Errorf("failed to load profile of user %s: %v", user.Name, err)
+		}
+		connection, err := appclient.ConnectWallet(profile, user.Peer, wallet, user.Identity)
+		if err != nil {
+			s.Close()
+			return nil, fmt.Errorf("failed to connect user %s: %v", user.Name, err)
+		}
+		network := connection.GetNetwork(config.Channel)
+		s.users[user.APIKeySHA256] = &userClient{
+			name:       user.Name,
+			mspID:      profile.MSPID(),
+			connection: connection,
+			asset:      asset.NewContract(network, config.AssetChaincode, profile.MSPID()),
+		}
+	}
+	return s, nil
+}
+
+// Close closes the connections of the users
+func (s *server) Close() {
+	for _, user := range s.users {
+		if user.connection == nil {
+			continue
+		}
+		if err := user.connection.Close(); err != nil {
+			log.Printf("failed to close connection of user %s: %v", user.name, err)
+		}
+	}
+}
+
+// graphQLRequest is the body of a POST to /graphql
+type graphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// ServeHTTP serves the schema to anyone and runs the queries of users with a valid API key
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/graphql/schema.graphql" && r.Method == http.MethodGet:
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(schemaGraphQL))
+	case r.URL.Path == "/graphql" && r.Method == http.MethodPost:
+		s.serveQuery(w, r)
+	case r.URL.Path == "/graphql":
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, &errcode.Error{Code: codeMethodNotAllowed, Message: fmt.Sprintf("%s is not allowed on %s", r.Method, r.URL.Path)})
+	default:
+		writeError(w, http.StatusNotFound, &errcode.Error{Code: errcode.CodeNotFound, Message: fmt.Sprintf("no API at %s", r.URL.Path)})
+	}
+}
+
+// serveQuery runs a query as the user of the API key of the request. Errors of the query are
+// returned in the errors of the GraphQL response, with a 200 status as with any GraphQL server.
+func (s *server) serveQuery(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.users[HashAPIKey(r.Header.Get(apiKeyHeader))]
+	if !ok {
+		writeError(w, http.StatusUnauthorized, &errcode.Error{Code: codeUnauthenticated, Message: fmt.Sprintf("missing or unknown %s header", apiKeyHeader)})
+		return
+	}
+
+	var request graphQLRequest
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize)).Decode(&request)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, &errcode.Error{Code: errcode.CodeInvalidArgument, Message: fmt.Sprintf("invalid request body: %v", err)})
+		return
+	}
+	ctx := context.WithValue(r.Context(), userKey{}, user)
+	response := s.schema.Exec(ctx, request.Query, request.OperationName, request.Variables)
+	writeJSON(w, http.StatusOK, response)
+}
+
+// writeError writes an error outside of a query in the format of GraphQL errors, with its code in
+// the extensions
+func writeError(w http.ResponseWriter, status int, apiErr *errcode.Error) {
+	type graphQLError struct {
+		Message    string                 `json:"message"`
+		Extensions map[string]interface{} `json:"extensions"`
+	}
+	writeJSON(w, status, struct {
+		Errors []graphQLError `json:"errors"`
+	}{[]graphQLError{{Message: apiErr.Message, Extensions: map[string]interface{}{"code": apiErr.Code}}}})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	bodyJSON, err := json.Marshal(body)
+	if err != nil {
+		log.Printf("failed to marshal response: %v", err)
+		status = http.StatusInternalServerError
+		bodyJSON = []byte(`{"errors":[{"message":"failed to marshal response","extensions":{"code":"INTERNAL"}}]}`)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(bodyJSON)
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-secured-agreement/assettypes"
+	"github.com/hyperledger/fabric-samples/internal/appclient"
+)
+
+// fakeIndex serves the balances, assets and events of a test from memory, applying the filters as
+// the store does
+type fakeIndex struct {
+	balances []*Balance
+	assets   []*IndexedAsset
+	events   []*Event
+}
+
+func (f *fakeIndex) Balances(ctx context.Context, filter *BalanceFilter) ([]*Balance, error) {
+	var balances []*Balance
+	for _, balance := range f.balances {
+		if (filter.Account == "" || balance.Account == filter.Account) && balance.Account > filter.After &&
+			(filter.MinBalance == nil || balance.Balance >= *filter.MinBalance) && len(balances) < filter.Limit {
+			balances = append(balances, balance)
+		}
+	}
+	return balances, nil
+}
+
+func (f *fakeIndex) Assets(ctx context.Context, filter *AssetFilter) ([]*IndexedAsset, error) {
+	var assets []*IndexedAsset
+	for _, asset := range f.assets {
+		if (filter.AssetID == "" || asset.AssetID == filter.AssetID) && (filter.OwnerOrg == "" || asset.OwnerOrg == filter.OwnerOrg) &&
+			asset.AssetID > filter.After && len(assets) < filter.Limit {
+			assets = append(assets, asset)
+		}
+	}
+	return assets, nil
+}
+
+func (f *fakeIndex) Events(ctx context.Context, filter *EventFilter) ([]*Event, error) {
+	var events []*Event
+	for _, event := range f.events {
+		var payload eventPayload
+		json.Unmarshal(event.Payload, &payload)
+		if (filter.Chaincode == "" || event.Chaincode == filter.Chaincode) && (filter.Name == "" || event.Name == filter.Name) &&
+			(filter.Account == "" || payload.From == filter.Account || payload.To == filter.Account) &&
+			(filter.AssetID == "" || payload.AssetID == filter.AssetID) && event.ID > filter.AfterID && len(events) < filter.Limit {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// fakeAssetReader returns the receipts and history of the assets of a test, or err
+type fakeAssetReader struct {
+	receipts map[string][]assettypes.Receipt
+	history  map[string][]assettypes.QueryResult
+	err      error
+}
+
+func (f *fakeAssetReader) GetAssetReceipts(assetID string) ([]assettypes.Receipt, error) {
+	return f.receipts[assetID], f.err
+}
+
+func (f *fakeAssetReader) QueryAssetHistoryPage(assetID string, pageSize int, bookmark string) (*assettypes.HistoryPage, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	history := f.history[assetID]
+	start := 0
+	for bookmark != "" && start < len(history) && history[start].TxId != bookmark {
+		start++
+	}
+	page := &assettypes.HistoryPage{History: history[start:]}
+	if len(page.History) > pageSize {
+		page.Bookmark = page.History[pageSize].TxId
+		page.History = page.History[:pageSize]
+	}
+	return page, nil
+}
+
+func newTestServer(t *testing.T, reader *fakeAssetReader) *server {
+	t.Helper()
+	timestamp := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	idx := &fakeIndex{
+		balances: []*Balance{
+			{Account: "alice", Balance: 70, BlockNumber: 9, TxID: "tx9"},
+			{Account: "bob", Balance: 30, BlockNumber: 9, TxID: "tx9"},
+			{Account: "carol", Balance: 5000000000, BlockNumber: 4, TxID: "tx4"},
+		},
+		assets: []*IndexedAsset{
+			{AssetID: "asset1", OwnerOrg: "Org2MSP", PublicDescription: "a bike", BlockNumber: 12, TxID: "tx12"},
+			{AssetID: "asset2", OwnerOrg: "Org1MSP", PublicDescription: "a car", BlockNumber: 7, TxID: "tx7"},
+		},
+		events: []*Event{
+			{ID: 1, Chaincode: "token_erc20", BlockNumber: 9, TxID: "tx9", Name: "Transfer", Payload: []byte(`{"from":"alice","to":"bob","value":30}`), ReceivedAt: timestamp},
+			{ID: 2, Chaincode: "secured", BlockNumber: 10, TxID: "tx10", Name: "AssetCreated", Payload: []byte(`{"assetID":"asset1","ownerOrg":"Org1MSP"}`), ReceivedAt: timestamp},
+			{ID: 3, Chaincode: "secured", BlockNumber: 12, TxID: "tx12", Name: "AssetTransferred", Payload: []byte(`{"assetID":"asset1","ownerOrg":"Org2MSP"}`), ReceivedAt: timestamp},
+		},
+	}
+	schema, err := newSchema(idx, "token_erc20", "secured")
+	if err != nil {
+		t.Fatalf("failed to parse schema: %v", err)
+	}
+	return &server{
+		users:  map[string]*userClient{HashAPIKey("secret"): {name: "org1-app", mspID: "Org1MSP", asset: reader}},
+		schema: schema,
+	}
+}
+
+// graphQLResponse is a response of the service
+type graphQLResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string                 `json:"message"`
+		Extensions map[string]interface{} `json:"extensions"`
+	} `json:"errors"`
+}
+
+func runQuery(t *testing.T, s *server, query string, variables map[string]interface{}) *graphQLResponse {
+	t.Helper()
+	body, _ := json.Marshal(graphQLRequest{Query: query, Variables: variables})
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body)))
+	request.Header.Set(apiKeyHeader, "secret")
+	s.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("query returned %d: %s", recorder.Code, recorder.Body)
+	}
+	response := &graphQLResponse{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), response); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	return response
+}
+
+func TestServeHTTPRequiresAPIKey(t *testing.T) {
+	s := newTestServer(t, &fakeAssetReader{})
+
+	for _, test := range []struct {
+		method, path string
+		status       int
+	}{
+		{http.MethodPost, "/graphql", http.StatusUnauthorized},
+		{http.MethodGet, "/graphql", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/graphql/schema.graphql", http.StatusOK},
+		{http.MethodGet, "/unknown", http.StatusNotFound},
+	} {
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(test.method, test.path, strings.NewReader(`{"query":"{ accounts { nodes { id } } }"}`))
+		request.Header.Set(apiKeyHeader, "unknown")
+		s.ServeHTTP(recorder, request)
+		if recorder.Code != test.status {
+			t.Errorf("%s %s returned %d, want %d", test.method, test.path, recorder.Code, test.status)
+		}
+	}
+}
+
+func TestQueryAssetGraph(t *testing.T) {
+	timestamp := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	s := newTestServer(t, &fakeAssetReader{
+		receipts: map[string][]assettypes.Receipt{
+			"asset1": {{AssetID: "asset1", Type: "sold", Counterparty: "Org2MSP", Price: 110, Timestamp: timestamp}},
+		},
+		history: map[string][]assettypes.QueryResult{
+			"asset1": {
+				{Record: &assettypes.Asset{ID: "asset1", OwnerOrg: "Org1MSP", PublicDescription: "a bike"}, TxId: "tx10", Timestamp: timestamp},
+				{Record: &assettypes.Asset{ID: "asset1", OwnerOrg: "Org2MSP", PublicDescription: "a bike"}, TxId: "tx12", Timestamp: timestamp},
+			},
+		},
+	})
+
+	response := runQuery(t, s, `query($id: ID!) {
+		asset(id: $id) {
+			owner { mspID assets { nodes { id } } }
+			receipts { type price counterparty { mspID } }
+			provenance(first: 1) { nodes { txID owner { mspID } } pageInfo { hasNextPage endCursor } }
+			events { nodes { name blockNumber asset { id } } }
+		}
+	}`, map[string]interface{}{"id": "asset1"})
+	if len(response.Errors) > 0 {
+		t.Fatalf("query failed: %v", response.Errors)
+	}
+	want := `{"asset":{` +
+		`"owner":{"mspID":"Org2MSP","assets":{"nodes":[{"id":"asset1"}]}},` +
+		`"receipts":[{"type":"sold","price":110,"counterparty":{"mspID":"Org2MSP"}}],` +
+		`"provenance":{"nodes":[{"txID":"tx10","owner":{"mspID":"Org1MSP"}}],"pageInfo":{"hasNextPage":true,"endCursor":"tx12"}},` +
+		`"events":{"nodes":[{"name":"AssetCreated","blockNumber":10,"asset":{"id":"asset1"}},{"name":"AssetTransferred","blockNumber":12,"asset":{"id":"asset1"}}]}` +
+		`}}`
+	if string(response.Data) != want {
+		t.Errorf("data is\n%s\nwant\n%s", response.Data, want)
+	}
+
+	response = runQuery(t, s, `{ asset(id: "missing") { id } }`, nil)
+	if len(response.Errors) > 0 || string(response.Data) != `{"asset":null}` {
+		t.Errorf("missing asset returned %s %v", response.Data, response.Errors)
+	}
+}
+
+func TestQueryAccountsPagination(t *testing.T) {
+	s := newTestServer(t, &fakeAssetReader{})
+
+	response := runQuery(t, s, `{ accounts(first: 2) { nodes { id balance } pageInfo { hasNextPage endCursor } } }`, nil)
+	want := `{"accounts":{"nodes":[{"id":"alice","balance":70},{"id":"bob","balance":30}],"pageInfo":{"hasNextPage":true,"endCursor":"bob"}}}`
+	if string(response.Data) != want {
+		t.Errorf("first page is %s", response.Data)
+	}
+	response = runQuery(t, s, `{ accounts(first: 2, after: "bob") { nodes { id balance } pageInfo { hasNextPage endCursor } } }`, nil)
+	want = `{"accounts":{"nodes":[{"id":"carol","balance":5000000000}],"pageInfo":{"hasNextPage":false,"endCursor":"carol"}}}`
+	if string(response.Data) != want {
+		t.Errorf("second page is %s", response.Data)
+	}
+
+	// a Long variable may be passed as a string
+	response = runQuery(t, s, `query($min: Long) { accounts(minBalance: $min) { nodes { id } } }`, map[string]interface{}{"min": "4000000000"})
+	if string(response.Data) != `{"accounts":{"nodes":[{"id":"carol"}]}}` {
+		t.Errorf("accounts with a minimum balance are %s %v", response.Data, response.Errors)
+	}
+
+	response = runQuery(t, s, `{ events(filter: {account: "bob"}) { nodes { id from { id balance } to { id } } } }`, nil)
+	want = `{"events":{"nodes":[{"id":"1","from":{"id":"alice","balance":70},"to":{"id":"bob"}}]}}`
+	if string(response.Data) != want {
+		t.Errorf("events of bob are %s %v", response.Data, response.Errors)
+	}
+
+	response = runQuery(t, s, `{ accounts(first: 5000) { nodes { id } } }`, nil)
+	if len(response.Errors) != 1 || response.Errors[0].Extensions["code"] != "INVALID_ARGUMENT" {
+		t.Errorf("oversized page returned %v", response.Errors)
+	}
+}
+
+func TestQueryContractError(t *testing.T) {
+	s := newTestServer(t, &fakeAssetReader{err: &appclient.Error{Code: "NOT_AUTHORIZED", Message: "client is not allowed to read receipts"}})
+
+	response := runQuery(t, s, `{ asset(id: "asset1") { id receipts { price } } }`, nil)
+	if len(response.Errors) != 1 {
+		t.Fatalf("expected one error, got %v", response.Errors)
+	}
+	if response.Errors[0].Extensions["code"] != "NOT_AUTHORIZED" || response.Errors[0].Message != "client is not allowed to read receipts" {
+		t.Errorf("error is %+v", response.Errors[0])
+	}
+}
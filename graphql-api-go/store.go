@@ -0,0 +1,238 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Store reads the tables the event listener keeps in PostgreSQL: the stored chaincode events and
+// the indexed balances and assets of a channel. It only reads them; the event listener creates and
+// fills them.
+type Store struct {
+	db      *sql.DB
+	channel string
+}
+
+// Balance is the indexed token balance of an account
+type Balance struct {
+	Account     string
+	Balance     int64
+	BlockNumber int64
+	TxID        string
+}
+
+// IndexedAsset is the indexed owner and public description of an asset
+type IndexedAsset struct {
+	AssetID           string
+	OwnerOrg          string
+	PublicDescription string
+	BlockNumber       int64
+	TxID              string
+}
+
+// Event is a stored chaincode event
+type Event struct {
+	ID          int64
+	Chaincode   string
+	BlockNumber int64
+	TxID        string
+	Name        string
+	Payload     []byte
+	ReceivedAt  time.Time
+}
+
+// BalanceFilter selects indexed balances of a chaincode. Empty fields match every balance.
+type BalanceFilter struct {
+	Chaincode  string
+	Account    string
+	MinBalance *int64
+	// After continues a listing after the last account of the previous page
+	After string
+	Limit int
+}
+
+// AssetFilter selects indexed assets of a chaincode. Empty fields match every asset.
+type AssetFilter struct {
+	Chaincode string
+	AssetID   string
+	OwnerOrg  string
+	// After continues a listing after the last asset ID of the previous page
+	After string
+	Limit int
+}
+
+// EventFilter selects stored events. Empty fields match every event.
+type EventFilter struct {
+	Chaincode string
+	Name      string
+	// Account matches the from and to accounts of token events
+	Account   string
+	AssetID   string
+	FromBlock *int64
+	ToBlock   *int64
+	// AfterID continues a listing after the last event ID of the previous page
+	AfterID int64
+	Limit   int
+}
+
+// OpenStore connects to the database of the event listener to read the events of a channel
+func OpenStore(ctx context.Context, dataSource string, channel string) (*Store, error) {
+	db, err := sql.Open("postgres", dataSource)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %v", err)
+	}
+	err = db.PingContext(ctx)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to database: %v", err)
+	}
+	return &Store{db: db, channel: channel}, nil
+}
+
+// Close closes the database connections
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Balances returns the indexed balances selected by a filter, ordered by account
+func (s *Store) Balances(ctx context.Context, filter *BalanceFilter) ([]*Balance, error) {
+	q := &query{}
+	q.where("channel = ?", s.channel)
+	q.where("chaincode = ?", filter.Chaincode)
+	if filter.Account != "" {
+		q.where("account = ?", filter.Account)
+	}
+	if filter.MinBalance != nil {
+		q.where("balance >= ?", *filter.MinBalance)
+	}
+	if filter.After != "" {
+		q.where("account > ?", filter.After)
+	}
+	rows, err := s.db.QueryContext(ctx, q.build("SELECT account, balance, block_number, tx_id FROM account_balances", "account", filter.Limit), q.args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query balances: %v", err)
+	}
+	defer rows.Close()
+
+	balances := []*Balance{}
+	for rows.Next() {
+		balance := &Balance{}
+		err := rows.Scan(&balance.Account, &balance.Balance, &balance.BlockNumber, &balance.TxID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read balance: %v", err)
+		}
+		balances = append(balances, balance)
+	}
+	return balances, rows.Err()
+}
+
+// Assets returns the indexed assets selected by a filter, ordered by asset ID
+func (s *Store) Assets(ctx context.Context, filter *AssetFilter) ([]*IndexedAsset, error) {
+	q := &query{}
+	q.where("channel = ?", s.channel)
+	q.where("chaincode = ?", filter.Chaincode)
+	if filter.AssetID != "" {
+		q.where("asset_id = ?", filter.AssetID)
+	}
+	if filter.OwnerOrg != "" {
+		q.where("owner_org = ?", filter.OwnerOrg)
+	}
+	if filter.After != "" {
+		q.where("asset_id > ?", filter.After)
+	}
+	rows, err := s.db.QueryContext(ctx, q.build("SELECT asset_id, owner_org, public_description, block_number, tx_id FROM assets", "asset_id", filter.Limit), q.args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query assets: %v", err)
+	}
+	defer rows.Close()
+
+	assets := []*IndexedAsset{}
+	for rows.Next() {
+		asset := &IndexedAsset{}
+		err := rows.Scan(&asset.AssetID, &asset.OwnerOrg, &asset.PublicDescription, &asset.BlockNumber, &asset.TxID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read asset: %v", err)
+		}
+		assets = append(assets, asset)
+	}
+	return assets, rows.Err()
+}
+
+// Events returns the stored events selected by a filter in the order they were stored
+func (s *Store) Events(ctx context.Context, filter *EventFilter) ([]*Event, error) {
+	q := eventsQuery(s.channel, filter)
+	rows, err := s.db.QueryContext(ctx, q.build("SELECT id, chaincode, block_number, tx_id, event_name, payload, received_at FROM chaincode_events", "id", filter.Limit), q.args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query events: %v", err)
+	}
+	defer rows.Close()
+
+	events := []*Event{}
+	for rows.Next() {
+		event := &Event{}
+		err := rows.Scan(&event.ID, &event.Chaincode, &event.BlockNumber, &event.TxID, &event.Name, &event.Payload, &event.ReceivedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read event: %v", err)
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// eventsQuery returns the conditions of the events of a channel selected by a filter
+func eventsQuery(channel string, filter *EventFilter) *query {
+	q := &query{}
+	q.where("channel = ?", channel)
+	if filter.Chaincode != "" {
+		q.where("chaincode = ?", filter.Chaincode)
+	}
+	if filter.Name != "" {
+		q.where("event_name = ?", filter.Name)
+	}
+	if filter.Account != "" {
+		q.where("(payload->>'from' = ? OR payload->>'to' = ?)", filter.Account)
+	}
+	if filter.AssetID != "" {
+		q.where("payload->>'assetID' = ?", filter.AssetID)
+	}
+	if filter.FromBlock != nil {
+		q.where("block_number >= ?", *filter.FromBlock)
+	}
+	if filter.ToBlock != nil {
+		q.where("block_number <= ?", *filter.ToBlock)
+	}
+	if filter.AfterID > 0 {
+		q.where("id > ?", filter.AfterID)
+	}
+	return q
+}
+
+// query collects the conditions of a query and their arguments
+type query struct {
+	conditions []string
+	args       []interface{}
+}
+
+// where adds a condition whose ? placeholders all stand for arg
+func (q *query) where(condition string, arg interface{}) {
+	q.args = append(q.args, arg)
+	q.conditions = append(q.conditions, strings.ReplaceAll(condition, "?", "$"+strconv.Itoa(len(q.args))))
+}
+
+// build returns the query of the rows of selection matching the conditions, ordered by orderBy and
+// limited to limit rows
+func (q *query) build(selection string, orderBy string, limit int) string {
+	if len(q.conditions) > 0 {
+		selection += " WHERE " + strings.Join(q.conditions, " AND ")
+	}
+	q.args = append(q.args, limit)
+	return selection + " ORDER BY " + orderBy + " LIMIT $" + strconv.Itoa(len(q.args))
+}
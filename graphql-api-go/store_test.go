@@ -0,0 +1,29 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEventsQuery(t *testing.T) {
+	q := eventsQuery("mychannel", &EventFilter{})
+	query := q.build("SELECT id FROM chaincode_events", "id", defaultLimit)
+	if query != "SELECT id FROM chaincode_events WHERE channel = $1 ORDER BY id LIMIT $2" {
+		t.Errorf("query is %q", query)
+	}
+	if !reflect.DeepEqual(q.args, []interface{}{"mychannel", defaultLimit}) {
+		t.Errorf("args are %v", q.args)
+	}
+
+	fromBlock := int64(5)
+	q = eventsQuery("mychannel", &EventFilter{Chaincode: "token_erc20", Account: "acc1", FromBlock: &fromBlock, AfterID: 42})
+	query = q.build("SELECT id FROM chaincode_events", "id", 11)
+	want := "SELECT id FROM chaincode_events WHERE channel = $1 AND chaincode = $2 AND (payload->>'from' = $3 OR payload->>'to' = $3)" +
+		" AND block_number >= $4 AND id > $5 ORDER BY id LIMIT $6"
+	if query != want {
+		t.Errorf("query is %q", query)
+	}
+	if !reflect.DeepEqual(q.args, []interface{}{"mychannel", "token_erc20", "acc1", int64(5), int64(42), 11}) {
+		t.Errorf("args are %v", q.args)
+	}
+}
@@ -0,0 +1,217 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-secured-agreement/application-go/asset"
+	"github.com/hyperledger/fabric-samples/asset-transfer-secured-agreement/assettypes"
+	"github.com/hyperledger/fabric-samples/grpc-api-go/pb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// assetServer implements the Asset service with the asset contract
+type assetServer struct {
+	pb.UnimplementedAssetServer
+	contract *asset.Contract
+}
+
+func (s *assetServer) CreateAsset(ctx context.Context, request *pb.CreateAssetRequest) (*pb.CreateAssetResult, error) {
+	if request.Properties == nil {
+		return nil, invalidArgument("properties are required")
+	}
+	properties := assetProperties(request.AssetId, request.Properties)
+	if properties.Salt == "" {
+		salt := make([]byte, 20)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, statusError(fmt.Errorf("failed to generate salt: %v", err))
+		}
+		properties.Salt = hex.EncodeToString(salt)
+	}
+	result, err := s.contract.WithContext(ctx).CreateAsset(request.AssetId, request.Description, properties)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &pb.CreateAssetResult{Result: assetResult(result), Properties: propertiesRecord(properties)}, nil
+}
+
+func (s *assetServer) UpdateAsset(ctx context.Context, request *pb.UpdateAssetRequest) (*pb.AssetResult, error) {
+	return assetResultOrError(s.contract.WithContext(ctx).UpdateAsset(request.AssetId, request.Description))
+}
+
+func (s *assetServer) AgreeToSell(ctx context.Context, request *pb.AgreementRequest) (*pb.AssetResult, error) {
+	return assetResultOrError(s.contract.WithContext(ctx).AgreeToSell(agreement(request.AssetId, request.Price, request.TradeId)))
+}
+
+func (s *assetServer) AgreeToBuy(ctx context.Context, request *pb.AgreementRequest) (*pb.AssetResult, error) {
+	return assetResultOrError(s.contract.WithContext(ctx).AgreeToBuy(agreement(request.AssetId, request.Price, request.TradeId)))
+}
+
+func (s *assetServer) SetInspection(ctx context.Context, request *pb.InspectionRequest) (*pb.InspectionResponse, error) {
+	if request.Properties == nil {
+		return nil, invalidArgument("properties are required")
+	}
+	matches, err := s.contract.WithContext(ctx).SetInspection(request.AssetId, assetProperties(request.AssetId, request.Properties))
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &pb.InspectionResponse{Matches: matches}, nil
+}
+
+func (s *assetServer) TransferAsset(ctx context.Context, request *pb.TransferAssetRequest) (*pb.AssetResult, error) {
+	contract := s.contract.WithContext(ctx)
+	properties, err := contract.GetAssetPrivateProperties(request.AssetId)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return assetResultOrError(contract.TransferAsset(request.AssetId, request.BuyerMspId, properties, agreement(request.AssetId, request.Price, request.TradeId)))
+}
+
+func (s *assetServer) ReadAsset(ctx context.Context, request *pb.AssetRequest) (*pb.AssetRecord, error) {
+	record, err := s.contract.WithContext(ctx).ReadAsset(request.AssetId)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return assetRecord(record), nil
+}
+
+func (s *assetServer) GetAssetPrivateProperties(ctx context.Context, request *pb.AssetRequest) (*pb.AssetProperties, error) {
+	contract := s.contract.WithContext(ctx)
+	properties, err := contract.GetAssetPrivateProperties(request.AssetId)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return propertiesRecord(properties), nil
+}
+
+func (s *assetServer) GetAssetReceipts(ctx context.Context, request *pb.AssetRequest) (*pb.ReceiptList, error) {
+	receipts, err := s.contract.WithContext(ctx).GetAssetReceipts(request.AssetId)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	response := &pb.ReceiptList{}
+	for _, receipt := range receipts {
+		response.Receipts = append(response.Receipts, &pb.Receipt{
+			AssetId:      receipt.AssetID,
+			Type:         receipt.Type,
+			Counterparty: receipt.Counterparty,
+			Price:        int64(receipt.Price),
+			Timestamp:    timestamppb.New(receipt.Timestamp),
+		})
+	}
+	return response, nil
+}
+
+func (s *assetServer) QueryAssetHistory(ctx context.Context, request *pb.AssetRequest) (*pb.HistoryList, error) {
+	history, err := s.contract.WithContext(ctx).QueryAssetHistory(request.AssetId)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	response := &pb.HistoryList{}
+	for _, entry := range history {
+		response.Entries = append(response.Entries, &pb.HistoryEntry{
+			TxId:      entry.TxId,
+			Timestamp: timestamppb.New(entry.Timestamp),
+			Asset:     assetRecord(entry.Record),
+		})
+	}
+	return response, nil
+}
+
+func (s *assetServer) GetAssetsPage(ctx context.Context, request *pb.AssetsPageRequest) (*pb.AssetsPage, error) {
+	if err := checkPageSize(request.PageSize); err != nil {
+		return nil, err
+	}
+	page, err := s.contract.WithContext(ctx).GetAssetsPage(int(request.PageSize), request.Bookmark)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	response := &pb.AssetsPage{Bookmark: page.Bookmark}
+	for _, record := range page.Assets {
+		response.Assets = append(response.Assets, assetRecord(record))
+	}
+	return response, nil
+}
+
+// ListAssets streams every asset, reading the next page only once the previous one was sent
+func (s *assetServer) ListAssets(request *pb.ListAssetsRequest, stream pb.Asset_ListAssetsServer) error {
+	if err := checkPageSize(request.PageSize); err != nil {
+		return err
+	}
+	err := s.contract.WithContext(stream.Context()).GetAllAssets(int(request.PageSize), func(assets []*assettypes.Asset) error {
+		for _, record := range assets {
+			if err := stream.Send(assetRecord(record)); err != nil {
+				return err
+			}
+		}
+		return stream.Context().Err()
+	})
+	if err != nil {
+		return statusError(err)
+	}
+	return nil
+}
+
+func checkPageSize(pageSize int32) error {
+	if pageSize < 1 || pageSize > asset.MaxPageSize {
+		return invalidArgument(fmt.Sprintf("page_size must be between 1 and %d", asset.MaxPageSize))
+	}
+	return nil
+}
+
+func assetResultOrError(result *assettypes.AssetResult, err error) (*pb.AssetResult, error) {
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return assetResult(result), nil
+}
+
+func assetResult(result *assettypes.AssetResult) *pb.AssetResult {
+	return &pb.AssetResult{
+		Status:    result.Status,
+		TxId:      result.TxID,
+		Timestamp: timestamppb.New(result.Timestamp),
+		Asset:     assetRecord(result.Asset),
+	}
+}
+
+func assetRecord(record *assettypes.Asset) *pb.AssetRecord {
+	if record == nil {
+		return nil
+	}
+	return &pb.AssetRecord{
+		AssetId:           record.ID,
+		OwnerOrg:          record.OwnerOrg,
+		PublicDescription: record.PublicDescription,
+	}
+}
+
+func assetProperties(assetID string, properties *pb.AssetProperties) *assettypes.AssetProperties {
+	return &assettypes.AssetProperties{
+		ObjectType:     "asset_properties",
+		ID:             assetID,
+		Color:          properties.Color,
+		Size:           int(properties.Size),
+		AppraisedValue: assettypes.Decimal(properties.AppraisedValue),
+		Salt:           properties.Salt,
+	}
+}
+
+func propertiesRecord(properties *assettypes.AssetProperties) *pb.AssetProperties {
+	return &pb.AssetProperties{
+		Color:          properties.Color,
+		Size:           int64(properties.Size),
+		AppraisedValue: string(properties.AppraisedValue),
+		Salt:           properties.Salt,
+	}
+}
+
+func agreement(assetID string, price int64, tradeID string) *assettypes.Agreement {
+	return &assettypes.Agreement{ID: assetID, Price: int(price), TradeID: tradeID}
+}
@@ -0,0 +1,58 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"github.com/hyperledger/fabric-samples/grpc-api-go/pb"
+	"github.com/hyperledger/fabric-samples/internal/appclient"
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil/errcode"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// grpcCodes are the gRPC status codes of the chaincode and Gateway error codes. Codes not listed,
+// such as CORRUPT_STATE and INTERNAL, are Internal.
+var grpcCodes = map[errcode.Code]codes.Code{
+	errcode.CodeInvalidArgument:       codes.InvalidArgument,
+	errcode.CodeAmountOverflow:        codes.InvalidArgument,
+	errcode.CodeUnknownTransaction:    codes.InvalidArgument,
+	errcode.CodeNotAuthorized:         codes.PermissionDenied,
+	errcode.CodeNotFound:              codes.NotFound,
+	errcode.CodeAccountNotFound:       codes.NotFound,
+	errcode.CodeAssetNotFound:         codes.NotFound,
+	errcode.CodeAssetExists:           codes.AlreadyExists,
+	errcode.CodeInsufficientFunds:     codes.FailedPrecondition,
+	errcode.CodeInsufficientAllowance: codes.FailedPrecondition,
+	errcode.CodeAgreementMismatch:     codes.FailedPrecondition,
+	errcode.CodeQuotaExceeded:         codes.ResourceExhausted,
+	// Aborted tells gRPC clients the call can be retried
+	appclient.CodeCommitFailed: codes.Aborted,
+	appclient.CodeUnavailable:  codes.Unavailable,
+	appclient.CodeTimeout:      codes.DeadlineExceeded,
+}
+
+// statusError returns the gRPC status error of an error returned by a contract call. The coded
+// error of the chaincode is attached to the status details as a pb.ChaincodeError.
+func statusError(err error) error {
+	if err == nil {
+		return nil
+	}
+	codedErr := appclient.ParseError(err)
+	code, ok := grpcCodes[errcode.Code(codedErr.Code)]
+	if !ok {
+		code = codes.Internal
+	}
+	grpcStatus := status.New(code, codedErr.Message)
+	detailed, detailErr := grpcStatus.WithDetails(&pb.ChaincodeError{Code: codedErr.Code, Message: codedErr.Message})
+	if detailErr != nil {
+		return grpcStatus.Err()
+	}
+	return detailed.Err()
+}
+
+// invalidArgument returns an INVALID_ARGUMENT error for a request the service rejects itself
+func invalidArgument(message string) error {
+	return statusError(&appclient.Error{Code: string(errcode.CodeInvalidArgument), Message: message})
+}
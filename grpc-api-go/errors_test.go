@@ -0,0 +1,42 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hyperledger/fabric-samples/grpc-api-go/pb"
+	"github.com/hyperledger/fabric-samples/internal/appclient"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestStatusError(t *testing.T) {
+	for _, test := range []struct {
+		err  error
+		code codes.Code
+	}{
+		{&appclient.Error{Code: "INSUFFICIENT_FUNDS", Message: "client account has insufficient funds"}, codes.FailedPrecondition},
+		{&appclient.Error{Code: "ASSET_NOT_FOUND", Message: "asset1 does not exist"}, codes.NotFound},
+		{&appclient.Error{Code: appclient.CodeCommitFailed, Message: "MVCC_READ_CONFLICT"}, codes.Aborted},
+		{errors.New("connection closed"), codes.Internal},
+	} {
+		grpcStatus, ok := status.FromError(statusError(test.err))
+		if !ok {
+			t.Fatalf("%v did not become a status error", test.err)
+		}
+		if grpcStatus.Code() != test.code {
+			t.Errorf("%v has code %v, want %v", test.err, grpcStatus.Code(), test.code)
+		}
+		details := grpcStatus.Details()
+		if len(details) != 1 {
+			t.Fatalf("%v has %d details", test.err, len(details))
+		}
+		if chaincodeErr, ok := details[0].(*pb.ChaincodeError); !ok || chaincodeErr.Code != appclient.ParseError(test.err).Code {
+			t.Errorf("%v has details %v", test.err, details[0])
+		}
+	}
+
+	if statusError(nil) != nil {
+		t.Error("nil error became a status error")
+	}
+}
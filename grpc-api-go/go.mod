@@ -0,0 +1,61 @@
+module github.com/hyperledger/fabric-samples/grpc-api-go
+
+go 1.18
+
+require (
+	github.com/hyperledger/fabric-samples/asset-transfer-secured-agreement/application-go v0.0.0
+	github.com/hyperledger/fabric-samples/asset-transfer-secured-agreement/assettypes v0.0.0
+	github.com/hyperledger/fabric-samples/internal/appclient v0.0.0
+	github.com/hyperledger/fabric-samples/internal/ledgerutil v0.0.0
+	github.com/hyperledger/fabric-samples/internal/metrics v0.0.0
+	github.com/hyperledger/fabric-samples/internal/tracing v0.0.0
+	github.com/hyperledger/fabric-samples/token-erc-20/application-go v0.0.0
+	google.golang.org/grpc v1.59.0
+	google.golang.org/protobuf v1.31.0
+)
+
+require (
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/cenkalti/backoff/v4 v4.1.3 // indirect
+	github.com/cespare/xxhash/v2 v2.2.0 // indirect
+	github.com/felixge/httpsnoop v1.0.3 // indirect
+	github.com/go-logr/logr v1.2.3 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/golang/protobuf v1.5.3 // indirect
+	github.com/grpc-ecosystem/grpc-gateway/v2 v2.7.0 // indirect
+	github.com/hyperledger/fabric-gateway v1.1.1 // indirect
+	github.com/hyperledger/fabric-protos-go-apiv2 v0.0.0-20220615102044-467be1c7b2e7 // indirect
+	github.com/matttproud/golang_protobuf_extensions v1.0.1 // indirect
+	github.com/miekg/pkcs11 v1.1.1 // indirect
+	github.com/prometheus/client_golang v1.14.0 // indirect
+	github.com/prometheus/client_model v0.3.0 // indirect
+	github.com/prometheus/common v0.37.0 // indirect
+	github.com/prometheus/procfs v0.8.0 // indirect
+	github.com/stretchr/testify v1.8.4 // indirect
+	go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc v0.35.0 // indirect
+	go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp v0.35.0 // indirect
+	go.opentelemetry.io/otel v1.10.0 // indirect
+	go.opentelemetry.io/otel/exporters/otlp/internal/retry v1.10.0 // indirect
+	go.opentelemetry.io/otel/exporters/otlp/otlptrace v1.10.0 // indirect
+	go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc v1.10.0 // indirect
+	go.opentelemetry.io/otel/metric v0.31.0 // indirect
+	go.opentelemetry.io/otel/sdk v1.10.0 // indirect
+	go.opentelemetry.io/otel/trace v1.10.0 // indirect
+	go.opentelemetry.io/proto/otlp v0.19.0 // indirect
+	golang.org/x/net v0.17.0 // indirect
+	golang.org/x/sys v0.14.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
+	google.golang.org/genproto v0.0.0-20231016165738-49dd2c1f3d0b // indirect
+	google.golang.org/genproto/googleapis/api v0.0.0-20231012201019-e917dd12ba7a // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20231030173426-d783a09b4405 // indirect
+)
+
+replace (
+	github.com/hyperledger/fabric-samples/asset-transfer-secured-agreement/application-go => ../asset-transfer-secured-agreement/application-go
+	github.com/hyperledger/fabric-samples/asset-transfer-secured-agreement/assettypes => ../asset-transfer-secured-agreement/assettypes
+	github.com/hyperledger/fabric-samples/internal/appclient => ../internal/appclient
+	github.com/hyperledger/fabric-samples/internal/ledgerutil => ../internal/ledgerutil
+	github.com/hyperledger/fabric-samples/internal/metrics => ../internal/metrics
+	github.com/hyperledger/fabric-samples/internal/tracing => ../internal/tracing
+	github.com/hyperledger/fabric-samples/token-erc-20/application-go => ../token-erc-20/application-go
+)
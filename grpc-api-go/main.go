@@ -0,0 +1,120 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// grpc-api-go serves the Token and Asset gRPC services defined in protos, calling the token and
+// secured agreement asset chaincodes through the Fabric Gateway as one identity of a wallet.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-secured-agreement/application-go/asset"
+	"github.com/hyperledger/fabric-samples/grpc-api-go/pb"
+	"github.com/hyperledger/fabric-samples/internal/appclient"
+	"github.com/hyperledger/fabric-samples/internal/metrics"
+	"github.com/hyperledger/fabric-samples/internal/tracing"
+	"github.com/hyperledger/fabric-samples/token-erc-20/application-go/token"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+var (
+	listen         = flag.String("listen", ":9090", "address the gRPC services listen on")
+	walletDir      = flag.String("wallet", "wallet", "wallet directory holding the identity")
+	label          = flag.String("identity", "", "label of the wallet identity the services call the chaincodes as")
+	profilePath    = flag.String("profile", "", "connection profile of the identity's org")
+	peer           = flag.String("peer", "", "peer of the profile to connect to, the first peer of the org by default")
+	channel        = flag.String("channel", "mychannel", "channel the chaincodes are deployed on")
+	tokenChaincode = flag.String("token-chaincode", "token_erc20", "name the token chaincode is deployed as")
+	assetChaincode = flag.String("asset-chaincode", "secured", "name the asset chaincode is deployed as")
+	tlsCert        = flag.String("tls-cert", "", "TLS certificate of the services, plaintext when empty")
+	tlsKey         = flag.String("tls-key", "", "TLS private key of the services")
+	metricsListen  = flag.String("metrics-listen", "", "address Prometheus metrics are served on at /metrics, none when empty")
+)
+
+func main() {
+	flag.Parse()
+	if *label == "" || *profilePath == "" {
+		log.Fatal("-identity and -profile are required")
+	}
+
+	shutdownTracing, err := tracing.Start(context.Background(), "grpc-api-go")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer shutdownTracing(context.Background())
+
+	profile, err := appclient.LoadProfile(*profilePath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	wallet, err := appclient.OpenWallet(*walletDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	connection, err := appclient.ConnectWallet(profile, *peer, wallet, *label)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer connection.Close()
+	network := connection.GetNetwork(*channel)
+
+	options := tracing.ServerOptions()
+	if *tlsCert != "" {
+		transportCredentials, err := credentials.NewServerTLSFromFile(*tlsCert, *tlsKey)
+		if err != nil {
+			log.Fatalf("failed to load TLS credentials: %v", err)
+		}
+		options = append(options, grpc.Creds(transportCredentials))
+	}
+	server := grpc.NewServer(options...)
+	tokenContract := token.NewContract(network, *tokenChaincode)
+	tokenContract.SetObserver(metrics.ObserveCall)
+	assetContract := asset.NewContract(network, *assetChaincode, profile.MSPID())
+	assetContract.SetObserver(metrics.ObserveCall)
+	pb.RegisterTokenServer(server, &tokenServer{contract: tokenContract})
+	pb.RegisterAssetServer(server, &assetServer{contract: assetContract})
+
+	if *metricsListen != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler())
+		go func() {
+			log.Fatal(http.ListenAndServe(*metricsListen, mux))
+		}()
+	}
+
+	listener, err := net.Listen("tcp", *listen)
+	if err != nil {
+		log.Fatalf("failed to listen: %v", err)
+	}
+	go func() {
+		interrupt := make(chan os.Signal, 1)
+		signal.Notify(interrupt, os.Interrupt)
+		<-interrupt
+		// event streams run until their clients cancel, so stop them after a grace period
+		stopped := make(chan struct{})
+		go func() {
+			server.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-time.After(10 * time.Second):
+			server.Stop()
+		}
+	}()
+
+	log.Printf("serving as %s of %s on %s", *label, profile.MSPID(), *listen)
+	err = server.Serve(listener)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
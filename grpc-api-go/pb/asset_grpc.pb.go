@@ -0,0 +1,541 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             v3.21.12
+// source: asset.proto
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// AssetClient is the client API for Asset service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type AssetClient interface {
+	// CreateAsset creates an asset owned by the service's org and returns its properties, which the
+	// owner shares with buyers for inspection
+	CreateAsset(ctx context.Context, in *CreateAssetRequest, opts ...grpc.CallOption) (*CreateAssetResult, error)
+	UpdateAsset(ctx context.Context, in *UpdateAssetRequest, opts ...grpc.CallOption) (*AssetResult, error)
+	AgreeToSell(ctx context.Context, in *AgreementRequest, opts ...grpc.CallOption) (*AssetResult, error)
+	AgreeToBuy(ctx context.Context, in *AgreementRequest, opts ...grpc.CallOption) (*AssetResult, error)
+	// SetInspection checks properties shared by the seller against the hash on the ledger
+	SetInspection(ctx context.Context, in *InspectionRequest, opts ...grpc.CallOption) (*InspectionResponse, error)
+	// TransferAsset sells an asset of the service's org at the agreed price, reading its properties
+	// from the org's private data
+	TransferAsset(ctx context.Context, in *TransferAssetRequest, opts ...grpc.CallOption) (*AssetResult, error)
+	ReadAsset(ctx context.Context, in *AssetRequest, opts ...grpc.CallOption) (*AssetRecord, error)
+	GetAssetPrivateProperties(ctx context.Context, in *AssetRequest, opts ...grpc.CallOption) (*AssetProperties, error)
+	GetAssetReceipts(ctx context.Context, in *AssetRequest, opts ...grpc.CallOption) (*ReceiptList, error)
+	QueryAssetHistory(ctx context.Context, in *AssetRequest, opts ...grpc.CallOption) (*HistoryList, error)
+	GetAssetsPage(ctx context.Context, in *AssetsPageRequest, opts ...grpc.CallOption) (*AssetsPage, error)
+	// ListAssets streams every asset, reading page_size assets at a time
+	ListAssets(ctx context.Context, in *ListAssetsRequest, opts ...grpc.CallOption) (Asset_ListAssetsClient, error)
+}
+
+type assetClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAssetClient(cc grpc.ClientConnInterface) AssetClient {
+	return &assetClient{cc}
+}
+
+func (c *assetClient) CreateAsset(ctx context.Context, in *CreateAssetRequest, opts ...grpc.CallOption) (*CreateAssetResult, error) {
+	out := new(CreateAssetResult)
+	err := c.cc.Invoke(ctx, "/fabricsamples.v1.Asset/CreateAsset", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *assetClient) UpdateAsset(ctx context.Context, in *UpdateAssetRequest, opts ...grpc.CallOption) (*AssetResult, error) {
+	out := new(AssetResult)
+	err := c.cc.Invoke(ctx, "/fabricsamples.v1.Asset/UpdateAsset", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *assetClient) AgreeToSell(ctx context.Context, in *AgreementRequest, opts ...grpc.CallOption) (*AssetResult, error) {
+	out := new(AssetResult)
+	err := c.cc.Invoke(ctx, "/fabricsamples.v1.Asset/AgreeToSell", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *assetClient) AgreeToBuy(ctx context.Context, in *AgreementRequest, opts ...grpc.CallOption) (*AssetResult, error) {
+	out := new(AssetResult)
+	err := c.cc.Invoke(ctx, "/fabricsamples.v1.Asset/AgreeToBuy", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *assetClient) SetInspection(ctx context.Context, in *InspectionRequest, opts ...grpc.CallOption) (*InspectionResponse, error) {
+	out := new(InspectionResponse)
+	err := c.cc.Invoke(ctx, "/fabricsamples.v1.Asset/SetInspection", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *assetClient) TransferAsset(ctx context.Context, in *TransferAssetRequest, opts ...grpc.CallOption) (*AssetResult, error) {
+	out := new(AssetResult)
+	err := c.cc.Invoke(ctx, "/fabricsamples.v1.Asset/TransferAsset", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *assetClient) ReadAsset(ctx context.Context, in *AssetRequest, opts ...grpc.CallOption) (*AssetRecord, error) {
+	out := new(AssetRecord)
+	err := c.cc.Invoke(ctx, "/fabricsamples.v1.Asset/ReadAsset", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *assetClient) GetAssetPrivateProperties(ctx context.Context, in *AssetRequest, opts ...grpc.CallOption) (*AssetProperties, error) {
+	out := new(AssetProperties)
+	err := c.cc.Invoke(ctx, "/fabricsamples.v1.Asset/GetAssetPrivateProperties", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *assetClient) GetAssetReceipts(ctx context.Context, in *AssetRequest, opts ...grpc.CallOption) (*ReceiptList, error) {
+	out := new(ReceiptList)
+	err := c.cc.Invoke(ctx, "/fabricsamples.v1.Asset/GetAssetReceipts", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *assetClient) QueryAssetHistory(ctx context.Context, in *AssetRequest, opts ...grpc.CallOption) (*HistoryList, error) {
+	out := new(HistoryList)
+	err := c.cc.Invoke(ctx, "/fabricsamples.v1.Asset/QueryAssetHistory", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *assetClient) GetAssetsPage(ctx context.Context, in *AssetsPageRequest, opts ...grpc.CallOption) (*AssetsPage, error) {
+	out := new(AssetsPage)
+	err := c.cc.Invoke(ctx, "/fabricsamples.v1.Asset/GetAssetsPage", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *assetClient) ListAssets(ctx context.Context, in *ListAssetsRequest, opts ...grpc.CallOption) (Asset_ListAssetsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Asset_ServiceDesc.Streams[0], "/fabricsamples.v1.Asset/ListAssets", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &assetListAssetsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Asset_ListAssetsClient interface {
+	Recv() (*AssetRecord, error)
+	grpc.ClientStream
+}
+
+type assetListAssetsClient struct {
+	grpc.ClientStream
+}
+
+func (x *assetListAssetsClient) Recv() (*AssetRecord, error) {
+	m := new(AssetRecord)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// AssetServer is the server API for Asset service.
+// All implementations must embed UnimplementedAssetServer
+// for forward compatibility
+type AssetServer interface {
+	// CreateAsset creates an asset owned by the service's org and returns its properties, which the
+	// owner shares with buyers for inspection
+	CreateAsset(context.Context, *CreateAssetRequest) (*CreateAssetResult, error)
+	UpdateAsset(context.Context, *UpdateAssetRequest) (*AssetResult, error)
+	AgreeToSell(context.Context, *AgreementRequest) (*AssetResult, error)
+	AgreeToBuy(context.Context, *AgreementRequest) (*AssetResult, error)
+	// SetInspection checks properties shared by the seller against the hash on the ledger
+	SetInspection(context.Context, *InspectionRequest) (*InspectionResponse, error)
+	// TransferAsset sells an asset of the service's org at the agreed price, reading its properties
+	// from the org's private data
+	TransferAsset(context.Context, *TransferAssetRequest) (*AssetResult, error)
+	ReadAsset(context.Context, *AssetRequest) (*AssetRecord, error)
+	GetAssetPrivateProperties(context.Context, *AssetRequest) (*AssetProperties, error)
+	GetAssetReceipts(context.Context, *AssetRequest) (*ReceiptList, error)
+	QueryAssetHistory(context.Context, *AssetRequest) (*HistoryList, error)
+	GetAssetsPage(context.Context, *AssetsPageRequest) (*AssetsPage, error)
+	// ListAssets streams every asset, reading page_size assets at a time
+	ListAssets(*ListAssetsRequest, Asset_ListAssetsServer) error
+	mustEmbedUnimplementedAssetServer()
+}
+
+// UnimplementedAssetServer must be embedded to have forward compatible implementations.
+type UnimplementedAssetServer struct {
+}
+
+func (UnimplementedAssetServer) CreateAsset(context.Context, *CreateAssetRequest) (*CreateAssetResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateAsset not implemented")
+}
+func (UnimplementedAssetServer) UpdateAsset(context.Context, *UpdateAssetRequest) (*AssetResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateAsset not implemented")
+}
+func (UnimplementedAssetServer) AgreeToSell(context.Context, *AgreementRequest) (*AssetResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AgreeToSell not implemented")
+}
+func (UnimplementedAssetServer) AgreeToBuy(context.Context, *AgreementRequest) (*AssetResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AgreeToBuy not implemented")
+}
+func (UnimplementedAssetServer) SetInspection(context.Context, *InspectionRequest) (*InspectionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetInspection not implemented")
+}
+func (UnimplementedAssetServer) TransferAsset(context.Context, *TransferAssetRequest) (*AssetResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TransferAsset not implemented")
+}
+func (UnimplementedAssetServer) ReadAsset(context.Context, *AssetRequest) (*AssetRecord, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReadAsset not implemented")
+}
+func (UnimplementedAssetServer) GetAssetPrivateProperties(context.Context, *AssetRequest) (*AssetProperties, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAssetPrivateProperties not implemented")
+}
+func (UnimplementedAssetServer) GetAssetReceipts(context.Context, *AssetRequest) (*ReceiptList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAssetReceipts not implemented")
+}
+func (UnimplementedAssetServer) QueryAssetHistory(context.Context, *AssetRequest) (*HistoryList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method QueryAssetHistory not implemented")
+}
+func (UnimplementedAssetServer) GetAssetsPage(context.Context, *AssetsPageRequest) (*AssetsPage, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAssetsPage not implemented")
+}
+func (UnimplementedAssetServer) ListAssets(*ListAssetsRequest, Asset_ListAssetsServer) error {
+	return status.Errorf(codes.Unimplemented, "method ListAssets not implemented")
+}
+func (UnimplementedAssetServer) mustEmbedUnimplementedAssetServer() {}
+
+// UnsafeAssetServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AssetServer will
+// result in compilation errors.
+type UnsafeAssetServer interface {
+	mustEmbedUnimplementedAssetServer()
+}
+
+func RegisterAssetServer(s grpc.ServiceRegistrar, srv AssetServer) {
+	s.RegisterService(&Asset_ServiceDesc, srv)
+}
+
+func _Asset_CreateAsset_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateAssetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AssetServer).CreateAsset(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/fabricsamples.v1.Asset/CreateAsset",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AssetServer).CreateAsset(ctx, req.(*CreateAssetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Asset_UpdateAsset_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateAssetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AssetServer).UpdateAsset(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/fabricsamples.v1.Asset/UpdateAsset",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AssetServer).UpdateAsset(ctx, req.(*UpdateAssetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Asset_AgreeToSell_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AgreementRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AssetServer).AgreeToSell(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/fabricsamples.v1.Asset/AgreeToSell",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AssetServer).AgreeToSell(ctx, req.(*AgreementRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Asset_AgreeToBuy_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AgreementRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AssetServer).AgreeToBuy(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/fabricsamples.v1.Asset/AgreeToBuy",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AssetServer).AgreeToBuy(ctx, req.(*AgreementRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Asset_SetInspection_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InspectionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AssetServer).SetInspection(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/fabricsamples.v1.Asset/SetInspection",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AssetServer).SetInspection(ctx, req.(*InspectionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Asset_TransferAsset_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TransferAssetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AssetServer).TransferAsset(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/fabricsamples.v1.Asset/TransferAsset",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AssetServer).TransferAsset(ctx, req.(*TransferAssetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Asset_ReadAsset_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AssetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AssetServer).ReadAsset(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/fabricsamples.v1.Asset/ReadAsset",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AssetServer).ReadAsset(ctx, req.(*AssetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Asset_GetAssetPrivateProperties_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AssetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AssetServer).GetAssetPrivateProperties(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/fabricsamples.v1.Asset/GetAssetPrivateProperties",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AssetServer).GetAssetPrivateProperties(ctx, req.(*AssetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Asset_GetAssetReceipts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AssetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AssetServer).GetAssetReceipts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/fabricsamples.v1.Asset/GetAssetReceipts",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AssetServer).GetAssetReceipts(ctx, req.(*AssetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Asset_QueryAssetHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AssetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AssetServer).QueryAssetHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/fabricsamples.v1.Asset/QueryAssetHistory",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AssetServer).QueryAssetHistory(ctx, req.(*AssetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Asset_GetAssetsPage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AssetsPageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AssetServer).GetAssetsPage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/fabricsamples.v1.Asset/GetAssetsPage",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AssetServer).GetAssetsPage(ctx, req.(*AssetsPageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Asset_ListAssets_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListAssetsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AssetServer).ListAssets(m, &assetListAssetsServer{stream})
+}
+
+type Asset_ListAssetsServer interface {
+	Send(*AssetRecord) error
+	grpc.ServerStream
+}
+
+type assetListAssetsServer struct {
+	grpc.ServerStream
+}
+
+func (x *assetListAssetsServer) Send(m *AssetRecord) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// Asset_ServiceDesc is the grpc.ServiceDesc for Asset service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Asset_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "fabricsamples.v1.Asset",
+	HandlerType: (*AssetServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateAsset",
+			Handler:    _Asset_CreateAsset_Handler,
+		},
+		{
+			MethodName: "UpdateAsset",
+			Handler:    _Asset_UpdateAsset_Handler,
+		},
+		{
+			MethodName: "AgreeToSell",
+			Handler:    _Asset_AgreeToSell_Handler,
+		},
+		{
+			MethodName: "AgreeToBuy",
+			Handler:    _Asset_AgreeToBuy_Handler,
+		},
+		{
+			MethodName: "SetInspection",
+			Handler:    _Asset_SetInspection_Handler,
+		},
+		{
+			MethodName: "TransferAsset",
+			Handler:    _Asset_TransferAsset_Handler,
+		},
+		{
+			MethodName: "ReadAsset",
+			Handler:    _Asset_ReadAsset_Handler,
+		},
+		{
+			MethodName: "GetAssetPrivateProperties",
+			Handler:    _Asset_GetAssetPrivateProperties_Handler,
+		},
+		{
+			MethodName: "GetAssetReceipts",
+			Handler:    _Asset_GetAssetReceipts_Handler,
+		},
+		{
+			MethodName: "QueryAssetHistory",
+			Handler:    _Asset_QueryAssetHistory_Handler,
+		},
+		{
+			MethodName: "GetAssetsPage",
+			Handler:    _Asset_GetAssetsPage_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ListAssets",
+			Handler:       _Asset_ListAssets_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "asset.proto",
+}
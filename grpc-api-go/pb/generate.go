@@ -0,0 +1,10 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package pb holds the Go code generated from the protobuf definitions in ../protos. Run go generate
+// in this directory after changing them, with protoc, protoc-gen-go and protoc-gen-go-grpc on the
+// PATH.
+package pb
+
+//go:generate protoc --proto_path=../protos --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative token.proto asset.proto errors.proto
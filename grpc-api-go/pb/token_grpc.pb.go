@@ -0,0 +1,461 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             v3.21.12
+// source: token.proto
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// TokenClient is the client API for Token service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type TokenClient interface {
+	Mint(ctx context.Context, in *MintRequest, opts ...grpc.CallOption) (*TokenResult, error)
+	Burn(ctx context.Context, in *BurnRequest, opts ...grpc.CallOption) (*TokenResult, error)
+	Transfer(ctx context.Context, in *TransferRequest, opts ...grpc.CallOption) (*TokenResult, error)
+	Approve(ctx context.Context, in *ApproveRequest, opts ...grpc.CallOption) (*TokenResult, error)
+	TransferFrom(ctx context.Context, in *TransferFromRequest, opts ...grpc.CallOption) (*TokenResult, error)
+	BalanceOf(ctx context.Context, in *BalanceOfRequest, opts ...grpc.CallOption) (*BalanceOfResponse, error)
+	Allowance(ctx context.Context, in *AllowanceRequest, opts ...grpc.CallOption) (*AllowanceResponse, error)
+	ClientAccountID(ctx context.Context, in *ClientAccountIDRequest, opts ...grpc.CallOption) (*ClientAccountIDResponse, error)
+	GetAuditRecord(ctx context.Context, in *GetAuditRecordRequest, opts ...grpc.CallOption) (*AuditRecord, error)
+	// Events streams the Transfer and Approval events committed from start_block on, or from the
+	// next block when it is unset, until the call is cancelled
+	Events(ctx context.Context, in *EventsRequest, opts ...grpc.CallOption) (Token_EventsClient, error)
+}
+
+type tokenClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewTokenClient(cc grpc.ClientConnInterface) TokenClient {
+	return &tokenClient{cc}
+}
+
+func (c *tokenClient) Mint(ctx context.Context, in *MintRequest, opts ...grpc.CallOption) (*TokenResult, error) {
+	out := new(TokenResult)
+	err := c.cc.Invoke(ctx, "/fabricsamples.v1.Token/Mint", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tokenClient) Burn(ctx context.Context, in *BurnRequest, opts ...grpc.CallOption) (*TokenResult, error) {
+	out := new(TokenResult)
+	err := c.cc.Invoke(ctx, "/fabricsamples.v1.Token/Burn", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tokenClient) Transfer(ctx context.Context, in *TransferRequest, opts ...grpc.CallOption) (*TokenResult, error) {
+	out := new(TokenResult)
+	err := c.cc.Invoke(ctx, "/fabricsamples.v1.Token/Transfer", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tokenClient) Approve(ctx context.Context, in *ApproveRequest, opts ...grpc.CallOption) (*TokenResult, error) {
+	out := new(TokenResult)
+	err := c.cc.Invoke(ctx, "/fabricsamples.v1.Token/Approve", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tokenClient) TransferFrom(ctx context.Context, in *TransferFromRequest, opts ...grpc.CallOption) (*TokenResult, error) {
+	out := new(TokenResult)
+	err := c.cc.Invoke(ctx, "/fabricsamples.v1.Token/TransferFrom", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tokenClient) BalanceOf(ctx context.Context, in *BalanceOfRequest, opts ...grpc.CallOption) (*BalanceOfResponse, error) {
+	out := new(BalanceOfResponse)
+	err := c.cc.Invoke(ctx, "/fabricsamples.v1.Token/BalanceOf", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tokenClient) Allowance(ctx context.Context, in *AllowanceRequest, opts ...grpc.CallOption) (*AllowanceResponse, error) {
+	out := new(AllowanceResponse)
+	err := c.cc.Invoke(ctx, "/fabricsamples.v1.Token/Allowance", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tokenClient) ClientAccountID(ctx context.Context, in *ClientAccountIDRequest, opts ...grpc.CallOption) (*ClientAccountIDResponse, error) {
+	out := new(ClientAccountIDResponse)
+	err := c.cc.Invoke(ctx, "/fabricsamples.v1.Token/ClientAccountID", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tokenClient) GetAuditRecord(ctx context.Context, in *GetAuditRecordRequest, opts ...grpc.CallOption) (*AuditRecord, error) {
+	out := new(AuditRecord)
+	err := c.cc.Invoke(ctx, "/fabricsamples.v1.Token/GetAuditRecord", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tokenClient) Events(ctx context.Context, in *EventsRequest, opts ...grpc.CallOption) (Token_EventsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Token_ServiceDesc.Streams[0], "/fabricsamples.v1.Token/Events", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &tokenEventsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Token_EventsClient interface {
+	Recv() (*TokenEvent, error)
+	grpc.ClientStream
+}
+
+type tokenEventsClient struct {
+	grpc.ClientStream
+}
+
+func (x *tokenEventsClient) Recv() (*TokenEvent, error) {
+	m := new(TokenEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// TokenServer is the server API for Token service.
+// All implementations must embed UnimplementedTokenServer
+// for forward compatibility
+type TokenServer interface {
+	Mint(context.Context, *MintRequest) (*TokenResult, error)
+	Burn(context.Context, *BurnRequest) (*TokenResult, error)
+	Transfer(context.Context, *TransferRequest) (*TokenResult, error)
+	Approve(context.Context, *ApproveRequest) (*TokenResult, error)
+	TransferFrom(context.Context, *TransferFromRequest) (*TokenResult, error)
+	BalanceOf(context.Context, *BalanceOfRequest) (*BalanceOfResponse, error)
+	Allowance(context.Context, *AllowanceRequest) (*AllowanceResponse, error)
+	ClientAccountID(context.Context, *ClientAccountIDRequest) (*ClientAccountIDResponse, error)
+	GetAuditRecord(context.Context, *GetAuditRecordRequest) (*AuditRecord, error)
+	// Events streams the Transfer and Approval events committed from start_block on, or from the
+	// next block when it is unset, until the call is cancelled
+	Events(*EventsRequest, Token_EventsServer) error
+	mustEmbedUnimplementedTokenServer()
+}
+
+// UnimplementedTokenServer must be embedded to have forward compatible implementations.
+type UnimplementedTokenServer struct {
+}
+
+func (UnimplementedTokenServer) Mint(context.Context, *MintRequest) (*TokenResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Mint not implemented")
+}
+func (UnimplementedTokenServer) Burn(context.Context, *BurnRequest) (*TokenResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Burn not implemented")
+}
+func (UnimplementedTokenServer) Transfer(context.Context, *TransferRequest) (*TokenResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Transfer not implemented")
+}
+func (UnimplementedTokenServer) Approve(context.Context, *ApproveRequest) (*TokenResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Approve not implemented")
+}
+func (UnimplementedTokenServer) TransferFrom(context.Context, *TransferFromRequest) (*TokenResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TransferFrom not implemented")
+}
+func (UnimplementedTokenServer) BalanceOf(context.Context, *BalanceOfRequest) (*BalanceOfResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BalanceOf not implemented")
+}
+func (UnimplementedTokenServer) Allowance(context.Context, *AllowanceRequest) (*AllowanceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Allowance not implemented")
+}
+func (UnimplementedTokenServer) ClientAccountID(context.Context, *ClientAccountIDRequest) (*ClientAccountIDResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ClientAccountID not implemented")
+}
+func (UnimplementedTokenServer) GetAuditRecord(context.Context, *GetAuditRecordRequest) (*AuditRecord, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAuditRecord not implemented")
+}
+func (UnimplementedTokenServer) Events(*EventsRequest, Token_EventsServer) error {
+	return status.Errorf(codes.Unimplemented, "method Events not implemented")
+}
+func (UnimplementedTokenServer) mustEmbedUnimplementedTokenServer() {}
+
+// UnsafeTokenServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to TokenServer will
+// result in compilation errors.
+type UnsafeTokenServer interface {
+	mustEmbedUnimplementedTokenServer()
+}
+
+func RegisterTokenServer(s grpc.ServiceRegistrar, srv TokenServer) {
+	s.RegisterService(&Token_ServiceDesc, srv)
+}
+
+func _Token_Mint_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MintRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TokenServer).Mint(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/fabricsamples.v1.Token/Mint",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TokenServer).Mint(ctx, req.(*MintRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Token_Burn_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BurnRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TokenServer).Burn(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/fabricsamples.v1.Token/Burn",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TokenServer).Burn(ctx, req.(*BurnRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Token_Transfer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TransferRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TokenServer).Transfer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/fabricsamples.v1.Token/Transfer",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TokenServer).Transfer(ctx, req.(*TransferRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Token_Approve_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ApproveRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TokenServer).Approve(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/fabricsamples.v1.Token/Approve",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TokenServer).Approve(ctx, req.(*ApproveRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Token_TransferFrom_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TransferFromRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TokenServer).TransferFrom(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/fabricsamples.v1.Token/TransferFrom",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TokenServer).TransferFrom(ctx, req.(*TransferFromRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Token_BalanceOf_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BalanceOfRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TokenServer).BalanceOf(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/fabricsamples.v1.Token/BalanceOf",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TokenServer).BalanceOf(ctx, req.(*BalanceOfRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Token_Allowance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AllowanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TokenServer).Allowance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/fabricsamples.v1.Token/Allowance",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TokenServer).Allowance(ctx, req.(*AllowanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Token_ClientAccountID_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ClientAccountIDRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TokenServer).ClientAccountID(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/fabricsamples.v1.Token/ClientAccountID",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TokenServer).ClientAccountID(ctx, req.(*ClientAccountIDRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Token_GetAuditRecord_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAuditRecordRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TokenServer).GetAuditRecord(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/fabricsamples.v1.Token/GetAuditRecord",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TokenServer).GetAuditRecord(ctx, req.(*GetAuditRecordRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Token_Events_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(EventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(TokenServer).Events(m, &tokenEventsServer{stream})
+}
+
+type Token_EventsServer interface {
+	Send(*TokenEvent) error
+	grpc.ServerStream
+}
+
+type tokenEventsServer struct {
+	grpc.ServerStream
+}
+
+func (x *tokenEventsServer) Send(m *TokenEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// Token_ServiceDesc is the grpc.ServiceDesc for Token service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Token_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "fabricsamples.v1.Token",
+	HandlerType: (*TokenServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Mint",
+			Handler:    _Token_Mint_Handler,
+		},
+		{
+			MethodName: "Burn",
+			Handler:    _Token_Burn_Handler,
+		},
+		{
+			MethodName: "Transfer",
+			Handler:    _Token_Transfer_Handler,
+		},
+		{
+			MethodName: "Approve",
+			Handler:    _Token_Approve_Handler,
+		},
+		{
+			MethodName: "TransferFrom",
+			Handler:    _Token_TransferFrom_Handler,
+		},
+		{
+			MethodName: "BalanceOf",
+			Handler:    _Token_BalanceOf_Handler,
+		},
+		{
+			MethodName: "Allowance",
+			Handler:    _Token_Allowance_Handler,
+		},
+		{
+			MethodName: "ClientAccountID",
+			Handler:    _Token_ClientAccountID_Handler,
+		},
+		{
+			MethodName: "GetAuditRecord",
+			Handler:    _Token_GetAuditRecord_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Events",
+			Handler:       _Token_Events_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "token.proto",
+}
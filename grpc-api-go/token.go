@@ -0,0 +1,144 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"context"
+
+	"github.com/hyperledger/fabric-samples/grpc-api-go/pb"
+	"github.com/hyperledger/fabric-samples/token-erc-20/application-go/token"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// tokenServer implements the Token service with the token contract
+type tokenServer struct {
+	pb.UnimplementedTokenServer
+	contract *token.Contract
+}
+
+func (s *tokenServer) Mint(ctx context.Context, request *pb.MintRequest) (*pb.TokenResult, error) {
+	return tokenResult(s.contract.WithContext(ctx).Mint(int(request.Amount)))
+}
+
+func (s *tokenServer) Burn(ctx context.Context, request *pb.BurnRequest) (*pb.TokenResult, error) {
+	return tokenResult(s.contract.WithContext(ctx).Burn(int(request.Amount)))
+}
+
+func (s *tokenServer) Transfer(ctx context.Context, request *pb.TransferRequest) (*pb.TokenResult, error) {
+	return tokenResult(s.contract.WithContext(ctx).Transfer(request.To, int(request.Amount)))
+}
+
+func (s *tokenServer) Approve(ctx context.Context, request *pb.ApproveRequest) (*pb.TokenResult, error) {
+	return tokenResult(s.contract.WithContext(ctx).Approve(request.Spender, int(request.Amount)))
+}
+
+func (s *tokenServer) TransferFrom(ctx context.Context, request *pb.TransferFromRequest) (*pb.TokenResult, error) {
+	return tokenResult(s.contract.WithContext(ctx).TransferFrom(request.From, request.To, int(request.Amount)))
+}
+
+func (s *tokenServer) BalanceOf(ctx context.Context, request *pb.BalanceOfRequest) (*pb.BalanceOfResponse, error) {
+	contract := s.contract.WithContext(ctx)
+	account := request.Account
+	if account == "" {
+		clientAccount, err := contract.ClientAccountID()
+		if err != nil {
+			return nil, statusError(err)
+		}
+		account = clientAccount
+	}
+	balance, err := contract.BalanceOf(account)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &pb.BalanceOfResponse{Account: account, Balance: int64(balance)}, nil
+}
+
+func (s *tokenServer) Allowance(ctx context.Context, request *pb.AllowanceRequest) (*pb.AllowanceResponse, error) {
+	allowance, err := s.contract.WithContext(ctx).Allowance(request.Owner, request.Spender)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &pb.AllowanceResponse{Allowance: int64(allowance)}, nil
+}
+
+func (s *tokenServer) ClientAccountID(ctx context.Context, request *pb.ClientAccountIDRequest) (*pb.ClientAccountIDResponse, error) {
+	account, err := s.contract.WithContext(ctx).ClientAccountID()
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &pb.ClientAccountIDResponse{Account: account}, nil
+}
+
+func (s *tokenServer) GetAuditRecord(ctx context.Context, request *pb.GetAuditRecordRequest) (*pb.AuditRecord, error) {
+	record, err := s.contract.WithContext(ctx).GetAuditRecord(request.TxId)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return auditRecord(record), nil
+}
+
+// Events streams the events of the token contract until the client cancels the call
+func (s *tokenServer) Events(request *pb.EventsRequest, stream pb.Token_EventsServer) error {
+	events, err := s.contract.Events(stream.Context(), request.StartBlock)
+	if err != nil {
+		return statusError(err)
+	}
+	for event := range events {
+		err := stream.Send(&pb.TokenEvent{
+			Name:        event.Name,
+			TxId:        event.TxID,
+			BlockNumber: event.BlockNumber,
+			From:        event.From,
+			To:          event.To,
+			Value:       int64(event.Value),
+			Audit:       auditRecord(event.Audit),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return stream.Context().Err()
+}
+
+func tokenResult(result *token.TxResult, err error) (*pb.TokenResult, error) {
+	if err != nil {
+		return nil, statusError(err)
+	}
+	response := &pb.TokenResult{
+		Status:    result.Status,
+		TxId:      result.TxID,
+		Timestamp: timestamppb.New(result.Timestamp),
+		Account:   result.Account,
+	}
+	if result.Balance != nil {
+		balance := int64(*result.Balance)
+		response.Balance = &balance
+	}
+	if result.Allowance != nil {
+		allowance := int64(*result.Allowance)
+		response.Allowance = &allowance
+	}
+	return response, nil
+}
+
+func auditRecord(record *token.AuditRecord) *pb.AuditRecord {
+	if record == nil {
+		return nil
+	}
+	response := &pb.AuditRecord{
+		TxId:      record.TxID,
+		Timestamp: timestamppb.New(record.Timestamp),
+		Event:     record.Event,
+	}
+	for _, change := range record.Changes {
+		response.Changes = append(response.Changes, &pb.AuditChange{
+			Kind:    change.Kind,
+			Subject: change.Subject,
+			Old:     int64(change.Old),
+			New:     int64(change.New),
+		})
+	}
+	return response
+}
@@ -0,0 +1,375 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil"
+)
+
+// recordsCollection is the private data collection defined in collections_config.json
+const recordsCollection = "healthRecordsCollection"
+
+// object names for prefix
+const (
+	consentPrefix = "consent"
+	recordPrefix  = "record"
+	accessPrefix  = "access"
+)
+
+// scopeAll grants access to records of every category
+const scopeAll = "all"
+
+// access event actions
+const (
+	actionWrite = "WRITE"
+	actionRead  = "READ"
+)
+
+// SmartContract provides functions for patient consent and logged access to health records
+type SmartContract struct {
+	contractapi.Contract
+}
+
+// Consent lets a provider org access a patient's records of one category, or of every category
+// with scope "all", until it is revoked or expires
+type Consent struct {
+	Patient   string `json:"patient"`
+	Provider  string `json:"provider"`
+	Scope     string `json:"scope"`
+	GrantedAt string `json:"grantedAt"`
+	Expiry    string `json:"expiry"`
+	Revoked   bool   `json:"revoked"`
+}
+
+// Record is the public metadata of a health record. The pointer to the record and its hash are
+// kept in the private data collection.
+type Record struct {
+	Patient   string `json:"patient"`
+	ID        string `json:"recordID"`
+	Category  string `json:"category"`
+	Provider  string `json:"provider"`
+	CreatedAt string `json:"createdAt"`
+}
+
+// RecordPointer is the private part of a record: where the record is stored off chain and the hash
+// that proves it has not changed
+type RecordPointer struct {
+	Pointer string `json:"pointer"`
+	Hash    string `json:"hash"`
+}
+
+// AccessEvent is an entry of a patient's access log. Entries are only ever added.
+type AccessEvent struct {
+	Patient   string `json:"patient"`
+	RecordID  string `json:"recordID"`
+	Accessor  string `json:"accessor"`
+	Provider  string `json:"provider"`
+	Action    string `json:"action"`
+	Purpose   string `json:"purpose"`
+	Timestamp string `json:"timestamp"`
+	TxID      string `json:"txID"`
+}
+
+// GrantConsent lets a provider org access the client's records in scope. expiry is an RFC3339
+// timestamp, or empty for consent that lasts until revoked. Granting again replaces the consent.
+func (s *SmartContract) GrantConsent(ctx contractapi.TransactionContextInterface, provider string, scope string, expiry string) error {
+	patient, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	if provider == "" || scope == "" {
+		return fmt.Errorf("provider and scope must be set")
+	}
+	now, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return err
+	}
+	if expiry != "" {
+		expiryTime, err := time.Parse(time.RFC3339, expiry)
+		if err != nil {
+			return fmt.Errorf("expiry %s is not an RFC3339 timestamp: %v", expiry, err)
+		}
+		if !expiryTime.After(now) {
+			return fmt.Errorf("expiry %s is in the past", expiry)
+		}
+	}
+
+	consent := Consent{
+		Patient:   patient,
+		Provider:  provider,
+		Scope:     scope,
+		GrantedAt: now.Format(time.RFC3339),
+		Expiry:    expiry,
+	}
+	return _putConsent(ctx, &consent)
+}
+
+// RevokeConsent withdraws a consent the client granted. Records already read stay in the access log.
+func (s *SmartContract) RevokeConsent(ctx contractapi.TransactionContextInterface, provider string, scope string) error {
+	patient, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	consent, err := _getConsent(ctx, patient, provider, scope)
+	if err != nil {
+		return err
+	}
+	if consent == nil || consent.Revoked {
+		return fmt.Errorf("there is no consent for %s with scope %s to revoke", provider, scope)
+	}
+
+	consent.Revoked = true
+	return _putConsent(ctx, consent)
+}
+
+// AddRecord stores a record of a patient on behalf of the client's org, which needs consent for the
+// record's category. The pointer and hash are passed in the transient field "record" as JSON and are
+// written to the private data collection; only the metadata is public.
+func (s *SmartContract) AddRecord(ctx contractapi.TransactionContextInterface, patient string, recordID string, category string) error {
+	provider, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+
+	if patient == "" || recordID == "" || category == "" {
+		return fmt.Errorf("patient, record ID and category must be set")
+	}
+
+	transientMap, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return fmt.Errorf("error getting transient: %v", err)
+	}
+	pointerJSON, ok := transientMap["record"]
+	if !ok {
+		return fmt.Errorf("record key not found in the transient map")
+	}
+	var pointer RecordPointer
+	err = json.Unmarshal(pointerJSON, &pointer)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal record: %v", err)
+	}
+	if pointer.Pointer == "" || pointer.Hash == "" {
+		return fmt.Errorf("record pointer and hash must be set")
+	}
+
+	err = _requireConsent(ctx, patient, provider, category)
+	if err != nil {
+		return err
+	}
+
+	recordKey, err := ctx.GetStub().CreateCompositeKey(recordPrefix, []string{patient, recordID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	existing, err := ctx.GetStub().GetState(recordKey)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if existing != nil {
+		return fmt.Errorf("record %s of the patient already exists", recordID)
+	}
+
+	now, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return err
+	}
+	record := Record{
+		Patient:   patient,
+		ID:        recordID,
+		Category:  category,
+		Provider:  provider,
+		CreatedAt: now.Format(time.RFC3339),
+	}
+	recordJSON, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal record: %v", err)
+	}
+	err = ctx.GetStub().PutState(recordKey, recordJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put record: %v", err)
+	}
+
+	// re-marshal so only the expected fields reach the collection
+	pointerJSON, err = json.Marshal(pointer)
+	if err != nil {
+		return fmt.Errorf("failed to marshal record pointer: %v", err)
+	}
+	err = ctx.GetStub().PutPrivateData(recordsCollection, recordKey, pointerJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put record pointer: %v", err)
+	}
+
+	return _logAccess(ctx, &record, actionWrite, "record created")
+}
+
+// AccessRecord returns the pointer and hash of a record to the patient, or to a provider org with
+// consent for the record's category, and logs the access. It must be submitted, not evaluated, for
+// the access to be logged, and is only served by peers of orgs in the records collection.
+func (s *SmartContract) AccessRecord(ctx contractapi.TransactionContextInterface, patient string, recordID string, purpose string) (*RecordPointer, error) {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client id: %v", err)
+	}
+	provider, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get MSPID: %v", err)
+	}
+
+	if purpose == "" {
+		return nil, fmt.Errorf("purpose of access must be set")
+	}
+
+	record, err := s.GetRecord(ctx, patient, recordID)
+	if err != nil {
+		return nil, err
+	}
+	if clientID != patient {
+		err = _requireConsent(ctx, patient, provider, record.Category)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	recordKey, err := ctx.GetStub().CreateCompositeKey(recordPrefix, []string{patient, recordID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	pointerJSON, err := ctx.GetStub().GetPrivateData(recordsCollection, recordKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read record pointer: %v", err)
+	}
+	if pointerJSON == nil {
+		return nil, fmt.Errorf("record pointer of %s is not available on this peer", recordID)
+	}
+	var pointer RecordPointer
+	err = json.Unmarshal(pointerJSON, &pointer)
+	if err != nil {
+		return nil, err
+	}
+
+	err = _logAccess(ctx, record, actionRead, purpose)
+	if err != nil {
+		return nil, err
+	}
+	return &pointer, nil
+}
+
+// _requireConsent checks the patient has an active consent for the provider covering the category
+func _requireConsent(ctx contractapi.TransactionContextInterface, patient string, provider string, category string) error {
+	now, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, scope := range []string{category, scopeAll} {
+		consent, err := _getConsent(ctx, patient, provider, scope)
+		if err != nil {
+			return err
+		}
+		if consent == nil || consent.Revoked {
+			continue
+		}
+		if consent.Expiry != "" {
+			expiry, err := time.Parse(time.RFC3339, consent.Expiry)
+			if err != nil {
+				return fmt.Errorf("failed to parse consent expiry: %v", err)
+			}
+			if !now.Before(expiry) {
+				continue
+			}
+		}
+		return nil
+	}
+
+	return fmt.Errorf("%s has no active consent for %s records of the patient", provider, category)
+}
+
+// _logAccess appends an access event to the patient's log and emits it
+func _logAccess(ctx contractapi.TransactionContextInterface, record *Record, action string, purpose string) error {
+	accessor, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+	provider, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+	now, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	accessEvent := AccessEvent{
+		Patient:   record.Patient,
+		RecordID:  record.ID,
+		Accessor:  accessor,
+		Provider:  provider,
+		Action:    action,
+		Purpose:   purpose,
+		Timestamp: now.Format(time.RFC3339),
+		TxID:      ctx.GetStub().GetTxID(),
+	}
+	accessJSON, err := json.Marshal(accessEvent)
+	if err != nil {
+		return fmt.Errorf("failed to marshal access event: %v", err)
+	}
+
+	accessKey, err := ctx.GetStub().CreateCompositeKey(accessPrefix, []string{record.Patient, accessEvent.TxID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = ctx.GetStub().PutState(accessKey, accessJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put access event: %v", err)
+	}
+
+	return ctx.GetStub().SetEvent("RecordAccessed", accessJSON)
+}
+
+// _getConsent reads a consent, returning nil when none was granted
+func _getConsent(ctx contractapi.TransactionContextInterface, patient string, provider string, scope string) (*Consent, error) {
+	consentKey, err := ctx.GetStub().CreateCompositeKey(consentPrefix, []string{patient, provider, scope})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	consentJSON, err := ctx.GetStub().GetState(consentKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if consentJSON == nil {
+		return nil, nil
+	}
+
+	var consent Consent
+	err = json.Unmarshal(consentJSON, &consent)
+	if err != nil {
+		return nil, err
+	}
+	return &consent, nil
+}
+
+// _putConsent writes the consent to the world state
+func _putConsent(ctx contractapi.TransactionContextInterface, consent *Consent) error {
+	consentKey, err := ctx.GetStub().CreateCompositeKey(consentPrefix, []string{consent.Patient, consent.Provider, consent.Scope})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	consentJSON, err := json.Marshal(consent)
+	if err != nil {
+		return fmt.Errorf("failed to marshal consent: %v", err)
+	}
+	err = ctx.GetStub().PutState(consentKey, consentJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put consent: %v", err)
+	}
+	return nil
+}
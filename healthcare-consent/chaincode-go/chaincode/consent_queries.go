@@ -0,0 +1,113 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// GetConsents returns every consent a patient has granted, including revoked ones
+func (s *SmartContract) GetConsents(ctx contractapi.TransactionContextInterface, patient string) ([]*Consent, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(consentPrefix, []string{patient})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get consents: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var consents []*Consent
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var consent Consent
+		err = json.Unmarshal(response.Value, &consent)
+		if err != nil {
+			return nil, err
+		}
+		consents = append(consents, &consent)
+	}
+
+	return consents, nil
+}
+
+// GetRecord returns the public metadata of a patient's record
+func (s *SmartContract) GetRecord(ctx contractapi.TransactionContextInterface, patient string, recordID string) (*Record, error) {
+	recordKey, err := ctx.GetStub().CreateCompositeKey(recordPrefix, []string{patient, recordID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	recordJSON, err := ctx.GetStub().GetState(recordKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if recordJSON == nil {
+		return nil, fmt.Errorf("record %s of the patient does not exist", recordID)
+	}
+
+	var record Record
+	err = json.Unmarshal(recordJSON, &record)
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// GetRecords returns the public metadata of every record of a patient
+func (s *SmartContract) GetRecords(ctx contractapi.TransactionContextInterface, patient string) ([]*Record, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(recordPrefix, []string{patient})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get records: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var records []*Record
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var record Record
+		err = json.Unmarshal(response.Value, &record)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, &record)
+	}
+
+	return records, nil
+}
+
+// GetAccessLog returns every logged access to a patient's records
+func (s *SmartContract) GetAccessLog(ctx contractapi.TransactionContextInterface, patient string) ([]*AccessEvent, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(accessPrefix, []string{patient})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get access log: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var accessLog []*AccessEvent
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var accessEvent AccessEvent
+		err = json.Unmarshal(response.Value, &accessEvent)
+		if err != nil {
+			return nil, err
+		}
+		accessLog = append(accessLog, &accessEvent)
+	}
+
+	return accessLog, nil
+}
@@ -0,0 +1,116 @@
+package chaincode
+
+import (
+	"strings"
+	"testing"
+)
+
+const (
+	providerMSPID = "Org2MSP"
+	recordJSON    = `{"pointer":"ipfs://lab1","hash":"ab12"}`
+)
+
+func checkError(t *testing.T, err error, expected string) {
+	t.Helper()
+	if expected == "" && err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected != "" && (err == nil || !strings.Contains(err.Error(), expected)) {
+		t.Fatalf("expected error containing %q, got %v", expected, err)
+	}
+}
+
+// addRecord has a doctor of Org2 add lab record lab1 of the patient
+func addRecord(t *testing.T, stub *fakeStub) {
+	t.Helper()
+	stub.transient = map[string][]byte{"record": []byte(recordJSON)}
+	checkError(t, new(SmartContract).AddRecord(newContext(stub, "doctor", providerMSPID), "patient", "lab1", "lab"), "")
+	stub.transient = nil
+}
+
+func TestGrantConsent(t *testing.T) {
+	tests := []struct {
+		name     string
+		scope    string
+		expiry   string
+		expected string
+	}{
+		{"until revoked", "lab", "", ""},
+		{"with expiry", scopeAll, "2020-09-13T12:30:00Z", ""},
+		{"no scope", "", "", "provider and scope must be set"},
+		{"bad expiry", "lab", "tomorrow", "expiry tomorrow is not an RFC3339 timestamp"},
+		{"past expiry", "lab", "2020-01-01T00:00:00Z", "expiry 2020-01-01T00:00:00Z is in the past"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stub := newFakeStub()
+			err := new(SmartContract).GrantConsent(newContext(stub, "patient", "Org1MSP"), providerMSPID, test.scope, test.expiry)
+			checkError(t, err, test.expected)
+		})
+	}
+}
+
+func TestAccessRecord(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+
+	stub.transient = map[string][]byte{"record": []byte(recordJSON)}
+	err := contract.AddRecord(newContext(stub, "doctor", providerMSPID), "patient", "lab1", "lab")
+	checkError(t, err, "Org2MSP has no active consent for lab records of the patient")
+
+	checkError(t, contract.GrantConsent(newContext(stub, "patient", "Org1MSP"), providerMSPID, "lab", ""), "")
+	stub.transient = nil
+	err = contract.AddRecord(newContext(stub, "doctor", providerMSPID), "patient", "lab1", "lab")
+	checkError(t, err, "record key not found in the transient map")
+	addRecord(t, stub)
+
+	// the pointer is only in the collection
+	record, err := contract.GetRecord(newContext(stub, "patient", "Org1MSP"), "patient", "lab1")
+	checkError(t, err, "")
+	if record.Provider != providerMSPID || len(stub.private[recordsCollection]) != 1 {
+		t.Fatalf("unexpected record %+v", record)
+	}
+	for key, value := range stub.state {
+		if strings.Contains(string(value), "ipfs") {
+			t.Fatalf("record pointer found in the world state under %s", key)
+		}
+	}
+
+	_, err = contract.AccessRecord(newContext(stub, "nurse", "Org3MSP"), "patient", "lab1", "second opinion")
+	checkError(t, err, "Org3MSP has no active consent for lab records of the patient")
+
+	pointer, err := contract.AccessRecord(newContext(stub, "patient", "Org1MSP"), "patient", "lab1", "own copy")
+	checkError(t, err, "")
+	if pointer.Pointer != "ipfs://lab1" || pointer.Hash != "ab12" {
+		t.Fatalf("unexpected pointer %+v", pointer)
+	}
+	_, err = contract.AccessRecord(newContext(stub, "doctor", providerMSPID), "patient", "lab1", "follow up")
+	checkError(t, err, "")
+
+	err = contract.RevokeConsent(newContext(stub, "doctor", providerMSPID), providerMSPID, "lab")
+	checkError(t, err, "there is no consent for Org2MSP with scope lab to revoke")
+	checkError(t, contract.RevokeConsent(newContext(stub, "patient", "Org1MSP"), providerMSPID, "lab"), "")
+	_, err = contract.AccessRecord(newContext(stub, "doctor", providerMSPID), "patient", "lab1", "follow up")
+	checkError(t, err, "Org2MSP has no active consent for lab records of the patient")
+
+	// the write and both reads are logged, the refused reads are not
+	accessLog, err := contract.GetAccessLog(newContext(stub, "patient", "Org1MSP"), "patient")
+	checkError(t, err, "")
+	if len(accessLog) != 3 || accessLog[0].Action != actionWrite || accessLog[2].Accessor != "doctor" || accessLog[2].Purpose != "follow up" {
+		t.Fatalf("unexpected access log %+v", accessLog)
+	}
+}
+
+func TestConsentExpiry(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	checkError(t, contract.GrantConsent(newContext(stub, "patient", "Org1MSP"), providerMSPID, scopeAll, "2020-09-13T12:30:00Z"), "")
+	addRecord(t, stub)
+
+	_, err := contract.AccessRecord(newContext(stub, "doctor", providerMSPID), "patient", "lab1", "follow up")
+	checkError(t, err, "")
+
+	stub.txCount += 200
+	_, err = contract.AccessRecord(newContext(stub, "doctor", providerMSPID), "patient", "lab1", "follow up")
+	checkError(t, err, "Org2MSP has no active consent for lab records of the patient")
+}
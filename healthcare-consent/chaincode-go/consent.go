@@ -0,0 +1,23 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"log"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/healthcare-consent/chaincode-go/chaincode"
+)
+
+func main() {
+	consentChaincode, err := contractapi.NewChaincode(&chaincode.SmartContract{})
+	if err != nil {
+		log.Panicf("Error creating healthcare-consent chaincode: %v", err)
+	}
+
+	if err := consentChaincode.Start(); err != nil {
+		log.Panicf("Error starting healthcare-consent chaincode: %v", err)
+	}
+}
@@ -0,0 +1,217 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-secured-agreement/application-go/asset"
+	"github.com/hyperledger/fabric-samples/asset-transfer-secured-agreement/assettypes"
+	"github.com/spf13/cobra"
+)
+
+// agreementFlags are the price flags of the commands agreeing to or completing a sale
+type agreementFlags struct {
+	price   int
+	tradeID string
+}
+
+func (f *agreementFlags) register(cmd *cobra.Command) {
+	cmd.Flags().IntVar(&f.price, "price", 0, "price of the asset")
+	cmd.Flags().StringVar(&f.tradeID, "trade-id", "", "ID of the trade, which the seller and buyer must both use")
+	cmd.MarkFlagRequired("price")
+	cmd.MarkFlagRequired("trade-id")
+}
+
+func (f *agreementFlags) agreement(assetID string) *assettypes.Agreement {
+	return &assettypes.Agreement{ID: assetID, Price: f.price, TradeID: f.tradeID}
+}
+
+func newAssetCommand(opts *options) *cobra.Command {
+	var chaincode string
+	assetCmd := &cobra.Command{
+		Use:   "asset",
+		Short: "Call the secured agreement asset chaincode",
+	}
+	assetCmd.PersistentFlags().StringVar(&chaincode, "chaincode", envOr("HLCC_ASSET_CHAINCODE", "secured"), "name the asset chaincode is deployed as, $HLCC_ASSET_CHAINCODE")
+
+	// run connects and runs fn with the asset contract, printing its result
+	run := func(fn func(contract *asset.Contract, args []string) (interface{}, error)) func(cmd *cobra.Command, args []string) error {
+		return func(cmd *cobra.Command, args []string) error {
+			network, mspID, closeConnection, err := opts.connect()
+			if err != nil {
+				return err
+			}
+			defer closeConnection()
+			result, err := fn(asset.NewContract(network, chaincode, mspID), args)
+			return printJSON(cmd.OutOrStdout(), result, err)
+		}
+	}
+
+	var properties assettypes.AssetProperties
+	create := &cobra.Command{
+		Use:   "create <assetID> <description>",
+		Short: "Create an asset owned by the identity's org",
+		Long: "Create an asset owned by the identity's org. The color, size, appraised value and salt are kept in the org's\n" +
+			"private data and printed, so they can be shared with a buyer for inspection. A random salt is generated when none\n" +
+			"is given.",
+		Args: cobra.ExactArgs(2),
+		RunE: run(func(contract *asset.Contract, args []string) (interface{}, error) {
+			properties.ObjectType = "asset_properties"
+			properties.ID = args[0]
+			if properties.AppraisedValue != "" {
+				if _, err := assettypes.ParseDecimal(string(properties.AppraisedValue)); err != nil {
+					return nil, fmt.Errorf("--appraised-value: %v", err)
+				}
+			}
+			if properties.Salt == "" {
+				salt := make([]byte, 20)
+				if _, err := rand.Read(salt); err != nil {
+					return nil, fmt.Errorf("failed to generate salt: %v", err)
+				}
+				properties.Salt = hex.EncodeToString(salt)
+			}
+			result, err := contract.CreateAsset(args[0], args[1], &properties)
+			if err != nil {
+				return nil, err
+			}
+			return struct {
+				*assettypes.AssetResult
+				Properties *assettypes.AssetProperties `json:"properties"`
+			}{result, &properties}, nil
+		}),
+	}
+	create.Flags().StringVar(&properties.Color, "color", "", "color of the asset")
+	create.Flags().IntVar(&properties.Size, "size", 0, "size of the asset")
+	create.Flags().StringVar((*string)(&properties.AppraisedValue), "appraised-value", "", "appraised value of the asset as a decimal, e.g. 1250.75")
+	create.Flags().StringVar(&properties.Salt, "salt", "", "salt hiding the properties from other orgs")
+
+	var sell, buy, transferAgreement agreementFlags
+	sellCmd := &cobra.Command{
+		Use:   "sell <assetID>",
+		Short: "Agree to sell an asset of the identity's org",
+		Args:  cobra.ExactArgs(1),
+		RunE: run(func(contract *asset.Contract, args []string) (interface{}, error) {
+			return contract.AgreeToSell(sell.agreement(args[0]))
+		}),
+	}
+	sell.register(sellCmd)
+	buyCmd := &cobra.Command{
+		Use:   "buy <assetID>",
+		Short: "Agree to buy an asset for the identity's org",
+		Args:  cobra.ExactArgs(1),
+		RunE: run(func(contract *asset.Contract, args []string) (interface{}, error) {
+			return contract.AgreeToBuy(buy.agreement(args[0]))
+		}),
+	}
+	buy.register(buyCmd)
+	transfer := &cobra.Command{
+		Use:   "transfer <assetID> <buyer MSP ID>",
+		Short: "Sell an asset of the identity's org at the agreed price",
+		Long:  "Sell an asset of the identity's org at the agreed price. The properties are read from the org's private data.",
+		Args:  cobra.ExactArgs(2),
+		RunE: run(func(contract *asset.Contract, args []string) (interface{}, error) {
+			properties, err := contract.GetAssetPrivateProperties(args[0])
+			if err != nil {
+				return nil, err
+			}
+			return contract.TransferAsset(args[0], args[1], properties, transferAgreement.agreement(args[0]))
+		}),
+	}
+	transferAgreement.register(transfer)
+
+	var pageSize int
+	var bookmark string
+	var all bool
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List the assets a page at a time",
+		Args:  cobra.NoArgs,
+		RunE: run(func(contract *asset.Contract, args []string) (interface{}, error) {
+			if !all {
+				return contract.GetAssetsPage(pageSize, bookmark)
+			}
+			var assets []*assettypes.Asset
+			err := contract.GetAllAssets(pageSize, func(page []*assettypes.Asset) error {
+				assets = append(assets, page...)
+				return nil
+			})
+			return assets, err
+		}),
+	}
+	list.Flags().IntVar(&pageSize, "page-size", 10, fmt.Sprintf("number of assets per page, at most %d", asset.MaxPageSize))
+	list.Flags().StringVar(&bookmark, "bookmark", "", "bookmark returned with the previous page")
+	list.Flags().BoolVar(&all, "all", false, "read every page")
+
+	assetCmd.AddCommand(
+		create,
+		&cobra.Command{
+			Use:   "update <assetID> <description>",
+			Short: "Change the public description of an asset",
+			Args:  cobra.ExactArgs(2),
+			RunE: run(func(contract *asset.Contract, args []string) (interface{}, error) {
+				return contract.UpdateAsset(args[0], args[1])
+			}),
+		},
+		sellCmd,
+		buyCmd,
+		&cobra.Command{
+			Use:   "inspect <assetID> <properties file>",
+			Short: "Check properties shared by the seller against the hash on the ledger",
+			Args:  cobra.ExactArgs(2),
+			RunE: run(func(contract *asset.Contract, args []string) (interface{}, error) {
+				propertiesJSON, err := os.ReadFile(args[1])
+				if err != nil {
+					return nil, fmt.Errorf("failed to read properties: %v", err)
+				}
+				var shared assettypes.AssetProperties
+				err = json.Unmarshal(propertiesJSON, &shared)
+				if err != nil {
+					return nil, fmt.Errorf("failed to unmarshal properties: %v", err)
+				}
+				return contract.SetInspection(args[0], &shared)
+			}),
+		},
+		transfer,
+		&cobra.Command{
+			Use:   "read <assetID>",
+			Short: "Public data of an asset",
+			Args:  cobra.ExactArgs(1),
+			RunE: run(func(contract *asset.Contract, args []string) (interface{}, error) {
+				return contract.ReadAsset(args[0])
+			}),
+		},
+		&cobra.Command{
+			Use:   "properties <assetID>",
+			Short: "Private properties of an asset held by the identity's org",
+			Args:  cobra.ExactArgs(1),
+			RunE: run(func(contract *asset.Contract, args []string) (interface{}, error) {
+				return contract.GetAssetPrivateProperties(args[0])
+			}),
+		},
+		&cobra.Command{
+			Use:   "history <assetID>",
+			Short: "Every modification of an asset",
+			Args:  cobra.ExactArgs(1),
+			RunE: run(func(contract *asset.Contract, args []string) (interface{}, error) {
+				return contract.QueryAssetHistory(args[0])
+			}),
+		},
+		&cobra.Command{
+			Use:   "receipts <assetID>",
+			Short: "Receipts of the identity org's trades of an asset",
+			Args:  cobra.ExactArgs(1),
+			RunE: run(func(contract *asset.Contract, args []string) (interface{}, error) {
+				return contract.GetAssetReceipts(args[0])
+			}),
+		},
+		list,
+	)
+	return assetCmd
+}
@@ -0,0 +1,204 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-protos-go-apiv2/common"
+	"github.com/hyperledger/fabric-protos-go-apiv2/ledger/rwset"
+	"github.com/hyperledger/fabric-protos-go-apiv2/ledger/rwset/kvrwset"
+	"github.com/hyperledger/fabric-protos-go-apiv2/msp"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+	"google.golang.org/protobuf/proto"
+)
+
+// blockTx is a transaction of a committed block, decoded from its envelope
+type blockTx struct {
+	block uint64
+	// blockDataHash is the data hash of the block header, which the header of the next block chains
+	blockDataHash  []byte
+	index          int
+	txID           string
+	timestamp      time.Time
+	creatorMSPID   string
+	validationCode peer.TxValidationCode
+	actions        []*txAction
+}
+
+// txAction is what a transaction did in one chaincode: the public state it wrote and the event
+// it set. Private data appears in blocks only as hashes and is not decoded.
+type txAction struct {
+	chaincode string
+	writes    []*kvrwset.KVWrite
+	event     *peer.ChaincodeEvent
+}
+
+// ledger reads the committed blocks of a channel through the query system chaincode
+type ledger struct {
+	qscc    *client.Contract
+	channel string
+}
+
+func newLedger(network *client.Network, channel string) *ledger {
+	return &ledger{qscc: network.GetContract("qscc"), channel: channel}
+}
+
+// height returns the number of blocks of the channel
+func (l *ledger) height() (uint64, error) {
+	infoBytes, err := l.qscc.EvaluateTransaction("GetChainInfo", l.channel)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get chain info: %w", err)
+	}
+	info := &common.BlockchainInfo{}
+	err = proto.Unmarshal(infoBytes, info)
+	if err != nil {
+		return 0, fmt.Errorf("failed to unmarshal chain info: %v", err)
+	}
+	return info.GetHeight(), nil
+}
+
+// block returns a committed block of the channel
+func (l *ledger) block(number uint64) (*common.Block, error) {
+	blockBytes, err := l.qscc.EvaluateTransaction("GetBlockByNumber", l.channel, strconv.FormatUint(number, 10))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get block %d: %w", number, err)
+	}
+	block := &common.Block{}
+	err = proto.Unmarshal(blockBytes, block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal block %d: %v", number, err)
+	}
+	return block, nil
+}
+
+// transactions returns the endorser transactions of a block. Config and other transactions are
+// skipped.
+func (l *ledger) transactions(number uint64) ([]*blockTx, error) {
+	block, err := l.block(number)
+	if err != nil {
+		return nil, err
+	}
+	return decodeBlock(block)
+}
+
+func decodeBlock(block *common.Block) ([]*blockTx, error) {
+	number := block.GetHeader().GetNumber()
+	// the transactions filter holds the validation code of each transaction of the block
+	var validationCodes []byte
+	if metadata := block.GetMetadata().GetMetadata(); len(metadata) > int(common.BlockMetadataIndex_TRANSACTIONS_FILTER) {
+		validationCodes = metadata[common.BlockMetadataIndex_TRANSACTIONS_FILTER]
+	}
+
+	var transactions []*blockTx
+	for i, envelopeBytes := range block.GetData().GetData() {
+		tx, err := decodeTransaction(envelopeBytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode transaction %d of block %d: %v", i, number, err)
+		}
+		if tx == nil {
+			continue
+		}
+		tx.block = number
+		tx.blockDataHash = block.GetHeader().GetDataHash()
+		tx.index = i
+		if i < len(validationCodes) {
+			tx.validationCode = peer.TxValidationCode(validationCodes[i])
+		}
+		transactions = append(transactions, tx)
+	}
+	return transactions, nil
+}
+
+// decodeTransaction decodes an endorser transaction, or returns nil for any other envelope
+func decodeTransaction(envelopeBytes []byte) (*blockTx, error) {
+	envelope := &common.Envelope{}
+	if err := proto.Unmarshal(envelopeBytes, envelope); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal envelope: %v", err)
+	}
+	payload := &common.Payload{}
+	if err := proto.Unmarshal(envelope.GetPayload(), payload); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal payload: %v", err)
+	}
+	channelHeader := &common.ChannelHeader{}
+	if err := proto.Unmarshal(payload.GetHeader().GetChannelHeader(), channelHeader); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal channel header: %v", err)
+	}
+	if common.HeaderType(channelHeader.GetType()) != common.HeaderType_ENDORSER_TRANSACTION {
+		return nil, nil
+	}
+
+	transaction := &peer.Transaction{}
+	if err := proto.Unmarshal(payload.GetData(), transaction); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal transaction: %v", err)
+	}
+	signatureHeader := &common.SignatureHeader{}
+	if err := proto.Unmarshal(payload.GetHeader().GetSignatureHeader(), signatureHeader); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal signature header: %v", err)
+	}
+	creator := &msp.SerializedIdentity{}
+	if err := proto.Unmarshal(signatureHeader.GetCreator(), creator); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal creator: %v", err)
+	}
+	tx := &blockTx{txID: channelHeader.GetTxId(), creatorMSPID: creator.GetMspid()}
+	if timestamp := channelHeader.GetTimestamp(); timestamp != nil {
+		tx.timestamp = timestamp.AsTime().UTC()
+	}
+	for _, transactionAction := range transaction.GetActions() {
+		actions, err := decodeAction(transactionAction)
+		if err != nil {
+			return nil, err
+		}
+		tx.actions = append(tx.actions, actions...)
+	}
+	return tx, nil
+}
+
+// decodeAction returns the public writes of each chaincode namespace of a transaction action,
+// with the chaincode event set on the chaincode that was invoked
+func decodeAction(transactionAction *peer.TransactionAction) ([]*txAction, error) {
+	actionPayload := &peer.ChaincodeActionPayload{}
+	if err := proto.Unmarshal(transactionAction.GetPayload(), actionPayload); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal chaincode action payload: %v", err)
+	}
+	responsePayload := &peer.ProposalResponsePayload{}
+	if err := proto.Unmarshal(actionPayload.GetAction().GetProposalResponsePayload(), responsePayload); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal proposal response payload: %v", err)
+	}
+	chaincodeAction := &peer.ChaincodeAction{}
+	if err := proto.Unmarshal(responsePayload.GetExtension(), chaincodeAction); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal chaincode action: %v", err)
+	}
+	txReadWriteSet := &rwset.TxReadWriteSet{}
+	if err := proto.Unmarshal(chaincodeAction.GetResults(), txReadWriteSet); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal read-write set: %v", err)
+	}
+
+	invoked := &txAction{chaincode: chaincodeAction.GetChaincodeId().GetName()}
+	if eventBytes := chaincodeAction.GetEvents(); len(eventBytes) > 0 {
+		event := &peer.ChaincodeEvent{}
+		if err := proto.Unmarshal(eventBytes, event); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal chaincode event: %v", err)
+		}
+		invoked.event = event
+	}
+	actions := []*txAction{invoked}
+	for _, nsReadWriteSet := range txReadWriteSet.GetNsRwset() {
+		kvReadWriteSet := &kvrwset.KVRWSet{}
+		if err := proto.Unmarshal(nsReadWriteSet.GetRwset(), kvReadWriteSet); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal read-write set of %s: %v", nsReadWriteSet.GetNamespace(), err)
+		}
+		if nsReadWriteSet.GetNamespace() == invoked.chaincode {
+			invoked.writes = kvReadWriteSet.GetWrites()
+			continue
+		}
+		// a chaincode-to-chaincode call, or the lifecycle namespace
+		actions = append(actions, &txAction{chaincode: nsReadWriteSet.GetNamespace(), writes: kvReadWriteSet.GetWrites()})
+	}
+	return actions, nil
+}
@@ -0,0 +1,385 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+	"github.com/spf13/cobra"
+)
+
+// mintBurnAccount is the account the token chaincode moves minted tokens from and burned tokens to
+const mintBurnAccount = "0x0"
+
+// tokenMovement is a Transfer event, or one payment of a BatchTransfer event, of the token chaincode
+// with the balances its transaction wrote
+type tokenMovement struct {
+	Block       uint64    `json:"block"`
+	TxIndex     int       `json:"txIndex"`
+	TxID        string    `json:"txID"`
+	Timestamp   time.Time `json:"timestamp"`
+	Chaincode   string    `json:"chaincode"`
+	Type        string    `json:"type"`
+	From        string    `json:"from"`
+	To          string    `json:"to"`
+	Value       int       `json:"value"`
+	FromBalance *int      `json:"fromBalance,omitempty"`
+	ToBalance   *int      `json:"toBalance,omitempty"`
+}
+
+var tokenMovementHeader = []string{"block", "txIndex", "txID", "timestamp", "chaincode", "type", "from", "to", "value", "fromBalance", "toBalance"}
+
+func (m *tokenMovement) row() []string {
+	return []string{
+		strconv.FormatUint(m.Block, 10), strconv.Itoa(m.TxIndex), m.TxID, m.Timestamp.Format(time.RFC3339Nano), m.Chaincode,
+		m.Type, m.From, m.To, strconv.Itoa(m.Value), optionalInt(m.FromBalance), optionalInt(m.ToBalance),
+	}
+}
+
+// assetChange is a write of the public state of an asset, with the event its transaction set
+type assetChange struct {
+	Block             uint64    `json:"block"`
+	TxIndex           int       `json:"txIndex"`
+	TxID              string    `json:"txID"`
+	Timestamp         time.Time `json:"timestamp"`
+	Chaincode         string    `json:"chaincode"`
+	Event             string    `json:"event,omitempty"`
+	AssetID           string    `json:"assetID"`
+	OwnerOrg          string    `json:"ownerOrg,omitempty"`
+	PreviousOwnerOrg  string    `json:"previousOwnerOrg,omitempty"`
+	PublicDescription string    `json:"publicDescription,omitempty"`
+	Deleted           bool      `json:"deleted,omitempty"`
+}
+
+var assetChangeHeader = []string{"block", "txIndex", "txID", "timestamp", "chaincode", "event", "assetID", "ownerOrg", "previousOwnerOrg", "publicDescription", "deleted"}
+
+func (c *assetChange) row() []string {
+	return []string{
+		strconv.FormatUint(c.Block, 10), strconv.Itoa(c.TxIndex), c.TxID, c.Timestamp.Format(time.RFC3339Nano), c.Chaincode,
+		c.Event, c.AssetID, c.OwnerOrg, c.PreviousOwnerOrg, c.PublicDescription, strconv.FormatBool(c.Deleted),
+	}
+}
+
+// transferPayload holds the fields of the Transfer and BatchTransfer event payloads the export reads
+type transferPayload struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Value    int    `json:"value"`
+	Payments []struct {
+		From     string `json:"from"`
+		Receiver string `json:"receiver"`
+		Amount   int    `json:"amount"`
+	} `json:"payments"`
+	Audit *struct {
+		Changes []struct {
+			Kind string `json:"kind"`
+			Old  int    `json:"old"`
+			New  int    `json:"new"`
+		} `json:"changes"`
+	} `json:"audit"`
+}
+
+// assetState holds the fields of the public asset state and asset events the export reads
+type assetState struct {
+	ObjectType        string `json:"objectType"`
+	ID                string `json:"assetID"`
+	OwnerOrg          string `json:"ownerOrg"`
+	PreviousOwnerOrg  string `json:"previousOwnerOrg"`
+	PublicDescription string `json:"publicDescription"`
+}
+
+// tokenMovements returns the token movements of a transaction, one per payment of a BatchTransfer
+func tokenMovements(tx *blockTx, tokenChaincode string) []*tokenMovement {
+	var movements []*tokenMovement
+	for _, action := range tx.actions {
+		if action.chaincode != tokenChaincode || action.event == nil {
+			continue
+		}
+		eventName := action.event.GetEventName()
+		if eventName != "Transfer" && eventName != "BatchTransfer" {
+			continue
+		}
+		var payload transferPayload
+		if json.Unmarshal(action.event.GetPayload(), &payload) != nil {
+			continue
+		}
+		// balances are stored under the account ID, and the new balance of each account is in the write set
+		balances := make(map[string]int)
+		for _, write := range action.writes {
+			if balance, err := strconv.Atoi(string(write.GetValue())); err == nil && !write.GetIsDelete() {
+				balances[write.GetKey()] = balance
+			}
+		}
+		newMovement := func(movementType string, from string, to string, value int) *tokenMovement {
+			movement := &tokenMovement{
+				Block:     tx.block,
+				TxIndex:   tx.index,
+				TxID:      tx.txID,
+				Timestamp: tx.timestamp,
+				Chaincode: action.chaincode,
+				Type:      movementType,
+				From:      from,
+				To:        to,
+				Value:     value,
+			}
+			if balance, ok := balances[from]; ok && from != mintBurnAccount {
+				movement.FromBalance = &balance
+			}
+			if balance, ok := balances[to]; ok && to != mintBurnAccount {
+				movement.ToBalance = &balance
+			}
+			return movement
+		}
+
+		if eventName == "Transfer" {
+			movements = append(movements, newMovement(movementType(&payload), payload.From, payload.To, payload.Value))
+			continue
+		}
+		// a payment pulled from another account against the client's allowance names that account
+		for _, payment := range payload.Payments {
+			from := payload.From
+			if payment.From != "" {
+				from = payment.From
+			}
+			movements = append(movements, newMovement("transfer", from, payment.Receiver, payment.Amount))
+		}
+	}
+	return movements
+}
+
+// movementType tells mints and burns from transfers. The chaincode emits both mints and burns
+// from the 0x0 account, so they are told apart by the change of the total supply.
+func movementType(payload *transferPayload) string {
+	if payload.From != mintBurnAccount && payload.To != mintBurnAccount {
+		return "transfer"
+	}
+	if payload.Audit != nil {
+		for _, change := range payload.Audit.Changes {
+			if change.Kind == "totalSupply" && change.New < change.Old {
+				return "burn"
+			}
+		}
+	}
+	return "mint"
+}
+
+// assetKeyPrefix starts the composite keys the asset chaincode stores the public asset state under
+const assetKeyPrefix = "\x00asset\x00"
+
+// assetChanges returns the changes of the public asset state of a transaction. Other keys, such as
+// those of prices and receipts, are skipped.
+func assetChanges(tx *blockTx, assetChaincode string) []*assetChange {
+	var changes []*assetChange
+	for _, action := range tx.actions {
+		if action.chaincode != assetChaincode {
+			continue
+		}
+		var eventName string
+		var event assetState
+		if action.event != nil {
+			eventName = action.event.GetEventName()
+			json.Unmarshal(action.event.GetPayload(), &event)
+		}
+		for _, write := range action.writes {
+			if !strings.HasPrefix(write.GetKey(), assetKeyPrefix) {
+				continue
+			}
+			change := &assetChange{
+				Block:     tx.block,
+				TxIndex:   tx.index,
+				TxID:      tx.txID,
+				Timestamp: tx.timestamp,
+				Chaincode: action.chaincode,
+				Event:     eventName,
+				AssetID:   strings.TrimSuffix(strings.TrimPrefix(write.GetKey(), assetKeyPrefix), "\x00"),
+				Deleted:   write.GetIsDelete(),
+			}
+			if !change.Deleted {
+				var state assetState
+				if json.Unmarshal(write.GetValue(), &state) != nil || state.ObjectType != "asset" {
+					continue
+				}
+				change.OwnerOrg = state.OwnerOrg
+				change.PublicDescription = state.PublicDescription
+			}
+			if event.ID == change.AssetID {
+				change.PreviousOwnerOrg = event.PreviousOwnerOrg
+			}
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
+
+// exportTable writes the records of one table as JSON Lines or CSV
+type exportTable struct {
+	file    *os.File
+	csv     *csv.Writer
+	encoder *json.Encoder
+	closed  bool
+}
+
+func createExportTable(dir string, name string, format string, header []string) (*exportTable, error) {
+	extension := map[string]string{"json": ".jsonl", "csv": ".csv"}[format]
+	file, err := os.Create(filepath.Join(dir, name+extension))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create export file: %v", err)
+	}
+	table := &exportTable{file: file}
+	if format == "csv" {
+		table.csv = csv.NewWriter(file)
+		if err := table.csv.Write(header); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to write %s: %v", file.Name(), err)
+		}
+	} else {
+		table.encoder = json.NewEncoder(file)
+	}
+	return table, nil
+}
+
+func (t *exportTable) write(record interface{ row() []string }) error {
+	var err error
+	if t.csv != nil {
+		err = t.csv.Write(record.row())
+	} else {
+		err = t.encoder.Encode(record)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %v", t.file.Name(), err)
+	}
+	return nil
+}
+
+// close flushes and closes the file of the table. Closing it again does nothing, so it can also be
+// deferred for the error paths.
+func (t *exportTable) close() error {
+	if t.closed {
+		return nil
+	}
+	t.closed = true
+	if t.csv != nil {
+		t.csv.Flush()
+		if err := t.csv.Error(); err != nil {
+			t.file.Close()
+			return fmt.Errorf("failed to write %s: %v", t.file.Name(), err)
+		}
+	}
+	return t.file.Close()
+}
+
+func newExportCommand(opts *options) *cobra.Command {
+	var (
+		fromBlock      uint64
+		toBlock        int64
+		format         string
+		outputDir      string
+		tokenChaincode string
+		assetChaincode string
+	)
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export the token movements and asset changes of the committed blocks as JSON Lines or CSV",
+		Long: `Walk the committed blocks of the channel and write the token movements of valid transactions to
+token-movements.jsonl and the public asset changes to asset-changes.jsonl, or to .csv files with --format csv.
+Invalid transactions changed nothing and are skipped.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != "json" && format != "csv" {
+				return fmt.Errorf("--format must be json or csv")
+			}
+			network, _, closeConnection, err := opts.connect()
+			if err != nil {
+				return err
+			}
+			defer closeConnection()
+
+			ledger := newLedger(network, opts.channel)
+			height, err := ledger.height()
+			if err != nil {
+				return err
+			}
+			lastBlock := height - 1
+			if toBlock >= 0 && uint64(toBlock) < lastBlock {
+				lastBlock = uint64(toBlock)
+			}
+			if fromBlock > lastBlock {
+				return fmt.Errorf("--from %d is after the last block %d", fromBlock, lastBlock)
+			}
+
+			err = os.MkdirAll(outputDir, 0755)
+			if err != nil {
+				return fmt.Errorf("failed to create output directory: %v", err)
+			}
+			movementsTable, err := createExportTable(outputDir, "token-movements", format, tokenMovementHeader)
+			if err != nil {
+				return err
+			}
+			defer movementsTable.close()
+			changesTable, err := createExportTable(outputDir, "asset-changes", format, assetChangeHeader)
+			if err != nil {
+				return err
+			}
+			defer changesTable.close()
+
+			movementCount, changeCount := 0, 0
+			for number := fromBlock; number <= lastBlock; number++ {
+				transactions, err := ledger.transactions(number)
+				if err != nil {
+					return err
+				}
+				for _, tx := range transactions {
+					if tx.validationCode != peer.TxValidationCode_VALID {
+						continue
+					}
+					for _, movement := range tokenMovements(tx, tokenChaincode) {
+						if err := movementsTable.write(movement); err != nil {
+							return err
+						}
+						movementCount++
+					}
+					for _, change := range assetChanges(tx, assetChaincode) {
+						if err := changesTable.write(change); err != nil {
+							return err
+						}
+						changeCount++
+					}
+				}
+			}
+
+			if err := movementsTable.close(); err != nil {
+				return err
+			}
+			if err := changesTable.close(); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "exported %d token movements and %d asset changes of blocks %d to %d\n", movementCount, changeCount, fromBlock, lastBlock)
+			return nil
+		},
+	}
+	flags := cmd.Flags()
+	flags.Uint64Var(&fromBlock, "from", 0, "first block to export")
+	flags.Int64Var(&toBlock, "to", -1, "last block to export, the last committed block by default")
+	flags.StringVar(&format, "format", "json", "output format, json (JSON Lines) or csv")
+	flags.StringVarP(&outputDir, "output", "o", ".", "directory the export files are written to")
+	flags.StringVar(&tokenChaincode, "token-chaincode", envOr("HLCC_TOKEN_CHAINCODE", "token_erc20"), "name the token chaincode is deployed as, $HLCC_TOKEN_CHAINCODE")
+	flags.StringVar(&assetChaincode, "asset-chaincode", envOr("HLCC_ASSET_CHAINCODE", "secured"), "name the asset chaincode is deployed as, $HLCC_ASSET_CHAINCODE")
+	return cmd
+}
+
+func optionalInt(value *int) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.Itoa(*value)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-protos-go-apiv2/ledger/rwset/kvrwset"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+)
+
+func TestTokenMovements(t *testing.T) {
+	tx := &blockTx{block: 7, index: 1, txID: "tx1", actions: []*txAction{
+		{
+			chaincode: "token_erc20",
+			event:     &peer.ChaincodeEvent{EventName: "Transfer", Payload: []byte(`{"from":"acc1","to":"acc2","value":4}`)},
+			writes: []*kvrwset.KVWrite{
+				{Key: "acc1", Value: []byte("6")},
+				{Key: "acc2", Value: []byte("4")},
+				{Key: "\x00auditrecord\x00tx1\x00", Value: []byte(`{"txID":"tx1"}`)},
+			},
+		},
+		{chaincode: "access_control", writes: []*kvrwset.KVWrite{{Key: "acc1", Value: []byte("1")}}},
+	}}
+	movements := tokenMovements(tx, "token_erc20")
+	if len(movements) != 1 {
+		t.Fatalf("expected 1 movement, got %d", len(movements))
+	}
+	movement := movements[0]
+	if movement.Type != "transfer" || movement.Block != 7 || movement.TxIndex != 1 || movement.Value != 4 {
+		t.Errorf("movement is %+v", movement)
+	}
+	if movement.FromBalance == nil || *movement.FromBalance != 6 || movement.ToBalance == nil || *movement.ToBalance != 4 {
+		t.Errorf("balances are %s and %s", optionalInt(movement.FromBalance), optionalInt(movement.ToBalance))
+	}
+
+	burn := &blockTx{actions: []*txAction{{
+		chaincode: "token_erc20",
+		event: &peer.ChaincodeEvent{EventName: "Transfer", Payload: []byte(`{"from":"0x0","to":"acc1","value":2,` +
+			`"audit":{"changes":[{"kind":"balance","old":6,"new":4},{"kind":"totalSupply","old":10,"new":8}]}}`)},
+		writes: []*kvrwset.KVWrite{{Key: "acc1", Value: []byte("4")}, {Key: "totalSupply", Value: []byte("8")}},
+	}}}
+	movements = tokenMovements(burn, "token_erc20")
+	if len(movements) != 1 || movements[0].Type != "burn" || movements[0].FromBalance != nil || *movements[0].ToBalance != 4 {
+		t.Errorf("burn movements are %+v", movements)
+	}
+
+	batch := &blockTx{actions: []*txAction{{
+		chaincode: "token_erc20",
+		event: &peer.ChaincodeEvent{EventName: "BatchTransfer", Payload: []byte(`{"from":"acc1","payments":[` +
+			`{"receiver":"acc2","amount":3},{"from":"acc3","receiver":"acc2","amount":2}],"value":5}`)},
+		writes: []*kvrwset.KVWrite{{Key: "acc1", Value: []byte("1")}, {Key: "acc2", Value: []byte("9")}, {Key: "acc3", Value: []byte("8")}},
+	}}}
+	movements = tokenMovements(batch, "token_erc20")
+	if len(movements) != 2 || movements[0].From != "acc1" || movements[0].Value != 3 || *movements[0].FromBalance != 1 ||
+		movements[1].From != "acc3" || movements[1].To != "acc2" || *movements[1].FromBalance != 8 || *movements[1].ToBalance != 9 {
+		t.Errorf("batch movements are %+v", movements)
+	}
+
+	approval := &blockTx{actions: []*txAction{{
+		chaincode: "token_erc20",
+		event:     &peer.ChaincodeEvent{EventName: "Approval", Payload: []byte(`{"from":"acc1","to":"acc2","value":5}`)},
+	}}}
+	if movements := tokenMovements(approval, "token_erc20"); len(movements) != 0 {
+		t.Errorf("expected no movement for an approval, got %+v", movements)
+	}
+}
+
+func TestAssetChanges(t *testing.T) {
+	tx := &blockTx{block: 9, txID: "tx2", actions: []*txAction{{
+		chaincode: "secured",
+		event: &peer.ChaincodeEvent{EventName: "AssetTransferred",
+			Payload: []byte(`{"assetID":"asset1","ownerOrg":"Org2MSP","previousOwnerOrg":"Org1MSP","publicDescription":"sold"}`)},
+		writes: []*kvrwset.KVWrite{
+			{Key: "\x00asset\x00asset1\x00", Value: []byte(`{"objectType":"asset","assetID":"asset1","ownerOrg":"Org2MSP","publicDescription":"sold"}`)},
+			{Key: "\x00S\x00asset1\x00", IsDelete: true},
+			{Key: "\x00salereceipt\x00asset1\x00tx2\x00", Value: []byte(`{}`)},
+		},
+	}}}
+	changes := assetChanges(tx, "secured")
+	if len(changes) != 1 {
+		t.Fatalf("expected 1 change, got %d", len(changes))
+	}
+	change := changes[0]
+	if change.AssetID != "asset1" || change.OwnerOrg != "Org2MSP" || change.PreviousOwnerOrg != "Org1MSP" ||
+		change.Event != "AssetTransferred" || change.PublicDescription != "sold" || change.Deleted {
+		t.Errorf("change is %+v", change)
+	}
+
+	deletion := &blockTx{actions: []*txAction{{chaincode: "secured", writes: []*kvrwset.KVWrite{{Key: "\x00asset\x00asset1\x00", IsDelete: true}}}}}
+	changes = assetChanges(deletion, "secured")
+	if len(changes) != 1 || !changes[0].Deleted || changes[0].AssetID != "asset1" {
+		t.Errorf("deletion changes are %+v", changes)
+	}
+}
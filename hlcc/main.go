@@ -0,0 +1,129 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// hlcc calls the token and secured agreement asset chaincodes through the Fabric Gateway as an
+// identity of a wallet directory.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-samples/internal/appclient"
+	"github.com/spf13/cobra"
+)
+
+// options are the persistent flags of the root command
+type options struct {
+	walletDir string
+	label     string
+	profile   string
+	peer      string
+	channel   string
+}
+
+func main() {
+	err := newRootCommand().Execute()
+	if err != nil {
+		os.Exit(1)
+	}
+}
+
+func newRootCommand() *cobra.Command {
+	opts := &options{}
+	root := &cobra.Command{
+		Use:          "hlcc",
+		Short:        "Call the token and asset chaincodes through the Fabric Gateway",
+		SilenceUsage: true,
+	}
+	flags := root.PersistentFlags()
+	flags.StringVarP(&opts.walletDir, "wallet", "w", envOr("HLCC_WALLET", "wallet"), "wallet directory holding the identities, $HLCC_WALLET")
+	flags.StringVarP(&opts.label, "identity", "i", os.Getenv("HLCC_IDENTITY"), "label of the wallet identity to connect as, $HLCC_IDENTITY; may be omitted when the wallet holds one identity")
+	flags.StringVarP(&opts.profile, "profile", "p", os.Getenv("HLCC_PROFILE"), "connection profile of the identity's org, $HLCC_PROFILE")
+	flags.StringVar(&opts.peer, "peer", "", "peer of the profile to connect to, the first peer of the org by default")
+	flags.StringVarP(&opts.channel, "channel", "C", envOr("HLCC_CHANNEL", "mychannel"), "channel the chaincodes are deployed on, $HLCC_CHANNEL")
+
+	root.AddCommand(newWalletCommand(opts), newTokenCommand(opts), newAssetCommand(opts), newExportCommand(opts), newSnapshotCommand(opts), newReportCommand(opts))
+	return root
+}
+
+// connect connects to the peer of the profile as the wallet identity and returns the channel and
+// the MSP ID of the identity
+func (o *options) connect() (*client.Network, string, func(), error) {
+	if o.profile == "" {
+		return nil, "", nil, fmt.Errorf("--profile or $HLCC_PROFILE must name the connection profile of the identity's org")
+	}
+	profile, err := appclient.LoadProfile(o.profile)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	wallet, err := appclient.OpenWallet(o.walletDir)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	label, err := o.identityLabel(wallet)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	connection, err := appclient.ConnectWallet(profile, o.peer, wallet, label)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	closeConnection := func() {
+		if err := connection.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to close connection: %v\n", err)
+		}
+	}
+	return connection.GetNetwork(o.channel), profile.MSPID(), closeConnection, nil
+}
+
+// identityLabel returns the label of the identity to connect as, the only identity of the wallet
+// when none is given
+func (o *options) identityLabel(wallet *appclient.Wallet) (string, error) {
+	if o.label != "" {
+		return o.label, nil
+	}
+	labels, err := wallet.List()
+	if err != nil {
+		return "", err
+	}
+	if len(labels) != 1 {
+		return "", fmt.Errorf("--identity or $HLCC_IDENTITY must select one of the %d identities of wallet %s", len(labels), o.walletDir)
+	}
+	return labels[0], nil
+}
+
+// printJSON prints a result as indented JSON, or returns its error
+func printJSON(out io.Writer, result interface{}, err error) error {
+	if err != nil {
+		return err
+	}
+	resultJSON, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal result: %v", err)
+	}
+	_, err = fmt.Fprintln(out, string(resultJSON))
+	return err
+}
+
+// parseInt parses an integer argument
+func parseInt(name string, arg string) (int, error) {
+	value, err := strconv.Atoi(arg)
+	if err != nil {
+		return 0, fmt.Errorf("%s %q is not an integer", name, arg)
+	}
+	return value, nil
+}
+
+func envOr(name string, fallback string) string {
+	if value, ok := os.LookupEnv(name); ok {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,500 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+	"github.com/spf13/cobra"
+)
+
+// reportFile is the report of an audit report package, which is otherwise an audit package
+const reportFile = "report.json"
+
+// receiptKeyPrefix starts the keys of the transfer receipts of the token chaincode, the composite
+// keys of the receipt object type
+const receiptKeyPrefix = "\x00receipt\x00"
+
+// Types of the report entries besides the token movement types mint, burn and transfer
+const (
+	entryApproval         = "approval"
+	entryAssetCreated     = "asset-created"
+	entryAssetUpdated     = "asset-updated"
+	entryAssetTransferred = "asset-transferred"
+	entryAssetDeleted     = "asset-deleted"
+)
+
+// auditReport is the token and asset activity of an account or org in a time range, in the order
+// the transactions were committed. Each entry holds the hash of the entry before it, so removing,
+// reordering or changing an entry breaks the chain up to LastHash, which the signed manifest
+// covers through the hash of the report file.
+type auditReport struct {
+	Channel        string         `json:"channel"`
+	TokenChaincode string         `json:"tokenChaincode"`
+	AssetChaincode string         `json:"assetChaincode"`
+	Account        string         `json:"account,omitempty"`
+	Org            string         `json:"org,omitempty"`
+	Since          time.Time      `json:"since"`
+	Until          time.Time      `json:"until"`
+	FromBlock      uint64         `json:"fromBlock"`
+	ToBlock        uint64         `json:"toBlock"`
+	Counts         map[string]int `json:"counts"`
+	Entries        []*reportEntry `json:"entries"`
+	LastHash       string         `json:"lastHash"`
+}
+
+// reportEntry is a token movement, allowance change or asset change of a valid transaction. Block
+// and BlockDataHash anchor it to the ledger: the data hash of the block header covers the
+// transaction, and the header of each later block chains it.
+type reportEntry struct {
+	Seq            int       `json:"seq"`
+	Block          uint64    `json:"block"`
+	BlockDataHash  string    `json:"blockDataHash"`
+	TxIndex        int       `json:"txIndex"`
+	TxID           string    `json:"txID"`
+	Timestamp      time.Time `json:"timestamp"`
+	SubmitterMSPID string    `json:"submitterMspId"`
+	Chaincode      string    `json:"chaincode"`
+	Type           string    `json:"type"`
+	// From, To and Value are the accounts and amount of a token movement, or the owner, spender
+	// and new allowance of an approval, which replaced Previous
+	From     string `json:"from,omitempty"`
+	To       string `json:"to,omitempty"`
+	Value    *int   `json:"value,omitempty"`
+	Previous *int   `json:"previous,omitempty"`
+	// Reference is the reference of the receipt of a transfer, or the reference of an allowance
+	Reference         string `json:"reference,omitempty"`
+	AssetID           string `json:"assetID,omitempty"`
+	OwnerOrg          string `json:"ownerOrg,omitempty"`
+	PreviousOwnerOrg  string `json:"previousOwnerOrg,omitempty"`
+	PublicDescription string `json:"publicDescription,omitempty"`
+	// TraceID is the trace ID the client passed with the transaction, from its audit record
+	TraceID      string `json:"traceID,omitempty"`
+	PreviousHash string `json:"previousHash"`
+	Hash         string `json:"hash"`
+}
+
+// digest returns the hex SHA-256 hash of the JSON of the entry without its own hash
+func (e *reportEntry) digest() (string, error) {
+	unhashed := *e
+	unhashed.Hash = ""
+	entryJSON, err := json.Marshal(&unhashed)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal entry %d: %v", e.Seq, err)
+	}
+	digest := sha256.Sum256(entryJSON)
+	return hex.EncodeToString(digest[:]), nil
+}
+
+// matches tells whether the entry is activity of the account or org. An org's activity is what
+// its identities submitted and the changes of the assets it owns or owned.
+func (e *reportEntry) matches(account string, org string) bool {
+	if account != "" && (e.From == account || e.To == account) {
+		return true
+	}
+	return org != "" && (e.SubmitterMSPID == org || e.OwnerOrg == org || e.PreviousOwnerOrg == org)
+}
+
+// approvalPayload holds the fields of the Approval event payload the report reads
+type approvalPayload struct {
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Value     int    `json:"value"`
+	Previous  int    `json:"previous"`
+	Reference string `json:"reference"`
+}
+
+// reportEntries returns the entries of the token movements, allowance changes and asset changes
+// of a transaction, without sequence numbers and hashes
+func reportEntries(tx *blockTx, tokenChaincode string, assetChaincode string) []*reportEntry {
+	newEntry := func(chaincode string, entryType string) *reportEntry {
+		return &reportEntry{
+			Block:          tx.block,
+			BlockDataHash:  hex.EncodeToString(tx.blockDataHash),
+			TxIndex:        tx.index,
+			TxID:           tx.txID,
+			Timestamp:      tx.timestamp,
+			SubmitterMSPID: tx.creatorMSPID,
+			Chaincode:      chaincode,
+			Type:           entryType,
+			TraceID:        traceID(tx, chaincode),
+		}
+	}
+
+	var entries []*reportEntry
+	for _, movement := range tokenMovements(tx, tokenChaincode) {
+		entry := newEntry(movement.Chaincode, movement.Type)
+		value := movement.Value
+		entry.From, entry.To, entry.Value = movement.From, movement.To, &value
+		entry.Reference = receiptReference(tx, tokenChaincode)
+		entries = append(entries, entry)
+	}
+	for _, action := range tx.actions {
+		if action.chaincode != tokenChaincode || action.event == nil || action.event.GetEventName() != "Approval" {
+			continue
+		}
+		var payload approvalPayload
+		if json.Unmarshal(action.event.GetPayload(), &payload) != nil {
+			continue
+		}
+		entry := newEntry(action.chaincode, entryApproval)
+		entry.From, entry.To, entry.Value, entry.Previous = payload.From, payload.To, &payload.Value, &payload.Previous
+		entry.Reference = payload.Reference
+		entries = append(entries, entry)
+	}
+	for _, change := range assetChanges(tx, assetChaincode) {
+		entry := newEntry(change.Chaincode, assetEntryType(change))
+		entry.AssetID = change.AssetID
+		entry.OwnerOrg, entry.PreviousOwnerOrg = change.OwnerOrg, change.PreviousOwnerOrg
+		entry.PublicDescription = change.PublicDescription
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// assetEntryType tells the custody events of an asset, its creation, transfer and deletion, from
+// other changes
+func assetEntryType(change *assetChange) string {
+	switch {
+	case change.Deleted:
+		return entryAssetDeleted
+	case change.Event == "AssetCreated":
+		return entryAssetCreated
+	case change.Event == "AssetTransferred":
+		return entryAssetTransferred
+	}
+	return entryAssetUpdated
+}
+
+// traceID returns the trace ID of the audit record in the event the chaincode set, if any
+func traceID(tx *blockTx, chaincode string) string {
+	for _, action := range tx.actions {
+		if action.chaincode != chaincode || action.event == nil {
+			continue
+		}
+		var payload struct {
+			Audit *struct {
+				TraceID string `json:"traceID"`
+			} `json:"audit"`
+		}
+		if json.Unmarshal(action.event.GetPayload(), &payload) == nil && payload.Audit != nil {
+			return payload.Audit.TraceID
+		}
+	}
+	return ""
+}
+
+// receiptReference returns the reference of the transfer receipt the transaction wrote, if any
+func receiptReference(tx *blockTx, tokenChaincode string) string {
+	for _, action := range tx.actions {
+		if action.chaincode != tokenChaincode {
+			continue
+		}
+		for _, write := range action.writes {
+			if !strings.HasPrefix(write.GetKey(), receiptKeyPrefix) || write.GetIsDelete() {
+				continue
+			}
+			var receipt struct {
+				Reference string `json:"reference"`
+			}
+			if json.Unmarshal(write.GetValue(), &receipt) == nil {
+				return receipt.Reference
+			}
+		}
+	}
+	return ""
+}
+
+// chain numbers the entries of the report, links each to the hash of the one before it and sets
+// LastHash. The first entry links to the empty string.
+func (r *auditReport) chain() error {
+	previousHash := ""
+	r.Counts = make(map[string]int)
+	for i, entry := range r.Entries {
+		entry.Seq = i + 1
+		entry.PreviousHash = previousHash
+		hash, err := entry.digest()
+		if err != nil {
+			return err
+		}
+		entry.Hash = hash
+		previousHash = hash
+		r.Counts[entry.Type]++
+	}
+	r.LastHash = previousHash
+	return nil
+}
+
+// verifyChain checks the sequence numbers, links and hashes of the entries up to LastHash
+func (r *auditReport) verifyChain() error {
+	previousHash := ""
+	for i, entry := range r.Entries {
+		if entry.Seq != i+1 {
+			return fmt.Errorf("entry %d has sequence number %d", i+1, entry.Seq)
+		}
+		if entry.PreviousHash != previousHash {
+			return fmt.Errorf("entry %d does not link to the hash of the entry before it", entry.Seq)
+		}
+		hash, err := entry.digest()
+		if err != nil {
+			return err
+		}
+		if entry.Hash != hash {
+			return fmt.Errorf("entry %d does not match its hash", entry.Seq)
+		}
+		previousHash = hash
+	}
+	if r.LastHash != previousHash {
+		return fmt.Errorf("last hash of the report does not match its last entry")
+	}
+	return nil
+}
+
+// writeReportPackage chains the entries of the report and writes it to dir as report.json with
+// the signed manifest of an audit package
+func writeReportPackage(dir string, report *auditReport, createdAt time.Time, mspID string, certificatePEM string, sign func(digest []byte) ([]byte, error)) error {
+	err := report.chain()
+	if err != nil {
+		return err
+	}
+	reportJSON, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal report: %v", err)
+	}
+	manifest := &auditManifest{
+		CreatedAt:         createdAt.UTC(),
+		Channel:           report.Channel,
+		BlockHeight:       report.ToBlock + 1,
+		SignerMSPID:       mspID,
+		SignerCertificate: certificatePEM,
+	}
+	return writeSignedPackage(dir, manifest, []packageContent{{reportFile, append(reportJSON, '\n')}}, sign)
+}
+
+// verifyReportPackage checks the signature and hashes of the audit package in dir, as
+// verifyAuditPackage does, and the hash chain of its report
+func verifyReportPackage(dir string, roots *x509.CertPool) (*auditReport, error) {
+	_, err := verifyAuditPackage(dir, roots)
+	if err != nil {
+		return nil, err
+	}
+	reportJSON, err := os.ReadFile(filepath.Join(dir, reportFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read report: %v", err)
+	}
+	var report auditReport
+	err = json.Unmarshal(reportJSON, &report)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal report: %v", err)
+	}
+	err = report.verifyChain()
+	if err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// readReport walks blocks FromBlock to ToBlock of the report and adds the entries of their valid
+// transactions with a timestamp from Since until before Until that are activity of its account or
+// org. Timestamps are set by the submitting clients, so the entries are in commit order rather
+// than sorted by timestamp.
+func readReport(ledger *ledger, report *auditReport) error {
+	report.Entries = []*reportEntry{}
+	for number := report.FromBlock; number <= report.ToBlock; number++ {
+		transactions, err := ledger.transactions(number)
+		if err != nil {
+			return err
+		}
+		for _, tx := range transactions {
+			if tx.validationCode != peer.TxValidationCode_VALID || tx.timestamp.Before(report.Since) || !tx.timestamp.Before(report.Until) {
+				continue
+			}
+			for _, entry := range reportEntries(tx, report.TokenChaincode, report.AssetChaincode) {
+				if entry.matches(report.Account, report.Org) {
+					report.Entries = append(report.Entries, entry)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// checkAnchors compares the block data hash of each entry with the block on the ledger
+func checkAnchors(ledger *ledger, report *auditReport) error {
+	dataHashes := make(map[uint64][]byte)
+	for _, entry := range report.Entries {
+		dataHash, ok := dataHashes[entry.Block]
+		if !ok {
+			block, err := ledger.block(entry.Block)
+			if err != nil {
+				return err
+			}
+			dataHash = block.GetHeader().GetDataHash()
+			dataHashes[entry.Block] = dataHash
+		}
+		entryHash, err := hex.DecodeString(entry.BlockDataHash)
+		if err != nil || !bytes.Equal(entryHash, dataHash) {
+			return fmt.Errorf("entry %d does not match the data hash of block %d on the ledger", entry.Seq, entry.Block)
+		}
+	}
+	return nil
+}
+
+// parseTime parses a time flag given as RFC 3339 or as a date, which is midnight UTC
+func parseTime(name string, value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s %q is not an RFC 3339 time or a date", name, value)
+	}
+	return t, nil
+}
+
+func newReportCommand(opts *options) *cobra.Command {
+	reportCmd := &cobra.Command{
+		Use:   "report",
+		Short: "Create and verify signed audit reports of the token and asset activity of an account or org",
+	}
+
+	var (
+		account        string
+		org            string
+		since          string
+		until          string
+		fromBlock      uint64
+		toBlock        int64
+		outputDir      string
+		tokenChaincode string
+		assetChaincode string
+		caFile         string
+		checkLedger    bool
+	)
+	create := &cobra.Command{
+		Use:   "create",
+		Short: "Collect the token movements, allowance changes and asset changes of an account or org into a signed audit report",
+		Long: `Walk the committed blocks of the channel and write the token movements, allowance changes, transfer receipts and
+asset changes of the valid transactions of the account or org with a timestamp in the time range to report.json, in the order
+they were committed, each with its block, block data hash and the hash of the entry before it. The package is signed like
+an audit snapshot. The activity of an org is what its identities submitted and the changes of the assets it owns or owned.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if account == "" && org == "" {
+				return fmt.Errorf("--account or --org must select the activity to report")
+			}
+			report := &auditReport{
+				Channel:        opts.channel,
+				TokenChaincode: tokenChaincode,
+				AssetChaincode: assetChaincode,
+				Account:        account,
+				Org:            org,
+				FromBlock:      fromBlock,
+				Until:          time.Now().UTC(),
+			}
+			var err error
+			if since != "" {
+				if report.Since, err = parseTime("--since", since); err != nil {
+					return err
+				}
+			}
+			if until != "" {
+				if report.Until, err = parseTime("--until", until); err != nil {
+					return err
+				}
+			}
+			if !report.Since.Before(report.Until) {
+				return fmt.Errorf("--since must be before --until")
+			}
+			id, sign, err := opts.signer()
+			if err != nil {
+				return err
+			}
+
+			network, _, closeConnection, err := opts.connect()
+			if err != nil {
+				return err
+			}
+			defer closeConnection()
+			ledger := newLedger(network, opts.channel)
+			height, err := ledger.height()
+			if err != nil {
+				return err
+			}
+			report.ToBlock = height - 1
+			if toBlock >= 0 && uint64(toBlock) < report.ToBlock {
+				report.ToBlock = uint64(toBlock)
+			}
+			if fromBlock > report.ToBlock {
+				return fmt.Errorf("--from %d is after the last block %d", fromBlock, report.ToBlock)
+			}
+			err = readReport(ledger, report)
+			if err != nil {
+				return err
+			}
+
+			err = writeReportPackage(outputDir, report, time.Now(), id.MSPID, id.Credentials.Certificate, sign)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "wrote the report of %d entries of blocks %d to %d to %s\n", len(report.Entries), report.FromBlock, report.ToBlock, outputDir)
+			return nil
+		},
+	}
+	flags := create.Flags()
+	flags.StringVar(&account, "account", "", "token account ID whose movements and allowances are reported")
+	flags.StringVar(&org, "org", "", "MSP ID of the org whose transactions and assets are reported")
+	flags.StringVar(&since, "since", "", "start of the time range, as RFC 3339 or a date, the first transaction by default")
+	flags.StringVar(&until, "until", "", "end of the time range, excluded, as RFC 3339 or a date, now by default")
+	flags.Uint64Var(&fromBlock, "from", 0, "first block to read")
+	flags.Int64Var(&toBlock, "to", -1, "last block to read, the last committed block by default")
+	flags.StringVarP(&outputDir, "output", "o", "audit-report", "directory the report package is written to")
+	flags.StringVar(&tokenChaincode, "token-chaincode", envOr("HLCC_TOKEN_CHAINCODE", "token_erc20"), "name the token chaincode is deployed as, $HLCC_TOKEN_CHAINCODE")
+	flags.StringVar(&assetChaincode, "asset-chaincode", envOr("HLCC_ASSET_CHAINCODE", "secured"), "name the asset chaincode is deployed as, $HLCC_ASSET_CHAINCODE")
+
+	verify := &cobra.Command{
+		Use:   "verify <dir>",
+		Short: "Check the signature, hashes and hash chain of an audit report, and with --ledger its block data hashes",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			roots, err := readRoots(caFile)
+			if err != nil {
+				return err
+			}
+			report, err := verifyReportPackage(args[0], roots)
+			if err != nil {
+				return err
+			}
+			if checkLedger {
+				network, _, closeConnection, err := opts.connect()
+				if err != nil {
+					return err
+				}
+				defer closeConnection()
+				err = checkAnchors(newLedger(network, report.Channel), report)
+				if err != nil {
+					return err
+				}
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "verified %d entries ending with hash %s\n", len(report.Entries), report.LastHash)
+			return nil
+		},
+	}
+	verify.Flags().StringVar(&caFile, "ca", "", "PEM file of the CA certificates the signer certificate must be issued by")
+	verify.Flags().BoolVar(&checkLedger, "ledger", false, "compare the block data hash of each entry with the block on the ledger")
+
+	reportCmd.AddCommand(create, verify)
+	return reportCmd
+}
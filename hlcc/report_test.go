@@ -0,0 +1,143 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-protos-go-apiv2/ledger/rwset/kvrwset"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+)
+
+func TestReportEntries(t *testing.T) {
+	transfer := &blockTx{block: 4, blockDataHash: []byte{0xab, 0xcd}, index: 2, txID: "tx1", creatorMSPID: "Org1MSP", actions: []*txAction{{
+		chaincode: "token_erc20",
+		event: &peer.ChaincodeEvent{EventName: "Transfer",
+			Payload: []byte(`{"from":"acc1","to":"acc2","value":4,"audit":{"txID":"tx1","traceID":"trace1","changes":[]}}`)},
+		writes: []*kvrwset.KVWrite{
+			{Key: "acc1", Value: []byte("6")},
+			{Key: "acc2", Value: []byte("4")},
+			{Key: "\x00receipt\x00tx1\x00r1\x00", Value: []byte(`{"objectType":"receipt","txID":"tx1","reference":"INV-7"}`)},
+			{Key: "\x00receiptref\x00INV7\x00tx1\x00r1\x00", Value: []byte{0}},
+		},
+	}}}
+	entries := reportEntries(transfer, "token_erc20", "secured")
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(entries))
+	}
+	entry := entries[0]
+	if entry.Type != "transfer" || entry.From != "acc1" || entry.To != "acc2" || *entry.Value != 4 || entry.Reference != "INV-7" ||
+		entry.TraceID != "trace1" || entry.BlockDataHash != "abcd" || entry.SubmitterMSPID != "Org1MSP" || entry.TxIndex != 2 {
+		t.Errorf("transfer entry is %+v", entry)
+	}
+	if !entry.matches("acc2", "") || !entry.matches("", "Org1MSP") || entry.matches("acc3", "Org2MSP") {
+		t.Errorf("transfer entry matches the wrong accounts and orgs")
+	}
+
+	approval := &blockTx{creatorMSPID: "Org1MSP", actions: []*txAction{{
+		chaincode: "token_erc20",
+		event:     &peer.ChaincodeEvent{EventName: "Approval", Payload: []byte(`{"from":"acc1","to":"acc2","value":5,"previous":3,"reference":"PO-1"}`)},
+	}}}
+	entries = reportEntries(approval, "token_erc20", "secured")
+	if len(entries) != 1 || entries[0].Type != entryApproval || *entries[0].Value != 5 || *entries[0].Previous != 3 || entries[0].Reference != "PO-1" {
+		t.Errorf("approval entries are %+v", entries)
+	}
+
+	sale := &blockTx{creatorMSPID: "Org1MSP", actions: []*txAction{{
+		chaincode: "secured",
+		event: &peer.ChaincodeEvent{EventName: "AssetTransferred",
+			Payload: []byte(`{"assetID":"asset1","ownerOrg":"Org2MSP","previousOwnerOrg":"Org1MSP","publicDescription":"sold"}`)},
+		writes: []*kvrwset.KVWrite{{Key: "\x00asset\x00asset1\x00", Value: []byte(`{"objectType":"asset","assetID":"asset1","ownerOrg":"Org2MSP","publicDescription":"sold"}`)}},
+	}}}
+	entries = reportEntries(sale, "token_erc20", "secured")
+	if len(entries) != 1 || entries[0].Type != entryAssetTransferred || entries[0].OwnerOrg != "Org2MSP" || entries[0].PreviousOwnerOrg != "Org1MSP" {
+		t.Fatalf("sale entries are %+v", entries)
+	}
+	if !entries[0].matches("", "Org2MSP") || entries[0].matches("acc1", "Org3MSP") {
+		t.Errorf("sale entry matches the wrong accounts and orgs")
+	}
+}
+
+func testReport() *auditReport {
+	one, two := 1, 2
+	return &auditReport{
+		Channel:   "mychannel",
+		Account:   "acc1",
+		Until:     time.Unix(1700000000, 0).UTC(),
+		FromBlock: 0,
+		ToBlock:   20,
+		Entries: []*reportEntry{
+			{Block: 3, TxID: "tx1", Type: "mint", From: "0x0", To: "acc1", Value: &two},
+			{Block: 5, TxID: "tx2", Type: "transfer", From: "acc1", To: "acc2", Value: &one},
+			{Block: 9, TxID: "tx3", Type: entryApproval, From: "acc1", To: "acc3", Value: &one},
+		},
+	}
+}
+
+func TestReportPackage(t *testing.T) {
+	certificatePEM, _, sign := newSigner(t)
+	dir := t.TempDir()
+	err := writeReportPackage(dir, testReport(), time.Unix(1700000100, 0), "Org1MSP", certificatePEM, sign)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := verifyReportPackage(dir, nil)
+	if err != nil {
+		t.Fatalf("failed to verify report: %v", err)
+	}
+	if len(report.Entries) != 3 || report.Entries[2].Seq != 3 || report.Entries[0].PreviousHash != "" ||
+		report.Entries[1].PreviousHash != report.Entries[0].Hash || report.LastHash != report.Entries[2].Hash {
+		t.Errorf("entries are not chained: %+v", report.Entries)
+	}
+	if report.Counts["mint"] != 1 || report.Counts[entryApproval] != 1 {
+		t.Errorf("counts are %v", report.Counts)
+	}
+	manifest, err := verifyAuditPackage(dir, nil)
+	if err != nil || manifest.BlockHeight != 21 || manifest.Files[0].Name != reportFile {
+		t.Errorf("manifest is %+v, %v", manifest, err)
+	}
+
+	// the chain tells which entry was changed or removed, even when the file hash was updated
+	changed := testReport()
+	if err := changed.chain(); err != nil {
+		t.Fatal(err)
+	}
+	changed.Entries[1].To = "acc9"
+	if err := changed.verifyChain(); err == nil || !strings.Contains(err.Error(), "entry 2 does not match its hash") {
+		t.Errorf("expected an error for a changed entry, got %v", err)
+	}
+	removed := testReport()
+	if err := removed.chain(); err != nil {
+		t.Fatal(err)
+	}
+	removed.Entries = append(removed.Entries[:1], removed.Entries[2])
+	removed.Entries[1].Seq = 2
+	if err := removed.verifyChain(); err == nil || !strings.Contains(err.Error(), "entry 2 does not link") {
+		t.Errorf("expected an error for a removed entry, got %v", err)
+	}
+
+	reportPath := filepath.Join(dir, reportFile)
+	reportJSON, _ := os.ReadFile(reportPath)
+	os.WriteFile(reportPath, []byte(strings.Replace(string(reportJSON), `"to": "acc2"`, `"to": "acc9"`, 1)), 0644)
+	_, err = verifyReportPackage(dir, nil)
+	if err == nil || !strings.Contains(err.Error(), "does not match the hash") {
+		t.Errorf("expected an error for a changed report, got %v", err)
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	date, err := parseTime("--since", "2024-03-31")
+	if err != nil || !date.Equal(time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("date is %v, %v", date, err)
+	}
+	instant, err := parseTime("--until", "2024-03-31T12:00:00+02:00")
+	if err != nil || !instant.Equal(time.Date(2024, 3, 31, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("time is %v, %v", instant, err)
+	}
+	if _, err := parseTime("--since", "yesterday"); err == nil {
+		t.Errorf("expected an error for an invalid time")
+	}
+}
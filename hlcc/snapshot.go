@@ -0,0 +1,370 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-secured-agreement/application-go/asset"
+	"github.com/hyperledger/fabric-samples/asset-transfer-secured-agreement/assettypes"
+	"github.com/hyperledger/fabric-samples/internal/appclient"
+	"github.com/hyperledger/fabric-samples/token-erc-20/application-go/token"
+	"github.com/spf13/cobra"
+)
+
+// Files of an audit package
+const (
+	snapshotFile  = "snapshot.json"
+	manifestFile  = "manifest.json"
+	signatureFile = "manifest.sig"
+)
+
+// snapshot is the world state of the token and asset chaincodes at one block height
+type snapshot struct {
+	Channel        string                 `json:"channel"`
+	BlockHeight    uint64                 `json:"blockHeight"`
+	TokenChaincode string                 `json:"tokenChaincode"`
+	AssetChaincode string                 `json:"assetChaincode"`
+	TotalSupply    int                    `json:"totalSupply"`
+	Balances       []token.AccountBalance `json:"balances"`
+	Allowances     []token.OwnerAllowance `json:"allowances"`
+	Assets         []*assettypes.Asset    `json:"assets"`
+}
+
+// canonicalJSON returns the snapshot as compact JSON with its entries sorted by key, so the same
+// state always has the same bytes and hash
+func (s *snapshot) canonicalJSON() ([]byte, error) {
+	sort.Slice(s.Balances, func(i, j int) bool { return s.Balances[i].Account < s.Balances[j].Account })
+	sort.Slice(s.Allowances, func(i, j int) bool {
+		if s.Allowances[i].Owner != s.Allowances[j].Owner {
+			return s.Allowances[i].Owner < s.Allowances[j].Owner
+		}
+		return s.Allowances[i].Spender < s.Allowances[j].Spender
+	})
+	sort.Slice(s.Assets, func(i, j int) bool { return s.Assets[i].ID < s.Assets[j].ID })
+	return json.Marshal(s)
+}
+
+// auditManifest describes an audit package and is what its signature covers
+type auditManifest struct {
+	CreatedAt         time.Time     `json:"createdAt"`
+	Channel           string        `json:"channel"`
+	BlockHeight       uint64        `json:"blockHeight"`
+	Files             []packageFile `json:"files"`
+	SignerMSPID       string        `json:"signerMspId"`
+	SignerCertificate string        `json:"signerCertificate"`
+}
+
+// packageFile is the size and SHA-256 hash of a file of an audit package
+type packageFile struct {
+	Name   string `json:"name"`
+	Size   int    `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// packageContent is a file of an audit package and its data
+type packageContent struct {
+	name string
+	data []byte
+}
+
+// writeAuditPackage writes the canonical snapshot, the manifest with its hash and the signer's
+// certificate, and the base64 signature of the manifest to dir
+func writeAuditPackage(dir string, snap *snapshot, createdAt time.Time, mspID string, certificatePEM string, sign func(digest []byte) ([]byte, error)) error {
+	snapshotJSON, err := snap.canonicalJSON()
+	if err != nil {
+		return fmt.Errorf("failed to marshal snapshot: %v", err)
+	}
+	manifest := &auditManifest{
+		CreatedAt:         createdAt.UTC(),
+		Channel:           snap.Channel,
+		BlockHeight:       snap.BlockHeight,
+		SignerMSPID:       mspID,
+		SignerCertificate: certificatePEM,
+	}
+	return writeSignedPackage(dir, manifest, []packageContent{{snapshotFile, snapshotJSON}}, sign)
+}
+
+// writeSignedPackage lists the size and hash of each file in the manifest, signs the manifest and
+// writes the files, the manifest and the base64 signature to dir
+func writeSignedPackage(dir string, manifest *auditManifest, contents []packageContent, sign func(digest []byte) ([]byte, error)) error {
+	manifest.Files = nil
+	for _, content := range contents {
+		digest := sha256.Sum256(content.data)
+		manifest.Files = append(manifest.Files, packageFile{Name: content.name, Size: len(content.data), SHA256: hex.EncodeToString(digest[:])})
+	}
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %v", err)
+	}
+	manifestDigest := sha256.Sum256(manifestJSON)
+	signature, err := sign(manifestDigest[:])
+	if err != nil {
+		return fmt.Errorf("failed to sign manifest: %v", err)
+	}
+
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create output directory: %v", err)
+	}
+	contents = append(contents,
+		packageContent{manifestFile, manifestJSON},
+		packageContent{signatureFile, []byte(base64.StdEncoding.EncodeToString(signature) + "\n")},
+	)
+	for _, content := range contents {
+		err = os.WriteFile(filepath.Join(dir, content.name), content.data, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %v", content.name, err)
+		}
+	}
+	return nil
+}
+
+// verifyAuditPackage checks the signature of the manifest in dir against the certificate it names
+// and the hashes of the files it lists. With roots, the certificate must also be issued by one of
+// them.
+func verifyAuditPackage(dir string, roots *x509.CertPool) (*auditManifest, error) {
+	manifestJSON, err := os.ReadFile(filepath.Join(dir, manifestFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %v", err)
+	}
+	var manifest auditManifest
+	err = json.Unmarshal(manifestJSON, &manifest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal manifest: %v", err)
+	}
+	signatureBase64, err := os.ReadFile(filepath.Join(dir, signatureFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signature: %v", err)
+	}
+	signature, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(signatureBase64)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode signature: %v", err)
+	}
+
+	block, _ := pem.Decode([]byte(manifest.SignerCertificate))
+	if block == nil {
+		return nil, fmt.Errorf("manifest has no PEM signer certificate")
+	}
+	certificate, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse signer certificate: %v", err)
+	}
+	publicKey, ok := certificate.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("signer certificate does not have an ECDSA key")
+	}
+	manifestDigest := sha256.Sum256(manifestJSON)
+	if !ecdsa.VerifyASN1(publicKey, manifestDigest[:], signature) {
+		return nil, fmt.Errorf("signature does not match the manifest and signer certificate")
+	}
+	if roots != nil {
+		_, err = certificate.Verify(x509.VerifyOptions{Roots: roots, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny}})
+		if err != nil {
+			return nil, fmt.Errorf("signer certificate is not issued by the CA: %v", err)
+		}
+	}
+
+	for _, file := range manifest.Files {
+		data, err := os.ReadFile(filepath.Join(dir, file.Name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", file.Name, err)
+		}
+		digest := sha256.Sum256(data)
+		if hex.EncodeToString(digest[:]) != file.SHA256 || len(data) != file.Size {
+			return nil, fmt.Errorf("%s does not match the hash of the manifest", file.Name)
+		}
+	}
+	return &manifest, nil
+}
+
+// readSnapshot reads the state of the chaincodes page by page. The pages are separate queries, so
+// the block height is read before and after them and the snapshot is read again when a block was
+// committed in between, up to attempts times.
+func readSnapshot(ledger *ledger, tokens *token.Contract, assets *asset.Contract, pageSize int, attempts int) (*snapshot, error) {
+	for attempt := 0; attempt < attempts; attempt++ {
+		height, err := ledger.height()
+		if err != nil {
+			return nil, err
+		}
+		snap := &snapshot{
+			Channel:     ledger.channel,
+			BlockHeight: height,
+			Balances:    []token.AccountBalance{},
+			Allowances:  []token.OwnerAllowance{},
+			Assets:      []*assettypes.Asset{},
+		}
+		snap.TotalSupply, err = tokens.TotalSupply()
+		if err != nil {
+			return nil, err
+		}
+		for bookmark := ""; ; {
+			page, err := tokens.GetBalancesPage(pageSize, bookmark)
+			if err != nil {
+				return nil, err
+			}
+			snap.Balances = append(snap.Balances, page.Balances...)
+			if page.Bookmark == "" || page.Bookmark == bookmark {
+				break
+			}
+			bookmark = page.Bookmark
+		}
+		for bookmark := ""; ; {
+			page, err := tokens.GetAllowancesPage(pageSize, bookmark)
+			if err != nil {
+				return nil, err
+			}
+			snap.Allowances = append(snap.Allowances, page.Allowances...)
+			if page.Bookmark == "" || page.Bookmark == bookmark {
+				break
+			}
+			bookmark = page.Bookmark
+		}
+		err = assets.GetAllAssets(pageSize, func(page []*assettypes.Asset) error {
+			snap.Assets = append(snap.Assets, page...)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		after, err := ledger.height()
+		if err != nil {
+			return nil, err
+		}
+		if after == height {
+			return snap, nil
+		}
+	}
+	return nil, fmt.Errorf("blocks were committed during each of %d attempts to read the snapshot, retry when the channel is quieter", attempts)
+}
+
+func newSnapshotCommand(opts *options) *cobra.Command {
+	snapshotCmd := &cobra.Command{
+		Use:   "snapshot",
+		Short: "Create and verify signed audit packages of the token and asset state",
+	}
+
+	var (
+		outputDir      string
+		pageSize       int
+		attempts       int
+		tokenChaincode string
+		assetChaincode string
+		caFile         string
+	)
+	create := &cobra.Command{
+		Use:   "create",
+		Short: "Read the balances, allowances and assets at one block height and write them as a signed audit package",
+		Long: `Read the total supply, balances and allowances of the token chaincode and the public assets of the asset
+chaincode with evaluate transactions, and write them to snapshot.json in canonical form, manifest.json with its SHA-256
+hash, the block height and the certificate of the identity, and manifest.sig with the identity's signature of the manifest.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if pageSize <= 0 || pageSize > token.MaxPageSize || attempts <= 0 {
+				return fmt.Errorf("--page-size must be between 1 and %d and --attempts positive", token.MaxPageSize)
+			}
+			id, sign, err := opts.signer()
+			if err != nil {
+				return err
+			}
+
+			network, mspID, closeConnection, err := opts.connect()
+			if err != nil {
+				return err
+			}
+			defer closeConnection()
+			snap, err := readSnapshot(newLedger(network, opts.channel), token.NewContract(network, tokenChaincode),
+				asset.NewContract(network, assetChaincode, mspID), pageSize, attempts)
+			if err != nil {
+				return err
+			}
+			snap.TokenChaincode = tokenChaincode
+			snap.AssetChaincode = assetChaincode
+
+			err = writeAuditPackage(outputDir, snap, time.Now(), id.MSPID, id.Credentials.Certificate, sign)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "wrote the snapshot of %d balances, %d allowances and %d assets at block height %d to %s\n",
+				len(snap.Balances), len(snap.Allowances), len(snap.Assets), snap.BlockHeight, outputDir)
+			return nil
+		},
+	}
+	flags := create.Flags()
+	flags.StringVarP(&outputDir, "output", "o", "audit-package", "directory the audit package is written to")
+	flags.IntVar(&pageSize, "page-size", token.MaxPageSize, "number of entries read per query")
+	flags.IntVar(&attempts, "attempts", 3, "times to read the snapshot when blocks are committed while reading it")
+	flags.StringVar(&tokenChaincode, "token-chaincode", envOr("HLCC_TOKEN_CHAINCODE", "token_erc20"), "name the token chaincode is deployed as, $HLCC_TOKEN_CHAINCODE")
+	flags.StringVar(&assetChaincode, "asset-chaincode", envOr("HLCC_ASSET_CHAINCODE", "secured"), "name the asset chaincode is deployed as, $HLCC_ASSET_CHAINCODE")
+
+	verify := &cobra.Command{
+		Use:   "verify <dir>",
+		Short: "Check the signature and hashes of an audit package",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			roots, err := readRoots(caFile)
+			if err != nil {
+				return err
+			}
+			manifest, err := verifyAuditPackage(args[0], roots)
+			return printJSON(cmd.OutOrStdout(), manifest, err)
+		},
+	}
+	verify.Flags().StringVar(&caFile, "ca", "", "PEM file of the CA certificates the signer certificate must be issued by")
+
+	snapshotCmd.AddCommand(create, verify)
+	return snapshotCmd
+}
+
+// signer returns the wallet identity of the options and its signing function, which sign audit
+// packages
+func (o *options) signer() (*appclient.WalletIdentity, func(digest []byte) ([]byte, error), error) {
+	wallet, err := appclient.OpenWallet(o.walletDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	label, err := o.identityLabel(wallet)
+	if err != nil {
+		return nil, nil, err
+	}
+	id, err := wallet.Get(label)
+	if err != nil {
+		return nil, nil, err
+	}
+	sign, err := id.Sign()
+	if err != nil {
+		return nil, nil, err
+	}
+	return id, sign, nil
+}
+
+// readRoots reads the CA certificates of a PEM file, or returns nil when caFile is empty
+func readRoots(caFile string) (*x509.CertPool, error) {
+	if caFile == "" {
+		return nil, nil
+	}
+	caPEM, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA certificate: %v", err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("%s holds no PEM certificate", caFile)
+	}
+	return roots, nil
+}
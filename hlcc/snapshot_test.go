@@ -0,0 +1,127 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-secured-agreement/assettypes"
+	"github.com/hyperledger/fabric-samples/token-erc-20/application-go/token"
+)
+
+// newSigner returns a self-signed CA certificate as PEM and a signing function of its key
+func newSigner(t *testing.T) (string, *x509.Certificate, func(digest []byte) ([]byte, error)) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "auditor"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	certificate, _ := x509.ParseCertificate(der)
+	certificatePEM := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	return certificatePEM, certificate, func(digest []byte) ([]byte, error) {
+		return ecdsa.SignASN1(rand.Reader, key, digest)
+	}
+}
+
+func testSnapshot() *snapshot {
+	return &snapshot{
+		Channel:     "mychannel",
+		BlockHeight: 12,
+		TotalSupply: 30,
+		Balances:    []token.AccountBalance{{Account: "b", Balance: 20}, {Account: "a", Balance: 10}},
+		Allowances:  []token.OwnerAllowance{{Owner: "b", Spender: "a", Allowance: 1}, {Owner: "a", Spender: "c", Allowance: 2}, {Owner: "a", Spender: "b", Allowance: 3}},
+		Assets:      []*assettypes.Asset{{ID: "asset2", OwnerOrg: "Org2MSP"}, {ID: "asset1", OwnerOrg: "Org1MSP"}},
+	}
+}
+
+func TestSnapshotCanonicalJSON(t *testing.T) {
+	first, err := testSnapshot().canonicalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	reordered := testSnapshot()
+	reordered.Balances[0], reordered.Balances[1] = reordered.Balances[1], reordered.Balances[0]
+	reordered.Allowances[0], reordered.Allowances[2] = reordered.Allowances[2], reordered.Allowances[0]
+	second, err := reordered.canonicalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(first) != string(second) {
+		t.Errorf("canonical JSON depends on the order of the entries:\n%s\n%s", first, second)
+	}
+	if !strings.Contains(string(first), `"balances":[{"account":"a","balance":10},{"account":"b","balance":20}]`) {
+		t.Errorf("balances are not sorted: %s", first)
+	}
+	if !strings.Contains(string(first), `"allowances":[{"owner":"a","spender":"b","allowance":3},{"owner":"a","spender":"c","allowance":2},{"owner":"b","spender":"a","allowance":1}]`) {
+		t.Errorf("allowances are not sorted: %s", first)
+	}
+}
+
+func TestAuditPackage(t *testing.T) {
+	certificatePEM, certificate, sign := newSigner(t)
+	dir := t.TempDir()
+	err := writeAuditPackage(dir, testSnapshot(), time.Unix(1600000000, 0), "Org1MSP", certificatePEM, sign)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manifest, err := verifyAuditPackage(dir, nil)
+	if err != nil {
+		t.Fatalf("failed to verify package: %v", err)
+	}
+	if manifest.BlockHeight != 12 || manifest.SignerMSPID != "Org1MSP" || len(manifest.Files) != 1 || manifest.Files[0].Name != snapshotFile {
+		t.Errorf("manifest is %+v", manifest)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(certificate)
+	_, err = verifyAuditPackage(dir, roots)
+	if err != nil {
+		t.Errorf("failed to verify package against the CA: %v", err)
+	}
+	_, otherCA, _ := newSigner(t)
+	otherRoots := x509.NewCertPool()
+	otherRoots.AddCert(otherCA)
+	_, err = verifyAuditPackage(dir, otherRoots)
+	if err == nil || !strings.Contains(err.Error(), "not issued by the CA") {
+		t.Errorf("expected an error for another CA, got %v", err)
+	}
+
+	snapshotPath := filepath.Join(dir, snapshotFile)
+	snapshotJSON, _ := os.ReadFile(snapshotPath)
+	os.WriteFile(snapshotPath, []byte(strings.Replace(string(snapshotJSON), `"balance":10`, `"balance":11`, 1)), 0644)
+	_, err = verifyAuditPackage(dir, nil)
+	if err == nil || !strings.Contains(err.Error(), "does not match the hash") {
+		t.Errorf("expected an error for a changed snapshot, got %v", err)
+	}
+
+	manifestPath := filepath.Join(dir, manifestFile)
+	manifestJSON, _ := os.ReadFile(manifestPath)
+	os.WriteFile(manifestPath, []byte(strings.Replace(string(manifestJSON), `"blockHeight": 12`, `"blockHeight": 13`, 1)), 0644)
+	_, err = verifyAuditPackage(dir, nil)
+	if err == nil || !strings.Contains(err.Error(), "signature does not match") {
+		t.Errorf("expected an error for a changed manifest, got %v", err)
+	}
+}
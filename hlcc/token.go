@@ -0,0 +1,135 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"github.com/hyperledger/fabric-samples/token-erc-20/application-go/token"
+	"github.com/spf13/cobra"
+)
+
+func newTokenCommand(opts *options) *cobra.Command {
+	var chaincode string
+	tokenCmd := &cobra.Command{
+		Use:   "token",
+		Short: "Call the token-erc-20 chaincode",
+	}
+	tokenCmd.PersistentFlags().StringVar(&chaincode, "chaincode", envOr("HLCC_TOKEN_CHAINCODE", "token_erc20"), "name the token chaincode is deployed as, $HLCC_TOKEN_CHAINCODE")
+
+	// run connects and runs fn with the token contract, printing its result
+	run := func(fn func(contract *token.Contract, args []string) (interface{}, error)) func(cmd *cobra.Command, args []string) error {
+		return func(cmd *cobra.Command, args []string) error {
+			network, _, closeConnection, err := opts.connect()
+			if err != nil {
+				return err
+			}
+			defer closeConnection()
+			result, err := fn(token.NewContract(network, chaincode), args)
+			return printJSON(cmd.OutOrStdout(), result, err)
+		}
+	}
+
+	tokenCmd.AddCommand(
+		&cobra.Command{
+			Use:   "mint <amount>",
+			Short: "Create tokens in the identity's account",
+			Args:  cobra.ExactArgs(1),
+			RunE: run(func(contract *token.Contract, args []string) (interface{}, error) {
+				amount, err := parseInt("amount", args[0])
+				if err != nil {
+					return nil, err
+				}
+				return contract.Mint(amount)
+			}),
+		},
+		&cobra.Command{
+			Use:   "burn <amount>",
+			Short: "Remove tokens from the identity's account",
+			Args:  cobra.ExactArgs(1),
+			RunE: run(func(contract *token.Contract, args []string) (interface{}, error) {
+				amount, err := parseInt("amount", args[0])
+				if err != nil {
+					return nil, err
+				}
+				return contract.Burn(amount)
+			}),
+		},
+		&cobra.Command{
+			Use:   "transfer <receiver> <amount>",
+			Short: "Move tokens to another account",
+			Args:  cobra.ExactArgs(2),
+			RunE: run(func(contract *token.Contract, args []string) (interface{}, error) {
+				amount, err := parseInt("amount", args[1])
+				if err != nil {
+					return nil, err
+				}
+				return contract.Transfer(args[0], amount)
+			}),
+		},
+		&cobra.Command{
+			Use:   "approve <spender> <amount>",
+			Short: "Allow spender to move tokens of the identity's account",
+			Args:  cobra.ExactArgs(2),
+			RunE: run(func(contract *token.Contract, args []string) (interface{}, error) {
+				amount, err := parseInt("amount", args[1])
+				if err != nil {
+					return nil, err
+				}
+				return contract.Approve(args[0], amount)
+			}),
+		},
+		&cobra.Command{
+			Use:   "transfer-from <from> <receiver> <amount>",
+			Short: "Spend an allowance given to the identity",
+			Args:  cobra.ExactArgs(3),
+			RunE: run(func(contract *token.Contract, args []string) (interface{}, error) {
+				amount, err := parseInt("amount", args[2])
+				if err != nil {
+					return nil, err
+				}
+				return contract.TransferFrom(args[0], args[1], amount)
+			}),
+		},
+		&cobra.Command{
+			Use:   "balance [account]",
+			Short: "Balance of an account, the identity's by default",
+			Args:  cobra.MaximumNArgs(1),
+			RunE: run(func(contract *token.Contract, args []string) (interface{}, error) {
+				if len(args) == 0 {
+					account, err := contract.ClientAccountID()
+					if err != nil {
+						return nil, err
+					}
+					args = []string{account}
+				}
+				return contract.BalanceOf(args[0])
+			}),
+		},
+		&cobra.Command{
+			Use:   "allowance <owner> <spender>",
+			Short: "Allowance owner gave spender",
+			Args:  cobra.ExactArgs(2),
+			RunE: run(func(contract *token.Contract, args []string) (interface{}, error) {
+				return contract.Allowance(args[0], args[1])
+			}),
+		},
+		&cobra.Command{
+			Use:   "id",
+			Short: "Account ID of the identity",
+			Args:  cobra.NoArgs,
+			RunE: run(func(contract *token.Contract, args []string) (interface{}, error) {
+				return contract.ClientAccountID()
+			}),
+		},
+		&cobra.Command{
+			Use:   "audit <txID>",
+			Short: "Audit record of a transaction, if kept on the ledger",
+			Args:  cobra.ExactArgs(1),
+			RunE: run(func(contract *token.Contract, args []string) (interface{}, error) {
+				return contract.GetAuditRecord(args[0])
+			}),
+		},
+	)
+	return tokenCmd
+}
@@ -0,0 +1,130 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-samples/internal/appclient"
+	"github.com/spf13/cobra"
+)
+
+func newWalletCommand(opts *options) *cobra.Command {
+	wallet := &cobra.Command{
+		Use:   "wallet",
+		Short: "Manage the identities of the wallet",
+	}
+
+	var mspID string
+	importCmd := &cobra.Command{
+		Use:     "import <label> <msp directory>",
+		Short:   "Store the certificate and key of an MSP directory in the wallet",
+		Example: "  hlcc wallet import org1-user1 --msp-id Org1MSP test-network/organizations/peerOrganizations/org1.example.com/users/User1@org1.example.com/msp",
+		Args:    cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			w, err := appclient.OpenWallet(opts.walletDir)
+			if err != nil {
+				return err
+			}
+			err = w.Import(args[0], mspID, args[1])
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintf(cmd.OutOrStdout(), "imported %s of %s\n", args[0], mspID)
+			return err
+		},
+	}
+	importCmd.Flags().StringVar(&mspID, "msp-id", "", "MSP ID of the identity's org")
+	importCmd.MarkFlagRequired("msp-id")
+
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List the identities of the wallet",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			w, err := appclient.OpenWallet(opts.walletDir)
+			if err != nil {
+				return err
+			}
+			labels, err := w.List()
+			if err != nil {
+				return err
+			}
+			for _, label := range labels {
+				id, err := w.Get(label)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "%s\t%s\n", label, id.MSPID)
+			}
+			return nil
+		},
+	}
+
+	var secret, caName string
+	enroll := &cobra.Command{
+		Use:     "enroll <label> <enrollment ID>",
+		Short:   "Enroll an identity registered at the certificate authority of the profile's org and store it in the wallet",
+		Example: "  hlcc wallet enroll org1-user1 user1 --secret user1pw -p test-network/organizations/peerOrganizations/org1.example.com/connection-org1.json",
+		Args:    cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			w, profile, ca, err := opts.openCA(caName)
+			if err != nil {
+				return err
+			}
+			err = w.Enroll(args[0], profile.MSPID(), ca, args[1], secret)
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintf(cmd.OutOrStdout(), "enrolled %s of %s as %s\n", args[1], profile.MSPID(), args[0])
+			return err
+		},
+	}
+	enroll.Flags().StringVar(&secret, "secret", "", "enrollment secret the identity was registered with")
+	enroll.Flags().StringVar(&caName, "ca", "", "certificate authority of the profile, the first one of the org by default")
+	enroll.MarkFlagRequired("secret")
+
+	reenroll := &cobra.Command{
+		Use:   "reenroll <label>",
+		Short: "Replace the certificate and private key of a wallet identity with new ones from its certificate authority",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			w, _, ca, err := opts.openCA(caName)
+			if err != nil {
+				return err
+			}
+			err = w.Reenroll(args[0], ca)
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintf(cmd.OutOrStdout(), "reenrolled %s\n", args[0])
+			return err
+		},
+	}
+	reenroll.Flags().StringVar(&caName, "ca", "", "certificate authority of the profile, the first one of the org by default")
+
+	wallet.AddCommand(importCmd, enroll, reenroll, list)
+	return wallet
+}
+
+// openCA opens the wallet and returns the named certificate authority of the connection profile
+func (o *options) openCA(caName string) (*appclient.Wallet, *appclient.Profile, *appclient.CertificateAuthority, error) {
+	if o.profile == "" {
+		return nil, nil, nil, fmt.Errorf("--profile or $HLCC_PROFILE must name the connection profile of the identity's org")
+	}
+	profile, err := appclient.LoadProfile(o.profile)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	ca, err := profile.CA(caName)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	w, err := appclient.OpenWallet(o.walletDir)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return w, profile, ca, nil
+}
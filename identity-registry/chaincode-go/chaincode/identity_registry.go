@@ -0,0 +1,233 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// object names for prefix
+const (
+	profilePrefix    = "profile"
+	orgProfilePrefix = "orgProfile"
+	orgClientPrefix  = "org~client"
+)
+
+// adminOU is the organizational unit Fabric CA puts in admin certificates when NodeOUs are enabled
+const adminOU = "admin"
+
+// SmartContract provides functions for publishing verified profiles of client identities
+type SmartContract struct {
+	contractapi.Contract
+}
+
+// Profile is the verified profile of a client identity. The contact details themselves stay off
+// chain, only their hash is published.
+type Profile struct {
+	ObjectType   string `json:"objectType"`
+	ClientID     string `json:"clientID"`
+	MSPID        string `json:"mspID"`
+	Name         string `json:"name"`
+	Role         string `json:"role"`
+	ContactHash  string `json:"contactHash"`
+	RegisteredBy string `json:"registeredBy"`
+}
+
+// OrgProfile is the human readable description of an organization
+type OrgProfile struct {
+	ObjectType  string `json:"objectType"`
+	MSPID       string `json:"mspID"`
+	Name        string `json:"name"`
+	ContactHash string `json:"contactHash"`
+}
+
+// SetOrgProfile publishes the display name of the client's org. Only an admin of the org can set it.
+func (s *SmartContract) SetOrgProfile(ctx contractapi.TransactionContextInterface, name string, contactHash string) error {
+	mspID, err := _requireOrgAdmin(ctx)
+	if err != nil {
+		return err
+	}
+
+	if name == "" {
+		return fmt.Errorf("org name must be set")
+	}
+	err = _validateContactHash(contactHash)
+	if err != nil {
+		return err
+	}
+
+	orgProfile := OrgProfile{
+		ObjectType:  orgProfilePrefix,
+		MSPID:       mspID,
+		Name:        name,
+		ContactHash: contactHash,
+	}
+	orgProfileJSON, err := json.Marshal(orgProfile)
+	if err != nil {
+		return fmt.Errorf("failed to marshal org profile: %v", err)
+	}
+
+	orgProfileKey, err := ctx.GetStub().CreateCompositeKey(orgProfilePrefix, []string{mspID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	return ctx.GetStub().PutState(orgProfileKey, orgProfileJSON)
+}
+
+// RegisterProfile publishes or updates the verified profile of a client identity belonging to the
+// admin's org. clientID is the value returned by GetClientIdentity().GetID() for that client.
+func (s *SmartContract) RegisterProfile(ctx contractapi.TransactionContextInterface, clientID string, name string, role string, contactHash string) error {
+	mspID, err := _requireOrgAdmin(ctx)
+	if err != nil {
+		return err
+	}
+	admin, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	if clientID == "" || name == "" || role == "" {
+		return fmt.Errorf("client ID, name and role must be set")
+	}
+	err = _validateContactHash(contactHash)
+	if err != nil {
+		return err
+	}
+
+	existing, err := _getProfile(ctx, clientID)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.MSPID != mspID {
+		return fmt.Errorf("client %s is registered by %s and cannot be changed by an admin of %s", clientID, existing.MSPID, mspID)
+	}
+
+	profile := Profile{
+		ObjectType:   profilePrefix,
+		ClientID:     clientID,
+		MSPID:        mspID,
+		Name:         name,
+		Role:         role,
+		ContactHash:  contactHash,
+		RegisteredBy: admin,
+	}
+	profileJSON, err := json.Marshal(profile)
+	if err != nil {
+		return fmt.Errorf("failed to marshal profile: %v", err)
+	}
+
+	profileKey, err := ctx.GetStub().CreateCompositeKey(profilePrefix, []string{clientID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = ctx.GetStub().PutState(profileKey, profileJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put profile: %v", err)
+	}
+
+	indexKey, err := ctx.GetStub().CreateCompositeKey(orgClientPrefix, []string{mspID, clientID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = ctx.GetStub().PutState(indexKey, []byte{0x00})
+	if err != nil {
+		return fmt.Errorf("failed to put org index: %v", err)
+	}
+
+	return ctx.GetStub().SetEvent("ProfileRegistered", profileJSON)
+}
+
+// RevokeProfile removes the profile of a client identity registered by the admin's org
+func (s *SmartContract) RevokeProfile(ctx contractapi.TransactionContextInterface, clientID string) error {
+	mspID, err := _requireOrgAdmin(ctx)
+	if err != nil {
+		return err
+	}
+
+	existing, err := _getProfile(ctx, clientID)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return fmt.Errorf("client %s has no registered profile", clientID)
+	}
+	if existing.MSPID != mspID {
+		return fmt.Errorf("client %s is registered by %s and cannot be revoked by an admin of %s", clientID, existing.MSPID, mspID)
+	}
+
+	profileKey, err := ctx.GetStub().CreateCompositeKey(profilePrefix, []string{clientID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = ctx.GetStub().DelState(profileKey)
+	if err != nil {
+		return fmt.Errorf("failed to delete profile: %v", err)
+	}
+
+	indexKey, err := ctx.GetStub().CreateCompositeKey(orgClientPrefix, []string{mspID, clientID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	return ctx.GetStub().DelState(indexKey)
+}
+
+// _requireOrgAdmin checks the client holds an admin certificate and returns its org
+func _requireOrgAdmin(ctx contractapi.TransactionContextInterface) (string, error) {
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get MSPID: %v", err)
+	}
+
+	cert, err := ctx.GetClientIdentity().GetX509Certificate()
+	if err != nil {
+		return "", fmt.Errorf("failed to get client certificate: %v", err)
+	}
+	for _, ou := range cert.Subject.OrganizationalUnit {
+		if ou == adminOU {
+			return mspID, nil
+		}
+	}
+
+	return "", fmt.Errorf("client is not an admin of %s", mspID)
+}
+
+// _validateContactHash checks the contact hash is empty or a hex SHA-256 digest
+func _validateContactHash(contactHash string) error {
+	if contactHash == "" {
+		return nil
+	}
+	hash, err := hex.DecodeString(contactHash)
+	if err != nil || len(hash) != 32 {
+		return fmt.Errorf("contact hash must be a hex encoded SHA-256 digest")
+	}
+	return nil
+}
+
+// _getProfile reads a profile, returning nil when the client has none
+func _getProfile(ctx contractapi.TransactionContextInterface, clientID string) (*Profile, error) {
+	profileKey, err := ctx.GetStub().CreateCompositeKey(profilePrefix, []string{clientID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	profileJSON, err := ctx.GetStub().GetState(profileKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if profileJSON == nil {
+		return nil, nil
+	}
+
+	var profile Profile
+	err = json.Unmarshal(profileJSON, &profile)
+	if err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
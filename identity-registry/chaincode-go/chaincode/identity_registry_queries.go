@@ -0,0 +1,81 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// GetProfile returns the verified profile of a client identity. Other chaincodes call this with
+// InvokeChaincode to show a name instead of a base64 client ID.
+func (s *SmartContract) GetProfile(ctx contractapi.TransactionContextInterface, clientID string) (*Profile, error) {
+	profile, err := _getProfile(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if profile == nil {
+		return nil, fmt.Errorf("client %s has no registered profile", clientID)
+	}
+	return profile, nil
+}
+
+// GetOrgProfile returns the display profile of an organization
+func (s *SmartContract) GetOrgProfile(ctx contractapi.TransactionContextInterface, mspID string) (*OrgProfile, error) {
+	orgProfileKey, err := ctx.GetStub().CreateCompositeKey(orgProfilePrefix, []string{mspID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	orgProfileJSON, err := ctx.GetStub().GetState(orgProfileKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if orgProfileJSON == nil {
+		return nil, fmt.Errorf("org %s has no registered profile", mspID)
+	}
+
+	var orgProfile OrgProfile
+	err = json.Unmarshal(orgProfileJSON, &orgProfile)
+	if err != nil {
+		return nil, err
+	}
+	return &orgProfile, nil
+}
+
+// GetProfilesByOrg returns the profiles an org has registered for its clients
+func (s *SmartContract) GetProfilesByOrg(ctx contractapi.TransactionContextInterface, mspID string) ([]*Profile, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(orgClientPrefix, []string{mspID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get profiles of org %s: %v", mspID, err)
+	}
+	defer resultsIterator.Close()
+
+	var profiles []*Profile
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(response.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split composite key: %v", err)
+		}
+		if len(keyParts) != 2 {
+			return nil, fmt.Errorf("unexpected index key %s", response.Key)
+		}
+
+		profile, err := s.GetProfile(ctx, keyParts[1])
+		if err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, profile)
+	}
+
+	return profiles, nil
+}
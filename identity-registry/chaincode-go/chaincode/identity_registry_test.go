@@ -0,0 +1,91 @@
+package chaincode
+
+import (
+	"testing"
+)
+
+const contactHash = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+
+func checkError(t *testing.T, err error, expected string) {
+	t.Helper()
+	if expected == "" && err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected != "" && (err == nil || err.Error() != expected) {
+		t.Fatalf("expected error %q, got %v", expected, err)
+	}
+}
+
+func TestSetOrgProfile(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+
+	err := contract.SetOrgProfile(newContext(stub, "alice", "Org1MSP"), "Org One Bank", contactHash)
+	checkError(t, err, "client is not an admin of Org1MSP")
+
+	err = contract.SetOrgProfile(newAdminContext(stub, "admin1", "Org1MSP"), "Org One Bank", "abc")
+	checkError(t, err, "contact hash must be a hex encoded SHA-256 digest")
+
+	checkError(t, contract.SetOrgProfile(newAdminContext(stub, "admin1", "Org1MSP"), "Org One Bank", contactHash), "")
+
+	orgProfile, err := contract.GetOrgProfile(newContext(stub, "bob", "Org2MSP"), "Org1MSP")
+	checkError(t, err, "")
+	if orgProfile.Name != "Org One Bank" || orgProfile.ContactHash != contactHash {
+		t.Fatalf("unexpected org profile %+v", orgProfile)
+	}
+
+	_, err = contract.GetOrgProfile(newContext(stub, "bob", "Org2MSP"), "Org2MSP")
+	checkError(t, err, "org Org2MSP has no registered profile")
+}
+
+func TestRegisterProfile(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+
+	err := contract.RegisterProfile(newContext(stub, "alice", "Org1MSP"), "alice", "Alice", "trader", "")
+	checkError(t, err, "client is not an admin of Org1MSP")
+
+	checkError(t, contract.RegisterProfile(newAdminContext(stub, "admin1", "Org1MSP"), "alice", "Alice", "trader", contactHash), "")
+	checkError(t, contract.RegisterProfile(newAdminContext(stub, "admin1", "Org1MSP"), "carol", "Carol", "auditor", ""), "")
+	if stub.eventName != "ProfileRegistered" {
+		t.Fatalf("expected ProfileRegistered event, got %q", stub.eventName)
+	}
+
+	err = contract.RegisterProfile(newAdminContext(stub, "admin2", "Org2MSP"), "alice", "Mallory", "trader", "")
+	checkError(t, err, "client alice is registered by Org1MSP and cannot be changed by an admin of Org2MSP")
+
+	profile, err := contract.GetProfile(newContext(stub, "bob", "Org2MSP"), "alice")
+	checkError(t, err, "")
+	if profile.Name != "Alice" || profile.MSPID != "Org1MSP" || profile.RegisteredBy != "admin1" {
+		t.Fatalf("unexpected profile %+v", profile)
+	}
+
+	profiles, err := contract.GetProfilesByOrg(newContext(stub, "bob", "Org2MSP"), "Org1MSP")
+	checkError(t, err, "")
+	if len(profiles) != 2 || profiles[0].ClientID != "alice" || profiles[1].ClientID != "carol" {
+		t.Fatalf("unexpected profiles %+v", profiles)
+	}
+}
+
+func TestRevokeProfile(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	checkError(t, contract.RegisterProfile(newAdminContext(stub, "admin1", "Org1MSP"), "alice", "Alice", "trader", ""), "")
+
+	err := contract.RevokeProfile(newAdminContext(stub, "admin2", "Org2MSP"), "alice")
+	checkError(t, err, "client alice is registered by Org1MSP and cannot be revoked by an admin of Org2MSP")
+
+	checkError(t, contract.RevokeProfile(newAdminContext(stub, "admin1", "Org1MSP"), "alice"), "")
+
+	_, err = contract.GetProfile(newContext(stub, "bob", "Org2MSP"), "alice")
+	checkError(t, err, "client alice has no registered profile")
+
+	profiles, err := contract.GetProfilesByOrg(newContext(stub, "bob", "Org2MSP"), "Org1MSP")
+	checkError(t, err, "")
+	if len(profiles) != 0 {
+		t.Fatalf("expected no profiles, got %+v", profiles)
+	}
+
+	err = contract.RevokeProfile(newAdminContext(stub, "admin1", "Org1MSP"), "alice")
+	checkError(t, err, "client alice has no registered profile")
+}
@@ -0,0 +1,23 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"log"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/identity-registry/chaincode-go/chaincode"
+)
+
+func main() {
+	identityChaincode, err := contractapi.NewChaincode(&chaincode.SmartContract{})
+	if err != nil {
+		log.Panicf("Error creating identity-registry chaincode: %v", err)
+	}
+
+	if err := identityChaincode.Start(); err != nil {
+		log.Panicf("Error starting identity-registry chaincode: %v", err)
+	}
+}
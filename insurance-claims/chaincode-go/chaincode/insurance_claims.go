@@ -0,0 +1,397 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// chaincode names used in the test network READMEs
+const (
+	defaultAssetChaincode = "secured"
+	defaultTokenChaincode = "token_erc20"
+)
+
+// object names for prefix
+const (
+	policyPrefix      = "policy"
+	claimPrefix       = "claim"
+	policyClaimPrefix = "policy~claim"
+)
+
+// claim status values
+const (
+	claimFiled    = "FILED"
+	claimAssessed = "ASSESSED"
+	claimApproved = "APPROVED"
+	claimDenied   = "DENIED"
+	claimPaid     = "PAID"
+)
+
+// SmartContract provides functions for issuing policies and settling claims against them
+type SmartContract struct {
+	contractapi.Contract
+}
+
+// Policy insures an asset registered in the asset transfer chaincode
+type Policy struct {
+	ObjectType     string `json:"objectType"`
+	ID             string `json:"policyID"`
+	AssetID        string `json:"assetID"`
+	Holder         string `json:"holder"`
+	HolderOrg      string `json:"holderOrg"`
+	InsurerOrg     string `json:"insurerOrg"`
+	AdjusterOrg    string `json:"adjusterOrg"`
+	Coverage       int    `json:"coverage"`
+	PaidOut        int    `json:"paidOut"`
+	TokenChaincode string `json:"tokenChaincode"`
+}
+
+// Claim is filed by the policy holder and moves through assessment, decision and payout
+type Claim struct {
+	ObjectType     string   `json:"objectType"`
+	ID             string   `json:"claimID"`
+	PolicyID       string   `json:"policyID"`
+	ClaimedAmount  int      `json:"claimedAmount"`
+	Description    string   `json:"description"`
+	EvidenceHashes []string `json:"evidenceHashes"`
+	AssessedAmount int      `json:"assessedAmount"`
+	AssessorNotes  string   `json:"assessorNotes,omitempty"`
+	DenialReason   string   `json:"denialReason,omitempty"`
+	Status         string   `json:"status"`
+	PaymentTxID    string   `json:"paymentTxID,omitempty"`
+}
+
+// asset is the part of the asset transfer chaincode's Asset the insurer relies on
+type asset struct {
+	ID       string `json:"assetID"`
+	OwnerOrg string `json:"ownerOrg"`
+}
+
+// event provides an organized struct for emitting claim status events
+type event struct {
+	ClaimID  string `json:"claimID"`
+	PolicyID string `json:"policyID"`
+	Status   string `json:"status"`
+	Amount   int    `json:"amount"`
+}
+
+// IssuePolicy is called by the insurer to cover an asset owned by the holder's org. The asset is
+// read from the asset chaincode to confirm it exists and that the holder's org owns it.
+// holder is the client ID that will receive payouts.
+func (s *SmartContract) IssuePolicy(ctx contractapi.TransactionContextInterface, policyID string, assetID string, holder string, holderOrg string, adjusterOrg string, coverage int, assetChaincode string, tokenChaincode string) error {
+	insurerOrg, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+
+	if policyID == "" || holder == "" || holderOrg == "" || adjusterOrg == "" {
+		return fmt.Errorf("policy ID, holder, holder org and adjuster org must be set")
+	}
+	if coverage <= 0 {
+		return fmt.Errorf("coverage must be a positive integer")
+	}
+	if assetChaincode == "" {
+		assetChaincode = defaultAssetChaincode
+	}
+	if tokenChaincode == "" {
+		tokenChaincode = defaultTokenChaincode
+	}
+
+	policyKey, err := ctx.GetStub().CreateCompositeKey(policyPrefix, []string{policyID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	existing, err := ctx.GetStub().GetState(policyKey)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if existing != nil {
+		return fmt.Errorf("the policy %s already exists", policyID)
+	}
+
+	insured, err := _readAsset(ctx, assetChaincode, assetID)
+	if err != nil {
+		return err
+	}
+	if insured.OwnerOrg != holderOrg {
+		return fmt.Errorf("asset %s is owned by %s, not by the policy holder org %s", assetID, insured.OwnerOrg, holderOrg)
+	}
+
+	policy := Policy{
+		ObjectType:     policyPrefix,
+		ID:             policyID,
+		AssetID:        assetID,
+		Holder:         holder,
+		HolderOrg:      holderOrg,
+		InsurerOrg:     insurerOrg,
+		AdjusterOrg:    adjusterOrg,
+		Coverage:       coverage,
+		TokenChaincode: tokenChaincode,
+	}
+	policyJSON, err := json.Marshal(policy)
+	if err != nil {
+		return fmt.Errorf("failed to marshal policy: %v", err)
+	}
+	err = ctx.GetStub().PutState(policyKey, policyJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put policy %s: %v", policyID, err)
+	}
+
+	return ctx.GetStub().SetEvent("PolicyIssued", policyJSON)
+}
+
+// FileClaim is called by the policy holder. evidenceHashes are hex SHA-256 digests of photos,
+// reports and receipts kept off chain.
+func (s *SmartContract) FileClaim(ctx contractapi.TransactionContextInterface, claimID string, policyID string, claimedAmount int, description string, evidenceHashes []string) error {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	policy, err := s.ReadPolicy(ctx, policyID)
+	if err != nil {
+		return err
+	}
+	if clientID != policy.Holder {
+		return fmt.Errorf("only the policy holder can file a claim on policy %s", policyID)
+	}
+	if claimID == "" || description == "" {
+		return fmt.Errorf("claim ID and description must be set")
+	}
+	if claimedAmount <= 0 {
+		return fmt.Errorf("claimed amount must be a positive integer")
+	}
+	if claimedAmount > policy.Coverage-policy.PaidOut {
+		return fmt.Errorf("claimed amount %d exceeds the remaining coverage %d", claimedAmount, policy.Coverage-policy.PaidOut)
+	}
+	if len(evidenceHashes) == 0 {
+		return fmt.Errorf("at least one piece of evidence must be provided")
+	}
+	for _, evidenceHash := range evidenceHashes {
+		hash, err := hex.DecodeString(evidenceHash)
+		if err != nil || len(hash) != 32 {
+			return fmt.Errorf("evidence hash %s is not a hex encoded SHA-256 digest", evidenceHash)
+		}
+	}
+
+	claimKey, err := ctx.GetStub().CreateCompositeKey(claimPrefix, []string{claimID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	existing, err := ctx.GetStub().GetState(claimKey)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if existing != nil {
+		return fmt.Errorf("the claim %s already exists", claimID)
+	}
+
+	indexKey, err := ctx.GetStub().CreateCompositeKey(policyClaimPrefix, []string{policyID, claimID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = ctx.GetStub().PutState(indexKey, []byte{0x00})
+	if err != nil {
+		return fmt.Errorf("failed to put claim index: %v", err)
+	}
+
+	claim := Claim{
+		ObjectType:     claimPrefix,
+		ID:             claimID,
+		PolicyID:       policyID,
+		ClaimedAmount:  claimedAmount,
+		Description:    description,
+		EvidenceHashes: evidenceHashes,
+		Status:         claimFiled,
+	}
+	return _putClaim(ctx, &claim, claimedAmount)
+}
+
+// AssessClaim is called by the policy's adjuster org to record the loss it has assessed
+func (s *SmartContract) AssessClaim(ctx contractapi.TransactionContextInterface, claimID string, assessedAmount int, notes string) error {
+	claim, policy, err := s._readClaimAndPolicy(ctx, claimID)
+	if err != nil {
+		return err
+	}
+
+	err = _requireOrg(ctx, policy.AdjusterOrg, "assess claims")
+	if err != nil {
+		return err
+	}
+	if claim.Status != claimFiled && claim.Status != claimAssessed {
+		return fmt.Errorf("claim %s is %s and cannot be assessed", claimID, claim.Status)
+	}
+	if assessedAmount < 0 || assessedAmount > claim.ClaimedAmount {
+		return fmt.Errorf("assessed amount must be between 0 and the claimed amount %d", claim.ClaimedAmount)
+	}
+
+	claim.AssessedAmount = assessedAmount
+	claim.AssessorNotes = notes
+	claim.Status = claimAssessed
+	return _putClaim(ctx, claim, assessedAmount)
+}
+
+// ApproveClaim is called by the insurer to accept the adjuster's assessment
+func (s *SmartContract) ApproveClaim(ctx contractapi.TransactionContextInterface, claimID string) error {
+	claim, policy, err := s._readClaimAndPolicy(ctx, claimID)
+	if err != nil {
+		return err
+	}
+
+	err = _requireOrg(ctx, policy.InsurerOrg, "approve claims")
+	if err != nil {
+		return err
+	}
+	if claim.Status != claimAssessed {
+		return fmt.Errorf("claim %s is %s, it must be assessed before approval", claimID, claim.Status)
+	}
+	if claim.AssessedAmount == 0 {
+		return fmt.Errorf("claim %s was assessed at zero and can only be denied", claimID)
+	}
+
+	claim.Status = claimApproved
+	return _putClaim(ctx, claim, claim.AssessedAmount)
+}
+
+// DenyClaim is called by the insurer to reject a claim that has not been paid
+func (s *SmartContract) DenyClaim(ctx contractapi.TransactionContextInterface, claimID string, reason string) error {
+	claim, policy, err := s._readClaimAndPolicy(ctx, claimID)
+	if err != nil {
+		return err
+	}
+
+	err = _requireOrg(ctx, policy.InsurerOrg, "deny claims")
+	if err != nil {
+		return err
+	}
+	if claim.Status == claimPaid || claim.Status == claimDenied {
+		return fmt.Errorf("claim %s is already %s", claimID, claim.Status)
+	}
+	if reason == "" {
+		return fmt.Errorf("a reason for the denial must be given")
+	}
+
+	claim.DenialReason = reason
+	claim.Status = claimDenied
+	return _putClaim(ctx, claim, 0)
+}
+
+// PayClaim is called by the insurer to pay an approved claim. The assessed amount is transferred
+// from the submitting insurer client's token account to the policy holder.
+func (s *SmartContract) PayClaim(ctx contractapi.TransactionContextInterface, claimID string) error {
+	claim, policy, err := s._readClaimAndPolicy(ctx, claimID)
+	if err != nil {
+		return err
+	}
+
+	err = _requireOrg(ctx, policy.InsurerOrg, "pay claims")
+	if err != nil {
+		return err
+	}
+	if claim.Status != claimApproved {
+		return fmt.Errorf("claim %s is %s, only approved claims can be paid", claimID, claim.Status)
+	}
+	if claim.AssessedAmount > policy.Coverage-policy.PaidOut {
+		return fmt.Errorf("payout %d exceeds the remaining coverage %d", claim.AssessedAmount, policy.Coverage-policy.PaidOut)
+	}
+
+	args := [][]byte{[]byte("Transfer"), []byte(policy.Holder), []byte(strconv.Itoa(claim.AssessedAmount))}
+	response := ctx.GetStub().InvokeChaincode(policy.TokenChaincode, args, "")
+	if response.Status != shim.OK {
+		return fmt.Errorf("failed to pay claim %s on %s: %s", claimID, policy.TokenChaincode, response.Message)
+	}
+
+	policy.PaidOut += claim.AssessedAmount
+	policyKey, err := ctx.GetStub().CreateCompositeKey(policyPrefix, []string{policy.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	policyJSON, err := json.Marshal(policy)
+	if err != nil {
+		return fmt.Errorf("failed to marshal policy: %v", err)
+	}
+	err = ctx.GetStub().PutState(policyKey, policyJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put policy %s: %v", policy.ID, err)
+	}
+
+	claim.PaymentTxID = ctx.GetStub().GetTxID()
+	claim.Status = claimPaid
+	return _putClaim(ctx, claim, claim.AssessedAmount)
+}
+
+// _readClaimAndPolicy reads a claim together with the policy it was filed against
+func (s *SmartContract) _readClaimAndPolicy(ctx contractapi.TransactionContextInterface, claimID string) (*Claim, *Policy, error) {
+	claim, err := s.ReadClaim(ctx, claimID)
+	if err != nil {
+		return nil, nil, err
+	}
+	policy, err := s.ReadPolicy(ctx, claim.PolicyID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return claim, policy, nil
+}
+
+// _requireOrg checks the client belongs to the given org
+func _requireOrg(ctx contractapi.TransactionContextInterface, org string, action string) error {
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+	if clientMSPID != org {
+		return fmt.Errorf("client from %s is not authorized to %s on this policy", clientMSPID, action)
+	}
+	return nil
+}
+
+// _readAsset reads the public asset record from the asset transfer chaincode
+func _readAsset(ctx contractapi.TransactionContextInterface, assetChaincode string, assetID string) (*asset, error) {
+	args := [][]byte{[]byte("ReadAsset"), []byte(assetID)}
+	response := ctx.GetStub().InvokeChaincode(assetChaincode, args, "")
+	if response.Status != shim.OK {
+		return nil, fmt.Errorf("failed to read asset %s from %s: %s", assetID, assetChaincode, response.Message)
+	}
+
+	var insured asset
+	err := json.Unmarshal(response.Payload, &insured)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal asset %s: %v", assetID, err)
+	}
+	return &insured, nil
+}
+
+// _putClaim writes the claim and emits a status event for it
+func _putClaim(ctx contractapi.TransactionContextInterface, claim *Claim, amount int) error {
+	claimKey, err := ctx.GetStub().CreateCompositeKey(claimPrefix, []string{claim.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	claimJSON, err := json.Marshal(claim)
+	if err != nil {
+		return fmt.Errorf("failed to marshal claim: %v", err)
+	}
+	err = ctx.GetStub().PutState(claimKey, claimJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put claim %s: %v", claim.ID, err)
+	}
+
+	eventJSON, err := json.Marshal(event{claim.ID, claim.PolicyID, claim.Status, amount})
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	err = ctx.GetStub().SetEvent("ClaimStatus", eventJSON)
+	if err != nil {
+		return fmt.Errorf("failed to set event: %v", err)
+	}
+	return nil
+}
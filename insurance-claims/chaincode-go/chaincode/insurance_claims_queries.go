@@ -0,0 +1,91 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// ReadPolicy returns the policy stored in the world state with the given ID
+func (s *SmartContract) ReadPolicy(ctx contractapi.TransactionContextInterface, policyID string) (*Policy, error) {
+	policyKey, err := ctx.GetStub().CreateCompositeKey(policyPrefix, []string{policyID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	policyJSON, err := ctx.GetStub().GetState(policyKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if policyJSON == nil {
+		return nil, fmt.Errorf("the policy %s does not exist", policyID)
+	}
+
+	var policy Policy
+	err = json.Unmarshal(policyJSON, &policy)
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// ReadClaim returns the claim stored in the world state with the given ID
+func (s *SmartContract) ReadClaim(ctx contractapi.TransactionContextInterface, claimID string) (*Claim, error) {
+	claimKey, err := ctx.GetStub().CreateCompositeKey(claimPrefix, []string{claimID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	claimJSON, err := ctx.GetStub().GetState(claimKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if claimJSON == nil {
+		return nil, fmt.Errorf("the claim %s does not exist", claimID)
+	}
+
+	var claim Claim
+	err = json.Unmarshal(claimJSON, &claim)
+	if err != nil {
+		return nil, err
+	}
+	return &claim, nil
+}
+
+// GetClaimsByPolicy returns every claim filed against a policy
+func (s *SmartContract) GetClaimsByPolicy(ctx contractapi.TransactionContextInterface, policyID string) ([]*Claim, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(policyClaimPrefix, []string{policyID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get claims for policy %s: %v", policyID, err)
+	}
+	defer resultsIterator.Close()
+
+	var claims []*Claim
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(response.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split composite key: %v", err)
+		}
+		if len(keyParts) != 2 {
+			return nil, fmt.Errorf("unexpected index key %s", response.Key)
+		}
+
+		claim, err := s.ReadClaim(ctx, keyParts[1])
+		if err != nil {
+			return nil, err
+		}
+		claims = append(claims, claim)
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,214 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+)
+
+const (
+	holderMSPID   = "Org1MSP"
+	insurerMSPID  = "Org2MSP"
+	adjusterMSPID = "Org3MSP"
+
+	photoHash = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+)
+
+// fakeToken stands in for the token chaincode. Transfers are paid from the payer's account.
+type fakeToken struct {
+	payer    string
+	balances map[string]int
+}
+
+func (f *fakeToken) invoke(args [][]byte) pb.Response {
+	if string(args[0]) != "Transfer" {
+		return shim.Error("unexpected function " + string(args[0]))
+	}
+	amount, err := strconv.Atoi(string(args[2]))
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if f.balances[f.payer] < amount {
+		return shim.Error(fmt.Sprintf("client account %s has insufficient funds", f.payer))
+	}
+	f.balances[f.payer] -= amount
+	f.balances[string(args[1])] += amount
+	return shim.Success(nil)
+}
+
+// fakeAssets stands in for the asset transfer chaincode, answering ReadAsset
+func fakeAssets(owners map[string]string) func(args [][]byte) pb.Response {
+	return func(args [][]byte) pb.Response {
+		ownerOrg, ok := owners[string(args[1])]
+		if string(args[0]) != "ReadAsset" || !ok {
+			return shim.Error(fmt.Sprintf("asset %s does not exist", args[1]))
+		}
+		assetJSON, _ := json.Marshal(asset{string(args[1]), ownerOrg})
+		return shim.Success(assetJSON)
+	}
+}
+
+func checkError(t *testing.T, err error, expected string) {
+	t.Helper()
+	if expected == "" && err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected != "" && (err == nil || !strings.Contains(err.Error(), expected)) {
+		t.Fatalf("expected error containing %q, got %v", expected, err)
+	}
+}
+
+func checkClaim(t *testing.T, stub *fakeStub, status string) {
+	t.Helper()
+	claim, err := new(SmartContract).ReadClaim(newContext(stub, "insurer", insurerMSPID), "claim1")
+	if err != nil {
+		t.Fatalf("failed to read claim: %v", err)
+	}
+	if claim.Status != status {
+		t.Fatalf("expected claim %s, got %s", status, claim.Status)
+	}
+}
+
+// issuePolicy covers asset1 for 5000 and files a claim for 2000 against it
+func issuePolicy(t *testing.T, stub *fakeStub) *fakeToken {
+	t.Helper()
+	token := &fakeToken{payer: "insurer", balances: map[string]int{"insurer": 10000}}
+	stub.chaincodes[defaultTokenChaincode] = token.invoke
+	stub.chaincodes[defaultAssetChaincode] = fakeAssets(map[string]string{"asset1": holderMSPID})
+
+	contract := new(SmartContract)
+	err := contract.IssuePolicy(newContext(stub, "insurer", insurerMSPID), "policy1", "asset1", "holder", holderMSPID, adjusterMSPID, 5000, "", "")
+	checkError(t, err, "")
+	err = contract.FileClaim(newContext(stub, "holder", holderMSPID), "claim1", "policy1", 2000, "hail damage", []string{photoHash})
+	checkError(t, err, "")
+	return token
+}
+
+func TestIssuePolicy(t *testing.T) {
+	tests := []struct {
+		name      string
+		assetID   string
+		holderOrg string
+		coverage  int
+		expected  string
+	}{
+		{"valid", "asset1", holderMSPID, 5000, ""},
+		{"no coverage", "asset1", holderMSPID, 0, "coverage must be a positive integer"},
+		{"unknown asset", "asset2", holderMSPID, 5000, "failed to read asset asset2 from secured: asset asset2 does not exist"},
+		{"not owner", "asset1", adjusterMSPID, 5000, "asset asset1 is owned by Org1MSP, not by the policy holder org Org3MSP"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stub := newFakeStub()
+			stub.chaincodes[defaultAssetChaincode] = fakeAssets(map[string]string{"asset1": holderMSPID})
+			err := new(SmartContract).IssuePolicy(newContext(stub, "insurer", insurerMSPID), "policy1", test.assetID, "holder", test.holderOrg, adjusterMSPID, test.coverage, "", "")
+			checkError(t, err, test.expected)
+		})
+	}
+}
+
+func TestFileClaim(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	issuePolicy(t, stub)
+
+	err := contract.FileClaim(newContext(stub, "insurer", insurerMSPID), "claim2", "policy1", 100, "theft", []string{photoHash})
+	checkError(t, err, "only the policy holder can file a claim on policy policy1")
+
+	err = contract.FileClaim(newContext(stub, "holder", holderMSPID), "claim2", "policy1", 6000, "theft", []string{photoHash})
+	checkError(t, err, "claimed amount 6000 exceeds the remaining coverage 5000")
+
+	err = contract.FileClaim(newContext(stub, "holder", holderMSPID), "claim2", "policy1", 100, "theft", []string{"photo"})
+	checkError(t, err, "evidence hash photo is not a hex encoded SHA-256 digest")
+
+	err = contract.FileClaim(newContext(stub, "holder", holderMSPID), "claim1", "policy1", 100, "theft", []string{photoHash})
+	checkError(t, err, "the claim claim1 already exists")
+
+	claims, err := contract.GetClaimsByPolicy(newContext(stub, "insurer", insurerMSPID), "policy1")
+	checkError(t, err, "")
+	if len(claims) != 1 || claims[0].ID != "claim1" || claims[0].Status != claimFiled {
+		t.Fatalf("unexpected claims %+v", claims)
+	}
+}
+
+func TestPayClaim(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	token := issuePolicy(t, stub)
+
+	err := contract.AssessClaim(newContext(stub, "insurer", insurerMSPID), "claim1", 1500, "")
+	checkError(t, err, "client from Org2MSP is not authorized to assess claims on this policy")
+
+	err = contract.ApproveClaim(newContext(stub, "insurer", insurerMSPID), "claim1")
+	checkError(t, err, "claim claim1 is FILED, it must be assessed before approval")
+
+	checkError(t, contract.AssessClaim(newContext(stub, "adjuster", adjusterMSPID), "claim1", 1500, "roof only"), "")
+
+	err = contract.ApproveClaim(newContext(stub, "adjuster", adjusterMSPID), "claim1")
+	checkError(t, err, "client from Org3MSP is not authorized to approve claims on this policy")
+
+	checkError(t, contract.ApproveClaim(newContext(stub, "insurer", insurerMSPID), "claim1"), "")
+
+	err = contract.PayClaim(newContext(stub, "holder", holderMSPID), "claim1")
+	checkError(t, err, "client from Org1MSP is not authorized to pay claims on this policy")
+
+	checkError(t, contract.PayClaim(newContext(stub, "insurer", insurerMSPID), "claim1"), "")
+	checkClaim(t, stub, claimPaid)
+	if token.balances["insurer"] != 8500 || token.balances["holder"] != 1500 {
+		t.Fatalf("unexpected balances %v", token.balances)
+	}
+
+	policy, err := contract.ReadPolicy(newContext(stub, "insurer", insurerMSPID), "policy1")
+	checkError(t, err, "")
+	if policy.PaidOut != 1500 {
+		t.Fatalf("expected 1500 paid out, got %d", policy.PaidOut)
+	}
+
+	var paid event
+	err = json.Unmarshal(stub.eventValue, &paid)
+	if err != nil {
+		t.Fatalf("failed to unmarshal event: %v", err)
+	}
+	if paid != (event{"claim1", "policy1", claimPaid, 1500}) {
+		t.Fatalf("unexpected event %+v", paid)
+	}
+
+	err = contract.DenyClaim(newContext(stub, "insurer", insurerMSPID), "claim1", "fraud")
+	checkError(t, err, "claim claim1 is already PAID")
+}
+
+func TestPayClaimInsufficientFunds(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	token := issuePolicy(t, stub)
+	token.balances["insurer"] = 100
+
+	checkError(t, contract.AssessClaim(newContext(stub, "adjuster", adjusterMSPID), "claim1", 1500, ""), "")
+	checkError(t, contract.ApproveClaim(newContext(stub, "insurer", insurerMSPID), "claim1"), "")
+
+	err := contract.PayClaim(newContext(stub, "insurer", insurerMSPID), "claim1")
+	checkError(t, err, "failed to pay claim claim1 on token_erc20: client account insurer has insufficient funds")
+	checkClaim(t, stub, claimApproved)
+}
+
+func TestDenyClaim(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	issuePolicy(t, stub)
+
+	checkError(t, contract.AssessClaim(newContext(stub, "adjuster", adjusterMSPID), "claim1", 0, "pre-existing damage"), "")
+
+	err := contract.ApproveClaim(newContext(stub, "insurer", insurerMSPID), "claim1")
+	checkError(t, err, "claim claim1 was assessed at zero and can only be denied")
+
+	err = contract.DenyClaim(newContext(stub, "insurer", insurerMSPID), "claim1", "")
+	checkError(t, err, "a reason for the denial must be given")
+
+	checkError(t, contract.DenyClaim(newContext(stub, "insurer", insurerMSPID), "claim1", "pre-existing damage"), "")
+	checkClaim(t, stub, claimDenied)
+}
@@ -0,0 +1,23 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"log"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/insurance-claims/chaincode-go/chaincode"
+)
+
+func main() {
+	claimsChaincode, err := contractapi.NewChaincode(&chaincode.SmartContract{})
+	if err != nil {
+		log.Panicf("Error creating insurance-claims chaincode: %v", err)
+	}
+
+	if err := claimsChaincode.Start(); err != nil {
+		log.Panicf("Error starting insurance-claims chaincode: %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+//go:build integration
+// +build integration
+
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+)
+
+type asset struct {
+	ID       string `json:"assetID"`
+	OwnerOrg string `json:"ownerOrg"`
+}
+
+type historyRecord struct {
+	Record *asset
+	TxID   string `json:"txId"`
+}
+
+func TestCreateTransferHistory(t *testing.T) {
+	assetID := fmt.Sprintf("asset%d", time.Now().UnixNano())
+	properties := fmt.Sprintf(`{"object_type":"asset_properties","asset_id":"%s","color":"blue","size":35,"salt":"a94a8fe5ccb19ba61c4c0873d391e987982fbbd3"}`, assetID)
+	price := fmt.Sprintf(`{"asset_id":"%s","trade_id":"109f4b3c50d7b0df729d299bc6f8e9ef9066971f","price":100}`, assetID)
+
+	// private data is only written on the peer of the client's org
+	steps := []struct {
+		client    Identity
+		endorsers []*Org
+		function  string
+		transient map[string]string
+		args      []string
+	}{
+		{org1User, []*Org{Org1}, "CreateAsset", map[string]string{"asset_properties": properties}, []string{assetID, "Asset for sale"}},
+		{org1User, []*Org{Org1}, "AgreeToSell", map[string]string{"asset_price": price}, []string{assetID}},
+		{org2User, []*Org{Org2}, "AgreeToBuy", map[string]string{"asset_price": price}, []string{assetID}},
+		{org1User, bothOrgs, "TransferAsset", map[string]string{"asset_properties": properties, "asset_price": price}, []string{assetID, Org2.MSPID}},
+	}
+	for _, step := range steps {
+		err := network.Invoke(step.client, step.endorsers, "secured", step.transient, step.function, step.args...)
+		if err != nil {
+			t.Fatalf("%s failed: %v", step.function, err)
+		}
+	}
+
+	result, err := network.Query(org1User, "secured", "ReadAsset", assetID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var transferred asset
+	err = json.Unmarshal([]byte(result), &transferred)
+	if err != nil {
+		t.Fatalf("failed to unmarshal asset %s: %v", result, err)
+	}
+	if transferred.OwnerOrg != Org2.MSPID {
+		t.Errorf("owner is %s, want %s", transferred.OwnerOrg, Org2.MSPID)
+	}
+
+	result, err = network.Query(org2User, "secured", "GetAssetPrivateProperties", assetID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != properties {
+		t.Errorf("buyer's private properties are %s, want %s", result, properties)
+	}
+
+	result, err = network.Query(org2User, "secured", "QueryAssetHistory", assetID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var history []historyRecord
+	err = json.Unmarshal([]byte(result), &history)
+	if err != nil {
+		t.Fatalf("failed to unmarshal history %s: %v", result, err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("history has %d records, want 2: %s", len(history), result)
+	}
+	owners := map[string]bool{history[0].Record.OwnerOrg: true, history[1].Record.OwnerOrg: true}
+	if !owners[Org1.MSPID] || !owners[Org2.MSPID] {
+		t.Errorf("history does not show the asset owned by both orgs: %s", result)
+	}
+
+	err = network.Invoke(org1User, []*Org{Org1}, "secured", nil, "UpdateAsset", assetID, "Sold")
+	if err == nil {
+		t.Errorf("the seller updated the asset after transferring it")
+	}
+}
@@ -0,0 +1,3 @@
+module github.com/hyperledger/fabric-samples/integration
+
+go 1.14
@@ -0,0 +1,78 @@
+//go:build integration
+// +build integration
+
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package integration
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+const channel = "mychannel"
+
+var network *Network
+
+var (
+	org1Admin = Identity{Org: Org1, User: "Admin"}
+	org1User  = Identity{Org: Org1, User: "User1"}
+	org2Admin = Identity{Org: Org2, User: "Admin"}
+	org2User  = Identity{Org: Org2, User: "User1"}
+	bothOrgs  = []*Org{Org1, Org2}
+)
+
+// TestMain starts one network for every test, deploys the chaincodes and grants the Org1 clients
+// the roles the scenarios need in the access-control chaincode
+func TestMain(m *testing.M) {
+	var err error
+	network, err = StartNetwork("..", channel, []Chaincode{
+		{Name: "acl", Path: "access-control/chaincode-go"},
+		{Name: "token_erc20", Path: "token-erc-20/chaincode-go"},
+		{Name: "secured", Path: "asset-transfer-secured-agreement/chaincode-go", Policy: "OR('Org1MSP.peer','Org2MSP.peer')"},
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to start the test network: %v\n", err)
+		os.Exit(1)
+	}
+
+	err = grantRoles()
+	code := 1
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to set up the access policy: %v\n", err)
+	} else {
+		code = m.Run()
+	}
+
+	if os.Getenv("KEEP_NETWORK") == "" {
+		err = network.Stop()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to stop the test network: %v\n", err)
+		}
+	}
+	os.Exit(code)
+}
+
+func grantRoles() error {
+	roles := []struct {
+		name       string
+		operations string
+	}{
+		{"minter", `["token.Mint","token.Burn"]`},
+		{"asset-owner", `["asset.CreateAsset"]`},
+	}
+	for _, role := range roles {
+		err := network.Invoke(org1Admin, bothOrgs, "acl", nil, "DefineRole", role.name, role.name, role.operations)
+		if err != nil {
+			return err
+		}
+		err = network.Invoke(org1Admin, bothOrgs, "acl", nil, "AssignRole", Org1.MSPID, role.name)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package integration drives the two-org Fabric test network with the peer CLI, so end-to-end
+// scenarios against the deployed chaincodes can be written as Go tests.
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Org is one of the peer organizations of the test network
+type Org struct {
+	MSPID       string
+	Domain      string
+	PeerAddress string
+}
+
+var (
+	Org1 = &Org{MSPID: "Org1MSP", Domain: "org1.example.com", PeerAddress: "localhost:7051"}
+	Org2 = &Org{MSPID: "Org2MSP", Domain: "org2.example.com", PeerAddress: "localhost:9051"}
+)
+
+// Identity is a user enrolled by the cryptographic material of the test network, e.g. Admin or User1
+type Identity struct {
+	Org  *Org
+	User string
+}
+
+// Chaincode is a chaincode of this repository to deploy on the channel
+type Chaincode struct {
+	Name string
+	// Path is relative to the root of the repository
+	Path string
+	// Policy is the chaincode endorsement policy, empty for the channel default
+	Policy string
+}
+
+// Network is a running test network with one channel
+type Network struct {
+	dir     string
+	channel string
+}
+
+// StartNetwork brings up the test network under rootDir/test-network, creates the channel and
+// deploys the chaincodes. The Fabric binaries and config are expected in rootDir/bin and
+// rootDir/config, as installed by the Fabric install script.
+func StartNetwork(rootDir string, channel string, chaincodes []Chaincode) (*Network, error) {
+	dir, err := filepath.Abs(filepath.Join(rootDir, "test-network"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find test network: %v", err)
+	}
+	network := &Network{dir: dir, channel: channel}
+
+	_, err = network.script("down")
+	if err != nil {
+		return nil, err
+	}
+	_, err = network.script("up", "createChannel", "-c", channel)
+	if err != nil {
+		return nil, err
+	}
+	for _, chaincode := range chaincodes {
+		args := []string{"deployCC", "-c", channel, "-ccn", chaincode.Name, "-ccp", filepath.Join("..", chaincode.Path), "-ccl", "go"}
+		if chaincode.Policy != "" {
+			args = append(args, "-ccep", chaincode.Policy)
+		}
+		_, err = network.script(args...)
+		if err != nil {
+			network.Stop()
+			return nil, err
+		}
+	}
+
+	return network, nil
+}
+
+// Stop tears the network down, removing its containers and chaincode images
+func (n *Network) Stop() error {
+	_, err := n.script("down")
+	return err
+}
+
+// Invoke submits a transaction as the client, endorsed by the peers of the given orgs, and waits
+// for it to be committed. Values of the transient map are passed as the bytes of the string.
+func (n *Network) Invoke(client Identity, endorsers []*Org, chaincode string, transient map[string]string, function string, args ...string) error {
+	input, err := _chaincodeInput(function, args)
+	if err != nil {
+		return err
+	}
+
+	cmdArgs := []string{"chaincode", "invoke",
+		"-o", "localhost:7050", "--ordererTLSHostnameOverride", "orderer.example.com", "--tls",
+		"--cafile", n.path("organizations/ordererOrganizations/example.com/orderers/orderer.example.com/msp/tlscacerts/tlsca.example.com-cert.pem"),
+		"-C", n.channel, "-n", chaincode, "-c", input, "--waitForEvent"}
+	for _, org := range endorsers {
+		cmdArgs = append(cmdArgs, "--peerAddresses", org.PeerAddress, "--tlsRootCertFiles", n.peerTLSRootCert(org))
+	}
+	if len(transient) > 0 {
+		// the peer CLI reads the transient map as map[string][]byte, i.e. base64 values
+		transientBytes := make(map[string][]byte)
+		for key, value := range transient {
+			transientBytes[key] = []byte(value)
+		}
+		transientJSON, err := json.Marshal(transientBytes)
+		if err != nil {
+			return fmt.Errorf("failed to marshal transient map: %v", err)
+		}
+		cmdArgs = append(cmdArgs, "--transient", string(transientJSON))
+	}
+
+	_, err = n.peer(client, cmdArgs...)
+	return err
+}
+
+// Query evaluates a function on the peer of the client's org and returns its result
+func (n *Network) Query(client Identity, chaincode string, function string, args ...string) (string, error) {
+	input, err := _chaincodeInput(function, args)
+	if err != nil {
+		return "", err
+	}
+
+	output, err := n.peer(client, "chaincode", "query", "-C", n.channel, "-n", chaincode, "-c", input)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// script runs network.sh with the arguments
+func (n *Network) script(args ...string) (string, error) {
+	cmd := exec.Command("./network.sh", args...)
+	cmd.Dir = n.dir
+	cmd.Env = n.env()
+	return _run(cmd)
+}
+
+// peer runs the peer CLI as the client, against the peer of the client's org
+func (n *Network) peer(client Identity, args ...string) (string, error) {
+	cmd := exec.Command("peer", args...)
+	cmd.Dir = n.dir
+	cmd.Env = append(n.env(),
+		"CORE_PEER_TLS_ENABLED=true",
+		"CORE_PEER_LOCALMSPID="+client.Org.MSPID,
+		"CORE_PEER_MSPCONFIGPATH="+n.path(fmt.Sprintf("organizations/peerOrganizations/%s/users/%s@%s/msp", client.Org.Domain, client.User, client.Org.Domain)),
+		"CORE_PEER_TLS_ROOTCERT_FILE="+n.peerTLSRootCert(client.Org),
+		"CORE_PEER_ADDRESS="+client.Org.PeerAddress,
+	)
+	return _run(cmd)
+}
+
+// env returns the environment of the current process with the Fabric binaries and config of the samples
+func (n *Network) env() []string {
+	return append(os.Environ(),
+		"PATH="+n.path("../bin")+string(os.PathListSeparator)+os.Getenv("PATH"),
+		"FABRIC_CFG_PATH="+n.path("../config"),
+	)
+}
+
+// path returns the absolute path of a file of the test network
+func (n *Network) path(name string) string {
+	return filepath.Join(n.dir, filepath.FromSlash(name))
+}
+
+func (n *Network) peerTLSRootCert(org *Org) string {
+	return n.path(fmt.Sprintf("organizations/peerOrganizations/%s/peers/peer0.%s/tls/ca.crt", org.Domain, org.Domain))
+}
+
+// _chaincodeInput builds the -c argument of the peer CLI
+func _chaincodeInput(function string, args []string) (string, error) {
+	if args == nil {
+		args = []string{}
+	}
+	input, err := json.Marshal(struct {
+		Function string   `json:"function"`
+		Args     []string `json:"Args"`
+	}{function, args})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal chaincode input: %v", err)
+	}
+	return string(input), nil
+}
+
+// _run runs the command and returns its standard output, or an error with everything it printed
+func _run(cmd *exec.Cmd) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if err != nil {
+		return "", fmt.Errorf("failed to run %s %s: %v\n%s%s", filepath.Base(cmd.Path), strings.Join(cmd.Args[1:], " "), err, stdout.String(), stderr.String())
+	}
+	return stdout.String(), nil
+}
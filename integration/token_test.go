@@ -0,0 +1,80 @@
+//go:build integration
+// +build integration
+
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package integration
+
+import (
+	"strconv"
+	"testing"
+)
+
+// queryInt queries a function of the token chaincode returning an amount
+func queryInt(t *testing.T, client Identity, function string, args ...string) int {
+	t.Helper()
+	result, err := network.Query(client, "token_erc20", function, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	amount, err := strconv.Atoi(result)
+	if err != nil {
+		t.Fatalf("%s returned %q, not an amount", function, result)
+	}
+	return amount
+}
+
+func TestMintApproveTransferFrom(t *testing.T) {
+	owner, err := network.Query(org1User, "token_erc20", "ClientAccountID")
+	if err != nil {
+		t.Fatal(err)
+	}
+	spender, err := network.Query(org2User, "token_erc20", "ClientAccountID")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ownerBalance := queryInt(t, org1User, "BalanceOf", owner)
+	spenderBalance := queryInt(t, org1User, "BalanceOf", spender)
+
+	err = network.Invoke(org1User, bothOrgs, "token_erc20", nil, "Mint", "1000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := queryInt(t, org1User, "BalanceOf", owner); got != ownerBalance+1000 {
+		t.Fatalf("balance after minting is %d, want %d", got, ownerBalance+1000)
+	}
+
+	err = network.Invoke(org2User, bothOrgs, "token_erc20", nil, "Mint", "1000")
+	if err == nil {
+		t.Fatalf("Org2 minted without the minter role")
+	}
+
+	err = network.Invoke(org1User, bothOrgs, "token_erc20", nil, "Approve", spender, "300")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := queryInt(t, org2User, "Allowance", owner, spender); got != 300 {
+		t.Fatalf("allowance is %d, want 300", got)
+	}
+
+	err = network.Invoke(org2User, bothOrgs, "token_erc20", nil, "TransferFrom", owner, spender, "200")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = network.Invoke(org2User, bothOrgs, "token_erc20", nil, "TransferFrom", owner, spender, "200")
+	if err == nil {
+		t.Fatalf("spent more than the allowance")
+	}
+
+	if got := queryInt(t, org1User, "BalanceOf", owner); got != ownerBalance+800 {
+		t.Errorf("owner balance is %d, want %d", got, ownerBalance+800)
+	}
+	if got := queryInt(t, org2User, "BalanceOf", spender); got != spenderBalance+200 {
+		t.Errorf("spender balance is %d, want %d", got, spenderBalance+200)
+	}
+	if got := queryInt(t, org2User, "Allowance", owner, spender); got != 100 {
+		t.Errorf("remaining allowance is %d, want 100", got)
+	}
+}
@@ -0,0 +1,542 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil"
+)
+
+// This sample assumes Org1 operates the netting scheme. Only the operator can admit members
+// and open and close cycles.
+const operatorMSPID = "Org1MSP"
+
+// chaincode name used in the test network READMEs
+const defaultTokenChaincode = "token_erc20"
+
+// object names for prefix
+const (
+	memberPrefix     = "member"
+	cyclePrefix      = "cycle"
+	obligationPrefix = "obligation"
+)
+
+// cycle status values
+const (
+	cycleOpen    = "OPEN"
+	cycleClosed  = "CLOSED"
+	cycleSettled = "SETTLED"
+)
+
+// maxInstructionsPerSettlement is the most instructions one ExecuteSettlement pays, the most
+// payments one BatchTransfer of the token chaincode makes
+const maxInstructionsPerSettlement = 100
+
+// SmartContract provides functions for multilateral netting of interbank obligations
+type SmartContract struct {
+	contractapi.Contract
+}
+
+// Member is a bank taking part in the scheme. Account is the client ID of the token account
+// the bank settles from and into.
+type Member struct {
+	ObjectType string `json:"objectType"`
+	MSPID      string `json:"mspID"`
+	Account    string `json:"account"`
+	Active     bool   `json:"active"`
+}
+
+// Cycle collects obligations until it is closed, then holds the net positions and the
+// transfers that settle them
+type Cycle struct {
+	ObjectType      string         `json:"objectType"`
+	ID              string         `json:"cycleID"`
+	Status          string         `json:"status"`
+	TokenChaincode  string         `json:"tokenChaincode"`
+	ObligationCount int            `json:"obligationCount"`
+	GrossValue      int            `json:"grossValue"`
+	Positions       []*Position    `json:"positions"`
+	Instructions    []*Instruction `json:"instructions"`
+}
+
+// Obligation is a bilateral payment one member owes another in a cycle
+type Obligation struct {
+	ObjectType string `json:"objectType"`
+	CycleID    string `json:"cycleID"`
+	ID         string `json:"obligationID"`
+	Payer      string `json:"payer"`
+	Payee      string `json:"payee"`
+	Amount     int    `json:"amount"`
+}
+
+// Position is a member's net position in a closed cycle, positive when it receives
+type Position struct {
+	Member string `json:"member"`
+	Net    int    `json:"net"`
+}
+
+// Instruction is one settlement transfer from a net payer to a net receiver
+type Instruction struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Amount   int    `json:"amount"`
+	Executed bool   `json:"executed"`
+	TxID     string `json:"txID,omitempty"`
+}
+
+// event provides an organized struct for emitting cycle status events
+type event struct {
+	CycleID      string `json:"cycleID"`
+	Status       string `json:"status"`
+	GrossValue   int    `json:"grossValue"`
+	Instructions int    `json:"instructions"`
+}
+
+// AdmitMember adds a bank to the scheme, or updates its settlement account. Only the operator
+// can admit members.
+func (s *SmartContract) AdmitMember(ctx contractapi.TransactionContextInterface, mspID string, account string) error {
+	err := _requireOperator(ctx)
+	if err != nil {
+		return err
+	}
+	if mspID == "" || account == "" {
+		return fmt.Errorf("member MSPID and settlement account must be set")
+	}
+
+	member := Member{
+		ObjectType: memberPrefix,
+		MSPID:      mspID,
+		Account:    account,
+		Active:     true,
+	}
+	return _putMember(ctx, &member)
+}
+
+// SuspendMember stops a bank submitting new obligations. Obligations already in an open cycle
+// are still netted and settled.
+func (s *SmartContract) SuspendMember(ctx contractapi.TransactionContextInterface, mspID string) error {
+	err := _requireOperator(ctx)
+	if err != nil {
+		return err
+	}
+
+	member, err := s.GetMember(ctx, mspID)
+	if err != nil {
+		return err
+	}
+	member.Active = false
+	return _putMember(ctx, member)
+}
+
+// OpenCycle starts a new netting cycle. Only the operator can open cycles.
+func (s *SmartContract) OpenCycle(ctx contractapi.TransactionContextInterface, cycleID string, tokenChaincode string) error {
+	err := _requireOperator(ctx)
+	if err != nil {
+		return err
+	}
+	if cycleID == "" {
+		return fmt.Errorf("cycle ID must be set")
+	}
+	if tokenChaincode == "" {
+		tokenChaincode = defaultTokenChaincode
+	}
+
+	existing, err := _getCycle(ctx, cycleID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("the cycle %s already exists", cycleID)
+	}
+
+	cycle := Cycle{
+		ObjectType:     cyclePrefix,
+		ID:             cycleID,
+		Status:         cycleOpen,
+		TokenChaincode: tokenChaincode,
+	}
+	return _putCycle(ctx, &cycle)
+}
+
+// SubmitObligation is called by a member bank to queue a payment it owes payee in an open cycle
+func (s *SmartContract) SubmitObligation(ctx contractapi.TransactionContextInterface, cycleID string, obligationID string, payee string, amount int) error {
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+
+	payer, err := _getMember(ctx, clientOrgID)
+	if err != nil {
+		return err
+	}
+	if payer == nil || !payer.Active {
+		return fmt.Errorf("client from %s is not an active member", clientOrgID)
+	}
+	payeeMember, err := _getMember(ctx, payee)
+	if err != nil {
+		return err
+	}
+	if payeeMember == nil {
+		return fmt.Errorf("payee %s is not a member", payee)
+	}
+	if payee == clientOrgID {
+		return fmt.Errorf("a member cannot owe itself")
+	}
+	if obligationID == "" {
+		return fmt.Errorf("obligation ID must be set")
+	}
+	if amount <= 0 {
+		return fmt.Errorf("amount must be a positive integer")
+	}
+
+	cycle, err := s.GetCycle(ctx, cycleID)
+	if err != nil {
+		return err
+	}
+	if cycle.Status != cycleOpen {
+		return fmt.Errorf("cycle %s is %s and does not accept obligations", cycleID, cycle.Status)
+	}
+
+	obligationKey, err := ctx.GetStub().CreateCompositeKey(obligationPrefix, []string{cycleID, obligationID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	existing, err := ctx.GetStub().GetState(obligationKey)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if existing != nil {
+		return fmt.Errorf("obligation %s already exists in cycle %s", obligationID, cycleID)
+	}
+
+	obligation := Obligation{
+		ObjectType: obligationPrefix,
+		CycleID:    cycleID,
+		ID:         obligationID,
+		Payer:      clientOrgID,
+		Payee:      payee,
+		Amount:     amount,
+	}
+	obligationJSON, err := json.Marshal(obligation)
+	if err != nil {
+		return fmt.Errorf("failed to marshal obligation: %v", err)
+	}
+	err = ctx.GetStub().PutState(obligationKey, obligationJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put obligation: %v", err)
+	}
+
+	cycle.ObligationCount++
+	cycle.GrossValue += amount
+	return _putCycle(ctx, cycle)
+}
+
+// CancelObligation is called by the payer to withdraw an obligation while the cycle is open
+func (s *SmartContract) CancelObligation(ctx contractapi.TransactionContextInterface, cycleID string, obligationID string) error {
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+
+	cycle, err := s.GetCycle(ctx, cycleID)
+	if err != nil {
+		return err
+	}
+	if cycle.Status != cycleOpen {
+		return fmt.Errorf("cycle %s is %s, obligations can no longer be cancelled", cycleID, cycle.Status)
+	}
+
+	obligationKey, err := ctx.GetStub().CreateCompositeKey(obligationPrefix, []string{cycleID, obligationID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	obligationJSON, err := ctx.GetStub().GetState(obligationKey)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if obligationJSON == nil {
+		return fmt.Errorf("obligation %s does not exist in cycle %s", obligationID, cycleID)
+	}
+	var obligation Obligation
+	err = json.Unmarshal(obligationJSON, &obligation)
+	if err != nil {
+		return err
+	}
+	if obligation.Payer != clientOrgID {
+		return fmt.Errorf("only the payer can cancel obligation %s", obligationID)
+	}
+
+	err = ctx.GetStub().DelState(obligationKey)
+	if err != nil {
+		return fmt.Errorf("failed to delete obligation: %v", err)
+	}
+
+	cycle.ObligationCount--
+	cycle.GrossValue -= obligation.Amount
+	return _putCycle(ctx, cycle)
+}
+
+// CloseCycle stops a cycle taking obligations and nets them. Each member's net position is what
+// it is owed minus what it owes. The positions are then settled with at most one transfer fewer
+// than there are members with a non-zero position: the largest payer pays the largest receiver
+// until one of them is square, ties broken by MSPID so every peer computes the same transfers.
+// Only the operator can close cycles.
+func (s *SmartContract) CloseCycle(ctx contractapi.TransactionContextInterface, cycleID string) error {
+	err := _requireOperator(ctx)
+	if err != nil {
+		return err
+	}
+
+	cycle, err := s.GetCycle(ctx, cycleID)
+	if err != nil {
+		return err
+	}
+	if cycle.Status != cycleOpen {
+		return fmt.Errorf("cycle %s is already %s", cycleID, cycle.Status)
+	}
+
+	obligations, err := s.GetObligations(ctx, cycleID)
+	if err != nil {
+		return err
+	}
+
+	net := make(map[string]int)
+	for _, obligation := range obligations {
+		net[obligation.Payer] -= obligation.Amount
+		net[obligation.Payee] += obligation.Amount
+	}
+
+	var payers, receivers []*Position
+	cycle.Positions = nil
+	for member, amount := range net {
+		position := &Position{Member: member, Net: amount}
+		cycle.Positions = append(cycle.Positions, position)
+		if amount < 0 {
+			payers = append(payers, &Position{Member: member, Net: -amount})
+		} else if amount > 0 {
+			receivers = append(receivers, &Position{Member: member, Net: amount})
+		}
+	}
+	sort.Slice(cycle.Positions, func(i, j int) bool { return cycle.Positions[i].Member < cycle.Positions[j].Member })
+	_sortPositions(payers)
+	_sortPositions(receivers)
+
+	cycle.Instructions = nil
+	for len(payers) > 0 && len(receivers) > 0 {
+		payer, receiver := payers[0], receivers[0]
+		amount := payer.Net
+		if receiver.Net < amount {
+			amount = receiver.Net
+		}
+		cycle.Instructions = append(cycle.Instructions, &Instruction{From: payer.Member, To: receiver.Member, Amount: amount})
+
+		payer.Net -= amount
+		receiver.Net -= amount
+		if payer.Net == 0 {
+			payers = payers[1:]
+		}
+		if receiver.Net == 0 {
+			receivers = receivers[1:]
+		}
+		_sortPositions(payers)
+		_sortPositions(receivers)
+	}
+
+	cycle.Status = cycleClosed
+	if len(cycle.Instructions) == 0 {
+		cycle.Status = cycleSettled
+	}
+	err = _putCycle(ctx, cycle)
+	if err != nil {
+		return err
+	}
+
+	return _emitEvent(ctx, "CycleClosed", event{cycle.ID, cycle.Status, cycle.GrossValue, len(cycle.Instructions)})
+}
+
+// ExecuteSettlement is called from the settlement account of a net paying member. Every
+// instruction of the member in the closed cycle is paid from that account to the receiving
+// members' accounts with one BatchTransfer, debiting the payer once; a member with more than
+// maxInstructionsPerSettlement instructions calls it again for the rest. The cycle is settled once
+// every instruction has been executed.
+func (s *SmartContract) ExecuteSettlement(ctx contractapi.TransactionContextInterface, cycleID string) error {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+
+	member, err := s.GetMember(ctx, clientOrgID)
+	if err != nil {
+		return err
+	}
+	if clientID != member.Account {
+		return fmt.Errorf("only the settlement account of %s can execute its settlement", clientOrgID)
+	}
+
+	cycle, err := s.GetCycle(ctx, cycleID)
+	if err != nil {
+		return err
+	}
+	if cycle.Status != cycleClosed {
+		return fmt.Errorf("cycle %s is %s, only closed cycles are settled", cycleID, cycle.Status)
+	}
+
+	var payments []ledgerutil.TokenPayment
+	executed := 0
+	pending := 0
+	for _, instruction := range cycle.Instructions {
+		if instruction.From == clientOrgID && !instruction.Executed && executed < maxInstructionsPerSettlement {
+			receiver, err := s.GetMember(ctx, instruction.To)
+			if err != nil {
+				return err
+			}
+			payments = append(payments, ledgerutil.TokenPayment{Receiver: receiver.Account, Amount: instruction.Amount})
+			instruction.Executed = true
+			instruction.TxID = ctx.GetStub().GetTxID()
+			executed++
+		}
+		if !instruction.Executed {
+			pending++
+		}
+	}
+	if executed == 0 {
+		return fmt.Errorf("%s has nothing to settle in cycle %s", clientOrgID, cycleID)
+	}
+	err = ledgerutil.TransferTokens(ctx, cycle.TokenChaincode, payments...)
+	if err != nil {
+		return err
+	}
+
+	if pending == 0 {
+		cycle.Status = cycleSettled
+	}
+	err = _putCycle(ctx, cycle)
+	if err != nil {
+		return err
+	}
+
+	return _emitEvent(ctx, "SettlementExecuted", event{cycle.ID, cycle.Status, cycle.GrossValue, executed})
+}
+
+// _sortPositions orders positions largest first, then by MSPID
+func _sortPositions(positions []*Position) {
+	sort.Slice(positions, func(i, j int) bool {
+		if positions[i].Net != positions[j].Net {
+			return positions[i].Net > positions[j].Net
+		}
+		return positions[i].Member < positions[j].Member
+	})
+}
+
+// _requireOperator checks the submitting client belongs to the operator org
+func _requireOperator(ctx contractapi.TransactionContextInterface) error {
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+	if clientMSPID != operatorMSPID {
+		return fmt.Errorf("client from %s is not the netting operator", clientMSPID)
+	}
+	return nil
+}
+
+// _getMember reads a member, returning nil when the org is not a member
+func _getMember(ctx contractapi.TransactionContextInterface, mspID string) (*Member, error) {
+	memberKey, err := ctx.GetStub().CreateCompositeKey(memberPrefix, []string{mspID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	memberJSON, err := ctx.GetStub().GetState(memberKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if memberJSON == nil {
+		return nil, nil
+	}
+
+	var member Member
+	err = json.Unmarshal(memberJSON, &member)
+	if err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+// _putMember writes the member to the world state
+func _putMember(ctx contractapi.TransactionContextInterface, member *Member) error {
+	memberKey, err := ctx.GetStub().CreateCompositeKey(memberPrefix, []string{member.MSPID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	memberJSON, err := json.Marshal(member)
+	if err != nil {
+		return fmt.Errorf("failed to marshal member: %v", err)
+	}
+	err = ctx.GetStub().PutState(memberKey, memberJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put member %s: %v", member.MSPID, err)
+	}
+	return nil
+}
+
+// _getCycle reads a cycle, returning nil when it does not exist
+func _getCycle(ctx contractapi.TransactionContextInterface, cycleID string) (*Cycle, error) {
+	cycleKey, err := ctx.GetStub().CreateCompositeKey(cyclePrefix, []string{cycleID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	cycleJSON, err := ctx.GetStub().GetState(cycleKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if cycleJSON == nil {
+		return nil, nil
+	}
+
+	var cycle Cycle
+	err = json.Unmarshal(cycleJSON, &cycle)
+	if err != nil {
+		return nil, err
+	}
+	return &cycle, nil
+}
+
+// _putCycle writes the cycle to the world state
+func _putCycle(ctx contractapi.TransactionContextInterface, cycle *Cycle) error {
+	cycleKey, err := ctx.GetStub().CreateCompositeKey(cyclePrefix, []string{cycle.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	cycleJSON, err := json.Marshal(cycle)
+	if err != nil {
+		return fmt.Errorf("failed to marshal cycle: %v", err)
+	}
+	err = ctx.GetStub().PutState(cycleKey, cycleJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put cycle %s: %v", cycle.ID, err)
+	}
+	return nil
+}
+
+// _emitEvent marshals the payload and sets it as the chaincode event
+func _emitEvent(ctx contractapi.TransactionContextInterface, name string, payload interface{}) error {
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	err = ctx.GetStub().SetEvent(name, payloadJSON)
+	if err != nil {
+		return fmt.Errorf("failed to set event: %v", err)
+	}
+	return nil
+}
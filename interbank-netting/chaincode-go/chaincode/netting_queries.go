@@ -0,0 +1,88 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// GetMember returns the member record of a bank
+func (s *SmartContract) GetMember(ctx contractapi.TransactionContextInterface, mspID string) (*Member, error) {
+	member, err := _getMember(ctx, mspID)
+	if err != nil {
+		return nil, err
+	}
+	if member == nil {
+		return nil, fmt.Errorf("the member %s does not exist", mspID)
+	}
+	return member, nil
+}
+
+// GetMembers returns every bank admitted to the scheme
+func (s *SmartContract) GetMembers(ctx contractapi.TransactionContextInterface) ([]*Member, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(memberPrefix, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get members: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var members []*Member
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var member Member
+		err = json.Unmarshal(response.Value, &member)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, &member)
+	}
+
+	return members, nil
+}
+
+// GetCycle returns the cycle stored in the world state with the given ID
+func (s *SmartContract) GetCycle(ctx contractapi.TransactionContextInterface, cycleID string) (*Cycle, error) {
+	cycle, err := _getCycle(ctx, cycleID)
+	if err != nil {
+		return nil, err
+	}
+	if cycle == nil {
+		return nil, fmt.Errorf("the cycle %s does not exist", cycleID)
+	}
+	return cycle, nil
+}
+
+// GetObligations returns the obligations queued in a cycle
+func (s *SmartContract) GetObligations(ctx contractapi.TransactionContextInterface, cycleID string) ([]*Obligation, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(obligationPrefix, []string{cycleID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get obligations of cycle %s: %v", cycleID, err)
+	}
+	defer resultsIterator.Close()
+
+	var obligations []*Obligation
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var obligation Obligation
+		err = json.Unmarshal(response.Value, &obligation)
+		if err != nil {
+			return nil, err
+		}
+		obligations = append(obligations, &obligation)
+	}
+
+	return obligations, nil
+}
@@ -0,0 +1,218 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+)
+
+// fakeToken stands in for the token chaincode. Batches are paid from the payer's account,
+// debited once with the total as by the token chaincode.
+type fakeToken struct {
+	payer    string
+	balances map[string]int
+	batches  int
+}
+
+func (f *fakeToken) invoke(args [][]byte) pb.Response {
+	if string(args[0]) != "BatchTransfer" {
+		return shim.Error("unexpected function " + string(args[0]))
+	}
+	var payments []struct {
+		Receiver string `json:"receiver"`
+		Amount   int    `json:"amount"`
+	}
+	err := json.Unmarshal(args[1], &payments)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	total := 0
+	for _, payment := range payments {
+		total += payment.Amount
+	}
+	if f.balances[f.payer] < total {
+		return shim.Error(fmt.Sprintf("failed to transfer: client account %s has insufficient funds", f.payer))
+	}
+	f.balances[f.payer] -= total
+	for _, payment := range payments {
+		f.balances[payment.Receiver] += payment.Amount
+	}
+	f.batches++
+	return shim.Success(nil)
+}
+
+func checkError(t *testing.T, err error, expected string) {
+	t.Helper()
+	if expected == "" && err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected != "" && (err == nil || !strings.Contains(err.Error(), expected)) {
+		t.Fatalf("expected error containing %q, got %v", expected, err)
+	}
+}
+
+// openCycle admits Org2MSP, Org3MSP and Org4MSP, settling from accounts bank2, bank3 and bank4,
+// and opens cycle c1
+func openCycle(t *testing.T, stub *fakeStub) *fakeToken {
+	t.Helper()
+	token := &fakeToken{balances: make(map[string]int)}
+	stub.chaincodes[defaultTokenChaincode] = token.invoke
+
+	contract := new(SmartContract)
+	for i := 2; i <= 4; i++ {
+		err := contract.AdmitMember(newContext(stub, "operator", operatorMSPID), fmt.Sprintf("Org%dMSP", i), fmt.Sprintf("bank%d", i))
+		checkError(t, err, "")
+	}
+	checkError(t, contract.OpenCycle(newContext(stub, "operator", operatorMSPID), "c1", ""), "")
+	return token
+}
+
+// closeCycle queues a ring of obligations that nets to Org2MSP paying 40 to Org3MSP and 30 to
+// Org4MSP, and closes cycle c1
+func closeCycle(t *testing.T, stub *fakeStub) {
+	t.Helper()
+	contract := new(SmartContract)
+	for _, obligation := range []Obligation{
+		{ID: "o1", Payer: "Org2MSP", Payee: "Org3MSP", Amount: 100},
+		{ID: "o2", Payer: "Org3MSP", Payee: "Org4MSP", Amount: 60},
+		{ID: "o3", Payer: "Org4MSP", Payee: "Org2MSP", Amount: 30},
+	} {
+		err := contract.SubmitObligation(newContext(stub, "teller", obligation.Payer), "c1", obligation.ID, obligation.Payee, obligation.Amount)
+		checkError(t, err, "")
+	}
+	checkError(t, contract.CloseCycle(newContext(stub, "operator", operatorMSPID), "c1"), "")
+}
+
+func TestSubmitObligation(t *testing.T) {
+	tests := []struct {
+		name         string
+		mspID        string
+		obligationID string
+		payee        string
+		amount       int
+		expected     string
+	}{
+		{"obligation", "Org2MSP", "o2", "Org3MSP", 100, ""},
+		{"not a member", "Org5MSP", "o2", "Org3MSP", 100, "client from Org5MSP is not an active member"},
+		{"suspended", "Org4MSP", "o2", "Org3MSP", 100, "client from Org4MSP is not an active member"},
+		{"unknown payee", "Org2MSP", "o2", "Org5MSP", 100, "payee Org5MSP is not a member"},
+		{"itself", "Org2MSP", "o2", "Org2MSP", 100, "a member cannot owe itself"},
+		{"no amount", "Org2MSP", "o2", "Org3MSP", 0, "amount must be a positive integer"},
+		{"existing obligation", "Org2MSP", "o1", "Org3MSP", 100, "obligation o1 already exists in cycle c1"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			contract := new(SmartContract)
+			stub := newFakeStub()
+			openCycle(t, stub)
+			checkError(t, contract.SubmitObligation(newContext(stub, "teller", "Org2MSP"), "c1", "o1", "Org3MSP", 50), "")
+			checkError(t, contract.SuspendMember(newContext(stub, "operator", operatorMSPID), "Org4MSP"), "")
+
+			err := contract.SubmitObligation(newContext(stub, "teller", test.mspID), "c1", test.obligationID, test.payee, test.amount)
+			checkError(t, err, test.expected)
+		})
+	}
+}
+
+func TestCloseCycle(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+
+	err := contract.AdmitMember(newContext(stub, "teller", "Org2MSP"), "Org2MSP", "bank2")
+	checkError(t, err, "client from Org2MSP is not the netting operator")
+
+	openCycle(t, stub)
+	err = contract.CloseCycle(newContext(stub, "teller", "Org2MSP"), "c1")
+	checkError(t, err, "client from Org2MSP is not the netting operator")
+
+	err = contract.CancelObligation(newContext(stub, "teller", "Org2MSP"), "c1", "o1")
+	checkError(t, err, "obligation o1 does not exist in cycle c1")
+	closeCycle(t, stub)
+
+	cycle, err := contract.GetCycle(newContext(stub, "operator", operatorMSPID), "c1")
+	checkError(t, err, "")
+	if cycle.Status != cycleClosed || cycle.ObligationCount != 3 || cycle.GrossValue != 190 {
+		t.Fatalf("unexpected cycle %+v", cycle)
+	}
+	if len(cycle.Instructions) != 2 || *cycle.Instructions[0] != (Instruction{From: "Org2MSP", To: "Org3MSP", Amount: 40}) ||
+		*cycle.Instructions[1] != (Instruction{From: "Org2MSP", To: "Org4MSP", Amount: 30}) {
+		t.Fatalf("unexpected instructions %+v %+v", cycle.Instructions[0], cycle.Instructions[1])
+	}
+	if stub.eventName != "CycleClosed" {
+		t.Fatalf("expected a CycleClosed event, got %s", stub.eventName)
+	}
+
+	err = contract.SubmitObligation(newContext(stub, "teller", "Org2MSP"), "c1", "o4", "Org3MSP", 10)
+	checkError(t, err, "cycle c1 is CLOSED and does not accept obligations")
+	err = contract.CancelObligation(newContext(stub, "teller", "Org2MSP"), "c1", "o1")
+	checkError(t, err, "cycle c1 is CLOSED, obligations can no longer be cancelled")
+}
+
+func TestExecuteSettlement(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	token := openCycle(t, stub)
+
+	err := contract.ExecuteSettlement(newContext(stub, "bank2", "Org2MSP"), "c1")
+	checkError(t, err, "cycle c1 is OPEN, only closed cycles are settled")
+	closeCycle(t, stub)
+
+	err = contract.ExecuteSettlement(newContext(stub, "teller", "Org2MSP"), "c1")
+	checkError(t, err, "only the settlement account of Org2MSP can execute its settlement")
+	err = contract.ExecuteSettlement(newContext(stub, "bank3", "Org3MSP"), "c1")
+	checkError(t, err, "Org3MSP has nothing to settle in cycle c1")
+
+	token.payer = "bank2"
+	token.balances["bank2"] = 69
+	err = contract.ExecuteSettlement(newContext(stub, "bank2", "Org2MSP"), "c1")
+	checkError(t, err, "failed to transfer tokens on token_erc20: failed to transfer: client account bank2 has insufficient funds")
+
+	// both instructions are paid in one batch debiting bank2 once
+	token.balances["bank2"] = 70
+	checkError(t, contract.ExecuteSettlement(newContext(stub, "bank2", "Org2MSP"), "c1"), "")
+	if token.batches != 1 || token.balances["bank2"] != 0 || token.balances["bank3"] != 40 || token.balances["bank4"] != 30 {
+		t.Fatalf("unexpected balances %v after %d batches", token.balances, token.batches)
+	}
+
+	cycle, err := contract.GetCycle(newContext(stub, "operator", operatorMSPID), "c1")
+	checkError(t, err, "")
+	if cycle.Status != cycleSettled || !cycle.Instructions[1].Executed || cycle.Instructions[1].TxID == "" {
+		t.Fatalf("unexpected cycle %+v", cycle)
+	}
+	err = contract.ExecuteSettlement(newContext(stub, "bank2", "Org2MSP"), "c1")
+	checkError(t, err, "cycle c1 is SETTLED, only closed cycles are settled")
+}
+
+func TestExecuteSettlementInBatches(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	token := openCycle(t, stub)
+
+	// Org2MSP owes a member each of the instructions, one more than a BatchTransfer pays
+	for i := 0; i <= maxInstructionsPerSettlement; i++ {
+		mspID := fmt.Sprintf("Bank%03dMSP", i)
+		checkError(t, contract.AdmitMember(newContext(stub, "operator", operatorMSPID), mspID, mspID), "")
+		checkError(t, contract.SubmitObligation(newContext(stub, "teller", "Org2MSP"), "c1", mspID, mspID, 10), "")
+	}
+	checkError(t, contract.CloseCycle(newContext(stub, "operator", operatorMSPID), "c1"), "")
+
+	token.payer = "bank2"
+	token.balances["bank2"] = 1010
+	checkError(t, contract.ExecuteSettlement(newContext(stub, "bank2", "Org2MSP"), "c1"), "")
+	cycle, err := contract.GetCycle(newContext(stub, "operator", operatorMSPID), "c1")
+	checkError(t, err, "")
+	if cycle.Status != cycleClosed || token.balances["bank2"] != 10 {
+		t.Fatalf("unexpected cycle %s after the first settlement, bank2 holds %d", cycle.Status, token.balances["bank2"])
+	}
+
+	checkError(t, contract.ExecuteSettlement(newContext(stub, "bank2", "Org2MSP"), "c1"), "")
+	cycle, err = contract.GetCycle(newContext(stub, "operator", operatorMSPID), "c1")
+	checkError(t, err, "")
+	if cycle.Status != cycleSettled || token.batches != 2 || token.balances["bank2"] != 0 {
+		t.Fatalf("unexpected cycle %s after %d batches", cycle.Status, token.batches)
+	}
+}
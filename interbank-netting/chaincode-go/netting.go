@@ -0,0 +1,23 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"log"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/interbank-netting/chaincode-go/chaincode"
+)
+
+func main() {
+	nettingChaincode, err := contractapi.NewChaincode(&chaincode.SmartContract{})
+	if err != nil {
+		log.Panicf("Error creating interbank-netting chaincode: %v", err)
+	}
+
+	if err := nettingChaincode.Start(); err != nil {
+		log.Panicf("Error starting interbank-netting chaincode: %v", err)
+	}
+}
@@ -0,0 +1,144 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package appclient
+
+import (
+	"crypto/x509"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-gateway/pkg/identity"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// Timeouts of the Gateway calls. Endorsing waits for every endorsing peer and committing for the
+// block, so they take longer than evaluating.
+const (
+	EvaluateTimeout     = 5 * time.Second
+	EndorseTimeout      = 15 * time.Second
+	SubmitTimeout       = 5 * time.Second
+	CommitStatusTimeout = time.Minute
+)
+
+// Connection is a Gateway connection of a client identity to a peer of its org
+type Connection struct {
+	*client.Gateway
+	clientConn *grpc.ClientConn
+}
+
+// Connect connects to a peer of the profile as the user whose MSP directory is mspDir, e.g.
+// test-network/organizations/peerOrganizations/org1.example.com/users/User1@org1.example.com/msp.
+// peerName selects the peer, empty meaning the first peer of the client's org.
+func Connect(profile *Profile, peerName string, mspDir string) (*Connection, error) {
+	id, err := NewIdentity(profile.MSPID(), mspDir)
+	if err != nil {
+		return nil, err
+	}
+	sign, err := NewSign(mspDir)
+	if err != nil {
+		return nil, err
+	}
+	return connect(profile, peerName, id, sign)
+}
+
+// connect connects to a peer of the profile as the identity signing with sign
+func connect(profile *Profile, peerName string, id identity.Identity, sign identity.Sign) (*Connection, error) {
+	peer, err := profile.Peer(peerName)
+	if err != nil {
+		return nil, err
+	}
+
+	clientConn, err := dial(peer)
+	if err != nil {
+		return nil, err
+	}
+	gateway, err := client.Connect(
+		id,
+		client.WithSign(sign),
+		client.WithClientConnection(clientConn),
+		client.WithEvaluateTimeout(EvaluateTimeout),
+		client.WithEndorseTimeout(EndorseTimeout),
+		client.WithSubmitTimeout(SubmitTimeout),
+		client.WithCommitStatusTimeout(CommitStatusTimeout),
+	)
+	if err != nil {
+		clientConn.Close()
+		return nil, fmt.Errorf("failed to connect to gateway of %s: %v", peer.Name, err)
+	}
+
+	return &Connection{Gateway: gateway, clientConn: clientConn}, nil
+}
+
+// Close closes the Gateway and its gRPC connection to the peer
+func (c *Connection) Close() error {
+	err := c.Gateway.Close()
+	if closeErr := c.clientConn.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// NewIdentity returns the X.509 identity of the user whose MSP directory is mspDir, read from the
+// certificate in its signcerts directory
+func NewIdentity(mspID string, mspDir string) (*identity.X509Identity, error) {
+	certificatePEM, err := readFirstFile(filepath.Join(mspDir, "signcerts"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read certificate: %v", err)
+	}
+	certificate, err := identity.CertificateFromPEM(certificatePEM)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse certificate: %v", err)
+	}
+	return identity.NewX509Identity(mspID, certificate)
+}
+
+// NewSign returns the signing function of the user whose MSP directory is mspDir, using the private
+// key in its keystore directory
+func NewSign(mspDir string) (identity.Sign, error) {
+	privateKeyPEM, err := readFirstFile(filepath.Join(mspDir, "keystore"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read private key: %v", err)
+	}
+	privateKey, err := identity.PrivateKeyFromPEM(privateKeyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %v", err)
+	}
+	return identity.NewPrivateKeySign(privateKey)
+}
+
+// dial opens the TLS gRPC connection to the peer
+func dial(peer *Peer) (*grpc.ClientConn, error) {
+	certPool := x509.NewCertPool()
+	if !certPool.AppendCertsFromPEM(peer.TLSCACert) {
+		return nil, fmt.Errorf("failed to add TLS CA certificate of %s", peer.Name)
+	}
+	transportCredentials := credentials.NewClientTLSFromCert(certPool, peer.HostOverride)
+
+	clientConn, err := grpc.Dial(peer.Endpoint, grpc.WithTransportCredentials(transportCredentials))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gRPC connection to %s: %v", peer.Endpoint, err)
+	}
+	return clientConn, nil
+}
+
+// readFirstFile reads the first file of a directory. The MSP directories of the test network hold
+// one certificate in signcerts and one key in keystore, whose file names vary between cryptogen
+// and the CAs.
+func readFirstFile(dir string) ([]byte, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			return os.ReadFile(filepath.Join(dir, entry.Name()))
+		}
+	}
+	return nil, fmt.Errorf("no file in %s", dir)
+}
@@ -0,0 +1,250 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package appclient
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// EnrollTimeout is the timeout of a request to a certificate authority
+const EnrollTimeout = 10 * time.Second
+
+// CertificateAuthority is a Fabric CA of a connection profile
+type CertificateAuthority struct {
+	Name string
+	// URL is the https:// address of the CA server
+	URL string
+	// CAName selects the CA of a server running several, e.g. ca-org1 in the test network
+	CAName string
+	// TLSCACert is the PEM of the CA that issued the server's TLS certificate
+	TLSCACert []byte
+}
+
+// Enrollment is a certificate issued by a certificate authority and the private key it certifies
+type Enrollment struct {
+	CertificatePEM []byte
+	PrivateKeyPEM  []byte
+}
+
+// caResponse is the response of the Fabric CA REST API
+type caResponse struct {
+	Success bool `json:"success"`
+	Result  struct {
+		Cert string `json:"Cert"`
+	} `json:"result"`
+	Errors []struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// Enroll generates a private key and has the CA certify it for the identity registered as
+// enrollmentID, e.g. user1 with the secret user1pw in the test network
+func (ca *CertificateAuthority) Enroll(enrollmentID string, secret string) (*Enrollment, error) {
+	key, csrPEM, err := newKeyRequest(enrollmentID)
+	if err != nil {
+		return nil, err
+	}
+	request, body, err := ca.newRequest("enroll", csrPEM)
+	if err != nil {
+		return nil, err
+	}
+	request.SetBasicAuth(enrollmentID, secret)
+	return ca.certify(request, body, key)
+}
+
+// Reenroll has the CA certify a new private key for the identity of an enrollment, e.g. before its
+// certificate expires. The request is authenticated with the current certificate and key.
+func (ca *CertificateAuthority) Reenroll(current *Enrollment) (*Enrollment, error) {
+	certificate, err := parseCertificate(current.CertificatePEM)
+	if err != nil {
+		return nil, err
+	}
+	currentKey, err := parseECDSAKey(current.PrivateKeyPEM)
+	if err != nil {
+		return nil, err
+	}
+	key, csrPEM, err := newKeyRequest(certificate.Subject.CommonName)
+	if err != nil {
+		return nil, err
+	}
+	request, body, err := ca.newRequest("reenroll", csrPEM)
+	if err != nil {
+		return nil, err
+	}
+	token, err := authToken(current.CertificatePEM, currentKey, request.Method, request.URL.RequestURI(), body)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Authorization", token)
+	return ca.certify(request, body, key)
+}
+
+// newRequest returns the POST request of the enroll or reenroll endpoint with a certificate
+// signing request
+func (ca *CertificateAuthority) newRequest(endpoint string, csrPEM []byte) (*http.Request, []byte, error) {
+	body, err := json.Marshal(map[string]string{"certificate_request": string(csrPEM), "caname": ca.CAName})
+	if err != nil {
+		return nil, nil, err
+	}
+	request, err := http.NewRequest(http.MethodPost, ca.URL+"/api/v1/"+endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid URL of certificate authority %s: %v", ca.Name, err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	return request, body, nil
+}
+
+// certify sends an enroll or reenroll request and returns the certificate the CA issued for key
+func (ca *CertificateAuthority) certify(request *http.Request, body []byte, key *ecdsa.PrivateKey) (*Enrollment, error) {
+	httpClient, err := ca.httpClient()
+	if err != nil {
+		return nil, err
+	}
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call certificate authority %s: %v", ca.Name, err)
+	}
+	defer response.Body.Close()
+	responseJSON, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response of certificate authority %s: %v", ca.Name, err)
+	}
+
+	var result caResponse
+	err = json.Unmarshal(responseJSON, &result)
+	if err != nil {
+		return nil, fmt.Errorf("certificate authority %s returned %s: %q", ca.Name, response.Status, responseJSON)
+	}
+	if !result.Success {
+		messages := make([]string, len(result.Errors))
+		for i, e := range result.Errors {
+			messages[i] = fmt.Sprintf("%s (code %d)", e.Message, e.Code)
+		}
+		return nil, fmt.Errorf("certificate authority %s refused the request: %s", ca.Name, strings.Join(messages, ", "))
+	}
+	certificatePEM, err := base64.StdEncoding.DecodeString(result.Result.Cert)
+	if err != nil {
+		return nil, fmt.Errorf("certificate authority %s returned an invalid certificate: %v", ca.Name, err)
+	}
+	certificate, err := parseCertificate(certificatePEM)
+	if err != nil {
+		return nil, err
+	}
+	if publicKey, ok := certificate.PublicKey.(*ecdsa.PublicKey); !ok || !publicKey.Equal(&key.PublicKey) {
+		return nil, fmt.Errorf("certificate authority %s returned a certificate of another key", ca.Name)
+	}
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return &Enrollment{
+		CertificatePEM: certificatePEM,
+		PrivateKeyPEM:  pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}),
+	}, nil
+}
+
+func (ca *CertificateAuthority) httpClient() (*http.Client, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if len(ca.TLSCACert) > 0 {
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(ca.TLSCACert) {
+			return nil, fmt.Errorf("invalid TLS CA certificate of certificate authority %s", ca.Name)
+		}
+	}
+	return &http.Client{
+		Timeout:   EnrollTimeout,
+		Transport: &http.Transport{TLSClientConfig: tlsConfig},
+	}, nil
+}
+
+// newKeyRequest generates a P-256 private key, as the Fabric CA client does, and the certificate
+// signing request of commonName for it
+func newKeyRequest(commonName string) (*ecdsa.PrivateKey, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate private key: %v", err)
+	}
+	csrDER, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:            pkix.Name{CommonName: commonName},
+		SignatureAlgorithm: x509.ECDSAWithSHA256,
+	}, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create certificate request: %v", err)
+	}
+	return key, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csrDER}), nil
+}
+
+// authToken returns the token authenticating a request to the Fabric CA with an enrollment: the
+// certificate and the signature of the method, URI, body and certificate, each base64 encoded
+func authToken(certificatePEM []byte, key *ecdsa.PrivateKey, method string, uri string, body []byte) (string, error) {
+	b64 := base64.StdEncoding.EncodeToString
+	b64Cert := b64(certificatePEM)
+	digest := sha256.Sum256([]byte(method + "." + b64([]byte(uri)) + "." + b64(body) + "." + b64Cert))
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign request: %v", err)
+	}
+	// Fabric accepts only signatures with a low S
+	halfOrder := new(big.Int).Rsh(key.Params().N, 1)
+	if s.Cmp(halfOrder) > 0 {
+		s.Sub(key.Params().N, s)
+	}
+	signature, err := asn1.Marshal(struct{ R, S *big.Int }{r, s})
+	if err != nil {
+		return "", err
+	}
+	return b64Cert + "." + b64(signature), nil
+}
+
+func parseCertificate(certificatePEM []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(certificatePEM)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("no PEM certificate found")
+	}
+	certificate, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse certificate: %v", err)
+	}
+	return certificate, nil
+}
+
+// parseECDSAKey parses a PKCS #8 or SEC 1 ECDSA private key, as written by the Fabric CA and cryptogen
+func parseECDSAKey(keyPEM []byte) (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM private key found")
+	}
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %v", err)
+	}
+	ecKey, ok := key.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key is a %T, not an ECDSA key", key)
+	}
+	return ecKey, nil
+}
@@ -0,0 +1,190 @@
+package appclient
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testCA is a Fabric CA server issuing certificates for the enroll and reenroll endpoints
+type testCA struct {
+	t       *testing.T
+	key     *ecdsa.PrivateKey
+	cert    *x509.Certificate
+	serial  int64
+	secrets map[string]string
+}
+
+func newTestCA(t *testing.T) (*testCA, *CertificateAuthority) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ca.org1.example.com"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	ca := &testCA{t: t, key: key, cert: cert, serial: 1, secrets: map[string]string{"user1": "user1pw"}}
+
+	server := httptest.NewTLSServer(ca)
+	t.Cleanup(server.Close)
+	tlsCACert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	return ca, &CertificateAuthority{Name: "ca.org1.example.com", URL: server.URL, CAName: "ca-org1", TLSCACert: tlsCACert}
+}
+
+func (ca *testCA) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		CSR    string `json:"certificate_request"`
+		CAName string `json:"caname"`
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil || json.Unmarshal(body, &request) != nil || request.CAName != "ca-org1" {
+		ca.fail(w, 0, "invalid request")
+		return
+	}
+
+	switch r.URL.Path {
+	case "/api/v1/enroll":
+		user, secret, ok := r.BasicAuth()
+		if !ok || ca.secrets[user] != secret {
+			ca.fail(w, 20, "Authentication failure")
+			return
+		}
+	case "/api/v1/reenroll":
+		if !ca.verifyToken(r.Header.Get("Authorization"), r.Method, r.URL.RequestURI(), body) {
+			ca.fail(w, 20, "Authentication failure")
+			return
+		}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	block, _ := pem.Decode([]byte(request.CSR))
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil || csr.CheckSignature() != nil {
+		ca.fail(w, 0, "invalid certificate request")
+		return
+	}
+	ca.serial++
+	der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(ca.serial),
+		Subject:      csr.Subject,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}, ca.cert, csr.PublicKey, ca.key)
+	if err != nil {
+		ca.t.Fatal(err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"result":  map[string]string{"Cert": base64.StdEncoding.EncodeToString(certPEM)},
+		"errors":  []interface{}{},
+	})
+}
+
+// verifyToken checks the token of a request as the Fabric CA does, with a certificate it issued
+func (ca *testCA) verifyToken(token string, method string, uri string, body []byte) bool {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return false
+	}
+	certPEM, err1 := base64.StdEncoding.DecodeString(parts[0])
+	signature, err2 := base64.StdEncoding.DecodeString(parts[1])
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	cert, err := parseCertificate(certPEM)
+	if err != nil || cert.CheckSignatureFrom(ca.cert) != nil {
+		return false
+	}
+	var sig struct{ R, S *big.Int }
+	if _, err := asn1.Unmarshal(signature, &sig); err != nil {
+		return false
+	}
+	if sig.S.Cmp(new(big.Int).Rsh(elliptic.P256().Params().N, 1)) > 0 {
+		return false
+	}
+	b64 := base64.StdEncoding.EncodeToString
+	digest := sha256.Sum256([]byte(method + "." + b64([]byte(uri)) + "." + b64(body) + "." + parts[0]))
+	return ecdsa.Verify(cert.PublicKey.(*ecdsa.PublicKey), digest[:], sig.R, sig.S)
+}
+
+func (ca *testCA) fail(w http.ResponseWriter, code int, message string) {
+	w.WriteHeader(http.StatusUnauthorized)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": false,
+		"errors":  []map[string]interface{}{{"code": code, "message": message}},
+	})
+}
+
+func TestWalletEnroll(t *testing.T) {
+	_, ca := newTestCA(t)
+	wallet, err := OpenWallet(filepath.Join(t.TempDir(), "wallet"))
+	if err != nil {
+		t.Fatalf("failed to open wallet: %v", err)
+	}
+
+	err = wallet.Enroll("org1-user1", "Org1MSP", ca, "user1", "wrong")
+	if err == nil || !strings.Contains(err.Error(), "Authentication failure (code 20)") {
+		t.Fatalf("expected authentication failure, got %v", err)
+	}
+
+	err = wallet.Enroll("org1-user1", "Org1MSP", ca, "user1", "user1pw")
+	if err != nil {
+		t.Fatalf("failed to enroll: %v", err)
+	}
+	enrolled, err := wallet.Get("org1-user1")
+	if err != nil {
+		t.Fatalf("failed to get identity: %v", err)
+	}
+	cert, err := parseCertificate([]byte(enrolled.Credentials.Certificate))
+	if err != nil || cert.Subject.CommonName != "user1" || enrolled.MSPID != "Org1MSP" {
+		t.Fatalf("enrolled %+v, certificate error %v", enrolled, err)
+	}
+	if _, err := parseECDSAKey([]byte(enrolled.Credentials.PrivateKey)); err != nil {
+		t.Errorf("invalid private key: %v", err)
+	}
+
+	err = wallet.Reenroll("org1-user1", ca)
+	if err != nil {
+		t.Fatalf("failed to reenroll: %v", err)
+	}
+	reenrolled, err := wallet.Get("org1-user1")
+	if err != nil {
+		t.Fatalf("failed to get identity: %v", err)
+	}
+	newCert, err := parseCertificate([]byte(reenrolled.Credentials.Certificate))
+	if err != nil || newCert.Subject.CommonName != "user1" || newCert.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+		t.Errorf("reenrolled certificate %v, error %v", newCert.Subject, err)
+	}
+	if reenrolled.Credentials.PrivateKey == enrolled.Credentials.PrivateKey {
+		t.Errorf("reenrolling kept the private key")
+	}
+}
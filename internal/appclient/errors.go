@@ -0,0 +1,98 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package appclient
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-protos-go-apiv2/gateway"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Codes of the errors of Gateway calls that did not reach or were not answered by a chaincode.
+// Chaincode errors keep the code the chaincode returned.
+const (
+	// CodeCommitFailed is the code of a transaction that was endorsed but invalidated when
+	// committed, e.g. by a read conflict with a concurrent transaction. It can be retried.
+	CodeCommitFailed = "COMMIT_FAILED"
+	CodeUnavailable  = "UNAVAILABLE"
+	CodeTimeout      = "TIMEOUT"
+	// CodeInternal is the code of any other error, as for the chaincodes' own uncoded errors
+	CodeInternal = "INTERNAL"
+)
+
+// Error is the coded error of a failed Gateway call, in the JSON form the chaincodes return their
+// errors in
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+// ParseError returns the coded error of an error returned by a Gateway call. The coded error a
+// chaincode returned is read from the details of the error's gRPC status, which hold the message
+// of each endorsing peer; the first peer message with a code other than INTERNAL wins.
+func ParseError(err error) *Error {
+	var codedErr *Error
+	if errors.As(err, &codedErr) {
+		return codedErr
+	}
+	var commitErr *client.CommitError
+	if errors.As(err, &commitErr) {
+		return &Error{Code: CodeCommitFailed, Message: commitErr.Error()}
+	}
+
+	var grpcErr interface{ GRPCStatus() *status.Status }
+	if !errors.As(err, &grpcErr) {
+		return &Error{Code: CodeInternal, Message: err.Error()}
+	}
+	grpcStatus := grpcErr.GRPCStatus()
+
+	switch grpcStatus.Code() {
+	case codes.DeadlineExceeded:
+		return &Error{Code: CodeTimeout, Message: grpcStatus.Message()}
+	case codes.Unavailable:
+		return &Error{Code: CodeUnavailable, Message: grpcStatus.Message()}
+	}
+
+	var peerErr *Error
+	for _, detail := range grpcStatus.Details() {
+		errorDetail, ok := detail.(*gateway.ErrorDetail)
+		if !ok {
+			continue
+		}
+		codedErr := parseChaincodeMessage(errorDetail.GetMessage())
+		if codedErr.Code != CodeInternal {
+			return codedErr
+		}
+		if peerErr == nil {
+			peerErr = codedErr
+		}
+	}
+	if peerErr != nil {
+		return peerErr
+	}
+	return parseChaincodeMessage(grpcStatus.Message())
+}
+
+// parseChaincodeMessage reads the coded error of a chaincode from a peer's message, such as
+// `chaincode response 500, {"code":"INSUFFICIENT_FUNDS","message":"..."}`. A message without one
+// is an INTERNAL error.
+func parseChaincodeMessage(message string) *Error {
+	if i := strings.Index(message, "{"); i >= 0 {
+		var codedErr Error
+		if json.Unmarshal([]byte(message[i:]), &codedErr) == nil && codedErr.Code != "" {
+			return &codedErr
+		}
+	}
+	return &Error{Code: CodeInternal, Message: message}
+}
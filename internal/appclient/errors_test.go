@@ -0,0 +1,25 @@
+package appclient
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestParseChaincodeMessage(t *testing.T) {
+	codedErr := parseChaincodeMessage(`chaincode response 500, {"code":"INSUFFICIENT_FUNDS","message":"client account has insufficient funds"}`)
+	if codedErr.Code != "INSUFFICIENT_FUNDS" || codedErr.Message != "client account has insufficient funds" {
+		t.Errorf("coded error is %+v", codedErr)
+	}
+	codedErr = parseChaincodeMessage("failed to collect enough transaction endorsements")
+	if codedErr.Code != CodeInternal || codedErr.Message != "failed to collect enough transaction endorsements" {
+		t.Errorf("coded error is %+v", codedErr)
+	}
+}
+
+func TestParseErrorWithoutStatus(t *testing.T) {
+	codedErr := ParseError(fmt.Errorf("failed to submit Mint: %w", errors.New("connection closed")))
+	if codedErr.Code != CodeInternal || codedErr.Message != "failed to submit Mint: connection closed" {
+		t.Errorf("coded error is %+v", codedErr)
+	}
+}
@@ -0,0 +1,27 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package appclient
+
+import "time"
+
+// BuildInfo is the chaincode build a peer runs, returned by the GetVersion function of the chaincodes
+type BuildInfo struct {
+	Contract  string `json:"contract"`
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	GoVersion string `json:"goVersion"`
+}
+
+// PingResult is returned by the Ping function of the chaincodes. PeerMSPID is the org of the peer
+// that answered.
+type PingResult struct {
+	Status        string    `json:"status"`
+	Contract      string    `json:"contract"`
+	Version       string    `json:"version"`
+	PeerMSPID     string    `json:"peerMSPID"`
+	SchemaVersion int       `json:"schemaVersion"`
+	TxID          string    `json:"txID"`
+	Timestamp     time.Time `json:"timestamp"`
+}
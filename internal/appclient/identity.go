@@ -0,0 +1,17 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package appclient
+
+// ClientIdentity is the identity of the connected client as the chaincodes decode it from its
+// certificate, returned by their WhoAmI function
+type ClientIdentity struct {
+	// ID is the client ID the chaincodes use as account ID, the base64 of its subject and issuer
+	ID                  string            `json:"id"`
+	MSPID               string            `json:"mspID"`
+	CommonName          string            `json:"commonName"`
+	OrganizationalUnits []string          `json:"organizationalUnits"`
+	IssuerCommonName    string            `json:"issuerCommonName"`
+	Attributes          map[string]string `json:"attributes"`
+}
@@ -0,0 +1,20 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package appclient
+
+// KeyEntry is one entry returned by the ListByCompositeKey function of the chaincodes: the
+// attributes of its composite key and its value as stored
+type KeyEntry struct {
+	Attributes []string `json:"attributes"`
+	Value      string   `json:"value"`
+}
+
+// KeyPage is one page of a composite key listing. Bookmark is passed to the next call to continue
+// and is empty on the last page.
+type KeyPage struct {
+	ObjectType string     `json:"objectType"`
+	Entries    []KeyEntry `json:"entries"`
+	Bookmark   string     `json:"bookmark"`
+}
@@ -0,0 +1,30 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package appclient
+
+import "time"
+
+// Call is a finished Gateway call of a contract
+type Call struct {
+	Chaincode string
+	Function  string
+	// Submit tells a submitted transaction from an evaluated query
+	Submit   bool
+	Duration time.Duration
+	// Err is the error the call returned, nil when it succeeded
+	Err error
+}
+
+// Observer is told of every Gateway call of the contracts it is set on, e.g. to record metrics. It
+// is called on the goroutine of the call, so it must not block.
+type Observer func(call *Call)
+
+// Observe tells the observer, if any, of a call that started at start
+func (o Observer) Observe(chaincode string, function string, submit bool, start time.Time, err error) {
+	if o == nil {
+		return
+	}
+	o(&Call{Chaincode: chaincode, Function: function, Submit: submit, Duration: time.Since(start), Err: err})
+}
@@ -0,0 +1,151 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package appclient connects client applications of this repository to the peers of the test network
+// through the Fabric Gateway, using the connection profiles written by the test network's
+// ccp-generate.sh and the MSP directories of its users.
+package appclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Profile is the part of a JSON connection profile, e.g.
+// test-network/organizations/peerOrganizations/org1.example.com/connection-org1.json, that the
+// Gateway needs: the client's org and the peers it can connect to
+type Profile struct {
+	Name   string `json:"name"`
+	Client struct {
+		Organization string `json:"organization"`
+	} `json:"client"`
+	Organizations map[string]struct {
+		MSPID                  string   `json:"mspid"`
+		Peers                  []string `json:"peers"`
+		CertificateAuthorities []string `json:"certificateAuthorities"`
+	} `json:"organizations"`
+	Peers map[string]struct {
+		URL        string `json:"url"`
+		TLSCACerts struct {
+			PEM string `json:"pem"`
+		} `json:"tlsCACerts"`
+		GRPCOptions struct {
+			SSLTargetNameOverride string `json:"ssl-target-name-override"`
+		} `json:"grpcOptions"`
+	} `json:"peers"`
+	CertificateAuthorities map[string]struct {
+		URL        string `json:"url"`
+		CAName     string `json:"caName"`
+		TLSCACerts struct {
+			PEM pemList `json:"pem"`
+		} `json:"tlsCACerts"`
+	} `json:"certificateAuthorities"`
+}
+
+// pemList is the PEM of TLS CA certificates in a connection profile, a string or, as ccp-generate.sh
+// writes it for the certificate authorities, a list of strings
+type pemList []string
+
+func (l *pemList) UnmarshalJSON(data []byte) error {
+	var pem string
+	if err := json.Unmarshal(data, &pem); err == nil {
+		*l = pemList{pem}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(l))
+}
+
+// Peer is a peer of a connection profile
+type Peer struct {
+	Name string
+	// Endpoint is the host:port of the peer, without the grpcs:// scheme of the profile
+	Endpoint string
+	// TLSCACert is the PEM of the CA that issued the peer's TLS certificate
+	TLSCACert []byte
+	// HostOverride is the host name in the peer's TLS certificate, when it differs from the endpoint host
+	HostOverride string
+}
+
+// LoadProfile reads a JSON connection profile
+func LoadProfile(path string) (*Profile, error) {
+	profileJSON, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read connection profile: %v", err)
+	}
+
+	var profile Profile
+	err = json.Unmarshal(profileJSON, &profile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal connection profile %s: %v", path, err)
+	}
+	if _, ok := profile.Organizations[profile.Client.Organization]; !ok {
+		return nil, fmt.Errorf("connection profile %s has no client organization", path)
+	}
+	return &profile, nil
+}
+
+// MSPID returns the MSP ID of the client's org
+func (p *Profile) MSPID() string {
+	return p.Organizations[p.Client.Organization].MSPID
+}
+
+// Peer returns the named peer of the client's org, or its first peer when name is empty
+func (p *Profile) Peer(name string) (*Peer, error) {
+	orgPeers := p.Organizations[p.Client.Organization].Peers
+	if len(orgPeers) == 0 {
+		return nil, fmt.Errorf("organization %s of connection profile %s has no peers", p.Client.Organization, p.Name)
+	}
+	if name == "" {
+		name = orgPeers[0]
+	}
+
+	peer, ok := p.Peers[name]
+	if !ok {
+		names := make([]string, 0, len(p.Peers))
+		for peerName := range p.Peers {
+			names = append(names, peerName)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("peer %s is not in connection profile %s, peers: %s", name, p.Name, strings.Join(names, ", "))
+	}
+	if peer.TLSCACerts.PEM == "" {
+		return nil, fmt.Errorf("peer %s of connection profile %s has no TLS CA certificate", name, p.Name)
+	}
+
+	endpoint := peer.URL
+	for _, scheme := range []string{"grpcs://", "grpc://"} {
+		endpoint = strings.TrimPrefix(endpoint, scheme)
+	}
+	return &Peer{
+		Name:         name,
+		Endpoint:     endpoint,
+		TLSCACert:    []byte(peer.TLSCACerts.PEM),
+		HostOverride: peer.GRPCOptions.SSLTargetNameOverride,
+	}, nil
+}
+
+// CA returns the named certificate authority of the client's org, or its first one when name is empty
+func (p *Profile) CA(name string) (*CertificateAuthority, error) {
+	orgCAs := p.Organizations[p.Client.Organization].CertificateAuthorities
+	if len(orgCAs) == 0 {
+		return nil, fmt.Errorf("organization %s of connection profile %s has no certificate authorities", p.Client.Organization, p.Name)
+	}
+	if name == "" {
+		name = orgCAs[0]
+	}
+
+	ca, ok := p.CertificateAuthorities[name]
+	if !ok {
+		return nil, fmt.Errorf("certificate authority %s is not in connection profile %s", name, p.Name)
+	}
+	return &CertificateAuthority{
+		Name:      name,
+		URL:       strings.TrimSuffix(ca.URL, "/"),
+		CAName:    ca.CAName,
+		TLSCACert: []byte(strings.Join(ca.TLSCACerts.PEM, "\n")),
+	}, nil
+}
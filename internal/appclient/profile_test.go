@@ -0,0 +1,111 @@
+package appclient
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testProfile is a connection profile as written by ccp-generate.sh for Org1
+const testProfile = `{
+    "name": "test-network-org1",
+    "version": "1.0.0",
+    "client": {
+        "organization": "Org1"
+    },
+    "organizations": {
+        "Org1": {
+            "mspid": "Org1MSP",
+            "peers": ["peer0.org1.example.com"],
+            "certificateAuthorities": ["ca.org1.example.com"]
+        }
+    },
+    "peers": {
+        "peer0.org1.example.com": {
+            "url": "grpcs://localhost:7051",
+            "tlsCACerts": {
+                "pem": "-----BEGIN CERTIFICATE-----\nMIIC\n-----END CERTIFICATE-----\n"
+            },
+            "grpcOptions": {
+                "ssl-target-name-override": "peer0.org1.example.com",
+                "hostnameOverride": "peer0.org1.example.com"
+            }
+        }
+    },
+    "certificateAuthorities": {
+        "ca.org1.example.com": {
+            "url": "https://localhost:7054",
+            "caName": "ca-org1",
+            "tlsCACerts": {
+                "pem": ["-----BEGIN CERTIFICATE-----\nMIIC\n-----END CERTIFICATE-----\n"]
+            },
+            "httpOptions": {
+                "verify": false
+            }
+        }
+    }
+}`
+
+func writeProfile(t *testing.T, profileJSON string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "connection-org1.json")
+	err := os.WriteFile(path, []byte(profileJSON), 0600)
+	if err != nil {
+		t.Fatalf("failed to write profile: %v", err)
+	}
+	return path
+}
+
+func TestLoadProfile(t *testing.T) {
+	profile, err := LoadProfile(writeProfile(t, testProfile))
+	if err != nil {
+		t.Fatalf("failed to load profile: %v", err)
+	}
+	if got := profile.MSPID(); got != "Org1MSP" {
+		t.Errorf("MSP ID is %q, want Org1MSP", got)
+	}
+
+	peer, err := profile.Peer("")
+	if err != nil {
+		t.Fatalf("failed to get peer: %v", err)
+	}
+	if peer.Name != "peer0.org1.example.com" || peer.Endpoint != "localhost:7051" || peer.HostOverride != "peer0.org1.example.com" {
+		t.Errorf("peer is %+v", peer)
+	}
+	if !strings.HasPrefix(string(peer.TLSCACert), "-----BEGIN CERTIFICATE-----\n") {
+		t.Errorf("TLS CA certificate is %q", peer.TLSCACert)
+	}
+
+	_, err = profile.Peer("peer1.org1.example.com")
+	if err == nil || !strings.Contains(err.Error(), "peers: peer0.org1.example.com") {
+		t.Errorf("expected error listing the peers, got %v", err)
+	}
+}
+
+func TestProfileCA(t *testing.T) {
+	profile, err := LoadProfile(writeProfile(t, testProfile))
+	if err != nil {
+		t.Fatalf("failed to load profile: %v", err)
+	}
+	ca, err := profile.CA("")
+	if err != nil {
+		t.Fatalf("failed to get certificate authority: %v", err)
+	}
+	if ca.Name != "ca.org1.example.com" || ca.URL != "https://localhost:7054" || ca.CAName != "ca-org1" ||
+		!strings.HasPrefix(string(ca.TLSCACert), "-----BEGIN CERTIFICATE-----\n") {
+		t.Errorf("certificate authority is %+v", ca)
+	}
+
+	_, err = profile.CA("ca.org2.example.com")
+	if err == nil || !strings.Contains(err.Error(), "is not in connection profile") {
+		t.Errorf("expected missing certificate authority error, got %v", err)
+	}
+}
+
+func TestLoadProfileWithoutClientOrganization(t *testing.T) {
+	_, err := LoadProfile(writeProfile(t, strings.Replace(testProfile, `"organization": "Org1"`, `"organization": "Org3"`, 1)))
+	if err == nil || !strings.Contains(err.Error(), "has no client organization") {
+		t.Errorf("expected missing client organization error, got %v", err)
+	}
+}
@@ -0,0 +1,124 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package appclient
+
+import (
+	"context"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TraceIDKey is the transient data key the trace ID of a submitted transaction is passed in. The
+// chaincodes read it with ledgerutil.TraceID and record it in their audit records and receipts.
+const TraceIDKey = "trace_id"
+
+// tracer records the spans of the Gateway calls. It is a no-op until the application sets a global
+// tracer provider, e.g. with the tracing package.
+var tracer = otel.Tracer("github.com/hyperledger/fabric-samples/internal/appclient")
+
+// Span attributes of the Gateway calls
+const (
+	chaincodeKey   = attribute.Key("fabric.chaincode")
+	functionKey    = attribute.Key("fabric.function")
+	txIDKey        = attribute.Key("fabric.tx_id")
+	blockNumberKey = attribute.Key("fabric.block_number")
+	statusCodeKey  = attribute.Key("fabric.validation_code")
+)
+
+// Submit submits a transaction of a contract and waits for it to be committed, as
+// client.Contract.Submit does, with a span of the transaction in the trace of ctx and a child span
+// for each phase: proposal, endorsement, ordering and commit, so a slow transaction shows where it
+// spent its time. The trace ID is added to the transient data of the proposal.
+func Submit(ctx context.Context, contract *client.Contract, function string, transient map[string][]byte, options ...client.ProposalOption) ([]byte, error) {
+	ctx, span := tracer.Start(ctx, contract.ChaincodeName()+" "+function,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(chaincodeKey.String(contract.ChaincodeName()), functionKey.String(function)),
+	)
+	defer span.End()
+
+	transient = traceTransient(ctx, transient)
+	if transient != nil {
+		options = append(options, client.WithTransient(transient))
+	}
+	_, phase := tracer.Start(ctx, "proposal")
+	proposal, err := contract.NewProposal(function, options...)
+	if err != nil {
+		return nil, endSpans(err, phase, span)
+	}
+	span.SetAttributes(txIDKey.String(proposal.TransactionID()))
+	phase.End()
+
+	_, phase = tracer.Start(ctx, "endorsement")
+	transaction, err := proposal.Endorse()
+	if err != nil {
+		return nil, endSpans(err, phase, span)
+	}
+	phase.End()
+
+	_, phase = tracer.Start(ctx, "ordering")
+	commit, err := transaction.Submit()
+	if err != nil {
+		return nil, endSpans(err, phase, span)
+	}
+	phase.End()
+
+	_, phase = tracer.Start(ctx, "commit")
+	status, err := commit.Status()
+	if err != nil {
+		return nil, endSpans(err, phase, span)
+	}
+	phase.SetAttributes(blockNumberKey.Int64(int64(status.BlockNumber)), statusCodeKey.String(status.Code.String()))
+	if !status.Successful {
+		return nil, endSpans(&client.CommitError{TransactionID: status.TransactionID, Code: status.Code}, phase, span)
+	}
+	phase.End()
+	return transaction.Result(), nil
+}
+
+// Evaluate evaluates a query of a contract, as client.Contract.Evaluate does, with a span in the
+// trace of ctx
+func Evaluate(ctx context.Context, contract *client.Contract, function string, options ...client.ProposalOption) ([]byte, error) {
+	_, span := tracer.Start(ctx, contract.ChaincodeName()+" "+function,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(chaincodeKey.String(contract.ChaincodeName()), functionKey.String(function)),
+	)
+	defer span.End()
+
+	result, err := contract.Evaluate(function, options...)
+	if err != nil {
+		return nil, endSpans(err, span)
+	}
+	return result, nil
+}
+
+// traceTransient returns the transient data of a proposal with the ID of the trace of ctx added,
+// without changing the caller's map. It returns transient as is when ctx is not traced.
+func traceTransient(ctx context.Context, transient map[string][]byte) map[string][]byte {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.HasTraceID() {
+		return transient
+	}
+	traced := make(map[string][]byte, len(transient)+1)
+	for key, value := range transient {
+		traced[key] = value
+	}
+	traced[TraceIDKey] = []byte(spanContext.TraceID().String())
+	return traced
+}
+
+// endSpans records err as the outcome of spans and ends them, returning err. The enclosing span
+// is ended by its deferred End, which has no effect on an ended span.
+func endSpans(err error, spans ...trace.Span) error {
+	for _, span := range spans {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, ParseError(err).Code)
+		span.End()
+	}
+	return err
+}
@@ -0,0 +1,32 @@
+package appclient
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTraceTransient(t *testing.T) {
+	transient := map[string][]byte{"asset_properties": []byte(`{"color":"blue"}`)}
+	if traced := traceTransient(context.Background(), transient); len(traced) != 1 {
+		t.Errorf("untraced transient data is %v", traced)
+	}
+	if traced := traceTransient(context.Background(), nil); traced != nil {
+		t.Errorf("untraced empty transient data is %v", traced)
+	}
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID}))
+	traced := traceTransient(ctx, transient)
+	if string(traced[TraceIDKey]) != "4bf92f3577b34da6a3ce929d0e0e4736" || string(traced["asset_properties"]) != `{"color":"blue"}` {
+		t.Errorf("traced transient data is %v", traced)
+	}
+	if _, ok := transient[TraceIDKey]; ok {
+		t.Error("caller's transient data was changed")
+	}
+	if traced := traceTransient(ctx, nil); len(traced) != 1 {
+		t.Errorf("traced empty transient data is %v", traced)
+	}
+}
@@ -0,0 +1,199 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package appclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/fabric-gateway/pkg/identity"
+)
+
+// walletIDExtension is the extension of the identity files of a wallet
+const walletIDExtension = ".id"
+
+// WalletIdentity is an X.509 identity of a wallet, stored as <label>.id in the JSON format of the
+// Fabric SDK file system wallets, so wallets written by the Node and Go SDKs can be shared
+type WalletIdentity struct {
+	Version     int    `json:"version"`
+	MSPID       string `json:"mspId"`
+	Type        string `json:"type"`
+	Credentials struct {
+		Certificate string `json:"certificate"`
+		PrivateKey  string `json:"privateKey"`
+	} `json:"credentials"`
+}
+
+// Wallet is a directory holding identities by label
+type Wallet struct {
+	dir string
+}
+
+// OpenWallet opens the wallet in dir, creating the directory if it does not exist
+func OpenWallet(dir string) (*Wallet, error) {
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create wallet directory: %v", err)
+	}
+	return &Wallet{dir: dir}, nil
+}
+
+// List returns the labels of the identities in the wallet, sorted
+func (w *Wallet) List() ([]string, error) {
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read wallet: %v", err)
+	}
+	var labels []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), walletIDExtension) {
+			labels = append(labels, strings.TrimSuffix(entry.Name(), walletIDExtension))
+		}
+	}
+	sort.Strings(labels)
+	return labels, nil
+}
+
+// Get returns the identity stored under label
+func (w *Wallet) Get(label string) (*WalletIdentity, error) {
+	if err := checkLabel(label); err != nil {
+		return nil, err
+	}
+	idJSON, err := os.ReadFile(w.path(label))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("no identity %s in wallet %s", label, w.dir)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read identity %s: %v", label, err)
+	}
+	var id WalletIdentity
+	err = json.Unmarshal(idJSON, &id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal identity %s: %v", label, err)
+	}
+	if id.Type != "X.509" {
+		return nil, fmt.Errorf("identity %s has type %q, only X.509 is supported", label, id.Type)
+	}
+	return &id, nil
+}
+
+// Put stores the certificate and private key of a member of mspID under label, replacing any
+// identity with the same label
+func (w *Wallet) Put(label string, mspID string, certificatePEM []byte, privateKeyPEM []byte) error {
+	if err := checkLabel(label); err != nil {
+		return err
+	}
+	id := WalletIdentity{Version: 1, MSPID: mspID, Type: "X.509"}
+	id.Credentials.Certificate = string(certificatePEM)
+	id.Credentials.PrivateKey = string(privateKeyPEM)
+	idJSON, err := json.Marshal(id)
+	if err != nil {
+		return fmt.Errorf("failed to marshal identity %s: %v", label, err)
+	}
+	err = os.WriteFile(w.path(label), idJSON, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to write identity %s: %v", label, err)
+	}
+	return nil
+}
+
+// Import stores the user whose MSP directory is mspDir under label
+func (w *Wallet) Import(label string, mspID string, mspDir string) error {
+	certificatePEM, err := readFirstFile(filepath.Join(mspDir, "signcerts"))
+	if err != nil {
+		return fmt.Errorf("failed to read certificate: %v", err)
+	}
+	privateKeyPEM, err := readFirstFile(filepath.Join(mspDir, "keystore"))
+	if err != nil {
+		return fmt.Errorf("failed to read private key: %v", err)
+	}
+	return w.Put(label, mspID, certificatePEM, privateKeyPEM)
+}
+
+// Enroll enrolls the identity registered at the certificate authority of the org mspID as
+// enrollmentID and stores it under label
+func (w *Wallet) Enroll(label string, mspID string, ca *CertificateAuthority, enrollmentID string, secret string) error {
+	if err := checkLabel(label); err != nil {
+		return err
+	}
+	enrollment, err := ca.Enroll(enrollmentID, secret)
+	if err != nil {
+		return err
+	}
+	return w.Put(label, mspID, enrollment.CertificatePEM, enrollment.PrivateKeyPEM)
+}
+
+// Reenroll replaces the certificate and private key of the identity stored under label with new
+// ones issued by the certificate authority, e.g. before its certificate expires
+func (w *Wallet) Reenroll(label string, ca *CertificateAuthority) error {
+	id, err := w.Get(label)
+	if err != nil {
+		return err
+	}
+	enrollment, err := ca.Reenroll(&Enrollment{
+		CertificatePEM: []byte(id.Credentials.Certificate),
+		PrivateKeyPEM:  []byte(id.Credentials.PrivateKey),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to reenroll %s: %v", label, err)
+	}
+	return w.Put(label, id.MSPID, enrollment.CertificatePEM, enrollment.PrivateKeyPEM)
+}
+
+// Identity returns the Gateway identity of the wallet identity
+func (id *WalletIdentity) Identity() (*identity.X509Identity, error) {
+	certificate, err := identity.CertificateFromPEM([]byte(id.Credentials.Certificate))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse certificate: %v", err)
+	}
+	return identity.NewX509Identity(id.MSPID, certificate)
+}
+
+// Sign returns the signing function of the wallet identity
+func (id *WalletIdentity) Sign() (identity.Sign, error) {
+	privateKey, err := identity.PrivateKeyFromPEM([]byte(id.Credentials.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %v", err)
+	}
+	return identity.NewPrivateKeySign(privateKey)
+}
+
+// ConnectWallet connects to a peer of the profile as the wallet identity stored under label.
+// peerName selects the peer as for Connect.
+func ConnectWallet(profile *Profile, peerName string, wallet *Wallet, label string) (*Connection, error) {
+	walletID, err := wallet.Get(label)
+	if err != nil {
+		return nil, err
+	}
+	if walletID.MSPID != profile.MSPID() {
+		return nil, fmt.Errorf("identity %s is a member of %s, the connection profile is for %s", label, walletID.MSPID, profile.MSPID())
+	}
+	id, err := walletID.Identity()
+	if err != nil {
+		return nil, err
+	}
+	sign, err := walletID.Sign()
+	if err != nil {
+		return nil, err
+	}
+	return connect(profile, peerName, id, sign)
+}
+
+func (w *Wallet) path(label string) string {
+	return filepath.Join(w.dir, label+walletIDExtension)
+}
+
+// checkLabel rejects labels that would name a file outside the wallet directory
+func checkLabel(label string) error {
+	if label == "" || label == "." || label == ".." || strings.ContainsAny(label, `/\`) {
+		return fmt.Errorf("invalid identity label %q", label)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package appclient
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWallet(t *testing.T) {
+	wallet, err := OpenWallet(filepath.Join(t.TempDir(), "wallet"))
+	if err != nil {
+		t.Fatalf("failed to open wallet: %v", err)
+	}
+
+	err = wallet.Put("user2", "Org2MSP", []byte("cert2"), []byte("key2"))
+	if err != nil {
+		t.Fatalf("failed to put identity: %v", err)
+	}
+
+	mspDir := t.TempDir()
+	for dir, content := range map[string]string{"signcerts": "cert1", "keystore": "key1"} {
+		if err := os.Mkdir(filepath.Join(mspDir, dir), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(mspDir, dir, "file"), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = wallet.Import("user1", "Org1MSP", mspDir)
+	if err != nil {
+		t.Fatalf("failed to import identity: %v", err)
+	}
+
+	labels, err := wallet.List()
+	if err != nil {
+		t.Fatalf("failed to list wallet: %v", err)
+	}
+	if !reflect.DeepEqual(labels, []string{"user1", "user2"}) {
+		t.Errorf("labels are %v", labels)
+	}
+
+	id, err := wallet.Get("user1")
+	if err != nil {
+		t.Fatalf("failed to get identity: %v", err)
+	}
+	if id.MSPID != "Org1MSP" || id.Type != "X.509" || id.Credentials.Certificate != "cert1" || id.Credentials.PrivateKey != "key1" {
+		t.Errorf("identity is %+v", id)
+	}
+
+	_, err = wallet.Get("user3")
+	if err == nil || !strings.Contains(err.Error(), "no identity user3") {
+		t.Errorf("expected missing identity error, got %v", err)
+	}
+	err = wallet.Put("../user1", "Org1MSP", nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "invalid identity label") {
+		t.Errorf("expected invalid label error, got %v", err)
+	}
+}
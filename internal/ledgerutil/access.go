@@ -0,0 +1,37 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ledgerutil
+
+import (
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// CheckAccess asks the access-control chaincode deployed as accessControlName whether the
+// submitting client may perform operation, e.g. token.Mint. The access-control chaincode sees the
+// same submitting client, so roles are checked for this client and its org.
+func CheckAccess(ctx contractapi.TransactionContextInterface, accessControlName string, operation string) error {
+	args := [][]byte{[]byte("CheckAccess"), []byte(operation)}
+	response := ctx.GetStub().InvokeChaincode(accessControlName, args, "") //empty channel means the channel of this chaincode
+	if response.Status != shim.OK {
+		return Wrap(ParseError(response.Message), "failed to check access for %s", operation)
+	}
+	if string(response.Payload) != "true" {
+		return Errorf(CodeNotAuthorized, "client is not authorized to perform %s", operation)
+	}
+	return nil
+}
+
+// CheckRegistered asks the identity registry chaincode deployed as registryName for the profile of
+// id with function, GetProfile for a client ID or GetOrgProfile for an MSP ID, and fails with
+// NOT_AUTHORIZED when it has none. Contracts call it for the parties of a transaction in strict KYC mode.
+func CheckRegistered(ctx contractapi.TransactionContextInterface, registryName string, function string, id string) error {
+	args := [][]byte{[]byte(function), []byte(id)}
+	response := ctx.GetStub().InvokeChaincode(registryName, args, "")
+	if response.Status != shim.OK {
+		return Errorf(CodeNotAuthorized, "%s has no verified profile in the identity registry, required in strict KYC mode: %s", id, ParseError(response.Message).Message)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ledgerutil
+
+import "strconv"
+
+// MaxAmount is the largest amount a balance, allowance or supply can hold
+const MaxAmount = int(^uint(0) >> 1)
+
+// ParseAmount parses an amount stored in the world state, which is always written with
+// strconv.Itoa. Anything else means the state is corrupt and must not be read as 0.
+func ParseAmount(value []byte) (int, error) {
+	amount, err := strconv.Atoi(string(value))
+	if err != nil || amount < 0 || strconv.Itoa(amount) != string(value) {
+		return 0, Errorf(CodeCorruptState, "stored amount %q is not a non-negative integer", value)
+	}
+	return amount, nil
+}
+
+// FormatAmount returns the world state value of an amount
+func FormatAmount(amount int) []byte {
+	return []byte(strconv.Itoa(amount))
+}
+
+// AddAmounts returns a + b, or an error if the sum does not fit in an int
+func AddAmounts(a int, b int) (int, error) {
+	if a < 0 || b < 0 {
+		return 0, Errorf(CodeInvalidArgument, "amounts cannot be negative")
+	}
+	if a > MaxAmount-b {
+		return 0, Errorf(CodeAmountOverflow, "adding %d to %d overflows", b, a)
+	}
+	return a + b, nil
+}
+
+// SubAmounts returns a - b, or an error if the result would be negative
+func SubAmounts(a int, b int) (int, error) {
+	if a < 0 || b < 0 {
+		return 0, Errorf(CodeInvalidArgument, "amounts cannot be negative")
+	}
+	if a < b {
+		return 0, Errorf(CodeInsufficientFunds, "insufficient funds: %d is less than %d", a, b)
+	}
+	return a - b, nil
+}
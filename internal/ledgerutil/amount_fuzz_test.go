@@ -0,0 +1,36 @@
+package ledgerutil
+
+import "testing"
+
+func FuzzParseAmount(f *testing.F) {
+	for _, seed := range []string{"0", "42", "-1", "", " 1", "+1", "007", "1e3", "9223372036854775807", "9223372036854775808", "abc"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, value string) {
+		amount, err := ParseAmount([]byte(value))
+		if err != nil {
+			return
+		}
+		if amount < 0 {
+			t.Fatalf("parsed %q as negative amount %d", value, amount)
+		}
+		if string(FormatAmount(amount)) != value {
+			t.Fatalf("parsed %q as %d", value, amount)
+		}
+	})
+}
+
+func FuzzAddAmounts(f *testing.F) {
+	f.Add(1, 2)
+	f.Add(MaxAmount, 1)
+	f.Add(-1, 5)
+	f.Fuzz(func(t *testing.T, a int, b int) {
+		sum, err := AddAmounts(a, b)
+		if err != nil {
+			return
+		}
+		if sum < a || sum < b || sum-a != b {
+			t.Fatalf("%d + %d returned %d", a, b, sum)
+		}
+	})
+}
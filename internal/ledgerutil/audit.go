@@ -0,0 +1,134 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ledgerutil
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+)
+
+// AuditConfigKey is the world state key of the AuditConfig of a chaincode
+const AuditConfigKey = "auditConfig"
+
+// AuditRecordPrefix is the object type of the audit records kept in the world state
+const AuditRecordPrefix = "auditrecord"
+
+// AuditConfig configures the audit records of a chaincode. Audit records are always added to the
+// event of a transaction; OnLedger also keeps them in the world state so they can be queried later.
+type AuditConfig struct {
+	OnLedger bool `json:"onLedger"`
+}
+
+// AuditChange records one value changed by a transaction. Subject holds the keys of the value, e.g.
+// the account of a balance or the owner and spender of an allowance.
+type AuditChange struct {
+	Kind    string   `json:"kind"`
+	Subject []string `json:"subject"`
+	Old     int      `json:"old"`
+	New     int      `json:"new"`
+}
+
+// AuditRecord lists the values changed by a transaction. It is added to the event of the
+// transaction under "audit", and written to the world state when OnLedger is set.
+type AuditRecord struct {
+	TxID      string        `json:"txID"`
+	Timestamp time.Time     `json:"timestamp"`
+	Event     string        `json:"event"`
+	Changes   []AuditChange `json:"changes"`
+	// TraceID is the OpenTelemetry trace ID the client passed with the transaction, if any
+	TraceID string `json:"traceID,omitempty" metadata:",optional"`
+}
+
+// Auditor collects the changes made by a transaction and publishes them with its event, so that
+// clients see them instead of them being written to the peer log
+type Auditor struct {
+	stub    shim.ChaincodeStubInterface
+	changes []AuditChange
+}
+
+// NewAuditor returns an Auditor for the transaction of stub
+func NewAuditor(stub shim.ChaincodeStubInterface) *Auditor {
+	return &Auditor{stub: stub, changes: []AuditChange{}}
+}
+
+// Change records that the kind value of subject changed from old to new
+func (a *Auditor) Change(kind string, old int, new int, subject ...string) {
+	a.changes = append(a.changes, AuditChange{Kind: kind, Subject: subject, Old: old, New: new})
+}
+
+// Emit sets the event of the transaction to the JSON object payload with the audit record of the
+// transaction added under "audit", and writes the record to the world state when OnLedger is set.
+// The fields of payload are kept as they are, so existing listeners are not affected.
+func (a *Auditor) Emit(schema EventSchema, payload interface{}) error {
+	txID, timestamp, err := TxInfo(a.stub)
+	if err != nil {
+		return err
+	}
+	record := AuditRecord{TxID: txID, Timestamp: timestamp, Event: schema.Name, Changes: a.changes, TraceID: TraceID(a.stub)}
+
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return Wrap(err, "failed to obtain JSON encoding of %s event", schema.Name)
+	}
+	fields := make(map[string]json.RawMessage)
+	err = json.Unmarshal(payloadJSON, &fields)
+	if err != nil {
+		return Wrap(err, "payload of %s event is not a JSON object", schema.Name)
+	}
+	fields["audit"], err = json.Marshal(record)
+	if err != nil {
+		return Wrap(err, "failed to obtain JSON encoding of audit record")
+	}
+	err = EmitEvent(a.stub, schema, fields)
+	if err != nil {
+		return err
+	}
+
+	config, err := GetAuditConfig(a.stub)
+	if err != nil {
+		return err
+	}
+	if !config.OnLedger {
+		return nil
+	}
+	recordKey, err := a.stub.CreateCompositeKey(AuditRecordPrefix, []string{txID})
+	if err != nil {
+		return Wrap(err, "failed to create audit record key")
+	}
+	return PutJSON(a.stub, recordKey, record)
+}
+
+// GetAuditConfig reads the audit configuration of the chaincode. Audit records are kept off the
+// ledger until PutAuditConfig is called.
+func GetAuditConfig(stub shim.ChaincodeStubInterface) (AuditConfig, error) {
+	var config AuditConfig
+	_, err := ReadJSON(stub, AuditConfigKey, &config)
+	return config, err
+}
+
+// PutAuditConfig writes the audit configuration of the chaincode. Callers check that the client may
+// change it.
+func PutAuditConfig(stub shim.ChaincodeStubInterface, config AuditConfig) error {
+	return PutJSON(stub, AuditConfigKey, config)
+}
+
+// GetAuditRecord reads the audit record written by the transaction txID when OnLedger was set
+func GetAuditRecord(stub shim.ChaincodeStubInterface, txID string) (*AuditRecord, error) {
+	recordKey, err := stub.CreateCompositeKey(AuditRecordPrefix, []string{txID})
+	if err != nil {
+		return nil, Wrap(err, "failed to create audit record key")
+	}
+	var record AuditRecord
+	found, err := ReadJSON(stub, recordKey, &record)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, Errorf(CodeNotFound, "no audit record for transaction %s", txID)
+	}
+	return &record, nil
+}
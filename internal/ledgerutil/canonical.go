@@ -0,0 +1,141 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ledgerutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MarshalCanonical returns the canonical JSON encoding of value: compact, with the keys of every
+// object sorted and every number in one format. Peers endorsing a transaction must write the same
+// bytes, so values written to the ledger or set as events are encoded with it rather than with
+// json.Marshal alone, whose output also depends on MarshalJSON methods that may not sort keys or
+// format numbers the same way on every build.
+func MarshalCanonical(value interface{}) ([]byte, error) {
+	valueJSON, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return Canonicalize(valueJSON)
+}
+
+// Canonicalize rewrites a JSON document in the canonical encoding of MarshalCanonical
+func Canonicalize(valueJSON []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(valueJSON))
+	decoder.UseNumber()
+	var tree interface{}
+	err := decoder.Decode(&tree)
+	if err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("unexpected data after the JSON value")
+	}
+
+	var buffer bytes.Buffer
+	err = writeCanonical(&buffer, tree)
+	if err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// writeCanonical writes a value decoded with UseNumber
+func writeCanonical(buffer *bytes.Buffer, value interface{}) error {
+	switch value := value.(type) {
+	case nil:
+		buffer.WriteString("null")
+	case bool:
+		buffer.WriteString(strconv.FormatBool(value))
+	case json.Number:
+		number, err := canonicalNumber(value)
+		if err != nil {
+			return err
+		}
+		buffer.WriteString(number)
+	case string:
+		return writeString(buffer, value)
+	case []interface{}:
+		buffer.WriteByte('[')
+		for i, element := range value {
+			if i > 0 {
+				buffer.WriteByte(',')
+			}
+			err := writeCanonical(buffer, element)
+			if err != nil {
+				return err
+			}
+		}
+		buffer.WriteByte(']')
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		buffer.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				buffer.WriteByte(',')
+			}
+			err := writeString(buffer, key)
+			if err != nil {
+				return err
+			}
+			buffer.WriteByte(':')
+			err = writeCanonical(buffer, value[key])
+			if err != nil {
+				return err
+			}
+		}
+		buffer.WriteByte('}')
+	default:
+		return fmt.Errorf("unexpected JSON value of type %T", value)
+	}
+	return nil
+}
+
+// writeString writes a string escaped as encoding/json does
+func writeString(buffer *bytes.Buffer, value string) error {
+	valueJSON, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	buffer.Write(valueJSON)
+	return nil
+}
+
+// canonicalNumber formats integers in decimal without sign for zero, and other numbers as the
+// shortest decimal that reads back as the same float64, without exponent when it is an integer
+// below 1e21. 2, 2.0 and 2e0 are thus all "2".
+func canonicalNumber(number json.Number) (string, error) {
+	literal := number.String()
+	if !strings.ContainsAny(literal, ".eE") {
+		integer, ok := new(big.Int).SetString(literal, 10)
+		if !ok {
+			return "", fmt.Errorf("invalid number %s", literal)
+		}
+		return integer.String(), nil
+	}
+
+	float, err := strconv.ParseFloat(literal, 64)
+	if err != nil {
+		return "", fmt.Errorf("number %s does not fit in a float64", literal)
+	}
+	if float == 0 {
+		return "0", nil
+	}
+	if float == math.Trunc(float) && math.Abs(float) < 1e21 {
+		return strconv.FormatFloat(float, 'f', -1, 64), nil
+	}
+	return strconv.FormatFloat(float, 'g', -1, 64), nil
+}
@@ -0,0 +1,53 @@
+package ledgerutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func FuzzCanonicalize(f *testing.F) {
+	for _, seed := range []string{`{"b":1,"a":[2.0,-0.0,1e3,"x"]}`, `{"a":{"d":null,"c":true}}`, `12345678901234567890123`, `1.5e300`, `"<&>"`, `[]`, `{"a":1}{"b":2}`, `1e999`} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, document string) {
+		canonical, err := Canonicalize([]byte(document))
+		if err != nil {
+			return
+		}
+		if !json.Valid(canonical) {
+			t.Fatalf("canonical form of %q is not JSON: %q", document, canonical)
+		}
+		again, err := Canonicalize(canonical)
+		if err != nil {
+			t.Fatalf("failed to canonicalize canonical form %q: %v", canonical, err)
+		}
+		if !bytes.Equal(again, canonical) {
+			t.Fatalf("canonical form of %q changed from %q to %q", document, canonical, again)
+		}
+	})
+}
+
+func TestMarshalCanonical(t *testing.T) {
+	type record struct {
+		Zebra  string                 `json:"zebra"`
+		Amount float64                `json:"amount"`
+		Fields map[string]interface{} `json:"fields"`
+		Raw    json.RawMessage        `json:"raw"`
+	}
+	value := record{
+		Zebra:  "z",
+		Amount: 2,
+		Fields: map[string]interface{}{"b": -0.0, "a": 1e21},
+		Raw:    json.RawMessage(`{ "y" : 2.50, "x" : 1E2 }`),
+	}
+
+	canonical, err := MarshalCanonical(value)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `{"amount":2,"fields":{"a":1e+21,"b":0},"raw":{"x":100,"y":2.5},"zebra":"z"}`
+	if string(canonical) != want {
+		t.Errorf("canonical JSON is %s, want %s", canonical, want)
+	}
+}
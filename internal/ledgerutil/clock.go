@@ -0,0 +1,83 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ledgerutil
+
+import (
+	"os"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// envMaxClockSkew is the largest difference, as a Go duration such as 5m, allowed between the
+// timestamp of a transaction and the clock of the endorsing peer. Unset or 0 turns the check off.
+const envMaxClockSkew = "CHAINCODE_MAX_CLOCK_SKEW"
+
+// TxTime returns the timestamp of the transaction, the time every expiry, deadline and TTL check of
+// a chaincode is made at. The client sets it in the proposal, so every endorsing peer reads the
+// same time and endorses the same result, which a check against each peer's own clock would not.
+//
+// A client can set any timestamp, e.g. one before an expiry it wants to escape. With
+// CHAINCODE_MAX_CLOCK_SKEW set on the chaincode, a transaction whose timestamp differs from the
+// endorsing peer's clock by more than that is rejected with INVALID_ARGUMENT. The peer's clock only
+// decides whether to endorse; the time the chaincode works with is always the transaction's.
+func TxTime(ctx contractapi.TransactionContextInterface) (time.Time, error) {
+	return txTime(ctx.GetStub())
+}
+
+// txTime is TxTime for the functions of the package taking a stub
+func txTime(stub shim.ChaincodeStubInterface) (time.Time, error) {
+	timestamp, err := stub.GetTxTimestamp()
+	if err != nil {
+		return time.Time{}, Wrap(err, "failed to get transaction timestamp")
+	}
+	txTime := time.Unix(timestamp.Seconds, int64(timestamp.Nanos)).UTC()
+
+	maxSkew, err := maxClockSkew()
+	if err != nil {
+		return time.Time{}, err
+	}
+	if maxSkew > 0 {
+		skew := time.Since(txTime)
+		if skew < 0 {
+			skew = -skew
+		}
+		if skew > maxSkew {
+			return time.Time{}, Errorf(CodeInvalidArgument, "transaction timestamp %s differs from the peer's clock by more than %s",
+				txTime.Format(time.RFC3339), maxSkew)
+		}
+	}
+	return txTime, nil
+}
+
+// maxClockSkew reads CHAINCODE_MAX_CLOCK_SKEW, 0 when it is unset
+func maxClockSkew() (time.Duration, error) {
+	value := os.Getenv(envMaxClockSkew)
+	if value == "" {
+		return 0, nil
+	}
+	skew, err := time.ParseDuration(value)
+	if err != nil || skew < 0 {
+		return 0, Errorf(CodeInternal, "%s must be a non-negative duration such as 5m, not %q", envMaxClockSkew, value)
+	}
+	return skew, nil
+}
+
+// FormatTime formats a time as JSON encodes a time.Time. Optional times of records and results are
+// such strings, empty when unset: contractapi builds no valid metadata schema for *time.Time, so a
+// contract with one in its functions' types does not start.
+func FormatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// ParseTime parses a time formatted by FormatTime
+func ParseTime(value string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, Wrap(err, "failed to parse time %q", value)
+	}
+	return t, nil
+}
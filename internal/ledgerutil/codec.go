@@ -0,0 +1,142 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ledgerutil
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+)
+
+// The state codecs records can be stored with
+const (
+	// CodecJSON stores records as canonical JSON, which CouchDB rich queries and off-chain tools
+	// reading the write sets of blocks can read
+	CodecJSON = "json"
+	// CodecProtobuf stores records in the protobuf encoding of their .proto schema, which is smaller
+	// and faster to encode and decode
+	CodecProtobuf = "protobuf"
+)
+
+// StateCodecParam is the codec a chaincode stores its records with. It is json until Initialize
+// selects it and cannot be changed afterwards, so it is not set with SetParameter.
+var StateCodecParam = ConfigParam{Name: "stateCodec", Kind: ConfigString, Default: `"` + CodecJSON + `"`,
+	Description: "encoding of the records with a protobuf schema, json or protobuf, selected once with Initialize"}
+
+// ProtoRecord is a record with a protobuf schema, which is stored in protobuf rather than JSON when
+// the state codec of the chaincode is protobuf. MarshalProto writes the fields in field number order,
+// so every endorsing peer encodes a record to the same bytes.
+type ProtoRecord interface {
+	MarshalProto() ([]byte, error)
+	UnmarshalProto(data []byte) error
+}
+
+// GetStateCodec reads the codec the chaincode stores its records with
+func GetStateCodec(stub shim.ChaincodeStubInterface) (string, error) {
+	return GetConfigString(stub, StateCodecParam)
+}
+
+// InitializeStateCodec selects the codec the chaincode stores its records with, once. Records
+// written before, in JSON, stay readable, since ReadRecord reads both encodings. Callers check that
+// the client may initialize the chaincode.
+func InitializeStateCodec(ctx TransactionContextInterface, codec string) (*ConfigParameter, error) {
+	v := NewValidator()
+	v.OneOf("stateCodec", codec, []string{CodecJSON, CodecProtobuf})
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	var current string
+	found, err := readConfigValue(ctx.GetStub(), StateCodecParam, &current)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		return nil, Errorf(CodeInvalidArgument, "invalid arguments: the state codec is already %s and cannot be changed", current)
+	}
+	parameters, err := PutConfig(ctx, ConfigValue{Param: StateCodecParam, Value: `"` + codec + `"`})
+	if err != nil {
+		return nil, err
+	}
+	return parameters[0], nil
+}
+
+// MarshalRecord encodes a record with the state codec of the chaincode
+func MarshalRecord(stub shim.ChaincodeStubInterface, record ProtoRecord) ([]byte, error) {
+	codec, err := GetStateCodec(stub)
+	if err != nil {
+		return nil, err
+	}
+	if codec != CodecProtobuf {
+		return MarshalCanonical(record)
+	}
+	data, err := record.MarshalProto()
+	if err != nil {
+		return nil, err
+	}
+	// an empty value would delete the key
+	if len(data) == 0 {
+		return nil, fmt.Errorf("record %T has no fields set", record)
+	}
+	return data, nil
+}
+
+// UnmarshalRecord decodes a record read from the ledger, e.g. by an iterator, in either encoding.
+// A JSON record starts with {, which cannot start a protobuf record as it is the tag of a group, a
+// wire type proto3 schemas do not have.
+func UnmarshalRecord(key string, data []byte, record ProtoRecord) error {
+	if len(data) > 0 && data[0] == '{' {
+		_, err := unmarshalValue(key, data, record)
+		return err
+	}
+	err := record.UnmarshalProto(data)
+	if err != nil {
+		return Errorf(CodeCorruptState, "failed to unmarshal %s: %v", key, err)
+	}
+	return nil
+}
+
+// PutRecord writes a record to key with the state codec of the chaincode
+func PutRecord(stub shim.ChaincodeStubInterface, key string, record ProtoRecord) error {
+	data, err := MarshalRecord(stub, record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %v", key, err)
+	}
+	err = stub.PutState(key, data)
+	if err != nil {
+		return fmt.Errorf("failed to put %s: %v", key, err)
+	}
+	return nil
+}
+
+// ReadRecord reads the record of key, in either encoding. It returns false when the key does not
+// exist.
+func ReadRecord(stub shim.ChaincodeStubInterface, key string, record ProtoRecord) (bool, error) {
+	data, err := stub.GetState(key)
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s from world state: %v", key, err)
+	}
+	if data == nil {
+		return false, nil
+	}
+	err = UnmarshalRecord(key, data, record)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// PutPrivateRecord writes a record to key of a private data collection with the state codec of the
+// chaincode
+func PutPrivateRecord(stub shim.ChaincodeStubInterface, collection string, key string, record ProtoRecord) error {
+	data, err := MarshalRecord(stub, record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %v", key, err)
+	}
+	err = stub.PutPrivateData(collection, key, data)
+	if err != nil {
+		return fmt.Errorf("failed to put %s in collection %s: %v", key, collection, err)
+	}
+	return nil
+}
@@ -0,0 +1,496 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ledgerutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-contract-api-go/metadata"
+)
+
+// ConfigPrefix is the object type of the configuration parameters kept in the world state
+const ConfigPrefix = "config"
+
+// EventConfigChanged is the event set by a transaction changing configuration parameters
+const EventConfigChanged = "ConfigChanged"
+
+// ConfigKind is the type of the JSON value of a configuration parameter
+type ConfigKind string
+
+const (
+	// ConfigInt parameters are integers of at least 0, e.g. 500
+	ConfigInt ConfigKind = "int"
+	// ConfigString parameters are JSON strings, e.g. "Org1MSP"
+	ConfigString ConfigKind = "string"
+	// ConfigStrings parameters are JSON arrays of non-empty strings, e.g. ["Org1MSP","Org2MSP"]
+	ConfigStrings ConfigKind = "strings"
+	// ConfigBool parameters are true or false
+	ConfigBool ConfigKind = "bool"
+)
+
+// ConfigParam declares a configuration parameter a chaincode reads: its name, the kind of its value
+// and the JSON value it has until it is set, the zero value of its kind when Default is empty
+type ConfigParam struct {
+	Name        string
+	Kind        ConfigKind
+	Default     string
+	Description string
+}
+
+// The query limits of QueryConfig are configuration parameters of every chaincode using the package
+var (
+	MaxPageSizeParam = ConfigParam{Name: "maxPageSize", Kind: ConfigInt, Default: strconv.Itoa(DefaultMaxPageSize),
+		Description: "largest page size a paginated query accepts, at most maxResults"}
+	MaxResultsParam = ConfigParam{Name: "maxResults", Kind: ConfigInt, Default: strconv.Itoa(DefaultMaxResults),
+		Description: "most results a query without pagination returns, at most " + strconv.Itoa(MaxResultsLimit)}
+)
+
+// ConfigParameter is the value of a configuration parameter with the client and transaction that
+// set it. A parameter that was never set has its default value and no transaction.
+type ConfigParameter struct {
+	Name        string    `json:"name"`
+	Kind        string    `json:"kind"`
+	Value       string    `json:"value"`
+	Description string    `json:"description,omitempty" metadata:",optional"`
+	UpdatedBy   string    `json:"updatedBy,omitempty" metadata:",optional"`
+	TxID        string    `json:"txID,omitempty" metadata:",optional"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// ConfigValue is a new JSON value of a parameter, passed to PutConfig
+type ConfigValue struct {
+	Param ConfigParam
+	Value string
+}
+
+// ConfigChange is one parameter changed by a transaction, as listed by its ConfigChanged event
+type ConfigChange struct {
+	Name string `json:"name"`
+	Old  string `json:"old"`
+	New  string `json:"new"`
+}
+
+// ConfigChangedEvent is the payload of the ConfigChanged event
+type ConfigChangedEvent struct {
+	Changes   []ConfigChange `json:"changes"`
+	UpdatedBy string         `json:"updatedBy"`
+}
+
+// GetConfigParameter reads the value of param, its default until it is set
+func GetConfigParameter(stub shim.ChaincodeStubInterface, param ConfigParam) (*ConfigParameter, error) {
+	parameter, _, err := readConfigParameter(stub, param)
+	return parameter, err
+}
+
+// GetConfigInt reads the value of an int parameter
+func GetConfigInt(stub shim.ChaincodeStubInterface, param ConfigParam) (int, error) {
+	var value int
+	err := getConfigValue(stub, param, ConfigInt, &value)
+	return value, err
+}
+
+// GetConfigString reads the value of a string parameter
+func GetConfigString(stub shim.ChaincodeStubInterface, param ConfigParam) (string, error) {
+	var value string
+	err := getConfigValue(stub, param, ConfigString, &value)
+	return value, err
+}
+
+// GetConfigStrings reads the value of a strings parameter, nil when it is empty
+func GetConfigStrings(stub shim.ChaincodeStubInterface, param ConfigParam) ([]string, error) {
+	var value []string
+	err := getConfigValue(stub, param, ConfigStrings, &value)
+	return value, err
+}
+
+// GetConfigBool reads the value of a bool parameter
+func GetConfigBool(stub shim.ChaincodeStubInterface, param ConfigParam) (bool, error) {
+	var value bool
+	err := getConfigValue(stub, param, ConfigBool, &value)
+	return value, err
+}
+
+// PutConfig writes new values of parameters and sets a ConfigChanged event listing the old and new
+// values. Each value must be JSON of the kind of its parameter and is stored canonical, with the
+// client and transaction setting it. Callers check that the client may change the parameters.
+func PutConfig(ctx TransactionContextInterface, values ...ConfigValue) ([]*ConfigParameter, error) {
+	stub := ctx.GetStub()
+	txID, timestamp, err := TxInfo(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	event := ConfigChangedEvent{Changes: []ConfigChange{}, UpdatedBy: ctx.GetClientID()}
+	var parameters []*ConfigParameter
+	for _, value := range values {
+		canonical, err := checkConfigValue(value.Param, value.Value)
+		if err != nil {
+			return nil, err
+		}
+		current, err := GetConfigParameter(stub, value.Param)
+		if err != nil {
+			return nil, err
+		}
+		parameter := &ConfigParameter{
+			Name:      value.Param.Name,
+			Kind:      string(value.Param.Kind),
+			Value:     canonical,
+			UpdatedBy: ctx.GetClientID(),
+			TxID:      txID,
+			Timestamp: timestamp,
+		}
+		key, err := configKey(stub, value.Param.Name)
+		if err != nil {
+			return nil, err
+		}
+		err = PutJSON(stub, key, parameter)
+		if err != nil {
+			return nil, err
+		}
+		parameters = append(parameters, parameter)
+		event.Changes = append(event.Changes, ConfigChange{Name: parameter.Name, Old: current.Value, New: canonical})
+	}
+
+	err = EmitEvent(stub, ConfigChangedSchema, event)
+	if err != nil {
+		return nil, err
+	}
+	return parameters, nil
+}
+
+// ConfigContract is the contract named "config" holding the configuration parameters of a
+// chaincode, so administrators tune fees, limits and the like with a transaction instead of a
+// chaincode upgrade. Chaincodes register it next to their own contracts.
+type ConfigContract struct {
+	contractapi.Contract
+	accessControlName string
+	params            map[string]ConfigParam
+}
+
+// NewConfigContract returns the config contract for the parameters a chaincode reads, besides
+// MaxPageSizeParam, MaxResultsParam and StateCodecParam. Setting a parameter needs
+// config.SetParameter in the access-control chaincode deployed as accessControlName.
+func NewConfigContract(accessControlName string, params ...ConfigParam) *ConfigContract {
+	configContract := &ConfigContract{accessControlName: accessControlName, params: make(map[string]ConfigParam)}
+	for _, param := range append([]ConfigParam{MaxPageSizeParam, MaxResultsParam, StateCodecParam}, params...) {
+		configContract.params[param.Name] = param
+	}
+	configContract.Name = "config"
+	configContract.Info = metadata.InfoMetadata{
+		Title:       "Configuration",
+		Description: "Administrator-tunable parameters of the chaincode with their history",
+		Version:     "1.0.0",
+		License:     &metadata.LicenseMetadata{Name: "Apache-2.0"},
+	}
+	configContract.TransactionContextHandler = new(TransactionContext)
+	configContract.BeforeTransaction = BeforeTransaction(configContract.GetEvaluateTransactions())
+	configContract.UnknownTransaction = UnknownTransaction(configContract)
+	return configContract
+}
+
+// GetEvaluateTransactions lists the read-only functions of the config contract
+func (c *ConfigContract) GetEvaluateTransactions() []string {
+	return []string{"GetParameter", "GetInt", "GetString", "GetStrings", "GetBool", "ListParameters", "GetParameterHistory", "GetFlags"}
+}
+
+// SetParameter sets the parameter name to the JSON value, e.g. 500, "Org1MSP" or ["Org1MSP"], and
+// returns it. Only clients allowed config.SetParameter in the access-control chaincode may set
+// parameters. maxPageSize and maxResults keep 1 <= maxPageSize <= maxResults as SetQueryConfig does.
+func (c *ConfigContract) SetParameter(ctx TransactionContextInterface, name string, value string) (*ConfigParameter, error) {
+	param, err := c.param(name)
+	if err != nil {
+		return nil, err
+	}
+	v := NewValidator()
+	v.Payload("value", []byte(value))
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	if name == StateCodecParam.Name {
+		return nil, Errorf(CodeInvalidArgument, "invalid arguments: %s is selected once with Initialize", name)
+	}
+	err = CheckAccess(ctx, c.accessControlName, "config.SetParameter")
+	if err != nil {
+		return nil, err
+	}
+
+	if name == MaxPageSizeParam.Name || name == MaxResultsParam.Name {
+		limit, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, Errorf(CodeInvalidArgument, "invalid arguments: value of %s must be an int", name)
+		}
+		config, err := GetQueryConfig(ctx.GetStub())
+		if err != nil {
+			return nil, err
+		}
+		if name == MaxPageSizeParam.Name {
+			config.MaxPageSize = limit
+		} else {
+			config.MaxResults = limit
+		}
+		err = PutQueryConfig(ctx, config)
+		if err != nil {
+			return nil, err
+		}
+		return GetConfigParameter(ctx.GetStub(), param)
+	}
+
+	parameters, err := PutConfig(ctx, ConfigValue{Param: param, Value: value})
+	if err != nil {
+		return nil, err
+	}
+	return parameters[0], nil
+}
+
+// Initialize selects the codec the chaincode stores its records with, json or protobuf, and returns
+// the stateCodec parameter. It succeeds once, for clients allowed config.Initialize in the
+// access-control chaincode, and is best called right after the chaincode is deployed.
+func (c *ConfigContract) Initialize(ctx TransactionContextInterface, stateCodec string) (*ConfigParameter, error) {
+	err := CheckAccess(ctx, c.accessControlName, "config.Initialize")
+	if err != nil {
+		return nil, err
+	}
+	return InitializeStateCodec(ctx, stateCodec)
+}
+
+// GetParameter returns the parameter name, with its default value until it is set
+func (c *ConfigContract) GetParameter(ctx TransactionContextInterface, name string) (*ConfigParameter, error) {
+	param, err := c.param(name)
+	if err != nil {
+		return nil, err
+	}
+	parameter, err := GetConfigParameter(ctx.GetStub(), param)
+	if err != nil {
+		return nil, err
+	}
+	parameter.Description = param.Description
+	return parameter, nil
+}
+
+// GetInt returns the value of the int parameter name
+func (c *ConfigContract) GetInt(ctx TransactionContextInterface, name string) (int, error) {
+	param, err := c.param(name)
+	if err != nil {
+		return 0, err
+	}
+	return GetConfigInt(ctx.GetStub(), param)
+}
+
+// GetString returns the value of the string parameter name
+func (c *ConfigContract) GetString(ctx TransactionContextInterface, name string) (string, error) {
+	param, err := c.param(name)
+	if err != nil {
+		return "", err
+	}
+	return GetConfigString(ctx.GetStub(), param)
+}
+
+// GetStrings returns the value of the strings parameter name
+func (c *ConfigContract) GetStrings(ctx TransactionContextInterface, name string) ([]string, error) {
+	param, err := c.param(name)
+	if err != nil {
+		return nil, err
+	}
+	return GetConfigStrings(ctx.GetStub(), param)
+}
+
+// GetBool returns the value of the bool parameter name
+func (c *ConfigContract) GetBool(ctx TransactionContextInterface, name string) (bool, error) {
+	param, err := c.param(name)
+	if err != nil {
+		return false, err
+	}
+	return GetConfigBool(ctx.GetStub(), param)
+}
+
+// ListParameters returns every parameter of the chaincode sorted by name, with its description
+func (c *ConfigContract) ListParameters(ctx TransactionContextInterface) ([]*ConfigParameter, error) {
+	var names []string
+	for name := range c.params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parameters := []*ConfigParameter{}
+	for _, name := range names {
+		parameter, err := c.GetParameter(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		parameters = append(parameters, parameter)
+	}
+	return parameters, nil
+}
+
+// GetParameterHistory returns the values the parameter name was set to, the latest first. It fails
+// with RESULTS_TRUNCATED when it was set more than maxResults times.
+func (c *ConfigContract) GetParameterHistory(ctx TransactionContextInterface, name string) ([]ConfigParameter, error) {
+	param, err := c.param(name)
+	if err != nil {
+		return nil, err
+	}
+	stub := ctx.GetStub()
+	config, err := GetQueryConfig(stub)
+	if err != nil {
+		return nil, err
+	}
+	key, err := configKey(stub, param.Name)
+	if err != nil {
+		return nil, err
+	}
+	iterator, err := stub.GetHistoryForKey(key)
+	if err != nil {
+		return nil, Wrap(err, "failed to get history of parameter %s", name)
+	}
+	defer iterator.Close()
+
+	history := []ConfigParameter{}
+	for iterator.HasNext() {
+		modification, err := iterator.Next()
+		if err != nil {
+			return nil, Wrap(err, "failed to iterate history of parameter %s", name)
+		}
+		if modification.IsDelete {
+			continue
+		}
+		if len(history) == config.MaxResults {
+			return nil, Errorf(CodeResultsTruncated, "results truncated at %d values of parameter %s", config.MaxResults, name)
+		}
+		var parameter ConfigParameter
+		_, err = unmarshalValue(key, modification.Value, &parameter)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, parameter)
+	}
+	return history, nil
+}
+
+// param looks up a parameter of the chaincode by name
+func (c *ConfigContract) param(name string) (ConfigParam, error) {
+	param, ok := c.params[name]
+	if !ok {
+		var names []string
+		for known := range c.params {
+			names = append(names, known)
+		}
+		sort.Strings(names)
+		return ConfigParam{}, Errorf(CodeInvalidArgument, "invalid arguments: %q is not a parameter, the parameters are %s", name, strings.Join(names, ", "))
+	}
+	return param, nil
+}
+
+// configKey returns the world state key of the parameter name
+func configKey(stub shim.ChaincodeStubInterface, name string) (string, error) {
+	key, err := stub.CreateCompositeKey(ConfigPrefix, []string{name})
+	if err != nil {
+		return "", Wrap(err, "failed to create key of parameter %s", name)
+	}
+	return key, nil
+}
+
+// readConfigParameter reads the value of param, returning its default and false when it was
+// never set
+func readConfigParameter(stub shim.ChaincodeStubInterface, param ConfigParam) (*ConfigParameter, bool, error) {
+	key, err := configKey(stub, param.Name)
+	if err != nil {
+		return nil, false, err
+	}
+	var parameter ConfigParameter
+	found, err := ReadJSON(stub, key, &parameter)
+	if err != nil {
+		return nil, false, err
+	}
+	if !found {
+		return &ConfigParameter{Name: param.Name, Kind: string(param.Kind), Value: param.Default}, false, nil
+	}
+	return &parameter, true, nil
+}
+
+// readConfigValue reads the value of a parameter that was set into value, returning false when
+// it was never set
+func readConfigValue(stub shim.ChaincodeStubInterface, param ConfigParam, value interface{}) (bool, error) {
+	parameter, found, err := readConfigParameter(stub, param)
+	if err != nil || !found {
+		return false, err
+	}
+	err = json.Unmarshal([]byte(parameter.Value), value)
+	if err != nil {
+		return false, Errorf(CodeCorruptState, "failed to read parameter %s: %v", param.Name, err)
+	}
+	return true, nil
+}
+
+// getConfigValue reads the value of a parameter of the kind, or its default, into value
+func getConfigValue(stub shim.ChaincodeStubInterface, param ConfigParam, kind ConfigKind, value interface{}) error {
+	if param.Kind != kind {
+		return Errorf(CodeInvalidArgument, "invalid arguments: parameter %s is of kind %s, not %s", param.Name, param.Kind, kind)
+	}
+	parameter, err := GetConfigParameter(stub, param)
+	if err != nil {
+		return err
+	}
+	if parameter.Value == "" {
+		return nil
+	}
+	err = json.Unmarshal([]byte(parameter.Value), value)
+	if err != nil {
+		return Errorf(CodeCorruptState, "failed to read parameter %s: %v", param.Name, err)
+	}
+	return nil
+}
+
+// checkConfigValue checks a value is JSON of the kind of param and returns its canonical encoding
+func checkConfigValue(param ConfigParam, value string) (string, error) {
+	decoder := json.NewDecoder(bytes.NewReader([]byte(value)))
+	var decoded interface{}
+	var err error
+	switch param.Kind {
+	case ConfigInt:
+		var number int
+		err = decoder.Decode(&number)
+		if err == nil && number < 0 {
+			return "", Errorf(CodeInvalidArgument, "invalid arguments: value of %s must not be negative", param.Name)
+		}
+		decoded = number
+	case ConfigString:
+		var text string
+		err = decoder.Decode(&text)
+		decoded = text
+	case ConfigStrings:
+		var texts []string
+		err = decoder.Decode(&texts)
+		for _, text := range texts {
+			if text == "" {
+				return "", Errorf(CodeInvalidArgument, "invalid arguments: value of %s must not contain empty strings", param.Name)
+			}
+		}
+		if texts == nil {
+			texts = []string{}
+		}
+		decoded = texts
+	case ConfigBool:
+		var flag bool
+		err = decoder.Decode(&flag)
+		decoded = flag
+	default:
+		return "", Errorf(CodeInternal, "parameter %s has unknown kind %q", param.Name, param.Kind)
+	}
+	if err != nil || decoder.More() {
+		return "", Errorf(CodeInvalidArgument, "invalid arguments: value of %s must be a JSON %s", param.Name, param.Kind)
+	}
+	canonical, err := MarshalCanonical(decoded)
+	if err != nil {
+		return "", Wrap(err, "failed to encode value of %s", param.Name)
+	}
+	return string(canonical), nil
+}
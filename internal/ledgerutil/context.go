@@ -0,0 +1,128 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ledgerutil
+
+import (
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// ReadOnlyAttribute is the enrollment certificate attribute of query-only clients, e.g. auditors
+// registered with fabric-ca-client register --id.attrs 'readonly=true:ecert'
+const ReadOnlyAttribute = "readonly"
+
+// AuditPrefix is the object type of the audit entries kept in the world state
+const AuditPrefix = "audit"
+
+// TransactionContext is the transaction context of the contracts using this package. It resolves the
+// submitting client once per transaction, so functions read the client's identity from the context
+// instead of calling the client identity and handling its errors each time.
+type TransactionContext struct {
+	contractapi.TransactionContext
+	identity *Identity
+}
+
+// TransactionContextInterface is the context taken by the functions of a contract whose
+// TransactionContextHandler is a *TransactionContext
+type TransactionContextInterface interface {
+	contractapi.TransactionContextInterface
+	ResolveClient() error
+	GetIdentity() *Identity
+	GetClientID() string
+	GetClientMSPID() string
+	IsReadOnly() bool
+}
+
+// AuditEntry records who submitted which function in a transaction
+type AuditEntry struct {
+	TxID      string    `json:"txID"`
+	Function  string    `json:"function"`
+	ClientID  string    `json:"clientID"`
+	MSPID     string    `json:"mspID"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// ResolveClient decodes the certificate of the submitting client into the context
+func (ctx *TransactionContext) ResolveClient() error {
+	identity, err := DecodeIdentity(ctx.GetClientIdentity())
+	if err != nil {
+		return err
+	}
+	ctx.identity = identity
+	return nil
+}
+
+// GetIdentity returns the decoded identity of the client, which authorization checks read instead
+// of comparing client IDs
+func (ctx *TransactionContext) GetIdentity() *Identity {
+	return ctx.identity
+}
+
+// GetClientID returns the ID of the client, as returned by the client identity's GetID
+func (ctx *TransactionContext) GetClientID() string {
+	return ctx.identity.ID
+}
+
+// GetClientMSPID returns the MSP ID of the client's org
+func (ctx *TransactionContext) GetClientMSPID() string {
+	return ctx.identity.MSPID
+}
+
+// IsReadOnly reports whether the client's certificate marks it as query-only
+func (ctx *TransactionContext) IsReadOnly() bool {
+	return ctx.identity.HasAttribute(ReadOnlyAttribute, "true")
+}
+
+// BeforeTransaction returns the hook to set as the BeforeTransaction of a contract whose functions
+// take TransactionContextInterface. The hook resolves the client, rejects every function but the
+// evaluate ones for read-only clients, and records an AuditEntry for every other function.
+func BeforeTransaction(evaluate []string) func(ctx TransactionContextInterface) error {
+	queries := make(map[string]bool)
+	for _, function := range evaluate {
+		queries[function] = true
+	}
+
+	return func(ctx TransactionContextInterface) error {
+		err := ctx.ResolveClient()
+		if err != nil {
+			return err
+		}
+
+		function, _ := ctx.GetStub().GetFunctionAndParameters()
+		// the functions of a named contract can be called as contract:function
+		function = function[strings.LastIndex(function, ":")+1:]
+		if queries[function] {
+			return nil
+		}
+		if ctx.IsReadOnly() {
+			return Errorf(CodeNotAuthorized, "read-only client %s of %s cannot submit %s", ctx.GetClientID(), ctx.GetClientMSPID(), function)
+		}
+		return recordAudit(ctx, function)
+	}
+}
+
+// recordAudit writes the audit entry of the transaction under the audit composite key of its ID
+func recordAudit(ctx TransactionContextInterface, function string) error {
+	stub := ctx.GetStub()
+	txID, timestamp, err := TxInfo(stub)
+	if err != nil {
+		return err
+	}
+	auditKey, err := stub.CreateCompositeKey(AuditPrefix, []string{txID})
+	if err != nil {
+		return Wrap(err, "failed to create audit key")
+	}
+
+	entry := AuditEntry{
+		TxID:      txID,
+		Function:  function,
+		ClientID:  ctx.GetClientID(),
+		MSPID:     ctx.GetClientMSPID(),
+		Timestamp: timestamp,
+	}
+	return PutJSON(stub, auditKey, entry)
+}
@@ -0,0 +1,89 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package errcode holds the coded errors returned by the chaincodes using ledgerutil. It has no
+// chaincode dependencies, so client applications import it to read the errors without linking the
+// chaincode shim, whose protos clash with those of the Fabric Gateway client.
+package errcode
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Code is a stable, machine-readable error code. Client applications branch on the code and show
+// the message.
+type Code string
+
+const (
+	CodeInvalidArgument       Code = "INVALID_ARGUMENT"
+	CodeNotAuthorized         Code = "NOT_AUTHORIZED"
+	CodeNotFound              Code = "NOT_FOUND"
+	CodeAccountNotFound       Code = "ACCOUNT_NOT_FOUND"
+	CodeAssetNotFound         Code = "ASSET_NOT_FOUND"
+	CodeAssetExists           Code = "ASSET_EXISTS"
+	CodeInsufficientFunds     Code = "INSUFFICIENT_FUNDS"
+	CodeInsufficientAllowance Code = "INSUFFICIENT_ALLOWANCE"
+	CodeAmountOverflow        Code = "AMOUNT_OVERFLOW"
+	CodeAgreementMismatch     Code = "AGREEMENT_MISMATCH"
+	CodeCorruptState          Code = "CORRUPT_STATE"
+	CodeUnknownTransaction    Code = "UNKNOWN_TRANSACTION"
+	CodeResultsTruncated      Code = "RESULTS_TRUNCATED"
+	CodeQuotaExceeded         Code = "QUOTA_EXCEEDED"
+	// CodeInternal is the code of errors from the peer or a called chaincode, and of any error
+	// without a code
+	CodeInternal Code = "INTERNAL"
+)
+
+// Error is an error with a code. It is returned by chaincode functions as its JSON encoding, which
+// the peer passes to the client as the message of the error response.
+type Error struct {
+	Code    Code   `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	errorJSON, err := json.Marshal(e)
+	if err != nil {
+		return string(e.Code) + ": " + e.Message
+	}
+	return string(errorJSON)
+}
+
+// Errorf returns an error with the code and a formatted message
+func Errorf(code Code, format string, args ...interface{}) error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Wrap prefixes the message of err with a formatted message, keeping the code of err. An error
+// without a code becomes an INTERNAL error.
+func Wrap(err error, format string, args ...interface{}) error {
+	prefix := fmt.Sprintf(format, args...)
+	var codedErr *Error
+	if errors.As(err, &codedErr) {
+		return &Error{Code: codedErr.Code, Message: prefix + ": " + codedErr.Message}
+	}
+	return &Error{Code: CodeInternal, Message: prefix + ": " + err.Error()}
+}
+
+// CodeOf returns the code of err, or CodeInternal for an error without a code
+func CodeOf(err error) Code {
+	var codedErr *Error
+	if errors.As(err, &codedErr) {
+		return codedErr.Code
+	}
+	return CodeInternal
+}
+
+// ParseError reads the message of an error response from the peer. A message that is not a coded
+// error, e.g. from an endorsement failure, is returned as an INTERNAL error.
+func ParseError(message string) *Error {
+	var codedErr Error
+	err := json.Unmarshal([]byte(message), &codedErr)
+	if err != nil || codedErr.Code == "" {
+		return &Error{Code: CodeInternal, Message: message}
+	}
+	return &codedErr
+}
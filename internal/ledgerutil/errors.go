@@ -0,0 +1,56 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ledgerutil
+
+import "github.com/hyperledger/fabric-samples/internal/ledgerutil/errcode"
+
+// The coded errors are defined in package errcode, which client applications import; chaincodes
+// keep using them through this package.
+
+// Code is a stable, machine-readable error code, see errcode.Code
+type Code = errcode.Code
+
+// Error is an error with a code, see errcode.Error
+type Error = errcode.Error
+
+const (
+	CodeInvalidArgument       = errcode.CodeInvalidArgument
+	CodeNotAuthorized         = errcode.CodeNotAuthorized
+	CodeNotFound              = errcode.CodeNotFound
+	CodeAccountNotFound       = errcode.CodeAccountNotFound
+	CodeAssetNotFound         = errcode.CodeAssetNotFound
+	CodeAssetExists           = errcode.CodeAssetExists
+	CodeInsufficientFunds     = errcode.CodeInsufficientFunds
+	CodeInsufficientAllowance = errcode.CodeInsufficientAllowance
+	CodeAmountOverflow        = errcode.CodeAmountOverflow
+	CodeAgreementMismatch     = errcode.CodeAgreementMismatch
+	CodeCorruptState          = errcode.CodeCorruptState
+	CodeUnknownTransaction    = errcode.CodeUnknownTransaction
+	CodeResultsTruncated      = errcode.CodeResultsTruncated
+	CodeQuotaExceeded         = errcode.CodeQuotaExceeded
+	CodeInternal              = errcode.CodeInternal
+)
+
+// Errorf returns an error with the code and a formatted message
+func Errorf(code Code, format string, args ...interface{}) error {
+	return errcode.Errorf(code, format, args...)
+}
+
+// Wrap prefixes the message of err with a formatted message, keeping the code of err. An error
+// without a code becomes an INTERNAL error.
+func Wrap(err error, format string, args ...interface{}) error {
+	return errcode.Wrap(err, format, args...)
+}
+
+// CodeOf returns the code of err, or CodeInternal for an error without a code
+func CodeOf(err error) Code {
+	return errcode.CodeOf(err)
+}
+
+// ParseError reads the message of an error response from the peer. A message that is not a coded
+// error, e.g. from an endorsement failure, is returned as an INTERNAL error.
+func ParseError(message string) *Error {
+	return errcode.ParseError(message)
+}
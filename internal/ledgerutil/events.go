@@ -0,0 +1,260 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ledgerutil
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-contract-api-go/metadata"
+)
+
+// EventSchemaPrefix is the object type of the event schemas kept in the world state
+const EventSchemaPrefix = "eventschema"
+
+// SchemaVersionField is the field of every event payload holding the version of its schema
+const SchemaVersionField = "schemaVersion"
+
+// EventSchema describes the payload of a chaincode event: the JSON type of each field besides
+// schemaVersion, e.g. string, integer, object or array, and a version raised whenever a field is
+// removed or changes meaning. Adding a field keeps the version, so consumers ignore fields they do
+// not know. The schemas a chaincode declares are published to the world state with PublishSchemas,
+// with the transaction that published them.
+type EventSchema struct {
+	Name        string            `json:"name"`
+	Version     int               `json:"version"`
+	Description string            `json:"description"`
+	Fields      map[string]string `json:"fields"`
+	PublishedBy string            `json:"publishedBy,omitempty" metadata:",optional"`
+	TxID        string            `json:"txID,omitempty" metadata:",optional"`
+	Timestamp   string            `json:"timestamp,omitempty" metadata:",optional"`
+}
+
+// ConfigChangedSchema is the schema of the ConfigChanged event set by PutConfig
+var ConfigChangedSchema = EventSchema{
+	Name:        EventConfigChanged,
+	Version:     1,
+	Description: "configuration parameters or feature flags changed, with their old and new JSON values",
+	Fields:      map[string]string{"changes": "array", "updatedBy": "string"},
+}
+
+// EmitEvent sets the event of the transaction to the canonical JSON encoding of payload, a JSON
+// object, with the version of its schema added under schemaVersion. It fails when the payload has
+// a field the schema does not declare, so a changed payload cannot be emitted under an old schema.
+func EmitEvent(stub shim.ChaincodeStubInterface, schema EventSchema, payload interface{}) error {
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return Wrap(err, "failed to obtain JSON encoding of %s event", schema.Name)
+	}
+	fields := make(map[string]json.RawMessage)
+	err = json.Unmarshal(payloadJSON, &fields)
+	if err != nil {
+		return Wrap(err, "payload of %s event is not a JSON object", schema.Name)
+	}
+	names := make([]string, 0, len(fields))
+	for field := range fields {
+		names = append(names, field)
+	}
+	sort.Strings(names)
+	for _, field := range names {
+		if _, ok := schema.Fields[field]; !ok {
+			return Errorf(CodeInternal, "%s event has field %s, which version %d of its schema does not declare", schema.Name, field, schema.Version)
+		}
+	}
+	fields[SchemaVersionField], err = json.Marshal(schema.Version)
+	if err != nil {
+		return Wrap(err, "failed to obtain JSON encoding of schema version")
+	}
+
+	eventJSON, err := MarshalCanonical(fields)
+	if err != nil {
+		return Wrap(err, "failed to obtain JSON encoding of %s event", schema.Name)
+	}
+	err = stub.SetEvent(schema.Name, eventJSON)
+	if err != nil {
+		return Wrap(err, "failed to set event")
+	}
+	return nil
+}
+
+// EventsContract publishes the event schemas of a chaincode to the world state and answers which
+// versions are current, so event consumers can check a payload version before decoding it
+type EventsContract struct {
+	contractapi.Contract
+	accessControlName string
+	schemas           map[string]EventSchema
+}
+
+// NewEventsContract returns the contract named "events" holding the schemas of the events the
+// chaincode emits and ConfigChangedSchema. Publishing them needs events.PublishSchemas in the
+// access-control chaincode deployed as accessControlName.
+func NewEventsContract(accessControlName string, schemas ...EventSchema) *EventsContract {
+	eventsContract := &EventsContract{accessControlName: accessControlName, schemas: make(map[string]EventSchema)}
+	for _, schema := range append([]EventSchema{ConfigChangedSchema}, schemas...) {
+		eventsContract.schemas[schema.Name] = schema
+	}
+	eventsContract.Name = "events"
+	eventsContract.Info = metadata.InfoMetadata{
+		Title:       "Event schemas",
+		Description: "Registry of the names and payload schema versions of the chaincode events",
+		Version:     "1.0.0",
+		License:     &metadata.LicenseMetadata{Name: "Apache-2.0"},
+	}
+	eventsContract.TransactionContextHandler = new(TransactionContext)
+	eventsContract.BeforeTransaction = BeforeTransaction(eventsContract.GetEvaluateTransactions())
+	eventsContract.UnknownTransaction = UnknownTransaction(eventsContract)
+	return eventsContract
+}
+
+// GetEvaluateTransactions lists the read-only functions of the events contract
+func (c *EventsContract) GetEvaluateTransactions() []string {
+	return []string{"GetSchemas", "GetSchema", "GetSchemaHistory"}
+}
+
+// PublishSchemas writes the schemas of this chaincode build whose version is newer than the
+// published one and returns them, after an upgrade changing an event. It fails when a published
+// version is newer than this build's, as the build is older than one already deployed. Only clients
+// allowed events.PublishSchemas in the access-control chaincode may publish.
+func (c *EventsContract) PublishSchemas(ctx TransactionContextInterface) ([]*EventSchema, error) {
+	err := CheckAccess(ctx, c.accessControlName, "events.PublishSchemas")
+	if err != nil {
+		return nil, err
+	}
+	stub := ctx.GetStub()
+	txID, timestamp, err := TxInfo(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	published := []*EventSchema{}
+	for _, name := range c.names() {
+		schema := c.schemas[name]
+		current, err := readEventSchema(stub, name)
+		if err != nil {
+			return nil, err
+		}
+		if current != nil && current.Version > schema.Version {
+			return nil, Errorf(CodeInvalidArgument, "invalid arguments: version %d of the %s event is published, this chaincode emits version %d", current.Version, name, schema.Version)
+		}
+		if current != nil && current.Version == schema.Version {
+			continue
+		}
+		schema.PublishedBy = ctx.GetClientID()
+		schema.TxID = txID
+		schema.Timestamp = FormatTime(timestamp)
+		key, err := eventSchemaKey(stub, name)
+		if err != nil {
+			return nil, err
+		}
+		err = PutJSON(stub, key, schema)
+		if err != nil {
+			return nil, err
+		}
+		published = append(published, &schema)
+	}
+	return published, nil
+}
+
+// GetSchemas returns the current schema of every event of the chaincode sorted by name: the
+// published one, or the one of this build with no transaction until it is published
+func (c *EventsContract) GetSchemas(ctx TransactionContextInterface) ([]*EventSchema, error) {
+	schemas := []*EventSchema{}
+	for _, name := range c.names() {
+		schema, err := c.GetSchema(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		schemas = append(schemas, schema)
+	}
+	return schemas, nil
+}
+
+// GetSchema returns the current schema of the event name
+func (c *EventsContract) GetSchema(ctx TransactionContextInterface, name string) (*EventSchema, error) {
+	schema, ok := c.schemas[name]
+	if !ok {
+		return nil, Errorf(CodeInvalidArgument, "invalid arguments: %q is not an event, the events are %s", name, strings.Join(c.names(), ", "))
+	}
+	current, err := readEventSchema(ctx.GetStub(), name)
+	if err != nil || current != nil {
+		return current, err
+	}
+	return &schema, nil
+}
+
+// GetSchemaHistory returns the published versions of the schema of the event name, the latest
+// first, so a consumer can decode events emitted before an upgrade. It fails with RESULTS_TRUNCATED
+// when there are more than maxResults.
+func (c *EventsContract) GetSchemaHistory(ctx TransactionContextInterface, name string) ([]EventSchema, error) {
+	if _, ok := c.schemas[name]; !ok {
+		return nil, Errorf(CodeInvalidArgument, "invalid arguments: %q is not an event, the events are %s", name, strings.Join(c.names(), ", "))
+	}
+	stub := ctx.GetStub()
+	config, err := GetQueryConfig(stub)
+	if err != nil {
+		return nil, err
+	}
+	key, err := eventSchemaKey(stub, name)
+	if err != nil {
+		return nil, err
+	}
+	iterator, err := stub.GetHistoryForKey(key)
+	if err != nil {
+		return nil, Wrap(err, "failed to get history of the %s event schema", name)
+	}
+	defer iterator.Close()
+
+	history := []EventSchema{}
+	for iterator.HasNext() {
+		modification, err := iterator.Next()
+		if err != nil {
+			return nil, Wrap(err, "failed to iterate history of the %s event schema", name)
+		}
+		if modification.IsDelete {
+			continue
+		}
+		if len(history) == config.MaxResults {
+			return nil, Errorf(CodeResultsTruncated, "results truncated at %d versions of the %s event schema", config.MaxResults, name)
+		}
+		var schema EventSchema
+		_, err = unmarshalValue(key, modification.Value, &schema)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, schema)
+	}
+	return history, nil
+}
+
+// names returns the names of the events of the chaincode, sorted
+func (c *EventsContract) names() []string {
+	var names []string
+	for name := range c.schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// eventSchemaKey returns the world state key of the schema of the event name
+func eventSchemaKey(stub shim.ChaincodeStubInterface, name string) (string, error) {
+	key, err := stub.CreateCompositeKey(EventSchemaPrefix, []string{name})
+	if err != nil {
+		return "", Wrap(err, "failed to create key of the %s event schema", name)
+	}
+	return key, nil
+}
+
+// readEventSchema reads the published schema of the event name, nil when none is published
+func readEventSchema(stub shim.ChaincodeStubInterface, name string) (*EventSchema, error) {
+	key, err := eventSchemaKey(stub, name)
+	if err != nil {
+		return nil, err
+	}
+	return GetJSON[EventSchema](stub, key)
+}
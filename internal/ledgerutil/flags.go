@@ -0,0 +1,93 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ledgerutil
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+)
+
+// StrictKYCFlag turns on strict KYC mode: parties of a transaction moving tokens or assets must have
+// a verified profile in the identity registry chaincode. Both the token and the asset contract check it.
+var StrictKYCFlag = NewFeatureFlag("strictKYC", "parties of transfers, mints and asset sales must have a verified profile in the identity registry")
+
+// NewFeatureFlag declares a feature flag, a bool configuration parameter that is off until an
+// administrator turns it on, so a new behavior can be rolled out on a running channel without an upgrade
+func NewFeatureFlag(name string, description string) ConfigParam {
+	return ConfigParam{Name: name, Kind: ConfigBool, Default: "false", Description: description}
+}
+
+// FlagEnabled reads whether the feature flag is on
+func FlagEnabled(stub shim.ChaincodeStubInterface, flag ConfigParam) (bool, error) {
+	return GetConfigBool(stub, flag)
+}
+
+// Flag is the state of a feature flag with the client and transaction that last toggled it, which
+// are empty while the flag has its default
+type Flag struct {
+	Name        string `json:"name"`
+	Enabled     bool   `json:"enabled"`
+	Description string `json:"description"`
+	UpdatedBy   string `json:"updatedBy,omitempty" metadata:",optional"`
+	TxID        string `json:"txID,omitempty" metadata:",optional"`
+}
+
+// SetFlag turns the feature flag name on or off and returns it. Only clients allowed config.SetFlag
+// in the access-control chaincode may toggle flags, so they can be granted apart from SetParameter.
+func (c *ConfigContract) SetFlag(ctx TransactionContextInterface, name string, enabled bool) (*Flag, error) {
+	param, err := c.flag(name)
+	if err != nil {
+		return nil, err
+	}
+	err = CheckAccess(ctx, c.accessControlName, "config.SetFlag")
+	if err != nil {
+		return nil, err
+	}
+	parameters, err := PutConfig(ctx, ConfigValue{Param: param, Value: strconv.FormatBool(enabled)})
+	if err != nil {
+		return nil, err
+	}
+	return &Flag{Name: name, Enabled: enabled, Description: param.Description, UpdatedBy: parameters[0].UpdatedBy, TxID: parameters[0].TxID}, nil
+}
+
+// GetFlags returns every feature flag of the chaincode sorted by name, with whether it is on
+func (c *ConfigContract) GetFlags(ctx TransactionContextInterface) ([]*Flag, error) {
+	var names []string
+	for name, param := range c.params {
+		if param.Kind == ConfigBool {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	flags := []*Flag{}
+	for _, name := range names {
+		param := c.params[name]
+		parameter, err := GetConfigParameter(ctx.GetStub(), param)
+		if err != nil {
+			return nil, err
+		}
+		enabled, err := FlagEnabled(ctx.GetStub(), param)
+		if err != nil {
+			return nil, err
+		}
+		flags = append(flags, &Flag{Name: name, Enabled: enabled, Description: param.Description, UpdatedBy: parameter.UpdatedBy, TxID: parameter.TxID})
+	}
+	return flags, nil
+}
+
+// flag looks up a feature flag of the chaincode by name
+func (c *ConfigContract) flag(name string) (ConfigParam, error) {
+	param, err := c.param(name)
+	if err != nil {
+		return param, err
+	}
+	if param.Kind != ConfigBool {
+		return ConfigParam{}, Errorf(CodeInvalidArgument, "invalid arguments: %s is a parameter of kind %s, not a feature flag", name, param.Kind)
+	}
+	return param, nil
+}
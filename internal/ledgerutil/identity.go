@@ -0,0 +1,103 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ledgerutil
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/json"
+
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+)
+
+// attributesOID is the certificate extension in which Fabric CA puts the attributes of an enrollment,
+// as the JSON {"attrs":{"name":"value"}}
+var attributesOID = asn1.ObjectIdentifier{1, 2, 3, 4, 5, 6, 7, 8, 1}
+
+// Identity is the client submitting a transaction, decoded from its certificate
+type Identity struct {
+	// ID is the client ID returned by the client identity's GetID, the base64 of its subject and issuer
+	ID                  string            `json:"id"`
+	MSPID               string            `json:"mspID"`
+	CommonName          string            `json:"commonName"`
+	OrganizationalUnits []string          `json:"organizationalUnits"`
+	IssuerCommonName    string            `json:"issuerCommonName"`
+	Attributes          map[string]string `json:"attributes"`
+}
+
+// DecodeIdentity reads the ID, MSP ID, subject and Fabric CA attributes of a client identity
+func DecodeIdentity(clientIdentity cid.ClientIdentity) (*Identity, error) {
+	clientID, err := clientIdentity.GetID()
+	if err != nil {
+		return nil, Wrap(err, "failed to get client ID")
+	}
+	mspID, err := clientIdentity.GetMSPID()
+	if err != nil {
+		return nil, Wrap(err, "failed to get client MSP ID")
+	}
+	cert, err := clientIdentity.GetX509Certificate()
+	if err != nil {
+		return nil, Wrap(err, "failed to get client certificate")
+	}
+	if cert == nil {
+		return nil, Errorf(CodeInvalidArgument, "client of %s has no certificate", mspID)
+	}
+	attributes, err := certificateAttributes(cert)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Identity{
+		ID:                  clientID,
+		MSPID:               mspID,
+		CommonName:          cert.Subject.CommonName,
+		OrganizationalUnits: append([]string{}, cert.Subject.OrganizationalUnit...),
+		IssuerCommonName:    cert.Issuer.CommonName,
+		Attributes:          attributes,
+	}, nil
+}
+
+// certificateAttributes returns the Fabric CA attributes of a certificate, empty when it has none
+func certificateAttributes(cert *x509.Certificate) (map[string]string, error) {
+	attributes := make(map[string]string)
+	for _, extension := range cert.Extensions {
+		if !extension.Id.Equal(attributesOID) {
+			continue
+		}
+		var value struct {
+			Attrs map[string]string `json:"attrs"`
+		}
+		err := json.Unmarshal(extension.Value, &value)
+		if err != nil {
+			return nil, Errorf(CodeInvalidArgument, "invalid attributes in the client certificate: %v", err)
+		}
+		for name, attribute := range value.Attrs {
+			attributes[name] = attribute
+		}
+	}
+	return attributes, nil
+}
+
+// InOrg reports whether the client belongs to the org of the MSP ID
+func (i *Identity) InOrg(mspID string) bool {
+	return i.MSPID == mspID
+}
+
+// HasOU reports whether the client's certificate has the organizational unit, e.g. admin or client
+// when the org's MSP enables NodeOUs
+func (i *Identity) HasOU(ou string) bool {
+	for _, unit := range i.OrganizationalUnits {
+		if unit == ou {
+			return true
+		}
+	}
+	return false
+}
+
+// HasAttribute reports whether the client's certificate has the attribute with the value
+func (i *Identity) HasAttribute(name string, value string) bool {
+	attribute, ok := i.Attributes[name]
+	return ok && attribute == value
+}
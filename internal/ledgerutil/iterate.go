@@ -0,0 +1,177 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ledgerutil
+
+import (
+	"encoding/base64"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+)
+
+// ForEachByPartialCompositeKey calls fn with the split key and value of every state entry whose
+// composite key starts with objectType and attributes, stopping at the first error fn returns
+func ForEachByPartialCompositeKey(stub shim.ChaincodeStubInterface, objectType string, attributes []string, fn func(keyAttributes []string, value []byte) error) error {
+	iterator, err := stub.GetStateByPartialCompositeKey(objectType, attributes)
+	if err != nil {
+		return fmt.Errorf("failed to get %s entries from world state: %v", objectType, err)
+	}
+	return forEach(stub, iterator, fn)
+}
+
+// ForEachPrivateByPartialCompositeKey is ForEachByPartialCompositeKey over a private data collection
+func ForEachPrivateByPartialCompositeKey(stub shim.ChaincodeStubInterface, collection string, objectType string, attributes []string, fn func(keyAttributes []string, value []byte) error) error {
+	iterator, err := stub.GetPrivateDataByPartialCompositeKey(collection, objectType, attributes)
+	if err != nil {
+		return fmt.Errorf("failed to get %s entries from collection %s: %v", objectType, collection, err)
+	}
+	return forEach(stub, iterator, fn)
+}
+
+// Page is one page of a paginated query. Bookmark is passed to the next query to continue after
+// the last result and is empty on the last page.
+type Page[T any] struct {
+	Results  []T    `json:"results"`
+	Bookmark string `json:"bookmark"`
+}
+
+// GetPageByPartialCompositeKey returns up to pageSize JSON values of a composite key prefix,
+// starting after bookmark. A pageSize of 0 or less is an error, so a query can never read an
+// unbounded number of keys.
+func GetPageByPartialCompositeKey[T any](stub shim.ChaincodeStubInterface, objectType string, attributes []string, pageSize int32, bookmark string) (*Page[T], error) {
+	if pageSize <= 0 {
+		return nil, fmt.Errorf("page size must be positive")
+	}
+	iterator, metadata, err := stub.GetStateByPartialCompositeKeyWithPagination(objectType, attributes, pageSize, bookmark)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s entries from world state: %v", objectType, err)
+	}
+
+	page := &Page[T]{Results: []T{}}
+	err = forEach(stub, iterator, func(keyAttributes []string, valueJSON []byte) error {
+		var value T
+		_, err := unmarshalValue(objectType, valueJSON, &value)
+		if err != nil {
+			return err
+		}
+		page.Results = append(page.Results, value)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(page.Results) == int(pageSize) {
+		page.Bookmark = metadata.Bookmark
+	}
+	return page, nil
+}
+
+// KeyEntry is one entry of a composite key listing: the attributes of its key and its value as stored,
+// e.g. an amount or a JSON record. A value that is not UTF-8, such as a record stored in protobuf, is
+// base64 encoded and Encoding is base64.
+type KeyEntry struct {
+	Attributes []string `json:"attributes"`
+	Value      string   `json:"value"`
+	Encoding   string   `json:"encoding,omitempty" metadata:",optional"`
+}
+
+// newKeyEntry returns the entry of a composite key listing for a stored value
+func newKeyEntry(keyAttributes []string, value []byte) KeyEntry {
+	if !utf8.Valid(value) {
+		return KeyEntry{Attributes: keyAttributes, Value: base64.StdEncoding.EncodeToString(value), Encoding: "base64"}
+	}
+	return KeyEntry{Attributes: keyAttributes, Value: string(value)}
+}
+
+// KeyPage is one page of a composite key listing. Bookmark is passed to the next query to continue
+// after the last entry and is empty on the last page.
+type KeyPage struct {
+	ObjectType string     `json:"objectType"`
+	Entries    []KeyEntry `json:"entries"`
+	Bookmark   string     `json:"bookmark"`
+}
+
+// ListByCompositeKey returns up to pageSize entries of the world state whose composite key starts
+// with objectType and partialKeys, starting at bookmark. Contracts expose it to operators for the
+// object types they choose, so a prefix can be inspected without a query of its own.
+func ListByCompositeKey(stub shim.ChaincodeStubInterface, objectType string, partialKeys []string, pageSize int32, bookmark string) (*KeyPage, error) {
+	if pageSize <= 0 {
+		return nil, fmt.Errorf("page size must be positive")
+	}
+	iterator, metadata, err := stub.GetStateByPartialCompositeKeyWithPagination(objectType, partialKeys, pageSize, bookmark)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s entries from world state: %v", objectType, err)
+	}
+
+	page := &KeyPage{ObjectType: objectType, Entries: []KeyEntry{}}
+	err = forEach(stub, iterator, func(keyAttributes []string, value []byte) error {
+		page.Entries = append(page.Entries, newKeyEntry(keyAttributes, value))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if metadata.FetchedRecordsCount == pageSize {
+		page.Bookmark = metadata.Bookmark
+	}
+	return page, nil
+}
+
+// ListPrivateByCompositeKey is ListByCompositeKey over a private data collection. The peer cannot
+// paginate private data, so the bookmark is the key of the first entry of the next page and the
+// entries before it are read and skipped.
+func ListPrivateByCompositeKey(stub shim.ChaincodeStubInterface, collection string, objectType string, partialKeys []string, pageSize int32, bookmark string) (*KeyPage, error) {
+	if pageSize <= 0 {
+		return nil, fmt.Errorf("page size must be positive")
+	}
+	iterator, err := stub.GetPrivateDataByPartialCompositeKey(collection, objectType, partialKeys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s entries from collection %s: %v", objectType, collection, err)
+	}
+	defer iterator.Close()
+
+	page := &KeyPage{ObjectType: objectType, Entries: []KeyEntry{}}
+	for iterator.HasNext() {
+		result, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate: %v", err)
+		}
+		if result.Key < bookmark {
+			continue
+		}
+		if len(page.Entries) == int(pageSize) {
+			page.Bookmark = result.Key
+			break
+		}
+		_, keyAttributes, err := stub.SplitCompositeKey(result.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split composite key: %v", err)
+		}
+		page.Entries = append(page.Entries, newKeyEntry(keyAttributes, result.Value))
+	}
+	return page, nil
+}
+
+// forEach drains and closes a composite key iterator
+func forEach(stub shim.ChaincodeStubInterface, iterator shim.StateQueryIteratorInterface, fn func(keyAttributes []string, value []byte) error) error {
+	defer iterator.Close()
+
+	for iterator.HasNext() {
+		result, err := iterator.Next()
+		if err != nil {
+			return fmt.Errorf("failed to iterate: %v", err)
+		}
+		_, keyAttributes, err := stub.SplitCompositeKey(result.Key)
+		if err != nil {
+			return fmt.Errorf("failed to split composite key: %v", err)
+		}
+		err = fn(keyAttributes, result.Value)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ledgerutil
+
+import (
+	"strconv"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+)
+
+// QueryConfigKey is the world state key the QueryConfig of a chaincode was kept under before the
+// limits became the maxPageSize and maxResults configuration parameters. It is still read, so the
+// limits set by an earlier version stay in force until the parameters are set.
+const QueryConfigKey = "queryConfig"
+
+const (
+	// DefaultMaxPageSize is the largest page of a paginated query until PutQueryConfig is called
+	DefaultMaxPageSize = 100
+	// DefaultMaxResults is the most results an unpaginated query returns until PutQueryConfig is
+	// called
+	DefaultMaxResults = 1000
+	// MaxResultsLimit bounds both limits of a QueryConfig, so a misconfigured chaincode still cannot
+	// read an unbounded number of keys in one query
+	MaxResultsLimit = 10000
+)
+
+// QueryConfig bounds the results of the queries of a chaincode. MaxPageSize is the largest page
+// size a paginated query accepts and MaxResults the most results a query without pagination, such
+// as a history, returns before failing with RESULTS_TRUNCATED.
+type QueryConfig struct {
+	MaxPageSize int `json:"maxPageSize"`
+	MaxResults  int `json:"maxResults"`
+}
+
+// GetQueryConfig reads the query limits of the chaincode, DefaultMaxPageSize and DefaultMaxResults
+// until PutQueryConfig is called
+func GetQueryConfig(stub shim.ChaincodeStubInterface) (QueryConfig, error) {
+	config := QueryConfig{MaxPageSize: DefaultMaxPageSize, MaxResults: DefaultMaxResults}
+	_, err := ReadJSON(stub, QueryConfigKey, &config)
+	if err != nil {
+		return config, err
+	}
+	_, err = readConfigValue(stub, MaxPageSizeParam, &config.MaxPageSize)
+	if err != nil {
+		return config, err
+	}
+	_, err = readConfigValue(stub, MaxResultsParam, &config.MaxResults)
+	return config, err
+}
+
+// PutQueryConfig writes the query limits of the chaincode as the maxPageSize and maxResults
+// configuration parameters after checking that 1 <= MaxPageSize <= MaxResults <= MaxResultsLimit.
+// Callers check that the client may change them.
+func PutQueryConfig(ctx TransactionContextInterface, config QueryConfig) error {
+	if config.MaxPageSize < 1 || config.MaxPageSize > config.MaxResults || config.MaxResults > MaxResultsLimit {
+		return Errorf(CodeInvalidArgument, "invalid arguments: maxPageSize must be at least 1 and at most maxResults, which must be at most %d", MaxResultsLimit)
+	}
+	_, err := PutConfig(ctx,
+		ConfigValue{Param: MaxPageSizeParam, Value: strconv.Itoa(config.MaxPageSize)},
+		ConfigValue{Param: MaxResultsParam, Value: strconv.Itoa(config.MaxResults)})
+	return err
+}
+
+// CheckPageSize fails with INVALID_ARGUMENT unless pageSize is between 1 and the MaxPageSize of
+// the chaincode, and returns the query limits otherwise
+func CheckPageSize(stub shim.ChaincodeStubInterface, pageSize int) (QueryConfig, error) {
+	config, err := GetQueryConfig(stub)
+	if err != nil {
+		return config, err
+	}
+	if pageSize <= 0 || pageSize > config.MaxPageSize {
+		return config, Errorf(CodeInvalidArgument, "invalid arguments: pageSize must be between 1 and %d", config.MaxPageSize)
+	}
+	return config, nil
+}
+
+// ResultsTruncated is the error of a query without pagination that found more than maxResults
+// results. It names the paginated function returning the same results with a bookmark.
+func ResultsTruncated(maxResults int, pagedFunction string) error {
+	return Errorf(CodeResultsTruncated, "results truncated at %d, use %s with a bookmark to read them all", maxResults, pagedFunction)
+}
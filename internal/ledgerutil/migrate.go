@@ -0,0 +1,175 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ledgerutil
+
+import (
+	"strconv"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+)
+
+// SchemaVersionKey is the world state key of the version of the format of a chaincode's records
+const SchemaVersionKey = "schemaVersion"
+
+// SchemaMigrationKey is the world state key of the progress of a running MigrateState
+const SchemaMigrationKey = "schemaMigration"
+
+// InitialSchemaVersion is the version of a world state without a schema version, i.e. the records
+// written before the chaincode used versioned state
+const InitialSchemaVersion = 1
+
+// Migration rewrites the records of a chaincode from the format of schema version From to the
+// format of To
+type Migration struct {
+	From int
+	To   int
+	// ObjectType selects the records with composite keys of the object type, which every page
+	// iterates from the first, as composite keys cannot start a range. When empty, the records
+	// with simple keys are migrated, except the keys this package keeps.
+	ObjectType string
+	// Rewrite returns the value of a record in the new format, or nil to leave it as it is. It is
+	// called again for records rewritten by a migration that failed, so it must accept them. JSON
+	// values are encoded with MarshalCanonical, as PutJSON does.
+	Rewrite func(key string, value []byte) ([]byte, error)
+}
+
+// MigrationProgress is the result of a MigrateState call
+type MigrationProgress struct {
+	FromVersion int `json:"fromVersion"`
+	ToVersion   int `json:"toVersion"`
+	// Migrated is the number of records the call rewrote
+	Migrated int `json:"migrated"`
+	// Bookmark is passed to the next call and is empty when the migration is done
+	Bookmark string `json:"bookmark"`
+	// SchemaVersion is the version of the world state after the call
+	SchemaVersion int `json:"schemaVersion"`
+}
+
+// migrationState is the progress of a running migration, kept under SchemaMigrationKey
+type migrationState struct {
+	From     int    `json:"fromVersion"`
+	To       int    `json:"toVersion"`
+	Bookmark string `json:"bookmark"`
+}
+
+// GetSchemaVersion returns the schema version of the world state
+func GetSchemaVersion(stub shim.ChaincodeStubInterface) (int, error) {
+	versionBytes, err := stub.GetState(SchemaVersionKey)
+	if err != nil {
+		return 0, Wrap(err, "failed to read schema version")
+	}
+	if versionBytes == nil {
+		return InitialSchemaVersion, nil
+	}
+	version, err := strconv.Atoi(string(versionBytes))
+	if err != nil {
+		return 0, Errorf(CodeCorruptState, "invalid schema version %q", versionBytes)
+	}
+	return version, nil
+}
+
+// MigrateState rewrites up to pageSize records of the migration from fromVersion to toVersion,
+// starting at bookmark, and sets the schema version to toVersion with the last page. A migration
+// of many records thus runs over several transactions, each passing the bookmark returned by the
+// one before; the bookmark is kept in the world state so pages cannot be skipped or repeated by
+// passing another. Calling it again once the world state is at toVersion does nothing.
+//
+// Until the migration is done, the world state holds records of both formats, so the chaincode of
+// the new version must read both.
+func MigrateState(stub shim.ChaincodeStubInterface, migrations []Migration, fromVersion int, toVersion int, pageSize int32, bookmark string) (*MigrationProgress, error) {
+	if pageSize <= 0 {
+		return nil, Errorf(CodeInvalidArgument, "invalid arguments: pageSize must be positive")
+	}
+	var migration *Migration
+	for i := range migrations {
+		if migrations[i].From == fromVersion && migrations[i].To == toVersion {
+			migration = &migrations[i]
+		}
+	}
+	if migration == nil {
+		return nil, Errorf(CodeInvalidArgument, "invalid arguments: there is no migration from schema version %d to %d", fromVersion, toVersion)
+	}
+
+	version, err := GetSchemaVersion(stub)
+	if err != nil {
+		return nil, err
+	}
+	progress := &MigrationProgress{FromVersion: fromVersion, ToVersion: toVersion, SchemaVersion: version}
+	if version == toVersion {
+		return progress, nil
+	}
+	if version != fromVersion {
+		return nil, Errorf(CodeInvalidArgument, "invalid arguments: the schema version is %d, not %d", version, fromVersion)
+	}
+	var state migrationState
+	running, err := ReadJSON(stub, SchemaMigrationKey, &state)
+	if err != nil {
+		return nil, err
+	}
+	if running && (state.From != fromVersion || state.To != toVersion) {
+		return nil, Errorf(CodeInvalidArgument, "invalid arguments: the migration from %d to %d is running", state.From, state.To)
+	}
+	if state.Bookmark != bookmark {
+		return nil, Errorf(CodeInvalidArgument, "invalid arguments: the migration continues at bookmark %q", state.Bookmark)
+	}
+
+	// paginated queries are not allowed in transactions that write, so the page is cut off after
+	// pageSize records and the key of the next one is the bookmark
+	var iterator shim.StateQueryIteratorInterface
+	if migration.ObjectType == "" {
+		iterator, err = stub.GetStateByRange(bookmark, "")
+	} else {
+		// composite keys cannot start a range, so the records before the bookmark are skipped
+		iterator, err = stub.GetStateByPartialCompositeKey(migration.ObjectType, []string{})
+	}
+	if err != nil {
+		return nil, Wrap(err, "failed to get records from world state")
+	}
+	defer iterator.Close()
+
+	var read int32
+	for iterator.HasNext() {
+		result, err := iterator.Next()
+		if err != nil {
+			return nil, Wrap(err, "failed to iterate records")
+		}
+		if result.Key < bookmark || result.Key == SchemaVersionKey || result.Key == SchemaMigrationKey || result.Key == AuditConfigKey ||
+			result.Key == QueryConfigKey {
+			continue
+		}
+		if read == pageSize {
+			progress.Bookmark = result.Key
+			err = PutJSON(stub, SchemaMigrationKey, migrationState{From: fromVersion, To: toVersion, Bookmark: result.Key})
+			if err != nil {
+				return nil, err
+			}
+			return progress, nil
+		}
+		read++
+		value, err := migration.Rewrite(result.Key, result.Value)
+		if err != nil {
+			return nil, Wrap(err, "failed to migrate %q", result.Key)
+		}
+		if value == nil {
+			continue
+		}
+		err = stub.PutState(result.Key, value)
+		if err != nil {
+			return nil, Wrap(err, "failed to put %q", result.Key)
+		}
+		progress.Migrated++
+	}
+
+	err = stub.PutState(SchemaVersionKey, []byte(strconv.Itoa(toVersion)))
+	if err != nil {
+		return nil, Wrap(err, "failed to put schema version")
+	}
+	err = stub.DelState(SchemaMigrationKey)
+	if err != nil {
+		return nil, Wrap(err, "failed to delete migration progress")
+	}
+	progress.SchemaVersion = toVersion
+	return progress, nil
+}
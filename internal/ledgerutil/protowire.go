@@ -0,0 +1,285 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ledgerutil
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+	"unicode/utf8"
+)
+
+// The wire types of the protobuf encoding
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// ProtoWriter appends the fields of a protobuf message in the proto3 wire format, so records with a
+// .proto schema are encoded without generated code or a protobuf dependency in the chaincode. Scalar
+// fields with their zero value are left out as proto3 does, and fields are written in the order of
+// the calls, so a record writing them in field number order always encodes to the same bytes.
+type ProtoWriter struct {
+	buffer []byte
+}
+
+// Bytes returns the encoded message
+func (w *ProtoWriter) Bytes() []byte {
+	return w.buffer
+}
+
+// String writes a string field
+func (w *ProtoWriter) String(field int, value string) {
+	if value != "" {
+		w.BytesField(field, []byte(value))
+	}
+}
+
+// Strings writes a repeated string field, empty strings included
+func (w *ProtoWriter) Strings(field int, values []string) {
+	for _, value := range values {
+		w.BytesField(field, []byte(value))
+	}
+}
+
+// BytesField writes a bytes field, or an embedded message encoded with another ProtoWriter. It is
+// written even when empty, so an embedded message with only default fields is still present.
+func (w *ProtoWriter) BytesField(field int, value []byte) {
+	w.tag(field, wireBytes)
+	w.buffer = appendVarint(w.buffer, uint64(len(value)))
+	w.buffer = append(w.buffer, value...)
+}
+
+// Int64 writes an int64 field. Negative values take ten bytes, as with protobuf int64.
+func (w *ProtoWriter) Int64(field int, value int64) {
+	if value != 0 {
+		w.tag(field, wireVarint)
+		w.buffer = appendVarint(w.buffer, uint64(value))
+	}
+}
+
+// Bool writes a bool field
+func (w *ProtoWriter) Bool(field int, value bool) {
+	if value {
+		w.tag(field, wireVarint)
+		w.buffer = append(w.buffer, 1)
+	}
+}
+
+// Timestamp writes a google.protobuf.Timestamp field, left out for the zero time
+func (w *ProtoWriter) Timestamp(field int, value time.Time) {
+	if value.IsZero() {
+		return
+	}
+	var timestamp ProtoWriter
+	timestamp.Int64(1, value.Unix())
+	timestamp.Int64(2, int64(value.Nanosecond()))
+	w.BytesField(field, timestamp.Bytes())
+}
+
+// StringMap writes a map<string, string> field with its entries sorted by key, since every endorsing
+// peer must write the same bytes
+func (w *ProtoWriter) StringMap(field int, values map[string]string) {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		var entry ProtoWriter
+		entry.String(1, key)
+		entry.String(2, values[key])
+		w.BytesField(field, entry.Bytes())
+	}
+}
+
+func (w *ProtoWriter) tag(field int, wireType int) {
+	w.buffer = appendVarint(w.buffer, uint64(field)<<3|uint64(wireType))
+}
+
+// appendVarint appends the base 128 varint encoding of value
+func appendVarint(buffer []byte, value uint64) []byte {
+	for value >= 0x80 {
+		buffer = append(buffer, byte(value)|0x80)
+		value >>= 7
+	}
+	return append(buffer, byte(value))
+}
+
+// ProtoReader reads the fields of a protobuf message one at a time. Next moves to the next field and
+// the Read methods read it; fields a record does not know are skipped by calling Next again, so
+// records written by a later schema can still be read. The first malformed field stops Next and is
+// returned by Err.
+type ProtoReader struct {
+	data     []byte
+	field    int
+	wireType int
+	varint   uint64
+	bytes    []byte
+	err      error
+}
+
+// NewProtoReader returns a reader of the fields of an encoded message
+func NewProtoReader(data []byte) *ProtoReader {
+	return &ProtoReader{data: data}
+}
+
+// Next moves to the next field, returning false at the end of the message or on an error
+func (r *ProtoReader) Next() bool {
+	if r.err != nil || len(r.data) == 0 {
+		return false
+	}
+	tag, n := binary.Uvarint(r.data)
+	if n <= 0 {
+		return r.fail("invalid field tag")
+	}
+	r.data = r.data[n:]
+	if tag>>3 == 0 || tag>>3 > math.MaxInt32 {
+		return r.fail("invalid field number %d", tag>>3)
+	}
+	r.field, r.wireType = int(tag>>3), int(tag&7)
+	switch r.wireType {
+	case wireVarint:
+		r.varint, n = binary.Uvarint(r.data)
+		if n <= 0 {
+			return r.fail("invalid varint of field %d", r.field)
+		}
+		r.data = r.data[n:]
+	case wireFixed64, wireFixed32:
+		size := 8
+		if r.wireType == wireFixed32 {
+			size = 4
+		}
+		if len(r.data) < size {
+			return r.fail("truncated field %d", r.field)
+		}
+		r.data = r.data[size:]
+	case wireBytes:
+		length, n := binary.Uvarint(r.data)
+		if n <= 0 || length > uint64(len(r.data)-n) {
+			return r.fail("truncated field %d", r.field)
+		}
+		r.bytes = r.data[n : n+int(length)]
+		r.data = r.data[n+int(length):]
+	default:
+		return r.fail("unsupported wire type %d of field %d", r.wireType, r.field)
+	}
+	return true
+}
+
+// Field returns the number of the current field
+func (r *ProtoReader) Field() int {
+	return r.field
+}
+
+// ReadString reads the current field as a string
+func (r *ProtoReader) ReadString() string {
+	if !r.expect(wireBytes) {
+		return ""
+	}
+	if !utf8.Valid(r.bytes) {
+		r.fail("field %d is not valid UTF-8", r.field)
+		return ""
+	}
+	return string(r.bytes)
+}
+
+// ReadBytes reads the current field as bytes or an embedded message
+func (r *ProtoReader) ReadBytes() []byte {
+	if !r.expect(wireBytes) {
+		return nil
+	}
+	return r.bytes
+}
+
+// ReadInt64 reads the current field as an int64
+func (r *ProtoReader) ReadInt64() int64 {
+	if !r.expect(wireVarint) {
+		return 0
+	}
+	return int64(r.varint)
+}
+
+// ReadBool reads the current field as a bool
+func (r *ProtoReader) ReadBool() bool {
+	if !r.expect(wireVarint) {
+		return false
+	}
+	return r.varint != 0
+}
+
+// ReadTimestamp reads the current field as a google.protobuf.Timestamp, in UTC
+func (r *ProtoReader) ReadTimestamp() time.Time {
+	var seconds, nanos int64
+	timestamp := NewProtoReader(r.ReadBytes())
+	for timestamp.Next() {
+		switch timestamp.Field() {
+		case 1:
+			seconds = timestamp.ReadInt64()
+		case 2:
+			nanos = timestamp.ReadInt64()
+		}
+	}
+	if timestamp.Err() != nil {
+		r.fail("invalid timestamp in field %d: %v", r.field, timestamp.Err())
+	}
+	if nanos < 0 || nanos > 999999999 {
+		r.fail("invalid nanoseconds %d of timestamp in field %d", nanos, r.field)
+		return time.Time{}
+	}
+	return time.Unix(seconds, nanos).UTC()
+}
+
+// ReadStringMapEntry reads the current field as an entry of a map<string, string> into values,
+// creating the map for the first entry
+func (r *ProtoReader) ReadStringMapEntry(values *map[string]string) {
+	var key, value string
+	entry := NewProtoReader(r.ReadBytes())
+	for entry.Next() {
+		switch entry.Field() {
+		case 1:
+			key = entry.ReadString()
+		case 2:
+			value = entry.ReadString()
+		}
+	}
+	if entry.Err() != nil {
+		r.fail("invalid map entry in field %d: %v", r.field, entry.Err())
+		return
+	}
+	if *values == nil {
+		*values = make(map[string]string)
+	}
+	(*values)[key] = value
+}
+
+// Err returns the first error found reading the message
+func (r *ProtoReader) Err() error {
+	return r.err
+}
+
+// expect checks the current field has the wire type of the getter reading it
+func (r *ProtoReader) expect(wireType int) bool {
+	if r.err != nil {
+		return false
+	}
+	if r.wireType != wireType {
+		r.fail("field %d has wire type %d, not %d", r.field, r.wireType, wireType)
+		return false
+	}
+	return true
+}
+
+func (r *ProtoReader) fail(format string, args ...interface{}) bool {
+	if r.err == nil {
+		r.err = fmt.Errorf(format, args...)
+	}
+	r.data = nil
+	return false
+}
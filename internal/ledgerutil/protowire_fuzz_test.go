@@ -0,0 +1,89 @@
+package ledgerutil
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func FuzzProtoReader(f *testing.F) {
+	for _, seed := range [][]byte{{0x0a, 0x05, 'a', 's', 's', 'e', 't'}, {0x08, 0x96, 0x01}, {0x0a, 0x10, 'r'}, {0x00}, {0x0b}, {0x09, 1, 2, 3}, {0x52, 0x04, 0x0a, 0x00, 0x12, 0x00}} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		reader := NewProtoReader(data)
+		for reader.Next() {
+			switch reader.Field() % 4 {
+			case 0:
+				reader.ReadString()
+			case 1:
+				reader.ReadInt64()
+			case 2:
+				reader.ReadTimestamp()
+			case 3:
+				var values map[string]string
+				reader.ReadStringMapEntry(&values)
+			}
+		}
+	})
+}
+
+func TestProtoWriter(t *testing.T) {
+	timestamp := time.Date(1969, 7, 20, 20, 17, 40, 123, time.UTC)
+	var w ProtoWriter
+	w.String(1, "asset")
+	w.Int64(2, -150)
+	w.Bool(3, true)
+	w.Timestamp(4, timestamp)
+	w.Strings(5, []string{"a", ""})
+	w.StringMap(6, map[string]string{"b": "2", "a": "1"})
+	w.String(7, "")
+	w.Int64(8, 0)
+	w.Timestamp(9, time.Time{})
+
+	var (
+		text      string
+		number    int64
+		flag      bool
+		read      time.Time
+		texts     []string
+		values    map[string]string
+		unchanged []int
+	)
+	reader := NewProtoReader(w.Bytes())
+	for reader.Next() {
+		switch reader.Field() {
+		case 1:
+			text = reader.ReadString()
+		case 2:
+			number = reader.ReadInt64()
+		case 3:
+			flag = reader.ReadBool()
+		case 4:
+			read = reader.ReadTimestamp()
+		case 5:
+			texts = append(texts, reader.ReadString())
+		case 6:
+			reader.ReadStringMapEntry(&values)
+		default:
+			unchanged = append(unchanged, reader.Field())
+		}
+	}
+	if reader.Err() != nil {
+		t.Fatalf("unexpected error: %v", reader.Err())
+	}
+	if text != "asset" || number != -150 || !flag || !read.Equal(timestamp) || !reflect.DeepEqual(texts, []string{"a", ""}) ||
+		!reflect.DeepEqual(values, map[string]string{"a": "1", "b": "2"}) || len(unchanged) != 0 {
+		t.Errorf("read %q %d %t %v %q %v %v", text, number, flag, read, texts, values, unchanged)
+	}
+	if want := "\x0a\x05asset"; string(w.Bytes()[:7]) != want {
+		t.Errorf("message starts with %q, want %q", w.Bytes()[:7], want)
+	}
+
+	reader = NewProtoReader(w.Bytes())
+	reader.Next()
+	reader.ReadInt64()
+	if reader.Err() == nil || reader.Next() {
+		t.Errorf("reading a string field as an int64 did not fail")
+	}
+}
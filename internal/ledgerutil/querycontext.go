@@ -0,0 +1,94 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ledgerutil
+
+import (
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+)
+
+// KeyedTransactionContext is the transaction context of contracts with submitted functions. The peer
+// does not record CouchDB rich queries in the read set of a transaction, so a function writing what it
+// decided from one commits even when another transaction changed the results in between. Its stub
+// fails rich queries, leaving the keyed reads and the range and composite key queries the peer checks
+// again at validation. Rich queries go to a contract with a QueryTransactionContext.
+type KeyedTransactionContext struct {
+	TransactionContext
+}
+
+// SetStub sets the stub of the transaction, wrapped to fail rich queries
+func (ctx *KeyedTransactionContext) SetStub(stub shim.ChaincodeStubInterface) {
+	ctx.TransactionContext.SetStub(keyedStub{stub})
+}
+
+// QueryTransactionContext is the transaction context of query-only contracts, whose functions are all
+// evaluate functions and may run rich queries. Its stub fails every write, so nothing a rich query
+// returned can be committed even when a client submits one of its functions.
+type QueryTransactionContext struct {
+	TransactionContext
+}
+
+// SetStub sets the stub of the transaction, wrapped to fail writes
+func (ctx *QueryTransactionContext) SetStub(stub shim.ChaincodeStubInterface) {
+	ctx.TransactionContext.SetStub(queryStub{stub})
+}
+
+// keyedStub is the stub of a KeyedTransactionContext
+type keyedStub struct {
+	shim.ChaincodeStubInterface
+}
+
+func richQueryError() error {
+	return Errorf(CodeInternal, "rich queries are not validated at commit and are only available to query-only contracts")
+}
+
+func (keyedStub) GetQueryResult(string) (shim.StateQueryIteratorInterface, error) {
+	return nil, richQueryError()
+}
+
+func (keyedStub) GetQueryResultWithPagination(string, int32, string) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	return nil, nil, richQueryError()
+}
+
+func (keyedStub) GetPrivateDataQueryResult(string, string) (shim.StateQueryIteratorInterface, error) {
+	return nil, richQueryError()
+}
+
+// queryStub is the stub of a QueryTransactionContext
+type queryStub struct {
+	shim.ChaincodeStubInterface
+}
+
+func writeError() error {
+	return Errorf(CodeNotAuthorized, "functions of a query-only contract cannot write to the ledger")
+}
+
+func (queryStub) PutState(string, []byte) error {
+	return writeError()
+}
+
+func (queryStub) DelState(string) error {
+	return writeError()
+}
+
+func (queryStub) SetStateValidationParameter(string, []byte) error {
+	return writeError()
+}
+
+func (queryStub) PutPrivateData(string, string, []byte) error {
+	return writeError()
+}
+
+func (queryStub) DelPrivateData(string, string) error {
+	return writeError()
+}
+
+func (queryStub) SetPrivateDataValidationParameter(string, string, []byte) error {
+	return writeError()
+}
+
+func (queryStub) SetEvent(string, []byte) error {
+	return writeError()
+}
@@ -0,0 +1,34 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ledgerutil
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+)
+
+// RecordID returns the ID of a record one call of a transaction writes, e.g. the receipt of a
+// transfer: the hex encoded SHA-256 hash of the transaction ID and the fields that set the record
+// apart from the others the transaction may write, such as the payer, receiver, amount and reference
+// of a receipt. Keying such records by the transaction ID alone keeps only the last one when a
+// transaction writes several, e.g. a chaincode making two transfers through InvokeChaincode, and the
+// world state cannot number them, as a transaction does not read its own writes.
+//
+// Every endorsing peer computes the same ID for the same call. Two calls of a transaction passing
+// the same fields get the same ID, so the second record replaces the first, as the second write of
+// the balances they change replaces the first.
+func RecordID(stub shim.ChaincodeStubInterface, fields ...string) string {
+	hash := sha256.New()
+	// each value is preceded by its length, so the values are kept apart whatever they contain
+	var length [binary.MaxVarintLen64]byte
+	for _, value := range append([]string{stub.GetTxID()}, fields...) {
+		hash.Write(length[:binary.PutUvarint(length[:], uint64(len(value)))])
+		hash.Write([]byte(value))
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,25 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ledgerutil
+
+import (
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+)
+
+// StatusSuccess is the status of the result returned by a function that changed the ledger. The
+// changes take effect once the transaction is committed.
+const StatusSuccess = "SUCCESS"
+
+// TxInfo returns the ID and timestamp of the transaction. The client sets both in the proposal, so
+// every endorsing peer returns the same values. The timestamp is checked as by TxTime.
+func TxInfo(stub shim.ChaincodeStubInterface) (string, time.Time, error) {
+	timestamp, err := txTime(stub)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return stub.GetTxID(), timestamp, nil
+}
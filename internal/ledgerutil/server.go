@@ -0,0 +1,122 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ledgerutil
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Environment variables of the chaincode-as-a-service mode, named as in the Fabric external builder
+// samples. The TLS variables are paths of PEM files.
+const (
+	envServerAddress = "CHAINCODE_SERVER_ADDRESS"
+	envChaincodeID   = "CHAINCODE_ID"
+	envTLSDisabled   = "CHAINCODE_TLS_DISABLED"
+	envTLSKey        = "CHAINCODE_TLS_KEY"
+	envTLSCert       = "CHAINCODE_TLS_CERT"
+	envClientCACert  = "CHAINCODE_CLIENT_CA_CERT"
+)
+
+// StartChaincode runs chaincode until it fails or the process is asked to stop. When
+// CHAINCODE_SERVER_ADDRESS is set, the chaincode runs as an external service listening on that
+// address for the peer, which needs the package ID in CHAINCODE_ID; otherwise it is a packaged
+// chaincode and connects to the peer that launched it.
+//
+// On SIGTERM or SIGINT the external service returns nil so the process exits cleanly, as when a
+// Kubernetes pod is stopped. The shim cannot drain the gRPC server, so a transaction being endorsed
+// at that moment fails and is retried by the client; nothing it wrote is committed.
+func StartChaincode(chaincode *contractapi.ContractChaincode) error {
+	address := os.Getenv(envServerAddress)
+	if address == "" {
+		return chaincode.Start()
+	}
+
+	ccid := os.Getenv(envChaincodeID)
+	if ccid == "" {
+		return fmt.Errorf("%s must be set with %s", envChaincodeID, envServerAddress)
+	}
+	tlsProps, err := tlsProperties()
+	if err != nil {
+		return err
+	}
+	server := &shim.ChaincodeServer{
+		CCID:     ccid,
+		Address:  address,
+		CC:       chaincode,
+		TLSProps: tlsProps,
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGTERM, syscall.SIGINT)
+	defer signal.Stop(stop)
+	failed := make(chan error, 1)
+	go func() {
+		failed <- server.Start()
+	}()
+	log.Printf("chaincode %s listening on %s, TLS disabled: %t", ccid, address, tlsProps.Disabled)
+
+	select {
+	case err := <-failed:
+		return err
+	case sig := <-stop:
+		log.Printf("chaincode %s stopping on %s", ccid, sig)
+		return nil
+	}
+}
+
+// tlsProperties reads the TLS settings of the external service. TLS is off unless
+// CHAINCODE_TLS_DISABLED is false, which then needs the key and certificate; the client CA
+// certificate is optional and turns on mutual TLS.
+func tlsProperties() (shim.TLSProperties, error) {
+	disabled := true
+	if value := os.Getenv(envTLSDisabled); value != "" {
+		var err error
+		disabled, err = strconv.ParseBool(value)
+		if err != nil {
+			return shim.TLSProperties{}, fmt.Errorf("%s must be true or false: %v", envTLSDisabled, err)
+		}
+	}
+	if disabled {
+		return shim.TLSProperties{Disabled: true}, nil
+	}
+
+	key, err := readPEM(envTLSKey, true)
+	if err != nil {
+		return shim.TLSProperties{}, err
+	}
+	cert, err := readPEM(envTLSCert, true)
+	if err != nil {
+		return shim.TLSProperties{}, err
+	}
+	clientCACerts, err := readPEM(envClientCACert, false)
+	if err != nil {
+		return shim.TLSProperties{}, err
+	}
+	return shim.TLSProperties{Key: key, Cert: cert, ClientCACerts: clientCACerts}, nil
+}
+
+// readPEM reads the file named by the environment variable env
+func readPEM(env string, required bool) ([]byte, error) {
+	path := os.Getenv(env)
+	if path == "" {
+		if required {
+			return nil, fmt.Errorf("%s must be set when TLS is enabled", env)
+		}
+		return nil, nil
+	}
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", env, err)
+	}
+	return pem, nil
+}
@@ -0,0 +1,95 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ledgerutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+)
+
+// ReadJSON reads the value of key and unmarshals it into value. It returns false when the key does
+// not exist, so callers decide whether a missing value is an error.
+func ReadJSON(stub shim.ChaincodeStubInterface, key string, value interface{}) (bool, error) {
+	valueJSON, err := stub.GetState(key)
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s from world state: %v", key, err)
+	}
+	return unmarshalValue(key, valueJSON, value)
+}
+
+// GetJSON is ReadJSON for modules on Go 1.18 or later. It returns nil when the key does not exist.
+func GetJSON[T any](stub shim.ChaincodeStubInterface, key string) (*T, error) {
+	value := new(T)
+	found, err := ReadJSON(stub, key, value)
+	if !found || err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// PutJSON writes the canonical JSON encoding of value to key
+func PutJSON(stub shim.ChaincodeStubInterface, key string, value interface{}) error {
+	valueJSON, err := MarshalCanonical(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %v", key, err)
+	}
+	err = stub.PutState(key, valueJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put %s: %v", key, err)
+	}
+	return nil
+}
+
+// ReadPrivateJSON reads the value of key from a private data collection and unmarshals it into
+// value. It returns false when the key does not exist.
+func ReadPrivateJSON(stub shim.ChaincodeStubInterface, collection string, key string, value interface{}) (bool, error) {
+	valueJSON, err := stub.GetPrivateData(collection, key)
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s from collection %s: %v", key, collection, err)
+	}
+	return unmarshalValue(key, valueJSON, value)
+}
+
+// GetPrivateJSON is ReadPrivateJSON for modules on Go 1.18 or later. It returns nil when the key
+// does not exist.
+func GetPrivateJSON[T any](stub shim.ChaincodeStubInterface, collection string, key string) (*T, error) {
+	value := new(T)
+	found, err := ReadPrivateJSON(stub, collection, key, value)
+	if !found || err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// PutPrivateJSON writes the canonical JSON encoding of value to key of a private data collection
+func PutPrivateJSON(stub shim.ChaincodeStubInterface, collection string, key string, value interface{}) error {
+	valueJSON, err := MarshalCanonical(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %v", key, err)
+	}
+	err = stub.PutPrivateData(collection, key, valueJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put %s in collection %s: %v", key, collection, err)
+	}
+	return nil
+}
+
+// unmarshalValue unmarshals a stored JSON value, treating a JSON null as corrupt rather than missing
+func unmarshalValue(key string, valueJSON []byte, value interface{}) (bool, error) {
+	if valueJSON == nil {
+		return false, nil
+	}
+	if bytes.Equal(bytes.TrimSpace(valueJSON), []byte("null")) {
+		return false, Errorf(CodeCorruptState, "stored value of %s is null", key)
+	}
+	err := json.Unmarshal(valueJSON, value)
+	if err != nil {
+		return false, Errorf(CodeCorruptState, "failed to unmarshal %s: %v", key, err)
+	}
+	return true, nil
+}
@@ -0,0 +1,49 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ledgerutil
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// TokenPayment is one receiver and amount of a BatchTransfer of the token chaincode. From, when set,
+// pulls the amount from that account against the submitting client's allowance.
+type TokenPayment struct {
+	From     string `json:"from,omitempty"`
+	Receiver string `json:"receiver"`
+	Amount   int    `json:"amount"`
+}
+
+// TransferTokens pays the payments from the submitting client's account, or the accounts they are
+// pulled from, with one BatchTransfer of the token chaincode deployed as tokenChaincode, skipping
+// payments of no tokens. Chaincodes call it rather than Transfer or TransferFrom once per payment:
+// a transaction does not read its own writes, so each call would start from the same balances and
+// only the last debit would be kept.
+func TransferTokens(ctx contractapi.TransactionContextInterface, tokenChaincode string, payments ...TokenPayment) error {
+	var batch []TokenPayment
+	for _, payment := range payments {
+		if payment.Amount > 0 {
+			batch = append(batch, payment)
+		}
+	}
+	if len(batch) == 0 {
+		return nil
+	}
+
+	batchJSON, err := json.Marshal(batch)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payments: %v", err)
+	}
+	args := [][]byte{[]byte("BatchTransfer"), batchJSON}
+	response := ctx.GetStub().InvokeChaincode(tokenChaincode, args, "") //empty channel means the channel of this chaincode
+	if response.Status != shim.OK {
+		return fmt.Errorf("failed to transfer tokens on %s: %s", tokenChaincode, response.Message)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ledgerutil
+
+import "github.com/hyperledger/fabric-chaincode-go/shim"
+
+// TraceIDKey is the transient data key in which client applications pass the ID of the
+// OpenTelemetry trace of a transaction, so the audit records and receipts of the transaction lead
+// to the trace of the request that submitted it. Transient data is not written to the ledger;
+// only the ID recorded by the chaincode is.
+const TraceIDKey = "trace_id"
+
+// TraceID returns the trace ID passed with the transaction, or an empty string when none was passed
+// or it is not a W3C trace ID of 32 lowercase hex digits. Tracing is optional, so it never fails
+// the transaction.
+func TraceID(stub shim.ChaincodeStubInterface) string {
+	transient, err := stub.GetTransient()
+	if err != nil {
+		return ""
+	}
+	traceID := string(transient[TraceIDKey])
+	if !isTraceID(traceID) {
+		return ""
+	}
+	return traceID
+}
+
+// isTraceID reports whether s is a valid W3C trace ID: 32 lowercase hex digits, not all zero
+func isTraceID(s string) bool {
+	if len(s) != 32 {
+		return false
+	}
+	zero := true
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+		if c != '0' {
+			zero = false
+		}
+	}
+	return !zero
+}
@@ -0,0 +1,49 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ledgerutil
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// UnknownTransaction returns the hook to set as the UnknownTransaction of contract. Instead of the
+// generic contractapi failure, a call to a function the contract does not have fails with
+// UNKNOWN_TRANSACTION and a message listing the contract's functions with the types of their
+// arguments, e.g. Transfer(string, int).
+func UnknownTransaction(contract interface{}) func(ctx TransactionContextInterface) error {
+	transactions := strings.Join(DescribeTransactions(contract), ", ")
+
+	return func(ctx TransactionContextInterface) error {
+		function, args := ctx.GetStub().GetFunctionAndParameters()
+		return Errorf(CodeUnknownTransaction, "%s with %d arguments is not a function of this contract, available functions: %s", function, len(args), transactions)
+	}
+}
+
+// DescribeTransactions returns the functions of a contract that take a transaction context, sorted by
+// name, each with the types of the arguments a client passes
+func DescribeTransactions(contract interface{}) []string {
+	contextType := reflect.TypeOf((*contractapi.TransactionContextInterface)(nil)).Elem()
+	contractType := reflect.TypeOf(contract)
+
+	var transactions []string
+	for i := 0; i < contractType.NumMethod(); i++ {
+		method := contractType.Method(i)
+		// the first input is the receiver, the second the transaction context
+		if method.Type.NumIn() < 2 || !method.Type.In(1).Implements(contextType) {
+			continue
+		}
+		var args []string
+		for j := 2; j < method.Type.NumIn(); j++ {
+			args = append(args, method.Type.In(j).String())
+		}
+		transactions = append(transactions, method.Name+"("+strings.Join(args, ", ")+")")
+	}
+	sort.Strings(transactions)
+	return transactions
+}
@@ -0,0 +1,119 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ledgerutil
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// MaxKeyLength bounds IDs that become world state keys or composite key attributes
+	MaxKeyLength = 256
+	// MaxTextLength bounds free text such as descriptions
+	MaxTextLength = 1024
+	// MaxPayloadLength bounds JSON documents passed as arguments or transient data
+	MaxPayloadLength = 16 * 1024
+)
+
+// Validator checks the arguments of a transaction and reports every problem in one
+// INVALID_ARGUMENT error:
+//
+//	v := ledgerutil.NewValidator()
+//	v.Key("assetID", assetID)
+//	v.PositiveAmount("amount", amount)
+//	if err := v.Err(); err != nil {
+//		return err
+//	}
+type Validator struct {
+	problems []string
+}
+
+func NewValidator() *Validator {
+	return &Validator{}
+}
+
+// Key checks an ID used as a world state key or composite key attribute. It must be valid UTF-8
+// of at most MaxKeyLength bytes without control characters, the U+0000 and U+10FFFF delimiters of
+// composite keys or the ~ used to show them.
+func (v *Validator) Key(name string, value string) {
+	switch {
+	case value == "":
+		v.addf("%s must be set", name)
+	case len(value) > MaxKeyLength:
+		v.addf("%s is longer than %d bytes", name, MaxKeyLength)
+	case !utf8.ValidString(value):
+		v.addf("%s is not valid UTF-8", name)
+	case strings.ContainsAny(value, "~\u0000\U0010FFFF"):
+		v.addf("%s must not contain ~, U+0000 or U+10FFFF", name)
+	case strings.IndexFunc(value, unicode.IsControl) >= 0:
+		v.addf("%s must not contain control characters", name)
+	}
+}
+
+// Text checks free text, which may be empty, is valid UTF-8 of at most maxLength bytes
+func (v *Validator) Text(name string, value string, maxLength int) {
+	switch {
+	case len(value) > maxLength:
+		v.addf("%s is longer than %d bytes", name, maxLength)
+	case !utf8.ValidString(value):
+		v.addf("%s is not valid UTF-8", name)
+	}
+}
+
+// Payload checks a JSON document passed as an argument or transient value is set and at most
+// MaxPayloadLength bytes. The caller unmarshals it.
+func (v *Validator) Payload(name string, value []byte) {
+	switch {
+	case len(value) == 0:
+		v.addf("%s must be set", name)
+	case len(value) > MaxPayloadLength:
+		v.addf("%s is longer than %d bytes", name, MaxPayloadLength)
+	}
+}
+
+// PositiveAmount checks an amount that must be at least 1
+func (v *Validator) PositiveAmount(name string, amount int) {
+	if amount <= 0 {
+		v.addf("%s must be a positive integer", name)
+	}
+}
+
+// Amount checks an amount that may be 0
+func (v *Validator) Amount(name string, amount int) {
+	if amount < 0 {
+		v.addf("%s must not be negative", name)
+	}
+}
+
+// OneOf checks a value that must be one of allowed, such as the object types a contract lists
+func (v *Validator) OneOf(name string, value string, allowed []string) {
+	for _, candidate := range allowed {
+		if value == candidate {
+			return
+		}
+	}
+	v.addf("%s must be one of %s", name, strings.Join(allowed, ", "))
+}
+
+// Err returns an INVALID_ARGUMENT error listing every problem found, or nil
+func (v *Validator) Err() error {
+	if len(v.problems) == 0 {
+		return nil
+	}
+	return Errorf(CodeInvalidArgument, "invalid arguments: %s", strings.Join(v.problems, "; "))
+}
+
+func (v *Validator) addf(format string, args ...interface{}) {
+	v.problems = append(v.problems, fmt.Sprintf(format, args...))
+}
+
+// NormalizeID trims the white space clients often copy along with an ID or MSP ID, so the same
+// identifier always maps to the same key
+func NormalizeID(value string) string {
+	return strings.TrimSpace(value)
+}
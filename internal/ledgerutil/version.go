@@ -0,0 +1,76 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ledgerutil
+
+import (
+	"runtime"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+)
+
+// Version and Commit identify the chaincode build. They are set when the chaincode is built, e.g.
+//
+//	go build -ldflags "-X github.com/hyperledger/fabric-samples/internal/ledgerutil.Version=1.2.0 \
+//	  -X github.com/hyperledger/fabric-samples/internal/ledgerutil.Commit=$(git rev-parse --short HEAD)"
+//
+// and keep their defaults in builds that do not set them, such as the packages the peer builds.
+var (
+	Version = "dev"
+	Commit  = "unknown"
+)
+
+// BuildInfo identifies the chaincode build a peer runs, returned by the GetVersion function of the
+// contracts
+type BuildInfo struct {
+	Contract  string `json:"contract"`
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	GoVersion string `json:"goVersion"`
+}
+
+// PingResult is returned by the Ping function of the contracts. PeerMSPID is the org of the peer that
+// answered, SchemaVersion shows the peer read its world state.
+type PingResult struct {
+	Status        string    `json:"status"`
+	Contract      string    `json:"contract"`
+	Version       string    `json:"version"`
+	PeerMSPID     string    `json:"peerMSPID"`
+	SchemaVersion int       `json:"schemaVersion"`
+	TxID          string    `json:"txID"`
+	Timestamp     time.Time `json:"timestamp"`
+}
+
+// GetBuildInfo returns the build of the chaincode running contract
+func GetBuildInfo(contract string) *BuildInfo {
+	return &BuildInfo{Contract: contract, Version: Version, Commit: Commit, GoVersion: runtime.Version()}
+}
+
+// Ping checks that the peer can run a function of contract: it resolves the peer's org, reads the
+// world state and the transaction's ID and timestamp, and writes nothing itself. Evaluated, it
+// checks the one peer the client asks; submitted, it checks every peer the endorsement policy needs.
+func Ping(stub shim.ChaincodeStubInterface, contract string) (*PingResult, error) {
+	peerMSPID, err := shim.GetMSPID()
+	if err != nil {
+		return nil, Wrap(err, "failed to get the org of the peer")
+	}
+	schemaVersion, err := GetSchemaVersion(stub)
+	if err != nil {
+		return nil, err
+	}
+	txID, timestamp, err := TxInfo(stub)
+	if err != nil {
+		return nil, err
+	}
+	return &PingResult{
+		Status:        "ok",
+		Contract:      contract,
+		Version:       Version,
+		PeerMSPID:     peerMSPID,
+		SchemaVersion: schemaVersion,
+		TxID:          txID,
+		Timestamp:     timestamp,
+	}, nil
+}
@@ -0,0 +1,132 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package metrics records Prometheus metrics of the Gateway calls, chaincode events and HTTP
+// requests of the client services, and serves them for scraping.
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-samples/internal/appclient"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// latencyBuckets cover queries of a few milliseconds up to submissions waiting for a slow commit
+var latencyBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30}
+
+var (
+	callDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "fabric",
+		Subsystem: "gateway",
+		Name:      "call_duration_seconds",
+		Help:      "Duration of Gateway calls by chaincode, function, type (submit or evaluate) and result code, OK when the call succeeded.",
+		Buckets:   latencyBuckets,
+	}, []string{"chaincode", "function", "type", "code"})
+
+	endorsementFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "fabric",
+		Subsystem: "gateway",
+		Name:      "endorsement_failures_total",
+		Help:      "Transactions the peers refused to endorse, by chaincode and function.",
+	}, []string{"chaincode", "function"})
+
+	commitFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "fabric",
+		Subsystem: "gateway",
+		Name:      "commit_failures_total",
+		Help:      "Endorsed transactions invalidated when committed, by chaincode, function and validation code, e.g. MVCC_READ_CONFLICT.",
+	}, []string{"chaincode", "function", "validation_code"})
+
+	eventsProcessed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "fabric",
+		Subsystem: "events",
+		Name:      "processed_total",
+		Help:      "Chaincode events processed, by chaincode and event name.",
+	}, []string{"chaincode", "event"})
+
+	eventsBlock = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "fabric",
+		Subsystem: "events",
+		Name:      "last_block",
+		Help:      "Block of the last chaincode event processed, by chaincode.",
+	}, []string{"chaincode"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Duration of HTTP requests by route, method and status code.",
+		Buckets: latencyBuckets,
+	}, []string{"route", "method", "status"})
+)
+
+func init() {
+	prometheus.MustRegister(callDuration, endorsementFailures, commitFailures, eventsProcessed, eventsBlock, httpDuration)
+}
+
+// ObserveCall records a Gateway call of a contract. It is an appclient.Observer, set with the
+// SetObserver method of the token and asset contracts.
+func ObserveCall(call *appclient.Call) {
+	callType := "evaluate"
+	if call.Submit {
+		callType = "submit"
+	}
+	code := "OK"
+	if call.Err != nil {
+		code = appclient.ParseError(call.Err).Code
+	}
+	callDuration.WithLabelValues(call.Chaincode, call.Function, callType, code).Observe(call.Duration.Seconds())
+
+	var endorseErr *client.EndorseError
+	if errors.As(call.Err, &endorseErr) {
+		endorsementFailures.WithLabelValues(call.Chaincode, call.Function).Inc()
+	}
+	var commitErr *client.CommitError
+	if errors.As(call.Err, &commitErr) {
+		commitFailures.WithLabelValues(call.Chaincode, call.Function, commitErr.Code.String()).Inc()
+	}
+}
+
+var _ appclient.Observer = ObserveCall
+
+// EventProcessed records a chaincode event that was processed
+func EventProcessed(chaincode string, event string, blockNumber uint64) {
+	eventsProcessed.WithLabelValues(chaincode, event).Inc()
+	eventsBlock.WithLabelValues(chaincode).Set(float64(blockNumber))
+}
+
+// ObserveHTTPRequest records an HTTP request that started at start. route must be the pattern the
+// request matched rather than its path, which would make a time series of every account and asset.
+func ObserveHTTPRequest(route string, method string, status int, start time.Time) {
+	httpDuration.WithLabelValues(route, method, strconv.Itoa(status)).Observe(time.Since(start).Seconds())
+}
+
+// StatusRecorder is a ResponseWriter recording the status code of the response, for
+// ObserveHTTPRequest
+type StatusRecorder struct {
+	http.ResponseWriter
+	Status int
+}
+
+// NewStatusRecorder returns a StatusRecorder of w, with the 200 status a handler writing no header
+// responds with
+func NewStatusRecorder(w http.ResponseWriter) *StatusRecorder {
+	return &StatusRecorder{ResponseWriter: w, Status: http.StatusOK}
+}
+
+// WriteHeader records the status code and writes it
+func (r *StatusRecorder) WriteHeader(status int) {
+	r.Status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Handler serves the metrics in the Prometheus exposition format, with the Go runtime and process
+// metrics of the default registry
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
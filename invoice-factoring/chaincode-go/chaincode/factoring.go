@@ -0,0 +1,625 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil"
+)
+
+// chaincode name used in the test network READMEs
+const defaultTokenChaincode = "token_erc20"
+
+// object names for prefix
+const (
+	invoicePrefix       = "invoice"
+	invoiceHashPrefix   = "invoiceHash"
+	bidPrefix           = "bid"
+	debtorPrefix        = "debtor"
+	debtorInvoicePrefix = "debtor~invoice"
+)
+
+// invoice status values
+const (
+	invoiceOpen      = "OPEN"
+	invoiceAccepted  = "ACCEPTED"
+	invoiceFactored  = "FACTORED"
+	invoiceSettled   = "SETTLED"
+	invoiceDefaulted = "DEFAULTED"
+)
+
+// SmartContract provides functions for financing invoices by selling them to factors
+type SmartContract struct {
+	contractapi.Contract
+}
+
+// Invoice is a receivable owed by the debtor org. It is owned by the supplier until a factor buys it.
+type Invoice struct {
+	ObjectType     string `json:"objectType"`
+	ID             string `json:"invoiceID"`
+	Hash           string `json:"hash"`
+	Amount         int    `json:"amount"`
+	DueDate        string `json:"dueDate"`
+	Supplier       string `json:"supplier"`
+	SupplierOrg    string `json:"supplierOrg"`
+	DebtorOrg      string `json:"debtorOrg"`
+	Acknowledged   bool   `json:"acknowledged"`
+	Owner          string `json:"owner"`
+	AcceptedBidder string `json:"acceptedBidder,omitempty"`
+	PurchasePrice  int    `json:"purchasePrice"`
+	SettledAt      string `json:"settledAt,omitempty"`
+	Status         string `json:"status"`
+	TokenChaincode string `json:"tokenChaincode"`
+}
+
+// Bid is a factor's offer to buy an invoice at a discount to its face amount
+type Bid struct {
+	ObjectType string `json:"objectType"`
+	InvoiceID  string `json:"invoiceID"`
+	Bidder     string `json:"bidder"`
+	BidderOrg  string `json:"bidderOrg"`
+	Price      int    `json:"price"`
+}
+
+// DebtorRecord is the settlement record of a debtor org across every invoice registered against it
+type DebtorRecord struct {
+	ObjectType    string `json:"objectType"`
+	DebtorOrg     string `json:"debtorOrg"`
+	SettledOnTime int    `json:"settledOnTime"`
+	SettledLate   int    `json:"settledLate"`
+	Defaulted     int    `json:"defaulted"`
+	AmountSettled int    `json:"amountSettled"`
+	AmountDefault int    `json:"amountDefaulted"`
+}
+
+// event provides an organized struct for emitting invoice status events
+type event struct {
+	InvoiceID string `json:"invoiceID"`
+	Owner     string `json:"owner"`
+	Status    string `json:"status"`
+	Amount    int    `json:"amount"`
+}
+
+// RegisterInvoice is called by the supplier. invoiceHash is the hex SHA-256 digest of the invoice
+// document kept off chain; an invoice with the same hash can only be registered once, so the same
+// receivable cannot be financed twice. dueDate is an RFC3339 timestamp.
+func (s *SmartContract) RegisterInvoice(ctx contractapi.TransactionContextInterface, invoiceID string, invoiceHash string, amount int, debtorOrg string, dueDate string, tokenChaincode string) error {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+
+	if invoiceID == "" || debtorOrg == "" {
+		return fmt.Errorf("invoice ID and debtor org must be set")
+	}
+	if debtorOrg == clientOrgID {
+		return fmt.Errorf("an org cannot register an invoice against itself")
+	}
+	if amount <= 0 {
+		return fmt.Errorf("invoice amount must be a positive integer")
+	}
+	hash, err := hex.DecodeString(invoiceHash)
+	if err != nil || len(hash) != 32 {
+		return fmt.Errorf("invoice hash %s is not a hex encoded SHA-256 digest", invoiceHash)
+	}
+	due, err := time.Parse(time.RFC3339, dueDate)
+	if err != nil {
+		return fmt.Errorf("due date %s is not an RFC3339 timestamp: %v", dueDate, err)
+	}
+	now, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return err
+	}
+	if !due.After(now) {
+		return fmt.Errorf("due date %s is in the past", dueDate)
+	}
+	if tokenChaincode == "" {
+		tokenChaincode = defaultTokenChaincode
+	}
+
+	existing, err := _getInvoice(ctx, invoiceID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("the invoice %s already exists", invoiceID)
+	}
+
+	hashKey, err := ctx.GetStub().CreateCompositeKey(invoiceHashPrefix, []string{invoiceHash})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	registered, err := ctx.GetStub().GetState(hashKey)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if registered != nil {
+		return fmt.Errorf("an invoice with hash %s is already registered as %s", invoiceHash, string(registered))
+	}
+	err = ctx.GetStub().PutState(hashKey, []byte(invoiceID))
+	if err != nil {
+		return fmt.Errorf("failed to put invoice hash: %v", err)
+	}
+
+	indexKey, err := ctx.GetStub().CreateCompositeKey(debtorInvoicePrefix, []string{debtorOrg, invoiceID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = ctx.GetStub().PutState(indexKey, []byte{0x00})
+	if err != nil {
+		return fmt.Errorf("failed to put invoice index: %v", err)
+	}
+
+	invoice := Invoice{
+		ObjectType:     invoicePrefix,
+		ID:             invoiceID,
+		Hash:           invoiceHash,
+		Amount:         amount,
+		DueDate:        due.UTC().Format(time.RFC3339),
+		Supplier:       clientID,
+		SupplierOrg:    clientOrgID,
+		DebtorOrg:      debtorOrg,
+		Owner:          clientID,
+		Status:         invoiceOpen,
+		TokenChaincode: tokenChaincode,
+	}
+	return _putInvoice(ctx, &invoice, amount)
+}
+
+// AcknowledgeInvoice is called by the debtor org to confirm it owes the invoice. Factors can
+// see the acknowledgement before bidding.
+func (s *SmartContract) AcknowledgeInvoice(ctx contractapi.TransactionContextInterface, invoiceID string) error {
+	invoice, err := s.ReadInvoice(ctx, invoiceID)
+	if err != nil {
+		return err
+	}
+
+	err = _requireOrg(ctx, invoice.DebtorOrg, "acknowledge invoice "+invoiceID)
+	if err != nil {
+		return err
+	}
+	if invoice.Acknowledged {
+		return fmt.Errorf("invoice %s is already acknowledged", invoiceID)
+	}
+
+	invoice.Acknowledged = true
+	return _putInvoice(ctx, invoice, invoice.Amount)
+}
+
+// PlaceBid is called by a factor to offer to buy an open invoice for price. A factor has at most
+// one bid on an invoice, bidding again replaces it.
+func (s *SmartContract) PlaceBid(ctx contractapi.TransactionContextInterface, invoiceID string, price int) error {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+
+	invoice, err := s.ReadInvoice(ctx, invoiceID)
+	if err != nil {
+		return err
+	}
+	if invoice.Status != invoiceOpen {
+		return fmt.Errorf("invoice %s is %s and does not take bids", invoiceID, invoice.Status)
+	}
+	if clientID == invoice.Supplier || clientOrgID == invoice.DebtorOrg {
+		return fmt.Errorf("the supplier and the debtor cannot bid on invoice %s", invoiceID)
+	}
+	if price <= 0 || price >= invoice.Amount {
+		return fmt.Errorf("bid price must be positive and below the invoice amount %d", invoice.Amount)
+	}
+
+	now, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return err
+	}
+	due, err := time.Parse(time.RFC3339, invoice.DueDate)
+	if err != nil {
+		return err
+	}
+	if !due.After(now) {
+		return fmt.Errorf("invoice %s is already due", invoiceID)
+	}
+
+	bid := Bid{
+		ObjectType: bidPrefix,
+		InvoiceID:  invoiceID,
+		Bidder:     clientID,
+		BidderOrg:  clientOrgID,
+		Price:      price,
+	}
+	bidJSON, err := json.Marshal(bid)
+	if err != nil {
+		return fmt.Errorf("failed to marshal bid: %v", err)
+	}
+
+	bidKey, err := ctx.GetStub().CreateCompositeKey(bidPrefix, []string{invoiceID, clientID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = ctx.GetStub().PutState(bidKey, bidJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put bid: %v", err)
+	}
+	return nil
+}
+
+// WithdrawBid removes the submitting factor's bid from an invoice that has not accepted it
+func (s *SmartContract) WithdrawBid(ctx contractapi.TransactionContextInterface, invoiceID string) error {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	invoice, err := s.ReadInvoice(ctx, invoiceID)
+	if err != nil {
+		return err
+	}
+	if invoice.Status == invoiceAccepted && invoice.AcceptedBidder == clientID {
+		return fmt.Errorf("the bid on invoice %s was accepted and can no longer be withdrawn", invoiceID)
+	}
+
+	bid, bidKey, err := _getBid(ctx, invoiceID, clientID)
+	if err != nil {
+		return err
+	}
+	if bid == nil {
+		return fmt.Errorf("no bid from the client on invoice %s", invoiceID)
+	}
+	return ctx.GetStub().DelState(bidKey)
+}
+
+// AcceptBid is called by the supplier to accept a factor's bid. The invoice stops taking bids
+// and waits for the factor to pay the bid price with PurchaseInvoice.
+func (s *SmartContract) AcceptBid(ctx contractapi.TransactionContextInterface, invoiceID string, bidder string) error {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	invoice, err := s.ReadInvoice(ctx, invoiceID)
+	if err != nil {
+		return err
+	}
+	if clientID != invoice.Supplier {
+		return fmt.Errorf("only the supplier can accept bids on invoice %s", invoiceID)
+	}
+	if invoice.Status != invoiceOpen {
+		return fmt.Errorf("invoice %s is %s and cannot accept a bid", invoiceID, invoice.Status)
+	}
+
+	bid, _, err := _getBid(ctx, invoiceID, bidder)
+	if err != nil {
+		return err
+	}
+	if bid == nil {
+		return fmt.Errorf("no bid from %s on invoice %s", bidder, invoiceID)
+	}
+
+	invoice.AcceptedBidder = bidder
+	invoice.PurchasePrice = bid.Price
+	invoice.Status = invoiceAccepted
+	return _putInvoice(ctx, invoice, bid.Price)
+}
+
+// PurchaseInvoice is called by the factor whose bid was accepted. The bid price is transferred
+// from the factor's token account to the supplier and the receivable passes to the factor.
+func (s *SmartContract) PurchaseInvoice(ctx contractapi.TransactionContextInterface, invoiceID string) error {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	invoice, err := s.ReadInvoice(ctx, invoiceID)
+	if err != nil {
+		return err
+	}
+	if invoice.Status != invoiceAccepted || clientID != invoice.AcceptedBidder {
+		return fmt.Errorf("the client has no accepted bid on invoice %s", invoiceID)
+	}
+
+	err = _transferTokens(ctx, invoice.TokenChaincode, invoice.Supplier, invoice.PurchasePrice)
+	if err != nil {
+		return err
+	}
+
+	_, bidKey, err := _getBid(ctx, invoiceID, clientID)
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().DelState(bidKey)
+	if err != nil {
+		return fmt.Errorf("failed to delete bid: %v", err)
+	}
+
+	invoice.Owner = clientID
+	invoice.Status = invoiceFactored
+	return _putInvoice(ctx, invoice, invoice.PurchasePrice)
+}
+
+// RejectAcceptedBid is called by the supplier to reopen an invoice when the accepted factor
+// does not complete the purchase. The unpaid bid is removed.
+func (s *SmartContract) RejectAcceptedBid(ctx contractapi.TransactionContextInterface, invoiceID string) error {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	invoice, err := s.ReadInvoice(ctx, invoiceID)
+	if err != nil {
+		return err
+	}
+	if clientID != invoice.Supplier {
+		return fmt.Errorf("only the supplier can reopen invoice %s", invoiceID)
+	}
+	if invoice.Status != invoiceAccepted {
+		return fmt.Errorf("invoice %s has no accepted bid", invoiceID)
+	}
+
+	_, bidKey, err := _getBid(ctx, invoiceID, invoice.AcceptedBidder)
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().DelState(bidKey)
+	if err != nil {
+		return fmt.Errorf("failed to delete bid: %v", err)
+	}
+
+	invoice.AcceptedBidder = ""
+	invoice.PurchasePrice = 0
+	invoice.Status = invoiceOpen
+	return _putInvoice(ctx, invoice, invoice.Amount)
+}
+
+// SettleInvoice is called by a client of the debtor org to pay the invoice amount to its current
+// owner, the supplier or the factor that bought it. The settlement is recorded against the
+// debtor as on time or late.
+func (s *SmartContract) SettleInvoice(ctx contractapi.TransactionContextInterface, invoiceID string) error {
+	invoice, err := s.ReadInvoice(ctx, invoiceID)
+	if err != nil {
+		return err
+	}
+
+	err = _requireOrg(ctx, invoice.DebtorOrg, "settle invoice "+invoiceID)
+	if err != nil {
+		return err
+	}
+	if invoice.Status == invoiceSettled {
+		return fmt.Errorf("invoice %s is already settled", invoiceID)
+	}
+	if invoice.Status == invoiceAccepted {
+		return fmt.Errorf("invoice %s is being sold, it can be settled once the purchase completes", invoiceID)
+	}
+
+	err = _transferTokens(ctx, invoice.TokenChaincode, invoice.Owner, invoice.Amount)
+	if err != nil {
+		return err
+	}
+
+	now, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return err
+	}
+	due, err := time.Parse(time.RFC3339, invoice.DueDate)
+	if err != nil {
+		return err
+	}
+
+	debtor, err := _getDebtorRecord(ctx, invoice.DebtorOrg)
+	if err != nil {
+		return err
+	}
+	switch {
+	case invoice.Status == invoiceDefaulted:
+		// a defaulted invoice that is paid after all moves from the default count to late
+		debtor.Defaulted--
+		debtor.AmountDefault -= invoice.Amount
+		debtor.SettledLate++
+	case now.After(due):
+		debtor.SettledLate++
+	default:
+		debtor.SettledOnTime++
+	}
+	debtor.AmountSettled += invoice.Amount
+	err = _putDebtorRecord(ctx, debtor)
+	if err != nil {
+		return err
+	}
+
+	invoice.SettledAt = now.Format(time.RFC3339)
+	invoice.Status = invoiceSettled
+	return _putInvoice(ctx, invoice, invoice.Amount)
+}
+
+// RecordDefault is called by the owner of an invoice that is past due and unpaid. The default is
+// recorded against the debtor. The debtor can still settle the invoice afterwards.
+func (s *SmartContract) RecordDefault(ctx contractapi.TransactionContextInterface, invoiceID string) error {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	invoice, err := s.ReadInvoice(ctx, invoiceID)
+	if err != nil {
+		return err
+	}
+	if clientID != invoice.Owner {
+		return fmt.Errorf("only the owner of invoice %s can record a default", invoiceID)
+	}
+	if invoice.Status != invoiceOpen && invoice.Status != invoiceFactored {
+		return fmt.Errorf("invoice %s is %s and cannot default", invoiceID, invoice.Status)
+	}
+
+	now, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return err
+	}
+	due, err := time.Parse(time.RFC3339, invoice.DueDate)
+	if err != nil {
+		return err
+	}
+	if !now.After(due) {
+		return fmt.Errorf("invoice %s is not due until %s", invoiceID, invoice.DueDate)
+	}
+
+	debtor, err := _getDebtorRecord(ctx, invoice.DebtorOrg)
+	if err != nil {
+		return err
+	}
+	debtor.Defaulted++
+	debtor.AmountDefault += invoice.Amount
+	err = _putDebtorRecord(ctx, debtor)
+	if err != nil {
+		return err
+	}
+
+	invoice.Status = invoiceDefaulted
+	return _putInvoice(ctx, invoice, invoice.Amount)
+}
+
+// _requireOrg checks the client belongs to the given org
+func _requireOrg(ctx contractapi.TransactionContextInterface, org string, action string) error {
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+	if clientMSPID != org {
+		return fmt.Errorf("client from %s is not authorized to %s", clientMSPID, action)
+	}
+	return nil
+}
+
+// _transferTokens transfers amount from the submitting client's token account to the recipient
+func _transferTokens(ctx contractapi.TransactionContextInterface, tokenChaincode string, recipient string, amount int) error {
+	args := [][]byte{[]byte("Transfer"), []byte(recipient), []byte(strconv.Itoa(amount))}
+	response := ctx.GetStub().InvokeChaincode(tokenChaincode, args, "")
+	if response.Status != shim.OK {
+		return fmt.Errorf("failed to transfer %d tokens on %s: %s", amount, tokenChaincode, response.Message)
+	}
+	return nil
+}
+
+// _getInvoice reads an invoice, returning nil when it does not exist
+func _getInvoice(ctx contractapi.TransactionContextInterface, invoiceID string) (*Invoice, error) {
+	invoiceKey, err := ctx.GetStub().CreateCompositeKey(invoicePrefix, []string{invoiceID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	invoiceJSON, err := ctx.GetStub().GetState(invoiceKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if invoiceJSON == nil {
+		return nil, nil
+	}
+
+	var invoice Invoice
+	err = json.Unmarshal(invoiceJSON, &invoice)
+	if err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+// _putInvoice writes the invoice and emits a status event for it
+func _putInvoice(ctx contractapi.TransactionContextInterface, invoice *Invoice, amount int) error {
+	invoiceKey, err := ctx.GetStub().CreateCompositeKey(invoicePrefix, []string{invoice.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	invoiceJSON, err := json.Marshal(invoice)
+	if err != nil {
+		return fmt.Errorf("failed to marshal invoice: %v", err)
+	}
+	err = ctx.GetStub().PutState(invoiceKey, invoiceJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put invoice %s: %v", invoice.ID, err)
+	}
+
+	eventJSON, err := json.Marshal(event{invoice.ID, invoice.Owner, invoice.Status, amount})
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	err = ctx.GetStub().SetEvent("InvoiceStatus", eventJSON)
+	if err != nil {
+		return fmt.Errorf("failed to set event: %v", err)
+	}
+	return nil
+}
+
+// _getBid reads a factor's bid on an invoice, returning nil when there is none
+func _getBid(ctx contractapi.TransactionContextInterface, invoiceID string, bidder string) (*Bid, string, error) {
+	bidKey, err := ctx.GetStub().CreateCompositeKey(bidPrefix, []string{invoiceID, bidder})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create composite key: %v", err)
+	}
+	bidJSON, err := ctx.GetStub().GetState(bidKey)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if bidJSON == nil {
+		return nil, bidKey, nil
+	}
+
+	var bid Bid
+	err = json.Unmarshal(bidJSON, &bid)
+	if err != nil {
+		return nil, "", err
+	}
+	return &bid, bidKey, nil
+}
+
+// _getDebtorRecord reads the settlement record of a debtor, starting an empty one for a new debtor
+func _getDebtorRecord(ctx contractapi.TransactionContextInterface, debtorOrg string) (*DebtorRecord, error) {
+	debtorKey, err := ctx.GetStub().CreateCompositeKey(debtorPrefix, []string{debtorOrg})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	debtorJSON, err := ctx.GetStub().GetState(debtorKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if debtorJSON == nil {
+		return &DebtorRecord{ObjectType: debtorPrefix, DebtorOrg: debtorOrg}, nil
+	}
+
+	var debtor DebtorRecord
+	err = json.Unmarshal(debtorJSON, &debtor)
+	if err != nil {
+		return nil, err
+	}
+	return &debtor, nil
+}
+
+// _putDebtorRecord writes the settlement record of a debtor to the world state
+func _putDebtorRecord(ctx contractapi.TransactionContextInterface, debtor *DebtorRecord) error {
+	debtorKey, err := ctx.GetStub().CreateCompositeKey(debtorPrefix, []string{debtor.DebtorOrg})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	debtorJSON, err := json.Marshal(debtor)
+	if err != nil {
+		return fmt.Errorf("failed to marshal debtor record: %v", err)
+	}
+	err = ctx.GetStub().PutState(debtorKey, debtorJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put debtor record %s: %v", debtor.DebtorOrg, err)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// ReadInvoice returns the invoice stored in the world state with the given ID
+func (s *SmartContract) ReadInvoice(ctx contractapi.TransactionContextInterface, invoiceID string) (*Invoice, error) {
+	invoice, err := _getInvoice(ctx, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	if invoice == nil {
+		return nil, fmt.Errorf("the invoice %s does not exist", invoiceID)
+	}
+	return invoice, nil
+}
+
+// GetBids returns the open bids on an invoice
+func (s *SmartContract) GetBids(ctx contractapi.TransactionContextInterface, invoiceID string) ([]*Bid, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(bidPrefix, []string{invoiceID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bids on invoice %s: %v", invoiceID, err)
+	}
+	defer resultsIterator.Close()
+
+	var bids []*Bid
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var bid Bid
+		err = json.Unmarshal(response.Value, &bid)
+		if err != nil {
+			return nil, err
+		}
+		bids = append(bids, &bid)
+	}
+
+	return bids, nil
+}
+
+// GetInvoicesByDebtor returns every invoice registered against a debtor org
+func (s *SmartContract) GetInvoicesByDebtor(ctx contractapi.TransactionContextInterface, debtorOrg string) ([]*Invoice, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(debtorInvoicePrefix, []string{debtorOrg})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invoices of %s: %v", debtorOrg, err)
+	}
+	defer resultsIterator.Close()
+
+	var invoices []*Invoice
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(response.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split composite key: %v", err)
+		}
+		if len(keyParts) != 2 {
+			return nil, fmt.Errorf("unexpected index key %s", response.Key)
+		}
+
+		invoice, err := s.ReadInvoice(ctx, keyParts[1])
+		if err != nil {
+			return nil, err
+		}
+		invoices = append(invoices, invoice)
+	}
+
+	return invoices, nil
+}
+
+// GetDebtorRecord returns the settlement record of a debtor org
+func (s *SmartContract) GetDebtorRecord(ctx contractapi.TransactionContextInterface, debtorOrg string) (*DebtorRecord, error) {
+	return _getDebtorRecord(ctx, debtorOrg)
+}
@@ -0,0 +1,169 @@
+package chaincode
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+)
+
+const (
+	supplierMSPID = "Org1MSP"
+	debtorMSPID   = "Org2MSP"
+	factorMSPID   = "Org3MSP"
+	dueDate       = "2020-09-13T13:00:00Z"
+)
+
+var invoiceHash = strings.Repeat("ab", 32)
+
+// fakeToken stands in for the token chaincode. Transfers are paid from the payer's account.
+type fakeToken struct {
+	payer    string
+	balances map[string]int
+}
+
+func (f *fakeToken) invoke(args [][]byte) pb.Response {
+	if string(args[0]) != "Transfer" {
+		return shim.Error("unexpected function " + string(args[0]))
+	}
+	amount, err := strconv.Atoi(string(args[2]))
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if f.balances[f.payer] < amount {
+		return shim.Error(fmt.Sprintf("client account %s has insufficient funds", f.payer))
+	}
+	f.balances[f.payer] -= amount
+	f.balances[string(args[1])] += amount
+	return shim.Success(nil)
+}
+
+func checkError(t *testing.T, err error, expected string) {
+	t.Helper()
+	if expected == "" && err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected != "" && (err == nil || !strings.Contains(err.Error(), expected)) {
+		t.Fatalf("expected error containing %q, got %v", expected, err)
+	}
+}
+
+func checkInvoice(t *testing.T, stub *fakeStub, status string, owner string) {
+	t.Helper()
+	invoice, err := new(SmartContract).ReadInvoice(newContext(stub, "supplier", supplierMSPID), "inv1")
+	if err != nil {
+		t.Fatalf("failed to read invoice: %v", err)
+	}
+	if invoice.Status != status || invoice.Owner != owner {
+		t.Fatalf("expected invoice %s owned by %s, got %s owned by %s", status, owner, invoice.Status, invoice.Owner)
+	}
+}
+
+// registerInvoice has the supplier register inv1 of 1000 owed by Org2 and the factor bid 950 on it
+func registerInvoice(t *testing.T, stub *fakeStub) *fakeToken {
+	t.Helper()
+	token := &fakeToken{balances: map[string]int{"factor": 950, "debtor": 1000}}
+	stub.chaincodes[defaultTokenChaincode] = token.invoke
+
+	contract := new(SmartContract)
+	checkError(t, contract.RegisterInvoice(newContext(stub, "supplier", supplierMSPID), "inv1", invoiceHash, 1000, debtorMSPID, dueDate, ""), "")
+	checkError(t, contract.AcknowledgeInvoice(newContext(stub, "debtor", debtorMSPID), "inv1"), "")
+	checkError(t, contract.PlaceBid(newContext(stub, "factor", factorMSPID), "inv1", 950), "")
+	return token
+}
+
+func TestRegisterInvoice(t *testing.T) {
+	tests := []struct {
+		name      string
+		invoiceID string
+		hash      string
+		debtorOrg string
+		dueDate   string
+		expected  string
+	}{
+		{"invoice", "inv2", strings.Repeat("cd", 32), debtorMSPID, dueDate, ""},
+		{"own org", "inv2", strings.Repeat("cd", 32), supplierMSPID, dueDate, "an org cannot register an invoice against itself"},
+		{"past due date", "inv2", strings.Repeat("cd", 32), debtorMSPID, "2020-01-01T00:00:00Z", "due date 2020-01-01T00:00:00Z is in the past"},
+		{"financed twice", "inv2", invoiceHash, debtorMSPID, dueDate, "an invoice with hash " + invoiceHash + " is already registered as inv1"},
+		{"existing invoice", "inv1", strings.Repeat("cd", 32), debtorMSPID, dueDate, "the invoice inv1 already exists"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stub := newFakeStub()
+			registerInvoice(t, stub)
+
+			err := new(SmartContract).RegisterInvoice(newContext(stub, "supplier", supplierMSPID), test.invoiceID, test.hash, 1000, test.debtorOrg, test.dueDate, "")
+			checkError(t, err, test.expected)
+		})
+	}
+}
+
+func TestFactorAndSettle(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	token := registerInvoice(t, stub)
+
+	err := contract.PlaceBid(newContext(stub, "debtor", debtorMSPID), "inv1", 900)
+	checkError(t, err, "the supplier and the debtor cannot bid on invoice inv1")
+	err = contract.AcceptBid(newContext(stub, "factor", factorMSPID), "inv1", "factor")
+	checkError(t, err, "only the supplier can accept bids on invoice inv1")
+	checkError(t, contract.AcceptBid(newContext(stub, "supplier", supplierMSPID), "inv1", "factor"), "")
+
+	err = contract.PurchaseInvoice(newContext(stub, "other", factorMSPID), "inv1")
+	checkError(t, err, "the client has no accepted bid on invoice inv1")
+	token.payer = "factor"
+	checkError(t, contract.PurchaseInvoice(newContext(stub, "factor", factorMSPID), "inv1"), "")
+	checkInvoice(t, stub, invoiceFactored, "factor")
+	if token.balances["supplier"] != 950 {
+		t.Fatalf("unexpected balances %v", token.balances)
+	}
+
+	// the debtor pays the full amount to the factor
+	err = contract.SettleInvoice(newContext(stub, "factor", factorMSPID), "inv1")
+	checkError(t, err, "client from Org3MSP is not authorized to settle invoice inv1")
+	token.payer = "debtor"
+	checkError(t, contract.SettleInvoice(newContext(stub, "debtor", debtorMSPID), "inv1"), "")
+	checkInvoice(t, stub, invoiceSettled, "factor")
+	if token.balances["factor"] != 1000 || token.balances["debtor"] != 0 {
+		t.Fatalf("unexpected balances %v", token.balances)
+	}
+
+	record, err := contract.GetDebtorRecord(newContext(stub, "factor", factorMSPID), debtorMSPID)
+	checkError(t, err, "")
+	if record.SettledOnTime != 1 || record.AmountSettled != 1000 {
+		t.Fatalf("unexpected debtor record %+v", record)
+	}
+}
+
+func TestPurchaseInsufficientFunds(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	token := registerInvoice(t, stub)
+	checkError(t, contract.AcceptBid(newContext(stub, "supplier", supplierMSPID), "inv1", "factor"), "")
+
+	token.payer = "factor"
+	token.balances["factor"] = 949
+	err := contract.PurchaseInvoice(newContext(stub, "factor", factorMSPID), "inv1")
+	checkError(t, err, "failed to transfer 950 tokens on token_erc20: client account factor has insufficient funds")
+	checkInvoice(t, stub, invoiceAccepted, "supplier")
+
+	// the supplier reopens the invoice and the debtor defaults on it
+	checkError(t, contract.RejectAcceptedBid(newContext(stub, "supplier", supplierMSPID), "inv1"), "")
+	checkInvoice(t, stub, invoiceOpen, "supplier")
+
+	err = contract.RecordDefault(newContext(stub, "supplier", supplierMSPID), "inv1")
+	checkError(t, err, "invoice inv1 is not due until "+dueDate)
+	stub.txCount += 3600
+	checkError(t, contract.RecordDefault(newContext(stub, "supplier", supplierMSPID), "inv1"), "")
+
+	token.payer = "debtor"
+	checkError(t, contract.SettleInvoice(newContext(stub, "debtor", debtorMSPID), "inv1"), "")
+	record, err := contract.GetDebtorRecord(newContext(stub, "factor", factorMSPID), debtorMSPID)
+	checkError(t, err, "")
+	if record.Defaulted != 0 || record.SettledLate != 1 || record.AmountDefault != 0 {
+		t.Fatalf("unexpected debtor record %+v", record)
+	}
+}
@@ -0,0 +1,23 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"log"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/invoice-factoring/chaincode-go/chaincode"
+)
+
+func main() {
+	factoringChaincode, err := contractapi.NewChaincode(&chaincode.SmartContract{})
+	if err != nil {
+		log.Panicf("Error creating invoice-factoring chaincode: %v", err)
+	}
+
+	if err := factoringChaincode.Start(); err != nil {
+		log.Panicf("Error starting invoice-factoring chaincode: %v", err)
+	}
+}
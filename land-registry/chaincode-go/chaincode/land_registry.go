@@ -0,0 +1,428 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// This sample assumes Org1 plays the land registry office. Only the registrar can
+// register parcels, endorse ownership transfers and record encumbrances.
+const registrarMSPID = "Org1MSP"
+
+// object names for prefix
+const (
+	transferRequestPrefix = "transferRequest"
+	encumbrancePrefix     = "encumbrance"
+)
+
+// parcel and encumbrance status values
+const (
+	statusRegistered = "REGISTERED"
+	statusPending    = "TRANSFER_PENDING"
+
+	encumbranceActive     = "ACTIVE"
+	encumbranceDischarged = "DISCHARGED"
+)
+
+// minimum length of a legal description, anything shorter cannot identify land
+const minLegalDescriptionLength = 10
+
+// SmartContract provides functions for registering land parcels and their ownership
+type SmartContract struct {
+	contractapi.Contract
+}
+
+// Parcel is the public title record for a piece of land
+type Parcel struct {
+	ObjectType       string `json:"objectType"`
+	ID               string `json:"parcelID"`
+	TitleNumber      string `json:"titleNumber"`
+	LegalDescription string `json:"legalDescription"`
+	AreaSqm          int    `json:"areaSqm"`
+	OwnerOrg         string `json:"ownerOrg"`
+	Status           string `json:"status"`
+}
+
+// TransferRequest is raised by the current owner and waits for registrar endorsement
+type TransferRequest struct {
+	ObjectType string `json:"objectType"`
+	ParcelID   string `json:"parcelID"`
+	SellerOrg  string `json:"sellerOrg"`
+	BuyerOrg   string `json:"buyerOrg"`
+	Price      int    `json:"price"`
+	TxID       string `json:"txID"`
+}
+
+// Encumbrance is a mortgage, charge or easement registered against a parcel
+type Encumbrance struct {
+	ObjectType string `json:"objectType"`
+	ID         string `json:"encumbranceID"`
+	ParcelID   string `json:"parcelID"`
+	Kind       string `json:"kind"`
+	HolderOrg  string `json:"holderOrg"`
+	Amount     int    `json:"amount"`
+	Status     string `json:"status"`
+}
+
+// event provides an organized struct for emitting ownership events
+type event struct {
+	ParcelID string `json:"parcelID"`
+	From     string `json:"from"`
+	To       string `json:"to"`
+}
+
+// encumbrance kinds the registry accepts
+var encumbranceKinds = map[string]bool{
+	"MORTGAGE": true,
+	"CHARGE":   true,
+	"EASEMENT": true,
+	"LIEN":     true,
+}
+
+// RegisterParcel records a new parcel on the register with the given org as first owner.
+// Only the registrar can register parcels.
+func (s *SmartContract) RegisterParcel(ctx contractapi.TransactionContextInterface, parcelID string, titleNumber string, legalDescription string, areaSqm int, ownerOrg string) error {
+	err := _requireRegistrar(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = _validateParcel(parcelID, titleNumber, legalDescription, areaSqm, ownerOrg)
+	if err != nil {
+		return err
+	}
+
+	exists, err := s.ParcelExists(ctx, parcelID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("the parcel %s already exists", parcelID)
+	}
+
+	parcel := Parcel{
+		ObjectType:       "parcel",
+		ID:               parcelID,
+		TitleNumber:      titleNumber,
+		LegalDescription: strings.TrimSpace(legalDescription),
+		AreaSqm:          areaSqm,
+		OwnerOrg:         ownerOrg,
+		Status:           statusRegistered,
+	}
+
+	return _putParcel(ctx, &parcel)
+}
+
+// RequestTransfer is called by the current owner to propose selling the parcel to buyerOrg.
+// The parcel is locked until the registrar approves or rejects the request.
+func (s *SmartContract) RequestTransfer(ctx contractapi.TransactionContextInterface, parcelID string, buyerOrg string, price int) error {
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+
+	parcel, err := s.ReadParcel(ctx, parcelID)
+	if err != nil {
+		return err
+	}
+
+	if clientOrgID != parcel.OwnerOrg {
+		return fmt.Errorf("a client from %s cannot transfer a parcel owned by %s", clientOrgID, parcel.OwnerOrg)
+	}
+	if parcel.Status != statusRegistered {
+		return fmt.Errorf("parcel %s already has a transfer pending", parcelID)
+	}
+	if buyerOrg == "" || buyerOrg == parcel.OwnerOrg {
+		return fmt.Errorf("buyer org must be set and differ from the current owner")
+	}
+	if price < 0 {
+		return fmt.Errorf("price must not be negative")
+	}
+
+	request := TransferRequest{
+		ObjectType: transferRequestPrefix,
+		ParcelID:   parcelID,
+		SellerOrg:  parcel.OwnerOrg,
+		BuyerOrg:   buyerOrg,
+		Price:      price,
+		TxID:       ctx.GetStub().GetTxID(),
+	}
+	requestJSON, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to marshal transfer request: %v", err)
+	}
+
+	requestKey, err := ctx.GetStub().CreateCompositeKey(transferRequestPrefix, []string{parcelID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = ctx.GetStub().PutState(requestKey, requestJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put transfer request: %v", err)
+	}
+
+	parcel.Status = statusPending
+	return _putParcel(ctx, parcel)
+}
+
+// ApproveTransfer is the registrar endorsement of a pending transfer. Ownership moves to the
+// buyer only if no active encumbrance is still registered against the parcel.
+func (s *SmartContract) ApproveTransfer(ctx contractapi.TransactionContextInterface, parcelID string) error {
+	err := _requireRegistrar(ctx)
+	if err != nil {
+		return err
+	}
+
+	parcel, err := s.ReadParcel(ctx, parcelID)
+	if err != nil {
+		return err
+	}
+
+	request, requestKey, err := _getTransferRequest(ctx, parcelID)
+	if err != nil {
+		return err
+	}
+
+	encumbrances, err := s.GetEncumbrances(ctx, parcelID)
+	if err != nil {
+		return err
+	}
+	for _, encumbrance := range encumbrances {
+		if encumbrance.Status == encumbranceActive && encumbrance.Kind != "EASEMENT" {
+			return fmt.Errorf("parcel %s has an active %s %s held by %s, it must be discharged before transfer",
+				parcelID, strings.ToLower(encumbrance.Kind), encumbrance.ID, encumbrance.HolderOrg)
+		}
+	}
+
+	parcel.OwnerOrg = request.BuyerOrg
+	parcel.Status = statusRegistered
+	err = _putParcel(ctx, parcel)
+	if err != nil {
+		return err
+	}
+
+	err = ctx.GetStub().DelState(requestKey)
+	if err != nil {
+		return fmt.Errorf("failed to delete transfer request: %v", err)
+	}
+
+	return _emitEvent(ctx, "Transfer", event{parcelID, request.SellerOrg, request.BuyerOrg})
+}
+
+// RejectTransfer cancels a pending transfer. It can be called by the registrar, or by the
+// seller to withdraw their own request.
+func (s *SmartContract) RejectTransfer(ctx contractapi.TransactionContextInterface, parcelID string) error {
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+
+	parcel, err := s.ReadParcel(ctx, parcelID)
+	if err != nil {
+		return err
+	}
+
+	if clientOrgID != registrarMSPID && clientOrgID != parcel.OwnerOrg {
+		return fmt.Errorf("client from %s is not authorized to reject the transfer of parcel %s", clientOrgID, parcelID)
+	}
+
+	_, requestKey, err := _getTransferRequest(ctx, parcelID)
+	if err != nil {
+		return err
+	}
+
+	err = ctx.GetStub().DelState(requestKey)
+	if err != nil {
+		return fmt.Errorf("failed to delete transfer request: %v", err)
+	}
+
+	parcel.Status = statusRegistered
+	return _putParcel(ctx, parcel)
+}
+
+// RegisterEncumbrance records a mortgage, charge, lien or easement against a parcel.
+// Only the registrar can register encumbrances.
+func (s *SmartContract) RegisterEncumbrance(ctx contractapi.TransactionContextInterface, parcelID string, encumbranceID string, kind string, holderOrg string, amount int) error {
+	err := _requireRegistrar(ctx)
+	if err != nil {
+		return err
+	}
+
+	kind = strings.ToUpper(kind)
+	if !encumbranceKinds[kind] {
+		return fmt.Errorf("unknown encumbrance kind %s", kind)
+	}
+	if encumbranceID == "" || holderOrg == "" {
+		return fmt.Errorf("encumbrance ID and holder org must be set")
+	}
+	if amount < 0 || (kind == "MORTGAGE" && amount == 0) {
+		return fmt.Errorf("invalid secured amount %d for %s", amount, strings.ToLower(kind))
+	}
+
+	exists, err := s.ParcelExists(ctx, parcelID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("the parcel %s does not exist", parcelID)
+	}
+
+	encumbranceKey, err := ctx.GetStub().CreateCompositeKey(encumbrancePrefix, []string{parcelID, encumbranceID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	existing, err := ctx.GetStub().GetState(encumbranceKey)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if existing != nil {
+		return fmt.Errorf("encumbrance %s already exists on parcel %s", encumbranceID, parcelID)
+	}
+
+	encumbrance := Encumbrance{
+		ObjectType: encumbrancePrefix,
+		ID:         encumbranceID,
+		ParcelID:   parcelID,
+		Kind:       kind,
+		HolderOrg:  holderOrg,
+		Amount:     amount,
+		Status:     encumbranceActive,
+	}
+	encumbranceJSON, err := json.Marshal(encumbrance)
+	if err != nil {
+		return fmt.Errorf("failed to marshal encumbrance: %v", err)
+	}
+
+	return ctx.GetStub().PutState(encumbranceKey, encumbranceJSON)
+}
+
+// DischargeEncumbrance releases an encumbrance once it is paid off or no longer applies.
+// It can be called by the registrar or the org holding the encumbrance.
+func (s *SmartContract) DischargeEncumbrance(ctx contractapi.TransactionContextInterface, parcelID string, encumbranceID string) error {
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+
+	encumbranceKey, err := ctx.GetStub().CreateCompositeKey(encumbrancePrefix, []string{parcelID, encumbranceID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	encumbranceJSON, err := ctx.GetStub().GetState(encumbranceKey)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if encumbranceJSON == nil {
+		return fmt.Errorf("encumbrance %s does not exist on parcel %s", encumbranceID, parcelID)
+	}
+
+	var encumbrance Encumbrance
+	err = json.Unmarshal(encumbranceJSON, &encumbrance)
+	if err != nil {
+		return err
+	}
+
+	if clientOrgID != registrarMSPID && clientOrgID != encumbrance.HolderOrg {
+		return fmt.Errorf("client from %s is not authorized to discharge encumbrance %s", clientOrgID, encumbranceID)
+	}
+	if encumbrance.Status == encumbranceDischarged {
+		return fmt.Errorf("encumbrance %s is already discharged", encumbranceID)
+	}
+
+	encumbrance.Status = encumbranceDischarged
+	encumbranceJSON, err = json.Marshal(encumbrance)
+	if err != nil {
+		return fmt.Errorf("failed to marshal encumbrance: %v", err)
+	}
+
+	return ctx.GetStub().PutState(encumbranceKey, encumbranceJSON)
+}
+
+// _requireRegistrar checks the submitting client belongs to the registrar org
+func _requireRegistrar(ctx contractapi.TransactionContextInterface) error {
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+	if clientMSPID != registrarMSPID {
+		return fmt.Errorf("client from %s is not the land registrar", clientMSPID)
+	}
+	return nil
+}
+
+// _validateParcel applies the registry rules for a new title
+func _validateParcel(parcelID string, titleNumber string, legalDescription string, areaSqm int, ownerOrg string) error {
+	if parcelID == "" {
+		return fmt.Errorf("parcel ID must be set")
+	}
+	if titleNumber == "" || strings.ContainsAny(titleNumber, " \t\n") {
+		return fmt.Errorf("title number %q is not valid", titleNumber)
+	}
+	if len(strings.TrimSpace(legalDescription)) < minLegalDescriptionLength {
+		return fmt.Errorf("legal description for parcel %s is too short to identify the land", parcelID)
+	}
+	if areaSqm <= 0 {
+		return fmt.Errorf("parcel area must be a positive number of square metres")
+	}
+	if ownerOrg == "" {
+		return fmt.Errorf("owner org must be set")
+	}
+	return nil
+}
+
+// _getTransferRequest reads the pending transfer request for a parcel
+func _getTransferRequest(ctx contractapi.TransactionContextInterface, parcelID string) (*TransferRequest, string, error) {
+	requestKey, err := ctx.GetStub().CreateCompositeKey(transferRequestPrefix, []string{parcelID})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	requestJSON, err := ctx.GetStub().GetState(requestKey)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if requestJSON == nil {
+		return nil, "", fmt.Errorf("parcel %s has no pending transfer", parcelID)
+	}
+
+	var request TransferRequest
+	err = json.Unmarshal(requestJSON, &request)
+	if err != nil {
+		return nil, "", err
+	}
+	return &request, requestKey, nil
+}
+
+// _putParcel writes the parcel to the world state
+func _putParcel(ctx contractapi.TransactionContextInterface, parcel *Parcel) error {
+	parcelJSON, err := json.Marshal(parcel)
+	if err != nil {
+		return fmt.Errorf("failed to marshal parcel: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(parcel.ID, parcelJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put parcel %s: %v", parcel.ID, err)
+	}
+	return nil
+}
+
+// _emitEvent marshals the payload and sets it as the chaincode event
+func _emitEvent(ctx contractapi.TransactionContextInterface, name string, payload interface{}) error {
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	err = ctx.GetStub().SetEvent(name, payloadJSON)
+	if err != nil {
+		return fmt.Errorf("failed to set event: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// QueryResult structure used for handling result of history query
+type QueryResult struct {
+	Record    *Parcel
+	TxId      string    `json:"txId"`
+	Timestamp time.Time `json:"timestamp"`
+	IsDelete  bool      `json:"isDelete"`
+}
+
+// ReadParcel returns the public title record for a parcel
+func (s *SmartContract) ReadParcel(ctx contractapi.TransactionContextInterface, parcelID string) (*Parcel, error) {
+	parcelJSON, err := ctx.GetStub().GetState(parcelID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if parcelJSON == nil {
+		return nil, fmt.Errorf("the parcel %s does not exist", parcelID)
+	}
+
+	var parcel Parcel
+	err = json.Unmarshal(parcelJSON, &parcel)
+	if err != nil {
+		return nil, err
+	}
+	return &parcel, nil
+}
+
+// ParcelExists returns true when a parcel with the given ID is on the register
+func (s *SmartContract) ParcelExists(ctx contractapi.TransactionContextInterface, parcelID string) (bool, error) {
+	parcelJSON, err := ctx.GetStub().GetState(parcelID)
+	if err != nil {
+		return false, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	return parcelJSON != nil, nil
+}
+
+// GetPendingTransfer returns the transfer request waiting for registrar endorsement
+func (s *SmartContract) GetPendingTransfer(ctx contractapi.TransactionContextInterface, parcelID string) (*TransferRequest, error) {
+	request, _, err := _getTransferRequest(ctx, parcelID)
+	return request, err
+}
+
+// GetEncumbrances returns every encumbrance, active or discharged, registered against a parcel
+func (s *SmartContract) GetEncumbrances(ctx contractapi.TransactionContextInterface, parcelID string) ([]*Encumbrance, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(encumbrancePrefix, []string{parcelID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get encumbrances for parcel %s: %v", parcelID, err)
+	}
+	defer resultsIterator.Close()
+
+	var encumbrances []*Encumbrance
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var encumbrance Encumbrance
+		err = json.Unmarshal(response.Value, &encumbrance)
+		if err != nil {
+			return nil, err
+		}
+		encumbrances = append(encumbrances, &encumbrance)
+	}
+
+	return encumbrances, nil
+}
+
+// QueryParcelHistory returns the cadastral history of a parcel since it was first registered
+func (s *SmartContract) QueryParcelHistory(ctx contractapi.TransactionContextInterface, parcelID string) ([]QueryResult, error) {
+	resultsIterator, err := ctx.GetStub().GetHistoryForKey(parcelID)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var results []QueryResult
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var parcel *Parcel
+		if !response.IsDelete {
+			err = json.Unmarshal(response.Value, &parcel)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		timestamp, err := ptypes.Timestamp(response.Timestamp)
+		if err != nil {
+			return nil, err
+		}
+		record := QueryResult{
+			TxId:      response.TxId,
+			Timestamp: timestamp,
+			Record:    parcel,
+			IsDelete:  response.IsDelete,
+		}
+		results = append(results, record)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,164 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const (
+	sellerMSPID = "Org2MSP"
+	buyerMSPID  = "Org3MSP"
+	bankMSPID   = "Org4MSP"
+)
+
+// registerParcel puts a parcel owned by the seller on the register
+func registerParcel(t *testing.T, stub *fakeStub) {
+	t.Helper()
+	err := new(SmartContract).RegisterParcel(newContext(stub, registrarMSPID), "parcel1", "TN-0001", "Lot 7, Riverside Estate", 500, sellerMSPID)
+	if err != nil {
+		t.Fatalf("failed to register parcel: %v", err)
+	}
+}
+
+func checkError(t *testing.T, err error, expected string) {
+	t.Helper()
+	if expected == "" && err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected != "" && (err == nil || err.Error() != expected) {
+		t.Fatalf("expected error %q, got %v", expected, err)
+	}
+}
+
+func checkParcel(t *testing.T, stub *fakeStub, ownerOrg string, status string) {
+	t.Helper()
+	parcel, err := new(SmartContract).ReadParcel(newContext(stub, registrarMSPID), "parcel1")
+	if err != nil {
+		t.Fatalf("failed to read parcel: %v", err)
+	}
+	if parcel.OwnerOrg != ownerOrg || parcel.Status != status {
+		t.Fatalf("expected parcel owned by %s with status %s, got %s %s", ownerOrg, status, parcel.OwnerOrg, parcel.Status)
+	}
+}
+
+func TestRegisterParcel(t *testing.T) {
+	tests := []struct {
+		name     string
+		mspID    string
+		parcelID string
+		title    string
+		area     int
+		expected string
+	}{
+		{"registrar", registrarMSPID, "parcel2", "TN-0002", 300, ""},
+		{"not registrar", sellerMSPID, "parcel2", "TN-0002", 300, "client from Org2MSP is not the land registrar"},
+		{"already registered", registrarMSPID, "parcel1", "TN-0002", 300, "the parcel parcel1 already exists"},
+		{"no area", registrarMSPID, "parcel2", "TN-0002", 0, "parcel area must be a positive number of square metres"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stub := newFakeStub()
+			registerParcel(t, stub)
+
+			err := new(SmartContract).RegisterParcel(newContext(stub, test.mspID), test.parcelID, test.title, "Lot 8, Riverside Estate", test.area, sellerMSPID)
+			checkError(t, err, test.expected)
+		})
+	}
+}
+
+func TestTransfer(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	registerParcel(t, stub)
+
+	err := contract.RequestTransfer(newContext(stub, buyerMSPID), "parcel1", buyerMSPID, 1000)
+	checkError(t, err, "a client from Org3MSP cannot transfer a parcel owned by Org2MSP")
+
+	err = contract.RequestTransfer(newContext(stub, sellerMSPID), "parcel1", buyerMSPID, 1000)
+	checkError(t, err, "")
+	checkParcel(t, stub, sellerMSPID, statusPending)
+
+	err = contract.RequestTransfer(newContext(stub, sellerMSPID), "parcel1", bankMSPID, 1000)
+	checkError(t, err, "parcel parcel1 already has a transfer pending")
+
+	err = contract.ApproveTransfer(newContext(stub, sellerMSPID), "parcel1")
+	checkError(t, err, "client from Org2MSP is not the land registrar")
+
+	err = contract.ApproveTransfer(newContext(stub, registrarMSPID), "parcel1")
+	checkError(t, err, "")
+	checkParcel(t, stub, buyerMSPID, statusRegistered)
+
+	if stub.eventName != "Transfer" {
+		t.Fatalf("expected Transfer event, got %q", stub.eventName)
+	}
+	var transferEvent event
+	err = json.Unmarshal(stub.event, &transferEvent)
+	if err != nil {
+		t.Fatalf("failed to unmarshal event: %v", err)
+	}
+	if transferEvent != (event{"parcel1", sellerMSPID, buyerMSPID}) {
+		t.Fatalf("unexpected event %+v", transferEvent)
+	}
+
+	_, err = contract.GetPendingTransfer(newContext(stub, registrarMSPID), "parcel1")
+	checkError(t, err, "parcel parcel1 has no pending transfer")
+
+	history, err := contract.QueryParcelHistory(newContext(stub, registrarMSPID), "parcel1")
+	checkError(t, err, "")
+	if len(history) != 3 {
+		t.Fatalf("expected 3 history records, got %d", len(history))
+	}
+}
+
+func TestRejectTransfer(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	registerParcel(t, stub)
+
+	err := contract.RequestTransfer(newContext(stub, sellerMSPID), "parcel1", buyerMSPID, 1000)
+	checkError(t, err, "")
+
+	err = contract.RejectTransfer(newContext(stub, buyerMSPID), "parcel1")
+	checkError(t, err, "client from Org3MSP is not authorized to reject the transfer of parcel parcel1")
+
+	err = contract.RejectTransfer(newContext(stub, sellerMSPID), "parcel1")
+	checkError(t, err, "")
+	checkParcel(t, stub, sellerMSPID, statusRegistered)
+}
+
+func TestEncumbranceBlocksTransfer(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	registerParcel(t, stub)
+
+	err := contract.RegisterEncumbrance(newContext(stub, registrarMSPID), "parcel1", "mortgage1", "MORTGAGE", bankMSPID, 800)
+	checkError(t, err, "")
+	err = contract.RegisterEncumbrance(newContext(stub, registrarMSPID), "parcel1", "easement1", "EASEMENT", buyerMSPID, 0)
+	checkError(t, err, "")
+
+	err = contract.RequestTransfer(newContext(stub, sellerMSPID), "parcel1", buyerMSPID, 1000)
+	checkError(t, err, "")
+
+	err = contract.ApproveTransfer(newContext(stub, registrarMSPID), "parcel1")
+	checkError(t, err, "parcel parcel1 has an active mortgage mortgage1 held by Org4MSP, it must be discharged before transfer")
+
+	err = contract.DischargeEncumbrance(newContext(stub, buyerMSPID), "parcel1", "mortgage1")
+	checkError(t, err, "client from Org3MSP is not authorized to discharge encumbrance mortgage1")
+
+	err = contract.DischargeEncumbrance(newContext(stub, bankMSPID), "parcel1", "mortgage1")
+	checkError(t, err, "")
+
+	err = contract.DischargeEncumbrance(newContext(stub, bankMSPID), "parcel1", "mortgage1")
+	checkError(t, err, "encumbrance mortgage1 is already discharged")
+
+	// an easement runs with the land and does not block the transfer
+	err = contract.ApproveTransfer(newContext(stub, registrarMSPID), "parcel1")
+	checkError(t, err, "")
+	checkParcel(t, stub, buyerMSPID, statusRegistered)
+
+	encumbrances, err := contract.GetEncumbrances(newContext(stub, registrarMSPID), "parcel1")
+	checkError(t, err, "")
+	if len(encumbrances) != 2 || encumbrances[0].Status != encumbranceActive || encumbrances[1].Status != encumbranceDischarged {
+		t.Fatalf("unexpected encumbrances %+v", encumbrances)
+	}
+}
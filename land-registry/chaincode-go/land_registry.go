@@ -0,0 +1,23 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"log"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/land-registry/chaincode-go/chaincode"
+)
+
+func main() {
+	landChaincode, err := contractapi.NewChaincode(&chaincode.SmartContract{})
+	if err != nil {
+		log.Panicf("Error creating land-registry chaincode: %v", err)
+	}
+
+	if err := landChaincode.Start(); err != nil {
+		log.Panicf("Error starting land-registry chaincode: %v", err)
+	}
+}
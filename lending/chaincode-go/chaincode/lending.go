@@ -0,0 +1,784 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil"
+)
+
+// chaincode names used in the test network READMEs
+const (
+	assetChaincode = "secured"
+	tokenChaincode = "token_erc20"
+)
+
+// This sample assumes Org1 operates the lending pool: it appoints the pool agent, sets rates and
+// appraises collateral
+const poolOperatorMSPID = "Org1MSP"
+
+// Define key names for options
+const poolKey = "pool"
+
+// object names for prefix
+const (
+	depositPrefix    = "deposit"
+	loanPrefix       = "loan"
+	collateralPrefix = "collateral"
+	payoutPrefix     = "payout"
+)
+
+// loan and payout status values
+const (
+	statusActive     = "ACTIVE"
+	statusRepaid     = "REPAID"
+	statusLiquidated = "LIQUIDATED"
+	statusPending    = "PENDING"
+	statusPaid       = "PAID"
+)
+
+// Risk parameters in basis points of the collateral value. A loan can be opened up to maxLoanToValueBps
+// and can be liquidated once its debt exceeds liquidationThresholdBps.
+const (
+	maxLoanToValueBps       = 6000
+	liquidationThresholdBps = 8000
+	basisPoints             = 10000
+	secondsPerYear          = 365 * 24 * 60 * 60
+)
+
+// SmartContract provides functions for a token lending pool collateralized by assets
+type SmartContract struct {
+	contractapi.Contract
+}
+
+// Pool holds the lending pool configuration and totals. Deposited tokens are held in the account
+// of the pool agent, which submits the payout transactions, since a chaincode cannot hold tokens.
+type Pool struct {
+	Agent          string `json:"agent"`
+	SupplyRateBps  int    `json:"supplyRateBps"`
+	BorrowRateBps  int    `json:"borrowRateBps"`
+	TotalDeposits  int    `json:"totalDeposits"`
+	TotalBorrowed  int    `json:"totalBorrowed"`
+	PendingPayouts int    `json:"pendingPayouts"`
+}
+
+// Deposit is an account's balance in the pool, including interest accrued up to LastAccrued
+type Deposit struct {
+	Account     string `json:"account"`
+	Balance     int    `json:"balance"`
+	LastAccrued int64  `json:"lastAccrued"`
+}
+
+// Loan is a token loan secured by an asset of the asset transfer chaincode
+type Loan struct {
+	ObjectType  string `json:"objectType"`
+	ID          string `json:"loanID"`
+	Borrower    string `json:"borrower"`
+	BorrowerOrg string `json:"borrowerOrg"`
+	AssetID     string `json:"assetID"`
+	Principal   int    `json:"principal"`
+	Debt        int    `json:"debt"`
+	RateBps     int    `json:"rateBps"`
+	LastAccrued int64  `json:"lastAccrued"`
+	Liquidator  string `json:"liquidator"`
+	Status      string `json:"status"`
+}
+
+// Payout is a token payment the pool agent owes, for a withdrawal or a loan disbursement
+type Payout struct {
+	ID        string `json:"payoutID"`
+	Recipient string `json:"recipient"`
+	Amount    int    `json:"amount"`
+	Reason    string `json:"reason"`
+	Status    string `json:"status"`
+}
+
+// asset is the part of the asset transfer chaincode's public record used as collateral
+type asset struct {
+	ID       string `json:"assetID"`
+	OwnerOrg string `json:"ownerOrg"`
+}
+
+// event provides an organized struct for emitting loan events
+type event struct {
+	LoanID string `json:"loanID"`
+	Amount int    `json:"amount"`
+	Status string `json:"status"`
+}
+
+// SetPoolAgent appoints the account that holds the pool's tokens. Only the pool operator can call it.
+func (s *SmartContract) SetPoolAgent(ctx contractapi.TransactionContextInterface, account string) error {
+	err := _requireOperator(ctx)
+	if err != nil {
+		return err
+	}
+	if account == "" {
+		return fmt.Errorf("pool agent account must be set")
+	}
+
+	pool, err := s.GetPool(ctx)
+	if err != nil {
+		return err
+	}
+	if pool.TotalDeposits > 0 || pool.TotalBorrowed > 0 {
+		return fmt.Errorf("the pool agent cannot be changed while the pool holds deposits or loans")
+	}
+
+	pool.Agent = account
+	return _putPool(ctx, pool)
+}
+
+// SetRates sets the yearly simple interest rates in basis points. Deposits and loans accrue at the
+// new rates from their next update.
+func (s *SmartContract) SetRates(ctx contractapi.TransactionContextInterface, supplyRateBps int, borrowRateBps int) error {
+	err := _requireOperator(ctx)
+	if err != nil {
+		return err
+	}
+	if supplyRateBps < 0 || borrowRateBps < 0 {
+		return fmt.Errorf("rates cannot be negative")
+	}
+	if supplyRateBps > borrowRateBps {
+		return fmt.Errorf("the supply rate cannot exceed the borrow rate")
+	}
+
+	pool, err := s.GetPool(ctx)
+	if err != nil {
+		return err
+	}
+	pool.SupplyRateBps = supplyRateBps
+	pool.BorrowRateBps = borrowRateBps
+	return _putPool(ctx, pool)
+}
+
+// SetCollateralValue records the appraised token value of an asset. Only the pool operator can call it.
+func (s *SmartContract) SetCollateralValue(ctx contractapi.TransactionContextInterface, assetID string, value int) error {
+	err := _requireOperator(ctx)
+	if err != nil {
+		return err
+	}
+	if value < 0 {
+		return fmt.Errorf("collateral value cannot be negative")
+	}
+
+	_, err = _readAsset(ctx, assetID)
+	if err != nil {
+		return err
+	}
+
+	collateralKey, err := ctx.GetStub().CreateCompositeKey(collateralPrefix, []string{assetID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	return ctx.GetStub().PutState(collateralKey, []byte(strconv.Itoa(value)))
+}
+
+// Deposit transfers amount tokens from the client to the pool agent and credits the client's deposit
+func (s *SmartContract) Deposit(ctx contractapi.TransactionContextInterface, amount int) error {
+	account, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+	if amount <= 0 {
+		return fmt.Errorf("deposit amount must be a positive integer")
+	}
+
+	pool, err := s.GetPool(ctx)
+	if err != nil {
+		return err
+	}
+	if pool.Agent == "" {
+		return fmt.Errorf("no pool agent has been appointed")
+	}
+
+	deposit, err := _getDeposit(ctx, account)
+	if err != nil {
+		return err
+	}
+	interest, err := _accrueDeposit(ctx, deposit, pool.SupplyRateBps)
+	if err != nil {
+		return err
+	}
+
+	err = _transferTokens(ctx, pool.Agent, amount)
+	if err != nil {
+		return err
+	}
+
+	deposit.Balance += amount
+	pool.TotalDeposits += amount + interest
+
+	err = _putDeposit(ctx, deposit)
+	if err != nil {
+		return err
+	}
+	return _putPool(ctx, pool)
+}
+
+// Withdraw debits amount tokens, including accrued interest, from the client's deposit and creates
+// a payout for the pool agent to pay. The payout ID is the transaction ID.
+func (s *SmartContract) Withdraw(ctx contractapi.TransactionContextInterface, amount int) (string, error) {
+	account, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get client id: %v", err)
+	}
+	if amount <= 0 {
+		return "", fmt.Errorf("withdrawal amount must be a positive integer")
+	}
+
+	pool, err := s.GetPool(ctx)
+	if err != nil {
+		return "", err
+	}
+	deposit, err := _getDeposit(ctx, account)
+	if err != nil {
+		return "", err
+	}
+	interest, err := _accrueDeposit(ctx, deposit, pool.SupplyRateBps)
+	if err != nil {
+		return "", err
+	}
+	pool.TotalDeposits += interest
+
+	if deposit.Balance < amount {
+		return "", fmt.Errorf("deposit balance %d is less than %d", deposit.Balance, amount)
+	}
+	if _available(pool) < amount {
+		return "", fmt.Errorf("the pool has %d tokens available to withdraw", _available(pool))
+	}
+
+	deposit.Balance -= amount
+	pool.TotalDeposits -= amount
+	err = _putDeposit(ctx, deposit)
+	if err != nil {
+		return "", err
+	}
+
+	return _createPayout(ctx, pool, account, amount, "withdrawal")
+}
+
+// Borrow opens a loan of amount tokens against an asset owned by the client's org. The asset must
+// have been appraised and is recorded as pledged to the loan until it is repaid or liquidated.
+// The loan is disbursed by the pool agent through the returned payout.
+func (s *SmartContract) Borrow(ctx contractapi.TransactionContextInterface, loanID string, assetID string, amount int) (string, error) {
+	borrower, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get client id: %v", err)
+	}
+	borrowerOrg, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get MSPID: %v", err)
+	}
+
+	if loanID == "" {
+		return "", fmt.Errorf("loan ID must be set")
+	}
+	if amount <= 0 {
+		return "", fmt.Errorf("loan amount must be a positive integer")
+	}
+
+	existing, err := _getLoan(ctx, loanID)
+	if err != nil {
+		return "", err
+	}
+	if existing != nil {
+		return "", fmt.Errorf("the loan %s already exists", loanID)
+	}
+
+	collateral, err := _readAsset(ctx, assetID)
+	if err != nil {
+		return "", err
+	}
+	if collateral.OwnerOrg != borrowerOrg {
+		return "", fmt.Errorf("asset %s is owned by %s, not %s", assetID, collateral.OwnerOrg, borrowerOrg)
+	}
+
+	value, err := _collateralValue(ctx, assetID)
+	if err != nil {
+		return "", err
+	}
+	if amount*basisPoints > value*maxLoanToValueBps {
+		return "", fmt.Errorf("loan of %d exceeds %d%% of the collateral value %d", amount, maxLoanToValueBps/100, value)
+	}
+
+	lienKey, err := ctx.GetStub().CreateCompositeKey(loanPrefix+"~asset", []string{assetID})
+	if err != nil {
+		return "", fmt.Errorf("failed to create composite key: %v", err)
+	}
+	lien, err := ctx.GetStub().GetState(lienKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if lien != nil {
+		return "", fmt.Errorf("asset %s already secures loan %s", assetID, string(lien))
+	}
+
+	pool, err := s.GetPool(ctx)
+	if err != nil {
+		return "", err
+	}
+	if _available(pool) < amount {
+		return "", fmt.Errorf("the pool has %d tokens available to lend", _available(pool))
+	}
+
+	now, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	loan := Loan{
+		ObjectType:  loanPrefix,
+		ID:          loanID,
+		Borrower:    borrower,
+		BorrowerOrg: borrowerOrg,
+		AssetID:     assetID,
+		Principal:   amount,
+		Debt:        amount,
+		RateBps:     pool.BorrowRateBps,
+		LastAccrued: now.Unix(),
+		Status:      statusActive,
+	}
+	err = _putLoan(ctx, &loan)
+	if err != nil {
+		return "", err
+	}
+	err = ctx.GetStub().PutState(lienKey, []byte(loanID))
+	if err != nil {
+		return "", fmt.Errorf("failed to put lien: %v", err)
+	}
+
+	pool.TotalBorrowed += amount
+	return _createPayout(ctx, pool, borrower, amount, "loan "+loanID)
+}
+
+// Repay transfers up to the outstanding debt of a loan from the client to the pool agent. Anyone can
+// repay a loan. Once the debt reaches zero the loan is repaid and the asset released.
+func (s *SmartContract) Repay(ctx contractapi.TransactionContextInterface, loanID string, amount int) error {
+	if amount <= 0 {
+		return fmt.Errorf("repayment amount must be a positive integer")
+	}
+
+	loan, err := s.GetLoan(ctx, loanID)
+	if err != nil {
+		return err
+	}
+	if loan.Status != statusActive {
+		return fmt.Errorf("loan %s is %s", loanID, loan.Status)
+	}
+	pool, err := s.GetPool(ctx)
+	if err != nil {
+		return err
+	}
+
+	interest, err := _accrueLoan(ctx, loan)
+	if err != nil {
+		return err
+	}
+	pool.TotalBorrowed += interest
+
+	if amount > loan.Debt {
+		amount = loan.Debt
+	}
+	err = _transferTokens(ctx, pool.Agent, amount)
+	if err != nil {
+		return err
+	}
+
+	loan.Debt -= amount
+	pool.TotalBorrowed -= amount
+	if loan.Debt == 0 {
+		loan.Status = statusRepaid
+		err = _releaseCollateral(ctx, loan.AssetID)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = _putLoan(ctx, loan)
+	if err != nil {
+		return err
+	}
+	err = _putPool(ctx, pool)
+	if err != nil {
+		return err
+	}
+
+	return _emitEvent(ctx, "LoanStatus", event{loanID, amount, loan.Status})
+}
+
+// Liquidate closes an unhealthy loan. The client repays the full debt to the pool agent and becomes
+// the loan's liquidator, entitled to the collateral. The asset itself must then be transferred to the
+// liquidator's org through the asset transfer chaincode, which only its owner can initiate.
+func (s *SmartContract) Liquidate(ctx contractapi.TransactionContextInterface, loanID string) error {
+	liquidator, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	loan, err := s.GetLoan(ctx, loanID)
+	if err != nil {
+		return err
+	}
+	if loan.Status != statusActive {
+		return fmt.Errorf("loan %s is %s", loanID, loan.Status)
+	}
+	if liquidator == loan.Borrower {
+		return fmt.Errorf("the borrower cannot liquidate their own loan, repay it instead")
+	}
+	pool, err := s.GetPool(ctx)
+	if err != nil {
+		return err
+	}
+
+	interest, err := _accrueLoan(ctx, loan)
+	if err != nil {
+		return err
+	}
+	pool.TotalBorrowed += interest
+
+	healthFactor, err := _healthFactor(ctx, loan)
+	if err != nil {
+		return err
+	}
+	if healthFactor >= basisPoints {
+		return fmt.Errorf("loan %s is healthy, its health factor is %d bps", loanID, healthFactor)
+	}
+
+	err = _transferTokens(ctx, pool.Agent, loan.Debt)
+	if err != nil {
+		return err
+	}
+
+	repaid := loan.Debt
+	pool.TotalBorrowed -= loan.Debt
+	loan.Debt = 0
+	loan.Liquidator = liquidator
+	loan.Status = statusLiquidated
+
+	err = _releaseCollateral(ctx, loan.AssetID)
+	if err != nil {
+		return err
+	}
+	err = _putLoan(ctx, loan)
+	if err != nil {
+		return err
+	}
+	err = _putPool(ctx, pool)
+	if err != nil {
+		return err
+	}
+
+	return _emitEvent(ctx, "LoanStatus", event{loanID, repaid, loan.Status})
+}
+
+// PayOut is submitted by the pool agent to pay a pending payout from its account
+func (s *SmartContract) PayOut(ctx contractapi.TransactionContextInterface, payoutID string) error {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	pool, err := s.GetPool(ctx)
+	if err != nil {
+		return err
+	}
+	if clientID != pool.Agent {
+		return fmt.Errorf("only the pool agent can pay out")
+	}
+
+	payout, err := s.GetPayout(ctx, payoutID)
+	if err != nil {
+		return err
+	}
+	if payout.Status != statusPending {
+		return fmt.Errorf("payout %s is %s", payoutID, payout.Status)
+	}
+
+	err = _transferTokens(ctx, payout.Recipient, payout.Amount)
+	if err != nil {
+		return err
+	}
+
+	payout.Status = statusPaid
+	pool.PendingPayouts -= payout.Amount
+	err = _putPayout(ctx, payout)
+	if err != nil {
+		return err
+	}
+	return _putPool(ctx, pool)
+}
+
+// _requireOperator checks the client belongs to the pool operator org
+func _requireOperator(ctx contractapi.TransactionContextInterface) error {
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+	if clientMSPID != poolOperatorMSPID {
+		return fmt.Errorf("client from %s is not authorized to operate the pool", clientMSPID)
+	}
+	return nil
+}
+
+// _available returns the tokens held by the pool agent that are not lent out or owed as payouts
+func _available(pool *Pool) int {
+	return pool.TotalDeposits - pool.TotalBorrowed - pool.PendingPayouts
+}
+
+// _interest returns simple interest on amount at rateBps per year for the time between from and to,
+// rounded down. big.Int keeps large balances from overflowing.
+func _interest(amount int, rateBps int, from int64, to int64) int {
+	if to <= from || amount <= 0 || rateBps <= 0 {
+		return 0
+	}
+	interest := new(big.Int).Mul(big.NewInt(int64(amount)), big.NewInt(int64(rateBps)))
+	interest.Mul(interest, big.NewInt(to-from))
+	interest.Quo(interest, big.NewInt(basisPoints*secondsPerYear))
+	return int(interest.Int64())
+}
+
+// _accrueDeposit adds interest since the last update to the deposit and returns the interest added
+func _accrueDeposit(ctx contractapi.TransactionContextInterface, deposit *Deposit, rateBps int) (int, error) {
+	now, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return 0, err
+	}
+	interest := _interest(deposit.Balance, rateBps, deposit.LastAccrued, now.Unix())
+	deposit.Balance += interest
+	deposit.LastAccrued = now.Unix()
+	return interest, nil
+}
+
+// _accrueLoan adds interest since the last update to the loan's debt and returns the interest added
+func _accrueLoan(ctx contractapi.TransactionContextInterface, loan *Loan) (int, error) {
+	now, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return 0, err
+	}
+	interest := _interest(loan.Debt, loan.RateBps, loan.LastAccrued, now.Unix())
+	loan.Debt += interest
+	loan.LastAccrued = now.Unix()
+	return interest, nil
+}
+
+// _healthFactor returns the liquidation threshold value of the collateral over the debt, in basis
+// points. Below 10000 the loan can be liquidated.
+func _healthFactor(ctx contractapi.TransactionContextInterface, loan *Loan) (int, error) {
+	if loan.Debt == 0 {
+		return 0, fmt.Errorf("loan %s has no debt", loan.ID)
+	}
+	value, err := _collateralValue(ctx, loan.AssetID)
+	if err != nil {
+		return 0, err
+	}
+	healthFactor := new(big.Int).Mul(big.NewInt(int64(value)), big.NewInt(liquidationThresholdBps))
+	healthFactor.Quo(healthFactor, big.NewInt(int64(loan.Debt)))
+	return int(healthFactor.Int64()), nil
+}
+
+// _collateralValue returns the appraised value of an asset
+func _collateralValue(ctx contractapi.TransactionContextInterface, assetID string) (int, error) {
+	collateralKey, err := ctx.GetStub().CreateCompositeKey(collateralPrefix, []string{assetID})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	valueBytes, err := ctx.GetStub().GetState(collateralKey)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if valueBytes == nil {
+		return 0, fmt.Errorf("asset %s has not been appraised", assetID)
+	}
+	value, err := strconv.Atoi(string(valueBytes))
+	if err != nil {
+		return 0, fmt.Errorf("failed to convert collateral value: %v", err)
+	}
+	return value, nil
+}
+
+// _releaseCollateral removes the lien of a closed loan on its asset
+func _releaseCollateral(ctx contractapi.TransactionContextInterface, assetID string) error {
+	lienKey, err := ctx.GetStub().CreateCompositeKey(loanPrefix+"~asset", []string{assetID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	return ctx.GetStub().DelState(lienKey)
+}
+
+// _readAsset reads the public asset record from the asset transfer chaincode
+func _readAsset(ctx contractapi.TransactionContextInterface, assetID string) (*asset, error) {
+	args := [][]byte{[]byte("ReadAsset"), []byte(assetID)}
+	response := ctx.GetStub().InvokeChaincode(assetChaincode, args, "")
+	if response.Status != shim.OK {
+		return nil, fmt.Errorf("failed to read asset %s from %s: %s", assetID, assetChaincode, response.Message)
+	}
+
+	var collateral asset
+	err := json.Unmarshal(response.Payload, &collateral)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal asset %s: %v", assetID, err)
+	}
+	return &collateral, nil
+}
+
+// _transferTokens pays amount tokens from the invoking client's account to receiver
+func _transferTokens(ctx contractapi.TransactionContextInterface, receiver string, amount int) error {
+	args := [][]byte{[]byte("Transfer"), []byte(receiver), []byte(strconv.Itoa(amount))}
+	response := ctx.GetStub().InvokeChaincode(tokenChaincode, args, "")
+	if response.Status != shim.OK {
+		return fmt.Errorf("failed to transfer %d tokens on %s: %s", amount, tokenChaincode, response.Message)
+	}
+	return nil
+}
+
+// _createPayout records a pending payout, updates the pool and returns the payout ID
+func _createPayout(ctx contractapi.TransactionContextInterface, pool *Pool, recipient string, amount int, reason string) (string, error) {
+	payout := Payout{
+		ID:        ctx.GetStub().GetTxID(),
+		Recipient: recipient,
+		Amount:    amount,
+		Reason:    reason,
+		Status:    statusPending,
+	}
+	err := _putPayout(ctx, &payout)
+	if err != nil {
+		return "", err
+	}
+
+	pool.PendingPayouts += amount
+	err = _putPool(ctx, pool)
+	if err != nil {
+		return "", err
+	}
+
+	return payout.ID, nil
+}
+
+// _getDeposit reads an account's deposit, returning an empty deposit when it has none
+func _getDeposit(ctx contractapi.TransactionContextInterface, account string) (*Deposit, error) {
+	depositKey, err := ctx.GetStub().CreateCompositeKey(depositPrefix, []string{account})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	depositJSON, err := ctx.GetStub().GetState(depositKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if depositJSON == nil {
+		return &Deposit{Account: account}, nil
+	}
+
+	var deposit Deposit
+	err = json.Unmarshal(depositJSON, &deposit)
+	if err != nil {
+		return nil, err
+	}
+	return &deposit, nil
+}
+
+// _getLoan reads a loan, returning nil when it does not exist
+func _getLoan(ctx contractapi.TransactionContextInterface, loanID string) (*Loan, error) {
+	loanKey, err := ctx.GetStub().CreateCompositeKey(loanPrefix, []string{loanID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	loanJSON, err := ctx.GetStub().GetState(loanKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if loanJSON == nil {
+		return nil, nil
+	}
+
+	var loan Loan
+	err = json.Unmarshal(loanJSON, &loan)
+	if err != nil {
+		return nil, err
+	}
+	return &loan, nil
+}
+
+// _putPool writes the pool to the world state
+func _putPool(ctx contractapi.TransactionContextInterface, pool *Pool) error {
+	poolJSON, err := json.Marshal(pool)
+	if err != nil {
+		return fmt.Errorf("failed to marshal pool: %v", err)
+	}
+	err = ctx.GetStub().PutState(poolKey, poolJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put pool: %v", err)
+	}
+	return nil
+}
+
+// _putDeposit writes the deposit to the world state
+func _putDeposit(ctx contractapi.TransactionContextInterface, deposit *Deposit) error {
+	depositKey, err := ctx.GetStub().CreateCompositeKey(depositPrefix, []string{deposit.Account})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	depositJSON, err := json.Marshal(deposit)
+	if err != nil {
+		return fmt.Errorf("failed to marshal deposit: %v", err)
+	}
+	err = ctx.GetStub().PutState(depositKey, depositJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put deposit: %v", err)
+	}
+	return nil
+}
+
+// _putLoan writes the loan to the world state
+func _putLoan(ctx contractapi.TransactionContextInterface, loan *Loan) error {
+	loanKey, err := ctx.GetStub().CreateCompositeKey(loanPrefix, []string{loan.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	loanJSON, err := json.Marshal(loan)
+	if err != nil {
+		return fmt.Errorf("failed to marshal loan: %v", err)
+	}
+	err = ctx.GetStub().PutState(loanKey, loanJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put loan %s: %v", loan.ID, err)
+	}
+	return nil
+}
+
+// _putPayout writes the payout to the world state
+func _putPayout(ctx contractapi.TransactionContextInterface, payout *Payout) error {
+	payoutKey, err := ctx.GetStub().CreateCompositeKey(payoutPrefix, []string{payout.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	payoutJSON, err := json.Marshal(payout)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payout: %v", err)
+	}
+	err = ctx.GetStub().PutState(payoutKey, payoutJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put payout %s: %v", payout.ID, err)
+	}
+	return nil
+}
+
+// _emitEvent marshals the payload and sets it as the chaincode event
+func _emitEvent(ctx contractapi.TransactionContextInterface, name string, payload event) error {
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	err = ctx.GetStub().SetEvent(name, payloadJSON)
+	if err != nil {
+		return fmt.Errorf("failed to set event: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// GetPool returns the pool configuration and totals
+func (s *SmartContract) GetPool(ctx contractapi.TransactionContextInterface) (*Pool, error) {
+	poolJSON, err := ctx.GetStub().GetState(poolKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if poolJSON == nil {
+		return &Pool{}, nil
+	}
+
+	var pool Pool
+	err = json.Unmarshal(poolJSON, &pool)
+	if err != nil {
+		return nil, err
+	}
+	return &pool, nil
+}
+
+// GetDeposit returns an account's deposit with interest accrued up to the query's timestamp
+func (s *SmartContract) GetDeposit(ctx contractapi.TransactionContextInterface, account string) (*Deposit, error) {
+	pool, err := s.GetPool(ctx)
+	if err != nil {
+		return nil, err
+	}
+	deposit, err := _getDeposit(ctx, account)
+	if err != nil {
+		return nil, err
+	}
+	_, err = _accrueDeposit(ctx, deposit, pool.SupplyRateBps)
+	if err != nil {
+		return nil, err
+	}
+	return deposit, nil
+}
+
+// GetLoan returns a loan with interest accrued up to the query's timestamp
+func (s *SmartContract) GetLoan(ctx contractapi.TransactionContextInterface, loanID string) (*Loan, error) {
+	loan, err := _getLoan(ctx, loanID)
+	if err != nil {
+		return nil, err
+	}
+	if loan == nil {
+		return nil, fmt.Errorf("the loan %s does not exist", loanID)
+	}
+	if loan.Status == statusActive {
+		_, err = _accrueLoan(ctx, loan)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return loan, nil
+}
+
+// HealthFactor returns the health factor of an active loan in basis points: the collateral value at
+// the liquidation threshold over the debt. A loan below 10000 can be liquidated.
+func (s *SmartContract) HealthFactor(ctx contractapi.TransactionContextInterface, loanID string) (int, error) {
+	loan, err := s.GetLoan(ctx, loanID)
+	if err != nil {
+		return 0, err
+	}
+	if loan.Status != statusActive {
+		return 0, fmt.Errorf("loan %s is %s", loanID, loan.Status)
+	}
+	return _healthFactor(ctx, loan)
+}
+
+// GetPayout returns the payout stored in the world state with the given ID
+func (s *SmartContract) GetPayout(ctx contractapi.TransactionContextInterface, payoutID string) (*Payout, error) {
+	payoutKey, err := ctx.GetStub().CreateCompositeKey(payoutPrefix, []string{payoutID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	payoutJSON, err := ctx.GetStub().GetState(payoutKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if payoutJSON == nil {
+		return nil, fmt.Errorf("the payout %s does not exist", payoutID)
+	}
+
+	var payout Payout
+	err = json.Unmarshal(payoutJSON, &payout)
+	if err != nil {
+		return nil, err
+	}
+	return &payout, nil
+}
+
+// GetPendingPayouts returns the payouts the pool agent has yet to pay
+func (s *SmartContract) GetPendingPayouts(ctx contractapi.TransactionContextInterface) ([]*Payout, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(payoutPrefix, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payouts: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var payouts []*Payout
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var payout Payout
+		err = json.Unmarshal(response.Value, &payout)
+		if err != nil {
+			return nil, err
+		}
+		if payout.Status == statusPending {
+			payouts = append(payouts, &payout)
+		}
+	}
+
+	return payouts, nil
+}
@@ -0,0 +1,215 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+)
+
+const borrowerMSPID = "Org2MSP"
+
+// fakeToken stands in for the token chaincode. Transfers are paid from the payer's account.
+type fakeToken struct {
+	payer    string
+	balances map[string]int
+}
+
+func (f *fakeToken) invoke(args [][]byte) pb.Response {
+	if string(args[0]) != "Transfer" {
+		return shim.Error("unexpected function " + string(args[0]))
+	}
+	amount, err := strconv.Atoi(string(args[2]))
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if f.balances[f.payer] < amount {
+		return shim.Error(fmt.Sprintf("client account %s has insufficient funds", f.payer))
+	}
+	f.balances[f.payer] -= amount
+	f.balances[string(args[1])] += amount
+	return shim.Success(nil)
+}
+
+// fakeAssets stands in for the asset transfer chaincode, answering ReadAsset
+func fakeAssets(owners map[string]string) func(args [][]byte) pb.Response {
+	return func(args [][]byte) pb.Response {
+		ownerOrg, ok := owners[string(args[1])]
+		if string(args[0]) != "ReadAsset" || !ok {
+			return shim.Error(fmt.Sprintf("asset %s does not exist", args[1]))
+		}
+		assetJSON, _ := json.Marshal(asset{string(args[1]), ownerOrg})
+		return shim.Success(assetJSON)
+	}
+}
+
+func checkError(t *testing.T, err error, expected string) {
+	t.Helper()
+	if expected == "" && err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected != "" && (err == nil || !strings.Contains(err.Error(), expected)) {
+		t.Fatalf("expected error containing %q, got %v", expected, err)
+	}
+}
+
+func checkLoan(t *testing.T, stub *fakeStub, status string, debt int) {
+	t.Helper()
+	loan, err := new(SmartContract).GetLoan(newContext(stub, "operator", poolOperatorMSPID), "loan1")
+	if err != nil {
+		t.Fatalf("failed to read loan: %v", err)
+	}
+	if loan.Status != status || loan.Debt != debt {
+		t.Fatalf("expected loan %s with debt %d, got %s with %d", status, debt, loan.Status, loan.Debt)
+	}
+}
+
+// openPool appoints the pool agent without interest, takes a deposit of 1000 from the lender and
+// appraises asset1 of the borrower's org at 1000
+func openPool(t *testing.T, stub *fakeStub) *fakeToken {
+	t.Helper()
+	token := &fakeToken{payer: "lender", balances: map[string]int{"lender": 1000}}
+	stub.chaincodes[tokenChaincode] = token.invoke
+	stub.chaincodes[assetChaincode] = fakeAssets(map[string]string{"asset1": borrowerMSPID})
+
+	contract := new(SmartContract)
+	checkError(t, contract.SetPoolAgent(newContext(stub, "operator", poolOperatorMSPID), "agent"), "")
+	checkError(t, contract.Deposit(newContext(stub, "lender", "Org3MSP"), 1000), "")
+	checkError(t, contract.SetCollateralValue(newContext(stub, "operator", poolOperatorMSPID), "asset1", 1000), "")
+	return token
+}
+
+// borrow opens loan1 of 600 against asset1 and has the pool agent disburse it
+func borrow(t *testing.T, stub *fakeStub, token *fakeToken) {
+	t.Helper()
+	contract := new(SmartContract)
+	payoutID, err := contract.Borrow(newContext(stub, "borrower", borrowerMSPID), "loan1", "asset1", 600)
+	checkError(t, err, "")
+
+	token.payer = "agent"
+	checkError(t, contract.PayOut(newContext(stub, "agent", poolOperatorMSPID), payoutID), "")
+}
+
+func TestPoolAdministration(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+
+	err := contract.Deposit(newContext(stub, "lender", "Org3MSP"), 1000)
+	checkError(t, err, "no pool agent has been appointed")
+
+	err = contract.SetPoolAgent(newContext(stub, "borrower", borrowerMSPID), "borrower")
+	checkError(t, err, "client from Org2MSP is not authorized to operate the pool")
+
+	openPool(t, stub)
+	err = contract.SetPoolAgent(newContext(stub, "operator", poolOperatorMSPID), "agent2")
+	checkError(t, err, "the pool agent cannot be changed while the pool holds deposits or loans")
+
+	err = contract.SetRates(newContext(stub, "operator", poolOperatorMSPID), 600, 500)
+	checkError(t, err, "the supply rate cannot exceed the borrow rate")
+
+	err = contract.SetCollateralValue(newContext(stub, "borrower", borrowerMSPID), "asset1", 5000)
+	checkError(t, err, "client from Org2MSP is not authorized to operate the pool")
+}
+
+func TestDepositInsufficientFunds(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	openPool(t, stub)
+
+	err := contract.Deposit(newContext(stub, "lender", "Org3MSP"), 1)
+	checkError(t, err, "failed to transfer 1 tokens on token_erc20: client account lender has insufficient funds")
+
+	pool, err := contract.GetPool(newContext(stub, "operator", poolOperatorMSPID))
+	checkError(t, err, "")
+	if pool.TotalDeposits != 1000 {
+		t.Fatalf("expected deposits of 1000, got %d", pool.TotalDeposits)
+	}
+}
+
+func TestBorrowRepay(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	token := openPool(t, stub)
+
+	_, err := contract.Borrow(newContext(stub, "borrower", borrowerMSPID), "loan1", "asset1", 601)
+	checkError(t, err, "loan of 601 exceeds 60% of the collateral value 1000")
+
+	_, err = contract.Borrow(newContext(stub, "thief", "Org3MSP"), "loan1", "asset1", 600)
+	checkError(t, err, "asset asset1 is owned by Org2MSP, not Org3MSP")
+
+	borrow(t, stub, token)
+	checkLoan(t, stub, statusActive, 600)
+	if token.balances["borrower"] != 600 || token.balances["agent"] != 400 {
+		t.Fatalf("unexpected balances %v", token.balances)
+	}
+
+	_, err = contract.Borrow(newContext(stub, "borrower", borrowerMSPID), "loan2", "asset1", 10)
+	checkError(t, err, "asset asset1 already secures loan loan1")
+
+	_, err = contract.Withdraw(newContext(stub, "lender", "Org3MSP"), 500)
+	checkError(t, err, "the pool has 400 tokens available to withdraw")
+
+	// repaying more than the debt only takes the debt
+	token.payer = "borrower"
+	checkError(t, contract.Repay(newContext(stub, "borrower", borrowerMSPID), "loan1", 1000), "")
+	checkLoan(t, stub, statusRepaid, 0)
+	if token.balances["borrower"] != 0 || token.balances["agent"] != 1000 {
+		t.Fatalf("unexpected balances %v", token.balances)
+	}
+
+	payoutID, err := contract.Withdraw(newContext(stub, "lender", "Org3MSP"), 1000)
+	checkError(t, err, "")
+	err = contract.PayOut(newContext(stub, "lender", "Org3MSP"), payoutID)
+	checkError(t, err, "only the pool agent can pay out")
+
+	token.payer = "agent"
+	checkError(t, contract.PayOut(newContext(stub, "agent", poolOperatorMSPID), payoutID), "")
+	err = contract.PayOut(newContext(stub, "agent", poolOperatorMSPID), payoutID)
+	checkError(t, err, fmt.Sprintf("payout %s is PAID", payoutID))
+	if token.balances["lender"] != 1000 {
+		t.Fatalf("unexpected balances %v", token.balances)
+	}
+}
+
+func TestLiquidate(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	token := openPool(t, stub)
+	borrow(t, stub, token)
+
+	err := contract.Liquidate(newContext(stub, "liquidator", "Org3MSP"), "loan1")
+	checkError(t, err, "loan loan1 is healthy, its health factor is 13333 bps")
+
+	checkError(t, contract.SetCollateralValue(newContext(stub, "operator", poolOperatorMSPID), "asset1", 700), "")
+	err = contract.Liquidate(newContext(stub, "borrower", borrowerMSPID), "loan1")
+	checkError(t, err, "the borrower cannot liquidate their own loan, repay it instead")
+
+	token.payer = "liquidator"
+	err = contract.Liquidate(newContext(stub, "liquidator", "Org3MSP"), "loan1")
+	checkError(t, err, "client account liquidator has insufficient funds")
+	checkLoan(t, stub, statusActive, 600)
+
+	token.balances["liquidator"] = 600
+	checkError(t, contract.Liquidate(newContext(stub, "liquidator", "Org3MSP"), "loan1"), "")
+	checkLoan(t, stub, statusLiquidated, 0)
+
+	loan, err := contract.GetLoan(newContext(stub, "operator", poolOperatorMSPID), "loan1")
+	checkError(t, err, "")
+	if loan.Liquidator != "liquidator" || token.balances["agent"] != 1000 {
+		t.Fatalf("unexpected loan %+v with balances %v", loan, token.balances)
+	}
+}
+
+func TestInterest(t *testing.T) {
+	// 10% a year on 1000 for half a year
+	if interest := _interest(1000, 1000, 0, secondsPerYear/2); interest != 50 {
+		t.Fatalf("expected interest of 50, got %d", interest)
+	}
+	if interest := _interest(1000, 1000, 100, 100); interest != 0 {
+		t.Fatalf("expected no interest, got %d", interest)
+	}
+}
@@ -0,0 +1,23 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"log"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/lending/chaincode-go/chaincode"
+)
+
+func main() {
+	lendingChaincode, err := contractapi.NewChaincode(&chaincode.SmartContract{})
+	if err != nil {
+		log.Panicf("Error creating lending chaincode: %v", err)
+	}
+
+	if err := lendingChaincode.Start(); err != nil {
+		log.Panicf("Error starting lending chaincode: %v", err)
+	}
+}
@@ -0,0 +1,325 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil"
+)
+
+// defaultTokenChaincode is the name the token-erc-20 sample is deployed under in the README
+const defaultTokenChaincode = "token_erc20"
+
+// letter of credit status values
+const (
+	statusRequested  = "REQUESTED"
+	statusIssued     = "ISSUED"
+	statusRejected   = "REJECTED"
+	statusPresented  = "PRESENTED"
+	statusDiscrepant = "DISCREPANT"
+	statusPaid       = "PAID"
+)
+
+// SmartContract provides functions for managing documentary letters of credit
+type SmartContract struct {
+	contractapi.Contract
+}
+
+// Document is a trade document presented by the beneficiary. Only the hash is put on the
+// ledger, the document itself is exchanged off chain.
+type Document struct {
+	Type string `json:"type"`
+	Hash string `json:"hash"`
+}
+
+// LetterOfCredit is the public record of an LC and its current place in the lifecycle
+type LetterOfCredit struct {
+	ObjectType        string     `json:"objectType"`
+	ID                string     `json:"lcID"`
+	Applicant         string     `json:"applicant"`
+	ApplicantOrg      string     `json:"applicantOrg"`
+	IssuingBankOrg    string     `json:"issuingBankOrg"`
+	Beneficiary       string     `json:"beneficiary"`
+	Amount            int        `json:"amount"`
+	TokenChaincode    string     `json:"tokenChaincode"`
+	Expiry            string     `json:"expiry"`
+	RequiredDocuments []string   `json:"requiredDocuments"`
+	Presented         []Document `json:"presented,omitempty"`
+	Discrepancies     []string   `json:"discrepancies,omitempty"`
+	Status            string     `json:"status"`
+	PaymentTxID       string     `json:"paymentTxID,omitempty"`
+}
+
+// event provides an organized struct for emitting LC status events
+type event struct {
+	LCID   string `json:"lcID"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// RequestLC is called by the applicant (the buyer) to ask the issuing bank to open a letter of credit
+// in favour of the beneficiary. Expiry is an RFC3339 timestamp and requiredDocuments lists the
+// document types that must be presented before payment, e.g. ["invoice","billOfLading"].
+func (s *SmartContract) RequestLC(ctx contractapi.TransactionContextInterface, lcID string, issuingBankOrg string, beneficiary string, amount int, expiry string, requiredDocuments []string, tokenChaincode string) error {
+	applicant, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+	applicantOrg, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+
+	if lcID == "" || issuingBankOrg == "" || beneficiary == "" {
+		return fmt.Errorf("LC ID, issuing bank and beneficiary must be set")
+	}
+	if beneficiary == applicant {
+		return fmt.Errorf("the beneficiary cannot be the applicant")
+	}
+	if amount <= 0 {
+		return fmt.Errorf("amount must be a positive integer")
+	}
+	if len(requiredDocuments) == 0 {
+		return fmt.Errorf("at least one required document must be listed")
+	}
+	_, err = time.Parse(time.RFC3339, expiry)
+	if err != nil {
+		return fmt.Errorf("expiry %s is not an RFC3339 timestamp: %v", expiry, err)
+	}
+	if tokenChaincode == "" {
+		tokenChaincode = defaultTokenChaincode
+	}
+
+	existing, err := ctx.GetStub().GetState(lcID)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if existing != nil {
+		return fmt.Errorf("the letter of credit %s already exists", lcID)
+	}
+
+	lc := LetterOfCredit{
+		ObjectType:        "letterOfCredit",
+		ID:                lcID,
+		Applicant:         applicant,
+		ApplicantOrg:      applicantOrg,
+		IssuingBankOrg:    issuingBankOrg,
+		Beneficiary:       beneficiary,
+		Amount:            amount,
+		TokenChaincode:    tokenChaincode,
+		Expiry:            expiry,
+		RequiredDocuments: requiredDocuments,
+		Status:            statusRequested,
+	}
+
+	return _putLC(ctx, &lc, "")
+}
+
+// ApproveLC is called by the issuing bank to issue the requested letter of credit
+func (s *SmartContract) ApproveLC(ctx contractapi.TransactionContextInterface, lcID string) error {
+	lc, err := s.ReadLC(ctx, lcID)
+	if err != nil {
+		return err
+	}
+
+	err = _requireIssuingBank(ctx, lc)
+	if err != nil {
+		return err
+	}
+	if lc.Status != statusRequested {
+		return fmt.Errorf("letter of credit %s is %s and cannot be issued", lcID, lc.Status)
+	}
+
+	lc.Status = statusIssued
+	return _putLC(ctx, lc, "")
+}
+
+// RejectLC is called by the issuing bank to decline the application
+func (s *SmartContract) RejectLC(ctx contractapi.TransactionContextInterface, lcID string, reason string) error {
+	lc, err := s.ReadLC(ctx, lcID)
+	if err != nil {
+		return err
+	}
+
+	err = _requireIssuingBank(ctx, lc)
+	if err != nil {
+		return err
+	}
+	if lc.Status != statusRequested {
+		return fmt.Errorf("letter of credit %s is %s and cannot be rejected", lcID, lc.Status)
+	}
+
+	lc.Status = statusRejected
+	return _putLC(ctx, lc, reason)
+}
+
+// PresentDocuments is called by the beneficiary to present the hashes of the shipping documents.
+// Presenting again after a discrepancy replaces the earlier presentation.
+func (s *SmartContract) PresentDocuments(ctx contractapi.TransactionContextInterface, lcID string, documents []Document) error {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	lc, err := s.ReadLC(ctx, lcID)
+	if err != nil {
+		return err
+	}
+	if clientID != lc.Beneficiary {
+		return fmt.Errorf("only the beneficiary can present documents under letter of credit %s", lcID)
+	}
+	if lc.Status != statusIssued && lc.Status != statusDiscrepant {
+		return fmt.Errorf("letter of credit %s is %s and cannot accept a presentation", lcID, lc.Status)
+	}
+
+	expired, err := _isExpired(ctx, lc)
+	if err != nil {
+		return err
+	}
+	if expired {
+		return fmt.Errorf("letter of credit %s expired at %s", lcID, lc.Expiry)
+	}
+
+	for _, document := range documents {
+		if document.Type == "" {
+			return fmt.Errorf("document type must be set")
+		}
+		hash, err := hex.DecodeString(document.Hash)
+		if err != nil || len(hash) != 32 {
+			return fmt.Errorf("hash for document %s must be a hex encoded SHA-256 digest", document.Type)
+		}
+	}
+
+	lc.Presented = documents
+	lc.Discrepancies = nil
+	lc.Status = statusPresented
+	return _putLC(ctx, lc, "")
+}
+
+// RaiseDiscrepancy is called by the issuing bank when the presented documents do not comply
+// with the terms of the credit. The beneficiary may then present corrected documents.
+func (s *SmartContract) RaiseDiscrepancy(ctx contractapi.TransactionContextInterface, lcID string, discrepancy string) error {
+	lc, err := s.ReadLC(ctx, lcID)
+	if err != nil {
+		return err
+	}
+
+	err = _requireIssuingBank(ctx, lc)
+	if err != nil {
+		return err
+	}
+	if lc.Status != statusPresented && lc.Status != statusDiscrepant {
+		return fmt.Errorf("letter of credit %s is %s, there is no presentation to examine", lcID, lc.Status)
+	}
+	if discrepancy == "" {
+		return fmt.Errorf("the discrepancy must be described")
+	}
+
+	lc.Discrepancies = append(lc.Discrepancies, discrepancy)
+	lc.Status = statusDiscrepant
+	return _putLC(ctx, lc, discrepancy)
+}
+
+// ReleasePayment is called by the issuing bank once it accepts a complying presentation. The LC
+// amount is paid from the calling bank client's token account to the beneficiary by invoking
+// Transfer on the token chaincode.
+func (s *SmartContract) ReleasePayment(ctx contractapi.TransactionContextInterface, lcID string) error {
+	lc, err := s.ReadLC(ctx, lcID)
+	if err != nil {
+		return err
+	}
+
+	err = _requireIssuingBank(ctx, lc)
+	if err != nil {
+		return err
+	}
+	if lc.Status != statusPresented {
+		return fmt.Errorf("letter of credit %s is %s, payment needs a complying presentation", lcID, lc.Status)
+	}
+
+	presented := make(map[string]bool)
+	for _, document := range lc.Presented {
+		presented[document.Type] = true
+	}
+	for _, required := range lc.RequiredDocuments {
+		if !presented[required] {
+			return fmt.Errorf("required document %s has not been presented", required)
+		}
+	}
+
+	err = _transferTokens(ctx, lc.TokenChaincode, lc.Beneficiary, lc.Amount)
+	if err != nil {
+		return err
+	}
+
+	lc.Status = statusPaid
+	lc.PaymentTxID = ctx.GetStub().GetTxID()
+	return _putLC(ctx, lc, strconv.Itoa(lc.Amount))
+}
+
+// _requireIssuingBank checks the client belongs to the LC's issuing bank
+func _requireIssuingBank(ctx contractapi.TransactionContextInterface, lc *LetterOfCredit) error {
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+	if clientMSPID != lc.IssuingBankOrg {
+		return fmt.Errorf("client from %s is not the issuing bank of letter of credit %s", clientMSPID, lc.ID)
+	}
+	return nil
+}
+
+// _isExpired compares the LC expiry with the transaction timestamp
+func _isExpired(ctx contractapi.TransactionContextInterface, lc *LetterOfCredit) (bool, error) {
+	expiry, err := time.Parse(time.RFC3339, lc.Expiry)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse expiry: %v", err)
+	}
+	now, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return false, err
+	}
+	return now.After(expiry), nil
+}
+
+// _transferTokens pays amount tokens from the invoking client's account to receiver
+func _transferTokens(ctx contractapi.TransactionContextInterface, tokenChaincode string, receiver string, amount int) error {
+	args := [][]byte{[]byte("Transfer"), []byte(receiver), []byte(strconv.Itoa(amount))}
+	response := ctx.GetStub().InvokeChaincode(tokenChaincode, args, "")
+	if response.Status != shim.OK {
+		return fmt.Errorf("failed to transfer %d tokens on %s: %s", amount, tokenChaincode, response.Message)
+	}
+	return nil
+}
+
+// _putLC writes the letter of credit and emits a status event for it
+func _putLC(ctx contractapi.TransactionContextInterface, lc *LetterOfCredit, detail string) error {
+	lcJSON, err := json.Marshal(lc)
+	if err != nil {
+		return fmt.Errorf("failed to marshal letter of credit: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(lc.ID, lcJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put letter of credit %s: %v", lc.ID, err)
+	}
+
+	eventJSON, err := json.Marshal(event{lc.ID, lc.Status, detail})
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	err = ctx.GetStub().SetEvent("LCStatus", eventJSON)
+	if err != nil {
+		return fmt.Errorf("failed to set event: %v", err)
+	}
+	return nil
+}
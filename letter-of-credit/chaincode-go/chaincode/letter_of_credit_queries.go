@@ -0,0 +1,70 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// QueryResult structure used for handling result of history query
+type QueryResult struct {
+	Record    *LetterOfCredit
+	TxId      string    `json:"txId"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// ReadLC returns the letter of credit stored in the world state with the given ID
+func (s *SmartContract) ReadLC(ctx contractapi.TransactionContextInterface, lcID string) (*LetterOfCredit, error) {
+	lcJSON, err := ctx.GetStub().GetState(lcID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if lcJSON == nil {
+		return nil, fmt.Errorf("the letter of credit %s does not exist", lcID)
+	}
+
+	var lc LetterOfCredit
+	err = json.Unmarshal(lcJSON, &lc)
+	if err != nil {
+		return nil, err
+	}
+	return &lc, nil
+}
+
+// QueryLCHistory returns every state the letter of credit has been through
+func (s *SmartContract) QueryLCHistory(ctx contractapi.TransactionContextInterface, lcID string) ([]QueryResult, error) {
+	resultsIterator, err := ctx.GetStub().GetHistoryForKey(lcID)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var results []QueryResult
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var lc *LetterOfCredit
+		err = json.Unmarshal(response.Value, &lc)
+		if err != nil {
+			return nil, err
+		}
+
+		record := QueryResult{
+			TxId:      response.TxId,
+			Timestamp: time.Unix(response.Timestamp.Seconds, int64(response.Timestamp.Nanos)).UTC(),
+			Record:    lc,
+		}
+		results = append(results, record)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,189 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+)
+
+const (
+	applicantMSPID = "Org1MSP"
+	bankMSPID      = "Org2MSP"
+	sellerMSPID    = "Org3MSP"
+
+	invoiceHash = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	billHash    = "60303ae22b998861bce3b28f33eec1be758a213c86c93c076dbe9f558c11c752"
+)
+
+// fakeToken stands in for the token chaincode. Transfers are paid from the payer's account.
+type fakeToken struct {
+	payer    string
+	balances map[string]int
+}
+
+func (f *fakeToken) invoke(args [][]byte) pb.Response {
+	if string(args[0]) != "Transfer" {
+		return shim.Error("unexpected function " + string(args[0]))
+	}
+	amount, err := strconv.Atoi(string(args[2]))
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if f.balances[f.payer] < amount {
+		return shim.Error(fmt.Sprintf("client account %s has insufficient funds", f.payer))
+	}
+	f.balances[f.payer] -= amount
+	f.balances[string(args[1])] += amount
+	return shim.Success(nil)
+}
+
+func checkError(t *testing.T, err error, expected string) {
+	t.Helper()
+	if expected == "" && err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected != "" && (err == nil || !strings.Contains(err.Error(), expected)) {
+		t.Fatalf("expected error containing %q, got %v", expected, err)
+	}
+}
+
+func checkStatus(t *testing.T, stub *fakeStub, status string) {
+	t.Helper()
+	lc, err := new(SmartContract).ReadLC(newContext(stub, "bank", bankMSPID), "lc1")
+	if err != nil {
+		t.Fatalf("failed to read letter of credit: %v", err)
+	}
+	if lc.Status != status {
+		t.Fatalf("expected letter of credit %s, got %s", status, lc.Status)
+	}
+}
+
+// issueLC requests and issues a letter of credit for 1000 tokens that needs an invoice and a bill of lading
+func issueLC(t *testing.T, stub *fakeStub) *fakeToken {
+	t.Helper()
+	token := &fakeToken{payer: "bank", balances: map[string]int{"bank": 5000}}
+	stub.chaincodes[defaultTokenChaincode] = token.invoke
+
+	contract := new(SmartContract)
+	err := contract.RequestLC(newContext(stub, "buyer", applicantMSPID), "lc1", bankMSPID, "seller", 1000, "2020-12-31T00:00:00Z", []string{"invoice", "billOfLading"}, "")
+	checkError(t, err, "")
+	checkError(t, contract.ApproveLC(newContext(stub, "bank", bankMSPID), "lc1"), "")
+	return token
+}
+
+func TestRequestLC(t *testing.T) {
+	tests := []struct {
+		name        string
+		beneficiary string
+		amount      int
+		expiry      string
+		documents   []string
+		expected    string
+	}{
+		{"valid", "seller", 1000, "2020-12-31T00:00:00Z", []string{"invoice"}, ""},
+		{"beneficiary is applicant", "buyer", 1000, "2020-12-31T00:00:00Z", []string{"invoice"}, "the beneficiary cannot be the applicant"},
+		{"no amount", "seller", 0, "2020-12-31T00:00:00Z", []string{"invoice"}, "amount must be a positive integer"},
+		{"no documents", "seller", 1000, "2020-12-31T00:00:00Z", nil, "at least one required document must be listed"},
+		{"bad expiry", "seller", 1000, "31/12/2020", []string{"invoice"}, "expiry 31/12/2020 is not an RFC3339 timestamp"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stub := newFakeStub()
+			err := new(SmartContract).RequestLC(newContext(stub, "buyer", applicantMSPID), "lc1", bankMSPID, test.beneficiary, test.amount, test.expiry, test.documents, "")
+			checkError(t, err, test.expected)
+		})
+	}
+}
+
+func TestApproveLC(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	err := contract.RequestLC(newContext(stub, "buyer", applicantMSPID), "lc1", bankMSPID, "seller", 1000, "2020-12-31T00:00:00Z", []string{"invoice"}, "")
+	checkError(t, err, "")
+
+	err = contract.ApproveLC(newContext(stub, "buyer", applicantMSPID), "lc1")
+	checkError(t, err, "client from Org1MSP is not the issuing bank of letter of credit lc1")
+
+	checkError(t, contract.ApproveLC(newContext(stub, "bank", bankMSPID), "lc1"), "")
+	checkStatus(t, stub, statusIssued)
+
+	err = contract.RejectLC(newContext(stub, "bank", bankMSPID), "lc1", "too late")
+	checkError(t, err, "letter of credit lc1 is ISSUED and cannot be rejected")
+}
+
+func TestReleasePayment(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	token := issueLC(t, stub)
+
+	invoice := []Document{{"invoice", invoiceHash}}
+	err := contract.PresentDocuments(newContext(stub, "buyer", applicantMSPID), "lc1", invoice)
+	checkError(t, err, "only the beneficiary can present documents under letter of credit lc1")
+
+	err = contract.PresentDocuments(newContext(stub, "seller", sellerMSPID), "lc1", []Document{{"invoice", "abc"}})
+	checkError(t, err, "hash for document invoice must be a hex encoded SHA-256 digest")
+
+	checkError(t, contract.PresentDocuments(newContext(stub, "seller", sellerMSPID), "lc1", invoice), "")
+	err = contract.ReleasePayment(newContext(stub, "bank", bankMSPID), "lc1")
+	checkError(t, err, "required document billOfLading has not been presented")
+
+	checkError(t, contract.RaiseDiscrepancy(newContext(stub, "bank", bankMSPID), "lc1", "bill of lading missing"), "")
+	checkStatus(t, stub, statusDiscrepant)
+
+	documents := []Document{{"invoice", invoiceHash}, {"billOfLading", billHash}}
+	checkError(t, contract.PresentDocuments(newContext(stub, "seller", sellerMSPID), "lc1", documents), "")
+
+	err = contract.ReleasePayment(newContext(stub, "seller", sellerMSPID), "lc1")
+	checkError(t, err, "client from Org3MSP is not the issuing bank of letter of credit lc1")
+
+	checkError(t, contract.ReleasePayment(newContext(stub, "bank", bankMSPID), "lc1"), "")
+	checkStatus(t, stub, statusPaid)
+	if token.balances["bank"] != 4000 || token.balances["seller"] != 1000 {
+		t.Fatalf("unexpected balances %v", token.balances)
+	}
+
+	var paid event
+	err = json.Unmarshal(stub.eventValue, &paid)
+	if err != nil {
+		t.Fatalf("failed to unmarshal event: %v", err)
+	}
+	if paid != (event{"lc1", statusPaid, "1000"}) {
+		t.Fatalf("unexpected event %+v", paid)
+	}
+
+	history, err := contract.QueryLCHistory(newContext(stub, "bank", bankMSPID), "lc1")
+	checkError(t, err, "")
+	if len(history) != 6 {
+		t.Fatalf("expected 6 history records, got %d", len(history))
+	}
+}
+
+func TestReleasePaymentInsufficientFunds(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	token := issueLC(t, stub)
+	token.balances["bank"] = 999
+
+	documents := []Document{{"invoice", invoiceHash}, {"billOfLading", billHash}}
+	checkError(t, contract.PresentDocuments(newContext(stub, "seller", sellerMSPID), "lc1", documents), "")
+
+	err := contract.ReleasePayment(newContext(stub, "bank", bankMSPID), "lc1")
+	checkError(t, err, "failed to transfer 1000 tokens on token_erc20: client account bank has insufficient funds")
+	checkStatus(t, stub, statusPresented)
+}
+
+func TestPresentDocumentsExpired(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	err := contract.RequestLC(newContext(stub, "buyer", applicantMSPID), "lc1", bankMSPID, "seller", 1000, "2020-09-13T12:26:41Z", []string{"invoice"}, "")
+	checkError(t, err, "")
+	checkError(t, contract.ApproveLC(newContext(stub, "bank", bankMSPID), "lc1"), "")
+
+	err = contract.PresentDocuments(newContext(stub, "seller", sellerMSPID), "lc1", []Document{{"invoice", invoiceHash}})
+	checkError(t, err, "letter of credit lc1 expired at 2020-09-13T12:26:41Z")
+}
@@ -0,0 +1,23 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"log"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/letter-of-credit/chaincode-go/chaincode"
+)
+
+func main() {
+	lcChaincode, err := contractapi.NewChaincode(&chaincode.SmartContract{})
+	if err != nil {
+		log.Panicf("Error creating letter-of-credit chaincode: %v", err)
+	}
+
+	if err := lcChaincode.Start(); err != nil {
+		log.Panicf("Error starting letter-of-credit chaincode: %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Config is the configuration file of the generator
+type Config struct {
+	// Wallet is the wallet directory holding the Fabric identities
+	Wallet         string     `json:"wallet"`
+	Channel        string     `json:"channel"`
+	TokenChaincode string     `json:"tokenChaincode"`
+	AssetChaincode string     `json:"assetChaincode"`
+	Identities     []Identity `json:"identities"`
+}
+
+// Identity is a Fabric identity seeding the ledger
+type Identity struct {
+	// Identity is the label of the identity in the wallet
+	Identity string `json:"identity"`
+	// Profile is the connection profile of the identity's org
+	Profile string `json:"profile"`
+	// Peer is the peer of the profile to connect to, the first peer of the org when empty
+	Peer string `json:"peer,omitempty"`
+	// Tokens tells that the identity mints tokens, funds accounts with them and gives allowances.
+	// Its org needs the token.Mint operation in the access-control chaincode.
+	Tokens bool `json:"tokens"`
+	// Assets tells that the identity creates assets owned by its org
+	Assets bool `json:"assets"`
+}
+
+// LoadConfig reads the configuration file at path. Relative wallet and profile paths are resolved
+// against the directory of the file.
+func LoadConfig(path string) (*Config, error) {
+	configJSON, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %v", err)
+	}
+	config := &Config{
+		Wallet:         "wallet",
+		Channel:        "mychannel",
+		TokenChaincode: "token_erc20",
+		AssetChaincode: "secured",
+	}
+	err = json.Unmarshal(configJSON, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config: %v", err)
+	}
+
+	dir := filepath.Dir(path)
+	config.Wallet = resolve(dir, config.Wallet)
+	for i := range config.Identities {
+		identity := &config.Identities[i]
+		if identity.Identity == "" || identity.Profile == "" {
+			return nil, fmt.Errorf("identity %d must have a label and profile", i)
+		}
+		identity.Profile = resolve(dir, identity.Profile)
+	}
+	if len(config.Identities) == 0 {
+		return nil, fmt.Errorf("config has no identities")
+	}
+	return config, nil
+}
+
+func resolve(dir string, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
@@ -0,0 +1,35 @@
+module github.com/hyperledger/fabric-samples/loadgen-go
+
+go 1.18
+
+require (
+	github.com/hyperledger/fabric-gateway v1.1.1
+	github.com/hyperledger/fabric-samples/asset-transfer-secured-agreement/application-go v0.0.0
+	github.com/hyperledger/fabric-samples/asset-transfer-secured-agreement/assettypes v0.0.0
+	github.com/hyperledger/fabric-samples/internal/appclient v0.0.0
+	github.com/hyperledger/fabric-samples/token-erc-20/application-go v0.0.0
+)
+
+require (
+	github.com/go-logr/logr v1.2.3 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/golang/protobuf v1.5.2 // indirect
+	github.com/hyperledger/fabric-protos-go-apiv2 v0.0.0-20220615102044-467be1c7b2e7 // indirect
+	github.com/miekg/pkcs11 v1.1.1 // indirect
+	go.opentelemetry.io/otel v1.10.0 // indirect
+	go.opentelemetry.io/otel/trace v1.10.0 // indirect
+	golang.org/x/net v0.1.0 // indirect
+	golang.org/x/sys v0.1.0 // indirect
+	golang.org/x/text v0.4.0 // indirect
+	google.golang.org/genproto v0.0.0-20221018160656-63c7b68cfc55 // indirect
+	google.golang.org/grpc v1.50.1 // indirect
+	google.golang.org/protobuf v1.28.1 // indirect
+)
+
+replace (
+	github.com/hyperledger/fabric-samples/asset-transfer-secured-agreement/application-go => ../asset-transfer-secured-agreement/application-go
+	github.com/hyperledger/fabric-samples/asset-transfer-secured-agreement/assettypes => ../asset-transfer-secured-agreement/assettypes
+	github.com/hyperledger/fabric-samples/internal/appclient => ../internal/appclient
+	github.com/hyperledger/fabric-samples/internal/tracing => ../internal/tracing
+	github.com/hyperledger/fabric-samples/token-erc-20/application-go => ../token-erc-20/application-go
+)
@@ -0,0 +1,145 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// loadgen-go seeds a test channel with large numbers of token accounts, balances and allowances and
+// secured agreement assets through the Fabric Gateway, with realistic distributions generated from
+// a seed, so pagination, indexing and sharding changes are validated against large ledgers.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-samples/asset-transfer-secured-agreement/application-go/asset"
+	"github.com/hyperledger/fabric-samples/internal/appclient"
+	"github.com/hyperledger/fabric-samples/token-erc-20/application-go/token"
+)
+
+var (
+	configPath   = flag.String("config", "config.json", "configuration file")
+	seed         = flag.Int64("seed", 1, "seed of the random data, the same seed and flags seed the same data")
+	prefix       = flag.String("prefix", "load", "prefix of the account and asset IDs, change it to seed a channel again")
+	accounts     = flag.Int("accounts", 10000, "number of accounts funded by the token holders")
+	balanceAlpha = flag.Float64("balance-alpha", 1.16, "shape of the Pareto distribution of the balances, lower is more unequal")
+	minBalance   = flag.Int("min-balance", 10, "smallest balance")
+	maxBalance   = flag.Int("max-balance", 1000000, "largest balance")
+	allowances   = flag.Int("allowances", 2000, "number of allowances the token holders give accounts")
+	maxAllowance = flag.Int("max-allowance", 10000, "largest allowance")
+	assets       = flag.Int("assets", 10000, "number of assets created")
+	concurrency  = flag.Int("concurrency", 8, "number of assets created at a time")
+	attempts     = flag.Int("attempts", 3, "attempts of a transaction invalidated when committed, e.g. by an MVCC read conflict")
+	planPath     = flag.String("plan", "", "file the seeded data is written to as JSON, to check queries against")
+	dryRun       = flag.Bool("dry-run", false, "print the distributions of the data without seeding it")
+)
+
+func main() {
+	flag.Parse()
+	config, err := LoadConfig(*configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *concurrency < 1 || *attempts < 1 {
+		log.Fatal("-concurrency and -attempts must be positive")
+	}
+
+	settings := DefaultSettings()
+	settings.Prefix = *prefix
+	settings.Accounts = *accounts
+	settings.BalanceAlpha = *balanceAlpha
+	settings.MinBalance = *minBalance
+	settings.MaxBalance = *maxBalance
+	settings.Allowances = *allowances
+	settings.MaxAllowance = *maxAllowance
+	settings.Assets = *assets
+
+	var holderIdentities, ownerIdentities []Identity
+	for _, identity := range config.Identities {
+		if identity.Tokens {
+			holderIdentities = append(holderIdentities, identity)
+		}
+		if identity.Assets {
+			ownerIdentities = append(ownerIdentities, identity)
+		}
+	}
+	err = settings.Validate(len(holderIdentities), len(ownerIdentities))
+	if err != nil {
+		log.Fatal(err)
+	}
+	plan := NewPlan(*seed, settings, len(holderIdentities), len(ownerIdentities))
+	log.Print(plan.Summary())
+	if *planPath != "" {
+		err = writePlan(*planPath, plan)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *dryRun {
+		return
+	}
+
+	wallet, err := appclient.OpenWallet(config.Wallet)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var connections []*appclient.Connection
+	defer func() {
+		for _, connection := range connections {
+			connection.Close()
+		}
+	}()
+	// connect connects as an identity of the wallet and returns the network of the channel and the
+	// MSP ID of the identity
+	connect := func(identity Identity) (*client.Network, string) {
+		profile, err := appclient.LoadProfile(identity.Profile)
+		if err != nil {
+			log.Fatalf("failed to load profile of %s: %v", identity.Identity, err)
+		}
+		connection, err := appclient.ConnectWallet(profile, identity.Peer, wallet, identity.Identity)
+		if err != nil {
+			log.Fatalf("failed to connect as %s: %v", identity.Identity, err)
+		}
+		connections = append(connections, connection)
+		return connection.GetNetwork(config.Channel), profile.MSPID()
+	}
+	var holders []tokenHolder
+	for _, identity := range holderIdentities {
+		network, _ := connect(identity)
+		holders = append(holders, token.NewContract(network, config.TokenChaincode))
+	}
+	var owners []assetOwner
+	for _, identity := range ownerIdentities {
+		network, mspID := connect(identity)
+		owners = append(owners, asset.NewContract(network, config.AssetChaincode, mspID))
+	}
+
+	seeder := NewSeeder(holders, owners)
+	seeder.Concurrency = *concurrency
+	seeder.Attempts = *attempts
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	start := time.Now()
+	reports, err := seeder.Seed(ctx, plan)
+	for _, report := range reports {
+		log.Print(report)
+	}
+	if err != nil {
+		log.Fatalf("seeding stopped after %s: %v", time.Since(start).Round(time.Second), err)
+	}
+	log.Printf("seeded in %s", time.Since(start).Round(time.Second))
+}
+
+// writePlan writes the plan as indented JSON to path
+func writePlan(path string, plan *Plan) error {
+	planJSON, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, planJSON, 0644)
+}
@@ -0,0 +1,231 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-secured-agreement/assettypes"
+)
+
+// Settings are the sizes and distributions of the seeded data
+type Settings struct {
+	// Prefix starts the account and asset IDs, so seeding the channel again with another prefix
+	// adds data instead of colliding with the earlier run
+	Prefix string
+	// Accounts is the number of accounts funded by transfers from the token holders
+	Accounts int
+	// BalanceAlpha is the shape of the Pareto distribution of the balances. The default 1.16 gives
+	// the 80/20 rule: a fifth of the accounts hold about four fifths of the tokens.
+	BalanceAlpha float64
+	// MinBalance and MaxBalance bound the balances
+	MinBalance int
+	MaxBalance int
+	// Allowances is the number of allowances the token holders give. Their spenders follow a Zipf
+	// distribution over the accounts, so a few accounts, like exchanges or merchants, are the
+	// spenders of many holders.
+	Allowances int
+	// MaxAllowance bounds the allowances, which are log-uniform from 1
+	MaxAllowance int
+	// Assets is the number of assets created. Their owners follow a Zipf distribution over the
+	// asset owners, so the first org owns the most.
+	Assets int
+}
+
+// DefaultSettings returns the settings of a ledger of ten thousand accounts and assets
+func DefaultSettings() Settings {
+	return Settings{
+		Prefix:       "load",
+		Accounts:     10000,
+		BalanceAlpha: 1.16,
+		MinBalance:   10,
+		MaxBalance:   1000000,
+		Allowances:   2000,
+		MaxAllowance: 10000,
+		Assets:       10000,
+	}
+}
+
+// Validate checks that the settings describe a ledger the holders and owners can seed
+func (s *Settings) Validate(holders int, owners int) error {
+	switch {
+	case s.Prefix == "":
+		return fmt.Errorf("prefix must be set")
+	case s.Accounts < 0 || s.Allowances < 0 || s.Assets < 0:
+		return fmt.Errorf("numbers of accounts, allowances and assets must not be negative")
+	case s.BalanceAlpha <= 0:
+		return fmt.Errorf("balance alpha must be positive")
+	case s.MinBalance < 1 || s.MaxBalance < s.MinBalance:
+		return fmt.Errorf("balances must be between a positive minimum and a maximum not below it")
+	case s.MaxAllowance < 1:
+		return fmt.Errorf("maximum allowance must be positive")
+	case (s.Accounts > 0 || s.Allowances > 0) && holders == 0:
+		return fmt.Errorf("accounts and allowances need an identity holding tokens")
+	case s.Assets > 0 && owners == 0:
+		return fmt.Errorf("assets need an identity owning assets")
+	case s.Allowances > holders*s.Accounts:
+		return fmt.Errorf("%d allowances exceed one per holder and account, %d", s.Allowances, holders*s.Accounts)
+	}
+	return nil
+}
+
+// AccountSeed is an account funded with Balance tokens by a transfer from the token holder Holder
+type AccountSeed struct {
+	ID      string `json:"id"`
+	Balance int    `json:"balance"`
+	Holder  int    `json:"holder"`
+}
+
+// AllowanceSeed is an allowance of Amount tokens the token holder Owner gives Spender
+type AllowanceSeed struct {
+	Owner   int    `json:"owner"`
+	Spender string `json:"spender"`
+	Amount  int    `json:"amount"`
+}
+
+// AssetSeed is an asset created by the asset owner Owner
+type AssetSeed struct {
+	ID          string                      `json:"id"`
+	Owner       int                         `json:"owner"`
+	Description string                      `json:"description"`
+	Properties  *assettypes.AssetProperties `json:"properties"`
+}
+
+// Plan is the data seeded onto the channel. Holders and owners are indexes of the identities
+// holding tokens and owning assets.
+type Plan struct {
+	Accounts   []*AccountSeed   `json:"accounts"`
+	Allowances []*AllowanceSeed `json:"allowances"`
+	Assets     []*AssetSeed     `json:"assets"`
+}
+
+// Kinds of the seeded assets, in decreasing frequency
+var assetKinds = []string{"bicycle", "vehicle", "electronics", "furniture", "machinery", "jewelry", "artwork", "instrument"}
+
+// Colors of the seeded assets, in decreasing frequency
+var colors = []string{"black", "white", "blue", "red", "green", "yellow", "silver", "orange"}
+
+// NewPlan returns the plan of the settings for holders token holders and owners asset owners. The
+// same seed and settings give the same plan, so ledgers seeded before and after a change hold the
+// same data.
+func NewPlan(seed int64, settings Settings, holders int, owners int) *Plan {
+	r := rand.New(rand.NewSource(seed))
+	plan := &Plan{}
+
+	for i := 0; i < settings.Accounts; i++ {
+		plan.Accounts = append(plan.Accounts, &AccountSeed{
+			ID:      fmt.Sprintf("%s-account-%07d", settings.Prefix, i),
+			Balance: pareto(r, settings.BalanceAlpha, settings.MinBalance, settings.MaxBalance),
+			Holder:  i % holders,
+		})
+	}
+
+	if settings.Allowances > 0 {
+		spenders := zipf(r, 1.2, settings.Accounts)
+		given := make(map[AllowanceSeed]bool)
+		for i := 0; i < settings.Allowances; i++ {
+			owner := i % holders
+			// a holder gives an account one allowance, so a spender it already has is replaced by
+			// the next account
+			spender := spenders()
+			for given[AllowanceSeed{Owner: owner, Spender: plan.Accounts[spender].ID}] {
+				spender = (spender + 1) % settings.Accounts
+			}
+			allowance := AllowanceSeed{Owner: owner, Spender: plan.Accounts[spender].ID}
+			given[allowance] = true
+			allowance.Amount = logUniform(r, settings.MaxAllowance)
+			plan.Allowances = append(plan.Allowances, &allowance)
+		}
+	}
+
+	if settings.Assets > 0 {
+		assetOwners := zipf(r, 1.5, owners)
+		kinds := zipf(r, 1.1, len(assetKinds))
+		assetColors := zipf(r, 1.1, len(colors))
+		for i := 0; i < settings.Assets; i++ {
+			id := fmt.Sprintf("%s-asset-%07d", settings.Prefix, i)
+			plan.Assets = append(plan.Assets, &AssetSeed{
+				ID:          id,
+				Owner:       assetOwners(),
+				Description: fmt.Sprintf("%s %d", assetKinds[kinds()], i),
+				Properties: &assettypes.AssetProperties{
+					ObjectType: "asset_properties",
+					ID:         id,
+					Color:      colors[assetColors()],
+					// sizes and values are log-normal: most assets are small and cheap, a few large
+					// and valuable
+					Size:           int(math.Ceil(math.Exp(3 + 0.8*r.NormFloat64()))),
+					AppraisedValue: assettypes.Decimal(fmt.Sprintf("%.2f", math.Exp(7+1.5*r.NormFloat64()))),
+					Salt:           fmt.Sprintf("%016x%016x", r.Uint64(), r.Uint64()),
+				},
+			})
+		}
+	}
+	return plan
+}
+
+// Supply returns the tokens each of holders token holders mints to fund its accounts
+func (p *Plan) Supply(holders int) []int {
+	supply := make([]int, holders)
+	for _, account := range p.Accounts {
+		supply[account.Holder] += account.Balance
+	}
+	return supply
+}
+
+// Summary describes the distributions of the plan, to check them before seeding
+func (p *Plan) Summary() string {
+	if len(p.Accounts) == 0 {
+		return fmt.Sprintf("%d allowances, %d assets", len(p.Allowances), len(p.Assets))
+	}
+	balances := make([]int, len(p.Accounts))
+	total := 0
+	for i, account := range p.Accounts {
+		balances[i] = account.Balance
+		total += account.Balance
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(balances)))
+	top := 0
+	for _, balance := range balances[:(len(balances)+4)/5] {
+		top += balance
+	}
+	return fmt.Sprintf("%d accounts holding %d tokens, median balance %d, largest %d, top 20%% holding %.0f%%; %d allowances; %d assets",
+		len(p.Accounts), total, balances[len(balances)/2], balances[0], 100*float64(top)/float64(total), len(p.Allowances), len(p.Assets))
+}
+
+// pareto returns a Pareto distributed integer of shape alpha from min, capped at max
+func pareto(r *rand.Rand, alpha float64, min int, max int) int {
+	value := float64(min) * math.Pow(1-r.Float64(), -1/alpha)
+	if value > float64(max) {
+		return max
+	}
+	return int(value)
+}
+
+// logUniform returns an integer from 1 to max whose logarithm is uniform, so small amounts are
+// as likely as large ones within each order of magnitude
+func logUniform(r *rand.Rand, max int) int {
+	value := int(math.Exp(r.Float64() * math.Log(float64(max)+1)))
+	if value < 1 {
+		return 1
+	}
+	if value > max {
+		return max
+	}
+	return value
+}
+
+// zipf returns a generator of Zipf distributed indexes below n with exponent s, index 0 being the
+// most frequent
+func zipf(r *rand.Rand, s float64, n int) func() int {
+	if n <= 1 {
+		return func() int { return 0 }
+	}
+	z := rand.NewZipf(r, s, 1, uint64(n-1))
+	return func() int { return int(z.Uint64()) }
+}
@@ -0,0 +1,109 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func testSettings() Settings {
+	settings := DefaultSettings()
+	settings.Accounts = 1000
+	settings.Allowances = 500
+	settings.Assets = 1000
+	return settings
+}
+
+func TestPlanIsDeterministic(t *testing.T) {
+	first := NewPlan(7, testSettings(), 2, 2)
+	if !reflect.DeepEqual(first, NewPlan(7, testSettings(), 2, 2)) {
+		t.Errorf("plans of the same seed differ")
+	}
+	if reflect.DeepEqual(first, NewPlan(8, testSettings(), 2, 2)) {
+		t.Errorf("plans of different seeds are the same")
+	}
+}
+
+func TestPlanBalances(t *testing.T) {
+	plan := NewPlan(1, testSettings(), 3, 1)
+
+	balances := make([]int, len(plan.Accounts))
+	total := 0
+	for i, account := range plan.Accounts {
+		if account.Balance < 10 || account.Balance > 1000000 {
+			t.Errorf("balance of %s is %d", account.ID, account.Balance)
+		}
+		if account.Holder != i%3 {
+			t.Errorf("%s is funded by holder %d", account.ID, account.Holder)
+		}
+		balances[i] = account.Balance
+		total += account.Balance
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(balances)))
+	top := 0
+	for _, balance := range balances[:200] {
+		top += balance
+	}
+	if top*2 < total {
+		t.Errorf("top 20%% of the accounts hold %d of %d tokens, want a heavy tail", top, total)
+	}
+	if balances[500] > 50 {
+		t.Errorf("median balance is %d, want most balances small", balances[500])
+	}
+
+	supply := plan.Supply(3)
+	if supply[0]+supply[1]+supply[2] != total {
+		t.Errorf("supply %v does not add up to %d", supply, total)
+	}
+}
+
+func TestPlanAllowances(t *testing.T) {
+	settings := testSettings()
+	settings.Accounts = 20
+	settings.Allowances = 40
+	plan := NewPlan(1, settings, 2, 1)
+
+	given := make(map[AllowanceSeed]bool)
+	spenders := make(map[string]int)
+	for _, allowance := range plan.Allowances {
+		key := AllowanceSeed{Owner: allowance.Owner, Spender: allowance.Spender}
+		if given[key] {
+			t.Errorf("holder %d gives %s two allowances", allowance.Owner, allowance.Spender)
+		}
+		given[key] = true
+		spenders[allowance.Spender]++
+		if allowance.Amount < 1 || allowance.Amount > settings.MaxAllowance {
+			t.Errorf("allowance of %s is %d", allowance.Spender, allowance.Amount)
+		}
+	}
+	// every holder gives every account an allowance
+	if len(given) != 40 || len(spenders) != 20 {
+		t.Errorf("%d allowances to %d spenders", len(given), len(spenders))
+	}
+
+	if err := settings.Validate(1, 1); err == nil {
+		t.Errorf("40 allowances of one holder to 20 accounts are valid")
+	}
+}
+
+func TestPlanAssets(t *testing.T) {
+	plan := NewPlan(1, testSettings(), 1, 2)
+
+	owned := make([]int, 2)
+	for _, asset := range plan.Assets {
+		owned[asset.Owner]++
+		if asset.Properties.ID != asset.ID || asset.Properties.Size < 1 || asset.Properties.AppraisedValue == "" || len(asset.Properties.Salt) != 32 {
+			t.Errorf("properties of %s are %+v", asset.ID, asset.Properties)
+		}
+	}
+	if owned[0] <= owned[1] || owned[1] == 0 {
+		t.Errorf("owners own %v assets, want most owned by the first", owned)
+	}
+	if plan.Assets[0].ID != "load-asset-0000000" || plan.Accounts[0].ID != "load-account-0000000" {
+		t.Errorf("first IDs are %s and %s", plan.Assets[0].ID, plan.Accounts[0].ID)
+	}
+}
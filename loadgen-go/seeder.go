@@ -0,0 +1,185 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-secured-agreement/assettypes"
+	"github.com/hyperledger/fabric-samples/internal/appclient"
+	"github.com/hyperledger/fabric-samples/token-erc-20/application-go/token"
+)
+
+// tokenHolder is the token contract of an identity holding tokens
+type tokenHolder interface {
+	Mint(amount int) (*token.TxResult, error)
+	Transfer(receiver string, amount int) (*token.TxResult, error)
+	Approve(spender string, amount int) (*token.TxResult, error)
+}
+
+// assetOwner is the asset contract of an identity owning assets
+type assetOwner interface {
+	CreateAsset(assetID string, publicDescription string, properties *assettypes.AssetProperties) (*assettypes.AssetResult, error)
+}
+
+// PhaseReport is the outcome of a phase of the seeding
+type PhaseReport struct {
+	Name     string
+	Done     int
+	Failed   int
+	Duration time.Duration
+}
+
+func (r *PhaseReport) String() string {
+	rate := float64(r.Done) / r.Duration.Seconds()
+	return fmt.Sprintf("%s: %d done, %d failed in %s (%.1f tx/s)", r.Name, r.Done, r.Failed, r.Duration.Round(time.Second), rate)
+}
+
+// Seeder submits the transactions of a plan
+type Seeder struct {
+	holders []tokenHolder
+	owners  []assetOwner
+	// Concurrency is the number of assets created at a time. The transactions of a token holder
+	// are submitted one at a time, since they conflict on its balance.
+	Concurrency int
+	// Attempts is the number of times a transaction invalidated when committed, e.g. by an MVCC
+	// read conflict, is submitted. Other errors are not retried, as the transaction may have been
+	// committed.
+	Attempts int
+	// ProgressInterval is the interval progress is logged at
+	ProgressInterval time.Duration
+}
+
+// NewSeeder returns a seeder submitting the token transactions as holders and creating the assets
+// as owners
+func NewSeeder(holders []tokenHolder, owners []assetOwner) *Seeder {
+	return &Seeder{holders: holders, owners: owners, Concurrency: 8, Attempts: 3, ProgressInterval: 10 * time.Second}
+}
+
+// Seed mints the supply of every holder, funds the accounts, gives the allowances and creates the
+// assets of the plan, in that order. Transactions that fail are logged and counted in the reports;
+// Seed stops when a holder cannot mint its supply or ctx is done.
+func (s *Seeder) Seed(ctx context.Context, plan *Plan) ([]*PhaseReport, error) {
+	var reports []*PhaseReport
+
+	mints := make([][]func() error, len(s.holders))
+	for i, supply := range plan.Supply(len(s.holders)) {
+		if supply > 0 {
+			holder, supply := s.holders[i], supply
+			mints[i] = append(mints[i], func() error {
+				_, err := holder.Mint(supply)
+				return err
+			})
+		}
+	}
+	report := s.run(ctx, "mint", mints)
+	reports = append(reports, report)
+	if report.Failed > 0 {
+		return reports, fmt.Errorf("failed to mint the supply of %d token holders", report.Failed)
+	}
+
+	transfers := make([][]func() error, len(s.holders))
+	for _, account := range plan.Accounts {
+		holder, account := s.holders[account.Holder], account
+		transfers[account.Holder] = append(transfers[account.Holder], func() error {
+			_, err := holder.Transfer(account.ID, account.Balance)
+			return err
+		})
+	}
+	reports = append(reports, s.run(ctx, "accounts", transfers))
+
+	approvals := make([][]func() error, len(s.holders))
+	for _, allowance := range plan.Allowances {
+		holder, allowance := s.holders[allowance.Owner], allowance
+		approvals[allowance.Owner] = append(approvals[allowance.Owner], func() error {
+			_, err := holder.Approve(allowance.Spender, allowance.Amount)
+			return err
+		})
+	}
+	reports = append(reports, s.run(ctx, "allowances", approvals))
+
+	creates := make([][]func() error, s.Concurrency)
+	for i, asset := range plan.Assets {
+		owner, asset := s.owners[asset.Owner], asset
+		creates[i%s.Concurrency] = append(creates[i%s.Concurrency], func() error {
+			_, err := owner.CreateAsset(asset.ID, asset.Description, asset.Properties)
+			return err
+		})
+	}
+	reports = append(reports, s.run(ctx, "assets", creates))
+
+	return reports, ctx.Err()
+}
+
+// run runs the transactions of a phase, each queue in order on a worker of its own, logging its
+// progress
+func (s *Seeder) run(ctx context.Context, name string, queues [][]func() error) *PhaseReport {
+	total := 0
+	for _, queue := range queues {
+		total += len(queue)
+	}
+	report := &PhaseReport{Name: name}
+	if total == 0 {
+		return report
+	}
+
+	start := time.Now()
+	var done, failed int64
+	stopProgress := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(s.ProgressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				log.Printf("%s: %d of %d done, %d failed", name, atomic.LoadInt64(&done), total, atomic.LoadInt64(&failed))
+			case <-stopProgress:
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for _, queue := range queues {
+		wg.Add(1)
+		go func(queue []func() error) {
+			defer wg.Done()
+			for _, submit := range queue {
+				if ctx.Err() != nil {
+					return
+				}
+				err := s.submit(submit)
+				if err != nil {
+					log.Printf("%s: %v", name, err)
+					atomic.AddInt64(&failed, 1)
+					continue
+				}
+				atomic.AddInt64(&done, 1)
+			}
+		}(queue)
+	}
+	wg.Wait()
+	close(stopProgress)
+
+	report.Done, report.Failed, report.Duration = int(done), int(failed), time.Since(start)
+	return report
+}
+
+// submit submits a transaction, again while it is invalidated when committed
+func (s *Seeder) submit(submit func() error) error {
+	var err error
+	for attempt := 0; attempt < s.Attempts; attempt++ {
+		err = submit()
+		if err == nil || appclient.ParseError(err).Code != appclient.CodeCommitFailed {
+			return err
+		}
+	}
+	return err
+}
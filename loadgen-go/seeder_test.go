@@ -0,0 +1,156 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-secured-agreement/assettypes"
+	"github.com/hyperledger/fabric-samples/internal/appclient"
+	"github.com/hyperledger/fabric-samples/token-erc-20/application-go/token"
+)
+
+// fakeLedger records the transactions of the fake contracts, failing the first submissions of the
+// accounts and assets in conflicts and every submission of those in rejected
+type fakeLedger struct {
+	mu         sync.Mutex
+	minted     map[int]int
+	balances   map[string]int
+	allowances map[string]int
+	assets     map[string]int
+	conflicts  map[string]int
+	rejected   map[string]bool
+}
+
+func newFakeLedger() *fakeLedger {
+	return &fakeLedger{
+		minted:     make(map[int]int),
+		balances:   make(map[string]int),
+		allowances: make(map[string]int),
+		assets:     make(map[string]int),
+		conflicts:  make(map[string]int),
+		rejected:   make(map[string]bool),
+	}
+}
+
+func (l *fakeLedger) fail(id string) error {
+	if l.rejected[id] {
+		return &appclient.Error{Code: "INSUFFICIENT_FUNDS", Message: "rejected " + id}
+	}
+	if l.conflicts[id] > 0 {
+		l.conflicts[id]--
+		return &appclient.Error{Code: appclient.CodeCommitFailed, Message: "conflict on " + id}
+	}
+	return nil
+}
+
+type fakeHolder struct {
+	ledger *fakeLedger
+	index  int
+}
+
+func (h *fakeHolder) Mint(amount int) (*token.TxResult, error) {
+	h.ledger.mu.Lock()
+	defer h.ledger.mu.Unlock()
+	h.ledger.minted[h.index] += amount
+	return &token.TxResult{}, nil
+}
+
+func (h *fakeHolder) Transfer(receiver string, amount int) (*token.TxResult, error) {
+	h.ledger.mu.Lock()
+	defer h.ledger.mu.Unlock()
+	if err := h.ledger.fail(receiver); err != nil {
+		return nil, err
+	}
+	h.ledger.balances[receiver] += amount
+	return &token.TxResult{}, nil
+}
+
+func (h *fakeHolder) Approve(spender string, amount int) (*token.TxResult, error) {
+	h.ledger.mu.Lock()
+	defer h.ledger.mu.Unlock()
+	h.ledger.allowances[spender] += amount
+	return &token.TxResult{}, nil
+}
+
+type fakeOwner struct {
+	ledger *fakeLedger
+	index  int
+}
+
+func (o *fakeOwner) CreateAsset(assetID string, publicDescription string, properties *assettypes.AssetProperties) (*assettypes.AssetResult, error) {
+	o.ledger.mu.Lock()
+	defer o.ledger.mu.Unlock()
+	if err := o.ledger.fail(assetID); err != nil {
+		return nil, err
+	}
+	o.ledger.assets[assetID] = o.index
+	return &assettypes.AssetResult{}, nil
+}
+
+func TestSeed(t *testing.T) {
+	settings := testSettings()
+	settings.Accounts = 100
+	settings.Allowances = 30
+	settings.Assets = 50
+	plan := NewPlan(1, settings, 2, 2)
+
+	ledger := newFakeLedger()
+	ledger.conflicts[plan.Accounts[3].ID] = 2
+	ledger.conflicts[plan.Assets[4].ID] = 5
+	ledger.rejected[plan.Accounts[5].ID] = true
+	seeder := NewSeeder(
+		[]tokenHolder{&fakeHolder{ledger, 0}, &fakeHolder{ledger, 1}},
+		[]assetOwner{&fakeOwner{ledger, 0}, &fakeOwner{ledger, 1}},
+	)
+	seeder.Concurrency = 4
+
+	reports, err := seeder.Seed(context.Background(), plan)
+	if err != nil {
+		t.Fatalf("failed to seed: %v", err)
+	}
+	want := []PhaseReport{{Name: "mint", Done: 2}, {Name: "accounts", Done: 99, Failed: 1}, {Name: "allowances", Done: 30}, {Name: "assets", Done: 49, Failed: 1}}
+	for i, report := range reports {
+		if report.Name != want[i].Name || report.Done != want[i].Done || report.Failed != want[i].Failed {
+			t.Errorf("report %d is %v, want %+v", i, report, want[i])
+		}
+	}
+
+	supply := plan.Supply(2)
+	if ledger.minted[0] != supply[0] || ledger.minted[1] != supply[1] {
+		t.Errorf("minted %v, want %v", ledger.minted, supply)
+	}
+	// the account conflicting twice is funded on the third attempt, once
+	if ledger.balances[plan.Accounts[3].ID] != plan.Accounts[3].Balance {
+		t.Errorf("balance of %s is %d, want %d", plan.Accounts[3].ID, ledger.balances[plan.Accounts[3].ID], plan.Accounts[3].Balance)
+	}
+	if _, ok := ledger.assets[plan.Assets[4].ID]; ok {
+		t.Errorf("asset conflicting on every attempt was created")
+	}
+	for _, asset := range plan.Assets[5:] {
+		if owner, ok := ledger.assets[asset.ID]; !ok || owner != asset.Owner {
+			t.Errorf("%s is owned by %d, want %d", asset.ID, owner, asset.Owner)
+		}
+	}
+}
+
+func TestSeedStopsWhenMintFails(t *testing.T) {
+	plan := NewPlan(1, testSettings(), 1, 1)
+	seeder := NewSeeder([]tokenHolder{failingHolder{}}, []assetOwner{&fakeOwner{newFakeLedger(), 0}})
+
+	reports, err := seeder.Seed(context.Background(), plan)
+	if err == nil || len(reports) != 1 {
+		t.Errorf("seeding without a supply returned %v after %d phases", err, len(reports))
+	}
+}
+
+type failingHolder struct{ *fakeHolder }
+
+func (failingHolder) Mint(amount int) (*token.TxResult, error) {
+	return nil, errors.New("client is not allowed to mint")
+}
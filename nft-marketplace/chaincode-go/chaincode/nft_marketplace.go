@@ -0,0 +1,425 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil"
+)
+
+// tokenChaincodeName is the name the token-erc-20 chaincode that sales are paid in is deployed under
+const tokenChaincodeName = "token_erc20"
+
+// object names for prefix
+const (
+	nftPrefix        = "nft"
+	ownerNFTPrefix   = "owner~nft"
+	creatorNFTPrefix = "creator~nft"
+	royaltyPrefix    = "royalty"
+)
+
+// basisPoints is 100%, royalties are expressed in basis points of the sale price
+const basisPoints = 10000
+
+// maxRoyaltyBps caps the royalty a creator can set at mint, so a token cannot be made unsellable
+const maxRoyaltyBps = 2500
+
+// maxPrice bounds list prices so that price times royalty cannot overflow
+const maxPrice = 1000000000000
+
+// SmartContract provides ERC-721 style functions for minting and transferring non-fungible tokens
+// and a marketplace that pays the creator's royalty on every sale
+type SmartContract struct {
+	contractapi.Contract
+}
+
+// NFT is a non-fungible token. RoyaltyBps is fixed by the creator at mint and paid to
+// RoyaltyRecipient out of every marketplace sale. Approved may transfer the token on the owner's
+// behalf until it next changes owner.
+type NFT struct {
+	ObjectType       string `json:"objectType"`
+	ID               string `json:"tokenID"`
+	URI              string `json:"tokenURI"`
+	Creator          string `json:"creator"`
+	Owner            string `json:"owner"`
+	Approved         string `json:"approved"`
+	RoyaltyRecipient string `json:"royaltyRecipient"`
+	RoyaltyBps       int    `json:"royaltyBps"`
+	ListPrice        int    `json:"listPrice"`
+	Sales            int    `json:"sales"`
+}
+
+// RoyaltyPayment records the royalty paid to a token's creator out of one sale
+type RoyaltyPayment struct {
+	ObjectType string    `json:"objectType"`
+	TokenID    string    `json:"tokenID"`
+	Creator    string    `json:"creator"`
+	Recipient  string    `json:"recipient"`
+	Seller     string    `json:"seller"`
+	Buyer      string    `json:"buyer"`
+	Price      int       `json:"price"`
+	Amount     int       `json:"amount"`
+	TxID       string    `json:"txID"`
+	PaidAt     time.Time `json:"paidAt"`
+}
+
+// transferEvent is emitted whenever a token changes owner. Price and Royalty are set for
+// marketplace sales.
+type transferEvent struct {
+	TokenID string `json:"tokenID"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Price   int    `json:"price"`
+	Royalty int    `json:"royalty"`
+}
+
+// approvalEvent is emitted when an owner approves an account to transfer a token
+type approvalEvent struct {
+	TokenID  string `json:"tokenID"`
+	Owner    string `json:"owner"`
+	Approved string `json:"approved"`
+}
+
+// Mint creates a token owned by the client, who becomes its creator. royaltyBps of 500 pays 5% of
+// every later sale to royaltyRecipient, which defaults to the creator when empty.
+func (s *SmartContract) Mint(ctx contractapi.TransactionContextInterface, tokenID string, tokenURI string, royaltyRecipient string, royaltyBps int) (*NFT, error) {
+	creator, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	if tokenID == "" || tokenURI == "" {
+		return nil, fmt.Errorf("token ID and URI must be set")
+	}
+	if royaltyBps < 0 || royaltyBps > maxRoyaltyBps {
+		return nil, fmt.Errorf("royalty must be between 0 and %d basis points", maxRoyaltyBps)
+	}
+	if royaltyRecipient == "" {
+		royaltyRecipient = creator
+	}
+
+	existing, err := _getNFT(ctx, tokenID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("the token %s already exists", tokenID)
+	}
+
+	nft := &NFT{
+		ObjectType:       nftPrefix,
+		ID:               tokenID,
+		URI:              tokenURI,
+		Creator:          creator,
+		Owner:            creator,
+		RoyaltyRecipient: royaltyRecipient,
+		RoyaltyBps:       royaltyBps,
+	}
+	err = _putNFT(ctx, nft)
+	if err != nil {
+		return nil, err
+	}
+
+	err = _putIndex(ctx, ownerNFTPrefix, creator, tokenID)
+	if err != nil {
+		return nil, err
+	}
+	err = _putIndex(ctx, creatorNFTPrefix, creator, tokenID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = _emitEvent(ctx, "Transfer", transferEvent{tokenID, "0x0", creator, 0, 0})
+	if err != nil {
+		return nil, err
+	}
+	return nft, nil
+}
+
+// SetRoyaltyRecipient is called by the creator of a token to have its royalties paid to another
+// account. The royalty rate cannot be changed after mint.
+func (s *SmartContract) SetRoyaltyRecipient(ctx contractapi.TransactionContextInterface, tokenID string, royaltyRecipient string) error {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	nft, err := s.ReadNFT(ctx, tokenID)
+	if err != nil {
+		return err
+	}
+	if clientID != nft.Creator {
+		return fmt.Errorf("only the creator can change the royalty recipient of token %s", tokenID)
+	}
+	if royaltyRecipient == "" {
+		return fmt.Errorf("royalty recipient must be set")
+	}
+
+	nft.RoyaltyRecipient = royaltyRecipient
+	return _putNFT(ctx, nft)
+}
+
+// Approve allows operator to transfer a token owned by the client. An empty operator revokes the
+// approval.
+func (s *SmartContract) Approve(ctx contractapi.TransactionContextInterface, operator string, tokenID string) error {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	nft, err := s.ReadNFT(ctx, tokenID)
+	if err != nil {
+		return err
+	}
+	if clientID != nft.Owner {
+		return fmt.Errorf("only the owner can approve transfers of token %s", tokenID)
+	}
+	if operator == nft.Owner {
+		return fmt.Errorf("cannot approve the owner of token %s", tokenID)
+	}
+
+	nft.Approved = operator
+	err = _putNFT(ctx, nft)
+	if err != nil {
+		return err
+	}
+
+	return _emitEvent(ctx, "Approval", approvalEvent{tokenID, nft.Owner, operator})
+}
+
+// TransferFrom moves a token from its owner to another account without payment. It is called by
+// the owner or the approved account. No royalty is due as nothing is paid; sales go through
+// ListToken and BuyToken.
+func (s *SmartContract) TransferFrom(ctx contractapi.TransactionContextInterface, from string, to string, tokenID string) error {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	nft, err := s.ReadNFT(ctx, tokenID)
+	if err != nil {
+		return err
+	}
+	if from != nft.Owner {
+		return fmt.Errorf("token %s is not owned by the sender", tokenID)
+	}
+	if clientID != nft.Owner && clientID != nft.Approved {
+		return fmt.Errorf("client is not allowed to transfer token %s", tokenID)
+	}
+	if to == "" || to == from {
+		return fmt.Errorf("receiver must be set and differ from the owner")
+	}
+
+	err = _changeOwner(ctx, nft, to)
+	if err != nil {
+		return err
+	}
+
+	return _emitEvent(ctx, "Transfer", transferEvent{tokenID, from, to, 0, 0})
+}
+
+// ListToken offers a token owned by the client for sale on the marketplace. A price of 0
+// withdraws the listing.
+func (s *SmartContract) ListToken(ctx contractapi.TransactionContextInterface, tokenID string, price int) error {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	nft, err := s.ReadNFT(ctx, tokenID)
+	if err != nil {
+		return err
+	}
+	if clientID != nft.Owner {
+		return fmt.Errorf("only the owner can list token %s", tokenID)
+	}
+	if price < 0 || price > maxPrice {
+		return fmt.Errorf("price %d is outside the allowed range 0 to %d", price, maxPrice)
+	}
+
+	nft.ListPrice = price
+	return _putNFT(ctx, nft)
+}
+
+// BuyToken buys a listed token. The buyer's payment is split through the token chaincode: the
+// royalty goes to the token's royalty recipient and the rest to the seller, in one BatchTransfer
+// debiting the buyer once. The royalty is recorded for the creator's royalty report.
+func (s *SmartContract) BuyToken(ctx contractapi.TransactionContextInterface, tokenID string) error {
+	buyer, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	nft, err := s.ReadNFT(ctx, tokenID)
+	if err != nil {
+		return err
+	}
+	if nft.ListPrice == 0 {
+		return fmt.Errorf("token %s is not for sale", tokenID)
+	}
+	if buyer == nft.Owner {
+		return fmt.Errorf("token %s is already owned by the buyer", tokenID)
+	}
+
+	seller := nft.Owner
+	price := nft.ListPrice
+	royalty := _royaltyAmount(nft, seller, buyer, price)
+
+	err = ledgerutil.TransferTokens(ctx, tokenChaincodeName,
+		ledgerutil.TokenPayment{Receiver: seller, Amount: price - royalty},
+		ledgerutil.TokenPayment{Receiver: nft.RoyaltyRecipient, Amount: royalty},
+	)
+	if err != nil {
+		return err
+	}
+	if royalty > 0 {
+		err = _putRoyaltyPayment(ctx, nft, seller, buyer, price, royalty)
+		if err != nil {
+			return err
+		}
+	}
+
+	nft.Sales++
+	err = _changeOwner(ctx, nft, buyer)
+	if err != nil {
+		return err
+	}
+
+	return _emitEvent(ctx, "Transfer", transferEvent{tokenID, seller, buyer, price, royalty})
+}
+
+// _royaltyAmount returns the royalty owed out of a sale of the token at price. Nothing is owed
+// when the royalty recipient is the seller or the buyer, who would pay it to itself.
+func _royaltyAmount(nft *NFT, seller string, buyer string, price int) int {
+	if seller == nft.RoyaltyRecipient || buyer == nft.RoyaltyRecipient {
+		return 0
+	}
+	return price * nft.RoyaltyBps / basisPoints
+}
+
+// _putRoyaltyPayment records a royalty paid out of a sale under the creator of the token
+func _putRoyaltyPayment(ctx contractapi.TransactionContextInterface, nft *NFT, seller string, buyer string, price int, amount int) error {
+	now, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return err
+	}
+	txID := ctx.GetStub().GetTxID()
+
+	payment := RoyaltyPayment{
+		ObjectType: royaltyPrefix,
+		TokenID:    nft.ID,
+		Creator:    nft.Creator,
+		Recipient:  nft.RoyaltyRecipient,
+		Seller:     seller,
+		Buyer:      buyer,
+		Price:      price,
+		Amount:     amount,
+		TxID:       txID,
+		PaidAt:     now,
+	}
+	paymentKey, err := ctx.GetStub().CreateCompositeKey(royaltyPrefix, []string{nft.Creator, nft.ID, txID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	paymentJSON, err := json.Marshal(payment)
+	if err != nil {
+		return fmt.Errorf("failed to marshal royalty payment: %v", err)
+	}
+	err = ctx.GetStub().PutState(paymentKey, paymentJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put royalty payment: %v", err)
+	}
+	return nil
+}
+
+// _changeOwner moves the token and the owner index to a new owner, clearing its listing and approval
+func _changeOwner(ctx contractapi.TransactionContextInterface, nft *NFT, newOwner string) error {
+	oldIndexKey, err := ctx.GetStub().CreateCompositeKey(ownerNFTPrefix, []string{nft.Owner, nft.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = ctx.GetStub().DelState(oldIndexKey)
+	if err != nil {
+		return fmt.Errorf("failed to delete owner index: %v", err)
+	}
+	err = _putIndex(ctx, ownerNFTPrefix, newOwner, nft.ID)
+	if err != nil {
+		return err
+	}
+
+	nft.Owner = newOwner
+	nft.Approved = ""
+	nft.ListPrice = 0
+	return _putNFT(ctx, nft)
+}
+
+// _putIndex writes an index entry pointing from account to token
+func _putIndex(ctx contractapi.TransactionContextInterface, prefix string, account string, tokenID string) error {
+	indexKey, err := ctx.GetStub().CreateCompositeKey(prefix, []string{account, tokenID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = ctx.GetStub().PutState(indexKey, []byte{0x00})
+	if err != nil {
+		return fmt.Errorf("failed to put %s index: %v", prefix, err)
+	}
+	return nil
+}
+
+// _getNFT reads a token, returning nil when it does not exist
+func _getNFT(ctx contractapi.TransactionContextInterface, tokenID string) (*NFT, error) {
+	nftKey, err := ctx.GetStub().CreateCompositeKey(nftPrefix, []string{tokenID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	nftJSON, err := ctx.GetStub().GetState(nftKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if nftJSON == nil {
+		return nil, nil
+	}
+
+	var nft NFT
+	err = json.Unmarshal(nftJSON, &nft)
+	if err != nil {
+		return nil, err
+	}
+	return &nft, nil
+}
+
+// _putNFT writes the token to the world state
+func _putNFT(ctx contractapi.TransactionContextInterface, nft *NFT) error {
+	nftKey, err := ctx.GetStub().CreateCompositeKey(nftPrefix, []string{nft.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	nftJSON, err := json.Marshal(nft)
+	if err != nil {
+		return fmt.Errorf("failed to marshal token: %v", err)
+	}
+	err = ctx.GetStub().PutState(nftKey, nftJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put token %s: %v", nft.ID, err)
+	}
+	return nil
+}
+
+// _emitEvent sets an event with a JSON payload
+func _emitEvent(ctx contractapi.TransactionContextInterface, name string, payload interface{}) error {
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	err = ctx.GetStub().SetEvent(name, payloadJSON)
+	if err != nil {
+		return fmt.Errorf("failed to set event: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// RoyaltyQuote is the royalty owed out of a sale of a token at a given price
+type RoyaltyQuote struct {
+	TokenID   string `json:"tokenID"`
+	Recipient string `json:"recipient"`
+	Price     int    `json:"price"`
+	Amount    int    `json:"amount"`
+	// SellerProceeds is the rest of the price, paid to the seller
+	SellerProceeds int `json:"sellerProceeds"`
+}
+
+// TokenRoyalties sums the royalties paid out of the sales of one token
+type TokenRoyalties struct {
+	TokenID   string `json:"tokenID"`
+	Sales     int    `json:"sales"`
+	Volume    int    `json:"volume"`
+	Royalties int    `json:"royalties"`
+}
+
+// RoyaltyReport sums the royalties paid to a creator, in total and per token. Only sales that paid
+// a royalty are counted, so primary sales by the royalty recipient are not.
+type RoyaltyReport struct {
+	Creator   string            `json:"creator"`
+	Sales     int               `json:"sales"`
+	Volume    int               `json:"volume"`
+	Royalties int               `json:"royalties"`
+	Tokens    []*TokenRoyalties `json:"tokens"`
+}
+
+// ReadNFT returns the token stored in the world state with the given ID
+func (s *SmartContract) ReadNFT(ctx contractapi.TransactionContextInterface, tokenID string) (*NFT, error) {
+	nft, err := _getNFT(ctx, tokenID)
+	if err != nil {
+		return nil, err
+	}
+	if nft == nil {
+		return nil, fmt.Errorf("the token %s does not exist", tokenID)
+	}
+	return nft, nil
+}
+
+// OwnerOf returns the owner of a token
+func (s *SmartContract) OwnerOf(ctx contractapi.TransactionContextInterface, tokenID string) (string, error) {
+	nft, err := s.ReadNFT(ctx, tokenID)
+	if err != nil {
+		return "", err
+	}
+	return nft.Owner, nil
+}
+
+// BalanceOf returns the number of tokens owned by an account
+func (s *SmartContract) BalanceOf(ctx contractapi.TransactionContextInterface, owner string) (int, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(ownerNFTPrefix, []string{owner})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get tokens from %s index: %v", ownerNFTPrefix, err)
+	}
+	defer resultsIterator.Close()
+
+	balance := 0
+	for resultsIterator.HasNext() {
+		_, err := resultsIterator.Next()
+		if err != nil {
+			return 0, err
+		}
+		balance++
+	}
+	return balance, nil
+}
+
+// GetTokensByOwner returns the tokens owned by an account
+func (s *SmartContract) GetTokensByOwner(ctx contractapi.TransactionContextInterface, owner string) ([]*NFT, error) {
+	return s._getTokensByIndex(ctx, ownerNFTPrefix, owner)
+}
+
+// GetTokensByCreator returns the tokens minted by an account, whoever owns them now
+func (s *SmartContract) GetTokensByCreator(ctx contractapi.TransactionContextInterface, creator string) ([]*NFT, error) {
+	return s._getTokensByIndex(ctx, creatorNFTPrefix, creator)
+}
+
+// RoyaltyInfo returns the royalty BuyToken would pay out of a sale of a token by its current owner
+// at salePrice to a buyer other than the royalty recipient, along the lines of EIP-2981
+func (s *SmartContract) RoyaltyInfo(ctx contractapi.TransactionContextInterface, tokenID string, salePrice int) (*RoyaltyQuote, error) {
+	nft, err := s.ReadNFT(ctx, tokenID)
+	if err != nil {
+		return nil, err
+	}
+	if salePrice < 0 || salePrice > maxPrice {
+		return nil, fmt.Errorf("price %d is outside the allowed range 0 to %d", salePrice, maxPrice)
+	}
+
+	amount := _royaltyAmount(nft, nft.Owner, "", salePrice)
+	return &RoyaltyQuote{
+		TokenID:        tokenID,
+		Recipient:      nft.RoyaltyRecipient,
+		Price:          salePrice,
+		Amount:         amount,
+		SellerProceeds: salePrice - amount,
+	}, nil
+}
+
+// GetRoyaltyReport returns the royalties paid out of the sales of a creator's tokens, in total and
+// per token in token ID order
+func (s *SmartContract) GetRoyaltyReport(ctx contractapi.TransactionContextInterface, creator string) (*RoyaltyReport, error) {
+	report := &RoyaltyReport{Creator: creator, Tokens: []*TokenRoyalties{}}
+	var current *TokenRoyalties
+	err := _forEachRoyaltyPayment(ctx, []string{creator}, func(payment *RoyaltyPayment) error {
+		// payments are keyed by creator then token, so each token's payments are adjacent
+		if current == nil || current.TokenID != payment.TokenID {
+			current = &TokenRoyalties{TokenID: payment.TokenID}
+			report.Tokens = append(report.Tokens, current)
+		}
+		current.Sales++
+		current.Volume += payment.Price
+		current.Royalties += payment.Amount
+
+		report.Sales++
+		report.Volume += payment.Price
+		report.Royalties += payment.Amount
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// GetRoyaltyPayments returns the royalties paid out of the sales of one of a creator's tokens
+func (s *SmartContract) GetRoyaltyPayments(ctx contractapi.TransactionContextInterface, creator string, tokenID string) ([]*RoyaltyPayment, error) {
+	payments := []*RoyaltyPayment{}
+	err := _forEachRoyaltyPayment(ctx, []string{creator, tokenID}, func(payment *RoyaltyPayment) error {
+		payments = append(payments, payment)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return payments, nil
+}
+
+// _getTokensByIndex reads the tokens an owner~nft or creator~nft index entry points to
+func (s *SmartContract) _getTokensByIndex(ctx contractapi.TransactionContextInterface, prefix string, account string) ([]*NFT, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(prefix, []string{account})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tokens from %s index: %v", prefix, err)
+	}
+	defer resultsIterator.Close()
+
+	nfts := []*NFT{}
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(response.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split composite key: %v", err)
+		}
+		if len(keyParts) != 2 {
+			return nil, fmt.Errorf("unexpected index key %s", response.Key)
+		}
+		nft, err := s.ReadNFT(ctx, keyParts[1])
+		if err != nil {
+			return nil, err
+		}
+		nfts = append(nfts, nft)
+	}
+	return nfts, nil
+}
+
+// _forEachRoyaltyPayment calls fn with every royalty payment under the partial key
+func _forEachRoyaltyPayment(ctx contractapi.TransactionContextInterface, attributes []string, fn func(payment *RoyaltyPayment) error) error {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(royaltyPrefix, attributes)
+	if err != nil {
+		return fmt.Errorf("failed to get royalty payments: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return err
+		}
+
+		var payment RoyaltyPayment
+		err = json.Unmarshal(response.Value, &payment)
+		if err != nil {
+			return err
+		}
+		err = fn(&payment)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
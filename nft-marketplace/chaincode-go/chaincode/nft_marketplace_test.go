@@ -0,0 +1,181 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/pkg/mockledger"
+	tokencc "github.com/hyperledger/fabric-samples/token-erc-20/chaincode-go/chaincode"
+)
+
+const marketplaceName = "nft_marketplace"
+
+// market is a mock ledger with the marketplace and the token chaincode sales are paid in deployed,
+// and clients holding tokens
+type market struct {
+	ledger *mockledger.Ledger
+	ids    map[*mockledger.Client]string
+}
+
+func newMarket(t *testing.T) *market {
+	t.Helper()
+	ledger := mockledger.New()
+	tokenContract := tokencc.NewContract()
+	token, err := contractapi.NewChaincode(tokenContract, tokencc.NewConfigContract(), tokencc.NewEventsContract())
+	if err != nil {
+		t.Fatal(err)
+	}
+	nft, err := contractapi.NewChaincode(new(SmartContract))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ledger.Deploy(tokenChaincodeName, token); err != nil {
+		t.Fatal(err)
+	}
+	if err := ledger.Deploy(marketplaceName, nft); err != nil {
+		t.Fatal(err)
+	}
+	return &market{ledger: ledger, ids: make(map[*mockledger.Client]string)}
+}
+
+// client enrolls a client of Org1MSP and funds its token account with balance
+func (m *market) client(t *testing.T, name string, balance int) *mockledger.Client {
+	t.Helper()
+	client, err := m.ledger.NewClient("Org1MSP", name, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := m.ledger.Evaluate(client, mockledger.Transaction{Chaincode: tokenChaincodeName, Function: "ClientAccountID"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.ids[client] = string(id)
+
+	stub, err := m.ledger.NewStub(nil, mockledger.Transaction{Chaincode: tokenChaincodeName})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stub.PutState(string(id), []byte(strconv.Itoa(balance))); err != nil {
+		t.Fatal(err)
+	}
+	if err := stub.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func (m *market) submit(client *mockledger.Client, function string, args ...string) error {
+	_, err := m.ledger.Submit(client, mockledger.Transaction{Chaincode: marketplaceName, Function: function, Args: args})
+	return err
+}
+
+// checkBalances fails the test when a client's token balance differs from want
+func (m *market) checkBalances(t *testing.T, want map[*mockledger.Client]int) {
+	t.Helper()
+	for client, balance := range want {
+		got := string(m.ledger.GetState(tokenChaincodeName, m.ids[client]))
+		if got != strconv.Itoa(balance) {
+			t.Errorf("balance of %s is %s, want %d", m.ids[client], got, balance)
+		}
+	}
+}
+
+func TestBuyTokenPaysSellerAndRoyalty(t *testing.T) {
+	m := newMarket(t)
+	creator := m.client(t, "creator", 0)
+	collector := m.client(t, "collector", 1000)
+	buyer := m.client(t, "buyer", 1000)
+
+	// a primary sale pays the creator the whole price
+	if err := m.submit(creator, "Mint", "art1", "ipfs://art1", "", "1000"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.submit(creator, "ListToken", "art1", "100"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.submit(collector, "BuyToken", "art1"); err != nil {
+		t.Fatal(err)
+	}
+	m.checkBalances(t, map[*mockledger.Client]int{creator: 100, collector: 900, buyer: 1000})
+
+	// a resale debits the buyer once with the price, paying the seller and the 10% royalty
+	if err := m.submit(collector, "ListToken", "art1", "200"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.submit(buyer, "BuyToken", "art1"); err != nil {
+		t.Fatal(err)
+	}
+	m.checkBalances(t, map[*mockledger.Client]int{creator: 120, collector: 1080, buyer: 800})
+
+	payload, err := m.ledger.Evaluate(buyer, mockledger.Transaction{Chaincode: marketplaceName, Function: "ReadNFT", Args: []string{"art1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var nft NFT
+	if err := json.Unmarshal(payload, &nft); err != nil {
+		t.Fatal(err)
+	}
+	if nft.Owner != m.ids[buyer] || nft.ListPrice != 0 || nft.Sales != 2 {
+		t.Errorf("token after the resale is %+v", nft)
+	}
+	payload, err = m.ledger.Evaluate(buyer, mockledger.Transaction{Chaincode: marketplaceName, Function: "GetRoyaltyReport", Args: []string{m.ids[creator]}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report RoyaltyReport
+	if err := json.Unmarshal(payload, &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Sales != 1 || report.Volume != 200 || report.Royalties != 20 {
+		t.Errorf("royalty report is %+v, want the resale", report)
+	}
+
+	// a buyer who cannot pay the price gets nothing and pays nothing
+	if err := m.submit(buyer, "ListToken", "art1", "5000"); err != nil {
+		t.Fatal(err)
+	}
+	err = m.submit(collector, "BuyToken", "art1")
+	if err == nil || !strings.Contains(err.Error(), "insufficient funds") {
+		t.Errorf("buying beyond the balance failed with %v, want insufficient funds", err)
+	}
+	m.checkBalances(t, map[*mockledger.Client]int{creator: 120, collector: 1080, buyer: 800})
+}
+
+func TestBuyTokenByRoyaltyRecipient(t *testing.T) {
+	m := newMarket(t)
+	creator := m.client(t, "creator", 1000)
+	collector := m.client(t, "collector", 1000)
+	if err := m.submit(creator, "Mint", "art1", "ipfs://art1", "", "1000"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.submit(creator, "ListToken", "art1", "100"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.submit(collector, "BuyToken", "art1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the creator buying the token back pays the collector the whole price and no royalty to itself
+	if err := m.submit(collector, "ListToken", "art1", "200"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.submit(creator, "BuyToken", "art1"); err != nil {
+		t.Fatal(err)
+	}
+	m.checkBalances(t, map[*mockledger.Client]int{creator: 900, collector: 1100})
+
+	payload, err := m.ledger.Evaluate(creator, mockledger.Transaction{Chaincode: marketplaceName, Function: "GetRoyaltyReport", Args: []string{m.ids[creator]}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report RoyaltyReport
+	if err := json.Unmarshal(payload, &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Sales != 0 || report.Royalties != 0 {
+		t.Errorf("royalty report is %+v, want no royalties", report)
+	}
+}
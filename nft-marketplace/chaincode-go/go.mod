@@ -0,0 +1,16 @@
+module github.com/hyperledger/fabric-samples/nft-marketplace/chaincode-go
+
+go 1.14
+
+require (
+	github.com/hyperledger/fabric-contract-api-go v1.2.2
+	github.com/hyperledger/fabric-samples/internal/ledgerutil v0.0.0
+	github.com/hyperledger/fabric-samples/pkg/mockledger v0.0.0
+	github.com/hyperledger/fabric-samples/token-erc-20/chaincode-go v0.0.0
+)
+
+replace (
+	github.com/hyperledger/fabric-samples/internal/ledgerutil => ../../internal/ledgerutil
+	github.com/hyperledger/fabric-samples/pkg/mockledger => ../../pkg/mockledger
+	github.com/hyperledger/fabric-samples/token-erc-20/chaincode-go => ../../token-erc-20/chaincode-go
+)
@@ -0,0 +1,23 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"log"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/nft-marketplace/chaincode-go/chaincode"
+)
+
+func main() {
+	nftChaincode, err := contractapi.NewChaincode(&chaincode.SmartContract{})
+	if err != nil {
+		log.Panicf("Error creating nft-marketplace chaincode: %v", err)
+	}
+
+	if err := nftChaincode.Start(); err != nil {
+		log.Panicf("Error starting nft-marketplace chaincode: %v", err)
+	}
+}
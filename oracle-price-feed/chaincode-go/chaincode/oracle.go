@@ -0,0 +1,377 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// This sample assumes Org1 administers the oracle: it creates feeds and whitelists reporters
+const oracleAdminMSPID = "Org1MSP"
+
+// object names for prefix
+const (
+	feedPrefix       = "feed"
+	reporterPrefix   = "reporter"
+	submissionPrefix = "submission"
+	roundPrefix      = "round"
+)
+
+// SmartContract provides functions for reporting and aggregating price observations
+type SmartContract struct {
+	contractapi.Contract
+}
+
+// Feed is a price or rate published by the oracle, e.g. "EUR/USD". Values are integers scaled by
+// 10^Decimals so no floating point arithmetic takes place on the peers.
+type Feed struct {
+	ObjectType     string `json:"objectType"`
+	ID             string `json:"feedID"`
+	Description    string `json:"description"`
+	Decimals       int    `json:"decimals"`
+	MinSubmissions int    `json:"minSubmissions"`
+	CurrentRound   int    `json:"currentRound"`
+}
+
+// Observation is one reporter's signed value for a round
+type Observation struct {
+	FeedID    string `json:"feedID"`
+	Round     int    `json:"round"`
+	Reporter  string `json:"reporter"`
+	Value     int64  `json:"value"`
+	Signature string `json:"signature"`
+	TxID      string `json:"txID"`
+}
+
+// Round is the aggregated result of a completed round
+type Round struct {
+	FeedID       string `json:"feedID"`
+	Round        int    `json:"round"`
+	Median       int64  `json:"median"`
+	Decimals     int    `json:"decimals"`
+	Submissions  int    `json:"submissions"`
+	FinalizedAt  string `json:"finalizedAt"`
+	FinalizeTxID string `json:"finalizeTxID"`
+}
+
+// ecdsaSignature is the ASN.1 structure of an ECDSA signature
+type ecdsaSignature struct {
+	R, S *big.Int
+}
+
+// CreateFeed is called by the oracle admin to publish a new feed
+func (s *SmartContract) CreateFeed(ctx contractapi.TransactionContextInterface, feedID string, description string, decimals int, minSubmissions int) error {
+	err := _requireAdmin(ctx)
+	if err != nil {
+		return err
+	}
+
+	if feedID == "" {
+		return fmt.Errorf("feed ID must be set")
+	}
+	if decimals < 0 || decimals > 18 {
+		return fmt.Errorf("decimals must be between 0 and 18")
+	}
+	if minSubmissions < 1 {
+		return fmt.Errorf("a round needs at least one submission")
+	}
+
+	feedKey, err := ctx.GetStub().CreateCompositeKey(feedPrefix, []string{feedID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	existing, err := ctx.GetStub().GetState(feedKey)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if existing != nil {
+		return fmt.Errorf("the feed %s already exists", feedID)
+	}
+
+	feed := Feed{
+		ObjectType:     feedPrefix,
+		ID:             feedID,
+		Description:    description,
+		Decimals:       decimals,
+		MinSubmissions: minSubmissions,
+		CurrentRound:   1,
+	}
+	return _putFeed(ctx, &feed)
+}
+
+// AddReporter whitelists a client identity to submit observations to a feed
+func (s *SmartContract) AddReporter(ctx contractapi.TransactionContextInterface, feedID string, reporter string) error {
+	err := _requireAdmin(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.GetFeed(ctx, feedID)
+	if err != nil {
+		return err
+	}
+	if reporter == "" {
+		return fmt.Errorf("reporter must be set")
+	}
+
+	reporterKey, err := ctx.GetStub().CreateCompositeKey(reporterPrefix, []string{feedID, reporter})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	return ctx.GetStub().PutState(reporterKey, []byte{0x00})
+}
+
+// RemoveReporter takes a reporter off a feed's whitelist
+func (s *SmartContract) RemoveReporter(ctx contractapi.TransactionContextInterface, feedID string, reporter string) error {
+	err := _requireAdmin(ctx)
+	if err != nil {
+		return err
+	}
+
+	reporterKey, err := ctx.GetStub().CreateCompositeKey(reporterPrefix, []string{feedID, reporter})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	return ctx.GetStub().DelState(reporterKey)
+}
+
+// SubmitObservation records a whitelisted reporter's value for the feed's current round.
+// signature is the base64 DER ECDSA signature, made with the reporter's enrollment key, over the
+// SHA-256 hash of "feedID:round:value". Only the submission key is written so that reporters
+// submitting in the same block do not conflict with each other.
+func (s *SmartContract) SubmitObservation(ctx contractapi.TransactionContextInterface, feedID string, round int, value int64, signature string) error {
+	reporter, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	feed, err := s.GetFeed(ctx, feedID)
+	if err != nil {
+		return err
+	}
+	if round != feed.CurrentRound {
+		return fmt.Errorf("round %d of feed %s is not open, the current round is %d", round, feedID, feed.CurrentRound)
+	}
+	if value <= 0 {
+		return fmt.Errorf("value must be a positive integer")
+	}
+
+	whitelisted, err := _isReporter(ctx, feedID, reporter)
+	if err != nil {
+		return err
+	}
+	if !whitelisted {
+		return fmt.Errorf("client is not a whitelisted reporter for feed %s", feedID)
+	}
+
+	err = _verifySignature(ctx, fmt.Sprintf("%s:%d:%d", feedID, round, value), signature)
+	if err != nil {
+		return err
+	}
+
+	submissionKey, err := ctx.GetStub().CreateCompositeKey(submissionPrefix, []string{feedID, _roundKeyPart(round), reporter})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	existing, err := ctx.GetStub().GetState(submissionKey)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if existing != nil {
+		return fmt.Errorf("reporter has already submitted for round %d of feed %s", round, feedID)
+	}
+
+	observation := Observation{
+		FeedID:    feedID,
+		Round:     round,
+		Reporter:  reporter,
+		Value:     value,
+		Signature: signature,
+		TxID:      ctx.GetStub().GetTxID(),
+	}
+	observationJSON, err := json.Marshal(observation)
+	if err != nil {
+		return fmt.Errorf("failed to marshal observation: %v", err)
+	}
+
+	return ctx.GetStub().PutState(submissionKey, observationJSON)
+}
+
+// FinalizeRound aggregates the current round once enough reporters have submitted. The result is
+// the median of the submitted values, which one dishonest reporter cannot move far. Any whitelisted
+// reporter or the admin can finalize.
+func (s *SmartContract) FinalizeRound(ctx contractapi.TransactionContextInterface, feedID string) (*Round, error) {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client id: %v", err)
+	}
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get MSPID: %v", err)
+	}
+	whitelisted, err := _isReporter(ctx, feedID, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if !whitelisted && clientMSPID != oracleAdminMSPID {
+		return nil, fmt.Errorf("client is not authorized to finalize rounds of feed %s", feedID)
+	}
+
+	feed, err := s.GetFeed(ctx, feedID)
+	if err != nil {
+		return nil, err
+	}
+
+	observations, err := s.GetObservations(ctx, feedID, feed.CurrentRound)
+	if err != nil {
+		return nil, err
+	}
+	if len(observations) < feed.MinSubmissions {
+		return nil, fmt.Errorf("round %d of feed %s has %d of the %d submissions it needs", feed.CurrentRound, feedID, len(observations), feed.MinSubmissions)
+	}
+
+	values := make([]int64, len(observations))
+	for i, observation := range observations {
+		values[i] = observation.Value
+	}
+
+	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+
+	result := Round{
+		FeedID:       feedID,
+		Round:        feed.CurrentRound,
+		Median:       median(values),
+		Decimals:     feed.Decimals,
+		Submissions:  len(values),
+		FinalizedAt:  time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos)).UTC().Format(time.RFC3339),
+		FinalizeTxID: ctx.GetStub().GetTxID(),
+	}
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal round: %v", err)
+	}
+
+	roundKey, err := ctx.GetStub().CreateCompositeKey(roundPrefix, []string{feedID, _roundKeyPart(result.Round)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = ctx.GetStub().PutState(roundKey, resultJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to put round: %v", err)
+	}
+
+	feed.CurrentRound++
+	err = _putFeed(ctx, feed)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ctx.GetStub().SetEvent("RoundFinalized", resultJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set event: %v", err)
+	}
+
+	return &result, nil
+}
+
+// median returns the middle value, or the mean of the two middle values rounded down.
+// The input is sorted in place so every peer computes the same result.
+func median(values []int64) int64 {
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	middle := len(values) / 2
+	if len(values)%2 == 1 {
+		return values[middle]
+	}
+	low, high := values[middle-1], values[middle]
+	return low + (high-low)/2
+}
+
+// _requireAdmin checks the client belongs to the oracle admin org
+func _requireAdmin(ctx contractapi.TransactionContextInterface) error {
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+	if clientMSPID != oracleAdminMSPID {
+		return fmt.Errorf("client from %s is not authorized to administer the oracle", clientMSPID)
+	}
+	return nil
+}
+
+// _isReporter returns true when the client is whitelisted for the feed
+func _isReporter(ctx contractapi.TransactionContextInterface, feedID string, reporter string) (bool, error) {
+	reporterKey, err := ctx.GetStub().CreateCompositeKey(reporterPrefix, []string{feedID, reporter})
+	if err != nil {
+		return false, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	whitelisted, err := ctx.GetStub().GetState(reporterKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	return whitelisted != nil, nil
+}
+
+// _verifySignature checks signature was made over message by the key of the client's certificate
+func _verifySignature(ctx contractapi.TransactionContextInterface, message string, signature string) error {
+	cert, err := ctx.GetClientIdentity().GetX509Certificate()
+	if err != nil {
+		return fmt.Errorf("failed to get client certificate: %v", err)
+	}
+	publicKey, ok := cert.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("client certificate does not hold an ECDSA key")
+	}
+
+	signatureDER, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("signature is not base64 encoded: %v", err)
+	}
+	var sig ecdsaSignature
+	rest, err := asn1.Unmarshal(signatureDER, &sig)
+	if err != nil || len(rest) != 0 || sig.R == nil || sig.S == nil {
+		return fmt.Errorf("signature is not a DER encoded ECDSA signature")
+	}
+
+	digest := sha256.Sum256([]byte(message))
+	if !ecdsa.Verify(publicKey, digest[:], sig.R, sig.S) {
+		return fmt.Errorf("signature does not match the observation %s", message)
+	}
+	return nil
+}
+
+// _roundKeyPart zero pads the round number so composite keys sort in round order
+func _roundKeyPart(round int) string {
+	return fmt.Sprintf("%010d", round)
+}
+
+// _putFeed writes the feed to the world state
+func _putFeed(ctx contractapi.TransactionContextInterface, feed *Feed) error {
+	feedKey, err := ctx.GetStub().CreateCompositeKey(feedPrefix, []string{feed.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	feedJSON, err := json.Marshal(feed)
+	if err != nil {
+		return fmt.Errorf("failed to marshal feed: %v", err)
+	}
+	err = ctx.GetStub().PutState(feedKey, feedJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put feed %s: %v", feed.ID, err)
+	}
+	return nil
+}
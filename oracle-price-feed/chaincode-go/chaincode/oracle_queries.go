@@ -0,0 +1,97 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// GetFeed returns the feed stored in the world state with the given ID
+func (s *SmartContract) GetFeed(ctx contractapi.TransactionContextInterface, feedID string) (*Feed, error) {
+	feedKey, err := ctx.GetStub().CreateCompositeKey(feedPrefix, []string{feedID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	feedJSON, err := ctx.GetStub().GetState(feedKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if feedJSON == nil {
+		return nil, fmt.Errorf("the feed %s does not exist", feedID)
+	}
+
+	var feed Feed
+	err = json.Unmarshal(feedJSON, &feed)
+	if err != nil {
+		return nil, err
+	}
+	return &feed, nil
+}
+
+// GetRound returns the aggregated result of a finalized round
+func (s *SmartContract) GetRound(ctx contractapi.TransactionContextInterface, feedID string, round int) (*Round, error) {
+	roundKey, err := ctx.GetStub().CreateCompositeKey(roundPrefix, []string{feedID, _roundKeyPart(round)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	roundJSON, err := ctx.GetStub().GetState(roundKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if roundJSON == nil {
+		return nil, fmt.Errorf("round %d of feed %s has not been finalized", round, feedID)
+	}
+
+	var result Round
+	err = json.Unmarshal(roundJSON, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetLatestRound returns the most recently finalized round of a feed. Consumer chaincodes call
+// this with InvokeChaincode to read the current price.
+func (s *SmartContract) GetLatestRound(ctx contractapi.TransactionContextInterface, feedID string) (*Round, error) {
+	feed, err := s.GetFeed(ctx, feedID)
+	if err != nil {
+		return nil, err
+	}
+	if feed.CurrentRound <= 1 {
+		return nil, fmt.Errorf("feed %s has no finalized rounds", feedID)
+	}
+	return s.GetRound(ctx, feedID, feed.CurrentRound-1)
+}
+
+// GetObservations returns the observations submitted for a round of a feed
+func (s *SmartContract) GetObservations(ctx contractapi.TransactionContextInterface, feedID string, round int) ([]*Observation, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(submissionPrefix, []string{feedID, _roundKeyPart(round)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get observations for feed %s: %v", feedID, err)
+	}
+	defer resultsIterator.Close()
+
+	var observations []*Observation
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var observation Observation
+		err = json.Unmarshal(response.Value, &observation)
+		if err != nil {
+			return nil, err
+		}
+		observations = append(observations, &observation)
+	}
+
+	return observations, nil
+}
@@ -0,0 +1,172 @@
+package chaincode
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/base64"
+	"fmt"
+	"testing"
+)
+
+const reporterMSPID = "Org2MSP"
+
+func checkError(t *testing.T, err error, expected string) {
+	t.Helper()
+	if expected == "" && err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected != "" && (err == nil || err.Error() != expected) {
+		t.Fatalf("expected error %q, got %v", expected, err)
+	}
+}
+
+func newKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	return key
+}
+
+// sign returns the signature a reporter submits with an observation
+func sign(t *testing.T, key *ecdsa.PrivateKey, feedID string, round int, value int64) string {
+	t.Helper()
+	digest := sha256.Sum256([]byte(fmt.Sprintf("%s:%d:%d", feedID, round, value)))
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	signature, err := asn1.Marshal(ecdsaSignature{r, s})
+	if err != nil {
+		t.Fatalf("failed to encode signature: %v", err)
+	}
+	return base64.StdEncoding.EncodeToString(signature)
+}
+
+// createFeed publishes the EUR/USD feed needing two submissions a round and whitelists reporters
+func createFeed(t *testing.T, stub *fakeStub, reporters ...string) {
+	t.Helper()
+	contract := new(SmartContract)
+	checkError(t, contract.CreateFeed(newContext(stub, "admin", oracleAdminMSPID), "EUR/USD", "Euro in US dollars", 4, 2), "")
+	for _, reporter := range reporters {
+		checkError(t, contract.AddReporter(newContext(stub, "admin", oracleAdminMSPID), "EUR/USD", reporter), "")
+	}
+}
+
+// submit records a correctly signed observation of the reporter
+func submit(t *testing.T, stub *fakeStub, reporter string, key *ecdsa.PrivateKey, round int, value int64) error {
+	t.Helper()
+	return new(SmartContract).SubmitObservation(newReporterContext(stub, reporter, reporterMSPID, key), "EUR/USD", round, value, sign(t, key, "EUR/USD", round, value))
+}
+
+func TestCreateFeed(t *testing.T) {
+	tests := []struct {
+		name           string
+		mspID          string
+		feedID         string
+		decimals       int
+		minSubmissions int
+		expected       string
+	}{
+		{"admin", oracleAdminMSPID, "GBP/USD", 4, 3, ""},
+		{"not admin", reporterMSPID, "GBP/USD", 4, 3, "client from Org2MSP is not authorized to administer the oracle"},
+		{"no feed ID", oracleAdminMSPID, "", 4, 3, "feed ID must be set"},
+		{"bad decimals", oracleAdminMSPID, "GBP/USD", 19, 3, "decimals must be between 0 and 18"},
+		{"no submissions", oracleAdminMSPID, "GBP/USD", 4, 0, "a round needs at least one submission"},
+		{"existing feed", oracleAdminMSPID, "EUR/USD", 4, 3, "the feed EUR/USD already exists"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stub := newFakeStub()
+			createFeed(t, stub)
+
+			err := new(SmartContract).CreateFeed(newContext(stub, "admin", test.mspID), test.feedID, "", test.decimals, test.minSubmissions)
+			checkError(t, err, test.expected)
+		})
+	}
+}
+
+func TestSubmitObservation(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	key := newKey(t)
+	createFeed(t, stub, "reporter1")
+
+	err := contract.AddReporter(newContext(stub, "reporter1", reporterMSPID), "EUR/USD", "reporter2")
+	checkError(t, err, "client from Org2MSP is not authorized to administer the oracle")
+
+	err = submit(t, stub, "reporter2", key, 1, 11000)
+	checkError(t, err, "client is not a whitelisted reporter for feed EUR/USD")
+
+	err = submit(t, stub, "reporter1", key, 2, 11000)
+	checkError(t, err, "round 2 of feed EUR/USD is not open, the current round is 1")
+
+	err = contract.SubmitObservation(newReporterContext(stub, "reporter1", reporterMSPID, key), "EUR/USD", 1, 11000, sign(t, key, "EUR/USD", 1, 12000))
+	checkError(t, err, "signature does not match the observation EUR/USD:1:11000")
+
+	err = contract.SubmitObservation(newContext(stub, "reporter1", reporterMSPID), "EUR/USD", 1, 11000, sign(t, key, "EUR/USD", 1, 11000))
+	checkError(t, err, "client certificate does not hold an ECDSA key")
+
+	checkError(t, submit(t, stub, "reporter1", key, 1, 11000), "")
+	err = submit(t, stub, "reporter1", key, 1, 11000)
+	checkError(t, err, "reporter has already submitted for round 1 of feed EUR/USD")
+
+	observations, err := contract.GetObservations(newContext(stub, "admin", oracleAdminMSPID), "EUR/USD", 1)
+	checkError(t, err, "")
+	if len(observations) != 1 || observations[0].Reporter != "reporter1" || observations[0].Value != 11000 {
+		t.Fatalf("unexpected observations %+v", observations)
+	}
+}
+
+func TestFinalizeRound(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	keys := []*ecdsa.PrivateKey{newKey(t), newKey(t), newKey(t)}
+	createFeed(t, stub, "reporter1", "reporter2", "reporter3")
+
+	checkError(t, submit(t, stub, "reporter1", keys[0], 1, 11000), "")
+	_, err := contract.FinalizeRound(newContext(stub, "reporter1", reporterMSPID), "EUR/USD")
+	checkError(t, err, "round 1 of feed EUR/USD has 1 of the 2 submissions it needs")
+
+	// the median of the three values ignores the outlier
+	checkError(t, submit(t, stub, "reporter2", keys[1], 1, 11020), "")
+	checkError(t, submit(t, stub, "reporter3", keys[2], 1, 99999), "")
+
+	_, err = contract.FinalizeRound(newContext(stub, "mallory", reporterMSPID), "EUR/USD")
+	checkError(t, err, "client is not authorized to finalize rounds of feed EUR/USD")
+
+	_, err = contract.GetLatestRound(newContext(stub, "consumer", reporterMSPID), "EUR/USD")
+	checkError(t, err, "feed EUR/USD has no finalized rounds")
+
+	result, err := contract.FinalizeRound(newContext(stub, "reporter2", reporterMSPID), "EUR/USD")
+	checkError(t, err, "")
+	if result.Median != 11020 || result.Submissions != 3 || result.Decimals != 4 {
+		t.Fatalf("unexpected round %+v", result)
+	}
+	if stub.eventName != "RoundFinalized" {
+		t.Fatalf("expected RoundFinalized event, got %q", stub.eventName)
+	}
+
+	latest, err := contract.GetLatestRound(newContext(stub, "consumer", reporterMSPID), "EUR/USD")
+	checkError(t, err, "")
+	if *latest != *result {
+		t.Fatalf("expected latest round %+v, got %+v", result, latest)
+	}
+
+	// the next round is open to submissions again and removed reporters are left out
+	checkError(t, contract.RemoveReporter(newContext(stub, "admin", oracleAdminMSPID), "EUR/USD", "reporter3"), "")
+	err = submit(t, stub, "reporter3", keys[2], 2, 11030)
+	checkError(t, err, "client is not a whitelisted reporter for feed EUR/USD")
+	checkError(t, submit(t, stub, "reporter1", keys[0], 2, 11010), "")
+	checkError(t, submit(t, stub, "reporter2", keys[1], 2, 11021), "")
+
+	result, err = contract.FinalizeRound(newContext(stub, "admin", oracleAdminMSPID), "EUR/USD")
+	checkError(t, err, "")
+	if result.Round != 2 || result.Median != 11015 {
+		t.Fatalf("unexpected round %+v", result)
+	}
+}
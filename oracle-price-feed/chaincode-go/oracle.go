@@ -0,0 +1,23 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"log"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/oracle-price-feed/chaincode-go/chaincode"
+)
+
+func main() {
+	oracleChaincode, err := contractapi.NewChaincode(&chaincode.SmartContract{})
+	if err != nil {
+		log.Panicf("Error creating oracle-price-feed chaincode: %v", err)
+	}
+
+	if err := oracleChaincode.Start(); err != nil {
+		log.Panicf("Error starting oracle-price-feed chaincode: %v", err)
+	}
+}
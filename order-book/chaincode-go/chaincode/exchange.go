@@ -0,0 +1,570 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// This sample assumes Org1 operates the exchange and lists the markets
+const operatorMSPID = "Org1MSP"
+
+// object names for prefix
+const (
+	marketPrefix     = "market"
+	orderPrefix      = "order"
+	bookPrefix       = "book"
+	ownerOrderPrefix = "owner~order"
+	tradePrefix      = "trade"
+	payoutPrefix     = "payout"
+)
+
+// order sides
+const (
+	sideBuy  = "BUY"
+	sideSell = "SELL"
+)
+
+// order and payout status values
+const (
+	statusOpen      = "OPEN"
+	statusFilled    = "FILLED"
+	statusCancelled = "CANCELLED"
+	statusPending   = "PENDING"
+	statusPaid      = "PAID"
+)
+
+// maxPrice bounds limit prices so book keys can be zero padded to a fixed width
+const maxPrice = 999999999999
+
+// maxMatchesPerOrder bounds the work one placement does. A larger order rests on the book with its
+// remaining quantity and is matched by later orders.
+const maxMatchesPerOrder = 50
+
+// SmartContract provides functions for limit order book markets between two ERC-20 deployments
+type SmartContract struct {
+	contractapi.Contract
+}
+
+// Market trades a base token for a quote token. Prices are quote tokens per base token. Funds of
+// open orders are held in the account of the market agent on both token chaincodes, which submits
+// the payout transactions, since a chaincode cannot hold tokens.
+type Market struct {
+	ObjectType string `json:"objectType"`
+	ID         string `json:"marketID"`
+	BaseToken  string `json:"baseToken"`
+	QuoteToken string `json:"quoteToken"`
+	Agent      string `json:"agent"`
+	Sequence   int    `json:"sequence"`
+	LastPrice  int    `json:"lastPrice"`
+}
+
+// Order is a limit order. Sequence is its place in the market's time priority.
+type Order struct {
+	ObjectType string `json:"objectType"`
+	ID         string `json:"orderID"`
+	MarketID   string `json:"marketID"`
+	Owner      string `json:"owner"`
+	Side       string `json:"side"`
+	Price      int    `json:"price"`
+	Quantity   int    `json:"quantity"`
+	Filled     int    `json:"filled"`
+	Sequence   int    `json:"sequence"`
+	Status     string `json:"status"`
+}
+
+// Trade is a match between a resting maker order and an incoming taker order, at the maker's price
+type Trade struct {
+	MarketID   string `json:"marketID"`
+	Sequence   int    `json:"sequence"`
+	MakerOrder string `json:"makerOrder"`
+	TakerOrder string `json:"takerOrder"`
+	Buyer      string `json:"buyer"`
+	Seller     string `json:"seller"`
+	Price      int    `json:"price"`
+	Quantity   int    `json:"quantity"`
+	TxID       string `json:"txID"`
+}
+
+// Payout is a token payment the market agent owes, for a trade, a price improvement or a cancellation
+type Payout struct {
+	ID        string `json:"payoutID"`
+	MarketID  string `json:"marketID"`
+	Token     string `json:"token"`
+	Recipient string `json:"recipient"`
+	Amount    int    `json:"amount"`
+	Status    string `json:"status"`
+}
+
+// orderPlacedEvent provides an organized struct for emitting an order with the trades it matched
+type orderPlacedEvent struct {
+	Order  *Order   `json:"order"`
+	Trades []*Trade `json:"trades"`
+}
+
+// CreateMarket lists a market between two token chaincodes
+func (s *SmartContract) CreateMarket(ctx contractapi.TransactionContextInterface, marketID string, baseToken string, quoteToken string, agent string) error {
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+	if clientMSPID != operatorMSPID {
+		return fmt.Errorf("client from %s is not authorized to create markets", clientMSPID)
+	}
+
+	if marketID == "" || baseToken == "" || quoteToken == "" || agent == "" {
+		return fmt.Errorf("market ID, token chaincodes and agent must be set")
+	}
+	if baseToken == quoteToken {
+		return fmt.Errorf("a market needs two different token chaincodes")
+	}
+
+	existing, err := _getMarket(ctx, marketID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("the market %s already exists", marketID)
+	}
+
+	market := Market{
+		ObjectType: marketPrefix,
+		ID:         marketID,
+		BaseToken:  baseToken,
+		QuoteToken: quoteToken,
+		Agent:      agent,
+	}
+	return _putMarket(ctx, &market)
+}
+
+// PlaceOrder places a limit order and matches it against the opposite side of the book in price-time
+// priority: best price first, then earliest order. A buy escrows price * quantity quote tokens and a
+// sell escrows quantity base tokens with the market agent. Any quantity left unmatched rests on the
+// book. The order ID is the transaction ID.
+func (s *SmartContract) PlaceOrder(ctx contractapi.TransactionContextInterface, marketID string, side string, price int, quantity int) (*Order, error) {
+	owner, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	if side != sideBuy && side != sideSell {
+		return nil, fmt.Errorf("side must be %s or %s", sideBuy, sideSell)
+	}
+	if price <= 0 || price > maxPrice {
+		return nil, fmt.Errorf("price must be between 1 and %d", maxPrice)
+	}
+	if quantity <= 0 {
+		return nil, fmt.Errorf("quantity must be a positive integer")
+	}
+
+	if !new(big.Int).Mul(big.NewInt(int64(price)), big.NewInt(int64(quantity))).IsInt64() {
+		return nil, fmt.Errorf("order value overflows")
+	}
+
+	market, err := s.GetMarket(ctx, marketID)
+	if err != nil {
+		return nil, err
+	}
+
+	if side == sideBuy {
+		err = _transferTokens(ctx, market.QuoteToken, market.Agent, price*quantity)
+	} else {
+		err = _transferTokens(ctx, market.BaseToken, market.Agent, quantity)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	market.Sequence++
+	order := Order{
+		ObjectType: orderPrefix,
+		ID:         ctx.GetStub().GetTxID(),
+		MarketID:   marketID,
+		Owner:      owner,
+		Side:       side,
+		Price:      price,
+		Quantity:   quantity,
+		Sequence:   market.Sequence,
+		Status:     statusOpen,
+	}
+
+	ownerKey, err := ctx.GetStub().CreateCompositeKey(ownerOrderPrefix, []string{owner, order.ID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = ctx.GetStub().PutState(ownerKey, []byte{0x00})
+	if err != nil {
+		return nil, fmt.Errorf("failed to put owner index: %v", err)
+	}
+
+	payouts := &payoutBatch{market: market}
+	trades, err := s.match(ctx, market, &order, payouts)
+	if err != nil {
+		return nil, err
+	}
+
+	if order.Status == statusOpen {
+		err = _addToBook(ctx, &order)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = _putOrder(ctx, &order)
+	if err != nil {
+		return nil, err
+	}
+	err = _putMarket(ctx, market)
+	if err != nil {
+		return nil, err
+	}
+
+	placedJSON, err := json.Marshal(orderPlacedEvent{&order, trades})
+	if err != nil {
+		return nil, fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	err = ctx.GetStub().SetEvent("OrderPlaced", placedJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set event: %v", err)
+	}
+
+	return &order, nil
+}
+
+// CancelOrder takes the client's open order off the book and pays back the escrow of its unfilled quantity
+func (s *SmartContract) CancelOrder(ctx contractapi.TransactionContextInterface, orderID string) error {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	order, err := s.GetOrder(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	if order.Owner != clientID {
+		return fmt.Errorf("only the owner can cancel order %s", orderID)
+	}
+	if order.Status != statusOpen {
+		return fmt.Errorf("order %s is %s", orderID, order.Status)
+	}
+	market, err := s.GetMarket(ctx, order.MarketID)
+	if err != nil {
+		return err
+	}
+
+	err = _removeFromBook(ctx, order)
+	if err != nil {
+		return err
+	}
+	order.Status = statusCancelled
+	err = _putOrder(ctx, order)
+	if err != nil {
+		return err
+	}
+
+	payouts := &payoutBatch{market: market}
+	remaining := order.Quantity - order.Filled
+	if order.Side == sideBuy {
+		return payouts.add(ctx, market.QuoteToken, order.Owner, order.Price*remaining)
+	}
+	return payouts.add(ctx, market.BaseToken, order.Owner, remaining)
+}
+
+// PayOut is submitted by the market agent to pay a pending payout from its account
+func (s *SmartContract) PayOut(ctx contractapi.TransactionContextInterface, payoutID string) error {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	payout, err := s.GetPayout(ctx, payoutID)
+	if err != nil {
+		return err
+	}
+	if payout.Status != statusPending {
+		return fmt.Errorf("payout %s is %s", payoutID, payout.Status)
+	}
+	market, err := s.GetMarket(ctx, payout.MarketID)
+	if err != nil {
+		return err
+	}
+	if clientID != market.Agent {
+		return fmt.Errorf("only the agent of market %s can pay out", market.ID)
+	}
+
+	err = _transferTokens(ctx, payout.Token, payout.Recipient, payout.Amount)
+	if err != nil {
+		return err
+	}
+
+	payout.Status = statusPaid
+	return _putPayout(ctx, payout)
+}
+
+// match fills the taker order against resting orders on the opposite side of the book. The book
+// keys sort in priority order, so the range query returns the best maker first.
+func (s *SmartContract) match(ctx contractapi.TransactionContextInterface, market *Market, taker *Order, payouts *payoutBatch) ([]*Trade, error) {
+	makerSide := sideSell
+	if taker.Side == sideSell {
+		makerSide = sideBuy
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(bookPrefix, []string{market.ID, makerSide})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the order book: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var trades []*Trade
+	for resultsIterator.HasNext() && taker.Filled < taker.Quantity && len(trades) < maxMatchesPerOrder {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		maker, err := s.GetOrder(ctx, string(response.Value))
+		if err != nil {
+			return nil, err
+		}
+		if taker.Side == sideBuy && maker.Price > taker.Price {
+			break
+		}
+		if taker.Side == sideSell && maker.Price < taker.Price {
+			break
+		}
+
+		quantity := taker.Quantity - taker.Filled
+		if makerRemaining := maker.Quantity - maker.Filled; makerRemaining < quantity {
+			quantity = makerRemaining
+		}
+
+		trade, err := s.settle(ctx, market, maker, taker, quantity, payouts)
+		if err != nil {
+			return nil, err
+		}
+		trades = append(trades, trade)
+
+		if maker.Filled == maker.Quantity {
+			maker.Status = statusFilled
+			err = ctx.GetStub().DelState(response.Key)
+			if err != nil {
+				return nil, fmt.Errorf("failed to remove order %s from the book: %v", maker.ID, err)
+			}
+		}
+		err = _putOrder(ctx, maker)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if taker.Filled == taker.Quantity {
+		taker.Status = statusFilled
+	}
+	return trades, nil
+}
+
+// settle records a trade at the maker's price and creates the payouts: base tokens to the buyer,
+// quote tokens to the seller, and the difference to a taker buyer whose limit was above the price
+func (s *SmartContract) settle(ctx contractapi.TransactionContextInterface, market *Market, maker *Order, taker *Order, quantity int, payouts *payoutBatch) (*Trade, error) {
+	buyer, seller := taker, maker
+	if taker.Side == sideSell {
+		buyer, seller = maker, taker
+	}
+
+	market.Sequence++
+	trade := Trade{
+		MarketID:   market.ID,
+		Sequence:   market.Sequence,
+		MakerOrder: maker.ID,
+		TakerOrder: taker.ID,
+		Buyer:      buyer.Owner,
+		Seller:     seller.Owner,
+		Price:      maker.Price,
+		Quantity:   quantity,
+		TxID:       ctx.GetStub().GetTxID(),
+	}
+	tradeJSON, err := json.Marshal(trade)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal trade: %v", err)
+	}
+	tradeKey, err := ctx.GetStub().CreateCompositeKey(tradePrefix, []string{market.ID, _padded(trade.Sequence)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = ctx.GetStub().PutState(tradeKey, tradeJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to put trade: %v", err)
+	}
+
+	maker.Filled += quantity
+	taker.Filled += quantity
+	market.LastPrice = trade.Price
+
+	err = payouts.add(ctx, market.BaseToken, buyer.Owner, quantity)
+	if err != nil {
+		return nil, err
+	}
+	err = payouts.add(ctx, market.QuoteToken, seller.Owner, trade.Price*quantity)
+	if err != nil {
+		return nil, err
+	}
+	if buyer.Price > trade.Price {
+		err = payouts.add(ctx, market.QuoteToken, buyer.Owner, (buyer.Price-trade.Price)*quantity)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &trade, nil
+}
+
+// payoutBatch numbers the payouts created by one transaction
+type payoutBatch struct {
+	market *Market
+	count  int
+}
+
+// add records a pending payout with the ID txID-n
+func (b *payoutBatch) add(ctx contractapi.TransactionContextInterface, token string, recipient string, amount int) error {
+	if amount == 0 {
+		return nil
+	}
+	b.count++
+	payout := Payout{
+		ID:        ctx.GetStub().GetTxID() + "-" + strconv.Itoa(b.count),
+		MarketID:  b.market.ID,
+		Token:     token,
+		Recipient: recipient,
+		Amount:    amount,
+		Status:    statusPending,
+	}
+	return _putPayout(ctx, &payout)
+}
+
+// _bookKey returns the order's key on the book. Sell orders sort by ascending price and buy orders by
+// descending price, so the best price comes first on both sides, then by sequence.
+func _bookKey(ctx contractapi.TransactionContextInterface, order *Order) (string, error) {
+	priceKey := order.Price
+	if order.Side == sideBuy {
+		priceKey = maxPrice - order.Price
+	}
+	bookKey, err := ctx.GetStub().CreateCompositeKey(bookPrefix, []string{order.MarketID, order.Side, _padded(priceKey), _padded(order.Sequence)})
+	if err != nil {
+		return "", fmt.Errorf("failed to create composite key: %v", err)
+	}
+	return bookKey, nil
+}
+
+// _addToBook rests the order on the book
+func _addToBook(ctx contractapi.TransactionContextInterface, order *Order) error {
+	bookKey, err := _bookKey(ctx, order)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(bookKey, []byte(order.ID))
+}
+
+// _removeFromBook takes the order off the book
+func _removeFromBook(ctx contractapi.TransactionContextInterface, order *Order) error {
+	bookKey, err := _bookKey(ctx, order)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().DelState(bookKey)
+}
+
+// _padded zero pads a number so composite keys sort numerically
+func _padded(n int) string {
+	return fmt.Sprintf("%012d", n)
+}
+
+// _transferTokens pays amount tokens on tokenChaincode from the invoking client's account to receiver
+func _transferTokens(ctx contractapi.TransactionContextInterface, tokenChaincode string, receiver string, amount int) error {
+	args := [][]byte{[]byte("Transfer"), []byte(receiver), []byte(strconv.Itoa(amount))}
+	response := ctx.GetStub().InvokeChaincode(tokenChaincode, args, "")
+	if response.Status != shim.OK {
+		return fmt.Errorf("failed to transfer %d tokens on %s: %s", amount, tokenChaincode, response.Message)
+	}
+	return nil
+}
+
+// _getMarket reads a market, returning nil when it does not exist
+func _getMarket(ctx contractapi.TransactionContextInterface, marketID string) (*Market, error) {
+	marketKey, err := ctx.GetStub().CreateCompositeKey(marketPrefix, []string{marketID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	marketJSON, err := ctx.GetStub().GetState(marketKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if marketJSON == nil {
+		return nil, nil
+	}
+
+	var market Market
+	err = json.Unmarshal(marketJSON, &market)
+	if err != nil {
+		return nil, err
+	}
+	return &market, nil
+}
+
+// _putMarket writes the market to the world state
+func _putMarket(ctx contractapi.TransactionContextInterface, market *Market) error {
+	marketKey, err := ctx.GetStub().CreateCompositeKey(marketPrefix, []string{market.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	marketJSON, err := json.Marshal(market)
+	if err != nil {
+		return fmt.Errorf("failed to marshal market: %v", err)
+	}
+	err = ctx.GetStub().PutState(marketKey, marketJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put market %s: %v", market.ID, err)
+	}
+	return nil
+}
+
+// _putOrder writes the order to the world state
+func _putOrder(ctx contractapi.TransactionContextInterface, order *Order) error {
+	orderKey, err := ctx.GetStub().CreateCompositeKey(orderPrefix, []string{order.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	orderJSON, err := json.Marshal(order)
+	if err != nil {
+		return fmt.Errorf("failed to marshal order: %v", err)
+	}
+	err = ctx.GetStub().PutState(orderKey, orderJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put order %s: %v", order.ID, err)
+	}
+	return nil
+}
+
+// _putPayout writes the payout to the world state
+func _putPayout(ctx contractapi.TransactionContextInterface, payout *Payout) error {
+	payoutKey, err := ctx.GetStub().CreateCompositeKey(payoutPrefix, []string{payout.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	payoutJSON, err := json.Marshal(payout)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payout: %v", err)
+	}
+	err = ctx.GetStub().PutState(payoutKey, payoutJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put payout %s: %v", payout.ID, err)
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// GetMarket returns the market stored in the world state with the given ID
+func (s *SmartContract) GetMarket(ctx contractapi.TransactionContextInterface, marketID string) (*Market, error) {
+	market, err := _getMarket(ctx, marketID)
+	if err != nil {
+		return nil, err
+	}
+	if market == nil {
+		return nil, fmt.Errorf("the market %s does not exist", marketID)
+	}
+	return market, nil
+}
+
+// GetOrder returns the order stored in the world state with the given ID
+func (s *SmartContract) GetOrder(ctx contractapi.TransactionContextInterface, orderID string) (*Order, error) {
+	orderKey, err := ctx.GetStub().CreateCompositeKey(orderPrefix, []string{orderID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	orderJSON, err := ctx.GetStub().GetState(orderKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if orderJSON == nil {
+		return nil, fmt.Errorf("the order %s does not exist", orderID)
+	}
+
+	var order Order
+	err = json.Unmarshal(orderJSON, &order)
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// GetOrdersByOwner returns every order an account has placed, open or closed
+func (s *SmartContract) GetOrdersByOwner(ctx contractapi.TransactionContextInterface, owner string) ([]*Order, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(ownerOrderPrefix, []string{owner})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get orders of %s: %v", owner, err)
+	}
+	defer resultsIterator.Close()
+
+	var orders []*Order
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(response.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split composite key: %v", err)
+		}
+		if len(keyParts) != 2 {
+			return nil, fmt.Errorf("unexpected index key %s", response.Key)
+		}
+
+		order, err := s.GetOrder(ctx, keyParts[1])
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+
+	return orders, nil
+}
+
+// GetOrderBook returns the open orders on one side of a market in priority order
+func (s *SmartContract) GetOrderBook(ctx contractapi.TransactionContextInterface, marketID string, side string) ([]*Order, error) {
+	if side != sideBuy && side != sideSell {
+		return nil, fmt.Errorf("side must be %s or %s", sideBuy, sideSell)
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(bookPrefix, []string{marketID, side})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the order book: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var orders []*Order
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		order, err := s.GetOrder(ctx, string(response.Value))
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+
+	return orders, nil
+}
+
+// GetTrades returns the trades of a market in the order they were matched
+func (s *SmartContract) GetTrades(ctx contractapi.TransactionContextInterface, marketID string) ([]*Trade, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(tradePrefix, []string{marketID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trades of market %s: %v", marketID, err)
+	}
+	defer resultsIterator.Close()
+
+	var trades []*Trade
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var trade Trade
+		err = json.Unmarshal(response.Value, &trade)
+		if err != nil {
+			return nil, err
+		}
+		trades = append(trades, &trade)
+	}
+
+	return trades, nil
+}
+
+// GetPayout returns the payout stored in the world state with the given ID
+func (s *SmartContract) GetPayout(ctx contractapi.TransactionContextInterface, payoutID string) (*Payout, error) {
+	payoutKey, err := ctx.GetStub().CreateCompositeKey(payoutPrefix, []string{payoutID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	payoutJSON, err := ctx.GetStub().GetState(payoutKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if payoutJSON == nil {
+		return nil, fmt.Errorf("the payout %s does not exist", payoutID)
+	}
+
+	var payout Payout
+	err = json.Unmarshal(payoutJSON, &payout)
+	if err != nil {
+		return nil, err
+	}
+	return &payout, nil
+}
+
+// GetPendingPayouts returns the payouts of a market its agent has yet to pay
+func (s *SmartContract) GetPendingPayouts(ctx contractapi.TransactionContextInterface, marketID string) ([]*Payout, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(payoutPrefix, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payouts: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var payouts []*Payout
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var payout Payout
+		err = json.Unmarshal(response.Value, &payout)
+		if err != nil {
+			return nil, err
+		}
+		if payout.MarketID == marketID && payout.Status == statusPending {
+			payouts = append(payouts, &payout)
+		}
+	}
+
+	return payouts, nil
+}
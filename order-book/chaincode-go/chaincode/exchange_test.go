@@ -0,0 +1,189 @@
+package chaincode
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+)
+
+const traderMSPID = "Org2MSP"
+
+// fakeToken stands in for a token chaincode. Transfers are paid from the payer's account.
+type fakeToken struct {
+	payer    string
+	balances map[string]int
+}
+
+func (f *fakeToken) invoke(args [][]byte) pb.Response {
+	if string(args[0]) != "Transfer" {
+		return shim.Error("unexpected function " + string(args[0]))
+	}
+	amount, err := strconv.Atoi(string(args[2]))
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if f.balances[f.payer] < amount {
+		return shim.Error(fmt.Sprintf("client account %s has insufficient funds", f.payer))
+	}
+	f.balances[f.payer] -= amount
+	f.balances[string(args[1])] += amount
+	return shim.Success(nil)
+}
+
+func checkError(t *testing.T, err error, expected string) {
+	t.Helper()
+	if expected == "" && err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected != "" && (err == nil || !strings.Contains(err.Error(), expected)) {
+		t.Fatalf("expected error containing %q, got %v", expected, err)
+	}
+}
+
+// payer sets the client paying on both token chaincodes
+func payer(account string, tokens ...*fakeToken) {
+	for _, token := range tokens {
+		token.payer = account
+	}
+}
+
+// createMarket lists market1 and rests two sell orders: 5 at 10 from seller1 and 3 at 9 from seller2
+func createMarket(t *testing.T, stub *fakeStub) (*fakeToken, *fakeToken, *Order) {
+	t.Helper()
+	base := &fakeToken{balances: map[string]int{"seller1": 5, "seller2": 3}}
+	quote := &fakeToken{balances: map[string]int{"buyer": 100}}
+	stub.chaincodes["base"] = base.invoke
+	stub.chaincodes["quote"] = quote.invoke
+
+	contract := new(SmartContract)
+	checkError(t, contract.CreateMarket(newContext(stub, "operator", operatorMSPID), "market1", "base", "quote", "agent"), "")
+
+	payer("seller1", base, quote)
+	order, err := contract.PlaceOrder(newContext(stub, "seller1", traderMSPID), "market1", sideSell, 10, 5)
+	checkError(t, err, "")
+	payer("seller2", base, quote)
+	_, err = contract.PlaceOrder(newContext(stub, "seller2", traderMSPID), "market1", sideSell, 9, 3)
+	checkError(t, err, "")
+	return base, quote, order
+}
+
+func TestCreateMarket(t *testing.T) {
+	tests := []struct {
+		name       string
+		mspID      string
+		marketID   string
+		quoteToken string
+		expected   string
+	}{
+		{"operator", operatorMSPID, "market2", "quote", ""},
+		{"not operator", traderMSPID, "market2", "quote", "client from Org2MSP is not authorized to create markets"},
+		{"same token", operatorMSPID, "market2", "base", "a market needs two different token chaincodes"},
+		{"existing market", operatorMSPID, "market1", "quote", "the market market1 already exists"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stub := newFakeStub()
+			createMarket(t, stub)
+
+			err := new(SmartContract).CreateMarket(newContext(stub, "operator", test.mspID), test.marketID, "base", test.quoteToken, "agent")
+			checkError(t, err, test.expected)
+		})
+	}
+}
+
+func TestPlaceOrderMatches(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	base, quote, _ := createMarket(t, stub)
+
+	book, err := contract.GetOrderBook(newContext(stub, "buyer", traderMSPID), "market1", sideSell)
+	checkError(t, err, "")
+	if len(book) != 2 || book[0].Owner != "seller2" || book[1].Owner != "seller1" {
+		t.Fatalf("expected the best price first, got %+v", book)
+	}
+
+	// a buy of 6 at 11 escrows 66 and fills 3 at 9, then 3 at 10
+	payer("buyer", base, quote)
+	order, err := contract.PlaceOrder(newContext(stub, "buyer", traderMSPID), "market1", sideBuy, 11, 6)
+	checkError(t, err, "")
+	if order.Status != statusFilled || quote.balances["buyer"] != 34 {
+		t.Fatalf("unexpected order %+v with balances %v", order, quote.balances)
+	}
+
+	trades, err := contract.GetTrades(newContext(stub, "buyer", traderMSPID), "market1")
+	checkError(t, err, "")
+	if len(trades) != 2 || trades[0].Seller != "seller2" || trades[0].Price != 9 || trades[1].Seller != "seller1" || trades[1].Price != 10 {
+		t.Fatalf("unexpected trades %+v", trades)
+	}
+
+	// each trade pays base to the buyer, quote to the seller and the price improvement back to the buyer
+	payouts, err := contract.GetPendingPayouts(newContext(stub, "agent", operatorMSPID), "market1")
+	checkError(t, err, "")
+	if len(payouts) != 6 {
+		t.Fatalf("expected 6 payouts, got %+v", payouts)
+	}
+
+	err = contract.PayOut(newContext(stub, "buyer", traderMSPID), payouts[0].ID)
+	checkError(t, err, "only the agent of market market1 can pay out")
+
+	payer("agent", base, quote)
+	for _, payout := range payouts {
+		checkError(t, contract.PayOut(newContext(stub, "agent", operatorMSPID), payout.ID), "")
+	}
+	if base.balances["buyer"] != 6 || quote.balances["buyer"] != 43 || quote.balances["seller1"] != 30 || quote.balances["seller2"] != 27 {
+		t.Fatalf("unexpected balances %v and %v", base.balances, quote.balances)
+	}
+	if base.balances["agent"] != 2 || quote.balances["agent"] != 0 {
+		t.Fatalf("expected the agent to hold only the escrow of the resting order, got %v and %v", base.balances, quote.balances)
+	}
+}
+
+func TestPlaceOrderInsufficientFunds(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	base, quote, _ := createMarket(t, stub)
+
+	payer("buyer", base, quote)
+	_, err := contract.PlaceOrder(newContext(stub, "buyer", traderMSPID), "market1", sideBuy, 11, 10)
+	checkError(t, err, "failed to transfer 110 tokens on quote: client account buyer has insufficient funds")
+
+	book, err := contract.GetOrderBook(newContext(stub, "buyer", traderMSPID), "market1", sideSell)
+	checkError(t, err, "")
+	if len(book) != 2 || book[0].Filled != 0 {
+		t.Fatalf("expected the book untouched, got %+v", book)
+	}
+}
+
+func TestCancelOrder(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	base, quote, order := createMarket(t, stub)
+
+	err := contract.CancelOrder(newContext(stub, "seller2", traderMSPID), order.ID)
+	checkError(t, err, "only the owner can cancel order "+order.ID)
+
+	checkError(t, contract.CancelOrder(newContext(stub, "seller1", traderMSPID), order.ID), "")
+	err = contract.CancelOrder(newContext(stub, "seller1", traderMSPID), order.ID)
+	checkError(t, err, fmt.Sprintf("order %s is CANCELLED", order.ID))
+
+	book, err := contract.GetOrderBook(newContext(stub, "buyer", traderMSPID), "market1", sideSell)
+	checkError(t, err, "")
+	if len(book) != 1 || book[0].Owner != "seller2" {
+		t.Fatalf("expected only the order of seller2 on the book, got %+v", book)
+	}
+
+	payouts, err := contract.GetPendingPayouts(newContext(stub, "agent", operatorMSPID), "market1")
+	checkError(t, err, "")
+	if len(payouts) != 1 || payouts[0].Recipient != "seller1" || payouts[0].Token != "base" || payouts[0].Amount != 5 {
+		t.Fatalf("unexpected payouts %+v", payouts)
+	}
+	payer("agent", base, quote)
+	checkError(t, contract.PayOut(newContext(stub, "agent", operatorMSPID), payouts[0].ID), "")
+	if base.balances["seller1"] != 5 {
+		t.Fatalf("unexpected balances %v", base.balances)
+	}
+}
@@ -0,0 +1,23 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"log"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/order-book/chaincode-go/chaincode"
+)
+
+func main() {
+	exchangeChaincode, err := contractapi.NewChaincode(&chaincode.SmartContract{})
+	if err != nil {
+		log.Panicf("Error creating order-book chaincode: %v", err)
+	}
+
+	if err := exchangeChaincode.Start(); err != nil {
+		log.Panicf("Error starting order-book chaincode: %v", err)
+	}
+}
@@ -0,0 +1,461 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil"
+)
+
+// defaultTokenChaincode is the name the token-erc-20 sample is deployed under in the README
+const defaultTokenChaincode = "token_erc20"
+
+// This sample assumes Org1 operates the payroll service and appoints the payroll agent
+const platformMSPID = "Org1MSP"
+
+// Define key names for options
+const payrollAgentKey = "payrollAgent"
+
+// object names for prefix
+const (
+	periodPrefix  = "period"
+	payslipPrefix = "payslip"
+)
+
+// period and payslip status values
+const (
+	statusDraft   = "DRAFT"
+	statusFunded  = "FUNDED"
+	statusPending = "PENDING"
+	statusPaid    = "PAID"
+	statusFailed  = "FAILED"
+)
+
+// maxPayslipsPerRun is the most payslips one RunPayroll pays, leaving room for the refund to the
+// employer in the 100 payments one BatchTransfer of the token chaincode makes
+const maxPayslipsPerRun = 99
+
+// SmartContract provides functions for running token payrolls
+type SmartContract struct {
+	contractapi.Contract
+}
+
+// Period is one pay period of an employer. Funding is transferred to the payroll agent, which holds it
+// until it submits RunPayroll, since a chaincode cannot hold tokens.
+type Period struct {
+	ObjectType     string `json:"objectType"`
+	ID             string `json:"periodID"`
+	Employer       string `json:"employer"`
+	Description    string `json:"description"`
+	TokenChaincode string `json:"tokenChaincode"`
+	PayrollAgent   string `json:"payrollAgent"`
+	Employees      int    `json:"employees"`
+	Total          int    `json:"total"`
+	Paid           int    `json:"paid"`
+	Refunded       int    `json:"refunded"`
+	Status         string `json:"status"`
+}
+
+// ScheduleEntry is one line of the salary schedule uploaded by the employer
+type ScheduleEntry struct {
+	EmployeeID string `json:"employeeID"`
+	Account    string `json:"account"`
+	Gross      int    `json:"gross"`
+	Deductions int    `json:"deductions"`
+}
+
+// Payslip records what an employee is paid for a period, and why a payment failed
+type Payslip struct {
+	PeriodID   string `json:"periodID"`
+	EmployeeID string `json:"employeeID"`
+	Account    string `json:"account"`
+	Gross      int    `json:"gross"`
+	Deductions int    `json:"deductions"`
+	Net        int    `json:"net"`
+	Status     string `json:"status"`
+	Reason     string `json:"reason"`
+	TxID       string `json:"txID"`
+}
+
+// event provides an organized struct for emitting payroll run summaries
+type event struct {
+	PeriodID string `json:"periodID"`
+	Paid     int    `json:"paid"`
+	Failed   int    `json:"failed"`
+	Refunded int    `json:"refunded"`
+}
+
+// SetPayrollAgent appoints the account that holds funded payrolls until they run.
+// Only the platform org can appoint the payroll agent.
+func (s *SmartContract) SetPayrollAgent(ctx contractapi.TransactionContextInterface, account string) error {
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+	if clientMSPID != platformMSPID {
+		return fmt.Errorf("client from %s is not authorized to appoint the payroll agent", clientMSPID)
+	}
+	if account == "" {
+		return fmt.Errorf("payroll agent account must be set")
+	}
+
+	return ctx.GetStub().PutState(payrollAgentKey, []byte(account))
+}
+
+// CreatePeriod opens a pay period for the client as employer. tokenChaincode defaults to token_erc20.
+func (s *SmartContract) CreatePeriod(ctx contractapi.TransactionContextInterface, periodID string, description string, tokenChaincode string) error {
+	employer, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	if periodID == "" {
+		return fmt.Errorf("period ID must be set")
+	}
+	if tokenChaincode == "" {
+		tokenChaincode = defaultTokenChaincode
+	}
+
+	payrollAgent, err := ctx.GetStub().GetState(payrollAgentKey)
+	if err != nil {
+		return fmt.Errorf("failed to read payroll agent: %v", err)
+	}
+	if payrollAgent == nil {
+		return fmt.Errorf("no payroll agent has been appointed")
+	}
+
+	existing, err := _getPeriod(ctx, periodID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("the period %s already exists", periodID)
+	}
+
+	period := Period{
+		ObjectType:     periodPrefix,
+		ID:             periodID,
+		Employer:       employer,
+		Description:    description,
+		TokenChaincode: tokenChaincode,
+		PayrollAgent:   string(payrollAgent),
+		Status:         statusDraft,
+	}
+	return _putPeriod(ctx, &period)
+}
+
+// UploadSchedule replaces the salary schedule of a draft period. schedule is a JSON array of
+// ScheduleEntry. Each employee is paid gross minus deductions.
+func (s *SmartContract) UploadSchedule(ctx contractapi.TransactionContextInterface, periodID string, schedule string) error {
+	period, err := _getEmployerPeriod(ctx, periodID)
+	if err != nil {
+		return err
+	}
+	if period.Status != statusDraft {
+		return fmt.Errorf("the schedule of period %s cannot change once it is %s", periodID, period.Status)
+	}
+
+	var entries []ScheduleEntry
+	err = json.Unmarshal([]byte(schedule), &entries)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal schedule: %v", err)
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("schedule has no entries")
+	}
+
+	// remove the payslips of a previously uploaded schedule
+	existing, err := s.GetPayslips(ctx, periodID)
+	if err != nil {
+		return err
+	}
+	for _, payslip := range existing {
+		payslipKey, err := ctx.GetStub().CreateCompositeKey(payslipPrefix, []string{periodID, payslip.EmployeeID})
+		if err != nil {
+			return fmt.Errorf("failed to create composite key: %v", err)
+		}
+		err = ctx.GetStub().DelState(payslipKey)
+		if err != nil {
+			return fmt.Errorf("failed to delete payslip: %v", err)
+		}
+	}
+
+	seen := make(map[string]bool)
+	total := 0
+	for _, entry := range entries {
+		if entry.EmployeeID == "" {
+			return fmt.Errorf("every schedule entry needs an employee ID")
+		}
+		if seen[entry.EmployeeID] {
+			return fmt.Errorf("employee %s is listed more than once", entry.EmployeeID)
+		}
+		seen[entry.EmployeeID] = true
+		if entry.Gross <= 0 || entry.Deductions < 0 || entry.Deductions > entry.Gross {
+			return fmt.Errorf("employee %s needs a positive gross pay and deductions no greater than it", entry.EmployeeID)
+		}
+
+		payslip := Payslip{
+			PeriodID:   periodID,
+			EmployeeID: entry.EmployeeID,
+			Account:    entry.Account,
+			Gross:      entry.Gross,
+			Deductions: entry.Deductions,
+			Net:        entry.Gross - entry.Deductions,
+			Status:     statusPending,
+		}
+		err = _putPayslip(ctx, &payslip)
+		if err != nil {
+			return err
+		}
+		total += payslip.Net
+	}
+
+	period.Employees = len(entries)
+	period.Total = total
+	return _putPeriod(ctx, period)
+}
+
+// FundPeriod transfers the net total of the schedule from the employer to the payroll agent
+func (s *SmartContract) FundPeriod(ctx contractapi.TransactionContextInterface, periodID string) error {
+	period, err := _getEmployerPeriod(ctx, periodID)
+	if err != nil {
+		return err
+	}
+	if period.Status != statusDraft {
+		return fmt.Errorf("period %s is already %s", periodID, period.Status)
+	}
+	if period.Total == 0 {
+		return fmt.Errorf("period %s has no salary schedule", periodID)
+	}
+
+	err = ledgerutil.TransferTokens(ctx, period.TokenChaincode, ledgerutil.TokenPayment{Receiver: period.PayrollAgent, Amount: period.Total})
+	if err != nil {
+		return err
+	}
+
+	period.Status = statusFunded
+	return _putPeriod(ctx, period)
+}
+
+// RunPayroll is submitted by the payroll agent to pay the payslips of a funded period. A payment to
+// an invalid account does not stop the run: the payslip is marked failed with the reason and its
+// amount is refunded to the employer, who can pay it later with PayFailedPayslip.
+//
+// The employees and the refund are paid with one BatchTransfer debiting the payroll agent once. One
+// run pays at most maxPayslipsPerRun payslips; the period stays FUNDED until the agent has run it
+// enough times to pay them all.
+func (s *SmartContract) RunPayroll(ctx contractapi.TransactionContextInterface, periodID string) error {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	period, err := s.GetPeriod(ctx, periodID)
+	if err != nil {
+		return err
+	}
+	if clientID != period.PayrollAgent {
+		return fmt.Errorf("only the payroll agent can run payroll for period %s", periodID)
+	}
+	if period.Status != statusFunded {
+		return fmt.Errorf("period %s is %s, not %s", periodID, period.Status, statusFunded)
+	}
+
+	payslips, err := s.GetPayslips(ctx, periodID)
+	if err != nil {
+		return err
+	}
+
+	var run []*Payslip
+	var payments []ledgerutil.TokenPayment
+	paid, failed, refunded := 0, 0, 0
+	period.Status = statusPaid
+	for _, payslip := range payslips {
+		if payslip.Status != statusPending {
+			continue
+		}
+		if len(run) == maxPayslipsPerRun {
+			period.Status = statusFunded
+			break
+		}
+		run = append(run, payslip)
+
+		err = _validateAccount(payslip.Account)
+		if err == nil && payslip.Account == period.PayrollAgent {
+			err = fmt.Errorf("account %s is the payroll agent", payslip.Account)
+		}
+
+		if err != nil {
+			payslip.Status = statusFailed
+			payslip.Reason = err.Error()
+			refunded += payslip.Net
+			failed++
+		} else {
+			payslip.Status = statusPaid
+			payslip.TxID = ctx.GetStub().GetTxID()
+			payments = append(payments, ledgerutil.TokenPayment{Receiver: payslip.Account, Amount: payslip.Net})
+			period.Paid += payslip.Net
+			paid++
+		}
+	}
+
+	payments = append(payments, ledgerutil.TokenPayment{Receiver: period.Employer, Amount: refunded})
+	err = ledgerutil.TransferTokens(ctx, period.TokenChaincode, payments...)
+	if err != nil {
+		return fmt.Errorf("failed to pay the payroll: %v", err)
+	}
+	for _, payslip := range run {
+		err = _putPayslip(ctx, payslip)
+		if err != nil {
+			return err
+		}
+	}
+
+	period.Refunded += refunded
+	err = _putPeriod(ctx, period)
+	if err != nil {
+		return err
+	}
+
+	summaryJSON, err := json.Marshal(event{periodID, paid, failed, refunded})
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	err = ctx.GetStub().SetEvent("PayrollRun", summaryJSON)
+	if err != nil {
+		return fmt.Errorf("failed to set event: %v", err)
+	}
+	return nil
+}
+
+// PayFailedPayslip lets the employer pay a failed payslip directly, to a corrected account
+func (s *SmartContract) PayFailedPayslip(ctx contractapi.TransactionContextInterface, periodID string, employeeID string, account string) error {
+	period, err := _getEmployerPeriod(ctx, periodID)
+	if err != nil {
+		return err
+	}
+
+	payslip, err := s.GetPayslip(ctx, periodID, employeeID)
+	if err != nil {
+		return err
+	}
+	if payslip.Status != statusFailed {
+		return fmt.Errorf("payslip of %s for period %s is %s", employeeID, periodID, payslip.Status)
+	}
+
+	err = _validateAccount(account)
+	if err != nil {
+		return err
+	}
+	err = ledgerutil.TransferTokens(ctx, period.TokenChaincode, ledgerutil.TokenPayment{Receiver: account, Amount: payslip.Net})
+	if err != nil {
+		return err
+	}
+
+	payslip.Account = account
+	payslip.Status = statusPaid
+	payslip.TxID = ctx.GetStub().GetTxID()
+	err = _putPayslip(ctx, payslip)
+	if err != nil {
+		return err
+	}
+
+	period.Paid += payslip.Net
+	return _putPeriod(ctx, period)
+}
+
+// _validateAccount checks the account is a client ID as returned by the token's ClientAccountID:
+// the base64 encoding of an x509 identity
+func _validateAccount(account string) error {
+	if account == "" {
+		return fmt.Errorf("account is empty")
+	}
+	decoded, err := base64.StdEncoding.DecodeString(account)
+	if err != nil || !strings.HasPrefix(string(decoded), "x509::") {
+		return fmt.Errorf("account %s is not a client account ID", account)
+	}
+	return nil
+}
+
+// _getEmployerPeriod reads a period and checks the client is its employer
+func _getEmployerPeriod(ctx contractapi.TransactionContextInterface, periodID string) (*Period, error) {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	period, err := _getPeriod(ctx, periodID)
+	if err != nil {
+		return nil, err
+	}
+	if period == nil {
+		return nil, fmt.Errorf("the period %s does not exist", periodID)
+	}
+	if period.Employer != clientID {
+		return nil, fmt.Errorf("only the employer can manage period %s", periodID)
+	}
+	return period, nil
+}
+
+// _getPeriod reads a period, returning nil when it does not exist
+func _getPeriod(ctx contractapi.TransactionContextInterface, periodID string) (*Period, error) {
+	periodKey, err := ctx.GetStub().CreateCompositeKey(periodPrefix, []string{periodID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	periodJSON, err := ctx.GetStub().GetState(periodKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if periodJSON == nil {
+		return nil, nil
+	}
+
+	var period Period
+	err = json.Unmarshal(periodJSON, &period)
+	if err != nil {
+		return nil, err
+	}
+	return &period, nil
+}
+
+// _putPeriod writes the period to the world state
+func _putPeriod(ctx contractapi.TransactionContextInterface, period *Period) error {
+	periodKey, err := ctx.GetStub().CreateCompositeKey(periodPrefix, []string{period.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	periodJSON, err := json.Marshal(period)
+	if err != nil {
+		return fmt.Errorf("failed to marshal period: %v", err)
+	}
+	err = ctx.GetStub().PutState(periodKey, periodJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put period %s: %v", period.ID, err)
+	}
+	return nil
+}
+
+// _putPayslip writes the payslip to the world state
+func _putPayslip(ctx contractapi.TransactionContextInterface, payslip *Payslip) error {
+	payslipKey, err := ctx.GetStub().CreateCompositeKey(payslipPrefix, []string{payslip.PeriodID, payslip.EmployeeID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	payslipJSON, err := json.Marshal(payslip)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payslip: %v", err)
+	}
+	err = ctx.GetStub().PutState(payslipKey, payslipJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put payslip: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// GetPeriod returns the pay period stored in the world state with the given ID
+func (s *SmartContract) GetPeriod(ctx contractapi.TransactionContextInterface, periodID string) (*Period, error) {
+	period, err := _getPeriod(ctx, periodID)
+	if err != nil {
+		return nil, err
+	}
+	if period == nil {
+		return nil, fmt.Errorf("the period %s does not exist", periodID)
+	}
+	return period, nil
+}
+
+// GetPayslip returns the payslip of an employee for a period
+func (s *SmartContract) GetPayslip(ctx contractapi.TransactionContextInterface, periodID string, employeeID string) (*Payslip, error) {
+	payslipKey, err := ctx.GetStub().CreateCompositeKey(payslipPrefix, []string{periodID, employeeID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	payslipJSON, err := ctx.GetStub().GetState(payslipKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if payslipJSON == nil {
+		return nil, fmt.Errorf("employee %s has no payslip for period %s", employeeID, periodID)
+	}
+
+	var payslip Payslip
+	err = json.Unmarshal(payslipJSON, &payslip)
+	if err != nil {
+		return nil, err
+	}
+	return &payslip, nil
+}
+
+// GetPayslips returns every payslip of a period
+func (s *SmartContract) GetPayslips(ctx contractapi.TransactionContextInterface, periodID string) ([]*Payslip, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(payslipPrefix, []string{periodID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payslips for period %s: %v", periodID, err)
+	}
+	defer resultsIterator.Close()
+
+	var payslips []*Payslip
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var payslip Payslip
+		err = json.Unmarshal(response.Value, &payslip)
+		if err != nil {
+			return nil, err
+		}
+		payslips = append(payslips, &payslip)
+	}
+
+	return payslips, nil
+}
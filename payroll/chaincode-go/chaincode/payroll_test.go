@@ -0,0 +1,240 @@
+package chaincode
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+)
+
+const employerMSPID = "Org2MSP"
+
+// fakeToken stands in for the token chaincode. Batches are paid from the payer's account,
+// debited once with the total as by the token chaincode.
+type fakeToken struct {
+	payer    string
+	balances map[string]int
+	batches  int
+}
+
+func (f *fakeToken) invoke(args [][]byte) pb.Response {
+	if string(args[0]) != "BatchTransfer" {
+		return shim.Error("unexpected function " + string(args[0]))
+	}
+	var payments []struct {
+		Receiver string `json:"receiver"`
+		Amount   int    `json:"amount"`
+	}
+	err := json.Unmarshal(args[1], &payments)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	total := 0
+	for _, payment := range payments {
+		total += payment.Amount
+	}
+	if f.balances[f.payer] < total {
+		return shim.Error(fmt.Sprintf("failed to transfer: client account %s has insufficient funds", f.payer))
+	}
+	f.balances[f.payer] -= total
+	for _, payment := range payments {
+		f.balances[payment.Receiver] += payment.Amount
+	}
+	f.batches++
+	return shim.Success(nil)
+}
+
+// account returns the client account ID the token chaincode gives a user
+func account(name string) string {
+	return base64.StdEncoding.EncodeToString([]byte("x509::CN=" + name + "::CN=ca.org1.example.com"))
+}
+
+var (
+	employer = account("employer")
+	agent    = account("agent")
+	alice    = account("alice")
+	bob      = account("bob")
+)
+
+func checkError(t *testing.T, err error, expected string) {
+	t.Helper()
+	if expected == "" && err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected != "" && (err == nil || !strings.Contains(err.Error(), expected)) {
+		t.Fatalf("expected error containing %q, got %v", expected, err)
+	}
+}
+
+func checkPayslip(t *testing.T, stub *fakeStub, employeeID string, status string) *Payslip {
+	t.Helper()
+	payslip, err := new(SmartContract).GetPayslip(newContext(stub, employer, employerMSPID), "2021-05", employeeID)
+	if err != nil {
+		t.Fatalf("failed to read payslip: %v", err)
+	}
+	if payslip.Status != status {
+		t.Fatalf("expected payslip of %s %s, got %s", employeeID, status, payslip.Status)
+	}
+	return payslip
+}
+
+// createPeriod appoints the payroll agent and opens period 2021-05 with the given schedule
+func createPeriod(t *testing.T, stub *fakeStub, schedule []ScheduleEntry) *fakeToken {
+	t.Helper()
+	token := &fakeToken{balances: make(map[string]int)}
+	stub.chaincodes[defaultTokenChaincode] = token.invoke
+
+	contract := new(SmartContract)
+	checkError(t, contract.SetPayrollAgent(newContext(stub, "operator", platformMSPID), agent), "")
+	checkError(t, contract.CreatePeriod(newContext(stub, employer, employerMSPID), "2021-05", "May 2021", ""), "")
+
+	scheduleJSON, err := json.Marshal(schedule)
+	if err != nil {
+		t.Fatalf("failed to marshal schedule: %v", err)
+	}
+	checkError(t, contract.UploadSchedule(newContext(stub, employer, employerMSPID), "2021-05", string(scheduleJSON)), "")
+	return token
+}
+
+// fundPeriod gives the employer just enough tokens and funds the period
+func fundPeriod(t *testing.T, stub *fakeStub, token *fakeToken, total int) {
+	t.Helper()
+	token.payer = employer
+	token.balances[employer] += total
+	checkError(t, new(SmartContract).FundPeriod(newContext(stub, employer, employerMSPID), "2021-05"), "")
+}
+
+func TestCreatePeriod(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+
+	err := contract.CreatePeriod(newContext(stub, employer, employerMSPID), "2021-05", "May 2021", "")
+	checkError(t, err, "no payroll agent has been appointed")
+
+	err = contract.SetPayrollAgent(newContext(stub, employer, employerMSPID), employer)
+	checkError(t, err, "client from Org2MSP is not authorized to appoint the payroll agent")
+
+	createPeriod(t, stub, []ScheduleEntry{{"E1", alice, 3000, 600}})
+	err = contract.CreatePeriod(newContext(stub, employer, employerMSPID), "2021-05", "May 2021", "")
+	checkError(t, err, "the period 2021-05 already exists")
+
+	err = contract.UploadSchedule(newContext(stub, bob, employerMSPID), "2021-05", "[]")
+	checkError(t, err, "only the employer can manage period 2021-05")
+
+	err = contract.UploadSchedule(newContext(stub, employer, employerMSPID), "2021-05", `[{"employeeID":"E1","gross":100,"deductions":101}]`)
+	checkError(t, err, "employee E1 needs a positive gross pay and deductions no greater than it")
+
+	period, err := contract.GetPeriod(newContext(stub, employer, employerMSPID), "2021-05")
+	checkError(t, err, "")
+	if period.Employees != 1 || period.Total != 2400 || period.TokenChaincode != defaultTokenChaincode {
+		t.Fatalf("unexpected period %+v", period)
+	}
+}
+
+func TestFundPeriodInsufficientFunds(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	token := createPeriod(t, stub, []ScheduleEntry{{"E1", alice, 3000, 600}})
+
+	token.payer = employer
+	token.balances[employer] = 2399
+	err := contract.FundPeriod(newContext(stub, employer, employerMSPID), "2021-05")
+	checkError(t, err, "failed to transfer tokens on token_erc20: failed to transfer: client account "+employer+" has insufficient funds")
+
+	period, err := contract.GetPeriod(newContext(stub, employer, employerMSPID), "2021-05")
+	checkError(t, err, "")
+	if period.Status != statusDraft {
+		t.Fatalf("expected period %s, got %s", statusDraft, period.Status)
+	}
+}
+
+func TestRunPayroll(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	token := createPeriod(t, stub, []ScheduleEntry{
+		{"E1", alice, 3000, 600},
+		{"E2", bob, 2500, 500},
+		{"E3", "carol", 1000, 0},
+	})
+	fundPeriod(t, stub, token, 5400)
+
+	err := contract.UploadSchedule(newContext(stub, employer, employerMSPID), "2021-05", "[]")
+	checkError(t, err, "the schedule of period 2021-05 cannot change once it is FUNDED")
+
+	err = contract.RunPayroll(newContext(stub, employer, employerMSPID), "2021-05")
+	checkError(t, err, "only the payroll agent can run payroll for period 2021-05")
+
+	// the employees and the refund of the invalid account are paid in one batch debiting the agent once
+	token.payer = agent
+	checkError(t, contract.RunPayroll(newContext(stub, agent, platformMSPID), "2021-05"), "")
+	expected := map[string]int{alice: 2400, bob: 2000, employer: 1000, agent: 0}
+	for account, balance := range expected {
+		if token.balances[account] != balance {
+			t.Fatalf("expected %s to hold %d, got %d", account, balance, token.balances[account])
+		}
+	}
+	if token.batches != 2 {
+		t.Fatalf("expected one batch to fund and one to run, got %d", token.batches)
+	}
+
+	var summary event
+	err = json.Unmarshal(stub.eventValue, &summary)
+	if err != nil {
+		t.Fatalf("failed to unmarshal event: %v", err)
+	}
+	if summary != (event{"2021-05", 2, 1, 1000}) {
+		t.Fatalf("unexpected event %+v", summary)
+	}
+
+	payslip := checkPayslip(t, stub, "E3", statusFailed)
+	if payslip.Reason != "account carol is not a client account ID" {
+		t.Fatalf("unexpected reason %q", payslip.Reason)
+	}
+	checkPayslip(t, stub, "E1", statusPaid)
+
+	err = contract.RunPayroll(newContext(stub, agent, platformMSPID), "2021-05")
+	checkError(t, err, "period 2021-05 is PAID, not FUNDED")
+
+	// the employer pays the failed payslip to the corrected account
+	err = contract.PayFailedPayslip(newContext(stub, employer, employerMSPID), "2021-05", "E1", alice)
+	checkError(t, err, "payslip of E1 for period 2021-05 is PAID")
+
+	token.payer = employer
+	checkError(t, contract.PayFailedPayslip(newContext(stub, employer, employerMSPID), "2021-05", "E3", account("carol")), "")
+	checkPayslip(t, stub, "E3", statusPaid)
+	if token.balances[account("carol")] != 1000 || token.balances[employer] != 0 {
+		t.Fatalf("unexpected balances %v", token.balances)
+	}
+}
+
+func TestRunPayrollInBatches(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	var schedule []ScheduleEntry
+	for i := 0; i < maxPayslipsPerRun+51; i++ {
+		schedule = append(schedule, ScheduleEntry{fmt.Sprintf("E%03d", i), account(fmt.Sprintf("employee%03d", i)), 10, 0})
+	}
+	token := createPeriod(t, stub, schedule)
+	fundPeriod(t, stub, token, 1500)
+
+	// the first run pays as many payslips as one BatchTransfer takes and leaves the period funded
+	token.payer = agent
+	checkError(t, contract.RunPayroll(newContext(stub, agent, platformMSPID), "2021-05"), "")
+	period, err := contract.GetPeriod(newContext(stub, employer, employerMSPID), "2021-05")
+	checkError(t, err, "")
+	if period.Status != statusFunded || period.Paid != 990 || token.balances[agent] != 510 {
+		t.Fatalf("unexpected period %+v after the first run", period)
+	}
+	checkPayslip(t, stub, fmt.Sprintf("E%03d", maxPayslipsPerRun), statusPending)
+
+	checkError(t, contract.RunPayroll(newContext(stub, agent, platformMSPID), "2021-05"), "")
+	period, err = contract.GetPeriod(newContext(stub, employer, employerMSPID), "2021-05")
+	checkError(t, err, "")
+	if period.Status != statusPaid || period.Paid != 1500 || token.balances[agent] != 0 {
+		t.Fatalf("unexpected period %+v after the last run", period)
+	}
+}
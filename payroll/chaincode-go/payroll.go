@@ -0,0 +1,23 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"log"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/payroll/chaincode-go/chaincode"
+)
+
+func main() {
+	payrollChaincode, err := contractapi.NewChaincode(&chaincode.SmartContract{})
+	if err != nil {
+		log.Panicf("Error creating payroll chaincode: %v", err)
+	}
+
+	if err := payrollChaincode.Start(); err != nil {
+		log.Panicf("Error starting payroll chaincode: %v", err)
+	}
+}
@@ -0,0 +1,868 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil"
+)
+
+// object names for prefix
+const (
+	productPrefix     = "product"
+	itemPrefix        = "item"
+	parentChildPrefix = "parent~child"
+	scanPrefix        = "scan"
+)
+
+// packaging levels, from the saleable unit up
+const (
+	levelUnit   = "UNIT"
+	levelCase   = "CASE"
+	levelPallet = "PALLET"
+)
+
+// levelRank orders the packaging levels: a container holds items of lower levels only
+var levelRank = map[string]int{levelUnit: 0, levelCase: 1, levelPallet: 2}
+
+// item status values
+const (
+	statusActive         = "ACTIVE"
+	statusInTransit      = "IN_TRANSIT"
+	statusSuspect        = "SUSPECT"
+	statusIllegitimate   = "ILLEGITIMATE"
+	statusDispensed      = "DISPENSED"
+	statusDecommissioned = "DECOMMISSIONED"
+)
+
+// scan outcomes
+const (
+	outcomeVerified       = "VERIFIED"
+	outcomeUnknown        = "UNKNOWN"
+	outcomeMismatch       = "MISMATCH"
+	outcomeExpired        = "EXPIRED"
+	outcomeDecommissioned = "DECOMMISSIONED"
+	outcomeNotCustodian   = "NOT_CUSTODIAN"
+	outcomeQuarantined    = "QUARANTINED"
+)
+
+// suspectOutcomes are the scan outcomes that flag the scanned item as suspect: its data does not
+// match what the manufacturer commissioned, it was already dispensed or unpacked, or it turned up
+// at an org that does not hold it, which is how a cloned serial number shows
+var suspectOutcomes = map[string]bool{outcomeMismatch: true, outcomeDecommissioned: true, outcomeNotCustodian: true}
+
+// dispositions of a suspect product investigation
+const (
+	dispositionCleared      = "CLEARED"
+	dispositionIllegitimate = "ILLEGITIMATE"
+)
+
+// maxCommission bounds the serial numbers commissioned by one transaction
+const maxCommission = 500
+
+// maxAggregationDepth bounds how many packaging levels a shipment, flag or resolution follows
+// down from a container
+const maxAggregationDepth = 3
+
+// serialPattern is a DSCSA serial number: up to 20 alphanumeric characters
+var serialPattern = regexp.MustCompile(`^[A-Za-z0-9]{1,20}$`)
+
+// SmartContract provides functions for tracing serialized prescription drug packages from their
+// manufacturer to the dispensing pharmacy
+type SmartContract struct {
+	contractapi.Contract
+}
+
+// Product is a drug product registered by its manufacturer under its GTIN. Only the manufacturer
+// can commission serial numbers of the product.
+type Product struct {
+	ObjectType      string `json:"objectType"`
+	GTIN            string `json:"gtin"`
+	Name            string `json:"name"`
+	NDC             string `json:"ndc"`
+	ManufacturerOrg string `json:"manufacturerOrg"`
+}
+
+// Item is a serialized saleable unit, identified by its GTIN and serial number, or a case or
+// pallet, identified by its SSCC. Each item is held by its custodian org and may be packed in a
+// container, its parent.
+type Item struct {
+	ObjectType      string `json:"objectType"`
+	ID              string `json:"itemID"`
+	Level           string `json:"level"`
+	GTIN            string `json:"gtin,omitempty" metadata:",optional"`
+	Serial          string `json:"serial,omitempty" metadata:",optional"`
+	Lot             string `json:"lot,omitempty" metadata:",optional"`
+	Expiry          string `json:"expiry,omitempty" metadata:",optional"`
+	ManufacturerOrg string `json:"manufacturerOrg"`
+	CustodianOrg    string `json:"custodianOrg"`
+	PendingOrg      string `json:"pendingOrg,omitempty" metadata:",optional"`
+	Parent          string `json:"parent,omitempty" metadata:",optional"`
+	Status          string `json:"status"`
+	// StatusBeforeSuspect is the status a suspect item returns to when it is cleared
+	StatusBeforeSuspect string `json:"statusBeforeSuspect,omitempty" metadata:",optional"`
+	SuspectReason       string `json:"suspectReason,omitempty" metadata:",optional"`
+	CreatedAt           string `json:"createdAt"`
+	UpdatedAt           string `json:"updatedAt"`
+}
+
+// ScanRecord is a verification scan of a product identifier by a trading partner, with what the
+// package showed and what the ledger found
+type ScanRecord struct {
+	ObjectType string `json:"objectType"`
+	ItemID     string `json:"itemID"`
+	Lot        string `json:"lot,omitempty" metadata:",optional"`
+	Expiry     string `json:"expiry,omitempty" metadata:",optional"`
+	Org        string `json:"org"`
+	Location   string `json:"location"`
+	Outcome    string `json:"outcome"`
+	Detail     string `json:"detail,omitempty" metadata:",optional"`
+	TxID       string `json:"txID"`
+	ScannedAt  string `json:"scannedAt"`
+}
+
+// suspectEvent is emitted when an item is flagged as suspect, or when a scan finds no item
+type suspectEvent struct {
+	ItemID string   `json:"itemID"`
+	Org    string   `json:"org"`
+	Reason string   `json:"reason"`
+	Items  []string `json:"items"`
+}
+
+// resolutionEvent is emitted when the investigation of a suspect item is closed
+type resolutionEvent struct {
+	ItemID      string   `json:"itemID"`
+	Disposition string   `json:"disposition"`
+	Note        string   `json:"note"`
+	Items       []string `json:"items"`
+}
+
+// custodyEvent is emitted whenever an item changes hands
+type custodyEvent struct {
+	ItemID string `json:"itemID"`
+	From   string `json:"from"`
+	To     string `json:"to"`
+}
+
+// RegisterProduct registers a drug product under its GTIN, owned by the client's org as its
+// manufacturer
+func (s *SmartContract) RegisterProduct(ctx contractapi.TransactionContextInterface, gtin string, name string, ndc string) error {
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+
+	if !_validGS1(gtin, 14) {
+		return fmt.Errorf("GTIN %s is not 14 digits with a valid check digit", gtin)
+	}
+	if name == "" || ndc == "" {
+		return fmt.Errorf("product name and NDC must be set")
+	}
+	existing, err := _getProduct(ctx, gtin)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("the product %s is already registered by %s", gtin, existing.ManufacturerOrg)
+	}
+
+	product := Product{
+		ObjectType:      productPrefix,
+		GTIN:            gtin,
+		Name:            name,
+		NDC:             ndc,
+		ManufacturerOrg: clientOrgID,
+	}
+	productJSON, err := json.Marshal(product)
+	if err != nil {
+		return fmt.Errorf("failed to marshal product: %v", err)
+	}
+	productKey, err := ctx.GetStub().CreateCompositeKey(productPrefix, []string{gtin})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = ctx.GetStub().PutState(productKey, productJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put product %s: %v", gtin, err)
+	}
+	return nil
+}
+
+// CommissionItems is called by the manufacturer of a product to serialize saleable units of one
+// lot. expiry is the expiration date of the lot as YYYY-MM-DD. It returns the IDs of the units,
+// the GTIN and serial number joined by a dot.
+func (s *SmartContract) CommissionItems(ctx contractapi.TransactionContextInterface, gtin string, lot string, expiry string, serials []string) ([]string, error) {
+	product, err := s.ReadProduct(ctx, gtin)
+	if err != nil {
+		return nil, err
+	}
+	err = _requireOrg(ctx, product.ManufacturerOrg, "commission serial numbers of product "+gtin)
+	if err != nil {
+		return nil, err
+	}
+	if lot == "" {
+		return nil, fmt.Errorf("lot must be set")
+	}
+	if _, err := time.Parse("2006-01-02", expiry); err != nil {
+		return nil, fmt.Errorf("expiry %s is not a date as YYYY-MM-DD", expiry)
+	}
+	if len(serials) == 0 || len(serials) > maxCommission {
+		return nil, fmt.Errorf("between 1 and %d serial numbers can be commissioned at a time", maxCommission)
+	}
+
+	now, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var itemIDs []string
+	for _, serial := range serials {
+		if !serialPattern.MatchString(serial) {
+			return nil, fmt.Errorf("serial number %s must be 1 to 20 letters and digits", serial)
+		}
+		if seen[serial] {
+			return nil, fmt.Errorf("serial number %s is listed more than once", serial)
+		}
+		seen[serial] = true
+
+		itemID := _unitID(gtin, serial)
+		existing, err := _getItem(ctx, itemID)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			return nil, fmt.Errorf("serial number %s of product %s is already commissioned", serial, gtin)
+		}
+		item := Item{
+			ObjectType:      itemPrefix,
+			ID:              itemID,
+			Level:           levelUnit,
+			GTIN:            gtin,
+			Serial:          serial,
+			Lot:             lot,
+			Expiry:          expiry,
+			ManufacturerOrg: product.ManufacturerOrg,
+			CustodianOrg:    product.ManufacturerOrg,
+			Status:          statusActive,
+			CreatedAt:       now.Format(time.RFC3339),
+			UpdatedAt:       now.Format(time.RFC3339),
+		}
+		err = _putItem(ctx, &item)
+		if err != nil {
+			return nil, err
+		}
+		itemIDs = append(itemIDs, itemID)
+	}
+	return itemIDs, nil
+}
+
+// Pack aggregates items held by the client's org into a new case or pallet identified by its
+// SSCC. The items must be active, not packed in another container and of a lower level than the
+// container.
+func (s *SmartContract) Pack(ctx contractapi.TransactionContextInterface, containerID string, level string, childIDs []string) error {
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+
+	if !_validGS1(containerID, 18) {
+		return fmt.Errorf("SSCC %s is not 18 digits with a valid check digit", containerID)
+	}
+	if level != levelCase && level != levelPallet {
+		return fmt.Errorf("container level must be %s or %s", levelCase, levelPallet)
+	}
+	if len(childIDs) == 0 {
+		return fmt.Errorf("a container must hold at least one item")
+	}
+	existing, err := _getItem(ctx, containerID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("the container %s already exists", containerID)
+	}
+
+	now, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return err
+	}
+	seen := make(map[string]bool)
+	for _, childID := range childIDs {
+		if seen[childID] {
+			return fmt.Errorf("item %s is listed more than once", childID)
+		}
+		seen[childID] = true
+
+		child, err := s.ReadItem(ctx, childID)
+		if err != nil {
+			return err
+		}
+		if child.CustodianOrg != clientOrgID {
+			return fmt.Errorf("a client from %s cannot pack item %s held by %s", clientOrgID, childID, child.CustodianOrg)
+		}
+		if child.Status != statusActive {
+			return fmt.Errorf("item %s is %s and cannot be packed", childID, child.Status)
+		}
+		if child.Parent != "" {
+			return fmt.Errorf("item %s is packed in %s", childID, child.Parent)
+		}
+		if levelRank[child.Level] >= levelRank[level] {
+			return fmt.Errorf("a %s cannot be packed in a %s", child.Level, level)
+		}
+
+		child.Parent = containerID
+		child.UpdatedAt = now.Format(time.RFC3339)
+		err = _putItem(ctx, child)
+		if err != nil {
+			return err
+		}
+		indexKey, err := ctx.GetStub().CreateCompositeKey(parentChildPrefix, []string{containerID, childID})
+		if err != nil {
+			return fmt.Errorf("failed to create composite key: %v", err)
+		}
+		err = ctx.GetStub().PutState(indexKey, []byte{0x00})
+		if err != nil {
+			return fmt.Errorf("failed to put index for %s: %v", childID, err)
+		}
+	}
+
+	container := Item{
+		ObjectType:      itemPrefix,
+		ID:              containerID,
+		Level:           level,
+		ManufacturerOrg: clientOrgID,
+		CustodianOrg:    clientOrgID,
+		Status:          statusActive,
+		CreatedAt:       now.Format(time.RFC3339),
+		UpdatedAt:       now.Format(time.RFC3339),
+	}
+	return _putItem(ctx, &container)
+}
+
+// Unpack takes the items out of a container held by the client's org. The container is
+// decommissioned, so a scan of its SSCC afterwards flags it as suspect.
+func (s *SmartContract) Unpack(ctx contractapi.TransactionContextInterface, containerID string) ([]string, error) {
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get MSPID: %v", err)
+	}
+
+	container, err := s.ReadItem(ctx, containerID)
+	if err != nil {
+		return nil, err
+	}
+	if container.Level == levelUnit {
+		return nil, fmt.Errorf("item %s is not a container", containerID)
+	}
+	if container.CustodianOrg != clientOrgID {
+		return nil, fmt.Errorf("a client from %s cannot unpack container %s held by %s", clientOrgID, containerID, container.CustodianOrg)
+	}
+	if container.Status != statusActive || container.Parent != "" {
+		return nil, fmt.Errorf("container %s must be active and not packed in another container to be unpacked", containerID)
+	}
+
+	now, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	children, err := s._children(ctx, containerID)
+	if err != nil {
+		return nil, err
+	}
+	var childIDs []string
+	for _, child := range children {
+		child.Parent = ""
+		child.UpdatedAt = now.Format(time.RFC3339)
+		err = _putItem(ctx, child)
+		if err != nil {
+			return nil, err
+		}
+		indexKey, err := ctx.GetStub().CreateCompositeKey(parentChildPrefix, []string{containerID, child.ID})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create composite key: %v", err)
+		}
+		err = ctx.GetStub().DelState(indexKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to delete index for %s: %v", child.ID, err)
+		}
+		childIDs = append(childIDs, child.ID)
+	}
+
+	container.Status = statusDecommissioned
+	container.UpdatedAt = now.Format(time.RFC3339)
+	err = _putItem(ctx, container)
+	if err != nil {
+		return nil, err
+	}
+	return childIDs, nil
+}
+
+// Ship hands an item, with everything packed in it, to another org. The items stay in transit
+// until the receiving org accepts them with Receive. Items packed in a container are shipped with
+// it and cannot be shipped on their own.
+func (s *SmartContract) Ship(ctx contractapi.TransactionContextInterface, itemID string, toOrg string) error {
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+
+	item, err := s.ReadItem(ctx, itemID)
+	if err != nil {
+		return err
+	}
+	if item.CustodianOrg != clientOrgID {
+		return fmt.Errorf("a client from %s cannot ship item %s held by %s", clientOrgID, itemID, item.CustodianOrg)
+	}
+	if item.Status != statusActive {
+		return fmt.Errorf("item %s is %s and cannot be shipped", itemID, item.Status)
+	}
+	if item.Parent != "" {
+		return fmt.Errorf("item %s is packed in %s and is shipped with it", itemID, item.Parent)
+	}
+	if toOrg == "" || toOrg == clientOrgID {
+		return fmt.Errorf("receiving org must be set and differ from the current custodian")
+	}
+
+	now, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = s._updateTree(ctx, item, func(i *Item) bool {
+		i.PendingOrg = toOrg
+		i.Status = statusInTransit
+		i.UpdatedAt = now.Format(time.RFC3339)
+		return true
+	})
+	return err
+}
+
+// Receive is called by the receiving org to confirm it has taken delivery of a shipped item and
+// everything packed in it
+func (s *SmartContract) Receive(ctx contractapi.TransactionContextInterface, itemID string) error {
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+
+	item, err := s.ReadItem(ctx, itemID)
+	if err != nil {
+		return err
+	}
+	if item.Status != statusInTransit || item.PendingOrg != clientOrgID || item.Parent != "" {
+		return fmt.Errorf("item %s is not shipped to %s", itemID, clientOrgID)
+	}
+
+	now, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return err
+	}
+	previousOrg := item.CustodianOrg
+	_, err = s._updateTree(ctx, item, func(i *Item) bool {
+		i.CustodianOrg = clientOrgID
+		i.PendingOrg = ""
+		i.Status = statusActive
+		i.UpdatedAt = now.Format(time.RFC3339)
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	return _emitEvent(ctx, "CustodyTransfer", custodyEvent{itemID, previousOrg, clientOrgID})
+}
+
+// VerifyScan records the verification of a product identifier scanned at a supply-chain node.
+// itemID is the GTIN and serial number of a unit joined by a dot, or the SSCC of a case or
+// pallet; lot and expiry are what the label of a unit shows and are empty for containers. The
+// scan is recorded whatever its outcome, and an item whose data does not match, which was
+// dispensed or unpacked, or which is scanned by an org not holding it is flagged as suspect.
+func (s *SmartContract) VerifyScan(ctx contractapi.TransactionContextInterface, itemID string, lot string, expiry string, location string) (*ScanRecord, error) {
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get MSPID: %v", err)
+	}
+
+	if itemID == "" || location == "" {
+		return nil, fmt.Errorf("item ID and scan location must be set")
+	}
+	now, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	item, err := _getItem(ctx, itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	record := &ScanRecord{
+		ObjectType: scanPrefix,
+		ItemID:     itemID,
+		Lot:        lot,
+		Expiry:     expiry,
+		Org:        clientOrgID,
+		Location:   location,
+		TxID:       ctx.GetStub().GetTxID(),
+		ScannedAt:  now.Format(time.RFC3339),
+	}
+	record.Outcome, record.Detail = _verify(item, lot, expiry, clientOrgID, now)
+	recordKey, err := ctx.GetStub().CreateCompositeKey(scanPrefix, []string{itemID, record.TxID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	recordJSON, err := json.Marshal(record)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal scan record: %v", err)
+	}
+	err = ctx.GetStub().PutState(recordKey, recordJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to put scan record of %s: %v", itemID, err)
+	}
+
+	switch {
+	case record.Outcome == outcomeUnknown:
+		err = _emitEvent(ctx, "SuspectProduct", suspectEvent{itemID, clientOrgID, record.Detail, []string{}})
+	case suspectOutcomes[record.Outcome]:
+		_, err = s._flag(ctx, item, clientOrgID, record.Detail, now)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// FlagSuspect quarantines an item, and everything packed in it, as suspect product for
+// investigation by its manufacturer. Its manufacturer, custodian or the org it is shipped to can
+// flag it. It returns the IDs of the flagged items.
+func (s *SmartContract) FlagSuspect(ctx contractapi.TransactionContextInterface, itemID string, reason string) ([]string, error) {
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get MSPID: %v", err)
+	}
+
+	item, err := s.ReadItem(ctx, itemID)
+	if err != nil {
+		return nil, err
+	}
+	if clientOrgID != item.ManufacturerOrg && clientOrgID != item.CustodianOrg && clientOrgID != item.PendingOrg {
+		return nil, fmt.Errorf("a client from %s is not authorized to flag item %s", clientOrgID, itemID)
+	}
+	if reason == "" {
+		return nil, fmt.Errorf("a reason must be given")
+	}
+	if item.Status == statusSuspect || item.Status == statusIllegitimate {
+		return nil, fmt.Errorf("item %s is already %s", itemID, item.Status)
+	}
+
+	now, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s._flag(ctx, item, clientOrgID, reason, now)
+}
+
+// ResolveSuspect closes the investigation of a suspect item by its manufacturer. CLEARED returns
+// the item and the suspect items packed in it to the status they had when flagged; ILLEGITIMATE
+// marks them as illegitimate product, which can no longer be packed, shipped or dispensed. It
+// returns the IDs of the resolved items.
+func (s *SmartContract) ResolveSuspect(ctx contractapi.TransactionContextInterface, itemID string, disposition string, note string) ([]string, error) {
+	item, err := s.ReadItem(ctx, itemID)
+	if err != nil {
+		return nil, err
+	}
+	err = _requireOrg(ctx, item.ManufacturerOrg, "resolve suspect item "+itemID)
+	if err != nil {
+		return nil, err
+	}
+	if item.Status != statusSuspect {
+		return nil, fmt.Errorf("item %s is %s, not %s", itemID, item.Status, statusSuspect)
+	}
+	if disposition != dispositionCleared && disposition != dispositionIllegitimate {
+		return nil, fmt.Errorf("disposition must be %s or %s", dispositionCleared, dispositionIllegitimate)
+	}
+
+	now, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resolved, err := s._updateTree(ctx, item, func(i *Item) bool {
+		if i.Status != statusSuspect {
+			return false
+		}
+		if disposition == dispositionCleared {
+			i.Status = i.StatusBeforeSuspect
+			i.SuspectReason = ""
+		} else {
+			i.Status = statusIllegitimate
+		}
+		i.StatusBeforeSuspect = ""
+		i.UpdatedAt = now.Format(time.RFC3339)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = _emitEvent(ctx, "SuspectResolved", resolutionEvent{itemID, disposition, note, resolved})
+	if err != nil {
+		return nil, err
+	}
+	return resolved, nil
+}
+
+// Dispense is called by the pharmacy holding a unit when it is dispensed to a patient. The serial
+// number is decommissioned, so a scan of it afterwards, e.g. of a refilled package, flags it as
+// suspect.
+func (s *SmartContract) Dispense(ctx contractapi.TransactionContextInterface, itemID string) error {
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+
+	item, err := s.ReadItem(ctx, itemID)
+	if err != nil {
+		return err
+	}
+	if item.Level != levelUnit {
+		return fmt.Errorf("only saleable units can be dispensed")
+	}
+	if item.CustodianOrg != clientOrgID {
+		return fmt.Errorf("a client from %s cannot dispense item %s held by %s", clientOrgID, itemID, item.CustodianOrg)
+	}
+	if item.Status != statusActive || item.Parent != "" {
+		return fmt.Errorf("item %s must be active and unpacked to be dispensed", itemID)
+	}
+
+	now, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return err
+	}
+	if item.Expiry < now.Format("2006-01-02") {
+		return fmt.Errorf("item %s expired on %s", itemID, item.Expiry)
+	}
+	item.Status = statusDispensed
+	item.UpdatedAt = now.Format(time.RFC3339)
+	return _putItem(ctx, item)
+}
+
+// _verify returns the outcome of a scan of item, which is nil when the scanned ID was not
+// commissioned, and a description of the problem the scan found
+func _verify(item *Item, lot string, expiry string, org string, now time.Time) (string, string) {
+	switch {
+	case item == nil:
+		return outcomeUnknown, "product identifier was not commissioned by a manufacturer"
+	case item.Status == statusSuspect || item.Status == statusIllegitimate:
+		return outcomeQuarantined, fmt.Sprintf("item is %s", item.Status)
+	case item.Status == statusDispensed || item.Status == statusDecommissioned:
+		return outcomeDecommissioned, fmt.Sprintf("item was %s", item.Status)
+	case item.Level == levelUnit && (lot != item.Lot || expiry != item.Expiry):
+		return outcomeMismatch, fmt.Sprintf("label shows lot %s expiring %s, commissioned as lot %s expiring %s", lot, expiry, item.Lot, item.Expiry)
+	case org != item.CustodianOrg && org != item.PendingOrg:
+		return outcomeNotCustodian, fmt.Sprintf("item is held by %s", item.CustodianOrg)
+	case item.Level == levelUnit && item.Expiry < now.Format("2006-01-02"):
+		return outcomeExpired, fmt.Sprintf("item expired on %s", item.Expiry)
+	}
+	return outcomeVerified, ""
+}
+
+// _flag marks an item and the items packed in it as suspect and emits the SuspectProduct event
+func (s *SmartContract) _flag(ctx contractapi.TransactionContextInterface, item *Item, org string, reason string, now time.Time) ([]string, error) {
+	flagged, err := s._updateTree(ctx, item, func(i *Item) bool {
+		if i.Status == statusSuspect || i.Status == statusIllegitimate {
+			return false
+		}
+		i.StatusBeforeSuspect = i.Status
+		i.Status = statusSuspect
+		i.SuspectReason = reason
+		i.UpdatedAt = now.Format(time.RFC3339)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = _emitEvent(ctx, "SuspectProduct", suspectEvent{item.ID, org, reason, flagged})
+	if err != nil {
+		return nil, err
+	}
+	return flagged, nil
+}
+
+// _updateTree applies update to an item and everything packed in it, down to
+// maxAggregationDepth levels, and writes the items it changed. update returns whether it changed
+// the item. It returns the IDs of the changed items.
+func (s *SmartContract) _updateTree(ctx contractapi.TransactionContextInterface, root *Item, update func(*Item) bool) ([]string, error) {
+	changed := []string{}
+	level := []*Item{root}
+	for depth := 0; len(level) > 0; depth++ {
+		if depth > maxAggregationDepth {
+			return nil, fmt.Errorf("item %s has more than %d packaging levels", root.ID, maxAggregationDepth)
+		}
+		var next []*Item
+		for _, item := range level {
+			if update(item) {
+				err := _putItem(ctx, item)
+				if err != nil {
+					return nil, err
+				}
+				changed = append(changed, item.ID)
+			}
+			if item.Level == levelUnit {
+				continue
+			}
+			children, err := s._children(ctx, item.ID)
+			if err != nil {
+				return nil, err
+			}
+			next = append(next, children...)
+		}
+		level = next
+	}
+	return changed, nil
+}
+
+// _children returns the items packed directly in a container
+func (s *SmartContract) _children(ctx contractapi.TransactionContextInterface, containerID string) ([]*Item, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(parentChildPrefix, []string{containerID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the contents of %s: %v", containerID, err)
+	}
+	defer resultsIterator.Close()
+
+	var children []*Item
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(response.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split composite key: %v", err)
+		}
+		if len(keyParts) != 2 {
+			return nil, fmt.Errorf("unexpected index key %s", response.Key)
+		}
+		child, err := s.ReadItem(ctx, keyParts[1])
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, child)
+	}
+	return children, nil
+}
+
+// _validGS1 checks a GS1 identifier of the given length, such as a GTIN-14 or an SSCC, and its
+// mod 10 check digit
+func _validGS1(id string, length int) bool {
+	if len(id) != length {
+		return false
+	}
+	sum := 0
+	for i := 0; i < length-1; i++ {
+		digit := id[i]
+		if digit < '0' || digit > '9' {
+			return false
+		}
+		// weights alternate 3 and 1 from the digit next to the check digit
+		weight := 1
+		if (length-1-i)%2 == 1 {
+			weight = 3
+		}
+		sum += int(digit-'0') * weight
+	}
+	return int(id[length-1]-'0') == (10-sum%10)%10
+}
+
+// _unitID returns the ID of a saleable unit
+func _unitID(gtin string, serial string) string {
+	return gtin + "." + serial
+}
+
+// _requireOrg checks the client belongs to the given org
+func _requireOrg(ctx contractapi.TransactionContextInterface, org string, action string) error {
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+	if clientMSPID != org {
+		return fmt.Errorf("client from %s is not authorized to %s", clientMSPID, action)
+	}
+	return nil
+}
+
+// _getProduct reads a product, returning nil when the GTIN is not registered
+func _getProduct(ctx contractapi.TransactionContextInterface, gtin string) (*Product, error) {
+	productKey, err := ctx.GetStub().CreateCompositeKey(productPrefix, []string{gtin})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	productJSON, err := ctx.GetStub().GetState(productKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if productJSON == nil {
+		return nil, nil
+	}
+
+	var product Product
+	err = json.Unmarshal(productJSON, &product)
+	if err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+// _getItem reads an item, returning nil when it does not exist
+func _getItem(ctx contractapi.TransactionContextInterface, itemID string) (*Item, error) {
+	itemKey, err := ctx.GetStub().CreateCompositeKey(itemPrefix, []string{itemID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	itemJSON, err := ctx.GetStub().GetState(itemKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if itemJSON == nil {
+		return nil, nil
+	}
+
+	var item Item
+	err = json.Unmarshal(itemJSON, &item)
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// _putItem writes the item to the world state
+func _putItem(ctx contractapi.TransactionContextInterface, item *Item) error {
+	itemKey, err := ctx.GetStub().CreateCompositeKey(itemPrefix, []string{item.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	itemJSON, err := json.Marshal(item)
+	if err != nil {
+		return fmt.Errorf("failed to marshal item: %v", err)
+	}
+	err = ctx.GetStub().PutState(itemKey, itemJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put item %s: %v", item.ID, err)
+	}
+	return nil
+}
+
+// _emitEvent marshals the payload and sets it as the chaincode event
+func _emitEvent(ctx contractapi.TransactionContextInterface, name string, payload interface{}) error {
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	err = ctx.GetStub().SetEvent(name, payloadJSON)
+	if err != nil {
+		return fmt.Errorf("failed to set event: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// HistoryRecord is a version of an item written by a transaction
+type HistoryRecord struct {
+	Record    *Item     `json:"record"`
+	TxId      string    `json:"txId"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// ReadProduct returns the product registered under a GTIN
+func (s *SmartContract) ReadProduct(ctx contractapi.TransactionContextInterface, gtin string) (*Product, error) {
+	product, err := _getProduct(ctx, gtin)
+	if err != nil {
+		return nil, err
+	}
+	if product == nil {
+		return nil, fmt.Errorf("the product %s is not registered", gtin)
+	}
+	return product, nil
+}
+
+// ReadItem returns the unit, case or pallet stored in the world state with the given ID
+func (s *SmartContract) ReadItem(ctx contractapi.TransactionContextInterface, itemID string) (*Item, error) {
+	item, err := _getItem(ctx, itemID)
+	if err != nil {
+		return nil, err
+	}
+	if item == nil {
+		return nil, fmt.Errorf("the item %s does not exist", itemID)
+	}
+	return item, nil
+}
+
+// GetContents returns every item packed in a container, including the contents of the cases
+// packed on a pallet
+func (s *SmartContract) GetContents(ctx contractapi.TransactionContextInterface, containerID string) ([]*Item, error) {
+	container, err := s.ReadItem(ctx, containerID)
+	if err != nil {
+		return nil, err
+	}
+
+	contents := []*Item{}
+	level := []*Item{container}
+	for depth := 0; len(level) > 0 && depth <= maxAggregationDepth; depth++ {
+		var next []*Item
+		for _, item := range level {
+			if item.Level == levelUnit {
+				continue
+			}
+			children, err := s._children(ctx, item.ID)
+			if err != nil {
+				return nil, err
+			}
+			contents = append(contents, children...)
+			next = append(next, children...)
+		}
+		level = next
+	}
+	return contents, nil
+}
+
+// GetScans returns the verification scans of a product identifier in transaction ID order,
+// including scans of identifiers that were never commissioned
+func (s *SmartContract) GetScans(ctx contractapi.TransactionContextInterface, itemID string) ([]*ScanRecord, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(scanPrefix, []string{itemID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get scans of %s: %v", itemID, err)
+	}
+	defer resultsIterator.Close()
+
+	scans := []*ScanRecord{}
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var scan ScanRecord
+		err = json.Unmarshal(response.Value, &scan)
+		if err != nil {
+			return nil, err
+		}
+		scans = append(scans, &scan)
+	}
+	return scans, nil
+}
+
+// GetItemHistory returns every version of an item, from its commissioning through each
+// aggregation, shipment, receipt and status change, as the transaction history of the item
+func (s *SmartContract) GetItemHistory(ctx contractapi.TransactionContextInterface, itemID string) ([]HistoryRecord, error) {
+	itemKey, err := ctx.GetStub().CreateCompositeKey(itemPrefix, []string{itemID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	resultsIterator, err := ctx.GetStub().GetHistoryForKey(itemKey)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var records []HistoryRecord
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var item Item
+		err = json.Unmarshal(response.Value, &item)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, HistoryRecord{
+			Record:    &item,
+			TxId:      response.TxId,
+			Timestamp: time.Unix(response.Timestamp.Seconds, int64(response.Timestamp.Nanos)).UTC(),
+		})
+	}
+	return records, nil
+}
@@ -0,0 +1,288 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/pkg/mockledger"
+)
+
+const (
+	pharmaName = "pharma"
+	gtin       = "00300001234566"
+	caseSSCC   = "003000010000000017"
+	palletSSCC = "003000010000000024"
+)
+
+// network is a mock ledger with the pharma chaincode deployed and a manufacturer, wholesaler and
+// pharmacy enrolled on it
+type network struct {
+	ledger       *mockledger.Ledger
+	manufacturer *mockledger.Client
+	wholesaler   *mockledger.Client
+	pharmacy     *mockledger.Client
+}
+
+func newNetwork(t *testing.T) *network {
+	t.Helper()
+	ledger := mockledger.New()
+	pharma, err := contractapi.NewChaincode(new(SmartContract))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ledger.Deploy(pharmaName, pharma); err != nil {
+		t.Fatal(err)
+	}
+
+	n := &network{ledger: ledger}
+	n.manufacturer = n.client(t, "Org1MSP", "manufacturer")
+	n.wholesaler = n.client(t, "Org2MSP", "wholesaler")
+	n.pharmacy = n.client(t, "Org3MSP", "pharmacy")
+	return n
+}
+
+func (n *network) client(t *testing.T, mspID string, name string) *mockledger.Client {
+	t.Helper()
+	client, err := n.ledger.NewClient(mspID, name, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+// commission registers the product and commissions its units of lot L2401 with the serials
+func (n *network) commission(t *testing.T, serials string) {
+	t.Helper()
+	n.submit(t, n.manufacturer, "RegisterProduct", gtin, "Amoxicillin 500mg", "0300-0001-23")
+	n.submit(t, n.manufacturer, "CommissionItems", gtin, "L2401", "2027-01-31", serials)
+}
+
+func (n *network) submit(t *testing.T, client *mockledger.Client, function string, args ...string) *mockledger.Result {
+	t.Helper()
+	result, err := n.ledger.Submit(client, mockledger.Transaction{Chaincode: pharmaName, Function: function, Args: args})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func (n *network) submitError(t *testing.T, client *mockledger.Client, want string, function string, args ...string) {
+	t.Helper()
+	_, err := n.ledger.Submit(client, mockledger.Transaction{Chaincode: pharmaName, Function: function, Args: args})
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("%s failed with %v, want %q", function, err, want)
+	}
+}
+
+func (n *network) evaluate(t *testing.T, result interface{}, function string, args ...string) {
+	t.Helper()
+	payload, err := n.ledger.Evaluate(n.manufacturer, mockledger.Transaction{Chaincode: pharmaName, Function: function, Args: args})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(payload, result); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// scan verifies the label of an item as a client and returns the outcome of the scan
+func (n *network) scan(t *testing.T, client *mockledger.Client, itemID string, lot string, expiry string) (*ScanRecord, *mockledger.Result) {
+	t.Helper()
+	result := n.submit(t, client, "VerifyScan", itemID, lot, expiry, "DC-1")
+	var record ScanRecord
+	if err := json.Unmarshal(result.Payload, &record); err != nil {
+		t.Fatal(err)
+	}
+	return &record, result
+}
+
+// checkItems fails the test when an item's custodian or status differs from want
+func (n *network) checkItems(t *testing.T, custodian string, status string, itemIDs ...string) {
+	t.Helper()
+	for _, itemID := range itemIDs {
+		var item Item
+		n.evaluate(t, &item, "ReadItem", itemID)
+		if item.CustodianOrg != custodian || item.Status != status {
+			t.Errorf("item %s is %s held by %s, want %s held by %s", itemID, item.Status, item.CustodianOrg, status, custodian)
+		}
+	}
+}
+
+func TestRegisterAndCommission(t *testing.T) {
+	n := newNetwork(t)
+	n.submitError(t, n.manufacturer, "GTIN 00300001234567 is not 14 digits with a valid check digit", "RegisterProduct", "00300001234567", "Amoxicillin 500mg", "0300-0001-23")
+	n.submit(t, n.manufacturer, "RegisterProduct", gtin, "Amoxicillin 500mg", "0300-0001-23")
+	n.submitError(t, n.wholesaler, "already registered by Org1MSP", "RegisterProduct", gtin, "Amoxicillin", "0300-0001-23")
+
+	n.submitError(t, n.wholesaler, "client from Org2MSP is not authorized", "CommissionItems", gtin, "L2401", "2027-01-31", `["A1"]`)
+	n.submitError(t, n.manufacturer, "expiry 31/01/2027 is not a date", "CommissionItems", gtin, "L2401", "31/01/2027", `["A1"]`)
+	n.submitError(t, n.manufacturer, "serial number A1 is listed more than once", "CommissionItems", gtin, "L2401", "2027-01-31", `["A1","A1"]`)
+	result := n.submit(t, n.manufacturer, "CommissionItems", gtin, "L2401", "2027-01-31", `["A1","A2"]`)
+	var itemIDs []string
+	if err := json.Unmarshal(result.Payload, &itemIDs); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(itemIDs, ",") != gtin+".A1,"+gtin+".A2" {
+		t.Errorf("CommissionItems returned %v", itemIDs)
+	}
+	n.submitError(t, n.manufacturer, "serial number A2 of product "+gtin+" is already commissioned", "CommissionItems", gtin, "L2401", "2027-01-31", `["A2","A3"]`)
+	n.checkItems(t, "Org1MSP", statusActive, gtin+".A1", gtin+".A2")
+}
+
+func TestPackShipAndUnpack(t *testing.T) {
+	n := newNetwork(t)
+	n.commission(t, `["A1","A2","A3"]`)
+	units := []string{gtin + ".A1", gtin + ".A2", gtin + ".A3"}
+
+	n.submitError(t, n.manufacturer, "SSCC 003000010000000018 is not 18 digits with a valid check digit", "Pack", "003000010000000018", levelCase, `["`+units[0]+`"]`)
+	n.submit(t, n.manufacturer, "Pack", caseSSCC, levelCase, `["`+strings.Join(units, `","`)+`"]`)
+	n.submitError(t, n.manufacturer, "item "+units[0]+" is packed in "+caseSSCC, "Pack", palletSSCC, levelPallet, `["`+units[0]+`"]`)
+	n.submit(t, n.manufacturer, "Pack", palletSSCC, levelPallet, `["`+caseSSCC+`"]`)
+	var contents []*Item
+	n.evaluate(t, &contents, "GetContents", palletSSCC)
+	if len(contents) != 4 {
+		t.Errorf("pallet holds %d items, want the case and its 3 units", len(contents))
+	}
+
+	// the pallet is shipped with everything packed in it, and custody passes on receipt
+	n.submitError(t, n.manufacturer, "item "+caseSSCC+" is packed in "+palletSSCC+" and is shipped with it", "Ship", caseSSCC, "Org2MSP")
+	n.submit(t, n.manufacturer, "Ship", palletSSCC, "Org2MSP")
+	n.checkItems(t, "Org1MSP", statusInTransit, append(units, caseSSCC, palletSSCC)...)
+	n.submitError(t, n.pharmacy, "item "+palletSSCC+" is not shipped to Org3MSP", "Receive", palletSSCC)
+	result := n.submit(t, n.wholesaler, "Receive", palletSSCC)
+	if result.Event == nil || result.Event.EventName != "CustodyTransfer" {
+		t.Fatalf("Receive set event %v, want CustodyTransfer", result.Event)
+	}
+	var custody custodyEvent
+	if err := json.Unmarshal(result.Event.Payload, &custody); err != nil {
+		t.Fatal(err)
+	}
+	if custody != (custodyEvent{palletSSCC, "Org1MSP", "Org2MSP"}) {
+		t.Errorf("CustodyTransfer event is %+v", custody)
+	}
+	n.checkItems(t, "Org2MSP", statusActive, append(units, caseSSCC, palletSSCC)...)
+
+	// unpacking decommissions the containers and frees their contents
+	n.submitError(t, n.manufacturer, "a client from Org1MSP cannot unpack container", "Unpack", palletSSCC)
+	n.submit(t, n.wholesaler, "Unpack", palletSSCC)
+	n.submit(t, n.wholesaler, "Unpack", caseSSCC)
+	n.checkItems(t, "Org2MSP", statusDecommissioned, caseSSCC, palletSSCC)
+	n.evaluate(t, &contents, "GetContents", caseSSCC)
+	if len(contents) != 0 {
+		t.Errorf("unpacked case holds %d items", len(contents))
+	}
+	var item Item
+	n.evaluate(t, &item, "ReadItem", units[0])
+	if item.Parent != "" {
+		t.Errorf("unpacked unit is still packed in %s", item.Parent)
+	}
+	var history []HistoryRecord
+	n.evaluate(t, &history, "GetItemHistory", units[0])
+	if len(history) != 5 {
+		t.Errorf("unit has %d versions, want commission, pack, ship, receive and unpack", len(history))
+	}
+
+	n.submit(t, n.wholesaler, "Ship", units[0], "Org3MSP")
+	n.submit(t, n.pharmacy, "Receive", units[0])
+	n.submit(t, n.pharmacy, "Dispense", units[0])
+	n.checkItems(t, "Org3MSP", statusDispensed, units[0])
+	n.submitError(t, n.pharmacy, "must be active and unpacked to be dispensed", "Dispense", units[0])
+
+	// a dispensed serial number or an unpacked SSCC scanned again is flagged, e.g. as a refilled package
+	if record, _ := n.scan(t, n.pharmacy, units[0], "L2401", "2027-01-31"); record.Outcome != outcomeDecommissioned {
+		t.Errorf("scan of a dispensed unit is %s, want %s", record.Outcome, outcomeDecommissioned)
+	}
+	if record, _ := n.scan(t, n.wholesaler, caseSSCC, "", ""); record.Outcome != outcomeDecommissioned {
+		t.Errorf("scan of an unpacked case is %s, want %s", record.Outcome, outcomeDecommissioned)
+	}
+	n.checkItems(t, "Org3MSP", statusSuspect, units[0])
+	n.checkItems(t, "Org2MSP", statusSuspect, caseSSCC)
+}
+
+func TestVerifyScan(t *testing.T) {
+	n := newNetwork(t)
+	n.commission(t, `["A1","A2","A3","A4"]`)
+	a1, a2, a3, a4 := gtin+".A1", gtin+".A2", gtin+".A3", gtin+".A4"
+
+	if record, result := n.scan(t, n.manufacturer, a1, "L2401", "2027-01-31"); record.Outcome != outcomeVerified || result.Event != nil {
+		t.Errorf("scan of a genuine unit is %s with event %v", record.Outcome, result.Event)
+	}
+
+	// an identifier that was never commissioned is reported, but there is no item to flag
+	record, result := n.scan(t, n.wholesaler, gtin+".X9", "L2401", "2027-01-31")
+	if record.Outcome != outcomeUnknown || result.Event == nil || result.Event.EventName != "SuspectProduct" {
+		t.Errorf("scan of an unknown unit is %s with event %v", record.Outcome, result.Event)
+	}
+
+	// a label not matching the commissioned data and a scan by an org not holding the unit flag it
+	if record, _ := n.scan(t, n.manufacturer, a2, "L2402", "2027-01-31"); record.Outcome != outcomeMismatch {
+		t.Errorf("scan of a relabelled unit is %s, want %s", record.Outcome, outcomeMismatch)
+	}
+	record, result = n.scan(t, n.pharmacy, a3, "L2401", "2027-01-31")
+	if record.Outcome != outcomeNotCustodian || record.Detail != "item is held by Org1MSP" {
+		t.Errorf("scan by an org not holding the unit is %+v", record)
+	}
+	var flagged suspectEvent
+	if err := json.Unmarshal(result.Event.Payload, &flagged); err != nil {
+		t.Fatal(err)
+	}
+	if flagged.ItemID != a3 || flagged.Org != "Org3MSP" || len(flagged.Items) != 1 {
+		t.Errorf("SuspectProduct event is %+v", flagged)
+	}
+	n.checkItems(t, "Org1MSP", statusSuspect, a2, a3)
+	if record, _ := n.scan(t, n.manufacturer, a2, "L2401", "2027-01-31"); record.Outcome != outcomeQuarantined {
+		t.Errorf("scan of a suspect unit is %s, want %s", record.Outcome, outcomeQuarantined)
+	}
+	var scans []*ScanRecord
+	n.evaluate(t, &scans, "GetScans", a2)
+	if len(scans) != 2 {
+		t.Errorf("unit has %d scans, want 2", len(scans))
+	}
+
+	// expired units are reported but not flagged
+	n.ledger.SetTime(time.Date(2027, 2, 1, 0, 0, 0, 0, time.UTC))
+	if record, result := n.scan(t, n.manufacturer, a4, "L2401", "2027-01-31"); record.Outcome != outcomeExpired || result.Event != nil {
+		t.Errorf("scan of an expired unit is %s with event %v", record.Outcome, result.Event)
+	}
+	n.checkItems(t, "Org1MSP", statusActive, a4)
+}
+
+func TestFlagAndResolveSuspect(t *testing.T) {
+	n := newNetwork(t)
+	n.commission(t, `["A1","A2","A3"]`)
+	a1, a2, a3 := gtin+".A1", gtin+".A2", gtin+".A3"
+	n.submit(t, n.manufacturer, "Pack", caseSSCC, levelCase, `["`+a1+`","`+a2+`"]`)
+	n.submit(t, n.manufacturer, "Ship", caseSSCC, "Org2MSP")
+
+	// the org a case is shipped to can flag it, quarantining everything packed in it
+	n.submitError(t, n.pharmacy, "a client from Org3MSP is not authorized to flag item", "FlagSuspect", caseSSCC, "seal broken")
+	result := n.submit(t, n.wholesaler, "FlagSuspect", caseSSCC, "seal broken")
+	var flagged []string
+	if err := json.Unmarshal(result.Payload, &flagged); err != nil {
+		t.Fatal(err)
+	}
+	if len(flagged) != 3 {
+		t.Errorf("FlagSuspect flagged %v, want the case and its 2 units", flagged)
+	}
+	n.checkItems(t, "Org1MSP", statusSuspect, caseSSCC, a1, a2)
+	n.submitError(t, n.wholesaler, "is already SUSPECT", "FlagSuspect", a1, "seal broken")
+	n.submitError(t, n.wholesaler, "item "+caseSSCC+" is not shipped to Org2MSP", "Receive", caseSSCC)
+
+	// clearing restores the status the items had when they were flagged
+	n.submitError(t, n.wholesaler, "client from Org2MSP is not authorized to resolve suspect item", "ResolveSuspect", caseSSCC, dispositionCleared, "")
+	n.submit(t, n.manufacturer, "ResolveSuspect", caseSSCC, dispositionCleared, "seal damaged in transit")
+	n.checkItems(t, "Org1MSP", statusInTransit, caseSSCC, a1, a2)
+	n.submit(t, n.wholesaler, "Receive", caseSSCC)
+	n.checkItems(t, "Org2MSP", statusActive, caseSSCC, a1, a2)
+
+	// illegitimate product can no longer be moved
+	n.submit(t, n.manufacturer, "FlagSuspect", a3, "counterfeit reported")
+	n.submitError(t, n.manufacturer, "disposition must be CLEARED or ILLEGITIMATE", "ResolveSuspect", a3, "RECALLED", "")
+	n.submit(t, n.manufacturer, "ResolveSuspect", a3, dispositionIllegitimate, "confirmed counterfeit")
+	n.checkItems(t, "Org1MSP", statusIllegitimate, a3)
+	n.submitError(t, n.manufacturer, "is ILLEGITIMATE and cannot be shipped", "Ship", a3, "Org2MSP")
+	n.submitError(t, n.manufacturer, "is ILLEGITIMATE, not SUSPECT", "ResolveSuspect", a3, dispositionCleared, "")
+}
@@ -0,0 +1,23 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"log"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/pharma-traceability/chaincode-go/chaincode"
+)
+
+func main() {
+	pharmaChaincode, err := contractapi.NewChaincode(&chaincode.SmartContract{})
+	if err != nil {
+		log.Panicf("Error creating pharma-traceability chaincode: %v", err)
+	}
+
+	if err := pharmaChaincode.Start(); err != nil {
+		log.Panicf("Error starting pharma-traceability chaincode: %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package eventreplay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Checkpoint is the position of the last event a handler processed successfully
+type Checkpoint struct {
+	BlockNumber   uint64 `json:"blockNumber"`
+	TransactionID string `json:"transactionID"`
+}
+
+// Checkpointer stores the checkpoint of a replayer durably. Load returns nil when no checkpoint was
+// saved yet. Implementations may store it in the same database as the handler's results, so both are
+// saved together.
+type Checkpointer interface {
+	Load(ctx context.Context) (*Checkpoint, error)
+	Save(ctx context.Context, checkpoint Checkpoint) error
+}
+
+// FileCheckpointer stores the checkpoint in a JSON file. Save writes a temporary file, syncs it and
+// renames it over the checkpoint, so a crash leaves either the previous or the new checkpoint.
+type FileCheckpointer struct {
+	path string
+}
+
+// NewFileCheckpointer returns a checkpointer storing the checkpoint in the file at path. The
+// directory of the file must exist.
+func NewFileCheckpointer(path string) *FileCheckpointer {
+	return &FileCheckpointer{path: path}
+}
+
+// Load reads the checkpoint file, returning nil when it does not exist
+func (c *FileCheckpointer) Load(ctx context.Context) (*Checkpoint, error) {
+	data, err := os.ReadFile(c.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %v", err)
+	}
+	var checkpoint Checkpoint
+	err = json.Unmarshal(data, &checkpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint %s: %v", c.path, err)
+	}
+	return &checkpoint, nil
+}
+
+// Save replaces the checkpoint file
+func (c *FileCheckpointer) Save(ctx context.Context, checkpoint Checkpoint) error {
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to save checkpoint: %v", err)
+	}
+	defer os.Remove(file.Name())
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), c.path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save checkpoint: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package eventreplay
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+)
+
+// eventLog is an event source serving a fixed list of events. The first stream is closed after
+// failAfter events, as a failing connection would.
+type eventLog struct {
+	events    []*client.ChaincodeEvent
+	failAfter int
+	starts    []uint64
+}
+
+func (l *eventLog) ChaincodeEvents(ctx context.Context, chaincode string, startBlock uint64) (<-chan *client.ChaincodeEvent, error) {
+	l.starts = append(l.starts, startBlock)
+	failAfter := 0
+	if len(l.starts) == 1 {
+		failAfter = l.failAfter
+	}
+	events := make(chan *client.ChaincodeEvent)
+	go func() {
+		defer close(events)
+		sent := 0
+		for _, event := range l.events {
+			if event.BlockNumber < startBlock {
+				continue
+			}
+			if failAfter > 0 && sent == failAfter {
+				return
+			}
+			select {
+			case events <- event:
+				sent++
+			case <-ctx.Done():
+				return
+			}
+		}
+		<-ctx.Done()
+	}()
+	return events, nil
+}
+
+func testEvents() []*client.ChaincodeEvent {
+	return []*client.ChaincodeEvent{
+		{BlockNumber: 3, TransactionID: "tx1", EventName: "Transfer"},
+		{BlockNumber: 5, TransactionID: "tx2", EventName: "Transfer"},
+		{BlockNumber: 5, TransactionID: "tx3", EventName: "Approval"},
+		{BlockNumber: 5, TransactionID: "tx4", EventName: "Transfer"},
+		{BlockNumber: 8, TransactionID: "tx5", EventName: "Transfer"},
+	}
+}
+
+// collect returns a handler recording the transaction IDs of the events, which cancels ctx after
+// the event of transaction last
+func collect(handled *[]string, last string, cancel context.CancelFunc) Handler {
+	return func(ctx context.Context, event *client.ChaincodeEvent) error {
+		*handled = append(*handled, event.TransactionID)
+		if event.TransactionID == last {
+			cancel()
+		}
+		return nil
+	}
+}
+
+func TestReplayResumesAtCheckpoint(t *testing.T) {
+	checkpointer := NewFileCheckpointer(filepath.Join(t.TempDir(), "checkpoint.json"))
+	source := &eventLog{events: testEvents()}
+	logger := log.New(io.Discard, "", 0)
+
+	var handled []string
+	ctx, cancel := context.WithCancel(context.Background())
+	handler := collect(&handled, "tx3", cancel)
+	err := New(source, "token_erc20", checkpointer, handler, WithStartBlock(4), WithLogger(logger)).Run(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run returned %v", err)
+	}
+	checkpoint, err := checkpointer.Load(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := (&Checkpoint{BlockNumber: 5, TransactionID: "tx3"}); !reflect.DeepEqual(checkpoint, want) {
+		t.Errorf("checkpoint %+v, want %+v", checkpoint, want)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	handler = collect(&handled, "tx5", cancel)
+	err = New(source, "token_erc20", checkpointer, handler, WithLogger(logger)).Run(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run returned %v", err)
+	}
+	if want := []string{"tx2", "tx3", "tx4", "tx5"}; !reflect.DeepEqual(handled, want) {
+		t.Errorf("handled %v, want %v", handled, want)
+	}
+	if want := []uint64{4, 5}; !reflect.DeepEqual(source.starts, want) {
+		t.Errorf("streams started at %v, want %v", source.starts, want)
+	}
+}
+
+func TestReplayReopensFailedStream(t *testing.T) {
+	checkpointer := NewFileCheckpointer(filepath.Join(t.TempDir(), "checkpoint.json"))
+	source := &eventLog{events: testEvents(), failAfter: 3}
+
+	var handled []string
+	ctx, cancel := context.WithCancel(context.Background())
+	replayer := New(source, "token_erc20", checkpointer, collect(&handled, "tx5", cancel),
+		WithRetryDelay(time.Millisecond, time.Millisecond), WithLogger(log.New(io.Discard, "", 0)))
+	err := replayer.Run(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run returned %v", err)
+	}
+	if want := []string{"tx1", "tx2", "tx3", "tx4", "tx5"}; !reflect.DeepEqual(handled, want) {
+		t.Errorf("handled %v, want %v", handled, want)
+	}
+	if want := []uint64{0, 5}; !reflect.DeepEqual(source.starts, want) {
+		t.Errorf("streams started at %v, want %v", source.starts, want)
+	}
+}
+
+func TestReplayStopsOnHandlerError(t *testing.T) {
+	checkpointer := NewFileCheckpointer(filepath.Join(t.TempDir(), "checkpoint.json"))
+	source := &eventLog{events: testEvents()}
+	failure := errors.New("database unavailable")
+
+	var handled []string
+	handler := func(ctx context.Context, event *client.ChaincodeEvent) error {
+		if event.TransactionID == "tx2" {
+			return failure
+		}
+		handled = append(handled, event.TransactionID)
+		return nil
+	}
+	err := New(source, "token_erc20", checkpointer, handler).Run(context.Background())
+	var handlerErr *HandlerError
+	if !errors.As(err, &handlerErr) || !errors.Is(err, failure) || handlerErr.Event.TransactionID != "tx2" {
+		t.Fatalf("Run returned %v", err)
+	}
+	checkpoint, err := checkpointer.Load(context.Background())
+	if err != nil || !reflect.DeepEqual(checkpoint, &Checkpoint{BlockNumber: 3, TransactionID: "tx1"}) {
+		t.Errorf("checkpoint %+v, error %v", checkpoint, err)
+	}
+}
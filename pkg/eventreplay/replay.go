@@ -0,0 +1,208 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package eventreplay replays the chaincode events of a channel to a handler from a block height
+// on, saving a durable checkpoint after each event the handler processed. After a restart, or when
+// the event stream fails, replay resumes at the checkpoint, so every event is delivered at least
+// once. An event is delivered again only when the process stops between its handler returning and
+// its checkpoint being saved.
+package eventreplay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+)
+
+// The default delays before the event stream is reopened
+const (
+	DefaultMinRetryDelay = time.Second
+	DefaultMaxRetryDelay = time.Minute
+)
+
+// Handler processes one chaincode event. A handler returning an error stops the replayer, and the
+// event is delivered again when the replayer is run next. Handlers should therefore be idempotent,
+// e.g. by recording the transaction IDs they processed.
+type Handler func(ctx context.Context, event *client.ChaincodeEvent) error
+
+// EventSource opens the stream of the events of a chaincode from a block on. The stream is closed
+// when ctx is done or the connection fails.
+type EventSource interface {
+	ChaincodeEvents(ctx context.Context, chaincode string, startBlock uint64) (<-chan *client.ChaincodeEvent, error)
+}
+
+// NetworkSource returns the event source of a channel of the Fabric Gateway
+func NetworkSource(network *client.Network) EventSource {
+	return networkSource{network: network}
+}
+
+type networkSource struct {
+	network *client.Network
+}
+
+func (s networkSource) ChaincodeEvents(ctx context.Context, chaincode string, startBlock uint64) (<-chan *client.ChaincodeEvent, error) {
+	return s.network.ChaincodeEvents(ctx, chaincode, client.WithStartBlock(startBlock))
+}
+
+// HandlerError is returned by Run when the handler failed to process an event
+type HandlerError struct {
+	Event *client.ChaincodeEvent
+	Err   error
+}
+
+func (e *HandlerError) Error() string {
+	return fmt.Sprintf("failed to handle event %s of transaction %s in block %d: %v",
+		e.Event.EventName, e.Event.TransactionID, e.Event.BlockNumber, e.Err)
+}
+
+func (e *HandlerError) Unwrap() error {
+	return e.Err
+}
+
+// Option configures a Replayer
+type Option func(r *Replayer)
+
+// WithStartBlock sets the block replay starts at when there is no checkpoint, block 0 by default,
+// which replays the whole history of the chaincode
+func WithStartBlock(blockNumber uint64) Option {
+	return func(r *Replayer) {
+		r.startBlock = blockNumber
+	}
+}
+
+// WithRetryDelay sets the delay before the event stream is reopened after it failed, doubled after
+// each failure without an event in between up to max
+func WithRetryDelay(min time.Duration, max time.Duration) Option {
+	return func(r *Replayer) {
+		r.minRetryDelay = min
+		r.maxRetryDelay = max
+	}
+}
+
+// WithLogger sets the logger of the stream failures, the standard logger by default
+func WithLogger(logger *log.Logger) Option {
+	return func(r *Replayer) {
+		r.logger = logger
+	}
+}
+
+// Replayer delivers the events of one chaincode to a handler
+type Replayer struct {
+	source        EventSource
+	chaincode     string
+	checkpointer  Checkpointer
+	handler       Handler
+	startBlock    uint64
+	minRetryDelay time.Duration
+	maxRetryDelay time.Duration
+	logger        *log.Logger
+}
+
+// New returns a replayer of the events of chaincode read from source, checkpointed with checkpointer
+func New(source EventSource, chaincode string, checkpointer Checkpointer, handler Handler, options ...Option) *Replayer {
+	r := &Replayer{
+		source:        source,
+		chaincode:     chaincode,
+		checkpointer:  checkpointer,
+		handler:       handler,
+		minRetryDelay: DefaultMinRetryDelay,
+		maxRetryDelay: DefaultMaxRetryDelay,
+		logger:        log.Default(),
+	}
+	for _, option := range options {
+		option(r)
+	}
+	return r
+}
+
+// Run delivers events until ctx is done, returning ctx.Err(), or until the handler fails, returning
+// a *HandlerError. When the event stream fails, e.g. because the peer went down, or the checkpoint
+// cannot be loaded or saved, it is reopened at the checkpoint after a growing delay.
+func (r *Replayer) Run(ctx context.Context) error {
+	delay := r.minRetryDelay
+	for {
+		handled, err := r.replay(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		var handlerErr *HandlerError
+		if errors.As(err, &handlerErr) {
+			return err
+		}
+		if handled {
+			delay = r.minRetryDelay
+		}
+		r.logger.Printf("%s: event replay stopped, resuming in %s: %v", r.chaincode, delay, err)
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		delay *= 2
+		if delay > r.maxRetryDelay {
+			delay = r.maxRetryDelay
+		}
+	}
+}
+
+// replay delivers events from the checkpoint on until the event stream ends. It reports whether any
+// event was handled.
+func (r *Replayer) replay(ctx context.Context) (bool, error) {
+	checkpoint, err := r.checkpointer.Load(ctx)
+	if err != nil {
+		return false, err
+	}
+	startBlock := r.startBlock
+	if checkpoint != nil {
+		startBlock = checkpoint.BlockNumber
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	events, err := r.source.ChaincodeEvents(ctx, r.chaincode, startBlock)
+	if err != nil {
+		return false, fmt.Errorf("failed to start chaincode event listening: %v", err)
+	}
+
+	handled := false
+	for event := range events {
+		// a stream may still deliver an event after ctx is done
+		if ctx.Err() != nil {
+			return handled, ctx.Err()
+		}
+		if checkpoint.handled(event) {
+			continue
+		}
+		checkpoint = nil
+
+		err := r.handler(ctx, event)
+		if err != nil {
+			return handled, &HandlerError{Event: event, Err: err}
+		}
+		err = r.checkpointer.Save(ctx, Checkpoint{BlockNumber: event.BlockNumber, TransactionID: event.TransactionID})
+		if err != nil {
+			return handled, err
+		}
+		handled = true
+	}
+	return handled, fmt.Errorf("event stream closed")
+}
+
+// handled reports whether an event read after resuming at the checkpoint was handled before. Replay
+// resumes at the block of the checkpoint, as its events after the checkpoint may not have been
+// handled yet; the events of that block up to the checkpoint's transaction are skipped.
+func (c *Checkpoint) handled(event *client.ChaincodeEvent) bool {
+	if c == nil || c.TransactionID == "" || event.BlockNumber != c.BlockNumber {
+		return false
+	}
+	if event.TransactionID == c.TransactionID {
+		c.TransactionID = ""
+	}
+	return true
+}
@@ -0,0 +1,133 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package mockledger
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-protos-go/msp"
+)
+
+// attributesOID is the certificate extension in which Fabric CA puts the attributes of an enrollment,
+// as the JSON {"attrs":{"name":"value"}}
+var attributesOID = asn1.ObjectIdentifier{1, 2, 3, 4, 5, 6, 7, 8, 1}
+
+// Client is a client of an org submitting transactions, with the enrollment certificate the client
+// identity of a chaincode reads
+type Client struct {
+	MSPID string
+	// Certificate is the PEM encoded enrollment certificate
+	Certificate []byte
+}
+
+// certificateAuthority is the CA of an org, issuing the certificates of its clients
+type certificateAuthority struct {
+	key    *ecdsa.PrivateKey
+	cert   *x509.Certificate
+	serial int64
+}
+
+// NewClient enrolls a client of the org mspID, as Fabric CA would with the common name name, the
+// organizational unit client and the attributes attrs, e.g. {"readonly": "true"}. The certificates
+// of an org are issued by one CA with the common name ca.<mspID in lower case>, so clients enrolled
+// twice with the same name have the same client ID.
+func (l *Ledger) NewClient(mspID string, name string, attrs map[string]string) (*Client, error) {
+	return l.enroll(mspID, name, "client", attrs)
+}
+
+// NewAdmin enrolls an admin of the org mspID, whose certificate has the organizational unit admin
+// as with NodeOUs enabled, e.g. to manage the policy of the access-control chaincode
+func (l *Ledger) NewAdmin(mspID string, name string) (*Client, error) {
+	return l.enroll(mspID, name, "admin", nil)
+}
+
+// enroll issues the certificate of a client of an org with the organizational unit of its type
+func (l *Ledger) enroll(mspID string, name string, clientType string, attrs map[string]string) (*Client, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	ca, err := l.certificateAuthority(mspID)
+	if err != nil {
+		return nil, err
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate the key of %s: %v", name, err)
+	}
+	// Fabric CA adds the enrollment ID, type and affiliation to the attributes of every certificate
+	attributes := map[string]string{"hf.EnrollmentID": name, "hf.Type": clientType, "hf.Affiliation": ""}
+	for attribute, value := range attrs {
+		attributes[attribute] = value
+	}
+	attributesJSON, err := json.Marshal(map[string]map[string]string{"attrs": attributes})
+	if err != nil {
+		return nil, err
+	}
+
+	ca.serial++
+	template := &x509.Certificate{
+		SerialNumber:    big.NewInt(ca.serial),
+		Subject:         pkix.Name{CommonName: name, OrganizationalUnit: []string{clientType}},
+		NotBefore:       StartTime.AddDate(-1, 0, 0),
+		NotAfter:        StartTime.AddDate(100, 0, 0),
+		KeyUsage:        x509.KeyUsageDigitalSignature,
+		ExtraExtensions: []pkix.Extension{{Id: attributesOID, Value: attributesJSON}},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to issue the certificate of %s: %v", name, err)
+	}
+	return &Client{MSPID: mspID, Certificate: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}, nil
+}
+
+// certificateAuthority returns the CA of an org, creating it for the first client of the org
+func (l *Ledger) certificateAuthority(mspID string) (*certificateAuthority, error) {
+	if ca, ok := l.cas[mspID]; ok {
+		return ca, nil
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate the CA key of %s: %v", mspID, err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ca." + strings.ToLower(mspID), Organization: []string{mspID}},
+		NotBefore:             StartTime.AddDate(-1, 0, 0),
+		NotAfter:              StartTime.AddDate(100, 0, 0),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the CA certificate of %s: %v", mspID, err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	ca := &certificateAuthority{key: key, cert: cert, serial: 1}
+	l.cas[mspID] = ca
+	return ca, nil
+}
+
+// serialize returns the serialized identity a peer passes to the chaincode as the creator of a proposal
+func (c *Client) serialize() ([]byte, error) {
+	creator, err := proto.Marshal(&msp.SerializedIdentity{Mspid: c.MSPID, IdBytes: c.Certificate})
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize the identity of a client of %s: %v", c.MSPID, err)
+	}
+	return creator, nil
+}
@@ -0,0 +1,289 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package mockledger is an in-memory channel ledger running chaincodes without a Fabric network. Its
+// stub implements shim.ChaincodeStubInterface over a real key-value store with composite keys, range
+// and rich queries, private data, key history and chaincode events, and commits the writes of a
+// transaction only when the chaincode succeeds, so contracts can be run end to end in tests and
+// offline development.
+package mockledger
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+)
+
+// DefaultChannel is the channel of a ledger created by New, as in the test network
+const DefaultChannel = "mychannel"
+
+// StartTime is the timestamp of the first transaction of a new ledger
+var StartTime = time.Unix(1600000000, 0).UTC()
+
+// Ledger is the world state, private data and history of one channel, with the chaincodes deployed on
+// it. Submit and Evaluate may be called from several goroutines; transactions run one at a time, so
+// they never conflict as concurrent transactions on a peer can.
+type Ledger struct {
+	mu         sync.Mutex
+	channelID  string
+	chaincodes map[string]shim.Chaincode
+	// state, validation and history are keyed by the chaincode namespace, then by key
+	state      map[string]map[string][]byte
+	validation map[string]map[string][]byte
+	history    map[string]map[string][]*queryresult.KeyModification
+	// private is keyed by the namespace and collection, then by key
+	private map[collectionID]map[string][]byte
+	events  []*pb.ChaincodeEvent
+	txCount int
+	now     time.Time
+	cas     map[string]*certificateAuthority
+}
+
+// collectionID is a private data collection of a chaincode
+type collectionID struct {
+	namespace  string
+	collection string
+}
+
+// Transaction is a proposal to call a function of a chaincode deployed on the ledger
+type Transaction struct {
+	Chaincode string
+	Function  string
+	Args      []string
+	// Transient is the transient map of the proposal, read by the chaincode and never committed
+	Transient map[string][]byte
+}
+
+// Result is a committed transaction
+type Result struct {
+	TxID    string
+	Payload []byte
+	// Event is the chaincode event the transaction set, nil when it set none
+	Event *pb.ChaincodeEvent
+}
+
+// Error is the failure of a chaincode function, with the status and message of its response
+type Error struct {
+	Function string
+	Status   int32
+	Message  string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s failed with status %d: %s", e.Function, e.Status, e.Message)
+}
+
+// New returns an empty ledger of DefaultChannel
+func New() *Ledger {
+	return NewChannel(DefaultChannel)
+}
+
+// NewChannel returns an empty ledger of the channel. Its first transaction has the timestamp StartTime
+// and every transaction is one second after the previous one.
+func NewChannel(channelID string) *Ledger {
+	return &Ledger{
+		channelID:  channelID,
+		chaincodes: make(map[string]shim.Chaincode),
+		state:      make(map[string]map[string][]byte),
+		validation: make(map[string]map[string][]byte),
+		history:    make(map[string]map[string][]*queryresult.KeyModification),
+		private:    make(map[collectionID]map[string][]byte),
+		now:        StartTime,
+		cas:        make(map[string]*certificateAuthority),
+	}
+}
+
+// Deploy deploys a chaincode as name, e.g. a *contractapi.ContractChaincode. Its functions are then
+// called with Submit and Evaluate, and by other chaincodes with InvokeChaincode. As with the Fabric
+// v2 lifecycle, Init is not called.
+func (l *Ledger) Deploy(name string, chaincode shim.Chaincode) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.chaincodes[name]; ok {
+		return fmt.Errorf("chaincode %s is already deployed", name)
+	}
+	l.chaincodes[name] = chaincode
+	return nil
+}
+
+// SetTime sets the timestamp of the next transaction, e.g. to pass an expiry
+func (l *Ledger) SetTime(t time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.now = t.UTC()
+}
+
+// Submit calls a function of a chaincode as the client and commits its writes and event when the
+// function succeeds. A failed function commits nothing and returns an *Error.
+func (l *Ledger) Submit(client *Client, tx Transaction) (*Result, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	stub, response, err := l.invoke(client, tx)
+	if err != nil {
+		return nil, err
+	}
+	l.commit(stub.tx)
+	return &Result{TxID: stub.tx.id, Payload: response.Payload, Event: stub.tx.event}, nil
+}
+
+// Evaluate calls a function of a chaincode as the client and returns its payload without committing
+// anything, as a query of one peer does
+func (l *Ledger) Evaluate(client *Client, tx Transaction) ([]byte, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, response, err := l.invoke(client, tx)
+	if err != nil {
+		return nil, err
+	}
+	return response.Payload, nil
+}
+
+// NewStub returns the stub of a transaction for calling the functions of a contract directly with a
+// transaction context set to it. Its writes reach the ledger when Commit is called.
+func (l *Ledger) NewStub(client *Client, tx Transaction) (*Stub, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.newStub(client, tx)
+}
+
+// GetState returns the committed value of a key of a chaincode, nil when it does not exist
+func (l *Ledger) GetState(chaincode string, key string) []byte {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.state[chaincode][key]
+}
+
+// GetPrivateData returns the committed value of a key in a private data collection of a chaincode,
+// nil when it does not exist
+func (l *Ledger) GetPrivateData(chaincode string, collection string, key string) []byte {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.private[collectionID{chaincode, collection}][key]
+}
+
+// Events returns the chaincode events of the committed transactions, oldest first
+func (l *Ledger) Events() []*pb.ChaincodeEvent {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]*pb.ChaincodeEvent(nil), l.events...)
+}
+
+// invoke runs a function of a chaincode in a new transaction, failing when it returns an error response
+func (l *Ledger) invoke(client *Client, tx Transaction) (*Stub, pb.Response, error) {
+	chaincode, ok := l.chaincodes[tx.Chaincode]
+	if !ok {
+		return nil, pb.Response{}, fmt.Errorf("chaincode %s is not deployed on channel %s", tx.Chaincode, l.channelID)
+	}
+	stub, err := l.newStub(client, tx)
+	if err != nil {
+		return nil, pb.Response{}, err
+	}
+	response := chaincode.Invoke(stub)
+	if response.Status >= shim.ERRORTHRESHOLD {
+		return nil, response, &Error{Function: tx.Function, Status: response.Status, Message: response.Message}
+	}
+	return stub, response, nil
+}
+
+// newStub starts a transaction with the next transaction ID and timestamp
+func (l *Ledger) newStub(client *Client, tx Transaction) (*Stub, error) {
+	var creator []byte
+	if client != nil {
+		var err error
+		creator, err = client.serialize()
+		if err != nil {
+			return nil, err
+		}
+	}
+	timestamp, err := ptypes.TimestampProto(l.now)
+	if err != nil {
+		return nil, err
+	}
+	l.txCount++
+	l.now = l.now.Add(time.Second)
+	txHash := sha256.Sum256([]byte(l.channelID + "/" + strconv.Itoa(l.txCount)))
+
+	args := [][]byte{[]byte(tx.Function)}
+	for _, arg := range tx.Args {
+		args = append(args, []byte(arg))
+	}
+	return &Stub{
+		ledger:    l,
+		namespace: tx.Chaincode,
+		args:      args,
+		tx: &transaction{
+			id:         hex.EncodeToString(txHash[:]),
+			chaincode:  tx.Chaincode,
+			timestamp:  timestamp,
+			creator:    creator,
+			transient:  tx.Transient,
+			writes:     make(map[string]map[string]*write),
+			private:    make(map[collectionID]map[string]*write),
+			validation: make(map[string]map[string][]byte),
+		},
+	}, nil
+}
+
+// commit applies the writes of a transaction to the ledger, recording each public key's history
+func (l *Ledger) commit(tx *transaction) {
+	tx.committed = true
+	timestamp := tx.timestamp
+	for _, namespace := range sortedKeys(tx.writes) {
+		if l.state[namespace] == nil {
+			l.state[namespace] = make(map[string][]byte)
+			l.history[namespace] = make(map[string][]*queryresult.KeyModification)
+		}
+		for key, write := range tx.writes[namespace] {
+			if write.deleted {
+				delete(l.state[namespace], key)
+			} else {
+				l.state[namespace][key] = write.value
+			}
+			modification := &queryresult.KeyModification{TxId: tx.id, Value: write.value, Timestamp: timestamp, IsDelete: write.deleted}
+			l.history[namespace][key] = append(l.history[namespace][key], modification)
+		}
+	}
+	for collection, writes := range tx.private {
+		if l.private[collection] == nil {
+			l.private[collection] = make(map[string][]byte)
+		}
+		for key, write := range writes {
+			if write.deleted {
+				delete(l.private[collection], key)
+			} else {
+				l.private[collection][key] = write.value
+			}
+		}
+	}
+	for namespace, parameters := range tx.validation {
+		if l.validation[namespace] == nil {
+			l.validation[namespace] = make(map[string][]byte)
+		}
+		for key, parameter := range parameters {
+			l.validation[namespace][key] = parameter
+		}
+	}
+	if tx.event != nil {
+		l.events = append(l.events, tx.event)
+	}
+}
+
+// sortedKeys returns the keys of a map in order
+func sortedKeys[T any](values map[string]T) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,405 @@
+package mockledger
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-protos-go/msp"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+)
+
+// kvChaincode is a chaincode writing the keys and events its functions are given
+type kvChaincode struct{}
+
+func (kvChaincode) Init(stub shim.ChaincodeStubInterface) pb.Response {
+	return shim.Success(nil)
+}
+
+func (kvChaincode) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
+	function, args := stub.GetFunctionAndParameters()
+	var err error
+	var payload []byte
+	switch function {
+	case "Put":
+		// Put key value [key value...]
+		for i := 0; i+1 < len(args); i += 2 {
+			if err = stub.PutState(args[i], []byte(args[i+1])); err != nil {
+				break
+			}
+		}
+	case "PutAndGet":
+		if err = stub.PutState(args[0], []byte(args[1])); err == nil {
+			payload, err = stub.GetState(args[0])
+		}
+	case "Del":
+		err = stub.DelState(args[0])
+	case "Event":
+		for _, name := range args {
+			if err = stub.SetEvent(name, []byte(name)); err != nil {
+				break
+			}
+		}
+	case "Call":
+		// Call chaincode function args..., passing through the response
+		callArgs := [][]byte{}
+		for _, arg := range args[1:] {
+			callArgs = append(callArgs, []byte(arg))
+		}
+		if err = stub.SetEvent("Call", nil); err == nil {
+			return stub.InvokeChaincode(args[0], callArgs, "")
+		}
+	case "PutAndFail":
+		stub.PutState(args[0], []byte(args[1]))
+		err = fmt.Errorf("failed after writing %s", args[0])
+	default:
+		err = fmt.Errorf("unknown function %s", function)
+	}
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(payload)
+}
+
+// newKVLedger returns a ledger with kvChaincode deployed as kv and other, and a client of Org1MSP
+func newKVLedger(t *testing.T) (*Ledger, *Client) {
+	t.Helper()
+	ledger := New()
+	for _, name := range []string{"kv", "other"} {
+		if err := ledger.Deploy(name, kvChaincode{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	client, err := ledger.NewClient("Org1MSP", "user1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ledger, client
+}
+
+// mustSubmit fails the test when a transaction fails
+func mustSubmit(t *testing.T, ledger *Ledger, client *Client, chaincode string, function string, args ...string) *Result {
+	t.Helper()
+	result, err := ledger.Submit(client, Transaction{Chaincode: chaincode, Function: function, Args: args})
+	if err != nil {
+		t.Fatalf("%s failed: %v", function, err)
+	}
+	return result
+}
+
+// keys drains an iterator into its keys
+func keys(t *testing.T, iterator shim.StateQueryIteratorInterface) []string {
+	t.Helper()
+	defer iterator.Close()
+	var found []string
+	for iterator.HasNext() {
+		result, err := iterator.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		found = append(found, result.Key)
+	}
+	return found
+}
+
+func TestSubmitCommitsOnSuccess(t *testing.T) {
+	ledger, client := newKVLedger(t)
+	mustSubmit(t, ledger, client, "kv", "Put", "a", "1")
+
+	_, err := ledger.Submit(client, Transaction{Chaincode: "kv", Function: "PutAndFail", Args: []string{"b", "2"}})
+	if err == nil || !strings.Contains(err.Error(), "failed after writing b") {
+		t.Fatalf("failed transaction returned %v", err)
+	}
+	if value := ledger.GetState("kv", "b"); value != nil {
+		t.Errorf("failed transaction committed b = %s", value)
+	}
+
+	payload, err := ledger.Evaluate(client, Transaction{Chaincode: "kv", Function: "PutAndGet", Args: []string{"a", "3"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(payload) != "1" {
+		t.Errorf("transaction read its own write %s, want the committed 1", payload)
+	}
+	if value := ledger.GetState("kv", "a"); string(value) != "1" {
+		t.Errorf("evaluated transaction committed a = %s", value)
+	}
+
+	_, err = ledger.Submit(client, Transaction{Chaincode: "missing", Function: "Put"})
+	if err == nil {
+		t.Errorf("called a chaincode that is not deployed")
+	}
+}
+
+func TestRangesAndCompositeKeys(t *testing.T) {
+	ledger, client := newKVLedger(t)
+	stub, err := ledger.NewStub(client, Transaction{Chaincode: "kv"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"b", "a", "c"} {
+		if err := stub.PutState(key, []byte(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, attributes := range [][]string{{"alice", "2"}, {"alice", "1"}, {"bob", "1"}} {
+		key, err := stub.CreateCompositeKey("owner", attributes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := stub.PutState(key, []byte("{}")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := stub.CreateCompositeKey("owner", []string{"a\x00b"}); err == nil {
+		t.Errorf("created a composite key with U+0000 in an attribute")
+	}
+	if err := stub.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if err := stub.Commit(); err == nil {
+		t.Errorf("committed a transaction twice")
+	}
+
+	stub, _ = ledger.NewStub(client, Transaction{Chaincode: "kv"})
+	iterator, err := stub.GetStateByRange("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found := keys(t, iterator); !reflect.DeepEqual(found, []string{"a", "b", "c"}) {
+		t.Errorf("range found %q, want the simple keys", found)
+	}
+	iterator, _ = stub.GetStateByRange("b", "c")
+	if found := keys(t, iterator); !reflect.DeepEqual(found, []string{"b"}) {
+		t.Errorf("range b to c found %q", found)
+	}
+
+	iterator, err = stub.GetStateByPartialCompositeKey("owner", []string{"alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var attributes []string
+	for _, key := range keys(t, iterator) {
+		objectType, split, err := stub.SplitCompositeKey(key)
+		if err != nil || objectType != "owner" {
+			t.Fatalf("split %q into %s %v: %v", key, objectType, split, err)
+		}
+		attributes = append(attributes, strings.Join(split, "/"))
+	}
+	if !reflect.DeepEqual(attributes, []string{"alice/1", "alice/2"}) {
+		t.Errorf("partial composite key found %q", attributes)
+	}
+
+	var pages [][]string
+	bookmark := ""
+	for len(pages) == 0 || bookmark != "" {
+		if len(pages) == 3 {
+			t.Fatalf("more pages than keys")
+		}
+		iterator, metadata, err := stub.GetStateByRangeWithPagination("", "", 2, bookmark)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages = append(pages, keys(t, iterator))
+		bookmark = metadata.Bookmark
+	}
+	if !reflect.DeepEqual(pages, [][]string{{"a", "b"}, {"c"}}) {
+		t.Errorf("pages are %q", pages)
+	}
+	if err := stub.PutState("d", nil); err == nil || !strings.Contains(err.Error(), "paginated query") {
+		t.Errorf("wrote after a paginated query: %v", err)
+	}
+}
+
+func TestHistoryAndTime(t *testing.T) {
+	ledger, client := newKVLedger(t)
+	first := mustSubmit(t, ledger, client, "kv", "Put", "a", "1")
+	mustSubmit(t, ledger, client, "kv", "Put", "a", "2")
+	last := mustSubmit(t, ledger, client, "kv", "Del", "a")
+	if first.TxID == last.TxID {
+		t.Errorf("transactions have the same ID %s", first.TxID)
+	}
+
+	stub, _ := ledger.NewStub(client, Transaction{Chaincode: "kv"})
+	iterator, err := stub.GetHistoryForKey("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var history []string
+	for iterator.HasNext() {
+		modification, err := iterator.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		history = append(history, fmt.Sprintf("%s %v %d", modification.Value, modification.IsDelete, modification.Timestamp.Seconds))
+	}
+	want := []string{" true 1600000002", "2 false 1600000001", "1 false 1600000000"}
+	if !reflect.DeepEqual(history, want) {
+		t.Errorf("history is %q, want %q", history, want)
+	}
+	timestamp, _ := stub.GetTxTimestamp()
+	if timestamp.Seconds != 1600000003 {
+		t.Errorf("fourth transaction has timestamp %d", timestamp.Seconds)
+	}
+
+	ledger.SetTime(StartTime.AddDate(1, 0, 0))
+	stub, _ = ledger.NewStub(client, Transaction{Chaincode: "kv"})
+	if timestamp, _ := stub.GetTxTimestamp(); timestamp.Seconds != StartTime.AddDate(1, 0, 0).Unix() {
+		t.Errorf("transaction after SetTime has timestamp %d", timestamp.Seconds)
+	}
+}
+
+func TestEventsAndChaincodeCalls(t *testing.T) {
+	ledger, client := newKVLedger(t)
+	result := mustSubmit(t, ledger, client, "kv", "Event", "First", "Second")
+	if result.Event == nil || result.Event.EventName != "Second" || result.Event.ChaincodeId != "kv" || result.Event.TxId != result.TxID {
+		t.Errorf("event is %+v, want the last one set", result.Event)
+	}
+
+	// the called chaincode writes its own namespace and its event is dropped
+	mustSubmit(t, ledger, client, "kv", "Call", "other", "Event", "Other")
+	result = mustSubmit(t, ledger, client, "kv", "Call", "other", "Put", "a", "1")
+	if result.Event == nil || result.Event.EventName != "Call" {
+		t.Errorf("event is %+v, want the event of the called chaincode dropped", result.Event)
+	}
+	if ledger.GetState("other", "a") == nil || ledger.GetState("kv", "a") != nil {
+		t.Errorf("called chaincode wrote to the namespace of the caller")
+	}
+
+	_, err := ledger.Submit(client, Transaction{Chaincode: "kv", Function: "Call", Args: []string{"other", "PutAndFail", "b", "2"}})
+	if err == nil {
+		t.Fatalf("failed call succeeded")
+	}
+	var names []string
+	for _, event := range ledger.Events() {
+		names = append(names, event.EventName)
+	}
+	if !reflect.DeepEqual(names, []string{"Second", "Call", "Call"}) {
+		t.Errorf("committed events are %q", names)
+	}
+}
+
+func TestRichQueries(t *testing.T) {
+	ledger, client := newKVLedger(t)
+	assets := map[string]string{
+		"asset1": `{"owner":"Org1MSP","value":300,"tags":["red"],"details":{"color":"red"}}`,
+		"asset2": `{"owner":"Org2MSP","value":100,"details":{"color":"blue"}}`,
+		"asset3": `{"owner":"Org1MSP","value":200}`,
+		"asset4": `{"owner":"Org1MSP","value":"400"}`,
+		"plain":  `not json`,
+	}
+	var args []string
+	for key, value := range assets {
+		args = append(args, key, value)
+	}
+	mustSubmit(t, ledger, client, "kv", "Put", args...)
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{`{"selector":{"owner":"Org1MSP"}}`, []string{"asset1", "asset3", "asset4"}},
+		{`{"selector":{"value":{"$gte":200,"$lt":300}}}`, []string{"asset3"}},
+		{`{"selector":{"value":{"$gt":1000}}}`, []string{"asset4"}},
+		{`{"selector":{"details.color":"blue"}}`, []string{"asset2"}},
+		{`{"selector":{"details":{"color":"red"}}}`, []string{"asset1"}},
+		{`{"selector":{"tags":{"$exists":false},"owner":{"$in":["Org1MSP"]}}}`, []string{"asset3", "asset4"}},
+		{`{"selector":{"$or":[{"owner":"Org2MSP"},{"value":200}]}}`, []string{"asset2", "asset3"}},
+		{`{"selector":{"owner":{"$ne":"Org2MSP"}},"sort":[{"value":"desc"}]}`, []string{"asset4", "asset1", "asset3"}},
+		{`{"selector":{"_id":{"$gt":"asset2"}},"use_index":["_design/index","value"]}`, []string{"asset3", "asset4"}},
+	}
+	stub, _ := ledger.NewStub(client, Transaction{Chaincode: "kv"})
+	for _, test := range tests {
+		iterator, err := stub.GetQueryResult(test.query)
+		if err != nil {
+			t.Fatalf("%s failed: %v", test.query, err)
+		}
+		if found := keys(t, iterator); !reflect.DeepEqual(found, test.want) {
+			t.Errorf("%s found %q, want %q", test.query, found, test.want)
+		}
+	}
+	if _, err := stub.GetQueryResult(`{"selector":{"value":{"$regex":"1"}}}`); err == nil {
+		t.Errorf("ran a query with an unsupported operator")
+	}
+
+	iterator, metadata, err := stub.GetQueryResultWithPagination(`{"selector":{"owner":"Org1MSP"},"fields":["value"]}`, 2, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _ := iterator.Next()
+	var projected map[string]interface{}
+	if err := json.Unmarshal(first.Value, &projected); err != nil || !reflect.DeepEqual(projected, map[string]interface{}{"value": 300.0}) {
+		t.Errorf("projected value is %s", first.Value)
+	}
+	if metadata.FetchedRecordsCount != 2 || metadata.Bookmark != "asset4" {
+		t.Errorf("first page has %d records and bookmark %q", metadata.FetchedRecordsCount, metadata.Bookmark)
+	}
+	iterator, metadata, _ = stub.GetQueryResultWithPagination(`{"selector":{"owner":"Org1MSP"}}`, 2, metadata.Bookmark)
+	if found := keys(t, iterator); !reflect.DeepEqual(found, []string{"asset4"}) || metadata.Bookmark != "" {
+		t.Errorf("last page found %q with bookmark %q", found, metadata.Bookmark)
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	ledger, client := newKVLedger(t)
+	auditor, err := ledger.NewClient("Org1MSP", "auditor", map[string]string{"readonly": "true"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stub, _ := ledger.NewStub(auditor, Transaction{Chaincode: "kv"})
+	creator, _ := stub.GetCreator()
+	var identity msp.SerializedIdentity
+	if err := proto.Unmarshal(creator, &identity); err != nil {
+		t.Fatal(err)
+	}
+	if identity.Mspid != "Org1MSP" {
+		t.Errorf("creator is of %s", identity.Mspid)
+	}
+
+	parse := func(certPEM []byte) *x509.Certificate {
+		t.Helper()
+		block, _ := pem.Decode(certPEM)
+		if block == nil {
+			t.Fatalf("certificate is not PEM encoded")
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cert
+	}
+	cert := parse(identity.IdBytes)
+	if cert.Subject.CommonName != "auditor" || cert.Issuer.CommonName != "ca.org1msp" || cert.Subject.OrganizationalUnit[0] != "client" {
+		t.Errorf("certificate of %s has issuer %s", cert.Subject, cert.Issuer)
+	}
+	if issuer := parse(client.Certificate).Issuer.String(); issuer != cert.Issuer.String() {
+		t.Errorf("clients of one org have the issuers %s and %s", issuer, cert.Issuer)
+	}
+	var attributes struct {
+		Attrs map[string]string `json:"attrs"`
+	}
+	for _, extension := range cert.Extensions {
+		if extension.Id.Equal(attributesOID) {
+			if err := json.Unmarshal(extension.Value, &attributes); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if attributes.Attrs["readonly"] != "true" || attributes.Attrs["hf.EnrollmentID"] != "auditor" {
+		t.Errorf("certificate attributes are %v", attributes.Attrs)
+	}
+
+	admin, err := ledger.NewAdmin("Org1MSP", "Admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if units := parse(admin.Certificate).Subject.OrganizationalUnit; !reflect.DeepEqual(units, []string{"admin"}) {
+		t.Errorf("admin certificate has the organizational units %v", units)
+	}
+}
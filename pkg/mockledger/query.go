@@ -0,0 +1,350 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package mockledger
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+)
+
+// Query is the part of a CouchDB Mango query the ledger runs. The selector may combine fields with
+// $and, $or and $not, and compare a field, or a nested field written a.b, with a value or the
+// operators $eq, $ne, $gt, $gte, $lt, $lte, $in, $nin and $exists, in CouchDB collation order: null,
+// booleans, numbers, strings, arrays, then objects. Results are sorted by key unless Sort names
+// fields, each "field" or {"field": "asc"} or {"field": "desc"}, and only values that are JSON objects
+// are found. Indexes named with use_index are not checked, as no index is needed.
+type Query struct {
+	Selector map[string]interface{} `json:"selector"`
+	Sort     []interface{}          `json:"sort"`
+	Fields   []string               `json:"fields"`
+	Limit    int                    `json:"limit"`
+	Skip     int                    `json:"skip"`
+}
+
+// sortField is a field of the sort of a query
+type sortField struct {
+	path       string
+	descending bool
+}
+
+// document is a JSON value of the state found by a query
+type document struct {
+	key    string
+	value  []byte
+	fields map[string]interface{}
+}
+
+// runQuery runs a rich query over the values of a namespace. A pageSize of 0 or less returns every
+// result; otherwise bookmark is the key of the first result of the page, empty for the first one.
+func runQuery(namespace string, values map[string][]byte, queryJSON string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	var query Query
+	err := json.Unmarshal([]byte(queryJSON), &query)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid query: %v", err)
+	}
+	if query.Selector == nil {
+		return nil, nil, fmt.Errorf("invalid query: it has no selector")
+	}
+	sortFields, err := parseSort(query.Sort)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var documents []*document
+	for _, key := range sortedKeys(values) {
+		var fields map[string]interface{}
+		if json.Unmarshal(values[key], &fields) != nil || fields == nil {
+			continue
+		}
+		fields["_id"] = key
+		matches, err := matchSelector(fields, query.Selector)
+		if err != nil {
+			return nil, nil, err
+		}
+		if matches {
+			documents = append(documents, &document{key: key, value: values[key], fields: fields})
+		}
+	}
+	sort.SliceStable(documents, func(i, j int) bool {
+		for _, field := range sortFields {
+			a, _ := lookup(documents[i].fields, field.path)
+			b, _ := lookup(documents[j].fields, field.path)
+			if order := collate(a, b); order != 0 {
+				return (order < 0) != field.descending
+			}
+		}
+		return false
+	})
+
+	if query.Skip > 0 {
+		documents = documents[min(query.Skip, len(documents)):]
+	}
+	if query.Limit > 0 {
+		documents = documents[:min(query.Limit, len(documents))]
+	}
+	if bookmark != "" {
+		start := -1
+		for i, document := range documents {
+			if document.key == bookmark {
+				start = i
+				break
+			}
+		}
+		if start < 0 {
+			return nil, nil, fmt.Errorf("bookmark %q is not a result of the query", bookmark)
+		}
+		documents = documents[start:]
+	}
+
+	results := make([]*queryresult.KV, 0, len(documents))
+	for _, document := range documents {
+		value := document.value
+		if len(query.Fields) > 0 {
+			projected := make(map[string]interface{})
+			for _, field := range query.Fields {
+				if fieldValue, ok := document.fields[field]; ok {
+					projected[field] = fieldValue
+				}
+			}
+			value, err = json.Marshal(projected)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		results = append(results, &queryresult.KV{Namespace: namespace, Key: document.key, Value: value})
+	}
+	return page(results, pageSize, func(next *queryresult.KV) string { return next.Key })
+}
+
+// parseSort reads the sort of a query
+func parseSort(sortJSON []interface{}) ([]sortField, error) {
+	var fields []sortField
+	for _, entry := range sortJSON {
+		switch entry := entry.(type) {
+		case string:
+			fields = append(fields, sortField{path: entry})
+		case map[string]interface{}:
+			if len(entry) != 1 {
+				return nil, fmt.Errorf("invalid query: a sort entry must name one field, not %v", entry)
+			}
+			for path, direction := range entry {
+				if direction != "asc" && direction != "desc" {
+					return nil, fmt.Errorf("invalid query: sort direction of %s must be asc or desc, not %v", path, direction)
+				}
+				fields = append(fields, sortField{path: path, descending: direction == "desc"})
+			}
+		default:
+			return nil, fmt.Errorf("invalid query: invalid sort entry %v", entry)
+		}
+	}
+	return fields, nil
+}
+
+// matchSelector reports whether a document matches every condition of a selector
+func matchSelector(fields map[string]interface{}, selector map[string]interface{}) (bool, error) {
+	for name, condition := range selector {
+		var matches bool
+		var err error
+		switch name {
+		case "$and", "$or":
+			matches, err = matchCombination(fields, name, condition)
+		case "$not":
+			subSelector, ok := condition.(map[string]interface{})
+			if !ok {
+				return false, fmt.Errorf("invalid query: $not takes a selector")
+			}
+			matches, err = matchSelector(fields, subSelector)
+			matches = !matches
+		default:
+			value, found := lookup(fields, name)
+			matches, err = matchCondition(name, value, found, condition)
+		}
+		if err != nil || !matches {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// matchCombination matches the selectors of $and or $or
+func matchCombination(fields map[string]interface{}, operator string, condition interface{}) (bool, error) {
+	selectors, ok := condition.([]interface{})
+	if !ok {
+		return false, fmt.Errorf("invalid query: %s takes an array of selectors", operator)
+	}
+	for _, entry := range selectors {
+		subSelector, ok := entry.(map[string]interface{})
+		if !ok {
+			return false, fmt.Errorf("invalid query: %s takes an array of selectors", operator)
+		}
+		matches, err := matchSelector(fields, subSelector)
+		if err != nil {
+			return false, err
+		}
+		if matches == (operator == "$or") {
+			return matches, nil
+		}
+	}
+	return operator == "$and", nil
+}
+
+// matchCondition matches a field against a value, operators, or a selector of its nested fields
+func matchCondition(name string, value interface{}, found bool, condition interface{}) (bool, error) {
+	operators, ok := condition.(map[string]interface{})
+	if !ok {
+		return found && collate(value, condition) == 0, nil
+	}
+	if !hasOperators(operators) {
+		nested, ok := value.(map[string]interface{})
+		if !ok {
+			return false, nil
+		}
+		return matchSelector(nested, operators)
+	}
+	for operator, operand := range operators {
+		var matches bool
+		switch operator {
+		case "$exists":
+			want, ok := operand.(bool)
+			if !ok {
+				return false, fmt.Errorf("invalid query: $exists of %s takes a boolean", name)
+			}
+			matches = found == want
+		case "$eq":
+			matches = found && collate(value, operand) == 0
+		case "$ne":
+			matches = !found || collate(value, operand) != 0
+		case "$gt":
+			matches = found && collate(value, operand) > 0
+		case "$gte":
+			matches = found && collate(value, operand) >= 0
+		case "$lt":
+			matches = found && collate(value, operand) < 0
+		case "$lte":
+			matches = found && collate(value, operand) <= 0
+		case "$in", "$nin":
+			candidates, ok := operand.([]interface{})
+			if !ok {
+				return false, fmt.Errorf("invalid query: %s of %s takes an array", operator, name)
+			}
+			in := false
+			for _, candidate := range candidates {
+				if found && collate(value, candidate) == 0 {
+					in = true
+				}
+			}
+			matches = in == (operator == "$in")
+		default:
+			return false, fmt.Errorf("invalid query: operator %s of %s is not supported", operator, name)
+		}
+		if !matches {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// hasOperators reports whether a condition holds operators rather than nested fields
+func hasOperators(condition map[string]interface{}) bool {
+	for name := range condition {
+		if strings.HasPrefix(name, "$") {
+			return true
+		}
+	}
+	return false
+}
+
+// lookup returns the value of a field, following the dots of a nested field
+func lookup(fields map[string]interface{}, path string) (interface{}, bool) {
+	var value interface{} = fields
+	for _, name := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		value, ok = object[name]
+		if !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// collate compares two JSON values in CouchDB collation order, returning -1, 0 or 1
+func collate(a interface{}, b interface{}) int {
+	rankA, rankB := collationRank(a), collationRank(b)
+	if rankA != rankB {
+		return compare(rankA, rankB)
+	}
+	switch a := a.(type) {
+	case bool:
+		return compare(boolRank(a), boolRank(b.(bool)))
+	case float64:
+		return compare(a, b.(float64))
+	case string:
+		return compare(a, b.(string))
+	case []interface{}:
+		b := b.([]interface{})
+		for i := 0; i < len(a) && i < len(b); i++ {
+			if order := collate(a[i], b[i]); order != 0 {
+				return order
+			}
+		}
+		return compare(len(a), len(b))
+	case map[string]interface{}:
+		aJSON, _ := json.Marshal(a)
+		bJSON, _ := json.Marshal(b)
+		return compare(string(aJSON), string(bJSON))
+	}
+	return 0
+}
+
+// collationRank orders the JSON types as CouchDB does
+func collationRank(value interface{}) int {
+	switch value.(type) {
+	case nil:
+		return 0
+	case bool:
+		return 1
+	case float64:
+		return 2
+	case string:
+		return 3
+	case []interface{}:
+		return 4
+	default:
+		return 5
+	}
+}
+
+func boolRank(value bool) int {
+	if value {
+		return 1
+	}
+	return 0
+}
+
+func compare[T int | float64 | string](a T, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func min(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,519 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package mockledger
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+)
+
+// emptyKeySubstitute is where a range query with an empty start key starts, after the composite keys
+// that start with U+0000, as in the shim
+const emptyKeySubstitute = "\x01"
+
+// compositeKeyNamespace starts every composite key
+const compositeKeyNamespace = "\x00"
+
+// Stub is the stub of one transaction, calling one chaincode. As on a peer, reads see the state
+// committed before the transaction and not its own writes, which are kept in the transaction until
+// it is committed. A stub returned by NewStub must not be used while other goroutines call Submit.
+type Stub struct {
+	ledger    *Ledger
+	tx        *transaction
+	namespace string
+	args      [][]byte
+}
+
+// transaction is the simulation of a transaction, shared by the stubs of the chaincodes it calls
+type transaction struct {
+	id        string
+	chaincode string
+	timestamp *timestamp.Timestamp
+	creator   []byte
+	transient map[string][]byte
+	// writes and validation are keyed by the namespace, then by key
+	writes     map[string]map[string]*write
+	private    map[collectionID]map[string]*write
+	validation map[string]map[string][]byte
+	event      *pb.ChaincodeEvent
+	committed  bool
+	// the peer allows paginated and private data queries only in transactions that write nothing
+	wrote, paginated, privateQueried bool
+}
+
+// write is a value written or deleted by a transaction
+type write struct {
+	value   []byte
+	deleted bool
+}
+
+var _ shim.ChaincodeStubInterface = (*Stub)(nil)
+
+// Commit commits the writes and event of the transaction of a stub returned by NewStub. It fails for
+// a transaction already committed.
+func (s *Stub) Commit() error {
+	s.ledger.mu.Lock()
+	defer s.ledger.mu.Unlock()
+	if s.tx.committed {
+		return fmt.Errorf("transaction %s is already committed", s.tx.id)
+	}
+	s.ledger.commit(s.tx)
+	return nil
+}
+
+func (s *Stub) GetArgs() [][]byte {
+	return s.args
+}
+
+func (s *Stub) GetStringArgs() []string {
+	args := make([]string, len(s.args))
+	for i, arg := range s.args {
+		args[i] = string(arg)
+	}
+	return args
+}
+
+func (s *Stub) GetFunctionAndParameters() (string, []string) {
+	args := s.GetStringArgs()
+	if len(args) == 0 {
+		return "", []string{}
+	}
+	return args[0], args[1:]
+}
+
+func (s *Stub) GetArgsSlice() ([]byte, error) {
+	return bytes.Join(s.args, nil), nil
+}
+
+func (s *Stub) GetTxID() string {
+	return s.tx.id
+}
+
+func (s *Stub) GetChannelID() string {
+	return s.ledger.channelID
+}
+
+// InvokeChaincode calls a chaincode deployed on the ledger in the same transaction, so its writes are
+// committed with the transaction in the namespace of the called chaincode. Its event is dropped, as
+// a transaction has only the event of the chaincode the client called.
+func (s *Stub) InvokeChaincode(chaincodeName string, args [][]byte, channel string) pb.Response {
+	if channel != "" && channel != s.ledger.channelID {
+		return shim.Error(fmt.Sprintf("calling chaincode %s on channel %s from channel %s is not supported", chaincodeName, channel, s.ledger.channelID))
+	}
+	chaincode, ok := s.ledger.chaincodes[chaincodeName]
+	if !ok {
+		return shim.Error(fmt.Sprintf("chaincode %s is not deployed on channel %s", chaincodeName, s.ledger.channelID))
+	}
+	return chaincode.Invoke(&Stub{ledger: s.ledger, tx: s.tx, namespace: chaincodeName, args: args})
+}
+
+func (s *Stub) GetState(key string) ([]byte, error) {
+	return s.ledger.state[s.namespace][key], nil
+}
+
+func (s *Stub) PutState(key string, value []byte) error {
+	return s.putWrite(s.tx.writes, s.namespace, key, &write{value: value})
+}
+
+func (s *Stub) DelState(key string) error {
+	return s.putWrite(s.tx.writes, s.namespace, key, &write{deleted: true})
+}
+
+func (s *Stub) SetStateValidationParameter(key string, ep []byte) error {
+	if err := s.checkWrite(key); err != nil {
+		return err
+	}
+	if s.tx.validation[s.namespace] == nil {
+		s.tx.validation[s.namespace] = make(map[string][]byte)
+	}
+	s.tx.validation[s.namespace][key] = ep
+	return nil
+}
+
+func (s *Stub) GetStateValidationParameter(key string) ([]byte, error) {
+	return s.ledger.validation[s.namespace][key], nil
+}
+
+func (s *Stub) GetStateByRange(startKey string, endKey string) (shim.StateQueryIteratorInterface, error) {
+	if err := validateSimpleKeys(startKey, endKey); err != nil {
+		return nil, err
+	}
+	if startKey == "" {
+		startKey = emptyKeySubstitute
+	}
+	return s.rangeIterator(s.ledger.state[s.namespace], startKey, endKey), nil
+}
+
+func (s *Stub) GetStateByRangeWithPagination(startKey string, endKey string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	if err := validateSimpleKeys(startKey, endKey); err != nil {
+		return nil, nil, err
+	}
+	if startKey == "" {
+		startKey = emptyKeySubstitute
+	}
+	return s.pageIterator(startKey, endKey, pageSize, bookmark)
+}
+
+func (s *Stub) GetStateByPartialCompositeKey(objectType string, keys []string) (shim.StateQueryIteratorInterface, error) {
+	startKey, err := s.CreateCompositeKey(objectType, keys)
+	if err != nil {
+		return nil, err
+	}
+	return s.rangeIterator(s.ledger.state[s.namespace], startKey, startKey+string(utf8.MaxRune)), nil
+}
+
+func (s *Stub) GetStateByPartialCompositeKeyWithPagination(objectType string, keys []string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	startKey, err := s.CreateCompositeKey(objectType, keys)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.pageIterator(startKey, startKey+string(utf8.MaxRune), pageSize, bookmark)
+}
+
+// CreateCompositeKey builds keys the same way as the shim
+func (s *Stub) CreateCompositeKey(objectType string, attributes []string) (string, error) {
+	if err := validateCompositeKeyAttribute(objectType); err != nil {
+		return "", err
+	}
+	key := compositeKeyNamespace + objectType + string(rune(0))
+	for _, attribute := range attributes {
+		if err := validateCompositeKeyAttribute(attribute); err != nil {
+			return "", err
+		}
+		key += attribute + string(rune(0))
+	}
+	return key, nil
+}
+
+func (s *Stub) SplitCompositeKey(compositeKey string) (string, []string, error) {
+	parts := strings.Split(compositeKey, string(rune(0)))
+	if len(parts) < 3 || parts[0] != "" || parts[len(parts)-1] != "" {
+		return "", nil, fmt.Errorf("invalid composite key %q", compositeKey)
+	}
+	return parts[1], parts[2 : len(parts)-1], nil
+}
+
+// GetQueryResult runs a CouchDB rich query over the JSON values of the state, see Query
+func (s *Stub) GetQueryResult(query string) (shim.StateQueryIteratorInterface, error) {
+	iterator, _, err := runQuery(s.namespace, s.ledger.state[s.namespace], query, 0, "")
+	return iterator, err
+}
+
+// GetQueryResultWithPagination runs a CouchDB rich query over the JSON values of the state, see Query
+func (s *Stub) GetQueryResultWithPagination(query string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	if err := s.checkPaginatedQuery(); err != nil {
+		return nil, nil, err
+	}
+	return runQuery(s.namespace, s.ledger.state[s.namespace], query, pageSize, bookmark)
+}
+
+// GetHistoryForKey returns the committed modifications of a key, newest first as on a Fabric v2 peer
+func (s *Stub) GetHistoryForKey(key string) (shim.HistoryQueryIteratorInterface, error) {
+	modifications := s.ledger.history[s.namespace][key]
+	iterator := &historyIterator{}
+	for i := len(modifications) - 1; i >= 0; i-- {
+		iterator.results = append(iterator.results, modifications[i])
+	}
+	return iterator, nil
+}
+
+func (s *Stub) GetPrivateData(collection string, key string) ([]byte, error) {
+	return s.ledger.private[collectionID{s.namespace, collection}][key], nil
+}
+
+// GetPrivateDataHash returns the SHA-256 hash every peer of the channel holds for private data
+func (s *Stub) GetPrivateDataHash(collection string, key string) ([]byte, error) {
+	value, ok := s.ledger.private[collectionID{s.namespace, collection}][key]
+	if !ok {
+		return nil, nil
+	}
+	hash := sha256.Sum256(value)
+	return hash[:], nil
+}
+
+func (s *Stub) PutPrivateData(collection string, key string, value []byte) error {
+	return s.putPrivateWrite(collection, key, &write{value: value})
+}
+
+func (s *Stub) DelPrivateData(collection string, key string) error {
+	return s.putPrivateWrite(collection, key, &write{deleted: true})
+}
+
+// PurgePrivateData deletes private data as the shims of Fabric v2.5 and later do. The ledger keeps no
+// private data history, so it is the same as DelPrivateData.
+func (s *Stub) PurgePrivateData(collection string, key string) error {
+	return s.putPrivateWrite(collection, key, &write{deleted: true})
+}
+
+func (s *Stub) SetPrivateDataValidationParameter(collection string, key string, ep []byte) error {
+	if err := s.checkWrite(key); err != nil {
+		return err
+	}
+	namespace := privateNamespace(s.namespace, collection)
+	if s.tx.validation[namespace] == nil {
+		s.tx.validation[namespace] = make(map[string][]byte)
+	}
+	s.tx.validation[namespace][key] = ep
+	return nil
+}
+
+func (s *Stub) GetPrivateDataValidationParameter(collection string, key string) ([]byte, error) {
+	return s.ledger.validation[privateNamespace(s.namespace, collection)][key], nil
+}
+
+func (s *Stub) GetPrivateDataByRange(collection string, startKey string, endKey string) (shim.StateQueryIteratorInterface, error) {
+	if err := s.checkPrivateQuery(); err != nil {
+		return nil, err
+	}
+	if err := validateSimpleKeys(startKey, endKey); err != nil {
+		return nil, err
+	}
+	if startKey == "" {
+		startKey = emptyKeySubstitute
+	}
+	return s.rangeIterator(s.ledger.private[collectionID{s.namespace, collection}], startKey, endKey), nil
+}
+
+func (s *Stub) GetPrivateDataByPartialCompositeKey(collection string, objectType string, keys []string) (shim.StateQueryIteratorInterface, error) {
+	if err := s.checkPrivateQuery(); err != nil {
+		return nil, err
+	}
+	startKey, err := s.CreateCompositeKey(objectType, keys)
+	if err != nil {
+		return nil, err
+	}
+	return s.rangeIterator(s.ledger.private[collectionID{s.namespace, collection}], startKey, startKey+string(utf8.MaxRune)), nil
+}
+
+// GetPrivateDataQueryResult runs a CouchDB rich query over the JSON values of a collection, see Query
+func (s *Stub) GetPrivateDataQueryResult(collection string, query string) (shim.StateQueryIteratorInterface, error) {
+	if err := s.checkPrivateQuery(); err != nil {
+		return nil, err
+	}
+	iterator, _, err := runQuery(s.namespace, s.ledger.private[collectionID{s.namespace, collection}], query, 0, "")
+	return iterator, err
+}
+
+// GetCreator returns the serialized identity of the client, as cid.New reads it
+func (s *Stub) GetCreator() ([]byte, error) {
+	return s.tx.creator, nil
+}
+
+func (s *Stub) GetTransient() (map[string][]byte, error) {
+	return s.tx.transient, nil
+}
+
+// GetBinding returns nil, the ledger does not sign proposals
+func (s *Stub) GetBinding() ([]byte, error) {
+	return nil, nil
+}
+
+func (s *Stub) GetDecorations() map[string][]byte {
+	return map[string][]byte{}
+}
+
+// GetSignedProposal returns an empty proposal, the ledger does not sign proposals
+func (s *Stub) GetSignedProposal() (*pb.SignedProposal, error) {
+	return &pb.SignedProposal{}, nil
+}
+
+func (s *Stub) GetTxTimestamp() (*timestamp.Timestamp, error) {
+	return s.tx.timestamp, nil
+}
+
+// SetEvent sets the event of the transaction, replacing any event set before as on a peer
+func (s *Stub) SetEvent(name string, payload []byte) error {
+	if name == "" {
+		return errors.New("event name can not be empty string")
+	}
+	if s.namespace == s.tx.chaincode {
+		s.tx.event = &pb.ChaincodeEvent{ChaincodeId: s.namespace, TxId: s.tx.id, EventName: name, Payload: payload}
+	}
+	return nil
+}
+
+// putWrite records a write of the transaction to a key of a namespace
+func (s *Stub) putWrite(writes map[string]map[string]*write, namespace string, key string, w *write) error {
+	if err := s.checkWrite(key); err != nil {
+		return err
+	}
+	if writes[namespace] == nil {
+		writes[namespace] = make(map[string]*write)
+	}
+	writes[namespace][key] = w
+	s.tx.wrote = true
+	return nil
+}
+
+// putPrivateWrite records a write of the transaction to a key of a collection
+func (s *Stub) putPrivateWrite(collection string, key string, w *write) error {
+	if err := s.checkWrite(key); err != nil {
+		return err
+	}
+	id := collectionID{s.namespace, collection}
+	if s.tx.private[id] == nil {
+		s.tx.private[id] = make(map[string]*write)
+	}
+	s.tx.private[id][key] = w
+	s.tx.wrote = true
+	return nil
+}
+
+// checkWrite fails the writes a peer refuses
+func (s *Stub) checkWrite(key string) error {
+	if key == "" {
+		return errors.New("key must not be an empty string")
+	}
+	if s.tx.paginated {
+		return errors.New("transaction has already performed a paginated query, writes are not allowed")
+	}
+	if s.tx.privateQueried {
+		return errors.New("transaction has already performed queries on private data, writes are not allowed")
+	}
+	return nil
+}
+
+// checkPaginatedQuery fails a paginated query after a write, which a peer refuses
+func (s *Stub) checkPaginatedQuery() error {
+	if s.tx.wrote {
+		return errors.New("transaction has already performed writes, performing a paginated query is not supported")
+	}
+	s.tx.paginated = true
+	return nil
+}
+
+// checkPrivateQuery fails a private data query after a write, which a peer refuses
+func (s *Stub) checkPrivateQuery() error {
+	if s.tx.wrote {
+		return errors.New("queries on private data are supported only in read-only transactions")
+	}
+	s.tx.privateQueried = true
+	return nil
+}
+
+// rangeIterator returns the entries of values from startKey up to but not including endKey, in key
+// order, every key after startKey when endKey is empty
+func (s *Stub) rangeIterator(values map[string][]byte, startKey string, endKey string) *stateIterator {
+	iterator := &stateIterator{}
+	for _, key := range sortedKeys(values) {
+		if key >= startKey && (endKey == "" || key < endKey) {
+			iterator.results = append(iterator.results, &queryresult.KV{Namespace: s.namespace, Key: key, Value: values[key]})
+		}
+	}
+	return iterator
+}
+
+// pageIterator returns up to pageSize entries of the state from bookmark, or from startKey for an
+// empty bookmark, with the key of the next entry as the bookmark of the next page
+func (s *Stub) pageIterator(startKey string, endKey string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	if err := s.checkPaginatedQuery(); err != nil {
+		return nil, nil, err
+	}
+	if bookmark != "" {
+		if bookmark < startKey || (endKey != "" && bookmark >= endKey) {
+			return nil, nil, fmt.Errorf("bookmark %q is not in the range of the query", bookmark)
+		}
+		startKey = bookmark
+	}
+	all := s.rangeIterator(s.ledger.state[s.namespace], startKey, endKey)
+	return page(all.results, pageSize, func(next *queryresult.KV) string { return next.Key })
+}
+
+// page returns the first pageSize results, all of them for a pageSize of 0 or less, and the bookmark
+// of the next page, empty when none is left
+func page(results []*queryresult.KV, pageSize int32, bookmarkOf func(next *queryresult.KV) string) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	metadata := &pb.QueryResponseMetadata{}
+	if pageSize > 0 && len(results) > int(pageSize) {
+		metadata.Bookmark = bookmarkOf(results[pageSize])
+		results = results[:pageSize]
+	}
+	metadata.FetchedRecordsCount = int32(len(results))
+	return &stateIterator{results: results}, metadata, nil
+}
+
+// privateNamespace is the namespace of the validation parameters of a collection
+func privateNamespace(namespace string, collection string) string {
+	return namespace + "$$p" + collection
+}
+
+// validateSimpleKeys fails range query keys that are composite keys, as the shim does
+func validateSimpleKeys(keys ...string) error {
+	for _, key := range keys {
+		if key != "" && key[0] == 0 {
+			return fmt.Errorf("first character of the key [%s] contains a null character which is not allowed", key)
+		}
+	}
+	return nil
+}
+
+// validateCompositeKeyAttribute fails the object types and attributes the shim refuses in composite keys
+func validateCompositeKeyAttribute(value string) error {
+	if !utf8.ValidString(value) {
+		return fmt.Errorf("not a valid utf8 string: [%x]", value)
+	}
+	for _, r := range value {
+		if r == 0 || r == utf8.MaxRune {
+			return fmt.Errorf("input contains unicode %#U starting at position [%d]. %#U and %#U are not allowed in the input attribute of a composite key", r, strings.IndexRune(value, r), 0, utf8.MaxRune)
+		}
+	}
+	return nil
+}
+
+// stateIterator iterates the results of a query
+type stateIterator struct {
+	results []*queryresult.KV
+	closed  bool
+}
+
+func (it *stateIterator) HasNext() bool {
+	return !it.closed && len(it.results) > 0
+}
+
+func (it *stateIterator) Next() (*queryresult.KV, error) {
+	if !it.HasNext() {
+		return nil, errors.New("no more results")
+	}
+	result := it.results[0]
+	it.results = it.results[1:]
+	return result, nil
+}
+
+func (it *stateIterator) Close() error {
+	it.closed = true
+	return nil
+}
+
+// historyIterator iterates the modifications of a key
+type historyIterator struct {
+	results []*queryresult.KeyModification
+	closed  bool
+}
+
+func (it *historyIterator) HasNext() bool {
+	return !it.closed && len(it.results) > 0
+}
+
+func (it *historyIterator) Next() (*queryresult.KeyModification, error) {
+	if !it.HasNext() {
+		return nil, errors.New("no more results")
+	}
+	result := it.results[0]
+	it.results = it.results[1:]
+	return result, nil
+}
+
+func (it *historyIterator) Close() error {
+	it.closed = true
+	return nil
+}
@@ -0,0 +1,23 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"log"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/randomness-beacon/chaincode-go/chaincode"
+)
+
+func main() {
+	beaconChaincode, err := contractapi.NewChaincode(&chaincode.SmartContract{})
+	if err != nil {
+		log.Panicf("Error creating randomness-beacon chaincode: %v", err)
+	}
+
+	if err := beaconChaincode.Start(); err != nil {
+		log.Panicf("Error starting randomness-beacon chaincode: %v", err)
+	}
+}
@@ -0,0 +1,428 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil"
+)
+
+// object names for prefix
+const (
+	roundPrefix       = "round"
+	commitPrefix      = "commit"
+	participantPrefix = "participant"
+)
+
+// round status
+const (
+	statusOpen      = "OPEN"
+	statusFinalized = "FINALIZED"
+	statusFailed    = "FAILED"
+)
+
+// maxMissedReveals is the number of reveals an org may miss before it is suspended from committing
+const maxMissedReveals = 3
+
+// SmartContract provides functions for generating random values by commit-reveal among member orgs
+type SmartContract struct {
+	contractapi.Contract
+}
+
+// Round is one commit-reveal round. Orgs commit to a secret before CommitDeadline and reveal it
+// before RevealDeadline. The random value is the hash of all revealed secrets.
+type Round struct {
+	ObjectType      string   `json:"objectType"`
+	ID              string   `json:"roundID"`
+	Purpose         string   `json:"purpose"`
+	Opener          string   `json:"opener"`
+	CommitDeadline  string   `json:"commitDeadline"`
+	RevealDeadline  string   `json:"revealDeadline"`
+	MinParticipants int      `json:"minParticipants"`
+	Status          string   `json:"status"`
+	Value           string   `json:"value"`
+	Contributors    []string `json:"contributors"`
+	Penalized       []string `json:"penalized"`
+}
+
+// Commitment is an org's commitment for a round, and its secret once revealed
+type Commitment struct {
+	RoundID    string `json:"roundID"`
+	MSPID      string `json:"mspID"`
+	Commitment string `json:"commitment"`
+	Secret     string `json:"secret"`
+	Revealed   bool   `json:"revealed"`
+}
+
+// Participant keeps the reveal record of an org across rounds
+type Participant struct {
+	ObjectType   string `json:"objectType"`
+	MSPID        string `json:"mspID"`
+	Revealed     int    `json:"revealed"`
+	MissedReveal int    `json:"missedReveal"`
+	Suspended    bool   `json:"suspended"`
+}
+
+// OpenRound starts a new round. Any member can open a round, e.g. an auction or lottery operator
+// that needs a random value at a known time.
+func (s *SmartContract) OpenRound(ctx contractapi.TransactionContextInterface, roundID string, purpose string, commitDeadline string, revealDeadline string, minParticipants int) error {
+	opener, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	if roundID == "" {
+		return fmt.Errorf("round ID must be set")
+	}
+	if minParticipants < 2 {
+		return fmt.Errorf("a round needs at least two participants")
+	}
+	commitTime, err := time.Parse(time.RFC3339, commitDeadline)
+	if err != nil {
+		return fmt.Errorf("commit deadline %s is not an RFC3339 timestamp: %v", commitDeadline, err)
+	}
+	revealTime, err := time.Parse(time.RFC3339, revealDeadline)
+	if err != nil {
+		return fmt.Errorf("reveal deadline %s is not an RFC3339 timestamp: %v", revealDeadline, err)
+	}
+	now, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return err
+	}
+	if !commitTime.After(now) {
+		return fmt.Errorf("commit deadline %s is in the past", commitDeadline)
+	}
+	if !revealTime.After(commitTime) {
+		return fmt.Errorf("reveal deadline must be after the commit deadline")
+	}
+
+	roundKey, err := ctx.GetStub().CreateCompositeKey(roundPrefix, []string{roundID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	existing, err := ctx.GetStub().GetState(roundKey)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if existing != nil {
+		return fmt.Errorf("the round %s already exists", roundID)
+	}
+
+	round := Round{
+		ObjectType:      roundPrefix,
+		ID:              roundID,
+		Purpose:         purpose,
+		Opener:          opener,
+		CommitDeadline:  commitDeadline,
+		RevealDeadline:  revealDeadline,
+		MinParticipants: minParticipants,
+		Status:          statusOpen,
+	}
+	return _putRound(ctx, &round)
+}
+
+// Commit records the client org's commitment for a round. commitment is the hex SHA-256 hash of
+// "mspID:secret", binding the secret to the org so another org cannot copy the commitment.
+func (s *SmartContract) Commit(ctx contractapi.TransactionContextInterface, roundID string, commitment string) error {
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+
+	round, err := s.GetRound(ctx, roundID)
+	if err != nil {
+		return err
+	}
+	if round.Status != statusOpen {
+		return fmt.Errorf("round %s is %s", roundID, round.Status)
+	}
+	passed, err := _deadlinePassed(ctx, round.CommitDeadline)
+	if err != nil {
+		return err
+	}
+	if passed {
+		return fmt.Errorf("the commit phase of round %s closed at %s", roundID, round.CommitDeadline)
+	}
+
+	hash, err := hex.DecodeString(commitment)
+	if err != nil || len(hash) != sha256.Size {
+		return fmt.Errorf("commitment must be a hex encoded SHA-256 digest")
+	}
+
+	participant, err := s.GetParticipant(ctx, mspID)
+	if err != nil {
+		return err
+	}
+	if participant.Suspended {
+		return fmt.Errorf("%s is suspended after missing %d reveals", mspID, participant.MissedReveal)
+	}
+
+	existing, err := _getCommitment(ctx, roundID, mspID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("%s has already committed to round %s", mspID, roundID)
+	}
+
+	return _putCommitment(ctx, &Commitment{RoundID: roundID, MSPID: mspID, Commitment: commitment})
+}
+
+// Reveal discloses the secret behind the client org's commitment. Reveals are accepted after the
+// commit phase closes and before the reveal deadline.
+func (s *SmartContract) Reveal(ctx contractapi.TransactionContextInterface, roundID string, secret string) error {
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+
+	round, err := s.GetRound(ctx, roundID)
+	if err != nil {
+		return err
+	}
+	if round.Status != statusOpen {
+		return fmt.Errorf("round %s is %s", roundID, round.Status)
+	}
+	commitClosed, err := _deadlinePassed(ctx, round.CommitDeadline)
+	if err != nil {
+		return err
+	}
+	if !commitClosed {
+		return fmt.Errorf("round %s is in its commit phase until %s", roundID, round.CommitDeadline)
+	}
+	revealClosed, err := _deadlinePassed(ctx, round.RevealDeadline)
+	if err != nil {
+		return err
+	}
+	if revealClosed {
+		return fmt.Errorf("the reveal phase of round %s closed at %s", roundID, round.RevealDeadline)
+	}
+
+	commitment, err := _getCommitment(ctx, roundID, mspID)
+	if err != nil {
+		return err
+	}
+	if commitment == nil {
+		return fmt.Errorf("%s has not committed to round %s", mspID, roundID)
+	}
+	if commitment.Revealed {
+		return fmt.Errorf("%s has already revealed for round %s", mspID, roundID)
+	}
+
+	if secret == "" {
+		return fmt.Errorf("secret must be set")
+	}
+	hash := sha256.Sum256([]byte(mspID + ":" + secret))
+	if hex.EncodeToString(hash[:]) != commitment.Commitment {
+		return fmt.Errorf("secret does not match the commitment of %s", mspID)
+	}
+
+	commitment.Secret = secret
+	commitment.Revealed = true
+	return _putCommitment(ctx, commitment)
+}
+
+// FinalizeRound closes a round after its reveal deadline. The random value is the SHA-256 hash of
+// the revealed secrets in MSP ID order. Orgs that committed but did not reveal are recorded as
+// missing a reveal, and are suspended after maxMissedReveals, since withholding a reveal is the
+// only way a participant can influence the result.
+func (s *SmartContract) FinalizeRound(ctx contractapi.TransactionContextInterface, roundID string) (*Round, error) {
+	round, err := s.GetRound(ctx, roundID)
+	if err != nil {
+		return nil, err
+	}
+	if round.Status != statusOpen {
+		return nil, fmt.Errorf("round %s is %s", roundID, round.Status)
+	}
+	passed, err := _deadlinePassed(ctx, round.RevealDeadline)
+	if err != nil {
+		return nil, err
+	}
+	if !passed {
+		return nil, fmt.Errorf("round %s is open for reveals until %s", roundID, round.RevealDeadline)
+	}
+
+	commitments, err := s.GetCommitments(ctx, roundID)
+	if err != nil {
+		return nil, err
+	}
+	// sort so every peer hashes the secrets in the same order
+	sort.Slice(commitments, func(i, j int) bool { return commitments[i].MSPID < commitments[j].MSPID })
+
+	hasher := sha256.New()
+	for _, commitment := range commitments {
+		participant, err := s.GetParticipant(ctx, commitment.MSPID)
+		if err != nil {
+			return nil, err
+		}
+
+		if commitment.Revealed {
+			hasher.Write([]byte(commitment.MSPID + ":" + commitment.Secret + "\n"))
+			round.Contributors = append(round.Contributors, commitment.MSPID)
+			participant.Revealed++
+		} else {
+			round.Penalized = append(round.Penalized, commitment.MSPID)
+			participant.MissedReveal++
+			if participant.MissedReveal >= maxMissedReveals {
+				participant.Suspended = true
+			}
+		}
+
+		err = _putParticipant(ctx, participant)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(round.Contributors) >= round.MinParticipants {
+		round.Value = hex.EncodeToString(hasher.Sum(nil))
+		round.Status = statusFinalized
+	} else {
+		round.Status = statusFailed
+	}
+
+	err = _putRound(ctx, round)
+	if err != nil {
+		return nil, err
+	}
+
+	roundJSON, err := json.Marshal(round)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal round: %v", err)
+	}
+	err = ctx.GetStub().SetEvent("RoundFinalized", roundJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set event: %v", err)
+	}
+
+	return round, nil
+}
+
+// ReinstateParticipant lifts the suspension of the client's own org. Only an admin of the org
+// can reinstate it, and its missed reveal count starts again from zero.
+func (s *SmartContract) ReinstateParticipant(ctx contractapi.TransactionContextInterface) error {
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+	cert, err := ctx.GetClientIdentity().GetX509Certificate()
+	if err != nil {
+		return fmt.Errorf("failed to get client certificate: %v", err)
+	}
+	isAdmin := false
+	for _, ou := range cert.Subject.OrganizationalUnit {
+		if ou == "admin" {
+			isAdmin = true
+		}
+	}
+	if !isAdmin {
+		return fmt.Errorf("client is not an admin of %s", mspID)
+	}
+
+	participant, err := s.GetParticipant(ctx, mspID)
+	if err != nil {
+		return err
+	}
+	if !participant.Suspended {
+		return fmt.Errorf("%s is not suspended", mspID)
+	}
+
+	participant.Suspended = false
+	participant.MissedReveal = 0
+	return _putParticipant(ctx, participant)
+}
+
+// _deadlinePassed compares an RFC3339 deadline with the transaction timestamp
+func _deadlinePassed(ctx contractapi.TransactionContextInterface, deadline string) (bool, error) {
+	deadlineTime, err := time.Parse(time.RFC3339, deadline)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse deadline: %v", err)
+	}
+	now, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return false, err
+	}
+	return !now.Before(deadlineTime), nil
+}
+
+// _getCommitment reads an org's commitment for a round, returning nil when it has none
+func _getCommitment(ctx contractapi.TransactionContextInterface, roundID string, mspID string) (*Commitment, error) {
+	commitKey, err := ctx.GetStub().CreateCompositeKey(commitPrefix, []string{roundID, mspID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	commitJSON, err := ctx.GetStub().GetState(commitKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if commitJSON == nil {
+		return nil, nil
+	}
+
+	var commitment Commitment
+	err = json.Unmarshal(commitJSON, &commitment)
+	if err != nil {
+		return nil, err
+	}
+	return &commitment, nil
+}
+
+// _putRound writes the round to the world state
+func _putRound(ctx contractapi.TransactionContextInterface, round *Round) error {
+	roundKey, err := ctx.GetStub().CreateCompositeKey(roundPrefix, []string{round.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	roundJSON, err := json.Marshal(round)
+	if err != nil {
+		return fmt.Errorf("failed to marshal round: %v", err)
+	}
+	err = ctx.GetStub().PutState(roundKey, roundJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put round %s: %v", round.ID, err)
+	}
+	return nil
+}
+
+// _putCommitment writes the commitment to the world state
+func _putCommitment(ctx contractapi.TransactionContextInterface, commitment *Commitment) error {
+	commitKey, err := ctx.GetStub().CreateCompositeKey(commitPrefix, []string{commitment.RoundID, commitment.MSPID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	commitJSON, err := json.Marshal(commitment)
+	if err != nil {
+		return fmt.Errorf("failed to marshal commitment: %v", err)
+	}
+	err = ctx.GetStub().PutState(commitKey, commitJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put commitment: %v", err)
+	}
+	return nil
+}
+
+// _putParticipant writes the participant record to the world state
+func _putParticipant(ctx contractapi.TransactionContextInterface, participant *Participant) error {
+	participantKey, err := ctx.GetStub().CreateCompositeKey(participantPrefix, []string{participant.MSPID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	participantJSON, err := json.Marshal(participant)
+	if err != nil {
+		return fmt.Errorf("failed to marshal participant: %v", err)
+	}
+	err = ctx.GetStub().PutState(participantKey, participantJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put participant %s: %v", participant.MSPID, err)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// GetRound returns the round stored in the world state with the given ID
+func (s *SmartContract) GetRound(ctx contractapi.TransactionContextInterface, roundID string) (*Round, error) {
+	roundKey, err := ctx.GetStub().CreateCompositeKey(roundPrefix, []string{roundID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	roundJSON, err := ctx.GetStub().GetState(roundKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if roundJSON == nil {
+		return nil, fmt.Errorf("the round %s does not exist", roundID)
+	}
+
+	var round Round
+	err = json.Unmarshal(roundJSON, &round)
+	if err != nil {
+		return nil, err
+	}
+	return &round, nil
+}
+
+// GetRandomValue returns the hex encoded random value of a finalized round. Consumer chaincodes
+// such as auctions or lotteries call this with InvokeChaincode.
+func (s *SmartContract) GetRandomValue(ctx contractapi.TransactionContextInterface, roundID string) (string, error) {
+	round, err := s.GetRound(ctx, roundID)
+	if err != nil {
+		return "", err
+	}
+	if round.Status != statusFinalized {
+		return "", fmt.Errorf("round %s is %s and has no random value", roundID, round.Status)
+	}
+	return round.Value, nil
+}
+
+// GetCommitments returns the commitments made to a round. Secrets are only filled in once revealed.
+func (s *SmartContract) GetCommitments(ctx contractapi.TransactionContextInterface, roundID string) ([]*Commitment, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(commitPrefix, []string{roundID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commitments for round %s: %v", roundID, err)
+	}
+	defer resultsIterator.Close()
+
+	var commitments []*Commitment
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var commitment Commitment
+		err = json.Unmarshal(response.Value, &commitment)
+		if err != nil {
+			return nil, err
+		}
+		commitments = append(commitments, &commitment)
+	}
+
+	return commitments, nil
+}
+
+// GetParticipant returns the reveal record of an org, which is empty if it has not taken part yet
+func (s *SmartContract) GetParticipant(ctx contractapi.TransactionContextInterface, mspID string) (*Participant, error) {
+	participantKey, err := ctx.GetStub().CreateCompositeKey(participantPrefix, []string{mspID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	participantJSON, err := ctx.GetStub().GetState(participantKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if participantJSON == nil {
+		return &Participant{ObjectType: participantPrefix, MSPID: mspID}, nil
+	}
+
+	var participant Participant
+	err = json.Unmarshal(participantJSON, &participant)
+	if err != nil {
+		return nil, err
+	}
+	return &participant, nil
+}
@@ -0,0 +1,145 @@
+package chaincode
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+const (
+	// the commit phase closes 100 transactions after the first one of a test, the reveal phase 200
+	commitDeadline = "2020-09-13T12:28:20Z"
+	revealDeadline = "2020-09-13T12:30:00Z"
+)
+
+func checkError(t *testing.T, err error, expected string) {
+	t.Helper()
+	if expected == "" && err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected != "" && (err == nil || err.Error() != expected) {
+		t.Fatalf("expected error %q, got %v", expected, err)
+	}
+}
+
+// commitment returns the commitment of an org to a secret
+func commitment(mspID string, secret string) string {
+	hash := sha256.Sum256([]byte(mspID + ":" + secret))
+	return hex.EncodeToString(hash[:])
+}
+
+// openRound opens round1 needing two participants and commits Org1 and Org2 to their secrets
+func openRound(t *testing.T, stub *fakeStub) {
+	t.Helper()
+	contract := new(SmartContract)
+	checkError(t, contract.OpenRound(newContext(stub, "operator", "Org1MSP"), "round1", "lottery draw", commitDeadline, revealDeadline, 2), "")
+	checkError(t, contract.Commit(newContext(stub, "peer1", "Org1MSP"), "round1", commitment("Org1MSP", "s1")), "")
+	checkError(t, contract.Commit(newContext(stub, "peer2", "Org2MSP"), "round1", commitment("Org2MSP", "s2")), "")
+}
+
+func TestOpenRound(t *testing.T) {
+	tests := []struct {
+		name            string
+		roundID         string
+		commitDeadline  string
+		minParticipants int
+		expected        string
+	}{
+		{"open", "round2", commitDeadline, 2, ""},
+		{"existing round", "round1", commitDeadline, 2, "the round round1 already exists"},
+		{"one participant", "round2", commitDeadline, 1, "a round needs at least two participants"},
+		{"past deadline", "round2", "2020-09-13T12:26:40Z", 2, "commit deadline 2020-09-13T12:26:40Z is in the past"},
+		{"reveal before commit", "round2", revealDeadline, 2, "reveal deadline must be after the commit deadline"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stub := newFakeStub()
+			openRound(t, stub)
+
+			err := new(SmartContract).OpenRound(newContext(stub, "operator", "Org2MSP"), test.roundID, "", test.commitDeadline, revealDeadline, test.minParticipants)
+			checkError(t, err, test.expected)
+		})
+	}
+}
+
+func TestCommitReveal(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	openRound(t, stub)
+
+	err := contract.Commit(newContext(stub, "peer1", "Org1MSP"), "round1", commitment("Org1MSP", "other"))
+	checkError(t, err, "Org1MSP has already committed to round round1")
+
+	err = contract.Commit(newContext(stub, "peer3", "Org3MSP"), "round1", "abc")
+	checkError(t, err, "commitment must be a hex encoded SHA-256 digest")
+
+	err = contract.Reveal(newContext(stub, "peer1", "Org1MSP"), "round1", "s1")
+	checkError(t, err, "round round1 is in its commit phase until "+commitDeadline)
+
+	stub.txCount = 100
+	err = contract.Commit(newContext(stub, "peer3", "Org3MSP"), "round1", commitment("Org3MSP", "s3"))
+	checkError(t, err, "the commit phase of round round1 closed at "+commitDeadline)
+
+	// a secret only opens the commitment of the org that made it
+	err = contract.Reveal(newContext(stub, "peer2", "Org2MSP"), "round1", "s1")
+	checkError(t, err, "secret does not match the commitment of Org2MSP")
+	err = contract.Reveal(newContext(stub, "peer3", "Org3MSP"), "round1", "s3")
+	checkError(t, err, "Org3MSP has not committed to round round1")
+
+	checkError(t, contract.Reveal(newContext(stub, "peer1", "Org1MSP"), "round1", "s1"), "")
+	err = contract.Reveal(newContext(stub, "peer1", "Org1MSP"), "round1", "s1")
+	checkError(t, err, "Org1MSP has already revealed for round round1")
+	checkError(t, contract.Reveal(newContext(stub, "peer2", "Org2MSP"), "round1", "s2"), "")
+
+	_, err = contract.FinalizeRound(newContext(stub, "operator", "Org1MSP"), "round1")
+	checkError(t, err, "round round1 is open for reveals until "+revealDeadline)
+
+	stub.txCount = 200
+	round, err := contract.FinalizeRound(newContext(stub, "operator", "Org1MSP"), "round1")
+	checkError(t, err, "")
+	hash := sha256.Sum256([]byte("Org1MSP:s1\nOrg2MSP:s2\n"))
+	if round.Status != statusFinalized || round.Value != hex.EncodeToString(hash[:]) || len(round.Contributors) != 2 {
+		t.Fatalf("unexpected round %+v", round)
+	}
+
+	value, err := contract.GetRandomValue(newContext(stub, "operator", "Org1MSP"), "round1")
+	checkError(t, err, "")
+	if value != round.Value {
+		t.Fatalf("expected random value %s, got %s", round.Value, value)
+	}
+}
+
+func TestMissedReveals(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+
+	// Org2 commits to three rounds in a row but never reveals
+	for _, roundID := range []string{"round1", "round2", "round3"} {
+		stub.txCount = 0
+		checkError(t, contract.OpenRound(newContext(stub, "operator", "Org1MSP"), roundID, "", commitDeadline, revealDeadline, 2), "")
+		checkError(t, contract.Commit(newContext(stub, "peer1", "Org1MSP"), roundID, commitment("Org1MSP", "s1")), "")
+		checkError(t, contract.Commit(newContext(stub, "peer2", "Org2MSP"), roundID, commitment("Org2MSP", "s2")), "")
+		stub.txCount = 100
+		checkError(t, contract.Reveal(newContext(stub, "peer1", "Org1MSP"), roundID, "s1"), "")
+		stub.txCount = 200
+
+		round, err := contract.FinalizeRound(newContext(stub, "operator", "Org1MSP"), roundID)
+		checkError(t, err, "")
+		if round.Status != statusFailed || len(round.Penalized) != 1 || round.Penalized[0] != "Org2MSP" {
+			t.Fatalf("unexpected round %+v", round)
+		}
+	}
+
+	stub.txCount = 0
+	checkError(t, contract.OpenRound(newContext(stub, "operator", "Org1MSP"), "round4", "", commitDeadline, revealDeadline, 2), "")
+	err := contract.Commit(newContext(stub, "peer2", "Org2MSP"), "round4", commitment("Org2MSP", "s2"))
+	checkError(t, err, "Org2MSP is suspended after missing 3 reveals")
+
+	err = contract.ReinstateParticipant(newContext(stub, "peer2", "Org2MSP"))
+	checkError(t, err, "client is not an admin of Org2MSP")
+	err = contract.ReinstateParticipant(newAdminContext(stub, "admin1", "Org1MSP"))
+	checkError(t, err, "Org1MSP is not suspended")
+
+	checkError(t, contract.ReinstateParticipant(newAdminContext(stub, "admin2", "Org2MSP")), "")
+	checkError(t, contract.Commit(newContext(stub, "peer2", "Org2MSP"), "round4", commitment("Org2MSP", "s2")), "")
+}
@@ -0,0 +1,654 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil"
+)
+
+// chaincode names used in the test network READMEs
+const (
+	defaultLandChaincode  = "land"
+	defaultTokenChaincode = "token_erc20"
+)
+
+// object names for prefix
+const (
+	propertyPrefix     = "property"
+	parcelPrefix       = "parcel"
+	holdingPrefix      = "holding"
+	whitelistPrefix    = "whitelist"
+	distributionPrefix = "distribution"
+	leasePrefix        = "lease"
+)
+
+// leaseKeyLayout formats the start of a lease in its key, fixed width in UTC so the leases of a
+// property are listed in the order they start
+const leaseKeyLayout = "2006-01-02T15:04:05.000000000Z"
+
+// status a parcel must have in the land registry to be tokenized
+const parcelRegistered = "REGISTERED"
+
+// maxShareholders is the most investors other than the sponsor a property can have, the most
+// payments one BatchTransfer of the token chaincode makes when DistributeIncome pays them
+const maxShareholders = 100
+
+// SmartContract provides functions for fractional ownership of registered properties
+type SmartContract struct {
+	contractapi.Contract
+}
+
+// Property is a land registry parcel split into shares. The sponsor's token account holds the
+// rental income pool until it is distributed, since a chaincode cannot hold tokens itself.
+type Property struct {
+	ObjectType        string        `json:"objectType"`
+	ID                string        `json:"propertyID"`
+	ParcelID          string        `json:"parcelID"`
+	Sponsor           string        `json:"sponsor"`
+	SponsorOrg        string        `json:"sponsorOrg"`
+	Classes           []*ShareClass `json:"classes"`
+	TotalShares       int           `json:"totalShares"`
+	IncomePool        int           `json:"incomePool"`
+	Distributed       int           `json:"distributed"`
+	DistributionCount int           `json:"distributionCount"`
+	LandChaincode     string        `json:"landChaincode"`
+	TokenChaincode    string        `json:"tokenChaincode"`
+}
+
+// ShareClass is a tranche of shares that can only be held by investors whitelisted for it
+type ShareClass struct {
+	Class  string `json:"class"`
+	Shares int    `json:"shares"`
+}
+
+// Holding is the number of shares of one class an investor holds
+type Holding struct {
+	ObjectType string `json:"objectType"`
+	PropertyID string `json:"propertyID"`
+	Class      string `json:"class"`
+	Investor   string `json:"investor"`
+	Shares     int    `json:"shares"`
+}
+
+// Distribution records a payout of the income pool to the shareholders
+type Distribution struct {
+	ObjectType string     `json:"objectType"`
+	PropertyID string     `json:"propertyID"`
+	Sequence   int        `json:"sequence"`
+	Amount     int        `json:"amount"`
+	Paid       int        `json:"paid"`
+	Payments   []*Payment `json:"payments"`
+	TxID       string     `json:"txID"`
+}
+
+// Payment is one shareholder's part of a distribution
+type Payment struct {
+	Investor string `json:"investor"`
+	Shares   int    `json:"shares"`
+	Amount   int    `json:"amount"`
+}
+
+// Lease books a property for a tenant from Start up to End. End is exclusive, so a lease may start
+// when the previous one ends.
+type Lease struct {
+	ObjectType string    `json:"objectType"`
+	PropertyID string    `json:"propertyID"`
+	LeaseID    string    `json:"leaseID"`
+	Tenant     string    `json:"tenant"`
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+	TxID       string    `json:"txID"`
+}
+
+// parcel is the part of the land registry's Parcel the tokenization relies on
+type parcel struct {
+	ID       string `json:"parcelID"`
+	OwnerOrg string `json:"ownerOrg"`
+	Status   string `json:"status"`
+}
+
+// TokenizeProperty is called by a client of the org that owns the parcel in the land registry.
+// classes is a JSON array of share classes, e.g. [{"class":"A","shares":600},{"class":"B","shares":400}].
+// The submitting client becomes the sponsor and initially holds every share.
+func (s *SmartContract) TokenizeProperty(ctx contractapi.TransactionContextInterface, propertyID string, parcelID string, classes string, landChaincode string, tokenChaincode string) error {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+
+	if propertyID == "" || parcelID == "" {
+		return fmt.Errorf("property ID and parcel ID must be set")
+	}
+	if landChaincode == "" {
+		landChaincode = defaultLandChaincode
+	}
+	if tokenChaincode == "" {
+		tokenChaincode = defaultTokenChaincode
+	}
+
+	var shareClasses []*ShareClass
+	err = json.Unmarshal([]byte(classes), &shareClasses)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal share classes: %v", err)
+	}
+	if len(shareClasses) == 0 {
+		return fmt.Errorf("at least one share class must be given")
+	}
+	totalShares := 0
+	seen := make(map[string]bool)
+	for _, shareClass := range shareClasses {
+		if shareClass.Class == "" || seen[shareClass.Class] {
+			return fmt.Errorf("share class names must be set and unique")
+		}
+		if shareClass.Shares <= 0 {
+			return fmt.Errorf("share class %s must have a positive number of shares", shareClass.Class)
+		}
+		seen[shareClass.Class] = true
+		totalShares += shareClass.Shares
+	}
+
+	existing, err := _getProperty(ctx, propertyID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("the property %s already exists", propertyID)
+	}
+
+	registered, err := _readParcel(ctx, landChaincode, parcelID)
+	if err != nil {
+		return err
+	}
+	if registered.OwnerOrg != clientOrgID {
+		return fmt.Errorf("parcel %s is owned by %s, not by %s", parcelID, registered.OwnerOrg, clientOrgID)
+	}
+	if registered.Status != parcelRegistered {
+		return fmt.Errorf("parcel %s is %s and cannot be tokenized", parcelID, registered.Status)
+	}
+
+	parcelKey, err := ctx.GetStub().CreateCompositeKey(parcelPrefix, []string{landChaincode, parcelID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	tokenized, err := ctx.GetStub().GetState(parcelKey)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if tokenized != nil {
+		return fmt.Errorf("parcel %s is already tokenized as property %s", parcelID, string(tokenized))
+	}
+	err = ctx.GetStub().PutState(parcelKey, []byte(propertyID))
+	if err != nil {
+		return fmt.Errorf("failed to put parcel index: %v", err)
+	}
+
+	for _, shareClass := range shareClasses {
+		err = _putHolding(ctx, &Holding{
+			ObjectType: holdingPrefix,
+			PropertyID: propertyID,
+			Class:      shareClass.Class,
+			Investor:   clientID,
+			Shares:     shareClass.Shares,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	property := Property{
+		ObjectType:     propertyPrefix,
+		ID:             propertyID,
+		ParcelID:       parcelID,
+		Sponsor:        clientID,
+		SponsorOrg:     clientOrgID,
+		Classes:        shareClasses,
+		TotalShares:    totalShares,
+		LandChaincode:  landChaincode,
+		TokenChaincode: tokenChaincode,
+	}
+	return _putProperty(ctx, &property)
+}
+
+// WhitelistInvestor is called by the sponsor to allow or stop an investor holding shares of a class.
+// Removing an investor from the whitelist does not take away shares they already hold, but they
+// cannot receive more.
+func (s *SmartContract) WhitelistInvestor(ctx contractapi.TransactionContextInterface, propertyID string, class string, investor string, allowed bool) error {
+	property, err := s.requireSponsor(ctx, propertyID)
+	if err != nil {
+		return err
+	}
+	if _shareClass(property, class) == nil {
+		return fmt.Errorf("property %s has no share class %s", propertyID, class)
+	}
+	if investor == "" {
+		return fmt.Errorf("investor must be set")
+	}
+
+	whitelistKey, err := ctx.GetStub().CreateCompositeKey(whitelistPrefix, []string{propertyID, class, investor})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	if !allowed {
+		return ctx.GetStub().DelState(whitelistKey)
+	}
+	return ctx.GetStub().PutState(whitelistKey, []byte{0x00})
+}
+
+// TransferShares moves shares of a class from the submitting client to the recipient. The
+// recipient must be whitelisted for the class, unless it is the sponsor, and cannot become a
+// shareholder once the property has maxShareholders investors besides the sponsor.
+func (s *SmartContract) TransferShares(ctx contractapi.TransactionContextInterface, propertyID string, class string, recipient string, shares int) error {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	property, err := s.GetProperty(ctx, propertyID)
+	if err != nil {
+		return err
+	}
+	if _shareClass(property, class) == nil {
+		return fmt.Errorf("property %s has no share class %s", propertyID, class)
+	}
+	if shares <= 0 {
+		return fmt.Errorf("number of shares must be a positive integer")
+	}
+	if recipient == "" || recipient == clientID {
+		return fmt.Errorf("recipient must be set and differ from the sender")
+	}
+
+	if recipient != property.Sponsor {
+		whitelisted, err := s.IsWhitelisted(ctx, propertyID, class, recipient)
+		if err != nil {
+			return err
+		}
+		if !whitelisted {
+			return fmt.Errorf("recipient is not whitelisted for class %s of property %s", class, propertyID)
+		}
+	}
+
+	from, err := _getHolding(ctx, propertyID, class, clientID)
+	if err != nil {
+		return err
+	}
+	if from.Shares < shares {
+		return fmt.Errorf("client holds %d shares of class %s, cannot transfer %d", from.Shares, class, shares)
+	}
+	to, err := _getHolding(ctx, propertyID, class, recipient)
+	if err != nil {
+		return err
+	}
+	if to.Shares == 0 && recipient != property.Sponsor {
+		holdings, err := s.GetHoldings(ctx, propertyID)
+		if err != nil {
+			return err
+		}
+		investors := _shareholders(property, holdings)
+		if !investors[recipient] && len(investors) >= maxShareholders {
+			return fmt.Errorf("property %s already has %d shareholders besides the sponsor", propertyID, maxShareholders)
+		}
+	}
+
+	from.Shares -= shares
+	to.Shares += shares
+	err = _putHolding(ctx, from)
+	if err != nil {
+		return err
+	}
+	return _putHolding(ctx, to)
+}
+
+// DepositIncome adds rental income to the property's pool. amount is transferred from the
+// submitting client's token account to the sponsor, who holds the pool until it is distributed.
+func (s *SmartContract) DepositIncome(ctx contractapi.TransactionContextInterface, propertyID string, amount int) error {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	property, err := s.GetProperty(ctx, propertyID)
+	if err != nil {
+		return err
+	}
+	if amount <= 0 {
+		return fmt.Errorf("amount must be a positive integer")
+	}
+
+	// the sponsor depositing income already holds the tokens
+	if clientID != property.Sponsor {
+		err = ledgerutil.TransferTokens(ctx, property.TokenChaincode, ledgerutil.TokenPayment{Receiver: property.Sponsor, Amount: amount})
+		if err != nil {
+			return err
+		}
+	}
+
+	property.IncomePool += amount
+	return _putProperty(ctx, property)
+}
+
+// DistributeIncome is called by the sponsor to pay the income pool to every shareholder pro-rata
+// to their shares across all classes. Payments are transferred from the sponsor's token account with
+// one BatchTransfer, debiting it once. Amounts are rounded down and the remainder stays in the pool
+// for the next distribution.
+func (s *SmartContract) DistributeIncome(ctx contractapi.TransactionContextInterface, propertyID string) error {
+	property, err := s.requireSponsor(ctx, propertyID)
+	if err != nil {
+		return err
+	}
+	if property.IncomePool == 0 {
+		return fmt.Errorf("property %s has no income to distribute", propertyID)
+	}
+
+	holdings, err := s.GetHoldings(ctx, propertyID)
+	if err != nil {
+		return err
+	}
+
+	// an investor holding several classes is paid once for all of them
+	var payments []*Payment
+	byInvestor := make(map[string]*Payment)
+	for _, holding := range holdings {
+		if holding.Shares == 0 {
+			continue
+		}
+		payment, ok := byInvestor[holding.Investor]
+		if !ok {
+			payment = &Payment{Investor: holding.Investor}
+			byInvestor[holding.Investor] = payment
+			payments = append(payments, payment)
+		}
+		payment.Shares += holding.Shares
+	}
+
+	distribution := Distribution{
+		ObjectType: distributionPrefix,
+		PropertyID: propertyID,
+		Sequence:   property.DistributionCount + 1,
+		Amount:     property.IncomePool,
+		TxID:       ctx.GetStub().GetTxID(),
+	}
+	var transfers []ledgerutil.TokenPayment
+	for _, payment := range payments {
+		payment.Amount = _mulDiv(property.IncomePool, payment.Shares, property.TotalShares)
+		if payment.Amount == 0 {
+			continue
+		}
+		// the sponsor's own part stays in its account
+		if payment.Investor != property.Sponsor {
+			transfers = append(transfers, ledgerutil.TokenPayment{Receiver: payment.Investor, Amount: payment.Amount})
+		}
+		distribution.Paid += payment.Amount
+	}
+	if len(transfers) > maxShareholders {
+		return fmt.Errorf("property %s has %d shareholders to pay, more than the %d one distribution pays", propertyID, len(transfers), maxShareholders)
+	}
+	err = ledgerutil.TransferTokens(ctx, property.TokenChaincode, transfers...)
+	if err != nil {
+		return err
+	}
+	distribution.Payments = payments
+
+	distributionJSON, err := json.Marshal(distribution)
+	if err != nil {
+		return fmt.Errorf("failed to marshal distribution: %v", err)
+	}
+	distributionKey, err := ctx.GetStub().CreateCompositeKey(distributionPrefix, []string{propertyID, fmt.Sprintf("%010d", distribution.Sequence)})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = ctx.GetStub().PutState(distributionKey, distributionJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put distribution: %v", err)
+	}
+
+	property.IncomePool -= distribution.Paid
+	property.Distributed += distribution.Paid
+	property.DistributionCount = distribution.Sequence
+	err = _putProperty(ctx, property)
+	if err != nil {
+		return err
+	}
+
+	err = ctx.GetStub().SetEvent("IncomeDistributed", distributionJSON)
+	if err != nil {
+		return fmt.Errorf("failed to set event: %v", err)
+	}
+	return nil
+}
+
+// CreateLease is called by the sponsor to book the property for a tenant from start up to end, both
+// RFC 3339 times. It fails when the window overlaps a lease already booked, naming the earliest
+// such lease, so every peer rejects a conflicting booking with the same error.
+func (s *SmartContract) CreateLease(ctx contractapi.TransactionContextInterface, propertyID string, leaseID string, tenant string, start string, end string) error {
+	_, err := s.requireSponsor(ctx, propertyID)
+	if err != nil {
+		return err
+	}
+	if leaseID == "" || tenant == "" {
+		return fmt.Errorf("lease ID and tenant must be set")
+	}
+	startTime, endTime, err := _parseWindow(start, end)
+	if err != nil {
+		return err
+	}
+
+	leases, err := s.GetLeases(ctx, propertyID)
+	if err != nil {
+		return err
+	}
+	for _, booked := range leases {
+		if booked.LeaseID == leaseID {
+			return fmt.Errorf("the lease %s of property %s already exists", leaseID, propertyID)
+		}
+	}
+	for _, booked := range leases {
+		if _overlaps(booked, startTime, endTime) {
+			return fmt.Errorf("property %s is leased from %s to %s under lease %s", propertyID,
+				booked.Start.Format(time.RFC3339), booked.End.Format(time.RFC3339), booked.LeaseID)
+		}
+	}
+
+	lease := Lease{
+		ObjectType: leasePrefix,
+		PropertyID: propertyID,
+		LeaseID:    leaseID,
+		Tenant:     tenant,
+		Start:      startTime,
+		End:        endTime,
+		TxID:       ctx.GetStub().GetTxID(),
+	}
+	leaseJSON, err := json.Marshal(lease)
+	if err != nil {
+		return fmt.Errorf("failed to marshal lease: %v", err)
+	}
+	leaseKey, err := ctx.GetStub().CreateCompositeKey(leasePrefix, []string{propertyID, startTime.Format(leaseKeyLayout), leaseID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = ctx.GetStub().PutState(leaseKey, leaseJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put lease: %v", err)
+	}
+
+	err = ctx.GetStub().SetEvent("LeaseCreated", leaseJSON)
+	if err != nil {
+		return fmt.Errorf("failed to set event: %v", err)
+	}
+	return nil
+}
+
+// requireSponsor reads a property and checks the submitting client is its sponsor
+func (s *SmartContract) requireSponsor(ctx contractapi.TransactionContextInterface, propertyID string) (*Property, error) {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	property, err := s.GetProperty(ctx, propertyID)
+	if err != nil {
+		return nil, err
+	}
+	if clientID != property.Sponsor {
+		return nil, fmt.Errorf("only the sponsor of property %s can do this", propertyID)
+	}
+	return property, nil
+}
+
+// _shareClass returns the named share class of a property, or nil
+func _shareClass(property *Property, class string) *ShareClass {
+	for _, shareClass := range property.Classes {
+		if shareClass.Class == class {
+			return shareClass
+		}
+	}
+	return nil
+}
+
+// _shareholders returns the investors other than the sponsor holding shares of any class
+func _shareholders(property *Property, holdings []*Holding) map[string]bool {
+	investors := make(map[string]bool)
+	for _, holding := range holdings {
+		if holding.Shares > 0 && holding.Investor != property.Sponsor {
+			investors[holding.Investor] = true
+		}
+	}
+	return investors
+}
+
+// _parseWindow parses the start and end of a time window, which must end after it starts
+func _parseWindow(start string, end string) (time.Time, time.Time, error) {
+	startTime, err := time.Parse(time.RFC3339, start)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("start must be an RFC 3339 time such as 2024-06-01T00:00:00Z, not %q", start)
+	}
+	endTime, err := time.Parse(time.RFC3339, end)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("end must be an RFC 3339 time such as 2024-07-01T00:00:00Z, not %q", end)
+	}
+	if !endTime.After(startTime) {
+		return time.Time{}, time.Time{}, fmt.Errorf("end must be after start")
+	}
+	return startTime.UTC(), endTime.UTC(), nil
+}
+
+// _overlaps returns true when the lease and the window from start up to end share any time
+func _overlaps(lease *Lease, start time.Time, end time.Time) bool {
+	return lease.Start.Before(end) && start.Before(lease.End)
+}
+
+// _mulDiv returns a*b/c rounded down without overflowing on large amounts
+func _mulDiv(a int, b int, c int) int {
+	result := new(big.Int).Mul(big.NewInt(int64(a)), big.NewInt(int64(b)))
+	return int(result.Div(result, big.NewInt(int64(c))).Int64())
+}
+
+// _readParcel reads the public title record from the land registry chaincode
+func _readParcel(ctx contractapi.TransactionContextInterface, landChaincode string, parcelID string) (*parcel, error) {
+	args := [][]byte{[]byte("ReadParcel"), []byte(parcelID)}
+	response := ctx.GetStub().InvokeChaincode(landChaincode, args, "")
+	if response.Status != shim.OK {
+		return nil, fmt.Errorf("failed to read parcel %s from %s: %s", parcelID, landChaincode, response.Message)
+	}
+
+	var registered parcel
+	err := json.Unmarshal(response.Payload, &registered)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal parcel %s: %v", parcelID, err)
+	}
+	return &registered, nil
+}
+
+// _getProperty reads a property, returning nil when it does not exist
+func _getProperty(ctx contractapi.TransactionContextInterface, propertyID string) (*Property, error) {
+	propertyKey, err := ctx.GetStub().CreateCompositeKey(propertyPrefix, []string{propertyID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	propertyJSON, err := ctx.GetStub().GetState(propertyKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if propertyJSON == nil {
+		return nil, nil
+	}
+
+	var property Property
+	err = json.Unmarshal(propertyJSON, &property)
+	if err != nil {
+		return nil, err
+	}
+	return &property, nil
+}
+
+// _putProperty writes the property to the world state
+func _putProperty(ctx contractapi.TransactionContextInterface, property *Property) error {
+	propertyKey, err := ctx.GetStub().CreateCompositeKey(propertyPrefix, []string{property.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	propertyJSON, err := json.Marshal(property)
+	if err != nil {
+		return fmt.Errorf("failed to marshal property: %v", err)
+	}
+	err = ctx.GetStub().PutState(propertyKey, propertyJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put property %s: %v", property.ID, err)
+	}
+	return nil
+}
+
+// _getHolding reads an investor's holding of a share class, starting an empty one when there is none
+func _getHolding(ctx contractapi.TransactionContextInterface, propertyID string, class string, investor string) (*Holding, error) {
+	holdingKey, err := ctx.GetStub().CreateCompositeKey(holdingPrefix, []string{propertyID, class, investor})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	holdingJSON, err := ctx.GetStub().GetState(holdingKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if holdingJSON == nil {
+		return &Holding{ObjectType: holdingPrefix, PropertyID: propertyID, Class: class, Investor: investor}, nil
+	}
+
+	var holding Holding
+	err = json.Unmarshal(holdingJSON, &holding)
+	if err != nil {
+		return nil, err
+	}
+	return &holding, nil
+}
+
+// _putHolding writes a holding, removing it once the investor holds no shares
+func _putHolding(ctx contractapi.TransactionContextInterface, holding *Holding) error {
+	holdingKey, err := ctx.GetStub().CreateCompositeKey(holdingPrefix, []string{holding.PropertyID, holding.Class, holding.Investor})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	if holding.Shares == 0 {
+		return ctx.GetStub().DelState(holdingKey)
+	}
+
+	holdingJSON, err := json.Marshal(holding)
+	if err != nil {
+		return fmt.Errorf("failed to marshal holding: %v", err)
+	}
+	err = ctx.GetStub().PutState(holdingKey, holdingJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put holding: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// GetProperty returns the property stored in the world state with the given ID
+func (s *SmartContract) GetProperty(ctx contractapi.TransactionContextInterface, propertyID string) (*Property, error) {
+	property, err := _getProperty(ctx, propertyID)
+	if err != nil {
+		return nil, err
+	}
+	if property == nil {
+		return nil, fmt.Errorf("the property %s does not exist", propertyID)
+	}
+	return property, nil
+}
+
+// GetHolding returns the shares of a class an investor holds
+func (s *SmartContract) GetHolding(ctx contractapi.TransactionContextInterface, propertyID string, class string, investor string) (*Holding, error) {
+	return _getHolding(ctx, propertyID, class, investor)
+}
+
+// GetHoldings returns every holding of a property, grouped by share class
+func (s *SmartContract) GetHoldings(ctx contractapi.TransactionContextInterface, propertyID string) ([]*Holding, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(holdingPrefix, []string{propertyID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get holdings of property %s: %v", propertyID, err)
+	}
+	defer resultsIterator.Close()
+
+	var holdings []*Holding
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var holding Holding
+		err = json.Unmarshal(response.Value, &holding)
+		if err != nil {
+			return nil, err
+		}
+		holdings = append(holdings, &holding)
+	}
+
+	return holdings, nil
+}
+
+// IsWhitelisted returns true when the investor may hold shares of the class
+func (s *SmartContract) IsWhitelisted(ctx contractapi.TransactionContextInterface, propertyID string, class string, investor string) (bool, error) {
+	whitelistKey, err := ctx.GetStub().CreateCompositeKey(whitelistPrefix, []string{propertyID, class, investor})
+	if err != nil {
+		return false, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	whitelisted, err := ctx.GetStub().GetState(whitelistKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	return whitelisted != nil, nil
+}
+
+// GetDistributions returns the income distributions of a property, oldest first
+func (s *SmartContract) GetDistributions(ctx contractapi.TransactionContextInterface, propertyID string) ([]*Distribution, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(distributionPrefix, []string{propertyID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get distributions of property %s: %v", propertyID, err)
+	}
+	defer resultsIterator.Close()
+
+	var distributions []*Distribution
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var distribution Distribution
+		err = json.Unmarshal(response.Value, &distribution)
+		if err != nil {
+			return nil, err
+		}
+		distributions = append(distributions, &distribution)
+	}
+
+	return distributions, nil
+}
+
+// Availability is returned by GetAvailability: the leases booked in the range and the windows of it
+// that are free
+type Availability struct {
+	PropertyID string    `json:"propertyID"`
+	From       time.Time `json:"from"`
+	To         time.Time `json:"to"`
+	Available  bool      `json:"available"`
+	Leases     []*Lease  `json:"leases"`
+	Free       []*Window `json:"free"`
+}
+
+// Window is a period of time from Start up to End
+type Window struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// GetLeases returns the leases of a property in the order they start
+func (s *SmartContract) GetLeases(ctx contractapi.TransactionContextInterface, propertyID string) ([]*Lease, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(leasePrefix, []string{propertyID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get leases of property %s: %v", propertyID, err)
+	}
+	defer resultsIterator.Close()
+
+	var leases []*Lease
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var lease Lease
+		err = json.Unmarshal(response.Value, &lease)
+		if err != nil {
+			return nil, err
+		}
+		leases = append(leases, &lease)
+	}
+
+	return leases, nil
+}
+
+// GetAvailability returns whether a property is free from one RFC 3339 time up to another, with the
+// leases booked in that range and the windows left free between them
+func (s *SmartContract) GetAvailability(ctx contractapi.TransactionContextInterface, propertyID string, from string, to string) (*Availability, error) {
+	fromTime, toTime, err := _parseWindow(from, to)
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.GetProperty(ctx, propertyID)
+	if err != nil {
+		return nil, err
+	}
+	leases, err := s.GetLeases(ctx, propertyID)
+	if err != nil {
+		return nil, err
+	}
+
+	availability := Availability{PropertyID: propertyID, From: fromTime, To: toTime, Leases: []*Lease{}, Free: []*Window{}}
+	cursor := fromTime
+	for _, lease := range leases {
+		if !_overlaps(lease, fromTime, toTime) {
+			continue
+		}
+		availability.Leases = append(availability.Leases, lease)
+		if lease.Start.After(cursor) {
+			availability.Free = append(availability.Free, &Window{Start: cursor, End: lease.Start})
+		}
+		if lease.End.After(cursor) {
+			cursor = lease.End
+		}
+	}
+	if cursor.Before(toTime) {
+		availability.Free = append(availability.Free, &Window{Start: cursor, End: toTime})
+	}
+	availability.Available = len(availability.Leases) == 0
+	return &availability, nil
+}
@@ -0,0 +1,184 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+)
+
+const (
+	sponsorMSPID  = "Org1MSP"
+	investorMSPID = "Org2MSP"
+)
+
+// fakeToken stands in for the token chaincode. Batches are paid from the payer's account,
+// debited once with the total as by the token chaincode.
+type fakeToken struct {
+	payer    string
+	balances map[string]int
+	batches  int
+}
+
+func (f *fakeToken) invoke(args [][]byte) pb.Response {
+	if string(args[0]) != "BatchTransfer" {
+		return shim.Error("unexpected function " + string(args[0]))
+	}
+	var payments []struct {
+		Receiver string `json:"receiver"`
+		Amount   int    `json:"amount"`
+	}
+	err := json.Unmarshal(args[1], &payments)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	total := 0
+	for _, payment := range payments {
+		total += payment.Amount
+	}
+	if f.balances[f.payer] < total {
+		return shim.Error(fmt.Sprintf("failed to transfer: client account %s has insufficient funds", f.payer))
+	}
+	f.balances[f.payer] -= total
+	for _, payment := range payments {
+		f.balances[payment.Receiver] += payment.Amount
+	}
+	f.batches++
+	return shim.Success(nil)
+}
+
+// fakeLand stands in for the land registry chaincode, answering ReadParcel
+func fakeLand(parcels ...parcel) func(args [][]byte) pb.Response {
+	return func(args [][]byte) pb.Response {
+		for _, registered := range parcels {
+			if string(args[0]) == "ReadParcel" && registered.ID == string(args[1]) {
+				parcelJSON, _ := json.Marshal(registered)
+				return shim.Success(parcelJSON)
+			}
+		}
+		return shim.Error(fmt.Sprintf("parcel %s does not exist", args[1]))
+	}
+}
+
+func checkError(t *testing.T, err error, expected string) {
+	t.Helper()
+	if expected == "" && err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected != "" && (err == nil || !strings.Contains(err.Error(), expected)) {
+		t.Fatalf("expected error containing %q, got %v", expected, err)
+	}
+}
+
+// tokenizeProperty has the sponsor split parcel1 into 600 A and 400 B shares, whitelist alice for
+// class A and bob for class B, and sell alice 300 A and bob 100 B
+func tokenizeProperty(t *testing.T, stub *fakeStub) *fakeToken {
+	t.Helper()
+	token := &fakeToken{balances: map[string]int{"tenant": 1001}}
+	stub.chaincodes[defaultTokenChaincode] = token.invoke
+	stub.chaincodes[defaultLandChaincode] = fakeLand(parcel{"parcel1", sponsorMSPID, parcelRegistered}, parcel{"parcel2", sponsorMSPID, "PENDING"}, parcel{"parcel3", sponsorMSPID, parcelRegistered})
+
+	contract := new(SmartContract)
+	classes := `[{"class":"A","shares":600},{"class":"B","shares":400}]`
+	checkError(t, contract.TokenizeProperty(newContext(stub, "sponsor", sponsorMSPID), "prop1", "parcel1", classes, "", ""), "")
+	checkError(t, contract.WhitelistInvestor(newContext(stub, "sponsor", sponsorMSPID), "prop1", "A", "alice", true), "")
+	checkError(t, contract.WhitelistInvestor(newContext(stub, "sponsor", sponsorMSPID), "prop1", "B", "bob", true), "")
+	checkError(t, contract.TransferShares(newContext(stub, "sponsor", sponsorMSPID), "prop1", "A", "alice", 300), "")
+	checkError(t, contract.TransferShares(newContext(stub, "sponsor", sponsorMSPID), "prop1", "B", "bob", 100), "")
+	return token
+}
+
+func TestTokenizeProperty(t *testing.T) {
+	tests := []struct {
+		name       string
+		mspID      string
+		propertyID string
+		parcelID   string
+		expected   string
+	}{
+		{"owner", sponsorMSPID, "prop2", "parcel3", ""},
+		{"pending parcel", sponsorMSPID, "prop2", "parcel2", "parcel parcel2 is PENDING and cannot be tokenized"},
+		{"not owner", investorMSPID, "prop2", "parcel1", "parcel parcel1 is owned by Org1MSP, not by Org2MSP"},
+		{"tokenized parcel", sponsorMSPID, "prop2", "parcel1", "parcel parcel1 is already tokenized as property prop1"},
+		{"existing property", sponsorMSPID, "prop1", "parcel1", "the property prop1 already exists"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stub := newFakeStub()
+			tokenizeProperty(t, stub)
+
+			err := new(SmartContract).TokenizeProperty(newContext(stub, "sponsor", test.mspID), test.propertyID, test.parcelID, `[{"class":"A","shares":10}]`, "", "")
+			checkError(t, err, test.expected)
+		})
+	}
+}
+
+func TestTransferShares(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	tokenizeProperty(t, stub)
+
+	err := contract.WhitelistInvestor(newContext(stub, "alice", investorMSPID), "prop1", "B", "alice", true)
+	checkError(t, err, "only the sponsor of property prop1 can do this")
+
+	err = contract.TransferShares(newContext(stub, "alice", investorMSPID), "prop1", "A", "bob", 10)
+	checkError(t, err, "recipient is not whitelisted for class A of property prop1")
+	err = contract.TransferShares(newContext(stub, "bob", investorMSPID), "prop1", "B", "sponsor", 101)
+	checkError(t, err, "client holds 100 shares of class B, cannot transfer 101")
+
+	// shares can always go back to the sponsor
+	checkError(t, contract.TransferShares(newContext(stub, "bob", investorMSPID), "prop1", "B", "sponsor", 100), "")
+	holding, err := contract.GetHolding(newContext(stub, "sponsor", sponsorMSPID), "prop1", "B", "sponsor")
+	checkError(t, err, "")
+	if holding.Shares != 400 {
+		t.Fatalf("expected the sponsor to hold 400 B shares, got %d", holding.Shares)
+	}
+}
+
+func TestDistributeIncome(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	token := tokenizeProperty(t, stub)
+
+	token.payer = "tenant"
+	err := contract.DepositIncome(newContext(stub, "tenant", investorMSPID), "prop1", 1002)
+	checkError(t, err, "failed to transfer tokens on token_erc20: failed to transfer: client account tenant has insufficient funds")
+	checkError(t, contract.DepositIncome(newContext(stub, "tenant", investorMSPID), "prop1", 1001), "")
+
+	err = contract.DistributeIncome(newContext(stub, "alice", investorMSPID), "prop1")
+	checkError(t, err, "only the sponsor of property prop1 can do this")
+
+	// the sponsor's part stays in its account and the others are paid in one batch
+	token.payer = "sponsor"
+	checkError(t, contract.DistributeIncome(newContext(stub, "sponsor", sponsorMSPID), "prop1"), "")
+	if token.balances["alice"] != 300 || token.balances["bob"] != 100 || token.balances["sponsor"] != 601 || token.batches != 2 {
+		t.Fatalf("unexpected balances %v after %d batches", token.balances, token.batches)
+	}
+
+	property, err := contract.GetProperty(newContext(stub, "sponsor", sponsorMSPID), "prop1")
+	checkError(t, err, "")
+	if property.IncomePool != 1 || property.Distributed != 1000 {
+		t.Fatalf("unexpected property %+v", property)
+	}
+}
+
+func TestMaxShareholders(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	tokenizeProperty(t, stub)
+
+	// alice and bob already hold shares
+	for i := 3; i <= maxShareholders; i++ {
+		investor := fmt.Sprintf("investor%03d", i)
+		checkError(t, contract.WhitelistInvestor(newContext(stub, "sponsor", sponsorMSPID), "prop1", "B", investor, true), "")
+		checkError(t, contract.TransferShares(newContext(stub, "sponsor", sponsorMSPID), "prop1", "B", investor, 1), "")
+	}
+	checkError(t, contract.TransferShares(newContext(stub, "sponsor", sponsorMSPID), "prop1", "A", "alice", 1), "")
+
+	checkError(t, contract.WhitelistInvestor(newContext(stub, "sponsor", sponsorMSPID), "prop1", "B", "carol", true), "")
+	err := contract.TransferShares(newContext(stub, "sponsor", sponsorMSPID), "prop1", "B", "carol", 1)
+	checkError(t, err, fmt.Sprintf("property prop1 already has %d shareholders besides the sponsor", maxShareholders))
+}
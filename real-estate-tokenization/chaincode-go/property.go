@@ -0,0 +1,23 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"log"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/real-estate-tokenization/chaincode-go/chaincode"
+)
+
+func main() {
+	propertyChaincode, err := contractapi.NewChaincode(&chaincode.SmartContract{})
+	if err != nil {
+		log.Panicf("Error creating real-estate-tokenization chaincode: %v", err)
+	}
+
+	if err := propertyChaincode.Start(); err != nil {
+		log.Panicf("Error starting real-estate-tokenization chaincode: %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-secured-agreement/application-go/asset"
+	"github.com/hyperledger/fabric-samples/asset-transfer-secured-agreement/assettypes"
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil/errcode"
+)
+
+// createAssetRequest is the body of POST /api/assets. The properties are passed to the chaincode
+// as transient data and kept in the private data of the user's org.
+type createAssetRequest struct {
+	ID          string                      `json:"id"`
+	Description string                      `json:"description"`
+	Properties  *assettypes.AssetProperties `json:"properties"`
+}
+
+// agreementRequest is the body of the requests agreeing to or completing a sale
+type agreementRequest struct {
+	Price   int    `json:"price"`
+	TradeID string `json:"tradeID"`
+	// Buyer is the MSP ID of the buyer's org, set when transferring
+	Buyer string `json:"buyer,omitempty"`
+}
+
+// assetRoutes registers the asset API. Queries are GET requests evaluated on a peer of the user's
+// org, which holds the org's private data; transactions are POST and PUT requests submitted and
+// committed before the response.
+func (s *server) assetRoutes() {
+	s.router.handle(http.MethodGet, "/api/assets", func(w http.ResponseWriter, r *http.Request, user *userClient, params map[string]string) {
+		pageSize := 10
+		if value := r.URL.Query().Get("pageSize"); value != "" {
+			size, err := strconv.Atoi(value)
+			if err != nil || size < 1 || size > asset.MaxPageSize {
+				writeError(w, http.StatusBadRequest, &errcode.Error{Code: errcode.CodeInvalidArgument, Message: fmt.Sprintf("pageSize must be between 1 and %d", asset.MaxPageSize)})
+				return
+			}
+			pageSize = size
+		}
+		page, err := user.asset.GetAssetsPage(pageSize, r.URL.Query().Get("bookmark"))
+		writeResult(w, http.StatusOK, page, err)
+	})
+	s.router.handle(http.MethodPost, "/api/assets", func(w http.ResponseWriter, r *http.Request, user *userClient, params map[string]string) {
+		var request createAssetRequest
+		if !decodeBody(w, r, &request) {
+			return
+		}
+		if request.Properties == nil {
+			writeError(w, http.StatusBadRequest, &errcode.Error{Code: errcode.CodeInvalidArgument, Message: "properties are required"})
+			return
+		}
+		request.Properties.ObjectType = "asset_properties"
+		request.Properties.ID = request.ID
+		result, err := user.asset.CreateAsset(request.ID, request.Description, request.Properties)
+		writeResult(w, http.StatusCreated, result, err)
+	})
+	s.router.handle(http.MethodGet, "/api/assets/{id}", func(w http.ResponseWriter, r *http.Request, user *userClient, params map[string]string) {
+		result, err := user.asset.ReadAsset(params["id"])
+		writeResult(w, http.StatusOK, result, err)
+	})
+	s.router.handle(http.MethodPut, "/api/assets/{id}", func(w http.ResponseWriter, r *http.Request, user *userClient, params map[string]string) {
+		var request struct {
+			Description string `json:"description"`
+		}
+		if decodeBody(w, r, &request) {
+			result, err := user.asset.UpdateAsset(params["id"], request.Description)
+			writeResult(w, http.StatusOK, result, err)
+		}
+	})
+	s.router.handle(http.MethodGet, "/api/assets/{id}/properties", func(w http.ResponseWriter, r *http.Request, user *userClient, params map[string]string) {
+		result, err := user.asset.GetAssetPrivateProperties(params["id"])
+		writeResult(w, http.StatusOK, result, err)
+	})
+	s.router.handle(http.MethodGet, "/api/assets/{id}/history", func(w http.ResponseWriter, r *http.Request, user *userClient, params map[string]string) {
+		result, err := user.asset.QueryAssetHistory(params["id"])
+		writeResult(w, http.StatusOK, result, err)
+	})
+	s.router.handle(http.MethodGet, "/api/assets/{id}/receipts", func(w http.ResponseWriter, r *http.Request, user *userClient, params map[string]string) {
+		result, err := user.asset.GetAssetReceipts(params["id"])
+		writeResult(w, http.StatusOK, result, err)
+	})
+	s.router.handle(http.MethodPost, "/api/assets/{id}/sell", func(w http.ResponseWriter, r *http.Request, user *userClient, params map[string]string) {
+		var request agreementRequest
+		if decodeBody(w, r, &request) {
+			result, err := user.asset.AgreeToSell(request.agreement(params["id"]))
+			writeResult(w, http.StatusOK, result, err)
+		}
+	})
+	s.router.handle(http.MethodPost, "/api/assets/{id}/buy", func(w http.ResponseWriter, r *http.Request, user *userClient, params map[string]string) {
+		var request agreementRequest
+		if decodeBody(w, r, &request) {
+			result, err := user.asset.AgreeToBuy(request.agreement(params["id"]))
+			writeResult(w, http.StatusOK, result, err)
+		}
+	})
+	s.router.handle(http.MethodPost, "/api/assets/{id}/inspect", func(w http.ResponseWriter, r *http.Request, user *userClient, params map[string]string) {
+		var properties assettypes.AssetProperties
+		if decodeBody(w, r, &properties) {
+			matches, err := user.asset.SetInspection(params["id"], &properties)
+			writeResult(w, http.StatusOK, map[string]bool{"matches": matches}, err)
+		}
+	})
+	s.router.handle(http.MethodPost, "/api/assets/{id}/transfer", func(w http.ResponseWriter, r *http.Request, user *userClient, params map[string]string) {
+		var request agreementRequest
+		if !decodeBody(w, r, &request) {
+			return
+		}
+		// the seller's properties are read from its own private data rather than the request
+		properties, err := user.asset.GetAssetPrivateProperties(params["id"])
+		if err != nil {
+			writeResult(w, http.StatusOK, nil, err)
+			return
+		}
+		result, err := user.asset.TransferAsset(params["id"], request.Buyer, properties, request.agreement(params["id"]))
+		writeResult(w, http.StatusOK, result, err)
+	})
+}
+
+func (r *agreementRequest) agreement(assetID string) *assettypes.Agreement {
+	return &assettypes.Agreement{ID: assetID, Price: r.Price, TradeID: r.TradeID}
+}
@@ -0,0 +1,96 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Config is the configuration file of the service
+type Config struct {
+	// Listen is the address the service listens on, e.g. ":8080"
+	Listen string `json:"listen"`
+	// Wallet is the wallet directory holding the Fabric identities of the users
+	Wallet         string `json:"wallet"`
+	Channel        string `json:"channel"`
+	TokenChaincode string `json:"tokenChaincode"`
+	AssetChaincode string `json:"assetChaincode"`
+	Users          []User `json:"users"`
+}
+
+// User is an API user and the Fabric identity its requests are made as
+type User struct {
+	Name string `json:"name"`
+	// APIKeySHA256 is the hex SHA-256 hash of the user's API key, so the configuration file does not
+	// hold the keys themselves
+	APIKeySHA256 string `json:"apiKeySHA256"`
+	// Identity is the label of the user's identity in the wallet
+	Identity string `json:"identity"`
+	// Profile is the connection profile of the identity's org
+	Profile string `json:"profile"`
+	// Peer is the peer of the profile to connect to, the first peer of the org when empty
+	Peer string `json:"peer,omitempty"`
+}
+
+// LoadConfig reads the configuration file at path. Relative wallet and profile paths are resolved
+// against the directory of the file.
+func LoadConfig(path string) (*Config, error) {
+	configJSON, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %v", err)
+	}
+	config := &Config{
+		Listen:         ":8080",
+		Wallet:         "wallet",
+		Channel:        "mychannel",
+		TokenChaincode: "token_erc20",
+		AssetChaincode: "secured",
+	}
+	err = json.Unmarshal(configJSON, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config: %v", err)
+	}
+
+	dir := filepath.Dir(path)
+	config.Wallet = resolve(dir, config.Wallet)
+	keys := make(map[string]bool)
+	for i := range config.Users {
+		user := &config.Users[i]
+		if user.Name == "" || user.Identity == "" || user.Profile == "" {
+			return nil, fmt.Errorf("user %d must have a name, identity and profile", i)
+		}
+		key, err := hex.DecodeString(user.APIKeySHA256)
+		if err != nil || len(key) != sha256.Size {
+			return nil, fmt.Errorf("API key hash of user %s is not a hex SHA-256 hash", user.Name)
+		}
+		if keys[user.APIKeySHA256] {
+			return nil, fmt.Errorf("API key of user %s is used by another user", user.Name)
+		}
+		keys[user.APIKeySHA256] = true
+		user.Profile = resolve(dir, user.Profile)
+	}
+	if len(config.Users) == 0 {
+		return nil, fmt.Errorf("config has no users")
+	}
+	return config, nil
+}
+
+// HashAPIKey returns the hex SHA-256 hash of an API key, as stored in the configuration
+func HashAPIKey(apiKey string) string {
+	hash := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(hash[:])
+}
+
+func resolve(dir string, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
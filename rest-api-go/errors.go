@@ -0,0 +1,49 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"net/http"
+
+	"github.com/hyperledger/fabric-samples/internal/appclient"
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil/errcode"
+)
+
+// Codes of the errors raised by the service rather than the chaincodes
+const (
+	codeUnauthenticated  errcode.Code = "UNAUTHENTICATED"
+	codeMethodNotAllowed errcode.Code = "METHOD_NOT_ALLOWED"
+)
+
+// httpStatuses are the HTTP statuses of the chaincode and Gateway error codes. Codes not listed,
+// such as CORRUPT_STATE and INTERNAL, are 500 Internal Server Error.
+var httpStatuses = map[errcode.Code]int{
+	errcode.CodeInvalidArgument:       http.StatusBadRequest,
+	errcode.CodeAmountOverflow:        http.StatusBadRequest,
+	errcode.CodeUnknownTransaction:    http.StatusBadRequest,
+	errcode.CodeNotAuthorized:         http.StatusForbidden,
+	errcode.CodeNotFound:              http.StatusNotFound,
+	errcode.CodeAccountNotFound:       http.StatusNotFound,
+	errcode.CodeAssetNotFound:         http.StatusNotFound,
+	errcode.CodeAssetExists:           http.StatusConflict,
+	errcode.CodeInsufficientFunds:     http.StatusConflict,
+	errcode.CodeInsufficientAllowance: http.StatusConflict,
+	errcode.CodeAgreementMismatch:     http.StatusConflict,
+	errcode.CodeQuotaExceeded:         http.StatusConflict,
+	appclient.CodeCommitFailed:        http.StatusConflict,
+	appclient.CodeUnavailable:         http.StatusServiceUnavailable,
+	appclient.CodeTimeout:             http.StatusGatewayTimeout,
+}
+
+// httpError returns the HTTP status and coded error of an error returned by a contract call
+func httpError(err error) (int, *errcode.Error) {
+	gatewayErr := appclient.ParseError(err)
+	codedErr := &errcode.Error{Code: errcode.Code(gatewayErr.Code), Message: gatewayErr.Message}
+	httpStatus, ok := httpStatuses[codedErr.Code]
+	if !ok {
+		httpStatus = http.StatusInternalServerError
+	}
+	return httpStatus, codedErr
+}
@@ -0,0 +1,81 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// rest-api-go exposes the token and secured agreement asset chaincodes as an HTTP/JSON API. Each
+// API user authenticates with an API key and its requests are made as a Fabric identity of a
+// wallet.
+package main
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/hyperledger/fabric-samples/internal/tracing"
+)
+
+// openAPISpec is the OpenAPI specification of the API, served at /api/openapi.yaml
+//
+//go:embed openapi.yaml
+var openAPISpec []byte
+
+var (
+	configPath = flag.String("config", "config.json", "configuration file")
+	hashKey    = flag.String("hash-key", "", "print the hash of an API key for the configuration and exit")
+)
+
+func main() {
+	flag.Parse()
+	if *hashKey != "" {
+		fmt.Println(HashAPIKey(*hashKey))
+		return
+	}
+
+	config, err := LoadConfig(*configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	shutdownTracing, err := tracing.Start(context.Background(), "rest-api-go")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer shutdownTracing(context.Background())
+	s, err := newServer(config)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer s.Close()
+	for _, user := range s.users {
+		log.Printf("user %s connected as a member of %s", user.name, user.mspID)
+	}
+
+	httpServer := &http.Server{
+		Addr:              config.Listen,
+		Handler:           tracing.Handler(s, "rest-api-go"),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			log.Printf("failed to shut down: %v", err)
+		}
+	}()
+
+	log.Printf("listening on %s", config.Listen)
+	err = httpServer.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,76 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// handlerFunc handles a request of an authenticated user. params holds the values of the {name}
+// segments of the route's pattern.
+type handlerFunc func(w http.ResponseWriter, r *http.Request, user *userClient, params map[string]string)
+
+type route struct {
+	method   string
+	pattern  string
+	segments []string
+	handler  handlerFunc
+}
+
+// router matches requests against patterns such as /api/assets/{id}/history
+type router struct {
+	routes []route
+}
+
+func (rt *router) handle(method string, pattern string, handler handlerFunc) {
+	rt.routes = append(rt.routes, route{method: method, pattern: pattern, segments: splitPath(pattern), handler: handler})
+}
+
+// match returns the route matching the method and escaped path and the unescaped values of its
+// parameters. Parameters are unescaped after the path is split, so account IDs may contain an
+// escaped "/". pathFound tells a path no route matches from a method the path does not allow.
+func (rt *router) match(method string, escapedPath string) (matched *route, params map[string]string, pathFound bool) {
+	segments := splitPath(escapedPath)
+	for i := range rt.routes {
+		route := &rt.routes[i]
+		params, ok := route.matchPath(segments)
+		if !ok {
+			continue
+		}
+		pathFound = true
+		if route.method == method {
+			return route, params, true
+		}
+	}
+	return nil, nil, pathFound
+}
+
+func (r *route) matchPath(segments []string) (map[string]string, bool) {
+	if len(segments) != len(r.segments) {
+		return nil, false
+	}
+	params := make(map[string]string)
+	for i, segment := range r.segments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			if segments[i] == "" {
+				return nil, false
+			}
+			value, err := url.PathUnescape(segments[i])
+			if err != nil {
+				return nil, false
+			}
+			params[segment[1:len(segment)-1]] = value
+		} else if segment != segments[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+func splitPath(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
@@ -0,0 +1,198 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-secured-agreement/application-go/asset"
+	"github.com/hyperledger/fabric-samples/internal/appclient"
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil/errcode"
+	"github.com/hyperledger/fabric-samples/internal/metrics"
+	"github.com/hyperledger/fabric-samples/token-erc-20/application-go/token"
+)
+
+// apiKeyHeader is the request header carrying the API key of the user
+const apiKeyHeader = "X-API-Key"
+
+// maxBodySize is the largest request body the service reads
+const maxBodySize = 1 << 20
+
+// userClient is an API user with its Gateway connection and contracts
+type userClient struct {
+	name       string
+	mspID      string
+	connection *appclient.Connection
+	token      *token.Contract
+	asset      *asset.Contract
+}
+
+// server routes the requests of API users to the contracts of their Fabric identities
+type server struct {
+	users  map[string]*userClient
+	router *router
+}
+
+// newServer connects every user of the configuration to its peer as its wallet identity
+func newServer(config *Config) (*server, error) {
+	wallet, err := appclient.OpenWallet(config.Wallet)
+	if err != nil {
+		return nil, err
+	}
+	s := &server{users: make(map[string]*userClient), router: &router{}}
+	for _, user := range config.Users {
+		profile, err := appclient.LoadProfile(user.Profile)
+		if err != nil {
+			s.Close()
+			return nil, fmt.Errorf("failed to load profile of user %s: %v", user.Name, err)
+		}
+		connection, err := appclient.ConnectWallet(profile, user.Peer, wallet, user.Identity)
+		if err != nil {
+			s.Close()
+			return nil, fmt.Errorf("failed to connect user %s: %v", user.Name, err)
+		}
+		network := connection.GetNetwork(config.Channel)
+		tokenContract := token.NewContract(network, config.TokenChaincode)
+		tokenContract.SetObserver(metrics.ObserveCall)
+		assetContract := asset.NewContract(network, config.AssetChaincode, profile.MSPID())
+		assetContract.SetObserver(metrics.ObserveCall)
+		s.users[user.APIKeySHA256] = &userClient{
+			name:       user.Name,
+			mspID:      profile.MSPID(),
+			connection: connection,
+			token:      tokenContract,
+			asset:      assetContract,
+		}
+	}
+	s.routes()
+	return s, nil
+}
+
+// Close closes the connections of the users
+func (s *server) Close() {
+	for _, user := range s.users {
+		if err := user.connection.Close(); err != nil {
+			log.Printf("failed to close connection of user %s: %v", user.name, err)
+		}
+	}
+}
+
+func (s *server) routes() {
+	s.tokenRoutes()
+	s.assetRoutes()
+	s.router.handle(http.MethodGet, "/api/status", writeStatus)
+}
+
+// chaincodeStatus is the build a chaincode runs on the user's peer and its answer to Ping
+type chaincodeStatus struct {
+	Build *appclient.BuildInfo  `json:"build"`
+	Ping  *appclient.PingResult `json:"ping"`
+}
+
+// writeStatus writes the build and Ping result of both chaincodes on the peer of the user, or the
+// first error, so operators can check which build is live and that the peer endorses
+func writeStatus(w http.ResponseWriter, r *http.Request, user *userClient, params map[string]string) {
+	var token, asset chaincodeStatus
+	var err error
+	token.Build, err = user.token.GetVersion()
+	if err == nil {
+		token.Ping, err = user.token.Ping()
+	}
+	if err == nil {
+		asset.Build, err = user.asset.GetVersion()
+	}
+	if err == nil {
+		asset.Ping, err = user.asset.Ping()
+	}
+	writeResult(w, http.StatusOK, map[string]chaincodeStatus{"token": token, "asset": asset}, err)
+}
+
+// ServeHTTP serves the OpenAPI specification and metrics to anyone and the API to users with a
+// valid API key, recording the duration of each request
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	recorder := metrics.NewStatusRecorder(w)
+	routeLabel := s.serve(recorder, r)
+	metrics.ObserveHTTPRequest(routeLabel, r.Method, recorder.Status, start)
+}
+
+// serve serves a request and returns the pattern of the route it matched, or "unmatched"
+func (s *server) serve(w http.ResponseWriter, r *http.Request) string {
+	if r.Method == http.MethodGet && r.URL.Path == "/api/openapi.yaml" {
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Write(openAPISpec)
+		return r.URL.Path
+	}
+	if r.Method == http.MethodGet && r.URL.Path == "/metrics" {
+		metrics.Handler().ServeHTTP(w, r)
+		return r.URL.Path
+	}
+
+	route, params, pathFound := s.router.match(r.Method, r.URL.EscapedPath())
+	if route == nil {
+		if pathFound {
+			writeError(w, http.StatusMethodNotAllowed, &errcode.Error{Code: codeMethodNotAllowed, Message: fmt.Sprintf("%s is not allowed on %s", r.Method, r.URL.Path)})
+		} else {
+			writeError(w, http.StatusNotFound, &errcode.Error{Code: errcode.CodeNotFound, Message: fmt.Sprintf("no API at %s", r.URL.Path)})
+		}
+		return "unmatched"
+	}
+
+	user, ok := s.users[HashAPIKey(r.Header.Get(apiKeyHeader))]
+	if !ok {
+		writeError(w, http.StatusUnauthorized, &errcode.Error{Code: codeUnauthenticated, Message: fmt.Sprintf("missing or unknown %s header", apiKeyHeader)})
+		return route.pattern
+	}
+	// the contracts make their calls in the context of the request, so their spans join its trace
+	requestUser := *user
+	requestUser.token = user.token.WithContext(r.Context())
+	requestUser.asset = user.asset.WithContext(r.Context())
+	route.handler(w, r, &requestUser, params)
+	return route.pattern
+}
+
+// decodeBody decodes the JSON request body into body, writing a 400 response when it is invalid
+func decodeBody(w http.ResponseWriter, r *http.Request, body interface{}) bool {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, &errcode.Error{Code: errcode.CodeInvalidArgument, Message: fmt.Sprintf("invalid request body: %v", err)})
+		return false
+	}
+	return true
+}
+
+// writeResult writes the result of a contract call, or the error the call returned
+func writeResult(w http.ResponseWriter, status int, result interface{}, err error) {
+	if err != nil {
+		httpStatus, apiErr := httpError(err)
+		writeError(w, httpStatus, apiErr)
+		return
+	}
+	writeJSON(w, status, result)
+}
+
+func writeError(w http.ResponseWriter, status int, apiErr *errcode.Error) {
+	writeJSON(w, status, struct {
+		Error *errcode.Error `json:"error"`
+	}{apiErr})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	bodyJSON, err := json.Marshal(body)
+	if err != nil {
+		log.Printf("failed to marshal response: %v", err)
+		status = http.StatusInternalServerError
+		bodyJSON = []byte(`{"error":{"code":"INTERNAL","message":"failed to marshal response"}}`)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(bodyJSON)
+}
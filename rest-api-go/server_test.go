@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil/errcode"
+)
+
+func TestRouterMatch(t *testing.T) {
+	s := &server{users: map[string]*userClient{}, router: &router{}}
+	s.routes()
+
+	matched, params, _ := s.router.match(http.MethodGet, "/api/token/allowance/x509%3A%3ACN%3Duser1%2FOU%3Dclient/acc2")
+	if matched == nil {
+		t.Fatal("allowance route did not match")
+	}
+	if matched.pattern != "/api/token/allowance/{owner}/{spender}" {
+		t.Errorf("matched route %s", matched.pattern)
+	}
+	if params["owner"] != "x509::CN=user1/OU=client" || params["spender"] != "acc2" {
+		t.Errorf("params are %v", params)
+	}
+
+	matched, _, pathFound := s.router.match(http.MethodDelete, "/api/assets/asset1")
+	if matched != nil || !pathFound {
+		t.Errorf("expected DELETE to be refused on an asset path, got route %v, pathFound %v", matched != nil, pathFound)
+	}
+	matched, _, pathFound = s.router.match(http.MethodGet, "/api/assets/asset1/unknown")
+	if matched != nil || pathFound {
+		t.Errorf("expected no route for an unknown path")
+	}
+}
+
+func TestServeHTTPRequiresAPIKey(t *testing.T) {
+	s := &server{users: map[string]*userClient{}, router: &router{}}
+	s.routes()
+
+	for _, test := range []struct {
+		method, path string
+		status       int
+	}{
+		{http.MethodGet, "/api/assets/asset1", http.StatusUnauthorized},
+		{http.MethodPatch, "/api/assets/asset1", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/api/status", http.StatusUnauthorized},
+		{http.MethodGet, "/api/unknown", http.StatusNotFound},
+		{http.MethodGet, "/api/openapi.yaml", http.StatusOK},
+	} {
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(test.method, test.path, nil)
+		request.Header.Set(apiKeyHeader, "unknown")
+		s.ServeHTTP(recorder, request)
+		if recorder.Code != test.status {
+			t.Errorf("%s %s returned %d, want %d", test.method, test.path, recorder.Code, test.status)
+		}
+	}
+}
+
+func TestHTTPError(t *testing.T) {
+	httpStatus, codedErr := httpError(errors.New("failed to marshal asset_price"))
+	if httpStatus != http.StatusInternalServerError || codedErr.Code != errcode.CodeInternal {
+		t.Errorf("client side error is %d %+v", httpStatus, codedErr)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	configJSON := `{"users":[{"name":"alice","apiKeySHA256":"` + HashAPIKey("secret") + `","identity":"org1-user1","profile":"profiles/org1.json"}]}`
+	if err := os.WriteFile(path, []byte(configJSON), 0600); err != nil {
+		t.Fatal(err)
+	}
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if config.Channel != "mychannel" || config.Wallet != filepath.Join(dir, "wallet") || config.Users[0].Profile != filepath.Join(dir, "profiles/org1.json") {
+		t.Errorf("config is %+v", config)
+	}
+
+	if err := os.WriteFile(path, []byte(strings.Replace(configJSON, HashAPIKey("secret"), "secret", 1)), 0600); err != nil {
+		t.Fatal(err)
+	}
+	_, err = LoadConfig(path)
+	if err == nil || !strings.Contains(err.Error(), "not a hex SHA-256 hash") {
+		t.Errorf("expected API key hash error, got %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"net/http"
+)
+
+// amountRequest is the body of the token requests moving or allowing an amount of tokens
+type amountRequest struct {
+	From    string `json:"from,omitempty"`
+	To      string `json:"to,omitempty"`
+	Spender string `json:"spender,omitempty"`
+	Amount  int    `json:"amount"`
+}
+
+// tokenRoutes registers the token API. Queries are GET requests evaluated on a peer; transactions
+// are POST requests submitted and committed before the response.
+func (s *server) tokenRoutes() {
+	s.router.handle(http.MethodGet, "/api/token/id", func(w http.ResponseWriter, r *http.Request, user *userClient, params map[string]string) {
+		account, err := user.token.ClientAccountID()
+		writeResult(w, http.StatusOK, map[string]string{"account": account}, err)
+	})
+	s.router.handle(http.MethodGet, "/api/token/balance", func(w http.ResponseWriter, r *http.Request, user *userClient, params map[string]string) {
+		account, err := user.token.ClientAccountID()
+		if err != nil {
+			writeResult(w, http.StatusOK, nil, err)
+			return
+		}
+		writeBalance(w, user, account)
+	})
+	s.router.handle(http.MethodGet, "/api/token/balance/{account}", func(w http.ResponseWriter, r *http.Request, user *userClient, params map[string]string) {
+		writeBalance(w, user, params["account"])
+	})
+	s.router.handle(http.MethodGet, "/api/token/allowance/{owner}/{spender}", func(w http.ResponseWriter, r *http.Request, user *userClient, params map[string]string) {
+		allowance, err := user.token.Allowance(params["owner"], params["spender"])
+		writeResult(w, http.StatusOK, map[string]interface{}{"owner": params["owner"], "spender": params["spender"], "allowance": allowance}, err)
+	})
+	s.router.handle(http.MethodGet, "/api/token/audit/{txID}", func(w http.ResponseWriter, r *http.Request, user *userClient, params map[string]string) {
+		record, err := user.token.GetAuditRecord(params["txID"])
+		writeResult(w, http.StatusOK, record, err)
+	})
+
+	s.router.handle(http.MethodPost, "/api/token/mint", func(w http.ResponseWriter, r *http.Request, user *userClient, params map[string]string) {
+		var request amountRequest
+		if decodeBody(w, r, &request) {
+			result, err := user.token.Mint(request.Amount)
+			writeResult(w, http.StatusOK, result, err)
+		}
+	})
+	s.router.handle(http.MethodPost, "/api/token/burn", func(w http.ResponseWriter, r *http.Request, user *userClient, params map[string]string) {
+		var request amountRequest
+		if decodeBody(w, r, &request) {
+			result, err := user.token.Burn(request.Amount)
+			writeResult(w, http.StatusOK, result, err)
+		}
+	})
+	s.router.handle(http.MethodPost, "/api/token/transfer", func(w http.ResponseWriter, r *http.Request, user *userClient, params map[string]string) {
+		var request amountRequest
+		if decodeBody(w, r, &request) {
+			result, err := user.token.Transfer(request.To, request.Amount)
+			writeResult(w, http.StatusOK, result, err)
+		}
+	})
+	s.router.handle(http.MethodPost, "/api/token/approve", func(w http.ResponseWriter, r *http.Request, user *userClient, params map[string]string) {
+		var request amountRequest
+		if decodeBody(w, r, &request) {
+			result, err := user.token.Approve(request.Spender, request.Amount)
+			writeResult(w, http.StatusOK, result, err)
+		}
+	})
+	s.router.handle(http.MethodPost, "/api/token/transfer-from", func(w http.ResponseWriter, r *http.Request, user *userClient, params map[string]string) {
+		var request amountRequest
+		if decodeBody(w, r, &request) {
+			result, err := user.token.TransferFrom(request.From, request.To, request.Amount)
+			writeResult(w, http.StatusOK, result, err)
+		}
+	})
+}
+
+func writeBalance(w http.ResponseWriter, user *userClient, account string) {
+	balance, err := user.token.BalanceOf(account)
+	writeResult(w, http.StatusOK, map[string]interface{}{"account": account, "balance": balance}, err)
+}
@@ -0,0 +1,624 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil"
+)
+
+// tokenChaincode is the name the token-erc-20 sample is deployed under in the README
+const tokenChaincode = "token_erc20"
+
+// This sample assumes Org1 operates the billing service and appoints the billing agent
+const platformMSPID = "Org1MSP"
+
+// Define key names for options
+const billingAgentKey = "billingAgent"
+
+// object names for prefix
+const (
+	planPrefix         = "plan"
+	subscriptionPrefix = "subscription"
+	invoicePrefix      = "invoice"
+)
+
+// subscription status values
+const (
+	statusActive    = "ACTIVE"
+	statusPastDue   = "PAST_DUE"
+	statusCancelled = "CANCELLED"
+	statusExpired   = "EXPIRED"
+)
+
+// maxDunningAttempts is the number of failed charges after which a subscription is cancelled
+const maxDunningAttempts = 3
+
+// maxChargesPerRun is the most due subscriptions one ChargeDue handles, the 100 payments one
+// BatchTransfer of the token chaincode makes
+const maxChargesPerRun = 100
+
+// SmartContract provides functions for recurring token subscriptions
+type SmartContract struct {
+	contractapi.Contract
+}
+
+// Plan is a recurring price a merchant charges every Period seconds
+type Plan struct {
+	ObjectType string `json:"objectType"`
+	ID         string `json:"planID"`
+	Merchant   string `json:"merchant"`
+	Name       string `json:"name"`
+	Price      int    `json:"price"`
+	Period     int64  `json:"period"`
+	Active     bool   `json:"active"`
+}
+
+// Subscription is a customer's subscription to a plan. Remaining is what is left of the pull
+// authorization the customer granted when subscribing.
+type Subscription struct {
+	ObjectType     string `json:"objectType"`
+	ID             string `json:"subscriptionID"`
+	PlanID         string `json:"planID"`
+	Customer       string `json:"customer"`
+	Merchant       string `json:"merchant"`
+	Price          int    `json:"price"`
+	Period         int64  `json:"period"`
+	NextChargeAt   int64  `json:"nextChargeAt"`
+	Remaining      int    `json:"remaining"`
+	Charges        int    `json:"charges"`
+	FailedAttempts int    `json:"failedAttempts"`
+	Status         string `json:"status"`
+}
+
+// Invoice records a successful charge
+type Invoice struct {
+	SubscriptionID string `json:"subscriptionID"`
+	Number         int    `json:"number"`
+	Customer       string `json:"customer"`
+	Merchant       string `json:"merchant"`
+	Amount         int    `json:"amount"`
+	PeriodStart    int64  `json:"periodStart"`
+	TxID           string `json:"txID"`
+}
+
+// DunningNotice records a failed charge so the merchant can contact the customer
+type DunningNotice struct {
+	SubscriptionID string `json:"subscriptionID"`
+	Customer       string `json:"customer"`
+	Attempt        int    `json:"attempt"`
+	Reason         string `json:"reason"`
+	Status         string `json:"status"`
+}
+
+// billingRunEvent provides an organized struct for emitting the result of a ChargeDue run.
+// Bookmark is where the next run continues, empty when the run reached the last subscription.
+type billingRunEvent struct {
+	Invoices []*Invoice       `json:"invoices"`
+	Dunning  []*DunningNotice `json:"dunning"`
+	Bookmark string           `json:"bookmark"`
+}
+
+// SetBillingAgent appoints the account that pulls subscription payments from its allowances.
+// Only the platform org can appoint the billing agent.
+func (s *SmartContract) SetBillingAgent(ctx contractapi.TransactionContextInterface, account string) error {
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+	if clientMSPID != platformMSPID {
+		return fmt.Errorf("client from %s is not authorized to appoint the billing agent", clientMSPID)
+	}
+	if account == "" {
+		return fmt.Errorf("billing agent account must be set")
+	}
+
+	return ctx.GetStub().PutState(billingAgentKey, []byte(account))
+}
+
+// CreatePlan defines a plan charged to subscribers every period seconds, paid to the client as merchant
+func (s *SmartContract) CreatePlan(ctx contractapi.TransactionContextInterface, planID string, name string, price int, period int64) error {
+	merchant, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	if planID == "" || name == "" {
+		return fmt.Errorf("plan ID and name must be set")
+	}
+	if price <= 0 {
+		return fmt.Errorf("price must be a positive integer")
+	}
+	if period <= 0 {
+		return fmt.Errorf("period must be a positive number of seconds")
+	}
+
+	existing, err := _getPlan(ctx, planID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("the plan %s already exists", planID)
+	}
+
+	plan := Plan{
+		ObjectType: planPrefix,
+		ID:         planID,
+		Merchant:   merchant,
+		Name:       name,
+		Price:      price,
+		Period:     period,
+		Active:     true,
+	}
+	return _putPlan(ctx, &plan)
+}
+
+// RetirePlan stops new subscriptions to a plan. Existing subscriptions continue.
+func (s *SmartContract) RetirePlan(ctx contractapi.TransactionContextInterface, planID string) error {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	plan, err := s.GetPlan(ctx, planID)
+	if err != nil {
+		return err
+	}
+	if plan.Merchant != clientID {
+		return fmt.Errorf("only the merchant can retire plan %s", planID)
+	}
+
+	plan.Active = false
+	return _putPlan(ctx, plan)
+}
+
+// Subscribe subscribes the client to a plan for at most maxCharges periods. The first charge is due
+// immediately. The client's token allowance for the billing agent is raised by price * maxCharges,
+// which bounds what can ever be pulled for this subscription.
+func (s *SmartContract) Subscribe(ctx contractapi.TransactionContextInterface, subscriptionID string, planID string, maxCharges int) error {
+	customer, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	if subscriptionID == "" {
+		return fmt.Errorf("subscription ID must be set")
+	}
+	if maxCharges <= 0 {
+		return fmt.Errorf("maximum number of charges must be a positive integer")
+	}
+
+	plan, err := s.GetPlan(ctx, planID)
+	if err != nil {
+		return err
+	}
+	if !plan.Active {
+		return fmt.Errorf("plan %s is retired", planID)
+	}
+
+	existing, err := _getSubscription(ctx, subscriptionID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("the subscription %s already exists", subscriptionID)
+	}
+
+	billingAgent, err := _getBillingAgent(ctx)
+	if err != nil {
+		return err
+	}
+	authorization := plan.Price * maxCharges
+	err = _adjustAllowance(ctx, customer, billingAgent, authorization)
+	if err != nil {
+		return err
+	}
+
+	now, err := _txUnix(ctx)
+	if err != nil {
+		return err
+	}
+
+	subscription := Subscription{
+		ObjectType:   subscriptionPrefix,
+		ID:           subscriptionID,
+		PlanID:       planID,
+		Customer:     customer,
+		Merchant:     plan.Merchant,
+		Price:        plan.Price,
+		Period:       plan.Period,
+		NextChargeAt: now,
+		Remaining:    authorization,
+		Status:       statusActive,
+	}
+	return _putSubscription(ctx, &subscription)
+}
+
+// CancelSubscription stops future charges. The customer or the merchant can cancel. When the customer
+// cancels, the unused part of the authorization is taken off their allowance for the billing agent.
+func (s *SmartContract) CancelSubscription(ctx contractapi.TransactionContextInterface, subscriptionID string) error {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	subscription, err := s.GetSubscription(ctx, subscriptionID)
+	if err != nil {
+		return err
+	}
+	if clientID != subscription.Customer && clientID != subscription.Merchant {
+		return fmt.Errorf("only the customer or the merchant can cancel subscription %s", subscriptionID)
+	}
+	if subscription.Status == statusCancelled || subscription.Status == statusExpired {
+		return fmt.Errorf("subscription %s is already %s", subscriptionID, subscription.Status)
+	}
+
+	if clientID == subscription.Customer {
+		billingAgent, err := _getBillingAgent(ctx)
+		if err != nil {
+			return err
+		}
+		err = _adjustAllowance(ctx, subscription.Customer, billingAgent, -subscription.Remaining)
+		if err != nil {
+			return err
+		}
+	}
+
+	subscription.Status = statusCancelled
+	return _putSubscription(ctx, subscription)
+}
+
+// ChargeDue is submitted by the billing agent to collect the subscriptions whose billing period has
+// elapsed, pulling the price from each customer to the merchant. Each success writes an invoice and
+// advances the subscription one period. A failed charge puts it past due and raises a dunning
+// notice; after maxDunningAttempts failures it is cancelled. A subscription whose authorization is
+// used up expires. One BillingRun event carries the invoices and dunning notices.
+//
+// A transaction does not read its own writes, so one TransferFrom per charge would only keep the
+// last debit of a customer. Every charge of the run is checked against the customer's balance and
+// allowance and then pulled in one BatchTransfer of the token chaincode. A run handles at most
+// maxChargesPerRun due subscriptions, scanning them in ID order from after bookmark, and returns the
+// bookmark to pass to the next run; it is empty once the scan has reached the last subscription.
+func (s *SmartContract) ChargeDue(ctx contractapi.TransactionContextInterface, bookmark string) (string, error) {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get client id: %v", err)
+	}
+	billingAgent, err := _getBillingAgent(ctx)
+	if err != nil {
+		return "", err
+	}
+	if clientID != billingAgent {
+		return "", fmt.Errorf("only the billing agent can charge subscriptions")
+	}
+
+	now, err := _txUnix(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	due, next, err := _dueSubscriptions(ctx, now, bookmark)
+	if err != nil {
+		return "", err
+	}
+
+	run := billingRunEvent{Bookmark: next}
+	funds := newFundsCheck(billingAgent)
+	var payments []ledgerutil.TokenPayment
+	for _, subscription := range due {
+		if subscription.Remaining < subscription.Price {
+			subscription.Status = statusExpired
+			continue
+		}
+
+		err = funds.reserve(ctx, subscription)
+		if err == nil {
+			payments = append(payments, ledgerutil.TokenPayment{From: subscription.Customer, Receiver: subscription.Merchant, Amount: subscription.Price})
+			subscription.Charges++
+			invoice := Invoice{
+				SubscriptionID: subscription.ID,
+				Number:         subscription.Charges,
+				Customer:       subscription.Customer,
+				Merchant:       subscription.Merchant,
+				Amount:         subscription.Price,
+				PeriodStart:    subscription.NextChargeAt,
+				TxID:           ctx.GetStub().GetTxID(),
+			}
+			err = _putInvoice(ctx, &invoice)
+			if err != nil {
+				return "", err
+			}
+			run.Invoices = append(run.Invoices, &invoice)
+
+			subscription.NextChargeAt += subscription.Period
+			subscription.Remaining -= subscription.Price
+			subscription.FailedAttempts = 0
+			subscription.Status = statusActive
+		} else {
+			subscription.FailedAttempts++
+			subscription.Status = statusPastDue
+			if subscription.FailedAttempts >= maxDunningAttempts {
+				subscription.Status = statusCancelled
+			}
+			run.Dunning = append(run.Dunning, &DunningNotice{
+				SubscriptionID: subscription.ID,
+				Customer:       subscription.Customer,
+				Attempt:        subscription.FailedAttempts,
+				Reason:         err.Error(),
+				Status:         subscription.Status,
+			})
+		}
+	}
+
+	err = ledgerutil.TransferTokens(ctx, tokenChaincode, payments...)
+	if err != nil {
+		return "", err
+	}
+	for _, subscription := range due {
+		err = _putSubscription(ctx, subscription)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	runJSON, err := json.Marshal(run)
+	if err != nil {
+		return "", fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	err = ctx.GetStub().SetEvent("BillingRun", runJSON)
+	if err != nil {
+		return "", fmt.Errorf("failed to set event: %v", err)
+	}
+
+	return next, nil
+}
+
+// fundsCheck tracks what the charges of a run leave of each customer's balance and allowance for
+// the billing agent, so the run's BatchTransfer only holds charges the token chaincode accepts
+type fundsCheck struct {
+	billingAgent string
+	balances     map[string]int
+	allowances   map[string]int
+}
+
+func newFundsCheck(billingAgent string) *fundsCheck {
+	return &fundsCheck{billingAgent: billingAgent, balances: make(map[string]int), allowances: make(map[string]int)}
+}
+
+// reserve takes the price of a subscription off its customer's balance and allowance, reading them
+// from the token chaincode on the customer's first charge of the run
+func (f *fundsCheck) reserve(ctx contractapi.TransactionContextInterface, subscription *Subscription) error {
+	customer := subscription.Customer
+	if customer == subscription.Merchant {
+		return fmt.Errorf("customer %s is the merchant", customer)
+	}
+	if _, ok := f.balances[customer]; !ok {
+		balance, err := _tokenQuery(ctx, "BalanceOf", customer)
+		if err != nil {
+			return err
+		}
+		allowance, err := _tokenQuery(ctx, "Allowance", customer, f.billingAgent)
+		if err != nil {
+			return err
+		}
+		f.balances[customer], f.allowances[customer] = balance, allowance
+	}
+
+	if f.balances[customer] < subscription.Price {
+		return fmt.Errorf("customer %s has insufficient funds", customer)
+	}
+	if f.allowances[customer] < subscription.Price {
+		return fmt.Errorf("customer %s has not authorized the billing agent to pull %d tokens", customer, subscription.Price)
+	}
+	f.balances[customer] -= subscription.Price
+	f.allowances[customer] -= subscription.Price
+	return nil
+}
+
+// _dueSubscriptions returns up to maxChargesPerRun active or past due subscriptions whose next
+// charge is due, in ID order after bookmark, and the bookmark of the next run. The world state is
+// scanned with an unpaginated query, as paginated queries are not allowed in update transactions.
+func _dueSubscriptions(ctx contractapi.TransactionContextInterface, now int64, bookmark string) ([]*Subscription, string, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(subscriptionPrefix, []string{})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get subscriptions: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var due []*Subscription
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, "", err
+		}
+
+		var subscription Subscription
+		err = json.Unmarshal(response.Value, &subscription)
+		if err != nil {
+			return nil, "", err
+		}
+		if subscription.ID <= bookmark {
+			continue
+		}
+		if subscription.Status != statusActive && subscription.Status != statusPastDue {
+			continue
+		}
+		if subscription.NextChargeAt > now {
+			continue
+		}
+
+		if len(due) == maxChargesPerRun {
+			return due, due[len(due)-1].ID, nil
+		}
+		due = append(due, &subscription)
+	}
+
+	return due, "", nil
+}
+
+// _getBillingAgent returns the appointed billing agent
+func _getBillingAgent(ctx contractapi.TransactionContextInterface) (string, error) {
+	billingAgent, err := ctx.GetStub().GetState(billingAgentKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to read billing agent: %v", err)
+	}
+	if billingAgent == nil {
+		return "", fmt.Errorf("no billing agent has been appointed")
+	}
+	return string(billingAgent), nil
+}
+
+// _adjustAllowance changes the invoking client's token allowance for spender by delta. The token's
+// Approve replaces the allowance, so the current value is read first to keep the authorizations of
+// other subscriptions.
+func _adjustAllowance(ctx contractapi.TransactionContextInterface, owner string, spender string, delta int) error {
+	args := [][]byte{[]byte("Allowance"), []byte(owner), []byte(spender)}
+	response := ctx.GetStub().InvokeChaincode(tokenChaincode, args, "")
+	if response.Status != shim.OK {
+		return fmt.Errorf("failed to read allowance on %s: %s", tokenChaincode, response.Message)
+	}
+	allowance, err := strconv.Atoi(string(response.Payload))
+	if err != nil {
+		return fmt.Errorf("failed to convert allowance: %v", err)
+	}
+
+	allowance += delta
+	if allowance < 0 {
+		allowance = 0
+	}
+
+	args = [][]byte{[]byte("Approve"), []byte(spender), []byte(strconv.Itoa(allowance))}
+	response = ctx.GetStub().InvokeChaincode(tokenChaincode, args, "")
+	if response.Status != shim.OK {
+		return fmt.Errorf("failed to approve allowance on %s: %s", tokenChaincode, response.Message)
+	}
+	return nil
+}
+
+// _tokenQuery calls a function of the token chaincode returning a number, e.g. BalanceOf
+func _tokenQuery(ctx contractapi.TransactionContextInterface, function string, args ...string) (int, error) {
+	invokeArgs := [][]byte{[]byte(function)}
+	for _, arg := range args {
+		invokeArgs = append(invokeArgs, []byte(arg))
+	}
+	response := ctx.GetStub().InvokeChaincode(tokenChaincode, invokeArgs, "")
+	if response.Status != shim.OK {
+		return 0, fmt.Errorf("failed to call %s on %s: %s", function, tokenChaincode, response.Message)
+	}
+	value, err := strconv.Atoi(string(response.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("failed to convert the result of %s: %v", function, err)
+	}
+	return value, nil
+}
+
+// _txUnix returns the transaction timestamp in seconds, which is the same on every endorsing peer
+func _txUnix(ctx contractapi.TransactionContextInterface) (int64, error) {
+	now, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return now.Unix(), nil
+}
+
+// _getPlan reads a plan, returning nil when it does not exist
+func _getPlan(ctx contractapi.TransactionContextInterface, planID string) (*Plan, error) {
+	planKey, err := ctx.GetStub().CreateCompositeKey(planPrefix, []string{planID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	planJSON, err := ctx.GetStub().GetState(planKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if planJSON == nil {
+		return nil, nil
+	}
+
+	var plan Plan
+	err = json.Unmarshal(planJSON, &plan)
+	if err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+// _getSubscription reads a subscription, returning nil when it does not exist
+func _getSubscription(ctx contractapi.TransactionContextInterface, subscriptionID string) (*Subscription, error) {
+	subscriptionKey, err := ctx.GetStub().CreateCompositeKey(subscriptionPrefix, []string{subscriptionID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	subscriptionJSON, err := ctx.GetStub().GetState(subscriptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if subscriptionJSON == nil {
+		return nil, nil
+	}
+
+	var subscription Subscription
+	err = json.Unmarshal(subscriptionJSON, &subscription)
+	if err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// _putPlan writes the plan to the world state
+func _putPlan(ctx contractapi.TransactionContextInterface, plan *Plan) error {
+	planKey, err := ctx.GetStub().CreateCompositeKey(planPrefix, []string{plan.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	planJSON, err := json.Marshal(plan)
+	if err != nil {
+		return fmt.Errorf("failed to marshal plan: %v", err)
+	}
+	err = ctx.GetStub().PutState(planKey, planJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put plan %s: %v", plan.ID, err)
+	}
+	return nil
+}
+
+// _putSubscription writes the subscription to the world state
+func _putSubscription(ctx contractapi.TransactionContextInterface, subscription *Subscription) error {
+	subscriptionKey, err := ctx.GetStub().CreateCompositeKey(subscriptionPrefix, []string{subscription.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	subscriptionJSON, err := json.Marshal(subscription)
+	if err != nil {
+		return fmt.Errorf("failed to marshal subscription: %v", err)
+	}
+	err = ctx.GetStub().PutState(subscriptionKey, subscriptionJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put subscription %s: %v", subscription.ID, err)
+	}
+	return nil
+}
+
+// _putInvoice writes the invoice to the world state
+func _putInvoice(ctx contractapi.TransactionContextInterface, invoice *Invoice) error {
+	invoiceKey, err := ctx.GetStub().CreateCompositeKey(invoicePrefix, []string{invoice.SubscriptionID, fmt.Sprintf("%06d", invoice.Number)})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	invoiceJSON, err := json.Marshal(invoice)
+	if err != nil {
+		return fmt.Errorf("failed to marshal invoice: %v", err)
+	}
+	err = ctx.GetStub().PutState(invoiceKey, invoiceJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put invoice: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// GetPlan returns the plan stored in the world state with the given ID
+func (s *SmartContract) GetPlan(ctx contractapi.TransactionContextInterface, planID string) (*Plan, error) {
+	plan, err := _getPlan(ctx, planID)
+	if err != nil {
+		return nil, err
+	}
+	if plan == nil {
+		return nil, fmt.Errorf("the plan %s does not exist", planID)
+	}
+	return plan, nil
+}
+
+// GetSubscription returns the subscription stored in the world state with the given ID
+func (s *SmartContract) GetSubscription(ctx contractapi.TransactionContextInterface, subscriptionID string) (*Subscription, error) {
+	subscription, err := _getSubscription(ctx, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	if subscription == nil {
+		return nil, fmt.Errorf("the subscription %s does not exist", subscriptionID)
+	}
+	return subscription, nil
+}
+
+// GetSubscriptions returns every subscription
+func (s *SmartContract) GetSubscriptions(ctx contractapi.TransactionContextInterface) ([]*Subscription, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(subscriptionPrefix, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subscriptions: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var subscriptions []*Subscription
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var subscription Subscription
+		err = json.Unmarshal(response.Value, &subscription)
+		if err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, &subscription)
+	}
+
+	return subscriptions, nil
+}
+
+// GetInvoices returns the invoices of a subscription, oldest first
+func (s *SmartContract) GetInvoices(ctx contractapi.TransactionContextInterface, subscriptionID string) ([]*Invoice, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(invoicePrefix, []string{subscriptionID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invoices of subscription %s: %v", subscriptionID, err)
+	}
+	defer resultsIterator.Close()
+
+	var invoices []*Invoice
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var invoice Invoice
+		err = json.Unmarshal(response.Value, &invoice)
+		if err != nil {
+			return nil, err
+		}
+		invoices = append(invoices, &invoice)
+	}
+
+	return invoices, nil
+}
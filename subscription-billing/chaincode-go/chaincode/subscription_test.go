@@ -0,0 +1,259 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+)
+
+const customerMSPID = "Org2MSP"
+
+// fakeToken stands in for the token chaincode. The payer is the client invoking it: it approves
+// allowances, and batches are paid from its account or pulled against its allowances.
+type fakeToken struct {
+	payer      string
+	balances   map[string]int
+	allowances map[[2]string]int
+	batches    int
+}
+
+func (f *fakeToken) invoke(args [][]byte) pb.Response {
+	switch string(args[0]) {
+	case "BalanceOf":
+		balance, ok := f.balances[string(args[1])]
+		if !ok {
+			return shim.Error(fmt.Sprintf("the account %s does not exist", args[1]))
+		}
+		return shim.Success([]byte(strconv.Itoa(balance)))
+	case "Allowance":
+		return shim.Success([]byte(strconv.Itoa(f.allowances[[2]string{string(args[1]), string(args[2])}])))
+	case "Approve":
+		value, err := strconv.Atoi(string(args[2]))
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		f.allowances[[2]string{f.payer, string(args[1])}] = value
+		return shim.Success(nil)
+	case "BatchTransfer":
+		var payments []struct {
+			From     string `json:"from"`
+			Receiver string `json:"receiver"`
+			Amount   int    `json:"amount"`
+		}
+		err := json.Unmarshal(args[1], &payments)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		for _, payment := range payments {
+			from := payment.From
+			if from == "" {
+				from = f.payer
+			}
+			if f.balances[from] < payment.Amount {
+				return shim.Error(fmt.Sprintf("failed to transfer: client account %s has insufficient funds", from))
+			}
+			if from != f.payer {
+				allowance := [2]string{from, f.payer}
+				if f.allowances[allowance] < payment.Amount {
+					return shim.Error(fmt.Sprintf("failed to transfer: spender does not have enough allowance to transfer from %s", from))
+				}
+				f.allowances[allowance] -= payment.Amount
+			}
+			f.balances[from] -= payment.Amount
+			f.balances[payment.Receiver] += payment.Amount
+		}
+		f.batches++
+		return shim.Success(nil)
+	}
+	return shim.Error("unexpected function " + string(args[0]))
+}
+
+func checkError(t *testing.T, err error, expected string) {
+	t.Helper()
+	if expected == "" && err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected != "" && (err == nil || !strings.Contains(err.Error(), expected)) {
+		t.Fatalf("expected error containing %q, got %v", expected, err)
+	}
+}
+
+func checkSubscription(t *testing.T, stub *fakeStub, subscriptionID string, status string, charges int) *Subscription {
+	t.Helper()
+	subscription, err := new(SmartContract).GetSubscription(newContext(stub, "merchant", platformMSPID), subscriptionID)
+	if err != nil {
+		t.Fatalf("failed to read subscription: %v", err)
+	}
+	if subscription.Status != status || subscription.Charges != charges {
+		t.Fatalf("expected subscription %s %s with %d charges, got %s with %d", subscriptionID, status, charges, subscription.Status, subscription.Charges)
+	}
+	return subscription
+}
+
+// createPlan appoints the billing agent and has the merchant create plan monthly, charging 10
+// every 1000 seconds
+func createPlan(t *testing.T, stub *fakeStub) *fakeToken {
+	t.Helper()
+	token := &fakeToken{balances: map[string]int{"merchant": 0}, allowances: make(map[[2]string]int)}
+	stub.chaincodes[tokenChaincode] = token.invoke
+
+	contract := new(SmartContract)
+	checkError(t, contract.SetBillingAgent(newContext(stub, "operator", platformMSPID), "agent"), "")
+	checkError(t, contract.CreatePlan(newContext(stub, "merchant", platformMSPID), "monthly", "Monthly", 10, 1000), "")
+	return token
+}
+
+// subscribe subscribes the customer to plan monthly for at most maxCharges periods
+func subscribe(t *testing.T, stub *fakeStub, token *fakeToken, customer string, subscriptionID string, maxCharges int) {
+	t.Helper()
+	token.payer = customer
+	checkError(t, new(SmartContract).Subscribe(newContext(stub, customer, customerMSPID), subscriptionID, "monthly", maxCharges), "")
+}
+
+// chargeDue runs ChargeDue as the billing agent and returns the BillingRun event
+func chargeDue(t *testing.T, stub *fakeStub, token *fakeToken, bookmark string) billingRunEvent {
+	t.Helper()
+	token.payer = "agent"
+	next, err := new(SmartContract).ChargeDue(newContext(stub, "agent", platformMSPID), bookmark)
+	checkError(t, err, "")
+
+	var run billingRunEvent
+	err = json.Unmarshal(stub.eventValue, &run)
+	if err != nil {
+		t.Fatalf("failed to unmarshal event: %v", err)
+	}
+	if run.Bookmark != next {
+		t.Fatalf("expected bookmark %q in the event, got %q", next, run.Bookmark)
+	}
+	return run
+}
+
+func TestCreatePlan(t *testing.T) {
+	tests := []struct {
+		name     string
+		planID   string
+		price    int
+		period   int64
+		expected string
+	}{
+		{"plan", "yearly", 100, 12000, ""},
+		{"free", "yearly", 0, 12000, "price must be a positive integer"},
+		{"no period", "yearly", 100, 0, "period must be a positive number of seconds"},
+		{"existing plan", "monthly", 100, 12000, "the plan monthly already exists"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stub := newFakeStub()
+			createPlan(t, stub)
+
+			err := new(SmartContract).CreatePlan(newContext(stub, "merchant", platformMSPID), test.planID, "Plan", test.price, test.period)
+			checkError(t, err, test.expected)
+		})
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+
+	err := contract.SetBillingAgent(newContext(stub, "alice", customerMSPID), "alice")
+	checkError(t, err, "client from Org2MSP is not authorized to appoint the billing agent")
+
+	token := createPlan(t, stub)
+	subscribe(t, stub, token, "alice", "sub1", 12)
+	if token.allowances[[2]string{"alice", "agent"}] != 120 {
+		t.Fatalf("expected an allowance of 120, got %v", token.allowances)
+	}
+
+	err = contract.CancelSubscription(newContext(stub, "bob", customerMSPID), "sub1")
+	checkError(t, err, "only the customer or the merchant can cancel subscription sub1")
+
+	checkError(t, contract.CancelSubscription(newContext(stub, "alice", customerMSPID), "sub1"), "")
+	checkSubscription(t, stub, "sub1", statusCancelled, 0)
+	if token.allowances[[2]string{"alice", "agent"}] != 0 {
+		t.Fatalf("expected the allowance revoked, got %v", token.allowances)
+	}
+}
+
+func TestChargeDue(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	token := createPlan(t, stub)
+	token.balances["alice"] = 25
+	token.balances["bob"] = 5
+	subscribe(t, stub, token, "alice", "sub1", 3)
+	subscribe(t, stub, token, "alice", "sub2", 1)
+	subscribe(t, stub, token, "bob", "sub3", 3)
+
+	_, err := contract.ChargeDue(newContext(stub, "merchant", platformMSPID), "")
+	checkError(t, err, "only the billing agent can charge subscriptions")
+
+	// both charges of alice are pulled in one batch, bob cannot pay
+	run := chargeDue(t, stub, token, "")
+	if len(run.Invoices) != 2 || len(run.Dunning) != 1 || run.Bookmark != "" || token.batches != 1 {
+		t.Fatalf("unexpected run %+v after %d batches", run, token.batches)
+	}
+	if token.balances["alice"] != 5 || token.balances["merchant"] != 20 || token.allowances[[2]string{"alice", "agent"}] != 20 {
+		t.Fatalf("unexpected balances %v and allowances %v", token.balances, token.allowances)
+	}
+	if run.Dunning[0].SubscriptionID != "sub3" || run.Dunning[0].Reason != "customer bob has insufficient funds" {
+		t.Fatalf("unexpected dunning notice %+v", run.Dunning[0])
+	}
+	checkSubscription(t, stub, "sub1", statusActive, 1)
+	checkSubscription(t, stub, "sub3", statusPastDue, 0)
+
+	invoices, err := contract.GetInvoices(newContext(stub, "merchant", platformMSPID), "sub1")
+	checkError(t, err, "")
+	if len(invoices) != 1 || invoices[0].Amount != 10 || invoices[0].Customer != "alice" {
+		t.Fatalf("unexpected invoices %+v", invoices)
+	}
+
+	// a period later alice cannot pay the second charge of sub1 and sub2 has used its authorization
+	stub.txCount += 1000
+	run = chargeDue(t, stub, token, "")
+	if len(run.Invoices) != 0 || len(run.Dunning) != 2 || token.batches != 1 {
+		t.Fatalf("unexpected run %+v after %d batches", run, token.batches)
+	}
+	checkSubscription(t, stub, "sub1", statusPastDue, 1)
+	checkSubscription(t, stub, "sub2", statusExpired, 1)
+
+	// the third failure in a row cancels the subscription
+	chargeDue(t, stub, token, "")
+	checkSubscription(t, stub, "sub3", statusCancelled, 0)
+	checkSubscription(t, stub, "sub1", statusPastDue, 1)
+	chargeDue(t, stub, token, "")
+	subscription := checkSubscription(t, stub, "sub1", statusCancelled, 1)
+	if subscription.FailedAttempts != maxDunningAttempts {
+		t.Fatalf("expected %d failed attempts, got %d", maxDunningAttempts, subscription.FailedAttempts)
+	}
+}
+
+func TestChargeDueInBatches(t *testing.T) {
+	stub := newFakeStub()
+	token := createPlan(t, stub)
+	for i := 0; i < maxChargesPerRun+50; i++ {
+		customer := fmt.Sprintf("customer%03d", i)
+		token.balances[customer] = 10
+		subscribe(t, stub, token, customer, fmt.Sprintf("sub%03d", i), 1)
+	}
+
+	// the first run charges as many subscriptions as one BatchTransfer takes
+	run := chargeDue(t, stub, token, "")
+	if len(run.Invoices) != maxChargesPerRun || run.Bookmark != fmt.Sprintf("sub%03d", maxChargesPerRun-1) {
+		t.Fatalf("unexpected first run with %d invoices and bookmark %q", len(run.Invoices), run.Bookmark)
+	}
+	checkSubscription(t, stub, fmt.Sprintf("sub%03d", maxChargesPerRun), statusActive, 0)
+
+	run = chargeDue(t, stub, token, run.Bookmark)
+	if len(run.Invoices) != 50 || run.Bookmark != "" {
+		t.Fatalf("unexpected last run with %d invoices and bookmark %q", len(run.Invoices), run.Bookmark)
+	}
+	if token.batches != 2 || token.balances["merchant"] != 1500 {
+		t.Fatalf("expected 1500 tokens paid in 2 batches, got %v after %d", token.balances["merchant"], token.batches)
+	}
+}
@@ -0,0 +1,23 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"log"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/subscription-billing/chaincode-go/chaincode"
+)
+
+func main() {
+	subscriptionChaincode, err := contractapi.NewChaincode(&chaincode.SmartContract{})
+	if err != nil {
+		log.Panicf("Error creating subscription-billing chaincode: %v", err)
+	}
+
+	if err := subscriptionChaincode.Start(); err != nil {
+		log.Panicf("Error starting subscription-billing chaincode: %v", err)
+	}
+}
@@ -0,0 +1,513 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// object names for prefix
+const (
+	transformationPrefix = "transformation"
+	outputIndexPrefix    = "input~output"
+	custodyPrefix        = "custody"
+)
+
+// batch status values
+const (
+	statusActive    = "ACTIVE"
+	statusInTransit = "IN_TRANSIT"
+	statusConsumed  = "CONSUMED"
+	statusRecalled  = "RECALLED"
+)
+
+// maxTraceDepth bounds how many transformation steps a trace or recall will follow
+const maxTraceDepth = 50
+
+// SmartContract provides functions for tracking batches of goods through a supply chain
+type SmartContract struct {
+	contractapi.Contract
+}
+
+// Batch is a lot of goods that moves between organizations as a single unit
+type Batch struct {
+	ObjectType   string   `json:"objectType"`
+	ID           string   `json:"batchID"`
+	Product      string   `json:"product"`
+	Quantity     int      `json:"quantity"`
+	Unit         string   `json:"unit"`
+	OriginOrg    string   `json:"originOrg"`
+	CustodianOrg string   `json:"custodianOrg"`
+	PendingOrg   string   `json:"pendingOrg,omitempty"`
+	Status       string   `json:"status"`
+	Inputs       []string `json:"inputs,omitempty"`
+	RecallReason string   `json:"recallReason,omitempty"`
+	CreatedAt    string   `json:"createdAt"`
+	// CustodySeq is the sequence number of the latest custody record of the batch
+	CustodySeq int `json:"custodySeq"`
+}
+
+// OutputSpec describes a batch produced by a transformation
+type OutputSpec struct {
+	ID       string `json:"batchID"`
+	Product  string `json:"product"`
+	Quantity int    `json:"quantity"`
+	Unit     string `json:"unit"`
+}
+
+// Transformation records which input batches were consumed to produce which outputs
+type Transformation struct {
+	ObjectType string   `json:"objectType"`
+	ID         string   `json:"transformationID"`
+	Org        string   `json:"org"`
+	Process    string   `json:"process"`
+	Inputs     []string `json:"inputs"`
+	Outputs    []string `json:"outputs"`
+	TxID       string   `json:"txID"`
+	Timestamp  string   `json:"timestamp"`
+}
+
+// CustodyRecord is one link of the chain of custody of a batch, signed off by the client handing
+// the batch over and the client accepting it, each in its own transaction. Record 0 is the creation
+// of the batch, accepted by the creating client with nobody handing it over. A record is pending
+// until the receiving client accepts it.
+type CustodyRecord struct {
+	ObjectType   string `json:"objectType"`
+	BatchID      string `json:"batchID"`
+	Seq          int    `json:"seq"`
+	FromOrg      string `json:"fromOrg,omitempty"`
+	FromClient   string `json:"fromClient,omitempty"`
+	SentTxID     string `json:"sentTxID,omitempty"`
+	SentAt       string `json:"sentAt,omitempty"`
+	ToOrg        string `json:"toOrg"`
+	ToClient     string `json:"toClient,omitempty"`
+	AcceptedTxID string `json:"acceptedTxID,omitempty"`
+	AcceptedAt   string `json:"acceptedAt,omitempty"`
+}
+
+// custodyEvent is emitted whenever a batch changes hands
+type custodyEvent struct {
+	BatchID string `json:"batchID"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+}
+
+// CreateBatch registers a new batch at its origin, owned by the client's org
+func (s *SmartContract) CreateBatch(ctx contractapi.TransactionContextInterface, batchID string, product string, quantity int, unit string) error {
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+
+	err = _validateOutput(OutputSpec{batchID, product, quantity, unit})
+	if err != nil {
+		return err
+	}
+
+	exists, err := s.BatchExists(ctx, batchID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("the batch %s already exists", batchID)
+	}
+
+	timestamp, err := _txTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+
+	batch := Batch{
+		ObjectType:   "batch",
+		ID:           batchID,
+		Product:      product,
+		Quantity:     quantity,
+		Unit:         unit,
+		OriginOrg:    clientOrgID,
+		CustodianOrg: clientOrgID,
+		Status:       statusActive,
+		CreatedAt:    timestamp,
+	}
+
+	err = _putBatch(ctx, &batch)
+	if err != nil {
+		return err
+	}
+	return _putCreationRecord(ctx, &batch)
+}
+
+// Transform consumes input batches held by the client's org and produces new output batches,
+// e.g. milling grain lots into flour or splitting a pallet into cases.
+func (s *SmartContract) Transform(ctx contractapi.TransactionContextInterface, transformationID string, process string, inputIDs []string, outputs []OutputSpec) error {
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+
+	if len(inputIDs) == 0 || len(outputs) == 0 {
+		return fmt.Errorf("a transformation needs at least one input and one output")
+	}
+
+	transformationKey, err := ctx.GetStub().CreateCompositeKey(transformationPrefix, []string{transformationID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	existing, err := ctx.GetStub().GetState(transformationKey)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if existing != nil {
+		return fmt.Errorf("the transformation %s already exists", transformationID)
+	}
+
+	seen := make(map[string]bool)
+	for _, inputID := range inputIDs {
+		if seen[inputID] {
+			return fmt.Errorf("input batch %s is listed more than once", inputID)
+		}
+		seen[inputID] = true
+
+		input, err := s.ReadBatch(ctx, inputID)
+		if err != nil {
+			return err
+		}
+		if input.CustodianOrg != clientOrgID {
+			return fmt.Errorf("a client from %s cannot transform batch %s held by %s", clientOrgID, inputID, input.CustodianOrg)
+		}
+		if input.Status != statusActive {
+			return fmt.Errorf("batch %s is %s and cannot be transformed", inputID, input.Status)
+		}
+
+		input.Status = statusConsumed
+		err = _putBatch(ctx, input)
+		if err != nil {
+			return err
+		}
+	}
+
+	timestamp, err := _txTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+
+	var outputIDs []string
+	for _, output := range outputs {
+		err = _validateOutput(output)
+		if err != nil {
+			return err
+		}
+		if seen[output.ID] {
+			return fmt.Errorf("batch %s is listed more than once", output.ID)
+		}
+		seen[output.ID] = true
+
+		exists, err := s.BatchExists(ctx, output.ID)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return fmt.Errorf("the batch %s already exists", output.ID)
+		}
+
+		batch := Batch{
+			ObjectType:   "batch",
+			ID:           output.ID,
+			Product:      output.Product,
+			Quantity:     output.Quantity,
+			Unit:         output.Unit,
+			OriginOrg:    clientOrgID,
+			CustodianOrg: clientOrgID,
+			Status:       statusActive,
+			Inputs:       inputIDs,
+			CreatedAt:    timestamp,
+		}
+		err = _putBatch(ctx, &batch)
+		if err != nil {
+			return err
+		}
+		err = _putCreationRecord(ctx, &batch)
+		if err != nil {
+			return err
+		}
+
+		// index every input -> output edge so recalls can be traced forward without a rich query
+		for _, inputID := range inputIDs {
+			indexKey, err := ctx.GetStub().CreateCompositeKey(outputIndexPrefix, []string{inputID, output.ID})
+			if err != nil {
+				return fmt.Errorf("failed to create composite key: %v", err)
+			}
+			err = ctx.GetStub().PutState(indexKey, []byte{0x00})
+			if err != nil {
+				return fmt.Errorf("failed to put index for %s: %v", output.ID, err)
+			}
+		}
+		outputIDs = append(outputIDs, output.ID)
+	}
+
+	transformation := Transformation{
+		ObjectType: transformationPrefix,
+		ID:         transformationID,
+		Org:        clientOrgID,
+		Process:    process,
+		Inputs:     inputIDs,
+		Outputs:    outputIDs,
+		TxID:       ctx.GetStub().GetTxID(),
+		Timestamp:  timestamp,
+	}
+	transformationJSON, err := json.Marshal(transformation)
+	if err != nil {
+		return fmt.Errorf("failed to marshal transformation: %v", err)
+	}
+	err = ctx.GetStub().PutState(transformationKey, transformationJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put transformation %s: %v", transformationID, err)
+	}
+
+	return ctx.GetStub().SetEvent("Transformation", transformationJSON)
+}
+
+// TransferCustody hands a batch to another org. The batch stays in transit until the
+// receiving org accepts it with AcceptCustody. The handing client's sign-off is the first half of
+// the batch's next custody record.
+func (s *SmartContract) TransferCustody(ctx contractapi.TransactionContextInterface, batchID string, toOrg string) error {
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	batch, err := s.ReadBatch(ctx, batchID)
+	if err != nil {
+		return err
+	}
+	if batch.CustodianOrg != clientOrgID {
+		return fmt.Errorf("a client from %s cannot hand over batch %s held by %s", clientOrgID, batchID, batch.CustodianOrg)
+	}
+	if batch.Status != statusActive {
+		return fmt.Errorf("batch %s is %s and cannot be handed over", batchID, batch.Status)
+	}
+	if toOrg == "" || toOrg == clientOrgID {
+		return fmt.Errorf("receiving org must be set and differ from the current custodian")
+	}
+
+	timestamp, err := _txTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+	batch.PendingOrg = toOrg
+	batch.Status = statusInTransit
+	batch.CustodySeq++
+	err = _putBatch(ctx, batch)
+	if err != nil {
+		return err
+	}
+	return _putCustodyRecord(ctx, &CustodyRecord{
+		ObjectType: custodyPrefix,
+		BatchID:    batchID,
+		Seq:        batch.CustodySeq,
+		FromOrg:    clientOrgID,
+		FromClient: clientID,
+		SentTxID:   ctx.GetStub().GetTxID(),
+		SentAt:     timestamp,
+		ToOrg:      toOrg,
+	})
+}
+
+// AcceptCustody is called by the receiving org to confirm it has taken delivery of a batch. The
+// accepting client's sign-off completes the custody record the handing client started.
+func (s *SmartContract) AcceptCustody(ctx contractapi.TransactionContextInterface, batchID string) error {
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	batch, err := s.ReadBatch(ctx, batchID)
+	if err != nil {
+		return err
+	}
+	if batch.Status != statusInTransit || batch.PendingOrg != clientOrgID {
+		return fmt.Errorf("batch %s is not in transit to %s", batchID, clientOrgID)
+	}
+
+	record, err := s.GetCustodyRecord(ctx, batchID, batch.CustodySeq)
+	if err != nil {
+		return err
+	}
+	if record.ToOrg != clientOrgID || record.AcceptedTxID != "" {
+		return fmt.Errorf("custody record %d of batch %s is not awaiting %s", record.Seq, batchID, clientOrgID)
+	}
+	timestamp, err := _txTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+	record.ToClient = clientID
+	record.AcceptedTxID = ctx.GetStub().GetTxID()
+	record.AcceptedAt = timestamp
+	err = _putCustodyRecord(ctx, record)
+	if err != nil {
+		return err
+	}
+
+	previousOrg := batch.CustodianOrg
+	batch.CustodianOrg = clientOrgID
+	batch.PendingOrg = ""
+	batch.Status = statusActive
+	err = _putBatch(ctx, batch)
+	if err != nil {
+		return err
+	}
+
+	custodyJSON, err := json.Marshal(custodyEvent{batchID, previousOrg, clientOrgID})
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	return ctx.GetStub().SetEvent("CustodyTransfer", custodyJSON)
+}
+
+// RecallBatch flags a batch and every batch derived from it as recalled. Only the org that
+// produced the batch or its current custodian can raise a recall.
+func (s *SmartContract) RecallBatch(ctx contractapi.TransactionContextInterface, batchID string, reason string) ([]string, error) {
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get MSPID: %v", err)
+	}
+
+	batch, err := s.ReadBatch(ctx, batchID)
+	if err != nil {
+		return nil, err
+	}
+	if clientOrgID != batch.OriginOrg && clientOrgID != batch.CustodianOrg {
+		return nil, fmt.Errorf("a client from %s is not authorized to recall batch %s", clientOrgID, batchID)
+	}
+	if reason == "" {
+		return nil, fmt.Errorf("a recall reason must be given")
+	}
+
+	affected, err := s.TraceForward(ctx, batchID)
+	if err != nil {
+		return nil, err
+	}
+	affected = append([]*Batch{batch}, affected...)
+
+	var recalled []string
+	for _, b := range affected {
+		if b.Status == statusRecalled {
+			continue
+		}
+		b.Status = statusRecalled
+		b.RecallReason = reason
+		err = _putBatch(ctx, b)
+		if err != nil {
+			return nil, err
+		}
+		recalled = append(recalled, b.ID)
+	}
+
+	recallJSON, err := json.Marshal(map[string]interface{}{"batchID": batchID, "reason": reason, "recalled": recalled})
+	if err != nil {
+		return nil, fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	err = ctx.GetStub().SetEvent("Recall", recallJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set event: %v", err)
+	}
+
+	return recalled, nil
+}
+
+// _validateOutput checks the fields of a new batch
+func _validateOutput(output OutputSpec) error {
+	if output.ID == "" {
+		return fmt.Errorf("batch ID must be set")
+	}
+	if output.Product == "" {
+		return fmt.Errorf("product must be set for batch %s", output.ID)
+	}
+	if output.Quantity <= 0 {
+		return fmt.Errorf("quantity for batch %s must be a positive integer", output.ID)
+	}
+	if output.Unit == "" {
+		return fmt.Errorf("unit must be set for batch %s", output.ID)
+	}
+	return nil
+}
+
+// _putBatch writes the batch to the world state
+func _putBatch(ctx contractapi.TransactionContextInterface, batch *Batch) error {
+	batchJSON, err := json.Marshal(batch)
+	if err != nil {
+		return fmt.Errorf("failed to marshal batch: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(batch.ID, batchJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put batch %s: %v", batch.ID, err)
+	}
+	return nil
+}
+
+// _putCreationRecord writes custody record 0 of a new batch, accepted by the creating client
+func _putCreationRecord(ctx contractapi.TransactionContextInterface, batch *Batch) error {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+	return _putCustodyRecord(ctx, &CustodyRecord{
+		ObjectType:   custodyPrefix,
+		BatchID:      batch.ID,
+		Seq:          0,
+		ToOrg:        batch.OriginOrg,
+		ToClient:     clientID,
+		AcceptedTxID: ctx.GetStub().GetTxID(),
+		AcceptedAt:   batch.CreatedAt,
+	})
+}
+
+// _putCustodyRecord writes a custody record under the custody composite key of its batch and
+// sequence number, zero-padded so the records of a batch are listed in order
+func _putCustodyRecord(ctx contractapi.TransactionContextInterface, record *CustodyRecord) error {
+	recordKey, err := _custodyKey(ctx, record.BatchID, record.Seq)
+	if err != nil {
+		return err
+	}
+	recordJSON, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal custody record: %v", err)
+	}
+	err = ctx.GetStub().PutState(recordKey, recordJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put custody record %d of batch %s: %v", record.Seq, record.BatchID, err)
+	}
+	return nil
+}
+
+// _custodyKey returns the key of a custody record
+func _custodyKey(ctx contractapi.TransactionContextInterface, batchID string, seq int) (string, error) {
+	recordKey, err := ctx.GetStub().CreateCompositeKey(custodyPrefix, []string{batchID, fmt.Sprintf("%08d", seq)})
+	if err != nil {
+		return "", fmt.Errorf("failed to create composite key: %v", err)
+	}
+	return recordKey, nil
+}
+
+// _txTimestamp returns the transaction timestamp, which is the same on every endorsing peer
+func _txTimestamp(ctx contractapi.TransactionContextInterface) (string, error) {
+	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return "", fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	return time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos)).UTC().Format(time.RFC3339), nil
+}
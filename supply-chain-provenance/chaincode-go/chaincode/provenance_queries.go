@@ -0,0 +1,290 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// ReadBatch returns the batch stored in the world state with the given ID
+func (s *SmartContract) ReadBatch(ctx contractapi.TransactionContextInterface, batchID string) (*Batch, error) {
+	batchJSON, err := ctx.GetStub().GetState(batchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if batchJSON == nil {
+		return nil, fmt.Errorf("the batch %s does not exist", batchID)
+	}
+
+	var batch Batch
+	err = json.Unmarshal(batchJSON, &batch)
+	if err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+// BatchExists returns true when a batch with the given ID exists in the world state
+func (s *SmartContract) BatchExists(ctx contractapi.TransactionContextInterface, batchID string) (bool, error) {
+	batchJSON, err := ctx.GetStub().GetState(batchID)
+	if err != nil {
+		return false, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	return batchJSON != nil, nil
+}
+
+// GetTransformation returns the record of a transformation event
+func (s *SmartContract) GetTransformation(ctx contractapi.TransactionContextInterface, transformationID string) (*Transformation, error) {
+	transformationKey, err := ctx.GetStub().CreateCompositeKey(transformationPrefix, []string{transformationID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	transformationJSON, err := ctx.GetStub().GetState(transformationKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if transformationJSON == nil {
+		return nil, fmt.Errorf("the transformation %s does not exist", transformationID)
+	}
+
+	var transformation Transformation
+	err = json.Unmarshal(transformationJSON, &transformation)
+	if err != nil {
+		return nil, err
+	}
+	return &transformation, nil
+}
+
+// TraceBack returns every batch the given batch was made from, walking transformations
+// back towards the original lots
+func (s *SmartContract) TraceBack(ctx contractapi.TransactionContextInterface, batchID string) ([]*Batch, error) {
+	batch, err := s.ReadBatch(ctx, batchID)
+	if err != nil {
+		return nil, err
+	}
+
+	visited := map[string]bool{batchID: true}
+	frontier := batch.Inputs
+	var results []*Batch
+	for depth := 0; len(frontier) > 0; depth++ {
+		if depth >= maxTraceDepth {
+			return nil, fmt.Errorf("trace of batch %s exceeds %d transformation steps", batchID, maxTraceDepth)
+		}
+
+		var next []string
+		for _, id := range frontier {
+			if visited[id] {
+				continue
+			}
+			visited[id] = true
+
+			input, err := s.ReadBatch(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, input)
+			next = append(next, input.Inputs...)
+		}
+		frontier = next
+	}
+
+	return results, nil
+}
+
+// TraceForward returns every batch produced from the given batch, directly or through
+// later transformations. This is the set of goods affected by a recall.
+func (s *SmartContract) TraceForward(ctx contractapi.TransactionContextInterface, batchID string) ([]*Batch, error) {
+	exists, err := s.BatchExists(ctx, batchID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("the batch %s does not exist", batchID)
+	}
+
+	visited := map[string]bool{batchID: true}
+	frontier := []string{batchID}
+	var results []*Batch
+	for depth := 0; len(frontier) > 0; depth++ {
+		if depth >= maxTraceDepth {
+			return nil, fmt.Errorf("trace of batch %s exceeds %d transformation steps", batchID, maxTraceDepth)
+		}
+
+		var next []string
+		for _, id := range frontier {
+			outputIDs, err := _getOutputIDs(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+			for _, outputID := range outputIDs {
+				if visited[outputID] {
+					continue
+				}
+				visited[outputID] = true
+
+				output, err := s.ReadBatch(ctx, outputID)
+				if err != nil {
+					return nil, err
+				}
+				results = append(results, output)
+				next = append(next, outputID)
+			}
+		}
+		frontier = next
+	}
+
+	return results, nil
+}
+
+// _getOutputIDs returns the IDs of batches directly produced from the given input
+func _getOutputIDs(ctx contractapi.TransactionContextInterface, inputID string) ([]string, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(outputIndexPrefix, []string{inputID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get outputs of batch %s: %v", inputID, err)
+	}
+	defer resultsIterator.Close()
+
+	var outputIDs []string
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(response.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split composite key: %v", err)
+		}
+		if len(keyParts) != 2 {
+			return nil, fmt.Errorf("unexpected index key %s", response.Key)
+		}
+		outputIDs = append(outputIDs, keyParts[1])
+	}
+
+	return outputIDs, nil
+}
+
+// GetCustodyRecord returns custody record seq of a batch
+func (s *SmartContract) GetCustodyRecord(ctx contractapi.TransactionContextInterface, batchID string, seq int) (*CustodyRecord, error) {
+	recordKey, err := _custodyKey(ctx, batchID, seq)
+	if err != nil {
+		return nil, err
+	}
+	recordJSON, err := ctx.GetStub().GetState(recordKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if recordJSON == nil {
+		return nil, fmt.Errorf("custody record %d of batch %s does not exist", seq, batchID)
+	}
+
+	var record CustodyRecord
+	err = json.Unmarshal(recordJSON, &record)
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// GetCustodyChain returns the custody records of a batch in order, from its creation to its
+// latest handoff
+func (s *SmartContract) GetCustodyChain(ctx contractapi.TransactionContextInterface, batchID string) ([]*CustodyRecord, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(custodyPrefix, []string{batchID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get custody records of batch %s: %v", batchID, err)
+	}
+	defer resultsIterator.Close()
+
+	records := []*CustodyRecord{}
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var record CustodyRecord
+		err = json.Unmarshal(response.Value, &record)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, &record)
+	}
+
+	return records, nil
+}
+
+// CustodyVerification is returned by VerifyCustodyChain. Problems lists every break found in the
+// chain, and is empty when the chain is valid.
+type CustodyVerification struct {
+	BatchID  string   `json:"batchID"`
+	Valid    bool     `json:"valid"`
+	Handoffs int      `json:"handoffs"`
+	Problems []string `json:"problems"`
+}
+
+// VerifyCustodyChain checks the chain of custody of a batch is unbroken back to its creation: the
+// first record is the creation of the batch by its origin org, every handoff was signed off by a
+// client of the org holding the batch and accepted by a client of the org it was handed to, and the
+// last record leads to the current custodian or, while the batch is in transit, to the pending org.
+// Batches created before custody records were kept have no creation record and fail the check.
+func (s *SmartContract) VerifyCustodyChain(ctx contractapi.TransactionContextInterface, batchID string) (*CustodyVerification, error) {
+	batch, err := s.ReadBatch(ctx, batchID)
+	if err != nil {
+		return nil, err
+	}
+	records, err := s.GetCustodyChain(ctx, batchID)
+	if err != nil {
+		return nil, err
+	}
+
+	problems := []string{}
+	if len(records) != batch.CustodySeq+1 {
+		problems = append(problems, fmt.Sprintf("batch has %d custody records, expected %d", len(records), batch.CustodySeq+1))
+	}
+	for i, record := range records {
+		if record.Seq != i {
+			problems = append(problems, fmt.Sprintf("custody record %d is missing", i))
+			break
+		}
+		if i == 0 {
+			if record.FromOrg != "" || record.ToOrg != batch.OriginOrg {
+				problems = append(problems, fmt.Sprintf("custody record 0 is not the creation of the batch by %s", batch.OriginOrg))
+			}
+		} else {
+			if record.FromOrg != records[i-1].ToOrg {
+				problems = append(problems, fmt.Sprintf("custody record %d hands the batch over from %s, which did not hold it", i, record.FromOrg))
+			}
+			if record.FromClient == "" || record.SentTxID == "" {
+				problems = append(problems, fmt.Sprintf("custody record %d has no sign-off by the sending client", i))
+			}
+		}
+		pending := i == len(records)-1 && batch.PendingOrg != ""
+		if !pending && (record.ToClient == "" || record.AcceptedTxID == "") {
+			problems = append(problems, fmt.Sprintf("custody record %d has no sign-off by the receiving client", i))
+		}
+	}
+
+	if len(records) > 0 {
+		last := records[len(records)-1]
+		if batch.PendingOrg != "" {
+			if len(records) == 1 || last.FromOrg != batch.CustodianOrg || last.ToOrg != batch.PendingOrg || last.AcceptedTxID != "" {
+				problems = append(problems, fmt.Sprintf("last custody record is not the handoff from %s to %s in transit", batch.CustodianOrg, batch.PendingOrg))
+			}
+		} else if last.ToOrg != batch.CustodianOrg {
+			problems = append(problems, fmt.Sprintf("last custody record leads to %s, not the custodian %s", last.ToOrg, batch.CustodianOrg))
+		}
+	}
+
+	return &CustodyVerification{
+		BatchID:  batchID,
+		Valid:    len(problems) == 0,
+		Handoffs: batch.CustodySeq,
+		Problems: problems,
+	}, nil
+}
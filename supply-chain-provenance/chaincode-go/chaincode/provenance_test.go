@@ -0,0 +1,135 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const (
+	farmMSPID   = "Org1MSP"
+	millMSPID   = "Org2MSP"
+	bakeryMSPID = "Org3MSP"
+)
+
+func checkError(t *testing.T, err error, expected string) {
+	t.Helper()
+	if expected == "" && err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected != "" && (err == nil || err.Error() != expected) {
+		t.Fatalf("expected error %q, got %v", expected, err)
+	}
+}
+
+func checkBatch(t *testing.T, stub *fakeStub, batchID string, custodianOrg string, status string) {
+	t.Helper()
+	batch, err := new(SmartContract).ReadBatch(newContext(stub, "auditor", farmMSPID), batchID)
+	if err != nil {
+		t.Fatalf("failed to read batch: %v", err)
+	}
+	if batch.CustodianOrg != custodianOrg || batch.Status != status {
+		t.Fatalf("expected batch %s held by %s with status %s, got %s %s", batchID, custodianOrg, status, batch.CustodianOrg, batch.Status)
+	}
+}
+
+// millGrain creates two grain lots at the farm, hands them to the mill and mills them into flour
+func millGrain(t *testing.T, stub *fakeStub) {
+	t.Helper()
+	contract := new(SmartContract)
+	for _, batchID := range []string{"grain1", "grain2"} {
+		checkError(t, contract.CreateBatch(newContext(stub, "farmer", farmMSPID), batchID, "wheat", 1000, "kg"), "")
+		checkError(t, contract.TransferCustody(newContext(stub, "farmer", farmMSPID), batchID, millMSPID), "")
+		checkError(t, contract.AcceptCustody(newContext(stub, "miller", millMSPID), batchID), "")
+	}
+	outputs := []OutputSpec{{"flour1", "flour", 1500, "kg"}, {"bran1", "bran", 400, "kg"}}
+	checkError(t, contract.Transform(newContext(stub, "miller", millMSPID), "milling1", "milling", []string{"grain1", "grain2"}, outputs), "")
+}
+
+func TestCustody(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+
+	checkError(t, contract.CreateBatch(newContext(stub, "farmer", farmMSPID), "grain1", "wheat", 1000, "kg"), "")
+	checkError(t, contract.CreateBatch(newContext(stub, "farmer", farmMSPID), "grain1", "wheat", 1000, "kg"), "the batch grain1 already exists")
+	checkError(t, contract.CreateBatch(newContext(stub, "farmer", farmMSPID), "grain2", "wheat", 0, "kg"), "quantity for batch grain2 must be a positive integer")
+
+	err := contract.TransferCustody(newContext(stub, "miller", millMSPID), "grain1", millMSPID)
+	checkError(t, err, "a client from Org2MSP cannot hand over batch grain1 held by Org1MSP")
+
+	checkError(t, contract.TransferCustody(newContext(stub, "farmer", farmMSPID), "grain1", millMSPID), "")
+	checkBatch(t, stub, "grain1", farmMSPID, statusInTransit)
+
+	err = contract.AcceptCustody(newContext(stub, "baker", bakeryMSPID), "grain1")
+	checkError(t, err, "batch grain1 is not in transit to Org3MSP")
+
+	checkError(t, contract.AcceptCustody(newContext(stub, "miller", millMSPID), "grain1"), "")
+	checkBatch(t, stub, "grain1", millMSPID, statusActive)
+
+	if stub.eventName != "CustodyTransfer" {
+		t.Fatalf("expected CustodyTransfer event, got %q", stub.eventName)
+	}
+	var custody custodyEvent
+	err = json.Unmarshal(stub.eventValue, &custody)
+	if err != nil {
+		t.Fatalf("failed to unmarshal event: %v", err)
+	}
+	if custody != (custodyEvent{"grain1", farmMSPID, millMSPID}) {
+		t.Fatalf("unexpected event %+v", custody)
+	}
+}
+
+func TestTransform(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+
+	checkError(t, contract.CreateBatch(newContext(stub, "farmer", farmMSPID), "grain1", "wheat", 1000, "kg"), "")
+	outputs := []OutputSpec{{"flour1", "flour", 800, "kg"}}
+	err := contract.Transform(newContext(stub, "miller", millMSPID), "milling1", "milling", []string{"grain1"}, outputs)
+	checkError(t, err, "a client from Org2MSP cannot transform batch grain1 held by Org1MSP")
+
+	stub = newFakeStub()
+	millGrain(t, stub)
+	checkBatch(t, stub, "grain1", millMSPID, statusConsumed)
+	checkBatch(t, stub, "flour1", millMSPID, statusActive)
+
+	err = contract.Transform(newContext(stub, "miller", millMSPID), "milling2", "milling", []string{"grain1"}, outputs)
+	checkError(t, err, "batch grain1 is CONSUMED and cannot be transformed")
+
+	transformation, err := contract.GetTransformation(newContext(stub, "auditor", farmMSPID), "milling1")
+	checkError(t, err, "")
+	if len(transformation.Inputs) != 2 || len(transformation.Outputs) != 2 || transformation.Org != millMSPID {
+		t.Fatalf("unexpected transformation %+v", transformation)
+	}
+
+	origins, err := contract.TraceBack(newContext(stub, "auditor", farmMSPID), "flour1")
+	checkError(t, err, "")
+	if len(origins) != 2 || origins[0].ID != "grain1" || origins[1].ID != "grain2" {
+		t.Fatalf("unexpected trace back %+v", origins)
+	}
+}
+
+func TestRecallBatch(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	millGrain(t, stub)
+
+	checkError(t, contract.CreateBatch(newContext(stub, "farmer", farmMSPID), "grain3", "wheat", 1000, "kg"), "")
+
+	_, err := contract.RecallBatch(newContext(stub, "baker", bakeryMSPID), "grain1", "mycotoxin")
+	checkError(t, err, "a client from Org3MSP is not authorized to recall batch grain1")
+
+	_, err = contract.RecallBatch(newContext(stub, "farmer", farmMSPID), "grain1", "")
+	checkError(t, err, "a recall reason must be given")
+
+	recalled, err := contract.RecallBatch(newContext(stub, "farmer", farmMSPID), "grain1", "mycotoxin")
+	checkError(t, err, "")
+	if len(recalled) != 3 || recalled[0] != "grain1" || recalled[1] != "bran1" || recalled[2] != "flour1" {
+		t.Fatalf("unexpected recalled batches %v", recalled)
+	}
+	checkBatch(t, stub, "flour1", millMSPID, statusRecalled)
+	checkBatch(t, stub, "grain2", millMSPID, statusConsumed)
+	checkBatch(t, stub, "grain3", farmMSPID, statusActive)
+	if stub.eventName != "Recall" {
+		t.Fatalf("expected Recall event, got %q", stub.eventName)
+	}
+}
@@ -0,0 +1,23 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"log"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/supply-chain-provenance/chaincode-go/chaincode"
+)
+
+func main() {
+	provenanceChaincode, err := contractapi.NewChaincode(&chaincode.SmartContract{})
+	if err != nil {
+		log.Panicf("Error creating supply-chain-provenance chaincode: %v", err)
+	}
+
+	if err := provenanceChaincode.Start(); err != nil {
+		log.Panicf("Error starting supply-chain-provenance chaincode: %v", err)
+	}
+}
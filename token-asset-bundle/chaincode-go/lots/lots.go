@@ -0,0 +1,276 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package lots sells several assets of the asset contract as one lot paid in tokens of the token
+// contract, delivery versus payment in one transaction of the bundle chaincode
+package lots
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-contract-api-go/metadata"
+	asset "github.com/hyperledger/fabric-samples/chaincode/tradingMarbles/chaincode"
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil"
+	token "github.com/hyperledger/fabric-samples/token-erc-20/chaincode-go/chaincode"
+)
+
+// bundlePrefix is the prefix of the lots in the world state
+const bundlePrefix = "bundle"
+
+// maxBundleAssets bounds the assets of a lot, which are all locked and transferred in one transaction
+const maxBundleAssets = 50
+
+// lot status values
+const (
+	bundleOpen      = "OPEN"
+	bundleSold      = "SOLD"
+	bundleCancelled = "CANCELLED"
+)
+
+// Bundle is a lot of assets of one org for sale at one price in tokens, paid to the account of the
+// client that created it. Its assets are locked while it is OPEN and move to the buyer org when it
+// is SOLD. The record is kept once sold or cancelled.
+type Bundle struct {
+	ObjectType  string    `json:"objectType"`
+	ID          string    `json:"bundleID"`
+	AssetIDs    []string  `json:"assetIDs"`
+	Price       int       `json:"price"`
+	SellerOrg   string    `json:"sellerOrg"`
+	Seller      string    `json:"seller"`
+	Status      string    `json:"status"`
+	Buyer       string    `json:"buyer,omitempty" metadata:",optional"`
+	BuyerOrg    string    `json:"buyerOrg,omitempty" metadata:",optional"`
+	TxID        string    `json:"txID"`
+	Timestamp   time.Time `json:"timestamp"`
+	SettledTxID string    `json:"settledTxID,omitempty" metadata:",optional"`
+}
+
+// Contract sells lots of assets against tokens. It calls the token and asset contracts of the
+// bundle in the same transaction, so the payment and the transfer of every asset commit together
+// or not at all.
+type Contract struct {
+	contractapi.Contract
+	token *token.SmartContract
+}
+
+// NewContract returns the lots contract named "lots", paying with tokenContract
+func NewContract(tokenContract *token.SmartContract) *Contract {
+	lotsContract := &Contract{token: tokenContract}
+	lotsContract.Name = "lots"
+	lotsContract.Info = metadata.InfoMetadata{
+		Title:       "Asset lots",
+		Description: "Lots of assets sold as a whole against tokens, delivery versus payment in one transaction",
+		Version:     "1.0.0",
+		License:     &metadata.LicenseMetadata{Name: "Apache-2.0"},
+	}
+	lotsContract.TransactionContextHandler = new(ledgerutil.TransactionContext)
+	lotsContract.BeforeTransaction = ledgerutil.BeforeTransaction(lotsContract.GetEvaluateTransactions())
+	lotsContract.UnknownTransaction = ledgerutil.UnknownTransaction(lotsContract)
+	return lotsContract
+}
+
+// GetEvaluateTransactions lists the read-only functions of the lots contract
+func (c *Contract) GetEvaluateTransactions() []string {
+	return []string{"GetBundle"}
+}
+
+// CreateBundle puts assets of the client's org up for sale as one lot at price tokens, paid to the
+// client's token account. The assets are locked, so they cannot be transferred or put in another
+// lot, until the lot is sold or cancelled. Only clients of the org owning every asset may create it.
+func (c *Contract) CreateBundle(ctx ledgerutil.TransactionContextInterface, bundleID string, assetIDs []string, price int) (*Bundle, error) {
+	bundleID = ledgerutil.NormalizeID(bundleID)
+	v := ledgerutil.NewValidator()
+	v.Key("bundleID", bundleID)
+	v.PositiveAmount("price", price)
+	seen := make(map[string]bool)
+	for i := range assetIDs {
+		assetIDs[i] = ledgerutil.NormalizeID(assetIDs[i])
+		v.Key("assetIDs", assetIDs[i])
+		if seen[assetIDs[i]] {
+			return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "invalid arguments: asset %s is listed twice", assetIDs[i])
+		}
+		seen[assetIDs[i]] = true
+	}
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	if len(assetIDs) == 0 || len(assetIDs) > maxBundleAssets {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "invalid arguments: a lot has 1 to %d assets, not %d", maxBundleAssets, len(assetIDs))
+	}
+
+	key, err := _bundleKey(ctx, bundleID)
+	if err != nil {
+		return nil, err
+	}
+	var existing Bundle
+	found, err := ledgerutil.ReadJSON(ctx.GetStub(), key, &existing)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "invalid arguments: lot %s already exists", bundleID)
+	}
+	for _, assetID := range assetIDs {
+		_, err := asset.LockAsset(ctx, assetID, bundleID)
+		if err != nil {
+			return nil, ledgerutil.Wrap(err, "failed to lock asset %s", assetID)
+		}
+	}
+
+	txID, timestamp, err := ledgerutil.TxInfo(ctx.GetStub())
+	if err != nil {
+		return nil, err
+	}
+	bundle := &Bundle{
+		ObjectType: bundlePrefix,
+		ID:         bundleID,
+		AssetIDs:   assetIDs,
+		Price:      price,
+		SellerOrg:  ctx.GetClientMSPID(),
+		Seller:     ctx.GetClientID(),
+		Status:     bundleOpen,
+		TxID:       txID,
+		Timestamp:  timestamp,
+	}
+	return bundle, _putBundle(ctx, bundle)
+}
+
+// BuyBundle buys an open lot for the client's org: the price moves from the client's token account
+// to the seller's and every asset of the lot to the client's org in the same transaction. The seller
+// shares the asset properties with the buyer, who passes them in the transient map as
+// asset_properties, a JSON object of the properties of each asset keyed by asset ID, checked
+// against their on-chain hashes. The event of the transaction is the Transfer event of the payment.
+// The asset endorsement policies need a peer of the seller org to endorse.
+func (c *Contract) BuyBundle(ctx ledgerutil.TransactionContextInterface, bundleID string) (*Bundle, error) {
+	bundle, err := c.GetBundle(ctx, bundleID)
+	if err != nil {
+		return nil, err
+	}
+	if bundle.Status != bundleOpen {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "lot %s is %s", bundle.ID, bundle.Status)
+	}
+	buyerOrg := ctx.GetClientMSPID()
+	if buyerOrg == bundle.SellerOrg {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "invalid arguments: lot %s is sold by the client's org %s", bundle.ID, buyerOrg)
+	}
+	properties, err := _getBundleProperties(ctx, bundle)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = c.token.Transfer(ctx, bundle.Seller, bundle.Price)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to pay for lot %s", bundle.ID)
+	}
+	for _, assetID := range bundle.AssetIDs {
+		_, err := asset.SettleLockedAsset(ctx, assetID, bundle.ID, buyerOrg, properties[assetID])
+		if err != nil {
+			return nil, ledgerutil.Wrap(err, "failed to transfer asset %s", assetID)
+		}
+	}
+
+	bundle.Status = bundleSold
+	bundle.Buyer = ctx.GetClientID()
+	bundle.BuyerOrg = buyerOrg
+	bundle.SettledTxID = ctx.GetStub().GetTxID()
+	return bundle, _putBundle(ctx, bundle)
+}
+
+// CancelBundle withdraws an open lot and unlocks its assets, which stay with the seller org. Only
+// clients of the seller org may cancel it.
+func (c *Contract) CancelBundle(ctx ledgerutil.TransactionContextInterface, bundleID string) (*Bundle, error) {
+	bundle, err := c.GetBundle(ctx, bundleID)
+	if err != nil {
+		return nil, err
+	}
+	if identity := ctx.GetIdentity(); !identity.InOrg(bundle.SellerOrg) {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeNotAuthorized, "a client from %s cannot cancel lot %s of %s", identity.MSPID, bundle.ID, bundle.SellerOrg)
+	}
+	if bundle.Status != bundleOpen {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "lot %s is %s", bundle.ID, bundle.Status)
+	}
+	for _, assetID := range bundle.AssetIDs {
+		err := asset.UnlockAsset(ctx, assetID, bundle.ID)
+		if err != nil {
+			return nil, ledgerutil.Wrap(err, "failed to unlock asset %s", assetID)
+		}
+	}
+	bundle.Status = bundleCancelled
+	bundle.SettledTxID = ctx.GetStub().GetTxID()
+	return bundle, _putBundle(ctx, bundle)
+}
+
+// GetBundle returns a lot
+func (c *Contract) GetBundle(ctx ledgerutil.TransactionContextInterface, bundleID string) (*Bundle, error) {
+	bundleID = ledgerutil.NormalizeID(bundleID)
+	v := ledgerutil.NewValidator()
+	v.Key("bundleID", bundleID)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	key, err := _bundleKey(ctx, bundleID)
+	if err != nil {
+		return nil, err
+	}
+	var bundle Bundle
+	found, err := ledgerutil.ReadJSON(ctx.GetStub(), key, &bundle)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeNotFound, "lot %s does not exist", bundleID)
+	}
+	return &bundle, nil
+}
+
+// _getBundleProperties returns the asset properties passed in the transient map for every asset of
+// the lot, each as the exact bytes the client passed so they hash as the stored properties do
+func _getBundleProperties(ctx ledgerutil.TransactionContextInterface, bundle *Bundle) (map[string][]byte, error) {
+	transientMap, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "error getting transient")
+	}
+	propertiesJSON, ok := transientMap["asset_properties"]
+	if !ok {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "asset_properties key not found in the transient map")
+	}
+	var raw map[string]json.RawMessage
+	err = json.Unmarshal(propertiesJSON, &raw)
+	if err != nil {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "invalid arguments: asset_properties is not a JSON object of the properties of each asset: %v", err)
+	}
+	properties := make(map[string][]byte, len(raw))
+	for _, assetID := range bundle.AssetIDs {
+		value, ok := raw[assetID]
+		if !ok {
+			return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "invalid arguments: asset_properties has no properties of asset %s", assetID)
+		}
+		properties[assetID] = value
+	}
+	return properties, nil
+}
+
+// _putBundle writes a lot to the world state
+func _putBundle(ctx ledgerutil.TransactionContextInterface, bundle *Bundle) error {
+	key, err := _bundleKey(ctx, bundle.ID)
+	if err != nil {
+		return err
+	}
+	err = ledgerutil.PutJSON(ctx.GetStub(), key, bundle)
+	if err != nil {
+		return ledgerutil.Wrap(err, "failed to put lot")
+	}
+	return nil
+}
+
+// _bundleKey returns the world state key of a lot
+func _bundleKey(ctx ledgerutil.TransactionContextInterface, bundleID string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(bundlePrefix, []string{bundleID})
+	if err != nil {
+		return "", ledgerutil.Wrap(err, "failed to create composite key for lot")
+	}
+	return key, nil
+}
@@ -0,0 +1,255 @@
+package lots
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+	asset "github.com/hyperledger/fabric-samples/chaincode/tradingMarbles/chaincode"
+	"github.com/hyperledger/fabric-samples/pkg/mockledger"
+	token "github.com/hyperledger/fabric-samples/token-erc-20/chaincode-go/chaincode"
+)
+
+const bundleName = "bundle"
+
+// allowAll is an access-control chaincode granting every operation
+type allowAll struct{}
+
+func (allowAll) Init(stub shim.ChaincodeStubInterface) pb.Response {
+	return shim.Success(nil)
+}
+
+func (allowAll) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
+	return shim.Success([]byte("true"))
+}
+
+// market is a mock ledger with the bundle chaincode deployed, a seller of Org1MSP owning asset1
+// and asset2 and a buyer of Org2MSP holding tokens
+type market struct {
+	ledger *mockledger.Ledger
+	seller *mockledger.Client
+	buyer  *mockledger.Client
+	ids    map[*mockledger.Client]string
+}
+
+func newMarket(t *testing.T) *market {
+	t.Helper()
+	ledger := mockledger.New()
+	tokenContract := token.NewContract()
+	bundle, err := contractapi.NewChaincode(tokenContract, asset.NewContract(), NewContract(tokenContract), token.NewConfigContract())
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundle.DefaultContract = tokenContract.GetName()
+	if err := ledger.Deploy("acl", allowAll{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ledger.Deploy(bundleName, bundle); err != nil {
+		t.Fatal(err)
+	}
+	m := &market{ledger: ledger, ids: make(map[*mockledger.Client]string)}
+	m.seller = m.client(t, "Org1MSP", "seller", 0)
+	m.buyer = m.client(t, "Org2MSP", "buyer", 1000)
+
+	// the asset contract writes the private data of the org of its peer
+	t.Setenv("CORE_PEER_LOCALMSPID", "Org1MSP")
+	for _, assetID := range []string{"asset1", "asset2"} {
+		_, err := ledger.Submit(m.seller, mockledger.Transaction{Chaincode: bundleName, Function: "asset:CreateAsset",
+			Args: []string{assetID, "an asset of Org1MSP"}, Transient: map[string][]byte{"asset_properties": properties(assetID)}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return m
+}
+
+// client enrolls a client of an org and funds its token account with balance
+func (m *market) client(t *testing.T, mspID string, name string, balance int) *mockledger.Client {
+	t.Helper()
+	client, err := m.ledger.NewClient(mspID, name, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := m.ledger.Evaluate(client, mockledger.Transaction{Chaincode: bundleName, Function: "ClientAccountID"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.ids[client] = string(id)
+
+	stub, err := m.ledger.NewStub(nil, mockledger.Transaction{Chaincode: bundleName})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stub.PutState(string(id), []byte(strconv.Itoa(balance))); err != nil {
+		t.Fatal(err)
+	}
+	if err := stub.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+// properties are the private properties of an asset, as its owner shares them with a buyer
+func properties(assetID string) []byte {
+	return []byte(`{"object_type":"asset_properties","asset_id":"` + assetID + `","color":"blue","salt":"a94a8fe5"}`)
+}
+
+// lotProperties is the asset_properties transient value of buying a lot of asset1 and asset2
+func lotProperties() map[string][]byte {
+	return map[string][]byte{"asset_properties": []byte(`{"asset1":` + string(properties("asset1")) + `,"asset2":` + string(properties("asset2")) + `}`)}
+}
+
+func (m *market) submit(client *mockledger.Client, function string, transient map[string][]byte, args ...string) (*Bundle, error) {
+	result, err := m.ledger.Submit(client, mockledger.Transaction{Chaincode: bundleName, Function: "lots:" + function, Args: args, Transient: transient})
+	if err != nil {
+		return nil, err
+	}
+	var bundle Bundle
+	if err := json.Unmarshal(result.Payload, &bundle); err != nil {
+		return nil, err
+	}
+	return &bundle, nil
+}
+
+func (m *market) getBundle(t *testing.T, bundleID string) *Bundle {
+	t.Helper()
+	payload, err := m.ledger.Evaluate(m.buyer, mockledger.Transaction{Chaincode: bundleName, Function: "lots:GetBundle", Args: []string{bundleID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var bundle Bundle
+	if err := json.Unmarshal(payload, &bundle); err != nil {
+		t.Fatal(err)
+	}
+	return &bundle
+}
+
+func (m *market) checkOwner(t *testing.T, assetID string, want string) {
+	t.Helper()
+	payload, err := m.ledger.Evaluate(m.buyer, mockledger.Transaction{Chaincode: bundleName, Function: "asset:ReadAsset", Args: []string{assetID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var a asset.Asset
+	if err := json.Unmarshal(payload, &a); err != nil {
+		t.Fatal(err)
+	}
+	if a.OwnerOrg != want {
+		t.Errorf("asset %s is owned by %s, want %s", assetID, a.OwnerOrg, want)
+	}
+}
+
+func (m *market) checkBalances(t *testing.T, want map[*mockledger.Client]int) {
+	t.Helper()
+	for client, balance := range want {
+		got := string(m.ledger.GetState(bundleName, m.ids[client]))
+		if got != strconv.Itoa(balance) {
+			t.Errorf("balance of %s is %s, want %d", client.MSPID, got, balance)
+		}
+	}
+}
+
+func checkError(t *testing.T, err error, want string) {
+	t.Helper()
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Fatalf("error is %v, want %q", err, want)
+	}
+}
+
+func TestBuyBundle(t *testing.T) {
+	m := newMarket(t)
+	bundle, err := m.submit(m.seller, "CreateBundle", nil, "lot1", `["asset1","asset2"]`, "250")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bundle.Status != bundleOpen || bundle.SellerOrg != "Org1MSP" || bundle.Seller != m.ids[m.seller] {
+		t.Errorf("created lot is %+v", bundle)
+	}
+
+	// the assets of an open lot are locked
+	_, err = m.submit(m.seller, "CreateBundle", nil, "lot2", `["asset2"]`, "100")
+	checkError(t, err, "failed to lock asset asset2")
+	_, err = m.submit(m.seller, "BuyBundle", lotProperties(), "lot1")
+	checkError(t, err, "lot lot1 is sold by the client's org Org1MSP")
+	_, err = m.submit(m.buyer, "BuyBundle", nil, "lot1")
+	checkError(t, err, "asset_properties key not found in the transient map")
+
+	// the payment and every asset move in one transaction
+	bundle, err = m.submit(m.buyer, "BuyBundle", lotProperties(), "lot1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bundle.Status != bundleSold || bundle.Buyer != m.ids[m.buyer] || bundle.BuyerOrg != "Org2MSP" || bundle.SettledTxID == "" {
+		t.Errorf("sold lot is %+v", bundle)
+	}
+	m.checkBalances(t, map[*mockledger.Client]int{m.seller: 250, m.buyer: 750})
+	m.checkOwner(t, "asset1", "Org2MSP")
+	m.checkOwner(t, "asset2", "Org2MSP")
+
+	_, err = m.submit(m.buyer, "BuyBundle", lotProperties(), "lot1")
+	checkError(t, err, "lot lot1 is SOLD")
+	if got := m.getBundle(t, " lot1 "); got.Status != bundleSold || got.SettledTxID != bundle.SettledTxID {
+		t.Errorf("GetBundle returned %+v", got)
+	}
+}
+
+func TestBuyBundleInsufficientFunds(t *testing.T) {
+	m := newMarket(t)
+	_, err := m.submit(m.seller, "CreateBundle", nil, "lot1", `["asset1","asset2"]`, "1001")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a failed payment commits nothing, the lot stays open and the assets with the seller
+	_, err = m.submit(m.buyer, "BuyBundle", lotProperties(), "lot1")
+	checkError(t, err, "failed to pay for lot lot1")
+	m.checkBalances(t, map[*mockledger.Client]int{m.seller: 0, m.buyer: 1000})
+	m.checkOwner(t, "asset1", "Org1MSP")
+	if got := m.getBundle(t, "lot1"); got.Status != bundleOpen {
+		t.Errorf("lot is %s after a failed purchase, want OPEN", got.Status)
+	}
+}
+
+func TestCancelBundle(t *testing.T) {
+	m := newMarket(t)
+	_, err := m.submit(m.seller, "CreateBundle", nil, "lot1", `["asset1","asset2"]`, "250")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// only the seller org may cancel a lot
+	_, err = m.submit(m.buyer, "CancelBundle", nil, "lot1")
+	checkError(t, err, "a client from Org2MSP cannot cancel lot lot1 of Org1MSP")
+
+	bundle, err := m.submit(m.seller, "CancelBundle", nil, "lot1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bundle.Status != bundleCancelled || bundle.SettledTxID == "" {
+		t.Errorf("cancelled lot is %+v", bundle)
+	}
+	_, err = m.submit(m.buyer, "BuyBundle", lotProperties(), "lot1")
+	checkError(t, err, "lot lot1 is CANCELLED")
+	_, err = m.submit(m.seller, "CancelBundle", nil, "lot1")
+	checkError(t, err, "lot lot1 is CANCELLED")
+
+	// the unlocked assets can be put in another lot
+	_, err = m.submit(m.seller, "CreateBundle", nil, "lot2", `["asset1"]`, "100")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.checkOwner(t, "asset1", "Org1MSP")
+	m.checkBalances(t, map[*mockledger.Client]int{m.seller: 0, m.buyer: 1000})
+}
+
+func TestGetBundle(t *testing.T) {
+	m := newMarket(t)
+	_, err := m.ledger.Evaluate(m.buyer, mockledger.Transaction{Chaincode: bundleName, Function: "lots:GetBundle", Args: []string{"lot1"}})
+	checkError(t, err, "lot lot1 does not exist")
+	_, err = m.ledger.Evaluate(m.buyer, mockledger.Transaction{Chaincode: bundleName, Function: "lots:GetBundle", Args: []string{" "}})
+	checkError(t, err, "bundleID must be set")
+}
@@ -0,0 +1,47 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// The bundle chaincode registers the token and secured agreement asset contracts in one chaincode,
+// so a single-channel deployment can change balances and assets in the same transaction.
+package main
+
+import (
+	"log"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-contract-api-go/metadata"
+	asset "github.com/hyperledger/fabric-samples/chaincode/tradingMarbles/chaincode"
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil"
+	"github.com/hyperledger/fabric-samples/token-asset-bundle/chaincode-go/lots"
+	token "github.com/hyperledger/fabric-samples/token-erc-20/chaincode-go/chaincode"
+)
+
+func main() {
+	tokenContract := token.NewContract()
+	erc20Contract := token.NewERC20Contract(tokenContract)
+	assetContract := asset.NewContract()
+	assetQueryContract := asset.NewQueryContract()
+	// sells lots of assets against tokens, calling both contracts in the same transaction
+	lotsContract := lots.NewContract(tokenContract)
+	// the contracts share one world state and so one config contract, the asset contract reads no
+	// parameters besides the query limits the token config contract holds as well
+	configContract := token.NewConfigContract()
+	// one events contract holds the schemas of the events of both contracts
+	eventsContract := token.NewEventsContract(asset.EventSchemas...)
+
+	// the contracts are called as token:<Function>, erc20:<Function>, asset:<Function>, assetquery:<Function>, lots:<Function>,
+	// config:<Function> and events:<Function>, functions without a contract name go to the token contract as in the
+	// token chaincode
+	bundle, err := contractapi.NewChaincode(tokenContract, erc20Contract, assetContract, assetQueryContract, lotsContract, configContract, eventsContract)
+	if err != nil {
+		log.Panicf("Error creating token and asset bundle chaincode: %v", err)
+	}
+	bundle.DefaultContract = tokenContract.GetName()
+	bundle.Info = metadata.InfoMetadata{Title: "token-asset-bundle", Version: "1.0.0"}
+
+	// packaged, or as an external service when CHAINCODE_SERVER_ADDRESS is set
+	if err := ledgerutil.StartChaincode(bundle); err != nil {
+		log.Panicf("Error starting token and asset bundle chaincode: %v", err)
+	}
+}
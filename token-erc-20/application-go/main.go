@@ -0,0 +1,308 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-samples/internal/appclient"
+	"github.com/hyperledger/fabric-samples/internal/tracing"
+	"github.com/hyperledger/fabric-samples/token-erc-20/application-go/token"
+)
+
+const usage = `Usage: application-go [flags] <command> [args]
+
+Commands:
+  demo                                 mint as Org1, transfer to and approve Org2, spend the allowance as Org2
+  mint <amount>                        create tokens in the client's account
+  burn <amount>                        remove tokens from the client's account
+  transfer <receiver> <amount>         move tokens to another account
+  approve <spender> <amount>           allow spender to move tokens of the client's account
+  transferfrom <from> <receiver> <amount>
+                                       spend an allowance given to the client
+  balance [account]                    balance of an account, the client's by default
+  allowance <owner> <spender>          allowance owner gave spender
+  id                                   account ID of the client
+  audit <txID>                         audit record of a transaction, if kept on the ledger
+  events                               print Transfer and Approval events until interrupted
+
+Flags:
+`
+
+var (
+	networkDir = flag.String("network", "../../test-network", "directory of the test network")
+	org        = flag.Int("org", 1, "org of the client, 1 or 2")
+	user       = flag.String("user", "User1", "user of the org to connect as")
+	walletDir  = flag.String("wallet", "", "wallet directory to connect from as the identity labeled org<N>-<user>, e.g. org1-user1, instead of the user's MSP directory")
+	channel    = flag.String("channel", "mychannel", "channel the chaincode is deployed on")
+	chaincode  = flag.String("chaincode", "token_erc20", "name the token chaincode is deployed as")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	args := flag.Args()
+	if len(args) == 0 {
+		args = []string{"demo"}
+	}
+	shutdownTracing, err := tracing.Start(context.Background(), "token-erc-20/application-go")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer shutdownTracing(context.Background())
+
+	if args[0] == "demo" {
+		err = demo()
+		if err != nil {
+			log.Fatalf("demo failed: %v", err)
+		}
+		return
+	}
+
+	contract, closeConnection, err := connect(*org, *user)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer closeConnection()
+
+	err = run(contract, args[0], args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// connect connects to the peer of the org as the user and returns the token contract
+func connect(orgNumber int, userName string) (*token.Contract, func(), error) {
+	domain := fmt.Sprintf("org%d.example.com", orgNumber)
+	orgDir := filepath.Join(*networkDir, "organizations", "peerOrganizations", domain)
+
+	profile, err := appclient.LoadProfile(filepath.Join(orgDir, fmt.Sprintf("connection-org%d.json", orgNumber)))
+	if err != nil {
+		return nil, nil, err
+	}
+	connection, err := connectUser(profile, filepath.Join(orgDir, "users", userName+"@"+domain, "msp"), orgNumber, userName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	closeConnection := func() {
+		if err := connection.Close(); err != nil {
+			log.Printf("failed to close connection: %v", err)
+		}
+	}
+	return token.NewContract(connection.GetNetwork(*channel), *chaincode), closeConnection, nil
+}
+
+// connectUser connects as the user of the org, from the wallet when one is given and from the user's
+// MSP directory in the test network otherwise
+func connectUser(profile *appclient.Profile, mspDir string, orgNumber int, userName string) (*appclient.Connection, error) {
+	if *walletDir == "" {
+		return appclient.Connect(profile, "", mspDir)
+	}
+	wallet, err := appclient.OpenWallet(*walletDir)
+	if err != nil {
+		return nil, err
+	}
+	return appclient.ConnectWallet(profile, "", wallet, fmt.Sprintf("org%d-%s", orgNumber, strings.ToLower(userName)))
+}
+
+// run runs one command as the connected client
+func run(contract *token.Contract, command string, args []string) error {
+	switch command {
+	case "mint", "burn":
+		amounts, err := parseAmounts(args, 1)
+		if err != nil {
+			return err
+		}
+		if command == "mint" {
+			return printResult(contract.Mint(amounts[0]))
+		}
+		return printResult(contract.Burn(amounts[0]))
+	case "transfer", "approve":
+		if len(args) != 2 {
+			return fmt.Errorf("%s takes an account and an amount", command)
+		}
+		amounts, err := parseAmounts(args[1:], 1)
+		if err != nil {
+			return err
+		}
+		if command == "transfer" {
+			return printResult(contract.Transfer(args[0], amounts[0]))
+		}
+		return printResult(contract.Approve(args[0], amounts[0]))
+	case "transferfrom":
+		if len(args) != 3 {
+			return fmt.Errorf("transferfrom takes the from and receiver accounts and an amount")
+		}
+		amounts, err := parseAmounts(args[2:], 1)
+		if err != nil {
+			return err
+		}
+		return printResult(contract.TransferFrom(args[0], args[1], amounts[0]))
+	case "balance":
+		account, err := accountOrClient(contract, args)
+		if err != nil {
+			return err
+		}
+		return printResult(contract.BalanceOf(account))
+	case "allowance":
+		if len(args) != 2 {
+			return fmt.Errorf("allowance takes the owner and spender accounts")
+		}
+		return printResult(contract.Allowance(args[0], args[1]))
+	case "id":
+		return printResult(contract.ClientAccountID())
+	case "audit":
+		if len(args) != 1 {
+			return fmt.Errorf("audit takes a transaction ID")
+		}
+		return printResult(contract.GetAuditRecord(args[0]))
+	case "events":
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer cancel()
+		events, err := contract.Events(ctx, nil)
+		if err != nil {
+			return err
+		}
+		for event := range events {
+			printEvent(event)
+		}
+		return nil
+	default:
+		flag.Usage()
+		return fmt.Errorf("unknown command %s", command)
+	}
+}
+
+// demo runs the flow of the token-erc-20 README: Org1 mints and transfers to an Org2 client, approves
+// it as a spender, and the Org2 client spends part of the allowance. Events are printed as they are
+// committed. Org1 needs the minter role of the access-control chaincode.
+func demo() error {
+	org1, closeOrg1, err := connect(1, *user)
+	if err != nil {
+		return err
+	}
+	defer closeOrg1()
+	org2, closeOrg2, err := connect(2, *user)
+	if err != nil {
+		return err
+	}
+	defer closeOrg2()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := org1.Events(ctx, nil)
+	if err != nil {
+		return err
+	}
+	go func() {
+		for event := range events {
+			printEvent(event)
+		}
+	}()
+
+	minter, err := org1.ClientAccountID()
+	if err != nil {
+		return err
+	}
+	recipient, err := org2.ClientAccountID()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("--> Org1 account %s\n--> Org2 account %s\n", minter, recipient)
+
+	fmt.Println("--> Mint 5000 tokens as Org1")
+	if err := printResult(org1.Mint(5000)); err != nil {
+		return err
+	}
+	fmt.Println("--> Transfer 100 tokens to Org2")
+	if err := printResult(org1.Transfer(recipient, 100)); err != nil {
+		return err
+	}
+	fmt.Println("--> Approve Org2 to spend 500 tokens of Org1")
+	if err := printResult(org1.Approve(recipient, 500)); err != nil {
+		return err
+	}
+	fmt.Println("--> Org2 moves 200 tokens of Org1 to itself")
+	if err := printResult(org2.TransferFrom(minter, recipient, 200)); err != nil {
+		return err
+	}
+
+	for name, account := range map[string]string{"Org1": minter, "Org2": recipient} {
+		balance, err := org1.BalanceOf(account)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("--> %s balance: %d\n", name, balance)
+	}
+	allowance, err := org1.Allowance(minter, recipient)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("--> Allowance left for Org2: %d\n", allowance)
+
+	// give the listener time to print the events of the last block
+	time.Sleep(2 * time.Second)
+	return nil
+}
+
+// accountOrClient returns the account argument, or the account of the client when there is none
+func accountOrClient(contract *token.Contract, args []string) (string, error) {
+	if len(args) > 0 {
+		return args[0], nil
+	}
+	return contract.ClientAccountID()
+}
+
+// parseAmounts parses the amount arguments of a command
+func parseAmounts(args []string, count int) ([]int, error) {
+	if len(args) != count {
+		return nil, fmt.Errorf("expected %d amount arguments, got %d", count, len(args))
+	}
+	amounts := make([]int, count)
+	for i, arg := range args {
+		amount, err := strconv.Atoi(arg)
+		if err != nil {
+			return nil, fmt.Errorf("amount %q is not an integer", arg)
+		}
+		amounts[i] = amount
+	}
+	return amounts, nil
+}
+
+// printResult prints the result of a function as indented JSON, or returns its error
+func printResult(result interface{}, err error) error {
+	if err != nil {
+		return err
+	}
+	resultJSON, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal result: %v", err)
+	}
+	fmt.Println(string(resultJSON))
+	return nil
+}
+
+func printEvent(event *token.Event) {
+	fmt.Printf("<-- %s event in block %d, transaction %s: %d from %s to %s\n", event.Name, event.BlockNumber, event.TxID, event.Value, event.From, event.To)
+	if event.Audit != nil {
+		for _, change := range event.Audit.Changes {
+			fmt.Printf("    %s of %v: %d -> %d\n", change.Kind, change.Subject, change.Old, change.New)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package token
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/identity"
+)
+
+// PaymentChannel is a deposit of the opener locked for off-ledger payments with the counterparty.
+// Status is PENDING until the counterparty joins, then OPEN, CLOSING during the dispute window and
+// SETTLED once paid out.
+type PaymentChannel struct {
+	ChannelID           string     `json:"channelID"`
+	Status              string     `json:"status"`
+	Opener              string     `json:"opener"`
+	Counterparty        string     `json:"counterparty"`
+	Deposit             int        `json:"deposit"`
+	OpenerKey           string     `json:"openerKey"`
+	CounterpartyKey     string     `json:"counterpartyKey,omitempty"`
+	Sequence            int        `json:"sequence"`
+	OpenerBalance       int        `json:"openerBalance"`
+	CounterpartyBalance int        `json:"counterpartyBalance"`
+	OpenedAt            time.Time  `json:"openedAt"`
+	DisputeEndsAt       *time.Time `json:"disputeEndsAt,omitempty"`
+}
+
+// ChannelState is a split of the deposit of a channel both parties sign, the fields in the key
+// order of the canonical JSON the chaincode verifies the signatures over
+type ChannelState struct {
+	ChannelID           string `json:"channelID"`
+	CounterpartyBalance int    `json:"counterpartyBalance"`
+	OpenerBalance       int    `json:"openerBalance"`
+	Sequence            int    `json:"sequence"`
+}
+
+// SignedChannelState is a state with the signatures of both parties, which CloseChannel submits
+type SignedChannelState struct {
+	State                 ChannelState `json:"state"`
+	OpenerSignature       string       `json:"openerSignature"`
+	CounterpartySignature string       `json:"counterpartySignature"`
+}
+
+// SignChannelState returns the signature of state with sign, the signing function of the
+// certificate the party opened or joined the channel with, e.g. from appclient.NewSign. The
+// parties exchange their signatures off the ledger.
+func SignChannelState(sign identity.Sign, state ChannelState) (string, error) {
+	message, err := json.Marshal(state)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode state %d: %v", state.Sequence, err)
+	}
+	digest := sha256.Sum256(message)
+	signature, err := sign(digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign state %d: %w", state.Sequence, err)
+	}
+	return base64.StdEncoding.EncodeToString(signature), nil
+}
+
+// OpenChannel locks deposit of the client's balance in a payment channel with counterparty, whose
+// ID is the ID of the transaction
+func (c *Contract) OpenChannel(counterparty string, deposit int) (*PaymentChannel, error) {
+	var channel PaymentChannel
+	err := c.submitJSON(&channel, "OpenChannel", counterparty, strconv.Itoa(deposit))
+	if err != nil {
+		return nil, err
+	}
+	return &channel, nil
+}
+
+// JoinChannel joins the payment channel channelID opened with the client as counterparty
+func (c *Contract) JoinChannel(channelID string) (*PaymentChannel, error) {
+	var channel PaymentChannel
+	err := c.submitJSON(&channel, "JoinChannel", channelID)
+	if err != nil {
+		return nil, err
+	}
+	return &channel, nil
+}
+
+// CloseChannel closes the payment channel of the state, or disputes the state it is closing with
+// by a later one during the dispute window
+func (c *Contract) CloseChannel(finalState SignedChannelState) (*PaymentChannel, error) {
+	stateJSON, err := json.Marshal(finalState)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode state %d: %v", finalState.State.Sequence, err)
+	}
+	var channel PaymentChannel
+	err = c.submitJSON(&channel, "CloseChannel", string(stateJSON))
+	if err != nil {
+		return nil, err
+	}
+	return &channel, nil
+}
+
+// SettleChannel pays out the payment channel channelID once its dispute window ended
+func (c *Contract) SettleChannel(channelID string) (*PaymentChannel, error) {
+	var channel PaymentChannel
+	err := c.submitJSON(&channel, "SettleChannel", channelID)
+	if err != nil {
+		return nil, err
+	}
+	return &channel, nil
+}
+
+// GetChannel returns the payment channel channelID
+func (c *Contract) GetChannel(channelID string) (*PaymentChannel, error) {
+	var channel PaymentChannel
+	err := c.evaluateJSON(&channel, "GetChannel", channelID)
+	if err != nil {
+		return nil, err
+	}
+	return &channel, nil
+}
@@ -0,0 +1,181 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package token
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-protos-go-apiv2/common"
+	"github.com/hyperledger/fabric-protos-go-apiv2/msp"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+	"google.golang.org/protobuf/proto"
+)
+
+// Receipt records a transfer made with TransferWithReference
+type Receipt struct {
+	TxID string `json:"txID"`
+	// ID tells apart the receipts of a transaction making several transfers with a reference
+	ID        string    `json:"id"`
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Amount    int       `json:"amount"`
+	Reference string    `json:"reference"`
+	Timestamp time.Time `json:"timestamp"`
+	// TraceID is the OpenTelemetry trace ID passed with the transfer, if any
+	TraceID string `json:"traceID,omitempty"`
+}
+
+// Endorsement is the signature of one endorsing peer over the proposal response payload of a
+// transaction, verified with the public key of Certificate over ProposalResponsePayload followed
+// by Endorser
+type Endorsement struct {
+	MSPID string `json:"mspID"`
+	// Certificate is the PEM certificate of the peer
+	Certificate string `json:"certificate"`
+	// Endorser is the serialized identity of the peer, as it was signed
+	Endorser  []byte `json:"endorser"`
+	Signature []byte `json:"signature"`
+}
+
+// FinalityReceipt is the settlement proof of a committed transaction: the block it was committed
+// in, the orgs that endorsed it with their signatures, and what the chaincode stored of it. A
+// counterparty verifies it by checking the signatures against the certificates of the orgs and
+// fetching the block from a peer of its own.
+type FinalityReceipt struct {
+	TxID        string    `json:"txID"`
+	Channel     string    `json:"channel"`
+	Chaincode   string    `json:"chaincode"`
+	Timestamp   time.Time `json:"timestamp"`
+	BlockNumber uint64    `json:"blockNumber"`
+	// BlockDataHash is the hash of the transactions of the block, from its header
+	BlockDataHash  []byte `json:"blockDataHash"`
+	ValidationCode string `json:"validationCode"`
+	// EndorsingOrgs are the MSP IDs of the endorsing peers, in endorsement order without repeats
+	EndorsingOrgs           []string      `json:"endorsingOrgs"`
+	Endorsements            []Endorsement `json:"endorsements"`
+	ProposalResponsePayload []byte        `json:"proposalResponsePayload"`
+	// Receipts are set for transfers made with a reference and Audit while on-ledger audit records
+	// are on
+	Receipts []*Receipt   `json:"receipts,omitempty"`
+	Audit    *AuditRecord `json:"audit,omitempty"`
+}
+
+// GetFinalityReceipt returns the settlement proof of the transaction txID. The block and
+// endorsements are looked up through the query system chaincode, and the receipt and audit record
+// through GetFinalityReceipt of the token chaincode, which fails for transactions that stored
+// neither. Transactions committed as invalid did not settle and fail too.
+func (c *Contract) GetFinalityReceipt(txID string) (*FinalityReceipt, error) {
+	channel := c.network.Name()
+	qscc := c.network.GetContract("qscc")
+
+	processedBytes, err := qscc.EvaluateTransaction("GetTransactionByID", channel, txID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction %s: %w", txID, err)
+	}
+	processed := &peer.ProcessedTransaction{}
+	if err := proto.Unmarshal(processedBytes, processed); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal transaction %s: %v", txID, err)
+	}
+	validationCode := peer.TxValidationCode(processed.GetValidationCode())
+	if validationCode != peer.TxValidationCode_VALID {
+		return nil, fmt.Errorf("transaction %s was committed as invalid: %s", txID, validationCode)
+	}
+	receipt, err := decodeEndorsements(processed.GetTransactionEnvelope())
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode transaction %s: %v", txID, err)
+	}
+	if receipt.Chaincode != c.chaincode {
+		return nil, fmt.Errorf("transaction %s invoked chaincode %s, not %s", txID, receipt.Chaincode, c.chaincode)
+	}
+	receipt.Channel = channel
+	receipt.ValidationCode = validationCode.String()
+
+	blockBytes, err := qscc.EvaluateTransaction("GetBlockByTxID", channel, txID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get block of transaction %s: %w", txID, err)
+	}
+	block := &common.Block{}
+	if err := proto.Unmarshal(blockBytes, block); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal block of transaction %s: %v", txID, err)
+	}
+	receipt.BlockNumber = block.GetHeader().GetNumber()
+	receipt.BlockDataHash = block.GetHeader().GetDataHash()
+
+	var stored FinalityReceipt
+	err = c.evaluateJSON(&stored, "GetFinalityReceipt", txID)
+	if err != nil {
+		return nil, err
+	}
+	receipt.Receipts = stored.Receipts
+	receipt.Audit = stored.Audit
+	return receipt, nil
+}
+
+// decodeEndorsements returns a receipt with the ID, timestamp, chaincode and endorsements of an
+// endorser transaction
+func decodeEndorsements(envelope *common.Envelope) (*FinalityReceipt, error) {
+	payload := &common.Payload{}
+	if err := proto.Unmarshal(envelope.GetPayload(), payload); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal payload: %v", err)
+	}
+	channelHeader := &common.ChannelHeader{}
+	if err := proto.Unmarshal(payload.GetHeader().GetChannelHeader(), channelHeader); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal channel header: %v", err)
+	}
+	if common.HeaderType(channelHeader.GetType()) != common.HeaderType_ENDORSER_TRANSACTION {
+		return nil, fmt.Errorf("not an endorser transaction but %s", common.HeaderType(channelHeader.GetType()))
+	}
+	transaction := &peer.Transaction{}
+	if err := proto.Unmarshal(payload.GetData(), transaction); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal transaction: %v", err)
+	}
+	// the gateway submits one action per transaction
+	if len(transaction.GetActions()) != 1 {
+		return nil, fmt.Errorf("transaction has %d actions, want 1", len(transaction.GetActions()))
+	}
+	actionPayload := &peer.ChaincodeActionPayload{}
+	if err := proto.Unmarshal(transaction.GetActions()[0].GetPayload(), actionPayload); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal chaincode action payload: %v", err)
+	}
+	responsePayloadBytes := actionPayload.GetAction().GetProposalResponsePayload()
+	responsePayload := &peer.ProposalResponsePayload{}
+	if err := proto.Unmarshal(responsePayloadBytes, responsePayload); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal proposal response payload: %v", err)
+	}
+	chaincodeAction := &peer.ChaincodeAction{}
+	if err := proto.Unmarshal(responsePayload.GetExtension(), chaincodeAction); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal chaincode action: %v", err)
+	}
+
+	receipt := &FinalityReceipt{
+		TxID:                    channelHeader.GetTxId(),
+		Chaincode:               chaincodeAction.GetChaincodeId().GetName(),
+		EndorsingOrgs:           []string{},
+		Endorsements:            []Endorsement{},
+		ProposalResponsePayload: responsePayloadBytes,
+	}
+	if timestamp := channelHeader.GetTimestamp(); timestamp != nil {
+		receipt.Timestamp = timestamp.AsTime().UTC()
+	}
+	seen := make(map[string]bool)
+	for _, endorsement := range actionPayload.GetAction().GetEndorsements() {
+		endorser := &msp.SerializedIdentity{}
+		if err := proto.Unmarshal(endorsement.GetEndorser(), endorser); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal endorser: %v", err)
+		}
+		receipt.Endorsements = append(receipt.Endorsements, Endorsement{
+			MSPID:       endorser.GetMspid(),
+			Certificate: string(endorser.GetIdBytes()),
+			Endorser:    endorsement.GetEndorser(),
+			Signature:   endorsement.GetSignature(),
+		})
+		if !seen[endorser.GetMspid()] {
+			seen[endorser.GetMspid()] = true
+			receipt.EndorsingOrgs = append(receipt.EndorsingOrgs, endorser.GetMspid())
+		}
+	}
+	return receipt, nil
+}
@@ -0,0 +1,31 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package token
+
+// NetChange is the change of the balance of an account after netting its obligations
+type NetChange struct {
+	Account string `json:"account"`
+	Change  int    `json:"change"`
+	Balance int    `json:"balance"`
+}
+
+// WindowSettlement is the outcome of SettleWindow. More is set when due obligations were left for
+// the next call.
+type WindowSettlement struct {
+	Obligations int         `json:"obligations"`
+	Changes     []NetChange `json:"changes"`
+	More        bool        `json:"more"`
+}
+
+// SettleWindow nets the obligations of the netting windows that have ended into one balance update
+// per account. Call it again while More is set.
+func (c *Contract) SettleWindow() (*WindowSettlement, error) {
+	var settlement WindowSettlement
+	err := c.submitJSON(&settlement, "SettleWindow")
+	if err != nil {
+		return nil, err
+	}
+	return &settlement, nil
+}
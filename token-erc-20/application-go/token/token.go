@@ -0,0 +1,602 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package token calls the functions of the token-erc-20 chaincode through the Fabric Gateway and
+// decodes their results and events into Go types.
+package token
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-samples/internal/appclient"
+)
+
+// ContractName is the name the token chaincode gives its contract
+const ContractName = "token"
+
+// TxResult is returned by the functions that move tokens or set allowances
+type TxResult struct {
+	Status    string    `json:"status"`
+	TxID      string    `json:"txID"`
+	Timestamp time.Time `json:"timestamp"`
+	// Account is the account the tokens were moved from, minted to or burned from, or the owner of an allowance
+	Account string `json:"account"`
+	// Balance is the balance of Account after the transaction, unset for Approve
+	Balance *int `json:"balance,omitempty"`
+	// Allowance is the remaining allowance after Approve or TransferFrom
+	Allowance *int `json:"allowance,omitempty"`
+	// SettlesAt is set for a transfer recorded as an obligation in deferred settlement mode, whose
+	// balances change when SettleWindow runs after it
+	SettlesAt *time.Time `json:"settlesAt,omitempty"`
+}
+
+// AuditChange is one balance, allowance or total supply value changed by a transaction
+type AuditChange struct {
+	Kind    string   `json:"kind"`
+	Subject []string `json:"subject"`
+	Old     int      `json:"old"`
+	New     int      `json:"new"`
+}
+
+// AuditRecord lists the values changed by a transaction
+type AuditRecord struct {
+	TxID      string        `json:"txID"`
+	Timestamp time.Time     `json:"timestamp"`
+	Event     string        `json:"event"`
+	Changes   []AuditChange `json:"changes"`
+	// TraceID is the OpenTelemetry trace ID passed with the transaction, if any
+	TraceID string `json:"traceID,omitempty"`
+}
+
+// AccountBalance is the balance of one account
+type AccountBalance struct {
+	Account string `json:"account"`
+	Balance int    `json:"balance"`
+}
+
+// BalancePage is one page of the account balances in key order. Bookmark is passed to the next
+// query to continue after the last account and is empty on the last page.
+type BalancePage struct {
+	Balances []AccountBalance `json:"balances"`
+	Bookmark string           `json:"bookmark"`
+}
+
+// OwnerAllowance is the allowance an owner has given a spender
+type OwnerAllowance struct {
+	Owner     string `json:"owner"`
+	Spender   string `json:"spender"`
+	Allowance int    `json:"allowance"`
+}
+
+// AllowancePage is one page of the allowances in owner and spender order, like BalancePage
+type AllowancePage struct {
+	Allowances []OwnerAllowance `json:"allowances"`
+	Bookmark   string           `json:"bookmark"`
+}
+
+// TransferPreview is the outcome SimulateTransfer and SimulateTransferFrom predict for a transfer
+type TransferPreview struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Amount int    `json:"amount"`
+	// FromBalance and ToBalance are the balances of the accounts after the transfer
+	FromBalance int `json:"fromBalance"`
+	ToBalance   int `json:"toBalance"`
+	// Allowance is the allowance of the client left after TransferFrom
+	Allowance *int `json:"allowance,omitempty"`
+	// Withheld is the part of the amount paid to the tax account instead of the receiver
+	Withheld int `json:"withheld,omitempty"`
+}
+
+// QuotaUsage is the mint quota of an org: its allocation for each period, what its clients minted
+// in the current period and what is left of it
+type QuotaUsage struct {
+	MSPID       string    `json:"mspID"`
+	Allocation  int       `json:"allocation"`
+	Period      string    `json:"period"`
+	PeriodStart time.Time `json:"periodStart"`
+	PeriodEnd   time.Time `json:"periodEnd"`
+	Minted      int       `json:"minted"`
+	Remaining   int       `json:"remaining"`
+}
+
+// WithholdingEntry records the tokens withheld from a transfer for the tax account. Rate is in basis
+// points and the receiver got Amount-Withheld.
+type WithholdingEntry struct {
+	TxID string `json:"txID"`
+	// ID tells apart the entries of a transaction making several transfers
+	ID         string    `json:"id"`
+	Timestamp  time.Time `json:"timestamp"`
+	From       string    `json:"from"`
+	To         string    `json:"to"`
+	Category   string    `json:"category"`
+	Rate       int       `json:"rate"`
+	Amount     int       `json:"amount"`
+	Withheld   int       `json:"withheld"`
+	TaxAccount string    `json:"taxAccount"`
+}
+
+// WithholdingEntryPage is one page of the withholding entries of a month, like BalancePage
+type WithholdingEntryPage struct {
+	Entries  []*WithholdingEntry `json:"entries"`
+	Bookmark string              `json:"bookmark"`
+}
+
+// WithholdingTotal is what was withheld from the transfers to the accounts of a category in a month
+type WithholdingTotal struct {
+	Category  string `json:"category"`
+	Transfers int    `json:"transfers"`
+	Amount    int    `json:"amount"`
+	Withheld  int    `json:"withheld"`
+}
+
+// WithholdingReport totals the withholding entries of a month by account category
+type WithholdingReport struct {
+	Period     string             `json:"period"`
+	Categories []WithholdingTotal `json:"categories"`
+	Transfers  int                `json:"transfers"`
+	Amount     int                `json:"amount"`
+	Withheld   int                `json:"withheld"`
+}
+
+// MaxPageSize is the largest page GetBalancesPage and GetAllowancesPage return with the default query
+// config of the chaincode
+const MaxPageSize = 100
+
+// Event is a Transfer or Approval event of the token chaincode. Mint transfers from and Burn to the
+// account 0x0. SchemaVersion is the version of the event's schema, which the events contract of the
+// chaincode returns, 0 for events emitted before the chaincode versioned them.
+type Event struct {
+	Name        string `json:"-"`
+	TxID        string `json:"-"`
+	BlockNumber uint64 `json:"-"`
+	From        string `json:"from"`
+	To          string `json:"to"`
+	Value       int    `json:"value"`
+	// Previous is the allowance an Approval replaced, and ExpiresAt and Reference the terms of the
+	// new one set by ApproveWithTerms, from version 2 of the Approval schema on
+	Previous      int          `json:"previous,omitempty"`
+	ExpiresAt     *time.Time   `json:"expiresAt,omitempty"`
+	Reference     string       `json:"reference,omitempty"`
+	Audit         *AuditRecord `json:"audit,omitempty"`
+	SchemaVersion int          `json:"schemaVersion"`
+}
+
+// AllowanceTerms are the expiry and reference of an allowance set by ApproveWithTerms
+type AllowanceTerms struct {
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	Reference string     `json:"reference,omitempty"`
+}
+
+// AccountRegistration records that the owner of an account registered it to receive tokens
+type AccountRegistration struct {
+	Account      string    `json:"account"`
+	MSPID        string    `json:"mspID"`
+	TxID         string    `json:"txID"`
+	RegisteredAt time.Time `json:"registeredAt"`
+}
+
+// AccountInfo is what a wallet shows of an account on one screen
+type AccountInfo struct {
+	Account            string     `json:"account"`
+	Balance            int        `json:"balance"`
+	KYCVerified        bool       `json:"kycVerified"`
+	Registered         bool       `json:"registered"`
+	OutboundAllowances int        `json:"outboundAllowances"`
+	InboundAllowances  int        `json:"inboundAllowances"`
+	LastActivity       *time.Time `json:"lastActivity,omitempty"`
+	// Holds are the deposits of the account in payment channels that have not settled
+	Holds []AccountHold `json:"holds"`
+}
+
+// AccountHold is part of the tokens of an account locked in a payment channel
+type AccountHold struct {
+	ChannelID string `json:"channelID"`
+	Status    string `json:"status"`
+	Amount    int    `json:"amount"`
+}
+
+// Contract is the token contract of a chaincode deployed on a channel
+type Contract struct {
+	network   *client.Network
+	chaincode string
+	contract  *client.Contract
+	observer  appclient.Observer
+	ctx       context.Context
+}
+
+// NewContract returns the token contract of the chaincode deployed as chaincodeName on the network
+func NewContract(network *client.Network, chaincodeName string) *Contract {
+	return &Contract{
+		network:   network,
+		chaincode: chaincodeName,
+		contract:  network.GetContractWithName(chaincodeName, ContractName),
+		ctx:       context.Background(),
+	}
+}
+
+// SetObserver sets the observer told of every transaction and query of the contract
+func (c *Contract) SetObserver(observer appclient.Observer) {
+	c.observer = observer
+}
+
+// WithContext returns a copy of the contract making its calls in ctx, whose trace the spans of the
+// calls are recorded in, e.g. the context of the API request they are made for
+func (c *Contract) WithContext(ctx context.Context) *Contract {
+	copy := *c
+	copy.ctx = ctx
+	return &copy
+}
+
+// Mint creates amount tokens in the account of the client. The client's org needs the token.Mint
+// operation in the access-control chaincode.
+func (c *Contract) Mint(amount int) (*TxResult, error) {
+	return c.submit("Mint", strconv.Itoa(amount))
+}
+
+// Burn removes amount tokens from the account of the client. The client's org needs the token.Burn
+// operation in the access-control chaincode.
+func (c *Contract) Burn(amount int) (*TxResult, error) {
+	return c.submit("Burn", strconv.Itoa(amount))
+}
+
+// Transfer moves amount tokens from the account of the client to receiver
+func (c *Contract) Transfer(receiver string, amount int) (*TxResult, error) {
+	return c.submit("Transfer", receiver, strconv.Itoa(amount))
+}
+
+// TransferFrom moves amount tokens from the account from to receiver, spending the allowance from
+// gave the client
+func (c *Contract) TransferFrom(from string, receiver string, amount int) (*TxResult, error) {
+	return c.submit("TransferFrom", from, receiver, strconv.Itoa(amount))
+}
+
+// SimulateTransfer evaluates Transfer without changing the ledger, returning the balances it
+// would leave or the error submitting it would fail with
+func (c *Contract) SimulateTransfer(receiver string, amount int) (*TransferPreview, error) {
+	var preview TransferPreview
+	err := c.evaluateJSON(&preview, "SimulateTransfer", receiver, strconv.Itoa(amount))
+	if err != nil {
+		return nil, err
+	}
+	return &preview, nil
+}
+
+// SimulateTransferFrom evaluates TransferFrom without changing the ledger, also returning the
+// allowance it would leave
+func (c *Contract) SimulateTransferFrom(from string, receiver string, amount int) (*TransferPreview, error) {
+	var preview TransferPreview
+	err := c.evaluateJSON(&preview, "SimulateTransferFrom", from, receiver, strconv.Itoa(amount))
+	if err != nil {
+		return nil, err
+	}
+	return &preview, nil
+}
+
+// Approve lets spender move up to amount tokens from the account of the client. An amount of 0
+// revokes the allowance.
+func (c *Contract) Approve(spender string, amount int) (*TxResult, error) {
+	return c.submit("Approve", spender, strconv.Itoa(amount))
+}
+
+// ApproveWithTerms is Approve with a time the allowance expires at, none when zero, and a reference
+// shown to the spender, none when empty
+func (c *Contract) ApproveWithTerms(spender string, amount int, expiresAt time.Time, reference string) (*TxResult, error) {
+	expiry := ""
+	if !expiresAt.IsZero() {
+		expiry = expiresAt.UTC().Format(time.RFC3339)
+	}
+	return c.submit("ApproveWithTerms", spender, strconv.Itoa(amount), expiry, reference)
+}
+
+// GetAllowanceTerms returns the expiry and reference of the allowance owner gave spender
+func (c *Contract) GetAllowanceTerms(owner string, spender string) (*AllowanceTerms, error) {
+	var terms AllowanceTerms
+	err := c.evaluateJSON(&terms, "GetAllowanceTerms", owner, spender)
+	if err != nil {
+		return nil, err
+	}
+	return &terms, nil
+}
+
+// BalanceOf returns the balance of account
+func (c *Contract) BalanceOf(account string) (int, error) {
+	return c.evaluateAmount("BalanceOf", account)
+}
+
+// Allowance returns the number of tokens spender may still move from the account of owner
+func (c *Contract) Allowance(owner string, spender string) (int, error) {
+	return c.evaluateAmount("Allowance", owner, spender)
+}
+
+// TotalSupply returns the number of tokens minted and not burned
+func (c *Contract) TotalSupply() (int, error) {
+	return c.evaluateAmount("TotalSupply")
+}
+
+// GetBalancesPage returns up to pageSize account balances starting at bookmark, empty for the
+// first page
+func (c *Contract) GetBalancesPage(pageSize int, bookmark string) (*BalancePage, error) {
+	var page BalancePage
+	err := c.evaluateJSON(&page, "GetBalancesPage", strconv.Itoa(pageSize), bookmark)
+	if err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// GetAllowancesPage returns up to pageSize allowances starting at bookmark, empty for the first
+// page
+func (c *Contract) GetAllowancesPage(pageSize int, bookmark string) (*AllowancePage, error) {
+	var page AllowancePage
+	err := c.evaluateJSON(&page, "GetAllowancesPage", strconv.Itoa(pageSize), bookmark)
+	if err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// SetMintQuota limits the tokens the clients of the org mspID may mint in each period, a Go duration
+// such as 24h, to allocation. The client needs token.SetMintQuota in the access-control chaincode.
+func (c *Contract) SetMintQuota(mspID string, allocation int, period string) (*QuotaUsage, error) {
+	var usage QuotaUsage
+	err := c.submitJSON(&usage, "SetMintQuota", mspID, strconv.Itoa(allocation), period)
+	if err != nil {
+		return nil, err
+	}
+	return &usage, nil
+}
+
+// RemoveMintQuota removes the mint quota of the org mspID. The client needs token.SetMintQuota in
+// the access-control chaincode.
+func (c *Contract) RemoveMintQuota(mspID string) error {
+	return c.submitJSON(nil, "RemoveMintQuota", mspID)
+}
+
+// GetQuotaUsage returns the mint quota of the org mspID and its usage in the current period
+func (c *Contract) GetQuotaUsage(mspID string) (*QuotaUsage, error) {
+	var usage QuotaUsage
+	err := c.evaluateJSON(&usage, "GetQuotaUsage", mspID)
+	if err != nil {
+		return nil, err
+	}
+	return &usage, nil
+}
+
+// SetWithholdingRate sets the rate in basis points withheld for the tax account from transfers to the
+// accounts of category, 0 to stop withholding. The client needs token.SetWithholding in the
+// access-control chaincode.
+func (c *Contract) SetWithholdingRate(category string, rate int) error {
+	return c.submitJSON(nil, "SetWithholdingRate", category, strconv.Itoa(rate))
+}
+
+// SetAccountCategory puts account in category, or takes it out of its category when category is
+// empty. The client needs token.SetWithholding in the access-control chaincode.
+func (c *Contract) SetAccountCategory(account string, category string) error {
+	return c.submitJSON(nil, "SetAccountCategory", account, category)
+}
+
+// GetWithholdingEntriesPage returns up to pageSize withholding entries of period, a month such as
+// 2024-01, starting at bookmark, empty for the first page. The client needs
+// token.GetWithholdingReport in the access-control chaincode.
+func (c *Contract) GetWithholdingEntriesPage(period string, pageSize int, bookmark string) (*WithholdingEntryPage, error) {
+	var page WithholdingEntryPage
+	err := c.evaluateJSON(&page, "GetWithholdingEntriesPage", period, strconv.Itoa(pageSize), bookmark)
+	if err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// GetWithholdingReport returns the tokens withheld in period, a month such as 2024-01, by account
+// category. The client needs token.GetWithholdingReport in the access-control chaincode.
+func (c *Contract) GetWithholdingReport(period string) (*WithholdingReport, error) {
+	var report WithholdingReport
+	err := c.evaluateJSON(&report, "GetWithholdingReport", period)
+	if err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// ClientAccountID returns the account ID of the client, which others use as its payment address
+func (c *Contract) ClientAccountID() (string, error) {
+	result, err := c.evaluate("ClientAccountID")
+	if err != nil {
+		return "", err
+	}
+	return string(result), nil
+}
+
+// RegisterAccount registers the client's account to receive tokens, which transfers to it need
+// while the strictRecipients flag is on
+func (c *Contract) RegisterAccount() (*AccountRegistration, error) {
+	var registration AccountRegistration
+	err := c.submitJSON(&registration, "RegisterAccount")
+	if err != nil {
+		return nil, err
+	}
+	return &registration, nil
+}
+
+// GetAccountRegistration returns the registration of account, failing with ACCOUNT_NOT_FOUND when
+// its owner did not register it
+func (c *Contract) GetAccountRegistration(account string) (*AccountRegistration, error) {
+	var registration AccountRegistration
+	err := c.evaluateJSON(&registration, "GetAccountRegistration", account)
+	if err != nil {
+		return nil, err
+	}
+	return &registration, nil
+}
+
+// GetAccountInfo returns the balance, KYC and registration status, allowance counts, last activity
+// and holds of account, which is 0 and empty for an account that never held tokens
+func (c *Contract) GetAccountInfo(account string) (*AccountInfo, error) {
+	var info AccountInfo
+	err := c.evaluateJSON(&info, "GetAccountInfo", account)
+	if err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// ListByCompositeKey returns up to pageSize raw entries of the allowance, audit or auditrecord
+// composite key prefix whose keys start with partialKeys, from bookmark on. The client needs
+// token.ListByCompositeKey in the access-control chaincode.
+func (c *Contract) ListByCompositeKey(objectType string, partialKeys []string, pageSize int, bookmark string) (*appclient.KeyPage, error) {
+	keysJSON, err := json.Marshal(append([]string{}, partialKeys...))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal partial keys: %v", err)
+	}
+	var page appclient.KeyPage
+	err = c.evaluateJSON(&page, "ListByCompositeKey", objectType, string(keysJSON), strconv.Itoa(pageSize), bookmark)
+	if err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// WhoAmI returns the identity of the client as the chaincode decodes it from its certificate
+func (c *Contract) WhoAmI() (*appclient.ClientIdentity, error) {
+	var identity appclient.ClientIdentity
+	err := c.evaluateJSON(&identity, "WhoAmI")
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// GetVersion returns the version and commit of the chaincode build running on the peer
+func (c *Contract) GetVersion() (*appclient.BuildInfo, error) {
+	var info appclient.BuildInfo
+	err := c.evaluateJSON(&info, "GetVersion")
+	if err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// Ping evaluates Ping on a peer, checking it runs the chaincode and reads its world state
+func (c *Contract) Ping() (*appclient.PingResult, error) {
+	var result appclient.PingResult
+	err := c.evaluateJSON(&result, "Ping")
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetAuditRecord returns the changes made by the transaction txID, kept while on-ledger audit
+// records are on
+func (c *Contract) GetAuditRecord(txID string) (*AuditRecord, error) {
+	var record AuditRecord
+	err := c.evaluateJSON(&record, "GetAuditRecord", txID)
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// Events returns the Transfer and Approval events of the chaincode committed from startBlock on, or
+// from the next block when startBlock is nil. The channel is closed when ctx is done.
+func (c *Contract) Events(ctx context.Context, startBlock *uint64) (<-chan *Event, error) {
+	var options []client.ChaincodeEventsOption
+	if startBlock != nil {
+		options = append(options, client.WithStartBlock(*startBlock))
+	}
+	chaincodeEvents, err := c.network.ChaincodeEvents(ctx, c.chaincode, options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start chaincode event listening: %w", err)
+	}
+
+	events := make(chan *Event)
+	go func() {
+		defer close(events)
+		for chaincodeEvent := range chaincodeEvents {
+			event := &Event{Name: chaincodeEvent.EventName, TxID: chaincodeEvent.TransactionID, BlockNumber: chaincodeEvent.BlockNumber}
+			if json.Unmarshal(chaincodeEvent.Payload, event) != nil {
+				// not an event of the token contract
+				continue
+			}
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events, nil
+}
+
+// submit submits a transaction, waits for it to be committed and decodes its result
+func (c *Contract) submit(function string, args ...string) (*TxResult, error) {
+	var result TxResult
+	err := c.submitJSON(&result, function, args...)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// submitJSON submits a transaction, waits for it to be committed and unmarshals its JSON result
+// into result, unless result is nil for the functions returning nothing
+func (c *Contract) submitJSON(result interface{}, function string, args ...string) error {
+	start := time.Now()
+	resultJSON, err := appclient.Submit(c.ctx, c.contract, function, nil, client.WithArguments(args...))
+	c.observer.Observe(c.chaincode, function, true, start, err)
+	if err != nil {
+		return fmt.Errorf("failed to submit %s: %w", function, err)
+	}
+	if result == nil {
+		return nil
+	}
+	err = json.Unmarshal(resultJSON, result)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal result of %s: %v", function, err)
+	}
+	return nil
+}
+
+// evaluate evaluates a query on a peer
+func (c *Contract) evaluate(function string, args ...string) ([]byte, error) {
+	start := time.Now()
+	result, err := appclient.Evaluate(c.ctx, c.contract, function, client.WithArguments(args...))
+	c.observer.Observe(c.chaincode, function, false, start, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate %s: %w", function, err)
+	}
+	return result, nil
+}
+
+// evaluateJSON evaluates a query and unmarshals its JSON result into result
+func (c *Contract) evaluateJSON(result interface{}, function string, args ...string) error {
+	resultJSON, err := c.evaluate(function, args...)
+	if err != nil {
+		return err
+	}
+	err = json.Unmarshal(resultJSON, result)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal result of %s: %v", function, err)
+	}
+	return nil
+}
+
+// evaluateAmount evaluates a query returning an amount
+func (c *Contract) evaluateAmount(function string, args ...string) (int, error) {
+	result, err := c.evaluate(function, args...)
+	if err != nil {
+		return 0, err
+	}
+	amount, err := strconv.Atoi(string(result))
+	if err != nil {
+		return 0, fmt.Errorf("result of %s is not an amount: %q", function, result)
+	}
+	return amount, nil
+}
@@ -0,0 +1,368 @@
+package chaincode
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	"github.com/hyperledger/fabric-protos-go/msp"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil"
+)
+
+// fakeStub is an in-memory world state standing in for the peer. Functions the token chaincode
+// does not call are left to the embedded interface and panic if called.
+type fakeStub struct {
+	shim.ChaincodeStubInterface
+	txID     string
+	function string
+	args     []string
+	// creator is the serialized identity of the client, which the contract API reads when the
+	// chaincode is invoked through it
+	creator []byte
+	// seconds is the transaction timestamp, 1600000000 unless a test moves the clock
+	seconds int64
+	state   map[string][]byte
+	// history holds the values written to each key, the latest first as the peer returns them
+	history map[string][]*queryresult.KeyModification
+	// chaincodes answers InvokeChaincode by chaincode name
+	chaincodes map[string]func(args [][]byte) pb.Response
+	// failures makes the named stub function return the error
+	failures   map[string]error
+	eventName  string
+	eventValue []byte
+	// transient is the transient data of the proposal
+	transient map[string][]byte
+}
+
+func newFakeStub() *fakeStub {
+	return &fakeStub{
+		seconds:    1600000000,
+		state:      make(map[string][]byte),
+		history:    make(map[string][]*queryresult.KeyModification),
+		chaincodes: make(map[string]func(args [][]byte) pb.Response),
+		failures:   make(map[string]error),
+	}
+}
+
+func (s *fakeStub) GetTxID() string {
+	return s.txID
+}
+
+func (s *fakeStub) GetTxTimestamp() (*timestamp.Timestamp, error) {
+	return &timestamp.Timestamp{Seconds: s.seconds}, nil
+}
+
+func (s *fakeStub) GetFunctionAndParameters() (string, []string) {
+	return s.function, s.args
+}
+
+func (s *fakeStub) GetCreator() ([]byte, error) {
+	return s.creator, nil
+}
+
+func (s *fakeStub) GetTransient() (map[string][]byte, error) {
+	return s.transient, nil
+}
+
+func (s *fakeStub) GetState(key string) ([]byte, error) {
+	if err := s.failures["GetState"]; err != nil {
+		return nil, err
+	}
+	return s.state[key], nil
+}
+
+func (s *fakeStub) PutState(key string, value []byte) error {
+	if err := s.failures["PutState"]; err != nil {
+		return err
+	}
+	s.state[key] = value
+	modification := &queryresult.KeyModification{TxId: s.txID, Value: value, Timestamp: &timestamp.Timestamp{Seconds: s.seconds}}
+	s.history[key] = append([]*queryresult.KeyModification{modification}, s.history[key]...)
+	return nil
+}
+
+func (s *fakeStub) GetHistoryForKey(key string) (shim.HistoryQueryIteratorInterface, error) {
+	return &fakeHistoryIterator{modifications: s.history[key]}, nil
+}
+
+func (s *fakeStub) DelState(key string) error {
+	if err := s.failures["DelState"]; err != nil {
+		return err
+	}
+	delete(s.state, key)
+	return nil
+}
+
+// CreateCompositeKey builds keys the same way as the shim, so tests can look them up
+func (s *fakeStub) CreateCompositeKey(objectType string, attributes []string) (string, error) {
+	key := "\x00" + objectType + "\x00"
+	for _, attribute := range attributes {
+		if !utf8.ValidString(attribute) || strings.ContainsRune(attribute, 0) || strings.ContainsRune(attribute, utf8.MaxRune) {
+			return "", fmt.Errorf("not a valid utf8 string without U+0000 and U+10FFFF: [%x]", attribute)
+		}
+		key += attribute + "\x00"
+	}
+	return key, nil
+}
+
+func (s *fakeStub) SplitCompositeKey(compositeKey string) (string, []string, error) {
+	parts := strings.Split(compositeKey, "\x00")
+	if len(parts) < 3 || parts[0] != "" || parts[len(parts)-1] != "" {
+		return "", nil, fmt.Errorf("not a composite key: %q", compositeKey)
+	}
+	return parts[1], parts[2 : len(parts)-1], nil
+}
+
+// GetStateByRange returns the simple keys from startKey up to endKey, or to the last key when
+// endKey is empty
+func (s *fakeStub) GetStateByRange(startKey string, endKey string) (shim.StateQueryIteratorInterface, error) {
+	iterator, _, err := s.page(func(key string) bool {
+		return !strings.HasPrefix(key, "\x00") && key >= startKey && (endKey == "" || key < endKey)
+	}, math.MaxInt32)
+	return iterator, err
+}
+
+// GetStateByPartialCompositeKey returns the keys of the prefix
+func (s *fakeStub) GetStateByPartialCompositeKey(objectType string, attributes []string) (shim.StateQueryIteratorInterface, error) {
+	iterator, _, err := s.GetStateByPartialCompositeKeyWithPagination(objectType, attributes, math.MaxInt32, "")
+	return iterator, err
+}
+
+// GetStateByRangeWithPagination returns the simple keys from bookmark on, leaving out composite
+// keys as the peer does. The bookmark of a page is the key after it.
+func (s *fakeStub) GetStateByRangeWithPagination(startKey string, endKey string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	return s.page(func(key string) bool {
+		return !strings.HasPrefix(key, "\x00") && key >= bookmark
+	}, pageSize)
+}
+
+// GetStateByPartialCompositeKeyWithPagination returns the keys of the prefix from bookmark on
+func (s *fakeStub) GetStateByPartialCompositeKeyWithPagination(objectType string, attributes []string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	prefix, err := s.CreateCompositeKey(objectType, attributes)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.page(func(key string) bool {
+		return strings.HasPrefix(key, prefix) && key >= bookmark
+	}, pageSize)
+}
+
+// page returns up to pageSize of the keys matching in order, with the next key as bookmark
+func (s *fakeStub) page(match func(key string) bool, pageSize int32) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	var keys []string
+	for key := range s.state {
+		if match(key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	iterator := &fakeStateIterator{}
+	metadata := &pb.QueryResponseMetadata{}
+	for i, key := range keys {
+		if i == int(pageSize) {
+			metadata.Bookmark = key
+			break
+		}
+		iterator.results = append(iterator.results, &queryresult.KV{Key: key, Value: s.state[key]})
+	}
+	metadata.FetchedRecordsCount = int32(len(iterator.results))
+	return iterator, metadata, nil
+}
+
+// fakeStateIterator iterates over the results of a range or partial composite key query
+type fakeStateIterator struct {
+	results []*queryresult.KV
+}
+
+func (i *fakeStateIterator) HasNext() bool {
+	return len(i.results) > 0
+}
+
+func (i *fakeStateIterator) Next() (*queryresult.KV, error) {
+	result := i.results[0]
+	i.results = i.results[1:]
+	return result, nil
+}
+
+func (i *fakeStateIterator) Close() error {
+	return nil
+}
+
+// fakeHistoryIterator iterates over the modifications of a key
+type fakeHistoryIterator struct {
+	modifications []*queryresult.KeyModification
+}
+
+func (i *fakeHistoryIterator) HasNext() bool {
+	return len(i.modifications) > 0
+}
+
+func (i *fakeHistoryIterator) Next() (*queryresult.KeyModification, error) {
+	modification := i.modifications[0]
+	i.modifications = i.modifications[1:]
+	return modification, nil
+}
+
+func (i *fakeHistoryIterator) Close() error {
+	return nil
+}
+
+func (s *fakeStub) SetEvent(name string, payload []byte) error {
+	if err := s.failures["SetEvent"]; err != nil {
+		return err
+	}
+	s.eventName = name
+	s.eventValue = payload
+	return nil
+}
+
+func (s *fakeStub) InvokeChaincode(chaincodeName string, args [][]byte, channel string) pb.Response {
+	chaincode, ok := s.chaincodes[chaincodeName]
+	if !ok {
+		return shim.Error(fmt.Sprintf("chaincode %s not found", chaincodeName))
+	}
+	return chaincode(args)
+}
+
+// fakeClientIdentity is the identity of the client submitting the transaction
+type fakeClientIdentity struct {
+	cid.ClientIdentity
+	id    string
+	mspID string
+	cert  *x509.Certificate
+}
+
+func (c *fakeClientIdentity) GetID() (string, error) {
+	return c.id, nil
+}
+
+func (c *fakeClientIdentity) GetMSPID() (string, error) {
+	return c.mspID, nil
+}
+
+func (c *fakeClientIdentity) GetX509Certificate() (*x509.Certificate, error) {
+	return c.cert, nil
+}
+
+// newCertificate returns a client certificate with the common name and the attributes in the
+// extension Fabric CA puts them in
+func newCertificate(commonName string, attrs map[string]string) *x509.Certificate {
+	value, err := json.Marshal(map[string]map[string]string{"attrs": attrs})
+	if err != nil {
+		panic(err)
+	}
+	return &x509.Certificate{
+		Subject:    pkix.Name{CommonName: commonName, OrganizationalUnit: []string{"client"}},
+		Issuer:     pkix.Name{CommonName: "ca.example.com"},
+		Extensions: []pkix.Extension{{Id: asn1.ObjectIdentifier{1, 2, 3, 4, 5, 6, 7, 8, 1}, Value: value}},
+	}
+}
+
+// setCreator makes the client of mspID with a self-signed certificate for commonName the creator of
+// the stub's transactions and returns the client's ID, as the contract API resolves it when the
+// chaincode is invoked through it
+func setCreator(stub *fakeStub, commonName string, mspID string) string {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	template := newCertificate(commonName, nil)
+	template.SerialNumber = big.NewInt(1)
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		panic(err)
+	}
+	stub.creator, err = proto.Marshal(&msp.SerializedIdentity{
+		Mspid:   mspID,
+		IdBytes: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}),
+	})
+	if err != nil {
+		panic(err)
+	}
+	clientID, err := cid.GetID(stub)
+	if err != nil {
+		panic(err)
+	}
+	return clientID
+}
+
+// newContext returns a transaction context for a client of the given org, resolved as the
+// BeforeTransaction hook does. attrs are the attributes of the client's certificate.
+func newContext(stub *fakeStub, clientID string, mspID string, attrs ...string) *ledgerutil.TransactionContext {
+	attributes := make(map[string]string)
+	for i := 0; i+1 < len(attrs); i += 2 {
+		attributes[attrs[i]] = attrs[i+1]
+	}
+	identity := &fakeClientIdentity{id: clientID, mspID: mspID, cert: newCertificate(clientID, attributes)}
+
+	ctx := new(ledgerutil.TransactionContext)
+	ctx.SetStub(stub)
+	ctx.SetClientIdentity(identity)
+	err := ctx.ResolveClient()
+	if err != nil {
+		panic(err)
+	}
+	return ctx
+}
+
+// newSigningContext is newContext for a client whose certificate holds the public key of key
+func newSigningContext(stub *fakeStub, clientID string, mspID string, key *ecdsa.PrivateKey) *ledgerutil.TransactionContext {
+	cert := newCertificate(clientID, map[string]string{})
+	cert.PublicKey = &key.PublicKey
+	ctx := new(ledgerutil.TransactionContext)
+	ctx.SetStub(stub)
+	ctx.SetClientIdentity(&fakeClientIdentity{id: clientID, mspID: mspID, cert: cert})
+	err := ctx.ResolveClient()
+	if err != nil {
+		panic(err)
+	}
+	return ctx
+}
+
+// accessControl fakes the access-control chaincode, granting the listed operations
+func accessControl(granted ...string) func(args [][]byte) pb.Response {
+	return func(args [][]byte) pb.Response {
+		if string(args[0]) != "CheckAccess" {
+			return shim.Error("unexpected function " + string(args[0]))
+		}
+		for _, operation := range granted {
+			if string(args[1]) == operation {
+				return shim.Success([]byte("true"))
+			}
+		}
+		return shim.Success([]byte("false"))
+	}
+}
+
+// identityRegistry fakes the identity-registry chaincode, which has profiles of the listed clients
+func identityRegistry(registered ...string) func(args [][]byte) pb.Response {
+	return func(args [][]byte) pb.Response {
+		if string(args[0]) != "GetProfile" {
+			return shim.Error("unexpected function " + string(args[0]))
+		}
+		for _, clientID := range registered {
+			if string(args[1]) == clientID {
+				return shim.Success([]byte(`{"clientID":"` + clientID + `"}`))
+			}
+		}
+		return shim.Error("client " + string(args[1]) + " has no registered profile")
+	}
+}
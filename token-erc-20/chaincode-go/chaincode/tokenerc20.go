@@ -1,25 +1,141 @@
 package chaincode
 
 import (
-	"encoding/json"
-	"fmt"
-	"log"
-	"strconv"
+	"strings"
+	"time"
 
+	"github.com/hyperledger/fabric-chaincode-go/shim"
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-contract-api-go/metadata"
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil"
 )
 
 const TokenName = "MSc Token" //TOken name can be set to initialise a token name
 const totalSupplyKey = "totalSupply"
+const identityRegistryName = "identity" //name the identity-registry chaincode is deployed under on the channel
+const accessControlName = "acl"         //name the access-control chaincode is deployed under on the channel
 
 // object names for prefix
 const allowancePrefix = "allowance"
 
+// kinds of the values recorded in audit records
+const (
+	balanceKind     = "balance"
+	allowanceKind   = "allowance"
+	totalSupplyKind = "totalSupply"
+)
+
 //provides function for transferring tokens between accounts using smart contract api.
 type SmartContract struct {
 	contractapi.Contract
 }
 
+// NewContract returns the token contract named "token" with the transaction hooks set, for the
+// chaincode of this module and for chaincodes bundling it with other contracts
+func NewContract() *SmartContract {
+	tokenContract := new(SmartContract)
+	tokenContract.Name = "token"
+	tokenContract.Info = metadata.InfoMetadata{
+		Title:       "MSc Token",
+		Description: "ERC-20 style fungible token with balances keyed by client ID, allowances and role-checked minting and burning",
+		Version:     "1.0.0",
+		License:     &metadata.LicenseMetadata{Name: "Apache-2.0"},
+	}
+	// resolve the client once per transaction, keep read-only clients to queries and audit the rest
+	tokenContract.TransactionContextHandler = new(ledgerutil.TransactionContext)
+	tokenContract.BeforeTransaction = ledgerutil.BeforeTransaction(tokenContract.GetEvaluateTransactions())
+	// list the functions and their arguments when a client calls one that does not exist
+	tokenContract.UnknownTransaction = ledgerutil.UnknownTransaction(tokenContract)
+	return tokenContract
+}
+
+// GetEvaluateTransactions lists the read-only functions, which the contract metadata tags as evaluate
+// so SDKs query one peer for them instead of submitting a transaction
+func (s *SmartContract) GetEvaluateTransactions() []string {
+	return []string{"BalanceOf", "Allowance", "TotalSupply", "GetBalancesPage", "GetAllowancesPage", "GetSchemaVersion", "ClientAccountID", "WhoAmI", "AccountProfile", "GetAuditRecord",
+		"SimulateTransfer", "SimulateTransferFrom", "GetQueryConfig", "ListByCompositeKey", "GetVersion", "Ping", "SearchReceiptsByReference", "GetQuotaUsage",
+		"GetWithholdingEntriesPage", "GetWithholdingReport", "GetAllowanceTerms", "GetFinalityReceipt", "GetChannel", "GetAccountRegistration", "GetAccountInfo"}
+}
+
+// listableObjectTypes are the composite key prefixes ListByCompositeKey may list
+var listableObjectTypes = []string{allowancePrefix, ledgerutil.AuditPrefix, ledgerutil.AuditRecordPrefix, receiptPrefix, receiptRefPrefix, mintQuotaPrefix,
+	withholdingRatePrefix, accountCategoryPrefix, withholdingEntryPrefix, allowanceTermsPrefix, paymentChannelPrefix, registeredAccountPrefix,
+	obligationPrefix, pendingDebitPrefix}
+
+// configuration parameters of the token, set through the config contract by clients allowed config.SetParameter
+var (
+	minterOrgsParam = ledgerutil.ConfigParam{Name: "minterOrgs", Kind: ledgerutil.ConfigStrings,
+		Description: "MSP IDs of the orgs whose clients may mint and burn besides having token.Mint and token.Burn, any org when empty"}
+	maxTransferAmountParam = ledgerutil.ConfigParam{Name: "maxTransferAmount", Kind: ledgerutil.ConfigInt, Default: "0",
+		Description: "largest amount one Transfer or TransferFrom moves, unlimited when 0"}
+)
+
+// ConfigParams are the configuration parameters and feature flags the token contract reads, for the
+// config contract of the chaincodes registering it
+var ConfigParams = []ledgerutil.ConfigParam{minterOrgsParam, maxTransferAmountParam, taxAccountParam, channelDisputeWindowParam, nettingWindowParam,
+	ledgerutil.StrictKYCFlag, strictRecipientsFlag, deferredSettlementFlag}
+
+// NewConfigContract returns the config contract of the token chaincode, holding ConfigParams and the
+// query limits
+func NewConfigContract() *ledgerutil.ConfigContract {
+	return ledgerutil.NewConfigContract(accessControlName, ConfigParams...)
+}
+
+// schemas of the events the token contract emits, each with the audit record added by the auditor
+var (
+	transferEvent = ledgerutil.EventSchema{Name: "Transfer", Version: 1,
+		Description: "tokens moved, minted from or burned to the 0x0 account, or recorded as an obligation moving them at settlesAt in deferred settlement mode",
+		Fields:      map[string]string{"from": "string", "to": "string", "value": "integer", "settlesAt": "string", "audit": "object"}}
+	approvalEvent = ledgerutil.EventSchema{Name: "Approval", Version: 2,
+		Description: "allowance of a spender over the tokens of an owner set, with the allowance it replaced and its expiry and reference if any",
+		Fields: map[string]string{"from": "string", "to": "string", "value": "integer", "previous": "integer",
+			"expiresAt": "string", "reference": "string", "audit": "object"}}
+)
+
+// EventSchemas are the schemas of the events the token contract emits, for the events contract of
+// the chaincodes registering it
+var EventSchemas = []ledgerutil.EventSchema{transferEvent, approvalEvent, batchTransferEvent, paymentChannelEvent, windowSettledEvent}
+
+// NewEventsContract returns the events contract of the token chaincode, holding EventSchemas, the
+// ConfigChanged schema and the schemas of the other contracts the chaincode registers
+func NewEventsContract(schemas ...ledgerutil.EventSchema) *ledgerutil.EventsContract {
+	return ledgerutil.NewEventsContract(accessControlName, append(EventSchemas, schemas...)...)
+}
+
+// maxMigrationPageSize bounds the number of records MigrateState rewrites in one transaction
+const maxMigrationPageSize = 1000
+
+// migrations lists the rewrites of the token records between schema versions. It is empty while
+// the records are in their first format; a version changing the format of balances or allowances
+// adds the migration from the previous version here.
+var migrations []ledgerutil.Migration
+
+// AccountBalance is the balance of one account
+type AccountBalance struct {
+	Account string `json:"account"`
+	Balance int    `json:"balance"`
+}
+
+// BalancePage is one page of the account balances in key order. Bookmark is passed to the next
+// query to continue after the last account and is empty on the last page.
+type BalancePage struct {
+	Balances []AccountBalance `json:"balances"`
+	Bookmark string           `json:"bookmark"`
+}
+
+// OwnerAllowance is the allowance an owner has given a spender
+type OwnerAllowance struct {
+	Owner     string `json:"owner"`
+	Spender   string `json:"spender"`
+	Allowance int    `json:"allowance"`
+}
+
+// AllowancePage is one page of the allowances in key order, like BalancePage
+type AllowancePage struct {
+	Allowances []OwnerAllowance `json:"allowances"`
+	Bookmark   string           `json:"bookmark"`
+}
+
 // event used for transactions
 type event struct {
 	From  string `json:"from"`
@@ -27,156 +143,311 @@ type event struct {
 	Value int    `json:"value"`
 }
 
+// approval is the payload of the Approval event. Value is the new allowance and Previous the one it
+// replaced; ExpiresAt and Reference are set by ApproveWithTerms.
+type approval struct {
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Value     int    `json:"value"`
+	Previous  int    `json:"previous"`
+	ExpiresAt string `json:"expiresAt,omitempty"`
+	Reference string `json:"reference,omitempty"`
+}
+
+// TxResult is returned by the functions that move tokens or set allowances, so clients learn the
+// outcome of their submission without a follow-up query
+type TxResult struct {
+	Status    string    `json:"status"`
+	TxID      string    `json:"txID"`
+	Timestamp time.Time `json:"timestamp"`
+	// Account is the account the tokens were moved from, minted to or burned from, or the owner of an allowance
+	Account string `json:"account"`
+	// Balance is the balance of Account after the transaction. It and Allowance hold an int or
+	// nothing, contractapi does not accept *int fields in the contract metadata.
+	Balance interface{} `json:"balance,omitempty" metadata:",optional"`
+	// Allowance is the remaining allowance after Approve or TransferFrom
+	Allowance interface{} `json:"allowance,omitempty" metadata:",optional"`
+	// SettlesAt is set when the transfer was recorded as an obligation in deferred settlement mode,
+	// the balances change when SettleWindow runs after it and Balance is what is left to spend
+	SettlesAt string `json:"settlesAt,omitempty" metadata:",optional"`
+}
+
+// TransferPreview is the outcome SimulateTransfer and SimulateTransferFrom predict for a transfer,
+// the same as the TxResult and events of submitting it against the current world state. Transfers
+// have no fees, so the receiver gets the whole amount.
+type TransferPreview struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Amount int    `json:"amount"`
+	// FromBalance and ToBalance are the balances of the accounts after the transfer
+	FromBalance int `json:"fromBalance"`
+	ToBalance   int `json:"toBalance"`
+	// Allowance is the allowance of the spender left after TransferFrom, an int or nothing as in TxResult
+	Allowance interface{} `json:"allowance,omitempty" metadata:",optional"`
+	// Withheld is the part of the amount paid to the tax account instead of the receiver
+	Withheld int `json:"withheld,omitempty" metadata:",optional"`
+}
+
+// transferPlan is the balances of both accounts of a transfer before and after it, the part of the
+// amount withheld for the tax account if any and what from owes by obligations not settled yet
+type transferPlan struct {
+	fromCurrent int
+	fromUpdated int
+	fromPending int
+	toCurrent   int
+	toUpdated   int
+	withholding *withholding
+}
+
 //**********************************************************************************************
 //****************ERC20 Contract Interface -- Common Functions From Ethereum*******************
 //**********************************************************************************************
-func (s *SmartContract) BalanceOf(ctx contractapi.TransactionContextInterface, account string) (int, error) {
+func (s *SmartContract) BalanceOf(ctx ledgerutil.TransactionContextInterface, account string) (int, error) {
+	account = ledgerutil.NormalizeID(account)
+	v := ledgerutil.NewValidator()
+	v.Key("account", account)
+	if err := v.Err(); err != nil {
+		return 0, err
+	}
 	//nil means if empty e.g []string
 	ownerBalance, err := ctx.GetStub().GetState(account) //read ledger used to access APIs and getstate retrives ledger of smartcontract struct.
 	if err != nil {
-		return 0, fmt.Errorf("failed to read balance from world state: %v", err)
+		return 0, ledgerutil.Wrap(err, "failed to read balance from world state")
 	}
 	if ownerBalance == nil {
-		return 0, fmt.Errorf("the account %s doesnt exist", account)
+		return 0, ledgerutil.Errorf(ledgerutil.CodeAccountNotFound, "the account %s doesnt exist", account)
+	}
+	balance, err := ledgerutil.ParseAmount(ownerBalance)
+	if err != nil {
+		return 0, ledgerutil.Wrap(err, "failed to read balance of account %s", account)
 	}
-	balance, _ := strconv.Atoi(string(ownerBalance)) //converts datatype to string reprisentation, Atoi is equivalent to parseint (string to int)
 	return balance, nil
 }
 
 //Transfer tokens from client account to recipient account triggering transfer event
 //Recipient account must be a valid clientID as returned by the GetClientID() function reading the ledger
 //Requires receiver address, and an amount
-func (s *SmartContract) Transfer(ctx contractapi.TransactionContextInterface, receiver string, amount int) error {
-	clientID, err := ctx.GetClientIdentity().GetID() //get the id of the client , verifying
-	if err != nil {
-		return fmt.Errorf("failed to get clientID:%v", err) //checking if clientid is valid
+func (s *SmartContract) Transfer(ctx ledgerutil.TransactionContextInterface, receiver string, amount int) (*TxResult, error) {
+	receiver = ledgerutil.NormalizeID(receiver)
+	v := ledgerutil.NewValidator() //check arguments before touching the ledger
+	v.Key("receiver", receiver)
+	v.Amount("amount", amount)
+	if err := v.Err(); err != nil {
+		return nil, err
 	}
-	err = _transferCalc(ctx, clientID, receiver, amount) //we create an error and call the transferHelper function
+	//the id of the client, resolved before the transaction
+	clientID := ctx.GetClientID()
+	auditor := ledgerutil.NewAuditor(ctx.GetStub()) //collects the balance changes published with the event
+	balance, settlesAt, err := _transferCalc(ctx, auditor, clientID, receiver, amount) //we create an error and call the transferHelper function
 	if err != nil {
-		return fmt.Errorf("failed to transfer: %v", err)
+		return nil, ledgerutil.Wrap(err, "failed to transfer")
 	}
 
-	transferEvent := event{clientID, receiver, amount}    //create a new event pass in updated variables
-	transferEventJSON, err := json.Marshal(transferEvent) //json encoding
+	err = auditor.Emit(transferEvent, _transferPayload(clientID, receiver, amount, settlesAt))
 	if err != nil {
-		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
-
+		return nil, err
 	}
-	err = ctx.GetStub().SetEvent("Transfer", transferEventJSON) //check errors for events, read api and setEvent named transfer and pass in json
+	result, err := _txResult(ctx, clientID, &balance, nil)
 	if err != nil {
-		return fmt.Errorf("failed to set event: %v", err)
+		return nil, err
 	}
-	return nil
+	result.SettlesAt = settlesAt
+	return result, nil
 }
 
 //Delegated transfer
 //The transferFrom() function transfers the tokens from an owner's account to the receiver account,
 //but only if the transaction initiator has sufficient allowance that has been previously approved by the owner to the transaction initiator
-func (s *SmartContract) TransferFrom(ctx contractapi.TransactionContextInterface, from string, receiver string, amount int) error {
-	var currentAllowance int //needed to set allowance
-	if amount <= 0 {
-		return fmt.Errorf("failed amount must be positive integer") //check amount is correct
-	}
-	spender, err := ctx.GetClientIdentity().GetID() //get spenderID which is the person calling the function, e.g clientID
-	if err != nil {
-		return fmt.Errorf("failed to get clientID: %v", err)
+func (s *SmartContract) TransferFrom(ctx ledgerutil.TransactionContextInterface, from string, receiver string, amount int) (*TxResult, error) {
+	from = ledgerutil.NormalizeID(from)
+	receiver = ledgerutil.NormalizeID(receiver)
+	v := ledgerutil.NewValidator()
+	v.Key("from", from)
+	v.Key("receiver", receiver)
+	v.PositiveAmount("amount", amount) //check amount is correct
+	if err := v.Err(); err != nil {
+		return nil, err
 	}
+	spender := ctx.GetClientID() //get spenderID which is the person calling the function, e.g clientID
 	//----------------------Current Allowance
-	allowanceKey, err := ctx.GetStub().CreateCompositeKey(allowancePrefix, []string{from, spender}) //get allowancekey by creating composite
+	allowanceKey, currentAllowance, err := _spendableAllowance(ctx, from, spender, amount)
 	if err != nil {
-		return fmt.Errorf("failed to create the composite key for prefix %s: %v", allowancePrefix, err)
-	}
-
-	currAllowanceTemp, err := ctx.GetStub().GetState(allowanceKey) //getstate accesses the ledger pass in allowance key to verify
-	if err != nil {
-		return fmt.Errorf("failed to retrieve the allowance for %s from world state: %v", allowanceKey, err)
-	}
-	currentAllowance, _ = strconv.Atoi(string(currAllowanceTemp)) //error handling not needed since Itoa()
-	if currentAllowance <= amount {
-		return fmt.Errorf("spender does not have enough allowance to transfer") //check amount vs currentallowance
+		return nil, err
 	}
 
 	// -------------------Initiate the transfer
-	err = _transferCalc(ctx, from, receiver, amount)
+	auditor := ledgerutil.NewAuditor(ctx.GetStub())
+	balance, settlesAt, err := _transferCalc(ctx, auditor, from, receiver, amount)
 	if err != nil {
-		return fmt.Errorf("failed to transfer:%v", err)
+		return nil, ledgerutil.Wrap(err, "failed to transfer")
 	}
 	//decrease the allowance
 	updatedAllowance := currentAllowance - amount
-	err = ctx.GetStub().PutState(allowanceKey, []byte(strconv.Itoa(updatedAllowance))) //updating the leger with putstate setting allowances
+	err = ctx.GetStub().PutState(allowanceKey, ledgerutil.FormatAmount(updatedAllowance)) //updating the leger with putstate setting allowances
 	if err != nil {
-		return err
+		return nil, err
 	}
-	//emit transfer event
-	transferEvent := event{from, receiver, amount} //pass in event data
-	transferEventJSON, err := json.Marshal(transferEvent)
+	auditor.Change(allowanceKind, currentAllowance, updatedAllowance, from, spender)
+	//emit transfer event with the balance and allowance changes
+	err = auditor.Emit(transferEvent, _transferPayload(from, receiver, amount, settlesAt))
 	if err != nil {
-		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+		return nil, err
 	}
-	err = ctx.GetStub().SetEvent("Transfer", transferEventJSON)
+
+	result, err := _txResult(ctx, from, &balance, &updatedAllowance)
 	if err != nil {
-		return fmt.Errorf("failed to set event: %v", err)
+		return nil, err
 	}
+	result.SettlesAt = settlesAt
+	return result, nil
+}
 
-	log.Printf("spender %s allowance updated from %d to %d", spender, currentAllowance, updatedAllowance) //pring log to user
+//Dry run of Transfer, runs the same checks and returns the balances the transfer would leave without writing them
+//Wallets evaluate it to show the effect of a transfer before the client submits it
+func (s *SmartContract) SimulateTransfer(ctx ledgerutil.TransactionContextInterface, receiver string, amount int) (*TransferPreview, error) {
+	receiver = ledgerutil.NormalizeID(receiver)
+	v := ledgerutil.NewValidator()
+	v.Key("receiver", receiver)
+	v.Amount("amount", amount)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	clientID := ctx.GetClientID()
+	plan, err := _planTransfer(ctx, clientID, receiver, amount)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to transfer")
+	}
+	return &TransferPreview{From: clientID, To: receiver, Amount: amount, FromBalance: plan.fromUpdated, ToBalance: plan.toUpdated, Withheld: plan.withheld()}, nil
+}
 
-	return nil
+//Dry run of TransferFrom by the client as spender, also returning the allowance it would leave
+func (s *SmartContract) SimulateTransferFrom(ctx ledgerutil.TransactionContextInterface, from string, receiver string, amount int) (*TransferPreview, error) {
+	from = ledgerutil.NormalizeID(from)
+	receiver = ledgerutil.NormalizeID(receiver)
+	v := ledgerutil.NewValidator()
+	v.Key("from", from)
+	v.Key("receiver", receiver)
+	v.PositiveAmount("amount", amount)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	_, currentAllowance, err := _spendableAllowance(ctx, from, ctx.GetClientID(), amount)
+	if err != nil {
+		return nil, err
+	}
+	plan, err := _planTransfer(ctx, from, receiver, amount)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to transfer")
+	}
+	allowance := currentAllowance - amount
+	return &TransferPreview{From: from, To: receiver, Amount: amount, FromBalance: plan.fromUpdated, ToBalance: plan.toUpdated, Allowance: allowance,
+		Withheld: plan.withheld()}, nil
 }
 
 //Approving transactions The allowance function tells how many tokens the ownerAddress has allowed the spender address to spend
-func (s *SmartContract) Approve(ctx contractapi.TransactionContextInterface, spender string, amount int) error {
-	owner, err := ctx.GetClientIdentity().GetID() //get owner id
-	if err != nil {
-		return fmt.Errorf("failed to get clientID : %v", err)
+//The allowance has no expiry or reference, replacing those set by ApproveWithTerms
+func (s *SmartContract) Approve(ctx ledgerutil.TransactionContextInterface, spender string, amount int) (*TxResult, error) {
+	return _approve(ctx, spender, amount, nil)
+}
 
+//Set the allowance of spender over the client's tokens with the terms, none for a plain allowance
+func _approve(ctx ledgerutil.TransactionContextInterface, spender string, amount int, terms *AllowanceTerms) (*TxResult, error) {
+	spender = ledgerutil.NormalizeID(spender)
+	v := ledgerutil.NewValidator()
+	v.Key("spender", spender)
+	v.Amount("amount", amount) //an allowance of 0 revokes it
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	//get owner id
+	owner := ctx.GetClientID()
+	//in strict KYC mode both accounts need a verified profile, an allowance can always be revoked
+	if amount > 0 {
+		err := _checkKYC(ctx, owner, spender)
+		if err != nil {
+			return nil, err
+		}
 	}
 	allowanceKey, err := ctx.GetStub().CreateCompositeKey(allowancePrefix, []string{owner, spender}) //create key
 	if err != nil {
-		return fmt.Errorf("failed to create composite key for prefix %s: %v", allowancePrefix, err)
+		return nil, ledgerutil.Wrap(err, "failed to create composite key for prefix %s", allowancePrefix)
+	}
+	//read the allowance being replaced for the audit record
+	currentAllowance := 0
+	currAllowanceTemp, err := ctx.GetStub().GetState(allowanceKey)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to retrieve the allowance for %s from world state", allowanceKey)
+	}
+	if currAllowanceTemp != nil {
+		currentAllowance, err = ledgerutil.ParseAmount(currAllowanceTemp)
+		if err != nil {
+			return nil, ledgerutil.Wrap(err, "failed to read the allowance for %s", allowanceKey)
+		}
 	}
 	// Update the state contract by adding the allowanceKey and value
-	err = ctx.GetStub().PutState(allowanceKey, []byte(strconv.Itoa(amount)))
+	err = ctx.GetStub().PutState(allowanceKey, ledgerutil.FormatAmount(amount))
 	if err != nil {
-		return fmt.Errorf("failed to update state of smart contract for key %s: %v", allowanceKey, err)
+		return nil, ledgerutil.Wrap(err, "failed to update state of smart contract for key %s", allowanceKey)
 	}
-	//init event approve
-	approvalEvent := event{owner, spender, amount}
-	approvalEventJSON, err := json.Marshal(approvalEvent)
+	err = _putAllowanceTerms(ctx, owner, spender, terms)
 	if err != nil {
-		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+		return nil, err
+	}
+	//init event approve, with the allowance replaced so monitoring sees the change without reading the state
+	auditor := ledgerutil.NewAuditor(ctx.GetStub())
+	auditor.Change(allowanceKind, currentAllowance, amount, owner, spender)
+	payload := approval{From: owner, To: spender, Value: amount, Previous: currentAllowance}
+	if terms != nil {
+		payload.ExpiresAt, payload.Reference = terms.ExpiresAt, terms.Reference
 	}
-	err = ctx.GetStub().SetEvent("Approval", approvalEventJSON)
+	err = auditor.Emit(approvalEvent, payload)
 	if err != nil {
-		return fmt.Errorf("failed to set event: %v", err)
+		return nil, err
 	}
-	//log print
-	log.Printf("client %s approved a withdrawal allowance of %d for spender %s", owner, amount, spender)
 
-	return nil
+	return _txResult(ctx, owner, nil, &amount)
 }
 
 //The allowance() function returns the token amount remaining
-func (s *SmartContract) Allowance(ctx contractapi.TransactionContextInterface, owner string, spender string) (int, error) {
+func (s *SmartContract) Allowance(ctx ledgerutil.TransactionContextInterface, owner string, spender string) (int, error) {
 	var allowance int
+	owner = ledgerutil.NormalizeID(owner)
+	spender = ledgerutil.NormalizeID(spender)
+	v := ledgerutil.NewValidator()
+	v.Key("owner", owner)
+	v.Key("spender", spender)
+	if err := v.Err(); err != nil {
+		return 0, err
+	}
 	//get ledger data create comp key pass in allowancePrefix set above and input datastruct string owner,spender
 	allowanceKey, err := ctx.GetStub().CreateCompositeKey(allowancePrefix, []string{owner, spender})
 	if err != nil {
-		return 0, fmt.Errorf("failed to create composite key fpr %s: %v", allowancePrefix, err)
+		return 0, ledgerutil.Wrap(err, "failed to create composite key fpr %s", allowancePrefix)
 	}
 
 	//read the allowance amount from the world state
 	allowanceTemp, err := ctx.GetStub().GetState(allowanceKey)
 	if err != nil {
-		return 0, fmt.Errorf("failed to read allowance for %s from world state: %v", allowanceKey, err)
+		return 0, ledgerutil.Wrap(err, "failed to read allowance for %s from world state", allowanceKey)
 	}
 	//cjecl allowance value if nil then we set the allowance to 0 just like balance
 	if allowanceTemp == nil {
 		allowance = 0
 	} else {
-		allowance, _ = strconv.Atoi(string(allowanceTemp)) //if we have an allowance then convert to int and get value
+		allowance, err = ledgerutil.ParseAmount(allowanceTemp) //if we have an allowance then convert to int and get value
+		if err != nil {
+			return 0, ledgerutil.Wrap(err, "failed to read allowance for %s", allowanceKey)
+		}
+	}
+	//nothing can be spent of an expired allowance
+	expired, err := _allowanceExpired(ctx, owner, spender)
+	if err != nil {
+		return 0, err
+	}
+	if expired {
+		allowance = 0
 	}
 
-	log.Printf("The allowance left for spender %s to withdraw from owner %s: %d", spender, owner, allowance) //display values
 	return allowance, nil
 }
 
@@ -184,175 +455,463 @@ func (s *SmartContract) Allowance(ctx contractapi.TransactionContextInterface, o
 //*********************************Other ERC20 Functions ***************************************
 //**********************************************************************************************
 //create/add a mintable token suply
-func (s *SmartContract) Mint(ctx contractapi.TransactionContextInterface, amount int) error {
+func (s *SmartContract) Mint(ctx ledgerutil.TransactionContextInterface, amount int) (*TxResult, error) {
 	var currentBalance int //setting variables
 	var totalSupply int
 
-	verifyClientID, err := ctx.GetClientIdentity().GetMSPID() //check authorization
+	err := _checkAccess(ctx, "token.Mint") //check authorization in the access-control chaincode
 	if err != nil {
-		return fmt.Errorf("failed to verify clientID: %v", err)
+		return nil, err
 	}
-	//we assume that the verifying client is ORG1
-	if verifyClientID != "Org1MSP" {
-		return fmt.Errorf("client %s is not authorized to create new tokens", verifyClientID)
+	err = _checkMinterOrg(ctx)
+	if err != nil {
+		return nil, err
 	}
 	//we get the ID of the minter
-	minter, err := ctx.GetClientIdentity().GetID()
+	minter := ctx.GetClientID()
+	v := ledgerutil.NewValidator()
+	v.PositiveAmount("amount", amount)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	err = _checkKYC(ctx, minter)
 	if err != nil {
-		return fmt.Errorf("failed to get client id: %v", err)
+		return nil, err
 	}
-	if amount <= 0 {
-		return fmt.Errorf("amount must be positive integer")
+	err = _useMintQuota(ctx, amount) //at most what is left of the allocation of the minter's org
+	if err != nil {
+		return nil, err
 	}
 
 	minterBalance, err := ctx.GetStub().GetState(minter) //get the balance of minter account
 	if err != nil {
-		return fmt.Errorf("failed to read minter account %s get current balance:%v", minter, err)
+		return nil, ledgerutil.Wrap(err, "failed to read minter account %s get current balance", minter)
 	}
 
 	// If minter current balance doesn't yet exist, we'll create it with a current balance of 0
 	if minterBalance == nil {
 		currentBalance = 0
 	} else {
-		currentBalance, _ = strconv.Atoi(string(minterBalance)) //if we have a balance then read as string return as int
+		currentBalance, err = ledgerutil.ParseAmount(minterBalance) //if we have a balance then read as string return as int
+		if err != nil {
+			return nil, ledgerutil.Wrap(err, "failed to read minter account %s", minter)
+		}
 	}
 
-	updatedBalance := currentBalance + amount                                  //update the balance
-	err = ctx.GetStub().PutState(minter, []byte(strconv.Itoa(updatedBalance))) //check err is nil
+	updatedBalance, err := ledgerutil.AddAmounts(currentBalance, amount) //update the balance
 	if err != nil {
-		return err
+		return nil, ledgerutil.Wrap(err, "failed to mint into account %s", minter)
+	}
+	err = ctx.GetStub().PutState(minter, ledgerutil.FormatAmount(updatedBalance)) //check err is nil
+	if err != nil {
+		return nil, err
 	}
 
 	//Updating Total supply
 	totalSupplyBytes, err := ctx.GetStub().GetState(totalSupplyKey)
 	if err != nil {
-		return fmt.Errorf("failed to retrieve total token supply: %v", err)
+		return nil, ledgerutil.Wrap(err, "failed to retrieve total token supply")
 	}
 	//set total supply as 0 if no data shown
 	if totalSupplyBytes == nil {
 		totalSupply = 0
 	} else {
-		totalSupply, _ = strconv.Atoi(string(totalSupplyBytes))
+		totalSupply, err = ledgerutil.ParseAmount(totalSupplyBytes)
+		if err != nil {
+			return nil, ledgerutil.Wrap(err, "failed to read total token supply")
+		}
 	}
 	//total suuply add
-	totalSupply += amount
-	err = ctx.GetStub().PutState(totalSupplyKey, []byte(strconv.Itoa(totalSupply)))
+	totalSupply, err = ledgerutil.AddAmounts(totalSupply, amount)
 	if err != nil {
-		return err
+		return nil, ledgerutil.Wrap(err, "failed to update total supply")
 	}
-
-	//pull transfer event
-	transferEvent := event{"0x0", minter, amount} //0x0 is minter address
-	transferEventJSON, err := json.Marshal(transferEvent)
+	err = ctx.GetStub().PutState(totalSupplyKey, ledgerutil.FormatAmount(totalSupply))
 	if err != nil {
-		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+		return nil, err
 	}
-	err = ctx.GetStub().SetEvent("Transfer", transferEventJSON) //create event under transfer
+
+	//pull transfer event
+	auditor := ledgerutil.NewAuditor(ctx.GetStub())
+	auditor.Change(balanceKind, currentBalance, updatedBalance, minter)
+	auditor.Change(totalSupplyKind, totalSupply-amount, totalSupply)
+	err = auditor.Emit(transferEvent, event{"0x0", minter, amount})
 	if err != nil {
-		return fmt.Errorf("failed to set event: %v", err)
+		return nil, err
 	}
 
-	log.Printf("minter account %s balance updated from %d to %d", minter, currentBalance, updatedBalance)
-
-	return nil
+	return _txResult(ctx, minter, &updatedBalance, nil)
 }
 
 //remove from totalsupply deflation option, same as Mint function except we take away from total supply
-func (s *SmartContract) Burn(ctx contractapi.TransactionContextInterface, amount int) error {
+func (s *SmartContract) Burn(ctx ledgerutil.TransactionContextInterface, amount int) (*TxResult, error) {
 	var currentBalance int
 	var totalSupply int
 
-	verifyClientID, err := ctx.GetClientIdentity().GetMSPID() //check authorization
-
+	err := _checkAccess(ctx, "token.Burn") //check authorization in the access-control chaincode
 	if err != nil {
-		return fmt.Errorf("failed to verify clientID: %v", err)
-	}
-	//we assume that the verifying client is ORG1
-	if verifyClientID != "Org1MSP" {
-		return fmt.Errorf("client %s is not authorized to burn tokens", verifyClientID)
+		return nil, err
 	}
-	//we get the ID of the minter/burner
-	burner, err := ctx.GetClientIdentity().GetID()
+	err = _checkMinterOrg(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get client id: %v", err)
+		return nil, err
 	}
-	if amount <= 0 {
-		return fmt.Errorf("amount must be positive integer")
+	//we get the ID of the minter/burner
+	burner := ctx.GetClientID()
+	v := ledgerutil.NewValidator()
+	v.PositiveAmount("amount", amount)
+	if err := v.Err(); err != nil {
+		return nil, err
 	}
 	burnerBalance, err := ctx.GetStub().GetState(burner)
 	if err != nil {
-		return fmt.Errorf("failed to read burner account %s from state:%v", burner, err)
+		return nil, ledgerutil.Wrap(err, "failed to read burner account %s from state", burner)
 	}
 
 	// If minter current balance doesn't yet exist, we'll create it with a current balance of 0
 	if burnerBalance == nil {
 		currentBalance = 0
 	} else {
-		currentBalance, _ = strconv.Atoi(string(burnerBalance))
+		currentBalance, err = ledgerutil.ParseAmount(burnerBalance)
+		if err != nil {
+			return nil, ledgerutil.Wrap(err, "failed to read burner account %s", burner)
+		}
+	}
+	//tokens owed by obligations not settled yet cannot be burned
+	pending, err := _pendingDebits(ctx, burner)
+	if err != nil {
+		return nil, err
+	}
+	if currentBalance-pending < amount {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeInsufficientFunds, "burner account %s has insufficient funds", burner)
 	}
 	updatedBalance := currentBalance - amount
-	err = ctx.GetStub().PutState(burner, []byte(strconv.Itoa(updatedBalance)))
+	err = ctx.GetStub().PutState(burner, ledgerutil.FormatAmount(updatedBalance))
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	//UPDATE Total supply
 	totalSupplyBytes, err := ctx.GetStub().GetState(totalSupplyKey)
 	if err != nil {
-		return fmt.Errorf("failed to retrieve total token supply: %v", err)
+		return nil, ledgerutil.Wrap(err, "failed to retrieve total token supply")
 	}
 
 	if totalSupplyBytes == nil {
 		totalSupply = 0
 	} else {
-		totalSupply, _ = strconv.Atoi(string(totalSupplyBytes)) // Error handling not needed since Itoa() was used when setting the totalSupply, guaranteeing it was an integer.
+		totalSupply, err = ledgerutil.ParseAmount(totalSupplyBytes)
+		if err != nil {
+			return nil, ledgerutil.Wrap(err, "failed to read total token supply")
+		}
+	}
+	if totalSupply < amount {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeInsufficientFunds, "total supply is less than the burned amount")
 	}
 	//total suuply we TAKE AWAY (Burn)
 	totalSupply -= amount
-	err = ctx.GetStub().PutState(totalSupplyKey, []byte(strconv.Itoa(totalSupply)))
+	err = ctx.GetStub().PutState(totalSupplyKey, ledgerutil.FormatAmount(totalSupply))
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	//pull transfer event
 	//in Ethereum Solidity means 0x0 is the value returned for not-yet created accounts in this case 0x0 would be the main orgs from: json:"from" address. geneis block 0x0
 	//FROM, TO , AMOUNT = creation account at 0x0 , to burner account, specified amount
-	transferEvent := event{"0x0", burner, amount}
-	transferEventJSON, err := json.Marshal(transferEvent)
+	auditor := ledgerutil.NewAuditor(ctx.GetStub())
+	auditor.Change(balanceKind, currentBalance, updatedBalance, burner)
+	auditor.Change(totalSupplyKind, totalSupply+amount, totalSupply)
+	err = auditor.Emit(transferEvent, event{"0x0", burner, amount})
 	if err != nil {
-		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+		return nil, err
 	}
-	err = ctx.GetStub().SetEvent("Transfer", transferEventJSON)
+
+	return _txResult(ctx, burner, &updatedBalance, nil)
+}
+
+//get and verify accountid
+// Users can use this function to get their own account id, which they can then give to others as the payment address
+func (s *SmartContract) ClientAccountID(ctx ledgerutil.TransactionContextInterface) (string, error) {
+	return ctx.GetClientID(), nil
+}
+
+//Return the decoded identity of the client: its ID, org, certificate subject and attributes
+//Unlike ClientAccountID it shows the common name and roles behind the base64 account ID
+func (s *SmartContract) WhoAmI(ctx ledgerutil.TransactionContextInterface) (*ledgerutil.Identity, error) {
+	return ctx.GetIdentity(), nil
+}
+
+//Look up the verified profile of an account in the identity registry chaincode
+//Returns the profile JSON so wallets can show a counterparty name instead of the base64 client ID
+func (s *SmartContract) AccountProfile(ctx ledgerutil.TransactionContextInterface, account string) (string, error) {
+	account = ledgerutil.NormalizeID(account)
+	v := ledgerutil.NewValidator()
+	v.Key("account", account)
+	if err := v.Err(); err != nil {
+		return "", err
+	}
+	args := [][]byte{[]byte("GetProfile"), []byte(account)}
+	response := ctx.GetStub().InvokeChaincode(identityRegistryName, args, "") //empty channel means the channel of this chaincode
+	if response.Status != shim.OK {
+		return "", ledgerutil.Wrap(ledgerutil.ParseError(response.Message), "failed to get profile for account %s", account)
+	}
+
+	return string(response.Payload), nil
+}
+
+//Return the version and commit the chaincode was built from, so operators can check which build each peer runs
+func (s *SmartContract) GetVersion(ctx ledgerutil.TransactionContextInterface) (*ledgerutil.BuildInfo, error) {
+	return ledgerutil.GetBuildInfo(s.Name), nil
+}
+
+//Check the peer can run the contract and read its world state, without changing it
+//Evaluate it to check one peer, or submit it to check the endorsement of every org the policy needs
+func (s *SmartContract) Ping(ctx ledgerutil.TransactionContextInterface) (*ledgerutil.PingResult, error) {
+	return ledgerutil.Ping(ctx.GetStub(), s.Name)
+}
+
+//Turn on-ledger audit records on or off. The changes made by every transaction are always published
+//with its event; with onLedger they are also kept in the world state for GetAuditRecord
+func (s *SmartContract) SetAuditConfig(ctx ledgerutil.TransactionContextInterface, onLedger bool) error {
+	err := _checkAccess(ctx, "token.SetAuditConfig") //check authorization in the access-control chaincode
 	if err != nil {
-		return fmt.Errorf("failed to set event: %v", err)
+		return err
 	}
+	return ledgerutil.PutAuditConfig(ctx.GetStub(), ledgerutil.AuditConfig{OnLedger: onLedger})
+}
 
-	log.Printf("burner account %s balance updated from %d to %d", burner, currentBalance, updatedBalance)
+//Set the largest page size of GetBalancesPage and GetAllowancesPage and the most results a query returns at once
+//Only clients allowed token.SetQueryConfig in the access-control chaincode may change them
+func (s *SmartContract) SetQueryConfig(ctx ledgerutil.TransactionContextInterface, maxPageSize int, maxResults int) error {
+	err := _checkAccess(ctx, "token.SetQueryConfig") //check authorization in the access-control chaincode
+	if err != nil {
+		return err
+	}
+	return ledgerutil.PutQueryConfig(ctx, ledgerutil.QueryConfig{MaxPageSize: maxPageSize, MaxResults: maxResults})
+}
 
-	return nil
+//Return the query limits, the defaults until SetQueryConfig is called
+func (s *SmartContract) GetQueryConfig(ctx ledgerutil.TransactionContextInterface) (*ledgerutil.QueryConfig, error) {
+	config, err := ledgerutil.GetQueryConfig(ctx.GetStub())
+	if err != nil {
+		return nil, err
+	}
+	return &config, nil
 }
 
-//get and verify accountid
-// Users can use this function to get their own account id, which they can then give to others as the payment address
-func (s *SmartContract) ClientAccountID(ctx contractapi.TransactionContextInterface) (string, error) {
-	clientAccountID, err := ctx.GetClientIdentity().GetID()
+//Read the balance and allowance changes made by a transaction, kept while on-ledger audit records are on
+func (s *SmartContract) GetAuditRecord(ctx ledgerutil.TransactionContextInterface, txID string) (*ledgerutil.AuditRecord, error) {
+	txID = ledgerutil.NormalizeID(txID)
+	v := ledgerutil.NewValidator()
+	v.Key("txID", txID)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	return ledgerutil.GetAuditRecord(ctx.GetStub(), txID)
+}
+
+//Return the number of tokens minted and not burned
+func (s *SmartContract) TotalSupply(ctx ledgerutil.TransactionContextInterface) (int, error) {
+	totalSupplyBytes, err := ctx.GetStub().GetState(totalSupplyKey)
+	if err != nil {
+		return 0, ledgerutil.Wrap(err, "failed to read total supply from world state")
+	}
+	if totalSupplyBytes == nil {
+		return 0, nil //nothing minted yet
+	}
+	totalSupply, err := ledgerutil.ParseAmount(totalSupplyBytes)
+	if err != nil {
+		return 0, ledgerutil.Wrap(err, "failed to read total supply")
+	}
+	return totalSupply, nil
+}
+
+//List up to pageSize account balances in key order starting at bookmark, empty for the first page
+//Balances are the only simple keys besides the total supply, audit and query configs and schema version, which are left out
+func (s *SmartContract) GetBalancesPage(ctx ledgerutil.TransactionContextInterface, pageSize int, bookmark string) (*BalancePage, error) {
+	_, err := ledgerutil.CheckPageSize(ctx.GetStub(), pageSize) //at most the maxPageSize of the query config
+	if err != nil {
+		return nil, err
+	}
+	//a range query over simple keys skips the composite keys of allowances, audit entries and the assets of a bundled asset contract
+	iterator, metadata, err := ctx.GetStub().GetStateByRangeWithPagination("", "", int32(pageSize), bookmark)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to get balances from world state")
+	}
+	defer iterator.Close()
+
+	page := &BalancePage{Balances: []AccountBalance{}}
+	for iterator.HasNext() {
+		result, err := iterator.Next()
+		if err != nil {
+			return nil, ledgerutil.Wrap(err, "failed to iterate balances")
+		}
+		if result.Key == totalSupplyKey || result.Key == ledgerutil.AuditConfigKey || result.Key == ledgerutil.QueryConfigKey ||
+			result.Key == ledgerutil.SchemaVersionKey || result.Key == ledgerutil.SchemaMigrationKey {
+			continue
+		}
+		balance, err := ledgerutil.ParseAmount(result.Value)
+		if err != nil {
+			return nil, ledgerutil.Wrap(err, "failed to read balance of account %s", result.Key)
+		}
+		page.Balances = append(page.Balances, AccountBalance{Account: result.Key, Balance: balance})
+	}
+	//the page is full when the peer returned pageSize keys, even if some were not balances
+	if metadata.FetchedRecordsCount == int32(pageSize) {
+		page.Bookmark = metadata.Bookmark
+	}
+	return page, nil
+}
+
+//List up to pageSize allowances in owner and spender order starting at bookmark, empty for the first page
+func (s *SmartContract) GetAllowancesPage(ctx ledgerutil.TransactionContextInterface, pageSize int, bookmark string) (*AllowancePage, error) {
+	_, err := ledgerutil.CheckPageSize(ctx.GetStub(), pageSize) //at most the maxPageSize of the query config
 	if err != nil {
-		return "", fmt.Errorf("failed to get client id: %v", err)
+		return nil, err
 	}
+	iterator, metadata, err := ctx.GetStub().GetStateByPartialCompositeKeyWithPagination(allowancePrefix, []string{}, int32(pageSize), bookmark)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to get allowances from world state")
+	}
+	defer iterator.Close()
 
-	return clientAccountID, nil
+	page := &AllowancePage{Allowances: []OwnerAllowance{}}
+	for iterator.HasNext() {
+		result, err := iterator.Next()
+		if err != nil {
+			return nil, ledgerutil.Wrap(err, "failed to iterate allowances")
+		}
+		_, attributes, err := ctx.GetStub().SplitCompositeKey(result.Key)
+		if err != nil || len(attributes) != 2 {
+			return nil, ledgerutil.Errorf(ledgerutil.CodeCorruptState, "invalid allowance key %q", result.Key)
+		}
+		allowance, err := ledgerutil.ParseAmount(result.Value)
+		if err != nil {
+			return nil, ledgerutil.Wrap(err, "failed to read allowance of %s for %s", attributes[0], attributes[1])
+		}
+		page.Allowances = append(page.Allowances, OwnerAllowance{Owner: attributes[0], Spender: attributes[1], Allowance: allowance})
+	}
+	if metadata.FetchedRecordsCount == int32(pageSize) {
+		page.Bookmark = metadata.Bookmark
+	}
+	return page, nil
 }
 
-//Used to help with transfer function and transferfrom, works out neccessary calcs.
-func _transferCalc(ctx contractapi.TransactionContextInterface, from string, receiver string, amount int) error {
+//List up to pageSize raw entries of a composite key prefix starting at bookmark, empty for the first page
+//objectType is one of listableObjectTypes, e.g. allowance, and partialKeys the leading attributes of the key, e.g. an owner
+//Only clients allowed token.ListByCompositeKey in the access-control chaincode may list, as audit entries name other clients
+func (s *SmartContract) ListByCompositeKey(ctx ledgerutil.TransactionContextInterface, objectType string, partialKeys []string, pageSize int, bookmark string) (*ledgerutil.KeyPage, error) {
+	v := ledgerutil.NewValidator()
+	v.OneOf("objectType", objectType, listableObjectTypes)
+	for i := range partialKeys {
+		partialKeys[i] = ledgerutil.NormalizeID(partialKeys[i])
+		v.Key("partialKeys", partialKeys[i])
+	}
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	_, err := ledgerutil.CheckPageSize(ctx.GetStub(), pageSize)
+	if err != nil {
+		return nil, err
+	}
+	err = _checkAccess(ctx, "token.ListByCompositeKey")
+	if err != nil {
+		return nil, err
+	}
+	return ledgerutil.ListByCompositeKey(ctx.GetStub(), objectType, partialKeys, int32(pageSize), bookmark)
+}
+
+//Return the schema version of the token records, 1 before any migration
+func (s *SmartContract) GetSchemaVersion(ctx ledgerutil.TransactionContextInterface) (int, error) {
+	return ledgerutil.GetSchemaVersion(ctx.GetStub())
+}
+
+//Rewrite up to pageSize records from the format of fromVersion to that of toVersion after an upgrade
+//Call it with the returned bookmark until it is empty, the schema version is then toVersion
+//Only clients allowed token.MigrateState in the access-control chaincode may migrate
+func (s *SmartContract) MigrateState(ctx ledgerutil.TransactionContextInterface, fromVersion int, toVersion int, pageSize int, bookmark string) (*ledgerutil.MigrationProgress, error) {
+	if pageSize <= 0 || pageSize > maxMigrationPageSize {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "invalid arguments: pageSize must be between 1 and %d", maxMigrationPageSize)
+	}
+	err := _checkAccess(ctx, "token.MigrateState")
+	if err != nil {
+		return nil, err
+	}
+	return ledgerutil.MigrateState(ctx.GetStub(), migrations, fromVersion, toVersion, int32(pageSize), bookmark)
+}
+
+//Used to help with transfer function and transferfrom, writes the balances worked out by _planTransfer
+//Returns the balance of from after the transfer, and records both balance changes with the auditor
+//In deferred settlement mode it records an obligation instead and returns when it settles
+func _transferCalc(ctx contractapi.TransactionContextInterface, auditor *ledgerutil.Auditor, from string, receiver string, amount int) (int, string, error) {
+	plan, err := _planTransfer(ctx, from, receiver, amount)
+	if err != nil {
+		return 0, "", err
+	}
+	//transfers withholding tax settle at once, so the tax account is credited with the withholding entry
+	deferred, err := ledgerutil.FlagEnabled(ctx.GetStub(), deferredSettlementFlag)
+	if err != nil {
+		return 0, "", err
+	}
+	if deferred && plan.withholding == nil {
+		obligation, err := _deferTransfer(ctx, from, receiver, amount)
+		if err != nil {
+			return 0, "", err
+		}
+		return plan.fromCurrent - plan.fromPending - amount, ledgerutil.FormatTime(obligation.SettlesAt), nil
+	}
+
+	err = ctx.GetStub().PutState(from, ledgerutil.FormatAmount(plan.fromUpdated))
+	if err != nil {
+		return 0, "", err
+	}
+
+	err = ctx.GetStub().PutState(receiver, ledgerutil.FormatAmount(plan.toUpdated))
+	if err != nil {
+		return 0, "", err
+	}
+
+	auditor.Change(balanceKind, plan.fromCurrent, plan.fromUpdated, from)
+	auditor.Change(balanceKind, plan.toCurrent, plan.toUpdated, receiver)
+
+	if plan.withholding != nil {
+		err = _putWithholding(ctx, auditor, plan.withholding, from, receiver, amount)
+		if err != nil {
+			return 0, "", err
+		}
+	}
+	return plan.fromUpdated, "", nil
+}
+
+//Works out the balances of both accounts after a transfer without writing them
+//Fails as the transfer would, so the simulate functions predict the same errors
+func _planTransfer(ctx contractapi.TransactionContextInterface, from string, receiver string, amount int) (*transferPlan, error) {
 	var toCurrentBalance int
 	//check to make sure addresses are different
 	if from == receiver {
-		return fmt.Errorf("failed to and from are both the same addresses ")
+		return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "failed to and from are both the same addresses ")
 	}
 	//check values is not negative
 	if amount < 0 {
-		return fmt.Errorf("failed, amount less than zero")
+		return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "failed, amount less than zero")
+	}
+	//check the amount is within the maxTransferAmount configuration parameter, 0 for no limit
+	maxAmount, err := ledgerutil.GetConfigInt(ctx.GetStub(), maxTransferAmountParam)
+	if err != nil {
+		return nil, err
+	}
+	if maxAmount > 0 && amount > maxAmount {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "failed, amount %d is more than the largest transfer of %d", amount, maxAmount)
+	}
+	//in strict KYC mode both accounts need a verified profile, the spender of a TransferFrom was checked when it was approved
+	err = _checkKYC(ctx, from, receiver)
+	if err != nil {
+		return nil, err
+	}
+	//while strictRecipients is on the receiver must have registered its account
+	err = _checkRecipient(ctx, receiver)
+	if err != nil {
+		return nil, err
 	}
 
 	//read ledger get currentbalancebytes
@@ -360,23 +919,31 @@ func _transferCalc(ctx contractapi.TransactionContextInterface, from string, rec
 	//check currentbalance is not nil
 	fromCurrentBalanceBytes, err := ctx.GetStub().GetState(from)
 	if err != nil {
-		return fmt.Errorf("failed to get client account balance: %v", err)
+		return nil, ledgerutil.Wrap(err, "failed to get client account balance")
 	}
 	//convert fromcurrentbalancebytes using strconv.atoi to create fromcurrentbalance
 	if fromCurrentBalanceBytes == nil {
-		return fmt.Errorf("client account %s has no balance", from)
+		return nil, ledgerutil.Errorf(ledgerutil.CodeAccountNotFound, "client account %s has no balance", from)
+	}
+	fromCurrentBalance, err := ledgerutil.ParseAmount(fromCurrentBalanceBytes)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to read client account %s", from)
 	}
-	fromCurrentBalance, _ := strconv.Atoi(string(fromCurrentBalanceBytes))
 
+	//tokens owed by obligations not settled yet cannot be spent again
+	fromPending, err := _pendingDebits(ctx, from)
+	if err != nil {
+		return nil, err
+	}
 	//if fromcurrentbalance less than value fail
-	if fromCurrentBalance < amount {
-		return fmt.Errorf("failed, client account %s has insufficient funds", from)
+	if fromCurrentBalance-fromPending < amount {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeInsufficientFunds, "failed, client account %s has insufficient funds", from)
 	}
 	//receiver address read GetStub.Get.State(to)
 	//check err
 	toCurrentBalanceBytes, err := ctx.GetStub().GetState(receiver)
 	if err != nil {
-		return fmt.Errorf("failed to get receiver account %s from world state:%v", receiver, err)
+		return nil, ledgerutil.Wrap(err, "failed to get receiver account %s from world state", receiver)
 	}
 
 	//if no balance for client create a empty one and set to 0
@@ -385,28 +952,129 @@ func _transferCalc(ctx contractapi.TransactionContextInterface, from string, rec
 	if toCurrentBalanceBytes == nil {
 		toCurrentBalance = 0
 	} else {
-		toCurrentBalance, _ = strconv.Atoi(string(toCurrentBalanceBytes))
+		toCurrentBalance, err = ledgerutil.ParseAmount(toCurrentBalanceBytes)
+		if err != nil {
+			return nil, ledgerutil.Wrap(err, "failed to read receiver account %s", receiver)
+		}
 	}
 
 	//update balances
 	//fromupdatedblance fromcurrentbalance - value
 	//toupdatedbalance tocurrentbalance + value
 
+	//the receiver gets the amount less what is withheld for the tax account
+	withholding, err := _planWithholding(ctx, from, receiver, amount)
+	if err != nil {
+		return nil, err
+	}
+	received := amount
+	if withholding != nil {
+		received -= withholding.withheld
+	}
+
 	fromUpdatedBalance := fromCurrentBalance - amount
-	toUpdatedBalance := toCurrentBalance + amount
+	toUpdatedBalance, err := ledgerutil.AddAmounts(toCurrentBalance, received)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to credit receiver account %s", receiver)
+	}
+
+	return &transferPlan{
+		fromCurrent: fromCurrentBalance,
+		fromUpdated: fromUpdatedBalance,
+		fromPending: fromPending,
+		toCurrent:   toCurrentBalance,
+		toUpdated:   toUpdatedBalance,
+		withholding: withholding,
+	}, nil
+}
 
-	err = ctx.GetStub().PutState(from, []byte(strconv.Itoa(fromUpdatedBalance)))
+//Reads the allowance the owner from has given spender and checks it covers amount
+//Returns the world state key of the allowance and its current value
+func _spendableAllowance(ctx contractapi.TransactionContextInterface, from string, spender string, amount int) (string, int, error) {
+	var currentAllowance int
+	allowanceKey, err := ctx.GetStub().CreateCompositeKey(allowancePrefix, []string{from, spender}) //get allowancekey by creating composite
 	if err != nil {
-		return err
+		return "", 0, ledgerutil.Wrap(err, "failed to create the composite key for prefix %s", allowancePrefix)
 	}
 
-	err = ctx.GetStub().PutState(receiver, []byte(strconv.Itoa(toUpdatedBalance)))
+	currAllowanceTemp, err := ctx.GetStub().GetState(allowanceKey) //getstate accesses the ledger pass in allowance key to verify
 	if err != nil {
-		return err
+		return "", 0, ledgerutil.Wrap(err, "failed to retrieve the allowance for %s from world state", allowanceKey)
+	}
+	if currAllowanceTemp != nil {
+		currentAllowance, err = ledgerutil.ParseAmount(currAllowanceTemp)
+		if err != nil {
+			return "", 0, ledgerutil.Wrap(err, "failed to read the allowance for %s", allowanceKey)
+		}
+	}
+	expired, err := _allowanceExpired(ctx, from, spender)
+	if err != nil {
+		return "", 0, err
+	}
+	if expired {
+		return "", 0, ledgerutil.Errorf(ledgerutil.CodeInsufficientAllowance, "allowance of spender has expired")
+	}
+	if currentAllowance < amount {
+		return "", 0, ledgerutil.Errorf(ledgerutil.CodeInsufficientAllowance, "spender does not have enough allowance to transfer") //check amount vs currentallowance
+	}
+	return allowanceKey, currentAllowance, nil
+}
+
+//build the result returned to the client from the transaction ID and timestamp, which are the same on every endorsing peer
+func _txResult(ctx contractapi.TransactionContextInterface, account string, balance *int, allowance *int) (*TxResult, error) {
+	txID, timestamp, err := ledgerutil.TxInfo(ctx.GetStub())
+	if err != nil {
+		return nil, err
+	}
+	result := &TxResult{
+		Status:    ledgerutil.StatusSuccess,
+		TxID:      txID,
+		Timestamp: timestamp,
+		Account:   account,
 	}
+	if balance != nil {
+		result.Balance = *balance
+	}
+	if allowance != nil {
+		result.Allowance = *allowance
+	}
+	return result, nil
+}
 
-	log.Printf("client %s %s balance updated from %d to %d", from, TokenName, fromCurrentBalance, fromUpdatedBalance)
-	log.Printf("recipient %s %s balance updated from %d to %d", receiver, TokenName, toCurrentBalance, toUpdatedBalance)
+//Ask the access-control chaincode whether the submitting client may perform the operation
+//The ACL chaincode sees the same submitting client, so roles are checked for this client and its org
+func _checkAccess(ctx contractapi.TransactionContextInterface, operation string) error {
+	return ledgerutil.CheckAccess(ctx, accessControlName, operation)
+}
+
+//Check the client's org is one of the minterOrgs configuration parameter, any org may mint and burn while it is empty
+func _checkMinterOrg(ctx ledgerutil.TransactionContextInterface) error {
+	minterOrgs, err := ledgerutil.GetConfigStrings(ctx.GetStub(), minterOrgsParam)
+	if err != nil {
+		return err
+	}
+	if len(minterOrgs) == 0 {
+		return nil
+	}
+	for _, org := range minterOrgs {
+		if ctx.GetIdentity().InOrg(org) {
+			return nil
+		}
+	}
+	return ledgerutil.Errorf(ledgerutil.CodeNotAuthorized, "clients of %s cannot mint or burn, the minter orgs are %s", ctx.GetClientMSPID(), strings.Join(minterOrgs, ", "))
+}
 
+//Check the accounts have a verified profile in the identity registry chaincode while the strictKYC feature flag is on
+func _checkKYC(ctx contractapi.TransactionContextInterface, accounts ...string) error {
+	strict, err := ledgerutil.FlagEnabled(ctx.GetStub(), ledgerutil.StrictKYCFlag)
+	if err != nil || !strict {
+		return err
+	}
+	for _, account := range accounts {
+		err = ledgerutil.CheckRegistered(ctx, identityRegistryName, "GetProfile", account)
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
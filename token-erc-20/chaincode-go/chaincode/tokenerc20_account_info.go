@@ -0,0 +1,146 @@
+package chaincode
+
+import (
+	"encoding/json"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil"
+)
+
+// AccountInfo is what a wallet shows of an account on one screen. The token has no frozen accounts,
+// so a transfer from an account is only blocked by its balance, its KYC status while the strictKYC
+// flag is on and the registration of the receiver while the strictRecipients flag is on.
+type AccountInfo struct {
+	Account string `json:"account"`
+	// Balance is 0 for an account that never held tokens
+	Balance int `json:"balance"`
+	// KYCVerified is whether the identity registry has a verified profile of the account
+	KYCVerified bool `json:"kycVerified"`
+	// Registered is whether the owner of the account registered it with RegisterAccount
+	Registered bool `json:"registered"`
+	// OutboundAllowances and InboundAllowances count the unexpired allowances above 0 the account
+	// has given spenders and has been given by owners
+	OutboundAllowances int `json:"outboundAllowances"`
+	InboundAllowances  int `json:"inboundAllowances"`
+	// LastActivity is when the balance of the account last changed, unset while it never held tokens
+	LastActivity string `json:"lastActivity,omitempty" metadata:",optional"`
+	// Holds are the deposits of the account locked in payment channels that have not settled
+	Holds []AccountHold `json:"holds"`
+}
+
+// AccountHold is part of the tokens of an account locked outside its balance
+type AccountHold struct {
+	ChannelID string `json:"channelID"`
+	Status    string `json:"status"`
+	Amount    int    `json:"amount"`
+}
+
+// Return the balance, KYC status, allowances, last activity and holds of an account in one query
+// Counting the inbound allowances and the holds reads every allowance and payment channel, and fails
+// with RESULTS_TRUNCATED past the maxResults of the query config
+func (s *SmartContract) GetAccountInfo(ctx ledgerutil.TransactionContextInterface, account string) (*AccountInfo, error) {
+	account = ledgerutil.NormalizeID(account)
+	v := ledgerutil.NewValidator()
+	v.Key("account", account)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	config, err := ledgerutil.GetQueryConfig(ctx.GetStub())
+	if err != nil {
+		return nil, err
+	}
+
+	info := &AccountInfo{Account: account, Holds: []AccountHold{}}
+	info.Balance, err = s.BalanceOf(ctx, account)
+	if err != nil && ledgerutil.CodeOf(err) != ledgerutil.CodeAccountNotFound {
+		return nil, err
+	}
+	//the registry fails for an account without a verified profile
+	info.KYCVerified = ledgerutil.CheckRegistered(ctx, identityRegistryName, "GetProfile", account) == nil
+	registration, err := _readRegistration(ctx, account)
+	if err != nil {
+		return nil, err
+	}
+	info.Registered = registration != nil
+	info.LastActivity, err = _lastActivity(ctx, account)
+	if err != nil {
+		return nil, err
+	}
+
+	//allowance keys start with the owner, so the inbound ones are found by reading them all
+	read := 0
+	err = ledgerutil.ForEachByPartialCompositeKey(ctx.GetStub(), allowancePrefix, []string{}, func(attributes []string, value []byte) error {
+		if read == config.MaxResults {
+			return ledgerutil.ResultsTruncated(config.MaxResults, "GetAllowancesPage")
+		}
+		read++
+		if len(attributes) != 2 || (attributes[0] != account && attributes[1] != account) {
+			return nil
+		}
+		allowance, err := ledgerutil.ParseAmount(value)
+		if err != nil {
+			return ledgerutil.Wrap(err, "failed to read allowance of %s for %s", attributes[0], attributes[1])
+		}
+		if allowance == 0 {
+			return nil
+		}
+		expired, err := _allowanceExpired(ctx, attributes[0], attributes[1])
+		if err != nil || expired {
+			return err
+		}
+		if attributes[0] == account {
+			info.OutboundAllowances++
+		} else {
+			info.InboundAllowances++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	read = 0
+	err = ledgerutil.ForEachByPartialCompositeKey(ctx.GetStub(), paymentChannelPrefix, []string{}, func(_ []string, value []byte) error {
+		if read == config.MaxResults {
+			return ledgerutil.ResultsTruncated(config.MaxResults, "ListByCompositeKey")
+		}
+		read++
+		var channel PaymentChannel
+		err := json.Unmarshal(value, &channel)
+		if err != nil {
+			return ledgerutil.Errorf(ledgerutil.CodeCorruptState, "failed to unmarshal payment channel: %v", err)
+		}
+		if channel.Opener == account && channel.Status != ChannelSettled {
+			info.Holds = append(info.Holds, AccountHold{ChannelID: channel.ChannelID, Status: channel.Status, Amount: channel.Deposit})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// Return when the balance of an account was last written, from the newest entry of its history
+func _lastActivity(ctx ledgerutil.TransactionContextInterface, account string) (string, error) {
+	iterator, err := ctx.GetStub().GetHistoryForKey(account)
+	if err != nil {
+		return "", ledgerutil.Wrap(err, "failed to get history of account %s", account)
+	}
+	defer iterator.Close()
+	if !iterator.HasNext() {
+		return "", nil
+	}
+	modification, err := iterator.Next()
+	if err != nil {
+		return "", ledgerutil.Wrap(err, "failed to iterate history of account %s", account)
+	}
+	if modification.Timestamp == nil {
+		return "", nil
+	}
+	timestamp, err := ptypes.Timestamp(modification.Timestamp)
+	if err != nil {
+		return "", ledgerutil.Wrap(err, "failed to read timestamp of account %s", account)
+	}
+	return ledgerutil.FormatTime(timestamp), nil
+}
@@ -0,0 +1,126 @@
+package chaincode
+
+import (
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil"
+)
+
+// allowanceTermsPrefix is the prefix of the terms of allowances, keyed by owner and spender like
+// the allowances
+const allowanceTermsPrefix = "allowanceterms"
+
+// AllowanceTerms are the expiry and reference an owner set on the allowance of a spender with
+// ApproveWithTerms. Nothing can be spent of the allowance from ExpiresAt, an RFC 3339 time, on, and
+// Reference tells the spender what the allowance is for, e.g. a contract or invoice number.
+type AllowanceTerms struct {
+	Owner     string `json:"owner"`
+	Spender   string `json:"spender"`
+	ExpiresAt string `json:"expiresAt,omitempty" metadata:",optional"`
+	Reference string `json:"reference,omitempty" metadata:",optional"`
+}
+
+// ApproveWithTerms is Approve with an RFC 3339 time the allowance expires at and a reference shown
+// to the spender, each optional when empty. Both are in the Approval event and GetAllowanceTerms.
+func (s *SmartContract) ApproveWithTerms(ctx ledgerutil.TransactionContextInterface, spender string, amount int, expiresAt string, reference string) (*TxResult, error) {
+	reference = ledgerutil.NormalizeID(reference)
+	v := ledgerutil.NewValidator()
+	v.Text("reference", reference, ledgerutil.MaxKeyLength)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	terms := &AllowanceTerms{Reference: reference}
+	if expiresAt != "" {
+		expiry, err := time.Parse(time.RFC3339, expiresAt)
+		if err != nil {
+			return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "invalid arguments: expiresAt must be an RFC 3339 time such as 2024-01-31T12:00:00Z, not %q", expiresAt)
+		}
+		timestamp, err := ledgerutil.TxTime(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if !expiry.After(timestamp) {
+			return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "invalid arguments: expiresAt %s is not after the transaction time %s",
+				expiresAt, timestamp.Format(time.RFC3339))
+		}
+		terms.ExpiresAt = ledgerutil.FormatTime(expiry)
+	}
+	if terms.ExpiresAt == "" && terms.Reference == "" {
+		terms = nil
+	}
+	return _approve(ctx, spender, amount, terms)
+}
+
+// GetAllowanceTerms returns the expiry and reference of the allowance owner gave spender, empty for
+// an allowance set by Approve
+func (s *SmartContract) GetAllowanceTerms(ctx ledgerutil.TransactionContextInterface, owner string, spender string) (*AllowanceTerms, error) {
+	owner = ledgerutil.NormalizeID(owner)
+	spender = ledgerutil.NormalizeID(spender)
+	v := ledgerutil.NewValidator()
+	v.Key("owner", owner)
+	v.Key("spender", spender)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	terms, err := _readAllowanceTerms(ctx, owner, spender)
+	if err != nil {
+		return nil, err
+	}
+	if terms == nil {
+		return &AllowanceTerms{Owner: owner, Spender: spender}, nil
+	}
+	return terms, nil
+}
+
+// _putAllowanceTerms stores the terms of an allowance, or removes them when terms is nil
+func _putAllowanceTerms(ctx contractapi.TransactionContextInterface, owner string, spender string, terms *AllowanceTerms) error {
+	key, err := ctx.GetStub().CreateCompositeKey(allowanceTermsPrefix, []string{owner, spender})
+	if err != nil {
+		return ledgerutil.Wrap(err, "failed to create the composite key for prefix %s", allowanceTermsPrefix)
+	}
+	if terms == nil {
+		return ctx.GetStub().DelState(key)
+	}
+	terms.Owner, terms.Spender = owner, spender
+	err = ledgerutil.PutJSON(ctx.GetStub(), key, terms)
+	if err != nil {
+		return ledgerutil.Wrap(err, "failed to put allowance terms")
+	}
+	return nil
+}
+
+// _readAllowanceTerms reads the terms of an allowance, nil when it has none
+func _readAllowanceTerms(ctx contractapi.TransactionContextInterface, owner string, spender string) (*AllowanceTerms, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(allowanceTermsPrefix, []string{owner, spender})
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to create the composite key for prefix %s", allowanceTermsPrefix)
+	}
+	var terms AllowanceTerms
+	found, err := ledgerutil.ReadJSON(ctx.GetStub(), key, &terms)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to read allowance terms")
+	}
+	if !found {
+		return nil, nil
+	}
+	return &terms, nil
+}
+
+// _allowanceExpired reports whether the allowance owner gave spender has an expiry the transaction
+// time has reached
+func _allowanceExpired(ctx contractapi.TransactionContextInterface, owner string, spender string) (bool, error) {
+	terms, err := _readAllowanceTerms(ctx, owner, spender)
+	if err != nil || terms == nil || terms.ExpiresAt == "" {
+		return false, err
+	}
+	expiry, err := ledgerutil.ParseTime(terms.ExpiresAt)
+	if err != nil {
+		return false, err
+	}
+	timestamp, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return false, err
+	}
+	return !expiry.After(timestamp), nil
+}
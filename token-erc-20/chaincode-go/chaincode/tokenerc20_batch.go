@@ -0,0 +1,259 @@
+package chaincode
+
+import (
+	"sort"
+
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil"
+)
+
+// maxBatchPayments bounds the number of payments one BatchTransfer makes
+const maxBatchPayments = 100
+
+// batchTransferEvent is emitted by BatchTransfer in place of a Transfer event per payment
+var batchTransferEvent = ledgerutil.EventSchema{Name: "BatchTransfer", Version: 1,
+	Description: "tokens moved from one or more accounts to several in one debit per account, value being the total",
+	Fields:      map[string]string{"from": "string", "payments": "array", "value": "integer", "audit": "object"}}
+
+// Payment is one receiver and amount of a BatchTransfer. From, when set to an account other than
+// the client's, pulls the amount from that account against the client's allowance, as TransferFrom.
+type Payment struct {
+	From     string `json:"from,omitempty" metadata:",optional"`
+	Receiver string `json:"receiver"`
+	Amount   int    `json:"amount"`
+}
+
+// batchTransfer is the payload of the BatchTransfer event
+type batchTransfer struct {
+	From     string    `json:"from"`
+	Payments []Payment `json:"payments"`
+	Value    int       `json:"value"`
+}
+
+// BatchTransfer moves tokens to several receivers in one transaction, debiting every paying account
+// once with its total. A chaincode paying more than one account in a transaction, e.g. a seller and
+// a royalty, calls it instead of Transfer or TransferFrom once per payment: each of those reads the
+// balances the transaction started with, so the last one would overwrite the others. Payments are
+// made from the client account, or pulled from the account in From against the client's allowance.
+// Payments between the same two accounts are added up, and tax is withheld from each as by Transfer.
+// A batch settles at once in deferred settlement mode too, and no paying account may spend what it
+// owes by obligations. The result holds the client balance after the batch, unset when the client
+// only pulled from other accounts.
+func (s *SmartContract) BatchTransfer(ctx ledgerutil.TransactionContextInterface, payments []Payment) (*TxResult, error) {
+	if len(payments) == 0 || len(payments) > maxBatchPayments {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "invalid arguments: a batch has 1 to %d payments, got %d", maxBatchPayments, len(payments))
+	}
+	v := ledgerutil.NewValidator()
+	for i := range payments {
+		payments[i].Receiver = ledgerutil.NormalizeID(payments[i].Receiver)
+		payments[i].From = ledgerutil.NormalizeID(payments[i].From)
+		v.Key("receiver", payments[i].Receiver)
+		if payments[i].From != "" {
+			v.Key("from", payments[i].From)
+		}
+		v.PositiveAmount("amount", payments[i].Amount)
+	}
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	clientID := ctx.GetClientID()
+
+	//add up the payments per pair of accounts, keeping the order pairs first appear in,
+	//and what every account is debited and credited and pulled against allowances
+	var merged []Payment
+	index := make(map[[2]string]int)
+	credits := make(map[string]int)
+	debits := make(map[string]int)
+	pulls := make(map[string]int)
+	total := 0
+	for _, payment := range payments {
+		from := payment.From
+		if from == "" {
+			from = clientID
+		}
+		if payment.Receiver == from {
+			return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "failed to transfer: receiver must differ from the paying account")
+		}
+		var err error
+		total, err = ledgerutil.AddAmounts(total, payment.Amount)
+		if err != nil {
+			return nil, ledgerutil.Wrap(err, "failed to add up the payments")
+		}
+
+		pair := [2]string{from, payment.Receiver}
+		if i, ok := index[pair]; ok {
+			merged[i].Amount += payment.Amount
+		} else {
+			index[pair] = len(merged)
+			merged = append(merged, Payment{From: payment.From, Receiver: payment.Receiver, Amount: payment.Amount})
+		}
+		if from != clientID {
+			pulls[from] += payment.Amount
+		}
+		debits[from] += payment.Amount
+		credits[payment.Receiver] += payment.Amount
+	}
+
+	//every merged payment is within the maxTransferAmount configuration parameter, 0 for no limit
+	maxAmount, err := ledgerutil.GetConfigInt(ctx.GetStub(), maxTransferAmountParam)
+	if err != nil {
+		return nil, err
+	}
+	for _, payment := range merged {
+		if maxAmount > 0 && payment.Amount > maxAmount {
+			return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "failed, amount %d to %s is more than the largest transfer of %d", payment.Amount, payment.Receiver, maxAmount)
+		}
+		//while strictRecipients is on every receiver must have registered its account
+		err = _checkRecipient(ctx, payment.Receiver)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	//every account and allowance is read and written once, in a fixed order so every endorsing peer records the same changes
+	owners := make([]string, 0, len(pulls))
+	for owner := range pulls {
+		owners = append(owners, owner)
+	}
+	sort.Strings(owners)
+	auditor := ledgerutil.NewAuditor(ctx.GetStub()) //collects the balance and allowance changes published with the event
+	for _, owner := range owners {
+		err = _spendAllowance(ctx, auditor, owner, clientID, pulls[owner])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	accounts := make([]string, 0, len(debits)+len(credits))
+	for account := range debits {
+		accounts = append(accounts, account)
+	}
+	for account := range credits {
+		if _, ok := debits[account]; !ok {
+			accounts = append(accounts, account)
+		}
+	}
+	sort.Strings(accounts)
+	//in strict KYC mode every paying and paid account needs a verified profile, the client pulling from
+	//other accounts was checked when the allowances were approved
+	err = _checkKYC(ctx, accounts...)
+	if err != nil {
+		return nil, err
+	}
+
+	//a payment to an account of a category with a withholding rate pays part of the amount to the tax
+	//account, as Transfer does, and records its withholding entry once the balances are written
+	type withheldPayment struct {
+		from    string
+		payment Payment
+		plan    *withholding
+	}
+	var withheld []withheldPayment
+	for _, payment := range merged {
+		from := payment.From
+		if from == "" {
+			from = clientID
+		}
+		plan, err := _planWithholding(ctx, from, payment.Receiver, payment.Amount)
+		if err != nil {
+			return nil, err
+		}
+		if plan == nil {
+			continue
+		}
+		if _, ok := credits[plan.taxAccount]; !ok {
+			if _, ok := debits[plan.taxAccount]; !ok {
+				accounts = append(accounts, plan.taxAccount)
+			}
+		}
+		credits[payment.Receiver] -= plan.withheld
+		credits[plan.taxAccount] += plan.withheld
+		withheld = append(withheld, withheldPayment{from, payment, plan})
+	}
+	sort.Strings(accounts)
+
+	var clientBalance *int
+	for _, account := range accounts {
+		currentBalanceBytes, err := ctx.GetStub().GetState(account)
+		if err != nil {
+			return nil, ledgerutil.Wrap(err, "failed to get account %s from world state", account)
+		}
+		if currentBalanceBytes == nil && debits[account] > 0 {
+			return nil, ledgerutil.Errorf(ledgerutil.CodeAccountNotFound, "failed to transfer: client account %s has no balance", account)
+		}
+		currentBalance := 0
+		if currentBalanceBytes != nil {
+			currentBalance, err = ledgerutil.ParseAmount(currentBalanceBytes)
+			if err != nil {
+				return nil, ledgerutil.Wrap(err, "failed to read account %s", account)
+			}
+		}
+		//tokens owed by obligations not settled yet cannot be spent again
+		pending := 0
+		if debits[account] > 0 {
+			pending, err = _pendingDebits(ctx, account)
+			if err != nil {
+				return nil, err
+			}
+		}
+		//an account paid and paying in the same batch must cover its payments before what it receives
+		if currentBalance-pending < debits[account] {
+			return nil, ledgerutil.Errorf(ledgerutil.CodeInsufficientFunds, "failed to transfer: client account %s has insufficient funds", account)
+		}
+
+		updatedBalance, err := ledgerutil.AddAmounts(currentBalance-debits[account], credits[account])
+		if err != nil {
+			return nil, ledgerutil.Wrap(err, "failed to credit account %s", account)
+		}
+		err = ctx.GetStub().PutState(account, ledgerutil.FormatAmount(updatedBalance))
+		if err != nil {
+			return nil, err
+		}
+		auditor.Change(balanceKind, currentBalance, updatedBalance, account)
+		if account == clientID {
+			clientBalance = &updatedBalance
+		}
+	}
+
+	for _, w := range withheld {
+		err = _putWithholdingEntry(ctx, w.plan, w.from, w.payment.Receiver, w.payment.Amount)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = auditor.Emit(batchTransferEvent, batchTransfer{clientID, merged, total})
+	if err != nil {
+		return nil, err
+	}
+	return _txResult(ctx, clientID, clientBalance, nil)
+}
+
+// _spendAllowance takes amount off the allowance owner granted spender, recording the change with auditor
+func _spendAllowance(ctx ledgerutil.TransactionContextInterface, auditor *ledgerutil.Auditor, owner string, spender string, amount int) error {
+	allowanceKey, err := ctx.GetStub().CreateCompositeKey(allowancePrefix, []string{owner, spender})
+	if err != nil {
+		return ledgerutil.Wrap(err, "failed to create the composite key for prefix %s", allowancePrefix)
+	}
+	currentAllowanceBytes, err := ctx.GetStub().GetState(allowanceKey)
+	if err != nil {
+		return ledgerutil.Wrap(err, "failed to retrieve the allowance for %s from world state", allowanceKey)
+	}
+	currentAllowance := 0
+	if currentAllowanceBytes != nil {
+		currentAllowance, err = ledgerutil.ParseAmount(currentAllowanceBytes)
+		if err != nil {
+			return ledgerutil.Wrap(err, "failed to read the allowance for %s", allowanceKey)
+		}
+	}
+	if currentAllowance < amount {
+		return ledgerutil.Errorf(ledgerutil.CodeInsufficientAllowance, "failed to transfer: spender does not have enough allowance to transfer from %s", owner)
+	}
+
+	updatedAllowance := currentAllowance - amount
+	err = ctx.GetStub().PutState(allowanceKey, ledgerutil.FormatAmount(updatedAllowance))
+	if err != nil {
+		return err
+	}
+	auditor.Change(allowanceKind, currentAllowance, updatedAllowance, owner, spender)
+	return nil
+}
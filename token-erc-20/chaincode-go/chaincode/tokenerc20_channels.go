@@ -0,0 +1,470 @@
+package chaincode
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"time"
+
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil"
+)
+
+// paymentChannelPrefix is the prefix of the payment channels, keyed by the ID of the transaction
+// opening them
+const paymentChannelPrefix = "paymentchannel"
+
+// statuses of a payment channel
+const (
+	// ChannelPending is a channel the counterparty has not joined yet
+	ChannelPending = "PENDING"
+	// ChannelOpen is a channel both parties exchange signed states of
+	ChannelOpen = "OPEN"
+	// ChannelClosing is a channel closed with a state either party may replace by a later one
+	// until the dispute window ends
+	ChannelClosing = "CLOSING"
+	// ChannelSettled is a channel whose deposit was paid out to the parties
+	ChannelSettled = "SETTLED"
+)
+
+// channelDisputeWindowParam is how long a closed channel waits for a later state before it settles
+var channelDisputeWindowParam = ledgerutil.ConfigParam{Name: "channelDisputeWindow", Kind: ledgerutil.ConfigString, Default: `"24h"`,
+	Description: "Go duration a closed payment channel waits for a later signed state before SettleChannel pays it out"}
+
+// paymentChannelEvent is emitted whenever a payment channel changes status
+var paymentChannelEvent = ledgerutil.EventSchema{Name: "PaymentChannel", Version: 1,
+	Description: "payment channel opened, joined, closed with a signed state or settled",
+	Fields: map[string]string{"channelID": "string", "status": "string", "opener": "string", "counterparty": "string",
+		"deposit": "integer", "sequence": "integer", "openerBalance": "integer", "counterpartyBalance": "integer",
+		"disputeEndsAt": "string", "audit": "object"}}
+
+// PaymentChannel locks a deposit of the opener for payments to and from the counterparty, which
+// the parties make off the ledger by signing ChannelStates. The ledger only sees the channel when
+// it is opened, joined, closed and settled. While it is closing, Sequence and the balances are
+// those of the latest state submitted.
+type PaymentChannel struct {
+	ObjectType   string `json:"objectType"`
+	ChannelID    string `json:"channelID"`
+	Status       string `json:"status"`
+	Opener       string `json:"opener"`
+	Counterparty string `json:"counterparty"`
+	Deposit      int    `json:"deposit"`
+	// OpenerKey and CounterpartyKey are the PEM public keys of the certificates the parties
+	// opened and joined the channel with, which verify their signatures of states
+	OpenerKey           string    `json:"openerKey"`
+	CounterpartyKey     string    `json:"counterpartyKey,omitempty" metadata:",optional"`
+	Sequence            int       `json:"sequence"`
+	OpenerBalance       int       `json:"openerBalance"`
+	CounterpartyBalance int       `json:"counterpartyBalance"`
+	OpenedAt            time.Time `json:"openedAt"`
+	// DisputeEndsAt is the RFC 3339 time the dispute window of a CLOSING channel ends at
+	DisputeEndsAt string `json:"disputeEndsAt,omitempty" metadata:",optional"`
+}
+
+// ChannelState is a split of the deposit of a channel the parties agreed on off the ledger. Each
+// payment raises Sequence, and a later state replaces an earlier one when the channel closes.
+// The state of sequence 0 is the opening one, the whole deposit to the opener.
+type ChannelState struct {
+	ChannelID           string `json:"channelID"`
+	Sequence            int    `json:"sequence"`
+	OpenerBalance       int    `json:"openerBalance"`
+	CounterpartyBalance int    `json:"counterpartyBalance"`
+}
+
+// SignedChannelState is a state with the signatures of both parties: base64 DER ECDSA signatures
+// over the SHA-256 of the canonical JSON of the state. The opening state needs no signatures.
+type SignedChannelState struct {
+	State                 ChannelState `json:"state"`
+	OpenerSignature       string       `json:"openerSignature"`
+	CounterpartySignature string       `json:"counterpartySignature"`
+}
+
+// channelEvent is the payload of the PaymentChannel event
+type channelEvent struct {
+	ChannelID           string `json:"channelID"`
+	Status              string `json:"status"`
+	Opener              string `json:"opener"`
+	Counterparty        string `json:"counterparty"`
+	Deposit             int    `json:"deposit"`
+	Sequence            int    `json:"sequence"`
+	OpenerBalance       int    `json:"openerBalance"`
+	CounterpartyBalance int    `json:"counterpartyBalance"`
+	DisputeEndsAt       string `json:"disputeEndsAt,omitempty"`
+}
+
+// ecdsaSignature is the ASN.1 structure of an ECDSA signature
+type ecdsaSignature struct {
+	R, S *big.Int
+}
+
+// Open a payment channel with counterparty, moving deposit from the client's balance into it
+// The channel is identified by the transaction ID and pending until the counterparty joins it
+func (s *SmartContract) OpenChannel(ctx ledgerutil.TransactionContextInterface, counterparty string, deposit int) (*PaymentChannel, error) {
+	counterparty = ledgerutil.NormalizeID(counterparty)
+	v := ledgerutil.NewValidator()
+	v.Key("counterparty", counterparty)
+	v.PositiveAmount("deposit", deposit)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	opener := ctx.GetClientID()
+	if counterparty == opener {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "invalid arguments: a payment channel needs a counterparty other than the opener")
+	}
+	err := _checkKYC(ctx, opener, counterparty)
+	if err != nil {
+		return nil, err
+	}
+	err = _checkRecipient(ctx, counterparty)
+	if err != nil {
+		return nil, err
+	}
+	key, err := _clientPublicKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	balanceBytes, err := ctx.GetStub().GetState(opener)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to read account %s from world state", opener)
+	}
+	if balanceBytes == nil {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeAccountNotFound, "client account %s has no balance", opener)
+	}
+	balance, err := ledgerutil.ParseAmount(balanceBytes)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to read account %s", opener)
+	}
+	//tokens owed by obligations not settled yet cannot be deposited
+	pending, err := _pendingDebits(ctx, opener)
+	if err != nil {
+		return nil, err
+	}
+	if balance-pending < deposit {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeInsufficientFunds, "client account %s has insufficient funds for a deposit of %d", opener, deposit)
+	}
+	err = ctx.GetStub().PutState(opener, ledgerutil.FormatAmount(balance-deposit))
+	if err != nil {
+		return nil, err
+	}
+
+	txID, timestamp, err := ledgerutil.TxInfo(ctx.GetStub())
+	if err != nil {
+		return nil, err
+	}
+	channel := &PaymentChannel{
+		ObjectType:    paymentChannelPrefix,
+		ChannelID:     txID,
+		Status:        ChannelPending,
+		Opener:        opener,
+		Counterparty:  counterparty,
+		Deposit:       deposit,
+		OpenerKey:     key,
+		OpenerBalance: deposit,
+		OpenedAt:      timestamp,
+	}
+	auditor := ledgerutil.NewAuditor(ctx.GetStub())
+	auditor.Change(balanceKind, balance, balance-deposit, opener)
+	err = _putChannel(ctx, auditor, channel)
+	if err != nil {
+		return nil, err
+	}
+	return channel, nil
+}
+
+// Join a payment channel opened with the client as counterparty, recording the key its signatures
+// of states are verified with
+func (s *SmartContract) JoinChannel(ctx ledgerutil.TransactionContextInterface, channelID string) (*PaymentChannel, error) {
+	channel, err := _readChannel(ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+	if ctx.GetClientID() != channel.Counterparty {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeNotAuthorized, "only the counterparty of payment channel %s can join it", channel.ChannelID)
+	}
+	if channel.Status != ChannelPending {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "payment channel %s is %s, not %s", channel.ChannelID, channel.Status, ChannelPending)
+	}
+	channel.CounterpartyKey, err = _clientPublicKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	channel.Status = ChannelOpen
+	err = _putChannel(ctx, ledgerutil.NewAuditor(ctx.GetStub()), channel)
+	if err != nil {
+		return nil, err
+	}
+	return channel, nil
+}
+
+// Close a payment channel with the JSON of a SignedChannelState, submitted by either party
+// The dispute window starts at the first close; until it ends either party may close the channel
+// again with a state of a higher sequence, which replaces the one submitted before
+// A pending channel can only be closed with the opening state, returning the deposit to the opener
+func (s *SmartContract) CloseChannel(ctx ledgerutil.TransactionContextInterface, finalState string) (*PaymentChannel, error) {
+	var signed SignedChannelState
+	err := json.Unmarshal([]byte(finalState), &signed)
+	if err != nil {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "invalid arguments: finalState is not a signed channel state: %v", err)
+	}
+	state := signed.State
+	channel, err := _readChannel(ctx, state.ChannelID)
+	if err != nil {
+		return nil, err
+	}
+	clientID := ctx.GetClientID()
+	if clientID != channel.Opener && clientID != channel.Counterparty {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeNotAuthorized, "only the parties of payment channel %s can close it", channel.ChannelID)
+	}
+	if state.Sequence < 0 || state.OpenerBalance < 0 || state.CounterpartyBalance < 0 ||
+		state.OpenerBalance > channel.Deposit || state.CounterpartyBalance != channel.Deposit-state.OpenerBalance {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "invalid arguments: the balances of state %d must be non-negative and add up to the deposit of %d",
+			state.Sequence, channel.Deposit)
+	}
+
+	timestamp, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	switch channel.Status {
+	case ChannelPending:
+		if state.Sequence != 0 {
+			return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "payment channel %s was not joined, it can only be closed with the opening state", channel.ChannelID)
+		}
+	case ChannelOpen:
+	case ChannelClosing:
+		disputeEndsAt, err := ledgerutil.ParseTime(channel.DisputeEndsAt)
+		if err != nil {
+			return nil, err
+		}
+		if !timestamp.Before(disputeEndsAt) {
+			return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "the dispute window of payment channel %s ended at %s", channel.ChannelID,
+				disputeEndsAt.Format(time.RFC3339))
+		}
+		if state.Sequence <= channel.Sequence {
+			return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "payment channel %s is closing with state %d, a dispute needs a later state, not %d",
+				channel.ChannelID, channel.Sequence, state.Sequence)
+		}
+	default:
+		return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "payment channel %s is %s", channel.ChannelID, channel.Status)
+	}
+
+	if state.Sequence == 0 {
+		if state.OpenerBalance != channel.Deposit {
+			return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "invalid arguments: the opening state gives the whole deposit of %d to the opener", channel.Deposit)
+		}
+	} else {
+		message, err := ledgerutil.MarshalCanonical(state)
+		if err != nil {
+			return nil, ledgerutil.Wrap(err, "failed to encode state %d", state.Sequence)
+		}
+		err = _verifyChannelSignature(channel.OpenerKey, message, signed.OpenerSignature, "opener")
+		if err != nil {
+			return nil, err
+		}
+		err = _verifyChannelSignature(channel.CounterpartyKey, message, signed.CounterpartySignature, "counterparty")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if channel.Status != ChannelClosing {
+		window, err := _channelDisputeWindow(ctx)
+		if err != nil {
+			return nil, err
+		}
+		channel.DisputeEndsAt = ledgerutil.FormatTime(timestamp.Add(window))
+		channel.Status = ChannelClosing
+	}
+	channel.Sequence = state.Sequence
+	channel.OpenerBalance = state.OpenerBalance
+	channel.CounterpartyBalance = state.CounterpartyBalance
+	err = _putChannel(ctx, ledgerutil.NewAuditor(ctx.GetStub()), channel)
+	if err != nil {
+		return nil, err
+	}
+	return channel, nil
+}
+
+// Pay out a closed payment channel once its dispute window ended, crediting each party its balance
+// of the latest state submitted; either party may settle it
+func (s *SmartContract) SettleChannel(ctx ledgerutil.TransactionContextInterface, channelID string) (*PaymentChannel, error) {
+	channel, err := _readChannel(ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+	clientID := ctx.GetClientID()
+	if clientID != channel.Opener && clientID != channel.Counterparty {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeNotAuthorized, "only the parties of payment channel %s can settle it", channel.ChannelID)
+	}
+	if channel.Status != ChannelClosing {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "payment channel %s is %s, not %s", channel.ChannelID, channel.Status, ChannelClosing)
+	}
+	timestamp, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	disputeEndsAt, err := ledgerutil.ParseTime(channel.DisputeEndsAt)
+	if err != nil {
+		return nil, err
+	}
+	if timestamp.Before(disputeEndsAt) {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "payment channel %s can be settled from %s on, when its dispute window ends", channel.ChannelID,
+			disputeEndsAt.Format(time.RFC3339))
+	}
+
+	auditor := ledgerutil.NewAuditor(ctx.GetStub())
+	payouts := []struct {
+		account string
+		amount  int
+	}{{channel.Opener, channel.OpenerBalance}, {channel.Counterparty, channel.CounterpartyBalance}}
+	for _, payout := range payouts {
+		if payout.amount == 0 {
+			continue
+		}
+		balanceBytes, err := ctx.GetStub().GetState(payout.account)
+		if err != nil {
+			return nil, ledgerutil.Wrap(err, "failed to read account %s from world state", payout.account)
+		}
+		balance := 0
+		if balanceBytes != nil {
+			balance, err = ledgerutil.ParseAmount(balanceBytes)
+			if err != nil {
+				return nil, ledgerutil.Wrap(err, "failed to read account %s", payout.account)
+			}
+		}
+		updated, err := ledgerutil.AddAmounts(balance, payout.amount)
+		if err != nil {
+			return nil, ledgerutil.Wrap(err, "failed to credit account %s", payout.account)
+		}
+		err = ctx.GetStub().PutState(payout.account, ledgerutil.FormatAmount(updated))
+		if err != nil {
+			return nil, err
+		}
+		auditor.Change(balanceKind, balance, updated, payout.account)
+	}
+	channel.Status = ChannelSettled
+	err = _putChannel(ctx, auditor, channel)
+	if err != nil {
+		return nil, err
+	}
+	return channel, nil
+}
+
+// Return a payment channel, with the latest state submitted while it is closing
+func (s *SmartContract) GetChannel(ctx ledgerutil.TransactionContextInterface, channelID string) (*PaymentChannel, error) {
+	return _readChannel(ctx, channelID)
+}
+
+// Store a payment channel and emit the PaymentChannel event of its status
+func _putChannel(ctx ledgerutil.TransactionContextInterface, auditor *ledgerutil.Auditor, channel *PaymentChannel) error {
+	key, err := ctx.GetStub().CreateCompositeKey(paymentChannelPrefix, []string{channel.ChannelID})
+	if err != nil {
+		return ledgerutil.Wrap(err, "failed to create the composite key for prefix %s", paymentChannelPrefix)
+	}
+	err = ledgerutil.PutJSON(ctx.GetStub(), key, channel)
+	if err != nil {
+		return ledgerutil.Wrap(err, "failed to put payment channel %s", channel.ChannelID)
+	}
+	return auditor.Emit(paymentChannelEvent, channelEvent{
+		ChannelID:           channel.ChannelID,
+		Status:              channel.Status,
+		Opener:              channel.Opener,
+		Counterparty:        channel.Counterparty,
+		Deposit:             channel.Deposit,
+		Sequence:            channel.Sequence,
+		OpenerBalance:       channel.OpenerBalance,
+		CounterpartyBalance: channel.CounterpartyBalance,
+		DisputeEndsAt:       channel.DisputeEndsAt,
+	})
+}
+
+// Read a payment channel, failing when it does not exist
+func _readChannel(ctx ledgerutil.TransactionContextInterface, channelID string) (*PaymentChannel, error) {
+	channelID = ledgerutil.NormalizeID(channelID)
+	v := ledgerutil.NewValidator()
+	v.Key("channelID", channelID)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	key, err := ctx.GetStub().CreateCompositeKey(paymentChannelPrefix, []string{channelID})
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to create the composite key for prefix %s", paymentChannelPrefix)
+	}
+	var channel PaymentChannel
+	found, err := ledgerutil.ReadJSON(ctx.GetStub(), key, &channel)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to read payment channel %s", channelID)
+	}
+	if !found {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeNotFound, "payment channel %s does not exist", channelID)
+	}
+	return &channel, nil
+}
+
+// Read the channelDisputeWindow configuration parameter as a duration
+func _channelDisputeWindow(ctx ledgerutil.TransactionContextInterface) (time.Duration, error) {
+	value, err := ledgerutil.GetConfigString(ctx.GetStub(), channelDisputeWindowParam)
+	if err != nil {
+		return 0, err
+	}
+	window, err := time.ParseDuration(value)
+	if err != nil || window < 0 {
+		return 0, ledgerutil.Errorf(ledgerutil.CodeCorruptState, "channelDisputeWindow must be a non-negative duration such as 24h, not %q", value)
+	}
+	return window, nil
+}
+
+// Return the PEM public key of the client's certificate, which must be an ECDSA key
+func _clientPublicKey(ctx ledgerutil.TransactionContextInterface) (string, error) {
+	cert, err := ctx.GetClientIdentity().GetX509Certificate()
+	if err != nil {
+		return "", ledgerutil.Wrap(err, "failed to get client certificate")
+	}
+	if cert == nil {
+		return "", ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "client has no certificate")
+	}
+	if _, ok := cert.PublicKey.(*ecdsa.PublicKey); !ok {
+		return "", ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "client certificate does not hold an ECDSA key to sign channel states with")
+	}
+	keyDER, err := x509.MarshalPKIXPublicKey(cert.PublicKey)
+	if err != nil {
+		return "", ledgerutil.Wrap(err, "failed to encode client public key")
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: keyDER})), nil
+}
+
+// Check signature was made over message by the PEM public key of a party of a channel
+func _verifyChannelSignature(publicKeyPEM string, message []byte, signature string, party string) error {
+	block, _ := pem.Decode([]byte(publicKeyPEM))
+	if block == nil {
+		return ledgerutil.Errorf(ledgerutil.CodeCorruptState, "payment channel has no valid key of the %s", party)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return ledgerutil.Errorf(ledgerutil.CodeCorruptState, "payment channel has no valid key of the %s: %v", party, err)
+	}
+	publicKey, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		return ledgerutil.Errorf(ledgerutil.CodeCorruptState, "key of the %s is not an ECDSA key", party)
+	}
+
+	signatureDER, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "invalid arguments: signature of the %s is not base64 encoded", party)
+	}
+	var sig ecdsaSignature
+	rest, err := asn1.Unmarshal(signatureDER, &sig)
+	if err != nil || len(rest) != 0 || sig.R == nil || sig.S == nil {
+		return ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "invalid arguments: signature of the %s is not a DER encoded ECDSA signature", party)
+	}
+	digest := sha256.Sum256(message)
+	if !ecdsa.Verify(publicKey, digest[:], sig.R, sig.S) {
+		return ledgerutil.Errorf(ledgerutil.CodeNotAuthorized, "signature of the %s does not match the state", party)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package chaincode
+
+import (
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-contract-api-go/metadata"
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil"
+)
+
+// TokenSymbol and TokenDecimals are what the erc20 contract reports as the symbol and decimals of
+// the token; amounts are whole tokens
+const (
+	TokenSymbol   = "MSC"
+	TokenDecimals = 0
+)
+
+// ERC20Contract exposes the token contract under the function names and argument shapes of the
+// fabric-samples ERC-20 token, so wallets and tools built for it work unchanged: BalanceOf and
+// Allowance return plain amounts, and Transfer, TransferFrom and Approve return true as in
+// Solidity. The contract API upper-cases the first letter of the function name, so the lower-case
+// names of the Ethereum ABI (balanceOf, transfer, ...) reach the same functions, for clients
+// calling through a bridge. Every function delegates to the token contract, so events, checks and
+// audit records are the same.
+type ERC20Contract struct {
+	contractapi.Contract
+	token *SmartContract
+}
+
+// NewERC20Contract returns the ERC-20 compatibility contract named "erc20", delegating to
+// tokenContract
+func NewERC20Contract(tokenContract *SmartContract) *ERC20Contract {
+	erc20Contract := &ERC20Contract{token: tokenContract}
+	erc20Contract.Contract.Name = "erc20" //Name is the ERC-20 function of the token name
+	erc20Contract.Info = metadata.InfoMetadata{
+		Title:       "MSc Token ERC-20",
+		Description: "The token under the fabric-samples ERC-20 function names, also callable by their Ethereum ABI names",
+		Version:     "1.0.0",
+		License:     &metadata.LicenseMetadata{Name: "Apache-2.0"},
+	}
+	erc20Contract.TransactionContextHandler = new(ledgerutil.TransactionContext)
+	erc20Contract.BeforeTransaction = ledgerutil.BeforeTransaction(erc20Contract.GetEvaluateTransactions())
+	erc20Contract.UnknownTransaction = ledgerutil.UnknownTransaction(erc20Contract)
+	return erc20Contract
+}
+
+// GetEvaluateTransactions lists the read-only functions of the erc20 contract. BeforeTransaction
+// sees the function name as the client sent it, so the Ethereum names are listed too for read-only
+// clients to call them.
+func (c *ERC20Contract) GetEvaluateTransactions() []string {
+	return []string{"Name", "Symbol", "Decimals", "TotalSupply", "BalanceOf", "ClientAccountBalance", "ClientAccountID", "Allowance",
+		"name", "symbol", "decimals", "totalSupply", "balanceOf", "allowance"}
+}
+
+// Name returns the name of the token
+func (c *ERC20Contract) Name(ctx ledgerutil.TransactionContextInterface) (string, error) {
+	return TokenName, nil
+}
+
+// Symbol returns the symbol of the token
+func (c *ERC20Contract) Symbol(ctx ledgerutil.TransactionContextInterface) (string, error) {
+	return TokenSymbol, nil
+}
+
+// Decimals returns the number of decimals of the token amounts
+func (c *ERC20Contract) Decimals(ctx ledgerutil.TransactionContextInterface) (int, error) {
+	return TokenDecimals, nil
+}
+
+// TotalSupply returns the number of tokens minted and not burned
+func (c *ERC20Contract) TotalSupply(ctx ledgerutil.TransactionContextInterface) (int, error) {
+	return c.token.TotalSupply(ctx)
+}
+
+// BalanceOf returns the balance of account, 0 for an account that never held tokens as in ERC-20
+func (c *ERC20Contract) BalanceOf(ctx ledgerutil.TransactionContextInterface, account string) (int, error) {
+	balance, err := c.token.BalanceOf(ctx, account)
+	if ledgerutil.CodeOf(err) == ledgerutil.CodeAccountNotFound {
+		return 0, nil
+	}
+	return balance, err
+}
+
+// ClientAccountBalance returns the balance of the client's account
+func (c *ERC20Contract) ClientAccountBalance(ctx ledgerutil.TransactionContextInterface) (int, error) {
+	return c.BalanceOf(ctx, ctx.GetClientID())
+}
+
+// ClientAccountID returns the account ID of the client, its payment address
+func (c *ERC20Contract) ClientAccountID(ctx ledgerutil.TransactionContextInterface) (string, error) {
+	return c.token.ClientAccountID(ctx)
+}
+
+// Transfer moves amount tokens from the client's account to recipient
+func (c *ERC20Contract) Transfer(ctx ledgerutil.TransactionContextInterface, recipient string, amount int) (bool, error) {
+	_, err := c.token.Transfer(ctx, recipient, amount)
+	return err == nil, err
+}
+
+// TransferFrom moves value tokens from the account from to the account to, spending the
+// allowance from gave the client
+func (c *ERC20Contract) TransferFrom(ctx ledgerutil.TransactionContextInterface, from string, to string, value int) (bool, error) {
+	_, err := c.token.TransferFrom(ctx, from, to, value)
+	return err == nil, err
+}
+
+// Approve sets the allowance of spender over the client's tokens to value
+func (c *ERC20Contract) Approve(ctx ledgerutil.TransactionContextInterface, spender string, value int) (bool, error) {
+	_, err := c.token.Approve(ctx, spender, value)
+	return err == nil, err
+}
+
+// Allowance returns the tokens spender may still transfer from the account of owner
+func (c *ERC20Contract) Allowance(ctx ledgerutil.TransactionContextInterface, owner string, spender string) (int, error) {
+	return c.token.Allowance(ctx, owner, spender)
+}
+
+// Mint creates amount tokens in the client's account, for clients allowed token.Mint
+func (c *ERC20Contract) Mint(ctx ledgerutil.TransactionContextInterface, amount int) error {
+	_, err := c.token.Mint(ctx, amount)
+	return err
+}
+
+// Burn removes amount tokens from the client's account, for clients allowed token.Burn
+func (c *ERC20Contract) Burn(ctx ledgerutil.TransactionContextInterface, amount int) error {
+	_, err := c.token.Burn(ctx, amount)
+	return err
+}
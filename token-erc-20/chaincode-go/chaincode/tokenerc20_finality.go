@@ -0,0 +1,41 @@
+package chaincode
+
+import (
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil"
+)
+
+// FinalityReceipt is what the world state records of a committed transaction: the receipts of the
+// transfers made with a reference and the audit record kept while on-ledger audit records are on.
+// The chaincode cannot see blocks, so the client library adds the block, validation code and
+// endorsements of the transaction from the system chaincodes to make a settlement proof.
+type FinalityReceipt struct {
+	TxID     string                  `json:"txID"`
+	Receipts []*Receipt              `json:"receipts,omitempty" metadata:",optional"`
+	Audit    *ledgerutil.AuditRecord `json:"audit,omitempty" metadata:",optional"`
+}
+
+// GetFinalityReceipt returns the receipts and audit record stored for the transaction txID, failing
+// with NOT_FOUND when it stored neither, e.g. a plain Transfer with on-ledger audit records off
+func (s *SmartContract) GetFinalityReceipt(ctx ledgerutil.TransactionContextInterface, txID string) (*FinalityReceipt, error) {
+	txID = ledgerutil.NormalizeID(txID)
+	v := ledgerutil.NewValidator()
+	v.Key("txID", txID)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	receipts, err := _findReceipts(ctx, txID)
+	if err != nil {
+		return nil, err
+	}
+	finality := &FinalityReceipt{TxID: txID, Receipts: receipts}
+	record, err := ledgerutil.GetAuditRecord(ctx.GetStub(), txID)
+	if err == nil {
+		finality.Audit = record
+	} else if ledgerutil.CodeOf(err) != ledgerutil.CodeNotFound {
+		return nil, err
+	}
+	if len(finality.Receipts) == 0 && finality.Audit == nil {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeNotFound, "no receipt or audit record for transaction %s", txID)
+	}
+	return finality, nil
+}
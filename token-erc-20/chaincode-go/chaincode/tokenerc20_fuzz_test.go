@@ -0,0 +1,100 @@
+//go:build go1.18
+// +build go1.18
+
+package chaincode
+
+import (
+	"strings"
+	"testing"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil"
+)
+
+// FuzzTransfer stores arbitrary balances and checks that a transfer either fails or moves exactly
+// the amount, never reading garbage as 0 or wrapping a balance around
+func FuzzTransfer(f *testing.F) {
+	f.Add("100", "5", 40)
+	f.Add("100", "", 100)
+	f.Add("abc", "5", 1)
+	f.Add("100", "-7", 1)
+	f.Add("100", "9223372036854775807", 1)
+	f.Add("", "", 0)
+	f.Fuzz(func(t *testing.T, senderBalance string, receiverBalance string, amount int) {
+		stub := newFakeStub()
+		stub.state[alice] = []byte(senderBalance)
+		if receiverBalance != "" {
+			stub.state[bob] = []byte(receiverBalance)
+		}
+
+		_, err := new(SmartContract).Transfer(newContext(stub, alice, "Org1MSP"), bob, amount)
+		if err != nil {
+			if string(stub.state[alice]) != senderBalance {
+				t.Fatalf("sender balance changed from %q to %q by a failed transfer", senderBalance, stub.state[alice])
+			}
+			return
+		}
+
+		before, err := ledgerutil.ParseAmount([]byte(senderBalance))
+		if err != nil {
+			t.Fatalf("transferred from unparsable balance %q", senderBalance)
+		}
+		received := 0
+		if receiverBalance != "" {
+			received, err = ledgerutil.ParseAmount([]byte(receiverBalance))
+			if err != nil {
+				t.Fatalf("transferred to unparsable balance %q", receiverBalance)
+			}
+		}
+		after, err := ledgerutil.ParseAmount(stub.state[alice])
+		if err != nil || after != before-amount {
+			t.Fatalf("sender balance is %q, want %d", stub.state[alice], before-amount)
+		}
+		total, err := ledgerutil.ParseAmount(stub.state[bob])
+		if err != nil || total != received+amount {
+			t.Fatalf("receiver balance is %q, want %d", stub.state[bob], received+amount)
+		}
+	})
+}
+
+// FuzzAllowanceKey approves arbitrary spender IDs, which become attributes of a composite key once
+// trimmed and validated
+func FuzzAllowanceKey(f *testing.F) {
+	f.Add("bob", 50)
+	f.Add("", 0)
+	f.Add("x509::CN=bob\x00", 1)
+	f.Add("\xff", 1)
+	f.Add("bob\U0010FFFF", 1)
+	f.Add("bob", -1)
+	f.Add(" bob~ ", 1)
+	f.Fuzz(func(t *testing.T, spender string, amount int) {
+		stub := newFakeStub()
+		contract := new(SmartContract)
+
+		_, err := contract.Approve(newContext(stub, alice, "Org1MSP"), spender, amount)
+		spender = strings.TrimSpace(spender)
+		invalidKey := spender == "" || len(spender) > ledgerutil.MaxKeyLength || !utf8.ValidString(spender) ||
+			strings.ContainsAny(spender, "~\x00\U0010FFFF") || strings.IndexFunc(spender, unicode.IsControl) >= 0
+		if invalidKey || amount < 0 {
+			if err == nil {
+				t.Fatalf("approved %d for spender %q", amount, spender)
+			}
+			if len(stub.state) != 0 {
+				t.Fatalf("failed approval wrote the world state")
+			}
+			return
+		}
+		if err != nil {
+			t.Fatalf("failed to approve %d for spender %q: %v", amount, spender, err)
+		}
+
+		allowance, err := contract.Allowance(newContext(stub, carol, "Org2MSP"), alice, spender)
+		if err != nil {
+			t.Fatalf("failed to read allowance: %v", err)
+		}
+		if allowance != amount {
+			t.Fatalf("allowance is %d, want %d", allowance, amount)
+		}
+	})
+}
@@ -0,0 +1,269 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil"
+)
+
+const (
+	// obligationPrefix is the prefix of the transfers waiting for settlement, keyed by the end of
+	// their netting window, the transaction ID and their ID so SettleWindow reads the due ones first
+	obligationPrefix = "obligation"
+	// pendingDebitPrefix is the prefix of the amounts owed by the senders of obligations, keyed by
+	// sender, transaction ID and obligation ID, so the tokens cannot be spent again before they settle
+	pendingDebitPrefix = "pendingdebit"
+)
+
+// obligationWindowFormat formats the end of a netting window in obligation keys, sorting by time
+const obligationWindowFormat = time.RFC3339
+
+// deferredSettlementFlag records transfers as obligations settled in netting windows, so frequent
+// transfers to and from the same accounts do not conflict on their balances
+var deferredSettlementFlag = ledgerutil.NewFeatureFlag("deferredSettlement", "Transfer and TransferFrom record obligations that SettleWindow nets into balance updates once their netting window ends")
+
+// nettingWindowParam is the length of the netting windows of deferred settlement
+var nettingWindowParam = ledgerutil.ConfigParam{Name: "nettingWindow", Kind: ledgerutil.ConfigString, Default: `"1h"`,
+	Description: "Go duration of the netting windows of deferred settlement, counted from midnight UTC"}
+
+// windowSettledEvent is emitted by SettleWindow, the netted balance changes are in its audit record
+var windowSettledEvent = ledgerutil.EventSchema{Name: "WindowSettled", Version: 1,
+	Description: "obligations of ended netting windows netted into balance updates, more is set when due obligations are left",
+	Fields:      map[string]string{"obligations": "integer", "more": "boolean", "audit": "object"}}
+
+// Obligation is a transfer recorded in deferred settlement mode. The tokens are owed by From
+// until SettleWindow moves them after SettlesAt, the end of the netting window of the transfer.
+type Obligation struct {
+	ObjectType string `json:"objectType"`
+	TxID       string `json:"txID"`
+	// ID sets the obligation apart from the others of a transaction making several transfers, e.g.
+	// through InvokeChaincode, see ledgerutil.RecordID
+	ID         string    `json:"id"`
+	From       string    `json:"from"`
+	To         string    `json:"to"`
+	Amount     int       `json:"amount"`
+	RecordedAt time.Time `json:"recordedAt"`
+	SettlesAt  time.Time `json:"settlesAt"`
+}
+
+// NetChange is the change of the balance of an account after netting its obligations
+type NetChange struct {
+	Account string `json:"account"`
+	Change  int    `json:"change"`
+	Balance int    `json:"balance"`
+}
+
+// WindowSettlement is the outcome of SettleWindow. Changes has one entry per account whose balance
+// changed, in account order, and More is set when due obligations were left for the next call.
+type WindowSettlement struct {
+	Obligations int         `json:"obligations"`
+	Changes     []NetChange `json:"changes"`
+	More        bool        `json:"more"`
+}
+
+// deferredEvent is the payload of the Transfer event of a transfer recorded as an obligation
+type deferredEvent struct {
+	event
+	SettlesAt string `json:"settlesAt"`
+}
+
+// windowSettled is the payload of the WindowSettled event
+type windowSettled struct {
+	Obligations int  `json:"obligations"`
+	More        bool `json:"more"`
+}
+
+// Settle the obligations of the netting windows that have ended, writing each account once with its net change
+// Settles at most maxResults obligations of the query config, call it again while More is set; any client may call it
+func (s *SmartContract) SettleWindow(ctx ledgerutil.TransactionContextInterface) (*WindowSettlement, error) {
+	now, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	config, err := ledgerutil.GetQueryConfig(ctx.GetStub())
+	if err != nil {
+		return nil, err
+	}
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(obligationPrefix, []string{})
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to get obligations from world state")
+	}
+	defer iterator.Close()
+
+	settlement := &WindowSettlement{Changes: []NetChange{}}
+	nets := make(map[string]int)
+	for iterator.HasNext() {
+		result, err := iterator.Next()
+		if err != nil {
+			return nil, ledgerutil.Wrap(err, "failed to iterate obligations")
+		}
+		_, attributes, err := ctx.GetStub().SplitCompositeKey(result.Key)
+		if err != nil || len(attributes) != 3 {
+			return nil, ledgerutil.Errorf(ledgerutil.CodeCorruptState, "invalid obligation key %q", result.Key)
+		}
+		windowEnd, err := time.Parse(obligationWindowFormat, attributes[0])
+		if err != nil {
+			return nil, ledgerutil.Errorf(ledgerutil.CodeCorruptState, "invalid obligation key %q", result.Key)
+		}
+		if windowEnd.After(now) {
+			break //the keys of later windows sort after this one
+		}
+		if settlement.Obligations == config.MaxResults {
+			settlement.More = true
+			break
+		}
+		var obligation Obligation
+		err = json.Unmarshal(result.Value, &obligation)
+		if err != nil {
+			return nil, ledgerutil.Errorf(ledgerutil.CodeCorruptState, "failed to unmarshal obligation %s: %v", attributes[2], err)
+		}
+		nets[obligation.From] -= obligation.Amount
+		nets[obligation.To] += obligation.Amount
+		err = ctx.GetStub().DelState(result.Key)
+		if err != nil {
+			return nil, ledgerutil.Wrap(err, "failed to delete obligation %s", obligation.ID)
+		}
+		debitKey, err := _pendingDebitKey(ctx, &obligation)
+		if err != nil {
+			return nil, err
+		}
+		err = ctx.GetStub().DelState(debitKey)
+		if err != nil {
+			return nil, ledgerutil.Wrap(err, "failed to delete pending debit %s", obligation.ID)
+		}
+		settlement.Obligations++
+	}
+
+	//write the accounts in a fixed order, every endorsing peer must record the same changes
+	accounts := make([]string, 0, len(nets))
+	for account, net := range nets {
+		if net != 0 {
+			accounts = append(accounts, account)
+		}
+	}
+	sort.Strings(accounts)
+	auditor := ledgerutil.NewAuditor(ctx.GetStub())
+	for _, account := range accounts {
+		current, err := _readBalance(ctx, account)
+		if err != nil {
+			return nil, err
+		}
+		var updated int
+		if nets[account] > 0 {
+			updated, err = ledgerutil.AddAmounts(current, nets[account])
+		} else {
+			updated, err = ledgerutil.SubAmounts(current, -nets[account])
+		}
+		if err != nil {
+			return nil, ledgerutil.Wrap(err, "failed to settle the obligations of account %s", account)
+		}
+		err = ctx.GetStub().PutState(account, ledgerutil.FormatAmount(updated))
+		if err != nil {
+			return nil, err
+		}
+		auditor.Change(balanceKind, current, updated, account)
+		settlement.Changes = append(settlement.Changes, NetChange{Account: account, Change: nets[account], Balance: updated})
+	}
+	err = auditor.Emit(windowSettledEvent, windowSettled{Obligations: settlement.Obligations, More: settlement.More})
+	if err != nil {
+		return nil, err
+	}
+	return settlement, nil
+}
+
+// Record a transfer as an obligation settled after the end of the current netting window
+func _deferTransfer(ctx contractapi.TransactionContextInterface, from string, receiver string, amount int) (*Obligation, error) {
+	value, err := ledgerutil.GetConfigString(ctx.GetStub(), nettingWindowParam)
+	if err != nil {
+		return nil, err
+	}
+	window, err := time.ParseDuration(value)
+	if err != nil || window <= 0 {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeCorruptState, "parameter %s is not a positive Go duration: %q", nettingWindowParam.Name, value)
+	}
+	txID, timestamp, err := ledgerutil.TxInfo(ctx.GetStub())
+	if err != nil {
+		return nil, err
+	}
+	obligation := &Obligation{
+		ObjectType: obligationPrefix,
+		TxID:       txID,
+		From:       from,
+		To:         receiver,
+		Amount:     amount,
+		RecordedAt: timestamp,
+		SettlesAt:  timestamp.Truncate(window).Add(window),
+	}
+	obligation.ID = ledgerutil.RecordID(ctx.GetStub(), from, receiver, strconv.Itoa(amount))
+	key, err := ctx.GetStub().CreateCompositeKey(obligationPrefix, []string{obligation.SettlesAt.Format(obligationWindowFormat), txID, obligation.ID})
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to create the composite key for prefix %s", obligationPrefix)
+	}
+	err = ledgerutil.PutJSON(ctx.GetStub(), key, obligation)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to put obligation %s", txID)
+	}
+	debitKey, err := _pendingDebitKey(ctx, obligation)
+	if err != nil {
+		return nil, err
+	}
+	err = ctx.GetStub().PutState(debitKey, ledgerutil.FormatAmount(amount))
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to put pending debit %s", obligation.ID)
+	}
+	return obligation, nil
+}
+
+// Return the payload of the Transfer event, with the time it settles for a transfer recorded as an obligation
+func _transferPayload(from string, receiver string, amount int, settlesAt string) interface{} {
+	if settlesAt == "" {
+		return event{from, receiver, amount}
+	}
+	return deferredEvent{event{from, receiver, amount}, settlesAt}
+}
+
+// Return the tokens an account owes by obligations not settled yet, which it cannot spend
+func _pendingDebits(ctx contractapi.TransactionContextInterface, account string) (int, error) {
+	pending := 0
+	err := ledgerutil.ForEachByPartialCompositeKey(ctx.GetStub(), pendingDebitPrefix, []string{account}, func(attributes []string, value []byte) error {
+		amount, err := ledgerutil.ParseAmount(value)
+		if err != nil {
+			return ledgerutil.Wrap(err, "failed to read pending debit of account %s", account)
+		}
+		pending, err = ledgerutil.AddAmounts(pending, amount)
+		return err
+	})
+	if err != nil {
+		return 0, ledgerutil.Wrap(err, "failed to read pending debits of account %s", account)
+	}
+	return pending, nil
+}
+
+// Return the world state key of the pending debit of an obligation
+func _pendingDebitKey(ctx contractapi.TransactionContextInterface, obligation *Obligation) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(pendingDebitPrefix, []string{obligation.From, obligation.TxID, obligation.ID})
+	if err != nil {
+		return "", ledgerutil.Wrap(err, "failed to create the composite key for prefix %s", pendingDebitPrefix)
+	}
+	return key, nil
+}
+
+// Read the balance of an account, 0 when it never held tokens
+func _readBalance(ctx contractapi.TransactionContextInterface, account string) (int, error) {
+	balanceBytes, err := ctx.GetStub().GetState(account)
+	if err != nil {
+		return 0, ledgerutil.Wrap(err, "failed to read account %s from world state", account)
+	}
+	if balanceBytes == nil {
+		return 0, nil
+	}
+	balance, err := ledgerutil.ParseAmount(balanceBytes)
+	if err != nil {
+		return 0, ledgerutil.Wrap(err, "failed to read account %s", account)
+	}
+	return balance, nil
+}
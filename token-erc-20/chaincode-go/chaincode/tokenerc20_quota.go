@@ -0,0 +1,204 @@
+package chaincode
+
+import (
+	"time"
+
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil"
+)
+
+// mintQuotaPrefix is the prefix of the mint quotas, keyed by MSP ID
+const mintQuotaPrefix = "mintquota"
+
+// MintQuota is the most an org's clients may mint in each period, and what they minted in the
+// current one. Periods are consecutive windows of Period counted from the zero time in UTC, so a
+// period of 24h runs from midnight to midnight UTC.
+type MintQuota struct {
+	MSPID      string `json:"mspID"`
+	Allocation int    `json:"allocation"`
+	// Period is a Go duration such as 24h or 168h
+	Period string `json:"period"`
+	// PeriodStart is the start of the period Minted counts
+	PeriodStart time.Time `json:"periodStart"`
+	Minted      int       `json:"minted"`
+}
+
+// QuotaUsage is the mint quota of an org at the transaction time
+type QuotaUsage struct {
+	MSPID       string    `json:"mspID"`
+	Allocation  int       `json:"allocation"`
+	Period      string    `json:"period"`
+	PeriodStart time.Time `json:"periodStart"`
+	PeriodEnd   time.Time `json:"periodEnd"`
+	Minted      int       `json:"minted"`
+	Remaining   int       `json:"remaining"`
+}
+
+// Limit the tokens the clients of an org may mint in each period to allocation, period being a Go duration such as 24h
+// Minting of orgs without a quota is only limited by token.Mint and minterOrgs
+// Changing the allocation keeps what the org minted in the current period, changing the period starts counting anew
+// Only clients allowed token.SetMintQuota in the access-control chaincode may set quotas
+func (s *SmartContract) SetMintQuota(ctx ledgerutil.TransactionContextInterface, mspID string, allocation int, period string) (*QuotaUsage, error) {
+	err := _checkAccess(ctx, "token.SetMintQuota") //check authorization in the access-control chaincode
+	if err != nil {
+		return nil, err
+	}
+	mspID = ledgerutil.NormalizeID(mspID)
+	v := ledgerutil.NewValidator()
+	v.Key("mspID", mspID)
+	v.Amount("allocation", allocation)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	duration, err := time.ParseDuration(period)
+	if err != nil || duration < time.Minute {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "invalid arguments: period must be a duration of at least 1m such as 24h, not %q", period)
+	}
+
+	quota, err := _readMintQuota(ctx, mspID)
+	if err != nil {
+		return nil, err
+	}
+	if quota == nil {
+		quota = &MintQuota{MSPID: mspID}
+	} else if current, _ := time.ParseDuration(quota.Period); current != duration {
+		quota = &MintQuota{MSPID: mspID} //the windows move, so nothing minted counts in the new one
+	}
+	quota.Allocation = allocation
+	quota.Period = period
+	return _putMintQuota(ctx, quota, 0)
+}
+
+// Remove the mint quota of an org, after which its minting is only limited by token.Mint and minterOrgs
+// Only clients allowed token.SetMintQuota in the access-control chaincode may remove quotas
+func (s *SmartContract) RemoveMintQuota(ctx ledgerutil.TransactionContextInterface, mspID string) error {
+	err := _checkAccess(ctx, "token.SetMintQuota") //check authorization in the access-control chaincode
+	if err != nil {
+		return err
+	}
+	mspID = ledgerutil.NormalizeID(mspID)
+	v := ledgerutil.NewValidator()
+	v.Key("mspID", mspID)
+	if err := v.Err(); err != nil {
+		return err
+	}
+	quota, err := _readMintQuota(ctx, mspID)
+	if err != nil {
+		return err
+	}
+	if quota == nil {
+		return ledgerutil.Errorf(ledgerutil.CodeNotFound, "org %s has no mint quota", mspID)
+	}
+	key, err := _mintQuotaKey(ctx, mspID)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().DelState(key)
+}
+
+// Return the allocation of an org, what its clients minted in the current period and what is left of it
+func (s *SmartContract) GetQuotaUsage(ctx ledgerutil.TransactionContextInterface, mspID string) (*QuotaUsage, error) {
+	mspID = ledgerutil.NormalizeID(mspID)
+	v := ledgerutil.NewValidator()
+	v.Key("mspID", mspID)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	quota, err := _readMintQuota(ctx, mspID)
+	if err != nil {
+		return nil, err
+	}
+	if quota == nil {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeNotFound, "org %s has no mint quota", mspID)
+	}
+	return _quotaUsage(ctx, quota)
+}
+
+// Count amount against the mint quota of the client's org, failing when it is more than what is left for the period
+func _useMintQuota(ctx ledgerutil.TransactionContextInterface, amount int) error {
+	quota, err := _readMintQuota(ctx, ctx.GetClientMSPID())
+	if err != nil || quota == nil {
+		return err //orgs without a quota are not limited
+	}
+	usage, err := _quotaUsage(ctx, quota)
+	if err != nil {
+		return err
+	}
+	if amount > usage.Remaining {
+		return ledgerutil.Errorf(ledgerutil.CodeQuotaExceeded, "org %s can mint %d more tokens until %s, not %d",
+			quota.MSPID, usage.Remaining, usage.PeriodEnd.Format(time.RFC3339), amount)
+	}
+	_, err = _putMintQuota(ctx, quota, amount)
+	return err
+}
+
+// Store the quota with amount more minted in the current period
+func _putMintQuota(ctx ledgerutil.TransactionContextInterface, quota *MintQuota, amount int) (*QuotaUsage, error) {
+	usage, err := _quotaUsage(ctx, quota)
+	if err != nil {
+		return nil, err
+	}
+	quota.PeriodStart = usage.PeriodStart
+	quota.Minted = usage.Minted + amount
+	key, err := _mintQuotaKey(ctx, quota.MSPID)
+	if err != nil {
+		return nil, err
+	}
+	err = ledgerutil.PutJSON(ctx.GetStub(), key, quota)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to put mint quota of %s", quota.MSPID)
+	}
+	return _quotaUsage(ctx, quota)
+}
+
+// Work out the period of the transaction time and the usage of the quota in it, nothing minted when a new period started
+func _quotaUsage(ctx ledgerutil.TransactionContextInterface, quota *MintQuota) (*QuotaUsage, error) {
+	period, err := time.ParseDuration(quota.Period)
+	if err != nil || period <= 0 {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeCorruptState, "mint quota of %s has an invalid period %q", quota.MSPID, quota.Period)
+	}
+	now, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	usage := &QuotaUsage{
+		MSPID:       quota.MSPID,
+		Allocation:  quota.Allocation,
+		Period:      quota.Period,
+		PeriodStart: now.Truncate(period),
+	}
+	usage.PeriodEnd = usage.PeriodStart.Add(period)
+	if quota.PeriodStart.Equal(usage.PeriodStart) {
+		usage.Minted = quota.Minted
+	}
+	usage.Remaining = usage.Allocation - usage.Minted
+	if usage.Remaining < 0 {
+		usage.Remaining = 0 //the allocation was lowered below what was already minted
+	}
+	return usage, nil
+}
+
+// Read the mint quota of an org, nil when it has none
+func _readMintQuota(ctx ledgerutil.TransactionContextInterface, mspID string) (*MintQuota, error) {
+	key, err := _mintQuotaKey(ctx, mspID)
+	if err != nil {
+		return nil, err
+	}
+	var quota MintQuota
+	found, err := ledgerutil.ReadJSON(ctx.GetStub(), key, &quota)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to read mint quota of %s", mspID)
+	}
+	if !found {
+		return nil, nil
+	}
+	return &quota, nil
+}
+
+// Return the world state key of the mint quota of an org
+func _mintQuotaKey(ctx ledgerutil.TransactionContextInterface, mspID string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(mintQuotaPrefix, []string{mspID})
+	if err != nil {
+		return "", ledgerutil.Wrap(err, "failed to create the composite key for prefix %s", mintQuotaPrefix)
+	}
+	return key, nil
+}
@@ -0,0 +1,184 @@
+package chaincode
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil"
+)
+
+// object names for the prefixes of the transfer receipts and of their index by reference
+const (
+	receiptPrefix    = "receipt"
+	receiptRefPrefix = "receiptref"
+)
+
+// Receipt records a transfer made with a reference, such as the invoice or purchase order it pays,
+// keyed by the transaction ID and its ID
+type Receipt struct {
+	ObjectType string `json:"objectType"`
+	TxID       string `json:"txID"`
+	// ID sets the receipt apart from the others of a transaction calling TransferWithReference more
+	// than once, e.g. through InvokeChaincode, see ledgerutil.RecordID
+	ID        string    `json:"id"`
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Amount    int       `json:"amount"`
+	Reference string    `json:"reference"`
+	Timestamp time.Time `json:"timestamp"`
+	// TraceID is the OpenTelemetry trace ID the client passed with the transfer, if any
+	TraceID string `json:"traceID,omitempty" metadata:",optional"`
+}
+
+// ReceiptPage is one page of the receipts of a reference in transaction ID order. Bookmark is passed
+// to the next query to continue after the last receipt and is empty on the last page.
+type ReceiptPage struct {
+	Receipts []*Receipt `json:"receipts"`
+	Bookmark string     `json:"bookmark"`
+}
+
+// TransferWithReference is Transfer recording a receipt of the payment with reference, e.g. the
+// invoice or purchase order number it settles, which SearchReceiptsByReference finds. The reference
+// is kept as passed and indexed in its normalized form.
+func (s *SmartContract) TransferWithReference(ctx ledgerutil.TransactionContextInterface, receiver string, amount int, reference string) (*TxResult, error) {
+	reference = strings.TrimSpace(reference)
+	v := ledgerutil.NewValidator()
+	v.Key("reference", reference)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	normalized, err := _normalizeReference(reference)
+	if err != nil {
+		return nil, err
+	}
+	result, err := s.Transfer(ctx, receiver, amount)
+	if err != nil {
+		return nil, err
+	}
+
+	receipt := &Receipt{
+		ObjectType: receiptPrefix,
+		TxID:       result.TxID,
+		From:       result.Account,
+		To:         ledgerutil.NormalizeID(receiver),
+		Amount:     amount,
+		Reference:  reference,
+		Timestamp:  result.Timestamp,
+		TraceID:    ledgerutil.TraceID(ctx.GetStub()),
+	}
+	receipt.ID = ledgerutil.RecordID(ctx.GetStub(), receipt.From, receipt.To, strconv.Itoa(amount), reference)
+	receiptKey, err := ctx.GetStub().CreateCompositeKey(receiptPrefix, []string{receipt.TxID, receipt.ID})
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to create the composite key for prefix %s", receiptPrefix)
+	}
+	err = ledgerutil.PutRecord(ctx.GetStub(), receiptKey, receipt)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to put receipt")
+	}
+	indexKey, err := ctx.GetStub().CreateCompositeKey(receiptRefPrefix, []string{normalized, receipt.TxID, receipt.ID})
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to create the composite key for prefix %s", receiptRefPrefix)
+	}
+	//the value is not read, but an empty value would delete the key
+	err = ctx.GetStub().PutState(indexKey, []byte{0x00})
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to put receipt reference")
+	}
+	return result, nil
+}
+
+// SearchReceiptsByReference returns up to pageSize receipts of the transfers made with reference,
+// starting at bookmark, empty for the first page. References are compared in their normalized
+// form, so "inv-2024/001" finds the payments made with "INV 2024 001".
+func (s *SmartContract) SearchReceiptsByReference(ctx ledgerutil.TransactionContextInterface, reference string, pageSize int, bookmark string) (*ReceiptPage, error) {
+	normalized, err := _normalizeReference(reference)
+	if err != nil {
+		return nil, err
+	}
+	_, err = ledgerutil.CheckPageSize(ctx.GetStub(), pageSize)
+	if err != nil {
+		return nil, err
+	}
+	iterator, metadata, err := ctx.GetStub().GetStateByPartialCompositeKeyWithPagination(receiptRefPrefix, []string{normalized}, int32(pageSize), bookmark)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to get receipts of reference %s from world state", reference)
+	}
+	defer iterator.Close()
+
+	page := &ReceiptPage{Receipts: []*Receipt{}}
+	for iterator.HasNext() {
+		result, err := iterator.Next()
+		if err != nil {
+			return nil, ledgerutil.Wrap(err, "failed to iterate receipts")
+		}
+		_, attributes, err := ctx.GetStub().SplitCompositeKey(result.Key)
+		if err != nil || len(attributes) != 3 {
+			return nil, ledgerutil.Errorf(ledgerutil.CodeCorruptState, "invalid receipt reference key %q", result.Key)
+		}
+		receipt, err := _readReceipt(ctx, attributes[1], attributes[2])
+		if err != nil {
+			return nil, err
+		}
+		page.Receipts = append(page.Receipts, receipt)
+	}
+	if metadata.FetchedRecordsCount == int32(pageSize) {
+		page.Bookmark = metadata.Bookmark
+	}
+	return page, nil
+}
+
+// _readReceipt reads a receipt of a transaction
+func _readReceipt(ctx ledgerutil.TransactionContextInterface, txID string, receiptID string) (*Receipt, error) {
+	receiptKey, err := ctx.GetStub().CreateCompositeKey(receiptPrefix, []string{txID, receiptID})
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to create the composite key for prefix %s", receiptPrefix)
+	}
+	var receipt Receipt
+	found, err := ledgerutil.ReadRecord(ctx.GetStub(), receiptKey, &receipt)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to read receipt %s", txID)
+	}
+	if !found {
+		return nil, nil
+	}
+	return &receipt, nil
+}
+
+// _findReceipts reads the receipts of a transaction in ID order, none when it made no transfer
+// with a reference
+func _findReceipts(ctx ledgerutil.TransactionContextInterface, txID string) ([]*Receipt, error) {
+	var receipts []*Receipt
+	err := ledgerutil.ForEachByPartialCompositeKey(ctx.GetStub(), receiptPrefix, []string{txID}, func(_ []string, value []byte) error {
+		var receipt Receipt
+		err := ledgerutil.UnmarshalRecord("receipt of "+txID, value, &receipt)
+		if err != nil {
+			return err
+		}
+		receipts = append(receipts, &receipt)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return receipts, nil
+}
+
+// _normalizeReference returns the form a reference is indexed under, its letters and digits in upper
+// case, so the spacing, punctuation and case clients write an invoice number with do not matter
+func _normalizeReference(reference string) (string, error) {
+	normalized := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToUpper(r)
+		}
+		return -1
+	}, reference)
+	if normalized == "" {
+		return "", ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "invalid arguments: reference must have a letter or digit")
+	}
+	if len(normalized) > ledgerutil.MaxKeyLength {
+		return "", ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "invalid arguments: reference is longer than %d bytes", ledgerutil.MaxKeyLength)
+	}
+	return normalized, nil
+}
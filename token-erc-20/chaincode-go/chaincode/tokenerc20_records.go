@@ -0,0 +1,79 @@
+package chaincode
+
+import (
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil"
+)
+
+// The records below are stored in protobuf when the stateCodec of the chaincode is protobuf, with
+// the schema of protos/records.proto. Fields are written in field number order.
+
+// Encode the registration in protobuf
+func (r *RegisteredAccount) MarshalProto() ([]byte, error) {
+	var w ledgerutil.ProtoWriter
+	w.String(1, r.Account)
+	w.String(2, r.MSPID)
+	w.String(3, r.TxID)
+	w.Timestamp(4, r.RegisteredAt)
+	return w.Bytes(), nil
+}
+
+// Decode the registration from protobuf
+func (r *RegisteredAccount) UnmarshalProto(data []byte) error {
+	reader := ledgerutil.NewProtoReader(data)
+	for reader.Next() {
+		switch reader.Field() {
+		case 1:
+			r.Account = reader.ReadString()
+		case 2:
+			r.MSPID = reader.ReadString()
+		case 3:
+			r.TxID = reader.ReadString()
+		case 4:
+			r.RegisteredAt = reader.ReadTimestamp()
+		}
+	}
+	return reader.Err()
+}
+
+// Encode the receipt in protobuf
+func (r *Receipt) MarshalProto() ([]byte, error) {
+	var w ledgerutil.ProtoWriter
+	w.String(1, r.ObjectType)
+	w.String(2, r.TxID)
+	w.String(3, r.From)
+	w.String(4, r.To)
+	w.Int64(5, int64(r.Amount))
+	w.String(6, r.Reference)
+	w.Timestamp(7, r.Timestamp)
+	w.String(8, r.ID)
+	w.String(9, r.TraceID)
+	return w.Bytes(), nil
+}
+
+// Decode the receipt from protobuf
+func (r *Receipt) UnmarshalProto(data []byte) error {
+	reader := ledgerutil.NewProtoReader(data)
+	for reader.Next() {
+		switch reader.Field() {
+		case 1:
+			r.ObjectType = reader.ReadString()
+		case 2:
+			r.TxID = reader.ReadString()
+		case 3:
+			r.From = reader.ReadString()
+		case 4:
+			r.To = reader.ReadString()
+		case 5:
+			r.Amount = int(reader.ReadInt64())
+		case 6:
+			r.Reference = reader.ReadString()
+		case 7:
+			r.Timestamp = reader.ReadTimestamp()
+		case 8:
+			r.ID = reader.ReadString()
+		case 9:
+			r.TraceID = reader.ReadString()
+		}
+	}
+	return reader.Err()
+}
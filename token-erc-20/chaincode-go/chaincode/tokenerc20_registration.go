@@ -0,0 +1,107 @@
+package chaincode
+
+import (
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil"
+)
+
+// registeredAccountPrefix is the prefix of the accounts registered by their owners, keyed by account
+const registeredAccountPrefix = "registeredaccount"
+
+// strictRecipientsFlag limits transfers to accounts their owner registered, so tokens cannot be sent
+// to a mistyped account ID or one no client ever used
+var strictRecipientsFlag = ledgerutil.NewFeatureFlag("strictRecipients", "tokens may only be transferred to accounts registered by their owner with RegisterAccount")
+
+// RegisteredAccount records that the owner of an account registered it to receive tokens
+type RegisteredAccount struct {
+	Account      string    `json:"account"`
+	MSPID        string    `json:"mspID"`
+	TxID         string    `json:"txID"`
+	RegisteredAt time.Time `json:"registeredAt"`
+}
+
+// Register the client's account to receive tokens, which transfers require while the strictRecipients flag is on
+// Registering an account again returns its registration unchanged
+func (s *SmartContract) RegisterAccount(ctx ledgerutil.TransactionContextInterface) (*RegisteredAccount, error) {
+	account := ctx.GetClientID()
+	registration, err := _readRegistration(ctx, account)
+	if err != nil || registration != nil {
+		return registration, err
+	}
+	txID, timestamp, err := ledgerutil.TxInfo(ctx.GetStub())
+	if err != nil {
+		return nil, err
+	}
+	registration = &RegisteredAccount{Account: account, MSPID: ctx.GetClientMSPID(), TxID: txID, RegisteredAt: timestamp}
+	key, err := _registrationKey(ctx, account)
+	if err != nil {
+		return nil, err
+	}
+	err = ledgerutil.PutRecord(ctx.GetStub(), key, registration)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to put registration of account %s", account)
+	}
+	return registration, nil
+}
+
+// Return the registration of an account, failing with ACCOUNT_NOT_FOUND when its owner did not register it
+func (s *SmartContract) GetAccountRegistration(ctx ledgerutil.TransactionContextInterface, account string) (*RegisteredAccount, error) {
+	account = ledgerutil.NormalizeID(account)
+	v := ledgerutil.NewValidator()
+	v.Key("account", account)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	registration, err := _readRegistration(ctx, account)
+	if err != nil {
+		return nil, err
+	}
+	if registration == nil {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeAccountNotFound, "account %s is not registered", account)
+	}
+	return registration, nil
+}
+
+// Check the receiving account was registered by its owner while the strictRecipients flag is on
+func _checkRecipient(ctx contractapi.TransactionContextInterface, receiver string) error {
+	strict, err := ledgerutil.FlagEnabled(ctx.GetStub(), strictRecipientsFlag)
+	if err != nil || !strict {
+		return err
+	}
+	registration, err := _readRegistration(ctx, receiver)
+	if err != nil {
+		return err
+	}
+	if registration == nil {
+		return ledgerutil.Errorf(ledgerutil.CodeAccountNotFound, "account %s is not registered, its owner must call RegisterAccount before it can receive tokens", receiver)
+	}
+	return nil
+}
+
+// Read the registration of an account, nil when it is not registered
+func _readRegistration(ctx contractapi.TransactionContextInterface, account string) (*RegisteredAccount, error) {
+	key, err := _registrationKey(ctx, account)
+	if err != nil {
+		return nil, err
+	}
+	var registration RegisteredAccount
+	found, err := ledgerutil.ReadRecord(ctx.GetStub(), key, &registration)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to read registration of account %s", account)
+	}
+	if !found {
+		return nil, nil
+	}
+	return &registration, nil
+}
+
+// Return the world state key of the registration of an account
+func _registrationKey(ctx contractapi.TransactionContextInterface, account string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(registeredAccountPrefix, []string{account})
+	if err != nil {
+		return "", ledgerutil.Wrap(err, "failed to create the composite key for prefix %s", registeredAccountPrefix)
+	}
+	return key, nil
+}
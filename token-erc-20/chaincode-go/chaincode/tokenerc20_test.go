@@ -0,0 +1,2354 @@
+package chaincode
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil"
+)
+
+const (
+	alice = "alice"
+	bob   = "bob"
+	carol = "carol"
+)
+
+// allowanceKey returns the world state key of the allowance owner has given spender
+func allowanceKey(t *testing.T, stub *fakeStub, owner string, spender string) string {
+	key, err := stub.CreateCompositeKey(allowancePrefix, []string{owner, spender})
+	if err != nil {
+		t.Fatalf("failed to create allowance key: %v", err)
+	}
+	return key
+}
+
+// checkResult fails the test when err does not match the expected error substring, "" meaning success
+func checkResult(t *testing.T, err error, wantErr string) {
+	t.Helper()
+	if wantErr == "" && err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if wantErr != "" && (err == nil || !strings.Contains(err.Error(), wantErr)) {
+		t.Fatalf("expected error containing %q, got %v", wantErr, err)
+	}
+}
+
+// checkState fails the test when the world state values differ from want
+func checkState(t *testing.T, stub *fakeStub, want map[string]string) {
+	t.Helper()
+	for key, value := range want {
+		if got := string(stub.state[key]); got != value {
+			t.Errorf("state of %q is %q, want %q", key, got, value)
+		}
+	}
+}
+
+// checkTxResult fails the test when the result of a successful transaction differs from want, whose
+// status, transaction ID and timestamp are filled in from the stub
+func checkTxResult(t *testing.T, stub *fakeStub, got *TxResult, want TxResult) {
+	t.Helper()
+	want.Status = ledgerutil.StatusSuccess
+	want.TxID = stub.txID
+	want.Timestamp = time.Unix(1600000000, 0).UTC()
+	if got == nil || !reflect.DeepEqual(*got, want) {
+		t.Errorf("result is %+v, want %+v", got, want)
+	}
+}
+
+// amountOf returns the amount stored as value
+func amountOf(t *testing.T, value string) int {
+	t.Helper()
+	amount, err := strconv.Atoi(value)
+	if err != nil {
+		t.Fatalf("invalid amount %q", value)
+	}
+	return amount
+}
+
+// checkEvent fails the test when the last event differs from want
+func checkEvent(t *testing.T, stub *fakeStub, name string, want event) {
+	t.Helper()
+	if stub.eventName != name {
+		t.Fatalf("event is %q, want %q", stub.eventName, name)
+	}
+	var got event
+	err := json.Unmarshal(stub.eventValue, &got)
+	if err != nil {
+		t.Fatalf("failed to unmarshal event: %v", err)
+	}
+	if got != want {
+		t.Errorf("event is %+v, want %+v", got, want)
+	}
+}
+
+func TestTransfer(t *testing.T) {
+	tests := []struct {
+		name      string
+		state     map[string]string
+		failures  map[string]error
+		receiver  string
+		amount    int
+		wantErr   string
+		wantState map[string]string
+	}{
+		{
+			name:      "moves tokens to a new account",
+			state:     map[string]string{alice: "100"},
+			receiver:  bob,
+			amount:    40,
+			wantState: map[string]string{alice: "60", bob: "40"},
+		},
+		{
+			name:      "adds to an existing account",
+			state:     map[string]string{alice: "100", bob: "5"},
+			receiver:  bob,
+			amount:    100,
+			wantState: map[string]string{alice: "0", bob: "105"},
+		},
+		{
+			name:     "fails without a balance",
+			receiver: bob,
+			amount:   1,
+			wantErr:  "has no balance",
+		},
+		{
+			name:      "fails with insufficient funds",
+			state:     map[string]string{alice: "10"},
+			receiver:  bob,
+			amount:    11,
+			wantErr:   "insufficient funds",
+			wantState: map[string]string{alice: "10", bob: ""},
+		},
+		{
+			name:     "fails sending to itself",
+			state:    map[string]string{alice: "10"},
+			receiver: alice,
+			amount:   1,
+			wantErr:  "same addresses",
+		},
+		{
+			name:     "fails with a negative amount",
+			state:    map[string]string{alice: "10"},
+			receiver: bob,
+			amount:   -1,
+			wantErr:  "amount must not be negative",
+		},
+		{
+			name:     "fails when the world state cannot be read",
+			state:    map[string]string{alice: "10"},
+			failures: map[string]error{"GetState": errors.New("unavailable")},
+			receiver: bob,
+			amount:   1,
+			wantErr:  "unavailable",
+		},
+		{
+			name:     "fails when the event cannot be set",
+			state:    map[string]string{alice: "10"},
+			failures: map[string]error{"SetEvent": errors.New("unavailable")},
+			receiver: bob,
+			amount:   1,
+			wantErr:  "failed to set event",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newFakeStub()
+			for key, value := range tt.state {
+				stub.state[key] = []byte(value)
+			}
+			for name, err := range tt.failures {
+				stub.failures[name] = err
+			}
+
+			result, err := new(SmartContract).Transfer(newContext(stub, alice, "Org1MSP"), tt.receiver, tt.amount)
+			checkResult(t, err, tt.wantErr)
+			checkState(t, stub, tt.wantState)
+			if tt.wantErr == "" {
+				checkEvent(t, stub, "Transfer", event{alice, tt.receiver, tt.amount})
+				checkTxResult(t, stub, result, TxResult{Account: alice, Balance: amountOf(t, tt.wantState[alice])})
+			}
+		})
+	}
+}
+
+func TestTransferFrom(t *testing.T) {
+	tests := []struct {
+		name          string
+		balance       string
+		allowance     string
+		amount        int
+		wantErr       string
+		wantBalance   string
+		wantReceived  string
+		wantAllowance string
+	}{
+		{
+			name:          "spends part of the allowance",
+			balance:       "100",
+			allowance:     "50",
+			amount:        30,
+			wantBalance:   "70",
+			wantReceived:  "30",
+			wantAllowance: "20",
+		},
+		{
+			name:          "spends the whole allowance",
+			balance:       "100",
+			allowance:     "50",
+			amount:        50,
+			wantBalance:   "50",
+			wantReceived:  "50",
+			wantAllowance: "0",
+		},
+		{
+			name:          "fails above the allowance",
+			balance:       "100",
+			allowance:     "50",
+			amount:        51,
+			wantErr:       "enough allowance",
+			wantBalance:   "100",
+			wantAllowance: "50",
+		},
+		{
+			name:        "fails without an allowance",
+			balance:     "100",
+			amount:      1,
+			wantErr:     "enough allowance",
+			wantBalance: "100",
+		},
+		{
+			name:          "fails when the owner has insufficient funds",
+			balance:       "10",
+			allowance:     "50",
+			amount:        20,
+			wantErr:       "insufficient funds",
+			wantBalance:   "10",
+			wantAllowance: "50",
+		},
+		{
+			name:          "fails with a zero amount",
+			balance:       "100",
+			allowance:     "50",
+			amount:        0,
+			wantErr:       "positive integer",
+			wantBalance:   "100",
+			wantAllowance: "50",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newFakeStub()
+			stub.state[alice] = []byte(tt.balance)
+			if tt.allowance != "" {
+				stub.state[allowanceKey(t, stub, alice, bob)] = []byte(tt.allowance)
+			}
+
+			// bob spends alice's tokens, paying carol
+			result, err := new(SmartContract).TransferFrom(newContext(stub, bob, "Org2MSP"), alice, carol, tt.amount)
+			checkResult(t, err, tt.wantErr)
+			checkState(t, stub, map[string]string{
+				alice:                             tt.wantBalance,
+				carol:                             tt.wantReceived,
+				allowanceKey(t, stub, alice, bob): tt.wantAllowance,
+			})
+			if tt.wantErr == "" {
+				checkEvent(t, stub, "Transfer", event{alice, carol, tt.amount})
+				checkTxResult(t, stub, result, TxResult{Account: alice, Balance: amountOf(t, tt.wantBalance), Allowance: amountOf(t, tt.wantAllowance)})
+			}
+		})
+	}
+}
+
+func TestBatchTransfer(t *testing.T) {
+	tests := []struct {
+		name          string
+		state         map[string]string
+		allowance     string
+		payments      []Payment
+		wantErr       string
+		wantState     map[string]string
+		wantAllowance string
+		wantEvent     string
+		wantChanges   []ledgerutil.AuditChange
+	}{
+		{
+			name:      "debits the client once and adds up payments to the same receiver",
+			state:     map[string]string{alice: "100", bob: "5"},
+			payments:  []Payment{{Receiver: bob, Amount: 30}, {Receiver: carol, Amount: 20}, {Receiver: bob, Amount: 10}},
+			wantState: map[string]string{alice: "40", bob: "45", carol: "20"},
+			wantEvent: `{"from":"alice","payments":[{"amount":40,"receiver":"bob"},{"amount":20,"receiver":"carol"}],"schemaVersion":1,"value":60}`,
+			wantChanges: []ledgerutil.AuditChange{
+				{Kind: balanceKind, Subject: []string{alice}, Old: 100, New: 40},
+				{Kind: balanceKind, Subject: []string{bob}, Old: 5, New: 45},
+				{Kind: balanceKind, Subject: []string{carol}, Old: 0, New: 20},
+			},
+		},
+		{
+			name:          "pulls from another account against the allowance",
+			state:         map[string]string{alice: "100", carol: "100"},
+			allowance:     "50",
+			payments:      []Payment{{Receiver: bob, Amount: 10}, {From: carol, Receiver: bob, Amount: 30}},
+			wantState:     map[string]string{alice: "90", bob: "40", carol: "70"},
+			wantAllowance: "20",
+			wantEvent:     `{"from":"alice","payments":[{"amount":10,"receiver":"bob"},{"amount":30,"from":"carol","receiver":"bob"}],"schemaVersion":1,"value":40}`,
+			wantChanges: []ledgerutil.AuditChange{
+				{Kind: allowanceKind, Subject: []string{carol, alice}, Old: 50, New: 20},
+				{Kind: balanceKind, Subject: []string{alice}, Old: 100, New: 90},
+				{Kind: balanceKind, Subject: []string{bob}, Old: 0, New: 40},
+				{Kind: balanceKind, Subject: []string{carol}, Old: 100, New: 70},
+			},
+		},
+		{
+			name:      "fails when the total exceeds the balance",
+			state:     map[string]string{alice: "50"},
+			payments:  []Payment{{Receiver: bob, Amount: 30}, {Receiver: carol, Amount: 30}},
+			wantErr:   "client account alice has insufficient funds",
+			wantState: map[string]string{alice: "50", bob: "", carol: ""},
+		},
+		{
+			name:          "fails above the allowance",
+			state:         map[string]string{carol: "100"},
+			allowance:     "20",
+			payments:      []Payment{{From: carol, Receiver: bob, Amount: 30}},
+			wantErr:       "enough allowance to transfer from carol",
+			wantState:     map[string]string{carol: "100", bob: ""},
+			wantAllowance: "20",
+		},
+		{
+			name:     "fails paying the paying account",
+			state:    map[string]string{alice: "100"},
+			payments: []Payment{{Receiver: alice, Amount: 1}},
+			wantErr:  "receiver must differ from the paying account",
+		},
+		{
+			name:     "fails with a zero amount",
+			state:    map[string]string{alice: "100"},
+			payments: []Payment{{Receiver: "bob", Amount: 40}, {Receiver: "  ", Amount: 0}},
+			wantErr:  "invalid arguments: receiver must be set; amount must be a positive integer",
+		},
+		{
+			name:    "fails without payments",
+			wantErr: "a batch has 1 to 100 payments, got 0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newFakeStub()
+			for key, value := range tt.state {
+				stub.state[key] = []byte(value)
+			}
+			if tt.allowance != "" {
+				stub.state[allowanceKey(t, stub, carol, alice)] = []byte(tt.allowance)
+			}
+
+			result, err := new(SmartContract).BatchTransfer(newContext(stub, alice, "Org1MSP"), tt.payments)
+			checkResult(t, err, tt.wantErr)
+			checkState(t, stub, tt.wantState)
+			if tt.allowance != "" {
+				checkState(t, stub, map[string]string{allowanceKey(t, stub, carol, alice): tt.wantAllowance})
+			}
+			if tt.wantErr == "" {
+				// the audit record is published with the event, next to its usual fields
+				var fields map[string]json.RawMessage
+				err = json.Unmarshal(stub.eventValue, &fields)
+				if err != nil {
+					t.Fatalf("failed to unmarshal event: %v", err)
+				}
+				var record ledgerutil.AuditRecord
+				err = json.Unmarshal(fields["audit"], &record)
+				if err != nil {
+					t.Fatalf("failed to unmarshal audit record: %v", err)
+				}
+				if !reflect.DeepEqual(record.Changes, tt.wantChanges) {
+					t.Errorf("audit changes are %+v, want %+v", record.Changes, tt.wantChanges)
+				}
+				delete(fields, "audit")
+				payload, _ := json.Marshal(fields)
+				if stub.eventName != "BatchTransfer" || string(payload) != tt.wantEvent {
+					t.Errorf("event is %s %s, want %s", stub.eventName, payload, tt.wantEvent)
+				}
+				checkTxResult(t, stub, result, TxResult{Account: alice, Balance: amountOf(t, tt.wantState[alice])})
+			}
+		})
+	}
+}
+
+func TestSimulateTransfer(t *testing.T) {
+	stub := newFakeStub()
+	stub.state[alice] = []byte("100")
+	stub.state[bob] = []byte("5")
+	contract := new(SmartContract)
+
+	preview, err := contract.SimulateTransfer(newContext(stub, alice, "Org1MSP"), bob, 40)
+	checkResult(t, err, "")
+	want := &TransferPreview{From: alice, To: bob, Amount: 40, FromBalance: 60, ToBalance: 45}
+	if !reflect.DeepEqual(preview, want) {
+		t.Errorf("preview is %+v, want %+v", preview, want)
+	}
+	checkState(t, stub, map[string]string{alice: "100", bob: "5"})
+	if stub.eventName != "" {
+		t.Errorf("simulation set event %s", stub.eventName)
+	}
+
+	// submitting the transfer leaves the predicted balances
+	_, err = contract.Transfer(newContext(stub, alice, "Org1MSP"), bob, 40)
+	checkResult(t, err, "")
+	checkState(t, stub, map[string]string{alice: "60", bob: "45"})
+
+	_, err = contract.SimulateTransfer(newContext(stub, alice, "Org1MSP"), bob, 61)
+	checkResult(t, err, "insufficient funds")
+	_, err = contract.SimulateTransfer(newContext(stub, alice, "Org1MSP"), " ", 1)
+	checkResult(t, err, "receiver must be set")
+}
+
+func TestWithholding(t *testing.T) {
+	stub := newFakeStub()
+	stub.chaincodes[accessControlName] = accessControl("config.SetParameter", "token.SetWithholding", "token.GetWithholdingReport")
+	stub.state[alice] = []byte("10000")
+	ctx := newContext(stub, alice, "Org1MSP")
+	contract := new(SmartContract)
+
+	err := contract.SetWithholdingRate(ctx, "contractor", 1500)
+	checkResult(t, err, "")
+	err = contract.SetAccountCategory(ctx, bob, "contractor")
+	checkResult(t, err, "")
+
+	// nothing is withheld until the tax account is set
+	stub.txID = "tx1"
+	_, err = contract.Transfer(ctx, bob, 100)
+	checkResult(t, err, "")
+	checkState(t, stub, map[string]string{alice: "9900", bob: "100"})
+	_, err = NewConfigContract().SetParameter(ctx, taxAccountParam.Name, `"tax"`)
+	checkResult(t, err, "")
+
+	preview, err := contract.SimulateTransfer(ctx, bob, 1001)
+	checkResult(t, err, "")
+	want := &TransferPreview{From: alice, To: bob, Amount: 1001, FromBalance: 8899, ToBalance: 951, Withheld: 150}
+	if !reflect.DeepEqual(preview, want) {
+		t.Errorf("preview is %+v, want %+v", preview, want)
+	}
+	stub.txID = "tx2"
+	_, err = contract.Transfer(ctx, bob, 1001)
+	checkResult(t, err, "")
+	checkState(t, stub, map[string]string{alice: "8899", bob: "951", "tax": "150"})
+	checkEvent(t, stub, "Transfer", event{alice, bob, 1001})
+
+	// accounts without a category, or of a category without a rate, receive the whole amount
+	stub.txID = "tx3"
+	_, err = contract.Transfer(ctx, carol, 100)
+	checkResult(t, err, "")
+	err = contract.SetWithholdingRate(ctx, "employee", 2000)
+	checkResult(t, err, "")
+	err = contract.SetAccountCategory(ctx, carol, "employee")
+	checkResult(t, err, "")
+	// a transaction making two transfers, e.g. through InvokeChaincode, records an entry for each
+	stub.txID = "tx4"
+	_, err = contract.Transfer(ctx, carol, 50)
+	checkResult(t, err, "")
+	_, err = contract.Transfer(ctx, carol, 40)
+	checkResult(t, err, "")
+	err = contract.SetAccountCategory(ctx, carol, "")
+	checkResult(t, err, "")
+	stub.txID = "tx5"
+	_, err = contract.Transfer(ctx, carol, 100)
+	checkResult(t, err, "")
+	checkState(t, stub, map[string]string{alice: "8609", carol: "272", "tax": "168"})
+
+	page, err := contract.GetWithholdingEntriesPage(ctx, "2020-09", 1, "")
+	checkResult(t, err, "")
+	timestamp := time.Unix(1600000000, 0).UTC()
+	stub.txID = "tx2"
+	wantEntries := []*WithholdingEntry{{"tx2", ledgerutil.RecordID(stub, alice, bob, "1001"), timestamp, alice, bob, "contractor", 1500, 1001, 150, "tax"}}
+	if !reflect.DeepEqual(page.Entries, wantEntries) || page.Bookmark == "" {
+		t.Fatalf("first page is %+v, want %+v and a bookmark", page, wantEntries)
+	}
+	page, err = contract.GetWithholdingEntriesPage(ctx, "2020-09", 2, page.Bookmark)
+	checkResult(t, err, "")
+	stub.txID = "tx4"
+	wantEntries = []*WithholdingEntry{{"tx4", ledgerutil.RecordID(stub, alice, carol, "50"), timestamp, alice, carol, "employee", 2000, 50, 10, "tax"},
+		{"tx4", ledgerutil.RecordID(stub, alice, carol, "40"), timestamp, alice, carol, "employee", 2000, 40, 8, "tax"}}
+	sort.Slice(wantEntries, func(i, j int) bool { return wantEntries[i].ID < wantEntries[j].ID })
+	if !reflect.DeepEqual(page.Entries, wantEntries) || page.Bookmark != "" {
+		t.Errorf("last page is %+v, want %+v", page, wantEntries)
+	}
+
+	report, err := contract.GetWithholdingReport(ctx, "2020-09")
+	checkResult(t, err, "")
+	wantReport := &WithholdingReport{Period: "2020-09", Categories: []WithholdingTotal{{"contractor", 1, 1001, 150}, {"employee", 2, 90, 18}},
+		Transfers: 3, Amount: 1091, Withheld: 168}
+	if !reflect.DeepEqual(report, wantReport) {
+		t.Errorf("report is %+v, want %+v", report, wantReport)
+	}
+	report, err = contract.GetWithholdingReport(ctx, "2020-10")
+	checkResult(t, err, "")
+	if len(report.Categories) != 0 || report.Transfers != 0 {
+		t.Errorf("report of a month without withholding is %+v", report)
+	}
+	_, err = contract.GetWithholdingReport(ctx, "2020-9")
+	checkResult(t, err, "period must be a month such as 2024-01")
+	err = contract.SetWithholdingRate(ctx, "contractor", 10001)
+	checkResult(t, err, "rate must be at most 10000 basis points")
+
+	stub.chaincodes[accessControlName] = accessControl()
+	err = contract.SetWithholdingRate(ctx, "contractor", 0)
+	checkResult(t, err, "not authorized to perform token.SetWithholding")
+	_, err = contract.GetWithholdingReport(ctx, "2020-09")
+	checkResult(t, err, "not authorized to perform token.GetWithholdingReport")
+}
+
+func TestBatchTransferWithholding(t *testing.T) {
+	stub := newFakeStub()
+	stub.chaincodes[accessControlName] = accessControl("config.SetParameter", "token.SetWithholding", "token.GetWithholdingReport")
+	stub.state[alice] = []byte("1000")
+	stub.state["tax"] = []byte("5")
+	ctx := newContext(stub, alice, "Org1MSP")
+	contract := new(SmartContract)
+
+	_, err := NewConfigContract().SetParameter(ctx, taxAccountParam.Name, `"tax"`)
+	checkResult(t, err, "")
+	err = contract.SetWithholdingRate(ctx, "contractor", 1500)
+	checkResult(t, err, "")
+	err = contract.SetAccountCategory(ctx, bob, "contractor")
+	checkResult(t, err, "")
+
+	// the tax account is credited once with what is withheld from every payment to a contractor
+	stub.txID = "tx1"
+	_, err = contract.BatchTransfer(ctx, []Payment{{Receiver: bob, Amount: 100}, {Receiver: carol, Amount: 100}, {Receiver: bob, Amount: 200}})
+	checkResult(t, err, "")
+	checkState(t, stub, map[string]string{alice: "600", bob: "255", carol: "100", "tax": "50"})
+
+	page, err := contract.GetWithholdingEntriesPage(ctx, "2020-09", 10, "")
+	checkResult(t, err, "")
+	wantEntries := []*WithholdingEntry{{"tx1", ledgerutil.RecordID(stub, alice, bob, "300"), time.Unix(1600000000, 0).UTC(), alice, bob, "contractor", 1500, 300, 45, "tax"}}
+	if !reflect.DeepEqual(page.Entries, wantEntries) {
+		t.Errorf("entries are %+v, want %+v", page.Entries, wantEntries)
+	}
+}
+
+func TestSimulateTransferFrom(t *testing.T) {
+	stub := newFakeStub()
+	stub.state[alice] = []byte("100")
+	stub.state[allowanceKey(t, stub, alice, bob)] = []byte("50")
+	contract := new(SmartContract)
+
+	// bob previews spending alice's tokens, paying carol
+	preview, err := contract.SimulateTransferFrom(newContext(stub, bob, "Org2MSP"), alice, carol, 30)
+	checkResult(t, err, "")
+	want := &TransferPreview{From: alice, To: carol, Amount: 30, FromBalance: 70, ToBalance: 30, Allowance: amountOf(t, "20")}
+	if !reflect.DeepEqual(preview, want) {
+		t.Errorf("preview is %+v, want %+v", preview, want)
+	}
+	checkState(t, stub, map[string]string{alice: "100", carol: "", allowanceKey(t, stub, alice, bob): "50"})
+
+	_, err = contract.SimulateTransferFrom(newContext(stub, bob, "Org2MSP"), alice, carol, 51)
+	checkResult(t, err, "enough allowance")
+	_, err = contract.SimulateTransferFrom(newContext(stub, carol, "Org1MSP"), alice, bob, 1)
+	checkResult(t, err, "enough allowance")
+}
+
+func TestApprove(t *testing.T) {
+	tests := []struct {
+		name      string
+		existing  string
+		failures  map[string]error
+		amount    int
+		wantErr   string
+		wantValue string
+	}{
+		{
+			name:      "sets an allowance",
+			amount:    50,
+			wantValue: "50",
+		},
+		{
+			name:      "replaces an existing allowance",
+			existing:  "80",
+			amount:    20,
+			wantValue: "20",
+		},
+		{
+			name:      "revokes an allowance with zero",
+			existing:  "80",
+			amount:    0,
+			wantValue: "0",
+		},
+		{
+			name:      "fails when the world state cannot be written",
+			existing:  "80",
+			failures:  map[string]error{"PutState": errors.New("unavailable")},
+			amount:    20,
+			wantErr:   "failed to update state",
+			wantValue: "80",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newFakeStub()
+			if tt.existing != "" {
+				stub.state[allowanceKey(t, stub, alice, bob)] = []byte(tt.existing)
+			}
+			for name, err := range tt.failures {
+				stub.failures[name] = err
+			}
+
+			contract := new(SmartContract)
+			result, err := contract.Approve(newContext(stub, alice, "Org1MSP"), bob, tt.amount)
+			checkResult(t, err, tt.wantErr)
+			checkState(t, stub, map[string]string{allowanceKey(t, stub, alice, bob): tt.wantValue})
+			if tt.wantErr != "" {
+				return
+			}
+			checkEvent(t, stub, "Approval", event{alice, bob, tt.amount})
+			checkTxResult(t, stub, result, TxResult{Account: alice, Allowance: tt.amount})
+
+			allowance, err := contract.Allowance(newContext(stub, carol, "Org2MSP"), alice, bob)
+			if err != nil {
+				t.Fatalf("failed to read allowance: %v", err)
+			}
+			if allowance != tt.amount {
+				t.Errorf("allowance is %d, want %d", allowance, tt.amount)
+			}
+		})
+	}
+}
+
+func TestApproveWithTerms(t *testing.T) {
+	stub := newFakeStub()
+	stub.state[alice] = []byte("100")
+	stub.state[allowanceKey(t, stub, alice, bob)] = []byte("20")
+	contract := new(SmartContract)
+	ctx := newContext(stub, alice, "Org1MSP")
+
+	_, err := contract.ApproveWithTerms(ctx, bob, 50, "2020-10-01T00:00:00+02:00", " PO-77 ")
+	checkResult(t, err, "")
+	checkState(t, stub, map[string]string{allowanceKey(t, stub, alice, bob): "50"})
+	var payload approval
+	err = json.Unmarshal(stub.eventValue, &payload)
+	checkResult(t, err, "")
+	want := approval{From: alice, To: bob, Value: 50, Previous: 20, ExpiresAt: "2020-09-30T22:00:00Z", Reference: "PO-77"}
+	if !reflect.DeepEqual(payload, want) {
+		t.Errorf("event is %+v, want %+v", payload, want)
+	}
+	terms, err := contract.GetAllowanceTerms(newContext(stub, bob, "Org2MSP"), alice, bob)
+	checkResult(t, err, "")
+	if !reflect.DeepEqual(terms, &AllowanceTerms{Owner: alice, Spender: bob, ExpiresAt: "2020-09-30T22:00:00Z", Reference: "PO-77"}) {
+		t.Errorf("terms are %+v", terms)
+	}
+	_, err = contract.TransferFrom(newContext(stub, bob, "Org2MSP"), alice, carol, 10)
+	checkResult(t, err, "")
+
+	// a plain Approve removes the terms and reports the allowance it replaced
+	_, err = contract.Approve(ctx, bob, 30)
+	checkResult(t, err, "")
+	payload = approval{}
+	err = json.Unmarshal(stub.eventValue, &payload)
+	checkResult(t, err, "")
+	if want := (approval{From: alice, To: bob, Value: 30, Previous: 40}); !reflect.DeepEqual(payload, want) {
+		t.Errorf("event is %+v, want %+v", payload, want)
+	}
+	terms, err = contract.GetAllowanceTerms(ctx, alice, bob)
+	checkResult(t, err, "")
+	if !reflect.DeepEqual(terms, &AllowanceTerms{Owner: alice, Spender: bob}) {
+		t.Errorf("terms after Approve are %+v, want none", terms)
+	}
+
+	// nothing can be spent once the allowance expired
+	termsKey, err := stub.CreateCompositeKey(allowanceTermsPrefix, []string{alice, bob})
+	checkResult(t, err, "")
+	stub.state[termsKey] = []byte(`{"expiresAt":"2020-09-13T12:26:40Z"}`)
+	_, err = contract.TransferFrom(newContext(stub, bob, "Org2MSP"), alice, carol, 10)
+	checkResult(t, err, "allowance of spender has expired")
+	allowance, err := contract.Allowance(ctx, alice, bob)
+	checkResult(t, err, "")
+	if allowance != 0 {
+		t.Errorf("expired allowance is %d, want 0", allowance)
+	}
+
+	_, err = contract.ApproveWithTerms(ctx, bob, 10, "2020-09-13T12:26:40Z", "")
+	checkResult(t, err, "expiresAt 2020-09-13T12:26:40Z is not after the transaction time")
+	_, err = contract.ApproveWithTerms(ctx, bob, 10, "tomorrow", "")
+	checkResult(t, err, "expiresAt must be an RFC 3339 time")
+}
+
+func TestMint(t *testing.T) {
+	tests := []struct {
+		name        string
+		granted     []string
+		noACL       bool
+		balance     string
+		supply      string
+		amount      int
+		wantErr     string
+		wantBalance string
+		wantSupply  string
+	}{
+		{
+			name:        "mints into a new account",
+			granted:     []string{"token.Mint"},
+			amount:      1000,
+			wantBalance: "1000",
+			wantSupply:  "1000",
+		},
+		{
+			name:        "adds to the balance and the total supply",
+			granted:     []string{"token.Mint"},
+			balance:     "200",
+			supply:      "500",
+			amount:      100,
+			wantBalance: "300",
+			wantSupply:  "600",
+		},
+		{
+			name:        "fails without the mint role",
+			granted:     []string{"token.Burn"},
+			balance:     "200",
+			supply:      "500",
+			amount:      100,
+			wantErr:     "not authorized to perform token.Mint",
+			wantBalance: "200",
+			wantSupply:  "500",
+		},
+		{
+			name:    "fails when the access-control chaincode is not deployed",
+			noACL:   true,
+			amount:  100,
+			wantErr: "failed to check access",
+		},
+		{
+			name:    "fails with a zero amount",
+			granted: []string{"token.Mint"},
+			amount:  0,
+			wantErr: "positive integer",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newFakeStub()
+			if !tt.noACL {
+				stub.chaincodes[accessControlName] = accessControl(tt.granted...)
+			}
+			if tt.balance != "" {
+				stub.state[alice] = []byte(tt.balance)
+			}
+			if tt.supply != "" {
+				stub.state[totalSupplyKey] = []byte(tt.supply)
+			}
+
+			result, err := new(SmartContract).Mint(newContext(stub, alice, "Org1MSP"), tt.amount)
+			checkResult(t, err, tt.wantErr)
+			checkState(t, stub, map[string]string{alice: tt.wantBalance, totalSupplyKey: tt.wantSupply})
+			if tt.wantErr == "" {
+				checkEvent(t, stub, "Transfer", event{"0x0", alice, tt.amount})
+				checkTxResult(t, stub, result, TxResult{Account: alice, Balance: amountOf(t, tt.wantBalance)})
+			}
+		})
+	}
+}
+
+func TestMintQuota(t *testing.T) {
+	stub := newFakeStub()
+	stub.chaincodes[accessControlName] = accessControl("token.Mint", "token.SetMintQuota")
+	ctx := newContext(stub, alice, "Org1MSP")
+	contract := new(SmartContract)
+	periodStart := time.Date(2020, 9, 13, 0, 0, 0, 0, time.UTC)
+
+	_, err := contract.GetQuotaUsage(ctx, "Org1MSP")
+	checkResult(t, err, "org Org1MSP has no mint quota")
+	_, err = contract.Mint(ctx, 500)
+	checkResult(t, err, "")
+
+	usage, err := contract.SetMintQuota(ctx, "Org1MSP", 100, "24h")
+	checkResult(t, err, "")
+	want := &QuotaUsage{"Org1MSP", 100, "24h", periodStart, periodStart.Add(24 * time.Hour), 0, 100}
+	if !reflect.DeepEqual(usage, want) {
+		t.Errorf("usage is %+v, want %+v", usage, want)
+	}
+	_, err = contract.Mint(ctx, 60)
+	checkResult(t, err, "")
+	_, err = contract.Mint(ctx, 41)
+	checkResult(t, err, "org Org1MSP can mint 40 more tokens until 2020-09-14T00:00:00Z, not 41")
+	if got := ledgerutil.ParseError(err.Error()).Code; got != ledgerutil.CodeQuotaExceeded {
+		t.Errorf("error code is %s, want %s", got, ledgerutil.CodeQuotaExceeded)
+	}
+	checkState(t, stub, map[string]string{alice: "560", totalSupplyKey: "560"})
+
+	// other orgs are not limited by the quota of Org1MSP
+	_, err = contract.Mint(newContext(stub, bob, "Org2MSP"), 1000)
+	checkResult(t, err, "")
+
+	// changing the allocation keeps the usage, changing the period starts counting anew
+	usage, err = contract.SetMintQuota(ctx, "Org1MSP", 50, "24h")
+	checkResult(t, err, "")
+	if usage.Minted != 60 || usage.Remaining != 0 {
+		t.Errorf("usage after lowering the allocation is %+v, want 60 minted and nothing remaining", usage)
+	}
+	usage, err = contract.SetMintQuota(ctx, "Org1MSP", 50, "1440m")
+	checkResult(t, err, "")
+	if usage.Minted != 60 {
+		t.Errorf("usage after setting the same period is %+v, want 60 minted", usage)
+	}
+	usage, err = contract.SetMintQuota(ctx, "Org1MSP", 50, "168h")
+	checkResult(t, err, "")
+	if usage.Minted != 0 || usage.Remaining != 50 {
+		t.Errorf("usage after changing the period is %+v, want 50 remaining", usage)
+	}
+
+	// what was minted in an earlier period does not count
+	quotaKey, err := stub.CreateCompositeKey(mintQuotaPrefix, []string{"Org1MSP"})
+	checkResult(t, err, "")
+	stub.state[quotaKey] = []byte(`{"mspID":"Org1MSP","allocation":50,"period":"24h","periodStart":"2020-09-12T00:00:00Z","minted":50}`)
+	usage, err = contract.GetQuotaUsage(ctx, "Org1MSP")
+	checkResult(t, err, "")
+	if usage.Minted != 0 || usage.Remaining != 50 || !usage.PeriodStart.Equal(periodStart) {
+		t.Errorf("usage in a new period is %+v, want 50 remaining from %s", usage, periodStart)
+	}
+
+	_, err = contract.SetMintQuota(ctx, "Org1MSP", 50, "30s")
+	checkResult(t, err, "period must be a duration of at least 1m")
+	_, err = contract.SetMintQuota(ctx, "Org1MSP", -1, "24h")
+	checkResult(t, err, "allocation")
+	_, err = contract.SetMintQuota(newContext(stub, bob, "Org2MSP"), "Org2MSP", 0, "24h")
+	checkResult(t, err, "")
+	_, err = contract.Mint(newContext(stub, bob, "Org2MSP"), 1)
+	checkResult(t, err, "org Org2MSP can mint 0 more tokens")
+
+	stub.chaincodes[accessControlName] = accessControl("token.Mint")
+	_, err = contract.SetMintQuota(ctx, "Org1MSP", 1000, "24h")
+	checkResult(t, err, "not authorized to perform token.SetMintQuota")
+	err = contract.RemoveMintQuota(ctx, "Org1MSP")
+	checkResult(t, err, "not authorized to perform token.SetMintQuota")
+
+	stub.chaincodes[accessControlName] = accessControl("token.Mint", "token.SetMintQuota")
+	err = contract.RemoveMintQuota(ctx, "Org1MSP")
+	checkResult(t, err, "")
+	_, err = contract.Mint(ctx, 1000)
+	checkResult(t, err, "")
+	err = contract.RemoveMintQuota(ctx, "Org1MSP")
+	checkResult(t, err, "org Org1MSP has no mint quota")
+}
+
+func TestBurn(t *testing.T) {
+	tests := []struct {
+		name        string
+		granted     []string
+		amount      int
+		wantErr     string
+		wantBalance string
+		wantSupply  string
+	}{
+		{
+			name:        "burns from the balance and the total supply",
+			granted:     []string{"token.Burn"},
+			amount:      40,
+			wantBalance: "60",
+			wantSupply:  "460",
+		},
+		{
+			name:        "fails without the burn role",
+			granted:     []string{"token.Mint"},
+			amount:      40,
+			wantErr:     "not authorized to perform token.Burn",
+			wantBalance: "100",
+			wantSupply:  "500",
+		},
+		{
+			name:        "fails with a negative amount",
+			granted:     []string{"token.Burn"},
+			amount:      -5,
+			wantErr:     "positive integer",
+			wantBalance: "100",
+			wantSupply:  "500",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newFakeStub()
+			stub.chaincodes[accessControlName] = accessControl(tt.granted...)
+			stub.state[alice] = []byte("100")
+			stub.state[totalSupplyKey] = []byte("500")
+
+			result, err := new(SmartContract).Burn(newContext(stub, alice, "Org1MSP"), tt.amount)
+			checkResult(t, err, tt.wantErr)
+			checkState(t, stub, map[string]string{alice: tt.wantBalance, totalSupplyKey: tt.wantSupply})
+			if tt.wantErr == "" {
+				checkEvent(t, stub, "Transfer", event{"0x0", alice, tt.amount})
+				checkTxResult(t, stub, result, TxResult{Account: alice, Balance: amountOf(t, tt.wantBalance)})
+			}
+		})
+	}
+}
+
+func TestErrorCodes(t *testing.T) {
+	tests := []struct {
+		name     string
+		state    map[string]string
+		fn       func(s *SmartContract, ctx *ledgerutil.TransactionContext) error
+		wantCode ledgerutil.Code
+	}{
+		{
+			name:  "transfer above the balance",
+			state: map[string]string{alice: "10"},
+			fn: func(s *SmartContract, ctx *ledgerutil.TransactionContext) error {
+				_, err := s.Transfer(ctx, bob, 11)
+				return err
+			},
+			wantCode: ledgerutil.CodeInsufficientFunds,
+		},
+		{
+			name: "transfer from an account without a balance",
+			fn: func(s *SmartContract, ctx *ledgerutil.TransactionContext) error {
+				_, err := s.Transfer(ctx, bob, 1)
+				return err
+			},
+			wantCode: ledgerutil.CodeAccountNotFound,
+		},
+		{
+			name:  "balance of a missing account",
+			state: map[string]string{alice: "10"},
+			fn: func(s *SmartContract, ctx *ledgerutil.TransactionContext) error {
+				_, err := s.BalanceOf(ctx, carol)
+				return err
+			},
+			wantCode: ledgerutil.CodeAccountNotFound,
+		},
+		{
+			name:  "transfer from above the allowance",
+			state: map[string]string{bob: "100"},
+			fn: func(s *SmartContract, ctx *ledgerutil.TransactionContext) error {
+				_, err := s.TransferFrom(ctx, bob, carol, 5)
+				return err
+			},
+			wantCode: ledgerutil.CodeInsufficientAllowance,
+		},
+		{
+			name: "mint without the minter role",
+			fn: func(s *SmartContract, ctx *ledgerutil.TransactionContext) error {
+				_, err := s.Mint(ctx, 5)
+				return err
+			},
+			wantCode: ledgerutil.CodeNotAuthorized,
+		},
+		{
+			name:  "transfer from a corrupt balance",
+			state: map[string]string{alice: "ten"},
+			fn: func(s *SmartContract, ctx *ledgerutil.TransactionContext) error {
+				_, err := s.Transfer(ctx, bob, 1)
+				return err
+			},
+			wantCode: ledgerutil.CodeCorruptState,
+		},
+		{
+			name:  "transfer to itself",
+			state: map[string]string{alice: "10"},
+			fn: func(s *SmartContract, ctx *ledgerutil.TransactionContext) error {
+				_, err := s.Transfer(ctx, alice, 1)
+				return err
+			},
+			wantCode: ledgerutil.CodeInvalidArgument,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newFakeStub()
+			stub.chaincodes[accessControlName] = accessControl()
+			for key, value := range tt.state {
+				stub.state[key] = []byte(value)
+			}
+
+			err := tt.fn(new(SmartContract), newContext(stub, alice, "Org1MSP"))
+			if err == nil {
+				t.Fatalf("expected error with code %s", tt.wantCode)
+			}
+			// clients only see the message of the error response
+			if got := ledgerutil.ParseError(err.Error()).Code; got != tt.wantCode {
+				t.Errorf("error code is %s, want %s: %v", got, tt.wantCode, err)
+			}
+		})
+	}
+}
+
+func TestValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		fn      func(s *SmartContract, ctx *ledgerutil.TransactionContext) error
+		wantErr string
+	}{
+		{
+			name: "transfer to an empty receiver",
+			fn: func(s *SmartContract, ctx *ledgerutil.TransactionContext) error {
+				_, err := s.Transfer(ctx, " ", 1)
+				return err
+			},
+			wantErr: "receiver must be set",
+		},
+		{
+			name: "transfer to a receiver breaking composite keys",
+			fn: func(s *SmartContract, ctx *ledgerutil.TransactionContext) error {
+				_, err := s.Transfer(ctx, "bob\x00", 1)
+				return err
+			},
+			wantErr: "receiver must not contain",
+		},
+		{
+			name: "approve a spender with a tilde",
+			fn: func(s *SmartContract, ctx *ledgerutil.TransactionContext) error {
+				_, err := s.Approve(ctx, "bob~carol", 1)
+				return err
+			},
+			wantErr: "spender must not contain",
+		},
+		{
+			name: "allowance of an oversized owner",
+			fn: func(s *SmartContract, ctx *ledgerutil.TransactionContext) error {
+				_, err := s.Allowance(ctx, strings.Repeat("a", ledgerutil.MaxKeyLength+1), bob)
+				return err
+			},
+			wantErr: "owner is longer than",
+		},
+		{
+			name: "transfer from with every argument invalid",
+			fn: func(s *SmartContract, ctx *ledgerutil.TransactionContext) error {
+				_, err := s.TransferFrom(ctx, "", "\xff", 0)
+				return err
+			},
+			wantErr: "from must be set; receiver is not valid UTF-8; amount must be a positive integer",
+		},
+		{
+			name: "profile of an empty account",
+			fn: func(s *SmartContract, ctx *ledgerutil.TransactionContext) error {
+				_, err := s.AccountProfile(ctx, "")
+				return err
+			},
+			wantErr: "account must be set",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newFakeStub()
+			stub.state[alice] = []byte("10")
+
+			err := tt.fn(new(SmartContract), newContext(stub, alice, "Org1MSP"))
+			checkResult(t, err, tt.wantErr)
+			if got := ledgerutil.ParseError(err.Error()).Code; got != ledgerutil.CodeInvalidArgument {
+				t.Errorf("error code is %s, want %s", got, ledgerutil.CodeInvalidArgument)
+			}
+			if len(stub.state) != 1 || stub.eventName != "" {
+				t.Errorf("ledger changed by an invalid transaction: %v", stub.state)
+			}
+		})
+	}
+}
+
+func TestTransferNormalizesReceiver(t *testing.T) {
+	stub := newFakeStub()
+	stub.state[alice] = []byte("10")
+
+	_, err := new(SmartContract).Transfer(newContext(stub, alice, "Org1MSP"), " bob\n", 4)
+	checkResult(t, err, "")
+	checkState(t, stub, map[string]string{alice: "6", bob: "4"})
+	checkEvent(t, stub, "Transfer", event{alice, bob, 4})
+}
+
+func TestSearchReceiptsByReference(t *testing.T) {
+	stub := newFakeStub()
+	stub.state[alice] = []byte("100")
+	stub.state[ledgerutil.QueryConfigKey] = []byte(`{"maxPageSize":100,"maxResults":1000}`)
+	ctx := newContext(stub, alice, "Org1MSP")
+	contract := new(SmartContract)
+
+	for i, transfer := range []struct {
+		receiver  string
+		amount    int
+		reference string
+	}{{bob, 10, "INV-2024/001"}, {carol, 20, "po 77"}, {bob, 5, " inv 2024 001 "}} {
+		stub.txID = "tx" + strconv.Itoa(i+1)
+		_, err := contract.TransferWithReference(ctx, transfer.receiver, transfer.amount, transfer.reference)
+		checkResult(t, err, "")
+	}
+	checkState(t, stub, map[string]string{alice: "65", bob: "15", carol: "20"})
+	checkEvent(t, stub, "Transfer", event{alice, bob, 5})
+
+	timestamp := time.Unix(1600000000, 0).UTC()
+	page, err := contract.SearchReceiptsByReference(ctx, "inv2024001", 1, "")
+	checkResult(t, err, "")
+	stub.txID = "tx1"
+	want := []*Receipt{{receiptPrefix, "tx1", ledgerutil.RecordID(stub, alice, bob, "10", "INV-2024/001"), alice, bob, 10, "INV-2024/001", timestamp, ""}}
+	if !reflect.DeepEqual(page.Receipts, want) || page.Bookmark == "" {
+		t.Fatalf("first page is %+v, want %+v and a bookmark", page, want)
+	}
+	page, err = contract.SearchReceiptsByReference(ctx, "inv2024001", 1, page.Bookmark)
+	checkResult(t, err, "")
+	stub.txID = "tx3"
+	want = []*Receipt{{receiptPrefix, "tx3", ledgerutil.RecordID(stub, alice, bob, "5", "inv 2024 001"), alice, bob, 5, "inv 2024 001", timestamp, ""}}
+	if !reflect.DeepEqual(page.Receipts, want) || page.Bookmark != "" {
+		t.Errorf("last page is %+v, want %+v", page, want)
+	}
+
+	page, err = contract.SearchReceiptsByReference(ctx, "unknown", 10, "")
+	checkResult(t, err, "")
+	if len(page.Receipts) != 0 {
+		t.Errorf("receipts of an unknown reference are %+v, want none", page.Receipts)
+	}
+
+	// a transaction paying two invoices, e.g. through InvokeChaincode, keeps a receipt of each
+	stub.txID = "tx4"
+	for _, reference := range []string{"INV-7", "INV-8"} {
+		_, err = contract.TransferWithReference(ctx, carol, 1, reference)
+		checkResult(t, err, "")
+	}
+	for _, reference := range []string{"INV-7", "INV-8"} {
+		page, err = contract.SearchReceiptsByReference(ctx, reference, 10, "")
+		checkResult(t, err, "")
+		if len(page.Receipts) != 1 || page.Receipts[0].TxID != "tx4" || page.Receipts[0].Reference != reference {
+			t.Errorf("receipts of %s are %+v, want the one of tx4", reference, page.Receipts)
+		}
+	}
+
+	_, err = contract.TransferWithReference(ctx, bob, 1, " -/ ")
+	checkResult(t, err, "reference must have a letter or digit")
+	_, err = contract.TransferWithReference(ctx, bob, 1000, "INV-1")
+	checkResult(t, err, "insufficient funds")
+	_, err = contract.SearchReceiptsByReference(ctx, "", 10, "")
+	checkResult(t, err, "reference must have a letter or digit")
+}
+
+func TestGetFinalityReceipt(t *testing.T) {
+	stub := newFakeStub()
+	stub.state[alice] = []byte("100")
+	ctx := newContext(stub, alice, "Org1MSP")
+	contract := new(SmartContract)
+
+	stub.txID = "tx1"
+	_, err := contract.TransferWithReference(ctx, bob, 10, "INV-1")
+	checkResult(t, err, "")
+	stub.txID = "tx2"
+	_, err = contract.Transfer(ctx, bob, 20)
+	checkResult(t, err, "")
+	stub.state[ledgerutil.AuditConfigKey] = []byte(`{"onLedger":true}`)
+	// a transaction paying two references has a receipt of each
+	stub.txID = "tx3"
+	_, err = contract.TransferWithReference(ctx, carol, 30, "INV-2")
+	checkResult(t, err, "")
+	_, err = contract.TransferWithReference(ctx, carol, 5, "INV-3")
+	checkResult(t, err, "")
+
+	timestamp := time.Unix(1600000000, 0).UTC()
+	finality, err := contract.GetFinalityReceipt(ctx, " tx1 ")
+	checkResult(t, err, "")
+	stub.txID = "tx1"
+	want := &FinalityReceipt{TxID: "tx1", Receipts: []*Receipt{{receiptPrefix, "tx1", ledgerutil.RecordID(stub, alice, bob, "10", "INV-1"), alice, bob, 10, "INV-1", timestamp, ""}}}
+	if !reflect.DeepEqual(finality, want) {
+		t.Errorf("finality receipt of tx1 is %+v, want %+v", finality, want)
+	}
+	finality, err = contract.GetFinalityReceipt(ctx, "tx3")
+	checkResult(t, err, "")
+	if len(finality.Receipts) != 2 || finality.Audit == nil || finality.Audit.TxID != "tx3" {
+		t.Errorf("finality receipt of tx3 is %+v, want its two receipts and audit record", finality)
+	}
+
+	_, err = contract.GetFinalityReceipt(ctx, "tx2")
+	checkResult(t, err, "no receipt or audit record for transaction tx2")
+	_, err = contract.GetFinalityReceipt(ctx, "")
+	checkResult(t, err, "txID")
+}
+
+// signState returns the signature of a payment channel state by key, as the parties make it off the ledger
+func signState(t *testing.T, key *ecdsa.PrivateKey, state ChannelState) string {
+	t.Helper()
+	message, err := ledgerutil.MarshalCanonical(state)
+	if err != nil {
+		t.Fatalf("failed to encode state: %v", err)
+	}
+	digest := sha256.Sum256(message)
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatalf("failed to sign state: %v", err)
+	}
+	signature, err := asn1.Marshal(ecdsaSignature{r, s})
+	if err != nil {
+		t.Fatalf("failed to encode signature: %v", err)
+	}
+	return base64.StdEncoding.EncodeToString(signature)
+}
+
+func TestPaymentChannel(t *testing.T) {
+	stub := newFakeStub()
+	stub.state[alice] = []byte("100")
+	stub.chaincodes[accessControlName] = accessControl("config.SetParameter")
+	aliceKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	checkResult(t, err, "")
+	bobKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	checkResult(t, err, "")
+	aliceCtx := newSigningContext(stub, alice, "Org1MSP", aliceKey)
+	bobCtx := newSigningContext(stub, bob, "Org2MSP", bobKey)
+	contract := new(SmartContract)
+
+	// the signed states of a channel and the JSON a party closes it with
+	closing := func(state ChannelState, openerKey *ecdsa.PrivateKey, counterpartyKey *ecdsa.PrivateKey) string {
+		signed := SignedChannelState{State: state}
+		if openerKey != nil {
+			signed.OpenerSignature = signState(t, openerKey, state)
+			signed.CounterpartySignature = signState(t, counterpartyKey, state)
+		}
+		signedJSON, err := json.Marshal(signed)
+		checkResult(t, err, "")
+		return string(signedJSON)
+	}
+
+	_, err = contract.OpenChannel(newContext(stub, alice, "Org1MSP"), bob, 60)
+	checkResult(t, err, "client certificate does not hold an ECDSA key")
+	_, err = contract.OpenChannel(aliceCtx, bob, 200)
+	checkResult(t, err, "insufficient funds")
+	_, err = contract.OpenChannel(aliceCtx, alice, 10)
+	checkResult(t, err, "counterparty other than the opener")
+	stub.txID = "ch1"
+	channel, err := contract.OpenChannel(aliceCtx, bob, 60)
+	checkResult(t, err, "")
+	if channel.ChannelID != "ch1" || channel.Status != ChannelPending || channel.OpenerBalance != 60 || channel.OpenerKey == "" {
+		t.Errorf("opened channel is %+v", channel)
+	}
+	checkState(t, stub, map[string]string{alice: "40"})
+	if stub.eventName != "PaymentChannel" {
+		t.Errorf("event is %q, want PaymentChannel", stub.eventName)
+	}
+
+	state1 := ChannelState{ChannelID: "ch1", Sequence: 1, OpenerBalance: 40, CounterpartyBalance: 20}
+	state2 := ChannelState{ChannelID: "ch1", Sequence: 2, OpenerBalance: 25, CounterpartyBalance: 35}
+	_, err = contract.CloseChannel(aliceCtx, closing(state1, aliceKey, bobKey))
+	checkResult(t, err, "was not joined, it can only be closed with the opening state")
+	_, err = contract.JoinChannel(newSigningContext(stub, carol, "Org2MSP", bobKey), "ch1")
+	checkResult(t, err, "only the counterparty of payment channel ch1 can join it")
+	channel, err = contract.JoinChannel(bobCtx, "ch1")
+	checkResult(t, err, "")
+	if channel.Status != ChannelOpen || channel.CounterpartyKey == "" {
+		t.Errorf("joined channel is %+v", channel)
+	}
+
+	_, err = contract.CloseChannel(aliceCtx, closing(state1, aliceKey, aliceKey))
+	checkResult(t, err, "signature of the counterparty does not match the state")
+	_, err = contract.CloseChannel(aliceCtx, closing(ChannelState{ChannelID: "ch1", Sequence: 3, OpenerBalance: 50, CounterpartyBalance: 20}, aliceKey, bobKey))
+	checkResult(t, err, "must be non-negative and add up to the deposit of 60")
+	_, err = contract.CloseChannel(newContext(stub, carol, "Org2MSP"), closing(state1, aliceKey, bobKey))
+	checkResult(t, err, "only the parties of payment channel ch1 can close it")
+
+	// the opener closes with an old state, the counterparty disputes it with the latest one
+	channel, err = contract.CloseChannel(aliceCtx, closing(state1, aliceKey, bobKey))
+	checkResult(t, err, "")
+	if channel.Status != ChannelClosing || channel.Sequence != 1 || channel.DisputeEndsAt != "2020-09-14T12:26:40Z" {
+		t.Errorf("closing channel is %+v", channel)
+	}
+	_, err = contract.SettleChannel(aliceCtx, "ch1")
+	checkResult(t, err, "payment channel ch1 can be settled from 2020-09-14T12:26:40Z on")
+	channel, err = contract.CloseChannel(bobCtx, closing(state2, aliceKey, bobKey))
+	checkResult(t, err, "")
+	if channel.Sequence != 2 || channel.OpenerBalance != 25 || channel.CounterpartyBalance != 35 || channel.DisputeEndsAt != "2020-09-14T12:26:40Z" {
+		t.Errorf("disputed channel is %+v", channel)
+	}
+	_, err = contract.CloseChannel(aliceCtx, closing(state1, aliceKey, bobKey))
+	checkResult(t, err, "a dispute needs a later state, not 1")
+
+	// without a dispute window a closed channel settles at once
+	_, err = NewConfigContract().SetParameter(aliceCtx, channelDisputeWindowParam.Name, `"0s"`)
+	checkResult(t, err, "")
+	stub.txID = "ch2"
+	_, err = contract.OpenChannel(aliceCtx, bob, 30)
+	checkResult(t, err, "")
+	_, err = contract.JoinChannel(bobCtx, "ch2")
+	checkResult(t, err, "")
+	_, err = contract.CloseChannel(bobCtx, closing(ChannelState{ChannelID: "ch2", Sequence: 7, OpenerBalance: 10, CounterpartyBalance: 20}, aliceKey, bobKey))
+	checkResult(t, err, "")
+	channel, err = contract.SettleChannel(bobCtx, "ch2")
+	checkResult(t, err, "")
+	if channel.Status != ChannelSettled {
+		t.Errorf("settled channel is %+v", channel)
+	}
+	checkState(t, stub, map[string]string{alice: "20", bob: "20"})
+	_, err = contract.SettleChannel(bobCtx, "ch2")
+	checkResult(t, err, "payment channel ch2 is SETTLED, not CLOSING")
+
+	// a channel the counterparty never joined returns the deposit with the opening state
+	stub.txID = "ch3"
+	_, err = contract.OpenChannel(aliceCtx, carol, 20)
+	checkResult(t, err, "")
+	checkState(t, stub, map[string]string{alice: "0"})
+	_, err = contract.CloseChannel(aliceCtx, closing(ChannelState{ChannelID: "ch3", OpenerBalance: 20}, nil, nil))
+	checkResult(t, err, "")
+	_, err = contract.SettleChannel(aliceCtx, "ch3")
+	checkResult(t, err, "")
+	checkState(t, stub, map[string]string{alice: "20"})
+
+	channel, err = contract.GetChannel(bobCtx, "ch1")
+	checkResult(t, err, "")
+	if channel.Status != ChannelClosing || channel.Sequence != 2 {
+		t.Errorf("channel is %+v", channel)
+	}
+	_, err = contract.GetChannel(bobCtx, "ch9")
+	checkResult(t, err, "payment channel ch9 does not exist")
+}
+
+func TestERC20Contract(t *testing.T) {
+	stub := newFakeStub()
+	stub.state[alice] = []byte("100")
+	stub.state[totalSupplyKey] = []byte("100")
+	ctx := newContext(stub, alice, "Org1MSP")
+	erc20 := NewERC20Contract(new(SmartContract))
+
+	ok, err := erc20.Transfer(ctx, bob, 30)
+	checkResult(t, err, "")
+	if !ok {
+		t.Errorf("Transfer returned false, want true")
+	}
+	checkEvent(t, stub, "Transfer", event{alice, bob, 30})
+	_, err = erc20.Approve(ctx, bob, 20)
+	checkResult(t, err, "")
+	_, err = erc20.TransferFrom(newContext(stub, bob, "Org2MSP"), alice, carol, 5)
+	checkResult(t, err, "")
+	checkState(t, stub, map[string]string{alice: "65", bob: "30", carol: "5"})
+	ok, err = erc20.TransferFrom(newContext(stub, bob, "Org2MSP"), alice, carol, 100)
+	checkResult(t, err, "spender does not have enough allowance")
+	if ok {
+		t.Errorf("failed TransferFrom returned true, want false")
+	}
+	balance, err := erc20.ClientAccountBalance(ctx)
+	checkResult(t, err, "")
+	if balance != 65 {
+		t.Errorf("client balance is %d, want 65", balance)
+	}
+	// ERC-20 has no missing accounts, only empty ones
+	balance, err = erc20.BalanceOf(ctx, "dave")
+	checkResult(t, err, "")
+	if balance != 0 {
+		t.Errorf("balance of an unknown account is %d, want 0", balance)
+	}
+}
+
+func TestERC20ContractInvoke(t *testing.T) {
+	// registered as by the chaincode, whose start fails on types the contract API has no schema for
+	tokenContract := NewContract()
+	cc, err := contractapi.NewChaincode(tokenContract, NewERC20Contract(tokenContract), NewConfigContract(), NewEventsContract())
+	if err != nil {
+		t.Fatalf("failed to create chaincode: %v", err)
+	}
+	stub := newFakeStub()
+	sender := setCreator(stub, "sender", "Org1MSP")
+	stub.state[sender] = []byte("100")
+	stub.state[totalSupplyKey] = []byte("100")
+
+	// the contract API upper-cases the first letter of the function, so the Ethereum names reach
+	// the same functions
+	for _, tt := range []struct {
+		function string
+		args     []string
+		want     string
+		wantErr  string
+	}{
+		{"erc20:Name", nil, TokenName, ""},
+		{"erc20:decimals", nil, "0", ""},
+		{"erc20:totalSupply", nil, "100", ""},
+		{"erc20:Transfer", []string{bob, "30"}, "true", ""},
+		{"erc20:transfer", []string{carol, "10"}, "true", ""},
+		{"erc20:transfer", []string{carol, "1000"}, "", "client account " + sender + " has insufficient funds"},
+		{"erc20:approve", []string{bob, "20"}, "true", ""},
+		{"erc20:balanceOf", []string{bob}, "30", ""},
+		{"token:BalanceOf", []string{bob}, "30", ""},
+		{"erc20:allowance", []string{sender, bob}, "20", ""},
+		{"erc20:balanceOf", []string{"dave"}, "0", ""},
+		{"erc20:balanceof", []string{bob}, "", "erc20:balanceof with 1 arguments is not a function of this contract"},
+	} {
+		stub.function = tt.function
+		stub.args = tt.args
+		response := cc.Invoke(stub)
+		if tt.wantErr == "" && response.Status != shim.OK {
+			t.Errorf("%s%v failed: %s", tt.function, tt.args, response.Message)
+		}
+		if tt.wantErr != "" && !strings.Contains(response.Message, tt.wantErr) {
+			t.Errorf("%s%v failed with %q, want %q", tt.function, tt.args, response.Message, tt.wantErr)
+		}
+		if got := string(response.Payload); got != tt.want {
+			t.Errorf("%s%v returned %q, want %q", tt.function, tt.args, got, tt.want)
+		}
+	}
+	checkState(t, stub, map[string]string{sender: "60", bob: "30", carol: "10"})
+}
+
+func TestStrictRecipients(t *testing.T) {
+	stub := newFakeStub()
+	stub.state[alice] = []byte("100")
+	stub.chaincodes[accessControlName] = accessControl("config.SetFlag")
+	ctx := newContext(stub, alice, "Org1MSP")
+	contract := new(SmartContract)
+
+	// transfers to unregistered accounts work until the flag is turned on
+	_, err := contract.Transfer(ctx, bob, 10)
+	checkResult(t, err, "")
+	_, err = NewConfigContract().SetFlag(ctx, strictRecipientsFlag.Name, true)
+	checkResult(t, err, "")
+	_, err = contract.Transfer(ctx, bob, 10)
+	checkResult(t, err, "account bob is not registered, its owner must call RegisterAccount before it can receive tokens")
+	_, err = contract.SimulateTransfer(ctx, bob, 10)
+	checkResult(t, err, "account bob is not registered")
+	_, err = contract.BatchTransfer(ctx, []Payment{{Receiver: bob, Amount: 10}})
+	checkResult(t, err, "account bob is not registered")
+	_, err = contract.GetAccountRegistration(ctx, bob)
+	checkResult(t, err, "account bob is not registered")
+
+	stub.txID = "tx1"
+	registration, err := contract.RegisterAccount(newContext(stub, bob, "Org2MSP"))
+	checkResult(t, err, "")
+	want := &RegisteredAccount{Account: bob, MSPID: "Org2MSP", TxID: "tx1", RegisteredAt: time.Unix(1600000000, 0).UTC()}
+	if !reflect.DeepEqual(registration, want) {
+		t.Errorf("registration is %+v, want %+v", registration, want)
+	}
+	stub.txID = "tx2"
+	registration, err = contract.RegisterAccount(newContext(stub, bob, "Org2MSP"))
+	checkResult(t, err, "")
+	if registration.TxID != "tx1" {
+		t.Errorf("registering again changed the registration to %+v", registration)
+	}
+	_, err = contract.Transfer(ctx, bob, 10)
+	checkResult(t, err, "")
+	checkState(t, stub, map[string]string{alice: "80", bob: "20"})
+	registration, err = contract.GetAccountRegistration(ctx, " bob ")
+	checkResult(t, err, "")
+	if !reflect.DeepEqual(registration, want) {
+		t.Errorf("registration is %+v, want %+v", registration, want)
+	}
+}
+
+func TestProtobufStateCodec(t *testing.T) {
+	stub := newFakeStub()
+	stub.state[alice] = []byte("100")
+	stub.chaincodes[accessControlName] = accessControl("config.Initialize")
+	ctx := newContext(stub, alice, "Org1MSP")
+	contract := new(SmartContract)
+	config := NewConfigContract()
+
+	// records written before the codec is selected stay JSON and readable
+	stub.txID = "tx1"
+	_, err := contract.TransferWithReference(ctx, bob, 10, "INV-1")
+	checkResult(t, err, "")
+	id1 := ledgerutil.RecordID(stub, alice, bob, "10", "INV-1")
+
+	_, err = config.Initialize(ctx, "xml")
+	checkResult(t, err, "stateCodec must be one of json, protobuf")
+	stub.chaincodes[accessControlName] = accessControl()
+	_, err = config.Initialize(ctx, ledgerutil.CodecProtobuf)
+	checkResult(t, err, "client is not authorized to perform config.Initialize")
+	stub.chaincodes[accessControlName] = accessControl("config.Initialize")
+	parameter, err := config.Initialize(ctx, ledgerutil.CodecProtobuf)
+	checkResult(t, err, "")
+	if parameter.Value != `"protobuf"` || parameter.UpdatedBy != alice {
+		t.Errorf("parameter is %+v", parameter)
+	}
+	_, err = config.Initialize(ctx, ledgerutil.CodecJSON)
+	checkResult(t, err, "the state codec is already protobuf and cannot be changed")
+	_, err = config.SetParameter(ctx, ledgerutil.StateCodecParam.Name, `"json"`)
+	checkResult(t, err, "stateCodec is selected once with Initialize")
+
+	stub.txID = "tx2"
+	_, err = contract.TransferWithReference(ctx, bob, 5, "inv 1")
+	checkResult(t, err, "")
+	id2 := ledgerutil.RecordID(stub, alice, bob, "5", "inv 1")
+	registration, err := contract.RegisterAccount(newContext(stub, bob, "Org2MSP"))
+	checkResult(t, err, "")
+
+	receiptKey, _ := stub.CreateCompositeKey(receiptPrefix, []string{"tx2", id2})
+	registrationKey, _ := stub.CreateCompositeKey(registeredAccountPrefix, []string{bob})
+	for _, key := range []string{receiptKey, registrationKey} {
+		if json.Valid(stub.state[key]) {
+			t.Errorf("%q is stored as JSON: %s", key, stub.state[key])
+		}
+	}
+	timestamp := time.Unix(1600000000, 0).UTC()
+	page, err := contract.SearchReceiptsByReference(ctx, "INV1", 10, "")
+	checkResult(t, err, "")
+	want := []*Receipt{{receiptPrefix, "tx1", id1, alice, bob, 10, "INV-1", timestamp, ""}, {receiptPrefix, "tx2", id2, alice, bob, 5, "inv 1", timestamp, ""}}
+	if !reflect.DeepEqual(page.Receipts, want) {
+		t.Errorf("receipts are %+v, want %+v", page.Receipts, want)
+	}
+	read, err := contract.GetAccountRegistration(ctx, bob)
+	checkResult(t, err, "")
+	if !reflect.DeepEqual(read, registration) {
+		t.Errorf("registration read back is %+v, want %+v", read, registration)
+	}
+
+	stub.state[receiptKey] = []byte{0x0a, 0x10, 'r'}
+	_, err = contract.SearchReceiptsByReference(ctx, "INV1", 10, "")
+	checkResult(t, err, "truncated field 1")
+}
+
+func TestGetAccountInfo(t *testing.T) {
+	stub := newFakeStub()
+	stub.state[alice] = []byte("100")
+	stub.chaincodes[identityRegistryName] = identityRegistry(alice)
+	ctx := newContext(stub, alice, "Org1MSP")
+	contract := new(SmartContract)
+
+	info, err := contract.GetAccountInfo(ctx, carol)
+	checkResult(t, err, "")
+	want := &AccountInfo{Account: carol, Holds: []AccountHold{}}
+	if !reflect.DeepEqual(info, want) {
+		t.Errorf("info of an unknown account is %+v, want %+v", info, want)
+	}
+
+	_, err = contract.Transfer(ctx, bob, 10)
+	checkResult(t, err, "")
+	_, err = contract.RegisterAccount(ctx)
+	checkResult(t, err, "")
+	stub.state[allowanceKey(t, stub, alice, bob)] = []byte("5")
+	stub.state[allowanceKey(t, stub, alice, carol)] = []byte("0")
+	stub.state[allowanceKey(t, stub, bob, alice)] = []byte("3")
+	stub.state[allowanceKey(t, stub, bob, carol)] = []byte("3")
+	for _, channel := range []PaymentChannel{
+		{ChannelID: "ch1", Status: ChannelOpen, Opener: alice, Counterparty: bob, Deposit: 20},
+		{ChannelID: "ch2", Status: ChannelSettled, Opener: alice, Counterparty: bob, Deposit: 30},
+		{ChannelID: "ch3", Status: ChannelOpen, Opener: bob, Counterparty: alice, Deposit: 40},
+	} {
+		key, err := stub.CreateCompositeKey(paymentChannelPrefix, []string{channel.ChannelID})
+		checkResult(t, err, "")
+		stub.state[key], err = json.Marshal(channel)
+		checkResult(t, err, "")
+	}
+
+	info, err = contract.GetAccountInfo(ctx, " alice ")
+	checkResult(t, err, "")
+	want = &AccountInfo{Account: alice, Balance: 90, KYCVerified: true, Registered: true, OutboundAllowances: 1, InboundAllowances: 1,
+		LastActivity: "2020-09-13T12:26:40Z", Holds: []AccountHold{{ChannelID: "ch1", Status: ChannelOpen, Amount: 20}}}
+	if !reflect.DeepEqual(info, want) {
+		t.Errorf("info is %+v, want %+v", info, want)
+	}
+
+	stub.chaincodes[accessControlName] = accessControl("token.SetQueryConfig")
+	err = contract.SetQueryConfig(ctx, 2, 2)
+	checkResult(t, err, "")
+	_, err = contract.GetAccountInfo(ctx, alice)
+	checkResult(t, err, "results truncated at 2, use GetAllowancesPage with a bookmark")
+	_, err = contract.GetAccountInfo(ctx, "")
+	checkResult(t, err, "account")
+}
+
+func TestDeferredSettlement(t *testing.T) {
+	stub := newFakeStub()
+	stub.state[alice] = []byte("100")
+	stub.state[bob] = []byte("50")
+	stub.chaincodes[accessControlName] = accessControl("config.SetFlag", "token.SetQueryConfig", "token.Burn")
+	ctx := newContext(stub, alice, "Org1MSP")
+	bobCtx := newContext(stub, bob, "Org2MSP")
+	contract := new(SmartContract)
+	_, err := NewConfigContract().SetFlag(ctx, deferredSettlementFlag.Name, true)
+	checkResult(t, err, "")
+
+	// transfers are recorded as obligations settling at the end of the hour, leaving the balances as they are
+	stub.txID = "tx1"
+	result, err := contract.Transfer(ctx, bob, 30)
+	checkResult(t, err, "")
+	checkTxResult(t, stub, result, TxResult{Account: alice, Balance: amountOf(t, "70"), SettlesAt: "2020-09-13T13:00:00Z"})
+	checkEvent(t, stub, "Transfer", event{alice, bob, 30})
+	if !strings.Contains(string(stub.eventValue), `"settlesAt":"2020-09-13T13:00:00Z"`) {
+		t.Errorf("event %s has no settlesAt", stub.eventValue)
+	}
+	checkState(t, stub, map[string]string{alice: "100", bob: "50"})
+	stub.txID = "tx2"
+	_, err = contract.Transfer(bobCtx, alice, 20)
+	checkResult(t, err, "")
+	// a transaction making two transfers, e.g. through InvokeChaincode, records two obligations
+	stub.txID = "tx3"
+	_, err = contract.Transfer(ctx, carol, 10)
+	checkResult(t, err, "")
+	_, err = contract.Transfer(ctx, carol, 5)
+	checkResult(t, err, "")
+
+	// what alice owes cannot be spent again
+	_, err = contract.Transfer(ctx, bob, 56)
+	checkResult(t, err, "client account alice has insufficient funds")
+	_, err = contract.Burn(ctx, 56)
+	checkResult(t, err, "burner account alice has insufficient funds")
+	_, err = contract.BatchTransfer(ctx, []Payment{{Receiver: bob, Amount: 50}, {Receiver: carol, Amount: 6}})
+	checkResult(t, err, "client account alice has insufficient funds")
+
+	settlement, err := contract.SettleWindow(ctx)
+	checkResult(t, err, "")
+	if settlement.Obligations != 0 || len(settlement.Changes) != 0 || settlement.More {
+		t.Errorf("settlement before the window ended is %+v", settlement)
+	}
+
+	// the window ended, the first two obligations net to one change of alice and bob
+	stub.seconds += 3600
+	err = contract.SetQueryConfig(ctx, 2, 2)
+	checkResult(t, err, "")
+	settlement, err = contract.SettleWindow(bobCtx)
+	checkResult(t, err, "")
+	want := &WindowSettlement{Obligations: 2, Changes: []NetChange{{Account: alice, Change: -10, Balance: 90}, {Account: bob, Change: 10, Balance: 60}}, More: true}
+	if !reflect.DeepEqual(settlement, want) {
+		t.Errorf("settlement is %+v, want %+v", settlement, want)
+	}
+	if stub.eventName != "WindowSettled" {
+		t.Errorf("event is %q, want WindowSettled", stub.eventName)
+	}
+	settlement, err = contract.SettleWindow(bobCtx)
+	checkResult(t, err, "")
+	want = &WindowSettlement{Obligations: 2, Changes: []NetChange{{Account: alice, Change: -15, Balance: 75}, {Account: carol, Change: 15, Balance: 15}}}
+	if !reflect.DeepEqual(settlement, want) {
+		t.Errorf("settlement is %+v, want %+v", settlement, want)
+	}
+	checkState(t, stub, map[string]string{alice: "75", bob: "60", carol: "15"})
+	for key := range stub.state {
+		if strings.HasPrefix(key, "\x00"+obligationPrefix+"\x00") || strings.HasPrefix(key, "\x00"+pendingDebitPrefix+"\x00") {
+			t.Errorf("settled obligation left key %q", key)
+		}
+	}
+
+	// with the flag off transfers settle at once
+	_, err = NewConfigContract().SetFlag(ctx, deferredSettlementFlag.Name, false)
+	checkResult(t, err, "")
+	result, err = contract.Transfer(ctx, bob, 75)
+	checkResult(t, err, "")
+	if result.SettlesAt != "" {
+		t.Errorf("transfer settles at %s with the flag off", result.SettlesAt)
+	}
+	checkState(t, stub, map[string]string{alice: "0", bob: "135"})
+}
+
+func TestTotalSupply(t *testing.T) {
+	stub := newFakeStub()
+	ctx := newContext(stub, alice, "Org1MSP")
+	supply, err := new(SmartContract).TotalSupply(ctx)
+	checkResult(t, err, "")
+	if supply != 0 {
+		t.Errorf("total supply before minting is %d, want 0", supply)
+	}
+
+	stub.state[totalSupplyKey] = []byte("1000")
+	supply, err = new(SmartContract).TotalSupply(ctx)
+	checkResult(t, err, "")
+	if supply != 1000 {
+		t.Errorf("total supply is %d, want 1000", supply)
+	}
+}
+
+func TestGetBalancesPage(t *testing.T) {
+	stub := newFakeStub()
+	stub.state[alice] = []byte("10")
+	stub.state[bob] = []byte("20")
+	stub.state[carol] = []byte("30")
+	stub.state[totalSupplyKey] = []byte("60")
+	stub.state[ledgerutil.AuditConfigKey] = []byte(`{"onLedger":true}`)
+	stub.state[ledgerutil.QueryConfigKey] = []byte(`{"maxPageSize":100,"maxResults":1000}`)
+	stub.state[allowanceKey(t, stub, alice, bob)] = []byte("5")
+	// an asset of a contract bundled in the same chaincode
+	assetKey, err := stub.CreateCompositeKey("asset", []string{"asset1"})
+	if err != nil {
+		t.Fatalf("failed to create asset key: %v", err)
+	}
+	stub.state[assetKey] = []byte(`{"objectType":"asset","assetID":"asset1"}`)
+
+	var balances []AccountBalance
+	bookmark := ""
+	for pages := 0; pages == 0 || bookmark != ""; pages++ {
+		if pages == 5 {
+			t.Fatalf("more pages than keys")
+		}
+		page, err := new(SmartContract).GetBalancesPage(newContext(stub, alice, "Org1MSP"), 2, bookmark)
+		checkResult(t, err, "")
+		balances = append(balances, page.Balances...)
+		bookmark = page.Bookmark
+	}
+	want := []AccountBalance{{alice, 10}, {bob, 20}, {carol, 30}}
+	if !reflect.DeepEqual(balances, want) {
+		t.Errorf("balances are %+v, want %+v", balances, want)
+	}
+
+	_, err = new(SmartContract).GetBalancesPage(newContext(stub, alice, "Org1MSP"), 101, "")
+	checkResult(t, err, "pageSize must be between 1 and 100")
+}
+
+func TestGetAllowancesPage(t *testing.T) {
+	stub := newFakeStub()
+	stub.state[alice] = []byte("10")
+	stub.state[allowanceKey(t, stub, alice, bob)] = []byte("5")
+	stub.state[allowanceKey(t, stub, alice, carol)] = []byte("6")
+	stub.state[allowanceKey(t, stub, bob, alice)] = []byte("7")
+
+	page, err := new(SmartContract).GetAllowancesPage(newContext(stub, alice, "Org1MSP"), 2, "")
+	checkResult(t, err, "")
+	want := []OwnerAllowance{{alice, bob, 5}, {alice, carol, 6}}
+	if !reflect.DeepEqual(page.Allowances, want) || page.Bookmark == "" {
+		t.Fatalf("first page is %+v, want %+v and a bookmark", page, want)
+	}
+	page, err = new(SmartContract).GetAllowancesPage(newContext(stub, alice, "Org1MSP"), 2, page.Bookmark)
+	checkResult(t, err, "")
+	want = []OwnerAllowance{{bob, alice, 7}}
+	if !reflect.DeepEqual(page.Allowances, want) || page.Bookmark != "" {
+		t.Errorf("last page is %+v, want %+v", page, want)
+	}
+}
+
+func TestListByCompositeKey(t *testing.T) {
+	stub := newFakeStub()
+	stub.chaincodes[accessControlName] = accessControl("token.ListByCompositeKey")
+	stub.state[allowanceKey(t, stub, alice, bob)] = []byte("5")
+	stub.state[allowanceKey(t, stub, alice, carol)] = []byte("6")
+	stub.state[allowanceKey(t, stub, bob, alice)] = []byte("7")
+	ctx := newContext(stub, alice, "Org1MSP")
+
+	page, err := new(SmartContract).ListByCompositeKey(ctx, allowancePrefix, []string{alice}, 1, "")
+	checkResult(t, err, "")
+	want := []ledgerutil.KeyEntry{{Attributes: []string{alice, bob}, Value: "5"}}
+	if !reflect.DeepEqual(page.Entries, want) || page.Bookmark == "" {
+		t.Fatalf("first page is %+v, want %+v and a bookmark", page, want)
+	}
+	page, err = new(SmartContract).ListByCompositeKey(ctx, allowancePrefix, []string{alice}, 1, page.Bookmark)
+	checkResult(t, err, "")
+	want = []ledgerutil.KeyEntry{{Attributes: []string{alice, carol}, Value: "6"}}
+	if !reflect.DeepEqual(page.Entries, want) || page.Bookmark != "" {
+		t.Errorf("last page is %+v, want %+v", page, want)
+	}
+
+	_, err = new(SmartContract).ListByCompositeKey(ctx, "balance", nil, 10, "")
+	checkResult(t, err, "objectType must be one of allowance, audit, auditrecord")
+	_, err = new(SmartContract).ListByCompositeKey(ctx, allowancePrefix, []string{""}, 10, "")
+	checkResult(t, err, "partialKeys must be set")
+	_, err = new(SmartContract).ListByCompositeKey(ctx, allowancePrefix, nil, 101, "")
+	checkResult(t, err, "pageSize must be between 1 and 100")
+
+	stub.chaincodes[accessControlName] = accessControl()
+	_, err = new(SmartContract).ListByCompositeKey(ctx, allowancePrefix, nil, 10, "")
+	checkResult(t, err, "client is not authorized to perform token.ListByCompositeKey")
+}
+
+func TestGetEvaluateTransactions(t *testing.T) {
+	contract := new(SmartContract)
+	contractType := reflect.TypeOf(contract)
+	for _, name := range contract.GetEvaluateTransactions() {
+		if _, ok := contractType.MethodByName(name); !ok {
+			t.Errorf("evaluate transaction %s is not a function of the contract", name)
+		}
+	}
+}
+
+func TestBeforeTransaction(t *testing.T) {
+	tests := []struct {
+		name      string
+		function  string
+		attrs     []string
+		wantErr   string
+		wantAudit bool
+	}{
+		{
+			name:      "audits a submitted function",
+			function:  "Transfer",
+			wantAudit: true,
+		},
+		{
+			name:      "audits a function called by contract name",
+			function:  "token:Mint",
+			wantAudit: true,
+		},
+		{
+			name:     "does not audit a query",
+			function: "BalanceOf",
+		},
+		{
+			name:     "lets a read-only client query",
+			function: "token:Allowance",
+			attrs:    []string{ledgerutil.ReadOnlyAttribute, "true"},
+		},
+		{
+			name:     "rejects a read-only client submitting",
+			function: "Transfer",
+			attrs:    []string{ledgerutil.ReadOnlyAttribute, "true"},
+			wantErr:  "read-only client alice of Org1MSP cannot submit Transfer",
+		},
+	}
+
+	contract := new(SmartContract)
+	before := ledgerutil.BeforeTransaction(contract.GetEvaluateTransactions())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newFakeStub()
+			stub.txID = "tx1"
+			stub.function = tt.function
+
+			err := before(newContext(stub, alice, "Org1MSP", tt.attrs...))
+			checkResult(t, err, tt.wantErr)
+
+			auditKey, _ := stub.CreateCompositeKey("audit", []string{"tx1"})
+			auditJSON, audited := stub.state[auditKey]
+			if audited != tt.wantAudit {
+				t.Fatalf("audited is %t, want %t", audited, tt.wantAudit)
+			}
+			if !audited {
+				return
+			}
+			var entry ledgerutil.AuditEntry
+			err = json.Unmarshal(auditJSON, &entry)
+			if err != nil {
+				t.Fatalf("failed to unmarshal audit entry: %v", err)
+			}
+			function := tt.function[strings.LastIndex(tt.function, ":")+1:]
+			if entry.TxID != "tx1" || entry.Function != function || entry.ClientID != alice || entry.MSPID != "Org1MSP" {
+				t.Errorf("audit entry is %+v", entry)
+			}
+		})
+	}
+}
+
+func TestBeforeTransactionClockSkew(t *testing.T) {
+	before := ledgerutil.BeforeTransaction(new(SmartContract).GetEvaluateTransactions())
+	stub := newFakeStub()
+	stub.function = "Transfer"
+
+	// the fake transaction timestamp is in 2020, so any bound on the skew rejects it
+	t.Setenv("CHAINCODE_MAX_CLOCK_SKEW", "5m")
+	err := before(newContext(stub, alice, "Org1MSP"))
+	checkResult(t, err, "transaction timestamp 2020-09-13T12:26:40Z differs from the peer's clock by more than 5m0s")
+	if got := ledgerutil.ParseError(err.Error()).Code; got != ledgerutil.CodeInvalidArgument {
+		t.Errorf("error code is %s, want %s", got, ledgerutil.CodeInvalidArgument)
+	}
+
+	t.Setenv("CHAINCODE_MAX_CLOCK_SKEW", "0")
+	err = before(newContext(stub, alice, "Org1MSP"))
+	checkResult(t, err, "")
+
+	t.Setenv("CHAINCODE_MAX_CLOCK_SKEW", "five minutes")
+	_, err = ledgerutil.TxTime(newContext(stub, alice, "Org1MSP"))
+	checkResult(t, err, "CHAINCODE_MAX_CLOCK_SKEW must be a non-negative duration")
+}
+
+func TestWhoAmI(t *testing.T) {
+	stub := newFakeStub()
+	identity, err := new(SmartContract).WhoAmI(newContext(stub, alice, "Org1MSP", "role", "minter"))
+	checkResult(t, err, "")
+	want := &ledgerutil.Identity{
+		ID:                  alice,
+		MSPID:               "Org1MSP",
+		CommonName:          alice,
+		OrganizationalUnits: []string{"client"},
+		IssuerCommonName:    "ca.example.com",
+		Attributes:          map[string]string{"role": "minter"},
+	}
+	if !reflect.DeepEqual(identity, want) {
+		t.Errorf("identity is %+v, want %+v", identity, want)
+	}
+}
+
+func TestResolveClientInvalidAttributes(t *testing.T) {
+	cert := newCertificate(alice, nil)
+	cert.Extensions[0].Value = []byte("not json")
+	ctx := new(ledgerutil.TransactionContext)
+	ctx.SetStub(newFakeStub())
+	ctx.SetClientIdentity(&fakeClientIdentity{id: alice, mspID: "Org1MSP", cert: cert})
+
+	err := ctx.ResolveClient()
+	checkResult(t, err, "invalid attributes in the client certificate")
+}
+
+func TestUnknownTransaction(t *testing.T) {
+	stub := newFakeStub()
+	stub.function = "token:Tranfer"
+
+	err := ledgerutil.UnknownTransaction(new(SmartContract))(newContext(stub, alice, "Org1MSP"))
+	checkResult(t, err, "token:Tranfer with 0 arguments is not a function of this contract, available functions: "+
+		"AccountProfile(string), Allowance(string, string), Approve(string, int), "+
+		"ApproveWithTerms(string, int, string, string), BalanceOf(string), "+
+		"BatchTransfer([]chaincode.Payment), Burn(int), ClientAccountID(), CloseChannel(string), "+
+		"GetAccountInfo(string), GetAccountRegistration(string), GetAllowanceTerms(string, string), "+
+		"GetAllowancesPage(int, string), GetAuditRecord(string), GetBalancesPage(int, string), "+
+		"GetChannel(string), GetFinalityReceipt(string), GetQueryConfig(), GetQuotaUsage(string), "+
+		"GetSchemaVersion(), GetVersion(), GetWithholdingEntriesPage(string, int, string), "+
+		"GetWithholdingReport(string), JoinChannel(string), "+
+		"ListByCompositeKey(string, []string, int, string), MigrateState(int, int, int, string), Mint(int), "+
+		"OpenChannel(string, int), Ping(), RegisterAccount(), RemoveMintQuota(string), "+
+		"SearchReceiptsByReference(string, int, string), SetAccountCategory(string, string), "+
+		"SetAuditConfig(bool), SetMintQuota(string, int, string), SetQueryConfig(int, int), "+
+		"SetWithholdingRate(string, int), SettleChannel(string), SettleWindow(), SimulateTransfer(string, int), "+
+		"SimulateTransferFrom(string, string, int), TotalSupply(), Transfer(string, int), "+
+		"TransferFrom(string, string, int), TransferWithReference(string, int, string), WhoAmI()")
+	if got := ledgerutil.ParseError(err.Error()).Code; got != ledgerutil.CodeUnknownTransaction {
+		t.Errorf("error code is %s, want %s", got, ledgerutil.CodeUnknownTransaction)
+	}
+}
+
+func TestAudit(t *testing.T) {
+	tests := []struct {
+		name       string
+		onLedger   bool
+		wantRecord bool
+	}{
+		{
+			name: "publishes the changes with the event",
+		},
+		{
+			name:       "keeps the changes on the ledger when configured",
+			onLedger:   true,
+			wantRecord: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newFakeStub()
+			stub.chaincodes[accessControlName] = accessControl("token.SetAuditConfig")
+			stub.state[alice] = []byte("10")
+			stub.state[allowanceKey(t, stub, alice, bob)] = []byte("5")
+			contract := new(SmartContract)
+			err := contract.SetAuditConfig(newContext(stub, alice, "Org1MSP"), tt.onLedger)
+			checkResult(t, err, "")
+
+			stub.txID = "tx1"
+			_, err = contract.TransferFrom(newContext(stub, bob, "Org2MSP"), alice, carol, 4)
+			checkResult(t, err, "")
+
+			var payload struct {
+				event
+				Audit ledgerutil.AuditRecord `json:"audit"`
+			}
+			err = json.Unmarshal(stub.eventValue, &payload)
+			if err != nil {
+				t.Fatalf("failed to unmarshal event: %v", err)
+			}
+			if payload.event != (event{alice, carol, 4}) {
+				t.Errorf("event is %+v", payload.event)
+			}
+			want := ledgerutil.AuditRecord{
+				TxID:      "tx1",
+				Timestamp: time.Unix(1600000000, 0).UTC(),
+				Event:     "Transfer",
+				Changes: []ledgerutil.AuditChange{
+					{Kind: balanceKind, Subject: []string{alice}, Old: 10, New: 6},
+					{Kind: balanceKind, Subject: []string{carol}, Old: 0, New: 4},
+					{Kind: allowanceKind, Subject: []string{alice, bob}, Old: 5, New: 1},
+				},
+			}
+			if !reflect.DeepEqual(payload.Audit, want) {
+				t.Errorf("audit record is %+v, want %+v", payload.Audit, want)
+			}
+
+			record, err := contract.GetAuditRecord(newContext(stub, carol, "Org1MSP"), "tx1")
+			if !tt.wantRecord {
+				checkResult(t, err, "no audit record for transaction tx1")
+				return
+			}
+			checkResult(t, err, "")
+			if !reflect.DeepEqual(*record, want) {
+				t.Errorf("stored audit record is %+v, want %+v", *record, want)
+			}
+		})
+	}
+}
+
+func TestTraceIDRecorded(t *testing.T) {
+	traceID := "4bf92f3577b34da6a3ce929d0e0e4736"
+	tests := []struct {
+		name   string
+		passed string
+		want   string
+	}{
+		{name: "records the trace ID passed by the client", passed: traceID, want: traceID},
+		{name: "ignores a malformed trace ID", passed: "not-a-trace-id"},
+		{name: "ignores an all-zero trace ID", passed: strings.Repeat("0", 32)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newFakeStub()
+			stub.state[alice] = []byte("100")
+			stub.transient = map[string][]byte{ledgerutil.TraceIDKey: []byte(tt.passed)}
+			ctx := newContext(stub, alice, "Org1MSP")
+			stub.txID = "tx1"
+			_, err := new(SmartContract).TransferWithReference(ctx, bob, 10, "INV-1")
+			checkResult(t, err, "")
+
+			var payload struct {
+				Audit ledgerutil.AuditRecord `json:"audit"`
+			}
+			err = json.Unmarshal(stub.eventValue, &payload)
+			if err != nil {
+				t.Fatalf("failed to unmarshal event: %v", err)
+			}
+			if payload.Audit.TraceID != tt.want {
+				t.Errorf("trace ID of the audit record is %q, want %q", payload.Audit.TraceID, tt.want)
+			}
+			receipt, err := _readReceipt(ctx, "tx1", ledgerutil.RecordID(stub, alice, bob, "10", "INV-1"))
+			checkResult(t, err, "")
+			if receipt.TraceID != tt.want {
+				t.Errorf("trace ID of the receipt is %q, want %q", receipt.TraceID, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetAuditConfigRequiresAccess(t *testing.T) {
+	stub := newFakeStub()
+	stub.chaincodes[accessControlName] = accessControl()
+
+	err := new(SmartContract).SetAuditConfig(newContext(stub, alice, "Org1MSP"), true)
+	checkResult(t, err, "client is not authorized to perform token.SetAuditConfig")
+	if _, ok := stub.state[ledgerutil.AuditConfigKey]; ok {
+		t.Errorf("audit config changed by an unauthorized client")
+	}
+}
+
+func TestSetQueryConfig(t *testing.T) {
+	stub := newFakeStub()
+	stub.chaincodes[accessControlName] = accessControl("token.SetQueryConfig")
+	stub.state[allowanceKey(t, stub, alice, bob)] = []byte("5")
+	stub.state[allowanceKey(t, stub, alice, carol)] = []byte("6")
+	ctx := newContext(stub, alice, "Org1MSP")
+
+	config, err := new(SmartContract).GetQueryConfig(ctx)
+	checkResult(t, err, "")
+	if *config != (ledgerutil.QueryConfig{MaxPageSize: ledgerutil.DefaultMaxPageSize, MaxResults: ledgerutil.DefaultMaxResults}) {
+		t.Errorf("default query config is %+v", config)
+	}
+
+	err = new(SmartContract).SetQueryConfig(ctx, 20, 10)
+	checkResult(t, err, "maxPageSize must be at least 1 and at most maxResults")
+	err = new(SmartContract).SetQueryConfig(ctx, 1, 10)
+	checkResult(t, err, "")
+	_, err = new(SmartContract).GetAllowancesPage(ctx, 2, "")
+	checkResult(t, err, "pageSize must be between 1 and 1")
+	page, err := new(SmartContract).GetAllowancesPage(ctx, 1, "")
+	checkResult(t, err, "")
+	if len(page.Allowances) != 1 || page.Bookmark == "" {
+		t.Errorf("page is %+v, want one allowance and a bookmark", page)
+	}
+}
+
+func TestSetQueryConfigRequiresAccess(t *testing.T) {
+	stub := newFakeStub()
+	stub.chaincodes[accessControlName] = accessControl()
+
+	err := new(SmartContract).SetQueryConfig(newContext(stub, alice, "Org1MSP"), 10, 100)
+	checkResult(t, err, "client is not authorized to perform token.SetQueryConfig")
+	if _, ok := stub.state[ledgerutil.QueryConfigKey]; ok {
+		t.Errorf("query config changed by an unauthorized client")
+	}
+}
+
+func TestMigrateState(t *testing.T) {
+	// a migration of raw balances to JSON accounts, the kind of change a later version would make
+	defer func(previous []ledgerutil.Migration) { migrations = previous }(migrations)
+	migrations = []ledgerutil.Migration{{
+		From: 1,
+		To:   2,
+		Rewrite: func(key string, value []byte) ([]byte, error) {
+			if key == totalSupplyKey || strings.HasPrefix(string(value), "{") {
+				return nil, nil
+			}
+			balance, err := ledgerutil.ParseAmount(value)
+			if err != nil {
+				return nil, err
+			}
+			return json.Marshal(map[string]int{"balance": balance})
+		},
+	}}
+
+	stub := newFakeStub()
+	stub.chaincodes[accessControlName] = accessControl("token.MigrateState")
+	stub.state[alice] = []byte("10")
+	stub.state[bob] = []byte("20")
+	stub.state[carol] = []byte("30")
+	stub.state[totalSupplyKey] = []byte("60")
+	stub.state[allowanceKey(t, stub, alice, bob)] = []byte("5")
+	ctx := newContext(stub, alice, "Org1MSP")
+
+	version, err := new(SmartContract).GetSchemaVersion(ctx)
+	checkResult(t, err, "")
+	if version != 1 {
+		t.Fatalf("schema version is %d, want 1", version)
+	}
+
+	progress, err := new(SmartContract).MigrateState(ctx, 1, 2, 2, "")
+	checkResult(t, err, "")
+	if progress.Migrated != 2 || progress.Bookmark == "" || progress.SchemaVersion != 1 {
+		t.Fatalf("first page progress is %+v", progress)
+	}
+	_, err = new(SmartContract).MigrateState(ctx, 1, 2, 2, "")
+	checkResult(t, err, "the migration continues at bookmark")
+
+	progress, err = new(SmartContract).MigrateState(ctx, 1, 2, 2, progress.Bookmark)
+	checkResult(t, err, "")
+	if progress.Migrated != 1 || progress.Bookmark != "" || progress.SchemaVersion != 2 {
+		t.Fatalf("last page progress is %+v", progress)
+	}
+	checkState(t, stub, map[string]string{
+		alice:                             `{"balance":10}`,
+		bob:                               `{"balance":20}`,
+		carol:                             `{"balance":30}`,
+		totalSupplyKey:                    "60",
+		allowanceKey(t, stub, alice, bob): "5",
+		ledgerutil.SchemaVersionKey:       "2",
+	})
+	if _, ok := stub.state[ledgerutil.SchemaMigrationKey]; ok {
+		t.Errorf("migration progress is kept after the migration")
+	}
+
+	progress, err = new(SmartContract).MigrateState(ctx, 1, 2, 2, "")
+	checkResult(t, err, "")
+	if progress.Migrated != 0 || progress.SchemaVersion != 2 {
+		t.Errorf("repeated migration progress is %+v", progress)
+	}
+	_, err = new(SmartContract).MigrateState(ctx, 2, 3, 2, "")
+	checkResult(t, err, "there is no migration from schema version 2 to 3")
+}
+
+func TestMigrateStateRequiresAccess(t *testing.T) {
+	stub := newFakeStub()
+	stub.chaincodes[accessControlName] = accessControl()
+
+	_, err := new(SmartContract).MigrateState(newContext(stub, alice, "Org1MSP"), 1, 2, 10, "")
+	checkResult(t, err, "client is not authorized to perform token.MigrateState")
+}
+
+func TestConfigContract(t *testing.T) {
+	stub := newFakeStub()
+	stub.chaincodes[accessControlName] = accessControl()
+	stub.txID = "tx1"
+	ctx := newContext(stub, alice, "Org1MSP")
+	config := NewConfigContract()
+
+	_, err := config.SetParameter(ctx, minterOrgsParam.Name, `["Org1MSP"]`)
+	checkResult(t, err, "client is not authorized to perform config.SetParameter")
+
+	stub.chaincodes[accessControlName] = accessControl("config.SetParameter", "token.Mint")
+	_, err = config.SetParameter(ctx, "minterOrg", `["Org1MSP"]`)
+	checkResult(t, err, `is not a parameter, the parameters are channelDisputeWindow, deferredSettlement, maxPageSize, maxResults, maxTransferAmount, minterOrgs, nettingWindow, stateCodec, strictKYC, strictRecipients, taxAccount`)
+	_, err = config.SetParameter(ctx, minterOrgsParam.Name, `"Org1MSP"`)
+	checkResult(t, err, "value of minterOrgs must be a JSON strings")
+	_, err = config.SetParameter(ctx, maxTransferAmountParam.Name, "-1")
+	checkResult(t, err, "value of maxTransferAmount must not be negative")
+
+	orgs, err := config.GetStrings(ctx, minterOrgsParam.Name)
+	checkResult(t, err, "")
+	if orgs != nil {
+		t.Errorf("default minter orgs are %v", orgs)
+	}
+	parameter, err := config.SetParameter(ctx, minterOrgsParam.Name, `[ "Org1MSP" ]`)
+	checkResult(t, err, "")
+	if parameter.Value != `["Org1MSP"]` || parameter.UpdatedBy != alice || parameter.TxID != "tx1" {
+		t.Errorf("parameter is %+v", parameter)
+	}
+	if stub.eventName != ledgerutil.EventConfigChanged || string(stub.eventValue) != `{"changes":[{"name":"minterOrgs","new":"[\"Org1MSP\"]","old":""}],"schemaVersion":1,"updatedBy":"alice"}` {
+		t.Errorf("event is %s %s", stub.eventName, stub.eventValue)
+	}
+	_, err = config.GetInt(ctx, minterOrgsParam.Name)
+	checkResult(t, err, "parameter minterOrgs is of kind strings, not int")
+
+	// only clients of the minter orgs may mint once the parameter is set
+	_, err = new(SmartContract).Mint(newContext(stub, bob, "Org2MSP"), 10)
+	checkResult(t, err, "clients of Org2MSP cannot mint or burn, the minter orgs are Org1MSP")
+	_, err = new(SmartContract).Mint(ctx, 10)
+	checkResult(t, err, "")
+
+	stub.txID = "tx2"
+	_, err = config.SetParameter(ctx, minterOrgsParam.Name, `["Org1MSP","Org2MSP"]`)
+	checkResult(t, err, "")
+	history, err := config.GetParameterHistory(ctx, minterOrgsParam.Name)
+	checkResult(t, err, "")
+	if len(history) != 2 || history[0].TxID != "tx2" || history[1].Value != `["Org1MSP"]` {
+		t.Errorf("history is %+v", history)
+	}
+
+	// the query limits are parameters too, kept consistent with each other
+	_, err = config.SetParameter(ctx, ledgerutil.MaxPageSizeParam.Name, "2000")
+	checkResult(t, err, "maxPageSize must be at least 1 and at most maxResults")
+	_, err = config.SetParameter(ctx, ledgerutil.MaxPageSizeParam.Name, "5")
+	checkResult(t, err, "")
+	_, err = new(SmartContract).GetBalancesPage(ctx, 6, "")
+	checkResult(t, err, "pageSize must be between 1 and 5")
+
+	parameters, err := config.ListParameters(ctx)
+	checkResult(t, err, "")
+	var listed []string
+	for _, parameter := range parameters {
+		listed = append(listed, parameter.Name+"="+parameter.Value)
+	}
+	if want := []string{`channelDisputeWindow="24h"`, "deferredSettlement=false", "maxPageSize=5", "maxResults=1000", "maxTransferAmount=0", `minterOrgs=["Org1MSP","Org2MSP"]`, `nettingWindow="1h"`, `stateCodec="json"`, "strictKYC=false", "strictRecipients=false", "taxAccount="}; !reflect.DeepEqual(listed, want) {
+		t.Errorf("parameters are %v, want %v", listed, want)
+	}
+}
+
+func TestMaxTransferAmount(t *testing.T) {
+	stub := newFakeStub()
+	stub.chaincodes[accessControlName] = accessControl("config.SetParameter")
+	stub.state[alice] = []byte("100")
+	ctx := newContext(stub, alice, "Org1MSP")
+	_, err := NewConfigContract().SetParameter(ctx, maxTransferAmountParam.Name, "50")
+	checkResult(t, err, "")
+
+	_, err = new(SmartContract).SimulateTransfer(ctx, bob, 51)
+	checkResult(t, err, "amount 51 is more than the largest transfer of 50")
+	_, err = new(SmartContract).Transfer(ctx, bob, 51)
+	checkResult(t, err, "amount 51 is more than the largest transfer of 50")
+	_, err = new(SmartContract).Transfer(ctx, bob, 50)
+	checkResult(t, err, "")
+	checkState(t, stub, map[string]string{alice: "50", bob: "50"})
+
+	//payments of a batch to the same receiver are added up before the check
+	_, err = new(SmartContract).BatchTransfer(ctx, []Payment{{Receiver: bob, Amount: 30}, {Receiver: bob, Amount: 21}})
+	checkResult(t, err, "amount 51 to "+bob+" is more than the largest transfer of 50")
+}
+
+func TestFeatureFlags(t *testing.T) {
+	stub := newFakeStub()
+	stub.chaincodes[accessControlName] = accessControl("token.Mint")
+	stub.chaincodes[identityRegistryName] = identityRegistry(alice)
+	ctx := newContext(stub, alice, "Org1MSP")
+	config := NewConfigContract()
+
+	flags, err := config.GetFlags(ctx)
+	checkResult(t, err, "")
+	if len(flags) != 3 || flags[0].Name != "deferredSettlement" || flags[1].Name != "strictKYC" || flags[1].Enabled || flags[1].TxID != "" || flags[2].Name != "strictRecipients" {
+		t.Errorf("flags are %+v", flags)
+	}
+	_, err = config.SetFlag(ctx, ledgerutil.StrictKYCFlag.Name, true)
+	checkResult(t, err, "client is not authorized to perform config.SetFlag")
+	stub.chaincodes[accessControlName] = accessControl("token.Mint", "config.SetFlag")
+	_, err = config.SetFlag(ctx, maxTransferAmountParam.Name, true)
+	checkResult(t, err, "maxTransferAmount is a parameter of kind int, not a feature flag")
+
+	// transfers to an unregistered account work until the flag is turned on
+	_, err = new(SmartContract).Mint(ctx, 100)
+	checkResult(t, err, "")
+	_, err = new(SmartContract).Transfer(ctx, bob, 10)
+	checkResult(t, err, "")
+	stub.txID = "tx2"
+	flag, err := config.SetFlag(ctx, ledgerutil.StrictKYCFlag.Name, true)
+	checkResult(t, err, "")
+	if !flag.Enabled || flag.UpdatedBy != alice || flag.TxID != "tx2" {
+		t.Errorf("flag is %+v", flag)
+	}
+	if stub.eventName != ledgerutil.EventConfigChanged {
+		t.Errorf("event is %s", stub.eventName)
+	}
+
+	_, err = new(SmartContract).Transfer(ctx, bob, 10)
+	checkResult(t, err, "bob has no verified profile in the identity registry, required in strict KYC mode: client bob has no registered profile")
+	_, err = new(SmartContract).SimulateTransfer(ctx, bob, 10)
+	checkResult(t, err, "bob has no verified profile")
+	_, err = new(SmartContract).BatchTransfer(ctx, []Payment{{Receiver: bob, Amount: 10}})
+	checkResult(t, err, "bob has no verified profile")
+	_, err = new(SmartContract).Approve(ctx, bob, 10)
+	checkResult(t, err, "bob has no verified profile")
+	_, err = new(SmartContract).Approve(ctx, bob, 0)
+	checkResult(t, err, "")
+	_, err = new(SmartContract).Mint(newContext(stub, bob, "Org1MSP"), 10)
+	checkResult(t, err, "bob has no verified profile")
+	_, err = new(SmartContract).Mint(ctx, 10)
+	checkResult(t, err, "")
+	checkState(t, stub, map[string]string{alice: "100", bob: "10"})
+
+	_, err = config.SetFlag(ctx, ledgerutil.StrictKYCFlag.Name, false)
+	checkResult(t, err, "")
+	_, err = new(SmartContract).Transfer(ctx, bob, 10)
+	checkResult(t, err, "")
+}
+
+func TestEventsContract(t *testing.T) {
+	stub := newFakeStub()
+	stub.chaincodes[accessControlName] = accessControl()
+	stub.txID = "tx1"
+	ctx := newContext(stub, alice, "Org1MSP")
+	events := NewEventsContract()
+
+	// the schemas of the build are returned until they are published
+	schemas, err := events.GetSchemas(ctx)
+	checkResult(t, err, "")
+	if len(schemas) != 6 || schemas[0].Name != "Approval" || schemas[1].Name != "BatchTransfer" || schemas[2].Name != ledgerutil.EventConfigChanged ||
+		schemas[3].Name != "PaymentChannel" || schemas[4].Name != "Transfer" || schemas[4].TxID != "" || schemas[5].Name != "WindowSettled" {
+		t.Errorf("schemas are %+v", schemas)
+	}
+	_, err = events.PublishSchemas(ctx)
+	checkResult(t, err, "client is not authorized to perform events.PublishSchemas")
+	stub.chaincodes[accessControlName] = accessControl("events.PublishSchemas")
+	published, err := events.PublishSchemas(ctx)
+	checkResult(t, err, "")
+	if len(published) != 6 || published[0].PublishedBy != alice || published[0].TxID != "tx1" {
+		t.Errorf("published schemas are %+v", published)
+	}
+	published, err = events.PublishSchemas(ctx)
+	checkResult(t, err, "")
+	if len(published) != 0 {
+		t.Errorf("published schemas again: %+v", published)
+	}
+
+	// an upgrade publishes the schemas whose version it raises, an older build cannot publish
+	transferV2 := transferEvent
+	transferV2.Version = 2
+	stub.txID = "tx2"
+	published, err = ledgerutil.NewEventsContract(accessControlName, transferV2, approvalEvent).PublishSchemas(ctx)
+	checkResult(t, err, "")
+	if len(published) != 1 || published[0].Name != "Transfer" || published[0].Version != 2 {
+		t.Errorf("published schemas are %+v", published)
+	}
+	_, err = events.PublishSchemas(ctx)
+	checkResult(t, err, "version 2 of the Transfer event is published, this chaincode emits version 1")
+	schema, err := events.GetSchema(ctx, "Transfer")
+	checkResult(t, err, "")
+	if schema.Version != 2 || schema.TxID != "tx2" {
+		t.Errorf("schema is %+v", schema)
+	}
+	history, err := events.GetSchemaHistory(ctx, "Transfer")
+	checkResult(t, err, "")
+	if len(history) != 2 || history[0].Version != 2 || history[1].Version != 1 {
+		t.Errorf("history is %+v", history)
+	}
+	_, err = events.GetSchema(ctx, "Mint")
+	checkResult(t, err, "is not an event, the events are Approval, BatchTransfer, ConfigChanged, PaymentChannel, Transfer, WindowSettled")
+
+	// the emitted events carry the version of their schema and only the fields it declares
+	_, err = new(SmartContract).Approve(ctx, bob, 10)
+	checkResult(t, err, "")
+	var payload map[string]interface{}
+	err = json.Unmarshal(stub.eventValue, &payload)
+	checkResult(t, err, "")
+	if payload["schemaVersion"] != 2.0 {
+		t.Errorf("event is %s", stub.eventValue)
+	}
+	err = ledgerutil.EmitEvent(stub, ledgerutil.EventSchema{Name: "Transfer", Version: 3, Fields: map[string]string{"from": "string"}}, event{alice, bob, 10})
+	checkResult(t, err, "Transfer event has field to, which version 3 of its schema does not declare")
+}
+
+func TestGetVersionAndPing(t *testing.T) {
+	stub := newFakeStub()
+	stub.txID = "tx1"
+	ctx := newContext(stub, alice, "Org1MSP")
+	contract := NewContract()
+
+	info, err := contract.GetVersion(ctx)
+	checkResult(t, err, "")
+	if info.Contract != "token" || info.Version != ledgerutil.Version || info.Commit != ledgerutil.Commit || info.GoVersion == "" {
+		t.Errorf("build info is %+v", info)
+	}
+
+	t.Setenv("CORE_PEER_LOCALMSPID", "Org1MSP")
+	stub.state[ledgerutil.SchemaVersionKey] = []byte("2")
+	result, err := contract.Ping(ctx)
+	checkResult(t, err, "")
+	if result.Status != "ok" || result.Contract != "token" || result.PeerMSPID != "Org1MSP" || result.SchemaVersion != 2 || result.TxID != "tx1" {
+		t.Errorf("ping result is %+v", result)
+	}
+}
@@ -0,0 +1,350 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil"
+)
+
+// object names for the prefixes of the withholding rates of the account categories, the categories
+// of the accounts and the withholding entries of the transfers
+const (
+	withholdingRatePrefix  = "withholdingrate"
+	accountCategoryPrefix  = "accountcategory"
+	withholdingEntryPrefix = "withholding"
+)
+
+// maxWithholdingRate is a rate of 100%, rates being in basis points
+const maxWithholdingRate = 10000
+
+// withholdingPeriodFormat formats the month of a withholding entry, the period reports cover
+const withholdingPeriodFormat = "2006-01"
+
+// taxAccountParam is the account withheld tokens are paid to, no tokens are withheld while it is empty
+var taxAccountParam = ledgerutil.ConfigParam{Name: "taxAccount", Kind: ledgerutil.ConfigString,
+	Description: "account the tokens withheld from transfers to accounts of a category with a withholding rate are paid to, no withholding when empty"}
+
+// WithholdingEntry records the tokens withheld from a transfer to an account of a category with a
+// withholding rate, keyed by the month, the transaction ID and its ID
+type WithholdingEntry struct {
+	TxID string `json:"txID"`
+	// ID sets the entry apart from the others of a transaction making several transfers, e.g.
+	// through InvokeChaincode, see ledgerutil.RecordID
+	ID        string    `json:"id"`
+	Timestamp time.Time `json:"timestamp"`
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Category  string    `json:"category"`
+	// Rate is the withholding rate in basis points
+	Rate int `json:"rate"`
+	// Amount is the amount transferred, of which To received Amount-Withheld
+	Amount     int    `json:"amount"`
+	Withheld   int    `json:"withheld"`
+	TaxAccount string `json:"taxAccount"`
+}
+
+// WithholdingEntryPage is one page of the withholding entries of a month in transaction ID order,
+// like BalancePage
+type WithholdingEntryPage struct {
+	Entries  []*WithholdingEntry `json:"entries"`
+	Bookmark string              `json:"bookmark"`
+}
+
+// WithholdingTotal is what was withheld from the transfers to the accounts of a category in a month
+type WithholdingTotal struct {
+	Category  string `json:"category"`
+	Transfers int    `json:"transfers"`
+	Amount    int    `json:"amount"`
+	Withheld  int    `json:"withheld"`
+}
+
+// WithholdingReport totals the withholding entries of a month by account category
+type WithholdingReport struct {
+	Period     string             `json:"period"`
+	Categories []WithholdingTotal `json:"categories"`
+	Transfers  int                `json:"transfers"`
+	Amount     int                `json:"amount"`
+	Withheld   int                `json:"withheld"`
+}
+
+// withholding is the part of a transfer paid to the tax account
+type withholding struct {
+	category   string
+	rate       int
+	withheld   int
+	taxAccount string
+	taxCurrent int
+	taxUpdated int
+}
+
+// withheld returns the part of the amount of the transfer paid to the tax account
+func (p *transferPlan) withheld() int {
+	if p.withholding == nil {
+		return 0
+	}
+	return p.withholding.withheld
+}
+
+// Set the rate in basis points withheld from transfers to the accounts of a category, 0 to stop withholding
+// Only clients allowed token.SetWithholding in the access-control chaincode may set rates
+func (s *SmartContract) SetWithholdingRate(ctx ledgerutil.TransactionContextInterface, category string, rate int) error {
+	err := _checkAccess(ctx, "token.SetWithholding") //check authorization in the access-control chaincode
+	if err != nil {
+		return err
+	}
+	category = ledgerutil.NormalizeID(category)
+	v := ledgerutil.NewValidator()
+	v.Key("category", category)
+	v.Amount("rate", rate)
+	if err := v.Err(); err != nil {
+		return err
+	}
+	if rate > maxWithholdingRate {
+		return ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "invalid arguments: rate must be at most %d basis points", maxWithholdingRate)
+	}
+	key, err := ctx.GetStub().CreateCompositeKey(withholdingRatePrefix, []string{category})
+	if err != nil {
+		return ledgerutil.Wrap(err, "failed to create the composite key for prefix %s", withholdingRatePrefix)
+	}
+	if rate == 0 {
+		return ctx.GetStub().DelState(key)
+	}
+	return ctx.GetStub().PutState(key, ledgerutil.FormatAmount(rate))
+}
+
+// Put an account in a category, whose withholding rate applies to the transfers it receives, an empty category removes it
+// Only clients allowed token.SetWithholding in the access-control chaincode may set categories
+func (s *SmartContract) SetAccountCategory(ctx ledgerutil.TransactionContextInterface, account string, category string) error {
+	err := _checkAccess(ctx, "token.SetWithholding") //check authorization in the access-control chaincode
+	if err != nil {
+		return err
+	}
+	account = ledgerutil.NormalizeID(account)
+	category = ledgerutil.NormalizeID(category)
+	v := ledgerutil.NewValidator()
+	v.Key("account", account)
+	if category != "" {
+		v.Key("category", category)
+	}
+	if err := v.Err(); err != nil {
+		return err
+	}
+	key, err := ctx.GetStub().CreateCompositeKey(accountCategoryPrefix, []string{account})
+	if err != nil {
+		return ledgerutil.Wrap(err, "failed to create the composite key for prefix %s", accountCategoryPrefix)
+	}
+	if category == "" {
+		return ctx.GetStub().DelState(key)
+	}
+	return ctx.GetStub().PutState(key, []byte(category))
+}
+
+// List up to pageSize withholding entries of a month, formatted 2006-01, starting at bookmark, empty for the first page
+// Only clients allowed token.GetWithholdingReport in the access-control chaincode may read them
+func (s *SmartContract) GetWithholdingEntriesPage(ctx ledgerutil.TransactionContextInterface, period string, pageSize int, bookmark string) (*WithholdingEntryPage, error) {
+	err := _checkWithholdingPeriod(ctx, period)
+	if err != nil {
+		return nil, err
+	}
+	_, err = ledgerutil.CheckPageSize(ctx.GetStub(), pageSize) //at most the maxPageSize of the query config
+	if err != nil {
+		return nil, err
+	}
+	iterator, metadata, err := ctx.GetStub().GetStateByPartialCompositeKeyWithPagination(withholdingEntryPrefix, []string{period}, int32(pageSize), bookmark)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to get withholding entries from world state")
+	}
+	defer iterator.Close()
+
+	page := &WithholdingEntryPage{Entries: []*WithholdingEntry{}}
+	for iterator.HasNext() {
+		result, err := iterator.Next()
+		if err != nil {
+			return nil, ledgerutil.Wrap(err, "failed to iterate withholding entries")
+		}
+		var entry WithholdingEntry
+		err = json.Unmarshal(result.Value, &entry)
+		if err != nil {
+			return nil, ledgerutil.Errorf(ledgerutil.CodeCorruptState, "failed to unmarshal withholding entry %q: %v", result.Key, err)
+		}
+		page.Entries = append(page.Entries, &entry)
+	}
+	if metadata.FetchedRecordsCount == int32(pageSize) {
+		page.Bookmark = metadata.Bookmark
+	}
+	return page, nil
+}
+
+// Total the tokens withheld in a month, formatted 2006-01, by account category
+// Fails with RESULTS_TRUNCATED when the month has more than maxResults entries, which GetWithholdingEntriesPage then lists
+// Only clients allowed token.GetWithholdingReport in the access-control chaincode may read it
+func (s *SmartContract) GetWithholdingReport(ctx ledgerutil.TransactionContextInterface, period string) (*WithholdingReport, error) {
+	err := _checkWithholdingPeriod(ctx, period)
+	if err != nil {
+		return nil, err
+	}
+	config, err := ledgerutil.GetQueryConfig(ctx.GetStub())
+	if err != nil {
+		return nil, err
+	}
+
+	all := &WithholdingTotal{}
+	totals := map[string]*WithholdingTotal{}
+	err = ledgerutil.ForEachByPartialCompositeKey(ctx.GetStub(), withholdingEntryPrefix, []string{period}, func(_ []string, value []byte) error {
+		if all.Transfers == config.MaxResults {
+			return ledgerutil.ResultsTruncated(config.MaxResults, "GetWithholdingEntriesPage")
+		}
+		var entry WithholdingEntry
+		err := json.Unmarshal(value, &entry)
+		if err != nil {
+			return ledgerutil.Errorf(ledgerutil.CodeCorruptState, "failed to unmarshal withholding entry: %v", err)
+		}
+		total, ok := totals[entry.Category]
+		if !ok {
+			total = &WithholdingTotal{Category: entry.Category}
+			totals[entry.Category] = total
+		}
+		err = _addWithholding(total, &entry)
+		if err != nil {
+			return err
+		}
+		return _addWithholding(all, &entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+	report := &WithholdingReport{Period: period, Categories: []WithholdingTotal{}, Transfers: all.Transfers, Amount: all.Amount, Withheld: all.Withheld}
+	for _, total := range totals {
+		report.Categories = append(report.Categories, *total)
+	}
+	sort.Slice(report.Categories, func(i, j int) bool { return report.Categories[i].Category < report.Categories[j].Category })
+	return report, nil
+}
+
+// Add a withholding entry to a total
+func _addWithholding(total *WithholdingTotal, entry *WithholdingEntry) error {
+	amount, err := ledgerutil.AddAmounts(total.Amount, entry.Amount)
+	if err != nil {
+		return err
+	}
+	withheld, err := ledgerutil.AddAmounts(total.Withheld, entry.Withheld)
+	if err != nil {
+		return err
+	}
+	total.Transfers++
+	total.Amount, total.Withheld = amount, withheld
+	return nil
+}
+
+// Work out the part of a transfer withheld for the tax account from the category of the receiver
+// Returns nil when no tax account is set, the receiver has no category with a rate or either account is the tax account
+func _planWithholding(ctx contractapi.TransactionContextInterface, from string, receiver string, amount int) (*withholding, error) {
+	taxAccount, err := ledgerutil.GetConfigString(ctx.GetStub(), taxAccountParam)
+	if err != nil {
+		return nil, err
+	}
+	if taxAccount == "" || taxAccount == from || taxAccount == receiver {
+		return nil, nil
+	}
+	categoryKey, err := ctx.GetStub().CreateCompositeKey(accountCategoryPrefix, []string{receiver})
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to create the composite key for prefix %s", accountCategoryPrefix)
+	}
+	category, err := ctx.GetStub().GetState(categoryKey)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to read category of account %s", receiver)
+	}
+	if category == nil {
+		return nil, nil
+	}
+	rateKey, err := ctx.GetStub().CreateCompositeKey(withholdingRatePrefix, []string{string(category)})
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to create the composite key for prefix %s", withholdingRatePrefix)
+	}
+	rateBytes, err := ctx.GetStub().GetState(rateKey)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to read withholding rate of category %s", category)
+	}
+	if rateBytes == nil {
+		return nil, nil
+	}
+	rate, err := ledgerutil.ParseAmount(rateBytes)
+	if err != nil || rate > maxWithholdingRate {
+		return nil, ledgerutil.Errorf(ledgerutil.CodeCorruptState, "invalid withholding rate %q of category %s", rateBytes, category)
+	}
+
+	taxBalance, err := ctx.GetStub().GetState(taxAccount)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to get tax account %s from world state", taxAccount)
+	}
+	plan := &withholding{category: string(category), rate: rate, taxAccount: taxAccount}
+	if taxBalance != nil {
+		plan.taxCurrent, err = ledgerutil.ParseAmount(taxBalance)
+		if err != nil {
+			return nil, ledgerutil.Wrap(err, "failed to read tax account %s", taxAccount)
+		}
+	}
+	//rounds down, split so amount*rate cannot overflow
+	plan.withheld = amount/maxWithholdingRate*rate + amount%maxWithholdingRate*rate/maxWithholdingRate
+	plan.taxUpdated, err = ledgerutil.AddAmounts(plan.taxCurrent, plan.withheld)
+	if err != nil {
+		return nil, ledgerutil.Wrap(err, "failed to credit tax account %s", taxAccount)
+	}
+	return plan, nil
+}
+
+// Pay the withheld tokens to the tax account and record the withholding entry of the transfer
+func _putWithholding(ctx contractapi.TransactionContextInterface, auditor *ledgerutil.Auditor, plan *withholding, from string, receiver string, amount int) error {
+	err := ctx.GetStub().PutState(plan.taxAccount, ledgerutil.FormatAmount(plan.taxUpdated))
+	if err != nil {
+		return err
+	}
+	auditor.Change(balanceKind, plan.taxCurrent, plan.taxUpdated, plan.taxAccount)
+	return _putWithholdingEntry(ctx, plan, from, receiver, amount)
+}
+
+// Record the withholding entry of a transfer, keyed by its month, transaction and record ID
+func _putWithholdingEntry(ctx contractapi.TransactionContextInterface, plan *withholding, from string, receiver string, amount int) error {
+	txID, timestamp, err := ledgerutil.TxInfo(ctx.GetStub())
+	if err != nil {
+		return err
+	}
+	entry := &WithholdingEntry{
+		TxID:       txID,
+		Timestamp:  timestamp,
+		From:       from,
+		To:         receiver,
+		Category:   plan.category,
+		Rate:       plan.rate,
+		Amount:     amount,
+		Withheld:   plan.withheld,
+		TaxAccount: plan.taxAccount,
+	}
+	entry.ID = ledgerutil.RecordID(ctx.GetStub(), from, receiver, strconv.Itoa(amount))
+	key, err := ctx.GetStub().CreateCompositeKey(withholdingEntryPrefix, []string{timestamp.Format(withholdingPeriodFormat), txID, entry.ID})
+	if err != nil {
+		return ledgerutil.Wrap(err, "failed to create the composite key for prefix %s", withholdingEntryPrefix)
+	}
+	err = ledgerutil.PutJSON(ctx.GetStub(), key, entry)
+	if err != nil {
+		return ledgerutil.Wrap(err, "failed to put withholding entry")
+	}
+	return nil
+}
+
+// Check the period is a month formatted 2006-01 and the client may read the withholding reports
+func _checkWithholdingPeriod(ctx ledgerutil.TransactionContextInterface, period string) error {
+	err := _checkAccess(ctx, "token.GetWithholdingReport") //check authorization in the access-control chaincode
+	if err != nil {
+		return err
+	}
+	_, err = time.Parse(withholdingPeriodFormat, period)
+	if err != nil {
+		return ledgerutil.Errorf(ledgerutil.CodeInvalidArgument, "invalid arguments: period must be a month such as 2024-01, not %q", period)
+	}
+	return nil
+}
@@ -8,16 +8,29 @@ import (
 	"log"
 
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-contract-api-go/metadata"
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil"
 	"github.com/hyperledger/fabric-samples/token-erc-20/chaincode-go/chaincode"
 )
 
 func main() {
-	tokenChaincode, err := contractapi.NewChaincode(&chaincode.SmartContract{})
+	tokenContract := chaincode.NewContract()
+	// the token under the fabric-samples ERC-20 names and the Ethereum ABI names, called as erc20:<Function>
+	erc20Contract := chaincode.NewERC20Contract(tokenContract)
+	// the parameters administrators tune without an upgrade, called as config:<Function>
+	configContract := chaincode.NewConfigContract()
+	// the names and payload versions of the events, called as events:<Function>
+	eventsContract := chaincode.NewEventsContract()
+
+	tokenChaincode, err := contractapi.NewChaincode(tokenContract, erc20Contract, configContract, eventsContract)
 	if err != nil {
 		log.Panicf("Error creating token-erc-20 chaincode: %v", err)
 	}
+	tokenChaincode.DefaultContract = tokenContract.GetName()
+	tokenChaincode.Info = metadata.InfoMetadata{Title: "token-erc-20", Version: "1.0.0"}
 
-	if err := tokenChaincode.Start(); err != nil {
+	// packaged, or as an external service when CHAINCODE_SERVER_ADDRESS is set
+	if err := ledgerutil.StartChaincode(tokenChaincode); err != nil {
 		log.Panicf("Error starting token-erc-20 chaincode: %v", err)
 	}
 }
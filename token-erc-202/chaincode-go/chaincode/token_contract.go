@@ -1,13 +1,12 @@
 package chaincode
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
 	"strconv"
 
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil"
 )
 
 // Define key names for options
@@ -16,6 +15,13 @@ const totalSupplyKey = "totalSupply"
 // Define objectType names for prefix
 const allowancePrefix = "allowance"
 
+// Define the kinds of values recorded in audit records
+const (
+	balanceKind     = "balance"
+	allowanceKind   = "allowance"
+	totalSupplyKind = "totalSupply"
+)
+
 // SmartContract provides functions for transferring tokens between accounts
 type SmartContract struct {
 	contractapi.Contract
@@ -28,6 +34,16 @@ type event struct {
 	Value int    `json:"value"`
 }
 
+// Define the schemas of the events, each with the audit record added by the auditor
+var (
+	transferEvent = ledgerutil.EventSchema{Name: "Transfer", Version: 1,
+		Description: "tokens moved, minted from or burned to the 0x0 account",
+		Fields:      map[string]string{"from": "string", "to": "string", "value": "integer", "audit": "object"}}
+	approvalEvent = ledgerutil.EventSchema{Name: "Approval", Version: 1,
+		Description: "allowance of a spender over the tokens of an owner set",
+		Fields:      map[string]string{"from": "string", "to": "string", "value": "integer", "audit": "object"}}
+)
+
 // Mint creates new tokens and adds them to minter's account balance
 // This function triggers a Transfer event
 func (s *SmartContract) Mint(ctx contractapi.TransactionContextInterface, amount int) error {
@@ -94,19 +110,15 @@ func (s *SmartContract) Mint(ctx contractapi.TransactionContextInterface, amount
 		return err
 	}
 
-	// Emit the Transfer event
-	transferEvent := event{"0x0", minter, amount}
-	transferEventJSON, err := json.Marshal(transferEvent)
-	if err != nil {
-		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
-	}
-	err = ctx.GetStub().SetEvent("Transfer", transferEventJSON)
+	// Emit the Transfer event with the balance and total supply changes
+	auditor := ledgerutil.NewAuditor(ctx.GetStub())
+	auditor.Change(balanceKind, currentBalance, updatedBalance, minter)
+	auditor.Change(totalSupplyKind, totalSupply-amount, totalSupply)
+	err = auditor.Emit(transferEvent, event{"0x0", minter, amount})
 	if err != nil {
-		return fmt.Errorf("failed to set event: %v", err)
+		return err
 	}
 
-	log.Printf("minter account %s balance updated from %d to %d", minter, currentBalance, updatedBalance)
-
 	return nil
 }
 
@@ -174,19 +186,15 @@ func (s *SmartContract) Burn(ctx contractapi.TransactionContextInterface, amount
 		return err
 	}
 
-	// Emit the Transfer event
-	transferEvent := event{minter, "0x0", amount}
-	transferEventJSON, err := json.Marshal(transferEvent)
-	if err != nil {
-		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
-	}
-	err = ctx.GetStub().SetEvent("Transfer", transferEventJSON)
+	// Emit the Transfer event with the balance and total supply changes
+	auditor := ledgerutil.NewAuditor(ctx.GetStub())
+	auditor.Change(balanceKind, currentBalance, updatedBalance, minter)
+	auditor.Change(totalSupplyKind, totalSupply+amount, totalSupply)
+	err = auditor.Emit(transferEvent, event{minter, "0x0", amount})
 	if err != nil {
-		return fmt.Errorf("failed to set event: %v", err)
+		return err
 	}
 
-	log.Printf("minter account %s balance updated from %d to %d", minter, currentBalance, updatedBalance)
-
 	return nil
 }
 
@@ -201,20 +209,16 @@ func (s *SmartContract) Transfer(ctx contractapi.TransactionContextInterface, re
 		return fmt.Errorf("failed to get client id: %v", err)
 	}
 
-	err = transferHelper(ctx, clientID, recipient, amount)
+	auditor := ledgerutil.NewAuditor(ctx.GetStub())
+	err = transferHelper(ctx, auditor, clientID, recipient, amount)
 	if err != nil {
 		return fmt.Errorf("failed to transfer: %v", err)
 	}
 
-	// Emit the Transfer event
-	transferEvent := event{clientID, recipient, amount}
-	transferEventJSON, err := json.Marshal(transferEvent)
+	// Emit the Transfer event with the balance changes
+	err = auditor.Emit(transferEvent, event{clientID, recipient, amount})
 	if err != nil {
-		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
-	}
-	err = ctx.GetStub().SetEvent("Transfer", transferEventJSON)
-	if err != nil {
-		return fmt.Errorf("failed to set event: %v", err)
+		return err
 	}
 
 	return nil
@@ -289,8 +293,6 @@ func (s *SmartContract) TotalSupply(ctx contractapi.TransactionContextInterface)
 		totalSupply, _ = strconv.Atoi(string(totalSupplyBytes)) // Error handling not needed since Itoa() was used when setting the totalSupply, guaranteeing it was an integer.
 	}
 
-	log.Printf("TotalSupply: %d tokens", totalSupply)
-
 	return totalSupply, nil
 }
 
@@ -311,25 +313,29 @@ func (s *SmartContract) Approve(ctx contractapi.TransactionContextInterface, spe
 		return fmt.Errorf("failed to create the composite key for prefix %s: %v", allowancePrefix, err)
 	}
 
+	// Read the allowance being replaced for the audit record
+	currentAllowanceBytes, err := ctx.GetStub().GetState(allowanceKey)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve the allowance for %s from world state: %v", allowanceKey, err)
+	}
+
+	var currentAllowance int
+	currentAllowance, _ = strconv.Atoi(string(currentAllowanceBytes)) // Error handling not needed since Itoa() was used when setting the allowance, guaranteeing it was an integer.
+
 	// Update the state of the smart contract by adding the allowanceKey and value
 	err = ctx.GetStub().PutState(allowanceKey, []byte(strconv.Itoa(value)))
 	if err != nil {
 		return fmt.Errorf("failed to update state of smart contract for key %s: %v", allowanceKey, err)
 	}
 
-	// Emit the Approval event
-	approvalEvent := event{owner, spender, value}
-	approvalEventJSON, err := json.Marshal(approvalEvent)
-	if err != nil {
-		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
-	}
-	err = ctx.GetStub().SetEvent("Approval", approvalEventJSON)
+	// Emit the Approval event with the allowance change
+	auditor := ledgerutil.NewAuditor(ctx.GetStub())
+	auditor.Change(allowanceKind, currentAllowance, value, owner, spender)
+	err = auditor.Emit(approvalEvent, event{owner, spender, value})
 	if err != nil {
-		return fmt.Errorf("failed to set event: %v", err)
+		return err
 	}
 
-	log.Printf("client %s approved a withdrawal allowance of %d for spender %s", owner, value, spender)
-
 	return nil
 }
 
@@ -357,8 +363,6 @@ func (s *SmartContract) Allowance(ctx contractapi.TransactionContextInterface, o
 		allowance, err = strconv.Atoi(string(allowanceBytes)) // Error handling not needed since Itoa() was used when setting the totalSupply, guaranteeing it was an integer.
 	}
 
-	log.Printf("The allowance left for spender %s to withdraw from owner %s: %d", spender, owner, allowance)
-
 	return allowance, nil
 }
 
@@ -393,7 +397,8 @@ func (s *SmartContract) TransferFrom(ctx contractapi.TransactionContextInterface
 	}
 
 	// Initiate the transfer
-	err = transferHelper(ctx, from, to, value)
+	auditor := ledgerutil.NewAuditor(ctx.GetStub())
+	err = transferHelper(ctx, auditor, from, to, value)
 	if err != nil {
 		return fmt.Errorf("failed to transfer: %v", err)
 	}
@@ -405,27 +410,45 @@ func (s *SmartContract) TransferFrom(ctx contractapi.TransactionContextInterface
 		return err
 	}
 
-	// Emit the Transfer event
-	transferEvent := event{from, to, value}
-	transferEventJSON, err := json.Marshal(transferEvent)
+	// Emit the Transfer event with the balance and allowance changes
+	auditor.Change(allowanceKind, currentAllowance, updatedAllowance, from, spender)
+	err = auditor.Emit(transferEvent, event{from, to, value})
 	if err != nil {
-		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+		return err
 	}
-	err = ctx.GetStub().SetEvent("Transfer", transferEventJSON)
+
+	return nil
+}
+
+// SetAuditConfig turns on-ledger audit records on or off
+// The changes made by every transaction are always published with its event; with onLedger they are
+// also kept in the world state for GetAuditRecord
+func (s *SmartContract) SetAuditConfig(ctx contractapi.TransactionContextInterface, onLedger bool) error {
+
+	// Check authorization - this sample assumes Org1 is the central banker, which also audits the token
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
 	if err != nil {
-		return fmt.Errorf("failed to set event: %v", err)
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+	if clientMSPID != "Org1MSP" {
+		return fmt.Errorf("client is not authorized to configure audit records")
 	}
 
-	log.Printf("spender %s allowance updated from %d to %d", spender, currentAllowance, updatedAllowance)
+	return ledgerutil.PutAuditConfig(ctx.GetStub(), ledgerutil.AuditConfig{OnLedger: onLedger})
+}
 
-	return nil
+// GetAuditRecord returns the balance, allowance and total supply changes made by a transaction
+// Records are only kept for transactions submitted while on-ledger audit records are on
+func (s *SmartContract) GetAuditRecord(ctx contractapi.TransactionContextInterface, txID string) (*ledgerutil.AuditRecord, error) {
+	return ledgerutil.GetAuditRecord(ctx.GetStub(), txID)
 }
 
 // Helper Functions
 
 // transferHelper is a helper function that transfers tokens from the "from" address to the "to" address
+// and records the balance changes with the auditor
 // Dependant functions include Transfer and TransferFrom
-func transferHelper(ctx contractapi.TransactionContextInterface, from string, to string, value int) error {
+func transferHelper(ctx contractapi.TransactionContextInterface, auditor *ledgerutil.Auditor, from string, to string, value int) error {
 
 	if from == to {
 		return fmt.Errorf("cannot transfer to and from same client account")
@@ -476,8 +499,8 @@ func transferHelper(ctx contractapi.TransactionContextInterface, from string, to
 		return err
 	}
 
-	log.Printf("client %s balance updated from %d to %d", from, fromCurrentBalance, fromUpdatedBalance)
-	log.Printf("recipient %s balance updated from %d to %d", to, toCurrentBalance, toUpdatedBalance)
+	auditor.Change(balanceKind, fromCurrentBalance, fromUpdatedBalance, from)
+	auditor.Change(balanceKind, toCurrentBalance, toUpdatedBalance, to)
 
 	return nil
 }
@@ -3,6 +3,8 @@ module github.com/hyperledger/fabric-samples/token-erc-20/chaincode-go
 go 1.14
 
 require (
-	github.com/hyperledger/fabric-contract-api-go v1.1.0
-	golang.org/x/tools v0.1.0 // indirect
+	github.com/hyperledger/fabric-contract-api-go v1.2.2
+	github.com/hyperledger/fabric-samples/internal/ledgerutil v0.0.0
 )
+
+replace github.com/hyperledger/fabric-samples/internal/ledgerutil => ../../internal/ledgerutil
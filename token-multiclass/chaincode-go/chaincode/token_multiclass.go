@@ -0,0 +1,496 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// This sample assumes Org1 issues the tokens: only it creates token classes, mints them and sets
+// the conversions between them
+const issuerMSPID = "Org1MSP"
+
+// oracleChaincodeName is the name the oracle-price-feed chaincode the conversion rates are read from
+// is deployed under
+const oracleChaincodeName = "oracle"
+
+// maxInt is the largest amount of a balance or supply
+const maxInt = int(^uint(0) >> 1)
+
+// object names for prefix
+const (
+	classPrefix      = "class"
+	balancePrefix    = "balance"
+	conversionPrefix = "conversion"
+	receiptPrefix    = "receipt"
+)
+
+// SmartContract provides functions for issuing several classes of fungible tokens in one chaincode
+// and converting between them at rates published by the oracle chaincode
+type SmartContract struct {
+	contractapi.Contract
+}
+
+// TokenClass is a kind of fungible token. Accounts hold a balance of each class.
+type TokenClass struct {
+	ObjectType string `json:"objectType"`
+	ID         string `json:"tokenID"`
+	Name       string `json:"name"`
+	Supply     int    `json:"supply"`
+}
+
+// Balance is the amount of a token class held by an account
+type Balance struct {
+	Account string `json:"account"`
+	TokenID string `json:"tokenID"`
+	Amount  int    `json:"amount"`
+}
+
+// Conversion lets clients convert one token class into another at the latest rate of an oracle
+// feed, the number of ToTokenID tokens one FromTokenID token is worth scaled by 10^decimals of the
+// feed. A rate finalized more than MaxAge seconds before the transaction is refused.
+type Conversion struct {
+	ObjectType  string `json:"objectType"`
+	FromTokenID string `json:"fromTokenID"`
+	ToTokenID   string `json:"toTokenID"`
+	FeedID      string `json:"feedID"`
+	MaxAge      int    `json:"maxAge"`
+}
+
+// ConversionReceipt records a conversion, keyed by the account and the transaction ID. A
+// transaction converts once: a second Convert in the same transaction reads the balances and
+// supplies as they were before the first, so its writes replace the first one's, receipt included.
+type ConversionReceipt struct {
+	ObjectType  string `json:"objectType"`
+	TxID        string `json:"txID"`
+	Account     string `json:"account"`
+	FromTokenID string `json:"fromTokenID"`
+	AmountIn    int    `json:"amountIn"`
+	ToTokenID   string `json:"toTokenID"`
+	AmountOut   int    `json:"amountOut"`
+	FeedID      string `json:"feedID"`
+	Round       int    `json:"round"`
+	Rate        int64  `json:"rate"`
+	Decimals    int    `json:"decimals"`
+	Timestamp   string `json:"timestamp"`
+}
+
+// oracleRound is the part of a round of the oracle chaincode a conversion uses
+type oracleRound struct {
+	Round       int    `json:"round"`
+	Median      int64  `json:"median"`
+	Decimals    int    `json:"decimals"`
+	FinalizedAt string `json:"finalizedAt"`
+}
+
+// event provides an organized struct for emitting token movements
+type event struct {
+	From    string `json:"from"`
+	To      string `json:"to"`
+	TokenID string `json:"tokenID"`
+	Amount  int    `json:"amount"`
+}
+
+// CreateClass is called by the issuer to define a token class
+func (s *SmartContract) CreateClass(ctx contractapi.TransactionContextInterface, tokenID string, name string) error {
+	err := _requireOrg(ctx, issuerMSPID, "create token classes")
+	if err != nil {
+		return err
+	}
+	if tokenID == "" || name == "" {
+		return fmt.Errorf("token ID and name must be set")
+	}
+
+	existing, err := _getClass(ctx, tokenID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("the token class %s already exists", tokenID)
+	}
+
+	class := TokenClass{
+		ObjectType: classPrefix,
+		ID:         tokenID,
+		Name:       name,
+	}
+	return _putClass(ctx, &class)
+}
+
+// Mint is called by the issuer to create tokens of a class in an account
+func (s *SmartContract) Mint(ctx contractapi.TransactionContextInterface, account string, tokenID string, amount int) error {
+	err := _requireOrg(ctx, issuerMSPID, "mint tokens")
+	if err != nil {
+		return err
+	}
+	if account == "" {
+		return fmt.Errorf("account must be set")
+	}
+
+	err = _mint(ctx, account, tokenID, amount)
+	if err != nil {
+		return err
+	}
+
+	return _emitEvent(ctx, "Transfer", event{"", account, tokenID, amount})
+}
+
+// Transfer moves tokens of a class from the client's account to the receiver
+func (s *SmartContract) Transfer(ctx contractapi.TransactionContextInterface, receiver string, tokenID string, amount int) error {
+	sender, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+	if receiver == "" || receiver == sender {
+		return fmt.Errorf("receiver must be another account")
+	}
+	if amount <= 0 {
+		return fmt.Errorf("amount must be a positive integer")
+	}
+
+	_, err = s.GetClass(ctx, tokenID)
+	if err != nil {
+		return err
+	}
+	err = _addBalance(ctx, sender, tokenID, -amount)
+	if err != nil {
+		return err
+	}
+	err = _addBalance(ctx, receiver, tokenID, amount)
+	if err != nil {
+		return err
+	}
+
+	return _emitEvent(ctx, "Transfer", event{sender, receiver, tokenID, amount})
+}
+
+// SetConversion is called by the issuer to let clients convert fromTokenID into toTokenID at the
+// latest rate of an oracle feed no older than maxAge seconds. The conversion only runs in that
+// direction, the other one needs a conversion and a feed of its own.
+func (s *SmartContract) SetConversion(ctx contractapi.TransactionContextInterface, fromTokenID string, toTokenID string, feedID string, maxAge int) error {
+	err := _requireOrg(ctx, issuerMSPID, "set conversions")
+	if err != nil {
+		return err
+	}
+	if fromTokenID == toTokenID {
+		return fmt.Errorf("a token class cannot be converted into itself")
+	}
+	if feedID == "" {
+		return fmt.Errorf("feed ID must be set")
+	}
+	if maxAge <= 0 {
+		return fmt.Errorf("max age must be a positive number of seconds")
+	}
+	for _, tokenID := range []string{fromTokenID, toTokenID} {
+		_, err = s.GetClass(ctx, tokenID)
+		if err != nil {
+			return err
+		}
+	}
+
+	conversion := Conversion{
+		ObjectType:  conversionPrefix,
+		FromTokenID: fromTokenID,
+		ToTokenID:   toTokenID,
+		FeedID:      feedID,
+		MaxAge:      maxAge,
+	}
+	conversionJSON, err := json.Marshal(conversion)
+	if err != nil {
+		return fmt.Errorf("failed to marshal conversion: %v", err)
+	}
+
+	conversionKey, err := ctx.GetStub().CreateCompositeKey(conversionPrefix, []string{fromTokenID, toTokenID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = ctx.GetStub().PutState(conversionKey, conversionJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put conversion from %s to %s: %v", fromTokenID, toTokenID, err)
+	}
+	return nil
+}
+
+// Convert burns amount tokens of fromTokenID from the client's account and mints the tokens of
+// toTokenID they are worth at the latest rate of the conversion's oracle feed, rounded down. The
+// slippage limit fails the conversion if the client would receive less than minAmountOut, so a
+// rate that moved between the client's quote and the transaction does not go unnoticed.
+func (s *SmartContract) Convert(ctx contractapi.TransactionContextInterface, fromTokenID string, toTokenID string, amount int, minAmountOut int) (*ConversionReceipt, error) {
+	account, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client id: %v", err)
+	}
+	if amount <= 0 {
+		return nil, fmt.Errorf("amount must be a positive integer")
+	}
+
+	conversion, err := s.GetConversion(ctx, fromTokenID, toTokenID)
+	if err != nil {
+		return nil, err
+	}
+	round, err := _readRate(ctx, conversion)
+	if err != nil {
+		return nil, err
+	}
+	amountOut, err := _convert(amount, round)
+	if err != nil {
+		return nil, err
+	}
+	if amountOut < minAmountOut {
+		return nil, fmt.Errorf("conversion would return %d, less than the minimum %d", amountOut, minAmountOut)
+	}
+
+	err = _burn(ctx, account, fromTokenID, amount)
+	if err != nil {
+		return nil, err
+	}
+	err = _mint(ctx, account, toTokenID, amountOut)
+	if err != nil {
+		return nil, err
+	}
+
+	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	receipt := ConversionReceipt{
+		ObjectType:  receiptPrefix,
+		TxID:        ctx.GetStub().GetTxID(),
+		Account:     account,
+		FromTokenID: fromTokenID,
+		AmountIn:    amount,
+		ToTokenID:   toTokenID,
+		AmountOut:   amountOut,
+		FeedID:      conversion.FeedID,
+		Round:       round.Round,
+		Rate:        round.Median,
+		Decimals:    round.Decimals,
+		Timestamp:   time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos)).UTC().Format(time.RFC3339),
+	}
+	receiptJSON, err := json.Marshal(receipt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal receipt: %v", err)
+	}
+	receiptKey, err := ctx.GetStub().CreateCompositeKey(receiptPrefix, []string{account, receipt.TxID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = ctx.GetStub().PutState(receiptKey, receiptJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to put conversion receipt: %v", err)
+	}
+
+	err = _emitEvent(ctx, "Converted", receipt)
+	if err != nil {
+		return nil, err
+	}
+	return &receipt, nil
+}
+
+// _readRate reads the latest round of the conversion's feed from the oracle chaincode and refuses
+// it when it is older than the conversion's max age
+func _readRate(ctx contractapi.TransactionContextInterface, conversion *Conversion) (*oracleRound, error) {
+	args := [][]byte{[]byte("GetLatestRound"), []byte(conversion.FeedID)}
+	response := ctx.GetStub().InvokeChaincode(oracleChaincodeName, args, "")
+	if response.Status != shim.OK {
+		return nil, fmt.Errorf("failed to read the rate of %s from %s: %s", conversion.FeedID, oracleChaincodeName, response.Message)
+	}
+
+	var round oracleRound
+	err := json.Unmarshal(response.Payload, &round)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal the round of %s: %v", conversion.FeedID, err)
+	}
+	if round.Median <= 0 {
+		return nil, fmt.Errorf("the rate of %s is %d, not a positive value", conversion.FeedID, round.Median)
+	}
+
+	finalizedAt, err := time.Parse(time.RFC3339, round.FinalizedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the finalization time of %s: %v", conversion.FeedID, err)
+	}
+	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	age := time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos)).Sub(finalizedAt)
+	if age > time.Duration(conversion.MaxAge)*time.Second {
+		return nil, fmt.Errorf("the rate of %s was finalized at %s, more than %d seconds ago", conversion.FeedID, round.FinalizedAt, conversion.MaxAge)
+	}
+	return &round, nil
+}
+
+// _convert returns the amount of the other token class amount tokens are worth at the rate of the
+// round, rounded down. The product is computed with big integers so it cannot overflow.
+func _convert(amount int, round *oracleRound) (int, error) {
+	if round.Decimals < 0 {
+		return 0, fmt.Errorf("the rate has %d decimals", round.Decimals)
+	}
+	product := new(big.Int).Mul(big.NewInt(int64(amount)), big.NewInt(round.Median))
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(round.Decimals)), nil)
+	amountOut := product.Quo(product, scale)
+	if !amountOut.IsInt64() || amountOut.Int64() > int64(maxInt) {
+		return 0, fmt.Errorf("converting %d tokens overflows", amount)
+	}
+	if amountOut.Sign() == 0 {
+		return 0, fmt.Errorf("%d tokens are worth less than one token at the current rate", amount)
+	}
+	return int(amountOut.Int64()), nil
+}
+
+// _mint adds newly created tokens of a class to an account and to the class's supply
+func _mint(ctx contractapi.TransactionContextInterface, account string, tokenID string, amount int) error {
+	if amount <= 0 {
+		return fmt.Errorf("amount must be a positive integer")
+	}
+	class, err := _getClass(ctx, tokenID)
+	if err != nil {
+		return err
+	}
+	if class == nil {
+		return fmt.Errorf("the token class %s does not exist", tokenID)
+	}
+	if class.Supply > maxInt-amount {
+		return fmt.Errorf("minting %d %s overflows its supply", amount, tokenID)
+	}
+
+	class.Supply += amount
+	err = _putClass(ctx, class)
+	if err != nil {
+		return err
+	}
+	return _addBalance(ctx, account, tokenID, amount)
+}
+
+// _burn removes tokens of a class from an account and from the class's supply
+func _burn(ctx contractapi.TransactionContextInterface, account string, tokenID string, amount int) error {
+	err := _addBalance(ctx, account, tokenID, -amount)
+	if err != nil {
+		return err
+	}
+
+	class, err := _getClass(ctx, tokenID)
+	if err != nil {
+		return err
+	}
+	if class == nil {
+		return fmt.Errorf("the token class %s does not exist", tokenID)
+	}
+	class.Supply -= amount
+	return _putClass(ctx, class)
+}
+
+// _addBalance changes the balance of a token class in an account. A negative delta removes tokens
+// and fails when the account holds fewer. Balances that reach zero are deleted.
+func _addBalance(ctx contractapi.TransactionContextInterface, account string, tokenID string, delta int) error {
+	if delta == 0 {
+		return fmt.Errorf("amount must not be zero")
+	}
+
+	balanceKey, err := ctx.GetStub().CreateCompositeKey(balancePrefix, []string{account, tokenID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	balanceBytes, err := ctx.GetStub().GetState(balanceKey)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	balance := 0
+	if balanceBytes != nil {
+		balance, err = strconv.Atoi(string(balanceBytes))
+		if err != nil {
+			return fmt.Errorf("failed to parse balance of %s: %v", tokenID, err)
+		}
+	}
+
+	if balance+delta < 0 {
+		return fmt.Errorf("account holds %d %s, fewer than %d", balance, tokenID, -delta)
+	}
+	balance += delta
+
+	if balance == 0 {
+		err = ctx.GetStub().DelState(balanceKey)
+		if err != nil {
+			return fmt.Errorf("failed to delete balance: %v", err)
+		}
+		return nil
+	}
+	err = ctx.GetStub().PutState(balanceKey, []byte(strconv.Itoa(balance)))
+	if err != nil {
+		return fmt.Errorf("failed to put balance: %v", err)
+	}
+	return nil
+}
+
+// _requireOrg checks the client belongs to the given org
+func _requireOrg(ctx contractapi.TransactionContextInterface, org string, action string) error {
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+	if clientMSPID != org {
+		return fmt.Errorf("client from %s is not authorized to %s", clientMSPID, action)
+	}
+	return nil
+}
+
+// _getClass reads a token class, returning nil when it does not exist
+func _getClass(ctx contractapi.TransactionContextInterface, tokenID string) (*TokenClass, error) {
+	classKey, err := ctx.GetStub().CreateCompositeKey(classPrefix, []string{tokenID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	classJSON, err := ctx.GetStub().GetState(classKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if classJSON == nil {
+		return nil, nil
+	}
+
+	var class TokenClass
+	err = json.Unmarshal(classJSON, &class)
+	if err != nil {
+		return nil, err
+	}
+	return &class, nil
+}
+
+// _putClass writes the token class to the world state
+func _putClass(ctx contractapi.TransactionContextInterface, class *TokenClass) error {
+	classKey, err := ctx.GetStub().CreateCompositeKey(classPrefix, []string{class.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	classJSON, err := json.Marshal(class)
+	if err != nil {
+		return fmt.Errorf("failed to marshal token class: %v", err)
+	}
+	err = ctx.GetStub().PutState(classKey, classJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put token class %s: %v", class.ID, err)
+	}
+	return nil
+}
+
+// _emitEvent marshals the payload and sets it as the chaincode event
+func _emitEvent(ctx contractapi.TransactionContextInterface, name string, payload interface{}) error {
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	err = ctx.GetStub().SetEvent(name, payloadJSON)
+	if err != nil {
+		return fmt.Errorf("failed to set event: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// GetClass returns the token class with the given ID
+func (s *SmartContract) GetClass(ctx contractapi.TransactionContextInterface, tokenID string) (*TokenClass, error) {
+	class, err := _getClass(ctx, tokenID)
+	if err != nil {
+		return nil, err
+	}
+	if class == nil {
+		return nil, fmt.Errorf("the token class %s does not exist", tokenID)
+	}
+	return class, nil
+}
+
+// GetClasses returns every token class
+func (s *SmartContract) GetClasses(ctx contractapi.TransactionContextInterface) ([]*TokenClass, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(classPrefix, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token classes: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var classes []*TokenClass
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var class TokenClass
+		err = json.Unmarshal(response.Value, &class)
+		if err != nil {
+			return nil, err
+		}
+		classes = append(classes, &class)
+	}
+
+	return classes, nil
+}
+
+// GetBalances returns the balances of every token class an account holds
+func (s *SmartContract) GetBalances(ctx contractapi.TransactionContextInterface, account string) ([]*Balance, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(balancePrefix, []string{account})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get balances of %s: %v", account, err)
+	}
+	defer resultsIterator.Close()
+
+	var balances []*Balance
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(response.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split composite key: %v", err)
+		}
+		amount, err := strconv.Atoi(string(response.Value))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse balance of %s: %v", keyParts[1], err)
+		}
+		balances = append(balances, &Balance{account, keyParts[1], amount})
+	}
+
+	return balances, nil
+}
+
+// ClientBalances returns the balances of the submitting client
+func (s *SmartContract) ClientBalances(ctx contractapi.TransactionContextInterface) ([]*Balance, error) {
+	account, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client id: %v", err)
+	}
+	return s.GetBalances(ctx, account)
+}
+
+// GetConversion returns the conversion from one token class into another
+func (s *SmartContract) GetConversion(ctx contractapi.TransactionContextInterface, fromTokenID string, toTokenID string) (*Conversion, error) {
+	conversionKey, err := ctx.GetStub().CreateCompositeKey(conversionPrefix, []string{fromTokenID, toTokenID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	conversionJSON, err := ctx.GetStub().GetState(conversionKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if conversionJSON == nil {
+		return nil, fmt.Errorf("%s cannot be converted into %s", fromTokenID, toTokenID)
+	}
+
+	var conversion Conversion
+	err = json.Unmarshal(conversionJSON, &conversion)
+	if err != nil {
+		return nil, err
+	}
+	return &conversion, nil
+}
+
+// QuoteConversion returns the tokens of toTokenID amount tokens of fromTokenID are worth at the
+// current rate, for clients to set the minAmountOut of Convert
+func (s *SmartContract) QuoteConversion(ctx contractapi.TransactionContextInterface, fromTokenID string, toTokenID string, amount int) (int, error) {
+	if amount <= 0 {
+		return 0, fmt.Errorf("amount must be a positive integer")
+	}
+	conversion, err := s.GetConversion(ctx, fromTokenID, toTokenID)
+	if err != nil {
+		return 0, err
+	}
+	round, err := _readRate(ctx, conversion)
+	if err != nil {
+		return 0, err
+	}
+	return _convert(amount, round)
+}
+
+// GetConversionReceipts returns the receipts of the conversions of an account in transaction ID order
+func (s *SmartContract) GetConversionReceipts(ctx contractapi.TransactionContextInterface, account string) ([]*ConversionReceipt, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(receiptPrefix, []string{account})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversion receipts of %s: %v", account, err)
+	}
+	defer resultsIterator.Close()
+
+	var receipts []*ConversionReceipt
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var receipt ConversionReceipt
+		err = json.Unmarshal(response.Value, &receipt)
+		if err != nil {
+			return nil, err
+		}
+		receipts = append(receipts, &receipt)
+	}
+
+	return receipts, nil
+}
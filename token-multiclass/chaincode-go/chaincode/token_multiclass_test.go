@@ -0,0 +1,192 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+)
+
+// fakeOracle stands in for the oracle chaincode, answering GetLatestRound with the round of a feed
+type fakeOracle struct {
+	rounds map[string]*oracleRound
+}
+
+func (f *fakeOracle) invoke(args [][]byte) pb.Response {
+	if string(args[0]) != "GetLatestRound" {
+		return shim.Error("unexpected function " + string(args[0]))
+	}
+	round, ok := f.rounds[string(args[1])]
+	if !ok {
+		return shim.Error(fmt.Sprintf("feed %s has no finalized rounds", args[1]))
+	}
+	roundJSON, err := json.Marshal(round)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(roundJSON)
+}
+
+// finalizedAt is the finalization time of a round finalized seconds before the stub's next transaction
+func finalizedAt(stub *fakeStub, seconds int64) string {
+	return time.Unix(1600000000+stub.txCount+1-seconds, 0).UTC().Format(time.RFC3339)
+}
+
+func checkError(t *testing.T, err error, expected string) {
+	t.Helper()
+	if expected == "" && err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected != "" && (err == nil || !strings.Contains(err.Error(), expected)) {
+		t.Fatalf("expected error containing %q, got %v", expected, err)
+	}
+}
+
+func checkBalances(t *testing.T, stub *fakeStub, account string, expected map[string]int) {
+	t.Helper()
+	balances, err := new(SmartContract).GetBalances(newContext(stub, account, "Org2MSP"), account)
+	checkError(t, err, "")
+	held := make(map[string]int)
+	for _, balance := range balances {
+		held[balance.TokenID] = balance.Amount
+	}
+	if fmt.Sprint(held) != fmt.Sprint(expected) {
+		t.Fatalf("expected %s to hold %v, got %v", account, expected, held)
+	}
+}
+
+func checkSupply(t *testing.T, stub *fakeStub, tokenID string, expected int) {
+	t.Helper()
+	class, err := new(SmartContract).GetClass(newContext(stub, "alice", "Org2MSP"), tokenID)
+	checkError(t, err, "")
+	if class.Supply != expected {
+		t.Fatalf("expected a supply of %d %s, got %d", expected, tokenID, class.Supply)
+	}
+}
+
+// createClasses defines EUR and USD tokens convertible from EUR to USD at the EUR/USD feed, with
+// rates up to an hour old, mints alice 1000 EUR and publishes a rate of 1.0842 finalized a minute ago
+func createClasses(t *testing.T, stub *fakeStub) *fakeOracle {
+	t.Helper()
+	contract := new(SmartContract)
+	checkError(t, contract.CreateClass(newContext(stub, "issuer", issuerMSPID), "EUR", "Euro token"), "")
+	checkError(t, contract.CreateClass(newContext(stub, "issuer", issuerMSPID), "USD", "US dollar token"), "")
+	checkError(t, contract.SetConversion(newContext(stub, "issuer", issuerMSPID), "EUR", "USD", "EUR/USD", 3600), "")
+	checkError(t, contract.Mint(newContext(stub, "issuer", issuerMSPID), "alice", "EUR", 1000), "")
+
+	oracle := &fakeOracle{rounds: map[string]*oracleRound{
+		"EUR/USD": {Round: 7, Median: 10842, Decimals: 4, FinalizedAt: finalizedAt(stub, 60)},
+	}}
+	stub.chaincodes[oracleChaincodeName] = oracle.invoke
+	return oracle
+}
+
+func TestSetConversion(t *testing.T) {
+	tests := []struct {
+		name     string
+		mspID    string
+		from     string
+		to       string
+		maxAge   int
+		expected string
+	}{
+		{"conversion", issuerMSPID, "USD", "EUR", 60, ""},
+		{"not the issuer", "Org2MSP", "USD", "EUR", 60, "client from Org2MSP is not authorized to set conversions"},
+		{"same class", issuerMSPID, "EUR", "EUR", 60, "a token class cannot be converted into itself"},
+		{"unknown class", issuerMSPID, "EUR", "GBP", 60, "the token class GBP does not exist"},
+		{"no max age", issuerMSPID, "USD", "EUR", 0, "max age must be a positive number of seconds"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stub := newFakeStub()
+			createClasses(t, stub)
+
+			err := new(SmartContract).SetConversion(newContext(stub, "issuer", test.mspID), test.from, test.to, "FX", test.maxAge)
+			checkError(t, err, test.expected)
+		})
+	}
+}
+
+func TestTransfer(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	createClasses(t, stub)
+
+	err := contract.Mint(newContext(stub, "alice", "Org2MSP"), "alice", "EUR", 1)
+	checkError(t, err, "client from Org2MSP is not authorized to mint tokens")
+	err = contract.Transfer(newContext(stub, "alice", "Org2MSP"), "bob", "USD", 1)
+	checkError(t, err, "account holds 0 USD, fewer than 1")
+
+	checkError(t, contract.Transfer(newContext(stub, "alice", "Org2MSP"), "bob", "EUR", 400), "")
+	checkBalances(t, stub, "alice", map[string]int{"EUR": 600})
+	checkBalances(t, stub, "bob", map[string]int{"EUR": 400})
+	checkSupply(t, stub, "EUR", 1000)
+}
+
+func TestConvert(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	createClasses(t, stub)
+
+	quote, err := contract.QuoteConversion(newContext(stub, "alice", "Org2MSP"), "EUR", "USD", 500)
+	checkError(t, err, "")
+	if quote != 542 {
+		t.Fatalf("expected a quote of 542 USD, got %d", quote)
+	}
+
+	// the slippage limit refuses a conversion paying less than the client expects
+	_, err = contract.Convert(newContext(stub, "alice", "Org2MSP"), "EUR", "USD", 500, 543)
+	checkError(t, err, "conversion would return 542, less than the minimum 543")
+	_, err = contract.Convert(newContext(stub, "alice", "Org2MSP"), "USD", "EUR", 500, 0)
+	checkError(t, err, "USD cannot be converted into EUR")
+	_, err = contract.Convert(newContext(stub, "alice", "Org2MSP"), "EUR", "USD", 1001, 0)
+	checkError(t, err, "account holds 1000 EUR, fewer than 1001")
+
+	// 500 EUR are burned and the 542.1 USD they are worth minted, rounded down
+	receipt, err := contract.Convert(newContext(stub, "alice", "Org2MSP"), "EUR", "USD", 500, 542)
+	checkError(t, err, "")
+	if receipt.AmountOut != 542 || receipt.Round != 7 || receipt.Rate != 10842 || receipt.Decimals != 4 || receipt.TxID != stub.GetTxID() {
+		t.Fatalf("unexpected receipt %+v", receipt)
+	}
+	if stub.eventName != "Converted" {
+		t.Fatalf("expected a Converted event, got %s", stub.eventName)
+	}
+	checkBalances(t, stub, "alice", map[string]int{"EUR": 500, "USD": 542})
+	checkSupply(t, stub, "EUR", 500)
+	checkSupply(t, stub, "USD", 542)
+
+	receipts, err := contract.GetConversionReceipts(newContext(stub, "alice", "Org2MSP"), "alice")
+	checkError(t, err, "")
+	if len(receipts) != 1 || *receipts[0] != *receipt {
+		t.Fatalf("unexpected receipts %v", receipts)
+	}
+
+	_, err = contract.Convert(newContext(stub, "alice", "Org2MSP"), "EUR", "USD", 0, 0)
+	checkError(t, err, "amount must be a positive integer")
+}
+
+func TestConvertRate(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	oracle := createClasses(t, stub)
+
+	oracle.rounds["EUR/USD"].FinalizedAt = finalizedAt(stub, 3601)
+	_, err := contract.Convert(newContext(stub, "alice", "Org2MSP"), "EUR", "USD", 100, 0)
+	checkError(t, err, "more than 3600 seconds ago")
+
+	oracle.rounds["EUR/USD"] = &oracleRound{Round: 8, Median: 0, Decimals: 4, FinalizedAt: finalizedAt(stub, 0)}
+	_, err = contract.Convert(newContext(stub, "alice", "Org2MSP"), "EUR", "USD", 100, 0)
+	checkError(t, err, "the rate of EUR/USD is 0, not a positive value")
+	oracle.rounds["EUR/USD"].Median = 5000
+	_, err = contract.Convert(newContext(stub, "alice", "Org2MSP"), "EUR", "USD", 1, 0)
+	checkError(t, err, "1 tokens are worth less than one token at the current rate")
+
+	delete(oracle.rounds, "EUR/USD")
+	_, err = contract.Convert(newContext(stub, "alice", "Org2MSP"), "EUR", "USD", 100, 0)
+	checkError(t, err, "failed to read the rate of EUR/USD from oracle: feed EUR/USD has no finalized rounds")
+	checkBalances(t, stub, "alice", map[string]int{"EUR": 1000})
+}
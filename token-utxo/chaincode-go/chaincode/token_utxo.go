@@ -0,0 +1,250 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// accessControlName is the name the access-control chaincode is deployed under on the channel
+const accessControlName = "acl"
+
+// object names for prefix
+const utxoPrefix = "utxo"
+
+// maxAmount is the largest total a transaction can spend or create
+const maxAmount = int(^uint(0) >> 1)
+
+// SmartContract provides functions for minting and transferring tokens held as unspent transaction outputs
+type SmartContract struct {
+	contractapi.Contract
+}
+
+// UTXO is an unspent transaction output. Key is txID#index of the transaction that created it.
+type UTXO struct {
+	Key    string `json:"utxo_key"`
+	Owner  string `json:"owner"`
+	Amount int    `json:"amount"`
+}
+
+// event provides an organized struct for emitting the outputs spent and created by a transaction
+type event struct {
+	Inputs  []string `json:"inputs"`
+	Outputs []UTXO   `json:"outputs"`
+}
+
+// Mint creates a new UTXO owned by the minter. The access-control chaincode decides which clients
+// and orgs may perform utxo.Mint.
+func (s *SmartContract) Mint(ctx contractapi.TransactionContextInterface, amount int) (*UTXO, error) {
+	err := _checkAccess(ctx, "utxo.Mint")
+	if err != nil {
+		return nil, err
+	}
+	if amount <= 0 {
+		return nil, fmt.Errorf("mint amount must be a positive integer")
+	}
+
+	minter, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	utxo := UTXO{
+		Key:    _outputKey(ctx, 0),
+		Owner:  minter,
+		Amount: amount,
+	}
+	err = _putUTXO(ctx, &utxo)
+	if err != nil {
+		return nil, err
+	}
+
+	err = _emitEvent(ctx, "Mint", event{nil, []UTXO{utxo}})
+	if err != nil {
+		return nil, err
+	}
+	return &utxo, nil
+}
+
+// Transfer spends UTXOs owned by the client and creates new ones. The amounts of the outputs must add
+// up to the amounts of the inputs, so change is returned by adding an output owned by the client.
+// Output keys are assigned by the chaincode and any key passed in utxoOutputs is ignored.
+//
+// Every UTXO is its own key, so transfers that spend different outputs never read or write the same
+// key and do not conflict during validation, unlike balances kept in one key per account.
+func (s *SmartContract) Transfer(ctx contractapi.TransactionContextInterface, utxoInputKeys []string, utxoOutputs []UTXO) ([]UTXO, error) {
+	if len(utxoInputKeys) == 0 {
+		return nil, fmt.Errorf("transfer must spend at least one utxo")
+	}
+	if len(utxoOutputs) == 0 {
+		return nil, fmt.Errorf("transfer must create at least one utxo")
+	}
+
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	// spend the inputs, which must all belong to the client
+	spent := make(map[string]bool)
+	inputTotal := 0
+	for _, inputKey := range utxoInputKeys {
+		if spent[inputKey] {
+			return nil, fmt.Errorf("utxo %s is spent more than once", inputKey)
+		}
+		spent[inputKey] = true
+
+		input, err := _getUTXO(ctx, clientID, inputKey)
+		if err != nil {
+			return nil, err
+		}
+		if input == nil {
+			return nil, fmt.Errorf("utxo %s is not an unspent output of the client", inputKey)
+		}
+		if input.Amount > maxAmount-inputTotal {
+			return nil, fmt.Errorf("input amounts overflow")
+		}
+		inputTotal += input.Amount
+
+		err = _delUTXO(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// create the outputs
+	outputTotal := 0
+	outputs := make([]UTXO, 0, len(utxoOutputs))
+	for i, output := range utxoOutputs {
+		if output.Owner == "" {
+			return nil, fmt.Errorf("output %d must have an owner", i)
+		}
+		if output.Amount <= 0 {
+			return nil, fmt.Errorf("output %d amount must be a positive integer", i)
+		}
+		if output.Amount > maxAmount-outputTotal {
+			return nil, fmt.Errorf("output amounts overflow")
+		}
+		outputTotal += output.Amount
+
+		utxo := UTXO{
+			Key:    _outputKey(ctx, i),
+			Owner:  output.Owner,
+			Amount: output.Amount,
+		}
+		err = _putUTXO(ctx, &utxo)
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, utxo)
+	}
+
+	if inputTotal != outputTotal {
+		return nil, fmt.Errorf("total utxo input amount %d does not equal total utxo output amount %d", inputTotal, outputTotal)
+	}
+
+	err = _emitEvent(ctx, "Transfer", event{utxoInputKeys, outputs})
+	if err != nil {
+		return nil, err
+	}
+	return outputs, nil
+}
+
+// ClientID returns the client ID of the submitting client, which others use as the owner of outputs
+// they send to it
+func (s *SmartContract) ClientID(ctx contractapi.TransactionContextInterface) (string, error) {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get client id: %v", err)
+	}
+	return clientID, nil
+}
+
+// _outputKey returns the key of the index-th output created by the transaction
+func _outputKey(ctx contractapi.TransactionContextInterface, index int) string {
+	return fmt.Sprintf("%s#%d", ctx.GetStub().GetTxID(), index)
+}
+
+// _getUTXO reads an output of the owner, returning nil when it does not exist or has been spent
+func _getUTXO(ctx contractapi.TransactionContextInterface, owner string, utxoKey string) (*UTXO, error) {
+	utxoCompositeKey, err := ctx.GetStub().CreateCompositeKey(utxoPrefix, []string{owner, utxoKey})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	utxoJSON, err := ctx.GetStub().GetState(utxoCompositeKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if utxoJSON == nil {
+		return nil, nil
+	}
+
+	var utxo UTXO
+	err = json.Unmarshal(utxoJSON, &utxo)
+	if err != nil {
+		return nil, err
+	}
+	return &utxo, nil
+}
+
+// _putUTXO writes an output under the key of its owner
+func _putUTXO(ctx contractapi.TransactionContextInterface, utxo *UTXO) error {
+	utxoCompositeKey, err := ctx.GetStub().CreateCompositeKey(utxoPrefix, []string{utxo.Owner, utxo.Key})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	utxoJSON, err := json.Marshal(utxo)
+	if err != nil {
+		return fmt.Errorf("failed to marshal utxo: %v", err)
+	}
+	err = ctx.GetStub().PutState(utxoCompositeKey, utxoJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put utxo %s: %v", utxo.Key, err)
+	}
+	return nil
+}
+
+// _delUTXO removes a spent output
+func _delUTXO(ctx contractapi.TransactionContextInterface, utxo *UTXO) error {
+	utxoCompositeKey, err := ctx.GetStub().CreateCompositeKey(utxoPrefix, []string{utxo.Owner, utxo.Key})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = ctx.GetStub().DelState(utxoCompositeKey)
+	if err != nil {
+		return fmt.Errorf("failed to spend utxo %s: %v", utxo.Key, err)
+	}
+	return nil
+}
+
+// _checkAccess asks the access-control chaincode whether the submitting client may perform the operation
+func _checkAccess(ctx contractapi.TransactionContextInterface, operation string) error {
+	args := [][]byte{[]byte("CheckAccess"), []byte(operation)}
+	response := ctx.GetStub().InvokeChaincode(accessControlName, args, "")
+	if response.Status != shim.OK {
+		return fmt.Errorf("failed to check access for %s: %s", operation, response.Message)
+	}
+	if string(response.Payload) != "true" {
+		return fmt.Errorf("client is not authorized to perform %s", operation)
+	}
+	return nil
+}
+
+// _emitEvent marshals the payload and sets it as the chaincode event
+func _emitEvent(ctx contractapi.TransactionContextInterface, name string, payload interface{}) error {
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	err = ctx.GetStub().SetEvent(name, payloadJSON)
+	if err != nil {
+		return fmt.Errorf("failed to set event: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// ClientUTXOs returns the unspent outputs owned by the submitting client
+func (s *SmartContract) ClientUTXOs(ctx contractapi.TransactionContextInterface) ([]*UTXO, error) {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client id: %v", err)
+	}
+	return s.GetUTXOs(ctx, clientID)
+}
+
+// GetUTXOs returns the unspent outputs owned by a client ID
+func (s *SmartContract) GetUTXOs(ctx contractapi.TransactionContextInterface, owner string) ([]*UTXO, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(utxoPrefix, []string{owner})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get utxos of %s: %v", owner, err)
+	}
+	defer resultsIterator.Close()
+
+	var utxos []*UTXO
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var utxo UTXO
+		err = json.Unmarshal(response.Value, &utxo)
+		if err != nil {
+			return nil, err
+		}
+		utxos = append(utxos, &utxo)
+	}
+
+	return utxos, nil
+}
+
+// GetBalance returns the sum of the unspent outputs owned by a client ID
+func (s *SmartContract) GetBalance(ctx contractapi.TransactionContextInterface, owner string) (int, error) {
+	utxos, err := s.GetUTXOs(ctx, owner)
+	if err != nil {
+		return 0, err
+	}
+
+	balance := 0
+	for _, utxo := range utxos {
+		balance += utxo.Amount
+	}
+	return balance, nil
+}
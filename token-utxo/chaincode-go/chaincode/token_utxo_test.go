@@ -0,0 +1,112 @@
+package chaincode
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+)
+
+// fakeACL stands in for the access-control chaincode, granting utxo.Mint to the minters
+type fakeACL struct {
+	client  string
+	minters map[string]bool
+}
+
+func (f *fakeACL) invoke(args [][]byte) pb.Response {
+	if string(args[0]) != "CheckAccess" || string(args[1]) != "utxo.Mint" {
+		return shim.Error("unexpected call " + string(args[0]))
+	}
+	return shim.Success([]byte(strconv.FormatBool(f.minters[f.client])))
+}
+
+func checkError(t *testing.T, err error, expected string) {
+	t.Helper()
+	if expected == "" && err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected != "" && (err == nil || !strings.Contains(err.Error(), expected)) {
+		t.Fatalf("expected error containing %q, got %v", expected, err)
+	}
+}
+
+func checkBalance(t *testing.T, stub *fakeStub, owner string, expected int) {
+	t.Helper()
+	balance, err := new(SmartContract).GetBalance(newContext(stub, owner, "Org1MSP"), owner)
+	checkError(t, err, "")
+	if balance != expected {
+		t.Fatalf("expected %s to hold %d, got %d", owner, expected, balance)
+	}
+}
+
+// mint has the minter mint an output of the given amount
+func mint(t *testing.T, stub *fakeStub, amount int) *UTXO {
+	t.Helper()
+	acl := &fakeACL{client: "minter", minters: map[string]bool{"minter": true}}
+	stub.chaincodes[accessControlName] = acl.invoke
+	utxo, err := new(SmartContract).Mint(newContext(stub, "minter", "Org1MSP"), amount)
+	checkError(t, err, "")
+	return utxo
+}
+
+func TestMint(t *testing.T) {
+	tests := []struct {
+		name     string
+		client   string
+		amount   int
+		expected string
+	}{
+		{"mint", "minter", 100, ""},
+		{"not a minter", "alice", 100, "client is not authorized to perform utxo.Mint"},
+		{"no amount", "minter", 0, "mint amount must be a positive integer"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stub := newFakeStub()
+			acl := &fakeACL{client: test.client, minters: map[string]bool{"minter": true}}
+			stub.chaincodes[accessControlName] = acl.invoke
+
+			utxo, err := new(SmartContract).Mint(newContext(stub, test.client, "Org1MSP"), test.amount)
+			checkError(t, err, test.expected)
+			if err == nil && (utxo.Key != stub.GetTxID()+"#0" || utxo.Owner != "minter") {
+				t.Fatalf("unexpected utxo %+v", utxo)
+			}
+		})
+	}
+}
+
+func TestTransfer(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	first := mint(t, stub, 100)
+	second := mint(t, stub, 50)
+
+	// alice is paid 120 and the minter keeps 30 in change
+	outputs, err := contract.Transfer(newContext(stub, "minter", "Org1MSP"), []string{first.Key, second.Key}, []UTXO{{Owner: "alice", Amount: 120}, {Owner: "minter", Amount: 30}})
+	checkError(t, err, "")
+	if len(outputs) != 2 || outputs[1].Key != stub.GetTxID()+"#1" || stub.eventName != "Transfer" {
+		t.Fatalf("unexpected outputs %+v", outputs)
+	}
+	checkBalance(t, stub, "alice", 120)
+	checkBalance(t, stub, "minter", 30)
+
+	_, err = contract.Transfer(newContext(stub, "minter", "Org1MSP"), []string{first.Key}, []UTXO{{Owner: "bob", Amount: 100}})
+	checkError(t, err, "utxo "+first.Key+" is not an unspent output of the client")
+
+	// only the owner spends an output
+	_, err = contract.Transfer(newContext(stub, "bob", "Org2MSP"), []string{outputs[0].Key}, []UTXO{{Owner: "bob", Amount: 120}})
+	checkError(t, err, "utxo "+outputs[0].Key+" is not an unspent output of the client")
+	_, err = contract.Transfer(newContext(stub, "alice", "Org2MSP"), []string{outputs[0].Key, outputs[0].Key}, []UTXO{{Owner: "bob", Amount: 240}})
+	checkError(t, err, "utxo "+outputs[0].Key+" is spent more than once")
+}
+
+func TestTransferInsufficientFunds(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	utxo := mint(t, stub, 100)
+
+	_, err := contract.Transfer(newContext(stub, "minter", "Org1MSP"), []string{utxo.Key}, []UTXO{{Owner: "alice", Amount: 101}})
+	checkError(t, err, "total utxo input amount 100 does not equal total utxo output amount 101")
+}
@@ -0,0 +1,562 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil"
+)
+
+// This sample assumes Org1 plays the vehicle licensing agency (the registrar). Only the
+// registrar can register vehicles, endorse keeper changes and authorize garages.
+const registrarMSPID = "Org1MSP"
+
+// object names for prefix
+const (
+	transferRequestPrefix = "transferRequest"
+	odometerPrefix        = "odometer"
+	historyPrefix         = "history"
+	garagePrefix          = "garage"
+)
+
+// vehicle status values
+const (
+	statusRegistered = "REGISTERED"
+	statusPending    = "TRANSFER_PENDING"
+)
+
+// history entry kinds and MOT results
+const (
+	kindMOT     = "MOT"
+	kindService = "SERVICE"
+
+	motPass = "PASS"
+	motFail = "FAIL"
+)
+
+// a VIN is 17 characters and never uses I, O or Q so they cannot be confused with 1 and 0
+const (
+	vinLength       = 17
+	vinInvalidChars = "IOQ"
+)
+
+// earliest model year the register accepts
+const minModelYear = 1885
+
+// SmartContract provides functions for registering vehicles and their odometer and MOT history
+type SmartContract struct {
+	contractapi.Contract
+}
+
+// Vehicle is the registration record for a vehicle, keyed by VIN
+type Vehicle struct {
+	ObjectType   string `json:"objectType"`
+	VIN          string `json:"vin"`
+	Make         string `json:"make"`
+	Model        string `json:"model"`
+	Year         int    `json:"year"`
+	OwnerOrg     string `json:"ownerOrg"`
+	Status       string `json:"status"`
+	Odometer     int    `json:"odometer"`
+	ReadingCount int    `json:"readingCount"`
+	HistoryCount int    `json:"historyCount"`
+	MOTExpiry    string `json:"motExpiry"`
+}
+
+// TransferRequest is raised by the current keeper and waits for registrar endorsement
+type TransferRequest struct {
+	ObjectType string `json:"objectType"`
+	VIN        string `json:"vin"`
+	SellerOrg  string `json:"sellerOrg"`
+	BuyerOrg   string `json:"buyerOrg"`
+	TxID       string `json:"txID"`
+}
+
+// OdometerReading is one append-only mileage reading of a vehicle
+type OdometerReading struct {
+	ObjectType string `json:"objectType"`
+	VIN        string `json:"vin"`
+	Sequence   int    `json:"sequence"`
+	Reading    int    `json:"reading"`
+	Source     string `json:"source"`
+	RecordedBy string `json:"recordedBy"`
+	RecordedAt string `json:"recordedAt"`
+}
+
+// HistoryEntry is an MOT test or a service carried out by an authorized garage
+type HistoryEntry struct {
+	ObjectType string   `json:"objectType"`
+	VIN        string   `json:"vin"`
+	Sequence   int      `json:"sequence"`
+	Kind       string   `json:"kind"`
+	Garage     string   `json:"garage"`
+	Odometer   int      `json:"odometer"`
+	Result     string   `json:"result,omitempty"`
+	Notes      []string `json:"notes"`
+	RecordedAt string   `json:"recordedAt"`
+}
+
+// event provides an organized struct for emitting keeper change events
+type event struct {
+	VIN  string `json:"vin"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// RegisterVehicle records a new vehicle with the given org as first keeper and its odometer
+// reading at registration. Only the registrar can register vehicles.
+func (s *SmartContract) RegisterVehicle(ctx contractapi.TransactionContextInterface, vin string, manufacturer string, model string, year int, ownerOrg string, odometer int) error {
+	err := _requireRegistrar(ctx)
+	if err != nil {
+		return err
+	}
+
+	vin = strings.ToUpper(vin)
+	err = _validateVIN(vin)
+	if err != nil {
+		return err
+	}
+	if manufacturer == "" || model == "" {
+		return fmt.Errorf("make and model must be set")
+	}
+	if year < minModelYear {
+		return fmt.Errorf("model year %d is not valid", year)
+	}
+	if ownerOrg == "" {
+		return fmt.Errorf("owner org must be set")
+	}
+	if odometer < 0 {
+		return fmt.Errorf("odometer reading must not be negative")
+	}
+
+	exists, err := s.VehicleExists(ctx, vin)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("the vehicle %s is already registered", vin)
+	}
+
+	vehicle := Vehicle{
+		ObjectType: "vehicle",
+		VIN:        vin,
+		Make:       manufacturer,
+		Model:      model,
+		Year:       year,
+		OwnerOrg:   ownerOrg,
+		Status:     statusRegistered,
+	}
+
+	err = _appendOdometer(ctx, &vehicle, odometer, "REGISTRATION")
+	if err != nil {
+		return err
+	}
+	return _putVehicle(ctx, &vehicle)
+}
+
+// RequestTransfer is called by the current keeper to hand the vehicle to buyerOrg.
+// The vehicle is locked until the registrar approves or rejects the request.
+func (s *SmartContract) RequestTransfer(ctx contractapi.TransactionContextInterface, vin string, buyerOrg string) error {
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+
+	vehicle, err := s.ReadVehicle(ctx, vin)
+	if err != nil {
+		return err
+	}
+
+	if clientOrgID != vehicle.OwnerOrg {
+		return fmt.Errorf("a client from %s cannot transfer a vehicle kept by %s", clientOrgID, vehicle.OwnerOrg)
+	}
+	if vehicle.Status != statusRegistered {
+		return fmt.Errorf("vehicle %s already has a transfer pending", vehicle.VIN)
+	}
+	if buyerOrg == "" || buyerOrg == vehicle.OwnerOrg {
+		return fmt.Errorf("buyer org must be set and differ from the current keeper")
+	}
+
+	request := TransferRequest{
+		ObjectType: transferRequestPrefix,
+		VIN:        vehicle.VIN,
+		SellerOrg:  vehicle.OwnerOrg,
+		BuyerOrg:   buyerOrg,
+		TxID:       ctx.GetStub().GetTxID(),
+	}
+	requestJSON, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to marshal transfer request: %v", err)
+	}
+
+	requestKey, err := ctx.GetStub().CreateCompositeKey(transferRequestPrefix, []string{vehicle.VIN})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = ctx.GetStub().PutState(requestKey, requestJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put transfer request: %v", err)
+	}
+
+	vehicle.Status = statusPending
+	return _putVehicle(ctx, vehicle)
+}
+
+// ApproveTransfer is the registrar endorsement of a pending keeper change
+func (s *SmartContract) ApproveTransfer(ctx contractapi.TransactionContextInterface, vin string) error {
+	err := _requireRegistrar(ctx)
+	if err != nil {
+		return err
+	}
+
+	vehicle, err := s.ReadVehicle(ctx, vin)
+	if err != nil {
+		return err
+	}
+
+	request, requestKey, err := _getTransferRequest(ctx, vehicle.VIN)
+	if err != nil {
+		return err
+	}
+
+	vehicle.OwnerOrg = request.BuyerOrg
+	vehicle.Status = statusRegistered
+	err = _putVehicle(ctx, vehicle)
+	if err != nil {
+		return err
+	}
+
+	err = ctx.GetStub().DelState(requestKey)
+	if err != nil {
+		return fmt.Errorf("failed to delete transfer request: %v", err)
+	}
+
+	return _emitEvent(ctx, "Transfer", event{vehicle.VIN, request.SellerOrg, request.BuyerOrg})
+}
+
+// RejectTransfer cancels a pending keeper change. It can be called by the registrar, or by
+// the seller to withdraw their own request.
+func (s *SmartContract) RejectTransfer(ctx contractapi.TransactionContextInterface, vin string) error {
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+
+	vehicle, err := s.ReadVehicle(ctx, vin)
+	if err != nil {
+		return err
+	}
+
+	if clientOrgID != registrarMSPID && clientOrgID != vehicle.OwnerOrg {
+		return fmt.Errorf("client from %s is not authorized to reject the transfer of vehicle %s", clientOrgID, vehicle.VIN)
+	}
+
+	_, requestKey, err := _getTransferRequest(ctx, vehicle.VIN)
+	if err != nil {
+		return err
+	}
+
+	err = ctx.GetStub().DelState(requestKey)
+	if err != nil {
+		return fmt.Errorf("failed to delete transfer request: %v", err)
+	}
+
+	vehicle.Status = statusRegistered
+	return _putVehicle(ctx, vehicle)
+}
+
+// AuthorizeGarage allows an org to record MOT tests and services, or withdraws that
+// authorization. Only the registrar can authorize garages.
+func (s *SmartContract) AuthorizeGarage(ctx contractapi.TransactionContextInterface, garageMSPID string, authorized bool) error {
+	err := _requireRegistrar(ctx)
+	if err != nil {
+		return err
+	}
+	if garageMSPID == "" {
+		return fmt.Errorf("garage MSPID must be set")
+	}
+
+	garageKey, err := ctx.GetStub().CreateCompositeKey(garagePrefix, []string{garageMSPID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	if !authorized {
+		return ctx.GetStub().DelState(garageKey)
+	}
+	return ctx.GetStub().PutState(garageKey, []byte(garageMSPID))
+}
+
+// RecordOdometer appends a mileage reading. It can be called by the keeper or the registrar
+// and is rejected when the reading is lower than the last one recorded.
+func (s *SmartContract) RecordOdometer(ctx contractapi.TransactionContextInterface, vin string, reading int) error {
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+
+	vehicle, err := s.ReadVehicle(ctx, vin)
+	if err != nil {
+		return err
+	}
+	if clientOrgID != registrarMSPID && clientOrgID != vehicle.OwnerOrg {
+		return fmt.Errorf("client from %s is not authorized to record odometer readings for vehicle %s", clientOrgID, vehicle.VIN)
+	}
+
+	source := "KEEPER"
+	if clientOrgID == registrarMSPID {
+		source = "REGISTRAR"
+	}
+	err = _appendOdometer(ctx, vehicle, reading, source)
+	if err != nil {
+		return err
+	}
+	return _putVehicle(ctx, vehicle)
+}
+
+// RecordMOT records an MOT test by an authorized garage along with the odometer reading taken
+// during the test. A pass extends the MOT expiry to one year from the test.
+func (s *SmartContract) RecordMOT(ctx contractapi.TransactionContextInterface, vin string, result string, odometer int, advisoriesJSON string) error {
+	result = strings.ToUpper(result)
+	if result != motPass && result != motFail {
+		return fmt.Errorf("MOT result must be %s or %s", motPass, motFail)
+	}
+
+	vehicle, entry, err := s.recordHistory(ctx, vin, kindMOT, odometer, advisoriesJSON)
+	if err != nil {
+		return err
+	}
+	entry.Result = result
+
+	if result == motPass {
+		testedAt, err := time.Parse(time.RFC3339, entry.RecordedAt)
+		if err != nil {
+			return err
+		}
+		vehicle.MOTExpiry = testedAt.AddDate(1, 0, 0).Format(time.RFC3339)
+	}
+
+	err = _putHistoryEntry(ctx, entry)
+	if err != nil {
+		return err
+	}
+	return _putVehicle(ctx, vehicle)
+}
+
+// RecordService records a service by an authorized garage. workJSON is a JSON array
+// describing the work carried out.
+func (s *SmartContract) RecordService(ctx contractapi.TransactionContextInterface, vin string, odometer int, workJSON string) error {
+	vehicle, entry, err := s.recordHistory(ctx, vin, kindService, odometer, workJSON)
+	if err != nil {
+		return err
+	}
+	if len(entry.Notes) == 0 {
+		return fmt.Errorf("a service must list the work carried out")
+	}
+
+	err = _putHistoryEntry(ctx, entry)
+	if err != nil {
+		return err
+	}
+	return _putVehicle(ctx, vehicle)
+}
+
+// recordHistory checks the garage, appends the odometer reading taken at the visit and
+// builds the history entry. The caller completes and stores both.
+func (s *SmartContract) recordHistory(ctx contractapi.TransactionContextInterface, vin string, kind string, odometer int, notesJSON string) (*Vehicle, *HistoryEntry, error) {
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get MSPID: %v", err)
+	}
+
+	authorized, err := s.IsGarageAuthorized(ctx, clientOrgID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !authorized {
+		return nil, nil, fmt.Errorf("client from %s is not an authorized garage", clientOrgID)
+	}
+
+	var notes []string
+	if notesJSON != "" {
+		err = json.Unmarshal([]byte(notesJSON), &notes)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to unmarshal notes: %v", err)
+		}
+	}
+
+	vehicle, err := s.ReadVehicle(ctx, vin)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = _appendOdometer(ctx, vehicle, odometer, kind)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	vehicle.HistoryCount++
+	entry := HistoryEntry{
+		ObjectType: historyPrefix,
+		VIN:        vehicle.VIN,
+		Sequence:   vehicle.HistoryCount,
+		Kind:       kind,
+		Garage:     clientOrgID,
+		Odometer:   odometer,
+		Notes:      notes,
+		RecordedAt: now.Format(time.RFC3339),
+	}
+	return vehicle, &entry, nil
+}
+
+// _requireRegistrar checks the submitting client belongs to the registrar org
+func _requireRegistrar(ctx contractapi.TransactionContextInterface) error {
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+	if clientMSPID != registrarMSPID {
+		return fmt.Errorf("client from %s is not the vehicle registrar", clientMSPID)
+	}
+	return nil
+}
+
+// _validateVIN checks the length and alphabet of a vehicle identification number
+func _validateVIN(vin string) error {
+	if len(vin) != vinLength {
+		return fmt.Errorf("VIN %q must be %d characters", vin, vinLength)
+	}
+	for _, c := range vin {
+		if !(c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') || strings.ContainsRune(vinInvalidChars, c) {
+			return fmt.Errorf("VIN %q contains invalid character %q", vin, c)
+		}
+	}
+	return nil
+}
+
+// _appendOdometer stores the next odometer reading of a vehicle. Readings never go down, a
+// lower reading than the last one points to a clocked odometer and is refused.
+func _appendOdometer(ctx contractapi.TransactionContextInterface, vehicle *Vehicle, reading int, source string) error {
+	if reading < vehicle.Odometer {
+		return fmt.Errorf("odometer reading %d for vehicle %s is lower than the last recorded reading %d", reading, vehicle.VIN, vehicle.Odometer)
+	}
+
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+	now, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	vehicle.ReadingCount++
+	vehicle.Odometer = reading
+	odometerReading := OdometerReading{
+		ObjectType: odometerPrefix,
+		VIN:        vehicle.VIN,
+		Sequence:   vehicle.ReadingCount,
+		Reading:    reading,
+		Source:     source,
+		RecordedBy: clientOrgID,
+		RecordedAt: now.Format(time.RFC3339),
+	}
+	readingJSON, err := json.Marshal(odometerReading)
+	if err != nil {
+		return fmt.Errorf("failed to marshal odometer reading: %v", err)
+	}
+
+	readingKey, err := ctx.GetStub().CreateCompositeKey(odometerPrefix, []string{vehicle.VIN, _padded(vehicle.ReadingCount)})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = ctx.GetStub().PutState(readingKey, readingJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put odometer reading: %v", err)
+	}
+	return nil
+}
+
+// _putHistoryEntry writes an MOT or service entry to the world state
+func _putHistoryEntry(ctx contractapi.TransactionContextInterface, entry *HistoryEntry) error {
+	entryJSON, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal history entry: %v", err)
+	}
+
+	entryKey, err := ctx.GetStub().CreateCompositeKey(historyPrefix, []string{entry.VIN, _padded(entry.Sequence)})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = ctx.GetStub().PutState(entryKey, entryJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put history entry: %v", err)
+	}
+	return nil
+}
+
+// _getTransferRequest reads the pending transfer request for a vehicle
+func _getTransferRequest(ctx contractapi.TransactionContextInterface, vin string) (*TransferRequest, string, error) {
+	requestKey, err := ctx.GetStub().CreateCompositeKey(transferRequestPrefix, []string{vin})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	requestJSON, err := ctx.GetStub().GetState(requestKey)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if requestJSON == nil {
+		return nil, "", fmt.Errorf("vehicle %s has no pending transfer", vin)
+	}
+
+	var request TransferRequest
+	err = json.Unmarshal(requestJSON, &request)
+	if err != nil {
+		return nil, "", err
+	}
+	return &request, requestKey, nil
+}
+
+// _putVehicle writes the vehicle to the world state
+func _putVehicle(ctx contractapi.TransactionContextInterface, vehicle *Vehicle) error {
+	vehicleJSON, err := json.Marshal(vehicle)
+	if err != nil {
+		return fmt.Errorf("failed to marshal vehicle: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(vehicle.VIN, vehicleJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put vehicle %s: %v", vehicle.VIN, err)
+	}
+	return nil
+}
+
+// _padded zero pads a sequence so composite keys sort in the order entries were recorded
+func _padded(sequence int) string {
+	return fmt.Sprintf("%010d", sequence)
+}
+
+// _emitEvent marshals the payload and sets it as the chaincode event
+func _emitEvent(ctx contractapi.TransactionContextInterface, name string, payload interface{}) error {
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	err = ctx.GetStub().SetEvent(name, payloadJSON)
+	if err != nil {
+		return fmt.Errorf("failed to set event: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// QueryResult structure used for handling result of history query
+type QueryResult struct {
+	Record    *Vehicle
+	TxId      string    `json:"txId"`
+	Timestamp time.Time `json:"timestamp"`
+	IsDelete  bool      `json:"isDelete"`
+}
+
+// ReadVehicle returns the registration record of a vehicle
+func (s *SmartContract) ReadVehicle(ctx contractapi.TransactionContextInterface, vin string) (*Vehicle, error) {
+	vehicleJSON, err := ctx.GetStub().GetState(strings.ToUpper(vin))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if vehicleJSON == nil {
+		return nil, fmt.Errorf("the vehicle %s does not exist", vin)
+	}
+
+	var vehicle Vehicle
+	err = json.Unmarshal(vehicleJSON, &vehicle)
+	if err != nil {
+		return nil, err
+	}
+	return &vehicle, nil
+}
+
+// VehicleExists returns true when a vehicle with the given VIN is registered
+func (s *SmartContract) VehicleExists(ctx contractapi.TransactionContextInterface, vin string) (bool, error) {
+	vehicleJSON, err := ctx.GetStub().GetState(strings.ToUpper(vin))
+	if err != nil {
+		return false, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	return vehicleJSON != nil, nil
+}
+
+// IsGarageAuthorized returns true when the org may record MOT tests and services
+func (s *SmartContract) IsGarageAuthorized(ctx contractapi.TransactionContextInterface, garageMSPID string) (bool, error) {
+	garageKey, err := ctx.GetStub().CreateCompositeKey(garagePrefix, []string{garageMSPID})
+	if err != nil {
+		return false, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	garage, err := ctx.GetStub().GetState(garageKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	return garage != nil, nil
+}
+
+// GetPendingTransfer returns the transfer request waiting for registrar endorsement
+func (s *SmartContract) GetPendingTransfer(ctx contractapi.TransactionContextInterface, vin string) (*TransferRequest, error) {
+	request, _, err := _getTransferRequest(ctx, strings.ToUpper(vin))
+	return request, err
+}
+
+// GetOdometerReadings returns every odometer reading of a vehicle, oldest first
+func (s *SmartContract) GetOdometerReadings(ctx contractapi.TransactionContextInterface, vin string) ([]*OdometerReading, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(odometerPrefix, []string{strings.ToUpper(vin)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get odometer readings for vehicle %s: %v", vin, err)
+	}
+	defer resultsIterator.Close()
+
+	var readings []*OdometerReading
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var reading OdometerReading
+		err = json.Unmarshal(response.Value, &reading)
+		if err != nil {
+			return nil, err
+		}
+		readings = append(readings, &reading)
+	}
+
+	return readings, nil
+}
+
+// GetServiceHistory returns the MOT tests and services of a vehicle, oldest first
+func (s *SmartContract) GetServiceHistory(ctx contractapi.TransactionContextInterface, vin string) ([]*HistoryEntry, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(historyPrefix, []string{strings.ToUpper(vin)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service history for vehicle %s: %v", vin, err)
+	}
+	defer resultsIterator.Close()
+
+	var entries []*HistoryEntry
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var entry HistoryEntry
+		err = json.Unmarshal(response.Value, &entry)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, &entry)
+	}
+
+	return entries, nil
+}
+
+// QueryVehicleHistory returns the registration history of a vehicle, including every keeper change
+func (s *SmartContract) QueryVehicleHistory(ctx contractapi.TransactionContextInterface, vin string) ([]QueryResult, error) {
+	resultsIterator, err := ctx.GetStub().GetHistoryForKey(strings.ToUpper(vin))
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var results []QueryResult
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var vehicle *Vehicle
+		if !response.IsDelete {
+			err = json.Unmarshal(response.Value, &vehicle)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		timestamp, err := ptypes.Timestamp(response.Timestamp)
+		if err != nil {
+			return nil, err
+		}
+		record := QueryResult{
+			TxId:      response.TxId,
+			Timestamp: timestamp,
+			Record:    vehicle,
+			IsDelete:  response.IsDelete,
+		}
+		results = append(results, record)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,132 @@
+package chaincode
+
+import (
+	"strings"
+	"testing"
+)
+
+const (
+	keeperMSPID = "Org2MSP"
+	garageMSPID = "Org3MSP"
+	vin         = "1HGCM82633A004352"
+)
+
+func checkError(t *testing.T, err error, expected string) {
+	t.Helper()
+	if expected == "" && err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected != "" && (err == nil || !strings.Contains(err.Error(), expected)) {
+		t.Fatalf("expected error containing %q, got %v", expected, err)
+	}
+}
+
+// registerVehicle registers the vehicle with Org2 as keeper at 12000 miles
+func registerVehicle(t *testing.T, stub *fakeStub) {
+	t.Helper()
+	checkError(t, new(SmartContract).RegisterVehicle(newContext(stub, "registrar", registrarMSPID), vin, "Honda", "Accord", 2003, keeperMSPID, 12000), "")
+}
+
+func TestRegisterVehicle(t *testing.T) {
+	tests := []struct {
+		name     string
+		mspID    string
+		vin      string
+		year     int
+		expected string
+	}{
+		{"registrar", registrarMSPID, "JH4KA7561PC008269", 1993, ""},
+		{"not registrar", keeperMSPID, "JH4KA7561PC008269", 1993, "client from Org2MSP is not the vehicle registrar"},
+		{"short VIN", registrarMSPID, "JH4KA7561", 1993, `VIN "JH4KA7561" must be 17 characters`},
+		{"VIN with O", registrarMSPID, "JH4KA7561PO008269", 1993, `contains invalid character 'O'`},
+		{"early year", registrarMSPID, "JH4KA7561PC008269", 1884, "model year 1884 is not valid"},
+		{"registered", registrarMSPID, vin, 2003, "the vehicle " + vin + " is already registered"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stub := newFakeStub()
+			registerVehicle(t, stub)
+
+			err := new(SmartContract).RegisterVehicle(newContext(stub, "registrar", test.mspID), test.vin, "Acura", "Legend", test.year, keeperMSPID, 0)
+			checkError(t, err, test.expected)
+		})
+	}
+}
+
+func TestTransfer(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	registerVehicle(t, stub)
+
+	err := contract.RequestTransfer(newContext(stub, "buyer", "Org4MSP"), vin, "Org4MSP")
+	checkError(t, err, "a client from Org4MSP cannot transfer a vehicle kept by Org2MSP")
+
+	checkError(t, contract.RequestTransfer(newContext(stub, "keeper", keeperMSPID), vin, "Org4MSP"), "")
+	err = contract.RequestTransfer(newContext(stub, "keeper", keeperMSPID), vin, "Org5MSP")
+	checkError(t, err, "vehicle "+vin+" already has a transfer pending")
+
+	err = contract.ApproveTransfer(newContext(stub, "keeper", keeperMSPID), vin)
+	checkError(t, err, "client from Org2MSP is not the vehicle registrar")
+	err = contract.RejectTransfer(newContext(stub, "buyer", "Org4MSP"), vin)
+	checkError(t, err, "client from Org4MSP is not authorized to reject the transfer of vehicle "+vin)
+
+	checkError(t, contract.ApproveTransfer(newContext(stub, "registrar", registrarMSPID), vin), "")
+	vehicle, err := contract.ReadVehicle(newContext(stub, "registrar", registrarMSPID), vin)
+	checkError(t, err, "")
+	if vehicle.OwnerOrg != "Org4MSP" || vehicle.Status != statusRegistered || stub.eventName != "Transfer" {
+		t.Fatalf("unexpected vehicle %+v", vehicle)
+	}
+
+	_, err = contract.GetPendingTransfer(newContext(stub, "registrar", registrarMSPID), vin)
+	checkError(t, err, "vehicle "+vin+" has no pending transfer")
+
+	history, err := contract.QueryVehicleHistory(newContext(stub, "registrar", registrarMSPID), vin)
+	checkError(t, err, "")
+	if len(history) != 3 || history[2].Record.OwnerOrg != "Org4MSP" {
+		t.Fatalf("unexpected history %+v", history)
+	}
+}
+
+func TestOdometerAndMOT(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	registerVehicle(t, stub)
+
+	err := contract.RecordOdometer(newContext(stub, "keeper", keeperMSPID), vin, 11999)
+	checkError(t, err, "odometer reading 11999 for vehicle "+vin+" is lower than the last recorded reading 12000")
+	err = contract.RecordOdometer(newContext(stub, "stranger", "Org4MSP"), vin, 12500)
+	checkError(t, err, "client from Org4MSP is not authorized to record odometer readings for vehicle "+vin)
+	checkError(t, contract.RecordOdometer(newContext(stub, "keeper", keeperMSPID), vin, 12500), "")
+
+	err = contract.RecordMOT(newContext(stub, "tester", garageMSPID), vin, "pass", 13000, "")
+	checkError(t, err, "client from Org3MSP is not an authorized garage")
+
+	err = contract.AuthorizeGarage(newContext(stub, "keeper", keeperMSPID), garageMSPID, true)
+	checkError(t, err, "client from Org2MSP is not the vehicle registrar")
+	checkError(t, contract.AuthorizeGarage(newContext(stub, "registrar", registrarMSPID), garageMSPID, true), "")
+
+	err = contract.RecordMOT(newContext(stub, "tester", garageMSPID), vin, "pass", 12400, "")
+	checkError(t, err, "odometer reading 12400 for vehicle "+vin+" is lower than the last recorded reading 12500")
+	checkError(t, contract.RecordMOT(newContext(stub, "tester", garageMSPID), vin, "pass", 13000, `["tyre wear"]`), "")
+
+	err = contract.RecordService(newContext(stub, "tester", garageMSPID), vin, 13100, "[]")
+	checkError(t, err, "a service must list the work carried out")
+	checkError(t, contract.RecordService(newContext(stub, "tester", garageMSPID), vin, 13100, `["oil change"]`), "")
+
+	vehicle, err := contract.ReadVehicle(newContext(stub, "registrar", registrarMSPID), vin)
+	checkError(t, err, "")
+	if vehicle.Odometer != 13100 || vehicle.ReadingCount != 4 || vehicle.MOTExpiry != "2021-09-13T12:26:49Z" {
+		t.Fatalf("unexpected vehicle %+v", vehicle)
+	}
+
+	readings, err := contract.GetOdometerReadings(newContext(stub, "registrar", registrarMSPID), vin)
+	checkError(t, err, "")
+	if len(readings) != 4 || readings[1].Source != "KEEPER" || readings[2].Source != kindMOT {
+		t.Fatalf("unexpected readings %+v", readings)
+	}
+	services, err := contract.GetServiceHistory(newContext(stub, "registrar", registrarMSPID), vin)
+	checkError(t, err, "")
+	if len(services) != 2 || services[0].Result != motPass || services[1].Notes[0] != "oil change" {
+		t.Fatalf("unexpected service history %+v", services)
+	}
+}
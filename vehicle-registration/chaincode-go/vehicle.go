@@ -0,0 +1,23 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"log"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/vehicle-registration/chaincode-go/chaincode"
+)
+
+func main() {
+	vehicleChaincode, err := contractapi.NewChaincode(&chaincode.SmartContract{})
+	if err != nil {
+		log.Panicf("Error creating vehicle-registration chaincode: %v", err)
+	}
+
+	if err := vehicleChaincode.Start(); err != nil {
+		log.Panicf("Error starting vehicle-registration chaincode: %v", err)
+	}
+}
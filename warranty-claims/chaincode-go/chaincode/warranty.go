@@ -0,0 +1,492 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil"
+)
+
+// chaincode name used in the test network READMEs
+const defaultTokenChaincode = "token_erc20"
+
+// object names for prefix
+const (
+	warrantyPrefix    = "warranty"
+	claimPrefix       = "claim"
+	serialClaimPrefix = "serial~claim"
+)
+
+// claim status values
+const (
+	claimFiled    = "FILED"
+	claimAssigned = "ASSIGNED"
+	claimRepaired = "REPAIRED"
+	claimResolved = "RESOLVED"
+	claimRejected = "REJECTED"
+)
+
+// resolutions a manufacturer can settle a claim with
+const (
+	resolutionRepair = "REPAIR"
+	resolutionRefund = "REFUND"
+)
+
+// SmartContract provides functions for registering product warranties and settling claims against them
+type SmartContract struct {
+	contractapi.Contract
+}
+
+// Warranty links a product serial number to the manufacturer's warranty and its current holder
+type Warranty struct {
+	ObjectType      string `json:"objectType"`
+	Serial          string `json:"serial"`
+	ProductModel    string `json:"productModel"`
+	ManufacturerOrg string `json:"manufacturerOrg"`
+	Holder          string `json:"holder"`
+	StartsAt        string `json:"startsAt"`
+	ExpiresAt       string `json:"expiresAt"`
+	Coverage        int    `json:"coverage"`
+	PaidOut         int    `json:"paidOut"`
+	TokenChaincode  string `json:"tokenChaincode"`
+}
+
+// Claim is filed by the warranty holder, repaired by an assigned repairer and resolved by the manufacturer
+type Claim struct {
+	ObjectType      string   `json:"objectType"`
+	ID              string   `json:"claimID"`
+	Serial          string   `json:"serial"`
+	Claimant        string   `json:"claimant"`
+	Description     string   `json:"description"`
+	EvidenceHashes  []string `json:"evidenceHashes"`
+	FiledAt         string   `json:"filedAt"`
+	RepairerOrg     string   `json:"repairerOrg,omitempty"`
+	Repairer        string   `json:"repairer,omitempty"`
+	RepairCost      int      `json:"repairCost"`
+	RepairReport    string   `json:"repairReport,omitempty"`
+	Resolution      string   `json:"resolution,omitempty"`
+	PayoutAmount    int      `json:"payoutAmount"`
+	RejectionReason string   `json:"rejectionReason,omitempty"`
+	Status          string   `json:"status"`
+	PaymentTxID     string   `json:"paymentTxID,omitempty"`
+}
+
+// event provides an organized struct for emitting claim status events
+type event struct {
+	ClaimID string `json:"claimID"`
+	Serial  string `json:"serial"`
+	Status  string `json:"status"`
+	Amount  int    `json:"amount"`
+}
+
+// RegisterWarranty is called by the manufacturer when a product is sold. The warranty starts
+// at the transaction time and runs for durationMonths. holder is the buyer's client ID and
+// coverage caps the total the manufacturer pays out over the life of the warranty.
+func (s *SmartContract) RegisterWarranty(ctx contractapi.TransactionContextInterface, serial string, productModel string, holder string, durationMonths int, coverage int, tokenChaincode string) error {
+	manufacturerOrg, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+
+	if serial == "" || productModel == "" || holder == "" {
+		return fmt.Errorf("serial, product model and holder must be set")
+	}
+	if durationMonths <= 0 {
+		return fmt.Errorf("warranty duration must be a positive number of months")
+	}
+	if coverage <= 0 {
+		return fmt.Errorf("coverage must be a positive integer")
+	}
+	if tokenChaincode == "" {
+		tokenChaincode = defaultTokenChaincode
+	}
+
+	existing, err := _getWarranty(ctx, serial)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("a warranty for serial %s already exists", serial)
+	}
+
+	now, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	warranty := Warranty{
+		ObjectType:      warrantyPrefix,
+		Serial:          serial,
+		ProductModel:    productModel,
+		ManufacturerOrg: manufacturerOrg,
+		Holder:          holder,
+		StartsAt:        now.Format(time.RFC3339),
+		ExpiresAt:       now.AddDate(0, durationMonths, 0).Format(time.RFC3339),
+		Coverage:        coverage,
+		TokenChaincode:  tokenChaincode,
+	}
+	return _putWarranty(ctx, &warranty)
+}
+
+// TransferWarranty is called by the warranty holder when the product is resold. The remaining
+// warranty period and coverage pass to the new holder.
+func (s *SmartContract) TransferWarranty(ctx contractapi.TransactionContextInterface, serial string, newHolder string) error {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	warranty, err := s.ReadWarranty(ctx, serial)
+	if err != nil {
+		return err
+	}
+	if clientID != warranty.Holder {
+		return fmt.Errorf("only the warranty holder can transfer the warranty for serial %s", serial)
+	}
+	if newHolder == "" || newHolder == warranty.Holder {
+		return fmt.Errorf("new holder must be set and differ from the current holder")
+	}
+
+	warranty.Holder = newHolder
+	return _putWarranty(ctx, warranty)
+}
+
+// FileClaim is called by the warranty holder while the warranty is in force. evidenceHashes are
+// hex SHA-256 digests of photos, receipts and fault reports kept off chain.
+func (s *SmartContract) FileClaim(ctx contractapi.TransactionContextInterface, claimID string, serial string, description string, evidenceHashes []string) error {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	warranty, err := s.ReadWarranty(ctx, serial)
+	if err != nil {
+		return err
+	}
+	if clientID != warranty.Holder {
+		return fmt.Errorf("only the warranty holder can file a claim for serial %s", serial)
+	}
+	if claimID == "" || description == "" {
+		return fmt.Errorf("claim ID and description must be set")
+	}
+	if warranty.PaidOut >= warranty.Coverage {
+		return fmt.Errorf("the warranty for serial %s has no coverage left", serial)
+	}
+	if len(evidenceHashes) == 0 {
+		return fmt.Errorf("at least one piece of evidence must be provided")
+	}
+	for _, evidenceHash := range evidenceHashes {
+		hash, err := hex.DecodeString(evidenceHash)
+		if err != nil || len(hash) != 32 {
+			return fmt.Errorf("evidence hash %s is not a hex encoded SHA-256 digest", evidenceHash)
+		}
+	}
+
+	now, err := ledgerutil.TxTime(ctx)
+	if err != nil {
+		return err
+	}
+	expiresAt, err := time.Parse(time.RFC3339, warranty.ExpiresAt)
+	if err != nil {
+		return err
+	}
+	if now.After(expiresAt) {
+		return fmt.Errorf("the warranty for serial %s expired at %s", serial, warranty.ExpiresAt)
+	}
+
+	existing, err := _getClaim(ctx, claimID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("the claim %s already exists", claimID)
+	}
+
+	indexKey, err := ctx.GetStub().CreateCompositeKey(serialClaimPrefix, []string{serial, claimID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = ctx.GetStub().PutState(indexKey, []byte{0x00})
+	if err != nil {
+		return fmt.Errorf("failed to put claim index: %v", err)
+	}
+
+	claim := Claim{
+		ObjectType:     claimPrefix,
+		ID:             claimID,
+		Serial:         serial,
+		Claimant:       clientID,
+		Description:    description,
+		EvidenceHashes: evidenceHashes,
+		FiledAt:        now.Format(time.RFC3339),
+		Status:         claimFiled,
+	}
+	return _putClaim(ctx, &claim, 0)
+}
+
+// AssignRepairer is called by the manufacturer to send a claim to a repairer org. A claim can be
+// reassigned until the repair is reported.
+func (s *SmartContract) AssignRepairer(ctx contractapi.TransactionContextInterface, claimID string, repairerOrg string) error {
+	claim, warranty, err := s._readClaimAndWarranty(ctx, claimID)
+	if err != nil {
+		return err
+	}
+
+	err = _requireOrg(ctx, warranty.ManufacturerOrg, "assign repairers")
+	if err != nil {
+		return err
+	}
+	if claim.Status != claimFiled && claim.Status != claimAssigned {
+		return fmt.Errorf("claim %s is %s and cannot be assigned", claimID, claim.Status)
+	}
+	if repairerOrg == "" {
+		return fmt.Errorf("repairer org must be set")
+	}
+
+	claim.RepairerOrg = repairerOrg
+	claim.Status = claimAssigned
+	return _putClaim(ctx, claim, 0)
+}
+
+// ReportRepair is called by a client of the assigned repairer org once the work is done. The
+// submitting client is paid the repair cost if the manufacturer resolves the claim as a repair.
+// reportHash is the hex SHA-256 digest of the repair report kept off chain.
+func (s *SmartContract) ReportRepair(ctx contractapi.TransactionContextInterface, claimID string, repairCost int, reportHash string) error {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	claim, err := s.ReadClaim(ctx, claimID)
+	if err != nil {
+		return err
+	}
+	if claim.Status != claimAssigned {
+		return fmt.Errorf("claim %s is %s, only assigned claims can be repaired", claimID, claim.Status)
+	}
+
+	err = _requireOrg(ctx, claim.RepairerOrg, "report repairs")
+	if err != nil {
+		return err
+	}
+	if repairCost < 0 {
+		return fmt.Errorf("repair cost must not be negative")
+	}
+	hash, err := hex.DecodeString(reportHash)
+	if err != nil || len(hash) != 32 {
+		return fmt.Errorf("report hash %s is not a hex encoded SHA-256 digest", reportHash)
+	}
+
+	claim.Repairer = clientID
+	claim.RepairCost = repairCost
+	claim.RepairReport = reportHash
+	claim.Status = claimRepaired
+	return _putClaim(ctx, claim, repairCost)
+}
+
+// ResolveClaim is called by the manufacturer to settle a claim. A REPAIR resolution pays the
+// reported repair cost to the repairer, a REFUND pays refundAmount to the claimant. Payouts
+// are transferred from the submitting manufacturer client's token account and count against
+// the remaining coverage of the warranty.
+func (s *SmartContract) ResolveClaim(ctx contractapi.TransactionContextInterface, claimID string, resolution string, refundAmount int) error {
+	claim, warranty, err := s._readClaimAndWarranty(ctx, claimID)
+	if err != nil {
+		return err
+	}
+
+	err = _requireOrg(ctx, warranty.ManufacturerOrg, "resolve claims")
+	if err != nil {
+		return err
+	}
+
+	var payee string
+	var amount int
+	switch resolution {
+	case resolutionRepair:
+		if claim.Status != claimRepaired {
+			return fmt.Errorf("claim %s is %s, a repair must be reported before it is resolved as a repair", claimID, claim.Status)
+		}
+		payee = claim.Repairer
+		amount = claim.RepairCost
+	case resolutionRefund:
+		if claim.Status == claimResolved || claim.Status == claimRejected {
+			return fmt.Errorf("claim %s is already %s", claimID, claim.Status)
+		}
+		if refundAmount <= 0 {
+			return fmt.Errorf("refund amount must be a positive integer")
+		}
+		payee = claim.Claimant
+		amount = refundAmount
+	default:
+		return fmt.Errorf("resolution must be %s or %s", resolutionRepair, resolutionRefund)
+	}
+
+	if amount > warranty.Coverage-warranty.PaidOut {
+		return fmt.Errorf("payout %d exceeds the remaining coverage %d", amount, warranty.Coverage-warranty.PaidOut)
+	}
+
+	if amount > 0 {
+		args := [][]byte{[]byte("Transfer"), []byte(payee), []byte(strconv.Itoa(amount))}
+		response := ctx.GetStub().InvokeChaincode(warranty.TokenChaincode, args, "")
+		if response.Status != shim.OK {
+			return fmt.Errorf("failed to pay claim %s on %s: %s", claimID, warranty.TokenChaincode, response.Message)
+		}
+
+		warranty.PaidOut += amount
+		err = _putWarranty(ctx, warranty)
+		if err != nil {
+			return err
+		}
+		claim.PaymentTxID = ctx.GetStub().GetTxID()
+	}
+
+	claim.Resolution = resolution
+	claim.PayoutAmount = amount
+	claim.Status = claimResolved
+	return _putClaim(ctx, claim, amount)
+}
+
+// RejectClaim is called by the manufacturer to reject a claim that has not been resolved,
+// for example because the fault is not covered
+func (s *SmartContract) RejectClaim(ctx contractapi.TransactionContextInterface, claimID string, reason string) error {
+	claim, warranty, err := s._readClaimAndWarranty(ctx, claimID)
+	if err != nil {
+		return err
+	}
+
+	err = _requireOrg(ctx, warranty.ManufacturerOrg, "reject claims")
+	if err != nil {
+		return err
+	}
+	if claim.Status == claimResolved || claim.Status == claimRejected {
+		return fmt.Errorf("claim %s is already %s", claimID, claim.Status)
+	}
+	if reason == "" {
+		return fmt.Errorf("a reason for the rejection must be given")
+	}
+
+	claim.RejectionReason = reason
+	claim.Status = claimRejected
+	return _putClaim(ctx, claim, 0)
+}
+
+// _readClaimAndWarranty reads a claim together with the warranty it was filed against
+func (s *SmartContract) _readClaimAndWarranty(ctx contractapi.TransactionContextInterface, claimID string) (*Claim, *Warranty, error) {
+	claim, err := s.ReadClaim(ctx, claimID)
+	if err != nil {
+		return nil, nil, err
+	}
+	warranty, err := s.ReadWarranty(ctx, claim.Serial)
+	if err != nil {
+		return nil, nil, err
+	}
+	return claim, warranty, nil
+}
+
+// _requireOrg checks the client belongs to the given org
+func _requireOrg(ctx contractapi.TransactionContextInterface, org string, action string) error {
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+	if clientMSPID != org {
+		return fmt.Errorf("client from %s is not authorized to %s on this warranty", clientMSPID, action)
+	}
+	return nil
+}
+
+// _getWarranty reads a warranty, returning nil when the serial has none
+func _getWarranty(ctx contractapi.TransactionContextInterface, serial string) (*Warranty, error) {
+	warrantyKey, err := ctx.GetStub().CreateCompositeKey(warrantyPrefix, []string{serial})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	warrantyJSON, err := ctx.GetStub().GetState(warrantyKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if warrantyJSON == nil {
+		return nil, nil
+	}
+
+	var warranty Warranty
+	err = json.Unmarshal(warrantyJSON, &warranty)
+	if err != nil {
+		return nil, err
+	}
+	return &warranty, nil
+}
+
+// _putWarranty writes the warranty to the world state
+func _putWarranty(ctx contractapi.TransactionContextInterface, warranty *Warranty) error {
+	warrantyKey, err := ctx.GetStub().CreateCompositeKey(warrantyPrefix, []string{warranty.Serial})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	warrantyJSON, err := json.Marshal(warranty)
+	if err != nil {
+		return fmt.Errorf("failed to marshal warranty: %v", err)
+	}
+	err = ctx.GetStub().PutState(warrantyKey, warrantyJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put warranty %s: %v", warranty.Serial, err)
+	}
+	return nil
+}
+
+// _getClaim reads a claim, returning nil when it does not exist
+func _getClaim(ctx contractapi.TransactionContextInterface, claimID string) (*Claim, error) {
+	claimKey, err := ctx.GetStub().CreateCompositeKey(claimPrefix, []string{claimID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+	claimJSON, err := ctx.GetStub().GetState(claimKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if claimJSON == nil {
+		return nil, nil
+	}
+
+	var claim Claim
+	err = json.Unmarshal(claimJSON, &claim)
+	if err != nil {
+		return nil, err
+	}
+	return &claim, nil
+}
+
+// _putClaim writes the claim and emits a status event for it
+func _putClaim(ctx contractapi.TransactionContextInterface, claim *Claim, amount int) error {
+	claimKey, err := ctx.GetStub().CreateCompositeKey(claimPrefix, []string{claim.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	claimJSON, err := json.Marshal(claim)
+	if err != nil {
+		return fmt.Errorf("failed to marshal claim: %v", err)
+	}
+	err = ctx.GetStub().PutState(claimKey, claimJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put claim %s: %v", claim.ID, err)
+	}
+
+	eventJSON, err := json.Marshal(event{claim.ID, claim.Serial, claim.Status, amount})
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	err = ctx.GetStub().SetEvent("ClaimStatus", eventJSON)
+	if err != nil {
+		return fmt.Errorf("failed to set event: %v", err)
+	}
+	return nil
+}
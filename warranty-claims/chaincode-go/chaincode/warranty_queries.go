@@ -0,0 +1,68 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// ReadWarranty returns the warranty registered for a product serial
+func (s *SmartContract) ReadWarranty(ctx contractapi.TransactionContextInterface, serial string) (*Warranty, error) {
+	warranty, err := _getWarranty(ctx, serial)
+	if err != nil {
+		return nil, err
+	}
+	if warranty == nil {
+		return nil, fmt.Errorf("the warranty for serial %s does not exist", serial)
+	}
+	return warranty, nil
+}
+
+// ReadClaim returns the claim stored in the world state with the given ID
+func (s *SmartContract) ReadClaim(ctx contractapi.TransactionContextInterface, claimID string) (*Claim, error) {
+	claim, err := _getClaim(ctx, claimID)
+	if err != nil {
+		return nil, err
+	}
+	if claim == nil {
+		return nil, fmt.Errorf("the claim %s does not exist", claimID)
+	}
+	return claim, nil
+}
+
+// GetClaimsBySerial returns every claim filed against the warranty of a product serial
+func (s *SmartContract) GetClaimsBySerial(ctx contractapi.TransactionContextInterface, serial string) ([]*Claim, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(serialClaimPrefix, []string{serial})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get claims for serial %s: %v", serial, err)
+	}
+	defer resultsIterator.Close()
+
+	var claims []*Claim
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(response.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split composite key: %v", err)
+		}
+		if len(keyParts) != 2 {
+			return nil, fmt.Errorf("unexpected index key %s", response.Key)
+		}
+
+		claim, err := s.ReadClaim(ctx, keyParts[1])
+		if err != nil {
+			return nil, err
+		}
+		claims = append(claims, claim)
+	}
+
+	return claims, nil
+}
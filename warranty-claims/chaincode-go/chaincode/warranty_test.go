@@ -0,0 +1,174 @@
+package chaincode
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+)
+
+const (
+	manufacturerMSPID = "Org1MSP"
+	holderMSPID       = "Org2MSP"
+	repairerMSPID     = "Org3MSP"
+)
+
+var evidence = []string{strings.Repeat("ab", 32)}
+
+// fakeToken stands in for the token chaincode. Transfers are paid from the payer's account.
+type fakeToken struct {
+	payer    string
+	balances map[string]int
+}
+
+func (f *fakeToken) invoke(args [][]byte) pb.Response {
+	if string(args[0]) != "Transfer" {
+		return shim.Error("unexpected function " + string(args[0]))
+	}
+	amount, err := strconv.Atoi(string(args[2]))
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if f.balances[f.payer] < amount {
+		return shim.Error(fmt.Sprintf("client account %s has insufficient funds", f.payer))
+	}
+	f.balances[f.payer] -= amount
+	f.balances[string(args[1])] += amount
+	return shim.Success(nil)
+}
+
+func checkError(t *testing.T, err error, expected string) {
+	t.Helper()
+	if expected == "" && err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected != "" && (err == nil || !strings.Contains(err.Error(), expected)) {
+		t.Fatalf("expected error containing %q, got %v", expected, err)
+	}
+}
+
+func checkClaim(t *testing.T, stub *fakeStub, status string) *Claim {
+	t.Helper()
+	claim, err := new(SmartContract).ReadClaim(newContext(stub, "holder", holderMSPID), "claim1")
+	if err != nil {
+		t.Fatalf("failed to read claim: %v", err)
+	}
+	if claim.Status != status {
+		t.Fatalf("expected claim %s, got %s", status, claim.Status)
+	}
+	return claim
+}
+
+// fileClaim registers a 12 month warranty covering 500 for the holder and files claim1 against it.
+// The manufacturer holds 1000 tokens.
+func fileClaim(t *testing.T, stub *fakeStub) *fakeToken {
+	t.Helper()
+	token := &fakeToken{payer: "manufacturer", balances: map[string]int{"manufacturer": 1000}}
+	stub.chaincodes[defaultTokenChaincode] = token.invoke
+
+	contract := new(SmartContract)
+	checkError(t, contract.RegisterWarranty(newContext(stub, "manufacturer", manufacturerMSPID), "SN1", "Kettle", "holder", 12, 500, ""), "")
+	checkError(t, contract.FileClaim(newContext(stub, "holder", holderMSPID), "claim1", "SN1", "does not boil", evidence), "")
+	return token
+}
+
+func TestRegisterWarranty(t *testing.T) {
+	tests := []struct {
+		name     string
+		serial   string
+		months   int
+		coverage int
+		expected string
+	}{
+		{"warranty", "SN2", 24, 500, ""},
+		{"no duration", "SN2", 0, 500, "warranty duration must be a positive number of months"},
+		{"no coverage", "SN2", 24, 0, "coverage must be a positive integer"},
+		{"existing warranty", "SN1", 24, 500, "a warranty for serial SN1 already exists"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stub := newFakeStub()
+			fileClaim(t, stub)
+
+			err := new(SmartContract).RegisterWarranty(newContext(stub, "manufacturer", manufacturerMSPID), test.serial, "Kettle", "holder", test.months, test.coverage, "")
+			checkError(t, err, test.expected)
+		})
+	}
+}
+
+func TestFileClaim(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	fileClaim(t, stub)
+
+	err := contract.FileClaim(newContext(stub, "thief", holderMSPID), "claim2", "SN1", "broken", evidence)
+	checkError(t, err, "only the warranty holder can file a claim for serial SN1")
+	err = contract.FileClaim(newContext(stub, "holder", holderMSPID), "claim2", "SN1", "broken", []string{"ab"})
+	checkError(t, err, "evidence hash ab is not a hex encoded SHA-256 digest")
+
+	// the warranty and its claims follow the product to the new owner
+	err = contract.TransferWarranty(newContext(stub, "thief", holderMSPID), "SN1", "thief")
+	checkError(t, err, "only the warranty holder can transfer the warranty for serial SN1")
+	checkError(t, contract.TransferWarranty(newContext(stub, "holder", holderMSPID), "SN1", "buyer"), "")
+
+	stub.txCount += 366 * 24 * 60 * 60
+	err = contract.FileClaim(newContext(stub, "buyer", holderMSPID), "claim2", "SN1", "broken", evidence)
+	checkError(t, err, "the warranty for serial SN1 expired at 2021-09-13T12:26:41Z")
+}
+
+func TestResolveRepair(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	token := fileClaim(t, stub)
+
+	err := contract.AssignRepairer(newContext(stub, "holder", holderMSPID), "claim1", repairerMSPID)
+	checkError(t, err, "client from Org2MSP is not authorized to assign repairers on this warranty")
+	checkError(t, contract.AssignRepairer(newContext(stub, "manufacturer", manufacturerMSPID), "claim1", repairerMSPID), "")
+
+	err = contract.ReportRepair(newContext(stub, "holder", holderMSPID), "claim1", 120, evidence[0])
+	checkError(t, err, "client from Org2MSP is not authorized to report repairs on this warranty")
+	checkError(t, contract.ReportRepair(newContext(stub, "repairer", repairerMSPID), "claim1", 120, evidence[0]), "")
+
+	err = contract.ResolveClaim(newContext(stub, "repairer", repairerMSPID), "claim1", resolutionRepair, 0)
+	checkError(t, err, "client from Org3MSP is not authorized to resolve claims on this warranty")
+	checkError(t, contract.ResolveClaim(newContext(stub, "manufacturer", manufacturerMSPID), "claim1", resolutionRepair, 0), "")
+
+	claim := checkClaim(t, stub, claimResolved)
+	if claim.PayoutAmount != 120 || token.balances["repairer"] != 120 || token.balances["manufacturer"] != 880 {
+		t.Fatalf("unexpected claim %+v with balances %v", claim, token.balances)
+	}
+	warranty, err := contract.ReadWarranty(newContext(stub, "holder", holderMSPID), "SN1")
+	checkError(t, err, "")
+	if warranty.PaidOut != 120 {
+		t.Fatalf("expected 120 paid out, got %d", warranty.PaidOut)
+	}
+
+	err = contract.RejectClaim(newContext(stub, "manufacturer", manufacturerMSPID), "claim1", "not covered")
+	checkError(t, err, "claim claim1 is already RESOLVED")
+}
+
+func TestResolveRefund(t *testing.T) {
+	contract := new(SmartContract)
+	stub := newFakeStub()
+	token := fileClaim(t, stub)
+
+	err := contract.ResolveClaim(newContext(stub, "manufacturer", manufacturerMSPID), "claim1", resolutionRefund, 501)
+	checkError(t, err, "payout 501 exceeds the remaining coverage 500")
+
+	token.balances["manufacturer"] = 100
+	err = contract.ResolveClaim(newContext(stub, "manufacturer", manufacturerMSPID), "claim1", resolutionRefund, 500)
+	checkError(t, err, "failed to pay claim claim1 on token_erc20: client account manufacturer has insufficient funds")
+	checkClaim(t, stub, claimFiled)
+
+	token.balances["manufacturer"] = 500
+	checkError(t, contract.ResolveClaim(newContext(stub, "manufacturer", manufacturerMSPID), "claim1", resolutionRefund, 500), "")
+	if token.balances["holder"] != 500 {
+		t.Fatalf("unexpected balances %v", token.balances)
+	}
+
+	err = contract.FileClaim(newContext(stub, "holder", holderMSPID), "claim2", "SN1", "still broken", evidence)
+	checkError(t, err, "the warranty for serial SN1 has no coverage left")
+}
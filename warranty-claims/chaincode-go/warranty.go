@@ -0,0 +1,23 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"log"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/warranty-claims/chaincode-go/chaincode"
+)
+
+func main() {
+	warrantyChaincode, err := contractapi.NewChaincode(&chaincode.SmartContract{})
+	if err != nil {
+		log.Panicf("Error creating warranty-claims chaincode: %v", err)
+	}
+
+	if err := warrantyChaincode.Start(); err != nil {
+		log.Panicf("Error starting warranty-claims chaincode: %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Config is the configuration file of the service
+type Config struct {
+	// Listen is the address the service listens on, e.g. ":8082"
+	Listen string `json:"listen"`
+	// Wallet is the wallet directory holding the Fabric identity the events are received as
+	Wallet string `json:"wallet"`
+	// Identity is the label of the identity in the wallet
+	Identity string `json:"identity"`
+	// Profile is the connection profile of the identity's org
+	Profile string `json:"profile"`
+	// Peer is the peer of the profile to connect to, the first peer of the org when empty
+	Peer           string `json:"peer,omitempty"`
+	Channel        string `json:"channel"`
+	TokenChaincode string `json:"tokenChaincode"`
+	AssetChaincode string `json:"assetChaincode"`
+	// AllowedOrigins are the origins of the web front-ends allowed to connect. When empty, only
+	// pages served from the host of the service may connect, as well as clients sending no Origin.
+	AllowedOrigins []string     `json:"allowedOrigins"`
+	Subscribers    []Subscriber `json:"subscribers"`
+}
+
+// Subscriber is a client allowed to subscribe to events
+type Subscriber struct {
+	Name string `json:"name"`
+	// APIKeySHA256 is the hex SHA-256 hash of the subscriber's API key, so the configuration file
+	// does not hold the keys themselves
+	APIKeySHA256 string `json:"apiKeySHA256"`
+}
+
+// LoadConfig reads the configuration file at path. Relative wallet and profile paths are resolved
+// against the directory of the file.
+func LoadConfig(path string) (*Config, error) {
+	configJSON, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %v", err)
+	}
+	config := &Config{
+		Listen:         ":8082",
+		Wallet:         "wallet",
+		Channel:        "mychannel",
+		TokenChaincode: "token_erc20",
+		AssetChaincode: "secured",
+	}
+	err = json.Unmarshal(configJSON, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config: %v", err)
+	}
+	if config.Identity == "" || config.Profile == "" {
+		return nil, fmt.Errorf("config must have an identity and profile")
+	}
+
+	dir := filepath.Dir(path)
+	config.Wallet = resolve(dir, config.Wallet)
+	config.Profile = resolve(dir, config.Profile)
+	keys := make(map[string]bool)
+	for i, subscriber := range config.Subscribers {
+		if subscriber.Name == "" {
+			return nil, fmt.Errorf("subscriber %d must have a name", i)
+		}
+		key, err := hex.DecodeString(subscriber.APIKeySHA256)
+		if err != nil || len(key) != sha256.Size {
+			return nil, fmt.Errorf("API key hash of subscriber %s is not a hex SHA-256 hash", subscriber.Name)
+		}
+		if keys[subscriber.APIKeySHA256] {
+			return nil, fmt.Errorf("API key of subscriber %s is used by another subscriber", subscriber.Name)
+		}
+		keys[subscriber.APIKeySHA256] = true
+	}
+	if len(config.Subscribers) == 0 {
+		return nil, fmt.Errorf("config has no subscribers")
+	}
+	return config, nil
+}
+
+// HashAPIKey returns the hex SHA-256 hash of an API key, as stored in the configuration
+func HashAPIKey(apiKey string) string {
+	hash := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(hash[:])
+}
+
+func resolve(dir string, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
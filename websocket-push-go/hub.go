@@ -0,0 +1,235 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// The kinds of topics, followed by an account ID, an asset ID or * for every account or asset
+const (
+	accountTopic = "account:"
+	assetTopic   = "asset:"
+	allTopic     = "*"
+)
+
+// maxTopics is the number of topics a subscriber may subscribe to at once
+const maxTopics = 100
+
+// sendBufferSize is the number of messages queued for a subscriber. A subscriber falling further
+// behind is disconnected rather than slowing down the others.
+const sendBufferSize = 256
+
+// pushEvent is a chaincode event as pushed to subscribers, with the payload as the chaincode set it
+type pushEvent struct {
+	Type        string          `json:"type"`
+	Chaincode   string          `json:"chaincode"`
+	Name        string          `json:"name"`
+	BlockNumber uint64          `json:"blockNumber"`
+	TxID        string          `json:"txID"`
+	Payload     json.RawMessage `json:"payload"`
+	// topics are the topics the event is published on
+	topics []string
+}
+
+// eventPayload holds the fields of the token and asset event payloads the topics are read from
+type eventPayload struct {
+	From     string   `json:"from"`
+	To       string   `json:"to"`
+	AssetID  string   `json:"assetID"`
+	AssetIDs []string `json:"assetIDs"`
+}
+
+// eventTopics returns the topics of an event of the token or asset chaincode: the accounts a
+// Transfer moves tokens between or an Approval is given by and to, and the assets an asset event
+// changed. Events without a JSON payload have no topics.
+func eventTopics(payload []byte) []string {
+	var fields eventPayload
+	if json.Unmarshal(payload, &fields) != nil {
+		return nil
+	}
+	var topics []string
+	for _, kind := range []struct {
+		prefix string
+		ids    []string
+	}{
+		{accountTopic, []string{fields.From, fields.To}},
+		{assetTopic, append([]string{fields.AssetID}, fields.AssetIDs...)},
+	} {
+		found := false
+		for _, id := range kind.ids {
+			if id != "" {
+				topics = append(topics, kind.prefix+id)
+				found = true
+			}
+		}
+		if found {
+			topics = append(topics, kind.prefix+allTopic)
+		}
+	}
+	return topics
+}
+
+// checkTopic rejects topics that are not an account or asset topic
+func checkTopic(topic string) error {
+	for _, kind := range []string{accountTopic, assetTopic} {
+		if strings.HasPrefix(topic, kind) && len(topic) > len(kind) {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid topic %q, topics are account:<account ID>, asset:<asset ID>, account:* and asset:*", topic)
+}
+
+// subscriber is a connected WebSocket client and the topics it subscribed to
+type subscriber struct {
+	name string
+	// send queues the messages to write to the client. It is closed when the subscriber is removed.
+	send   chan []byte
+	topics map[string]bool
+	// slow is set when the subscriber was removed because its queue was full
+	slow bool
+}
+
+// hub publishes events to the subscribers of their topics
+type hub struct {
+	mu          sync.Mutex
+	subscribers map[*subscriber]bool
+}
+
+func newHub() *hub {
+	return &hub{subscribers: make(map[*subscriber]bool)}
+}
+
+// add registers a new subscriber without topics
+func (h *hub) add(name string) *subscriber {
+	s := &subscriber{name: name, send: make(chan []byte, sendBufferSize), topics: make(map[string]bool)}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.subscribers[s] = true
+	return s
+}
+
+// remove unregisters a subscriber and closes its send queue, once
+func (h *hub) remove(s *subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.removeLocked(s)
+}
+
+func (h *hub) removeLocked(s *subscriber) {
+	if h.subscribers[s] {
+		delete(h.subscribers, s)
+		close(s.send)
+	}
+}
+
+// closeAll removes every subscriber, closing their connections, e.g. when the service stops.
+// Shutdown of the HTTP server does not close hijacked WebSocket connections.
+func (h *hub) closeAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for s := range h.subscribers {
+		h.removeLocked(s)
+	}
+}
+
+// subscribe adds topics to a subscriber, or removes them when unsubscribe is set, and returns its
+// topics sorted
+func (h *hub) subscribe(s *subscriber, topics []string, unsubscribe bool) ([]string, error) {
+	for _, topic := range topics {
+		if err := checkTopic(topic); err != nil {
+			return nil, err
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if unsubscribe {
+		for _, topic := range topics {
+			delete(s.topics, topic)
+		}
+	} else {
+		added := 0
+		for _, topic := range topics {
+			if !s.topics[topic] {
+				added++
+			}
+		}
+		if len(s.topics)+added > maxTopics {
+			return nil, fmt.Errorf("a subscriber may subscribe to at most %d topics", maxTopics)
+		}
+		for _, topic := range topics {
+			s.topics[topic] = true
+		}
+	}
+	return sortedTopics(s.topics), nil
+}
+
+// publish queues an event for every subscriber of one of its topics. Subscribers whose queue is full
+// are removed, which closes their connection.
+func (h *hub) publish(event *pushEvent) {
+	if len(event.topics) == 0 {
+		return
+	}
+	message, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("failed to marshal event %s of transaction %s: %v", event.Name, event.TxID, err)
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for s := range h.subscribers {
+		if s.subscribed(event.topics) {
+			h.queueLocked(s, message)
+		}
+	}
+}
+
+// queue queues a message for a subscriber that was not removed
+func (h *hub) queue(s *subscriber, message []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.queueLocked(s, message)
+}
+
+// queueLocked queues a message for a subscriber that was not removed, removing it when its queue is
+// full. The hub's lock is held.
+func (h *hub) queueLocked(s *subscriber, message []byte) {
+	if !h.subscribers[s] {
+		return
+	}
+	select {
+	case s.send <- message:
+	default:
+		log.Printf("subscriber %s is too slow, disconnecting it", s.name)
+		s.slow = true
+		h.removeLocked(s)
+	}
+}
+
+// subscribed reports whether the subscriber subscribed to one of the topics. The hub's lock is held.
+func (s *subscriber) subscribed(topics []string) bool {
+	for _, topic := range topics {
+		if s.topics[topic] {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedTopics(topics map[string]bool) []string {
+	sorted := make([]string, 0, len(topics))
+	for topic := range topics {
+		sorted = append(sorted, topic)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
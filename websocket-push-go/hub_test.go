@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEventTopics(t *testing.T) {
+	for _, test := range []struct {
+		payload string
+		topics  []string
+	}{
+		{`{"from":"acc1","to":"acc2","value":10}`, []string{"account:acc1", "account:acc2", "account:*"}},
+		{`{"from":"0x0","to":"acc2","value":10}`, []string{"account:0x0", "account:acc2", "account:*"}},
+		{`{"assetID":"asset1","ownerOrg":"Org1MSP"}`, []string{"asset:asset1", "asset:*"}},
+		{`{"assetIDs":["asset1","asset2"]}`, []string{"asset:asset1", "asset:asset2", "asset:*"}},
+		{`{"schemas":[]}`, nil},
+		{`not JSON`, nil},
+	} {
+		if topics := eventTopics([]byte(test.payload)); !reflect.DeepEqual(topics, test.topics) {
+			t.Errorf("topics of %s are %v, want %v", test.payload, topics, test.topics)
+		}
+	}
+}
+
+func TestHubPublish(t *testing.T) {
+	h := newHub()
+	buyer := h.add("buyer")
+	auditor := h.add("auditor")
+	if _, err := h.subscribe(buyer, []string{"asset:asset1", "account:acc1"}, false); err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	topics, err := h.subscribe(buyer, []string{"account:acc1"}, true)
+	if err != nil || !reflect.DeepEqual(topics, []string{"asset:asset1"}) {
+		t.Errorf("topics after unsubscribing are %v, error %v", topics, err)
+	}
+	if _, err := h.subscribe(auditor, []string{"account:*"}, false); err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+
+	h.publish(&pushEvent{Name: "Transfer", TxID: "tx1", topics: eventTopics([]byte(`{"from":"acc1","to":"acc2"}`))})
+	h.publish(&pushEvent{Name: "AssetUpdated", TxID: "tx2", topics: eventTopics([]byte(`{"assetID":"asset1"}`))})
+	h.publish(&pushEvent{Name: "AssetUpdated", TxID: "tx3", topics: eventTopics([]byte(`{"assetID":"asset2"}`))})
+
+	for _, test := range []struct {
+		subscriber *subscriber
+		txIDs      []string
+	}{{buyer, []string{"tx2"}}, {auditor, []string{"tx1"}}} {
+		var txIDs []string
+		for len(test.subscriber.send) > 0 {
+			message := string(<-test.subscriber.send)
+			for _, txID := range []string{"tx1", "tx2", "tx3"} {
+				if strings.Contains(message, `"txID":"`+txID+`"`) {
+					txIDs = append(txIDs, txID)
+				}
+			}
+		}
+		if !reflect.DeepEqual(txIDs, test.txIDs) {
+			t.Errorf("%s received %v, want %v", test.subscriber.name, txIDs, test.txIDs)
+		}
+	}
+}
+
+func TestHubRejectsInvalidSubscriptions(t *testing.T) {
+	h := newHub()
+	s := h.add("client")
+	for _, topic := range []string{"balance:acc1", "account:", ""} {
+		if _, err := h.subscribe(s, []string{topic}, false); err == nil || !strings.Contains(err.Error(), "invalid topic") {
+			t.Errorf("expected invalid topic error for %q, got %v", topic, err)
+		}
+	}
+
+	topics := make([]string, maxTopics+1)
+	for i := range topics {
+		topics[i] = fmt.Sprintf("asset:asset%d", i)
+	}
+	if _, err := h.subscribe(s, topics, false); err == nil || !strings.Contains(err.Error(), "at most") {
+		t.Errorf("expected too many topics error, got %v", err)
+	}
+	if _, err := h.subscribe(s, topics[:maxTopics], false); err != nil {
+		t.Errorf("failed to subscribe to %d topics: %v", maxTopics, err)
+	}
+}
+
+func TestHubRemovesSlowSubscriber(t *testing.T) {
+	h := newHub()
+	s := h.add("slow")
+	if _, err := h.subscribe(s, []string{"account:*"}, false); err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	for i := 0; i <= sendBufferSize; i++ {
+		h.publish(&pushEvent{Name: "Transfer", topics: []string{"account:*"}})
+	}
+	if !s.slow || h.subscribers[s] {
+		t.Fatalf("slow subscriber was not removed")
+	}
+	received := 0
+	for range s.send {
+		received++
+	}
+	if received != sendBufferSize {
+		t.Errorf("received %d events before the queue was closed, want %d", received, sendBufferSize)
+	}
+}
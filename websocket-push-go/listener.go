@@ -0,0 +1,107 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+)
+
+const (
+	minRetryDelay = time.Second
+	maxRetryDelay = time.Minute
+)
+
+// listener publishes the events of one chaincode
+type listener struct {
+	hub       *hub
+	network   *client.Network
+	chaincode string
+	// lastBlock is the block of the last event published and published the transactions of that
+	// block whose events were published, so a stream reopened at lastBlock skips them
+	lastBlock *uint64
+	published map[string]bool
+}
+
+// run publishes the events of the chaincode until ctx is done. The stream starts with the next
+// committed block. When it fails, e.g. because the peer went down, it is reopened at the block of the
+// last event published after a growing delay, so subscribers miss no event committed in between.
+func (l *listener) run(ctx context.Context) {
+	delay := minRetryDelay
+	for {
+		published, err := l.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if published {
+			delay = minRetryDelay
+		}
+		log.Printf("%s: event listening stopped, resuming in %s: %v", l.chaincode, delay, err)
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return
+		}
+		delay *= 2
+		if delay > maxRetryDelay {
+			delay = maxRetryDelay
+		}
+	}
+}
+
+// listen publishes events until the event stream ends. It reports whether any event was published.
+func (l *listener) listen(ctx context.Context) (bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var options []client.ChaincodeEventsOption
+	if l.lastBlock != nil {
+		options = append(options, client.WithStartBlock(*l.lastBlock))
+	}
+	events, err := l.network.ChaincodeEvents(ctx, l.chaincode, options...)
+	if err != nil {
+		return false, fmt.Errorf("failed to start chaincode event listening: %v", err)
+	}
+
+	published := false
+	for event := range events {
+		if l.lastBlock != nil && event.BlockNumber == *l.lastBlock && l.published[event.TransactionID] {
+			continue
+		}
+		if l.lastBlock == nil || event.BlockNumber != *l.lastBlock {
+			blockNumber := event.BlockNumber
+			l.lastBlock = &blockNumber
+			l.published = make(map[string]bool)
+		}
+		l.published[event.TransactionID] = true
+
+		l.hub.publish(&pushEvent{
+			Type:        "event",
+			Chaincode:   event.ChaincodeName,
+			Name:        event.EventName,
+			BlockNumber: event.BlockNumber,
+			TxID:        event.TransactionID,
+			Payload:     jsonPayload(event.Payload),
+			topics:      eventTopics(event.Payload),
+		})
+		published = true
+	}
+	return published, fmt.Errorf("event stream closed")
+}
+
+// jsonPayload returns the payload of an event as JSON, a JSON string when the chaincode set another
+// payload
+func jsonPayload(payload []byte) []byte {
+	if json.Valid(payload) {
+		return payload
+	}
+	quoted, _ := json.Marshal(string(payload))
+	return quoted
+}
@@ -0,0 +1,95 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// websocket-push-go pushes the chaincode events of the token and secured agreement asset
+// chaincodes to WebSocket subscribers authenticated with an API key, filtered by the accounts and
+// assets they subscribed to.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric-samples/internal/appclient"
+)
+
+var (
+	configPath = flag.String("config", "config.json", "configuration file")
+	hashKey    = flag.String("hash-key", "", "print the hash of an API key for the configuration and exit")
+)
+
+func main() {
+	flag.Parse()
+	if *hashKey != "" {
+		fmt.Println(HashAPIKey(*hashKey))
+		return
+	}
+
+	config, err := LoadConfig(*configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	profile, err := appclient.LoadProfile(config.Profile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	wallet, err := appclient.OpenWallet(config.Wallet)
+	if err != nil {
+		log.Fatal(err)
+	}
+	connection, err := appclient.ConnectWallet(profile, config.Peer, wallet, config.Identity)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer connection.Close()
+	network := connection.GetNetwork(config.Channel)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	h := newHub()
+	var listeners sync.WaitGroup
+	chaincodes := []string{config.TokenChaincode}
+	if config.AssetChaincode != config.TokenChaincode {
+		// the token and asset bundle deploys both contracts as one chaincode
+		chaincodes = append(chaincodes, config.AssetChaincode)
+	}
+	for _, chaincode := range chaincodes {
+		l := &listener{hub: h, network: network, chaincode: chaincode}
+		listeners.Add(1)
+		go func() {
+			defer listeners.Done()
+			l.run(ctx)
+		}()
+	}
+
+	httpServer := &http.Server{
+		Addr:              config.Listen,
+		Handler:           newServer(config, h),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		httpServer.Shutdown(shutdownCtx)
+		h.closeAll()
+	}()
+
+	log.Printf("pushing events of %s and %s on %s as %s of %s to subscribers on %s",
+		config.TokenChaincode, config.AssetChaincode, config.Channel, config.Identity, profile.MSPID(), config.Listen)
+	err = httpServer.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
+	listeners.Wait()
+}
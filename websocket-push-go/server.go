@@ -0,0 +1,200 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/hyperledger/fabric-samples/internal/ledgerutil/errcode"
+)
+
+// apiKeyHeader is the request header carrying the API key of the subscriber. Browsers cannot set
+// headers on WebSocket requests, so the key may be passed as the apiKey query parameter instead.
+const (
+	apiKeyHeader = "X-API-Key"
+	apiKeyParam  = "apiKey"
+)
+
+// codeUnauthenticated is the code of the error returned for a missing or unknown API key
+const codeUnauthenticated errcode.Code = "UNAUTHENTICATED"
+
+// Timeouts of the WebSocket connections. The service pings every client and closes the connection
+// when no pong arrived before the next ping.
+const (
+	writeTimeout   = 10 * time.Second
+	pongTimeout    = 60 * time.Second
+	pingInterval   = pongTimeout * 9 / 10
+	maxMessageSize = 64 << 10
+)
+
+// request is a message of a client, subscribing to or unsubscribing from topics
+type request struct {
+	Action string   `json:"action"`
+	Topics []string `json:"topics"`
+}
+
+// reply is a message of the service other than an event: the topics of the client after a request,
+// or the error of an invalid request
+type reply struct {
+	Type   string         `json:"type"`
+	Topics []string       `json:"topics,omitempty"`
+	Error  *errcode.Error `json:"error,omitempty"`
+}
+
+// server accepts the WebSocket connections of the subscribers
+type server struct {
+	hub         *hub
+	subscribers map[string]string
+	origins     map[string]bool
+	upgrader    websocket.Upgrader
+}
+
+func newServer(config *Config, h *hub) *server {
+	s := &server{hub: h, subscribers: make(map[string]string), origins: make(map[string]bool)}
+	for _, subscriber := range config.Subscribers {
+		s.subscribers[subscriber.APIKeySHA256] = subscriber.Name
+	}
+	for _, origin := range config.AllowedOrigins {
+		s.origins[origin] = true
+	}
+	s.upgrader = websocket.Upgrader{ReadBufferSize: 4096, WriteBufferSize: 4096}
+	if len(s.origins) > 0 {
+		s.upgrader.CheckOrigin = s.checkOrigin
+	}
+	return s
+}
+
+// checkOrigin accepts requests of the allowed origins, and of clients sending no Origin
+func (s *server) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	return origin == "" || s.origins[origin]
+}
+
+// ServeHTTP accepts WebSocket connections at /events from subscribers with a valid API key. The
+// topic query parameters, e.g. ?topic=asset:asset1, subscribe the new connection to them.
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/events" {
+		writeError(w, http.StatusNotFound, &errcode.Error{Code: errcode.CodeNotFound, Message: fmt.Sprintf("no endpoint at %s", r.URL.Path)})
+		return
+	}
+	apiKey := r.Header.Get(apiKeyHeader)
+	if apiKey == "" {
+		apiKey = r.URL.Query().Get(apiKeyParam)
+	}
+	name, ok := s.subscribers[HashAPIKey(apiKey)]
+	if !ok {
+		writeError(w, http.StatusUnauthorized, &errcode.Error{Code: codeUnauthenticated, Message: fmt.Sprintf("missing or unknown %s header or %s parameter", apiKeyHeader, apiKeyParam)})
+		return
+	}
+	for _, topic := range r.URL.Query()["topic"] {
+		if err := checkTopic(topic); err != nil {
+			writeError(w, http.StatusBadRequest, &errcode.Error{Code: errcode.CodeInvalidArgument, Message: err.Error()})
+			return
+		}
+	}
+
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// the upgrader wrote the error response
+		return
+	}
+	sub := s.hub.add(name)
+	go s.write(conn, sub)
+	s.read(conn, sub, r.URL.Query()["topic"])
+}
+
+// read handles the requests of a client until its connection fails or it closes it
+func (s *server) read(conn *websocket.Conn, sub *subscriber, initialTopics []string) {
+	defer s.hub.remove(sub)
+	conn.SetReadLimit(maxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(pongTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongTimeout))
+	})
+
+	topics, err := s.hub.subscribe(sub, initialTopics, false)
+	s.reply(sub, topics, err)
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				log.Printf("subscriber %s disconnected: %v", sub.name, err)
+			}
+			return
+		}
+		var req request
+		err = json.Unmarshal(message, &req)
+		if err != nil {
+			s.reply(sub, nil, fmt.Errorf("invalid request: %v", err))
+			continue
+		}
+		switch req.Action {
+		case "subscribe", "unsubscribe":
+			topics, err := s.hub.subscribe(sub, req.Topics, req.Action == "unsubscribe")
+			s.reply(sub, topics, err)
+		default:
+			s.reply(sub, nil, fmt.Errorf("unknown action %q, actions are subscribe and unsubscribe", req.Action))
+		}
+	}
+}
+
+// reply queues the topics of the subscriber, or the error of its request
+func (s *server) reply(sub *subscriber, topics []string, err error) {
+	message := reply{Type: "subscriptions", Topics: topics}
+	if err != nil {
+		message = reply{Type: "error", Error: &errcode.Error{Code: errcode.CodeInvalidArgument, Message: err.Error()}}
+	}
+	messageJSON, _ := json.Marshal(message)
+	s.hub.queue(sub, messageJSON)
+}
+
+// write writes the queued messages of a subscriber to its connection and pings it, until the
+// subscriber is removed or a write fails
+func (s *server) write(conn *websocket.Conn, sub *subscriber) {
+	ticker := time.NewTicker(pingInterval)
+	defer func() {
+		ticker.Stop()
+		conn.Close()
+	}()
+	for {
+		select {
+		case message, ok := <-sub.send:
+			conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			if !ok {
+				// removed by the hub, because the client disconnected, fell behind or the service stops
+				closeMessage := websocket.FormatCloseMessage(websocket.CloseGoingAway, "service stopping")
+				if sub.slow {
+					closeMessage = websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "subscriber too slow")
+				}
+				conn.WriteMessage(websocket.CloseMessage, closeMessage)
+				return
+			}
+			if err := conn.WriteMessage(websocket.TextMessage, message); err != nil {
+				s.hub.remove(sub)
+				return
+			}
+		case <-ticker.C:
+			conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				s.hub.remove(sub)
+				return
+			}
+		}
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, apiErr *errcode.Error) {
+	bodyJSON, _ := json.Marshal(struct {
+		Error *errcode.Error `json:"error"`
+	}{apiErr})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(bodyJSON)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func newTestServer(t *testing.T) (*hub, string) {
+	config := &Config{Subscribers: []Subscriber{{Name: "dashboard", APIKeySHA256: HashAPIKey("key1")}}}
+	h := newHub()
+	httpServer := httptest.NewServer(newServer(config, h))
+	t.Cleanup(httpServer.Close)
+	t.Cleanup(h.closeAll)
+	return h, "ws" + strings.TrimPrefix(httpServer.URL, "http") + "/events"
+}
+
+// readMessage reads the next message of the service
+func readMessage(t *testing.T, conn *websocket.Conn) map[string]interface{} {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var message map[string]interface{}
+	if err := conn.ReadJSON(&message); err != nil {
+		t.Fatalf("failed to read message: %v", err)
+	}
+	return message
+}
+
+func TestServeHTTPRequiresAPIKey(t *testing.T) {
+	_, url := newTestServer(t)
+	for _, header := range []http.Header{nil, {apiKeyHeader: []string{"unknown"}}} {
+		_, response, err := websocket.DefaultDialer.Dial(url, header)
+		if err == nil || response == nil || response.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("expected 401, got %v", err)
+		}
+		var body struct {
+			Error struct {
+				Code string `json:"code"`
+			} `json:"error"`
+		}
+		if json.NewDecoder(response.Body).Decode(&body) != nil || body.Error.Code != "UNAUTHENTICATED" {
+			t.Errorf("error body has code %q", body.Error.Code)
+		}
+	}
+
+	_, response, err := websocket.DefaultDialer.Dial(url+"?apiKey=key1&topic=balance:acc1", nil)
+	if err == nil || response.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid topic, got %v", err)
+	}
+}
+
+func TestSubscribeAndPush(t *testing.T) {
+	h, url := newTestServer(t)
+	conn, _, err := websocket.DefaultDialer.Dial(url+"?topic=asset:asset1", http.Header{apiKeyHeader: []string{"key1"}})
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer conn.Close()
+	if message := readMessage(t, conn); message["type"] != "subscriptions" || !reflect.DeepEqual(message["topics"], []interface{}{"asset:asset1"}) {
+		t.Fatalf("first message is %v", message)
+	}
+
+	err = conn.WriteJSON(request{Action: "subscribe", Topics: []string{"account:acc2"}})
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	if message := readMessage(t, conn); !reflect.DeepEqual(message["topics"], []interface{}{"account:acc2", "asset:asset1"}) {
+		t.Fatalf("subscriptions are %v", message)
+	}
+	conn.WriteJSON(request{Action: "resubscribe"})
+	if message := readMessage(t, conn); message["type"] != "error" || !strings.Contains(message["error"].(map[string]interface{})["message"].(string), "unknown action") {
+		t.Fatalf("expected an error for an unknown action, got %v", message)
+	}
+
+	h.publish(&pushEvent{Type: "event", Name: "AssetUpdated", TxID: "tx1", Payload: json.RawMessage(`{"assetID":"asset2"}`), topics: eventTopics([]byte(`{"assetID":"asset2"}`))})
+	payload := `{"from":"acc1","to":"acc2","value":10}`
+	h.publish(&pushEvent{Type: "event", Chaincode: "token_erc20", Name: "Transfer", BlockNumber: 7, TxID: "tx2", Payload: json.RawMessage(payload), topics: eventTopics([]byte(payload))})
+	message := readMessage(t, conn)
+	want := map[string]interface{}{"type": "event", "chaincode": "token_erc20", "name": "Transfer", "blockNumber": float64(7), "txID": "tx2",
+		"payload": map[string]interface{}{"from": "acc1", "to": "acc2", "value": float64(10)}}
+	if !reflect.DeepEqual(message, want) {
+		t.Errorf("pushed %v, want %v", message, want)
+	}
+}